@@ -0,0 +1,49 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+// GetAggregator retrieves the state of the aggregator role of snapd through
+// an HTTP GET call. The aggregator returns if it succeeds. Otherwise, an
+// error is returned.
+func (c *Client) GetAggregator() *GetAggregatorResult {
+	resp, err := c.do("GET", "/aggregator", ContentTypeJSON, nil)
+	if err != nil {
+		return &GetAggregatorResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.AggregatorReturnedType:
+		// Success
+		return &GetAggregatorResult{resp.Body.(*rbody.AggregatorReturned), nil}
+	case rbody.ErrorType:
+		return &GetAggregatorResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetAggregatorResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// GetAggregatorResult is the response from snap/client on a GetAggregator call.
+type GetAggregatorResult struct {
+	*rbody.AggregatorReturned
+	Err error
+}
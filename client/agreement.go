@@ -0,0 +1,241 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
+	"github.com/intelsdi-x/snap/mgmt/tribe/agreement"
+)
+
+// ExportAgreementResult is the response from snap/client on a ExportAgreement call.
+type ExportAgreementResult struct {
+	*agreement.Spec
+	Err error
+}
+
+// ImportAgreementResult is the response from snap/client on a ImportAgreement call.
+type ImportAgreementResult struct {
+	// Joined are the names of the members joined to the agreement.
+	Joined []string
+	// Loaded are the paths of the plugins loaded.
+	Loaded []string
+	// Added are the URLs of the plugins added for the members to download.
+	Added []string
+	// Created are the IDs of the tasks created, by task name.
+	Created map[string]string
+	// Missing are the plugins without a path or a URL which no member has.
+	Missing []agreement.PluginSpec
+	Err     error
+}
+
+// ExportAgreement returns the spec of a tribe agreement: its members, its
+// plugins and its tasks, which are retrieved from the members of the
+// agreement. The client sends task requests to those members from then on,
+// as UseAgreement does.
+func (c *Client) ExportAgreement(name string) *ExportAgreementResult {
+	a := c.GetAgreement(name)
+	if a.Err != nil {
+		return &ExportAgreementResult{Err: a.Err}
+	}
+	spec := &agreement.Spec{Name: name}
+	for m := range a.Agreement.Members {
+		spec.Members = append(spec.Members, m)
+	}
+	sort.Strings(spec.Members)
+	if a.Agreement.PluginAgreement != nil {
+		for _, p := range a.Agreement.PluginAgreement.Plugins {
+			spec.Plugins = append(spec.Plugins, agreement.PluginSpec{
+				Name:    p.Name(),
+				Type:    p.TypeName(),
+				Version: p.Version(),
+				URL:     p.URL(),
+			})
+		}
+	}
+	if a.Agreement.TaskAgreement == nil || len(a.Agreement.TaskAgreement.Tasks) == 0 {
+		return &ExportAgreementResult{Spec: spec}
+	}
+	if err := c.UseAgreement(name); err != nil {
+		return &ExportAgreementResult{Err: err}
+	}
+	names := map[string]bool{}
+	for _, t := range a.Agreement.TaskAgreement.Tasks {
+		r := c.GetTask(t.ID)
+		if r.Err != nil {
+			return &ExportAgreementResult{Err: fmt.Errorf("task %s: %v", t.ID, r.Err)}
+		}
+		tr := &request.TaskCreationRequest{
+			Deadline:    r.Deadline,
+			Workflow:    r.Workflow,
+			Priority:    r.Priority,
+			Alerts:      r.Alerts,
+			Shard:       r.Sharded,
+			Timestamps:  r.Timestamps,
+			Description: r.Description,
+		}
+		if r.Schedule != nil {
+			tr.Schedule = *r.Schedule
+		}
+		if tr.Schedule.Overrun == "" {
+			tr.Schedule.Overrun = r.OverrunPolicy
+		}
+		if tr.Schedule.OverrunQueueDepth == 0 {
+			tr.Schedule.OverrunQueueDepth = uint(r.OverrunQueueDepth)
+		}
+		// tasks are identified by their names in a spec
+		tname := r.Name
+		if tname == "" || names[tname] {
+			tname = t.ID
+		}
+		names[tname] = true
+		spec.Tasks = append(spec.Tasks, agreement.TaskSpec{
+			Name:    tname,
+			Task:    tr,
+			NoStart: !t.StartOnCreate,
+		})
+	}
+	return &ExportAgreementResult{Spec: spec}
+}
+
+// ImportAgreement creates the agreement of the spec unless it exists, joins
+// the members the spec selects to it, then loads the plugins and creates the
+// tasks of the spec which the agreement does not have yet through one of its
+// members. Importing the same spec again therefore changes nothing.
+func (c *Client) ImportAgreement(spec *agreement.Spec) *ImportAgreementResult {
+	res := &ImportAgreementResult{Created: map[string]string{}}
+	if errs := spec.Validate(); len(errs) > 0 {
+		res.Err = errs[0]
+		return res
+	}
+	a := c.GetAgreement(spec.Name)
+	if a.Err != nil {
+		if r := c.AddAgreement(spec.Name); r.Err != nil {
+			res.Err = r.Err
+			return res
+		}
+		if a = c.GetAgreement(spec.Name); a.Err != nil {
+			res.Err = a.Err
+			return res
+		}
+	}
+	members := c.ListMembers()
+	if members.Err != nil {
+		res.Err = members.Err
+		return res
+	}
+	for _, m := range members.Members {
+		if _, ok := a.Agreement.Members[m]; ok || !spec.Selects(m) {
+			continue
+		}
+		if r := c.JoinAgreement(spec.Name, m); r.Err != nil {
+			res.Err = fmt.Errorf("member %s: %v", m, r.Err)
+			return res
+		}
+		res.Joined = append(res.Joined, m)
+	}
+	if len(spec.Plugins) == 0 && len(spec.Tasks) == 0 {
+		return res
+	}
+	if err := c.UseAgreement(spec.Name); err != nil {
+		res.Err = err
+		return res
+	}
+	mc := c.memberClient()
+	for _, p := range spec.Plugins {
+		if p.Name != "" && hasPlugin(a, p) {
+			continue
+		}
+		if p.Path == "" && p.URL != "" {
+			if r := c.AddAgreementPlugin(spec.Name, p.Name, p.Type, p.Version, p.URL); r.Err != nil {
+				res.Err = fmt.Errorf("plugin %s: %v", p.URL, r.Err)
+				return res
+			}
+			res.Added = append(res.Added, p.URL)
+			continue
+		}
+		if p.Path == "" {
+			res.Missing = append(res.Missing, p)
+			continue
+		}
+		if r := mc.LoadPlugin([]string{p.Path}); r.Err != nil {
+			res.Err = fmt.Errorf("plugin %s: %v", p.Path, r.Err)
+			return res
+		}
+		res.Loaded = append(res.Loaded, p.Path)
+	}
+	existing := map[string]bool{}
+	if a.Agreement.TaskAgreement != nil {
+		for _, t := range a.Agreement.TaskAgreement.Tasks {
+			if r := c.GetTask(t.ID); r.Err == nil {
+				existing[r.Name] = true
+			}
+		}
+	}
+	for _, t := range spec.Tasks {
+		if existing[t.Name] {
+			continue
+		}
+		tr, err := t.Request()
+		if err != nil {
+			res.Err = fmt.Errorf("task %s: %v", t.Name, err)
+			return res
+		}
+		r := c.CreateTaskFromRequest(tr)
+		if r.Err != nil {
+			res.Err = fmt.Errorf("task %s: %v", t.Name, r.Err)
+			return res
+		}
+		res.Created[t.Name] = r.ID
+	}
+	return res
+}
+
+// memberClient returns a copy of the client which only sends requests to
+// the agreement members found by UseAgreement.
+func (c *Client) memberClient() *Client {
+	mc := *c
+	mc.endpoints = &endpoints{}
+	c.endpoints.Lock()
+	defer c.endpoints.Unlock()
+	for _, ep := range c.endpoints.list {
+		if ep.member {
+			mc.endpoints.list = append(mc.endpoints.list, &endpoint{url: ep.url, prefix: ep.prefix, member: true})
+		}
+	}
+	return &mc
+}
+
+// hasPlugin returns whether the agreement has the plugin, whatever its
+// version when the spec gives none.
+func hasPlugin(a *GetAgreementResult, p agreement.PluginSpec) bool {
+	if a.Agreement.PluginAgreement == nil {
+		return false
+	}
+	for _, ap := range a.Agreement.PluginAgreement.Plugins {
+		if ap.Name() == p.Name && strings.EqualFold(ap.TypeName(), p.Type) && (p.Version == 0 || ap.Version() == p.Version) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+// GetAlerts retrieves the alerts firing through an HTTP GET call.
+// A list of alerts returns if it succeeds. Otherwise, an error is returned.
+func (c *Client) GetAlerts() *GetAlertsResult {
+	resp, err := c.do("GET", "/alerts", ContentTypeJSON, nil)
+	if err != nil {
+		return &GetAlertsResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.AlertListReturnedType:
+		// Success
+		return &GetAlertsResult{resp.Body.(*rbody.AlertListReturned), nil}
+	case rbody.ErrorType:
+		return &GetAlertsResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetAlertsResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// GetAlertsResult is the response from snap/client on a GetAlerts call.
+type GetAlertsResult struct {
+	*rbody.AlertListReturned
+	Err error
+}
@@ -0,0 +1,110 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+// GetAliases retrieves the aliases of the metric catalog through an HTTP GET
+// call. A list of aliases returns if it succeeds. Otherwise, an error is
+// returned.
+func (c *Client) GetAliases() *GetAliasesResult {
+	resp, err := c.do("GET", "/aliases", ContentTypeJSON, nil)
+	if err != nil {
+		return &GetAliasesResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.AliasListReturnedType:
+		// Success
+		return &GetAliasesResult{resp.Body.(*rbody.AliasListReturned), nil}
+	case rbody.ErrorType:
+		return &GetAliasesResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetAliasesResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// AddAlias makes the alias namespace (e.g. /company/cpu/util) stand for the
+// target namespace (e.g. /intel/psutil/cpu/percent) through an HTTP PUT
+// call. The added alias returns if it succeeds. Otherwise, an error is
+// returned.
+func (c *Client) AddAlias(alias, target string) *AddAliasResult {
+	j, err := json.Marshal(struct {
+		Target string `json:"target"`
+	}{target})
+	if err != nil {
+		return &AddAliasResult{Err: err}
+	}
+	resp, err := c.do("PUT", fmt.Sprintf("/aliases/%s", strings.TrimPrefix(alias, "/")), ContentTypeJSON, j)
+	if err != nil {
+		return &AddAliasResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.AliasAddedType:
+		// Success
+		return &AddAliasResult{resp.Body.(*rbody.AliasAdded), nil}
+	case rbody.ErrorType:
+		return &AddAliasResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &AddAliasResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// RemoveAlias removes an alias of the metric catalog through an HTTP DELETE
+// call. The removed alias returns if it succeeds. Otherwise, an error is
+// returned.
+func (c *Client) RemoveAlias(alias string) *RemoveAliasResult {
+	resp, err := c.do("DELETE", fmt.Sprintf("/aliases/%s", strings.TrimPrefix(alias, "/")), ContentTypeJSON, nil)
+	if err != nil {
+		return &RemoveAliasResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.AliasRemovedType:
+		// Success
+		return &RemoveAliasResult{resp.Body.(*rbody.AliasRemoved), nil}
+	case rbody.ErrorType:
+		return &RemoveAliasResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &RemoveAliasResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// GetAliasesResult is the response from snap/client on a GetAliases call.
+type GetAliasesResult struct {
+	*rbody.AliasListReturned
+	Err error
+}
+
+// AddAliasResult is the response from snap/client on an AddAlias call.
+type AddAliasResult struct {
+	*rbody.AliasAdded
+	Err error
+}
+
+// RemoveAliasResult is the response from snap/client on a RemoveAlias call.
+type RemoveAliasResult struct {
+	*rbody.AliasRemoved
+	Err error
+}
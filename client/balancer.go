@@ -0,0 +1,265 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/mgmt/tribe/agreement"
+)
+
+// DefaultHealthCheckInterval is how long an endpoint which could not be
+// reached is avoided before requests are sent to it again
+const DefaultHealthCheckInterval = 30 * time.Second
+
+// ErrNoAgreementMembers is returned by UseAgreement when none of the members
+// of the agreement advertise a REST API.
+var ErrNoAgreementMembers = errors.New("No member of the agreement has a REST API")
+
+// Endpoints is an option that can be provided to the func client.New to add
+// snapd instances, e.g. the other members of a tribe, which requests fail
+// over to when the URL given to client.New cannot be reached.
+func Endpoints(urls ...string) metaOp {
+	return func(c *Client) {
+		c.urls = append(c.urls, urls...)
+	}
+}
+
+// EndpointStatus describes one of the snapd instances of a client.
+type EndpointStatus struct {
+	URL string
+	// Healthy is false while the endpoint is avoided after a failure.
+	Healthy bool
+	// Latency is the duration of the last health check of the endpoint,
+	// zero when it has not been checked.
+	Latency time.Duration
+	// Member is true for the endpoints of the agreement members found by
+	// UseAgreement.
+	Member bool
+}
+
+// endpoint is one of the snapd instances a client sends its requests to.
+type endpoint struct {
+	url     string
+	prefix  string
+	member  bool
+	latency time.Duration
+	// failed is when the endpoint last failed, zero when it is healthy.
+	failed time.Time
+}
+
+// endpoints is shared by a client and its copies made with WithContext.
+type endpoints struct {
+	sync.Mutex
+	list []*endpoint
+	// next rotates the agreement members task requests are sent to.
+	next int
+}
+
+func (e *endpoints) add(url, ver string, member bool) {
+	e.Lock()
+	defer e.Unlock()
+	for _, ep := range e.list {
+		if ep.url == url {
+			ep.member = ep.member || member
+			return
+		}
+	}
+	e.list = append(e.list, &endpoint{url: url, prefix: url + "/" + ver, member: member})
+}
+
+// route returns the endpoints to send a request for the path to, in the
+// order they should be tried. Healthy endpoints come first:
+//   - task requests go to the agreement members, when known, in turn since
+//     the tasks of an agreement are shared by all its members;
+//   - metric requests go to the endpoint with the lowest latency;
+//   - other requests go to the endpoints in the order they were given.
+func (e *endpoints) route(path string, recheck time.Duration) []*endpoint {
+	e.Lock()
+	defer e.Unlock()
+	var up, down []*endpoint
+	for _, ep := range e.list {
+		if strings.HasPrefix(path, "/tasks") && e.hasMembers() && !ep.member {
+			continue
+		}
+		if ep.failed.IsZero() || time.Since(ep.failed) > recheck {
+			up = append(up, ep)
+		} else {
+			down = append(down, ep)
+		}
+	}
+	switch {
+	case strings.HasPrefix(path, "/tasks") && len(up) > 1:
+		n := e.next % len(up)
+		e.next++
+		up = append(up[n:], up[:n]...)
+	case strings.HasPrefix(path, "/metrics"):
+		sort.SliceStable(up, func(i, j int) bool {
+			return nearer(up[i], up[j])
+		})
+	}
+	return append(up, down...)
+}
+
+func (e *endpoints) hasMembers() bool {
+	for _, ep := range e.list {
+		if ep.member {
+			return true
+		}
+	}
+	return false
+}
+
+// nearer returns whether a has a lower latency than b, endpoints which
+// have not been checked being the farthest.
+func nearer(a, b *endpoint) bool {
+	if a.latency == 0 || b.latency == 0 {
+		return a.latency != 0
+	}
+	return a.latency < b.latency
+}
+
+// observe records whether the endpoint answered a request.
+func (e *endpoints) observe(ep *endpoint, rsp *http.Response, err error) {
+	e.Lock()
+	defer e.Unlock()
+	switch {
+	case err != nil:
+		if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+			ep.failed = time.Now()
+		}
+	case rsp.StatusCode == http.StatusBadGateway, rsp.StatusCode == http.StatusServiceUnavailable, rsp.StatusCode == http.StatusGatewayTimeout:
+		ep.failed = time.Now()
+	default:
+		ep.failed = time.Time{}
+	}
+}
+
+func (e *endpoints) status(recheck time.Duration) []EndpointStatus {
+	e.Lock()
+	defer e.Unlock()
+	s := make([]EndpointStatus, len(e.list))
+	for i, ep := range e.list {
+		s[i] = EndpointStatus{
+			URL:     ep.url,
+			Healthy: ep.failed.IsZero() || time.Since(ep.failed) > recheck,
+			Latency: ep.latency,
+			Member:  ep.member,
+		}
+	}
+	return s
+}
+
+// prefixFor returns the prefix of the first endpoint to send a request for
+// the path to.
+func (c *Client) prefixFor(path string) string {
+	return c.endpoints.route(path, c.HealthCheckInterval)[0].prefix
+}
+
+// Endpoints returns the status of the snapd instances of the client.
+func (c *Client) Endpoints() []EndpointStatus {
+	return c.endpoints.status(c.HealthCheckInterval)
+}
+
+// CheckHealth requests every snapd instance of the client, recording which
+// ones are reachable and their latency, which metric requests are routed by.
+func (c *Client) CheckHealth() []EndpointStatus {
+	c.endpoints.Lock()
+	list := append([]*endpoint{}, c.endpoints.list...)
+	c.endpoints.Unlock()
+
+	var wg sync.WaitGroup
+	for _, ep := range list {
+		wg.Add(1)
+		go func(ep *endpoint) {
+			defer wg.Done()
+			req, err := c.newRequest("GET", ep.prefix+"/plugins", nil)
+			if err != nil {
+				return
+			}
+			start := time.Now()
+			rsp, err := c.http.Do(req)
+			latency := time.Since(start)
+			if err == nil {
+				rsp.Body.Close()
+			}
+			c.endpoints.observe(ep, rsp, err)
+			if err == nil {
+				c.endpoints.Lock()
+				ep.latency = latency
+				c.endpoints.Unlock()
+			}
+		}(ep)
+	}
+	wg.Wait()
+	return c.Endpoints()
+}
+
+// UseAgreement adds the members of the tribe agreement as endpoints of the
+// client, and sends task requests to them from then on.
+func (c *Client) UseAgreement(name string) error {
+	a := c.GetAgreement(name)
+	if a.Err != nil {
+		return a.Err
+	}
+	found := false
+	for mname := range a.Agreement.Members {
+		m := c.GetMember(mname)
+		if m.Err != nil {
+			return m.Err
+		}
+		url := memberURL(m.TribeMemberShow)
+		if url == "" {
+			continue
+		}
+		if err := parseURL(url); err != nil {
+			return err
+		}
+		c.endpoints.add(url, c.Version, true)
+		found = true
+	}
+	if !found {
+		return ErrNoAgreementMembers
+	}
+	return nil
+}
+
+// memberURL returns the URL of the REST API of a tribe member, or an empty
+// string when the member does not advertise it.
+func memberURL(m *rbody.TribeMemberShow) string {
+	port := m.Tags[agreement.RestPort]
+	if m.Addr == "" || port == "" {
+		return ""
+	}
+	proto := m.Tags[agreement.RestProtocol]
+	if proto == "" {
+		proto = "http"
+	}
+	return fmt.Sprintf("%s://%s", proto, net.JoinHostPort(m.Addr, port))
+}
@@ -0,0 +1,143 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/mgmt/tribe/agreement"
+)
+
+func countingServer(calls *int32, delay time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		time.Sleep(delay)
+		fmt.Fprint(w, emptyLogs)
+	}))
+}
+
+func TestClientEndpoints(t *testing.T) {
+	Convey("Client with several endpoints", t, func() {
+		var aCalls, bCalls int32
+		a := countingServer(&aCalls, 20*time.Millisecond)
+		defer a.Close()
+		b := countingServer(&bCalls, 0)
+		defer b.Close()
+		down := httptest.NewServer(http.NotFoundHandler())
+		down.Close()
+
+		Convey("rejects an invalid endpoint", func() {
+			_, err := New(a.URL, "v1", false, Endpoints("localhost"))
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("fails over when an endpoint cannot be reached", func() {
+			c, err := New(down.URL, "v1", false, Endpoints(b.URL), Retries(0, 0))
+			So(err, ShouldBeNil)
+			_, err = c.do("POST", "/tasks", ContentTypeJSON, []byte("{}"))
+			So(err, ShouldBeNil)
+			So(atomic.LoadInt32(&bCalls), ShouldEqual, 1)
+
+			Convey("and avoids it afterwards", func() {
+				eps := c.Endpoints()
+				So(eps, ShouldHaveLength, 2)
+				So(eps[0].Healthy, ShouldBeFalse)
+				So(eps[1].Healthy, ShouldBeTrue)
+				So(c.GetLogs("", 0).Err, ShouldBeNil)
+				So(atomic.LoadInt32(&bCalls), ShouldEqual, 2)
+			})
+
+			Convey("until the health check interval is over", func() {
+				c.HealthCheckInterval = 0
+				So(c.Endpoints()[0].Healthy, ShouldBeTrue)
+			})
+		})
+
+		Convey("sends other requests to the first endpoint", func() {
+			c, err := New(a.URL, "v1", false, Endpoints(b.URL))
+			So(err, ShouldBeNil)
+			So(c.GetLogs("", 0).Err, ShouldBeNil)
+			So(atomic.LoadInt32(&aCalls), ShouldEqual, 1)
+			So(atomic.LoadInt32(&bCalls), ShouldEqual, 0)
+		})
+
+		Convey("sends metric requests to the nearest endpoint", func() {
+			c, err := New(a.URL, "v1", false, Endpoints(b.URL, down.URL))
+			So(err, ShouldBeNil)
+			eps := c.CheckHealth()
+			So(eps[0].Latency, ShouldBeGreaterThan, eps[1].Latency)
+			So(eps[2].Healthy, ShouldBeFalse)
+			So(eps[2].Latency, ShouldEqual, 0)
+			_, err = c.do("GET", "/metrics", ContentTypeJSON)
+			So(err, ShouldBeNil)
+			So(atomic.LoadInt32(&aCalls), ShouldEqual, 1)
+			So(atomic.LoadInt32(&bCalls), ShouldEqual, 2)
+		})
+
+		Convey("sends task requests to the agreement members in turn", func() {
+			var mCalls int32
+			m := countingServer(&mCalls, 0)
+			defer m.Close()
+			c, err := New(a.URL, "v1", false)
+			So(err, ShouldBeNil)
+			c.endpoints.add(b.URL, "v1", true)
+			c.endpoints.add(m.URL, "v1", true)
+			for i := 0; i < 4; i++ {
+				_, err = c.do("GET", "/tasks", ContentTypeJSON)
+				So(err, ShouldBeNil)
+			}
+			So(atomic.LoadInt32(&aCalls), ShouldEqual, 0)
+			So(atomic.LoadInt32(&bCalls), ShouldEqual, 2)
+			So(atomic.LoadInt32(&mCalls), ShouldEqual, 2)
+		})
+	})
+}
+
+func TestMemberURL(t *testing.T) {
+	Convey("memberURL", t, func() {
+		m := &rbody.TribeMemberShow{
+			Addr: "10.0.0.1",
+			Tags: map[string]string{agreement.RestPort: "8181"},
+		}
+		Convey("defaults to http", func() {
+			So(memberURL(m), ShouldEqual, "http://10.0.0.1:8181")
+		})
+		Convey("uses the protocol of the member", func() {
+			m.Tags[agreement.RestProtocol] = "https"
+			So(memberURL(m), ShouldEqual, "https://10.0.0.1:8181")
+		})
+		Convey("brackets IPv6 addresses", func() {
+			m.Addr = "::1"
+			So(memberURL(m), ShouldEqual, "http://[::1]:8181")
+		})
+		Convey("is empty without a REST API port", func() {
+			delete(m.Tags, agreement.RestPort)
+			So(memberURL(m), ShouldEqual, "")
+		})
+	})
+}
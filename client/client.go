@@ -0,0 +1,537 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/pkg/reqsign"
+)
+
+var (
+	CompressUpload = true
+
+	ErrUnknown     = errors.New("Unknown error calling API")
+	ErrNilResponse = errors.New("Nil response from JSON unmarshalling")
+	ErrDirNotFile  = errors.New("Provided plugin path is a directory not file")
+)
+
+const (
+	ContentTypeJSON contentType = iota
+	ContentTypeBinary
+)
+
+type contentType int
+
+var (
+	contentTypes = [...]string{
+		"application/json",
+		"application/octet-stream",
+	}
+)
+
+const (
+	// DefaultRetries is the number of times a failed request is retried
+	DefaultRetries = 2
+	// DefaultRetryBackoff is the delay before the first retry, doubled
+	// before each of the next ones
+	DefaultRetryBackoff = 100 * time.Millisecond
+)
+
+type Client struct {
+	// URL specifies HTTP API request uniform resource locator.
+	URL string
+	// Version specifies the version of a HTTP client.
+	Version string
+	// http is a pointer to a net/http client.
+	http *http.Client
+	// prefix is the string concatenation of a request URL, forward slash
+	// and the request client version.
+	prefix string
+	// Basic http auth username/password
+	Username string
+	Password string
+	// Token is the bearer token sent instead of the basic authentication,
+	// e.g. a static token of snapd or a token of its OIDC provider
+	Token string
+	// signer signs the requests, e.g. of a tribe member to another, which
+	// are then sent without password
+	signer *reqsign.Signer
+	// Retries is the number of times a request is retried when snapd cannot
+	// be reached, or for GET requests when the response is lost or snapd
+	// answers 502, 503 or 504.
+	Retries int
+	// RetryBackoff is the delay before the first retry, doubled before
+	// each of the next ones.
+	RetryBackoff time.Duration
+	// HealthCheckInterval is how long an endpoint which failed is avoided
+	// when the client has several endpoints.
+	HealthCheckInterval time.Duration
+
+	ctx       context.Context
+	tlsConfig *tls.Config
+	urls      []string
+	endpoints *endpoints
+}
+
+// Checks validity of URL
+func parseURL(s string) error {
+	if !(govalidator.IsURL(s) || isIPv6URL(s)) || !strings.HasPrefix(s, "http") {
+		return fmt.Errorf("URL %s is not in the format of http(s)://<ip>:<port>", s)
+	}
+	return nil
+}
+
+// isIPv6URL returns whether the host of the URL is an IPv6 address, e.g.
+// http://[::1]:8181, which govalidator does not accept.
+func isIPv6URL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(u.Hostname())
+	return ip != nil && ip.To4() == nil
+}
+
+type metaOp func(c *Client)
+
+//Password is an option than can be provided to the func client.New.
+func Password(p string) metaOp {
+	return func(c *Client) {
+		c.Password = strings.TrimSpace(p)
+	}
+}
+
+//Token is an option that can be provided to the func client.New.
+func Token(t string) metaOp {
+	return func(c *Client) {
+		c.Token = strings.TrimSpace(t)
+	}
+}
+
+//RequestSigner is an option that can be provided to the func client.New
+//for the requests to be signed. A nil signer leaves them unsigned.
+func RequestSigner(s *reqsign.Signer) metaOp {
+	return func(c *Client) {
+		c.signer = s
+	}
+}
+
+//Username is an option that can be provided to the func client.New.
+func Username(u string) metaOp {
+	return func(c *Client) {
+		c.Username = u
+	}
+}
+
+// Retries is an option that can be provided to the func client.New to set
+// the number of retries of the failed requests and the delay before the
+// first one.
+func Retries(n int, backoff time.Duration) metaOp {
+	return func(c *Client) {
+		c.Retries = n
+		c.RetryBackoff = backoff
+	}
+}
+
+// TLSConfig is an option that can be provided to the func client.New to set
+// the TLS configuration used with an https URL, e.g. its root CAs or client
+// certificates.
+func TLSConfig(cfg *tls.Config) metaOp {
+	return func(c *Client) {
+		c.tlsConfig = cfg
+	}
+}
+
+// New returns a pointer to a snap api client
+// if ver is an empty string, v1 is used by default
+func New(url, ver string, insecure bool, opts ...metaOp) (*Client, error) {
+	if err := parseURL(url); err != nil {
+		return nil, err
+	}
+	if ver == "" {
+		ver = "v1"
+	}
+	c := &Client{
+		URL:          url,
+		Version:      ver,
+		Retries:             DefaultRetries,
+		RetryBackoff:        DefaultRetryBackoff,
+		HealthCheckInterval: DefaultHealthCheckInterval,
+		tlsConfig:           &tls.Config{},
+		endpoints:           &endpoints{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.endpoints.add(url, ver, false)
+	for _, u := range c.urls {
+		if err := parseURL(u); err != nil {
+			return nil, err
+		}
+		c.endpoints.add(u, ver, false)
+	}
+	if insecure {
+		// the config may be shared by the caller
+		c.tlsConfig = c.tlsConfig.Clone()
+		c.tlsConfig.InsecureSkipVerify = true
+	}
+	c.http = &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: c.tlsConfig,
+		},
+	}
+	c.prefix = url + "/" + ver
+	return c, nil
+}
+
+// WithContext returns a copy of the client whose requests are made with the
+// context, so that they can be cancelled or given a deadline. Streams, like
+// the ones of WatchTask and TailLogs, end when the context is done.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	c2 := *c
+	c2.ctx = ctx
+	return &c2
+}
+
+func (c *Client) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// newRequest returns a request to snapd with the context and the auth of
+// the client.
+func (c *Client) newRequest(method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.authorize(req); err != nil {
+		return nil, err
+	}
+	return req.WithContext(c.context()), nil
+}
+
+// String returns the string representation of the content type given a content number.
+func (t contentType) String() string {
+	return contentTypes[t]
+}
+
+// authorize signs the request when the client has a signer, and adds the
+// token or password of the client otherwise
+func (c *Client) authorize(req *http.Request) error {
+	if c.signer != nil {
+		return c.signer.Sign(req)
+	}
+	addAuth(req, c.Username, c.Password, c.Token)
+	return nil
+}
+
+/*
+   Add's auth info to request if a token or password is set.
+*/
+func addAuth(req *http.Request, username, password, token string) {
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return
+	}
+	if password != "" {
+		if username == "" {
+			username = "snap"
+		}
+		req.SetBasicAuth(username, password)
+	}
+}
+
+/*
+   do handles all interactions with snap's REST API.
+   we use the variadic function signature so that all actions can use the same
+   function, including ones which do not include a request body.
+*/
+
+func (c *Client) do(method, path string, ct contentType, body ...[]byte) (*rbody.APIResponse, error) {
+	var b []byte
+	if len(body) > 0 {
+		b = body[0]
+	}
+	rsp, err := c.send(method, path, ct, b)
+	if err != nil {
+		return nil, err
+	}
+	return httpRespToAPIResp(rsp)
+}
+
+// send sends a request to snapd. As long as it fails in a way that is safe
+// to retry, it fails over to the other endpoints of the client, then retries
+// it with a backoff.
+func (c *Client) send(method, path string, ct contentType, body []byte) (*http.Response, error) {
+	eps := c.endpoints.route(path, c.HealthCheckInterval)
+	backoff := c.RetryBackoff
+	retries := 0
+	for i := 0; ; i++ {
+		ep := eps[i%len(eps)]
+		req, err := c.newRequest(method, ep.prefix+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		switch method {
+		case "PUT", "POST", "PATCH":
+			req.Header.Add("Content-Type", ct.String())
+		case "DELETE":
+			req.Header.Add("Content-Type", "application/json")
+		}
+		rsp, err := c.http.Do(req)
+		c.endpoints.observe(ep, rsp, err)
+		if retryable(method, rsp, err) && (i+1 < len(eps) || retries < c.Retries) {
+			if rsp != nil {
+				rsp.Body.Close()
+			}
+			if i+1 < len(eps) {
+				continue
+			}
+			retries++
+			select {
+			case <-time.After(backoff):
+			case <-c.context().Done():
+				return nil, c.context().Err()
+			}
+			backoff *= 2
+			continue
+		}
+		if err != nil {
+			return nil, c.connectionError(err)
+		}
+		return rsp, nil
+	}
+}
+
+// retryable returns whether a request can be sent again: when it did not
+// reach snapd, or for a GET when its response was lost or snapd was
+// unavailable.
+func retryable(method string, rsp *http.Response, err error) bool {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return true
+		}
+		return method == "GET"
+	}
+	if method != "GET" {
+		return false
+	}
+	switch rsp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func (c *Client) connectionError(err error) error {
+	if strings.Contains(err.Error(), "tls: oversized record") || strings.Contains(err.Error(), "malformed HTTP response") {
+		return fmt.Errorf("error connecting to API URI: %s. Do you have an http/https mismatch?", c.URL)
+	}
+	return fmt.Errorf("URL target is not available. %v", err)
+}
+
+func httpRespToAPIResp(rsp *http.Response) (*rbody.APIResponse, error) {
+	if rsp.StatusCode == 401 {
+		return nil, fmt.Errorf("Invalid credentials")
+	}
+	resp := new(rbody.APIResponse)
+	b, err := ioutil.ReadAll(rsp.Body)
+	rsp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	jErr := json.Unmarshal(b, resp)
+	// If unmarshaling fails show first part of response to help debug
+	// connection issues.
+	if jErr != nil {
+		bound := 1000
+		var invalidResp string
+		if len(b) > bound {
+			invalidResp = string(b[:bound])
+		} else {
+			invalidResp = string(b)
+		}
+		return nil, fmt.Errorf("Unknown API response: %s\n\n Received: %s", jErr, invalidResp)
+	}
+	if resp == nil {
+		// Catch corner case where JSON gives no error but resp is nil
+		return nil, ErrNilResponse
+	}
+	// Add copy of JSON response string
+	resp.JSONResponse = string(b)
+	return resp, nil
+}
+
+func (c *Client) pluginUploadRequest(pluginPaths []string) (*rbody.APIResponse, error) {
+	errChan := make(chan error, 1)
+	pr, pw := io.Pipe()
+	var body io.Reader = pr
+	var out io.WriteCloser = pw
+	if c.signer != nil {
+		// the body of a signed request is digested, so it is buffered
+		// rather than streamed
+		buf := &bytes.Buffer{}
+		body, out = buf, nopWriteCloser{buf}
+	}
+	writer := multipart.NewWriter(out)
+	var bufins []*bufio.Reader
+	var paths []string
+
+	for i, pluginPath := range pluginPaths {
+		path, err := os.Stat(pluginPaths[i])
+		if err != nil {
+			return nil, err
+		}
+		if path.IsDir() {
+			err := ErrDirNotFile
+			return nil, err
+		}
+		file, err := os.Open(pluginPaths[i])
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		bufin := bufio.NewReader(file)
+		bufins = append(bufins, bufin)
+
+		paths = append(paths, filepath.Base(pluginPath))
+	}
+	if c.signer != nil {
+		writePluginToWriter(out, bufins, writer, paths, errChan)
+		if err := <-errChan; err != nil {
+			return nil, err
+		}
+		close(errChan)
+	} else {
+		// with io.Pipe the write needs to be async
+		go writePluginToWriter(pw, bufins, writer, paths, errChan)
+	}
+
+	req, err := c.newRequest("POST", c.prefixFor("/plugins")+"/plugins", body)
+	if err != nil {
+		return nil, fmt.Errorf("URL target is not available. %v", err)
+	}
+
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	if CompressUpload {
+		req.Header.Add("Plugin-Compression", "gzip")
+	}
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return nil, c.connectionError(err)
+	}
+	cErr := <-errChan
+	if cErr != nil {
+		return nil, cErr
+	}
+	return httpRespToAPIResp(rsp)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func writePluginToWriter(pw io.WriteCloser, bufin []*bufio.Reader, writer *multipart.Writer, pluginPaths []string, errChan chan error) {
+	for i, pluginPath := range pluginPaths {
+		part, err := writer.CreateFormFile("snap-plugins", pluginPath)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		if CompressUpload {
+			cpart := gzip.NewWriter(part)
+			_, err := bufin[i].WriteTo(cpart)
+			if err != nil {
+				errChan <- err
+				return
+			}
+			err = cpart.Close()
+			if err != nil {
+				errChan <- err
+				return
+			}
+		} else {
+			_, err := bufin[i].WriteTo(part)
+			if err != nil {
+				errChan <- err
+				return
+			}
+		}
+	}
+	err := writer.Close()
+	if err != nil {
+		errChan <- err
+		return
+	}
+	err = pw.Close()
+	if err != nil {
+		errChan <- err
+		return
+	}
+	errChan <- nil
+}
+
+// Passthrough for tribe request to allow use of client auth.
+func (c *Client) TribeRequest() (*http.Response, error) {
+	req, err := http.NewRequest("GET", c.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(c.context())
+	if err := c.authorize(req); err != nil {
+		return nil, err
+	}
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
@@ -34,6 +34,7 @@ import (
 
 	"github.com/intelsdi-x/snap/control"
 	"github.com/intelsdi-x/snap/mgmt/rest"
+	"github.com/intelsdi-x/snap/pkg/logbuffer"
 	"github.com/intelsdi-x/snap/scheduler"
 	"github.com/intelsdi-x/snap/scheduler/wmap"
 	. "github.com/smartystreets/goconvey/convey"
@@ -57,7 +58,7 @@ var (
 )
 
 func getWMFromSample(sample string) *wmap.WorkflowMap {
-	jsonP, err := ioutil.ReadFile("../wmap_sample/" + sample)
+	jsonP, err := ioutil.ReadFile("../mgmt/rest/wmap_sample/" + sample)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -83,6 +84,7 @@ func startAPI() string {
 	r.BindConfigManager(c.Config)
 	r.BindMetricManager(c)
 	r.BindTaskManager(s)
+	r.BindLogBuffer(logbuffer.Default)
 	go func(ch <-chan error) {
 		// Block on the error channel. Will return exit status 1 for an error or just return if the channel closes.
 		err, ok := <-ch
@@ -484,7 +486,12 @@ func TestSnapClient(t *testing.T) {
 				So(p2.Version, ShouldEqual, 2)
 				So(p2.Type, ShouldEqual, "collector")
 
+				// the publisher is still used by a running task
 				p3 := c.UnloadPlugin("publisher", "file", 3)
+				So(p3.Err, ShouldNotBeNil)
+				So(p3.Err.Error(), ShouldContainSubstring, "in use")
+
+				p3 = c.UnloadPluginForce("publisher", "file", 3)
 				So(p3.Err, ShouldBeNil)
 				So(p3.Name, ShouldEqual, "file")
 				So(p3.Version, ShouldEqual, 3)
@@ -533,3 +540,49 @@ func TestSnapClient(t *testing.T) {
 		So(c, ShouldBeNil)
 	})
 }
+
+func TestSnapClientLogs(t *testing.T) {
+	uri := startAPI()
+	c, cerr := New(uri, "v1", true)
+
+	Convey("Client should get and tail the logs", t, func() {
+		So(cerr, ShouldBeNil)
+		logbuffer.Default.Add(logbuffer.Entry{Time: time.Now(), Level: "info", Component: "plugin:client-test", Message: "first"})
+		logbuffer.Default.Add(logbuffer.Entry{Time: time.Now(), Level: "info", Component: "plugin:client-test", Message: "second"})
+
+		Convey("GetLogs returns the last entries of a component", func() {
+			r := c.GetLogs("plugin:client-test", 1)
+			So(r.Err, ShouldBeNil)
+			So(len(r.Entries), ShouldEqual, 1)
+			So(r.Entries[0].Message, ShouldEqual, "second")
+			So(r.Entries[0].Component, ShouldEqual, "plugin:client-test")
+		})
+		Convey("GetLogs errors on a bad number of lines", func() {
+			resp, err := c.do("GET", "/logs?lines=-1", ContentTypeJSON, nil)
+			So(err, ShouldBeNil)
+			So(resp.Meta.Code, ShouldEqual, 400)
+		})
+		Convey("TailLogs streams the last entries then the next ones", func() {
+			r := c.TailLogs("plugin:client-test", 1)
+			So(r.Err, ShouldBeNil)
+			defer r.Close()
+			var got []string
+			timeout := time.After(5 * time.Second)
+			for len(got) < 2 {
+				select {
+				case e, ok := <-r.EntryChan:
+					if !ok {
+						t.Fatal("log stream ended early")
+					}
+					got = append(got, e.Message)
+					if len(got) == 1 {
+						logbuffer.Default.Add(logbuffer.Entry{Time: time.Now(), Level: "info", Component: "plugin:client-test", Message: "third"})
+					}
+				case <-timeout:
+					t.Fatal("timed out waiting for log entries")
+				}
+			}
+			So(got, ShouldResemble, []string{"second", "third"})
+		})
+	})
+}
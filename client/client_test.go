@@ -0,0 +1,158 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const emptyLogs = `{"meta":{"code":200,"message":"Log entries returned","type":"log_entries_returned","version":1},"body":{"entries":[]}}`
+
+func TestClientRetries(t *testing.T) {
+	Convey("Client retries", t, func() {
+		var calls int32
+		failures := int32(2)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) <= atomic.LoadInt32(&failures) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, emptyLogs)
+		}))
+		defer ts.Close()
+
+		Convey("GET requests until snapd is available", func() {
+			c, err := New(ts.URL, "v1", false, Retries(2, time.Millisecond))
+			So(err, ShouldBeNil)
+			r := c.GetLogs("", 0)
+			So(r.Err, ShouldBeNil)
+			So(atomic.LoadInt32(&calls), ShouldEqual, 3)
+		})
+
+		Convey("GET requests no more than the retries", func() {
+			atomic.StoreInt32(&failures, 5)
+			c, err := New(ts.URL, "v1", false, Retries(1, time.Millisecond))
+			So(err, ShouldBeNil)
+			r := c.GetLogs("", 0)
+			So(r.Err, ShouldNotBeNil)
+			So(atomic.LoadInt32(&calls), ShouldEqual, 2)
+		})
+
+		Convey("not POST requests snapd answered", func() {
+			c, err := New(ts.URL, "v1", false, Retries(2, time.Millisecond))
+			So(err, ShouldBeNil)
+			_, err = c.do("POST", "/tasks", ContentTypeJSON, []byte("{}"))
+			So(err, ShouldNotBeNil)
+			So(atomic.LoadInt32(&calls), ShouldEqual, 1)
+		})
+
+		Convey("requests which did not reach snapd", func() {
+			closed := httptest.NewServer(http.NotFoundHandler())
+			closed.Close()
+			c, err := New(closed.URL, "v1", false, Retries(2, time.Millisecond))
+			So(err, ShouldBeNil)
+			_, err = c.do("POST", "/tasks", ContentTypeJSON, []byte("{}"))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, "URL target is not available.")
+		})
+	})
+}
+
+func TestClientContext(t *testing.T) {
+	Convey("Client with a context", t, func() {
+		block := make(chan struct{})
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-block:
+			case <-r.Context().Done():
+			}
+		}))
+		defer ts.Close()
+		defer close(block)
+
+		c, err := New(ts.URL, "v1", false)
+		So(err, ShouldBeNil)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		r := c.WithContext(ctx).GetLogs("", 0)
+		So(r.Err, ShouldNotBeNil)
+		So(time.Since(start), ShouldBeLessThan, 2*time.Second)
+		So(c.ctx, ShouldBeNil)
+	})
+}
+
+func TestClientTLS(t *testing.T) {
+	Convey("Client over TLS", t, func() {
+		ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, emptyLogs)
+		}))
+		defer ts.Close()
+
+		Convey("fails with an unknown certificate", func() {
+			c, err := New(ts.URL, "v1", false, Retries(0, 0))
+			So(err, ShouldBeNil)
+			So(c.GetLogs("", 0).Err, ShouldNotBeNil)
+		})
+
+		Convey("succeeds with the CA in the TLS config", func() {
+			pool := x509.NewCertPool()
+			pool.AddCert(ts.Certificate())
+			cfg := &tls.Config{RootCAs: pool}
+			c, err := New(ts.URL, "v1", false, TLSConfig(cfg))
+			So(err, ShouldBeNil)
+			So(c.GetLogs("", 0).Err, ShouldBeNil)
+		})
+
+		Convey("succeeds insecure without altering the TLS config", func() {
+			cfg := &tls.Config{}
+			c, err := New(ts.URL, "v1", true, TLSConfig(cfg))
+			So(err, ShouldBeNil)
+			So(c.GetLogs("", 0).Err, ShouldBeNil)
+			So(cfg.InsecureSkipVerify, ShouldBeFalse)
+		})
+	})
+}
+
+func TestParseURL(t *testing.T) {
+	Convey("parseURL", t, func() {
+		Convey("accepts IPv4, IPv6 and hostnames", func() {
+			for _, u := range []string{"http://localhost:8181", "https://10.0.0.1:8181", "http://[::1]:8181", "http://[2001:db8::1]:8181"} {
+				So(parseURL(u), ShouldBeNil)
+			}
+		})
+		Convey("rejects URLs which are not http(s)", func() {
+			for _, u := range []string{"localhost:8181", "ftp://[::1]:21", "http://[not-an-ip]:8181"} {
+				So(parseURL(u), ShouldNotBeNil)
+			}
+		})
+	})
+}
@@ -33,9 +33,9 @@ import (
 	log "github.com/Sirupsen/logrus"
 	. "github.com/smartystreets/goconvey/convey"
 
+	"github.com/intelsdi-x/snap/client"
 	"github.com/intelsdi-x/snap/control"
 	"github.com/intelsdi-x/snap/mgmt/rest"
-	"github.com/intelsdi-x/snap/mgmt/rest/client"
 	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
 	"github.com/intelsdi-x/snap/mgmt/tribe"
 	"github.com/intelsdi-x/snap/scheduler"
@@ -148,6 +148,16 @@ func TestSnapClientTribe(t *testing.T) {
 								So(resp.Agreement.Name, ShouldNotBeNil)
 								So(resp.Agreement.Name, ShouldResemble, agreement)
 								So(len(resp.Agreement.Members), ShouldEqual, 3)
+								// a client using the agreement sends task requests to its members
+								c2, err := client.New(fmt.Sprintf("http://localhost:%d", ports[0]), "v1", true)
+								So(err, ShouldBeNil)
+								So(c2.UseAgreement(agreement), ShouldBeNil)
+								eps := c2.Endpoints()
+								So(len(eps), ShouldEqual, 4)
+								for _, ep := range eps[1:] {
+									So(ep.Member, ShouldBeTrue)
+								}
+								So(c2.GetTasks().Err, ShouldBeNil)
 								Convey("An agreement is deleted", func() {
 									resp := c.DeleteAgreement(agreement)
 									So(resp.Err, ShouldBeNil)
@@ -0,0 +1,151 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+// GetLogs retrieves the last lines log entries of a component through an
+// HTTP GET call: control, rest, scheduler, plugin for the output of all the
+// plugins, plugin:<name> for one, or all of them when empty.
+// The log entries return if it succeeds. Otherwise, an error is returned.
+func (c *Client) GetLogs(component string, lines int) *GetLogsResult {
+	resp, err := c.do("GET", "/logs?"+logsQuery(component, lines, false), ContentTypeJSON, nil)
+	if err != nil {
+		return &GetLogsResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.LogEntriesReturnedType:
+		// Success
+		return &GetLogsResult{resp.Body.(*rbody.LogEntriesReturned), nil}
+	case rbody.ErrorType:
+		return &GetLogsResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetLogsResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// TailLogs streams the last lines log entries of a component like GetLogs,
+// then the ones logged next until the result is closed.
+func (c *Client) TailLogs(component string, lines int) *TailLogsResult {
+	r := &TailLogsResult{
+		EntryChan: make(chan *rbody.LogEntry),
+		DoneChan:  make(chan struct{}),
+	}
+
+	req, err := c.newRequest("GET", fmt.Sprintf("%s/logs?%s", c.prefix, logsQuery(component, lines, true)), nil)
+	if err != nil {
+		r.Err = err
+		close(r.EntryChan)
+		return r
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if strings.Contains(err.Error(), "tls: oversized record") || strings.Contains(err.Error(), "malformed HTTP response") {
+			r.Err = fmt.Errorf("error connecting to API URI: %s. Do you have an http/https mismatch?", c.URL)
+		} else {
+			r.Err = err
+		}
+		close(r.EntryChan)
+		return r
+	}
+
+	if resp.StatusCode != 200 {
+		ar, err := httpRespToAPIResp(resp)
+		if err != nil {
+			r.Err = err
+		} else {
+			r.Err = errors.New(ar.Meta.Message)
+		}
+		close(r.EntryChan)
+		return r
+	}
+
+	go func() {
+		defer close(r.EntryChan)
+		go func() {
+			// unblocks the scanner once closed
+			<-r.DoneChan
+			resp.Body.Close()
+		}()
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+			le := &rbody.LogEntry{}
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), le); err != nil {
+				r.Err = err
+				return
+			}
+			select {
+			case r.EntryChan <- le:
+			case <-r.DoneChan:
+				return
+			}
+		}
+	}()
+	return r
+}
+
+func logsQuery(component string, lines int, follow bool) string {
+	v := url.Values{}
+	if component != "" {
+		v.Set("component", component)
+	}
+	if lines > 0 {
+		v.Set("lines", strconv.Itoa(lines))
+	}
+	if follow {
+		v.Set("follow", "true")
+	}
+	return v.Encode()
+}
+
+// GetLogsResult is the response from snap/client on a GetLogs call.
+type GetLogsResult struct {
+	*rbody.LogEntriesReturned
+	Err error
+}
+
+// TailLogsResult is the response from snap/client on a TailLogs call. The
+// entries are received on EntryChan, closed once Close is called or the
+// stream ends, Err telling why.
+type TailLogsResult struct {
+	Err       error
+	EntryChan chan *rbody.LogEntry
+	DoneChan  chan struct{}
+	closeOnce sync.Once
+}
+
+func (t *TailLogsResult) Close() {
+	t.closeOnce.Do(func() { close(t.DoneChan) })
+}
@@ -0,0 +1,189 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+// MemberPlugin is a plugin required by the agreements of a tribe member or
+// loaded on it.
+type MemberPlugin struct {
+	Type    string
+	Name    string
+	Version int
+	// Agreements are the agreements of the member requiring the plugin.
+	Agreements []string
+	// Loaded is true when the plugin is loaded on the member.
+	Loaded bool
+	// Diverged is true when the plugin is required but not loaded, or
+	// loaded on a member of an agreement which does not require it.
+	Diverged bool
+}
+
+// MemberTask is a task required by the agreements of a tribe member or
+// scheduled on it.
+type MemberTask struct {
+	ID   string
+	Name string
+	// Agreements are the agreements of the member requiring the task.
+	Agreements []string
+	// State is the state of the task on the member, empty when the member
+	// does not have it.
+	State string
+	// Diverged is true when the task is required but the member does not
+	// have it, or scheduled on a member of an agreement which does not
+	// require it.
+	Diverged bool
+}
+
+// DescribeMemberResult is the response from snap/client on a DescribeMember call.
+type DescribeMemberResult struct {
+	*rbody.TribeMemberShow
+	// URL is the URL of the REST API of the member.
+	URL string
+	// Agreements are the agreements the member belongs to.
+	Agreements []string
+	Plugins    []MemberPlugin
+	Tasks      []MemberTask
+	Err        error
+}
+
+// DescribeMember retrieves a tribe member, the plugins and tasks its
+// agreements require and the plugins and tasks actually loaded and scheduled
+// on it, which are asked to the REST API of the member. The plugins and tasks
+// which diverge from what the agreements require are marked as such.
+func (c *Client) DescribeMember(name string) *DescribeMemberResult {
+	m := c.GetMember(name)
+	if m.Err != nil {
+		return &DescribeMemberResult{Err: m.Err}
+	}
+	res := &DescribeMemberResult{TribeMemberShow: m.TribeMemberShow, URL: memberURL(m.TribeMemberShow)}
+	if res.URL == "" {
+		res.Err = fmt.Errorf("member %s does not advertise its REST API", name)
+		return res
+	}
+	if err := parseURL(res.URL); err != nil {
+		res.Err = err
+		return res
+	}
+	la := c.ListAgreements()
+	if la.Err != nil {
+		res.Err = la.Err
+		return res
+	}
+
+	plugins := map[string]*MemberPlugin{}
+	tasks := map[string]*MemberTask{}
+	for an, a := range la.Agreements {
+		if _, ok := a.Members[name]; !ok {
+			continue
+		}
+		res.Agreements = append(res.Agreements, an)
+		if a.PluginAgreement != nil {
+			for _, p := range a.PluginAgreement.Plugins {
+				key := pluginKey(p.TypeName(), p.Name(), p.Version())
+				if plugins[key] == nil {
+					plugins[key] = &MemberPlugin{Type: p.TypeName(), Name: p.Name(), Version: p.Version()}
+				}
+				plugins[key].Agreements = append(plugins[key].Agreements, an)
+			}
+		}
+		if a.TaskAgreement != nil {
+			for _, t := range a.TaskAgreement.Tasks {
+				if tasks[t.ID] == nil {
+					tasks[t.ID] = &MemberTask{ID: t.ID}
+				}
+				tasks[t.ID].Agreements = append(tasks[t.ID].Agreements, an)
+			}
+		}
+	}
+	sort.Strings(res.Agreements)
+
+	mc := c.clientAt(res.URL)
+	lp := mc.GetPlugins(false)
+	if lp.Err != nil {
+		res.Err = fmt.Errorf("member %s: %v", name, lp.Err)
+		return res
+	}
+	for _, p := range lp.LoadedPlugins {
+		key := pluginKey(p.Type, p.Name, p.Version)
+		if plugins[key] == nil {
+			plugins[key] = &MemberPlugin{Type: p.Type, Name: p.Name, Version: p.Version}
+		}
+		plugins[key].Loaded = true
+	}
+	lt := mc.GetTasks()
+	if lt.Err != nil {
+		res.Err = fmt.Errorf("member %s: %v", name, lt.Err)
+		return res
+	}
+	for _, t := range lt.ScheduledTasks {
+		if tasks[t.ID] == nil {
+			tasks[t.ID] = &MemberTask{ID: t.ID}
+		}
+		tasks[t.ID].Name = t.Name
+		tasks[t.ID].State = t.State
+	}
+
+	inAgreement := len(res.Agreements) > 0
+	for _, p := range plugins {
+		sort.Strings(p.Agreements)
+		p.Diverged = inAgreement && (len(p.Agreements) > 0) != p.Loaded
+		res.Plugins = append(res.Plugins, *p)
+	}
+	sort.Slice(res.Plugins, func(i, j int) bool {
+		a, b := res.Plugins[i], res.Plugins[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Version < b.Version
+	})
+	for _, t := range tasks {
+		sort.Strings(t.Agreements)
+		t.Diverged = inAgreement && (len(t.Agreements) > 0) != (t.State != "")
+		res.Tasks = append(res.Tasks, *t)
+	}
+	sort.Slice(res.Tasks, func(i, j int) bool {
+		return res.Tasks[i].ID < res.Tasks[j].ID
+	})
+	return res
+}
+
+// clientAt returns a copy of the client sending all its requests to the
+// snapd instance at the URL.
+func (c *Client) clientAt(url string) *Client {
+	mc := *c
+	mc.URL = url
+	mc.endpoints = &endpoints{}
+	mc.endpoints.add(url, c.Version, false)
+	return &mc
+}
+
+func pluginKey(typ, name string, ver int) string {
+	return fmt.Sprintf("%s:%s:%d", strings.ToLower(typ), name, ver)
+}
@@ -0,0 +1,110 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/mgmt/tribe/agreement"
+)
+
+// memberServer serves the tribe member m1 of the agreement a1, which is also
+// the snapd instance of the member.
+func memberServer() *httptest.Server {
+	a := agreement.New("a1")
+	a.Members["m1"] = &agreement.Member{Name: "m1"}
+	a.PluginAgreement.Add(agreement.Plugin{Name_: "psutil", Version_: 9, Type_: core.CollectorPluginType})
+	a.PluginAgreement.Add(agreement.Plugin{Name_: "file", Version_: 3, Type_: core.PublisherPluginType})
+	a.TaskAgreement.Add(agreement.Task{ID: "t1"})
+	a.TaskAgreement.Add(agreement.Task{ID: "t2"})
+	other := agreement.New("a2")
+	other.TaskAgreement.Add(agreement.Task{ID: "t4"})
+
+	var port string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var b rbody.Body
+		switch r.URL.Path {
+		case "/v1/tribe/members/m1":
+			b = &rbody.TribeMemberShow{Name: "m1", Addr: "127.0.0.1", Tags: map[string]string{agreement.RestPort: port}}
+		case "/v1/tribe/agreements":
+			b = &rbody.TribeListAgreement{Agreements: map[string]*agreement.Agreement{"a1": a, "a2": other}}
+		case "/v1/plugins":
+			b = &rbody.PluginList{LoadedPlugins: []rbody.LoadedPlugin{
+				{Name: "psutil", Version: 9, Type: "collector"},
+				{Name: "mock", Version: 1, Type: "collector"},
+			}}
+		case "/v1/tasks":
+			b = &rbody.ScheduledTaskListReturned{ScheduledTasks: []rbody.ScheduledTask{
+				{ID: "t1", Name: "cpu", State: "Running"},
+				{ID: "t3", Name: "local", State: "Stopped"},
+			}}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(&rbody.APIResponse{
+			Meta: &rbody.APIResponseMeta{Code: 200, Type: b.ResponseBodyType(), Version: 1},
+			Body: b,
+		})
+	}))
+	u, _ := url.Parse(s.URL)
+	_, port, _ = net.SplitHostPort(u.Host)
+	return s
+}
+
+func TestDescribeMember(t *testing.T) {
+	Convey("DescribeMember", t, func() {
+		s := memberServer()
+		defer s.Close()
+		c, err := New(s.URL, "v1", false)
+		So(err, ShouldBeNil)
+		r := c.DescribeMember("m1")
+		So(r.Err, ShouldBeNil)
+		So(r.URL, ShouldEqual, s.URL)
+		So(r.Agreements, ShouldResemble, []string{"a1"})
+
+		Convey("compares the plugins required with the plugins loaded", func() {
+			So(r.Plugins, ShouldResemble, []MemberPlugin{
+				{Type: "collector", Name: "mock", Version: 1, Loaded: true, Diverged: true},
+				{Type: "collector", Name: "psutil", Version: 9, Agreements: []string{"a1"}, Loaded: true},
+				{Type: "publisher", Name: "file", Version: 3, Agreements: []string{"a1"}, Diverged: true},
+			})
+		})
+		Convey("compares the tasks required with the tasks scheduled", func() {
+			So(r.Tasks, ShouldResemble, []MemberTask{
+				{ID: "t1", Name: "cpu", Agreements: []string{"a1"}, State: "Running"},
+				{ID: "t2", Agreements: []string{"a1"}, Diverged: true},
+				{ID: "t3", Name: "local", State: "Stopped", Diverged: true},
+			})
+		})
+		Convey("fails for an unknown member", func() {
+			So(c.DescribeMember("m2").Err, ShouldNotBeNil)
+		})
+	})
+}
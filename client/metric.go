@@ -22,6 +22,8 @@ package client
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/url"
 
 	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
 )
@@ -77,6 +79,27 @@ func (c *Client) FetchMetrics(ns string, ver int) *GetMetricsResult {
 	return r
 }
 
+// QueryMetrics retrieves the metrics of the catalog matching a query such as
+// "ns=/intel/cpu/* AND version>=3" through an HTTP GET request.
+func (c *Client) QueryMetrics(query string) *GetMetricsResult {
+	r := &GetMetricsResult{}
+	resp, err := c.do("GET", "/metrics?q="+url.QueryEscape(query), ContentTypeJSON)
+	if err != nil {
+		return &GetMetricsResult{Err: err}
+	}
+
+	switch resp.Meta.Type {
+	case rbody.MetricsReturnedType:
+		mc := resp.Body.(*rbody.MetricsReturned)
+		r.Catalog = convertCatalog(mc)
+	case rbody.ErrorType:
+		r.Err = resp.Body.(*rbody.Error)
+	default:
+		r.Err = ErrAPIResponseMetaType
+	}
+	return r
+}
+
 // GetMetricVersions retrieves all versions of a metric at a given namespace.
 func (c *Client) GetMetricVersions(ns string) *GetMetricsResult {
 	r := &GetMetricsResult{}
@@ -126,6 +149,55 @@ type GetMetricsResult struct {
 	Err     error
 }
 
+// ExportMetricCatalog retrieves the descriptors of the metrics in the catalog
+// in the given format (json, prometheus or openmetrics). The JSON export is
+// returned in Catalog, the text formats are returned unparsed in Text.
+func (c *Client) ExportMetricCatalog(format string) *ExportMetricsResult {
+	if format == "" || format == "json" {
+		resp, err := c.do("GET", "/catalog?format=json", ContentTypeJSON)
+		if err != nil {
+			return &ExportMetricsResult{Err: err}
+		}
+		switch resp.Meta.Type {
+		case rbody.MetricCatalogExportedType:
+			mc := resp.Body.(*rbody.MetricCatalogExported)
+			return &ExportMetricsResult{Catalog: *mc}
+		case rbody.ErrorType:
+			return &ExportMetricsResult{Err: resp.Body.(*rbody.Error)}
+		default:
+			return &ExportMetricsResult{Err: ErrAPIResponseMetaType}
+		}
+	}
+	rsp, err := c.send("GET", "/catalog?format="+url.QueryEscape(format), ContentTypeJSON, nil)
+	if err != nil {
+		return &ExportMetricsResult{Err: err}
+	}
+	if rsp.StatusCode != 200 {
+		// errors are returned as a regular JSON API response
+		resp, err := httpRespToAPIResp(rsp)
+		if err != nil {
+			return &ExportMetricsResult{Err: err}
+		}
+		if e, ok := resp.Body.(*rbody.Error); ok {
+			return &ExportMetricsResult{Err: e}
+		}
+		return &ExportMetricsResult{Err: ErrAPIResponseMetaType}
+	}
+	b, err := ioutil.ReadAll(rsp.Body)
+	rsp.Body.Close()
+	if err != nil {
+		return &ExportMetricsResult{Err: err}
+	}
+	return &ExportMetricsResult{Text: b}
+}
+
+// ExportMetricsResult is the response from snap/client on an ExportMetricCatalog call.
+type ExportMetricsResult struct {
+	Catalog rbody.MetricCatalogExported
+	Text    []byte
+	Err     error
+}
+
 // GetMetricResult is the response from snap/client on a GetMetricCatalog call.
 type GetMetricResult struct {
 	Metric *rbody.Metric
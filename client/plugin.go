@@ -20,12 +20,15 @@ limitations under the License.
 package client
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/url"
 	"time"
 
 	"github.com/intelsdi-x/snap/core/serror"
 	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
 )
 
 // LoadPlugin loads plugins for the given plugin names.
@@ -58,8 +61,18 @@ func (c *Client) LoadPlugin(p []string) *LoadPluginResult {
 // UnloadPlugin unloads a plugin given plugin type, name, and version through an HTTP DELETE request.
 // The unloaded plugin returns if succeeded. Otherwise, an error is returned.
 func (c *Client) UnloadPlugin(pluginType, name string, version int) *UnloadPluginResult {
+	return c.unloadPlugin(fmt.Sprintf("/plugins/%s/%s/%d", pluginType, url.QueryEscape(name), version))
+}
+
+// UnloadPluginForce unloads a plugin like UnloadPlugin, stopping the running
+// tasks which depend on it first.
+func (c *Client) UnloadPluginForce(pluginType, name string, version int) *UnloadPluginResult {
+	return c.unloadPlugin(fmt.Sprintf("/plugins/%s/%s/%d?force=true", pluginType, url.QueryEscape(name), version))
+}
+
+func (c *Client) unloadPlugin(path string) *UnloadPluginResult {
 	r := &UnloadPluginResult{}
-	resp, err := c.do("DELETE", fmt.Sprintf("/plugins/%s/%s/%d", pluginType, url.QueryEscape(name), version), ContentTypeJSON)
+	resp, err := c.do("DELETE", path, ContentTypeJSON)
 	if err != nil {
 		r.Err = err
 		return r
@@ -112,6 +125,83 @@ func (c *Client) GetPlugins(details bool) *GetPluginsResult {
 	return r
 }
 
+// GetPlugin returns a loaded plugin given its type, name and version through
+// an HTTP GET request, along with the config policy of processors and
+// publishers. An error returns if it failed.
+func (c *Client) GetPlugin(pluginType, name string, version int) *GetPluginResult {
+	resp, err := c.do("GET", fmt.Sprintf("/plugins/%s/%s/%d", pluginType, url.QueryEscape(name), version), ContentTypeJSON)
+	if err != nil {
+		return &GetPluginResult{Err: err}
+	}
+
+	switch resp.Meta.Type {
+	case rbody.PluginReturnedType:
+		// Success
+		p := rbody.LoadedPlugin(*resp.Body.(*rbody.PluginReturned))
+		return &GetPluginResult{LoadedPlugin: LoadedPlugin{&p}}
+	case rbody.ErrorType:
+		return &GetPluginResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetPluginResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// DownloadPlugin returns the executable of a loaded plugin given its type,
+// name and version through an HTTP GET request. An error returns if it failed.
+func (c *Client) DownloadPlugin(pluginType, name string, version int) ([]byte, error) {
+	rsp, err := c.send("GET", fmt.Sprintf("/plugins/%s/%s/%d?download=true", pluginType, url.QueryEscape(name), version), ContentTypeJSON, nil)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.StatusCode != 200 {
+		ar, err := httpRespToAPIResp(rsp)
+		if err != nil {
+			return nil, err
+		}
+		if e, ok := ar.Body.(*rbody.Error); ok {
+			return nil, e
+		}
+		return nil, ErrAPIResponseMetaType
+	}
+	defer rsp.Body.Close()
+	// the transport decompresses the gzip encoded executable
+	return ioutil.ReadAll(rsp.Body)
+}
+
+// ProcessMetrics has a processor given its name and version process a batch
+// of metrics offloaded by a task through an HTTP POST request. The content the
+// processor returned returns if succeeded. Otherwise, an error is returned.
+func (c *Client) ProcessMetrics(name string, version int, pr *request.ProcessRequest) *ProcessMetricsResult {
+	r := &ProcessMetricsResult{}
+	b, err := json.Marshal(pr)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	resp, err := c.do("POST", fmt.Sprintf("/plugins/processor/%s/%d/process", url.QueryEscape(name), version), ContentTypeJSON, b)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+
+	switch resp.Meta.Type {
+	case rbody.PluginProcessedType:
+		// Success
+		r.PluginProcessed = resp.Body.(*rbody.PluginProcessed)
+	case rbody.ErrorType:
+		r.Err = resp.Body.(*rbody.Error)
+	default:
+		r.Err = ErrAPIResponseMetaType
+	}
+	return r
+}
+
+// GetPluginResult is the response from snap/client on a GetPlugin call.
+type GetPluginResult struct {
+	LoadedPlugin
+	Err error
+}
+
 // GetPluginsResult is the response from snap/client on a GetPlugins call.
 type GetPluginsResult struct {
 	LoadedPlugins    []LoadedPlugin
@@ -131,6 +221,12 @@ type UnloadPluginResult struct {
 	Err error
 }
 
+// ProcessMetricsResult is the response from snap/client on a ProcessMetrics call.
+type ProcessMetricsResult struct {
+	*rbody.PluginProcessed
+	Err error
+}
+
 // We wrap this so we can provide some functionality (like LoadedTime)
 type LoadedPlugin struct {
 	*rbody.LoadedPlugin
@@ -0,0 +1,83 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+// GetWorkerPools retrieves the state of the worker pools of the scheduler
+// through an HTTP GET call. A list of worker pools returns if it succeeds.
+// Otherwise, an error is returned.
+func (c *Client) GetWorkerPools() *GetWorkerPoolsResult {
+	resp, err := c.do("GET", "/scheduler/workers", ContentTypeJSON, nil)
+	if err != nil {
+		return &GetWorkerPoolsResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.WorkerPoolListReturnedType:
+		// Success
+		return &GetWorkerPoolsResult{resp.Body.(*rbody.WorkerPoolListReturned), nil}
+	case rbody.ErrorType:
+		return &GetWorkerPoolsResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetWorkerPoolsResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// ResizeWorkerPool sets the number of workers of a worker pool of the
+// scheduler through an HTTP PUT call. The resized worker pool returns if it
+// succeeds. Otherwise, an error is returned.
+func (c *Client) ResizeWorkerPool(pool string, workers uint) *ResizeWorkerPoolResult {
+	j, err := json.Marshal(struct {
+		Workers uint `json:"workers"`
+	}{workers})
+	if err != nil {
+		return &ResizeWorkerPoolResult{Err: err}
+	}
+	resp, err := c.do("PUT", fmt.Sprintf("/scheduler/workers/%s", pool), ContentTypeJSON, j)
+	if err != nil {
+		return &ResizeWorkerPoolResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.WorkerPoolResizedType:
+		// Success
+		return &ResizeWorkerPoolResult{resp.Body.(*rbody.WorkerPoolResized), nil}
+	case rbody.ErrorType:
+		return &ResizeWorkerPoolResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &ResizeWorkerPoolResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// GetWorkerPoolsResult is the response from snap/client on a GetWorkerPools call.
+type GetWorkerPoolsResult struct {
+	*rbody.WorkerPoolListReturned
+	Err error
+}
+
+// ResizeWorkerPoolResult is the response from snap/client on a ResizeWorkerPool call.
+type ResizeWorkerPoolResult struct {
+	*rbody.WorkerPoolResized
+	Err error
+}
@@ -0,0 +1,80 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+// GetSchemas retrieves the current version of the schemas of the metrics
+// snapd publishes as JSON through an HTTP GET call. A list of schemas
+// returns if it succeeds. Otherwise, an error is returned.
+func (c *Client) GetSchemas() *GetSchemasResult {
+	resp, err := c.do("GET", "/schemas", ContentTypeJSON, nil)
+	if err != nil {
+		return &GetSchemasResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.SchemaListReturnedType:
+		// Success
+		return &GetSchemasResult{resp.Body.(*rbody.SchemaListReturned), nil}
+	case rbody.ErrorType:
+		return &GetSchemasResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetSchemasResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// GetSchema retrieves a version of a schema through an HTTP GET call, the
+// current one when the version is 0. The schema returns if it succeeds.
+// Otherwise, an error is returned.
+func (c *Client) GetSchema(name string, version int) *GetSchemaResult {
+	path := "/schemas/" + name
+	if version > 0 {
+		path = fmt.Sprintf("%s/%d", path, version)
+	}
+	resp, err := c.do("GET", path, ContentTypeJSON, nil)
+	if err != nil {
+		return &GetSchemaResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.SchemaReturnedType:
+		// Success
+		return &GetSchemaResult{resp.Body.(*rbody.SchemaReturned), nil}
+	case rbody.ErrorType:
+		return &GetSchemaResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetSchemaResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// GetSchemasResult is the response from snap/client on a GetSchemas call.
+type GetSchemasResult struct {
+	*rbody.SchemaListReturned
+	Err error
+}
+
+// GetSchemaResult is the response from snap/client on a GetSchema call.
+type GetSchemaResult struct {
+	*rbody.SchemaReturned
+	Err error
+}
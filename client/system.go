@@ -0,0 +1,85 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
+)
+
+// GetMaintenance retrieves the maintenance mode of snapd through an HTTP GET
+// call. The maintenance mode returns if it succeeds. Otherwise, an error is
+// returned.
+func (c *Client) GetMaintenance() *GetMaintenanceResult {
+	resp, err := c.do("GET", "/system/maintenance", ContentTypeJSON, nil)
+	if err != nil {
+		return &GetMaintenanceResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.MaintenanceReturnedType:
+		// Success
+		return &GetMaintenanceResult{resp.Body.(*rbody.MaintenanceReturned), nil}
+	case rbody.ErrorType:
+		return &GetMaintenanceResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetMaintenanceResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// SetMaintenance turns the maintenance mode of snapd on or off through an
+// HTTP PUT call. With holdPublish the running tasks keep collecting while
+// their publishing is held instead of being paused. The maintenance mode
+// returns if it succeeds. Otherwise, an error is returned.
+func (c *Client) SetMaintenance(enabled, holdPublish bool) *SetMaintenanceResult {
+	j, err := json.Marshal(request.MaintenanceRequest{
+		Enabled:     enabled,
+		HoldPublish: holdPublish,
+	})
+	if err != nil {
+		return &SetMaintenanceResult{Err: err}
+	}
+	resp, err := c.do("PUT", "/system/maintenance", ContentTypeJSON, j)
+	if err != nil {
+		return &SetMaintenanceResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.MaintenanceChangedType:
+		// Success
+		return &SetMaintenanceResult{resp.Body.(*rbody.MaintenanceChanged), nil}
+	case rbody.ErrorType:
+		return &SetMaintenanceResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &SetMaintenanceResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// GetMaintenanceResult is the response from snap/client on a GetMaintenance call.
+type GetMaintenanceResult struct {
+	*rbody.MaintenanceReturned
+	Err error
+}
+
+// SetMaintenanceResult is the response from snap/client on a SetMaintenance call.
+type SetMaintenanceResult struct {
+	*rbody.MaintenanceChanged
+	Err error
+}
@@ -0,0 +1,715 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015,2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+type Schedule struct {
+	// Type specifies the type of the schedule. Currently, the type of "simple", "windowed", "cron" and "trigger" are supported.
+	Type string
+	// Interval specifies the time duration.
+	Interval string
+	// Jitter specifies the maximum delay of the first fire of a simple schedule.
+	Jitter string
+	// Overrun specifies what happens when the schedule fires while the task is still running.
+	// The policies "skip", "queue" and "stretch" are supported.
+	Overrun string
+	// OverrunQueueDepth specifies how many runs the "queue" overrun policy queues.
+	OverrunQueueDepth uint `json:"overrun_queue_depth"`
+	// StartTime specifies the beginning time.
+	StartTime *time.Time
+	// StopTime specifies the end time.
+	StopTime *time.Time
+	// Triggers specifies the sources of the events firing a "trigger" schedule.
+	Triggers []request.Trigger
+}
+
+// TaskOption sets an optional property of a task created through CreateTask.
+type TaskOption func(*request.TaskCreationRequest)
+
+// TaskPriority sets the priority class of the task [critical, normal or low].
+func TaskPriority(priority string) TaskOption {
+	return func(t *request.TaskCreationRequest) {
+		t.Priority = priority
+	}
+}
+
+// TaskAlerts sets the alert rules evaluated against the metrics collected by
+// the task.
+func TaskAlerts(rules []request.AlertRule) TaskOption {
+	return func(t *request.TaskCreationRequest) {
+		t.Alerts = rules
+	}
+}
+
+// TaskShard makes each member of the tribe agreements sharing the task
+// collect its shard of the metrics of the task only.
+func TaskShard(shard bool) TaskOption {
+	return func(t *request.TaskCreationRequest) {
+		t.Shard = shard
+	}
+}
+
+// TaskTimestamps sets where the timestamps of the metrics collected by the
+// task come from and bounds those set by the collectors.
+func TaskTimestamps(policy *request.TimestampPolicy) TaskOption {
+	return func(t *request.TaskCreationRequest) {
+		t.Timestamps = policy
+	}
+}
+
+// TaskDescription sets a free-text description of the task.
+func TaskDescription(description string) TaskOption {
+	return func(t *request.TaskCreationRequest) {
+		t.Description = description
+	}
+}
+
+// TaskDependsOn runs the task, which must have a trigger schedule, when the
+// runs of the tasks it depends on end.
+func TaskDependsOn(deps []request.TaskDependency) TaskOption {
+	return func(t *request.TaskCreationRequest) {
+		t.DependsOn = deps
+	}
+}
+
+// CreateTask creates a task given the schedule, workflow, task name, and task state.
+// If the startTask flag is true, the newly created task is started after the creation.
+// Otherwise, it's in the Stopped state. CreateTask is accomplished through a POST HTTP JSON request.
+// A ScheduledTask is returned if it succeeds, otherwise an error is returned.
+func (c *Client) CreateTask(s *Schedule, wf *wmap.WorkflowMap, name string, deadline string, startTask bool, opts ...TaskOption) *CreateTaskResult {
+	t := request.TaskCreationRequest{
+		Schedule: request.Schedule{
+			Type:     s.Type,
+			Interval: s.Interval,
+			Jitter:   s.Jitter,
+
+			Overrun:           s.Overrun,
+			OverrunQueueDepth: s.OverrunQueueDepth,
+			Triggers:          s.Triggers,
+		},
+		Workflow: wf,
+		Start:    startTask,
+	}
+	// Add start and/or stop timestamps if they exist
+	if s.StartTime != nil {
+		u := s.StartTime.Unix()
+		t.Schedule.StartTimestamp = &u
+	}
+	if s.StopTime != nil {
+		u := s.StopTime.Unix()
+		t.Schedule.StopTimestamp = &u
+	}
+
+	if name != "" {
+		t.Name = name
+	}
+	if deadline != "" {
+		t.Deadline = deadline
+	}
+	for _, opt := range opts {
+		opt(&t)
+	}
+	return c.CreateTaskFromRequest(&t)
+}
+
+// CreateTaskFromRequest creates the task described by a task creation
+// request, as a task manifest does, through a POST HTTP JSON request.
+// A ScheduledTask is returned if it succeeds, otherwise an error is returned.
+func (c *Client) CreateTaskFromRequest(t *request.TaskCreationRequest) *CreateTaskResult {
+	// Marshal to JSON for request body
+	j, err := json.Marshal(t)
+	if err != nil {
+		return &CreateTaskResult{Err: err}
+	}
+
+	resp, err := c.do("POST", "/tasks", ContentTypeJSON, j)
+	if err != nil {
+		return &CreateTaskResult{Err: err}
+	}
+
+	switch resp.Meta.Type {
+	case rbody.AddScheduledTaskType:
+		// Success
+		return &CreateTaskResult{resp.Body.(*rbody.AddScheduledTask), nil}
+	case rbody.ErrorType:
+		return &CreateTaskResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &CreateTaskResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// WatchTask retrieves running tasks by running a goroutine to
+// interactive with Event and Done channels. An HTTP GET request retrieves tasks.
+// StreamedTaskEvent returns if it succeeds. Otherwise, an error is returned.
+func (c *Client) WatchTask(id string) *WatchTasksResult {
+	return c.watchTask(fmt.Sprintf("%s/tasks/%v/watch", c.prefixFor("/tasks"), id))
+}
+
+// WatchTaskEvents watches a task like WatchTask. With lifecycleOnly the
+// collected metrics are left out of the stream. The last replay lifecycle
+// events of the task are sent first, with Replayed set.
+func (c *Client) WatchTaskEvents(id string, lifecycleOnly bool, replay uint) *WatchTasksResult {
+	return c.watchTask(fmt.Sprintf("%s/tasks/%v/watch?lifecycle=%t&replay=%d", c.prefixFor("/tasks"), id, lifecycleOnly, replay))
+}
+
+func (c *Client) watchTask(url string) *WatchTasksResult {
+	r := &WatchTasksResult{
+		EventChan: make(chan *rbody.StreamedTaskEvent),
+		DoneChan:  make(chan struct{}),
+	}
+
+	req, err := c.newRequest("GET", url, nil)
+	if err != nil {
+		r.Err = err
+		r.Close()
+		return r
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if strings.Contains(err.Error(), "tls: oversized record") || strings.Contains(err.Error(), "malformed HTTP response") {
+			r.Err = fmt.Errorf("error connecting to API URI: %s. Do you have an http/https mismatch?", c.URL)
+		} else {
+			r.Err = err
+		}
+		r.Close()
+		return r
+	}
+
+	if resp.StatusCode != 200 {
+		ar, err := httpRespToAPIResp(resp)
+		if err != nil {
+			r.Err = err
+		} else {
+			r.Err = errors.New(ar.Meta.Message)
+		}
+		r.Close()
+		return r
+	}
+
+	// Start watching
+	go func() {
+		reader := bufio.NewReader(resp.Body)
+		for {
+			select {
+			case <-r.DoneChan:
+				resp.Body.Close()
+				return
+			default:
+				line, _ := reader.ReadBytes('\n')
+				sline := string(line)
+				if sline == "" || sline == "\n" {
+					continue
+				}
+				if strings.HasPrefix(sline, "data:") {
+					sline = strings.TrimPrefix(sline, "data:")
+					line = []byte(sline)
+				}
+				ste := &rbody.StreamedTaskEvent{}
+				err := json.Unmarshal(line, ste)
+				if err != nil {
+					r.Err = err
+					r.Close()
+					return
+				}
+				switch ste.EventType {
+				case rbody.TaskWatchTaskDisabled:
+					r.EventChan <- ste
+					if !ste.Replayed {
+						r.Close()
+					}
+				case rbody.TaskWatchTaskStopped, rbody.TaskWatchTaskStarted, rbody.TaskWatchMetricEvent:
+					r.EventChan <- ste
+				}
+			}
+		}
+	}()
+	return r
+}
+
+// GetTasks retrieves a slice of tasks through an HTTP GET call.
+// A list of scheduled tasks returns if it succeeds.
+// Otherwise. an error is returned.
+func (c *Client) GetTasks() *GetTasksResult {
+	return c.getTasks("/tasks")
+}
+
+// GetTasksUsingMetric retrieves the tasks subscribed to the metrics matching
+// the namespace, which may contain wildcards (e.g. /intel/cpu/*).
+func (c *Client) GetTasksUsingMetric(ns string) *GetTasksResult {
+	return c.getTasks("/tasks?metric=" + url.QueryEscape(ns))
+}
+
+// GetTasksUsingPlugin retrieves the tasks subscribed to the plugin. An empty
+// plugin type matches any type and a version less than 1 any version.
+func (c *Client) GetTasksUsingPlugin(pluginType, name string, ver int) *GetTasksResult {
+	v := url.Values{}
+	v.Set("plugin", name)
+	if pluginType != "" {
+		v.Set("plugin_type", pluginType)
+	}
+	if ver > 0 {
+		v.Set("plugin_version", strconv.Itoa(ver))
+	}
+	return c.getTasks("/tasks?" + v.Encode())
+}
+
+func (c *Client) getTasks(path string) *GetTasksResult {
+	resp, err := c.do("GET", path, ContentTypeJSON, nil)
+	if err != nil {
+		return &GetTasksResult{Err: err}
+	}
+
+	switch resp.Meta.Type {
+	case rbody.ScheduledTaskListReturnedType:
+		// Success
+		return &GetTasksResult{resp.Body.(*rbody.ScheduledTaskListReturned), nil}
+	case rbody.ErrorType:
+		return &GetTasksResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetTasksResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// GetTask retrieves the task given a task id through an HTTP GET call.
+// A scheduled task returns if it succeeds. Otherwise, an error is returned.
+func (c *Client) GetTask(id string) *GetTaskResult {
+	resp, err := c.do("GET", fmt.Sprintf("/tasks/%v", id), ContentTypeJSON, nil)
+	if err != nil {
+		return &GetTaskResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.ScheduledTaskReturnedType:
+		// Success
+		return &GetTaskResult{resp.Body.(*rbody.ScheduledTaskReturned), nil}
+	case rbody.ErrorType:
+		return &GetTaskResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetTaskResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// GetTaskRuns retrieves the last runs of the task given a task id through
+// an HTTP GET call. The runs return from the oldest to the latest if it
+// succeeds. Otherwise, an error is returned.
+func (c *Client) GetTaskRuns(id string) *GetTaskRunsResult {
+	resp, err := c.do("GET", fmt.Sprintf("/tasks/%v/runs", id), ContentTypeJSON, nil)
+	if err != nil {
+		return &GetTaskRunsResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.ScheduledTaskRunsReturnedType:
+		// Success
+		return &GetTaskRunsResult{resp.Body.(*rbody.ScheduledTaskRunsReturned), nil}
+	case rbody.ErrorType:
+		return &GetTaskRunsResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetTaskRunsResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// GetTaskConfig retrieves the config each metric of a task is collected with
+// through an HTTP GET call. When resolve is true the source of each value and
+// the values it overrides are returned as well. Otherwise, an error is returned.
+func (c *Client) GetTaskConfig(id string, resolve bool) *GetTaskConfigResult {
+	resp, err := c.do("GET", fmt.Sprintf("/tasks/%v/config?resolve=%v", id, resolve), ContentTypeJSON, nil)
+	if err != nil {
+		return &GetTaskConfigResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.ScheduledTaskConfigReturnedType:
+		// Success
+		return &GetTaskConfigResult{resp.Body.(*rbody.ScheduledTaskConfigReturned), nil}
+	case rbody.ErrorType:
+		return &GetTaskConfigResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetTaskConfigResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// StartTask starts a task given a task id. The scheduled task will be in
+// the started state if it succeeds. Otherwise, an error is returned.
+func (c *Client) StartTask(id string) *StartTasksResult {
+	resp, err := c.do("PUT", fmt.Sprintf("/tasks/%v/start", id), ContentTypeJSON)
+
+	if err != nil {
+		return &StartTasksResult{Err: err}
+	}
+
+	switch resp.Meta.Type {
+	case rbody.ScheduledTaskStartedType:
+		// Success
+		return &StartTasksResult{resp.Body.(*rbody.ScheduledTaskStarted), nil}
+	case rbody.ErrorType:
+		return &StartTasksResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &StartTasksResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// StopTask stops a running task given a task id. It uses an HTTP PUT call.
+// The stopped task id returns if it succeeds. Otherwise, an error is returned.
+func (c *Client) StopTask(id string) *StopTasksResult {
+	resp, err := c.do("PUT", fmt.Sprintf("/tasks/%v/stop", id), ContentTypeJSON)
+	if err != nil {
+		return &StopTasksResult{Err: err}
+	}
+
+	if resp == nil {
+		return nil
+	}
+	switch resp.Meta.Type {
+	case rbody.ScheduledTaskStoppedType:
+		// Success
+		return &StopTasksResult{resp.Body.(*rbody.ScheduledTaskStopped), nil}
+	case rbody.ErrorType:
+		return &StopTasksResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &StopTasksResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// PauseTask pauses a running task given a task id through an HTTP PUT call.
+// A paused task stays subscribed to its plugins but does not fire until it
+// is resumed, or until d elapses when d is not zero. The paused task id
+// returns if it succeeds. Otherwise, an error is returned.
+func (c *Client) PauseTask(id string, d time.Duration) *PauseTaskResult {
+	pr := request.TaskPauseRequest{}
+	if d > 0 {
+		pr.For = d.String()
+	}
+	j, err := json.Marshal(pr)
+	if err != nil {
+		return &PauseTaskResult{Err: err}
+	}
+	resp, err := c.do("PUT", fmt.Sprintf("/tasks/%v/pause", id), ContentTypeJSON, j)
+	if err != nil {
+		return &PauseTaskResult{Err: err}
+	}
+
+	switch resp.Meta.Type {
+	case rbody.ScheduledTaskPausedType:
+		return &PauseTaskResult{resp.Body.(*rbody.ScheduledTaskPaused), nil}
+	case rbody.ErrorType:
+		return &PauseTaskResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &PauseTaskResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// ResumeTask resumes a paused task given a task id through an HTTP PUT call.
+// The resumed task id returns if it succeeds. Otherwise, an error is returned.
+func (c *Client) ResumeTask(id string) *ResumeTaskResult {
+	resp, err := c.do("PUT", fmt.Sprintf("/tasks/%v/resume", id), ContentTypeJSON)
+	if err != nil {
+		return &ResumeTaskResult{Err: err}
+	}
+
+	switch resp.Meta.Type {
+	case rbody.ScheduledTaskResumedType:
+		return &ResumeTaskResult{resp.Body.(*rbody.ScheduledTaskResumed), nil}
+	case rbody.ErrorType:
+		return &ResumeTaskResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &ResumeTaskResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// TriggerTask fires a running task with a trigger schedule given a task id
+// through an HTTP POST call. The triggered task id returns if it succeeds.
+// Otherwise, an error is returned.
+func (c *Client) TriggerTask(id string) *TriggerTaskResult {
+	resp, err := c.do("POST", fmt.Sprintf("/tasks/%v/trigger", id), ContentTypeJSON)
+	if err != nil {
+		return &TriggerTaskResult{Err: err}
+	}
+
+	switch resp.Meta.Type {
+	case rbody.ScheduledTaskTriggeredType:
+		return &TriggerTaskResult{resp.Body.(*rbody.ScheduledTaskTriggered), nil}
+	case rbody.ErrorType:
+		return &TriggerTaskResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &TriggerTaskResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// RemoveTask removes a task from the schedule tasks given a task id. It's through an HTTP DELETE call.
+// The removed task id returns if it succeeds. Otherwise, an error is returned.
+func (c *Client) RemoveTask(id string) *RemoveTasksResult {
+	resp, err := c.do("DELETE", fmt.Sprintf("/tasks/%v", id), ContentTypeJSON)
+	if err != nil {
+		return &RemoveTasksResult{Err: err}
+	}
+
+	switch resp.Meta.Type {
+	case rbody.ScheduledTaskRemovedType:
+		// Success
+		return &RemoveTasksResult{resp.Body.(*rbody.ScheduledTaskRemoved), nil}
+	case rbody.ErrorType:
+		return &RemoveTasksResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &RemoveTasksResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// GetDeletedTasks retrieves the deleted tasks which can still be restored
+// through an HTTP GET call.
+func (c *Client) GetDeletedTasks() *GetDeletedTasksResult {
+	resp, err := c.do("GET", "/deleted_tasks", ContentTypeJSON, nil)
+	if err != nil {
+		return &GetDeletedTasksResult{Err: err}
+	}
+
+	switch resp.Meta.Type {
+	case rbody.DeletedTaskListReturnedType:
+		return &GetDeletedTasksResult{resp.Body.(*rbody.DeletedTaskListReturned), nil}
+	case rbody.ErrorType:
+		return &GetDeletedTasksResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetDeletedTasksResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// GetTaskGraph retrieves the graph of the dependencies between the tasks
+// through an HTTP GET call. The graph returns if it succeeds. Otherwise, an
+// error is returned.
+func (c *Client) GetTaskGraph() *GetTaskGraphResult {
+	resp, err := c.do("GET", "/task_graph", ContentTypeJSON, nil)
+	if err != nil {
+		return &GetTaskGraphResult{Err: err}
+	}
+
+	switch resp.Meta.Type {
+	case rbody.TaskGraphReturnedType:
+		return &GetTaskGraphResult{resp.Body.(*rbody.TaskGraphReturned), nil}
+	case rbody.ErrorType:
+		return &GetTaskGraphResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetTaskGraphResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// GetDeletedTask retrieves a deleted task given a task id through an HTTP
+// GET call. The deleted task returns if it is not purged yet. Otherwise, an
+// error is returned.
+func (c *Client) GetDeletedTask(id string) *GetDeletedTaskResult {
+	resp, err := c.do("GET", fmt.Sprintf("/deleted_tasks/%v", id), ContentTypeJSON, nil)
+	if err != nil {
+		return &GetDeletedTaskResult{Err: err}
+	}
+
+	switch resp.Meta.Type {
+	case rbody.DeletedTaskReturnedType:
+		return &GetDeletedTaskResult{resp.Body.(*rbody.DeletedTaskReturned), nil}
+	case rbody.ErrorType:
+		return &GetDeletedTaskResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetDeletedTaskResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// RestoreTask brings a deleted task back, stopped, given a task id through an
+// HTTP PUT call. The restored task returns if it succeeds. Otherwise, an error
+// is returned.
+func (c *Client) RestoreTask(id string) *RestoreTaskResult {
+	resp, err := c.do("PUT", fmt.Sprintf("/tasks/%v/restore", id), ContentTypeJSON)
+	if err != nil {
+		return &RestoreTaskResult{Err: err}
+	}
+
+	switch resp.Meta.Type {
+	case rbody.ScheduledTaskRestoredType:
+		return &RestoreTaskResult{resp.Body.(*rbody.ScheduledTaskRestored), nil}
+	case rbody.ErrorType:
+		return &RestoreTaskResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &RestoreTaskResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// EnableTask enables a disabled task given a task id. The request is an HTTP PUT call.
+// The enabled task id returns if it succeeds. Otherwise, an error is returned.
+func (c *Client) EnableTask(id string) *EnableTaskResult {
+	resp, err := c.do("PUT", fmt.Sprintf("/tasks/%v/enable", id), ContentTypeJSON)
+	if err != nil {
+		return &EnableTaskResult{Err: err}
+	}
+
+	switch resp.Meta.Type {
+	case rbody.ScheduledTaskEnabledType:
+		return &EnableTaskResult{resp.Body.(*rbody.ScheduledTaskEnabled), nil}
+	case rbody.ErrorType:
+		return &EnableTaskResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &EnableTaskResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// UpdateTask changes the schedule interval, the collector config and the
+// metrics of a task without recreating it through an HTTP PATCH call.
+// A running task picks the changes up between two runs. The updated task
+// returns if it succeeds. Otherwise, an error is returned.
+func (c *Client) UpdateTask(id string, u *request.TaskUpdateRequest) *UpdateTaskResult {
+	j, err := json.Marshal(u)
+	if err != nil {
+		return &UpdateTaskResult{Err: err}
+	}
+	resp, err := c.do("PATCH", fmt.Sprintf("/tasks/%v", id), ContentTypeJSON, j)
+	if err != nil {
+		return &UpdateTaskResult{Err: err}
+	}
+
+	switch resp.Meta.Type {
+	case rbody.ScheduledTaskUpdatedType:
+		return &UpdateTaskResult{resp.Body.(*rbody.ScheduledTaskUpdated), nil}
+	case rbody.ErrorType:
+		return &UpdateTaskResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &UpdateTaskResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// CreateTaskResult is the response from snap/client on a CreateTask call.
+type CreateTaskResult struct {
+	*rbody.AddScheduledTask
+	Err error
+}
+
+// WatchTaskResult is the response from snap/client on a WatchTask call.
+type WatchTasksResult struct {
+	count     int
+	Err       error
+	EventChan chan *rbody.StreamedTaskEvent
+	DoneChan  chan struct{}
+}
+
+func (w *WatchTasksResult) Close() {
+	close(w.DoneChan)
+}
+
+// GetTasksResult is the response from snap/client on a GetTasks call.
+type GetTasksResult struct {
+	*rbody.ScheduledTaskListReturned
+	Err error
+}
+
+// GetTaskResult is the response from snap/client on a GetTask call.
+type GetTaskResult struct {
+	*rbody.ScheduledTaskReturned
+	Err error
+}
+
+// GetTaskRunsResult is the response from snap/client on a GetTaskRuns call.
+type GetTaskRunsResult struct {
+	*rbody.ScheduledTaskRunsReturned
+	Err error
+}
+
+// UpdateTaskResult is the response from snap/client on an UpdateTask call.
+type UpdateTaskResult struct {
+	*rbody.ScheduledTaskUpdated
+	Err error
+}
+
+// GetTaskConfigResult is the response from snap/client on a GetTaskConfig call.
+type GetTaskConfigResult struct {
+	*rbody.ScheduledTaskConfigReturned
+	Err error
+}
+
+// StartTasksResult is the response from snap/client on a StartTask call.
+type StartTasksResult struct {
+	*rbody.ScheduledTaskStarted
+	Err error
+}
+
+// StopTasksResult is the response from snap/client on a StopTask call.
+type StopTasksResult struct {
+	*rbody.ScheduledTaskStopped
+	Err error
+}
+
+// PauseTaskResult is the response from snap/client on a PauseTask call.
+type PauseTaskResult struct {
+	*rbody.ScheduledTaskPaused
+	Err error
+}
+
+// ResumeTaskResult is the response from snap/client on a ResumeTask call.
+type ResumeTaskResult struct {
+	*rbody.ScheduledTaskResumed
+	Err error
+}
+
+// GetTaskGraphResult is the response from snap/client on a GetTaskGraph call.
+type GetTaskGraphResult struct {
+	*rbody.TaskGraphReturned
+	Err error
+}
+
+// TriggerTaskResult is the response from snap/client on a TriggerTask call.
+type TriggerTaskResult struct {
+	*rbody.ScheduledTaskTriggered
+	Err error
+}
+
+// RemoveTasksResult is the response from snap/client on a RemoveTask call.
+type RemoveTasksResult struct {
+	*rbody.ScheduledTaskRemoved
+	Err error
+}
+
+// GetDeletedTasksResult is the response from snap/client on a GetDeletedTasks call.
+type GetDeletedTasksResult struct {
+	*rbody.DeletedTaskListReturned
+	Err error
+}
+
+// GetDeletedTaskResult is the response from snap/client on a GetDeletedTask call.
+type GetDeletedTaskResult struct {
+	*rbody.DeletedTaskReturned
+	Err error
+}
+
+// RestoreTaskResult is the response from snap/client on a RestoreTask call.
+type RestoreTaskResult struct {
+	*rbody.ScheduledTaskRestored
+	Err error
+}
+
+// EnableTasksResult is the response from snap/client on a EnableTask call.
+type EnableTaskResult struct {
+	*rbody.ScheduledTaskEnabled
+	Err error
+}
@@ -0,0 +1,470 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
+	"github.com/intelsdi-x/snap/mgmt/tribe/agreement"
+)
+
+// ListMembers retrieves a list of tribe members through an HTTP GET call.
+// A list of tribe member returns if it succeeds. Otherwise, an error is returned.
+func (c *Client) ListMembers() *ListMembersResult {
+	resp, err := c.do("GET", "/tribe/members", ContentTypeJSON, nil)
+	if err != nil {
+		return &ListMembersResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.TribeMemberListType:
+		// Success
+		return &ListMembersResult{resp.Body.(*rbody.TribeMemberList), nil}
+	case rbody.ErrorType:
+		return &ListMembersResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &ListMembersResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// GetMember retrieves the tribe member given a member name.
+// The request is an HTTP GET call.  The corresponding tribe member object returns
+// if it succeeds. Otherwise, an error is returned.
+func (c *Client) GetMember(name string) *GetMemberResult {
+	resp, err := c.do("GET", fmt.Sprintf("/tribe/members/%s", name), ContentTypeJSON, nil)
+	if err != nil {
+		return &GetMemberResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.TribeMemberShowType:
+		// Success
+		return &GetMemberResult{resp.Body.(*rbody.TribeMemberShow), nil}
+	case rbody.ErrorType:
+		return &GetMemberResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetMemberResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// ListAgreements retrieves a list of a tribe agreements through an HTTP GET call.
+// A list of tribe agreement map returns if it succeeds. Otherwise, an error is returned.
+func (c *Client) ListAgreements() *ListAgreementResult {
+	resp, err := c.do("GET", "/tribe/agreements", ContentTypeJSON, nil)
+	if err != nil {
+		return &ListAgreementResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.TribeListAgreementType:
+		return &ListAgreementResult{resp.Body.(*rbody.TribeListAgreement), nil}
+	case rbody.ErrorType:
+		return &ListAgreementResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &ListAgreementResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// AddAgreement adds a tribe agreement giving an agreement name into tribe agreement list
+// through an HTTP POST call. A map of tribe agreements with the newly added named agreement
+// returns if it succeeds. Otherwise, an error is returned. Note that the newly added agreement
+// has no effect unless members join the agreement.
+func (c *Client) AddAgreement(name string) *AddAgreementResult {
+	b, err := json.Marshal(struct {
+		Name string `json:"name"`
+	}{Name: name})
+	if err != nil {
+		return &AddAgreementResult{Err: err}
+	}
+	resp, err := c.do("POST", "/tribe/agreements", ContentTypeJSON, b)
+	if err != nil {
+		return &AddAgreementResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.TribeAddAgreementType:
+		return &AddAgreementResult{resp.Body.(*rbody.TribeAddAgreement), nil}
+	case rbody.ErrorType:
+		return &AddAgreementResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &AddAgreementResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// DeleteAgreement removes a tribe agreement giving an agreement name from the tribe agreement list
+// through an HTTP DELETE call. A map of tribe agreements with the specified agreement removed returns
+// if it succeeds. Otherwise, an error is returned. Note deleting an agreement removes the agreement
+// from the tribe entirely for all the members of the agreement.
+func (c *Client) DeleteAgreement(name string) *DeleteAgreementResult {
+	resp, err := c.do("DELETE", fmt.Sprintf("/tribe/agreements/%s", name), ContentTypeJSON, nil)
+	if err != nil {
+		return &DeleteAgreementResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.TribeDeleteAgreementType:
+		return &DeleteAgreementResult{resp.Body.(*rbody.TribeDeleteAgreement), nil}
+	case rbody.ErrorType:
+		return &DeleteAgreementResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &DeleteAgreementResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// GetAgreement retrieves a tribe agreement given an agreement name through an HTTP GET call.
+// A tribe agreement returns if it succeeded. Otherwise, an error is returned.
+func (c *Client) GetAgreement(name string) *GetAgreementResult {
+	resp, err := c.do("GET", fmt.Sprintf("/tribe/agreements/%s", name), ContentTypeJSON, nil)
+	if err != nil {
+		return &GetAgreementResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.TribeGetAgreementType:
+		return &GetAgreementResult{resp.Body.(*rbody.TribeGetAgreement), nil}
+	case rbody.ErrorType:
+		return &GetAgreementResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &GetAgreementResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// JoinAgreement adds a tribe member into the agreement given the agreement name and the member name.
+// It is an HTTP PUT request. The agreement with the newly added member returns if it succeeds.
+// Otherwise, an error is returned. Note that dual directional agreement replication happens automatically
+// through the gossip protocol between a newly joined member and existing members within the same agreement.
+func (c *Client) JoinAgreement(agreementName, memberName string) *JoinAgreementResult {
+	b, err := json.Marshal(struct {
+		MemberName string `json:"member_name"`
+	}{MemberName: memberName})
+	if err != nil {
+		return &JoinAgreementResult{Err: err}
+	}
+	resp, err := c.do("PUT", fmt.Sprintf("/tribe/agreements/%s/join", agreementName), ContentTypeJSON, b)
+	if err != nil {
+		return &JoinAgreementResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.TribeJoinAgreementType:
+		return &JoinAgreementResult{resp.Body.(*rbody.TribeJoinAgreement), nil}
+	case rbody.ErrorType:
+		return &JoinAgreementResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &JoinAgreementResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// AddAgreementPlugin adds a plugin to the agreement given the agreement name, through an HTTP PUT
+// call. The members of the agreement download the plugin from one another, or from its URL when none
+// of them has it. The agreement with the added plugin returns if it succeeds. Otherwise, an error is returned.
+func (c *Client) AddAgreementPlugin(agreementName, name, typ string, version int, url string) *AddAgreementPluginResult {
+	b, err := json.Marshal(struct {
+		Name    string `json:"name"`
+		Type    string `json:"type"`
+		Version int    `json:"version"`
+		URL     string `json:"url"`
+	}{Name: name, Type: typ, Version: version, URL: url})
+	if err != nil {
+		return &AddAgreementPluginResult{Err: err}
+	}
+	resp, err := c.do("PUT", fmt.Sprintf("/tribe/agreements/%s/plugins", agreementName), ContentTypeJSON, b)
+	if err != nil {
+		return &AddAgreementPluginResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.TribeAddPluginType:
+		return &AddAgreementPluginResult{resp.Body.(*rbody.TribeAddPlugin), nil}
+	case rbody.ErrorType:
+		return &AddAgreementPluginResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &AddAgreementPluginResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// RemoveAgreementPlugin removes a plugin from the agreement given the agreement name, through an
+// HTTP DELETE call. The members of the agreement unload the plugin. The agreement without the plugin
+// returns if it succeeds. Otherwise, an error is returned.
+func (c *Client) RemoveAgreementPlugin(agreementName, name, typ string, version int) *RemoveAgreementPluginResult {
+	b, err := json.Marshal(struct {
+		Name    string `json:"name"`
+		Type    string `json:"type"`
+		Version int    `json:"version"`
+	}{Name: name, Type: typ, Version: version})
+	if err != nil {
+		return &RemoveAgreementPluginResult{Err: err}
+	}
+	resp, err := c.do("DELETE", fmt.Sprintf("/tribe/agreements/%s/plugins", agreementName), ContentTypeJSON, b)
+	if err != nil {
+		return &RemoveAgreementPluginResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.TribeRemovePluginType:
+		return &RemoveAgreementPluginResult{resp.Body.(*rbody.TribeRemovePlugin), nil}
+	case rbody.ErrorType:
+		return &RemoveAgreementPluginResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &RemoveAgreementPluginResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// ListAgreementTasks retrieves the tasks of an agreement given the agreement name through an HTTP GET
+// call. The tasks, with their name and state on the member asked, return if it succeeds. Otherwise, an
+// error is returned.
+func (c *Client) ListAgreementTasks(agreementName string) *ListAgreementTasksResult {
+	resp, err := c.do("GET", fmt.Sprintf("/tribe/agreements/%s/tasks", agreementName), ContentTypeJSON, nil)
+	if err != nil {
+		return &ListAgreementTasksResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.TribeListTasksType:
+		return &ListAgreementTasksResult{resp.Body.(*rbody.TribeListAgreementTasks), nil}
+	case rbody.ErrorType:
+		return &ListAgreementTasksResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &ListAgreementTasksResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// AddAgreementTask adds a task of the member asked to the agreement given the agreement name and the
+// task id, through an HTTP POST call. The other members of the agreement create the task, and start it
+// when startOnCreate is true. The agreement with the added task returns if it succeeds. Otherwise, an
+// error is returned.
+func (c *Client) AddAgreementTask(agreementName, taskID string, startOnCreate bool) *AgreementTaskResult {
+	b, err := json.Marshal(struct {
+		TaskID        string `json:"task_id"`
+		StartOnCreate bool   `json:"start_on_create"`
+	}{TaskID: taskID, StartOnCreate: startOnCreate})
+	if err != nil {
+		return &AgreementTaskResult{Err: err}
+	}
+	resp, err := c.do("POST", fmt.Sprintf("/tribe/agreements/%s/tasks", agreementName), ContentTypeJSON, b)
+	if err != nil {
+		return &AgreementTaskResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.TribeAddTaskType:
+		return &AgreementTaskResult{resp.Body.(*rbody.TribeAddAgreementTask).Agreement, nil}
+	case rbody.ErrorType:
+		return &AgreementTaskResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &AgreementTaskResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// RemoveAgreementTask removes a task from the agreement given the agreement name and the task id,
+// through an HTTP DELETE call. The members of the agreement remove the task. The agreement without
+// the task returns if it succeeds. Otherwise, an error is returned.
+func (c *Client) RemoveAgreementTask(agreementName, taskID string) *AgreementTaskResult {
+	resp, err := c.do("DELETE", fmt.Sprintf("/tribe/agreements/%s/tasks/%s", agreementName, taskID), ContentTypeJSON, nil)
+	if err != nil {
+		return &AgreementTaskResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.TribeRemoveTaskType:
+		return &AgreementTaskResult{resp.Body.(*rbody.TribeRemoveAgreementTask).Agreement, nil}
+	case rbody.ErrorType:
+		return &AgreementTaskResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &AgreementTaskResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// StartAgreementTask starts a task of the agreement on all the members of the agreement given the
+// agreement name and the task id, through an HTTP PUT call. The agreement returns if it succeeds.
+// Otherwise, an error is returned.
+func (c *Client) StartAgreementTask(agreementName, taskID string) *AgreementTaskResult {
+	resp, err := c.do("PUT", fmt.Sprintf("/tribe/agreements/%s/tasks/%s/start", agreementName, taskID), ContentTypeJSON, nil)
+	if err != nil {
+		return &AgreementTaskResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.TribeStartTaskType:
+		return &AgreementTaskResult{resp.Body.(*rbody.TribeStartAgreementTask).Agreement, nil}
+	case rbody.ErrorType:
+		return &AgreementTaskResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &AgreementTaskResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// StopAgreementTask stops a task of the agreement on all the members of the agreement given the
+// agreement name and the task id, through an HTTP PUT call. The agreement returns if it succeeds.
+// Otherwise, an error is returned.
+func (c *Client) StopAgreementTask(agreementName, taskID string) *AgreementTaskResult {
+	resp, err := c.do("PUT", fmt.Sprintf("/tribe/agreements/%s/tasks/%s/stop", agreementName, taskID), ContentTypeJSON, nil)
+	if err != nil {
+		return &AgreementTaskResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.TribeStopTaskType:
+		return &AgreementTaskResult{resp.Body.(*rbody.TribeStopAgreementTask).Agreement, nil}
+	case rbody.ErrorType:
+		return &AgreementTaskResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &AgreementTaskResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// StartRollout replaces a task of an agreement by the task of the request through an HTTP POST
+// call. The new task is started on canary members first, a given count or percentage of the members
+// of the agreement, then on the other members once it ran without failing on the canaries for the
+// bake period. The rollout started returns if it succeeds. Otherwise, an error is returned.
+func (c *Client) StartRollout(agreementName, taskID string, tr *request.TaskCreationRequest, count, percent int, bakePeriod time.Duration) *RolloutResult {
+	b, err := json.Marshal(struct {
+		TaskID     string                       `json:"task_id"`
+		Task       *request.TaskCreationRequest `json:"task"`
+		Count      int                          `json:"canary_count,omitempty"`
+		Percent    int                          `json:"canary_percent,omitempty"`
+		BakePeriod string                       `json:"bake_period"`
+	}{TaskID: taskID, Task: tr, Count: count, Percent: percent, BakePeriod: bakePeriod.String()})
+	if err != nil {
+		return &RolloutResult{Err: err}
+	}
+	resp, err := c.do("POST", fmt.Sprintf("/tribe/agreements/%s/rollout", agreementName), ContentTypeJSON, b)
+	if err != nil {
+		return &RolloutResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.TribeStartRolloutType:
+		return &RolloutResult{resp.Body.(*rbody.TribeStartRollout).Rollout, nil}
+	case rbody.ErrorType:
+		return &RolloutResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &RolloutResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// GetRollout retrieves the last rollout of an agreement through an HTTP GET call.
+// The rollout returns if it succeeds. Otherwise, an error is returned.
+func (c *Client) GetRollout(agreementName string) *RolloutResult {
+	resp, err := c.do("GET", fmt.Sprintf("/tribe/agreements/%s/rollout", agreementName), ContentTypeJSON, nil)
+	if err != nil {
+		return &RolloutResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.TribeGetRolloutType:
+		return &RolloutResult{resp.Body.(*rbody.TribeGetRollout).Rollout, nil}
+	case rbody.ErrorType:
+		return &RolloutResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &RolloutResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// LeaveAgreement removes a member from the agreement given the agreement and member names through
+// an HTTP DELETE call. The agreement with the removed member returns if it succeeds.
+// Otherwise, an error is returned. For example, it is useful to leave an agreement for a member node repair.
+func (c *Client) LeaveAgreement(agreementName, memberName string) *LeaveAgreementResult {
+	b, err := json.Marshal(struct {
+		MemberName string `json:"member_name"`
+	}{MemberName: memberName})
+	if err != nil {
+		return &LeaveAgreementResult{Err: err}
+	}
+	resp, err := c.do("DELETE", fmt.Sprintf("/tribe/agreements/%s/leave", agreementName), ContentTypeJSON, b)
+	if err != nil {
+		return &LeaveAgreementResult{Err: err}
+	}
+	switch resp.Meta.Type {
+	case rbody.TribeLeaveAgreementType:
+		return &LeaveAgreementResult{resp.Body.(*rbody.TribeLeaveAgreement), nil}
+	case rbody.ErrorType:
+		return &LeaveAgreementResult{Err: resp.Body.(*rbody.Error)}
+	default:
+		return &LeaveAgreementResult{Err: ErrAPIResponseMetaType}
+	}
+}
+
+// ListMembersResult is the response from snap/client on a ListMembers call.
+type ListMembersResult struct {
+	*rbody.TribeMemberList
+	Err error
+}
+
+// GetMemberResult is the response from snap/client on a GetMember call.
+type GetMemberResult struct {
+	*rbody.TribeMemberShow
+	Err error
+}
+
+// AddAgreementResult is the response from snap/client on a AddAgreement call.
+type AddAgreementResult struct {
+	*rbody.TribeAddAgreement
+	Err error
+}
+
+// ListAgreementResult is the response from snap/client on a ListAgreements call.
+type ListAgreementResult struct {
+	*rbody.TribeListAgreement
+	Err error
+}
+
+// DeleteAgreementResult is the response from snap/client on a DeleteAgreement call.
+type DeleteAgreementResult struct {
+	*rbody.TribeDeleteAgreement
+	Err error
+}
+
+// GetAgreementResult is the response from snap/client on a GetAgreement call.
+type GetAgreementResult struct {
+	*rbody.TribeGetAgreement
+	Err error
+}
+
+// JoinAgreementResult is the response from snap/client on a JoinAgreement call.
+type JoinAgreementResult struct {
+	*rbody.TribeJoinAgreement
+	Err error
+}
+
+// RolloutResult is the response from snap/client on a StartRollout or GetRollout call.
+type RolloutResult struct {
+	*agreement.Rollout
+	Err error
+}
+
+// AddAgreementPluginResult is the response from snap/client on an AddAgreementPlugin call.
+type AddAgreementPluginResult struct {
+	*rbody.TribeAddPlugin
+	Err error
+}
+
+// RemoveAgreementPluginResult is the response from snap/client on a RemoveAgreementPlugin call.
+type RemoveAgreementPluginResult struct {
+	*rbody.TribeRemovePlugin
+	Err error
+}
+
+// ListAgreementTasksResult is the response from snap/client on a ListAgreementTasks call.
+type ListAgreementTasksResult struct {
+	*rbody.TribeListAgreementTasks
+	Err error
+}
+
+// AgreementTaskResult is the response from snap/client on an AddAgreementTask, RemoveAgreementTask,
+// StartAgreementTask or StopAgreementTask call.
+type AgreementTaskResult struct {
+	*agreement.Agreement
+	Err error
+}
+
+// LeaveAgreementResult is the response from snap/client on a LeaveAgreement call.
+type LeaveAgreementResult struct {
+	*rbody.TribeLeaveAgreement
+	Err error
+}
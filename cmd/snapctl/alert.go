@@ -0,0 +1,61 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codegangsta/cli"
+)
+
+func listAlerts(ctx *cli.Context) {
+	r := pClient.GetAlerts()
+	if r.Err != nil {
+		fmt.Printf("Error getting alerts:\n%v\n", r.Err)
+		os.Exit(1)
+	}
+	if len(r.Alerts) == 0 {
+		fmt.Println("No alerts firing")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	printFields(w, false, 0,
+		"TASK",
+		"RULE",
+		"SEVERITY",
+		"NAMESPACE",
+		"VALUE",
+		"FIRED",
+	)
+	for _, a := range r.Alerts {
+		printFields(w, false, 0,
+			a.TaskName,
+			a.Rule,
+			a.Severity,
+			a.Namespace,
+			a.Value,
+			time.Unix(a.FiredAt, 0).Format(unionParseFormat),
+		)
+	}
+	w.Flush()
+}
@@ -0,0 +1,74 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+)
+
+func listAliases(ctx *cli.Context) {
+	r := pClient.GetAliases()
+	if r.Err != nil {
+		fmt.Printf("Error getting aliases:\n%v\n", r.Err)
+		os.Exit(1)
+	}
+	if len(r.Aliases) == 0 {
+		fmt.Println("No aliases defined")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	printFields(w, false, 0, "ALIAS", "TARGET")
+	for _, a := range r.Aliases {
+		printFields(w, false, 0, a.Alias, a.Target)
+	}
+	w.Flush()
+}
+
+func addAlias(ctx *cli.Context) {
+	if len(ctx.Args()) != 2 {
+		fmt.Print("Incorrect usage\n")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+	r := pClient.AddAlias(ctx.Args().Get(0), ctx.Args().Get(1))
+	if r.Err != nil {
+		fmt.Printf("Error adding alias:\n%v\n", r.Err)
+		os.Exit(1)
+	}
+	fmt.Printf("Alias added\n%s -> %s\n", r.Alias, r.Target)
+}
+
+func removeAlias(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		fmt.Print("Incorrect usage\n")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+	r := pClient.RemoveAlias(ctx.Args().First())
+	if r.Err != nil {
+		fmt.Printf("Error removing alias:\n%v\n", r.Err)
+		os.Exit(1)
+	}
+	fmt.Printf("Alias removed\n%s\n", r.Alias)
+}
@@ -0,0 +1,209 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/intelsdi-x/snap/client"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// benchTask is a synthetic task run by bench and the times its collections
+// were streamed at
+type benchTask struct {
+	id          string
+	watch       *client.WatchTasksResult
+	mutex       sync.Mutex
+	collections []time.Time
+}
+
+func (b *benchTask) watchCollections() {
+	for {
+		select {
+		case e := <-b.watch.EventChan:
+			if e.EventType == rbody.TaskWatchMetricEvent {
+				b.mutex.Lock()
+				b.collections = append(b.collections, time.Now())
+				b.mutex.Unlock()
+			}
+		case <-b.watch.DoneChan:
+			return
+		}
+	}
+}
+
+// lateness returns how far apart from the interval consecutive collections were
+func (b *benchTask) lateness(interval time.Duration) []time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	var ds []time.Duration
+	for i := 1; i < len(b.collections); i++ {
+		d := b.collections[i].Sub(b.collections[i-1]) - interval
+		if d < 0 {
+			d = -d
+		}
+		ds = append(ds, d)
+	}
+	return ds
+}
+
+func bench(ctx *cli.Context) {
+	interval, err := time.ParseDuration(ctx.String("interval"))
+	if err != nil {
+		fmt.Printf("Bad interval format:\n%v\n", err)
+		os.Exit(1)
+	}
+	duration, err := time.ParseDuration(ctx.String("duration"))
+	if err != nil {
+		fmt.Printf("Bad duration format:\n%v\n", err)
+		os.Exit(1)
+	}
+	pid := ctx.Int("snapd-pid")
+
+	wf := wmap.NewWorkflowMap()
+	wf.CollectNode.AddMetric("/intel/mock/*/baz", 2)
+	wf.CollectNode.AddConfigItem("/intel/mock", "test_fixture", true)
+	wf.CollectNode.AddConfigItem("/intel/mock", "fixture_metric_count", ctx.Int("metrics"))
+	if ctx.IsSet("plugin-latency") {
+		wf.CollectNode.AddConfigItem("/intel/mock", "fixture_latency", ctx.String("plugin-latency"))
+	}
+
+	rssBefore := snapdRSS(pid)
+	tasks := []*benchTask{}
+	fmt.Printf("Creating %d tasks collecting %d metrics every %s\n", ctx.Int("tasks"), ctx.Int("metrics"), interval)
+	for i := 0; i < ctx.Int("tasks"); i++ {
+		sch := &client.Schedule{Type: "simple", Interval: interval.String()}
+		r := pClient.CreateTask(sch, wf, fmt.Sprintf("bench-%d", i), "", true)
+		if r.Err != nil {
+			fmt.Printf("Error creating task:\n%v\n", r.Err)
+			removeBenchTasks(tasks)
+			os.Exit(1)
+		}
+		t := &benchTask{id: r.ID, watch: pClient.WatchTask(r.ID)}
+		if t.watch.Err != nil {
+			fmt.Printf("Error watching task %s:\n%v\n", r.ID, t.watch.Err)
+			removeBenchTasks(append(tasks, t))
+			os.Exit(1)
+		}
+		go t.watchCollections()
+		tasks = append(tasks, t)
+	}
+
+	fmt.Printf("Running the tasks for %s\n", duration)
+	time.Sleep(duration)
+
+	var hits, misses, failures, sheds int
+	var lateness []time.Duration
+	for _, t := range tasks {
+		t.watch.Close()
+		lateness = append(lateness, t.lateness(interval)...)
+		r := pClient.GetTask(t.id)
+		if r.Err != nil {
+			fmt.Printf("Error getting task %s:\n%v\n", t.id, r.Err)
+			continue
+		}
+		hits += r.HitCount
+		misses += r.MissCount
+		failures += r.FailedCount
+		sheds += r.ShedCount
+	}
+	rssAfter := snapdRSS(pid)
+	removeBenchTasks(tasks)
+	tasks = nil
+
+	sort.Sort(durations(lateness))
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	printFields(w, false, 0, "COLLECTIONS", "MISSED", "FAILED", "SHED")
+	printFields(w, false, 0, hits, misses, failures, sheds)
+	printFields(w, false, 0)
+	printFields(w, false, 0, "LATENESS", "P50", "P90", "P99", "MAX")
+	printFields(w, false, 0, "", percentile(lateness, 50), percentile(lateness, 90), percentile(lateness, 99), percentile(lateness, 100))
+	if pid > 0 {
+		printFields(w, false, 0)
+		printFields(w, false, 0, "SNAPD RSS", "BEFORE", "AFTER")
+		printFields(w, false, 0, "", rssBefore, rssAfter)
+	}
+	w.Flush()
+}
+
+func removeBenchTasks(tasks []*benchTask) {
+	for _, t := range tasks {
+		pClient.StopTask(t.id)
+		// tasks stop once their running collection completes
+		for i := 0; i < 50; i++ {
+			if r := pClient.GetTask(t.id); r.Err != nil || r.State == "Stopped" {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		if r := pClient.RemoveTask(t.id); r.Err != nil {
+			fmt.Printf("Error removing task %s:\n%v\n", t.id, r.Err)
+		}
+	}
+}
+
+type durations []time.Duration
+
+func (d durations) Len() int           { return len(d) }
+func (d durations) Less(i, j int) bool { return d[i] < d[j] }
+func (d durations) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// percentile returns the pth percentile of sorted durations
+func percentile(ds []time.Duration, p int) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	i := (len(ds)*p+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+	return ds[i]
+}
+
+// snapdRSS returns the resident memory of the process as reported by
+// /proc/<pid>/status, or "unknown"
+func snapdRSS(pid int) string {
+	if pid <= 0 {
+		return ""
+	}
+	f, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return "unknown"
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if strings.HasPrefix(s.Text(), "VmRSS:") {
+			return strings.TrimSpace(strings.TrimPrefix(s.Text(), "VmRSS:"))
+		}
+	}
+	return "unknown"
+}
@@ -47,8 +47,13 @@ var (
 						flTaskSchedStopTime,
 						flTaskName,
 						flTaskSchedDuration,
+						flTaskSchedJitter,
+						flTaskSchedOverrun,
+						flTaskSchedOverrunQueueDepth,
 						flTaskSchedNoStart,
 						flTaskDeadline,
+						flTaskPriority,
+						flTaskDescription,
 					},
 				},
 				{
@@ -66,11 +71,47 @@ var (
 					Usage:  "stop <task_id>",
 					Action: stopTask,
 				},
+				{
+					Name:   "pause",
+					Usage:  "pause <task_id>",
+					Action: pauseTask,
+					Flags: []cli.Flag{
+						flTaskPauseFor,
+					},
+				},
+				{
+					Name:   "resume",
+					Usage:  "resume <task_id>",
+					Action: resumeTask,
+				},
+				{
+					Name:   "trigger",
+					Usage:  "trigger <task_id>",
+					Action: triggerTask,
+				},
 				{
 					Name:   "remove",
 					Usage:  "remove <task_id>",
 					Action: removeTask,
 				},
+				{
+					Name:   "deleted",
+					Usage:  "deleted [<task_id>]",
+					Action: deletedTasks,
+				},
+				{
+					Name:   "restore",
+					Usage:  "restore <task_id>",
+					Action: restoreTask,
+				},
+				{
+					Name:   "graph",
+					Usage:  "graph",
+					Action: taskGraph,
+					Flags: []cli.Flag{
+						flTaskGraphDot,
+					},
+				},
 				{
 					Name:   "export",
 					Usage:  "export <task_id>",
@@ -80,12 +121,65 @@ var (
 					Name:   "watch",
 					Usage:  "watch <task_id>",
 					Action: watchTask,
+					Flags: []cli.Flag{
+						flTaskWatchLifecycle,
+						flTaskWatchReplay,
+						flTaskWatchFilter,
+						flTaskWatchMin,
+						flTaskWatchMax,
+						flTaskWatchQuiet,
+					},
 				},
 				{
 					Name:   "enable",
 					Usage:  "enable <task_id>",
 					Action: enableTask,
 				},
+				{
+					Name:   "history",
+					Usage:  "history <task_id>",
+					Action: taskHistory,
+				},
+				{
+					Name:   "update",
+					Usage:  "update <task_id>",
+					Action: updateTask,
+					Flags: []cli.Flag{
+						flTaskUpdateInterval,
+						flTaskUpdateConfig,
+						flTaskUpdateUnsetConfig,
+						flTaskUpdateAddMetric,
+						flTaskUpdateRemoveMetric,
+						flTaskDescription,
+					},
+				},
+				{
+					Name:   "config",
+					Usage:  "config <task_id>",
+					Action: taskConfig,
+					Flags: []cli.Flag{
+						flTaskConfigResolve,
+					},
+				},
+				{
+					Name:   "scaffold",
+					Usage:  "print a task manifest collecting the metrics of a plugin, with the config of its policy",
+					Action: scaffoldTask,
+					Flags: []cli.Flag{
+						flTaskScaffoldPlugin,
+						flTaskScaffoldMetric,
+						flTaskScaffoldPluginVersion,
+						flTaskSchedInterval,
+					},
+				},
+				{
+					Name:   "convert",
+					Usage:  "convert --from <telegraf|collectd> <config_path>, print a task manifest doing what the configuration of another agent does",
+					Action: convertTask,
+					Flags: []cli.Flag{
+						flTaskConvertFrom,
+					},
+				},
 			},
 		},
 		{
@@ -107,6 +201,7 @@ var (
 						flPluginType,
 						flPluginName,
 						flPluginVersion,
+						flPluginForce,
 					},
 				},
 				{
@@ -117,6 +212,50 @@ var (
 						flRunning,
 					},
 				},
+				{
+					Name:   "tasks",
+					Usage:  "tasks <plugin_name> [-t <plugin_type>] [-v <plugin_version>]",
+					Action: pluginTasks,
+					Flags: []cli.Flag{
+						flPluginType,
+						flPluginVersion,
+					},
+				},
+				{
+					Name: "config",
+					Subcommands: []cli.Command{
+						{
+							Name:   "get",
+							Usage:  "get <plugin_type>:<plugin_name>:<plugin_version> or get -t <plugin_type> -n <plugin_name> -v <plugin_version>",
+							Action: getConfig,
+							Flags: []cli.Flag{
+								flPluginName,
+								flPluginType,
+								flPluginVersion,
+							},
+						},
+						{
+							Name:   "set",
+							Usage:  "set <plugin_type>:<plugin_name>:<plugin_version> <key>=<value>... or set -t <plugin_type> -n <plugin_name> -v <plugin_version> <key>=<value>...",
+							Action: setConfig,
+							Flags: []cli.Flag{
+								flPluginName,
+								flPluginType,
+								flPluginVersion,
+							},
+						},
+						{
+							Name:   "unset",
+							Usage:  "unset <plugin_type>:<plugin_name>:<plugin_version> <key>... or unset -t <plugin_type> -n <plugin_name> -v <plugin_version> <key>...",
+							Action: unsetConfig,
+							Flags: []cli.Flag{
+								flPluginName,
+								flPluginType,
+								flPluginVersion,
+							},
+						},
+					},
+				},
 			},
 		},
 		{
@@ -129,6 +268,7 @@ var (
 					Flags: []cli.Flag{
 						flMetricVersion,
 						flMetricNamespace,
+						flMetricQuery,
 					},
 				},
 				{
@@ -140,6 +280,127 @@ var (
 						flMetricNamespace,
 					},
 				},
+				{
+					Name:   "export",
+					Usage:  "export the metric catalog (json, prometheus or openmetrics)",
+					Action: exportMetrics,
+					Flags: []cli.Flag{
+						flMetricExportFormat,
+					},
+				},
+				{
+					Name:   "tasks",
+					Usage:  "tasks <metric_namespace>",
+					Action: metricTasks,
+				},
+			},
+		},
+		{
+			Name: "alias",
+			Subcommands: []cli.Command{
+				{
+					Name:   "list",
+					Usage:  "list",
+					Action: listAliases,
+				},
+				{
+					Name:        "add",
+					Usage:       "add <alias_namespace> <metric_namespace>",
+					Description: "Makes a namespace of the metric catalog stand for the namespace of a metric",
+					Action:      addAlias,
+				},
+				{
+					Name:   "remove",
+					Usage:  "remove <alias_namespace>",
+					Action: removeAlias,
+				},
+			},
+		},
+		{
+			Name: "alert",
+			Subcommands: []cli.Command{
+				{
+					Name:   "list",
+					Usage:  "list",
+					Action: listAlerts,
+				},
+			},
+		},
+		{
+			Name: "workers",
+			Subcommands: []cli.Command{
+				{
+					Name:        "list",
+					Usage:       "list",
+					Description: "Shows the worker pools of the scheduler and how busy they are",
+					Action:      listWorkerPools,
+				},
+				{
+					Name:        "resize",
+					Usage:       "resize <pool> <workers>",
+					Description: "Sets the number of workers of a worker pool (collect, process or publish)",
+					Action:      resizeWorkerPool,
+				},
+			},
+		},
+		{
+			Name: "system",
+			Subcommands: []cli.Command{
+				{
+					Name:        "maintenance",
+					Usage:       "maintenance [on|off] [--hold-publish]",
+					Description: "Shows or turns the maintenance mode of snapd on or off. The running tasks are paused, or keep collecting while their publishing is held with --hold-publish",
+					Action:      maintenance,
+					Flags: []cli.Flag{
+						flMaintenanceHoldPublish,
+					},
+				},
+				{
+					Name:        "aggregator",
+					Usage:       "aggregator",
+					Description: "Shows the aggregator role of snapd, republishing the metrics forwarded by the other members of the tribe, and the members forwarding them",
+					Action:      aggregator,
+				},
+			},
+		},
+		{
+			Name: "log",
+			Subcommands: []cli.Command{
+				{
+					Name:        "tail",
+					Usage:       "tail [--component <component>] [--lines <count>] [--follow]",
+					Description: "Shows the recent log entries of snapd and the output of its plugins",
+					Action:      tailLogs,
+					Flags: []cli.Flag{
+						flLogComponent,
+						flLogLines,
+						flLogFollow,
+					},
+				},
+			},
+		},
+		{
+			Name:        "bench",
+			Usage:       "bench [--tasks <count>] [--metrics <count>] [--interval <interval>] [--duration <duration>]",
+			Description: "Runs synthetic tasks against the mock collector (version 2) and reports how they were scheduled",
+			Action:      bench,
+			Flags: []cli.Flag{
+				flBenchTasks,
+				flBenchMetrics,
+				flBenchInterval,
+				flBenchDuration,
+				flBenchLatency,
+				flBenchPid,
+			},
+		},
+		{
+			Name:        "discover",
+			Usage:       "discover [--timeout <timeout>] [--interface <interface>]",
+			Description: "Lists the snapd advertised on the local network over mDNS",
+			Action:      discover,
+			Flags: []cli.Flag{
+				flDiscoverTimeout,
+				flDiscoverInterface,
 			},
 		},
 	}
@@ -160,6 +421,11 @@ var (
 					Action: showMember,
 					Flags:  []cli.Flag{flVerbose},
 				},
+				{
+					Name:   "describe",
+					Usage:  "describe <member_name>",
+					Action: describeMember,
+				},
 			},
 		},
 		{
@@ -196,6 +462,91 @@ var (
 					Usage:  "members <agreement_name>",
 					Action: agreementMembers,
 				},
+				{
+					Name:   "export",
+					Usage:  "export <agreement_name> [--format json|yaml]",
+					Action: exportAgreement,
+					Flags: []cli.Flag{
+						flAgreementFormat,
+					},
+				},
+				{
+					Name:   "import",
+					Usage:  "import <spec_file>",
+					Action: importAgreement,
+				},
+				{
+					Name:  "task",
+					Usage: "task list|add|remove|start|stop",
+					Subcommands: []cli.Command{
+						{
+							Name:   "list",
+							Usage:  "list <agreement_name>",
+							Action: listAgreementTasks,
+						},
+						{
+							Name:   "add",
+							Usage:  "add <agreement_name> <task_id> [--no-start]",
+							Action: addAgreementTask,
+							Flags: []cli.Flag{
+								flTaskSchedNoStart,
+							},
+						},
+						{
+							Name:   "remove",
+							Usage:  "remove <agreement_name> <task_id>",
+							Action: removeAgreementTask,
+						},
+						{
+							Name:   "start",
+							Usage:  "start <agreement_name> <task_id>",
+							Action: startAgreementTask,
+						},
+						{
+							Name:   "stop",
+							Usage:  "stop <agreement_name> <task_id>",
+							Action: stopAgreementTask,
+						},
+					},
+				},
+				{
+					Name:  "plugin",
+					Usage: "plugin add|remove",
+					Subcommands: []cli.Command{
+						{
+							Name:   "add",
+							Usage:  "add <agreement_name> <plugin_type>:<plugin_name>:<plugin_version> <url>",
+							Action: addAgreementPlugin,
+						},
+						{
+							Name:   "remove",
+							Usage:  "remove <agreement_name> <plugin_type>:<plugin_name>:<plugin_version>",
+							Action: removeAgreementPlugin,
+						},
+					},
+				},
+				{
+					Name:  "rollout",
+					Usage: "rollout start|status",
+					Subcommands: []cli.Command{
+						{
+							Name:   "start",
+							Usage:  "start <agreement_name> <task_id> --task-manifest <file> [--canary-count <count> | --canary-percent <percent>] [--bake-period <duration>]",
+							Action: startRollout,
+							Flags: []cli.Flag{
+								flTaskManifest,
+								flRolloutCanaryCount,
+								flRolloutCanaryPercent,
+								flRolloutBakePeriod,
+							},
+						},
+						{
+							Name:   "status",
+							Usage:  "status <agreement_name>",
+							Action: rolloutStatus,
+						},
+					},
+				},
 			},
 		},
 		{
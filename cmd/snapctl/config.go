@@ -27,6 +27,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/codegangsta/cli"
@@ -38,6 +39,8 @@ type config struct {
 }
 type restAPIConfig struct {
 	Password *string `json:"rest-auth-pwd"`
+	Username *string `json:"rest-auth-user"`
+	Token    *string `json:"rest-auth-token"`
 }
 
 func (c *config) loadConfig(path string) error {
@@ -52,7 +55,9 @@ func (c *config) loadConfig(path string) error {
 	return nil
 }
 
-func getConfig(ctx *cli.Context) {
+// pluginConfigTarget returns the type, name and version of the plugin given
+// either as <plugin_type>:<plugin_name>:<plugin_version> or through flags
+func pluginConfigTarget(ctx *cli.Context) (string, string, int) {
 	pDetails := filepath.SplitList(ctx.Args().First())
 	var ptyp string
 	var pname string
@@ -89,6 +94,37 @@ func getConfig(ctx *cli.Context) {
 		cli.ShowCommandHelp(ctx, ctx.Command.Name)
 		os.Exit(1)
 	}
+	return ptyp, pname, pver
+}
+
+// configArgs returns the arguments following the plugin, which may have
+// been given through flags instead
+func configArgs(ctx *cli.Context) []string {
+	args := ctx.Args()
+	if len(args) > 0 && len(filepath.SplitList(args.First())) == 3 {
+		return args.Tail()
+	}
+	return args
+}
+
+// parseConfigValue converts the value of a <key>=<value> argument into a
+// config value, trying an integer, a float and a boolean before falling
+// back to a string
+func parseConfigValue(s string) ctypes.ConfigValue {
+	if i, err := strconv.Atoi(s); err == nil {
+		return ctypes.ConfigValueInt{Value: i}
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return ctypes.ConfigValueFloat{Value: f}
+	}
+	if b, err := strconv.ParseBool(s); err == nil {
+		return ctypes.ConfigValueBool{Value: b}
+	}
+	return ctypes.ConfigValueStr{Value: s}
+}
+
+func getConfig(ctx *cli.Context) {
+	ptyp, pname, pver := pluginConfigTarget(ctx)
 	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
 	defer w.Flush()
 	r := pClient.GetPluginConfig(ptyp, pname, strconv.Itoa(pver))
@@ -101,7 +137,70 @@ func getConfig(ctx *cli.Context) {
 		"VALUE",
 		"TYPE",
 	)
-	for k, v := range r.Table() {
+	printConfigTable(w, r.Table())
+}
+
+func setConfig(ctx *cli.Context) {
+	ptyp, pname, pver := pluginConfigTarget(ctx)
+	args := configArgs(ctx)
+	if len(args) == 0 {
+		fmt.Println("Must provide at least one <key>=<value>")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+	var table map[string]ctypes.ConfigValue
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			fmt.Printf("Invalid config item %q, expected <key>=<value>\n", arg)
+			os.Exit(1)
+		}
+		r := pClient.SetPluginConfig(ptyp, pname, strconv.Itoa(pver), kv[0], parseConfigValue(kv[1]))
+		if r.Err != nil {
+			fmt.Println("Error setting config: ", r.Err)
+			os.Exit(1)
+		}
+		table = r.Table()
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	defer w.Flush()
+	printFields(w, false, 0,
+		"NAME",
+		"VALUE",
+		"TYPE",
+	)
+	printConfigTable(w, table)
+}
+
+func unsetConfig(ctx *cli.Context) {
+	ptyp, pname, pver := pluginConfigTarget(ctx)
+	keys := configArgs(ctx)
+	if len(keys) == 0 {
+		fmt.Println("Must provide at least one key")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+	var table map[string]ctypes.ConfigValue
+	for _, key := range keys {
+		r := pClient.DeletePluginConfig(ptyp, pname, strconv.Itoa(pver), key)
+		if r.Err != nil {
+			fmt.Println("Error removing config: ", r.Err)
+			os.Exit(1)
+		}
+		table = r.Table()
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	defer w.Flush()
+	printFields(w, false, 0,
+		"NAME",
+		"VALUE",
+		"TYPE",
+	)
+	printConfigTable(w, table)
+}
+
+func printConfigTable(w *tabwriter.Writer, table map[string]ctypes.ConfigValue) {
+	for k, v := range table {
 		switch t := v.(type) {
 		case ctypes.ConfigValueInt:
 			printFields(w, false, 0, k, t.Value, t.Type())
@@ -111,6 +210,9 @@ func getConfig(ctx *cli.Context) {
 			printFields(w, false, 0, k, t.Value, t.Type())
 		case ctypes.ConfigValueStr:
 			printFields(w, false, 0, k, t.Value, t.Type())
+		case ctypes.ConfigValueList:
+			b, _ := json.Marshal(t)
+			printFields(w, false, 0, k, string(b), t.Type())
 		}
 	}
 }
@@ -0,0 +1,58 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/intelsdi-x/snap/pkg/convert"
+)
+
+// convertTask prints the task manifest a Telegraf or collectd configuration
+// converts to, what is not converted being noted in comments at its top
+func convertTask(ctx *cli.Context) {
+	from := ctx.String("from")
+	if from == "" || len(ctx.Args()) != 1 {
+		fmt.Print("Incorrect usage\n")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+	f, err := os.Open(ctx.Args().First())
+	if err != nil {
+		fmt.Printf("Error converting configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	r, err := convert.Convert(strings.ToLower(from), f)
+	if err != nil {
+		fmt.Printf("Error converting configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+	m, err := r.Manifest()
+	if err != nil {
+		fmt.Printf("Error converting configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(string(m))
+}
@@ -0,0 +1,86 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/intelsdi-x/snap/mgmt/mdns"
+)
+
+func discover(ctx *cli.Context) {
+	timeout, err := time.ParseDuration(ctx.String("timeout"))
+	if err != nil {
+		fmt.Printf("Bad timeout format:\n%v\n", err)
+		os.Exit(1)
+	}
+	var iface *net.Interface
+	if ctx.IsSet("interface") {
+		iface, err = net.InterfaceByName(ctx.String("interface"))
+		if err != nil {
+			fmt.Printf("Error getting interface %s:\n%v\n", ctx.String("interface"), err)
+			os.Exit(1)
+		}
+	}
+	agents, err := mdns.Discover(timeout, iface)
+	if err != nil {
+		fmt.Printf("Error discovering snapd:\n%v\n", err)
+		os.Exit(1)
+	}
+	if len(agents) == 0 {
+		fmt.Println("No snapd advertised on the local network")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	printFields(w, false, 0,
+		"INSTANCE",
+		"HOST",
+		"REST API",
+		"TRIBE",
+		"MEMBER",
+		"VERSION",
+		"AGENT ID",
+	)
+	for _, a := range agents {
+		printFields(w, false, 0,
+			a.Instance,
+			a.Host,
+			orNone(a.URL()),
+			orNone(a.TribeAddr()),
+			orNone(a.TribeMember),
+			a.Version,
+			a.AgentID,
+		)
+	}
+	w.Flush()
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
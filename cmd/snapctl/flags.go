@@ -19,17 +19,26 @@ limitations under the License.
 
 package main
 
-import "github.com/codegangsta/cli"
+import (
+	"time"
+
+	"github.com/codegangsta/cli"
+)
 
 var (
 
 	// Main flags
 	flURL = cli.StringFlag{
 		Name:   "url, u",
-		Usage:  "Sets the URL to use",
+		Usage:  "Sets the URL to use, or a comma separated list of URLs to fail over to",
 		EnvVar: "SNAP_URL",
 		Value:  "http://localhost:8181",
 	}
+	flTribeAgreement = cli.StringFlag{
+		Name:   "tribe-agreement",
+		Usage:  "Sends task requests to the members of the tribe agreement",
+		EnvVar: "SNAP_TRIBE_AGREEMENT",
+	}
 	flAPIVer = cli.StringFlag{
 		Name:  "api-version, a",
 		Usage: "The snap API version",
@@ -39,6 +48,21 @@ var (
 		Name:  "insecure",
 		Usage: "Ignore certificate errors when snap's API is running HTTPS",
 	}
+	flCACert = cli.StringFlag{
+		Name:   "ca-cert",
+		Usage:  "Path to the CA certificates verifying the certificate of snap's API when running HTTPS",
+		EnvVar: "SNAP_CA_CERT",
+	}
+	flClientCert = cli.StringFlag{
+		Name:   "client-cert",
+		Usage:  "Path to the certificate presented to snap's API when running HTTPS, with --client-key",
+		EnvVar: "SNAP_CLIENT_CERT",
+	}
+	flClientKey = cli.StringFlag{
+		Name:   "client-key",
+		Usage:  "Path to the key of the certificate presented to snap's API",
+		EnvVar: "SNAP_CLIENT_KEY",
+	}
 	flRunning = cli.BoolFlag{
 		Name:  "running",
 		Usage: "Shows running plugins",
@@ -47,6 +71,16 @@ var (
 		Name:  "password, p",
 		Usage: "Password for REST API authentication",
 	}
+	flUsername = cli.StringFlag{
+		Name:   "username",
+		Usage:  "User for REST API authentication, e.g. with LDAP (default: snap)",
+		EnvVar: "SNAP_USERNAME",
+	}
+	flToken = cli.StringFlag{
+		Name:   "token",
+		Usage:  "Bearer token for REST API authentication, a static token of snapd or one of its OIDC provider",
+		EnvVar: "SNAP_TOKEN",
+	}
 	flConfig = cli.StringFlag{
 		Name:   "config, c",
 		EnvVar: "SNAPCTL_CONFIG_PATH",
@@ -71,6 +105,10 @@ var (
 		Name:  "plugin-version, v",
 		Usage: "The plugin version",
 	}
+	flPluginForce = cli.BoolFlag{
+		Name:  "force, f",
+		Usage: "Stop the running tasks which depend on the plugin before unloading it",
+	}
 
 	// Task flags
 	flTaskName = cli.StringFlag{
@@ -110,6 +148,19 @@ var (
 		Name:  "stop-date",
 		Usage: "Stop date for the task schedule [defaults to today]",
 	}
+	flTaskSchedJitter = cli.StringFlag{
+		Name:  "jitter",
+		Usage: "Maximum delay of the first collection of a simple schedule, derived from the task ID [ex: 500ms, 5s]",
+	}
+	flTaskSchedOverrun = cli.StringFlag{
+		Name:  "overrun",
+		Usage: "What to do when the schedule fires while the task is still running [skip (default), queue or stretch]",
+	}
+	flTaskSchedOverrunQueueDepth = cli.IntFlag{
+		Name:  "overrun-queue-depth",
+		Usage: "Maximum number of runs queued by the queue overrun policy",
+		Value: 1,
+	}
 	flTaskSchedDuration = cli.StringFlag{
 		Name:  "duration, d",
 		Usage: "The amount of time to run the task [appends to start or creates a start time before a stop]",
@@ -118,10 +169,99 @@ var (
 		Name:  "no-start",
 		Usage: "Do not start task on creation [normally started on creation]",
 	}
+	flTaskPriority = cli.StringFlag{
+		Name:  "priority",
+		Usage: "Priority of the task when the workers are saturated [critical, normal (default) or low]",
+	}
+	flTaskDescription = cli.StringFlag{
+		Name:  "description",
+		Usage: "A free-text description of the task",
+	}
 	flTaskDeadline = cli.StringFlag{
 		Name:  "deadline",
 		Usage: "The deadline for the task to be killed after started if the task runs too long (All tasks default to 5s)",
 	}
+	flTaskWatchLifecycle = cli.BoolFlag{
+		Name:  "lifecycle",
+		Usage: "Only watch the task started, stopped and disabled events, leaving out the collected metrics",
+	}
+	flTaskUpdateInterval = cli.StringFlag{
+		Name:  "interval, i",
+		Usage: "The new interval of the simple schedule of the task [ex: 250ms, 1s, 30m]",
+	}
+	flTaskUpdateConfig = cli.StringSliceFlag{
+		Name:  "config, c",
+		Usage: "A config item of the collect node to set, as <namespace>:<key>=<value> [ex: /intel/mock:password=secret]",
+		Value: &cli.StringSlice{},
+	}
+	flTaskUpdateUnsetConfig = cli.StringSliceFlag{
+		Name:  "unset-config",
+		Usage: "A config item of the collect node to remove, as <namespace>:<key>",
+		Value: &cli.StringSlice{},
+	}
+	flTaskUpdateAddMetric = cli.StringSliceFlag{
+		Name:  "add-metric, a",
+		Usage: "A metric to collect, as <namespace>[:<version>]",
+		Value: &cli.StringSlice{},
+	}
+	flTaskUpdateRemoveMetric = cli.StringSliceFlag{
+		Name:  "remove-metric, r",
+		Usage: "A metric namespace of the task to stop collecting",
+		Value: &cli.StringSlice{},
+	}
+	flMaintenanceHoldPublish = cli.BoolFlag{
+		Name:  "hold-publish",
+		Usage: "Keep the tasks collecting and hold what they publish in write-ahead logs until the maintenance mode is off, instead of pausing them",
+	}
+	flTaskPauseFor = cli.StringFlag{
+		Name:  "for",
+		Usage: "Resume the task once the duration elapses [ex: 30m, 2h]",
+	}
+	flTaskGraphDot = cli.BoolFlag{
+		Name:  "dot",
+		Usage: "Print the graph in the Graphviz DOT language [ex: snapctl task graph --dot | dot -Tsvg > tasks.svg]",
+	}
+	flTaskConfigResolve = cli.BoolFlag{
+		Name:  "resolve",
+		Usage: "Show the source of each value (default, global, task or metric config) and the values it overrides",
+	}
+	flTaskWatchFilter = cli.StringFlag{
+		Name:  "filter, f",
+		Usage: "Only watch the metrics whose namespace matches the glob [ex: /intel/psutil/cpu/*]",
+	}
+	flTaskWatchMin = cli.Float64Flag{
+		Name:  "min",
+		Usage: "Highlight the values below the threshold",
+	}
+	flTaskWatchMax = cli.Float64Flag{
+		Name:  "max",
+		Usage: "Highlight the values above the threshold",
+	}
+	flTaskWatchQuiet = cli.BoolFlag{
+		Name:  "quiet, q",
+		Usage: "Only print the values crossing the --min or --max threshold, one after the other",
+	}
+	flTaskWatchReplay = cli.IntFlag{
+		Name:  "replay",
+		Usage: "Number of the last lifecycle events of the task shown when the watch starts [max 20]",
+	}
+	flTaskScaffoldPlugin = cli.StringFlag{
+		Name:  "plugin, p",
+		Usage: "The collector plugin whose metrics the task collects",
+	}
+	flTaskScaffoldMetric = cli.StringSliceFlag{
+		Name:  "metric, m",
+		Usage: "A metric namespace of the plugin to collect, which may use wildcards [defaults to all the metrics of the plugin]",
+		Value: &cli.StringSlice{},
+	}
+	flTaskScaffoldPluginVersion = cli.IntFlag{
+		Name:  "plugin-version, v",
+		Usage: "The plugin version. Default (0) is any",
+	}
+	flTaskConvertFrom = cli.StringFlag{
+		Name:  "from",
+		Usage: "The agent the configuration is of [telegraf or collectd]",
+	}
 
 	// metric
 	flMetricVersion = cli.IntFlag{
@@ -132,6 +272,91 @@ var (
 		Name:  "metric-namespace, m",
 		Usage: "A metric namespace",
 	}
+	flMetricQuery = cli.StringFlag{
+		Name:  "query, q",
+		Usage: "A catalog query (e.g. 'ns=/intel/cpu/* AND plugin=psutil AND version>=3')",
+	}
+	flMetricExportFormat = cli.StringFlag{
+		Name:  "format, f",
+		Usage: "The export format (json, prometheus or openmetrics)",
+		Value: "json",
+	}
+
+	// agreement
+	flAgreementFormat = cli.StringFlag{
+		Name:  "format, f",
+		Usage: "The export format (json or yaml)",
+		Value: "json",
+	}
+	flRolloutCanaryCount = cli.IntFlag{
+		Name:  "canary-count",
+		Usage: "The number of members the new task is started on first",
+	}
+	flRolloutCanaryPercent = cli.IntFlag{
+		Name:  "canary-percent",
+		Usage: "The percentage of the members the new task is started on first, when no canary count is given",
+	}
+	flRolloutBakePeriod = cli.DurationFlag{
+		Name:  "bake-period",
+		Usage: "How long the new task must run without failing on the canaries before it is started on the other members",
+		Value: 5 * time.Minute,
+	}
+
+	// bench
+	flBenchTasks = cli.IntFlag{
+		Name:  "tasks",
+		Usage: "The number of synthetic tasks to create",
+		Value: 10,
+	}
+	flBenchMetrics = cli.IntFlag{
+		Name:  "metrics",
+		Usage: "The number of metrics collected by each task",
+		Value: 10,
+	}
+	flBenchInterval = cli.StringFlag{
+		Name:  "interval, i",
+		Usage: "The collection interval of the tasks",
+		Value: "1s",
+	}
+	flBenchDuration = cli.StringFlag{
+		Name:  "duration, d",
+		Usage: "How long the tasks are run for",
+		Value: "30s",
+	}
+	flBenchLatency = cli.StringFlag{
+		Name:  "plugin-latency",
+		Usage: "Latency injected in each collection of the mock collector [ex: 50ms]",
+	}
+	flBenchPid = cli.IntFlag{
+		Name:  "snapd-pid",
+		Usage: "The pid of a local snapd to report the memory of (Linux only)",
+	}
+
+	// discover
+	flDiscoverTimeout = cli.StringFlag{
+		Name:  "timeout",
+		Usage: "How long the answers of the snapd advertised are waited for",
+		Value: "2s",
+	}
+	flDiscoverInterface = cli.StringFlag{
+		Name:  "interface",
+		Usage: "The network interface the query is sent on. Default is every multicast interface",
+	}
+
+	// log
+	flLogComponent = cli.StringFlag{
+		Name:  "component, c",
+		Usage: "The component of the entries [control, rest, scheduler, tribe, plugin or plugin:<executable>]. Default is all of them",
+	}
+	flLogLines = cli.IntFlag{
+		Name:  "lines, n",
+		Usage: "The number of recent entries shown",
+		Value: 100,
+	}
+	flLogFollow = cli.BoolFlag{
+		Name:  "follow, f",
+		Usage: "Keep showing the entries as they are logged",
+	}
 
 	// general
 	flVerbose = cli.BoolFlag{
@@ -0,0 +1,92 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+const logTimeFormat = "2006-01-02 15:04:05.000"
+
+func tailLogs(ctx *cli.Context) {
+	component := ctx.String("component")
+	lines := ctx.Int("lines")
+	if !ctx.Bool("follow") {
+		r := pClient.GetLogs(component, lines)
+		if r.Err != nil {
+			fmt.Printf("Error getting logs:\n%v\n", r.Err)
+			os.Exit(1)
+		}
+		for _, e := range r.Entries {
+			printLogEntry(&e)
+		}
+		return
+	}
+
+	r := pClient.TailLogs(component, lines)
+	if r.Err != nil {
+		fmt.Printf("Error tailing logs:\n%v\n", r.Err)
+		os.Exit(1)
+	}
+	// catch interrupt so we signal the server we are done before exiting
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, syscall.SIGTERM)
+	go func() {
+		<-c
+		r.Close()
+	}()
+	for e := range r.EntryChan {
+		printLogEntry(e)
+	}
+	if r.Err != nil {
+		fmt.Printf("Error tailing logs:\n%v\n", r.Err)
+		os.Exit(1)
+	}
+}
+
+func printLogEntry(e *rbody.LogEntry) {
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fields := make([]string, len(keys))
+	for i, k := range keys {
+		fields[i] = fmt.Sprintf("%s=%s", k, e.Fields[k])
+	}
+	line := fmt.Sprintf("%s %-7s %-12s %s %s",
+		e.Time.Local().Format(logTimeFormat),
+		strings.ToUpper(e.Level),
+		e.Component,
+		e.Message,
+		strings.Join(fields, " "),
+	)
+	fmt.Println(strings.TrimSpace(line))
+}
@@ -20,7 +20,10 @@ limitations under the License.
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"sort"
 	"strings"
@@ -29,7 +32,7 @@ import (
 	"golang.org/x/crypto/ssh/terminal"
 
 	"github.com/codegangsta/cli"
-	"github.com/intelsdi-x/snap/mgmt/rest/client"
+	"github.com/intelsdi-x/snap/client"
 )
 
 var (
@@ -44,7 +47,7 @@ func main() {
 	app.Name = "snapctl"
 	app.Version = gitversion
 	app.Usage = "A powerful telemetry framework"
-	app.Flags = []cli.Flag{flURL, flSecure, flAPIVer, flPassword, flConfig}
+	app.Flags = []cli.Flag{flURL, flSecure, flCACert, flClientCert, flClientKey, flAPIVer, flPassword, flUsername, flToken, flConfig, flTribeAgreement}
 	app.Commands = append(commands, tribeCommands...)
 	sort.Sort(ByCommand(app.Commands))
 	app.Before = beforeAction
@@ -53,14 +56,27 @@ func main() {
 
 // Run before every command
 func beforeAction(ctx *cli.Context) error {
-	username, password := checkForAuth(ctx)
-	pClient, err = client.New(ctx.String("url"), ctx.String("api-version"), ctx.Bool("insecure"))
+	username, password, token := checkForAuth(ctx)
+	urls := strings.Split(ctx.String("url"), ",")
+	var tlsConfig *tls.Config
+	if tlsConfig, err = clientTLSConfig(ctx); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	pClient, err = client.New(urls[0], ctx.String("api-version"), ctx.Bool("insecure"), client.Endpoints(urls[1:]...), client.TLSConfig(tlsConfig))
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 	pClient.Password = password
 	pClient.Username = username
+	pClient.Token = token
+	if ctx.IsSet("tribe-agreement") {
+		if err = pClient.UseAgreement(ctx.String("tribe-agreement")); err != nil {
+			fmt.Printf("Error using tribe agreement %s: %v\n", ctx.String("tribe-agreement"), err)
+			os.Exit(1)
+		}
+	}
 	if err = checkTribeCommand(ctx); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -68,6 +84,30 @@ func beforeAction(ctx *cli.Context) error {
 	return nil
 }
 
+// Returns the TLS configuration verifying the certificate of snapd against
+// the CA certificates and presenting the client certificate, if given
+func clientTLSConfig(ctx *cli.Context) (*tls.Config, error) {
+	tc := &tls.Config{}
+	if path := ctx.String("ca-cert"); path != "" {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading the CA certificates: %v", err)
+		}
+		tc.RootCAs = x509.NewCertPool()
+		if !tc.RootCAs.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("Error reading the CA certificates: no certificate found in %s", path)
+		}
+	}
+	if ctx.String("client-cert") != "" || ctx.String("client-key") != "" {
+		cert, err := tls.LoadX509KeyPair(ctx.String("client-cert"), ctx.String("client-key"))
+		if err != nil {
+			return nil, fmt.Errorf("Error loading the client certificate: %v", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	return tc, nil
+}
+
 // Checks if a tribe command was issued when tribe mode was not
 // enabled on the specified snapd instance.
 func checkTribeCommand(ctx *cli.Context) error {
@@ -96,11 +136,17 @@ func checkTribeCommand(ctx *cli.Context) error {
 	return nil
 }
 
-// Checks for authentication flags and returns a username/password
-// from the specified settings
-func checkForAuth(ctx *cli.Context) (username, password string) {
+// Checks for authentication flags and returns a username/password or a
+// token from the specified settings
+func checkForAuth(ctx *cli.Context) (username, password, token string) {
+	if token = ctx.String("token"); token != "" {
+		return
+	}
+	username = ctx.String("username")
+	if username == "" {
+		username = "snap" // needs to exist for basicAuth when snapd only checks the password
+	}
 	if ctx.IsSet("password") {
-		username = "snap" // for now since username is unused but needs to exist for basicAuth
 		// Prompt for password
 		fmt.Print("Password:")
 		pass, err := terminal.ReadPassword(0)
@@ -119,6 +165,13 @@ func checkForAuth(ctx *cli.Context) (username, password string) {
 		if err := cfg.loadConfig(ctx.String("config")); err != nil {
 			fmt.Println(err)
 		}
+		if cfg.RestAPI.Token != nil {
+			token = *cfg.RestAPI.Token
+			return
+		}
+		if cfg.RestAPI.Username != nil {
+			username = *cfg.RestAPI.Username
+		}
 		if cfg.RestAPI.Password != nil {
 			password = *cfg.RestAPI.Password
 		} else {
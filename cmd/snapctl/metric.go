@@ -20,6 +20,7 @@ limitations under the License.
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"sort"
@@ -29,11 +30,19 @@ import (
 	"time"
 
 	"github.com/codegangsta/cli"
+
+	"github.com/intelsdi-x/snap/client"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
 )
 
 func listMetrics(ctx *cli.Context) {
 	ns := ctx.String("metric-namespace")
 	ver := ctx.Int("metric-version")
+	query := ctx.String("query")
+	if query != "" && (ns != "" || ver != 0) {
+		fmt.Println("--query cannot be combined with --metric-namespace or --metric-version")
+		os.Exit(1)
+	}
 	if ns != "" {
 		//if the user doesn't provide '/*' we fix it
 		if ns[len(ns)-2:] != "/*" {
@@ -46,7 +55,12 @@ func listMetrics(ctx *cli.Context) {
 	} else {
 		ns = "/*"
 	}
-	mts := pClient.FetchMetrics(ns, ver)
+	var mts *client.GetMetricsResult
+	if query != "" {
+		mts = pClient.QueryMetrics(query)
+	} else {
+		mts = pClient.FetchMetrics(ns, ver)
+	}
 	if mts.Err != nil {
 		fmt.Printf("Error getting metrics: %v\n", mts.Err)
 		os.Exit(1)
@@ -98,10 +112,12 @@ func getMetric(ctx *cli.Context) {
 
 		  Rules for collecting /intel/mock/foo:
 
-		     NAME        TYPE            DEFAULT         REQUIRED     MINIMUM   MAXIMUM
+		     NAME        TYPE            DEFAULT         REQUIRED     MINIMUM   MAXIMUM   ALLOWED
 		     name        string          bob             false
 		     password    string                          true
 		     portRange   int                             false        9000      10000
+		     mode        enum            fast            false                            one of fast, slow
+		     hosts       list(string)                    false
 	*/
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
@@ -109,9 +125,60 @@ func getMetric(ctx *cli.Context) {
 	printFields(w, false, 0, metric.Metric.Namespace, metric.Metric.Version, time.Unix(metric.Metric.LastAdvertisedTimestamp, 0).Format(time.RFC1123))
 	w.Flush()
 	fmt.Printf("\n  Rules for collecting %s:\n\n", metric.Metric.Namespace)
-	printFields(w, true, 6, "NAME", "TYPE", "DEFAULT", "REQUIRED", "MINIMUM", "MAXIMUM")
+	printFields(w, true, 6, "NAME", "TYPE", "DEFAULT", "REQUIRED", "MINIMUM", "MAXIMUM", "ALLOWED")
 	for _, rule := range metric.Metric.Policy {
-		printFields(w, true, 6, rule.Name, rule.Type, rule.Default, rule.Required, rule.Minimum, rule.Maximum)
+		printFields(w, true, 6, rule.Name, ruleType(rule), rule.Default, rule.Required, rule.Minimum, rule.Maximum, ruleAllowed(rule))
 	}
 	w.Flush()
 }
+
+func ruleType(rule rbody.PolicyTable) string {
+	if rule.ElementType != "" {
+		return fmt.Sprintf("%s(%s)", rule.Type, rule.ElementType)
+	}
+	return rule.Type
+}
+
+// ruleAllowed describes the values allowed by an enum or regex rule.
+func ruleAllowed(rule rbody.PolicyTable) string {
+	switch {
+	case len(rule.Choices) > 0:
+		return "one of " + strings.Join(rule.Choices, ", ")
+	case rule.Pattern != "":
+		return "matching " + rule.Pattern
+	}
+	return ""
+}
+
+func exportMetrics(ctx *cli.Context) {
+	format := ctx.String("format")
+	exp := pClient.ExportMetricCatalog(format)
+	if exp.Err != nil {
+		fmt.Printf("Error exporting metrics: %v\n", exp.Err)
+		os.Exit(1)
+	}
+	if format != "" && format != "json" {
+		os.Stdout.Write(exp.Text)
+		return
+	}
+	b, err := json.MarshalIndent(exp.Catalog, "", "  ")
+	if err != nil {
+		fmt.Printf("Error exporting metrics: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(b))
+}
+
+func metricTasks(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		fmt.Print("Incorrect usage\n")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+	tasks := pClient.GetTasksUsingMetric(ctx.Args().First())
+	if tasks.Err != nil {
+		fmt.Printf("Error getting tasks:\n%v\n", tasks.Err)
+		os.Exit(1)
+	}
+	printTasks(tasks.ScheduledTasks)
+}
@@ -29,6 +29,9 @@ import (
 	"time"
 
 	"github.com/codegangsta/cli"
+
+	"github.com/intelsdi-x/snap/client"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
 )
 
 func loadPlugin(ctx *cli.Context) {
@@ -104,9 +107,18 @@ func unloadPlugin(ctx *cli.Context) {
 		os.Exit(1)
 	}
 
-	r := pClient.UnloadPlugin(pType, pName, pVer)
+	var r *client.UnloadPluginResult
+	if ctx.Bool("force") {
+		r = pClient.UnloadPluginForce(pType, pName, pVer)
+	} else {
+		r = pClient.UnloadPlugin(pType, pName, pVer)
+	}
 	if r.Err != nil {
 		fmt.Printf("Error unloading plugin:\n%v\n", r.Err.Error())
+		if e, ok := r.Err.(*rbody.Error); ok && e.Fields["tasks"] != "" {
+			fmt.Printf("Dependent tasks: %s\n", strings.Replace(e.Fields["tasks"], ",", ", ", -1))
+			fmt.Println("Stop them or unload the plugin with --force")
+		}
 		os.Exit(1)
 	}
 
@@ -136,3 +148,17 @@ func listPlugins(ctx *cli.Context) {
 	}
 	w.Flush()
 }
+
+func pluginTasks(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		fmt.Print("Incorrect usage\n")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+	tasks := pClient.GetTasksUsingPlugin(ctx.String("plugin-type"), ctx.Args().First(), ctx.Int("plugin-version"))
+	if tasks.Err != nil {
+		fmt.Printf("Error getting tasks:\n%v\n", tasks.Err)
+		os.Exit(1)
+	}
+	printTasks(tasks.ScheduledTasks)
+}
@@ -0,0 +1,172 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+func scaffoldTask(ctx *cli.Context) {
+	plugin := ctx.String("plugin")
+	if plugin == "" {
+		fmt.Println("Must provide the collector plugin with --plugin")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+	interval := ctx.String("interval")
+	if interval == "" {
+		interval = "1s"
+	}
+	namespaces := ctx.StringSlice("metric")
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+
+	var mets []*rbody.Metric
+	for _, ns := range namespaces {
+		query := "plugin=" + plugin
+		if ns != "" {
+			query += " AND ns=" + ns
+		}
+		if ver := ctx.Int("plugin-version"); ver > 0 {
+			query += fmt.Sprintf(" AND version=%d", ver)
+		}
+		r := pClient.QueryMetrics(query)
+		if r.Err != nil {
+			fmt.Printf("Error getting metrics: %v\n", r.Err)
+			os.Exit(1)
+		}
+		mets = append(mets, r.Catalog...)
+	}
+	if len(mets) == 0 {
+		fmt.Printf("No metrics found for plugin %s\n", plugin)
+		os.Exit(1)
+	}
+	fmt.Print(scaffoldManifest(mets, interval))
+}
+
+// scaffoldManifest returns a YAML task manifest collecting the metrics, with
+// the keys of their policies configured at the namespace they have in common.
+// Keys with a default are set to it, required keys without one are left
+// empty for the task creation to fail until they are filled in and the other
+// keys are commented out.
+func scaffoldManifest(mets []*rbody.Metric, interval string) string {
+	var namespaces []string
+	seen := map[string]bool{}
+	rules := map[string]rbody.PolicyTable{}
+	for _, m := range mets {
+		if !seen[m.Namespace] {
+			seen[m.Namespace] = true
+			namespaces = append(namespaces, m.Namespace)
+		}
+		for _, r := range m.Policy {
+			if _, ok := rules[r.Name]; !ok {
+				rules[r.Name] = r
+			}
+		}
+	}
+	sort.Strings(namespaces)
+	keys := make([]string, 0, len(rules))
+	for k := range rules {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "---\n")
+	fmt.Fprintf(b, "  version: 1\n")
+	fmt.Fprintf(b, "  schedule:\n")
+	fmt.Fprintf(b, "    type: \"simple\"\n")
+	fmt.Fprintf(b, "    interval: %q\n", interval)
+	fmt.Fprintf(b, "  workflow:\n")
+	fmt.Fprintf(b, "    collect:\n")
+	fmt.Fprintf(b, "      metrics:\n")
+	for _, ns := range namespaces {
+		fmt.Fprintf(b, "        %s: {}\n", ns)
+	}
+	if len(keys) > 0 {
+		fmt.Fprintf(b, "      config:\n")
+		fmt.Fprintf(b, "        %s:\n", commonNamespace(namespaces))
+		for _, k := range keys {
+			r := rules[k]
+			fmt.Fprintf(b, "          # %s\n", ruleComment(r))
+			switch {
+			case r.Default != nil:
+				v, _ := json.Marshal(r.Default)
+				fmt.Fprintf(b, "          %s: %s\n", k, v)
+			case r.Required:
+				fmt.Fprintf(b, "          %s:\n", k)
+			default:
+				fmt.Fprintf(b, "          # %s:\n", k)
+			}
+		}
+	}
+	fmt.Fprintf(b, "      # publish:\n")
+	fmt.Fprintf(b, "      #   -\n")
+	fmt.Fprintf(b, "      #     plugin_name: \"file\"\n")
+	fmt.Fprintf(b, "      #     config:\n")
+	fmt.Fprintf(b, "      #       file: \"/tmp/snap_published.log\"\n")
+	return b.String()
+}
+
+// commonNamespace returns the longest namespace prefix of the namespaces
+// without wildcards, "/" if there is none.
+func commonNamespace(namespaces []string) string {
+	var common []string
+	for i, ns := range namespaces {
+		elems := strings.Split(strings.Trim(ns, "/"), "/")
+		if i == 0 {
+			common = elems
+		}
+		n := 0
+		for n < len(common) && n < len(elems) && common[n] == elems[n] && !strings.Contains(elems[n], "*") {
+			n++
+		}
+		common = common[:n]
+	}
+	return "/" + strings.Join(common, "/")
+}
+
+// ruleComment describes the values allowed by a rule.
+func ruleComment(r rbody.PolicyTable) string {
+	parts := []string{ruleType(r)}
+	if r.Required {
+		parts = append(parts, "required")
+	}
+	if r.Minimum != nil {
+		parts = append(parts, fmt.Sprintf("minimum %v", r.Minimum))
+	}
+	if r.Maximum != nil {
+		parts = append(parts, fmt.Sprintf("maximum %v", r.Maximum))
+	}
+	if a := ruleAllowed(r); a != "" {
+		parts = append(parts, a)
+	}
+	return strings.Join(parts, ", ")
+}
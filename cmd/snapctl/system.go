@@ -0,0 +1,117 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+func maintenance(ctx *cli.Context) {
+	if len(ctx.Args()) > 1 {
+		fmt.Print("Incorrect usage\n")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	var m rbody.Maintenance
+	switch ctx.Args().First() {
+	case "":
+		r := pClient.GetMaintenance()
+		if r.Err != nil {
+			fmt.Printf("Error getting maintenance mode:\n%v\n", r.Err)
+			os.Exit(1)
+		}
+		m = r.Maintenance
+	case "on", "off":
+		on := ctx.Args().First() == "on"
+		r := pClient.SetMaintenance(on, ctx.Bool("hold-publish"))
+		if r.Err != nil {
+			fmt.Printf("Error turning maintenance mode %s:\n%v\n", ctx.Args().First(), r.Err)
+			os.Exit(1)
+		}
+		m = r.Maintenance
+	default:
+		fmt.Print("Incorrect usage\n")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Status: %s\n", m.Status)
+	if !m.Enabled {
+		return
+	}
+	mode := "tasks paused"
+	if m.HoldPublish {
+		mode = "publishing held"
+	}
+	fmt.Printf("Mode: %s\n", mode)
+	fmt.Printf("Since: %s\n", m.Since().Format(time.RFC1123))
+	if len(m.Tasks) > 0 {
+		fmt.Printf("Tasks: %s\n", strings.Join(m.Tasks, ", "))
+	}
+}
+
+func aggregator(ctx *cli.Context) {
+	r := pClient.GetAggregator()
+	if r.Err != nil {
+		fmt.Printf("Error getting aggregator:\n%v\n", r.Err)
+		os.Exit(1)
+	}
+	a := r.Aggregator
+	if !a.Enabled {
+		fmt.Println("Aggregator: disabled")
+		return
+	}
+	fmt.Println("Aggregator: enabled")
+	fmt.Printf("Interval: %s\n", a.Interval)
+	fmt.Printf("Dedup: %v\n", a.Dedup)
+	if a.Downsample != "" {
+		fmt.Printf("Downsample: %s\n", a.Downsample)
+	}
+	fmt.Printf("Buffered: %d\n", a.Buffered)
+	fmt.Printf("Received: %d (%d duplicates)\n", a.Received, a.Duplicates)
+	fmt.Printf("Dropped: %d\n", a.Dropped)
+	fmt.Printf("Published: %d\n", a.Published)
+	fmt.Printf("Failures: %d\n", a.Failures)
+	if a.LastFlushTimestamp > 0 {
+		fmt.Printf("Last flush: %s\n", a.LastFlush().Format(time.RFC1123))
+	}
+	if a.LastError != "" {
+		fmt.Printf("Last error: %s\n", a.LastError)
+	}
+	if len(a.Sources) == 0 {
+		return
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	printFields(w, false, 0, "PRINCIPAL", "RECEIVED", "LAST SEEN")
+	for _, src := range a.Sources {
+		printFields(w, false, 0, src.Principal, src.Received, time.Unix(src.LastSeenTimestamp, 0).Format(time.RFC1123))
+	}
+	w.Flush()
+}
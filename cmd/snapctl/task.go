@@ -25,6 +25,7 @@ import (
 	"io/ioutil"
 	"os"
 	"os/signal"
+	"path"
 	"path/filepath"
 	"sort"
 	"strconv"
@@ -34,7 +35,9 @@ import (
 	"time"
 
 	"github.com/codegangsta/cli"
-	"github.com/intelsdi-x/snap/mgmt/rest/client"
+	"github.com/intelsdi-x/snap/client"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
 	"github.com/intelsdi-x/snap/scheduler/wmap"
 	"github.com/robfig/cron"
 
@@ -90,6 +93,13 @@ type task struct {
 	Workflow *wmap.WorkflowMap
 	Name     string
 	Deadline string
+	Priority string
+	Alerts   []request.AlertRule
+	Shard    bool
+	// Timestamps is the timestamp policy of the task
+	Timestamps  *request.TimestampPolicy
+	Description string
+	DependsOn   []request.TaskDependency `json:"depends_on"`
 }
 
 func createTask(ctx *cli.Context) {
@@ -142,7 +152,13 @@ func createTaskUsingTaskManifest(ctx *cli.Context) {
 		fmt.Println("Invalid version provided")
 		os.Exit(1)
 	}
-	r := pClient.CreateTask(t.Schedule, t.Workflow, t.Name, t.Deadline, !ctx.IsSet("no-start"))
+	if ctx.IsSet("priority") {
+		t.Priority = ctx.String("priority")
+	}
+	if ctx.IsSet("description") {
+		t.Description = ctx.String("description")
+	}
+	r := pClient.CreateTask(t.Schedule, t.Workflow, t.Name, t.Deadline, !ctx.IsSet("no-start"), client.TaskPriority(t.Priority), client.TaskAlerts(t.Alerts), client.TaskShard(t.Shard), client.TaskTimestamps(t.Timestamps), client.TaskDescription(t.Description), client.TaskDependsOn(t.DependsOn))
 
 	if r.Err != nil {
 		errors := strings.Split(r.Err.Error(), " -- ")
@@ -238,6 +254,9 @@ func createTaskUsingWFManifest(ctx *cli.Context) {
 				Type:     t,
 				Interval: i,
 			}
+			if !isCron {
+				sch.Jitter = ctx.String("jitter")
+			}
 		}
 	} else {
 		// We have some form of windowed schedule
@@ -276,7 +295,11 @@ func createTaskUsingWFManifest(ctx *cli.Context) {
 		}
 	}
 	// Create task
-	r := pClient.CreateTask(sch, wf, name, dl, !ctx.IsSet("no-start"))
+	if ctx.IsSet("overrun") {
+		sch.Overrun = ctx.String("overrun")
+		sch.OverrunQueueDepth = uint(ctx.Int("overrun-queue-depth"))
+	}
+	r := pClient.CreateTask(sch, wf, name, dl, !ctx.IsSet("no-start"), client.TaskPriority(ctx.String("priority")), client.TaskDescription(ctx.String("description")))
 	if r.Err != nil {
 		errors := strings.Split(r.Err.Error(), " -- ")
 		fmt.Println("Error creating task:")
@@ -326,7 +349,10 @@ func listTask(ctx *cli.Context) {
 		fmt.Printf("Error getting tasks:\n%v\n", tasks.Err)
 		os.Exit(1)
 	}
+	printTasks(tasks.ScheduledTasks)
+}
 
+func printTasks(tasks []rbody.ScheduledTask) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
 	printFields(w, false, 0,
 		"ID",
@@ -336,9 +362,10 @@ func listTask(ctx *cli.Context) {
 		"MISS",
 		"FAIL",
 		"CREATED",
+		"CREATED BY",
 		"LAST FAILURE",
 	)
-	for _, task := range tasks.ScheduledTasks {
+	for _, task := range tasks {
 		printFields(w, false, 0,
 			task.ID,
 			task.Name,
@@ -347,6 +374,7 @@ func listTask(ctx *cli.Context) {
 			trunc(task.MissCount),
 			trunc(task.FailedCount),
 			task.CreationTime().Format(unionParseFormat),
+			task.CreatedBy,
 			task.LastFailureMessage,
 		)
 	}
@@ -361,7 +389,17 @@ func watchTask(ctx *cli.Context) {
 	}
 
 	id := ctx.Args().First()
-	r := pClient.WatchTask(id)
+	replay := ctx.Int("replay")
+	if replay < 0 {
+		fmt.Println("Replay must be a positive number")
+		os.Exit(1)
+	}
+	wf, err := newWatchFilter(ctx)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	r := pClient.WatchTaskEvents(id, ctx.Bool("lifecycle"), uint(replay))
 	if r.Err != nil {
 		fmt.Println(r.Err)
 		os.Exit(1)
@@ -381,7 +419,10 @@ func watchTask(ctx *cli.Context) {
 	}()
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
-	printFields(w, false, 0, "NAMESPACE", "DATA", "TIMESTAMP", "SOURCE")
+	wf.printFields(w, false, "NAMESPACE", "DATA", "TIMESTAMP", "SOURCE")
+	if wf.quiet {
+		w.Flush()
+	}
 	// Loop listening to events
 	for {
 		select {
@@ -389,20 +430,34 @@ func watchTask(ctx *cli.Context) {
 			switch e.EventType {
 			case "metric-event":
 				sort.Sort(e.Event)
+				// the quiet mode prints the metrics crossing the thresholds
+				// one after the other instead of redrawing the last ones
+				if wf.quiet {
+					for _, event := range e.Event {
+						if wf.selects(event.Namespace) && wf.crosses(event.Data) {
+							wf.printMetric(w, event)
+						}
+					}
+					w.Flush()
+					continue
+				}
+				lines = 0
 				for _, event := range e.Event {
+					if !wf.selects(event.Namespace) {
+						continue
+					}
 					fmt.Printf("\033[0J")
-					printFields(w, false, 0,
-						event.Namespace,
-						event.Data,
-						event.Timestamp,
-						event.Source,
-					)
+					wf.printMetric(w, event)
+					lines++
 				}
-				lines = len(e.Event)
 				fmt.Fprintf(w, "\033[%dA\n", lines+1)
 				w.Flush()
 			default:
-				fmt.Printf("%s[%s]\n", strings.Repeat("\n", lines), e.EventType)
+				if e.Replayed {
+					fmt.Printf("%s[%s] (replayed) %s\n", strings.Repeat("\n", lines), e.EventType, e.Message)
+				} else {
+					fmt.Printf("%s[%s]\n", strings.Repeat("\n", lines), e.EventType)
+				}
 			}
 
 		case <-r.DoneChan:
@@ -412,6 +467,91 @@ func watchTask(ctx *cli.Context) {
 
 }
 
+// watchFilter selects the metrics printed by task watch, and highlights the
+// values out of the thresholds
+type watchFilter struct {
+	// filter is a glob matched against the namespaces, as path.Match does
+	filter   string
+	min, max *float64
+	quiet    bool
+}
+
+func newWatchFilter(ctx *cli.Context) (*watchFilter, error) {
+	wf := &watchFilter{filter: ctx.String("filter"), quiet: ctx.Bool("quiet")}
+	if _, err := path.Match(wf.filter, ""); err != nil {
+		return nil, fmt.Errorf("Invalid filter %q: %v", wf.filter, err)
+	}
+	if ctx.IsSet("min") {
+		min := ctx.Float64("min")
+		wf.min = &min
+	}
+	if ctx.IsSet("max") {
+		max := ctx.Float64("max")
+		wf.max = &max
+	}
+	if wf.min != nil && wf.max != nil && *wf.min > *wf.max {
+		return nil, fmt.Errorf("The min threshold must not be greater than the max one")
+	}
+	if wf.quiet && !wf.highlights() {
+		return nil, fmt.Errorf("Quiet mode needs a min or max threshold")
+	}
+	return wf, nil
+}
+
+// highlights returns whether any threshold is set
+func (wf *watchFilter) highlights() bool {
+	return wf.min != nil || wf.max != nil
+}
+
+func (wf *watchFilter) selects(ns string) bool {
+	if wf.filter == "" {
+		return true
+	}
+	ok, _ := path.Match(wf.filter, ns)
+	return ok
+}
+
+// crosses returns whether the value is a number out of the thresholds
+func (wf *watchFilter) crosses(data interface{}) bool {
+	var v float64
+	switch d := data.(type) {
+	case float64:
+		v = d
+	case int:
+		v = float64(d)
+	case int64:
+		v = float64(d)
+	case string:
+		f, err := strconv.ParseFloat(d, 64)
+		if err != nil {
+			return false
+		}
+		v = f
+	default:
+		return false
+	}
+	return (wf.min != nil && v < *wf.min) || (wf.max != nil && v > *wf.max)
+}
+
+func (wf *watchFilter) printMetric(w *tabwriter.Writer, m rbody.StreamedMetric) {
+	wf.printFields(w, wf.crosses(m.Data), m.Namespace, m.Data, m.Timestamp, m.Source)
+}
+
+// printFields prints a line of the watch. With thresholds set, every line is
+// wrapped in color codes of the same length so that the columns stay aligned,
+// the ones crossing the thresholds in red.
+func (wf *watchFilter) printFields(w *tabwriter.Writer, crossed bool, fields ...interface{}) {
+	if wf.highlights() {
+		color := "\033[39m"
+		if crossed {
+			color = "\033[31m"
+		}
+		fields[0] = fmt.Sprint(color, fields[0])
+		fields[len(fields)-1] = fmt.Sprint(fields[len(fields)-1], "\033[0m")
+	}
+	printFields(w, false, 0, fields...)
+}
+
 func startTask(ctx *cli.Context) {
 	if len(ctx.Args()) != 1 {
 		fmt.Print("Incorrect usage\n")
@@ -456,6 +596,70 @@ func stopTask(ctx *cli.Context) {
 	fmt.Printf("ID: %s\n", r.ID)
 }
 
+func pauseTask(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		fmt.Print("Incorrect usage\n")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	var d time.Duration
+	if f := ctx.String("for"); f != "" {
+		var err error
+		if d, err = time.ParseDuration(f); err != nil || d <= 0 {
+			fmt.Printf("Incorrect usage - invalid duration (%s)\n", f)
+			cli.ShowCommandHelp(ctx, ctx.Command.Name)
+			os.Exit(1)
+		}
+	}
+
+	id := ctx.Args().First()
+	r := pClient.PauseTask(id, d)
+	if r.Err != nil {
+		fmt.Printf("Error pausing task:\n%v\n", r.Err)
+		os.Exit(1)
+	}
+	fmt.Println("Task paused:")
+	fmt.Printf("ID: %s\n", r.ID)
+	if r.PausedUntilTimestamp != 0 {
+		fmt.Printf("Until: %s\n", time.Unix(r.PausedUntilTimestamp, 0).Format(unionParseFormat))
+	}
+}
+
+func resumeTask(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		fmt.Print("Incorrect usage\n")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	id := ctx.Args().First()
+	r := pClient.ResumeTask(id)
+	if r.Err != nil {
+		fmt.Printf("Error resuming task:\n%v\n", r.Err)
+		os.Exit(1)
+	}
+	fmt.Println("Task resumed:")
+	fmt.Printf("ID: %s\n", r.ID)
+}
+
+func triggerTask(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		fmt.Print("Incorrect usage\n")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	id := ctx.Args().First()
+	r := pClient.TriggerTask(id)
+	if r.Err != nil {
+		fmt.Printf("Error triggering task:\n%v\n", r.Err)
+		os.Exit(1)
+	}
+	fmt.Println("Task triggered:")
+	fmt.Printf("ID: %s\n", r.ID)
+}
+
 func removeTask(ctx *cli.Context) {
 	if len(ctx.Args()) != 1 {
 		fmt.Print("Incorrect usage\n")
@@ -473,6 +677,118 @@ func removeTask(ctx *cli.Context) {
 	fmt.Printf("ID: %s\n", r.ID)
 }
 
+// deletedTasks lists the deleted tasks which can be restored, or prints the
+// deleted task given as JSON
+func deletedTasks(ctx *cli.Context) {
+	if len(ctx.Args()) > 1 {
+		fmt.Print("Incorrect usage\n")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+	if id := ctx.Args().First(); id != "" {
+		r := pClient.GetDeletedTask(id)
+		if r.Err != nil {
+			fmt.Printf("Error getting deleted task:\n%v\n", r.Err)
+			os.Exit(1)
+		}
+		tb, err := json.MarshalIndent(r.DeletedTaskReturned, "", "  ")
+		if err != nil {
+			fmt.Printf("Error getting deleted task:\n%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(tb))
+		return
+	}
+	r := pClient.GetDeletedTasks()
+	if r.Err != nil {
+		fmt.Printf("Error getting deleted tasks:\n%v\n", r.Err)
+		os.Exit(1)
+	}
+	if len(r.DeletedTasks) == 0 {
+		fmt.Println("No deleted tasks found")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	printFields(w, false, 0,
+		"ID",
+		"NAME",
+		"DELETED",
+		"DELETED BY",
+		"PURGED",
+	)
+	for _, t := range r.DeletedTasks {
+		printFields(w, false, 0,
+			t.ID,
+			t.Name,
+			t.DeleteTime().Format(unionParseFormat),
+			t.UpdatedBy,
+			t.PurgeTime().Format(unionParseFormat),
+		)
+	}
+	w.Flush()
+}
+
+func taskGraph(ctx *cli.Context) {
+	if len(ctx.Args()) != 0 {
+		fmt.Print("Incorrect usage\n")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+	r := pClient.GetTaskGraph()
+	if r.Err != nil {
+		fmt.Printf("Error getting task graph:\n%v\n", r.Err)
+		os.Exit(1)
+	}
+	if ctx.Bool("dot") {
+		fmt.Print(r.DOT())
+		return
+	}
+	if len(r.Edges) == 0 {
+		fmt.Println("No task dependencies found")
+		return
+	}
+	names := map[string]string{}
+	for _, n := range r.Nodes {
+		names[n.ID] = n.Name
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	printFields(w, false, 0,
+		"TASK",
+		"NAME",
+		"RUNS ON",
+		"OF TASK",
+		"NAME",
+	)
+	for _, e := range r.Edges {
+		printFields(w, false, 0,
+			e.To,
+			names[e.To],
+			e.On,
+			e.From,
+			names[e.From],
+		)
+	}
+	w.Flush()
+}
+
+func restoreTask(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		fmt.Print("Incorrect usage\n")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	id := ctx.Args().First()
+	r := pClient.RestoreTask(id)
+	if r.Err != nil {
+		fmt.Printf("Error restoring task:\n%v\n", r.Err)
+		os.Exit(1)
+	}
+	fmt.Println("Task restored:")
+	fmt.Printf("ID: %s\n", r.ID)
+	fmt.Printf("State: %s\n", r.State)
+}
+
 func exportTask(ctx *cli.Context) {
 	if len(ctx.Args()) != 1 {
 		fmt.Print("Incorrect usage\n")
@@ -493,6 +809,184 @@ func exportTask(ctx *cli.Context) {
 	fmt.Println(string(tb))
 }
 
+func taskHistory(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		fmt.Print("Incorrect usage\n")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	id := ctx.Args().First()
+	r := pClient.GetTaskRuns(id)
+	if r.Err != nil {
+		fmt.Printf("Error getting task history:\n%v\n", r.Err)
+		os.Exit(1)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	printFields(w, false, 0,
+		"STARTED",
+		"DURATION",
+		"METRICS",
+		"STEP",
+		"PLUGIN",
+		"STEP DURATION",
+		"ERRORS",
+	)
+	for _, run := range r.Runs {
+		printFields(w, false, 0,
+			time.Unix(run.Timestamp, 0).Format(unionParseFormat),
+			run.Duration,
+			run.MetricCount,
+		)
+		for _, step := range run.Steps {
+			printFields(w, false, 0,
+				"",
+				"",
+				"",
+				step.Type,
+				step.Plugin,
+				step.Duration,
+				strings.Join(step.Errors, "; "),
+			)
+		}
+	}
+	w.Flush()
+}
+
+func updateTask(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		fmt.Print("Incorrect usage\n")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	id := ctx.Args().First()
+	u := &request.TaskUpdateRequest{
+		Interval:      ctx.String("interval"),
+		Config:        map[string]map[string]interface{}{},
+		AddMetrics:    map[string]int{},
+		RemoveMetrics: ctx.StringSlice("remove-metric"),
+	}
+	for _, c := range ctx.StringSlice("config") {
+		ns, key, value, ok := parseConfigItem(c)
+		if !ok {
+			fmt.Printf("Error updating task:\ninvalid config item %q, expected <namespace>:<key>=<value>\n", c)
+			os.Exit(1)
+		}
+		setConfigItem(u.Config, ns, key, value)
+	}
+	for _, c := range ctx.StringSlice("unset-config") {
+		i := strings.LastIndex(c, ":")
+		if i < 1 || i == len(c)-1 {
+			fmt.Printf("Error updating task:\ninvalid config item %q, expected <namespace>:<key>\n", c)
+			os.Exit(1)
+		}
+		setConfigItem(u.Config, c[:i], c[i+1:], nil)
+	}
+	for _, m := range ctx.StringSlice("add-metric") {
+		ns, ver := m, 0
+		if i := strings.LastIndex(m, ":"); i > 0 {
+			v, err := strconv.Atoi(m[i+1:])
+			if err != nil {
+				fmt.Printf("Error updating task:\ninvalid metric version %q\n", m)
+				os.Exit(1)
+			}
+			ns, ver = m[:i], v
+		}
+		u.AddMetrics[ns] = ver
+	}
+	if ctx.IsSet("description") {
+		d := ctx.String("description")
+		u.Description = &d
+	}
+	if u.Interval == "" && len(u.Config) == 0 && len(u.AddMetrics) == 0 && len(u.RemoveMetrics) == 0 && u.Description == nil {
+		fmt.Print("Incorrect usage\n")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	r := pClient.UpdateTask(id, u)
+	if r.Err != nil {
+		fmt.Printf("Error updating task:\n%v\n", r.Err)
+		os.Exit(1)
+	}
+	fmt.Println("Task updated:")
+	fmt.Printf("ID: %s\n", r.ID)
+}
+
+// parseConfigItem splits <namespace>:<key>=<value>, the value being decoded
+// as JSON when it is valid JSON and kept as a string otherwise
+func parseConfigItem(s string) (string, string, interface{}, bool) {
+	eq := strings.Index(s, "=")
+	if eq < 0 {
+		return "", "", nil, false
+	}
+	i := strings.LastIndex(s[:eq], ":")
+	if i < 1 || i == eq-1 {
+		return "", "", nil, false
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(s[eq+1:]), &value); err != nil || value == nil {
+		value = s[eq+1:]
+	}
+	return s[:i], s[i+1 : eq], value, true
+}
+
+func setConfigItem(cfg map[string]map[string]interface{}, ns, key string, value interface{}) {
+	if cfg[ns] == nil {
+		cfg[ns] = map[string]interface{}{}
+	}
+	cfg[ns][key] = value
+}
+
+func taskConfig(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		fmt.Print("Incorrect usage\n")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	id := ctx.Args().First()
+	resolve := ctx.Bool("resolve")
+	r := pClient.GetTaskConfig(id, resolve)
+	if r.Err != nil {
+		fmt.Printf("Error getting task config:\n%v\n", r.Err)
+		os.Exit(1)
+	}
+	nss := make([]string, 0, len(r.Metrics))
+	for ns := range r.Metrics {
+		nss = append(nss, ns)
+	}
+	sort.Strings(nss)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	if resolve {
+		printFields(w, false, 0, "NAMESPACE", "KEY", "VALUE", "SOURCE", "OVERRIDES")
+	} else {
+		printFields(w, false, 0, "NAMESPACE", "KEY", "VALUE")
+	}
+	for _, ns := range nss {
+		keys := make([]string, 0, len(r.Metrics[ns]))
+		for k := range r.Metrics[ns] {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			v := r.Metrics[ns][k]
+			if !resolve {
+				printFields(w, false, 0, ns, k, v.Value)
+				continue
+			}
+			overrides := make([]string, len(v.Overridden))
+			for i, o := range v.Overridden {
+				overrides[i] = fmt.Sprintf("%s=%v", o.Source, o.Value)
+			}
+			printFields(w, false, 0, ns, k, v.Value, v.Source, strings.Join(overrides, "; "))
+		}
+	}
+	w.Flush()
+}
+
 func enableTask(ctx *cli.Context) {
 	if len(ctx.Args()) != 1 {
 		fmt.Print("Incorrect usage\n")
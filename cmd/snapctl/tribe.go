@@ -25,9 +25,12 @@ import (
 	"fmt"
 	"os"
 	"sort"
+	"strconv"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/codegangsta/cli"
+	"github.com/ghodss/yaml"
 	"github.com/intelsdi-x/snap/mgmt/tribe/agreement"
 )
 
@@ -67,7 +70,7 @@ func showMember(ctx *cli.Context) {
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
 	defer w.Flush()
-	fields := []interface{}{"Name", "Plugin Agreement", "Task Agreements"}
+	fields := []interface{}{"Name", "Plugin Agreement", "Task Agreements", "Clock Skew"}
 	if ctx.Bool("verbose") {
 		fields = append(fields, "tags")
 	}
@@ -87,7 +90,7 @@ func showMember(ctx *cli.Context) {
 		os.Exit(1)
 	}
 
-	values := []interface{}{resp.Name, resp.PluginAgreement, tasks.String()}
+	values := []interface{}{resp.Name, resp.PluginAgreement, tasks.String(), resp.ClockSkew}
 	if ctx.Bool("verbose") {
 		values = append(values, string(tags))
 	}
@@ -96,6 +99,79 @@ func showMember(ctx *cli.Context) {
 
 }
 
+func describeMember(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		fmt.Println("Incorrect usage:")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	resp := pClient.DescribeMember(ctx.Args().First())
+	if resp.Err != nil {
+		fmt.Printf("Error describing member:\n%v\n", resp.Err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Name:       %s\n", resp.Name)
+	fmt.Printf("URL:        %s\n", resp.URL)
+	fmt.Printf("Agreements: %s\n", strings.Join(resp.Agreements, ", "))
+	if resp.ClockSkew != "" {
+		fmt.Printf("Clock skew: %s\n", resp.ClockSkew)
+	}
+
+	fmt.Println("\nPlugins:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	printFields(w, true, 2, "TYPE", "NAME", "VERSION", "REQUIRED BY", "LOADED")
+	for _, p := range resp.Plugins {
+		printFields(w, true, 2, p.Type, p.Name, p.Version, strings.Join(p.Agreements, ","), p.Loaded)
+	}
+	w.Flush()
+
+	fmt.Println("\nTasks:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	printFields(w, true, 2, "ID", "NAME", "REQUIRED BY", "STATE")
+	for _, t := range resp.Tasks {
+		state := t.State
+		if state == "" {
+			state = "Missing"
+		}
+		printFields(w, true, 2, t.ID, t.Name, strings.Join(t.Agreements, ","), state)
+	}
+	w.Flush()
+
+	// the plugins and tasks required but missing are shown as removed from
+	// the member, the ones no agreement requires as added to it
+	var diff []string
+	for _, p := range resp.Plugins {
+		if !p.Diverged {
+			continue
+		}
+		if p.Loaded {
+			diff = append(diff, fmt.Sprintf("+ plugin %s:%s:%d is loaded but not required by %s", p.Type, p.Name, p.Version, strings.Join(resp.Agreements, ", ")))
+		} else {
+			diff = append(diff, fmt.Sprintf("- plugin %s:%s:%d is required by %s but not loaded", p.Type, p.Name, p.Version, strings.Join(p.Agreements, ", ")))
+		}
+	}
+	for _, t := range resp.Tasks {
+		if !t.Diverged {
+			continue
+		}
+		if t.State != "" {
+			diff = append(diff, fmt.Sprintf("+ task %s (%s) is scheduled but not required by %s", t.ID, t.Name, strings.Join(resp.Agreements, ", ")))
+		} else {
+			diff = append(diff, fmt.Sprintf("- task %s is required by %s but not scheduled", t.ID, strings.Join(t.Agreements, ", ")))
+		}
+	}
+	fmt.Println("\nDivergence:")
+	if len(diff) == 0 {
+		fmt.Println("  None")
+		return
+	}
+	for _, d := range diff {
+		fmt.Printf("  %s\n", d)
+	}
+}
+
 func listAgreements(ctx *cli.Context) {
 	resp := pClient.ListAgreements()
 	if resp.Err != nil {
@@ -186,6 +262,267 @@ func agreementMembers(ctx *cli.Context) {
 	}
 }
 
+func exportAgreement(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		fmt.Println("Incorrect usage:")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	resp := pClient.ExportAgreement(ctx.Args().First())
+	if resp.Err != nil {
+		fmt.Printf("Error exporting agreement: %v\n", resp.Err)
+		os.Exit(1)
+	}
+	var b []byte
+	var err error
+	switch ctx.String("format") {
+	case "", "json":
+		b, err = json.MarshalIndent(resp.Spec, "", "  ")
+		b = append(b, '\n')
+	case "yaml":
+		b, err = yaml.Marshal(resp.Spec)
+	default:
+		err = fmt.Errorf("unknown format %q", ctx.String("format"))
+	}
+	if err != nil {
+		fmt.Printf("Error exporting agreement: %v\n", err)
+		os.Exit(1)
+	}
+	os.Stdout.Write(b)
+}
+
+func importAgreement(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		fmt.Println("Incorrect usage:")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	spec, err := agreement.ReadSpec(ctx.Args().First())
+	if err != nil {
+		fmt.Printf("Error reading agreement: %v\n", err)
+		os.Exit(1)
+	}
+	if errs := spec.Validate(); len(errs) > 0 {
+		fmt.Println("Error: invalid agreement:")
+		for _, e := range errs {
+			fmt.Printf("    %v\n", e)
+		}
+		os.Exit(1)
+	}
+	resp := pClient.ImportAgreement(spec)
+	for _, m := range resp.Joined {
+		fmt.Printf("Joined member %s\n", m)
+	}
+	for _, p := range resp.Loaded {
+		fmt.Printf("Loaded plugin %s\n", p)
+	}
+	for _, p := range resp.Added {
+		fmt.Printf("Added plugin %s\n", p)
+	}
+	var names []string
+	for n := range resp.Created {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		fmt.Printf("Created task %s (%s)\n", n, resp.Created[n])
+	}
+	for _, p := range resp.Missing {
+		fmt.Printf("Warning: no member has the %s plugin %s and no path or url is given to load it\n", p.Type, p.Name)
+	}
+	if resp.Err != nil {
+		fmt.Printf("Error importing agreement: %v\n", resp.Err)
+		os.Exit(1)
+	}
+}
+
+func startRollout(ctx *cli.Context) {
+	if len(ctx.Args()) != 2 || ctx.String("task-manifest") == "" {
+		fmt.Println("Incorrect usage:")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	ts := agreement.TaskSpec{Manifest: ctx.String("task-manifest")}
+	tr, err := ts.Request()
+	if err != nil {
+		fmt.Printf("Error reading task manifest: %v\n", err)
+		os.Exit(1)
+	}
+	resp := pClient.StartRollout(ctx.Args().First(), ctx.Args().Get(1), tr, ctx.Int("canary-count"), ctx.Int("canary-percent"), ctx.Duration("bake-period"))
+	if resp.Err != nil {
+		fmt.Printf("Error starting rollout:\n%v\n", resp.Err)
+		os.Exit(1)
+	}
+	fmt.Println("Rollout started")
+	printRollout(resp.Rollout)
+}
+
+func rolloutStatus(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		fmt.Println("Incorrect usage:")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	resp := pClient.GetRollout(ctx.Args().First())
+	if resp.Err != nil {
+		fmt.Printf("Error getting rollout:\n%v\n", resp.Err)
+		os.Exit(1)
+	}
+	printRollout(resp.Rollout)
+}
+
+func listAgreementTasks(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		fmt.Println("Incorrect usage:")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	resp := pClient.ListAgreementTasks(ctx.Args().First())
+	if resp.Err != nil {
+		fmt.Printf("Error getting agreement tasks:\n%v\n", resp.Err)
+		os.Exit(1)
+	}
+	if len(resp.Tasks) == 0 {
+		fmt.Println("None")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	defer w.Flush()
+	printFields(w, false, 0, "ID", "Name", "State", "Start on create")
+	for _, t := range resp.Tasks {
+		printFields(w, false, 0, t.ID, t.Name, t.State, t.StartOnCreate)
+	}
+}
+
+func addAgreementTask(ctx *cli.Context) {
+	if len(ctx.Args()) != 2 {
+		fmt.Println("Incorrect usage:")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	resp := pClient.AddAgreementTask(ctx.Args().First(), ctx.Args().Get(1), !ctx.Bool("no-start"))
+	if resp.Err != nil {
+		fmt.Printf("Error adding task to agreement: %v\n", resp.Err)
+		os.Exit(1)
+	}
+	printAgreements(map[string]*agreement.Agreement{resp.Agreement.Name: resp.Agreement})
+}
+
+func removeAgreementTask(ctx *cli.Context) {
+	if len(ctx.Args()) != 2 {
+		fmt.Println("Incorrect usage:")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	resp := pClient.RemoveAgreementTask(ctx.Args().First(), ctx.Args().Get(1))
+	if resp.Err != nil {
+		fmt.Printf("Error removing task from agreement: %v\n", resp.Err)
+		os.Exit(1)
+	}
+	printAgreements(map[string]*agreement.Agreement{resp.Agreement.Name: resp.Agreement})
+}
+
+func startAgreementTask(ctx *cli.Context) {
+	if len(ctx.Args()) != 2 {
+		fmt.Println("Incorrect usage:")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	resp := pClient.StartAgreementTask(ctx.Args().First(), ctx.Args().Get(1))
+	if resp.Err != nil {
+		fmt.Printf("Error starting agreement task: %v\n", resp.Err)
+		os.Exit(1)
+	}
+	fmt.Printf("Task %s started on the members of %s\n", ctx.Args().Get(1), resp.Agreement.Name)
+}
+
+func stopAgreementTask(ctx *cli.Context) {
+	if len(ctx.Args()) != 2 {
+		fmt.Println("Incorrect usage:")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	resp := pClient.StopAgreementTask(ctx.Args().First(), ctx.Args().Get(1))
+	if resp.Err != nil {
+		fmt.Printf("Error stopping agreement task: %v\n", resp.Err)
+		os.Exit(1)
+	}
+	fmt.Printf("Task %s stopped on the members of %s\n", ctx.Args().Get(1), resp.Agreement.Name)
+}
+
+func addAgreementPlugin(ctx *cli.Context) {
+	if len(ctx.Args()) != 3 {
+		fmt.Println("Incorrect usage:")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	typ, name, ver := agreementPluginArg(ctx, ctx.Args().Get(1))
+	resp := pClient.AddAgreementPlugin(ctx.Args().First(), name, typ, ver, ctx.Args().Get(2))
+	if resp.Err != nil {
+		fmt.Printf("Error adding plugin to agreement: %v\n", resp.Err)
+		os.Exit(1)
+	}
+	printAgreements(map[string]*agreement.Agreement{resp.Agreement.Name: resp.Agreement})
+}
+
+func removeAgreementPlugin(ctx *cli.Context) {
+	if len(ctx.Args()) != 2 {
+		fmt.Println("Incorrect usage:")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+
+	typ, name, ver := agreementPluginArg(ctx, ctx.Args().Get(1))
+	resp := pClient.RemoveAgreementPlugin(ctx.Args().First(), name, typ, ver)
+	if resp.Err != nil {
+		fmt.Printf("Error removing plugin from agreement: %v\n", resp.Err)
+		os.Exit(1)
+	}
+	printAgreements(map[string]*agreement.Agreement{resp.Agreement.Name: resp.Agreement})
+}
+
+// agreementPluginArg splits a <plugin_type>:<plugin_name>:<plugin_version>
+// argument.
+func agreementPluginArg(ctx *cli.Context, arg string) (string, string, int) {
+	parts := strings.Split(arg, ":")
+	if len(parts) == 3 {
+		if ver, err := strconv.Atoi(parts[2]); err == nil && ver > 0 {
+			return parts[0], parts[1], ver
+		}
+	}
+	fmt.Printf("Invalid plugin %q, expected <plugin_type>:<plugin_name>:<plugin_version>\n", arg)
+	cli.ShowCommandHelp(ctx, ctx.Command.Name)
+	os.Exit(1)
+	return "", "", 0
+}
+
+func printRollout(r *agreement.Rollout) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	printFields(w, false, 0, "ID", "Agreement", "Old task", "New task", "State", "Bake period", "Updated")
+	printFields(w, false, 0, r.ID, r.Agreement, r.OldTaskID, r.NewTaskID, r.State, r.BakePeriod, r.Updated.Format(timeFormat))
+	w.Flush()
+	fmt.Printf("Owner: %s\n", r.Owner)
+	fmt.Printf("Canaries: %s\n", strings.Join(r.Canaries, ", "))
+	var names []string
+	for n := range r.Failures {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		fmt.Printf("Failed on %s: %s\n", n, r.Failures[n])
+	}
+}
+
 func printAgreements(agreements map[string]*agreement.Agreement) {
 	if len(agreements) > 0 {
 		w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
@@ -0,0 +1,87 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+)
+
+func listWorkerPools(ctx *cli.Context) {
+	r := pClient.GetWorkerPools()
+	if r.Err != nil {
+		fmt.Printf("Error getting worker pools:\n%v\n", r.Err)
+		os.Exit(1)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	printFields(w, false, 0,
+		"POOL",
+		"WORKERS",
+		"BUSY",
+		"QUEUED",
+		"JOBS",
+		"SHED",
+		"UTILIZATION",
+		"MEAN WAIT",
+		"MAX WAIT",
+	)
+	for _, p := range r.Pools {
+		queued := strconv.FormatUint(uint64(p.QueueDepth), 10)
+		if p.QueueLimit > 0 {
+			queued = fmt.Sprintf("%d/%d", p.QueueDepth, p.QueueLimit)
+		}
+		printFields(w, false, 0,
+			p.Name,
+			p.Workers,
+			p.Busy,
+			queued,
+			p.Jobs,
+			p.Shed,
+			fmt.Sprintf("%.1f%%", p.Utilization*100),
+			p.MeanWait,
+			p.MaxWait,
+		)
+	}
+	w.Flush()
+}
+
+func resizeWorkerPool(ctx *cli.Context) {
+	if len(ctx.Args()) != 2 {
+		fmt.Print("Incorrect usage\n")
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+		os.Exit(1)
+	}
+	pool := ctx.Args().Get(0)
+	workers, err := strconv.ParseUint(ctx.Args().Get(1), 10, 0)
+	if err != nil {
+		fmt.Printf("Invalid number of workers: %v\n", ctx.Args().Get(1))
+		os.Exit(1)
+	}
+	r := pClient.ResizeWorkerPool(pool, uint(workers))
+	if r.Err != nil {
+		fmt.Printf("Error resizing worker pool:\n%v\n", r.Err)
+		os.Exit(1)
+	}
+	fmt.Printf("Worker pool (%s) resized to %d workers\n", r.Name, r.Workers)
+}
@@ -0,0 +1,218 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+)
+
+var (
+	// ErrAliasNotFound - error message when removing a namespace which is not an alias
+	ErrAliasNotFound = errors.New("Alias not found")
+)
+
+// aliasNamespace validates an alias or the namespace it stands for: a
+// concrete namespace, without wildcards or tuples
+func aliasNamespace(s string) ([]string, error) {
+	if !strings.HasPrefix(s, "/") || len(s) < 2 {
+		return nil, fmt.Errorf("Invalid alias namespace %q: must start with /", s)
+	}
+	ns := strings.Split(strings.Trim(s, "/"), "/")
+	for _, e := range ns {
+		if e == "" || strings.Contains(e, "*") {
+			return nil, fmt.Errorf("Invalid alias namespace %q: must be a concrete namespace", s)
+		}
+	}
+	if err := validateMetricNamespace(ns); err != nil {
+		return nil, err
+	}
+	return ns, nil
+}
+
+// AddAlias makes the alias namespace stand for the target namespace. The
+// catalog resolves the alias to the target both when it is queried and when
+// the metric is collected, the metrics collected being returned under the
+// alias. An alias cannot stand for another alias nor hide a cataloged
+// namespace.
+func (mc *metricCatalog) AddAlias(alias, target string) error {
+	ans, err := aliasNamespace(alias)
+	if err != nil {
+		return err
+	}
+	tns, err := aliasNamespace(target)
+	if err != nil {
+		return err
+	}
+	akey, tkey := getMetricKey(ans), getMetricKey(tns)
+	if akey == tkey {
+		return fmt.Errorf("Alias %s stands for itself", alias)
+	}
+	if mts, err := mc.tree.Get(ans); err == nil && len(mts) > 0 {
+		return fmt.Errorf("Alias %s is a cataloged namespace", alias)
+	}
+	mc.aliasMutex.Lock()
+	defer mc.aliasMutex.Unlock()
+	if _, ok := mc.aliases[tkey]; ok {
+		return fmt.Errorf("Alias %s stands for the alias %s", alias, target)
+	}
+	for _, t := range mc.aliases {
+		if getMetricKey(t) == akey {
+			return fmt.Errorf("Alias %s is the target of another alias", alias)
+		}
+	}
+	if mc.aliases == nil {
+		mc.aliases = map[string][]string{}
+	}
+	mc.aliases[akey] = tns
+	return nil
+}
+
+// RemoveAlias removes the alias
+func (mc *metricCatalog) RemoveAlias(alias string) error {
+	ans, err := aliasNamespace(alias)
+	if err != nil {
+		return err
+	}
+	mc.aliasMutex.Lock()
+	defer mc.aliasMutex.Unlock()
+	akey := getMetricKey(ans)
+	if _, ok := mc.aliases[akey]; !ok {
+		return ErrAliasNotFound
+	}
+	delete(mc.aliases, akey)
+	return nil
+}
+
+// Aliases returns the namespaces the aliases stand for, by alias
+func (mc *metricCatalog) Aliases() map[string]string {
+	mc.aliasMutex.RLock()
+	defer mc.aliasMutex.RUnlock()
+	aliases := make(map[string]string, len(mc.aliases))
+	for akey, tns := range mc.aliases {
+		aliases[core.JoinNamespace(getMetricNamespace(akey))] = core.JoinNamespace(tns)
+	}
+	return aliases
+}
+
+// Unalias returns the namespace the alias stands for, the namespace itself
+// when it is not an alias
+func (mc *metricCatalog) Unalias(ns []string) []string {
+	mc.aliasMutex.RLock()
+	defer mc.aliasMutex.RUnlock()
+	if tns, ok := mc.aliases[getMetricKey(ns)]; ok {
+		return tns
+	}
+	return ns
+}
+
+// isAlias returns whether the namespace is an alias
+func (mc *metricCatalog) isAlias(ns []string) bool {
+	mc.aliasMutex.RLock()
+	defer mc.aliasMutex.RUnlock()
+	_, ok := mc.aliases[getMetricKey(ns)]
+	return ok
+}
+
+// setAliases adds the aliases of the configuration, logging the invalid ones
+func setAliases(mc catalogsMetrics, aliases map[string]string) {
+	keys := make([]string, 0, len(aliases))
+	for alias := range aliases {
+		keys = append(keys, alias)
+	}
+	sort.Strings(keys)
+	for _, alias := range keys {
+		if err := mc.AddAlias(alias, aliases[alias]); err != nil {
+			controlLogger.WithFields(log.Fields{
+				"_block": "set-aliases",
+				"alias":  alias,
+				"target": aliases[alias],
+				"error":  err,
+			}).Error("invalid alias ignored")
+		}
+	}
+}
+
+// AddAlias makes the alias namespace stand for the target namespace in the
+// metric catalog
+func (p *pluginControl) AddAlias(alias, target string) error {
+	if err := p.metricCatalog.AddAlias(alias, target); err != nil {
+		return err
+	}
+	controlLogger.WithFields(log.Fields{
+		"_block": "add-alias",
+		"alias":  alias,
+		"target": target,
+	}).Info("alias added")
+	return nil
+}
+
+// RemoveAlias removes the alias from the metric catalog
+func (p *pluginControl) RemoveAlias(alias string) error {
+	if err := p.metricCatalog.RemoveAlias(alias); err != nil {
+		return err
+	}
+	controlLogger.WithFields(log.Fields{
+		"_block": "remove-alias",
+		"alias":  alias,
+	}).Info("alias removed")
+	return nil
+}
+
+// Aliases returns the namespaces the aliases of the metric catalog stand
+// for, by alias
+func (p *pluginControl) Aliases() map[string]string {
+	return p.metricCatalog.Aliases()
+}
+
+// unaliasMetrics returns the metrics collected for the aliased namespaces of
+// pmt under their aliases. A metric requested both under its namespace and
+// under aliases is returned once under each of them.
+func unaliasMetrics(pmt pluginMetricTypes, collected []core.Metric) []core.Metric {
+	if len(pmt.aliases) == 0 {
+		return collected
+	}
+	mts := make([]core.Metric, 0, len(collected))
+	for _, m := range collected {
+		key := getMetricKey(m.Namespace())
+		aliases, ok := pmt.aliases[key]
+		if !ok {
+			mts = append(mts, m)
+			continue
+		}
+		if pmt.unaliased[key] {
+			mts = append(mts, m)
+		}
+		for _, ans := range aliases {
+			if pm, ok := m.(plugin.PluginMetricType); ok {
+				pm.Namespace_ = ans
+				mts = append(mts, pm)
+			}
+		}
+	}
+	return mts
+}
@@ -0,0 +1,87 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAliases(t *testing.T) {
+	Convey("metricCatalog aliases", t, func() {
+		mc := newMetricCatalog()
+		lp := new(loadedPlugin)
+		lp.Meta.Name = "psutil"
+		lp.Meta.Version = 1
+		m := newMetricType([]string{"intel", "psutil", "cpu", "percent"}, time.Now(), lp)
+		mc.Add(m)
+		So(mc.AddAlias("/company/cpu/util", "/intel/psutil/cpu/percent"), ShouldBeNil)
+
+		Convey("resolve to the namespace they stand for", func() {
+			am, err := mc.Get([]string{"company", "cpu", "util"}, -1)
+			So(err, ShouldBeNil)
+			So(am, ShouldEqual, m)
+			am, err = mc.Resolve([]string{"company", "cpu", "util"}, 1)
+			So(err, ShouldBeNil)
+			So(am, ShouldEqual, m)
+			nss, err := mc.MatchQuery([]string{"company", "cpu", "util"})
+			So(err, ShouldBeNil)
+			So(nss, ShouldResemble, [][]string{{"company", "cpu", "util"}})
+			So(mc.Aliases(), ShouldResemble, map[string]string{"/company/cpu/util": "/intel/psutil/cpu/percent"})
+		})
+		Convey("are concrete namespaces", func() {
+			So(mc.AddAlias("/company/*/util", "/intel/psutil/cpu/percent"), ShouldNotBeNil)
+			So(mc.AddAlias("company/cpu", "/intel/psutil/cpu/percent"), ShouldNotBeNil)
+		})
+		Convey("do not hide cataloged namespaces nor chain", func() {
+			So(mc.AddAlias("/intel/psutil/cpu/percent", "/intel/psutil/cpu/idle"), ShouldNotBeNil)
+			So(mc.AddAlias("/company/cpu/load", "/company/cpu/util"), ShouldNotBeNil)
+			So(mc.AddAlias("/intel/psutil/cpu/idle", "/company/cpu/util"), ShouldNotBeNil)
+		})
+		Convey("can be removed", func() {
+			So(mc.RemoveAlias("/company/cpu/util"), ShouldBeNil)
+			So(mc.RemoveAlias("/company/cpu/util"), ShouldEqual, ErrAliasNotFound)
+			_, err := mc.Get([]string{"company", "cpu", "util"}, -1)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("are collected from the namespace they stand for", func() {
+			mts := []core.Metric{
+				plugin.PluginMetricType{Namespace_: []string{"company", "cpu", "util"}},
+				plugin.PluginMetricType{Namespace_: []string{"intel", "psutil", "cpu", "percent"}},
+			}
+			pmts, err := groupMetricTypesByPlugin(mc, mts)
+			So(err, ShouldBeNil)
+			pmt := pmts[lp.Key()]
+			So(pmt.metricTypes, ShouldHaveLength, 2)
+			So(pmt.metricTypes[0].Namespace(), ShouldResemble, []string{"intel", "psutil", "cpu", "percent"})
+
+			collected := []core.Metric{plugin.PluginMetricType{Namespace_: []string{"intel", "psutil", "cpu", "percent"}, Data_: 42}}
+			unaliased := unaliasMetrics(pmt, collected)
+			So(unaliased, ShouldHaveLength, 2)
+			So(unaliased[0].Namespace(), ShouldResemble, []string{"intel", "psutil", "cpu", "percent"})
+			So(unaliased[1].Namespace(), ShouldResemble, []string{"company", "cpu", "util"})
+			So(unaliased[1].Data(), ShouldEqual, 42)
+		})
+	})
+}
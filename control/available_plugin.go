@@ -28,6 +28,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
@@ -35,6 +36,7 @@ import (
 	"github.com/intelsdi-x/gomit"
 	"github.com/intelsdi-x/snap/control/plugin"
 	"github.com/intelsdi-x/snap/control/plugin/client"
+	"github.com/intelsdi-x/snap/control/plugin/encoding"
 	"github.com/intelsdi-x/snap/control/strategy"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/control_event"
@@ -49,11 +51,20 @@ const (
 	DefaultHealthCheckTimeout = time.Second * 1
 	// DefaultHealthCheckFailureLimit - how any consecutive health check timeouts must occur to trigger a failure
 	DefaultHealthCheckFailureLimit = 3
+	// DefaultRPCFailureLimit - how many failed calls within DefaultRPCFailureWindow get a plugin reloaded
+	DefaultRPCFailureLimit = 10
+	// DefaultRPCFailureWindow - the window failed calls are counted in
+	DefaultRPCFailureWindow = time.Minute
 )
 
 var (
 	ErrPoolNotFound = errors.New("plugin pool not found")
 	ErrBadKey       = errors.New("bad key")
+	// ErrPinnedPluginNotLoaded - a process or publish step giving a plugin
+	// version is pinned to it and never moved to another version
+	ErrPinnedPluginNotLoaded = errors.New("Pinned plugin not loaded")
+	// ErrPlainJSONRPCSecure - PlainJSONRPC plugins must set Unsecure in their meta
+	ErrPlainJSONRPCSecure = errors.New("plugins using PlainJSONRPC must be unsecure")
 )
 
 // availablePlugin represents a plugin which is
@@ -75,11 +86,26 @@ type availablePlugin struct {
 	exec               string
 	execPath           string
 	fromPackage        bool
+	startTime          time.Time
+	// ready is set once the plugin reported it is ready, accessed atomically
+	ready int32
+	// rpcFailures holds the times of the calls which failed within the
+	// window, until the plugin is declared dead
+	rpcFailures []time.Time
+	dead        bool
+	lastRequest *pluginRequest
+	rpcMutex    sync.Mutex
+	// hitMutex guards the hit count and the time of the last hit, updated
+	// by the calls made in parallel
+	hitMutex sync.Mutex
+	// crashPath is the directory crash reports are written in, none when
+	// empty
+	crashPath string
 }
 
 // newAvailablePlugin returns an availablePlugin with information from a
-// plugin.Response
-func newAvailablePlugin(resp *plugin.Response, emitter gomit.Emitter, ep executablePlugin) (*availablePlugin, error) {
+// plugin.Response, its client following the transport settings
+func newAvailablePlugin(resp *plugin.Response, emitter gomit.Emitter, ep executablePlugin, transports *transportSettings) (*availablePlugin, error) {
 	if resp.Type != plugin.CollectorPluginType && resp.Type != plugin.ProcessorPluginType && resp.Type != plugin.PublisherPluginType {
 		return nil, strategy.ErrBadType
 	}
@@ -92,22 +118,35 @@ func newAvailablePlugin(resp *plugin.Response, emitter gomit.Emitter, ep executa
 		healthChan:  make(chan error, 1),
 		lastHitTime: time.Now(),
 		ePlugin:     ep,
+		startTime:   time.Now(),
 	}
 	ap.key = fmt.Sprintf("%s:%s:%d", ap.pluginType.String(), ap.name, ap.version)
 
+	// The language-neutral protocol does not negotiate session encryption
+	if resp.Meta.RPCType == plugin.PlainJSONRPC && !resp.Meta.Unsecure {
+		return nil, ErrPlainJSONRPCSecure
+	}
+
 	listenURL := fmt.Sprintf("http://%v/rpc", resp.ListenAddress)
+	cs := transports.getConn()
 	// Create RPC Client
 	switch resp.Type {
 	case plugin.CollectorPluginType:
 		switch resp.Meta.RPCType {
 		case plugin.JSONRPC:
-			c, e := client.NewCollectorHttpJSONRPCClient(listenURL, DefaultClientTimeout, resp.PublicKey, !resp.Meta.Unsecure)
+			c, e := client.NewCollectorHttpJSONRPCClient(listenURL, DefaultClientTimeout, cs, resp.PublicKey, !resp.Meta.Unsecure)
+			if e != nil {
+				return nil, errors.New("error while creating client connection: " + e.Error())
+			}
+			ap.client = c
+		case plugin.PlainJSONRPC:
+			c, e := client.NewCollectorPlainJSONRPCClient(listenURL, DefaultClientTimeout, cs)
 			if e != nil {
 				return nil, errors.New("error while creating client connection: " + e.Error())
 			}
 			ap.client = c
 		case plugin.NativeRPC:
-			c, e := client.NewCollectorNativeClient(resp.ListenAddress, DefaultClientTimeout, resp.PublicKey, !resp.Meta.Unsecure)
+			c, e := client.NewCollectorNativeClient(resp.ListenAddress, DefaultClientTimeout, cs, resp.PublicKey, !resp.Meta.Unsecure)
 			if e != nil {
 				return nil, errors.New("error while creating client connection: " + e.Error())
 			}
@@ -116,13 +155,19 @@ func newAvailablePlugin(resp *plugin.Response, emitter gomit.Emitter, ep executa
 	case plugin.PublisherPluginType:
 		switch resp.Meta.RPCType {
 		case plugin.JSONRPC:
-			c, e := client.NewPublisherHttpJSONRPCClient(listenURL, DefaultClientTimeout, resp.PublicKey, !resp.Meta.Unsecure)
+			c, e := client.NewPublisherHttpJSONRPCClient(listenURL, DefaultClientTimeout, cs, resp.PublicKey, !resp.Meta.Unsecure)
+			if e != nil {
+				return nil, errors.New("error while creating client connection: " + e.Error())
+			}
+			ap.client = c
+		case plugin.PlainJSONRPC:
+			c, e := client.NewPublisherPlainJSONRPCClient(listenURL, DefaultClientTimeout, cs)
 			if e != nil {
 				return nil, errors.New("error while creating client connection: " + e.Error())
 			}
 			ap.client = c
 		case plugin.NativeRPC:
-			c, e := client.NewPublisherNativeClient(resp.ListenAddress, DefaultClientTimeout, resp.PublicKey, !resp.Meta.Unsecure)
+			c, e := client.NewPublisherNativeClient(resp.ListenAddress, DefaultClientTimeout, cs, resp.PublicKey, !resp.Meta.Unsecure)
 			if e != nil {
 				return nil, errors.New("error while creating client connection: " + e.Error())
 			}
@@ -131,13 +176,19 @@ func newAvailablePlugin(resp *plugin.Response, emitter gomit.Emitter, ep executa
 	case plugin.ProcessorPluginType:
 		switch resp.Meta.RPCType {
 		case plugin.JSONRPC:
-			c, e := client.NewProcessorHttpJSONRPCClient(listenURL, DefaultClientTimeout, resp.PublicKey, !resp.Meta.Unsecure)
+			c, e := client.NewProcessorHttpJSONRPCClient(listenURL, DefaultClientTimeout, cs, resp.PublicKey, !resp.Meta.Unsecure)
+			if e != nil {
+				return nil, errors.New("error while creating client connection: " + e.Error())
+			}
+			ap.client = c
+		case plugin.PlainJSONRPC:
+			c, e := client.NewProcessorPlainJSONRPCClient(listenURL, DefaultClientTimeout, cs)
 			if e != nil {
 				return nil, errors.New("error while creating client connection: " + e.Error())
 			}
 			ap.client = c
 		case plugin.NativeRPC:
-			c, e := client.NewProcessorNativeClient(resp.ListenAddress, DefaultClientTimeout, resp.PublicKey, !resp.Meta.Unsecure)
+			c, e := client.NewProcessorNativeClient(resp.ListenAddress, DefaultClientTimeout, cs, resp.PublicKey, !resp.Meta.Unsecure)
 			if e != nil {
 				return nil, errors.New("error while creating client connection: " + e.Error())
 			}
@@ -146,6 +197,22 @@ func newAvailablePlugin(resp *plugin.Response, emitter gomit.Emitter, ep executa
 	default:
 		return nil, errors.New("Cannot create a client for a plugin of the type: " + resp.Type.String())
 	}
+	if chunkSize := transports.getChunkSize(); chunkSize > 0 && (resp.Meta.Features & plugin.SnapdFeatures).Has(plugin.FeatureChunking) {
+		if c, ok := ap.client.(client.PluginChunkingClient); ok {
+			c.EnableChunking(chunkSize)
+		}
+	}
+	if c, ok := ap.client.(client.PluginTransportClient); ok {
+		pt := transports.get(resp.Type, resp.Meta.Name)
+		c.SetMaxMessageSize(pt.MaxMessageSize)
+		// the language-neutral protocol exchanges JSON, not encoded messages
+		if pt.Compression != "" && pt.Compression != encoding.NoCompression && resp.Meta.RPCType != plugin.PlainJSONRPC &&
+			(resp.Meta.Features & plugin.SnapdFeatures).Has(plugin.FeatureCompression) {
+			if err := c.SetCompression(pt.Compression); err != nil {
+				return nil, errors.New("error while setting the compression: " + err.Error())
+			}
+		}
+	}
 
 	return ap, nil
 }
@@ -195,13 +262,25 @@ func (a *availablePlugin) Version() int {
 }
 
 func (a *availablePlugin) HitCount() int {
+	a.hitMutex.Lock()
+	defer a.hitMutex.Unlock()
 	return a.hitCount
 }
 
 func (a *availablePlugin) LastHit() time.Time {
+	a.hitMutex.Lock()
+	defer a.hitMutex.Unlock()
 	return a.lastHitTime
 }
 
+// hit updates the plugin stats after a successful call
+func (a *availablePlugin) hit() {
+	a.hitMutex.Lock()
+	defer a.hitMutex.Unlock()
+	a.hitCount++
+	a.lastHitTime = time.Now()
+}
+
 // Stop halts a running availablePlugin
 func (a *availablePlugin) Stop(r string) error {
 	log.WithFields(log.Fields{
@@ -231,6 +310,27 @@ func (a *availablePlugin) Kill(r string) error {
 	return a.ePlugin.Kill()
 }
 
+// Ready returns nil once the plugin is ready to collect: its warm-up is over
+// and, if it supports readiness, it reported it is ready. Otherwise it returns
+// the reason the plugin is not ready yet.
+func (a *availablePlugin) Ready() error {
+	if atomic.LoadInt32(&a.ready) == 1 {
+		return nil
+	}
+	if left := a.meta.WarmUp - time.Since(a.startTime); left > 0 {
+		return fmt.Errorf("warming up for another %v", left)
+	}
+	if (a.meta.Features & plugin.SnapdFeatures).Has(plugin.FeatureReadiness) {
+		if c, ok := a.client.(client.PluginReadinessClient); ok {
+			if err := c.Ready(); err != nil {
+				return err
+			}
+		}
+	}
+	atomic.StoreInt32(&a.ready, 1)
+	return nil
+}
+
 // CheckHealth checks the health of a plugin and updates
 // a.failedHealthChecks
 func (a *availablePlugin) CheckHealth() {
@@ -273,12 +373,13 @@ func (a *availablePlugin) healthCheckFailed() {
 			"aplugin": a,
 		}).Warning("heartbeat failed")
 		pde := &control_event.DeadAvailablePluginEvent{
-			Name:    a.name,
-			Version: a.version,
-			Type:    int(a.pluginType),
-			Key:     a.key,
-			Id:      a.ID(),
-			String:  a.String(),
+			Name:        a.name,
+			Version:     a.version,
+			Type:        int(a.pluginType),
+			Key:         a.key,
+			Id:          a.ID(),
+			String:      a.String(),
+			CrashReport: a.writeCrashReport("heartbeat failed"),
 		}
 		defer a.emitter.Emit(pde)
 	}
@@ -290,6 +391,29 @@ func (a *availablePlugin) healthCheckFailed() {
 	defer a.emitter.Emit(hcfe)
 }
 
+// rpcFailed records a failed call and returns the count of the failures
+// within the window, and whether it just reached the limit, which happens
+// once in the life of the plugin
+func (a *availablePlugin) rpcFailed(limit int, window time.Duration, now time.Time) (int, bool) {
+	a.rpcMutex.Lock()
+	defer a.rpcMutex.Unlock()
+	if a.dead {
+		return len(a.rpcFailures), false
+	}
+	kept := a.rpcFailures[:0]
+	for _, t := range a.rpcFailures {
+		if now.Sub(t) < window {
+			kept = append(kept, t)
+		}
+	}
+	a.rpcFailures = append(kept, now)
+	if limit > 0 && len(a.rpcFailures) >= limit {
+		a.dead = true
+		return len(a.rpcFailures), true
+	}
+	return len(a.rpcFailures), false
+}
+
 type availablePlugins struct {
 	// Used to coordinate operations on the table.
 	*sync.RWMutex
@@ -297,12 +421,32 @@ type availablePlugins struct {
 	// The Pools' primary keys are equal to
 	// {plugin_type}:{plugin_name}:{plugin_version}
 	table map[string]strategy.Pool
+	// a plugin whose calls failed rpcFailureLimit times within
+	// rpcFailureWindow is declared dead, to be reloaded
+	rpcFailureLimit  int
+	rpcFailureWindow time.Duration
+	// crashPath is the directory the crash reports of the plugins are
+	// written in
+	crashPath string
+	// the metrics a task collects are split across the instances of the
+	// pool by splitter when there are at least splitMinMetrics for each,
+	// never when splitMinMetrics is 0
+	splitMinMetrics int
+	splitter        strategy.Splitter
+	// transports are the transport settings of the plugins started
+	transports *transportSettings
 }
 
 func newAvailablePlugins() *availablePlugins {
+	splitter, _ := strategy.GetSplitter(strategy.DefaultSplitter)
 	return &availablePlugins{
-		RWMutex: &sync.RWMutex{},
-		table:   make(map[string]strategy.Pool),
+		RWMutex:    &sync.RWMutex{},
+		table:      make(map[string]strategy.Pool),
+		transports: newTransportSettings(),
+
+		rpcFailureLimit:  DefaultRPCFailureLimit,
+		rpcFailureWindow: DefaultRPCFailureWindow,
+		splitter:         splitter,
 	}
 }
 
@@ -314,6 +458,7 @@ func (ap *availablePlugins) insert(pl *availablePlugin) error {
 	ap.Lock()
 	defer ap.Unlock()
 
+	pl.crashPath = ap.crashPath
 	key := fmt.Sprintf("%s:%s:%d", pl.TypeName(), pl.name, pl.version)
 	_, exists := ap.table[key]
 	if !exists {
@@ -383,21 +528,23 @@ func (ap *availablePlugins) collectMetrics(pluginKey string, metricTypes []core.
 
 	pool.RLock()
 	defer pool.RUnlock()
-	p, serr := pool.SelectAP(taskID)
+	var metrics []core.Metric
+	var err error
+	instances, serr := ap.splitInstances(pool, taskID, len(metricsToCollect))
 	if serr != nil {
 		return nil, serr
 	}
-
-	// cast client to PluginCollectorClient
-	cli, ok := p.(*availablePlugin).client.(client.PluginCollectorClient)
-	if !ok {
-		return nil, serror.New(errors.New("unable to cast client to PluginCollectorClient"))
+	if len(instances) > 1 {
+		metrics, err = ap.collectSplit(instances, metricsToCollect, taskID)
+	} else {
+		p, serr := pool.SelectAP(taskID)
+		if serr != nil {
+			return nil, serr
+		}
+		metrics, err = ap.collectFrom(p.(*availablePlugin), metricsToCollect, taskID)
 	}
-
-	// collect metrics
-	metrics, err := cli.CollectMetrics(metricsToCollect)
 	if err != nil {
-		return nil, serror.New(err)
+		return nil, err
 	}
 
 	pool.UpdateCache(metrics, taskID)
@@ -413,11 +560,98 @@ func (ap *availablePlugins) collectMetrics(pluginKey string, metricTypes []core.
 		idx++
 	}
 
+	return results, nil
+}
+
+// collectFrom collects the metrics from one instance of a collector
+func (ap *availablePlugins) collectFrom(p *availablePlugin, metricTypes []core.Metric, taskID string) ([]core.Metric, error) {
+	// cast client to PluginCollectorClient
+	cli, ok := p.client.(client.PluginCollectorClient)
+	if !ok {
+		return nil, serror.New(errors.New("unable to cast client to PluginCollectorClient"))
+	}
+
+	// collect metrics
+	req := collectRequest(metricTypes, taskID)
+	p.requestStarted(req)
+	metrics, err := cli.CollectMetrics(metricTypes)
+	p.requestDone(req)
+	if err != nil {
+		ap.rpcFailed(p, err)
+		return nil, serror.New(err)
+	}
+
 	// update plugin stats
-	p.(*availablePlugin).hitCount++
-	p.(*availablePlugin).lastHitTime = time.Now()
+	p.hit()
 
-	return results, nil
+	return metrics, nil
+}
+
+// splitInstances returns the instances of the pool the metrics are split
+// across, selected by the routing strategy, a part of at least
+// splitMinMetrics each, none when the collector routes its tasks to given
+// instances
+func (ap *availablePlugins) splitInstances(pool strategy.Pool, taskID string, count int) ([]*availablePlugin, serror.SnapError) {
+	if ap.splitMinMetrics <= 0 || ap.splitter == nil {
+		return nil, nil
+	}
+	parts := count / ap.splitMinMetrics
+	if parts < 2 || pool.Count() < 2 {
+		return nil, nil
+	}
+	for _, p := range pool.Plugins() {
+		if a, ok := p.(*availablePlugin); !ok || a.RoutingStrategy() != plugin.DefaultRouting {
+			return nil, nil
+		}
+	}
+	sps, err := pool.SelectAPs(taskID, parts)
+	if err != nil {
+		return nil, err
+	}
+	instances := make([]*availablePlugin, len(sps))
+	for i, sp := range sps {
+		instances[i] = sp.(*availablePlugin)
+	}
+	return instances, nil
+}
+
+// collectSplit collects the metrics split across the instances in
+// parallel and merges what they return
+func (ap *availablePlugins) collectSplit(instances []*availablePlugin, metricTypes []core.Metric, taskID string) ([]core.Metric, error) {
+	start := time.Now()
+	parts := ap.splitter.Split(metricTypes, len(instances))
+	results := make([][]core.Metric, len(parts))
+	errs := make([]error, len(parts))
+	var wg sync.WaitGroup
+	for i, part := range parts {
+		if len(part) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, part []core.Metric) {
+			defer wg.Done()
+			results[i], errs[i] = ap.collectFrom(instances[i], part, taskID)
+		}(i, part)
+	}
+	wg.Wait()
+
+	var metrics []core.Metric
+	for i := range parts {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		metrics = append(metrics, results[i]...)
+	}
+	log.WithFields(log.Fields{
+		"_module":   "control-aplugin",
+		"block":     "collect-split",
+		"task-id":   taskID,
+		"strategy":  ap.splitter.String(),
+		"instances": len(instances),
+		"metrics":   len(metricTypes),
+		"duration":  time.Since(start),
+	}).Debug("metrics collected in parallel")
+	return metrics, nil
 }
 
 func (ap *availablePlugins) publishMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, taskID string) []error {
@@ -429,7 +663,7 @@ func (ap *availablePlugins) publishMetrics(contentType string, content []byte, p
 		return errs
 	}
 	if pool == nil {
-		return []error{serror.New(ErrPoolNotFound, map[string]interface{}{"pool-key": key})}
+		return []error{poolNotFound(key, pluginVersion)}
 	}
 
 	pool.RLock()
@@ -445,12 +679,19 @@ func (ap *availablePlugins) publishMetrics(contentType string, content []byte, p
 		return []error{errors.New("unable to cast client to PluginPublisherClient")}
 	}
 
+	req := contentRequest("Publish", contentType, content, config, taskID)
+	p.(*availablePlugin).requestStarted(req)
 	errp := cli.Publish(contentType, content, config)
+	p.(*availablePlugin).requestDone(req)
+	if errp == plugin.ErrSlowDown {
+		// a publisher asking to slow down is alive and well
+		return []error{errp}
+	}
 	if errp != nil {
+		ap.rpcFailed(p.(*availablePlugin), errp)
 		return []error{errp}
 	}
-	p.(*availablePlugin).hitCount++
-	p.(*availablePlugin).lastHitTime = time.Now()
+	p.(*availablePlugin).hit()
 	return nil
 }
 
@@ -463,7 +704,7 @@ func (ap *availablePlugins) processMetrics(contentType string, content []byte, p
 		return "", nil, errs
 	}
 	if pool == nil {
-		return "", nil, []error{serror.New(ErrPoolNotFound, map[string]interface{}{"pool-key": key})}
+		return "", nil, []error{poolNotFound(key, pluginVersion)}
 	}
 
 	pool.RLock()
@@ -479,15 +720,92 @@ func (ap *availablePlugins) processMetrics(contentType string, content []byte, p
 		return "", nil, []error{errors.New("unable to cast client to PluginProcessorClient")}
 	}
 
+	req := contentRequest("Process", contentType, content, config, taskID)
+	p.(*availablePlugin).requestStarted(req)
 	ct, c, errp := cli.Process(contentType, content, config)
+	p.(*availablePlugin).requestDone(req)
 	if errp != nil {
+		ap.rpcFailed(p.(*availablePlugin), errp)
 		return "", nil, []error{errp}
 	}
-	p.(*availablePlugin).hitCount++
-	p.(*availablePlugin).lastHitTime = time.Now()
+	p.(*availablePlugin).hit()
 	return ct, c, nil
 }
 
+// setRPCFailureLimit sets how many failed calls within the window get a
+// plugin reloaded, never when limit is 0
+func (ap *availablePlugins) setRPCFailureLimit(limit int, window time.Duration) {
+	ap.rpcFailureLimit = limit
+	ap.rpcFailureWindow = window
+}
+
+// setParallelCollect sets the least number of metrics for each instance
+// of a pool the metrics a task collects are split across, never when 0,
+// and how they are split
+func (ap *availablePlugins) setParallelCollect(minMetrics int, splitter strategy.Splitter) {
+	ap.splitMinMetrics = minMetrics
+	ap.splitter = splitter
+}
+
+// setTransports sets the transport settings of the plugins started from now
+// on
+func (ap *availablePlugins) setTransports(t *transportSettings) {
+	ap.transports = t
+}
+
+// setCrashPath sets the directory the crash reports of the plugins are
+// written in, none when empty
+func (ap *availablePlugins) setCrashPath(path string) {
+	ap.crashPath = path
+}
+
+// rpcFailed counts a failed call to the plugin and, once they reach the
+// limit, declares it dead for the runner to kill and restart it as its
+// restart policy allows
+func (ap *availablePlugins) rpcFailed(a *availablePlugin, err error) {
+	failures, exceeded := a.rpcFailed(ap.rpcFailureLimit, ap.rpcFailureWindow, time.Now())
+	if !exceeded {
+		return
+	}
+	log.WithFields(log.Fields{
+		"_module":  "control-aplugin",
+		"block":    "rpc-failed",
+		"aplugin":  a,
+		"failures": failures,
+		"window":   ap.rpcFailureWindow,
+		"_error":   err,
+	}).Warning("plugin calls failing, reloading plugin")
+	a.emitter.Emit(&control_event.RPCFailuresExceededEvent{
+		Name:      a.name,
+		Version:   a.version,
+		Type:      int(a.pluginType),
+		Key:       a.key,
+		Id:        a.ID(),
+		Failures:  failures,
+		Window:    ap.rpcFailureWindow,
+		LastError: err.Error(),
+	})
+	a.emitter.Emit(&control_event.DeadAvailablePluginEvent{
+		Name:        a.name,
+		Version:     a.version,
+		Type:        int(a.pluginType),
+		Key:         a.key,
+		Id:          a.ID(),
+		String:      a.String(),
+		CrashReport: a.writeCrashReport(fmt.Sprintf("%d calls failed within %v: %v", failures, ap.rpcFailureWindow, err)),
+	})
+}
+
+// poolNotFound returns the error of a process or publish step whose pool is
+// missing, the pinned version of the plugin is not loaded when one was given
+func poolNotFound(key string, version int) serror.SnapError {
+	err := ErrPoolNotFound
+	if version > 0 {
+		err = ErrPinnedPluginNotLoaded
+	}
+	return serror.New(err, map[string]interface{}{"pool-key": key})
+}
+
 func (ap *availablePlugins) findLatestPool(pType, name string) (strategy.Pool, serror.SnapError) {
 	// see if there exists a pool at all which matches name version.
 	var latest strategy.Pool
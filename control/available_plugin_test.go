@@ -20,14 +20,34 @@ limitations under the License.
 package control
 
 import (
+	"encoding/json"
 	"errors"
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/client"
+	"github.com/intelsdi-x/snap/control/strategy"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/control_event"
+	"github.com/intelsdi-x/snap/core/ctypes"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
+type mockReadinessClient struct {
+	client.PluginClient
+	err error
+}
+
+func (m *mockReadinessClient) Ready() error {
+	return m.err
+}
+
 func TestAvailablePlugin(t *testing.T) {
 	Convey("newAvailablePlugin()", t, func() {
 		Convey("returns an availablePlugin", func() {
@@ -41,12 +61,39 @@ func TestAvailablePlugin(t *testing.T) {
 				Type:          plugin.CollectorPluginType,
 				ListenAddress: "127.0.0.1:4000",
 			}
-			ap, err := newAvailablePlugin(resp, nil, nil)
+			ap, err := newAvailablePlugin(resp, nil, nil, newTransportSettings())
 			So(ap, ShouldHaveSameTypeAs, new(availablePlugin))
 			So(err, ShouldBeNil)
 		})
 	})
 
+	Convey("Ready()", t, func() {
+		rc := &mockReadinessClient{}
+		ap := &availablePlugin{client: rc, startTime: time.Now()}
+		Convey("is ready right away without warm-up or readiness", func() {
+			So(ap.Ready(), ShouldBeNil)
+		})
+		Convey("is not ready during the warm-up", func() {
+			ap.meta.WarmUp = time.Minute
+			err := ap.Ready()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, "warming up")
+			ap.startTime = time.Now().Add(-time.Minute)
+			So(ap.Ready(), ShouldBeNil)
+		})
+		Convey("asks plugins supporting readiness", func() {
+			ap.meta.Features = plugin.FeatureReadiness
+			rc.err = errors.New("scanning devices")
+			So(ap.Ready(), ShouldEqual, rc.err)
+			rc.err = nil
+			So(ap.Ready(), ShouldBeNil)
+			Convey("and remembers once they are ready", func() {
+				rc.err = errors.New("scanning devices")
+				So(ap.Ready(), ShouldBeNil)
+			})
+		})
+	})
+
 	Convey("Stop()", t, func() {
 		Convey("returns nil if plugin successfully stopped", func() {
 			r := newRunner()
@@ -64,6 +111,130 @@ func TestAvailablePlugin(t *testing.T) {
 	})
 }
 
+func TestRPCFailures(t *testing.T) {
+	Convey("rpcFailed()", t, func() {
+		ap := &availablePlugin{}
+		now := time.Now()
+		Convey("counts the failures within the window", func() {
+			n, exceeded := ap.rpcFailed(3, time.Minute, now.Add(-2*time.Minute))
+			So(n, ShouldEqual, 1)
+			So(exceeded, ShouldBeFalse)
+			ap.rpcFailed(3, time.Minute, now.Add(-30*time.Second))
+			n, exceeded = ap.rpcFailed(3, time.Minute, now)
+			So(n, ShouldEqual, 2)
+			So(exceeded, ShouldBeFalse)
+		})
+		Convey("reports reaching the limit once", func() {
+			ap.rpcFailed(2, time.Minute, now)
+			n, exceeded := ap.rpcFailed(2, time.Minute, now)
+			So(n, ShouldEqual, 2)
+			So(exceeded, ShouldBeTrue)
+			_, exceeded = ap.rpcFailed(2, time.Minute, now)
+			So(exceeded, ShouldBeFalse)
+		})
+		Convey("never reports a limit of 0", func() {
+			for i := 0; i < DefaultRPCFailureLimit; i++ {
+				_, exceeded := ap.rpcFailed(0, time.Minute, now)
+				So(exceeded, ShouldBeFalse)
+			}
+		})
+	})
+	Convey("Given plugins failing their calls", t, func() {
+		emitter := &recordingEmitter{}
+		aps := newAvailablePlugins()
+		aps.setRPCFailureLimit(2, time.Minute)
+		ap := &availablePlugin{
+			pluginType: plugin.CollectorPluginType,
+			name:       "test",
+			version:    1,
+			key:        "collector:test:1",
+			emitter:    emitter,
+		}
+		Convey("a plugin failing too often is declared dead", func() {
+			aps.rpcFailed(ap, errors.New("connection refused"))
+			So(emitter.events, ShouldBeEmpty)
+			aps.rpcFailed(ap, errors.New("connection refused"))
+			So(emitter.events, ShouldHaveLength, 2)
+			So(emitter.events[0], ShouldResemble, &control_event.RPCFailuresExceededEvent{
+				Name:      "test",
+				Version:   1,
+				Type:      int(plugin.CollectorPluginType),
+				Key:       "collector:test:1",
+				Failures:  2,
+				Window:    time.Minute,
+				LastError: "connection refused",
+			})
+			dead, ok := emitter.events[1].(*control_event.DeadAvailablePluginEvent)
+			So(ok, ShouldBeTrue)
+			So(dead.Key, ShouldEqual, "collector:test:1")
+			Convey("only once", func() {
+				aps.rpcFailed(ap, errors.New("connection refused"))
+				So(emitter.events, ShouldHaveLength, 2)
+			})
+		})
+	})
+}
+
+type mockForensicPlugin struct {
+	executablePlugin
+}
+
+func (m *mockForensicPlugin) Pid() int { return 42 }
+
+func (m *mockForensicPlugin) ExitStatus() *plugin.ExitStatus {
+	return &plugin.ExitStatus{Code: -1, Signal: "segmentation fault"}
+}
+
+func (m *mockForensicPlugin) StderrTail() []string {
+	return []string{"SIGSEGV: segmentation violation"}
+}
+
+func TestCrashReport(t *testing.T) {
+	Convey("Given a plugin which crashed", t, func() {
+		dir, err := ioutil.TempDir("", "snap-crashes")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		ap := &availablePlugin{
+			pluginType: plugin.CollectorPluginType,
+			name:       "test",
+			version:    1,
+			key:        "collector:test:1",
+			ePlugin:    &mockForensicPlugin{},
+		}
+		Convey("no report is written without crash path", func() {
+			So(ap.writeCrashReport("heartbeat failed"), ShouldBeEmpty)
+		})
+		Convey("a report is written in the directory of the plugin", func() {
+			ap.crashPath = dir
+			cfg := cdata.NewNode()
+			cfg.AddItem("user", ctypes.ConfigValueStr{Value: "jane"})
+			cfg.AddItem("password", ctypes.ConfigValueStr{Value: "s3cret"})
+			cfg.AddItem("api_key", ctypes.ConfigValueStr{Value: "abc123"})
+			req := collectRequest([]core.Metric{
+				MockMetricType{namespace: []string{"intel", "mock", "foo"}, cfg: cfg},
+			}, "task-1")
+			ap.requestStarted(req)
+
+			path := ap.writeCrashReport("heartbeat failed")
+			So(filepath.Dir(path), ShouldEqual, filepath.Join(dir, "collector-test-1"))
+			b, err := ioutil.ReadFile(path)
+			So(err, ShouldBeNil)
+			var report map[string]interface{}
+			So(json.Unmarshal(b, &report), ShouldBeNil)
+			So(report["reason"], ShouldEqual, "heartbeat failed")
+			So(report["pid"], ShouldEqual, 42)
+			So(report["exit"], ShouldResemble, map[string]interface{}{"code": float64(-1), "signal": "segmentation fault"})
+			So(report["stderr"], ShouldResemble, []interface{}{"SIGSEGV: segmentation violation"})
+			lr := report["last_request"].(map[string]interface{})
+			So(lr["method"], ShouldEqual, "CollectMetrics")
+			So(lr["task_id"], ShouldEqual, "task-1")
+			So(lr["namespaces"], ShouldResemble, []interface{}{"/intel/mock/foo"})
+			So(lr["config"], ShouldResemble, map[string]interface{}{"user": "jane", "password": "********", "api_key": "********"})
+			So(lr["in_flight"], ShouldBeTrue)
+		})
+	})
+}
+
 func TestAvailablePlugins(t *testing.T) {
 	Convey("newAvailablePlugins()", t, func() {
 		Convey("returns a pointer to an availablePlugins struct", func() {
@@ -100,6 +271,16 @@ func TestAvailablePlugins(t *testing.T) {
 			So(err, ShouldResemble, errors.New("bad plugin type"))
 		})
 	})
+	Convey("a step pinned to a version which is not loaded fails", t, func() {
+		aps := newAvailablePlugins()
+		So(aps.insert(&availablePlugin{pluginType: plugin.PublisherPluginType, name: "test", version: 1}), ShouldBeNil)
+		errs := aps.publishMetrics(plugin.SnapGOBContentType, nil, "test", 2, nil, "task")
+		So(errs, ShouldHaveLength, 1)
+		So(errs[0].Error(), ShouldEqual, ErrPinnedPluginNotLoaded.Error())
+		_, _, errs = aps.processMetrics(plugin.SnapGOBContentType, nil, "test", -1, nil, "task")
+		So(errs, ShouldHaveLength, 1)
+		So(errs[0].Error(), ShouldEqual, ErrPoolNotFound.Error())
+	})
 	Convey("it returns an error if client cannot be created", t, func() {
 		resp := &plugin.Response{
 			Meta: plugin.PluginMeta{
@@ -109,8 +290,106 @@ func TestAvailablePlugins(t *testing.T) {
 			Type:          plugin.CollectorPluginType,
 			ListenAddress: "localhost:",
 		}
-		ap, err := newAvailablePlugin(resp, nil, nil)
+		ap, err := newAvailablePlugin(resp, nil, nil, newTransportSettings())
 		So(ap, ShouldBeNil)
 		So(err, ShouldNotBeNil)
 	})
 }
+
+// mockSplitClient returns the metrics it is asked to collect
+type mockSplitClient struct {
+	client.PluginClient
+	collected []core.Metric
+	err       error
+}
+
+func (m *mockSplitClient) CollectMetrics(mts []core.Metric) ([]core.Metric, error) {
+	m.collected = mts
+	return mts, m.err
+}
+
+func (m *mockSplitClient) GetMetricTypes(plugin.PluginConfigType) ([]core.Metric, error) {
+	return nil, nil
+}
+
+func TestParallelCollect(t *testing.T) {
+	Convey("Given a pool of three collectors", t, func() {
+		aps := newAvailablePlugins()
+		clients := []*mockSplitClient{{}, {}, {}}
+		instances := make([]*availablePlugin, len(clients))
+		for i, c := range clients {
+			instances[i] = &availablePlugin{
+				pluginType:  plugin.CollectorPluginType,
+				name:        "test",
+				version:     1,
+				client:      c,
+				lastHitTime: time.Now().Add(time.Duration(i) * time.Second),
+			}
+			So(aps.insert(instances[i]), ShouldBeNil)
+		}
+		var mts []core.Metric
+		for _, ns := range []string{"a", "b", "c", "d", "e", "f"} {
+			mts = append(mts, plugin.PluginMetricType{Namespace_: []string{"intel", "mock", ns}})
+		}
+		Convey("the metrics are collected by one instance by default", func() {
+			collected, err := aps.collectMetrics("collector:test:1", mts, "task")
+			So(err, ShouldBeNil)
+			So(collected, ShouldHaveLength, 6)
+			So(clients[0].collected, ShouldHaveLength, 6)
+			So(clients[1].collected, ShouldBeEmpty)
+		})
+		Convey("the instances split across are selected by the routing strategy", func() {
+			splitter, _ := strategy.GetSplitter("round-robin")
+			aps.setParallelCollect(3, splitter)
+			_, err := aps.collectMetrics("collector:test:1", mts, "task")
+			So(err, ShouldBeNil)
+			So(clients[0].collected, ShouldHaveLength, 3)
+			So(clients[1].collected, ShouldHaveLength, 3)
+			So(clients[2].collected, ShouldBeEmpty)
+		})
+		Convey("the metrics are split across the instances", func() {
+			splitter, _ := strategy.GetSplitter("round-robin")
+			aps.setParallelCollect(2, splitter)
+			collected, err := aps.collectMetrics("collector:test:1", mts, "task")
+			So(err, ShouldBeNil)
+			So(collected, ShouldHaveLength, 6)
+			for _, c := range clients {
+				So(c.collected, ShouldHaveLength, 2)
+			}
+			Convey("only as many as there are parts of the least number", func() {
+				aps.setParallelCollect(3, splitter)
+				for _, c := range clients {
+					c.collected = nil
+				}
+				_, err := aps.collectMetrics("collector:test:1", []core.Metric{
+					plugin.PluginMetricType{Namespace_: []string{"intel", "mock", "g"}},
+					plugin.PluginMetricType{Namespace_: []string{"intel", "mock", "h"}},
+					plugin.PluginMetricType{Namespace_: []string{"intel", "mock", "i"}},
+					plugin.PluginMetricType{Namespace_: []string{"intel", "mock", "j"}},
+					plugin.PluginMetricType{Namespace_: []string{"intel", "mock", "k"}},
+					plugin.PluginMetricType{Namespace_: []string{"intel", "mock", "l"}},
+				}, "task")
+				So(err, ShouldBeNil)
+				busy := 0
+				for _, c := range clients {
+					if len(c.collected) > 0 {
+						busy++
+					}
+				}
+				So(busy, ShouldEqual, 2)
+			})
+			Convey("and an instance failing fails the collection", func() {
+				clients[1].err = errors.New("connection refused")
+				_, err := aps.collectMetrics("collector:test:1", []core.Metric{
+					plugin.PluginMetricType{Namespace_: []string{"intel", "mock", "g"}},
+					plugin.PluginMetricType{Namespace_: []string{"intel", "mock", "h"}},
+					plugin.PluginMetricType{Namespace_: []string{"intel", "mock", "i"}},
+					plugin.PluginMetricType{Namespace_: []string{"intel", "mock", "j"}},
+					plugin.PluginMetricType{Namespace_: []string{"intel", "mock", "k"}},
+					plugin.PluginMetricType{Namespace_: []string{"intel", "mock", "l"}},
+				}, "task")
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
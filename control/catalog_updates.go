@@ -0,0 +1,102 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control/plugin/client"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/control_event"
+)
+
+// A collector supporting catalog updates pushes the metric types it starts
+// and stops providing to snapd while it is running, e.g. when it detects a new
+// device. snapd keeps a call waiting for the next update to each running
+// instance of such a collector, and applies the updates it gets to the catalog
+// right away, so wildcard queries of tasks match the new metrics on their next
+// collection.
+
+// catalogUpdateWait is how long a single call waits for a catalog update
+var catalogUpdateWait = 10 * time.Second
+
+// watchCatalogUpdates applies the catalog updates pushed by the available
+// plugin until it stops.
+func (r *runner) watchCatalogUpdates(ap *availablePlugin, lp *loadedPlugin) {
+	cc, ok := ap.client.(client.PluginCatalogClient)
+	if !ok {
+		return
+	}
+	f := log.Fields{
+		"_block":           "watch-catalog-updates",
+		"available-plugin": ap.String(),
+	}
+	for {
+		added, removed, err := cc.WaitCatalogUpdate(catalogUpdateWait)
+		if err != nil {
+			f["error"] = err.Error()
+			runnerLog.WithFields(f).Debug("stopped watching catalog updates")
+			return
+		}
+		if len(added) == 0 && len(removed) == 0 {
+			continue
+		}
+		r.applyCatalogUpdate(lp, added, removed)
+	}
+}
+
+// applyCatalogUpdate applies a catalog update of the plugin to the catalog and
+// emits an event for the applied changes.
+func (r *runner) applyCatalogUpdate(lp *loadedPlugin, added, removed []core.Metric) {
+	for i, mt := range added {
+		added[i] = defaultMetricVersion(mt, lp.Version())
+	}
+	for i, mt := range removed {
+		removed[i] = defaultMetricVersion(mt, lp.Version())
+	}
+	addedNss, removedNss, err := r.metricCatalog.UpdatePluginMetrics(lp, added, removed)
+	f := log.Fields{
+		"_block":         "apply-catalog-update",
+		"plugin-name":    lp.Name(),
+		"plugin-version": lp.Version(),
+		"added":          len(addedNss),
+		"removed":        len(removedNss),
+	}
+	if err != nil {
+		f["error"] = err.Error()
+		runnerLog.WithFields(f).Warn("unable to apply catalog update")
+		if le, ok := err.(*metricLimitError); ok {
+			r.emitter.Emit(le.event())
+		}
+	} else {
+		runnerLog.WithFields(f).Info("catalog updated by plugin")
+	}
+	if len(addedNss) == 0 && len(removedNss) == 0 {
+		return
+	}
+	r.emitter.Emit(&control_event.CatalogUpdatedEvent{
+		PluginName:    lp.Name(),
+		PluginVersion: lp.Version(),
+		Added:         addedNss,
+		Removed:       removedNss,
+	})
+}
@@ -0,0 +1,110 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/gomit"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/client"
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/control_event"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type catalogUpdate struct {
+	added, removed []core.Metric
+}
+
+// mockCatalogClient replies with the queued catalog updates and fails once
+// they are consumed, like a stopped plugin
+type mockCatalogClient struct {
+	client.PluginClient
+	updates []catalogUpdate
+}
+
+func (m *mockCatalogClient) WaitCatalogUpdate(time.Duration) ([]core.Metric, []core.Metric, error) {
+	if len(m.updates) == 0 {
+		return nil, nil, errors.New("connection is shut down")
+	}
+	u := m.updates[0]
+	m.updates = m.updates[1:]
+	return u.added, u.removed, nil
+}
+
+type recordingEmitter struct {
+	events []gomit.EventBody
+}
+
+func (r *recordingEmitter) Emit(e gomit.EventBody) (int, error) {
+	r.events = append(r.events, e)
+	return 0, nil
+}
+
+func TestWatchCatalogUpdates(t *testing.T) {
+	Convey("Given a collector pushing catalog updates", t, func() {
+		mc := newMetricCatalog()
+		emitter := &recordingEmitter{}
+		r := newRunner()
+		r.SetMetricCatalog(mc)
+		r.SetEmitter(emitter)
+		lp := new(loadedPlugin)
+		lp.Meta.Name = "disk"
+		lp.Meta.Version = 2
+		lp.Meta.Features = plugin.FeatureCatalogUpdates
+		lp.ConfigPolicy = cpolicy.New()
+		disk := func(id string) core.Metric {
+			return plugin.PluginMetricType{Namespace_: []string{"intel", "disk", id, "reads"}}
+		}
+		ap := &availablePlugin{
+			name:    "disk",
+			version: 2,
+			client: &mockCatalogClient{updates: []catalogUpdate{
+				{added: []core.Metric{disk("sda"), disk("sdb")}},
+				{},
+				{removed: []core.Metric{disk("sda")}},
+			}},
+		}
+		r.watchCatalogUpdates(ap, lp)
+		Convey("the updates are applied to the catalog until the plugin stops", func() {
+			_, err := mc.Get([]string{"intel", "disk", "sda", "reads"}, 2)
+			So(err, ShouldNotBeNil)
+			m, err := mc.Get([]string{"intel", "disk", "sdb", "reads"}, 2)
+			So(err, ShouldBeNil)
+			So(m.Version(), ShouldEqual, 2)
+		})
+		Convey("an event is emitted for each applied update", func() {
+			So(emitter.events, ShouldHaveLength, 2)
+			So(emitter.events[0], ShouldResemble, &control_event.CatalogUpdatedEvent{
+				PluginName:    "disk",
+				PluginVersion: 2,
+				Added:         []string{"/intel/disk/sda/reads", "/intel/disk/sdb/reads"},
+				Removed:       []string{},
+			})
+			So(emitter.events[1].(*control_event.CatalogUpdatedEvent).Removed, ShouldResemble, []string{"/intel/disk/sda/reads"})
+		})
+	})
+}
@@ -0,0 +1,278 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/core"
+)
+
+// computedVersion is the version computed metrics are advertised with
+const computedVersion = 1
+
+// computedMetricType advertises a computed metric in the metric catalog
+type computedMetricType struct {
+	metric *core.ComputedMetric
+	added  time.Time
+}
+
+func (c *computedMetricType) Namespace() []string {
+	return c.metric.Namespace
+}
+
+func (c *computedMetricType) Version() int {
+	return computedVersion
+}
+
+func (c *computedMetricType) LastAdvertisedTime() time.Time {
+	return c.added
+}
+
+func (c *computedMetricType) Policy() *cpolicy.ConfigPolicyNode {
+	return cpolicy.NewPolicyNode()
+}
+
+func (c *computedMetricType) Unit() string {
+	return ""
+}
+
+func (c *computedMetricType) Description() string {
+	return "computed: " + c.metric.Expression
+}
+
+// AddComputed adds a metric computed by the expression from other metrics
+// to the catalog. Its namespace and the namespaces the expression uses must
+// be concrete, and it cannot hide a cataloged namespace, an alias, or be
+// used by another computed metric.
+func (mc *metricCatalog) AddComputed(namespace, expression string) error {
+	ns, err := aliasNamespace(namespace)
+	if err != nil {
+		return err
+	}
+	cm, err := core.NewComputedMetric(ns, expression)
+	if err != nil {
+		return err
+	}
+	if mts, err := mc.tree.Get(ns); err == nil && len(mts) > 0 {
+		return fmt.Errorf("Computed metric %s is a cataloged namespace", namespace)
+	}
+	if mc.isAlias(ns) {
+		return fmt.Errorf("Computed metric %s is an alias", namespace)
+	}
+	key := getMetricKey(ns)
+	mc.computedMutex.Lock()
+	defer mc.computedMutex.Unlock()
+	for _, o := range cm.Operands() {
+		if _, ok := mc.computed[getMetricKey(o)]; ok || getMetricKey(o) == key {
+			return fmt.Errorf("Computed metric %s uses the computed metric %s", namespace, core.JoinNamespace(o))
+		}
+	}
+	for _, c := range mc.computed {
+		for _, o := range c.metric.Operands() {
+			if getMetricKey(o) == key {
+				return fmt.Errorf("Computed metric %s is used by the computed metric %s", namespace, core.JoinNamespace(c.metric.Namespace))
+			}
+		}
+	}
+	if mc.computed == nil {
+		mc.computed = map[string]*computedMetricType{}
+	}
+	mc.computed[key] = &computedMetricType{metric: cm, added: time.Now()}
+	return nil
+}
+
+// Computed returns the computed metric of the namespace, nil if there is
+// none
+func (mc *metricCatalog) Computed(ns []string) *core.ComputedMetric {
+	mc.computedMutex.RLock()
+	defer mc.computedMutex.RUnlock()
+	if c, ok := mc.computed[getMetricKey(ns)]; ok {
+		return c.metric
+	}
+	return nil
+}
+
+// ComputedMetrics returns the computed metrics falling under the namespace,
+// ordered by namespace
+func (mc *metricCatalog) ComputedMetrics(ns []string) []core.CatalogedMetric {
+	mc.computedMutex.RLock()
+	defer mc.computedMutex.RUnlock()
+	prefix := getMetricKey(ns)
+	keys := make([]string, 0, len(mc.computed))
+	for key := range mc.computed {
+		if prefix == "" || key == prefix || strings.HasPrefix(key, prefix+".") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	mts := make([]core.CatalogedMetric, len(keys))
+	for i, key := range keys {
+		mts[i] = mc.computed[key]
+	}
+	return mts
+}
+
+// computedMetric returns the computed metric of the namespace as advertised
+// in the catalog, nil if there is none
+func computedMetric(cat catalogsMetrics, ns []string) core.CatalogedMetric {
+	for _, cmt := range cat.ComputedMetrics(ns) {
+		if getMetricKey(cmt.Namespace()) == getMetricKey(ns) {
+			return cmt
+		}
+	}
+	return nil
+}
+
+// setComputed adds the computed metrics of the configuration, logging the
+// invalid ones
+func setComputed(mc catalogsMetrics, computed map[string]string) {
+	keys := make([]string, 0, len(computed))
+	for ns := range computed {
+		keys = append(keys, ns)
+	}
+	sort.Strings(keys)
+	for _, ns := range keys {
+		if err := mc.AddComputed(ns, computed[ns]); err != nil {
+			controlLogger.WithFields(log.Fields{
+				"_block":     "set-computed",
+				"namespace":  ns,
+				"expression": computed[ns],
+				"error":      err,
+			}).Error("invalid computed metric ignored")
+		}
+	}
+}
+
+// computedPlan is the computation of the computed metrics requested by a
+// task, the stage run once their operands are collected
+type computedPlan struct {
+	metrics []*core.ComputedMetric
+	// operandsOnly holds the keys of the operands the task did not request
+	// for themselves
+	operandsOnly map[string]bool
+}
+
+// expandComputed replaces the computed metrics requested by the metrics
+// their expressions use. The operands are requested with the config of the
+// computed metric and the latest version of the plugins collecting them.
+func expandComputed(cat catalogsMetrics, mts []core.Metric) ([]core.Metric, *computedPlan) {
+	var plan *computedPlan
+	requested := map[string]bool{}
+	expanded := make([]core.Metric, 0, len(mts))
+	var computed []core.Metric
+	for _, mt := range mts {
+		if cm := cat.Computed(mt.Namespace()); cm != nil {
+			if plan == nil {
+				plan = &computedPlan{operandsOnly: map[string]bool{}}
+			}
+			plan.metrics = append(plan.metrics, cm)
+			computed = append(computed, mt)
+			continue
+		}
+		requested[getMetricKey(mt.Namespace())] = true
+		expanded = append(expanded, mt)
+	}
+	for i, cm := range plan.computedMetrics() {
+		for _, o := range cm.Operands() {
+			key := getMetricKey(o)
+			if requested[key] {
+				continue
+			}
+			requested[key] = true
+			plan.operandsOnly[key] = true
+			expanded = append(expanded, plugin.PluginMetricType{
+				Namespace_: o,
+				Config_:    computed[i].Config(),
+			})
+		}
+	}
+	return expanded, plan
+}
+
+func (p *computedPlan) computedMetrics() []*core.ComputedMetric {
+	if p == nil {
+		return nil
+	}
+	return p.metrics
+}
+
+// compute adds the computed metrics to the metrics collected and removes the
+// operands the task did not request. A computed metric whose expression
+// cannot be evaluated, e.g. dividing by zero, is left out.
+func (p *computedPlan) compute(collected []core.Metric) []core.Metric {
+	if p == nil {
+		return collected
+	}
+	values := make(map[string]interface{}, len(collected))
+	operands := make(map[string]core.Metric, len(collected))
+	mts := make([]core.Metric, 0, len(collected)+len(p.metrics))
+	for _, m := range collected {
+		key := getMetricKey(m.Namespace())
+		if _, ok := operands[key]; !ok {
+			operands[key] = m
+			values[core.JoinNamespace(m.Namespace())] = m.Data()
+		}
+		if !p.operandsOnly[key] {
+			mts = append(mts, m)
+		}
+	}
+	for _, cm := range p.metrics {
+		v, err := cm.Evaluate(values)
+		if err != nil {
+			controlLogger.WithFields(log.Fields{
+				"_block":    "compute-metrics",
+				"namespace": core.JoinNamespace(cm.Namespace),
+				"error":     err,
+			}).Warn("unable to compute metric")
+			continue
+		}
+		mt := plugin.PluginMetricType{
+			Namespace_: cm.Namespace,
+			Version_:   computedVersion,
+			Data_:      v,
+		}
+		// the computed metric is collected from the same host as its first
+		// operand, when its operands were collected
+		first := true
+		for _, o := range cm.Operands() {
+			om, ok := operands[getMetricKey(o)].(plugin.PluginMetricType)
+			if !ok {
+				continue
+			}
+			if first {
+				mt.Tags_, mt.Source_, mt.Host_ = om.Tags_, om.Source_, om.Host_
+				first = false
+			}
+			if om.Timestamp_.After(mt.Timestamp_) {
+				mt.Timestamp_ = om.Timestamp_
+			}
+		}
+		mts = append(mts, mt)
+	}
+	return mts
+}
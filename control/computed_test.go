@@ -0,0 +1,93 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestComputedMetrics(t *testing.T) {
+	Convey("metricCatalog computed metrics", t, func() {
+		mc := newMetricCatalog()
+		lp := new(loadedPlugin)
+		lp.Meta.Name = "mem"
+		lp.Meta.Version = 1
+		mc.Add(newMetricType([]string{"intel", "mem", "used"}, time.Now(), lp))
+		mc.Add(newMetricType([]string{"intel", "mem", "total"}, time.Now(), lp))
+		So(mc.AddComputed("/company/mem/used_pct", "/intel/mem/used / /intel/mem/total * 100"), ShouldBeNil)
+
+		Convey("are advertised like cataloged metrics", func() {
+			So(mc.Computed([]string{"company", "mem", "used_pct"}), ShouldNotBeNil)
+			cmts := mc.ComputedMetrics([]string{"company"})
+			So(cmts, ShouldHaveLength, 1)
+			So(cmts[0].Version(), ShouldEqual, computedVersion)
+			So(cmts[0].(core.DescribedMetric).Description(), ShouldEqual, "computed: /intel/mem/used / /intel/mem/total * 100")
+			So(mc.ComputedMetrics([]string{"intel"}), ShouldBeEmpty)
+			nss, err := mc.MatchQuery([]string{"company", "mem", "used_pct"})
+			So(err, ShouldBeNil)
+			So(nss, ShouldResemble, [][]string{{"company", "mem", "used_pct"}})
+		})
+		Convey("are validated", func() {
+			So(mc.AddComputed("/intel/mem/used", "/intel/mem/total * 2"), ShouldNotBeNil)
+			So(mc.AddComputed("/company/mem/used_ratio", "/company/mem/used_pct / 100"), ShouldNotBeNil)
+			So(mc.AddComputed("/company/mem/used_x", "/intel/mem/used *"), ShouldNotBeNil)
+		})
+		Convey("are computed from their collected operands", func() {
+			mts := []core.Metric{
+				plugin.PluginMetricType{Namespace_: []string{"company", "mem", "used_pct"}},
+				plugin.PluginMetricType{Namespace_: []string{"intel", "mem", "total"}},
+			}
+			expanded, plan := expandComputed(mc, mts)
+			So(expanded, ShouldHaveLength, 2)
+			So(expanded[0].Namespace(), ShouldResemble, []string{"intel", "mem", "total"})
+			So(expanded[1].Namespace(), ShouldResemble, []string{"intel", "mem", "used"})
+
+			ts := time.Now()
+			collected := []core.Metric{
+				plugin.PluginMetricType{Namespace_: []string{"intel", "mem", "total"}, Data_: uint64(400), Timestamp_: ts, Source_: "host1"},
+				plugin.PluginMetricType{Namespace_: []string{"intel", "mem", "used"}, Data_: uint64(100), Timestamp_: ts.Add(time.Millisecond), Source_: "host1"},
+			}
+			computed := plan.compute(collected)
+			So(computed, ShouldHaveLength, 2)
+			So(computed[0].Namespace(), ShouldResemble, []string{"intel", "mem", "total"})
+			So(computed[1].Namespace(), ShouldResemble, []string{"company", "mem", "used_pct"})
+			So(computed[1].Data(), ShouldEqual, 25.0)
+			So(computed[1].Timestamp(), ShouldResemble, ts.Add(time.Millisecond))
+			So(computed[1].Source(), ShouldEqual, "host1")
+
+			Convey("leaving out the ones which cannot be evaluated", func() {
+				collected[0] = plugin.PluginMetricType{Namespace_: []string{"intel", "mem", "total"}, Data_: 0}
+				So(plan.compute(collected), ShouldHaveLength, 1)
+			})
+		})
+		Convey("do not change the metrics of tasks requesting none", func() {
+			mts := []core.Metric{plugin.PluginMetricType{Namespace_: []string{"intel", "mem", "used"}}}
+			expanded, plan := expandComputed(mc, mts)
+			So(expanded, ShouldResemble, mts)
+			So(plan, ShouldBeNil)
+			So(plan.compute(mts), ShouldResemble, mts)
+		})
+	})
+}
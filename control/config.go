@@ -23,13 +23,20 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/vrischmann/jsonutil"
 
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/client"
+	"github.com/intelsdi-x/snap/control/strategy"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/ctypes"
@@ -42,8 +49,23 @@ const (
 	defaultAutoDiscoverPath  string        = ""
 	defaultKeyringPaths      string        = ""
 	defaultCacheExpiration   time.Duration = 500 * time.Millisecond
+	defaultVersionFallback   string        = VersionFallbackFail
+	defaultMetricRefresh     time.Duration = 60 * time.Second
+	defaultPluginLogMaxSize  int           = 10
+	defaultPluginLogMaxFiles int           = 5
+	defaultContainerIDTag    string        = "container_id"
+	defaultContainerRuntime  string        = ContainerRuntimeDocker
+	defaultContainerNS       string        = "k8s.io"
 )
 
+// minPluginChunkSize is the smallest size of the chunks the metric batches
+// are transferred to the plugins in
+const minPluginChunkSize = 1024
+
+// defaultCrashPath is where the crash reports of the plugins are written by
+// default
+var defaultCrashPath = filepath.Join(os.TempDir(), "snap-crashes")
+
 type pluginConfig struct {
 	All         *cdata.ConfigDataNode `json:"all"`
 	Collector   *pluginTypeConfigItem `json:"collector"`
@@ -64,26 +86,247 @@ type pluginConfigItem struct {
 
 // holds the configuration passed in through the SNAP config file
 type Config struct {
-	MaxRunningPlugins int               `json:"max_running_plugins,omitempty"yaml:"max_running_plugins,omitempty"`
-	PluginTrust       int               `json:"plugin_trust_level,omitempty"yaml:"plugin_trust_level,omitempty"`
-	AutoDiscoverPath  string            `json:"auto_discover_path,omitempty"yaml:"auto_discover_path,omitempty"`
-	KeyringPaths      string            `json:"keyring_paths,omitempty"yaml:"keyring_paths,omitempty"`
-	CacheExpiration   jsonutil.Duration `json:"cache_expiration,omitempty"yaml:"cache_expiration,omitempty"`
-	Plugins           *pluginConfig     `json:"plugins,omitempty"yaml:"plugins,omitempty"`
+	MaxRunningPlugins      int               `json:"max_running_plugins,omitempty"yaml:"max_running_plugins,omitempty"`
+	PluginTrust            int               `json:"plugin_trust_level,omitempty"yaml:"plugin_trust_level,omitempty"`
+	AutoDiscoverPath       string            `json:"auto_discover_path,omitempty"yaml:"auto_discover_path,omitempty"`
+	KeyringPaths           string            `json:"keyring_paths,omitempty"yaml:"keyring_paths,omitempty"`
+	CacheExpiration        jsonutil.Duration `json:"cache_expiration,omitempty"yaml:"cache_expiration,omitempty"`
+	VersionFallback        string            `json:"version_fallback,omitempty"yaml:"version_fallback,omitempty"`
+	MetricRefreshInterval  jsonutil.Duration `json:"metric_refresh_interval,omitempty"yaml:"metric_refresh_interval,omitempty"`
+	MetricTTL              jsonutil.Duration `json:"metric_ttl,omitempty"yaml:"metric_ttl,omitempty"`
+	RecordPath             string            `json:"record_path,omitempty"yaml:"record_path,omitempty"`
+	ReplayPath             string            `json:"replay_path,omitempty"yaml:"replay_path,omitempty"`
+	MaxCatalogMetrics      int               `json:"max_catalog_metrics,omitempty"yaml:"max_catalog_metrics,omitempty"`
+	MaxPluginMetrics       int               `json:"max_plugin_metrics,omitempty"yaml:"max_plugin_metrics,omitempty"`
+	MaxTaskMetrics         int               `json:"max_task_metrics,omitempty"yaml:"max_task_metrics,omitempty"`
+	PluginRPCFailureLimit  int               `json:"plugin_rpc_failure_limit"yaml:"plugin_rpc_failure_limit"`
+	PluginRPCFailureWindow jsonutil.Duration `json:"plugin_rpc_failure_window,omitempty"yaml:"plugin_rpc_failure_window,omitempty"`
+	CrashPath              string            `json:"crash_path"yaml:"crash_path"`
+	PluginLogMaxSize       int               `json:"plugin_log_max_size"yaml:"plugin_log_max_size"`
+	PluginLogMaxFiles      int               `json:"plugin_log_max_files"yaml:"plugin_log_max_files"`
+	PluginLogInline        bool              `json:"plugin_log_inline,omitempty"yaml:"plugin_log_inline,omitempty"`
+	PluginChunkSize        int               `json:"plugin_chunk_size"yaml:"plugin_chunk_size"`
+	PluginConnKeepAlive    jsonutil.Duration `json:"plugin_conn_keepalive"yaml:"plugin_conn_keepalive"`
+	PluginConnIdleTimeout  jsonutil.Duration `json:"plugin_conn_idle_timeout"yaml:"plugin_conn_idle_timeout"`
+	PluginConnMaxIdle      int               `json:"plugin_conn_max_idle,omitempty"yaml:"plugin_conn_max_idle,omitempty"`
+	PluginReconnectBackoff jsonutil.Duration `json:"plugin_reconnect_max_backoff,omitempty"yaml:"plugin_reconnect_max_backoff,omitempty"`
+	PluginMaxMessageSize   int               `json:"plugin_max_message_size"yaml:"plugin_max_message_size"`
+	PluginCompression      string            `json:"plugin_compression,omitempty"yaml:"plugin_compression,omitempty"`
+	PluginTransport        PluginTransports  `json:"plugin_transport,omitempty"yaml:"plugin_transport,omitempty"`
+	ParallelCollectMin     int               `json:"parallel_collect_min_metrics"yaml:"parallel_collect_min_metrics"`
+	ParallelCollectSplit   string            `json:"parallel_collect_split,omitempty"yaml:"parallel_collect_split,omitempty"`
+	Tags                   map[string]string `json:"tags,omitempty"yaml:"tags,omitempty"`
+	Aliases                map[string]string `json:"aliases,omitempty"yaml:"aliases,omitempty"`
+	ComputedMetrics        map[string]string `json:"computed_metrics,omitempty"yaml:"computed_metrics,omitempty"`
+	ContainerSocket        string            `json:"container_socket,omitempty"yaml:"container_socket,omitempty"`
+	ContainerIDTag         string            `json:"container_id_tag,omitempty"yaml:"container_id_tag,omitempty"`
+	ContainerRuntime       string            `json:"container_runtime,omitempty"yaml:"container_runtime,omitempty"`
+	ContainerNamespace     string            `json:"container_namespace,omitempty"yaml:"container_namespace,omitempty"`
+	Plugins                *pluginConfig     `json:"plugins,omitempty"yaml:"plugins,omitempty"`
+	// Tenants are the scopes of the tasks of the tenants by tenant ID
+	Tenants map[string]*TenantConfig `json:"tenants,omitempty"yaml:"tenants,omitempty"`
+	// the file the plugin config changes are persisted to
+	pluginConfigFile string
 }
 
 // get the default snapd configuration
 func GetDefaultConfig() *Config {
 	return &Config{
-		MaxRunningPlugins: defaultMaxRunningPlugins,
-		PluginTrust:       defaultPluginTrust,
-		AutoDiscoverPath:  defaultAutoDiscoverPath,
-		KeyringPaths:      defaultKeyringPaths,
-		CacheExpiration:   jsonutil.Duration{defaultCacheExpiration},
-		Plugins:           newPluginConfig(),
+		MaxRunningPlugins:      defaultMaxRunningPlugins,
+		PluginTrust:            defaultPluginTrust,
+		AutoDiscoverPath:       defaultAutoDiscoverPath,
+		KeyringPaths:           defaultKeyringPaths,
+		CacheExpiration:        jsonutil.Duration{defaultCacheExpiration},
+		VersionFallback:        defaultVersionFallback,
+		MetricRefreshInterval:  jsonutil.Duration{defaultMetricRefresh},
+		PluginRPCFailureLimit:  DefaultRPCFailureLimit,
+		PluginRPCFailureWindow: jsonutil.Duration{DefaultRPCFailureWindow},
+		CrashPath:              defaultCrashPath,
+		PluginLogMaxSize:       defaultPluginLogMaxSize,
+		PluginLogMaxFiles:      defaultPluginLogMaxFiles,
+		PluginChunkSize:        plugin.DefaultChunkSize,
+		PluginConnKeepAlive:    jsonutil.Duration{client.DefaultKeepAlive},
+		PluginConnIdleTimeout:  jsonutil.Duration{client.DefaultIdleTimeout},
+		PluginConnMaxIdle:      client.DefaultMaxIdleConns,
+		PluginReconnectBackoff: jsonutil.Duration{client.DefaultMaxReconnectBackoff},
+		ParallelCollectSplit:   strategy.DefaultSplitter,
+		ContainerIDTag:         defaultContainerIDTag,
+		ContainerRuntime:       defaultContainerRuntime,
+		ContainerNamespace:     defaultContainerNS,
+		Plugins:                newPluginConfig(),
 	}
 }
 
+// Validate returns the problems found in the configuration, including
+// auto discover and keyring paths which do not exist or cannot be read
+func (c *Config) Validate() []error {
+	var errs []error
+	if c.MaxRunningPlugins < 1 {
+		errs = append(errs, fmt.Errorf("control.max_running_plugins: must be greater than 0"))
+	}
+	if c.PluginTrust < 0 || c.PluginTrust > 2 {
+		errs = append(errs, fmt.Errorf("control.plugin_trust_level: %d is not one of 0 (disabled), 1 (enabled) or 2 (warning)", c.PluginTrust))
+	}
+	if c.CacheExpiration.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("control.cache_expiration: must be greater than 0"))
+	}
+	if c.MetricRefreshInterval.Duration < 0 {
+		errs = append(errs, fmt.Errorf("control.metric_refresh_interval: must not be negative"))
+	}
+	if c.VersionFallback != "" && !validVersionFallback(c.VersionFallback) {
+		errs = append(errs, fmt.Errorf("control.version_fallback: %q is not one of %s", c.VersionFallback, strings.Join(VersionFallbackPolicies, ", ")))
+	}
+	if c.MetricTTL.Duration < 0 {
+		errs = append(errs, fmt.Errorf("control.metric_ttl: must not be negative"))
+	}
+	if c.MaxCatalogMetrics < 0 {
+		errs = append(errs, fmt.Errorf("control.max_catalog_metrics: must not be negative"))
+	}
+	if c.MaxPluginMetrics < 0 {
+		errs = append(errs, fmt.Errorf("control.max_plugin_metrics: must not be negative"))
+	}
+	if c.MaxTaskMetrics < 0 {
+		errs = append(errs, fmt.Errorf("control.max_task_metrics: must not be negative"))
+	}
+	if c.PluginRPCFailureLimit < 0 {
+		errs = append(errs, fmt.Errorf("control.plugin_rpc_failure_limit: must not be negative"))
+	}
+	if c.PluginRPCFailureLimit > 0 && c.PluginRPCFailureWindow.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("control.plugin_rpc_failure_window: must be greater than 0"))
+	}
+	if c.PluginLogMaxSize < 0 {
+		errs = append(errs, fmt.Errorf("control.plugin_log_max_size: must not be negative"))
+	}
+	if c.PluginLogMaxFiles < 0 {
+		errs = append(errs, fmt.Errorf("control.plugin_log_max_files: must not be negative"))
+	}
+	if c.PluginChunkSize < 0 || c.PluginChunkSize > 0 && c.PluginChunkSize < minPluginChunkSize {
+		errs = append(errs, fmt.Errorf("control.plugin_chunk_size: must be 0 or at least %d", minPluginChunkSize))
+	}
+	if c.PluginConnKeepAlive.Duration < 0 {
+		errs = append(errs, fmt.Errorf("control.plugin_conn_keepalive: must not be negative"))
+	}
+	if c.PluginConnIdleTimeout.Duration < 0 {
+		errs = append(errs, fmt.Errorf("control.plugin_conn_idle_timeout: must not be negative"))
+	}
+	if c.PluginConnMaxIdle < 1 {
+		errs = append(errs, fmt.Errorf("control.plugin_conn_max_idle: must be greater than 0"))
+	}
+	if c.PluginReconnectBackoff.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("control.plugin_reconnect_max_backoff: must be greater than 0"))
+	}
+	defaults := PluginTransport{MaxMessageSize: c.PluginMaxMessageSize, Compression: c.PluginCompression}
+	for _, err := range defaults.validate() {
+		errs = append(errs, fmt.Errorf("control.plugin_%v", err))
+	}
+	for key, pt := range c.PluginTransport {
+		if err := transportKey(key); err != nil {
+			errs = append(errs, fmt.Errorf("control.plugin_transport: %v", err))
+			continue
+		}
+		if pt == nil {
+			continue
+		}
+		for _, err := range pt.validate() {
+			errs = append(errs, fmt.Errorf("control.plugin_transport.%s.%v", key, err))
+		}
+	}
+	if c.ParallelCollectMin < 0 {
+		errs = append(errs, fmt.Errorf("control.parallel_collect_min_metrics: must not be negative"))
+	}
+	if c.ParallelCollectSplit != "" {
+		if _, err := strategy.GetSplitter(c.ParallelCollectSplit); err != nil {
+			errs = append(errs, fmt.Errorf("control.parallel_collect_split: %q is not one of %s", c.ParallelCollectSplit, strings.Join(strategy.Splitters(), ", ")))
+		}
+	}
+	if c.CrashPath != "" {
+		if fi, err := os.Stat(c.CrashPath); err == nil && !fi.IsDir() {
+			errs = append(errs, fmt.Errorf("control.crash_path: %s is not a directory", c.CrashPath))
+		}
+	}
+	// the container runtime may start after snapd, so the socket does not
+	// have to exist
+	if c.ContainerSocket != "" {
+		if fi, err := os.Stat(c.ContainerSocket); err == nil && fi.Mode()&os.ModeSocket == 0 {
+			errs = append(errs, fmt.Errorf("control.container_socket: %s is not a socket", c.ContainerSocket))
+		}
+		if c.ContainerIDTag == "" {
+			errs = append(errs, fmt.Errorf("control.container_id_tag: must not be empty"))
+		}
+		if c.ContainerRuntime != ContainerRuntimeDocker && c.ContainerRuntime != ContainerRuntimeContainerd {
+			errs = append(errs, fmt.Errorf("control.container_runtime: must be %s or %s, not %s", ContainerRuntimeDocker, ContainerRuntimeContainerd, c.ContainerRuntime))
+		}
+	}
+	for alias, target := range c.Aliases {
+		for _, ns := range []string{alias, target} {
+			if _, err := aliasNamespace(ns); err != nil {
+				errs = append(errs, fmt.Errorf("control.aliases: %v", err))
+			}
+		}
+	}
+	for namespace, expression := range c.ComputedMetrics {
+		ns, err := aliasNamespace(namespace)
+		if err == nil {
+			_, err = core.NewComputedMetric(ns, expression)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("control.computed_metrics: %v", err))
+		}
+	}
+	for k, v := range c.Tags {
+		if k == "" {
+			errs = append(errs, fmt.Errorf("control.tags: tag names must not be empty"))
+			continue
+		}
+		if err := validTagValue(v); err != nil {
+			errs = append(errs, fmt.Errorf("control.tags.%s: %v", k, err))
+		}
+	}
+	errs = append(errs, validateTenants(c.Tenants)...)
+	if c.RecordPath != "" && c.ReplayPath != "" {
+		errs = append(errs, fmt.Errorf("control.record_path: cannot record while replaying control.replay_path"))
+	}
+	if c.ReplayPath != "" {
+		if _, err := os.Stat(c.ReplayPath); err != nil {
+			errs = append(errs, fmt.Errorf("control.replay_path: %v", err))
+		}
+	}
+	for _, p := range filepath.SplitList(c.AutoDiscoverPath) {
+		fi, err := os.Stat(p)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("control.auto_discover_path: %v", err))
+			continue
+		}
+		if !fi.IsDir() {
+			errs = append(errs, fmt.Errorf("control.auto_discover_path: %s is not a directory", p))
+			continue
+		}
+		if _, err := ioutil.ReadDir(p); err != nil {
+			errs = append(errs, fmt.Errorf("control.auto_discover_path: %v", err))
+			continue
+		}
+		m, err := ReadAutodiscoverManifest(p)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("control.auto_discover_path: %v", err))
+			continue
+		}
+		if m == nil {
+			continue
+		}
+		for _, e := range m.Plugins {
+			if _, err := os.Stat(filepath.Join(p, e.File)); err != nil {
+				errs = append(errs, fmt.Errorf("control.auto_discover_path: manifest in %s: %v", p, err))
+			}
+		}
+	}
+	for _, p := range filepath.SplitList(c.KeyringPaths) {
+		if _, err := os.Stat(p); err != nil {
+			errs = append(errs, fmt.Errorf("control.keyring_paths: %v", err))
+		}
+	}
+	return errs
+}
+
 // NewPluginsConfig returns a map of *pluginConfigItems where the key is the plugin name.
 func NewPluginsConfig() map[string]*pluginConfigItem {
 	return map[string]*pluginConfigItem{}
@@ -120,18 +363,31 @@ func (p *Config) GetPluginConfigDataNode(pluginType core.PluginType, name string
 
 func (p *Config) MergePluginConfigDataNode(pluginType core.PluginType, name string, ver int, cdn *cdata.ConfigDataNode) cdata.ConfigDataNode {
 	p.Plugins.mergePluginConfigDataNode(pluginType, name, ver, cdn)
+	p.persistPluginConfig()
 	return *p.Plugins.getPluginConfigDataNode(pluginType, name, ver)
 }
 
 func (p *Config) MergePluginConfigDataNodeAll(cdn *cdata.ConfigDataNode) cdata.ConfigDataNode {
 	p.Plugins.mergePluginConfigDataNodeAll(cdn)
+	p.persistPluginConfig()
 	return *p.Plugins.All
 }
 
+// ReplacePluginConfigDataNode swaps the global config of the given plugins
+// for cdn, rather than merging cdn into it. An empty name replaces the
+// config of all the plugins of the type, a version below 1 the config of
+// all the versions of the plugin.
+func (p *Config) ReplacePluginConfigDataNode(pluginType core.PluginType, name string, ver int, cdn *cdata.ConfigDataNode) cdata.ConfigDataNode {
+	p.Plugins.replacePluginConfigDataNode(pluginType, name, ver, cdn)
+	p.persistPluginConfig()
+	return *p.Plugins.getPluginConfigDataNode(pluginType, name, ver)
+}
+
 func (p *Config) DeletePluginConfigDataNodeField(pluginType core.PluginType, name string, ver int, fields ...string) cdata.ConfigDataNode {
 	for _, field := range fields {
 		p.Plugins.deletePluginConfigDataNodeField(pluginType, name, ver, field)
 	}
+	p.persistPluginConfig()
 	return *p.Plugins.getPluginConfigDataNode(pluginType, name, ver)
 }
 
@@ -139,6 +395,7 @@ func (p *Config) DeletePluginConfigDataNodeFieldAll(fields ...string) cdata.Conf
 	for _, field := range fields {
 		p.Plugins.deletePluginConfigDataNodeFieldAll(field)
 	}
+	p.persistPluginConfig()
 	return *p.Plugins.All
 }
 
@@ -290,6 +547,37 @@ func (p *pluginConfig) mergePluginConfigDataNode(pluginType core.PluginType, nam
 	}
 }
 
+func (p *pluginConfig) replacePluginConfigDataNode(pluginType core.PluginType, name string, ver int, cdn *cdata.ConfigDataNode) {
+	// clear cache
+	p.pluginCache = make(map[string]*cdata.ConfigDataNode)
+
+	var item *pluginTypeConfigItem
+	switch pluginType {
+	case core.CollectorPluginType:
+		item = p.Collector
+	case core.ProcessorPluginType:
+		item = p.Processor
+	case core.PublisherPluginType:
+		item = p.Publisher
+	default:
+		return
+	}
+	cn := cdata.NewNode()
+	cn.Merge(cdn)
+	if name == "" {
+		item.All = cn
+		return
+	}
+	if _, ok := item.Plugins[name]; !ok {
+		item.Plugins[name] = newPluginConfigItem()
+	}
+	if ver > 0 {
+		item.Plugins[name].Versions[ver] = cn
+		return
+	}
+	item.Plugins[name].ConfigDataNode = cn
+}
+
 func (p *pluginConfig) deletePluginConfigDataNodeField(pluginType core.PluginType, name string, ver int, key string) {
 	// clear cache
 	p.pluginCache = make(map[string]*cdata.ConfigDataNode)
@@ -379,6 +667,33 @@ func (p *pluginConfig) getPluginConfigDataNode(pluginType core.PluginType, name
 	return p.pluginCache[key]
 }
 
+// getPluginConfigLayers returns the global config of a plugin as layers in
+// the order getPluginConfigDataNode merges them
+func (p *pluginConfig) getPluginConfigLayers(pluginType core.PluginType, name string, ver int) []cdata.ConfigLayer {
+	layers := []cdata.ConfigLayer{{Source: cdata.GlobalConfigSource + ":all", Node: p.All}}
+
+	var typeItem *pluginTypeConfigItem
+	switch pluginType {
+	case core.CollectorPluginType:
+		typeItem = p.Collector
+	case core.ProcessorPluginType:
+		typeItem = p.Processor
+	case core.PublisherPluginType:
+		typeItem = p.Publisher
+	default:
+		return layers
+	}
+	source := fmt.Sprintf("%s:%s", cdata.GlobalConfigSource, pluginType)
+	layers = append(layers, cdata.ConfigLayer{Source: source, Node: typeItem.All})
+	if res, ok := typeItem.Plugins[name]; ok {
+		layers = append(layers, cdata.ConfigLayer{Source: fmt.Sprintf("%s:%s", source, name), Node: res.ConfigDataNode})
+		if res2, ok2 := res.Versions[ver]; ok2 {
+			layers = append(layers, cdata.ConfigLayer{Source: fmt.Sprintf("%s:%s:%d", source, name, ver), Node: res2})
+		}
+	}
+	return layers
+}
+
 func unmarshalPluginConfig(typ string, p *pluginConfig, t map[string]interface{}) error {
 	if v, ok := t[typ]; ok {
 		switch plugins := v.(type) {
@@ -0,0 +1,159 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core/cdata"
+)
+
+// pluginConfigFileMutex serializes the rewrites of the plugin config file
+var pluginConfigFileMutex sync.Mutex
+
+// SetPluginConfigFile makes the changes to the plugin config made at runtime
+// persist to the JSON file at path, leaving the snapd configuration file
+// untouched. The plugin config of the file, when it exists, replaces the
+// "control.plugins" section of the configuration file.
+func (p *Config) SetPluginConfigFile(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		plugins := newPluginConfig()
+		if err := json.Unmarshal(b, plugins); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		p.Plugins = plugins
+	}
+	p.pluginConfigFile = path
+	return nil
+}
+
+// persistPluginConfig writes the plugin config to the plugin config file, if
+// one was set. Failures are logged as the change has already been applied in
+// memory.
+func (p *Config) persistPluginConfig() {
+	if p.pluginConfigFile == "" {
+		return
+	}
+	if err := writePluginConfig(p.pluginConfigFile, p.Plugins); err != nil {
+		log.WithFields(log.Fields{
+			"_module": "control",
+			"_block":  "persist-plugin-config",
+			"path":    p.pluginConfigFile,
+			"error":   err.Error(),
+		}).Error("unable to persist the plugin config")
+	}
+}
+
+// writePluginConfig replaces the file at path with the given plugin config
+func writePluginConfig(path string, plugins *pluginConfig) error {
+	pluginConfigFileMutex.Lock()
+	defer pluginConfigFileMutex.Unlock()
+
+	b, err := json.MarshalIndent(plugins, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	// write to a temporary file first so that a failure never leaves a
+	// truncated file behind, readable by snapd only as it holds passwords
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// MarshalJSON marshals the plugin config into the layout it is read from
+// in the configuration file, leaving out the empty nodes.
+func (p *pluginConfig) MarshalJSON() ([]byte, error) {
+	m := map[string]interface{}{}
+	if !isEmptyNode(p.All) {
+		m["all"] = p.All
+	}
+	for typ, item := range map[string]*pluginTypeConfigItem{
+		"collector": p.Collector,
+		"processor": p.Processor,
+		"publisher": p.Publisher,
+	} {
+		if t := item.marshalable(); len(t) > 0 {
+			m[typ] = t
+		}
+	}
+	return json.Marshal(m)
+}
+
+func (p *pluginTypeConfigItem) marshalable() map[string]interface{} {
+	m := map[string]interface{}{}
+	if p == nil {
+		return m
+	}
+	if !isEmptyNode(p.All) {
+		m["all"] = p.All
+	}
+	for name, item := range p.Plugins {
+		plugin := map[string]interface{}{}
+		if !isEmptyNode(item.ConfigDataNode) {
+			plugin["all"] = item.ConfigDataNode
+		}
+		versions := map[string]*cdata.ConfigDataNode{}
+		for ver, node := range item.Versions {
+			if !isEmptyNode(node) {
+				versions[strconv.Itoa(ver)] = node
+			}
+		}
+		if len(versions) > 0 {
+			plugin["versions"] = versions
+		}
+		if len(plugin) > 0 {
+			m[name] = plugin
+		}
+	}
+	return m
+}
+
+func isEmptyNode(n *cdata.ConfigDataNode) bool {
+	return n == nil || len(n.Table()) == 0
+}
@@ -0,0 +1,93 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPersistPluginConfig(t *testing.T) {
+	Convey("Given a plugin config file", t, func() {
+		dir, err := ioutil.TempDir("", "snap-config")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "plugin_config.json")
+
+		cfg := GetDefaultConfig()
+		cdn := cdata.NewNode()
+		cdn.AddItem("interval_cache", ctypes.ConfigValueStr{Value: "1s"})
+		cfg.Plugins.mergePluginConfigDataNode(core.CollectorPluginType, "psutil", 7, cdn)
+		So(cfg.SetPluginConfigFile(path), ShouldBeNil)
+
+		reread := func() *Config {
+			c := GetDefaultConfig()
+			So(c.SetPluginConfigFile(path), ShouldBeNil)
+			return c
+		}
+
+		Convey("the plugin config is left as it is while the file does not exist", func() {
+			node := cfg.GetPluginConfigDataNode(core.CollectorPluginType, "psutil", 7)
+			So(node.Table()["interval_cache"], ShouldResemble, ctypes.ConfigValueStr{Value: "1s"})
+			_, err := os.Stat(path)
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+		Convey("setting a plugin config item writes the plugin config to the file", func() {
+			cdn := cdata.NewNode()
+			cdn.AddItem("interval_cache", ctypes.ConfigValueStr{Value: "2s"})
+			cdn.AddItem("ratio", ctypes.ConfigValueFloat{Value: 2})
+			cdn.AddItem("max", ctypes.ConfigValueInt{Value: 1<<53 + 1})
+			cfg.MergePluginConfigDataNode(core.CollectorPluginType, "psutil", 7, cdn)
+
+			node := reread().GetPluginConfigDataNode(core.CollectorPluginType, "psutil", 7)
+			So(node.Table()["interval_cache"], ShouldResemble, ctypes.ConfigValueStr{Value: "2s"})
+			So(node.Table()["ratio"], ShouldResemble, ctypes.ConfigValueFloat{Value: 2})
+			So(node.Table()["max"], ShouldResemble, ctypes.ConfigValueInt{Value: 1<<53 + 1})
+			fi, err := os.Stat(path)
+			So(err, ShouldBeNil)
+			So(fi.Mode().Perm(), ShouldEqual, os.FileMode(0600))
+		})
+		Convey("deleting a plugin config item removes it from the file", func() {
+			cfg.DeletePluginConfigDataNodeField(core.CollectorPluginType, "psutil", 7, "interval_cache")
+
+			node := reread().GetPluginConfigDataNode(core.CollectorPluginType, "psutil", 7)
+			So(node.Table(), ShouldNotContainKey, "interval_cache")
+		})
+		Convey("setting a config item for all plugins writes it to the file", func() {
+			cdn := cdata.NewNode()
+			cdn.AddItem("password", ctypes.ConfigValueStr{Value: "secret"})
+			cfg.MergePluginConfigDataNodeAll(cdn)
+
+			all := reread().GetPluginConfigDataNodeAll()
+			So(all.Table()["password"], ShouldResemble, ctypes.ConfigValueStr{Value: "secret"})
+		})
+		Convey("a malformed file is an error", func() {
+			So(ioutil.WriteFile(path, []byte("{"), 0600), ShouldBeNil)
+			So(GetDefaultConfig().SetPluginConfigFile(path), ShouldNotBeNil)
+		})
+	})
+}
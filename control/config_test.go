@@ -57,6 +57,15 @@ func TestPluginConfig(t *testing.T) {
 							So(len(cd.Table()), ShouldEqual, 4)
 							So(cd.Table()["user"], ShouldResemble, ctypes.ConfigValueStr{Value: "john"})
 						})
+						Convey("we can get the layers of the conf for the given plugin", func() {
+							layers := cfg.Plugins.getPluginConfigLayers(core.CollectorPluginType, "test", 1)
+							So(len(layers), ShouldEqual, 4)
+							So(layers[3].Source, ShouldEqual, "global:collector:test:1")
+							r := cdata.Resolve(layers...)
+							So(r["user"].Source, ShouldEqual, "global:collector:test")
+							So(r["user"].Overridden[0].Source, ShouldEqual, "global:collector")
+							So(r["gvar"].Source, ShouldEqual, "global:all")
+						})
 					})
 				})
 			})
@@ -148,6 +157,9 @@ func TestControlConfigJSON(t *testing.T) {
 		Convey("PluginTrust should be set to 0", func() {
 			So(cfg.PluginTrust, ShouldEqual, 0)
 		})
+		Convey("Tags should be set to the hostname and datacenter", func() {
+			So(cfg.Tags, ShouldResemble, map[string]string{"host": "$hostname", "datacenter": "dc1"})
+		})
 		Convey("Plugins section of control configuration should not be nil", func() {
 			So(cfg.Plugins, ShouldNotBeNil)
 		})
@@ -265,3 +277,130 @@ func TestControlDefaultConfig(t *testing.T) {
 		})
 	})
 }
+
+func TestControlConfigValidate(t *testing.T) {
+	Convey("Validating a control config", t, func() {
+		cfg := GetDefaultConfig()
+		Convey("the default config is valid", func() {
+			So(cfg.Validate(), ShouldBeEmpty)
+		})
+		Convey("invalid values are reported", func() {
+			cfg.MaxRunningPlugins = 0
+			cfg.PluginTrust = 3
+			cfg.AutoDiscoverPath = "/this/path/does/not/exist"
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 3)
+			So(errs[0].Error(), ShouldStartWith, "control.max_running_plugins")
+			So(errs[1].Error(), ShouldStartWith, "control.plugin_trust_level")
+			So(errs[2].Error(), ShouldStartWith, "control.auto_discover_path")
+		})
+		Convey("an unknown version fallback policy is reported", func() {
+			cfg.VersionFallback = "newest"
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Error(), ShouldStartWith, "control.version_fallback")
+		})
+		Convey("a negative metric refresh interval is reported", func() {
+			cfg.MetricRefreshInterval.Duration = -time.Second
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Error(), ShouldStartWith, "control.metric_refresh_interval")
+		})
+		Convey("a negative metric TTL is reported", func() {
+			cfg.MetricTTL.Duration = -time.Second
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Error(), ShouldStartWith, "control.metric_ttl")
+		})
+		Convey("negative metric limits are reported", func() {
+			cfg.MaxCatalogMetrics = -1
+			cfg.MaxPluginMetrics = -1
+			cfg.MaxTaskMetrics = -1
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 3)
+			So(errs[0].Error(), ShouldStartWith, "control.max_catalog_metrics")
+			So(errs[1].Error(), ShouldStartWith, "control.max_plugin_metrics")
+			So(errs[2].Error(), ShouldStartWith, "control.max_task_metrics")
+		})
+		Convey("an RPC failure limit without window is reported", func() {
+			cfg.PluginRPCFailureWindow.Duration = 0
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Error(), ShouldStartWith, "control.plugin_rpc_failure_window")
+			cfg.PluginRPCFailureLimit = 0
+			So(cfg.Validate(), ShouldBeEmpty)
+		})
+		Convey("negative plugin log rotation settings are reported", func() {
+			cfg.PluginLogMaxSize = -1
+			cfg.PluginLogMaxFiles = -1
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 2)
+			So(errs[0].Error(), ShouldStartWith, "control.plugin_log_max_size")
+			So(errs[1].Error(), ShouldStartWith, "control.plugin_log_max_files")
+		})
+		Convey("a plugin chunk size too small to be useful is reported", func() {
+			cfg.PluginChunkSize = 100
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Error(), ShouldStartWith, "control.plugin_chunk_size")
+			cfg.PluginChunkSize = 0
+			So(cfg.Validate(), ShouldBeEmpty)
+		})
+		Convey("plugin connection settings are checked", func() {
+			cfg.PluginConnKeepAlive.Duration = -time.Second
+			cfg.PluginConnIdleTimeout.Duration = -time.Second
+			cfg.PluginConnMaxIdle = 0
+			cfg.PluginReconnectBackoff.Duration = 0
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 4)
+			So(errs[0].Error(), ShouldStartWith, "control.plugin_conn_keepalive")
+			So(errs[1].Error(), ShouldStartWith, "control.plugin_conn_idle_timeout")
+			So(errs[2].Error(), ShouldStartWith, "control.plugin_conn_max_idle")
+			So(errs[3].Error(), ShouldStartWith, "control.plugin_reconnect_max_backoff")
+		})
+		Convey("plugin transport settings are checked", func() {
+			cfg.PluginMaxMessageSize = -1
+			cfg.PluginCompression = "lz4"
+			cfg.PluginTransport = PluginTransports{
+				"psutil":           {Compression: "gzip"},
+				"collector:psutil": {Compression: "zstd"},
+				"exporter:psutil":  {},
+			}
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 5)
+			So(errs[0].Error(), ShouldStartWith, "control.plugin_max_message_size")
+			So(errs[1].Error(), ShouldStartWith, "control.plugin_compression")
+			cfg.PluginMaxMessageSize = 1 << 28
+			cfg.PluginCompression = "snappy"
+			cfg.PluginTransport = PluginTransports{"collector:psutil": {Compression: "gzip"}}
+			So(cfg.Validate(), ShouldBeEmpty)
+		})
+		Convey("parallel collection settings are checked", func() {
+			cfg.ParallelCollectMin = -1
+			cfg.ParallelCollectSplit = "random"
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 2)
+			So(errs[0].Error(), ShouldStartWith, "control.parallel_collect_min_metrics")
+			So(errs[1].Error(), ShouldContainSubstring, "contiguous, namespace-hash, round-robin")
+			cfg.ParallelCollectMin = 1000
+			cfg.ParallelCollectSplit = "round-robin"
+			So(cfg.Validate(), ShouldBeEmpty)
+		})
+		Convey("tags with an unknown source are reported", func() {
+			cfg.Tags = map[string]string{"host": "$hostname", "zone": "$ec2:", "rack": "$rack"}
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 2)
+			for _, err := range errs {
+				So(err.Error(), ShouldStartWith, "control.tags.")
+			}
+		})
+		Convey("recording while replaying is reported", func() {
+			cfg.RecordPath = "/tmp/snap-record"
+			cfg.ReplayPath = "/tmp/snap-replay-does-not-exist"
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 2)
+			So(errs[0].Error(), ShouldStartWith, "control.record_path")
+			So(errs[1].Error(), ShouldStartWith, "control.replay_path")
+		})
+	})
+}
@@ -0,0 +1,233 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+)
+
+// The container runtimes whose containers can be looked up
+const (
+	ContainerRuntimeDocker     = "docker"
+	ContainerRuntimeContainerd = "containerd"
+)
+
+// The tags added to the metrics of a container
+const (
+	ContainerNameTag  = "container_name"
+	ContainerImageTag = "container_image"
+	// ContainerLabelTagPrefix followed by the name of a label of the
+	// container is the tag of that label
+	ContainerLabelTagPrefix = "container_label_"
+)
+
+var (
+	containerTimeout = 2 * time.Second
+	// containers are looked up again once their entry expires, so that
+	// renamed and relabelled containers are picked up
+	containerCacheExpiration = time.Minute
+)
+
+// container is what the container runtime tells about a container
+type container struct {
+	Name   string
+	Image  string
+	Labels map[string]string
+}
+
+// tags returns the tags of the container
+func (c *container) tags() map[string]string {
+	tags := make(map[string]string, len(c.Labels)+2)
+	if c.Name != "" {
+		tags[ContainerNameTag] = c.Name
+	}
+	if c.Image != "" {
+		tags[ContainerImageTag] = c.Image
+	}
+	for k, v := range c.Labels {
+		tags[ContainerLabelTagPrefix+k] = v
+	}
+	return tags
+}
+
+type containerEntry struct {
+	container *container
+	expires   time.Time
+}
+
+// containerRuntime looks a container up by its ID
+type containerRuntime interface {
+	inspect(id string) (*container, error)
+}
+
+// containerEnricher adds the name, image and labels of the container whose ID
+// a metric is tagged with to the tags of the metric. The containers are
+// looked up in the background through the API of the container runtime, and
+// cached: the metrics of a container are tagged once its first lookup is done.
+type containerEnricher struct {
+	idTag   string
+	runtime containerRuntime
+	mutex   sync.Mutex
+	// the containers by ID, nil for the IDs the runtime does not know
+	cache map[string]containerEntry
+	// the IDs being looked up
+	pending map[string]bool
+	lookups sync.WaitGroup
+}
+
+func newContainerEnricher(runtime containerRuntime, idTag string) *containerEnricher {
+	return &containerEnricher{
+		idTag:   idTag,
+		runtime: runtime,
+		cache:   make(map[string]containerEntry),
+		pending: make(map[string]bool),
+	}
+}
+
+// newContainerRuntime returns the client of the API of the container runtime
+// listening on the unix socket
+func newContainerRuntime(runtime, socket, namespace string) (containerRuntime, error) {
+	switch runtime {
+	case ContainerRuntimeDocker:
+		return newDockerRuntime(socket), nil
+	case ContainerRuntimeContainerd:
+		return newContainerdRuntime(socket, namespace), nil
+	}
+	return nil, fmt.Errorf("unknown container runtime: %s", runtime)
+}
+
+// enrich adds the tags of their container to the metrics tagged with a
+// container ID, the tags set by the plugins taking precedence
+func (e *containerEnricher) enrich(mts []core.Metric) {
+	for i, m := range mts {
+		mt, ok := m.(plugin.PluginMetricType)
+		if !ok || mt.Tags_[e.idTag] == "" {
+			continue
+		}
+		c := e.container(mt.Tags_[e.idTag])
+		if c == nil {
+			continue
+		}
+		tags := c.tags()
+		merged := make(map[string]string, len(tags)+len(mt.Tags_))
+		for k, v := range tags {
+			merged[k] = v
+		}
+		for k, v := range mt.Tags_ {
+			merged[k] = v
+		}
+		mt.Tags_ = merged
+		mts[i] = mt
+	}
+}
+
+// container returns the container with the ID from the cache without waiting
+// for the runtime, looking it up in the background when its entry is missing
+// or expired. An expired entry is returned until it is looked up again.
+func (e *containerEnricher) container(id string) *container {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	entry, ok := e.cache[id]
+	if (!ok || time.Now().After(entry.expires)) && !e.pending[id] {
+		e.pending[id] = true
+		e.lookups.Add(1)
+		go e.lookup(id)
+	}
+	return entry.container
+}
+
+// lookup looks the container with the ID up and caches it
+func (e *containerEnricher) lookup(id string) {
+	defer e.lookups.Done()
+	c, err := e.runtime.inspect(id)
+	if err != nil {
+		controlLogger.WithFields(log.Fields{
+			"_block":       "enrich-container",
+			"container-id": id,
+		}).Warn("container tags left out: ", err)
+	}
+	now := time.Now()
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	delete(e.pending, id)
+	e.cache[id] = containerEntry{container: c, expires: now.Add(containerCacheExpiration)}
+	// forget the containers which were not looked up again, e.g. removed ones
+	for k, v := range e.cache {
+		if now.After(v.expires.Add(containerCacheExpiration)) {
+			delete(e.cache, k)
+		}
+	}
+}
+
+// dockerRuntime looks the containers up through the Docker Engine API
+type dockerRuntime struct {
+	client *http.Client
+}
+
+func newDockerRuntime(socket string) *dockerRuntime {
+	return &dockerRuntime{
+		client: &http.Client{
+			Timeout: containerTimeout,
+			Transport: &http.Transport{
+				Dial: func(_, _ string) (net.Conn, error) {
+					return net.DialTimeout("unix", socket, containerTimeout)
+				},
+			},
+		},
+	}
+}
+
+func (d *dockerRuntime) inspect(id string) (*container, error) {
+	rsp, err := d.client.Get("http://docker/containers/" + url.QueryEscape(id) + "/json")
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("inspecting container: %s", rsp.Status)
+	}
+	var body struct {
+		Name   string
+		Config struct {
+			Image  string
+			Labels map[string]string
+		}
+	}
+	if err := json.NewDecoder(rsp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return &container{
+		Name:   strings.TrimPrefix(body.Name, "/"),
+		Image:  body.Config.Image,
+		Labels: body.Config.Labels,
+	}, nil
+}
@@ -0,0 +1,171 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/http2"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+)
+
+func TestContainerEnricher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "container-enricher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "docker.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inspected := 0
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inspected++
+		if r.URL.Path != "/containers/4f66ad9a0b2e/json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"Id": "4f66ad9a0b2e", "Name": "/web", "Config": {"Image": "nginx:1.11", "Labels": {"app": "shop"}}}`)
+	}))
+	ts.Listener = ln
+	ts.Start()
+	defer ts.Close()
+
+	Convey("Given a container enricher", t, func() {
+		inspected = 0
+		e := newContainerEnricher(newDockerRuntime(socket), "container_id")
+		collect := func() []core.Metric {
+			return []core.Metric{
+				plugin.PluginMetricType{Namespace_: []string{"foo", "bar"}},
+				plugin.PluginMetricType{
+					Namespace_: []string{"foo", "baz"},
+					Tags_:      map[string]string{"container_id": "4f66ad9a0b2e", "container_label_app": "cart"},
+				},
+				plugin.PluginMetricType{
+					Namespace_: []string{"foo", "qux"},
+					Tags_:      map[string]string{"container_id": "gone"},
+				},
+			}
+		}
+		first := collect()
+		e.enrich(first)
+		Convey("the metrics are not held up by the lookups", func() {
+			So(first[1].Tags(), ShouldResemble, map[string]string{"container_id": "4f66ad9a0b2e", "container_label_app": "cart"})
+		})
+		e.lookups.Wait()
+		mts := collect()
+		e.enrich(mts)
+		Convey("the metrics of a container are tagged with its name, image and labels", func() {
+			So(mts[1].Tags(), ShouldResemble, map[string]string{
+				"container_id":        "4f66ad9a0b2e",
+				"container_name":      "web",
+				"container_image":     "nginx:1.11",
+				"container_label_app": "cart",
+			})
+		})
+		Convey("the other metrics are left as they are", func() {
+			So(mts[0].Tags(), ShouldBeEmpty)
+			So(mts[2].Tags(), ShouldResemble, map[string]string{"container_id": "gone"})
+		})
+		Convey("the containers are looked up once", func() {
+			e.enrich(collect())
+			e.lookups.Wait()
+			So(inspected, ShouldEqual, 2)
+		})
+	})
+}
+
+func TestContainerdRuntime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "containerd-runtime")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "containerd.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	var namespace string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		namespace = r.Header.Get("containerd-namespace")
+		b, _ := ioutil.ReadAll(r.Body)
+		var id string
+		if r.URL.Path == containerdGetContainer && len(b) > 5 {
+			protoFields(b[5:], func(num int, v []byte) error {
+				id = string(v)
+				return nil
+			})
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+		if id != "4f66ad9a0b2e" {
+			w.Header().Set("Grpc-Status", "5")
+			w.Header().Set("Grpc-Message", "container not found")
+			return
+		}
+		labels := append(protoString(1, "io.kubernetes.container.name"), protoString(2, "web")...)
+		ctr := append(protoString(1, id), protoString(2, string(labels))...)
+		ctr = append(ctr, protoString(3, "docker.io/library/nginx:1.11")...)
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Write(grpcFrame(protoString(1, string(ctr))))
+		w.Header().Set("Grpc-Status", "0")
+	})
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go (&http2.Server{}).ServeConn(conn, &http2.ServeConnOpts{Handler: handler})
+		}
+	}()
+
+	Convey("Given the containerd runtime", t, func() {
+		r := newContainerdRuntime(socket, "k8s.io")
+		Convey("a container is looked up in the namespace", func() {
+			c, err := r.inspect("4f66ad9a0b2e")
+			So(err, ShouldBeNil)
+			So(namespace, ShouldEqual, "k8s.io")
+			So(c, ShouldResemble, &container{
+				Name:   "web",
+				Image:  "docker.io/library/nginx:1.11",
+				Labels: map[string]string{"io.kubernetes.container.name": "web"},
+			})
+		})
+		Convey("an unknown container is an error", func() {
+			_, err := r.inspect("gone")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "container not found")
+		})
+	})
+}
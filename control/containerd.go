@@ -0,0 +1,223 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/http2"
+)
+
+const (
+	// the method of the containerd gRPC API returning a container
+	containerdGetContainer = "/containerd.services.containers.v1.Containers/Get"
+	// containerdMaxResponse bounds the response read for a container, its
+	// OCI spec included
+	containerdMaxResponse = 4 << 20
+)
+
+// The labels naming the containers of containerd, which have no name
+var containerdNameLabels = []string{
+	"io.kubernetes.container.name",
+	"nerdctl/name",
+}
+
+var errContainerdMessage = errors.New("malformed containerd message")
+
+// containerdRuntime looks the containers up through the containers service
+// of the containerd gRPC API, in a containerd namespace. The calls are made
+// over HTTP/2 on the socket, decoding only the fields of the messages used.
+type containerdRuntime struct {
+	namespace string
+	client    *http.Client
+}
+
+func newContainerdRuntime(socket, namespace string) *containerdRuntime {
+	return &containerdRuntime{
+		namespace: namespace,
+		client: &http.Client{
+			Timeout: containerTimeout,
+			// gRPC is served without TLS on the socket
+			Transport: &http2.Transport{
+				AllowHTTP: true,
+				DialTLS: func(_, _ string, _ *tls.Config) (net.Conn, error) {
+					return net.DialTimeout("unix", socket, containerTimeout)
+				},
+			},
+		},
+	}
+}
+
+func (c *containerdRuntime) inspect(id string) (*container, error) {
+	// GetContainerRequest{id: 1}
+	req, err := http.NewRequest("POST", "http://containerd"+containerdGetContainer, bytes.NewReader(grpcFrame(protoString(1, id))))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	req.Header.Set("containerd-namespace", c.namespace)
+	rsp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("getting container: %s", rsp.Status)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(rsp.Body, containerdMaxResponse))
+	if err != nil {
+		return nil, err
+	}
+	// the status is in the trailers once the body is read, in the headers
+	// of a response without body
+	status, msg := rsp.Trailer.Get("Grpc-Status"), rsp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status, msg = rsp.Header.Get("Grpc-Status"), rsp.Header.Get("Grpc-Message")
+	}
+	if status != "0" {
+		return nil, fmt.Errorf("getting container: grpc status %s: %s", status, msg)
+	}
+	if len(b) < 5 || int(binary.BigEndian.Uint32(b[1:5])) != len(b)-5 {
+		return nil, errContainerdMessage
+	}
+	// GetContainerResponse{container: 1}
+	var ctr *container
+	err = protoFields(b[5:], func(num int, v []byte) error {
+		if num == 1 {
+			ctr, err = decodeContainerdContainer(v)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if ctr == nil {
+		return nil, errContainerdMessage
+	}
+	return ctr, nil
+}
+
+// decodeContainerdContainer decodes Container{labels: 2, image: 3}
+func decodeContainerdContainer(b []byte) (*container, error) {
+	ctr := &container{Labels: make(map[string]string)}
+	err := protoFields(b, func(num int, v []byte) error {
+		switch num {
+		case 2:
+			// map<string, string> entries are {key: 1, value: 2}
+			var key, value string
+			err := protoFields(v, func(num int, v []byte) error {
+				switch num {
+				case 1:
+					key = string(v)
+				case 2:
+					value = string(v)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			ctr.Labels[key] = value
+		case 3:
+			ctr.Image = string(v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range containerdNameLabels {
+		if name, ok := ctr.Labels[l]; ok {
+			ctr.Name = name
+			break
+		}
+	}
+	return ctr, nil
+}
+
+// grpcFrame frames an uncompressed gRPC message
+func grpcFrame(msg []byte) []byte {
+	b := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(b[1:], uint32(len(msg)))
+	return append(b, msg...)
+}
+
+// protoString encodes a string field of a protobuf message
+func protoString(num int, s string) []byte {
+	b := make([]byte, 0, 2*binary.MaxVarintLen64+len(s))
+	b = appendVarint(b, uint64(num)<<3|2)
+	b = appendVarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return append(b, buf[:binary.PutUvarint(buf, v)]...)
+}
+
+// protoFields calls f with the number and the bytes of each length delimited
+// field of a protobuf message, skipping the fields of the other wire types
+func protoFields(b []byte, f func(num int, v []byte) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errContainerdMessage
+		}
+		b = b[n:]
+		switch key & 7 {
+		case 0:
+			if _, n = binary.Uvarint(b); n <= 0 {
+				return errContainerdMessage
+			}
+			b = b[n:]
+		case 1:
+			if len(b) < 8 {
+				return errContainerdMessage
+			}
+			b = b[8:]
+		case 2:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || l > uint64(len(b)-n) {
+				return errContainerdMessage
+			}
+			if err := f(int(key>>3), b[n:n+int(l)]); err != nil {
+				return err
+			}
+			b = b[n+int(l):]
+		case 5:
+			if len(b) < 4 {
+				return errContainerdMessage
+			}
+			b = b[4:]
+		default:
+			return errContainerdMessage
+		}
+	}
+	return nil
+}
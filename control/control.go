@@ -27,6 +27,9 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -34,13 +37,16 @@ import (
 	"github.com/intelsdi-x/gomit"
 
 	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/client"
 	"github.com/intelsdi-x/snap/control/strategy"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/control_event"
 	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/core/scheduler_event"
 	"github.com/intelsdi-x/snap/core/serror"
 	"github.com/intelsdi-x/snap/pkg/aci"
+	"github.com/intelsdi-x/snap/pkg/datadir"
 	"github.com/intelsdi-x/snap/pkg/psigning"
 )
 
@@ -80,9 +86,31 @@ type pluginControl struct {
 	metricCatalog  catalogsMetrics
 	pluginRunner   runsPlugins
 	signingManager managesSigning
+	refresher      *metricRefresher
+	refreshes      *metricRefreshes
+	sweeper        *metricRefresher
+	remoteSweeper  *metricRefresher
+	recorder       *responseRecorder
+	replayer       *responseReplayer
 
 	pluginTrust  int
 	keyringFiles []string
+	snapdVersion string
+	// tags and host are added to every collected metric
+	tags map[string]string
+	host core.Host
+	// containers adds the tags of their container to the metrics tagged
+	// with a container ID, when enabled
+	containers *containerEnricher
+	// remote holds the subscriptions of the tasks of other snapd instances
+	// offloading process steps to the processors of this snapd
+	remote *remoteSubscriptions
+	// tenants holds the scopes of the tenants and the tasks scoped to them
+	tenants *tenantScopes
+	// transports are the transport settings of the plugins started
+	transports *transportSettings
+	// output holds the files the output of the plugins is logged to
+	output *plugin.Output
 }
 
 type runsPlugins interface {
@@ -105,22 +133,41 @@ type managesPlugins interface {
 	UnloadPlugin(core.Plugin) (*loadedPlugin, serror.SnapError)
 	SetMetricCatalog(catalogsMetrics)
 	GenerateArgs(pluginPath string) plugin.Arg
+	newExecutablePlugin(*pluginDetails) (*plugin.ExecutablePlugin, error)
 	SetPluginConfig(*pluginConfig)
+	SetDataDir(*datadir.DataDir)
 }
 
 type catalogsMetrics interface {
 	Get([]string, int) (*metricType, error)
+	Resolve([]string, int) (*metricType, error)
+	SetVersionFallback(string)
+	SetMetricLimits(int, int)
+	AddAlias(string, string) error
+	RemoveAlias(string) error
+	Aliases() map[string]string
+	Unalias([]string) []string
+	AddComputed(string, string) error
+	Computed([]string) *core.ComputedMetric
+	ComputedMetrics([]string) []core.CatalogedMetric
+	CheckMetricLimits(*loadedPlugin, int) error
 	GetQueriedNamespaces([]string) ([][]string, error)
 	MatchQuery([]string) ([][]string, error)
 	Add(*metricType)
 	AddLoadedMetricType(*loadedPlugin, core.Metric) error
 	RmUnloadedPluginMetrics(lp *loadedPlugin)
+	RefreshPluginMetrics(*loadedPlugin, []core.Metric) (int, int, error)
+	RemoveStaleMetrics(time.Time) []*metricType
+	UpdatePluginMetrics(*loadedPlugin, []core.Metric, []core.Metric) ([]string, []string, error)
 	GetVersions([]string) ([]*metricType, error)
 	Fetch([]string) ([]*metricType, error)
+	Query(*core.MetricQuery) []*metricType
 	Item() (string, []*metricType)
 	Next() bool
-	Subscribe([]string, int) error
-	Unsubscribe([]string, int) error
+	Subscribe([]string, int, string) error
+	Unsubscribe([]string, int, string) error
+	Subscribers([]string, int) ([]string, error)
+	UnsubscribeTask(string)
 	GetPlugin([]string, int) (*loadedPlugin, error)
 }
 
@@ -145,11 +192,93 @@ func CacheExpiration(t time.Duration) PluginControlOpt {
 	}
 }
 
+// PluginRPCFailures sets how many failed calls to a running plugin within
+// the window get it killed and restarted, never when limit is 0
+func PluginRPCFailures(limit int, window time.Duration) PluginControlOpt {
+	return func(c *pluginControl) {
+		c.pluginRunner.AvailablePlugins().setRPCFailureLimit(limit, window)
+	}
+}
+
+// PluginCrashPath sets the directory the crash reports of the plugins are
+// written in, none when empty
+func PluginCrashPath(path string) PluginControlOpt {
+	return func(c *pluginControl) {
+		c.pluginRunner.AvailablePlugins().setCrashPath(path)
+	}
+}
+
+// PluginOutput sets the size in megabytes the output files of the plugins
+// are rotated at, never when 0, how many rotated files are kept and whether
+// the output is also logged by snapd
+func PluginOutput(maxSize, maxFiles int, inline bool) PluginControlOpt {
+	return func(c *pluginControl) {
+		c.output.Configure(int64(maxSize)<<20, maxFiles, inline)
+	}
+}
+
+// PluginChunkSize sets the size in bytes of the chunks the metric batches
+// larger than it are transferred to and from the plugins in, never when 0
+func PluginChunkSize(size int) PluginControlOpt {
+	return func(c *pluginControl) {
+		c.transports.setChunkSize(size)
+	}
+}
+
+// PluginConnections sets the period of the TCP keep-alives sent on the
+// connections to the plugins, how long and how many idle HTTP connections
+// are kept open to each and the longest wait between the attempts to
+// connect again to a plugin
+func PluginConnections(keepAlive, idleTimeout time.Duration, maxIdle int, maxBackoff time.Duration) PluginControlOpt {
+	return func(c *pluginControl) {
+		c.transports.setConn(client.ConnSettings{
+			KeepAlive:           keepAlive,
+			IdleTimeout:         idleTimeout,
+			MaxIdleConns:        maxIdle,
+			MaxReconnectBackoff: maxBackoff,
+		})
+	}
+}
+
+// PluginTransportSettings sets the max size of the metric batches exchanged
+// with the plugins, none when 0, and the compression of the messages, none
+// when empty, the settings of a plugin given by <type>:<name> taking
+// precedence
+func PluginTransportSettings(maxMessageSize int, compression string, plugins PluginTransports) PluginControlOpt {
+	return func(c *pluginControl) {
+		c.transports.set(PluginTransport{MaxMessageSize: maxMessageSize, Compression: compression}, plugins)
+	}
+}
+
+// ParallelCollect sets the least number of metrics for each instance of a
+// pool the metrics a task collects are split across, never when 0, and the
+// name of the split strategy, the default one when empty
+func ParallelCollect(minMetrics int, split string) PluginControlOpt {
+	return func(c *pluginControl) {
+		if split == "" {
+			split = strategy.DefaultSplitter
+		}
+		splitter, err := strategy.GetSplitter(split)
+		if err != nil {
+			controlLogger.WithFields(log.Fields{
+				"_block": "parallel-collect",
+				"error":  err,
+			}).Error("metrics are not collected in parallel")
+			return
+		}
+		c.pluginRunner.AvailablePlugins().setParallelCollect(minMetrics, splitter)
+	}
+}
+
 // OptSetConfig sets the plugin control configuration.
 func OptSetConfig(cfg *Config) PluginControlOpt {
 	return func(c *pluginControl) {
 		c.Config = cfg
 		c.pluginManager.SetPluginConfig(cfg.Plugins)
+		c.metricCatalog.SetVersionFallback(cfg.VersionFallback)
+		c.metricCatalog.SetMetricLimits(cfg.MaxCatalogMetrics, cfg.MaxPluginMetrics)
+		setAliases(c.metricCatalog, cfg.Aliases)
+		setComputed(c.metricCatalog, cfg.ComputedMetrics)
 	}
 }
 
@@ -159,10 +288,22 @@ func New(cfg *Config) *pluginControl {
 	opts := []PluginControlOpt{
 		MaxRunningPlugins(cfg.MaxRunningPlugins),
 		CacheExpiration(cfg.CacheExpiration.Duration),
+		PluginRPCFailures(cfg.PluginRPCFailureLimit, cfg.PluginRPCFailureWindow.Duration),
+		PluginCrashPath(cfg.CrashPath),
+		PluginOutput(cfg.PluginLogMaxSize, cfg.PluginLogMaxFiles, cfg.PluginLogInline),
+		PluginChunkSize(cfg.PluginChunkSize),
+		PluginConnections(cfg.PluginConnKeepAlive.Duration, cfg.PluginConnIdleTimeout.Duration, cfg.PluginConnMaxIdle, cfg.PluginReconnectBackoff.Duration),
+		PluginTransportSettings(cfg.PluginMaxMessageSize, cfg.PluginCompression, cfg.PluginTransport),
+		ParallelCollect(cfg.ParallelCollectMin, cfg.ParallelCollectSplit),
 		OptSetConfig(cfg),
 	}
 	c := &pluginControl{}
 	c.Config = cfg
+	c.remote = newRemoteSubscriptions()
+	c.tenants = newTenantScopes(cfg.Tenants)
+	c.refreshes = newMetricRefreshes()
+	c.transports = newTransportSettings()
+	c.output = plugin.NewOutput(plugin.DefaultOutputMaxSize, plugin.DefaultOutputMaxFiles, false)
 	// Initialize components
 	//
 	// Event Manager
@@ -179,7 +320,7 @@ func New(cfg *Config) *pluginControl {
 	}).Debug("metric catalog created")
 
 	// Plugin Manager
-	c.pluginManager = newPluginManager()
+	c.pluginManager = newPluginManager(optSetTransports(c.transports), optSetOutput(c.output))
 	controlLogger.WithFields(log.Fields{
 		"_block": "new",
 	}).Debug("plugin manager created")
@@ -194,6 +335,7 @@ func New(cfg *Config) *pluginControl {
 
 	// Plugin Runner
 	c.pluginRunner = newRunner()
+	c.pluginRunner.AvailablePlugins().setTransports(c.transports)
 	controlLogger.WithFields(log.Fields{
 		"_block": "new",
 	}).Debug("runner created")
@@ -230,15 +372,78 @@ func (p *pluginControl) RegisterEventHandler(name string, h gomit.Handler) error
 // Begin handling load, unload, and inventory
 func (p *pluginControl) Start() error {
 	// Start pluginManager when pluginControl starts
+	if p.Config.ReplayPath != "" {
+		r, err := newResponseReplayer(p.Config.ReplayPath)
+		if err != nil {
+			return err
+		}
+		p.replayer = r
+		controlLogger.WithFields(log.Fields{
+			"_block":      "start",
+			"replay-path": p.Config.ReplayPath,
+		}).Warn("replaying recorded plugin responses instead of calling collectors and processors")
+	} else if p.Config.RecordPath != "" {
+		r, err := newResponseRecorder(p.Config.RecordPath)
+		if err != nil {
+			return err
+		}
+		p.recorder = r
+		controlLogger.WithFields(log.Fields{
+			"_block":      "start",
+			"record-path": p.Config.RecordPath,
+		}).Info("recording plugin responses")
+	}
+	p.tags = resolveTags(p.Config.Tags)
+	addDownwardAPITags(p.tags)
+	if p.Config.ContainerSocket != "" {
+		runtime, err := newContainerRuntime(p.Config.ContainerRuntime, p.Config.ContainerSocket, p.Config.ContainerNamespace)
+		if err != nil {
+			return err
+		}
+		p.containers = newContainerEnricher(runtime, p.Config.ContainerIDTag)
+		controlLogger.WithFields(log.Fields{
+			"_block":            "start",
+			"container-runtime": p.Config.ContainerRuntime,
+			"container-socket":  p.Config.ContainerSocket,
+			"container-id-tag":  p.Config.ContainerIDTag,
+		}).Info("enriching the metrics of containers")
+	}
 	p.Started = true
+	p.refresher = newMetricRefresher(metricRefreshTick, func() { p.refreshMetricTypes(time.Now()) })
+	p.refresher.Start()
+	ttl := p.Config.MetricTTL.Duration
+	p.sweeper = newMetricRefresher(ttl/2, func() { p.sweepMetricTypes(ttl) })
+	p.sweeper.Start()
+	p.remoteSweeper = newMetricRefresher(remoteSubscriptionTTL/2, func() { p.sweepRemoteSubscriptions(remoteSubscriptionTTL) })
+	p.remoteSweeper.Start()
 	controlLogger.WithFields(log.Fields{
 		"_block": "start",
 	}).Info("control started")
 	return nil
 }
 
+// Running returns whether control is started
+func (p *pluginControl) Running() bool {
+	return p.Started
+}
+
 func (p *pluginControl) Stop() {
 	p.Started = false
+	if p.refresher != nil {
+		p.refresher.Stop()
+	}
+	if p.sweeper != nil {
+		p.sweeper.Stop()
+	}
+	if p.remoteSweeper != nil {
+		p.remoteSweeper.Stop()
+	}
+	if p.recorder != nil {
+		if err := p.recorder.Close(); err != nil {
+			controlLogger.Error(err)
+		}
+		p.recorder = nil
+	}
 	controlLogger.WithFields(log.Fields{
 		"_block": "stop",
 	}).Info("control stopped")
@@ -257,6 +462,12 @@ func (p *pluginControl) Stop() {
 
 	// unload plugins
 	p.pluginManager.teardown()
+
+	if p.output != nil {
+		if err := p.output.Close(); err != nil {
+			controlLogger.Error(err)
+		}
+	}
 }
 
 // Load is the public method to load a plugin into
@@ -288,6 +499,15 @@ func (p *pluginControl) Load(rp *core.RequestedPlugin) (core.CatalogedPlugin, se
 		return nil, se
 	}
 
+	// Plugins whose dependencies are not met are unloaded straight away
+	if se := p.checkDependencies(pl); se != nil {
+		controlLogger.WithFields(f).Error(se)
+		if _, ue := p.pluginManager.UnloadPlugin(pl); ue != nil {
+			controlLogger.WithFields(f).Error(ue)
+		}
+		return nil, se
+	}
+
 	// If plugin was loaded from a package, remove ExecPath for
 	// the temporary plugin that was used for load
 	if pl.Details.IsPackage {
@@ -373,6 +593,11 @@ func (p *pluginControl) returnPluginDetails(rp *core.RequestedPlugin) (*pluginDe
 }
 
 func (p *pluginControl) Unload(pl core.Plugin) (core.CatalogedPlugin, serror.SnapError) {
+	if tasks := p.DependentTasks(pl); len(tasks) > 0 {
+		return nil, serror.New(core.ErrPluginInUse, map[string]interface{}{
+			"tasks": strings.Join(tasks, ","),
+		})
+	}
 	up, err := p.pluginManager.UnloadPlugin(pl)
 	if err != nil {
 		return nil, err
@@ -387,6 +612,32 @@ func (p *pluginControl) Unload(pl core.Plugin) (core.CatalogedPlugin, serror.Sna
 	return up, nil
 }
 
+// DependentTasks returns the IDs of the running tasks which would fail if the
+// plugin was unloaded. Subscriptions to the latest version of a plugin move to
+// another loaded version, so those tasks only depend on the plugin when it is
+// the last loaded version. The remote tasks offloading process steps to the
+// plugin are not tasks of this snapd and are left out.
+func (p *pluginControl) DependentTasks(pl core.Plugin) []string {
+	pool, err := p.pluginRunner.AvailablePlugins().getPool(fmt.Sprintf("%s:%s:%d", pl.TypeName(), pl.Name(), pl.Version()))
+	if err != nil || pool == nil {
+		return nil
+	}
+	subs := pool.Subscribers()
+	for _, lp := range p.pluginManager.all() {
+		if lp.TypeName() == pl.TypeName() && lp.Name() == pl.Name() && lp.Version() != pl.Version() {
+			subs = pool.BoundSubscribers()
+			break
+		}
+	}
+	tasks := []string{}
+	for _, id := range subs {
+		if !isRemoteSubscriber(id) {
+			tasks = append(tasks, id)
+		}
+	}
+	return tasks
+}
+
 func (p *pluginControl) SwapPlugins(in *core.RequestedPlugin, out core.CatalogedPlugin) serror.SnapError {
 	details, serr := p.returnPluginDetails(in)
 	if serr != nil {
@@ -422,6 +673,12 @@ func (p *pluginControl) SwapPlugins(in *core.RequestedPlugin, out core.Cataloged
 		return serr
 	}
 
+	// tasks pinning the outgoing version may be moved to another version
+	var pinned []string
+	if pool, err := p.pluginRunner.AvailablePlugins().getPool(fmt.Sprintf("%s:%s:%d", out.TypeName(), out.Name(), out.Version())); err == nil && pool != nil {
+		pinned = pool.BoundSubscribers()
+	}
+
 	up, err := p.pluginManager.UnloadPlugin(out)
 	if err != nil {
 		_, err2 := p.pluginManager.UnloadPlugin(lp)
@@ -436,6 +693,10 @@ func (p *pluginControl) SwapPlugins(in *core.RequestedPlugin, out core.Cataloged
 		return err
 	}
 
+	if len(pinned) > 0 {
+		p.substituteVersion(pinned, out)
+	}
+
 	event := &control_event.SwapPluginsEvent{
 		LoadedPluginName:      lp.Meta.Name,
 		LoadedPluginVersion:   lp.Meta.Version,
@@ -471,6 +732,16 @@ func (p *pluginControl) ExpandWildcards(ns []string) ([][]string, serror.SnapErr
 
 func (p *pluginControl) ValidateDeps(mts []core.Metric, plugins []core.SubscribedPlugin) []serror.SnapError {
 	var serrs []serror.SnapError
+	mts, _ = expandComputed(p.metricCatalog, mts)
+	if max := p.Config.MaxTaskMetrics; max > 0 && len(mts) > max {
+		err := &metricLimitError{limit: "max_task_metrics", max: max, count: len(mts)}
+		controlLogger.WithFields(log.Fields{
+			"_block": "validate-deps",
+			"error":  err.Error(),
+		}).Error("metric limit exceeded")
+		p.eventManager.Emit(err.event())
+		return []serror.SnapError{serror.New(err)}
+	}
 	for _, mt := range mts {
 		errs := p.validateMetricTypeSubscription(mt, mt.Config())
 		if len(errs) > 0 {
@@ -516,6 +787,11 @@ func (p *pluginControl) validatePluginSubscription(pl core.SubscribedPlugin) []s
 		return serrs
 	}
 
+	if se := p.checkDependencies(lp); se != nil {
+		serrs = append(serrs, se)
+		return serrs
+	}
+
 	if lp.ConfigPolicy != nil {
 		ncd := lp.ConfigPolicy.Get([]string{""})
 		_, errs := ncd.Process(pl.Config().Table())
@@ -538,7 +814,7 @@ func (p *pluginControl) validateMetricTypeSubscription(mt core.RequestedMetric,
 		"version":   mt.Version(),
 	}).Info("subscription called on metric")
 
-	m, err := p.metricCatalog.Get(mt.Namespace(), mt.Version())
+	m, err := resolveMetric(p.metricCatalog, mt)
 
 	if err != nil {
 		serrs = append(serrs, serror.New(err, map[string]interface{}{
@@ -554,6 +830,11 @@ func (p *pluginControl) validateMetricTypeSubscription(mt core.RequestedMetric,
 		return serrs
 	}
 
+	if se := p.checkDependencies(m.Plugin); se != nil {
+		serrs = append(serrs, se)
+		return serrs
+	}
+
 	m.config = cd
 
 	typ, serr := core.ToPluginType(m.Plugin.TypeName())
@@ -561,9 +842,9 @@ func (p *pluginControl) validateMetricTypeSubscription(mt core.RequestedMetric,
 		return []serror.SnapError{serror.New(err)}
 	}
 
-	// merge global plugin config
+	// merge global plugin config, the config of the task takes precedence
 	if m.config != nil {
-		m.config.Merge(p.Config.Plugins.getPluginConfigDataNode(typ, m.Plugin.Name(), m.Plugin.Version()))
+		m.config.ReverseMerge(p.Config.Plugins.getPluginConfigDataNode(typ, m.Plugin.Name(), m.Plugin.Version()))
 	} else {
 		m.config = p.Config.Plugins.getPluginConfigDataNode(typ, m.Plugin.Name(), m.Plugin.Version())
 	}
@@ -608,7 +889,7 @@ func (p *pluginControl) gatherCollectors(mts []core.Metric) ([]gatheredPlugin, [
 	for _, mt := range mts {
 		// If the version provided is <1 we will get the latest
 		// plugin for the given metric.
-		m, err := p.metricCatalog.Get(mt.Namespace(), mt.Version())
+		m, err := resolveMetric(p.metricCatalog, mt)
 		if err != nil {
 			serrs = append(serrs, serror.New(err, map[string]interface{}{
 				"name":    core.JoinNamespace(mt.Namespace()),
@@ -641,6 +922,7 @@ func (p *pluginControl) gatherCollectors(mts []core.Metric) ([]gatheredPlugin, [
 
 func (p *pluginControl) SubscribeDeps(taskID string, mts []core.Metric, plugins []core.Plugin) []serror.SnapError {
 	var serrs []serror.SnapError
+	mts, _ = expandComputed(p.metricCatalog, mts)
 	collectors, errs := p.gatherCollectors(mts)
 	if len(errs) > 0 {
 		serrs = append(serrs)
@@ -729,10 +1011,113 @@ func (p *pluginControl) SubscribeDeps(taskID string, mts []core.Metric, plugins
 			serrs = append(serrs, serr)
 		}
 	}
+	substituted := make(map[string]bool)
+	for _, mt := range mts {
+		// metrics missing from the catalog were reported by gatherCollectors
+		m, err := resolveMetric(p.metricCatalog, mt)
+		if err != nil {
+			continue
+		}
+		p.metricCatalog.Subscribe(mt.Namespace(), m.Version(), taskID)
+		key := fmt.Sprintf("%s:%d", m.Plugin.Key(), mt.Version())
+		if mt.Version() > 0 && m.Version() != mt.Version() && !substituted[key] {
+			substituted[key] = true
+			if serr := p.sendVersionSubstitutionEvent(taskID, m.Plugin, mt.Version()); serr != nil {
+				serrs = append(serrs, serr)
+			}
+		}
+	}
 
 	return serrs
 }
 
+// substituteVersion moves the tasks pinning the version of a collector which
+// was swapped out to the version picked by the version fallback policy. The
+// version of a processor or publisher is an exact pin, never substituted.
+func (p *pluginControl) substituteVersion(taskIDs []string, out core.Plugin) {
+	if out.TypeName() != core.CollectorPluginType.String() {
+		controlLogger.WithFields(log.Fields{
+			"_block":         "substitute-version",
+			"plugin-type":    out.TypeName(),
+			"plugin-name":    out.Name(),
+			"plugin-version": out.Version(),
+			"tasks":          strings.Join(taskIDs, ","),
+		}).Warn("pinned plugin version swapped out, the tasks pinning it fail until it is loaded again")
+		return
+	}
+	var loaded []int
+	for _, lp := range p.pluginManager.all() {
+		if lp.TypeName() == out.TypeName() && lp.Name() == out.Name() {
+			loaded = append(loaded, lp.Version())
+		}
+	}
+	ver, ok := fallbackVersion(p.Config.VersionFallback, out.Version(), loaded)
+	if !ok {
+		controlLogger.WithFields(log.Fields{
+			"_block":         "substitute-version",
+			"plugin-name":    out.Name(),
+			"plugin-version": out.Version(),
+			"policy":         p.Config.VersionFallback,
+			"tasks":          strings.Join(taskIDs, ","),
+		}).Warn("pinned plugin version swapped out and no version substituted")
+		return
+	}
+	lp, err := p.pluginManager.get(fmt.Sprintf("%s:%s:%d", out.TypeName(), out.Name(), ver))
+	if err != nil {
+		return
+	}
+	pool, err := p.pluginRunner.AvailablePlugins().getOrCreatePool(lp.Key())
+	if err != nil {
+		return
+	}
+	for _, id := range taskIDs {
+		pool.Subscribe(id, strategy.BoundSubscriptionType)
+		p.sendVersionSubstitutionEvent(id, lp, out.Version())
+	}
+	if pool.Eligible() {
+		if err := p.verifyPlugin(lp); err != nil {
+			controlLogger.WithFields(log.Fields{
+				"_block": "substitute-version",
+				"error":  err,
+			}).Error("unable to verify substituted plugin")
+			return
+		}
+		if err := p.pluginRunner.runPlugin(lp.Details); err != nil {
+			controlLogger.WithFields(log.Fields{
+				"_block": "substitute-version",
+				"error":  err,
+			}).Error("unable to start substituted plugin")
+		}
+	}
+}
+
+func (p *pluginControl) sendVersionSubstitutionEvent(taskID string, pl core.Plugin, requested int) serror.SnapError {
+	pt, err := core.ToPluginType(pl.TypeName())
+	if err != nil {
+		return serror.New(err)
+	}
+	controlLogger.WithFields(log.Fields{
+		"_block":            "substitute-version",
+		"task-id":           taskID,
+		"plugin-name":       pl.Name(),
+		"requested-version": requested,
+		"plugin-version":    pl.Version(),
+		"policy":            p.Config.VersionFallback,
+	}).Info("substituting plugin version pinned by task")
+	e := &control_event.VersionSubstitutionEvent{
+		TaskId:           taskID,
+		PluginName:       pl.Name(),
+		PluginType:       int(pt),
+		RequestedVersion: requested,
+		Version:          pl.Version(),
+		Policy:           p.Config.VersionFallback,
+	}
+	if _, err := p.eventManager.Emit(e); err != nil {
+		return serror.New(err)
+	}
+	return nil
+}
+
 func (p *pluginControl) verifyPlugin(lp *loadedPlugin) error {
 	b, err := ioutil.ReadFile(lp.Details.Path)
 	if err != nil {
@@ -771,6 +1156,7 @@ func (p *pluginControl) sendPluginSubscriptionEvent(taskID string, pl core.Plugi
 
 func (p *pluginControl) UnsubscribeDeps(taskID string, mts []core.Metric, plugins []core.Plugin) []serror.SnapError {
 	var serrs []serror.SnapError
+	mts, _ = expandComputed(p.metricCatalog, mts)
 
 	collectors, errs := p.gatherCollectors(mts)
 	if len(errs) > 0 {
@@ -779,6 +1165,13 @@ func (p *pluginControl) UnsubscribeDeps(taskID string, mts []core.Metric, plugin
 	for _, gc := range collectors {
 		plugins = append(plugins, gc.plugin)
 	}
+	for _, mt := range mts {
+		ver := mt.Version()
+		if m, err := resolveMetric(p.metricCatalog, mt); err == nil && ver > 0 {
+			ver = m.Version()
+		}
+		p.metricCatalog.Unsubscribe(mt.Namespace(), ver, taskID)
+	}
 
 	for _, sub := range plugins {
 		pool, err := p.pluginRunner.AvailablePlugins().getPool(fmt.Sprintf("%s:%s:%d", sub.TypeName(), sub.Name(), sub.Version()))
@@ -798,6 +1191,84 @@ func (p *pluginControl) UnsubscribeDeps(taskID string, mts []core.Metric, plugin
 	return serrs
 }
 
+// MetricSubscribers returns the IDs of the tasks subscribed to the metric.
+// A version less than 1 returns the subscribers of the latest version.
+func (p *pluginControl) MetricSubscribers(ns []string, ver int) ([]string, error) {
+	return p.metricCatalog.Subscribers(ns, ver)
+}
+
+// TasksUsingMetric returns the IDs of the tasks subscribed to the metrics
+// matching ns, which may contain wildcards and tuples (/intel/cpu/*).
+func (p *pluginControl) TasksUsingMetric(ns string) ([]string, error) {
+	q, err := core.ParseMetricQuery("ns=" + strconv.Quote(ns))
+	if err != nil {
+		return nil, err
+	}
+	mts := p.metricCatalog.Query(q)
+	if len(mts) == 0 {
+		return nil, errorMetricNotFound(strings.Split(strings.Trim(ns, "/"), "/"))
+	}
+	ids := map[string]bool{}
+	for _, mt := range mts {
+		subs, err := p.metricCatalog.Subscribers(mt.Namespace(), mt.Version())
+		if err != nil {
+			// the metric was removed in the meantime
+			continue
+		}
+		for _, id := range subs {
+			ids[id] = true
+		}
+	}
+	return sortedKeys(ids), nil
+}
+
+// TasksUsingPlugin returns the IDs of the tasks subscribed to the plugin. An
+// empty type matches any plugin type and a version less than 1 any version.
+func (p *pluginControl) TasksUsingPlugin(pluginType, name string, ver int) []string {
+	ids := map[string]bool{}
+	for key, pool := range p.pluginRunner.AvailablePlugins().pools() {
+		// pools are keyed type:name:version
+		fields := strings.Split(key, ":")
+		if len(fields) != 3 || fields[1] != name {
+			continue
+		}
+		if pluginType != "" && fields[0] != pluginType {
+			continue
+		}
+		if ver > 0 && fields[2] != strconv.Itoa(ver) {
+			continue
+		}
+		for _, id := range pool.Subscribers() {
+			ids[id] = true
+		}
+	}
+	return sortedKeys(ids)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// HandleGomitEvent releases the metric and plugin subscriptions of deleted tasks
+func (p *pluginControl) HandleGomitEvent(e gomit.Event) {
+	switch v := e.Body.(type) {
+	case *scheduler_event.TaskDeletedEvent:
+		controlLogger.WithFields(log.Fields{
+			"_block":  "handle-events",
+			"task-id": v.TaskID,
+		}).Debug("releasing subscriptions of deleted task")
+		p.metricCatalog.UnsubscribeTask(v.TaskID)
+		for _, pool := range p.pluginRunner.AvailablePlugins().pools() {
+			pool.Unsubscribe(v.TaskID)
+		}
+	}
+}
+
 func (p *pluginControl) sendPluginUnsubscriptionEvent(taskID string, pl core.Plugin) serror.SnapError {
 	pt, err := core.ToPluginType(pl.TypeName())
 	if err != nil {
@@ -853,10 +1324,11 @@ func (p *pluginControl) MetricCatalog() ([]core.CatalogedMetric, error) {
 // NOTE: The returned data from this function should be considered constant and read only
 func (p *pluginControl) FetchMetrics(ns []string, version int) ([]core.CatalogedMetric, error) {
 	mts, err := p.metricCatalog.Fetch(ns)
-	if err != nil {
+	computed := p.metricCatalog.ComputedMetrics(ns)
+	if err != nil && len(computed) == 0 {
 		return nil, err
 	}
-	cmt := make([]core.CatalogedMetric, 0, len(mts))
+	cmt := make([]core.CatalogedMetric, 0, len(mts)+len(computed))
 	for _, mt := range mts {
 		if version > 0 {
 			if mt.version == version {
@@ -866,14 +1338,34 @@ func (p *pluginControl) FetchMetrics(ns []string, version int) ([]core.Cataloged
 			cmt = append(cmt, mt)
 		}
 	}
+	if version < 1 || version == computedVersion {
+		cmt = append(cmt, computed...)
+	}
+	return cmt, nil
+}
+
+// QueryMetrics returns the metrics of the catalog matching the query q
+// NOTE: The returned data from this function should be considered constant and read only
+func (p *pluginControl) QueryMetrics(q *core.MetricQuery) ([]core.CatalogedMetric, error) {
+	mts := p.metricCatalog.Query(q)
+	cmt := make([]core.CatalogedMetric, len(mts))
+	for i, mt := range mts {
+		cmt[i] = mt
+	}
 	return cmt, nil
 }
 
 func (p *pluginControl) GetMetric(ns []string, ver int) (core.CatalogedMetric, error) {
+	if cmt := computedMetric(p.metricCatalog, ns); cmt != nil && (ver < 1 || ver == computedVersion) {
+		return cmt, nil
+	}
 	return p.metricCatalog.Get(ns, ver)
 }
 
 func (p *pluginControl) GetMetricVersions(ns []string) ([]core.CatalogedMetric, error) {
+	if cmt := computedMetric(p.metricCatalog, ns); cmt != nil {
+		return []core.CatalogedMetric{cmt}, nil
+	}
 	mts, err := p.metricCatalog.GetVersions(ns)
 	if err != nil {
 		return nil, err
@@ -886,6 +1378,29 @@ func (p *pluginControl) GetMetricVersions(ns []string) ([]core.CatalogedMetric,
 	return rmts, nil
 }
 
+// ConfigLayers returns the config a metric gets from the defaults of the
+// plugin collecting it and from the global config, ordered from the lowest
+// precedence to the highest. The config of a task applies on top of them.
+func (p *pluginControl) ConfigLayers(mt core.RequestedMetric) ([]cdata.ConfigLayer, error) {
+	// computed metrics are not collected by a plugin
+	if computedMetric(p.metricCatalog, mt.Namespace()) != nil {
+		return nil, nil
+	}
+	m, err := resolveMetric(p.metricCatalog, mt)
+	if err != nil {
+		return nil, err
+	}
+	var layers []cdata.ConfigLayer
+	if m.policy != nil {
+		layers = append(layers, cdata.ConfigLayer{
+			Source: cdata.DefaultConfigSource,
+			Node:   cdata.FromTable(m.policy.Defaults()),
+		})
+	}
+	layers = append(layers, p.Config.Plugins.getPluginConfigLayers(core.CollectorPluginType, m.Plugin.Name(), m.Plugin.Version())...)
+	return layers, nil
+}
+
 func (p *pluginControl) MetricExists(mns []string, ver int) bool {
 	_, err := p.metricCatalog.Get(mns, ver)
 	if err == nil {
@@ -894,10 +1409,48 @@ func (p *pluginControl) MetricExists(mns []string, ver int) bool {
 	return false
 }
 
+// CollectorsReady returns nil once the running collectors the task is
+// subscribed to are ready to collect, or the reason one of them is not.
+func (p *pluginControl) CollectorsReady(taskID string) error {
+	for key, pool := range p.pluginRunner.AvailablePlugins().pools() {
+		if !strings.HasPrefix(key, core.CollectorPluginType.String()+":") || !hasSubscriber(pool, taskID) {
+			continue
+		}
+		pool.RLock()
+		aps := pool.Plugins()
+		pool.RUnlock()
+		for _, a := range aps {
+			ap, ok := a.(*availablePlugin)
+			if !ok {
+				continue
+			}
+			if err := ap.Ready(); err != nil {
+				return fmt.Errorf("%s is not ready: %v", ap.String(), err)
+			}
+		}
+	}
+	return nil
+}
+
+func hasSubscriber(pool strategy.Pool, taskID string) bool {
+	for _, id := range pool.Subscribers() {
+		if id == taskID {
+			return true
+		}
+	}
+	return false
+}
+
 // CollectMetrics is a blocking call to collector plugins returning a collection
 // of metrics and errors.  If an error is encountered no metrics will be
 // returned.
 func (p *pluginControl) CollectMetrics(metricTypes []core.Metric, deadline time.Time, taskID string) (metrics []core.Metric, errs []error) {
+	// the tasks of a tenant only collect the metrics the tenant sees
+	scope := p.tenants.taskScope(taskID)
+	metricTypes = visibleMetrics(scope, metricTypes)
+
+	// the computed metrics are computed from their operands once collected
+	metricTypes, computed := expandComputed(p.metricCatalog, metricTypes)
 
 	pluginToMetricMap, err := groupMetricTypesByPlugin(p.metricCatalog, metricTypes)
 	if err != nil {
@@ -911,23 +1464,37 @@ func (p *pluginControl) CollectMetrics(metricTypes []core.Metric, deadline time.
 
 	// For each available plugin call available plugin using RPC client and wait for response (goroutines)
 	for pluginKey, pmt := range pluginToMetricMap {
-		// merge global plugin config into the config for the metric
+		// merge the config of the tenant then the global plugin config into
+		// the config for the metric, the config of the task takes precedence
+		tenantCfg := p.tenantPluginConfig(taskID, core.CollectorPluginType, pmt.plugin.Name(), pmt.plugin.Version())
 		for _, mt := range pmt.metricTypes {
 			if mt.Config() != nil {
-				mt.Config().Merge(p.Config.Plugins.getPluginConfigDataNode(core.CollectorPluginType, pmt.plugin.Name(), pmt.plugin.Version()))
+				if tenantCfg != nil {
+					mt.Config().ReverseMerge(tenantCfg)
+				}
+				mt.Config().ReverseMerge(p.Config.Plugins.getPluginConfigDataNode(core.CollectorPluginType, pmt.plugin.Name(), pmt.plugin.Version()))
 			}
 		}
 
 		wg.Add(1)
 
-		go func(pluginKey string, mt []core.Metric) {
-			mts, err := p.pluginRunner.AvailablePlugins().collectMetrics(pluginKey, mt, taskID)
+		go func(pluginKey string, pmt pluginMetricTypes) {
+			mts, err := p.collectMetrics(pluginKey, pmt.metricTypes, taskID)
 			if err != nil {
 				cError <- err
 			} else {
-				cMetrics <- mts
+				markCollected(pmt, mts)
+				mts = unaliasMetrics(pmt, mts)
+				if p.containers != nil {
+					p.containers.enrich(mts)
+				}
+				addTags(mts, p.tags)
+				addHost(mts, p.host)
+				// a plugin may return metrics under a wildcard the
+				// tenant only partly sees
+				cMetrics <- visibleMetrics(scope, mts)
 			}
-		}(pluginKey, pmt.metricTypes)
+		}(pluginKey, pmt)
 	}
 
 	go func() {
@@ -951,6 +1518,7 @@ func (p *pluginControl) CollectMetrics(metricTypes []core.Metric, deadline time.
 	if len(errs) > 0 {
 		return nil, errs
 	}
+	metrics = computed.compute(metrics)
 	return
 }
 
@@ -961,6 +1529,12 @@ func (p *pluginControl) PublishMetrics(contentType string, content []byte, plugi
 	for k, v := range cfg {
 		config[k] = v
 	}
+	// then the config of the tenant of the task
+	if tenantCfg := p.tenantPluginConfig(taskID, core.PublisherPluginType, pluginName, pluginVersion); tenantCfg != nil {
+		for k, v := range tenantCfg.Table() {
+			config[k] = v
+		}
+	}
 	return p.pluginRunner.AvailablePlugins().publishMetrics(contentType, content, pluginName, pluginVersion, config, taskID)
 }
 
@@ -971,7 +1545,50 @@ func (p *pluginControl) ProcessMetrics(contentType string, content []byte, plugi
 	for k, v := range cfg {
 		config[k] = v
 	}
-	return p.pluginRunner.AvailablePlugins().processMetrics(contentType, content, pluginName, pluginVersion, config, taskID)
+	// then the config of the tenant of the task
+	if tenantCfg := p.tenantPluginConfig(taskID, core.ProcessorPluginType, pluginName, pluginVersion); tenantCfg != nil {
+		for k, v := range tenantCfg.Table() {
+			config[k] = v
+		}
+	}
+	if p.replayer == nil && p.recorder == nil {
+		return p.pluginRunner.AvailablePlugins().processMetrics(contentType, content, pluginName, pluginVersion, config, taskID)
+	}
+	key := fmt.Sprintf("%s:%s:%d", core.ProcessorPluginType.String(), pluginName, pluginVersion)
+	if p.replayer != nil {
+		return p.replayer.replayProcess(key)
+	}
+	ct, c, errs := p.pluginRunner.AvailablePlugins().processMetrics(contentType, content, pluginName, pluginVersion, config, taskID)
+	p.recorder.recordProcess(key, taskID, ct, c, errs)
+	return ct, c, errs
+}
+
+// collectMetrics collects the metrics from the plugin, or replays the
+// responses recorded for it
+func (p *pluginControl) collectMetrics(pluginKey string, mts []core.Metric, taskID string) ([]core.Metric, error) {
+	if p.replayer != nil {
+		return p.replayer.replayCollect(pluginKey)
+	}
+	ret, err := p.pluginRunner.AvailablePlugins().collectMetrics(pluginKey, mts, taskID)
+	if p.recorder != nil {
+		p.recorder.recordCollect(pluginKey, taskID, ret, err)
+	}
+	return ret, err
+}
+
+// markCollected records the cataloged metric types metrics were collected for
+// as seen
+func markCollected(pmt pluginMetricTypes, collected []core.Metric) {
+	now := time.Now()
+	nss := make(map[string]bool, len(collected))
+	for _, m := range collected {
+		nss[core.JoinNamespace(m.Namespace())] = true
+	}
+	for i, mt := range pmt.metricTypes {
+		if nss[core.JoinNamespace(mt.Namespace())] {
+			pmt.cataloged[i].seen(now)
+		}
+	}
 }
 
 // GetPluginContentTypes returns accepted and returned content types for the
@@ -1002,6 +1619,17 @@ func (p *pluginControl) SetKeyringFile(keyring string) {
 	p.keyringFiles = append(p.keyringFiles, keyring)
 }
 
+// SetHost sets the host added to every collected metric, which identifies
+// this snapd.
+func (p *pluginControl) SetHost(h core.Host) {
+	p.host = h
+}
+
+// SetDataDir sets the data directory used for plugin logs and uploaded plugins
+func (p *pluginControl) SetDataDir(d *datadir.DataDir) {
+	p.pluginManager.SetDataDir(d)
+}
+
 type requestedPlugin struct {
 	name    string
 	version int
@@ -1026,6 +1654,12 @@ func (r *requestedPlugin) Config() *cdata.ConfigDataNode {
 type pluginMetricTypes struct {
 	plugin      *loadedPlugin
 	metricTypes []core.Metric
+	// cataloged holds the cataloged metric type of each of metricTypes
+	cataloged []*metricType
+	// aliases holds the aliases requested by the key of the namespace they
+	// stand for, unaliased the keys of the namespaces requested as such
+	aliases   map[string][][]string
+	unaliased map[string]bool
 }
 
 func (p *pluginMetricTypes) Count() int {
@@ -1037,17 +1671,14 @@ func groupMetricTypesByPlugin(cat catalogsMetrics, metricTypes []core.Metric) (m
 	pmts := make(map[string]pluginMetricTypes)
 	// For each plugin type select a matching available plugin to call
 	for _, incomingmt := range metricTypes {
-		version := incomingmt.Version()
-		if version == 0 {
-			// If the version is not provided we will choose the latest
-			version = -1
-		}
-		catalogedmt, err := cat.Get(incomingmt.Namespace(), version)
+		// If the version is not provided we will choose the latest
+		catalogedmt, err := resolveMetric(cat, incomingmt)
 		if err != nil {
 			return nil, serror.New(err)
 		}
+		ns := cat.Unalias(incomingmt.Namespace())
 		returnedmt := plugin.PluginMetricType{
-			Namespace_:          incomingmt.Namespace(),
+			Namespace_:          ns,
 			LastAdvertisedTime_: catalogedmt.LastAdvertisedTime(),
 			Version_:            incomingmt.Version(),
 			Tags_:               catalogedmt.Tags(),
@@ -1062,6 +1693,16 @@ func groupMetricTypesByPlugin(cat catalogsMetrics, metricTypes []core.Metric) (m
 		pmt, _ := pmts[key]
 		pmt.plugin = lp
 		pmt.metricTypes = append(pmt.metricTypes, returnedmt)
+		pmt.cataloged = append(pmt.cataloged, catalogedmt)
+		if pmt.aliases == nil {
+			pmt.aliases = map[string][][]string{}
+			pmt.unaliased = map[string]bool{}
+		}
+		if nskey := getMetricKey(ns); nskey != getMetricKey(incomingmt.Namespace()) {
+			pmt.aliases[nskey] = append(pmt.aliases[nskey], incomingmt.Namespace())
+		} else {
+			pmt.unaliased[nskey] = true
+		}
 		pmts[key] = pmt
 	}
 	return pmts, nil
@@ -44,7 +44,9 @@ import (
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/control_event"
 	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/core/scheduler_event"
 	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/pkg/datadir"
 )
 
 // Mock Executor used to test
@@ -74,8 +76,13 @@ func (m *MockPluginManagerBadSwap) teardown()                         {}
 func (m *MockPluginManagerBadSwap) SetPluginConfig(*pluginConfig)     {}
 func (m *MockPluginManagerBadSwap) SetMetricCatalog(catalogsMetrics)  {}
 func (m *MockPluginManagerBadSwap) SetEmitter(gomit.Emitter)          {}
+func (m *MockPluginManagerBadSwap) SetDataDir(*datadir.DataDir)       {}
 func (m *MockPluginManagerBadSwap) GenerateArgs(string) plugin.Arg    { return plugin.Arg{} }
 
+func (m *MockPluginManagerBadSwap) newExecutablePlugin(*pluginDetails) (*plugin.ExecutablePlugin, error) {
+	return nil, nil
+}
+
 func (m *MockPluginManagerBadSwap) all() map[string]*loadedPlugin {
 	return m.loadedPlugins.table
 }
@@ -620,6 +627,10 @@ func (m *mc) Fetch(ns []string) ([]*metricType, error) {
 	return nil, nil
 }
 
+func (m *mc) Query(*core.MetricQuery) []*metricType {
+	return nil
+}
+
 func (m *mc) resolvePlugin(mns []string, ver int) (*loadedPlugin, error) {
 	return nil, nil
 }
@@ -641,23 +652,52 @@ func (m *mc) Get(ns []string, ver int) (*metricType, error) {
 	return nil, serror.New(errorMetricNotFound(ns))
 }
 
-func (m *mc) Subscribe(ns []string, ver int) error {
+func (m *mc) Resolve(ns []string, ver int) (*metricType, error) {
+	return m.Get(ns, ver)
+}
+
+func (m *mc) SetVersionFallback(string) {}
+
+func (m *mc) SetMetricLimits(int, int) {}
+
+func (m *mc) AddAlias(string, string) error { return nil }
+
+func (m *mc) RemoveAlias(string) error { return nil }
+
+func (m *mc) Aliases() map[string]string { return nil }
+
+func (m *mc) Unalias(ns []string) []string { return ns }
+
+func (m *mc) AddComputed(string, string) error { return nil }
+
+func (m *mc) Computed([]string) *core.ComputedMetric { return nil }
+
+func (m *mc) ComputedMetrics([]string) []core.CatalogedMetric { return nil }
+
+func (m *mc) CheckMetricLimits(*loadedPlugin, int) error {
+	return nil
+}
+
+func (m *mc) Subscribe(ns []string, ver int, taskID string) error {
 	if ns[0] == "nf" {
 		return serror.New(errorMetricNotFound(ns))
 	}
 	return nil
 }
 
-func (m *mc) Unsubscribe(ns []string, ver int) error {
+func (m *mc) Unsubscribe(ns []string, ver int, taskID string) error {
 	if ns[0] == "nf" {
 		return serror.New(errorMetricNotFound(ns))
 	}
-	if ns[0] == "neg" {
-		return errNegativeSubCount
-	}
 	return nil
 }
 
+func (m *mc) Subscribers([]string, int) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mc) UnsubscribeTask(string) {}
+
 func (m *mc) Add(*metricType)                 {}
 func (m *mc) Table() map[string][]*metricType { return map[string][]*metricType{} }
 func (m *mc) Item() (string, []*metricType)   { return "", []*metricType{} }
@@ -676,6 +716,18 @@ func (m *mc) RmUnloadedPluginMetrics(lp *loadedPlugin) {
 
 }
 
+func (m *mc) RefreshPluginMetrics(*loadedPlugin, []core.Metric) (int, int, error) {
+	return 0, 0, nil
+}
+
+func (m *mc) RemoveStaleMetrics(time.Time) []*metricType {
+	return nil
+}
+
+func (m *mc) UpdatePluginMetrics(*loadedPlugin, []core.Metric, []core.Metric) ([]string, []string, error) {
+	return nil, nil, nil
+}
+
 func (m *mc) GetQueriedNamespaces(ns []string) ([][]string, error) {
 	return [][]string{ns}, nil
 }
@@ -700,6 +752,10 @@ func (m *mockCDProc) HasRules() bool {
 	return true
 }
 
+func (m *mockCDProc) Defaults() map[string]ctypes.ConfigValue {
+	return map[string]ctypes.ConfigValue{}
+}
+
 // TODO move to metricCatalog
 // func TestResolvePlugin(t *testing.T) {
 // 	Convey(".resolvePlugin()", t, func() {
@@ -1750,3 +1806,268 @@ func TestMetricSubscriptionToOlderVersion(t *testing.T) {
 	})
 
 }
+
+func TestTaskDeletedReleasesSubscriptions(t *testing.T) {
+	Convey("Given a task subscribed to a metric and a plugin", t, func() {
+		c := New(GetDefaultConfig())
+		lp := &loadedPlugin{}
+		lp.Meta.Name = "foo"
+		lp.Meta.Version = 1
+		lp.Type = plugin.CollectorPluginType
+		c.metricCatalog.Add(newMetricType([]string{"intel", "foo"}, time.Now(), lp))
+		So(c.metricCatalog.Subscribe([]string{"intel", "foo"}, -1, "task1"), ShouldBeNil)
+		pool, err := c.pluginRunner.AvailablePlugins().getOrCreatePool(lp.Key())
+		So(err, ShouldBeNil)
+		pool.Subscribe("task1", strategy.UnboundSubscriptionType)
+		Convey("the task is found by metric and by plugin", func() {
+			ids, err := c.TasksUsingMetric("/intel/*")
+			So(err, ShouldBeNil)
+			So(ids, ShouldResemble, []string{"task1"})
+			_, err = c.TasksUsingMetric("/intel/bar")
+			So(err, ShouldNotBeNil)
+			So(c.TasksUsingPlugin("", "foo", 0), ShouldResemble, []string{"task1"})
+			So(c.TasksUsingPlugin("collector", "foo", 1), ShouldResemble, []string{"task1"})
+			So(c.TasksUsingPlugin("publisher", "foo", 0), ShouldBeEmpty)
+			So(c.TasksUsingPlugin("", "foo", 2), ShouldBeEmpty)
+		})
+		Convey("deleting the task releases its subscriptions", func() {
+			c.HandleGomitEvent(gomit.Event{Body: &scheduler_event.TaskDeletedEvent{TaskID: "task1"}})
+			subs, err := c.MetricSubscribers([]string{"intel", "foo"}, -1)
+			So(err, ShouldBeNil)
+			So(subs, ShouldBeEmpty)
+			So(pool.SubscriptionCount(), ShouldEqual, 0)
+		})
+	})
+}
+
+func TestUnloadPluginInUse(t *testing.T) {
+	Convey("Given a plugin tasks are subscribed to", t, func() {
+		c := New(GetDefaultConfig())
+		lp := &loadedPlugin{}
+		lp.Meta.Name = "foo"
+		lp.Meta.Version = 1
+		lp.Type = plugin.CollectorPluginType
+		pool, err := c.pluginRunner.AvailablePlugins().getOrCreatePool(lp.Key())
+		So(err, ShouldBeNil)
+		pool.Subscribe("bound", strategy.BoundSubscriptionType)
+		pool.Subscribe("unbound", strategy.UnboundSubscriptionType)
+		Convey("unloading it is refused with the dependent tasks", func() {
+			So(c.DependentTasks(lp), ShouldResemble, []string{"bound", "unbound"})
+			_, serr := c.Unload(lp)
+			So(serr, ShouldNotBeNil)
+			So(serr.Error(), ShouldEqual, core.ErrPluginInUse.Error())
+			So(serr.Fields()["tasks"], ShouldEqual, "bound,unbound")
+		})
+		Convey("tasks subscribed to the latest version do not depend on it when another version is loaded", func() {
+			lp2 := &loadedPlugin{}
+			lp2.Meta.Name = "foo"
+			lp2.Meta.Version = 2
+			lp2.Type = plugin.CollectorPluginType
+			So(c.pluginManager.(*pluginManager).loadedPlugins.add(lp2), ShouldBeNil)
+			So(c.DependentTasks(lp), ShouldResemble, []string{"bound"})
+		})
+	})
+}
+
+type listenToVersionSubstitutionEvent struct {
+	events chan *control_event.VersionSubstitutionEvent
+}
+
+func (l *listenToVersionSubstitutionEvent) HandleGomitEvent(e gomit.Event) {
+	if v, ok := e.Body.(*control_event.VersionSubstitutionEvent); ok {
+		l.events <- v
+	}
+}
+
+func (l *listenToVersionSubstitutionEvent) wait() *control_event.VersionSubstitutionEvent {
+	select {
+	case e := <-l.events:
+		return e
+	case <-time.After(10 * time.Second):
+		return nil
+	}
+}
+
+func TestVersionFallback(t *testing.T) {
+	cd := cdata.NewNode()
+	cd.AddItem("password", ctypes.ConfigValueStr{Value: "secret"})
+	metric := MockMetricType{
+		namespace: []string{"intel", "mock", "foo"},
+		cfg:       cd,
+		ver:       1,
+	}
+	Convey("Given a task pinning v1 of a metric when only v2 is loaded", t, func() {
+		cfg := GetDefaultConfig()
+		Convey("the fail policy refuses the task", func() {
+			c := New(cfg)
+			c.Start()
+			_, err := load(c, path.Join(SnapPath, "plugin", "snap-collector-mock2"))
+			So(err, ShouldBeNil)
+			errs := c.ValidateDeps([]core.Metric{metric}, []core.SubscribedPlugin{})
+			So(errs, ShouldNotBeEmpty)
+			So(errs[0].Error(), ShouldContainSubstring, "Metric not found")
+		})
+		Convey("the compatible policy substitutes v2", func() {
+			cfg.VersionFallback = VersionFallbackCompatible
+			c := New(cfg)
+			l := &listenToVersionSubstitutionEvent{events: make(chan *control_event.VersionSubstitutionEvent, 1)}
+			c.eventManager.RegisterHandler("TestVersionFallback", l)
+			c.Start()
+			_, err := load(c, path.Join(SnapPath, "plugin", "snap-collector-mock2"))
+			So(err, ShouldBeNil)
+			errs := c.ValidateDeps([]core.Metric{metric}, []core.SubscribedPlugin{})
+			So(errs, ShouldBeEmpty)
+			serrs := c.SubscribeDeps("testTaskID", []core.Metric{metric}, []core.Plugin{})
+			So(serrs, ShouldBeEmpty)
+			e := l.wait()
+			So(e, ShouldNotBeNil)
+			So(e.TaskId, ShouldEqual, "testTaskID")
+			So(e.RequestedVersion, ShouldEqual, 1)
+			So(e.Version, ShouldEqual, 2)
+			So(e.Policy, ShouldEqual, VersionFallbackCompatible)
+			mts, cerrs := c.CollectMetrics([]core.Metric{metric}, time.Now(), "testTaskID")
+			So(cerrs, ShouldBeNil)
+			So(len(mts), ShouldEqual, 1)
+			// v2's data is an int
+			_, ok := mts[0].Data().(int)
+			So(ok, ShouldBeTrue)
+		})
+	})
+	Convey("Given a task pinning v1 of a metric when v1 is swapped for v2", t, func() {
+		cfg := GetDefaultConfig()
+		cfg.VersionFallback = VersionFallbackAny
+		c := New(cfg)
+		l := &listenToVersionSubstitutionEvent{events: make(chan *control_event.VersionSubstitutionEvent, 1)}
+		c.eventManager.RegisterHandler("TestVersionFallback", l)
+		c.Start()
+		_, err := load(c, path.Join(SnapPath, "plugin", "snap-collector-mock1"))
+		So(err, ShouldBeNil)
+		serrs := c.SubscribeDeps("testTaskID", []core.Metric{metric}, []core.Plugin{})
+		So(serrs, ShouldBeEmpty)
+		lp, err2 := c.pluginManager.get("collector:mock:1")
+		So(err2, ShouldBeNil)
+		rp, rerr := core.NewRequestedPlugin(path.Join(SnapPath, "plugin", "snap-collector-mock2"))
+		So(rerr, ShouldBeNil)
+		So(c.SwapPlugins(rp, lp), ShouldBeNil)
+		Convey("the task is moved to v2", func() {
+			e := l.wait()
+			So(e, ShouldNotBeNil)
+			So(e.RequestedVersion, ShouldEqual, 1)
+			So(e.Version, ShouldEqual, 2)
+			pool, err := c.pluginRunner.AvailablePlugins().getPool("collector:mock:2")
+			So(err, ShouldBeNil)
+			So(pool.BoundSubscribers(), ShouldResemble, []string{"testTaskID"})
+			mts, cerrs := c.CollectMetrics([]core.Metric{metric}, time.Now(), "testTaskID")
+			So(cerrs, ShouldBeNil)
+			So(len(mts), ShouldEqual, 1)
+		})
+	})
+}
+
+type listenToMetricLimitEvent struct {
+	events chan *control_event.MetricLimitExceededEvent
+}
+
+func (l *listenToMetricLimitEvent) HandleGomitEvent(e gomit.Event) {
+	if v, ok := e.Body.(*control_event.MetricLimitExceededEvent); ok {
+		l.events <- v
+	}
+}
+
+func (l *listenToMetricLimitEvent) wait() *control_event.MetricLimitExceededEvent {
+	select {
+	case e := <-l.events:
+		return e
+	case <-time.After(10 * time.Second):
+		return nil
+	}
+}
+
+func TestMetricLimits(t *testing.T) {
+	Convey("Given metric limits", t, func() {
+		cfg := GetDefaultConfig()
+		l := &listenToMetricLimitEvent{events: make(chan *control_event.MetricLimitExceededEvent, 1)}
+		Convey("a collector with more metric types than allowed per plugin is not loaded", func() {
+			cfg.MaxPluginMetrics = 1
+			c := New(cfg)
+			c.eventManager.RegisterHandler("TestMetricLimits", l)
+			c.Start()
+			_, err := load(c, path.Join(SnapPath, "plugin", "snap-collector-mock2"))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "control.max_plugin_metrics is 1")
+			So(len(c.pluginManager.all()), ShouldEqual, 0)
+			e := l.wait()
+			So(e, ShouldNotBeNil)
+			So(e.Limit, ShouldEqual, "max_plugin_metrics")
+			So(e.PluginName, ShouldEqual, "mock")
+		})
+		Convey("a task matching more metrics than allowed is refused", func() {
+			cfg.MaxTaskMetrics = 1
+			c := New(cfg)
+			c.eventManager.RegisterHandler("TestMetricLimits", l)
+			c.Start()
+			mts := []core.Metric{
+				MockMetricType{namespace: []string{"intel", "mock", "foo"}, ver: 2},
+				MockMetricType{namespace: []string{"intel", "mock", "bar"}, ver: 2},
+			}
+			errs := c.ValidateDeps(mts, []core.SubscribedPlugin{})
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Error(), ShouldContainSubstring, "2 metrics requested where control.max_task_metrics is 1")
+			e := l.wait()
+			So(e, ShouldNotBeNil)
+			So(e.Limit, ShouldEqual, "max_task_metrics")
+			So(e.Count, ShouldEqual, 2)
+		})
+	})
+}
+
+type listenToMetricRemovedEvent struct {
+	events chan *control_event.MetricRemovedEvent
+}
+
+func (l *listenToMetricRemovedEvent) HandleGomitEvent(e gomit.Event) {
+	if v, ok := e.Body.(*control_event.MetricRemovedEvent); ok {
+		l.events <- v
+	}
+}
+
+func (l *listenToMetricRemovedEvent) wait() *control_event.MetricRemovedEvent {
+	select {
+	case e := <-l.events:
+		return e
+	case <-time.After(10 * time.Second):
+		return nil
+	}
+}
+
+func TestSweepMetricTypes(t *testing.T) {
+	Convey("Given a catalog with metric types of a dynamic collector", t, func() {
+		c := New(GetDefaultConfig())
+		l := &listenToMetricRemovedEvent{events: make(chan *control_event.MetricRemovedEvent, 1)}
+		c.eventManager.RegisterHandler("TestSweepMetricTypes", l)
+		lp := new(loadedPlugin)
+		lp.Meta.Name = "docker"
+		lp.Meta.Version = 1
+		lp.Meta.Features = plugin.FeatureDynamicMetrics
+		lp.ConfigPolicy = cpolicy.New()
+		for _, id := range []string{"a", "b"} {
+			So(c.metricCatalog.AddLoadedMetricType(lp, &metricType{namespace: []string{"intel", "docker", id, "cpu"}, version: 1}), ShouldBeNil)
+		}
+		Convey("the metric types collected within the ttl are kept", func() {
+			time.Sleep(20 * time.Millisecond)
+			mts := []core.Metric{MockMetricType{namespace: []string{"intel", "docker", "a", "cpu"}, ver: 1}}
+			pmts, serr := groupMetricTypesByPlugin(c.metricCatalog, mts)
+			So(serr, ShouldBeNil)
+			markCollected(pmts[lp.Key()], mts)
+			c.sweepMetricTypes(10 * time.Millisecond)
+			e := l.wait()
+			So(e, ShouldNotBeNil)
+			So(e.MetricNamespace, ShouldEqual, "/intel/docker/b/cpu")
+			So(e.PluginName, ShouldEqual, "docker")
+			_, err := c.metricCatalog.Get([]string{"intel", "docker", "a", "cpu"}, 1)
+			So(err, ShouldBeNil)
+			_, err = c.metricCatalog.Get([]string{"intel", "docker", "b", "cpu"}, 1)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
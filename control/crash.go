@@ -0,0 +1,187 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+// pluginRequest is a call made to a plugin, kept as the last request in its
+// crash report
+type pluginRequest struct {
+	Method        string                        `json:"method"`
+	Time          time.Time                     `json:"time"`
+	TaskID        string                        `json:"task_id,omitempty"`
+	Namespaces    []string                      `json:"namespaces,omitempty"`
+	ContentType   string                        `json:"content_type,omitempty"`
+	ContentLength int                           `json:"content_length,omitempty"`
+	Config        map[string]ctypes.ConfigValue `json:"config,omitempty"`
+	// InFlight is set while the plugin did not answer the request
+	InFlight bool `json:"in_flight"`
+}
+
+func collectRequest(metrics []core.Metric, taskID string) *pluginRequest {
+	r := &pluginRequest{
+		Method:   "CollectMetrics",
+		Time:     time.Now(),
+		TaskID:   taskID,
+		InFlight: true,
+	}
+	for _, m := range metrics {
+		r.Namespaces = append(r.Namespaces, core.JoinNamespace(m.Namespace()))
+		if m.Config() != nil {
+			r.Config = configValues(r.Config, m.Config().Table())
+		}
+	}
+	return r
+}
+
+func contentRequest(method, contentType string, content []byte, config map[string]ctypes.ConfigValue, taskID string) *pluginRequest {
+	return &pluginRequest{
+		Method:        method,
+		Time:          time.Now(),
+		TaskID:        taskID,
+		ContentType:   contentType,
+		ContentLength: len(content),
+		Config:        configValues(nil, config),
+		InFlight:      true,
+	}
+}
+
+// sensitiveConfigKeys are the parts of the names of the config items whose
+// value is not written in the crash reports
+var sensitiveConfigKeys = []string{"password", "passwd", "secret", "token", "key", "credential"}
+
+// configValues adds the config items to the values of a request, with the
+// value of the sensitive ones redacted as snapd redacts its own
+// configuration when printing it
+func configValues(values, config map[string]ctypes.ConfigValue) map[string]ctypes.ConfigValue {
+	for k, v := range config {
+		if values == nil {
+			values = map[string]ctypes.ConfigValue{}
+		}
+		if sensitiveConfigKey(k) {
+			v = ctypes.ConfigValueStr{Value: "********"}
+		}
+		values[k] = v
+	}
+	return values
+}
+
+func sensitiveConfigKey(k string) bool {
+	k = strings.ToLower(k)
+	for _, s := range sensitiveConfigKeys {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// crashReport is written when a plugin is declared dead, to tell what it
+// was doing and why it died
+type crashReport struct {
+	Plugin      string             `json:"plugin"`
+	Pid         int                `json:"pid,omitempty"`
+	Reason      string             `json:"reason"`
+	Time        time.Time          `json:"time"`
+	StartTime   time.Time          `json:"start_time"`
+	Exit        *plugin.ExitStatus `json:"exit,omitempty"`
+	Stderr      []string           `json:"stderr"`
+	LastRequest *pluginRequest     `json:"last_request,omitempty"`
+}
+
+// forensicPlugin is implemented by the plugin processes which can tell how
+// they exited and what they wrote to STDERR
+type forensicPlugin interface {
+	Pid() int
+	ExitStatus() *plugin.ExitStatus
+	StderrTail() []string
+}
+
+// requestStarted records the request made to the plugin, answered once
+// requestDone is called
+func (a *availablePlugin) requestStarted(r *pluginRequest) {
+	a.rpcMutex.Lock()
+	a.lastRequest = r
+	a.rpcMutex.Unlock()
+}
+
+func (a *availablePlugin) requestDone(r *pluginRequest) {
+	a.rpcMutex.Lock()
+	r.InFlight = false
+	a.rpcMutex.Unlock()
+}
+
+// writeCrashReport writes the crash report of the plugin in the directory
+// of the plugin under crashPath and returns its path, or an empty one when
+// crash reports are disabled or could not be written
+func (a *availablePlugin) writeCrashReport(reason string) string {
+	if a.crashPath == "" {
+		return ""
+	}
+	now := time.Now()
+	cr := &crashReport{
+		Plugin:    a.String(),
+		Reason:    reason,
+		Time:      now,
+		StartTime: a.startTime,
+	}
+	a.rpcMutex.Lock()
+	if a.lastRequest != nil {
+		lr := *a.lastRequest
+		cr.LastRequest = &lr
+	}
+	a.rpcMutex.Unlock()
+	if fp, ok := a.ePlugin.(forensicPlugin); ok {
+		cr.Pid = fp.Pid()
+		cr.Exit = fp.ExitStatus()
+		cr.Stderr = fp.StderrTail()
+	}
+	b, err := json.MarshalIndent(cr, "", "  ")
+	if err == nil {
+		dir := filepath.Join(a.crashPath, strings.Replace(a.key, ":", "-", -1))
+		path := filepath.Join(dir, fmt.Sprintf("%s-id%d.json", now.UTC().Format("20060102T150405.000Z"), a.ID()))
+		if err = os.MkdirAll(dir, 0700); err == nil {
+			if err = ioutil.WriteFile(path, b, 0600); err == nil {
+				return path
+			}
+		}
+	}
+	log.WithFields(log.Fields{
+		"_module": "control-aplugin",
+		"block":   "crash-report",
+		"aplugin": a,
+		"_error":  err,
+	}).Error("unable to write crash report")
+	return ""
+}
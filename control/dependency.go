@@ -0,0 +1,147 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core/serror"
+)
+
+var (
+	ErrUnmetDependencies = errors.New("plugin dependencies are not met")
+)
+
+// SetSnapdVersion sets the version of snapd checked against the snapd
+// versions plugins declare they support
+func (p *pluginControl) SetSnapdVersion(v string) {
+	p.snapdVersion = v
+}
+
+// checkDependencies verifies that the plugins lp depends on are loaded and
+// that snapd is a version lp supports
+func (p *pluginControl) checkDependencies(lp *loadedPlugin) serror.SnapError {
+	var unmet []string
+	if lp.Meta.MinSnapdVersion != "" || lp.Meta.MaxSnapdVersion != "" {
+		if msg, ok := p.checkSnapdVersion(lp.Meta.MinSnapdVersion, lp.Meta.MaxSnapdVersion); !ok {
+			unmet = append(unmet, msg)
+		}
+	}
+	loaded := p.pluginManager.all()
+	for _, dep := range lp.Meta.Dependencies {
+		found := false
+		for _, other := range loaded {
+			if other.Type == dep.Type && other.Name() == dep.Name && dep.Accepts(other.Version()) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unmet = append(unmet, fmt.Sprintf("%s is not loaded", dep))
+		}
+	}
+	if len(unmet) == 0 {
+		return nil
+	}
+	return serror.New(fmt.Errorf("%v: %s", ErrUnmetDependencies, strings.Join(unmet, "; ")), map[string]interface{}{
+		"plugin-name":    lp.Name(),
+		"plugin-version": lp.Version(),
+		"plugin-type":    lp.TypeName(),
+	})
+}
+
+func (p *pluginControl) checkSnapdVersion(min, max string) (string, bool) {
+	running, err := parseVersion(p.snapdVersion)
+	if err != nil {
+		// Development builds do not have a version so nothing can be checked
+		controlLogger.WithFields(log.Fields{
+			"_block":        "check-dependencies",
+			"snapd-version": p.snapdVersion,
+		}).Warning("unable to check the snapd version required by plugin")
+		return "", true
+	}
+	if min != "" {
+		v, err := parseVersion(min)
+		if err != nil {
+			return fmt.Sprintf("invalid minimum snapd version %q", min), false
+		}
+		if compareVersions(running, v) < 0 {
+			return fmt.Sprintf("snapd %s or later is required (running %s)", min, p.snapdVersion), false
+		}
+	}
+	if max != "" {
+		v, err := parseVersion(max)
+		if err != nil {
+			return fmt.Sprintf("invalid maximum snapd version %q", max), false
+		}
+		if compareVersions(running, v) > 0 {
+			return fmt.Sprintf("snapd %s or earlier is required (running %s)", max, p.snapdVersion), false
+		}
+	}
+	return "", true
+}
+
+// parseVersion parses versions like "0.13.0", "v0.13.0-beta" or
+// "0.13.0-12-gabcdef" into their numeric components
+func parseVersion(v string) ([]int, error) {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	if v == "" {
+		return nil, fmt.Errorf("invalid version")
+	}
+	parts := strings.Split(v, ".")
+	nums := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		nums[i] = n
+	}
+	return nums, nil
+}
+
+// compareVersions returns -1, 0 or 1 as a is lower than, equal to or higher
+// than b. Missing components count as zero.
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x < y {
+			return -1
+		}
+		if x > y {
+			return 1
+		}
+	}
+	return 0
+}
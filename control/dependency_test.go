@@ -0,0 +1,85 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+)
+
+func newDependencyTestPlugin(typ plugin.PluginType, name string, ver int, opts ...func(*plugin.PluginMeta)) *loadedPlugin {
+	lp := &loadedPlugin{
+		Meta:  plugin.PluginMeta{Name: name, Version: ver, Type: typ},
+		Type:  typ,
+		State: LoadedState,
+	}
+	for _, opt := range opts {
+		opt(&lp.Meta)
+	}
+	return lp
+}
+
+func TestPluginDependencies(t *testing.T) {
+	Convey("Plugin dependencies", t, func() {
+		c := New(GetDefaultConfig())
+		pm := c.pluginManager.(*pluginManager)
+		collector := newDependencyTestPlugin(plugin.CollectorPluginType, "foo", 2)
+		So(pm.loadedPlugins.add(collector), ShouldBeNil)
+
+		Convey("are met when the required plugin is loaded", func() {
+			lp := newDependencyTestPlugin(plugin.ProcessorPluginType, "bar", 1,
+				plugin.RequiresPlugin(plugin.CollectorPluginType, "foo", 1, 2))
+			So(c.checkDependencies(lp), ShouldBeNil)
+		})
+		Convey("are not met when the required version is not loaded", func() {
+			lp := newDependencyTestPlugin(plugin.ProcessorPluginType, "bar", 1,
+				plugin.RequiresPlugin(plugin.CollectorPluginType, "foo", 3, 0))
+			se := c.checkDependencies(lp)
+			So(se, ShouldNotBeNil)
+			So(se.Error(), ShouldContainSubstring, "collector:foo (version 3 or later) is not loaded")
+		})
+		Convey("are not met when the required plugin type differs", func() {
+			lp := newDependencyTestPlugin(plugin.PublisherPluginType, "bar", 1,
+				plugin.RequiresPlugin(plugin.ProcessorPluginType, "foo", 0, 0))
+			So(c.checkDependencies(lp), ShouldNotBeNil)
+		})
+		Convey("check the snapd version", func() {
+			lp := newDependencyTestPlugin(plugin.CollectorPluginType, "baz", 1,
+				plugin.RequiresSnapd("0.13.0", "0.14"))
+			c.SetSnapdVersion("v0.13.1-beta-12-gabcdef")
+			So(c.checkDependencies(lp), ShouldBeNil)
+			c.SetSnapdVersion("0.15.0")
+			se := c.checkDependencies(lp)
+			So(se, ShouldNotBeNil)
+			So(se.Error(), ShouldContainSubstring, "snapd 0.14 or earlier is required")
+			c.SetSnapdVersion("0.12.9")
+			So(c.checkDependencies(lp), ShouldNotBeNil)
+		})
+		Convey("skip the snapd version when it is unknown", func() {
+			lp := newDependencyTestPlugin(plugin.CollectorPluginType, "baz", 1,
+				plugin.RequiresSnapd("0.13.0", ""))
+			c.SetSnapdVersion("unknown")
+			So(c.checkDependencies(lp), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,129 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/cfgfile"
+)
+
+// AutodiscoverManifestFile is the name of the optional manifest in an auto
+// discover directory. When present only the plugins listed in it are loaded
+// from the directory.
+const AutodiscoverManifestFile = "plugins.manifest"
+
+var (
+	ErrManifestEntryNoFile    = errors.New("manifest entry is missing a file")
+	ErrManifestChecksum       = errors.New("plugin checksum does not match the manifest")
+	ErrManifestSignature      = errors.New("plugin is not signed but the manifest requires a signature")
+	ErrManifestNotListed      = errors.New("plugin is not listed in the manifest")
+	ErrManifestPluginMismatch = errors.New("loaded plugin does not match the manifest")
+)
+
+// AutodiscoverManifest pins the plugins loaded from an auto discover
+// directory so that dropping a new binary into the directory does not change
+// what is loaded until the manifest is updated.
+type AutodiscoverManifest struct {
+	Plugins []*ManifestEntry `json:"plugins"yaml:"plugins"`
+}
+
+// ManifestEntry pins a single plugin file
+type ManifestEntry struct {
+	// File is the name of the plugin file in the directory
+	File string `json:"file"yaml:"file"`
+	// Name and Version, when set, must match the loaded plugin
+	Name    string `json:"name,omitempty"yaml:"name,omitempty"`
+	Version int    `json:"version,omitempty"yaml:"version,omitempty"`
+	// Checksum, when set, is the hex encoded SHA-256 of the plugin file
+	Checksum string `json:"checksum,omitempty"yaml:"checksum,omitempty"`
+	// RequireSignature refuses the plugin if it has no signature file,
+	// whatever the plugin trust level is
+	RequireSignature bool `json:"require_signature,omitempty"yaml:"require_signature,omitempty"`
+}
+
+// ReadAutodiscoverManifest reads the manifest in dir. A nil manifest and nil
+// error are returned when the directory has no manifest.
+func ReadAutodiscoverManifest(dir string) (*AutodiscoverManifest, error) {
+	path := filepath.Join(dir, AutodiscoverManifestFile)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	m := &AutodiscoverManifest{}
+	if err := cfgfile.Read(path, m); err != nil {
+		return nil, fmt.Errorf("unable to read manifest %s: %v", path, err)
+	}
+	for _, e := range m.Plugins {
+		if e.File == "" {
+			return nil, ErrManifestEntryNoFile
+		}
+	}
+	return m, nil
+}
+
+// Entry returns the entry for the given file name or an error if it is not
+// listed.
+func (m *AutodiscoverManifest) Entry(file string) (*ManifestEntry, error) {
+	for _, e := range m.Plugins {
+		if e.File == file {
+			return e, nil
+		}
+	}
+	return nil, ErrManifestNotListed
+}
+
+// CheckRequested verifies a plugin against the entry before it is loaded
+func (e *ManifestEntry) CheckRequested(rp *core.RequestedPlugin) error {
+	if e.Checksum != "" {
+		sum := rp.CheckSum()
+		if !strings.EqualFold(e.Checksum, hex.EncodeToString(sum[:])) {
+			return ErrManifestChecksum
+		}
+	}
+	if e.RequireSignature && len(rp.Signature()) == 0 {
+		return ErrManifestSignature
+	}
+	return nil
+}
+
+// CheckLoaded verifies a plugin against the entry once it is loaded
+func (e *ManifestEntry) CheckLoaded(pl core.Plugin) error {
+	if (e.Name != "" && e.Name != pl.Name()) || (e.Version != 0 && e.Version != pl.Version()) {
+		return fmt.Errorf("%v: expected %s, got %s:%d", ErrManifestPluginMismatch, e.pinned(), pl.Name(), pl.Version())
+	}
+	return nil
+}
+
+func (e *ManifestEntry) pinned() string {
+	name := e.Name
+	if name == "" {
+		name = "*"
+	}
+	if e.Version == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s:%d", name, e.Version)
+}
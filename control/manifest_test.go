@@ -0,0 +1,89 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+func TestAutodiscoverManifest(t *testing.T) {
+	Convey("Autodiscover manifest", t, func() {
+		dir, err := ioutil.TempDir("", "snap-manifest")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		plugin := []byte("#!/bin/sh\n")
+		So(ioutil.WriteFile(filepath.Join(dir, "snap-collector-foo"), plugin, 0700), ShouldBeNil)
+		sum := sha256.Sum256(plugin)
+
+		Convey("is optional", func() {
+			m, err := ReadAutodiscoverManifest(dir)
+			So(err, ShouldBeNil)
+			So(m, ShouldBeNil)
+		})
+		Convey("is read from YAML", func() {
+			manifest := "plugins:\n  - file: snap-collector-foo\n    name: foo\n    version: 2\n    checksum: " + hex.EncodeToString(sum[:]) + "\n"
+			So(ioutil.WriteFile(filepath.Join(dir, AutodiscoverManifestFile), []byte(manifest), 0600), ShouldBeNil)
+			m, err := ReadAutodiscoverManifest(dir)
+			So(err, ShouldBeNil)
+			So(m, ShouldNotBeNil)
+
+			Convey("only lists pinned files", func() {
+				_, err := m.Entry("snap-collector-bar")
+				So(err, ShouldEqual, ErrManifestNotListed)
+			})
+			e, err := m.Entry("snap-collector-foo")
+			So(err, ShouldBeNil)
+			rp, err := core.NewRequestedPlugin(filepath.Join(dir, "snap-collector-foo"))
+			So(err, ShouldBeNil)
+
+			Convey("checks the checksum", func() {
+				So(e.CheckRequested(rp), ShouldBeNil)
+				e.Checksum = "00"
+				So(e.CheckRequested(rp), ShouldEqual, ErrManifestChecksum)
+			})
+			Convey("checks the signature requirement", func() {
+				e.RequireSignature = true
+				So(e.CheckRequested(rp), ShouldEqual, ErrManifestSignature)
+				rp.SetSignature([]byte("sig"))
+				So(e.CheckRequested(rp), ShouldBeNil)
+			})
+			Convey("checks the loaded name and version", func() {
+				So(e.CheckLoaded(mockPlugin{name: "foo", ver: 2}), ShouldBeNil)
+				err := e.CheckLoaded(mockPlugin{name: "foo", ver: 3})
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "expected foo:2, got foo:3")
+			})
+		})
+		Convey("requires a file for every entry", func() {
+			So(ioutil.WriteFile(filepath.Join(dir, AutodiscoverManifestFile), []byte(`{"plugins": [{"version": 1}]}`), 0600), ShouldBeNil)
+			_, err := ReadAutodiscoverManifest(dir)
+			So(err, ShouldEqual, ErrManifestEntryNoFile)
+		})
+	})
+}
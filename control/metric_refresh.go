@@ -0,0 +1,250 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/client"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/control_event"
+)
+
+// The metric types of a collector are cataloged when it is loaded. A collector
+// which supports dynamic metrics (e.g. one metric per container) may advertise
+// new metric types while it is running, so the metric types of the running
+// collectors supporting the feature are refreshed periodically. Since wildcard
+// queries are expanded against the catalog on every collection, tasks pick up
+// the new metrics without being restarted.
+//
+// A task may set its own refresh interval for the collectors it is subscribed
+// to; the metric types of a collector are refreshed at the shortest interval
+// of its subscribers, which defaults to the metric refresh interval of the
+// config.
+//
+// Metric types are only refreshed from running collectors, so the ones whose
+// source disappeared while the collector was not running, or while refreshing
+// is disabled, stay in the catalog. If a metric TTL is configured, the metric
+// types of those collectors which were neither advertised nor collected within
+// the TTL are swept from the catalog, unless a task is subscribed to them.
+
+// metricRefreshTick is how often the collectors due for a refresh of their
+// metric types are looked for
+const metricRefreshTick = time.Second
+
+type metricRefresher struct {
+	interval time.Duration
+	refresh  func()
+	quit     chan struct{}
+}
+
+func newMetricRefresher(interval time.Duration, refresh func()) *metricRefresher {
+	return &metricRefresher{
+		interval: interval,
+		refresh:  refresh,
+	}
+}
+
+// Start starts refreshing every interval. It does nothing if the interval is 0.
+func (r *metricRefresher) Start() {
+	if r.interval <= 0 || r.quit != nil {
+		return
+	}
+	ticker := time.NewTicker(r.interval)
+	quit := make(chan struct{})
+	r.quit = quit
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				r.refresh()
+			case <-quit:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops refreshing
+func (r *metricRefresher) Stop() {
+	if r.quit == nil {
+		return
+	}
+	close(r.quit)
+	r.quit = nil
+}
+
+// metricRefreshes holds the refresh intervals set by tasks and when the
+// metric types of each collector were last refreshed
+type metricRefreshes struct {
+	sync.Mutex
+	intervals map[string]time.Duration
+	last      map[string]time.Time
+}
+
+func newMetricRefreshes() *metricRefreshes {
+	return &metricRefreshes{
+		intervals: map[string]time.Duration{},
+		last:      map[string]time.Time{},
+	}
+}
+
+// SetMetricRefreshInterval sets how often the metric types of the collectors
+// the task is subscribed to are refreshed. An interval of 0 leaves it to the
+// metric refresh interval of the config.
+func (p *pluginControl) SetMetricRefreshInterval(taskID string, interval time.Duration) {
+	p.refreshes.Lock()
+	defer p.refreshes.Unlock()
+	if interval <= 0 {
+		delete(p.refreshes.intervals, taskID)
+		return
+	}
+	p.refreshes.intervals[taskID] = interval
+}
+
+// due returns whether the metric types of the plugin are due for a refresh,
+// given the default interval and the tasks subscribed to the plugin, and
+// records the refresh if they are
+func (r *metricRefreshes) due(key string, subscribers []string, interval time.Duration, now time.Time) bool {
+	r.Lock()
+	defer r.Unlock()
+	for _, id := range subscribers {
+		if i, ok := r.intervals[id]; ok && (interval <= 0 || i < interval) {
+			interval = i
+		}
+	}
+	if interval <= 0 {
+		return false
+	}
+	last, ok := r.last[key]
+	if !ok {
+		// the metric types were cataloged when the plugin was loaded
+		r.last[key] = now
+		return false
+	}
+	if now.Sub(last) < interval {
+		return false
+	}
+	r.last[key] = now
+	return true
+}
+
+// refreshMetricTypes refreshes the cataloged metric types of the running
+// collectors supporting dynamic metrics which are due for a refresh.
+func (p *pluginControl) refreshMetricTypes(now time.Time) {
+	for _, lp := range p.pluginManager.all() {
+		if lp.Type != plugin.CollectorPluginType || !lp.Supports(plugin.FeatureDynamicMetrics) {
+			continue
+		}
+		var subscribers []string
+		if pool, err := p.pluginRunner.AvailablePlugins().getPool(lp.Key()); err == nil && pool != nil {
+			subscribers = pool.Subscribers()
+		}
+		if !p.refreshes.due(lp.Key(), subscribers, p.Config.MetricRefreshInterval.Duration, now) {
+			continue
+		}
+		ap := p.runningPlugin(lp.Key())
+		if ap == nil {
+			// metric types are only refreshed from plugins which are already running
+			continue
+		}
+		added, removed, err := p.refreshPluginMetricTypes(lp, ap)
+		f := log.Fields{
+			"_block":         "refresh-metric-types",
+			"plugin-name":    lp.Name(),
+			"plugin-version": lp.Version(),
+		}
+		if le, ok := err.(*metricLimitError); ok {
+			p.eventManager.Emit(le.event())
+		}
+		if err != nil {
+			f["error"] = err.Error()
+			controlLogger.WithFields(f).Warn("unable to refresh metric types")
+			continue
+		}
+		if added > 0 || removed > 0 {
+			f["added"] = added
+			f["removed"] = removed
+			controlLogger.WithFields(f).Info("metric types refreshed")
+		}
+	}
+}
+
+// runningPlugin returns a running instance of the plugin or nil if there is none
+func (p *pluginControl) runningPlugin(key string) *availablePlugin {
+	pool, err := p.pluginRunner.AvailablePlugins().getPool(key)
+	if err != nil || pool == nil {
+		return nil
+	}
+	pool.RLock()
+	defer pool.RUnlock()
+	for _, a := range pool.Plugins() {
+		if ap, ok := a.(*availablePlugin); ok {
+			return ap
+		}
+	}
+	return nil
+}
+
+func (p *pluginControl) refreshPluginMetricTypes(lp *loadedPlugin, ap *availablePlugin) (int, int, error) {
+	colClient, ok := ap.client.(client.PluginCollectorClient)
+	if !ok {
+		return 0, 0, nil
+	}
+	cfg := plugin.PluginConfigType{
+		ConfigDataNode: p.Config.Plugins.getPluginConfigDataNode(core.CollectorPluginType, lp.Name(), lp.Version()),
+	}
+	mts, err := colClient.GetMetricTypes(cfg)
+	if err != nil {
+		return 0, 0, err
+	}
+	for i, mt := range mts {
+		mts[i] = defaultMetricVersion(mt, lp.Version())
+	}
+	return p.metricCatalog.RefreshPluginMetrics(lp, mts)
+}
+
+// sweepMetricTypes removes the metric types of the collectors supporting
+// dynamic metrics which were not seen within the ttl, emitting an event for
+// each of them.
+func (p *pluginControl) sweepMetricTypes(ttl time.Duration) {
+	for _, mt := range p.metricCatalog.RemoveStaleMetrics(time.Now().Add(-ttl)) {
+		controlLogger.WithFields(log.Fields{
+			"_block":         "sweep-metric-types",
+			"namespace":      mt.NamespaceAsString(),
+			"version":        mt.Version(),
+			"plugin-name":    mt.Plugin.Name(),
+			"plugin-version": mt.Plugin.Version(),
+			"last-seen":      mt.LastSeen(),
+		}).Info("stale metric type removed")
+		p.eventManager.Emit(&control_event.MetricRemovedEvent{
+			MetricNamespace: mt.NamespaceAsString(),
+			Version:         mt.Version(),
+			PluginName:      mt.Plugin.Name(),
+			PluginVersion:   mt.Plugin.Version(),
+			LastSeen:        mt.LastSeen(),
+		})
+	}
+}
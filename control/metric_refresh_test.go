@@ -0,0 +1,79 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMetricRefresher(t *testing.T) {
+	Convey("metricRefresher", t, func() {
+		var count int32
+		refresh := func() { atomic.AddInt32(&count, 1) }
+		Convey("refreshes every interval until stopped", func() {
+			r := newMetricRefresher(10*time.Millisecond, refresh)
+			r.Start()
+			time.Sleep(55 * time.Millisecond)
+			r.Stop()
+			// a tick may race with stopping
+			time.Sleep(15 * time.Millisecond)
+			n := atomic.LoadInt32(&count)
+			So(n, ShouldBeGreaterThanOrEqualTo, 2)
+			time.Sleep(30 * time.Millisecond)
+			So(atomic.LoadInt32(&count), ShouldEqual, n)
+		})
+		Convey("is disabled by an interval of 0", func() {
+			r := newMetricRefresher(0, refresh)
+			r.Start()
+			time.Sleep(20 * time.Millisecond)
+			r.Stop()
+			So(atomic.LoadInt32(&count), ShouldEqual, 0)
+		})
+	})
+}
+
+func TestMetricRefreshes(t *testing.T) {
+	Convey("metricRefreshes", t, func() {
+		r := newMetricRefreshes()
+		now := time.Now()
+		key := "collector:docker:1"
+		So(r.due(key, nil, time.Minute, now), ShouldBeFalse)
+		Convey("are due every default interval", func() {
+			So(r.due(key, nil, time.Minute, now.Add(30*time.Second)), ShouldBeFalse)
+			So(r.due(key, nil, time.Minute, now.Add(time.Minute)), ShouldBeTrue)
+			So(r.due(key, nil, time.Minute, now.Add(90*time.Second)), ShouldBeFalse)
+		})
+		Convey("are due every shortest interval of the subscribed tasks", func() {
+			r.intervals["a"] = 10 * time.Second
+			r.intervals["b"] = 20 * time.Second
+			So(r.due(key, []string{"b"}, time.Minute, now.Add(15*time.Second)), ShouldBeFalse)
+			So(r.due(key, []string{"a", "b", "c"}, time.Minute, now.Add(15*time.Second)), ShouldBeTrue)
+		})
+		Convey("are due every interval of the subscribed tasks when the default is disabled", func() {
+			r.intervals["a"] = 10 * time.Second
+			So(r.due(key, nil, 0, now.Add(time.Hour)), ShouldBeFalse)
+			So(r.due(key, []string{"a"}, 0, now.Add(10*time.Second)), ShouldBeTrue)
+		})
+	})
+}
@@ -22,24 +22,25 @@ package control
 import (
 	"errors"
 	"fmt"
-	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
 
+	"github.com/intelsdi-x/snap/control/plugin"
 	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/control_event"
 	"github.com/intelsdi-x/snap/core/ctypes"
-	"github.com/intelsdi-x/snap/core/serror"
 )
 
 var (
-	errMetricNotFound   = errors.New("metric not found")
-	errNegativeSubCount = serror.New(errors.New("subscription count cannot be < 0"))
-	notAllowedChars     = map[string][]string{
+	errMetricNotFound = errors.New("metric not found")
+	notAllowedChars   = map[string][]string{
 		"brackets":     {"(", ")", "[", "]", "{", "}"},
 		"spaces":       {" "},
 		"punctuations": {".", ",", ";", "?", "!"},
@@ -56,6 +57,10 @@ func errorMetricNotFound(ns []string, ver ...int) error {
 	return fmt.Errorf("Metric not found: %s", core.JoinNamespace(ns))
 }
 
+func errorPinnedPluginNotLoaded(ns []string, name string, ver int) error {
+	return fmt.Errorf("Pinned plugin not loaded: %s is pinned to collector %s version %d", core.JoinNamespace(ns), name, ver)
+}
+
 func errorMetricContainsNotAllowedChars(ns []string) error {
 	return fmt.Errorf("Metric namespace %s contains not allowed characters. Avoid using %s", ns, listNotAllowedChars())
 }
@@ -64,6 +69,36 @@ func errorMetricEndsWithAsterisk(ns []string) error {
 	return fmt.Errorf("Metric namespace %s ends with an asterisk is not allowed", ns)
 }
 
+// metricLimitError is returned when cataloging metric types or creating a task
+// would exceed one of the metric limits of the configuration
+type metricLimitError struct {
+	limit  string
+	max    int
+	count  int
+	plugin *loadedPlugin
+}
+
+func (e *metricLimitError) Error() string {
+	if e.plugin != nil {
+		return fmt.Sprintf("Metric limit exceeded: plugin %s:%d would bring %d metric types where control.%s is %d", e.plugin.Name(), e.plugin.Version(), e.count, e.limit, e.max)
+	}
+	return fmt.Sprintf("Metric limit exceeded: %d metrics requested where control.%s is %d", e.count, e.limit, e.max)
+}
+
+// event returns the event emitted for the exceeded limit
+func (e *metricLimitError) event() *control_event.MetricLimitExceededEvent {
+	ev := &control_event.MetricLimitExceededEvent{
+		Limit: e.limit,
+		Max:   e.max,
+		Count: e.count,
+	}
+	if e.plugin != nil {
+		ev.PluginName = e.plugin.Name()
+		ev.PluginVersion = e.plugin.Version()
+	}
+	return ev
+}
+
 // listNotAllowedChars returns list of not allowed characters in metric's namespace as a string
 // which is used in construct errorMetricContainsNotAllowedChars as a recommendation
 // exemplary output: "brackets [( ) [ ] { }], spaces [ ], punctuations [. , ; ? !], slashes [| \ /], carets [^], quotations [" ` ']"
@@ -90,11 +125,15 @@ func (m *metricCatalogItem) Versions() map[int]core.Metric {
 }
 
 type metricType struct {
+	// lastSeen is the time in unix nanoseconds the metric type was last
+	// advertised or collected, accessed atomically
+	lastSeen int64
+
 	Plugin             *loadedPlugin
 	namespace          []string
 	version            int
 	lastAdvertisedTime time.Time
-	subscriptions      int
+	subscriptions      map[string]struct{} // keyed by task ID
 	policy             processesConfigData
 	config             *cdata.ConfigDataNode
 	data               interface{}
@@ -102,11 +141,14 @@ type metricType struct {
 	labels             []core.Label
 	tags               map[string]string
 	timestamp          time.Time
+	unit               string
+	description        string
 }
 
 type processesConfigData interface {
 	Process(map[string]ctypes.ConfigValue) (*map[string]ctypes.ConfigValue, *cpolicy.ProcessingErrors)
 	HasRules() bool
+	Defaults() map[string]ctypes.ConfigValue
 }
 
 func newMetricType(ns []string, last time.Time, plugin *loadedPlugin) *metricType {
@@ -138,20 +180,45 @@ func (m *metricType) LastAdvertisedTime() time.Time {
 	return m.lastAdvertisedTime
 }
 
-func (m *metricType) Subscribe() {
-	m.subscriptions++
+// LastSeen returns when the metric type was last advertised by its plugin or
+// collected from it
+func (m *metricType) LastSeen() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&m.lastSeen))
 }
 
-func (m *metricType) Unsubscribe() serror.SnapError {
-	if m.subscriptions == 0 {
-		return errNegativeSubCount
+// seen records that the metric type was advertised or collected at t
+func (m *metricType) seen(t time.Time) {
+	atomic.StoreInt64(&m.lastSeen, t.UnixNano())
+}
+
+// Subscribe records that the task subscribes to the metric.
+// Using Subscribe is idempotent.
+func (m *metricType) Subscribe(taskID string) {
+	if m.subscriptions == nil {
+		m.subscriptions = map[string]struct{}{}
 	}
-	m.subscriptions--
-	return nil
+	m.subscriptions[taskID] = struct{}{}
 }
 
+// Unsubscribe removes the subscription of the task to the metric.
+// Using Unsubscribe is idempotent.
+func (m *metricType) Unsubscribe(taskID string) {
+	delete(m.subscriptions, taskID)
+}
+
+// SubscriptionCount returns the number of tasks subscribed to the metric
 func (m *metricType) SubscriptionCount() int {
-	return m.subscriptions
+	return len(m.subscriptions)
+}
+
+// Subscribers returns the sorted IDs of the tasks subscribed to the metric
+func (m *metricType) Subscribers() []string {
+	ids := make([]string, 0, len(m.subscriptions))
+	for id := range m.subscriptions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
 }
 
 func (m *metricType) Version() int {
@@ -188,14 +255,49 @@ func (m *metricType) Timestamp() time.Time {
 	return m.timestamp
 }
 
+// PluginName returns the name of the plugin exposing the metric
+func (m *metricType) PluginName() string {
+	if m.Plugin == nil {
+		return ""
+	}
+	return m.Plugin.Name()
+}
+
+func (m *metricType) Unit() string {
+	return m.unit
+}
+
+func (m *metricType) Description() string {
+	return m.description
+}
+
 type metricCatalog struct {
 	tree  *MTTrie
 	mutex *sync.Mutex
 	keys  []string
 
 	// mKeys holds requested metric's keys which can include wildcards and matched to them the cataloged keys
-	mKeys       map[string][]string
+	mKeys map[string][]string
+	// queries indexes the requested metric's keys to keep mKeys up to date
+	// as cataloged keys are added and removed
+	queries     *queryIndex
 	currentIter int
+
+	// versionFallback is the policy applied by Resolve
+	versionFallback string
+
+	// maxMetrics and maxPluginMetrics limit the number of cataloged metric
+	// types in total and per plugin, 0 meaning no limit
+	maxMetrics       int
+	maxPluginMetrics int
+
+	// aliases holds the namespaces the aliases stand for by alias key
+	aliases    map[string][]string
+	aliasMutex sync.RWMutex
+
+	// computed holds the computed metrics by key
+	computed      map[string]*computedMetricType
+	computedMutex sync.RWMutex
 }
 
 func newMetricCatalog() *metricCatalog {
@@ -205,6 +307,7 @@ func newMetricCatalog() *metricCatalog {
 		currentIter: 0,
 		keys:        []string{},
 		mKeys:       make(map[string][]string),
+		queries:     newQueryIndex(),
 	}
 }
 
@@ -228,18 +331,29 @@ func (mc *metricCatalog) matchedNamespaces(wkey string) ([][]string, error) {
 // GetQueriedNamespaces returns all matched metrics namespaces for query 'ns' which can contain
 // an asterisk or tuple (refer to query support)
 func (mc *metricCatalog) GetQueriedNamespaces(ns []string) ([][]string, error) {
+	if mc.isAlias(ns) || mc.Computed(ns) != nil {
+		return [][]string{ns}, nil
+	}
 	mc.mutex.Lock()
 	defer mc.mutex.Unlock()
 
 	// get metric key (might contain wildcard(s))
 	wkey := getMetricKey(ns)
 
+	// the query is dropped once the metrics it matched went away
+	if err := mc.addItemToMatchingMap(wkey); err != nil {
+		return nil, err
+	}
+
 	return mc.matchedNamespaces(wkey)
 }
 
 // MatchQuery matches given 'ns' which could contain an asterisk or a tuple and add them to matching map under key 'ns'
 // The matched metrics namespaces are also returned (as a [][]string)
 func (mc *metricCatalog) MatchQuery(ns []string) ([][]string, error) {
+	if mc.isAlias(ns) || mc.Computed(ns) != nil {
+		return [][]string{ns}, nil
+	}
 	mc.mutex.Lock()
 	defer mc.mutex.Unlock()
 
@@ -247,7 +361,9 @@ func (mc *metricCatalog) MatchQuery(ns []string) ([][]string, error) {
 	wkey := getMetricKey(ns)
 
 	// adding matched namespaces to map
-	mc.addItemToMatchingMap(wkey)
+	if err := mc.addItemToMatchingMap(wkey); err != nil {
+		return nil, err
+	}
 
 	return mc.matchedNamespaces(wkey)
 }
@@ -264,21 +380,24 @@ func convertKeysToNamespaces(keys []string) [][]string {
 	return nss
 }
 
-// addItemToMatchingMap adds `wkey` to matching map (or updates if `wkey` exists) with corresponding cataloged keys as a content;
-// if this 'wkey' does not match to any cataloged keys, it will be removed from matching map
-func (mc *metricCatalog) addItemToMatchingMap(wkey string) {
-	matchedKeys := []string{}
-
-	// wkey contains `.` which should not be interpreted as regexp tokens, but as a single character
-	exp := strings.Replace(wkey, ".", "[.]", -1)
+// addItemToMatchingMap adds `wkey` to matching map with corresponding cataloged keys as a content;
+// if this 'wkey' does not match to any cataloged keys, it will be removed from matching map.
+// Once added, `wkey` and its compiled query are kept as long as it matches cataloged keys,
+// and its content is kept up to date by addKey and removeKey.
+func (mc *metricCatalog) addItemToMatchingMap(wkey string) error {
+	if _, ok := mc.queries.get(wkey); ok {
+		return nil
+	}
 
-	// change `*` into regexp `.*` which matches any characters
-	exp = strings.Replace(exp, "*", ".*", -1)
+	query, err := core.CompileWildcardNamespace(getMetricNamespace(wkey))
+	if err != nil {
+		return err
+	}
+	mc.queries.add(wkey, query)
 
-	regex := regexp.MustCompile("^" + exp + "$")
+	matchedKeys := []string{}
 	for _, key := range mc.keys {
-		match := regex.FindStringSubmatch(key)
-		if match == nil {
+		if !query.Match(getMetricNamespace(key)) {
 			continue
 		}
 		matchedKeys = appendIfMissing(matchedKeys, key)
@@ -288,30 +407,50 @@ func (mc *metricCatalog) addItemToMatchingMap(wkey string) {
 	} else {
 		mc.mKeys[wkey] = matchedKeys
 	}
+	return nil
 }
 
-// removeItemFromMatchingMap removes `wkey` from matching map
+// removeItemFromMatchingMap removes `wkey` from matching map and from the index of queries
 func (mc *metricCatalog) removeItemFromMatchingMap(wkey string) {
 	if _, exist := mc.mKeys[wkey]; exist {
 		delete(mc.mKeys, wkey)
 	}
+	mc.queries.remove(wkey)
+}
+
+// addKey adds a cataloged key and adds it to the items of the matching map it matches
+func (mc *metricCatalog) addKey(key string) {
+	for _, k := range mc.keys {
+		if k == key {
+			return
+		}
+	}
+	mc.keys = append(mc.keys, key)
+	for _, wkey := range mc.queries.matching(key) {
+		mc.mKeys[wkey] = appendIfMissing(mc.mKeys[wkey], key)
+	}
 }
 
-// updateMatchingMap updates the contents of matching map
-func (mc *metricCatalog) updateMatchingMap() {
-	for wkey := range mc.mKeys {
-		// add (or update if exist) item `wkey'
-		mc.addItemToMatchingMap(wkey)
+// removeKey removes a cataloged key and removes it from the items of the matching map it matched
+func (mc *metricCatalog) removeKey(key string) {
+	for i, k := range mc.keys {
+		if k == key {
+			mc.keys = append(mc.keys[:i], mc.keys[i+1:]...)
+			break
+		}
 	}
+	mc.removeMatchedKey(key)
 }
 
-// removeMatchedKey iterates over all items in the mKey and removes `key` from its content
+// removeMatchedKey removes `key` from the content of the items in mKey it matched
 func (mc *metricCatalog) removeMatchedKey(key string) {
-	for wkey, mkeys := range mc.mKeys {
+	for _, wkey := range mc.queries.matching(key) {
+		mkeys := mc.mKeys[wkey]
 		for index, mkey := range mkeys {
 			if mkey == key {
 				// remove this key from slice
 				mc.mKeys[wkey] = append(mkeys[:index], mkeys[index+1:]...)
+				break
 			}
 		}
 		// if no matched key left, remove this item from map
@@ -368,6 +507,11 @@ func (mc *metricCatalog) AddLoadedMetricType(lp *loadedPlugin, mt core.Metric) e
 		labels:             mt.Labels(),
 		policy:             lp.ConfigPolicy.Get(mt.Namespace()),
 	}
+	if d, ok := mt.(core.DescribedMetric); ok {
+		newMt.unit = d.Unit()
+		newMt.description = d.Description()
+	}
+	newMt.seen(time.Now())
 	mc.Add(&newMt)
 	return nil
 }
@@ -377,9 +521,156 @@ func (mc *metricCatalog) AddLoadedMetricType(lp *loadedPlugin, mt core.Metric) e
 func (mc *metricCatalog) RmUnloadedPluginMetrics(lp *loadedPlugin) {
 	mc.mutex.Lock()
 	defer mc.mutex.Unlock()
+	var keys []string
+	mts, _ := mc.tree.Fetch([]string{})
+	for _, mt := range mts {
+		if mt.Plugin.Key() == lp.Key() {
+			keys = appendIfMissing(keys, getMetricKey(mt.Namespace()))
+		}
+	}
 	mc.tree.DeleteByPlugin(lp)
-	// update the contents of matching map (mKeys)
-	mc.updateMatchingMap()
+	// remove the keys no version is cataloged for anymore, which
+	// updates the contents of matching map (mKeys)
+	for _, key := range keys {
+		if mts, _ := mc.tree.Get(getMetricNamespace(key)); len(mts) == 0 {
+			mc.removeKey(key)
+		}
+	}
+}
+
+// RefreshPluginMetrics brings the metrics cataloged for a loaded plugin in line
+// with the metric types it currently advertises. The ones the plugin stopped
+// advertising are removed, unless a task is still subscribed to them, and new
+// metric types are added as long as they fit the metric limits. The matching
+// map is updated along the way.
+func (mc *metricCatalog) RefreshPluginMetrics(lp *loadedPlugin, mts []core.Metric) (added, removed int, err error) {
+	advertised := map[string]bool{}
+	for _, mt := range mts {
+		advertised[fmt.Sprintf("%s:%d", getMetricKey(mt.Namespace()), mt.Version())] = true
+	}
+
+	mc.mutex.Lock()
+	var unadvertised []*metricType
+	cataloged, _ := mc.tree.Fetch([]string{})
+	for _, mt := range cataloged {
+		if mt.Plugin.Key() != lp.Key() || mt.SubscriptionCount() > 0 {
+			continue
+		}
+		if !advertised[fmt.Sprintf("%s:%d", getMetricKey(mt.Namespace()), mt.Version())] {
+			unadvertised = append(unadvertised, mt)
+		}
+	}
+	mc.remove(unadvertised)
+	mc.mutex.Unlock()
+
+	added, err = mc.addPluginMetrics(lp, mts)
+	return added, len(unadvertised), err
+}
+
+// UpdatePluginMetrics applies a catalog update pushed by a running plugin. The
+// metric types the plugin added are cataloged as long as they fit the metric
+// limits, and the ones it removed are removed unless a task is subscribed to
+// them. The namespaces of the added and removed metric types are returned.
+func (mc *metricCatalog) UpdatePluginMetrics(lp *loadedPlugin, added, removed []core.Metric) ([]string, []string, error) {
+	mc.mutex.Lock()
+	var gone []*metricType
+	for _, m := range removed {
+		mt := cataloged(mc.tree, m.Namespace(), m.Version())
+		if mt == nil || mt.Plugin.Key() != lp.Key() || mt.SubscriptionCount() > 0 {
+			continue
+		}
+		gone = append(gone, mt)
+	}
+	mc.remove(gone)
+	mc.mutex.Unlock()
+	removedNss := make([]string, len(gone))
+	for i, mt := range gone {
+		removedNss[i] = mt.NamespaceAsString()
+	}
+
+	var addedNss []string
+	for _, m := range added {
+		if cataloged(mc.tree, m.Namespace(), m.Version()) == nil {
+			addedNss = append(addedNss, core.JoinNamespace(m.Namespace()))
+		}
+	}
+	if _, err := mc.addPluginMetrics(lp, added); err != nil {
+		return nil, removedNss, err
+	}
+	return addedNss, removedNss, nil
+}
+
+// addPluginMetrics catalogs the metric types of the plugin which are not
+// cataloged yet, as long as they fit the metric limits, and records the ones
+// which are as seen. It returns the number of metric types added.
+func (mc *metricCatalog) addPluginMetrics(lp *loadedPlugin, mts []core.Metric) (int, error) {
+	var fresh []core.Metric
+	for _, mt := range mts {
+		if cur := cataloged(mc.tree, mt.Namespace(), mt.Version()); cur != nil {
+			cur.seen(time.Now())
+			continue
+		}
+		fresh = append(fresh, mt)
+	}
+	if err := mc.CheckMetricLimits(lp, len(fresh)); err != nil {
+		return 0, err
+	}
+	for i, mt := range fresh {
+		if err := mc.AddLoadedMetricType(lp, mt); err != nil {
+			return i, err
+		}
+	}
+	return len(fresh), nil
+}
+
+// remove removes the metric types from the tree and updates the matching map.
+// The catalog must be locked.
+func (mc *metricCatalog) remove(mts []*metricType) {
+	var keys []string
+	for _, mt := range mts {
+		mc.tree.RemoveMetric(*mt)
+		keys = appendIfMissing(keys, getMetricKey(mt.Namespace()))
+	}
+	// remove the keys no version is cataloged for anymore
+	for _, key := range keys {
+		if mts, _ := mc.tree.Get(getMetricNamespace(key)); len(mts) == 0 {
+			mc.removeKey(key)
+		}
+	}
+}
+
+// cataloged returns the metric type cataloged with the namespace and version
+// or nil if there is none
+func cataloged(tree *MTTrie, ns []string, ver int) *metricType {
+	mts, _ := tree.Get(ns)
+	for _, mt := range mts {
+		if mt.Version() == ver {
+			return mt
+		}
+	}
+	return nil
+}
+
+// RemoveStaleMetrics removes the metric types of the plugins supporting dynamic
+// metrics which were last seen before the given time and no task is subscribed
+// to, returning the removed metric types. The matching map is updated along
+// the way.
+func (mc *metricCatalog) RemoveStaleMetrics(before time.Time) []*metricType {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	var stale []*metricType
+	mts, _ := mc.tree.Fetch([]string{})
+	for _, mt := range mts {
+		if mt.Plugin == nil || !mt.Plugin.Supports(plugin.FeatureDynamicMetrics) {
+			continue
+		}
+		if mt.SubscriptionCount() > 0 || !mt.LastSeen().Before(before) {
+			continue
+		}
+		stale = append(stale, mt)
+	}
+	mc.remove(stale)
+	return stale
 }
 
 // Add adds a metricType
@@ -387,34 +678,110 @@ func (mc *metricCatalog) Add(m *metricType) {
 	mc.mutex.Lock()
 	defer mc.mutex.Unlock()
 
-	key := getMetricKey(m.Namespace())
+	mc.tree.Add(m)
 
 	// adding key as a cataloged keys (mc.keys)
-	mc.keys = appendIfMissing(mc.keys, key)
-
-	mc.tree.Add(m)
+	mc.addKey(getMetricKey(m.Namespace()))
 }
 
 // Get retrieves a metric given a namespace and version.
 // If provided a version of -1 the latest plugin will be returned.
+// The trie is read from a snapshot so the catalog is not locked.
 func (mc *metricCatalog) Get(ns []string, version int) (*metricType, error) {
+	return mc.get(ns, version)
+}
+
+// Resolve retrieves a metric like Get. If the requested version is pinned but
+// not in the catalog the version fallback policy picks another version.
+func (mc *metricCatalog) Resolve(ns []string, version int) (*metricType, error) {
+	m, err := mc.get(ns, version)
+	if err == nil || version < 1 {
+		return m, err
+	}
+	mts, verr := mc.getVersions(ns)
+	if verr != nil {
+		return nil, err
+	}
+	loaded := make([]int, len(mts))
+	for i, mt := range mts {
+		loaded[i] = mt.Version()
+	}
+	mc.mutex.Lock()
+	policy := mc.versionFallback
+	mc.mutex.Unlock()
+	ver, ok := fallbackVersion(policy, version, loaded)
+	if !ok {
+		return nil, err
+	}
+	return mc.get(ns, ver)
+}
+
+// resolveMetric retrieves the cataloged metric of a requested metric. A
+// metric pinned to a plugin resolves to the exact version of that plugin
+// only, the version fallback policy is never applied to it.
+func resolveMetric(cat catalogsMetrics, mt core.RequestedMetric) (*metricType, error) {
+	name := core.MetricPluginName(mt)
+	if name == "" {
+		return cat.Resolve(mt.Namespace(), mt.Version())
+	}
+	m, err := cat.Get(mt.Namespace(), mt.Version())
+	if err != nil || m.PluginName() != name || m.Version() != mt.Version() {
+		return nil, errorPinnedPluginNotLoaded(mt.Namespace(), name, mt.Version())
+	}
+	return m, nil
+}
+
+// SetVersionFallback sets the policy used by Resolve for pinned versions
+// which are not loaded.
+func (mc *metricCatalog) SetVersionFallback(policy string) {
 	mc.mutex.Lock()
 	defer mc.mutex.Unlock()
-	return mc.get(ns, version)
+	mc.versionFallback = policy
 }
 
-// GetVersions retrieves all versions of a given metric namespace.
-func (mc *metricCatalog) GetVersions(ns []string) ([]*metricType, error) {
+// SetMetricLimits sets the maximum number of metric types cataloged in total
+// and per plugin. A limit of 0 means no limit.
+func (mc *metricCatalog) SetMetricLimits(total, perPlugin int) {
 	mc.mutex.Lock()
 	defer mc.mutex.Unlock()
+	mc.maxMetrics = total
+	mc.maxPluginMetrics = perPlugin
+}
+
+// CheckMetricLimits returns a *metricLimitError if cataloging n more metric
+// types of the plugin would exceed the metric limits.
+func (mc *metricCatalog) CheckMetricLimits(lp *loadedPlugin, n int) error {
+	mc.mutex.Lock()
+	maxMetrics, maxPluginMetrics := mc.maxMetrics, mc.maxPluginMetrics
+	mc.mutex.Unlock()
+	if maxMetrics > 0 {
+		if count := mc.tree.Count([]string{}) + n; count > maxMetrics {
+			return &metricLimitError{limit: "max_catalog_metrics", max: maxMetrics, count: count, plugin: lp}
+		}
+	}
+	if maxPluginMetrics > 0 {
+		count := n
+		for _, mt := range mc.tree.gatherMetricTypes() {
+			if mt.Plugin.Key() == lp.Key() {
+				count++
+			}
+		}
+		if count > maxPluginMetrics {
+			return &metricLimitError{limit: "max_plugin_metrics", max: maxPluginMetrics, count: count, plugin: lp}
+		}
+	}
+	return nil
+}
+
+// GetVersions retrieves all versions of a given metric namespace.
+func (mc *metricCatalog) GetVersions(ns []string) ([]*metricType, error) {
 	return mc.getVersions(ns)
 }
 
-// Fetch transactionally retrieves all metrics which fall under namespace ns
+// Fetch retrieves all metrics which fall under namespace ns from a snapshot
+// of the trie, without locking the catalog
 func (mc *metricCatalog) Fetch(ns []string) ([]*metricType, error) {
-	mc.mutex.Lock()
-	defer mc.mutex.Unlock()
-	mtsi, err := mc.tree.Fetch(ns)
+	mtsi, err := mc.tree.Fetch(mc.Unalias(ns))
 	if err != nil {
 		log.WithFields(log.Fields{
 			"_module": "control",
@@ -427,6 +794,25 @@ func (mc *metricCatalog) Fetch(ns []string) ([]*metricType, error) {
 	return mtsi, nil
 }
 
+// Query returns all cataloged metrics (any version) matching the query q
+func (mc *metricCatalog) Query(q *core.MetricQuery) []*metricType {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	mts := []*metricType{}
+	for _, key := range mc.keys {
+		mtsi, err := mc.tree.Get(getMetricNamespace(key))
+		if err != nil {
+			continue
+		}
+		for _, mt := range mtsi {
+			if q.Match(mt) {
+				mts = append(mts, mt)
+			}
+		}
+	}
+	return mts
+}
+
 // Remove removes a metricType from the catalog and from matching map
 func (mc *metricCatalog) Remove(ns []string) {
 	mc.mutex.Lock()
@@ -435,8 +821,7 @@ func (mc *metricCatalog) Remove(ns []string) {
 	mc.tree.Remove(ns)
 
 	// remove all items from map mKey mapped for this 'ns'
-	key := getMetricKey(ns)
-	mc.removeMatchedKey(key)
+	mc.removeKey(getMetricKey(ns))
 }
 
 // Item returns the current metricType in the collection.  The method Next()
@@ -464,8 +849,9 @@ func (mc *metricCatalog) Next() bool {
 	return true
 }
 
-// Subscribe atomically increments a metric's subscription count in the table.
-func (mc *metricCatalog) Subscribe(ns []string, version int) error {
+// Subscribe records that the task subscribes to the metric. A version less
+// than 1 subscribes to the latest version.
+func (mc *metricCatalog) Subscribe(ns []string, version int, taskID string) error {
 	mc.mutex.Lock()
 	defer mc.mutex.Unlock()
 
@@ -480,16 +866,23 @@ func (mc *metricCatalog) Subscribe(ns []string, version int) error {
 		return err
 	}
 
-	m.Subscribe()
+	m.Subscribe(taskID)
 	return nil
 }
 
-// Unsubscribe atomically decrements a metric's count in the table
-func (mc *metricCatalog) Unsubscribe(ns []string, version int) error {
+// Unsubscribe removes the subscription of the task to the metric. A version
+// less than 1 removes it from all versions since the latest version may have
+// changed since the task subscribed.
+func (mc *metricCatalog) Unsubscribe(ns []string, version int, taskID string) error {
 	mc.mutex.Lock()
 	defer mc.mutex.Unlock()
 
-	m, err := mc.get(ns, version)
+	mts, err := mc.getVersions(ns)
+	if err == nil && version > 0 {
+		var m *metricType
+		m, err = mc.get(ns, version)
+		mts = []*metricType{m}
+	}
 	if err != nil {
 		log.WithFields(log.Fields{
 			"_module": "control",
@@ -499,8 +892,38 @@ func (mc *metricCatalog) Unsubscribe(ns []string, version int) error {
 		}).Error("error getting metrics")
 		return err
 	}
+	for _, m := range mts {
+		m.Unsubscribe(taskID)
+	}
+	return nil
+}
+
+// Subscribers returns the IDs of the tasks subscribed to the metric
+func (mc *metricCatalog) Subscribers(ns []string, version int) ([]string, error) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
 
-	return m.Unsubscribe()
+	m, err := mc.get(ns, version)
+	if err != nil {
+		return nil, err
+	}
+	return m.Subscribers(), nil
+}
+
+// UnsubscribeTask removes all subscriptions of the task
+func (mc *metricCatalog) UnsubscribeTask(taskID string) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	for _, key := range mc.keys {
+		mts, err := mc.tree.Get(getMetricNamespace(key))
+		if err != nil {
+			continue
+		}
+		for _, m := range mts {
+			m.Unsubscribe(taskID)
+		}
+	}
 }
 
 func (mc *metricCatalog) GetPlugin(mns []string, ver int) (*loadedPlugin, error) {
@@ -547,7 +970,7 @@ func (mc *metricCatalog) get(ns []string, ver int) (*metricType, error) {
 }
 
 func (mc *metricCatalog) getVersions(ns []string) ([]*metricType, error) {
-	mts, err := mc.tree.Get(ns)
+	mts, err := mc.tree.Get(mc.Unalias(ns))
 	if err != nil {
 		log.WithFields(log.Fields{
 			"_module": "control",
@@ -24,6 +24,9 @@ import (
 	"time"
 
 	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/control_event"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -169,6 +172,12 @@ func TestMetricMatching(t *testing.T) {
 				So(nss, ShouldBeEmpty)
 				So(err.Error(), ShouldContainSubstring, "Metric not found:")
 			})
+			Convey("malformed query", func() {
+				nss, err := mc.MatchQuery([]string{"mock", "(foo|[asdf", "bar"})
+				So(err, ShouldNotBeNil)
+				So(nss, ShouldBeEmpty)
+				So(err.Error(), ShouldContainSubstring, "Invalid metric query /mock/(foo|[asdf/bar: unclosed tuple")
+			})
 		})
 		Convey("verify query support for dynamic metrics", func() {
 			mc := newMetricCatalog()
@@ -246,10 +255,53 @@ func TestMetricMatching(t *testing.T) {
 				})
 			})
 		})
-
+		Convey("keeps matched namespaces up to date", func() {
+			mc := newMetricCatalog()
+			ts := time.Now()
+			lp := new(loadedPlugin)
+			lp.Meta.Name = "foo"
+			lp2 := new(loadedPlugin)
+			lp2.Meta.Name = "bar"
+			mc.Add(newMetricType([]string{"mock", "foo", "bar"}, ts, lp))
+			nss, err := mc.MatchQuery([]string{"mock", "*", "bar"})
+			So(err, ShouldBeNil)
+			So(nss, ShouldHaveLength, 1)
+			Convey("when metrics are added", func() {
+				mc.Add(newMetricType([]string{"mock", "baz", "bar"}, ts, lp2))
+				mc.Add(newMetricType([]string{"other", "baz", "bar"}, ts, lp2))
+				nss, err := mc.GetQueriedNamespaces([]string{"mock", "*", "bar"})
+				So(err, ShouldBeNil)
+				So(nss, ShouldResemble, [][]string{
+					{"mock", "foo", "bar"},
+					{"mock", "baz", "bar"},
+				})
+				Convey("and when a plugin is unloaded", func() {
+					mc.RmUnloadedPluginMetrics(lp)
+					nss, err := mc.GetQueriedNamespaces([]string{"mock", "*", "bar"})
+					So(err, ShouldBeNil)
+					So(nss, ShouldResemble, [][]string{{"mock", "baz", "bar"}})
+					So(mc.Keys(), ShouldResemble, []string{"mock.baz.bar", "other.baz.bar"})
+				})
+			})
+			Convey("when the last matched metric is removed", func() {
+				mc.Remove([]string{"mock", "foo", "bar"})
+				_, err := mc.GetQueriedNamespaces([]string{"mock", "*", "bar"})
+				So(err, ShouldNotBeNil)
+			})
+		})
 	})
 }
 
+type pinnedMetric struct {
+	namespace  []string
+	version    int
+	pluginName string
+}
+
+func (m pinnedMetric) Namespace() []string { return m.namespace }
+func (m pinnedMetric) Version() int        { return m.version }
+func (m pinnedMetric) PluginName() string  { return m.pluginName }
+
 func TestMetricCatalog(t *testing.T) {
 	Convey("newMetricCatalog()", t, func() {
 		Convey("returns a metricCatalog", func() {
@@ -331,6 +383,97 @@ func TestMetricCatalog(t *testing.T) {
 			So(err.Error(), ShouldContainSubstring, "Metric not found:")
 		})
 	})
+	Convey("metricCatalog.Resolve()", t, func() {
+		mc := newMetricCatalog()
+		ts := time.Now()
+		lp2 := new(loadedPlugin)
+		lp2.Meta.Version = 2
+		lp4 := new(loadedPlugin)
+		lp4.Meta.Version = 4
+		m2 := newMetricType([]string{"foo", "bar"}, ts, lp2)
+		mc.Add(m2)
+		m4 := newMetricType([]string{"foo", "bar"}, ts, lp4)
+		mc.Add(m4)
+		Convey("it returns a loaded version whatever the policy", func() {
+			m, err := mc.Resolve([]string{"foo", "bar"}, 2)
+			So(err, ShouldBeNil)
+			So(m, ShouldEqual, m2)
+		})
+		Convey("it fails for a missing version by default", func() {
+			_, err := mc.Resolve([]string{"foo", "bar"}, 3)
+			So(err.Error(), ShouldContainSubstring, "Metric not found:")
+		})
+		Convey("it substitutes a newer version with the compatible policy", func() {
+			mc.SetVersionFallback(VersionFallbackCompatible)
+			m, err := mc.Resolve([]string{"foo", "bar"}, 3)
+			So(err, ShouldBeNil)
+			So(m, ShouldEqual, m4)
+			_, err = mc.Resolve([]string{"foo", "bar"}, 5)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("it substitutes the latest version with the any policy", func() {
+			mc.SetVersionFallback(VersionFallbackAny)
+			m, err := mc.Resolve([]string{"foo", "bar"}, 5)
+			So(err, ShouldBeNil)
+			So(m, ShouldEqual, m4)
+		})
+		Convey("it does not substitute an unknown namespace", func() {
+			mc.SetVersionFallback(VersionFallbackAny)
+			_, err := mc.Resolve([]string{"foo", "baz"}, 1)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("it never substitutes a metric pinned to a plugin", func() {
+			mc.SetVersionFallback(VersionFallbackAny)
+			lp2.Meta.Name = "mock"
+			lp4.Meta.Name = "mock"
+			m, err := resolveMetric(mc, pinnedMetric{[]string{"foo", "bar"}, 2, "mock"})
+			So(err, ShouldBeNil)
+			So(m, ShouldEqual, m2)
+			_, err = resolveMetric(mc, pinnedMetric{[]string{"foo", "bar"}, 3, "mock"})
+			So(err.Error(), ShouldContainSubstring, "Pinned plugin not loaded:")
+			_, err = resolveMetric(mc, pinnedMetric{[]string{"foo", "bar"}, 4, "mock2"})
+			So(err.Error(), ShouldContainSubstring, "Pinned plugin not loaded:")
+			m, err = resolveMetric(mc, pinnedMetric{[]string{"foo", "bar"}, 3, ""})
+			So(err, ShouldBeNil)
+			So(m, ShouldEqual, m4)
+		})
+	})
+	Convey("metricCatalog.Query()", t, func() {
+		mc := newMetricCatalog()
+		ts := time.Now()
+		lp2 := new(loadedPlugin)
+		lp2.Meta.Name = "psutil"
+		lp2.Meta.Version = 2
+		lp3 := new(loadedPlugin)
+		lp3.Meta.Name = "psutil"
+		lp3.Meta.Version = 3
+		lpm := new(loadedPlugin)
+		lpm.Meta.Name = "mock"
+		lpm.Meta.Version = 3
+		mc.Add(newMetricType([]string{"intel", "cpu", "user"}, ts, lp2))
+		user3 := newMetricType([]string{"intel", "cpu", "user"}, ts, lp3)
+		user3.tags = map[string]string{"unit": "percent"}
+		mc.Add(user3)
+		mc.Add(newMetricType([]string{"intel", "cpu", "idle"}, ts, lp3))
+		mc.Add(newMetricType([]string{"intel", "mock", "foo"}, ts, lpm))
+		Convey("returns the metrics matching all conditions", func() {
+			q, err := core.ParseMetricQuery("ns=/intel/cpu/* AND plugin=psutil AND version>=3 AND tag.unit=percent")
+			So(err, ShouldBeNil)
+			mts := mc.Query(q)
+			So(mts, ShouldHaveLength, 1)
+			So(mts[0], ShouldEqual, user3)
+		})
+		Convey("returns every matching version", func() {
+			q, err := core.ParseMetricQuery("ns=/intel/cpu/user")
+			So(err, ShouldBeNil)
+			So(mc.Query(q), ShouldHaveLength, 2)
+		})
+		Convey("returns nothing when no metric matches", func() {
+			q, err := core.ParseMetricQuery("plugin=psutil AND version>3")
+			So(err, ShouldBeNil)
+			So(mc.Query(q), ShouldBeEmpty)
+		})
+	})
 	Convey("metricCatalog.Table()", t, func() {
 		Convey("returns a copy of the table", func() {
 			mc := newMetricCatalog()
@@ -447,6 +590,206 @@ func TestMetricCatalog(t *testing.T) {
 			})
 		})
 	})
+	Convey("metricCatalog.RefreshPluginMetrics()", t, func() {
+		mc := newMetricCatalog()
+		lp := new(loadedPlugin)
+		lp.Meta.Name = "docker"
+		lp.Meta.Version = 1
+		lp.ConfigPolicy = cpolicy.New()
+		advertise := func(ids ...string) []core.Metric {
+			mts := []core.Metric{}
+			for _, id := range ids {
+				mts = append(mts, &metricType{namespace: []string{"intel", "docker", id, "cpu"}, version: 1})
+			}
+			return mts
+		}
+		added, removed, err := mc.RefreshPluginMetrics(lp, advertise("a"))
+		So(err, ShouldBeNil)
+		So(added, ShouldEqual, 1)
+		So(removed, ShouldEqual, 0)
+		_, err = mc.MatchQuery([]string{"intel", "docker", "*", "cpu"})
+		So(err, ShouldBeNil)
+		Convey("adds the metric types which appeared", func() {
+			added, removed, err := mc.RefreshPluginMetrics(lp, advertise("a", "b"))
+			So(err, ShouldBeNil)
+			So(added, ShouldEqual, 1)
+			So(removed, ShouldEqual, 0)
+			nss, err := mc.GetQueriedNamespaces([]string{"intel", "docker", "*", "cpu"})
+			So(err, ShouldBeNil)
+			So(nss, ShouldResemble, [][]string{
+				{"intel", "docker", "a", "cpu"},
+				{"intel", "docker", "b", "cpu"},
+			})
+		})
+		Convey("removes the metric types which disappeared", func() {
+			added, removed, err := mc.RefreshPluginMetrics(lp, advertise("b"))
+			So(err, ShouldBeNil)
+			So(added, ShouldEqual, 1)
+			So(removed, ShouldEqual, 1)
+			nss, err := mc.GetQueriedNamespaces([]string{"intel", "docker", "*", "cpu"})
+			So(err, ShouldBeNil)
+			So(nss, ShouldResemble, [][]string{{"intel", "docker", "b", "cpu"}})
+		})
+		Convey("drops the queries left without metrics and indexes them again when queried", func() {
+			_, removed, err := mc.RefreshPluginMetrics(lp, advertise())
+			So(err, ShouldBeNil)
+			So(removed, ShouldEqual, 1)
+			_, ok := mc.queries.get("intel.docker.*.cpu")
+			So(ok, ShouldBeFalse)
+			_, _, err = mc.RefreshPluginMetrics(lp, advertise("c"))
+			So(err, ShouldBeNil)
+			nss, err := mc.GetQueriedNamespaces([]string{"intel", "docker", "*", "cpu"})
+			So(err, ShouldBeNil)
+			So(nss, ShouldResemble, [][]string{{"intel", "docker", "c", "cpu"}})
+		})
+		Convey("keeps the metric types tasks are subscribed to", func() {
+			So(mc.Subscribe([]string{"intel", "docker", "a", "cpu"}, 1, "task"), ShouldBeNil)
+			_, removed, err := mc.RefreshPluginMetrics(lp, advertise("b"))
+			So(err, ShouldBeNil)
+			So(removed, ShouldEqual, 0)
+			_, err = mc.Get([]string{"intel", "docker", "a", "cpu"}, 1)
+			So(err, ShouldBeNil)
+		})
+		Convey("refuses the metric types beyond the limit per plugin", func() {
+			mc.SetMetricLimits(0, 2)
+			added, removed, err := mc.RefreshPluginMetrics(lp, advertise("a", "b", "c"))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "control.max_plugin_metrics is 2")
+			So(added, ShouldEqual, 0)
+			So(removed, ShouldEqual, 0)
+			Convey("after removing the ones which disappeared", func() {
+				added, removed, err := mc.RefreshPluginMetrics(lp, advertise("b", "c"))
+				So(err, ShouldBeNil)
+				So(added, ShouldEqual, 2)
+				So(removed, ShouldEqual, 1)
+			})
+		})
+	})
+	Convey("metricCatalog.RemoveStaleMetrics()", t, func() {
+		mc := newMetricCatalog()
+		dynamic := new(loadedPlugin)
+		dynamic.Meta.Name = "docker"
+		dynamic.Meta.Version = 1
+		dynamic.Meta.Features = plugin.FeatureDynamicMetrics
+		dynamic.ConfigPolicy = cpolicy.New()
+		static := new(loadedPlugin)
+		static.Meta.Name = "static"
+		static.Meta.Version = 1
+		static.ConfigPolicy = cpolicy.New()
+		for _, id := range []string{"a", "b", "c"} {
+			So(mc.AddLoadedMetricType(dynamic, &metricType{namespace: []string{"intel", "docker", id, "cpu"}, version: 1}), ShouldBeNil)
+		}
+		So(mc.AddLoadedMetricType(static, &metricType{namespace: []string{"intel", "static", "cpu"}, version: 1}), ShouldBeNil)
+		_, err := mc.MatchQuery([]string{"intel", "docker", "*", "cpu"})
+		So(err, ShouldBeNil)
+		Convey("keeps the metric types seen after the given time", func() {
+			So(mc.RemoveStaleMetrics(time.Now().Add(-time.Minute)), ShouldBeEmpty)
+		})
+		Convey("removes the unseen metric types of dynamic plugins only", func() {
+			So(mc.Subscribe([]string{"intel", "docker", "a", "cpu"}, 1, "task"), ShouldBeNil)
+			m, err := mc.Get([]string{"intel", "docker", "b", "cpu"}, 1)
+			So(err, ShouldBeNil)
+			m.seen(time.Now().Add(time.Minute))
+			stale := mc.RemoveStaleMetrics(time.Now())
+			So(stale, ShouldHaveLength, 1)
+			So(stale[0].Namespace(), ShouldResemble, []string{"intel", "docker", "c", "cpu"})
+			nss, err := mc.GetQueriedNamespaces([]string{"intel", "docker", "*", "cpu"})
+			So(err, ShouldBeNil)
+			So(nss, ShouldResemble, [][]string{
+				{"intel", "docker", "a", "cpu"},
+				{"intel", "docker", "b", "cpu"},
+			})
+			_, err = mc.Get([]string{"intel", "static", "cpu"}, 1)
+			So(err, ShouldBeNil)
+		})
+		Convey("a refresh marks the advertised metric types as seen", func() {
+			before := time.Now()
+			_, _, err := mc.RefreshPluginMetrics(dynamic, []core.Metric{
+				&metricType{namespace: []string{"intel", "docker", "a", "cpu"}, version: 1},
+			})
+			So(err, ShouldBeNil)
+			m, err := mc.Get([]string{"intel", "docker", "a", "cpu"}, 1)
+			So(err, ShouldBeNil)
+			So(m.LastSeen().Before(before), ShouldBeFalse)
+		})
+	})
+	Convey("metricCatalog.UpdatePluginMetrics()", t, func() {
+		mc := newMetricCatalog()
+		lp := new(loadedPlugin)
+		lp.Meta.Name = "disk"
+		lp.Meta.Version = 1
+		lp.ConfigPolicy = cpolicy.New()
+		disk := func(ids ...string) []core.Metric {
+			mts := []core.Metric{}
+			for _, id := range ids {
+				mts = append(mts, &metricType{namespace: []string{"intel", "disk", id, "reads"}, version: 1})
+			}
+			return mts
+		}
+		_, _, err := mc.UpdatePluginMetrics(lp, disk("sda", "sdb"), nil)
+		So(err, ShouldBeNil)
+		_, err = mc.MatchQuery([]string{"intel", "disk", "*", "reads"})
+		So(err, ShouldBeNil)
+		Convey("adds and removes the metric types and updates the matching map", func() {
+			added, removed, err := mc.UpdatePluginMetrics(lp, disk("sdb", "sdc"), disk("sda"))
+			So(err, ShouldBeNil)
+			So(added, ShouldResemble, []string{"/intel/disk/sdc/reads"})
+			So(removed, ShouldResemble, []string{"/intel/disk/sda/reads"})
+			nss, err := mc.GetQueriedNamespaces([]string{"intel", "disk", "*", "reads"})
+			So(err, ShouldBeNil)
+			So(nss, ShouldResemble, [][]string{
+				{"intel", "disk", "sdb", "reads"},
+				{"intel", "disk", "sdc", "reads"},
+			})
+		})
+		Convey("keeps the removed metric types tasks are subscribed to", func() {
+			So(mc.Subscribe([]string{"intel", "disk", "sda", "reads"}, 1, "task"), ShouldBeNil)
+			_, removed, err := mc.UpdatePluginMetrics(lp, nil, disk("sda"))
+			So(err, ShouldBeNil)
+			So(removed, ShouldBeEmpty)
+		})
+		Convey("refuses additions beyond the metric limits", func() {
+			mc.SetMetricLimits(2, 0)
+			added, _, err := mc.UpdatePluginMetrics(lp, disk("sdc"), nil)
+			So(err, ShouldNotBeNil)
+			So(added, ShouldBeEmpty)
+		})
+	})
+	Convey("metricCatalog.CheckMetricLimits()", t, func() {
+		mc := newMetricCatalog()
+		lp := new(loadedPlugin)
+		lp.Meta.Name = "dynamic"
+		lp.Meta.Version = 1
+		other := new(loadedPlugin)
+		other.Meta.Name = "other"
+		other.Meta.Version = 1
+		ts := time.Now()
+		mc.Add(newMetricType([]string{"intel", "dynamic", "a"}, ts, lp))
+		mc.Add(newMetricType([]string{"intel", "other", "a"}, ts, other))
+		Convey("allows anything without limits", func() {
+			So(mc.CheckMetricLimits(lp, 1000), ShouldBeNil)
+		})
+		Convey("refuses to exceed the catalog limit", func() {
+			mc.SetMetricLimits(3, 0)
+			So(mc.CheckMetricLimits(lp, 1), ShouldBeNil)
+			err := mc.CheckMetricLimits(lp, 2)
+			So(err, ShouldNotBeNil)
+			le, ok := err.(*metricLimitError)
+			So(ok, ShouldBeTrue)
+			So(le.event(), ShouldResemble, &control_event.MetricLimitExceededEvent{
+				Limit:         "max_catalog_metrics",
+				Max:           3,
+				Count:         4,
+				PluginName:    "dynamic",
+				PluginVersion: 1,
+			})
+		})
+		Convey("counts the metric types of the plugin only for the plugin limit", func() {
+			mc.SetMetricLimits(0, 2)
+			So(mc.CheckMetricLimits(lp, 1), ShouldBeNil)
+			So(mc.CheckMetricLimits(lp, 2), ShouldNotBeNil)
+		})
+	})
 }
 
 func TestSubscribe(t *testing.T) {
@@ -468,17 +811,22 @@ func TestSubscribe(t *testing.T) {
 	}
 	Convey("when the metric is not in the table", t, func() {
 		Convey("then it returns an error", func() {
-			err := mc.Subscribe([]string{"test4"}, -1)
+			err := mc.Subscribe([]string{"test4"}, -1, "task1")
 			So(err.Error(), ShouldContainSubstring, "Metric not found:")
 		})
 	})
 	Convey("when the metric is in the table", t, func() {
-		Convey("then it gets correctly increments the count", func() {
-			err := mc.Subscribe([]string{"test1"}, -1)
+		Convey("then it records the subscribing task once", func() {
+			err := mc.Subscribe([]string{"test1"}, -1, "task1")
+			So(err, ShouldBeNil)
+			err = mc.Subscribe([]string{"test1"}, -1, "task1")
 			So(err, ShouldBeNil)
 			m, err2 := mc.Get([]string{"test1"}, -1)
 			So(err2, ShouldBeNil)
-			So(m.subscriptions, ShouldEqual, 1)
+			So(m.SubscriptionCount(), ShouldEqual, 1)
+			subs, err3 := mc.Subscribers([]string{"test1"}, -1)
+			So(err3, ShouldBeNil)
+			So(subs, ShouldResemble, []string{"task1"})
 		})
 	})
 }
@@ -501,40 +849,57 @@ func TestUnsubscribe(t *testing.T) {
 		mc.Add(v)
 	}
 	Convey("when the metric is in the table", t, func() {
-		Convey("then its subscription count is decremented", func() {
-			err := mc.Subscribe([]string{"test1"}, -1)
+		Convey("then the task's subscription is removed", func() {
+			So(mc.Subscribe([]string{"test1"}, -1, "task1"), ShouldBeNil)
+			So(mc.Subscribe([]string{"test1"}, -1, "task2"), ShouldBeNil)
+			So(mc.Unsubscribe([]string{"test1"}, -1, "task1"), ShouldBeNil)
+			m, err := mc.Get([]string{"test1"}, -1)
 			So(err, ShouldBeNil)
-			err1 := mc.Unsubscribe([]string{"test1"}, -1)
-			So(err1, ShouldBeNil)
-			m, err2 := mc.Get([]string{"test1"}, -1)
-			So(err2, ShouldBeNil)
-			So(m.subscriptions, ShouldEqual, 0)
+			So(m.Subscribers(), ShouldResemble, []string{"task2"})
 		})
 	})
 	Convey("when the metric is not in the table", t, func() {
 		Convey("then it returns metric not found error", func() {
-			err := mc.Unsubscribe([]string{"test4"}, -1)
+			err := mc.Unsubscribe([]string{"test4"}, -1, "task1")
 			So(err.Error(), ShouldContainSubstring, "Metric not found:")
 		})
 	})
-	Convey("when the metric's count is already 0", t, func() {
-		Convey("then it returns negative subscription count error", func() {
-			err := mc.Unsubscribe([]string{"test1"}, -1)
-			So(err, ShouldResemble, errNegativeSubCount)
+	Convey("when the task is not subscribed", t, func() {
+		Convey("then unsubscribing does nothing", func() {
+			So(mc.Unsubscribe([]string{"test2"}, -1, "task1"), ShouldBeNil)
+			So(mc.Unsubscribe([]string{"test2"}, -1, "task1"), ShouldBeNil)
+			m, err := mc.Get([]string{"test2"}, -1)
+			So(err, ShouldBeNil)
+			So(m.SubscriptionCount(), ShouldEqual, 0)
+		})
+	})
+	Convey("when a task is deleted", t, func() {
+		Convey("then all its subscriptions are removed", func() {
+			So(mc.Subscribe([]string{"test1"}, -1, "task3"), ShouldBeNil)
+			So(mc.Subscribe([]string{"test3"}, -1, "task3"), ShouldBeNil)
+			mc.UnsubscribeTask("task3")
+			for _, n := range ns {
+				subs, err := mc.Subscribers(n, -1)
+				So(err, ShouldBeNil)
+				So(subs, ShouldNotContain, "task3")
+			}
 		})
 	})
 }
 
 func TestSubscriptionCount(t *testing.T) {
 	m := newMetricType([]string{"test"}, time.Now(), &loadedPlugin{})
-	Convey("it returns the subscription count", t, func() {
-		m.Subscribe()
+	Convey("it returns the number of subscribed tasks", t, func() {
+		m.Subscribe("task1")
 		So(m.SubscriptionCount(), ShouldEqual, 1)
-		m.Subscribe()
-		m.Subscribe()
+		m.Subscribe("task2")
+		m.Subscribe("task3")
+		m.Subscribe("task3")
 		So(m.SubscriptionCount(), ShouldEqual, 3)
-		m.Unsubscribe()
+		m.Unsubscribe("task2")
+		m.Unsubscribe("task2")
 		So(m.SubscriptionCount(), ShouldEqual, 2)
+		So(m.Subscribers(), ShouldResemble, []string{"task1", "task3"})
 	})
 }
 
@@ -22,6 +22,8 @@ package control
 import (
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 )
 
 /*
@@ -47,6 +49,10 @@ manner:
 FETCH /metric/root/foo -> trie.Fetch([]string{"root", "foo"}) ->
     [a,b]
 
+Nodes are never modified once they can be reached from the root. A write
+copies the nodes on the path it changes and then swaps in the new root, so
+reads work on a consistent snapshot of the trie without taking a lock and
+a Fetch of the whole trie does not hold up writers or other readers.
 */
 
 // ErrNotFound is returned when Get cannot find the given namespace
@@ -55,19 +61,27 @@ var ErrNotFound = errors.New("metric not found")
 type mttNode struct {
 	children map[string]*mttNode
 	mts      map[int]*metricType
+	// count is the number of metric types in this node and below it
+	count int
 }
 
 // MTTrie struct representing the root in the trie
 type MTTrie struct {
-	*mttNode
+	// root holds the current *mttNode snapshot
+	root atomic.Value
+	// mutex serializes writers
+	mutex sync.Mutex
 }
 
 // NewMTTrie returns an empty trie
 func NewMTTrie() *MTTrie {
-	m := &mttNode{
-		children: map[string]*mttNode{},
-	}
-	return &MTTrie{m}
+	m := &MTTrie{}
+	m.root.Store(&mttNode{})
+	return m
+}
+
+func (m *MTTrie) snapshot() *mttNode {
+	return m.root.Load().(*mttNode)
 }
 
 // String prints out of the tr(i)e
@@ -79,115 +93,80 @@ func (m *MTTrie) String() string {
 	return out
 }
 
-func (m *MTTrie) gatherMetricTypes() []metricType {
-	var mts []metricType
-	var children []*mttNode
-	for _, node := range m.children {
-		children = gatherChildren(children, node)
-	}
-	for _, c := range children {
-		for _, mt := range c.mts {
-			mts = append(mts, *mt)
-		}
-	}
-	return mts
+func (m *MTTrie) gatherMetricTypes() []*metricType {
+	root := m.snapshot()
+	return root.gather(make([]*metricType, 0, root.count))
 }
 
 // DeleteByPlugin removes all metrics from the catalog if they match a loadedPlugin
 func (m *MTTrie) DeleteByPlugin(lp *loadedPlugin) {
-	for _, mt := range m.gatherMetricTypes() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	root := m.snapshot()
+	for _, mt := range root.gather(nil) {
 		if mt.Plugin.Key() == lp.Key() {
-			// Remove this metric
-			m.RemoveMetric(mt)
+			root = root.removeMetric(mt.Namespace(), mt.Version())
 		}
 	}
+	m.root.Store(root)
 }
 
 // RemoveMetric removes a specific metric by namespace and version from the tree
 func (m *MTTrie) RemoveMetric(mt metricType) {
-	a, _ := m.find(mt.Namespace())
-	if a != nil {
-		for v, x := range a.mts {
-			if mt.Version() == x.Version() {
-				// Delete the metric from the node
-				delete(a.mts, v)
-			}
-		}
-	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.root.Store(m.snapshot().removeMetric(mt.Namespace(), mt.Version()))
 }
 
 // Add adds a node with the given namespace with the
 // given MetricType
-func (mtt *mttNode) Add(mt *metricType) {
-	ns := mt.Namespace()
-	node, index := mtt.walk(ns)
-	if index == len(ns) {
-		if node.mts == nil {
-			node.mts = make(map[int]*metricType)
-		}
-		node.mts[mt.Version()] = mt
-		return
-	}
-	// walk through the remaining namespace and build out the
-	// new branch in the trie.
-	for _, n := range ns[index:] {
-		if node.children == nil {
-			node.children = make(map[string]*mttNode)
-		}
-		node.children[n] = &mttNode{}
-		node = node.children[n]
-	}
-	node.mts = make(map[int]*metricType)
-	node.mts[mt.Version()] = mt
+func (m *MTTrie) Add(mt *metricType) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.root.Store(m.snapshot().add(mt.Namespace(), mt))
 }
 
 // Fetch collects all children below a given namespace
 // and concatenates their metric types into a single slice
-func (mtt *mttNode) Fetch(ns []string) ([]*metricType, error) {
-	node, err := mtt.find(ns)
+func (m *MTTrie) Fetch(ns []string) ([]*metricType, error) {
+	node, err := m.snapshot().find(ns)
 	if err != nil {
 		return nil, err
 	}
-
-	var children []*mttNode
-	if node.mts != nil {
-		children = append(children, node)
-	}
-	if node.children != nil {
-		children = gatherChildren(children, node)
+	if node.count == 0 {
+		return nil, nil
 	}
+	return node.gather(make([]*metricType, 0, node.count)), nil
+}
 
-	var mts []*metricType
-	for _, child := range children {
-		for _, mt := range child.mts {
-			mts = append(mts, mt)
-		}
+// Count returns the number of metric types at and below the given namespace
+func (m *MTTrie) Count(ns []string) int {
+	node, err := m.snapshot().find(ns)
+	if err != nil {
+		return 0
 	}
-
-	return mts, nil
+	return node.count
 }
 
 // Remove removes all children below a given namespace
-func (mtt *mttNode) Remove(ns []string) error {
-	_, err := mtt.find(ns)
-	if err != nil {
-		return err
+func (m *MTTrie) Remove(ns []string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	root := m.snapshot()
+	if len(ns) == 0 {
+		return errorMetricNotFound(ns)
 	}
-
-	//remove node from parent
-	parent, err := mtt.find(ns[:len(ns)-1])
-	if err != nil {
+	if _, err := root.find(ns); err != nil {
 		return err
 	}
-	delete(parent.children, ns[len(ns)-1:][0])
-
+	m.root.Store(root.remove(ns))
 	return nil
 }
 
 // Get works like fetch, but only returns the MT at the given node
 // and does not gather the node's children.
-func (mtt *mttNode) Get(ns []string) ([]*metricType, error) {
-	node, err := mtt.find(ns)
+func (m *MTTrie) Get(ns []string) ([]*metricType, error) {
+	node, err := m.snapshot().find(ns)
 	if err != nil {
 		return nil, err
 	}
@@ -201,38 +180,117 @@ func (mtt *mttNode) Get(ns []string) ([]*metricType, error) {
 	return mts, nil
 }
 
-// walk returns the last leaf / branch present
-// in the trie and the index in the namespace that the last node exists.
-func (mtt *mttNode) walk(ns []string) (*mttNode, int) {
-	parent := mtt
-	var pp *mttNode
-	for i, n := range ns {
-		if parent.children == nil {
-			return parent, i
+func (mtt *mttNode) find(ns []string) (*mttNode, error) {
+	node := mtt
+	for _, n := range ns {
+		node = node.children[n]
+		if node == nil {
+			return nil, errorMetricNotFound(ns)
 		}
-		pp = parent
-		parent = parent.children[n]
-		if parent == nil {
-			return pp, i
+	}
+	return node, nil
+}
+
+// gather appends the metric types of the node and all nodes below it to mts
+func (mtt *mttNode) gather(mts []*metricType) []*metricType {
+	for _, mt := range mtt.mts {
+		mts = append(mts, mt)
+	}
+	for _, child := range mtt.children {
+		if child.count > 0 {
+			mts = child.gather(mts)
 		}
 	}
-	return parent, len(ns)
+	return mts
 }
 
-func (mtt *mttNode) find(ns []string) (*mttNode, error) {
-	node, index := mtt.walk(ns)
-	if index != len(ns) {
-		return nil, errorMetricNotFound(ns)
+// withChild returns a copy of the node with the child n replaced
+func (mtt *mttNode) withChild(n string, child *mttNode) *mttNode {
+	c := &mttNode{
+		children: make(map[string]*mttNode, len(mtt.children)+1),
+		mts:      mtt.mts,
+		count:    mtt.count + child.count,
 	}
-	return node, nil
+	for k, v := range mtt.children {
+		c.children[k] = v
+	}
+	if old, ok := mtt.children[n]; ok {
+		c.count -= old.count
+	}
+	c.children[n] = child
+	return c
+}
+
+// withMetricTypes returns a copy of the node holding the given metric types
+func (mtt *mttNode) withMetricTypes(mts map[int]*metricType) *mttNode {
+	return &mttNode{
+		children: mtt.children,
+		mts:      mts,
+		count:    mtt.count - len(mtt.mts) + len(mts),
+	}
+}
+
+// add returns a copy of the node with mt added at ns below it
+func (mtt *mttNode) add(ns []string, mt *metricType) *mttNode {
+	if len(ns) == 0 {
+		mts := make(map[int]*metricType, len(mtt.mts)+1)
+		for v, x := range mtt.mts {
+			mts[v] = x
+		}
+		mts[mt.Version()] = mt
+		return mtt.withMetricTypes(mts)
+	}
+	child := mtt.children[ns[0]]
+	if child == nil {
+		child = &mttNode{}
+	}
+	return mtt.withChild(ns[0], child.add(ns[1:], mt))
 }
 
-func gatherChildren(children []*mttNode, node *mttNode) []*mttNode {
-	for _, child := range node.children {
-		if child.children != nil {
-			children = gatherChildren(children, child)
+// removeMetric returns a copy of the node without the given version of the
+// metric at ns below it
+func (mtt *mttNode) removeMetric(ns []string, version int) *mttNode {
+	if len(ns) == 0 {
+		if _, ok := mtt.mts[version]; !ok {
+			return mtt
+		}
+		mts := make(map[int]*metricType, len(mtt.mts))
+		for v, x := range mtt.mts {
+			if v != version {
+				mts[v] = x
+			}
+		}
+		return mtt.withMetricTypes(mts)
+	}
+	child := mtt.children[ns[0]]
+	if child == nil {
+		return mtt
+	}
+	nc := child.removeMetric(ns[1:], version)
+	if nc == child {
+		return mtt
+	}
+	return mtt.withChild(ns[0], nc)
+}
+
+// remove returns a copy of the node without the branch at ns below it
+func (mtt *mttNode) remove(ns []string) *mttNode {
+	child := mtt.children[ns[0]]
+	if child == nil {
+		return mtt
+	}
+	if len(ns) > 1 {
+		return mtt.withChild(ns[0], child.remove(ns[1:]))
+	}
+	c := &mttNode{
+		children: make(map[string]*mttNode, len(mtt.children)),
+		mts:      mtt.mts,
+		count:    mtt.count - child.count,
+	}
+	for k, v := range mtt.children {
+		if k != ns[0] {
+			c.children[k] = v
 		}
-		children = append(children, child)
 	}
-	return children
+	return c
 }
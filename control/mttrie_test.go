@@ -138,4 +138,40 @@ func TestTrie(t *testing.T) {
 			So(err.Error(), ShouldContainSubstring, "Metric not found:")
 		})
 	})
+	Convey("Count", t, func() {
+		trie := NewMTTrie()
+		lp := new(loadedPlugin)
+		lp.Meta.Name = "foo"
+		lp.Meta.Version = 1
+		lp2 := new(loadedPlugin)
+		lp2.Meta.Name = "foo"
+		lp2.Meta.Version = 2
+		trie.Add(newMetricType([]string{"intel", "foo", "bar"}, time.Now(), lp))
+		trie.Add(newMetricType([]string{"intel", "foo", "bar"}, time.Now(), lp2))
+		trie.Add(newMetricType([]string{"intel", "foo", "baz"}, time.Now(), lp))
+		trie.Add(newMetricType([]string{"intel", "qux"}, time.Now(), lp2))
+		Convey("counts the metric types below a namespace", func() {
+			So(trie.Count([]string{}), ShouldEqual, 4)
+			So(trie.Count([]string{"intel", "foo"}), ShouldEqual, 3)
+			So(trie.Count([]string{"intel", "foo", "bar"}), ShouldEqual, 2)
+			So(trie.Count([]string{"not", "present"}), ShouldEqual, 0)
+		})
+		Convey("keeps counts up to date when metrics are removed", func() {
+			trie.DeleteByPlugin(lp)
+			So(trie.Count([]string{}), ShouldEqual, 2)
+			So(trie.Count([]string{"intel", "foo"}), ShouldEqual, 1)
+			So(trie.Remove([]string{"intel", "foo"}), ShouldBeNil)
+			So(trie.Count([]string{}), ShouldEqual, 1)
+			_, err := trie.Fetch([]string{"intel", "foo"})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("reads are not affected by later writes", func() {
+			snapshot := trie.snapshot()
+			trie.Add(newMetricType([]string{"intel", "foo", "new"}, time.Now(), lp))
+			trie.DeleteByPlugin(lp2)
+			So(snapshot.count, ShouldEqual, 4)
+			So(len(snapshot.gather(nil)), ShouldEqual, 4)
+			So(trie.Count([]string{}), ShouldEqual, 3)
+		})
+	})
 }
@@ -0,0 +1,218 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultChunkSize is the default size in bytes of the chunks the metric
+// batches larger than it are transferred in
+const DefaultChunkSize = 1 << 20
+
+// chunkTransferTimeout is how long a partial transfer is kept without a
+// chunk being sent or fetched
+const chunkTransferTimeout = time.Minute
+
+// ErrUnknownTransfer is returned for a chunk of a transfer the plugin does
+// not hold, e.g. one which timed out
+var ErrUnknownTransfer = errors.New("unknown chunked transfer")
+
+// PutChunkArgs is a chunk of the args of a call snapd sends before calling
+// CallChunked with the transfer
+type PutChunkArgs struct {
+	Transfer uint64
+	// Offset is where the chunk starts in the args, the chunks being sent
+	// in order
+	Offset int
+	// Total is the size of the args
+	Total int
+	Data  []byte
+}
+
+// CallChunkedArgs calls a method of the plugin, e.g. Collector.CollectMetrics,
+// with either the args given or those sent in the chunks of a transfer
+type CallChunkedArgs struct {
+	Method   string
+	Args     []byte
+	Transfer uint64
+}
+
+// CallChunkedReply holds the first chunk of the reply of the method. The
+// rest of a reply larger than that is fetched with GetChunk.
+type CallChunkedReply struct {
+	Transfer uint64
+	// Total is the size of the reply
+	Total int
+	Data  []byte
+}
+
+// GetChunkArgs fetches the chunk of a reply starting at the offset
+type GetChunkArgs struct {
+	Transfer uint64
+	Offset   int
+}
+
+// GetChunkReply holds a chunk of a reply
+type GetChunkReply struct {
+	Data []byte
+}
+
+// rpcHandler is a method of a plugin proxy
+type rpcHandler func(args []byte, reply *[]byte) error
+
+// chunkTransfer is the args or the reply of a call being transferred
+type chunkTransfer struct {
+	data    []byte
+	total   int
+	touched time.Time
+}
+
+// chunkTransfers holds the transfers in progress of a session
+type chunkTransfers struct {
+	sync.Mutex
+	handlers map[string]rpcHandler
+	// args are the args being received, by the transfer of snapd
+	args map[uint64]*chunkTransfer
+	// replies are the replies being fetched, by the transfer of the plugin
+	replies map[uint64]*chunkTransfer
+	next    uint64
+}
+
+func newChunkTransfers() *chunkTransfers {
+	return &chunkTransfers{
+		handlers: map[string]rpcHandler{},
+		args:     map[uint64]*chunkTransfer{},
+		replies:  map[uint64]*chunkTransfer{},
+	}
+}
+
+// handle makes a method of a plugin proxy callable with CallChunked
+func (c *chunkTransfers) handle(method string, h rpcHandler) {
+	c.Lock()
+	defer c.Unlock()
+	c.handlers[method] = h
+}
+
+// expire drops the transfers untouched for longer than the timeout. The
+// transfers must be locked.
+func (c *chunkTransfers) expire(now time.Time) {
+	for id, t := range c.args {
+		if now.Sub(t.touched) > chunkTransferTimeout {
+			delete(c.args, id)
+		}
+	}
+	for id, t := range c.replies {
+		if now.Sub(t.touched) > chunkTransferTimeout {
+			delete(c.replies, id)
+		}
+	}
+}
+
+// PutChunk receives a chunk of the args of a call
+func (s *SessionState) PutChunk(args PutChunkArgs, reply *[]byte) error {
+	s.ResetHeartbeat()
+	c := s.chunks
+	c.Lock()
+	defer c.Unlock()
+	now := time.Now()
+	c.expire(now)
+	t, ok := c.args[args.Transfer]
+	if !ok {
+		if args.Offset != 0 {
+			return fmt.Errorf("%v: %d", ErrUnknownTransfer, args.Transfer)
+		}
+		t = &chunkTransfer{data: make([]byte, 0, args.Total), total: args.Total}
+		c.args[args.Transfer] = t
+	}
+	if args.Offset != len(t.data) || args.Total != t.total || len(t.data)+len(args.Data) > t.total {
+		delete(c.args, args.Transfer)
+		return fmt.Errorf("chunk of %d bytes at %d does not follow the %d of %d bytes received of transfer %d", len(args.Data), args.Offset, len(t.data), t.total, args.Transfer)
+	}
+	t.data = append(t.data, args.Data...)
+	t.touched = now
+	*reply = []byte{}
+	return nil
+}
+
+// CallChunked calls a method of the plugin with the args given or
+// received in chunks, replying with the first chunk of its reply
+func (s *SessionState) CallChunked(args CallChunkedArgs, reply *CallChunkedReply) error {
+	c := s.chunks
+	c.Lock()
+	h, ok := c.handlers[args.Method]
+	in := args.Args
+	if args.Transfer != 0 {
+		t, tok := c.args[args.Transfer]
+		delete(c.args, args.Transfer)
+		if !tok || len(t.data) != t.total {
+			c.Unlock()
+			return fmt.Errorf("%v: args of %s were not fully received in transfer %d", ErrUnknownTransfer, args.Method, args.Transfer)
+		}
+		in = t.data
+	}
+	c.Unlock()
+	if !ok {
+		return fmt.Errorf("rpc: can't find method %s", args.Method)
+	}
+
+	var out []byte
+	if err := h(in, &out); err != nil {
+		return err
+	}
+	reply.Total = len(out)
+	if s.ChunkSize <= 0 || len(out) <= s.ChunkSize {
+		reply.Data = out
+		return nil
+	}
+	reply.Transfer = atomic.AddUint64(&c.next, 1)
+	reply.Data = out[:s.ChunkSize]
+	c.Lock()
+	c.replies[reply.Transfer] = &chunkTransfer{data: out, total: len(out), touched: time.Now()}
+	c.Unlock()
+	return nil
+}
+
+// GetChunk replies with a chunk of the reply of a call, dropping the reply
+// once its last chunk is fetched
+func (s *SessionState) GetChunk(args GetChunkArgs, reply *GetChunkReply) error {
+	s.ResetHeartbeat()
+	c := s.chunks
+	c.Lock()
+	defer c.Unlock()
+	now := time.Now()
+	c.expire(now)
+	t, ok := c.replies[args.Transfer]
+	if !ok || args.Offset < 0 || args.Offset >= t.total {
+		return fmt.Errorf("%v: %d at %d", ErrUnknownTransfer, args.Transfer, args.Offset)
+	}
+	end := args.Offset + s.ChunkSize
+	if end >= t.total {
+		end = t.total
+		delete(c.replies, args.Transfer)
+	}
+	reply.Data = t.data[args.Offset:end]
+	t.touched = now
+	return nil
+}
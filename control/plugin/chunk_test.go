@@ -0,0 +1,87 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestChunkedCalls(t *testing.T) {
+	Convey("Given a session chunking the replies in chunks of 4 bytes", t, func() {
+		ss := &SessionState{
+			Arg:    &Arg{ChunkSize: 4},
+			chunks: newChunkTransfers(),
+		}
+		// the method replies with its args twice
+		ss.chunks.handle("Processor.Process", func(args []byte, reply *[]byte) error {
+			*reply = append(append([]byte{}, args...), args...)
+			return nil
+		})
+
+		Convey("a call with small args and reply is made in one round trip", func() {
+			var r CallChunkedReply
+			So(ss.CallChunked(CallChunkedArgs{Method: "Processor.Process", Args: []byte("ab")}, &r), ShouldBeNil)
+			So(string(r.Data), ShouldEqual, "abab")
+			So(r.Total, ShouldEqual, 4)
+		})
+		Convey("args received in chunks are reassembled and the reply is fetched in chunks", func() {
+			args := []byte("0123456789")
+			for off := 0; off < len(args); off += 4 {
+				end := off + 4
+				if end > len(args) {
+					end = len(args)
+				}
+				So(ss.PutChunk(PutChunkArgs{Transfer: 7, Offset: off, Total: len(args), Data: args[off:end]}, &[]byte{}), ShouldBeNil)
+			}
+			var r CallChunkedReply
+			So(ss.CallChunked(CallChunkedArgs{Method: "Processor.Process", Transfer: 7}, &r), ShouldBeNil)
+			So(r.Total, ShouldEqual, 20)
+			So(string(r.Data), ShouldEqual, "0123")
+			reply := r.Data
+			for len(reply) < r.Total {
+				var gr GetChunkReply
+				So(ss.GetChunk(GetChunkArgs{Transfer: r.Transfer, Offset: len(reply)}, &gr), ShouldBeNil)
+				reply = append(reply, gr.Data...)
+			}
+			So(bytes.Equal(reply, []byte("01234567890123456789")), ShouldBeTrue)
+
+			Convey("and the transfers are dropped once done", func() {
+				So(ss.chunks.args, ShouldBeEmpty)
+				So(ss.chunks.replies, ShouldBeEmpty)
+				So(ss.GetChunk(GetChunkArgs{Transfer: r.Transfer, Offset: 16}, &GetChunkReply{}), ShouldNotBeNil)
+			})
+		})
+		Convey("a chunk out of order fails the transfer", func() {
+			So(ss.PutChunk(PutChunkArgs{Transfer: 8, Offset: 0, Total: 8, Data: []byte("0123")}, &[]byte{}), ShouldBeNil)
+			So(ss.PutChunk(PutChunkArgs{Transfer: 8, Offset: 2, Total: 8, Data: []byte("4567")}, &[]byte{}), ShouldNotBeNil)
+			So(ss.CallChunked(CallChunkedArgs{Method: "Processor.Process", Transfer: 8}, &CallChunkedReply{}), ShouldNotBeNil)
+		})
+		Convey("args not fully received are refused", func() {
+			So(ss.PutChunk(PutChunkArgs{Transfer: 9, Offset: 0, Total: 8, Data: []byte("0123")}, &[]byte{}), ShouldBeNil)
+			So(ss.CallChunked(CallChunkedArgs{Method: "Processor.Process", Transfer: 9}, &CallChunkedReply{}), ShouldNotBeNil)
+		})
+		Convey("an unknown method is an error", func() {
+			So(ss.CallChunked(CallChunkedArgs{Method: "Publisher.Publish"}, &CallChunkedReply{}), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,114 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+)
+
+var chunkLogger = log.WithField("_module", "client-chunk")
+
+// chunker calls the methods exchanging metric batches through
+// SessionState.CallChunked, the args and replies larger than the chunk size
+// being transferred in chunks a plugin reassembles, or snapd does.
+type chunker struct {
+	// call calls a method with args, decoding its reply into reply
+	call func(method string, args interface{}, reply interface{}) error
+	size int
+	// max is the max message size, none when 0
+	max int
+	// next is the last transfer started, accessed atomically
+	next uint64
+}
+
+// do calls the method with the encoded args and returns its encoded reply
+func (c *chunker) do(method string, args []byte) ([]byte, error) {
+	start := time.Now()
+	ca := plugin.CallChunkedArgs{Method: method}
+	chunks, sent := 0, 0
+	if len(args) > c.size {
+		ca.Transfer = atomic.AddUint64(&c.next, 1)
+		for sent < len(args) {
+			end := sent + c.size
+			if end > len(args) {
+				end = len(args)
+			}
+			pa := plugin.PutChunkArgs{
+				Transfer: ca.Transfer,
+				Offset:   sent,
+				Total:    len(args),
+				Data:     args[sent:end],
+			}
+			if err := c.call("SessionState.PutChunk", pa, &[]byte{}); err != nil {
+				return nil, err
+			}
+			chunks++
+			sent = end
+		}
+	} else {
+		ca.Args = args
+	}
+
+	var r plugin.CallChunkedReply
+	if err := c.call("SessionState.CallChunked", ca, &r); err != nil {
+		return nil, err
+	}
+	total := len(r.Data)
+	if r.Total > total {
+		total = r.Total
+	}
+	if err := checkMessageSize(method, "reply", total, c.max); err != nil {
+		return nil, err
+	}
+	reply := r.Data
+	if r.Total > len(r.Data) {
+		reply = make([]byte, len(r.Data), r.Total)
+		copy(reply, r.Data)
+		chunks++
+		for len(reply) < r.Total {
+			var gr plugin.GetChunkReply
+			if err := c.call("SessionState.GetChunk", plugin.GetChunkArgs{Transfer: r.Transfer, Offset: len(reply)}, &gr); err != nil {
+				return nil, err
+			}
+			if len(gr.Data) == 0 || len(reply)+len(gr.Data) > r.Total {
+				return nil, errors.New("chunk of the reply of " + method + " does not fit")
+			}
+			reply = append(reply, gr.Data...)
+			chunks++
+		}
+	}
+	if chunks > 0 {
+		chunkLogger.WithFields(log.Fields{
+			"_block":     "do",
+			"method":     method,
+			"args-size":  len(args),
+			"reply-size": len(reply),
+			"chunks":     chunks,
+			"duration":   time.Since(start),
+		}).Debug("chunked transfer")
+	}
+	return reply, nil
+}
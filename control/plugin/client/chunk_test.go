@@ -0,0 +1,105 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// chunkPlugin plays the chunked calls of a plugin whose method replies with
+// its args twice, its replies chunked in size bytes
+type chunkPlugin struct {
+	size    int
+	args    map[uint64][]byte
+	reply   []byte
+	calls   map[string]int
+	lastArg plugin.CallChunkedArgs
+}
+
+func (p *chunkPlugin) call(method string, args interface{}, reply interface{}) error {
+	p.calls[method]++
+	switch method {
+	case "SessionState.PutChunk":
+		a := args.(plugin.PutChunkArgs)
+		if a.Offset != len(p.args[a.Transfer]) {
+			return fmt.Errorf("chunk at %d out of order", a.Offset)
+		}
+		p.args[a.Transfer] = append(p.args[a.Transfer], a.Data...)
+	case "SessionState.CallChunked":
+		a := args.(plugin.CallChunkedArgs)
+		p.lastArg = a
+		in := a.Args
+		if a.Transfer != 0 {
+			in = p.args[a.Transfer]
+		}
+		p.reply = append(append([]byte{}, in...), in...)
+		r := reply.(*plugin.CallChunkedReply)
+		r.Total = len(p.reply)
+		r.Data = p.reply
+		if len(p.reply) > p.size {
+			r.Transfer = 1
+			r.Data = p.reply[:p.size]
+		}
+	case "SessionState.GetChunk":
+		a := args.(plugin.GetChunkArgs)
+		end := a.Offset + p.size
+		if end > len(p.reply) {
+			end = len(p.reply)
+		}
+		reply.(*plugin.GetChunkReply).Data = p.reply[a.Offset:end]
+	}
+	return nil
+}
+
+func TestChunker(t *testing.T) {
+	Convey("Given a chunker with chunks of 4 bytes", t, func() {
+		p := &chunkPlugin{size: 4, args: map[uint64][]byte{}, calls: map[string]int{}}
+		c := &chunker{call: p.call, size: 4}
+
+		Convey("small args and replies are sent in a single call", func() {
+			reply, err := c.do("Processor.Process", []byte("ab"))
+			So(err, ShouldBeNil)
+			So(string(reply), ShouldEqual, "abab")
+			So(p.calls, ShouldResemble, map[string]int{"SessionState.CallChunked": 1})
+			So(string(p.lastArg.Args), ShouldEqual, "ab")
+		})
+		Convey("large args are put in chunks and large replies fetched in chunks", func() {
+			reply, err := c.do("Processor.Process", []byte("0123456789"))
+			So(err, ShouldBeNil)
+			So(string(reply), ShouldEqual, "01234567890123456789")
+			So(p.calls["SessionState.PutChunk"], ShouldEqual, 3)
+			So(p.calls["SessionState.GetChunk"], ShouldEqual, 4)
+			So(p.lastArg.Method, ShouldEqual, "Processor.Process")
+			So(p.lastArg.Args, ShouldBeEmpty)
+			So(p.lastArg.Transfer, ShouldNotEqual, 0)
+		})
+		Convey("replies larger than the max message size are not fetched", func() {
+			c.max = 16
+			_, err := c.do("Processor.Process", []byte("0123456789"))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, ErrMessageTooLarge.Error())
+			So(p.calls["SessionState.GetChunk"], ShouldEqual, 0)
+		})
+	})
+}
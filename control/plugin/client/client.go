@@ -20,6 +20,8 @@ limitations under the License.
 package client
 
 import (
+	"time"
+
 	"github.com/intelsdi-x/snap/control/plugin"
 	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
 	"github.com/intelsdi-x/snap/core"
@@ -41,6 +43,18 @@ type PluginCollectorClient interface {
 	GetMetricTypes(plugin.PluginConfigType) ([]core.Metric, error)
 }
 
+// PluginCatalogClient A client waiting for the catalog updates pushed by a
+// collector supporting plugin.FeatureCatalogUpdates.
+type PluginCatalogClient interface {
+	WaitCatalogUpdate(time.Duration) (added []core.Metric, removed []core.Metric, err error)
+}
+
+// PluginReadinessClient A client asking a collector supporting
+// plugin.FeatureReadiness whether it is ready to collect.
+type PluginReadinessClient interface {
+	Ready() error
+}
+
 // PluginProcessorClient A client providing processor specific plugin method calls.
 type PluginProcessorClient interface {
 	PluginClient
@@ -52,3 +66,17 @@ type PluginPublisherClient interface {
 	PluginClient
 	Publish(contentType string, content []byte, config map[string]ctypes.ConfigValue) error
 }
+
+// PluginChunkingClient A client transferring the metric batches larger than
+// the chunk size in chunks with a plugin supporting plugin.FeatureChunking.
+type PluginChunkingClient interface {
+	EnableChunking(size int)
+}
+
+// PluginTransportClient A client limiting the size of the metric batches
+// exchanged with a plugin, and compressing the messages with a plugin
+// supporting plugin.FeatureCompression.
+type PluginTransportClient interface {
+	SetMaxMessageSize(size int)
+	SetCompression(compression string) error
+}
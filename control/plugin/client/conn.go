@@ -0,0 +1,211 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/rpc"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	// DefaultKeepAlive is the default period of the TCP keep-alives sent on
+	// the connections to the plugins
+	DefaultKeepAlive = 30 * time.Second
+	// DefaultIdleTimeout is how long an idle HTTP connection to a plugin is
+	// kept open by default
+	DefaultIdleTimeout = 90 * time.Second
+	// DefaultMaxIdleConns is the default number of idle HTTP connections
+	// kept open to each plugin
+	DefaultMaxIdleConns = 4
+	// DefaultMaxReconnectBackoff is the default longest wait between the
+	// attempts to connect again to a plugin
+	DefaultMaxReconnectBackoff = 5 * time.Second
+
+	minReconnectBackoff = 100 * time.Millisecond
+)
+
+// ConnSettings are the settings of the connections of a client to a plugin
+type ConnSettings struct {
+	// KeepAlive is the period of the TCP keep-alives sent on the
+	// connections, none when 0
+	KeepAlive time.Duration
+	// IdleTimeout is how long an idle HTTP connection is kept open, forever
+	// when 0
+	IdleTimeout time.Duration
+	// MaxIdleConns is the number of idle HTTP connections kept open
+	MaxIdleConns int
+	// MaxReconnectBackoff is the longest wait between the attempts to
+	// connect again once the connection was shut down
+	MaxReconnectBackoff time.Duration
+}
+
+// DefaultConnSettings returns the default settings of the connections to
+// the plugins
+func DefaultConnSettings() ConnSettings {
+	return ConnSettings{
+		KeepAlive:           DefaultKeepAlive,
+		IdleTimeout:         DefaultIdleTimeout,
+		MaxIdleConns:        DefaultMaxIdleConns,
+		MaxReconnectBackoff: DefaultMaxReconnectBackoff,
+	}
+}
+
+var connLogger = log.WithField("_module", "client-conn")
+
+// ErrMessageTooLarge is returned for the args and replies larger than the
+// max message size
+var ErrMessageTooLarge = errors.New("message exceeds the max message size")
+
+// checkMessageSize returns ErrMessageTooLarge if the args or reply of a
+// method are larger than max, never when max is 0
+func checkMessageSize(method, what string, size, max int) error {
+	if max > 0 && size > max {
+		return fmt.Errorf("%v: %s of %s is %d bytes, the max is %d", ErrMessageTooLarge, what, method, size, max)
+	}
+	return nil
+}
+
+// newHTTPClient returns the client of a plugin speaking JSON-RPC over HTTP,
+// keeping its connections to the plugin open between the calls
+func newHTTPClient(timeout time.Duration, cs ConnSettings) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Dial: (&net.Dialer{
+				Timeout:   timeout,
+				KeepAlive: cs.KeepAlive,
+			}).Dial,
+			MaxIdleConnsPerHost: cs.MaxIdleConns,
+			IdleConnTimeout:     cs.IdleTimeout,
+		},
+	}
+}
+
+// rpcConn is the net/rpc connection to a native plugin. Once it is shut
+// down, because the plugin closed it or the keep-alives went unanswered,
+// the next call connects again, backing off while the plugin cannot be
+// reached.
+type rpcConn struct {
+	sync.Mutex
+	address  string
+	timeout  time.Duration
+	settings ConnSettings
+	client   *rpc.Client
+	// no attempt to connect is made before retry, the backoff doubling
+	// with each failed attempt
+	backoff time.Duration
+	retry   time.Time
+}
+
+// dialRPC connects to the native plugin listening on address
+func dialRPC(address string, timeout time.Duration, cs ConnSettings) (*rpcConn, error) {
+	c := &rpcConn{address: address, timeout: timeout, settings: cs}
+	client, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	c.client = client
+	return c, nil
+}
+
+func (c *rpcConn) dial() (*rpc.Client, error) {
+	d := &net.Dialer{Timeout: c.timeout, KeepAlive: c.settings.KeepAlive}
+	conn, err := d.Dial("tcp", c.address)
+	if err != nil {
+		return nil, err
+	}
+	return rpc.NewClient(conn), nil
+}
+
+// Call calls a method of the plugin. A call failing because the connection
+// was shut down before it was sent is made again on a new connection.
+func (c *rpcConn) Call(method string, args interface{}, reply interface{}) error {
+	client, err := c.get()
+	if err != nil {
+		return err
+	}
+	err = client.Call(method, args, reply)
+	if err != rpc.ErrShutdown {
+		return err
+	}
+	c.shutdown(client)
+	if client, err = c.get(); err != nil {
+		return err
+	}
+	err = client.Call(method, args, reply)
+	if err == rpc.ErrShutdown {
+		c.shutdown(client)
+	}
+	return err
+}
+
+// get returns the connection, connecting again if it was shut down
+func (c *rpcConn) get() (*rpc.Client, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.client != nil {
+		return c.client, nil
+	}
+	if wait := c.retry.Sub(time.Now()); wait > 0 {
+		return nil, fmt.Errorf("connection to %s is shut down, connecting again in %v", c.address, wait)
+	}
+	client, err := c.dial()
+	if err != nil {
+		c.backoff *= 2
+		if c.backoff < minReconnectBackoff {
+			c.backoff = minReconnectBackoff
+		}
+		if c.backoff > c.settings.MaxReconnectBackoff {
+			c.backoff = c.settings.MaxReconnectBackoff
+		}
+		c.retry = time.Now().Add(c.backoff)
+		connLogger.WithFields(log.Fields{
+			"_block":  "get",
+			"address": c.address,
+			"backoff": c.backoff,
+			"error":   err,
+		}).Warning("failed to connect to plugin again")
+		return nil, err
+	}
+	connLogger.WithFields(log.Fields{
+		"_block":  "get",
+		"address": c.address,
+	}).Info("connected to plugin again")
+	c.client = client
+	c.backoff = 0
+	return client, nil
+}
+
+// shutdown drops the connection, unless it was already replaced
+func (c *rpcConn) shutdown(client *rpc.Client) {
+	c.Lock()
+	defer c.Unlock()
+	if c.client == client {
+		client.Close()
+		c.client = nil
+	}
+}
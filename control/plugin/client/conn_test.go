@@ -0,0 +1,165 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/rpc"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// Echo replies to the calls with their args
+type Echo struct{}
+
+func (e *Echo) Say(args string, reply *string) error {
+	*reply = args
+	return nil
+}
+
+// echoServer serves Echo, keeping the connections it accepts
+type echoServer struct {
+	sync.Mutex
+	listener net.Listener
+	conns    []net.Conn
+}
+
+func newEchoServer() (*echoServer, error) {
+	server := rpc.NewServer()
+	if err := server.Register(&Echo{}); err != nil {
+		return nil, err
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &echoServer{listener: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.Lock()
+			s.conns = append(s.conns, conn)
+			s.Unlock()
+			go server.ServeConn(conn)
+		}
+	}()
+	return s, nil
+}
+
+// drop closes the connections accepted so far
+func (s *echoServer) drop() {
+	s.Lock()
+	defer s.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func TestRPCConn(t *testing.T) {
+	Convey("Given a connection to a native plugin", t, func() {
+		s, err := newEchoServer()
+		So(err, ShouldBeNil)
+		defer s.listener.Close()
+		c, err := dialRPC(s.listener.Addr().String(), time.Second, DefaultConnSettings())
+		So(err, ShouldBeNil)
+		var reply string
+		So(c.Call("Echo.Say", "hello", &reply), ShouldBeNil)
+		So(reply, ShouldEqual, "hello")
+
+		Convey("the same connection is used by the calls", func() {
+			So(c.Call("Echo.Say", "again", &reply), ShouldBeNil)
+			s.Lock()
+			So(s.conns, ShouldHaveLength, 1)
+			s.Unlock()
+		})
+		Convey("a connection closed by the plugin is opened again", func() {
+			s.drop()
+			// the call in flight when the connection is lost fails, the
+			// next one is made on a new connection
+			for i := 0; i < 10 && c.Call("Echo.Say", "again", &reply) != nil; i++ {
+				time.Sleep(10 * time.Millisecond)
+			}
+			So(reply, ShouldEqual, "again")
+			s.Lock()
+			So(s.conns, ShouldHaveLength, 2)
+			s.Unlock()
+		})
+		Convey("a plugin which cannot be reached is not dialed before the backoff", func() {
+			s.listener.Close()
+			s.drop()
+			connected := func() bool {
+				c.Lock()
+				defer c.Unlock()
+				return c.client != nil
+			}
+			for i := 0; i < 10 && connected(); i++ {
+				c.Call("Echo.Say", "again", &reply)
+				time.Sleep(10 * time.Millisecond)
+			}
+			So(connected(), ShouldBeFalse)
+			err := c.Call("Echo.Say", "again", &reply)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "connecting again in")
+		})
+	})
+}
+
+func TestHTTPClient(t *testing.T) {
+	Convey("Given a plugin speaking JSON-RPC over HTTP", t, func() {
+		var mutex sync.Mutex
+		conns := map[net.Conn]bool{}
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"id": 0, "result": null}` + "\n"))
+		}))
+		srv.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				mutex.Lock()
+				conns[conn] = true
+				mutex.Unlock()
+			}
+		}
+		srv.Start()
+		defer srv.Close()
+
+		Convey("the calls are made on the same connection", func() {
+			h := newPlainJSONRPCClient(srv.URL, time.Second, DefaultConnSettings(), 0)
+			for i := 0; i < 5; i++ {
+				So(h.Ping(), ShouldBeNil)
+			}
+			mutex.Lock()
+			So(conns, ShouldHaveLength, 1)
+			mutex.Unlock()
+		})
+		Convey("the connections follow the settings of the client", func() {
+			h := newPlainJSONRPCClient(srv.URL, time.Second, ConnSettings{MaxIdleConns: 2, IdleTimeout: time.Minute}, 0)
+			t := h.client.Transport.(*http.Transport)
+			So(t.MaxIdleConnsPerHost, ShouldEqual, 2)
+			So(t.IdleConnTimeout, ShouldEqual, time.Minute)
+		})
+	})
+}
@@ -25,6 +25,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"sync/atomic"
@@ -46,16 +47,25 @@ type httpJSONRPCClient struct {
 	url        string
 	id         uint64
 	timeout    time.Duration
+	client     *http.Client
 	pluginType plugin.PluginType
 	encrypter  *encrypter.Encrypter
 	encoder    encoding.Encoder
+	// plain is set for plugins speaking the language-neutral PlainJSONRPC
+	// protocol where params and results are JSON objects, not encoded bytes.
+	plain bool
+	// chunker is set once chunking is enabled
+	chunker *chunker
+	// maxMessageSize is the max size of the metric batches, none when 0
+	maxMessageSize int
 }
 
 // NewCollectorHttpJSONRPCClient returns CollectorHttpJSONRPCClient
-func NewCollectorHttpJSONRPCClient(u string, timeout time.Duration, pub *rsa.PublicKey, secure bool) (PluginCollectorClient, error) {
+func NewCollectorHttpJSONRPCClient(u string, timeout time.Duration, cs ConnSettings, pub *rsa.PublicKey, secure bool) (PluginCollectorClient, error) {
 	hjr := &httpJSONRPCClient{
 		url:        u,
 		timeout:    timeout,
+		client:     newHTTPClient(timeout, cs),
 		pluginType: plugin.CollectorPluginType,
 		encoder:    encoding.NewJsonEncoder(),
 	}
@@ -72,10 +82,11 @@ func NewCollectorHttpJSONRPCClient(u string, timeout time.Duration, pub *rsa.Pub
 	return hjr, nil
 }
 
-func NewProcessorHttpJSONRPCClient(u string, timeout time.Duration, pub *rsa.PublicKey, secure bool) (PluginProcessorClient, error) {
+func NewProcessorHttpJSONRPCClient(u string, timeout time.Duration, cs ConnSettings, pub *rsa.PublicKey, secure bool) (PluginProcessorClient, error) {
 	hjr := &httpJSONRPCClient{
 		url:        u,
 		timeout:    timeout,
+		client:     newHTTPClient(timeout, cs),
 		pluginType: plugin.ProcessorPluginType,
 		encoder:    encoding.NewJsonEncoder(),
 	}
@@ -92,10 +103,11 @@ func NewProcessorHttpJSONRPCClient(u string, timeout time.Duration, pub *rsa.Pub
 	return hjr, nil
 }
 
-func NewPublisherHttpJSONRPCClient(u string, timeout time.Duration, pub *rsa.PublicKey, secure bool) (PluginPublisherClient, error) {
+func NewPublisherHttpJSONRPCClient(u string, timeout time.Duration, cs ConnSettings, pub *rsa.PublicKey, secure bool) (PluginPublisherClient, error) {
 	hjr := &httpJSONRPCClient{
 		url:        u,
 		timeout:    timeout,
+		client:     newHTTPClient(timeout, cs),
 		pluginType: plugin.PublisherPluginType,
 		encoder:    encoding.NewJsonEncoder(),
 	}
@@ -112,6 +124,35 @@ func NewPublisherHttpJSONRPCClient(u string, timeout time.Duration, pub *rsa.Pub
 	return hjr, nil
 }
 
+// NewCollectorPlainJSONRPCClient returns a collector client for plugins
+// speaking the language-neutral PlainJSONRPC protocol
+func NewCollectorPlainJSONRPCClient(u string, timeout time.Duration, cs ConnSettings) (PluginCollectorClient, error) {
+	return newPlainJSONRPCClient(u, timeout, cs, plugin.CollectorPluginType), nil
+}
+
+// NewProcessorPlainJSONRPCClient returns a processor client for plugins
+// speaking the language-neutral PlainJSONRPC protocol
+func NewProcessorPlainJSONRPCClient(u string, timeout time.Duration, cs ConnSettings) (PluginProcessorClient, error) {
+	return newPlainJSONRPCClient(u, timeout, cs, plugin.ProcessorPluginType), nil
+}
+
+// NewPublisherPlainJSONRPCClient returns a publisher client for plugins
+// speaking the language-neutral PlainJSONRPC protocol
+func NewPublisherPlainJSONRPCClient(u string, timeout time.Duration, cs ConnSettings) (PluginPublisherClient, error) {
+	return newPlainJSONRPCClient(u, timeout, cs, plugin.PublisherPluginType), nil
+}
+
+func newPlainJSONRPCClient(u string, timeout time.Duration, cs ConnSettings, t plugin.PluginType) *httpJSONRPCClient {
+	return &httpJSONRPCClient{
+		url:        u,
+		timeout:    timeout,
+		client:     newHTTPClient(timeout, cs),
+		pluginType: t,
+		encoder:    encoding.NewJsonEncoder(),
+		plain:      true,
+	}
+}
+
 // Ping
 func (h *httpJSONRPCClient) Ping() error {
 	_, err := h.call("SessionState.Ping", []interface{}{})
@@ -136,7 +177,7 @@ func (h *httpJSONRPCClient) Kill(reason string) error {
 		return err
 	}
 
-	_, err = h.call("SessionState.Kill", []interface{}{out})
+	_, err = h.call("SessionState.Kill", []interface{}{h.param(out)})
 	return err
 }
 
@@ -166,20 +207,19 @@ func (h *httpJSONRPCClient) CollectMetrics(mts []core.Metric) ([]core.Metric, er
 		return nil, err
 	}
 
-	res, err := h.call("Collector.CollectMetrics", []interface{}{out})
+	reply, err := h.callBatch("Collector.CollectMetrics", out)
 	if err != nil {
 		return nil, err
 	}
-	if len(res.Result) == 0 {
+	if len(reply) == 0 {
 		err := errors.New("Invalid response: result is 0")
 		logger.WithFields(log.Fields{
-			"_block":           "CollectMetrics",
-			"jsonrpc response": fmt.Sprintf("%+v", res),
+			"_block": "CollectMetrics",
 		}).Error(err)
 		return nil, err
 	}
 	r := &plugin.CollectMetricsReply{}
-	err = h.encoder.Decode(res.Result, r)
+	err = h.decodeReply(reply, r)
 	if err != nil {
 		return nil, err
 	}
@@ -203,12 +243,12 @@ func (h *httpJSONRPCClient) GetMetricTypes(config plugin.PluginConfigType) ([]co
 		return nil, err
 	}
 
-	res, err := h.call("Collector.GetMetricTypes", []interface{}{out})
+	res, err := h.call("Collector.GetMetricTypes", []interface{}{h.param(out)})
 	if err != nil {
 		return nil, err
 	}
 	var mtr plugin.GetMetricTypesReply
-	err = h.encoder.Decode(res.Result, &mtr)
+	err = h.decode(res, &mtr)
 	if err != nil {
 		return nil, err
 	}
@@ -231,11 +271,11 @@ func (h *httpJSONRPCClient) GetConfigPolicy() (*cpolicy.ConfigPolicy, error) {
 		}).Error("error getting config policy")
 		return nil, err
 	}
-	if len(res.Result) == 0 {
+	if res.empty() {
 		return nil, errors.New(res.Error)
 	}
 	var cpr plugin.GetConfigPolicyReply
-	err = h.encoder.Decode(res.Result, &cpr)
+	err = h.decode(res, &cpr)
 	if err != nil {
 		return nil, err
 	}
@@ -248,10 +288,20 @@ func (h *httpJSONRPCClient) Publish(contentType string, content []byte, config m
 	if err != nil {
 		return nil
 	}
-	_, err = h.call("Publisher.Publish", []interface{}{out})
+	reply, err := h.callBatch("Publisher.Publish", out)
 	if err != nil {
 		return err
 	}
+	if len(reply) == 0 {
+		return nil
+	}
+	r := &plugin.PublishReply{}
+	if err := h.decodeReply(reply, r); err != nil {
+		return err
+	}
+	if r.SlowDown {
+		return plugin.ErrSlowDown
+	}
 	return nil
 }
 
@@ -261,12 +311,12 @@ func (h *httpJSONRPCClient) Process(contentType string, content []byte, config m
 	if err != nil {
 		return "", nil, err
 	}
-	res, err := h.call("Processor.Process", []interface{}{out})
+	reply, err := h.callBatch("Processor.Process", out)
 	if err != nil {
 		return "", nil, err
 	}
 	processorReply := &plugin.ProcessorReply{}
-	if err := h.encoder.Decode(res.Result, processorReply); err != nil {
+	if err := h.decodeReply(reply, processorReply); err != nil {
 		return "", nil, err
 	}
 	return processorReply.ContentType, processorReply.Content, nil
@@ -276,10 +326,104 @@ func (h *httpJSONRPCClient) GetType() string {
 	return upcaseInitial(h.pluginType.String())
 }
 
+// EnableChunking transfers the metric batches larger than size in chunks
+func (h *httpJSONRPCClient) EnableChunking(size int) {
+	h.chunker = &chunker{
+		call: func(method string, args interface{}, reply interface{}) error {
+			res, err := h.call(method, []interface{}{args})
+			if err != nil || res.empty() {
+				return err
+			}
+			return json.Unmarshal(res.Result, reply)
+		},
+		size: size,
+		max:  h.maxMessageSize,
+	}
+}
+
+// SetMaxMessageSize limits the size of the metric batches, none when 0
+func (h *httpJSONRPCClient) SetMaxMessageSize(size int) {
+	h.maxMessageSize = size
+	h.encoder.SetMaxMessageSize(size)
+	if h.chunker != nil {
+		h.chunker.max = size
+	}
+}
+
+// SetCompression compresses the messages exchanged with the plugin from now
+// on
+func (h *httpJSONRPCClient) SetCompression(compression string) error {
+	if !encoding.ValidCompression(compression) {
+		return fmt.Errorf("unknown compression %s", compression)
+	}
+	a := plugin.SetCompressionArgs{Compression: compression}
+	if _, err := h.call("SessionState.SetCompression", []interface{}{a}); err != nil {
+		return err
+	}
+	return h.encoder.SetCompression(compression)
+}
+
+// callBatch calls a method exchanging a metric batch, in chunks once
+// enabled, and returns its encoded reply, empty when the result is
+func (h *httpJSONRPCClient) callBatch(method string, out []byte) ([]byte, error) {
+	if err := checkMessageSize(method, "args", len(out), h.maxMessageSize); err != nil {
+		return nil, err
+	}
+	if h.chunker != nil {
+		return h.chunker.do(method, out)
+	}
+	res, err := h.call(method, []interface{}{h.param(out)})
+	if err != nil || res.empty() {
+		return nil, err
+	}
+	if h.plain {
+		return res.Result, checkMessageSize(method, "reply", len(res.Result), h.maxMessageSize)
+	}
+	var b []byte
+	if err := json.Unmarshal(res.Result, &b); err != nil {
+		return nil, err
+	}
+	return b, checkMessageSize(method, "reply", len(b), h.maxMessageSize)
+}
+
 type jsonRpcResp struct {
-	Id     int    `json:"id"`
-	Result []byte `json:"result"`
-	Error  string `json:"error"`
+	Id     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  string          `json:"error"`
+}
+
+// empty returns true when the response carries no result
+func (r *jsonRpcResp) empty() bool {
+	return len(r.Result) == 0 || string(r.Result) == "null" || string(r.Result) == `""`
+}
+
+// param wraps an encoded argument for the wire. The JSONRPC protocol sends
+// encoded bytes (base64 in JSON) while PlainJSONRPC embeds the JSON as is.
+func (h *httpJSONRPCClient) param(out []byte) interface{} {
+	if h.plain {
+		return json.RawMessage(out)
+	}
+	return out
+}
+
+// decode unpacks the result of a response into out
+func (h *httpJSONRPCClient) decode(res *jsonRpcResp, out interface{}) error {
+	if h.plain {
+		return json.Unmarshal(res.Result, out)
+	}
+	var b []byte
+	if err := json.Unmarshal(res.Result, &b); err != nil {
+		return err
+	}
+	return h.encoder.Decode(b, out)
+}
+
+// decodeReply unpacks an encoded reply into out
+func (h *httpJSONRPCClient) decodeReply(b []byte, out interface{}) error {
+	if h.plain {
+		return json.Unmarshal(b, out)
+	}
+	return h.encoder.Decode(b, out)
 }
 
 func (h *httpJSONRPCClient) call(method string, args []interface{}) (*jsonRpcResp, error) {
@@ -299,8 +443,7 @@ func (h *httpJSONRPCClient) call(method string, args []interface{}) (*jsonRpcRes
 		}).Error("error encoding request to json")
 		return nil, err
 	}
-	client := http.Client{Timeout: h.timeout}
-	resp, err := client.Post(h.url, "application/json", bytes.NewReader(data))
+	resp, err := h.client.Post(h.url, "application/json", bytes.NewReader(data))
 	if err != nil {
 		logger.WithFields(log.Fields{
 			"_block":  "call",
@@ -310,7 +453,11 @@ func (h *httpJSONRPCClient) call(method string, args []interface{}) (*jsonRpcRes
 		}).Error("error posting request to plugin")
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer func() {
+		// a drained body leaves the connection open for the next call
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}()
 	result := &jsonRpcResp{}
 	if err = json.NewDecoder(resp.Body).Decode(result); err != nil {
 		bs, _ := ioutil.ReadAll(resp.Body)
@@ -28,6 +28,7 @@ import (
 	"math/rand"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/rpc"
 	"testing"
 	"time"
@@ -182,7 +183,7 @@ func TestHTTPJSONRPC(t *testing.T) {
 
 	Convey("Collector Client", t, func() {
 		session.c = true
-		c, err := NewCollectorHttpJSONRPCClient(fmt.Sprintf("http://%v", addr), 1*time.Second, &key.PublicKey, true)
+		c, err := NewCollectorHttpJSONRPCClient(fmt.Sprintf("http://%v", addr), 1*time.Second, DefaultConnSettings(), &key.PublicKey, true)
 		So(err, ShouldBeNil)
 		So(c, ShouldNotBeNil)
 		cl := c.(*httpJSONRPCClient)
@@ -279,7 +280,7 @@ func TestHTTPJSONRPC(t *testing.T) {
 
 	Convey("Processor Client", t, func() {
 		session.c = false
-		p, _ := NewProcessorHttpJSONRPCClient(fmt.Sprintf("http://%v", addr), 1*time.Second, &key.PublicKey, true)
+		p, _ := NewProcessorHttpJSONRPCClient(fmt.Sprintf("http://%v", addr), 1*time.Second, DefaultConnSettings(), &key.PublicKey, true)
 		cl := p.(*httpJSONRPCClient)
 		cl.encrypter.Key = symkey
 		So(p, ShouldNotBeNil)
@@ -319,7 +320,7 @@ func TestHTTPJSONRPC(t *testing.T) {
 
 	Convey("Publisher Client", t, func() {
 		session.c = false
-		p, _ := NewPublisherHttpJSONRPCClient(fmt.Sprintf("http://%v", addr), 1*time.Second, &key.PublicKey, true)
+		p, _ := NewPublisherHttpJSONRPCClient(fmt.Sprintf("http://%v", addr), 1*time.Second, DefaultConnSettings(), &key.PublicKey, true)
 		cl := p.(*httpJSONRPCClient)
 		cl.encrypter.Key = symkey
 		So(p, ShouldNotBeNil)
@@ -350,3 +351,101 @@ func TestHTTPJSONRPC(t *testing.T) {
 
 	})
 }
+
+// plainJSONRPCHandler mimics a plugin written in another language. It only
+// works with generic JSON values and never touches snap's Go types.
+func plainJSONRPCHandler(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	var in struct {
+		Method string                   `json:"method"`
+		ID     interface{}              `json:"id"`
+		Params []map[string]interface{} `json:"params"`
+	}
+	out := map[string]interface{}{"error": nil}
+	if err := json.NewDecoder(req.Body).Decode(&in); err != nil {
+		out["error"] = err.Error()
+	}
+	out["id"] = in.ID
+	switch in.Method {
+	case "SessionState.Ping", "SessionState.Kill", "Publisher.Publish":
+		out["result"] = map[string]interface{}{}
+	case "Collector.GetMetricTypes":
+		out["result"] = map[string]interface{}{
+			"PluginMetricTypes": []interface{}{
+				map[string]interface{}{"namespace": []string{"py", "foo"}, "version": 1},
+			},
+		}
+	case "Collector.CollectMetrics":
+		var mts []interface{}
+		for _, m := range in.Params[0]["PluginMetricTypes"].([]interface{}) {
+			mt := m.(map[string]interface{})
+			mts = append(mts, map[string]interface{}{
+				"namespace": mt["namespace"],
+				"data":      42,
+				"source":    "py",
+				"timestamp": time.Now(),
+			})
+		}
+		out["result"] = map[string]interface{}{"PluginMetrics": mts}
+	case "Processor.Process":
+		out["result"] = map[string]interface{}{
+			"ContentType": in.Params[0]["ContentType"],
+			"Content":     in.Params[0]["Content"],
+		}
+	default:
+		out["error"] = "unknown method " + in.Method
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+func TestPlainJSONRPC(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(plainJSONRPCHandler))
+	defer ts.Close()
+
+	Convey("Collector Client", t, func() {
+		c, err := NewCollectorPlainJSONRPCClient(ts.URL, 1*time.Second, DefaultConnSettings())
+		So(err, ShouldBeNil)
+		So(c, ShouldNotBeNil)
+
+		Convey("Ping", func() {
+			So(c.Ping(), ShouldBeNil)
+		})
+
+		Convey("Kill", func() {
+			So(c.Kill("somereason"), ShouldBeNil)
+		})
+
+		Convey("GetMetricTypes", func() {
+			mts, err := c.GetMetricTypes(plugin.NewPluginConfigType())
+			So(err, ShouldBeNil)
+			So(len(mts), ShouldEqual, 1)
+			So(mts[0].Namespace(), ShouldResemble, []string{"py", "foo"})
+		})
+
+		Convey("CollectMetrics", func() {
+			mts, err := c.CollectMetrics([]core.Metric{
+				&plugin.PluginMetricType{Namespace_: []string{"py", "foo"}},
+			})
+			So(err, ShouldBeNil)
+			So(len(mts), ShouldEqual, 1)
+			So(mts[0].Source(), ShouldEqual, "py")
+			So(mts[0].Data(), ShouldEqual, float64(42))
+		})
+	})
+
+	Convey("Processor Client", t, func() {
+		p, err := NewProcessorPlainJSONRPCClient(ts.URL, 1*time.Second, DefaultConnSettings())
+		So(err, ShouldBeNil)
+		ct, content, err := p.Process(plugin.SnapJSONContentType, []byte("[]"), nil)
+		So(err, ShouldBeNil)
+		So(ct, ShouldEqual, plugin.SnapJSONContentType)
+		So(string(content), ShouldEqual, "[]")
+	})
+
+	Convey("Publisher Client", t, func() {
+		p, err := NewPublisherPlainJSONRPCClient(ts.URL, 1*time.Second, DefaultConnSettings())
+		So(err, ShouldBeNil)
+		So(p.Publish(plugin.SnapJSONContentType, []byte("[]"), nil), ShouldBeNil)
+	})
+}
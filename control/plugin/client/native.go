@@ -23,8 +23,7 @@ import (
 	"crypto/rsa"
 	"encoding/gob"
 	"errors"
-	"net"
-	"net/rpc"
+	"fmt"
 	"time"
 	"unicode"
 
@@ -50,18 +49,22 @@ type PluginNativeClient struct {
 	pluginType plugin.PluginType
 	encoder    encoding.Encoder
 	encrypter  *encrypter.Encrypter
+	// chunker is set once chunking is enabled
+	chunker *chunker
+	// maxMessageSize is the max size of the metric batches, none when 0
+	maxMessageSize int
 }
 
-func NewCollectorNativeClient(address string, timeout time.Duration, pub *rsa.PublicKey, secure bool) (PluginCollectorClient, error) {
-	return newNativeClient(address, timeout, plugin.CollectorPluginType, pub, secure)
+func NewCollectorNativeClient(address string, timeout time.Duration, cs ConnSettings, pub *rsa.PublicKey, secure bool) (PluginCollectorClient, error) {
+	return newNativeClient(address, timeout, cs, plugin.CollectorPluginType, pub, secure)
 }
 
-func NewPublisherNativeClient(address string, timeout time.Duration, pub *rsa.PublicKey, secure bool) (PluginPublisherClient, error) {
-	return newNativeClient(address, timeout, plugin.PublisherPluginType, pub, secure)
+func NewPublisherNativeClient(address string, timeout time.Duration, cs ConnSettings, pub *rsa.PublicKey, secure bool) (PluginPublisherClient, error) {
+	return newNativeClient(address, timeout, cs, plugin.PublisherPluginType, pub, secure)
 }
 
-func NewProcessorNativeClient(address string, timeout time.Duration, pub *rsa.PublicKey, secure bool) (PluginProcessorClient, error) {
-	return newNativeClient(address, timeout, plugin.ProcessorPluginType, pub, secure)
+func NewProcessorNativeClient(address string, timeout time.Duration, cs ConnSettings, pub *rsa.PublicKey, secure bool) (PluginProcessorClient, error) {
+	return newNativeClient(address, timeout, cs, plugin.ProcessorPluginType, pub, secure)
 }
 
 func (p *PluginNativeClient) Ping() error {
@@ -100,9 +103,22 @@ func (p *PluginNativeClient) Publish(contentType string, content []byte, config
 		return err
 	}
 
-	var reply []byte
-	err = p.connection.Call("Publisher.Publish", out, &reply)
-	return err
+	reply, err := p.callBatch("Publisher.Publish", out)
+	if err != nil {
+		return err
+	}
+	// publishers built before back pressure reply nothing
+	if len(reply) == 0 {
+		return nil
+	}
+	r := plugin.PublishReply{}
+	if err := p.encoder.Decode(reply, &r); err != nil {
+		return err
+	}
+	if r.SlowDown {
+		return plugin.ErrSlowDown
+	}
+	return nil
 }
 
 func (p *PluginNativeClient) Process(contentType string, content []byte, config map[string]ctypes.ConfigValue) (string, []byte, error) {
@@ -113,8 +129,7 @@ func (p *PluginNativeClient) Process(contentType string, content []byte, config
 		return "", nil, err
 	}
 
-	var reply []byte
-	err = p.connection.Call("Processor.Process", out, &reply)
+	reply, err := p.callBatch("Processor.Process", out)
 	if err != nil {
 		return "", nil, err
 	}
@@ -155,8 +170,7 @@ func (p *PluginNativeClient) CollectMetrics(mts []core.Metric) ([]core.Metric, e
 		return nil, err
 	}
 
-	var reply []byte
-	err = p.connection.Call("Collector.CollectMetrics", out, &reply)
+	reply, err := p.callBatch("Collector.CollectMetrics", out)
 	if err != nil {
 		return nil, err
 	}
@@ -208,6 +222,46 @@ func (p *PluginNativeClient) GetMetricTypes(config plugin.PluginConfigType) ([]c
 	return retMetricTypes, nil
 }
 
+// Ready returns nil if the collector is ready to collect, or the reason
+// it is not.
+func (p *PluginNativeClient) Ready() error {
+	var reply []byte
+	return p.connection.Call("Collector.Ready", []byte{}, &reply)
+}
+
+// WaitCatalogUpdate waits up to the given duration for the collector to push
+// a catalog update, returning the metric types it added and removed.
+func (p *PluginNativeClient) WaitCatalogUpdate(wait time.Duration) ([]core.Metric, []core.Metric, error) {
+	out, err := p.encoder.Encode(plugin.WaitCatalogUpdateArgs{Wait: wait})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var reply []byte
+	err = p.connection.Call("Collector.WaitCatalogUpdate", out, &reply)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r := &plugin.WaitCatalogUpdateReply{}
+	err = p.encoder.Decode(reply, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	added := make([]core.Metric, len(r.Update.Added))
+	for i, mt := range r.Update.Added {
+		mt.LastAdvertisedTime_ = now
+		added[i] = mt
+	}
+	removed := make([]core.Metric, len(r.Update.Removed))
+	for i, mt := range r.Update.Removed {
+		removed[i] = mt
+	}
+	return added, removed, nil
+}
+
 func (p *PluginNativeClient) GetConfigPolicy() (*cpolicy.ConfigPolicy, error) {
 	var reply []byte
 	err := p.connection.Call("SessionState.GetConfigPolicy", []byte{}, &reply)
@@ -224,20 +278,62 @@ func (p *PluginNativeClient) GetConfigPolicy() (*cpolicy.ConfigPolicy, error) {
 	return r.Policy, nil
 }
 
+// EnableChunking transfers the metric batches larger than size in chunks
+func (p *PluginNativeClient) EnableChunking(size int) {
+	p.chunker = &chunker{call: p.connection.Call, size: size, max: p.maxMessageSize}
+}
+
+// SetMaxMessageSize limits the size of the metric batches, none when 0
+func (p *PluginNativeClient) SetMaxMessageSize(size int) {
+	p.maxMessageSize = size
+	p.encoder.SetMaxMessageSize(size)
+	if p.chunker != nil {
+		p.chunker.max = size
+	}
+}
+
+// SetCompression compresses the messages exchanged with the plugin from now
+// on
+func (p *PluginNativeClient) SetCompression(compression string) error {
+	if !encoding.ValidCompression(compression) {
+		return fmt.Errorf("unknown compression %s", compression)
+	}
+	err := p.connection.Call("SessionState.SetCompression", plugin.SetCompressionArgs{Compression: compression}, &[]byte{})
+	if err != nil {
+		return err
+	}
+	return p.encoder.SetCompression(compression)
+}
+
+// callBatch calls a method exchanging a metric batch, in chunks once
+// enabled, and returns its encoded reply
+func (p *PluginNativeClient) callBatch(method string, out []byte) ([]byte, error) {
+	if err := checkMessageSize(method, "args", len(out), p.maxMessageSize); err != nil {
+		return nil, err
+	}
+	if p.chunker != nil {
+		return p.chunker.do(method, out)
+	}
+	var reply []byte
+	if err := p.connection.Call(method, out, &reply); err != nil {
+		return nil, err
+	}
+	return reply, checkMessageSize(method, "reply", len(reply), p.maxMessageSize)
+}
+
 // GetType returns the string type of the plugin
 // Note: the first letter of the type will be capitalized.
 func (p *PluginNativeClient) GetType() string {
 	return upcaseInitial(p.pluginType.String())
 }
 
-func newNativeClient(address string, timeout time.Duration, t plugin.PluginType, pub *rsa.PublicKey, secure bool) (*PluginNativeClient, error) {
+func newNativeClient(address string, timeout time.Duration, cs ConnSettings, t plugin.PluginType, pub *rsa.PublicKey, secure bool) (*PluginNativeClient, error) {
 	// Attempt to dial address error on timeout or problem
-	conn, err := net.DialTimeout("tcp", address, timeout)
+	r, err := dialRPC(address, timeout, cs)
 	// Return nil RPCClient and err if encoutered
 	if err != nil {
 		return nil, err
 	}
-	r := rpc.NewClient(conn)
 	p := &PluginNativeClient{
 		connection: r,
 		pluginType: t,
@@ -264,12 +360,18 @@ func init() {
 	gob.RegisterName("conf_value_int", *(&ctypes.ConfigValueInt{}))
 	gob.RegisterName("conf_value_float", *(&ctypes.ConfigValueFloat{}))
 	gob.RegisterName("conf_value_bool", *(&ctypes.ConfigValueBool{}))
+	gob.RegisterName("conf_value_duration", *(&ctypes.ConfigValueDuration{}))
+	gob.RegisterName("conf_value_list", *(&ctypes.ConfigValueList{}))
 
 	gob.RegisterName("conf_policy_node", cpolicy.NewPolicyNode())
 	gob.RegisterName("conf_data_node", &cdata.ConfigDataNode{})
 	gob.RegisterName("conf_policy_string", &cpolicy.StringRule{})
 	gob.RegisterName("conf_policy_int", &cpolicy.IntRule{})
 	gob.RegisterName("conf_policy_float", &cpolicy.FloatRule{})
+	gob.RegisterName("conf_policy_enum", &cpolicy.EnumRule{})
+	gob.RegisterName("conf_policy_regex", &cpolicy.RegexRule{})
+	gob.RegisterName("conf_policy_duration", &cpolicy.DurationRule{})
+	gob.RegisterName("conf_policy_list", &cpolicy.ListRule{})
 }
 
 func upcaseInitial(str string) string {
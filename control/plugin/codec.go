@@ -0,0 +1,125 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// The codecs of the content types of this package are registered in core,
+// where snapd looks them up by the content type a plugin accepts or
+// returns. A new content type only needs its codec registered.
+func init() {
+	for ct, c := range map[string]core.Codec{
+		SnapGOBContentType:      {Encode: encodeWith(encodeGOB), Decode: decodeWith(decodeGOB)},
+		SnapJSONContentType:     {Encode: encodeWith(encodeJSON), Decode: decodeWith(decodeJSON)},
+		SnapProtobufContentType: {Encode: encodeWith(encodeProtobuf), Decode: decodeWith(decodeProtobuf)},
+		SnapMsgpackContentType:  {Encode: encodeWith(encodeMsgpack), Decode: decodeWith(decodeMsgpack)},
+		JSONLinesContentType:    {Encode: encodeWith(encodeJSONLines)},
+		JSONEnvelopeContentType: {Encode: encodeWith(encodeJSONEnvelope)},
+		InfluxLineContentType:   {Encode: encodeWith(encodeInfluxLines)},
+		OTLPJSONContentType:     {Encode: encodeWith(encodeOTLPJSON)},
+		OTLPProtoContentType:    {Encode: encodeWith(encodeOTLPProto)},
+	} {
+		if err := core.RegisterCodec(ct, c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// encodeWith returns the encoder of a codec encoding the metrics as plugins
+// receive them
+func encodeWith(enc func([]PluginMetricType) ([]byte, error)) func([]core.Metric) ([]byte, error) {
+	return func(metrics []core.Metric) ([]byte, error) {
+		mts, err := toPluginMetricTypes(metrics)
+		if err != nil {
+			return nil, err
+		}
+		return enc(mts)
+	}
+}
+
+// decodeWith returns the decoder of a codec decoding the metrics as plugins
+// return them
+func decodeWith(dec func([]byte) ([]PluginMetricType, error)) func([]byte) ([]core.Metric, error) {
+	return func(content []byte) ([]core.Metric, error) {
+		mts, err := dec(content)
+		if err != nil {
+			return nil, err
+		}
+		return toCoreMetrics(mts), nil
+	}
+}
+
+func toPluginMetricTypes(metrics []core.Metric) ([]PluginMetricType, error) {
+	mts := make([]PluginMetricType, len(metrics))
+	for i, m := range metrics {
+		switch mt := m.(type) {
+		case PluginMetricType:
+			mts[i] = mt
+		case *PluginMetricType:
+			mts[i] = *mt
+		default:
+			return nil, fmt.Errorf("cannot encode a metric of type %T", m)
+		}
+	}
+	return mts, nil
+}
+
+func toCoreMetrics(mts []PluginMetricType) []core.Metric {
+	metrics := make([]core.Metric, len(mts))
+	for i, m := range mts {
+		metrics[i] = m
+	}
+	return metrics
+}
+
+func encodeGOB(metrics []PluginMetricType) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(metrics); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeGOB(content []byte) ([]PluginMetricType, error) {
+	var metrics []PluginMetricType
+	if err := gob.NewDecoder(bytes.NewReader(content)).Decode(&metrics); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+func encodeJSON(metrics []PluginMetricType) ([]byte, error) {
+	return json.Marshal(metrics)
+}
+
+func decodeJSON(content []byte) ([]PluginMetricType, error) {
+	var metrics []PluginMetricType
+	if err := json.Unmarshal(content, &metrics); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
@@ -28,3 +28,28 @@ type CollectorPlugin interface {
 	CollectMetrics([]PluginMetricType) ([]PluginMetricType, error)
 	GetMetricTypes(PluginConfigType) ([]PluginMetricType, error)
 }
+
+// CatalogUpdate holds the metric types a collector started or stopped
+// providing.
+type CatalogUpdate struct {
+	Added   []PluginMetricType
+	Removed []PluginMetricType
+}
+
+// CatalogUpdater is implemented by collectors which push changes of their
+// metric types to snapd between collections, e.g. when a device is plugged in,
+// instead of snapd learning them only when the plugin is loaded. Such
+// collectors declare FeatureCatalogUpdates in their meta.
+type CatalogUpdater interface {
+	// CatalogUpdates returns the channel the collector sends its updates on.
+	CatalogUpdates() <-chan CatalogUpdate
+}
+
+// ReadinessChecker is implemented by collectors which need time after starting
+// before they can collect, e.g. to open connections or scan hardware. snapd
+// defers the first collection of a task until Ready returns nil. Such
+// collectors declare FeatureReadiness in their meta.
+type ReadinessChecker interface {
+	// Ready returns an error telling why the collector is not ready yet.
+	Ready() error
+}
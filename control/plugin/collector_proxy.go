@@ -22,6 +22,7 @@ package plugin
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/intelsdi-x/snap/core/cdata"
 )
@@ -46,6 +47,17 @@ type GetMetricTypesReply struct {
 	PluginMetricTypes []PluginMetricType
 }
 
+// WaitCatalogUpdateArgs args passed to WaitCatalogUpdate
+type WaitCatalogUpdateArgs struct {
+	// Wait is how long to wait for an update before replying without one
+	Wait time.Duration
+}
+
+// WaitCatalogUpdateReply assigned by WaitCatalogUpdate
+type WaitCatalogUpdateReply struct {
+	Update CatalogUpdate
+}
+
 type collectorPluginProxy struct {
 	Plugin  CollectorPlugin
 	Session Session
@@ -96,3 +108,50 @@ func (c *collectorPluginProxy) CollectMetrics(args []byte, reply *[]byte) error
 	}
 	return nil
 }
+
+// WaitCatalogUpdate replies with the next catalog update of a CatalogUpdater,
+// or with an empty update once the wait is over. snapd keeps calling it while
+// the plugin runs, which lets the plugin decide when snapd learns about changes
+// of its metric types. The wait itself is not traffic: only an update resets
+// the heartbeat, so the plugin still times out when snapd stops calling it.
+func (c *collectorPluginProxy) WaitCatalogUpdate(args []byte, reply *[]byte) error {
+	defer catchPluginPanic(c.Session.Logger())
+
+	u, ok := c.Plugin.(CatalogUpdater)
+	if !ok {
+		return errors.New("WaitCatalogUpdate call error : catalog updates are not supported")
+	}
+	dargs := &WaitCatalogUpdateArgs{}
+	c.Session.Decode(args, dargs)
+
+	var r WaitCatalogUpdateReply
+	select {
+	case update, ok := <-u.CatalogUpdates():
+		if !ok {
+			return errors.New("WaitCatalogUpdate call error : catalog updates closed")
+		}
+		// Reset heartbeat
+		c.Session.ResetHeartbeat()
+		r.Update = update
+	case <-time.After(dargs.Wait):
+	}
+	var err error
+	*reply, err = c.Session.Encode(r)
+	return err
+}
+
+// Ready replies successfully once a ReadinessChecker reports it is ready.
+// Collectors which do not implement it are always ready.
+func (c *collectorPluginProxy) Ready(args []byte, reply *[]byte) error {
+	defer catchPluginPanic(c.Session.Logger())
+	// Reset heartbeat
+	c.Session.ResetHeartbeat()
+
+	if r, ok := c.Plugin.(ReadinessChecker); ok {
+		if err := r.Ready(); err != nil {
+			return errors.New(fmt.Sprintf("Ready call error : %s", err.Error()))
+		}
+	}
+	*reply = []byte{}
+	return nil
+}
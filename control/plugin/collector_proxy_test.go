@@ -81,6 +81,24 @@ func (p *mockErrorPlugin) GetConfigPolicy() (*cpolicy.ConfigPolicy, error) {
 	return &cpolicy.ConfigPolicy{}, errors.New("Error in get config policy")
 }
 
+type mockCatalogUpdaterPlugin struct {
+	mockPlugin
+	updates chan CatalogUpdate
+}
+
+func (p *mockCatalogUpdaterPlugin) CatalogUpdates() <-chan CatalogUpdate {
+	return p.updates
+}
+
+type mockReadinessPlugin struct {
+	mockPlugin
+	err error
+}
+
+func (p *mockReadinessPlugin) Ready() error {
+	return p.err
+}
+
 func TestCollectorProxy(t *testing.T) {
 	Convey("Test collector plugin proxy for get metric types ", t, func() {
 
@@ -120,6 +138,61 @@ func TestCollectorProxy(t *testing.T) {
 			err := errC.GetMetricTypes([]byte{}, &reply)
 			So(err.Error(), ShouldResemble, "GetMetricTypes call error : Error in get Metric Type")
 		})
+		Convey("Wait Catalog Update", func() {
+			updater := &mockCatalogUpdaterPlugin{updates: make(chan CatalogUpdate, 1)}
+			uc := &collectorPluginProxy{
+				Plugin:  updater,
+				Session: mockSessionState,
+			}
+			out, err := uc.Session.Encode(WaitCatalogUpdateArgs{Wait: 10 * time.Millisecond})
+			So(err, ShouldBeNil)
+			Convey("replies with the update pushed by the plugin", func() {
+				updater.updates <- CatalogUpdate{Added: mockPluginMetricType[:1]}
+				var reply []byte
+				So(uc.WaitCatalogUpdate(out, &reply), ShouldBeNil)
+				var r WaitCatalogUpdateReply
+				So(uc.Session.Decode(reply, &r), ShouldBeNil)
+				So(r.Update.Added, ShouldHaveLength, 1)
+				So(r.Update.Added[0].Namespace(), ShouldResemble, []string{"foo", "bar"})
+				So(mockSessionState.heartbeats, ShouldEqual, 1)
+			})
+			Convey("replies with an empty update once the wait is over", func() {
+				var reply []byte
+				So(uc.WaitCatalogUpdate(out, &reply), ShouldBeNil)
+				var r WaitCatalogUpdateReply
+				So(uc.Session.Decode(reply, &r), ShouldBeNil)
+				So(r.Update.Added, ShouldBeEmpty)
+				So(r.Update.Removed, ShouldBeEmpty)
+				// waiting does not keep the plugin alive
+				So(mockSessionState.heartbeats, ShouldEqual, 0)
+			})
+			Convey("returns an error once the plugin closed its updates", func() {
+				close(updater.updates)
+				var reply []byte
+				So(uc.WaitCatalogUpdate(out, &reply), ShouldNotBeNil)
+			})
+			Convey("returns an error if the plugin does not push updates", func() {
+				var reply []byte
+				err := c.WaitCatalogUpdate(out, &reply)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "not supported")
+			})
+		})
+		Convey("Ready", func() {
+			var reply []byte
+			Convey("succeeds for plugins which do not check readiness", func() {
+				So(c.Ready([]byte{}, &reply), ShouldBeNil)
+			})
+			Convey("returns the reason a plugin is not ready", func() {
+				rc := &collectorPluginProxy{
+					Plugin:  &mockReadinessPlugin{err: errors.New("scanning devices")},
+					Session: mockSessionState,
+				}
+				err := rc.Ready([]byte{}, &reply)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Ready call error : scanning devices")
+			})
+		})
 		Convey("Collect Metric ", func() {
 			args := CollectMetricsArgs{
 				PluginMetricTypes: mockPluginMetricType,
@@ -0,0 +1,150 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpolicy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+const (
+	// The first key is required when any of the others is set
+	RequiredWithConstraint = "required_with"
+	// The value of the first key must be greater than the value of the second
+	GreaterThanConstraint = "greater_than"
+	// At most one of the keys may be set
+	MutuallyExclusiveConstraint = "mutually_exclusive"
+)
+
+// A constraint spanning several keys of a policy node. Constraints are
+// checked by Process once the rules of the node have been applied, so they
+// see the defaults and converted values.
+type Constraint struct {
+	Type string   `json:"type"`
+	Keys []string `json:"keys"`
+}
+
+// Returns a constraint requiring key when any of others is set, e.g. a
+// password when a user is.
+func RequiredWith(key string, others ...string) *Constraint {
+	return &Constraint{Type: RequiredWithConstraint, Keys: append([]string{key}, others...)}
+}
+
+// Returns a constraint requiring the value of key to be greater than the
+// value of other when both are set. Integers, floats and durations can be
+// compared.
+func GreaterThan(key, other string) *Constraint {
+	return &Constraint{Type: GreaterThanConstraint, Keys: []string{key, other}}
+}
+
+// Returns a constraint allowing at most one of keys to be set.
+func MutuallyExclusive(keys ...string) *Constraint {
+	return &Constraint{Type: MutuallyExclusiveConstraint, Keys: keys}
+}
+
+// Checks a processed config against this constraint.
+func (c *Constraint) Check(m map[string]ctypes.ConfigValue) error {
+	switch c.Type {
+	case RequiredWithConstraint:
+		if len(c.Keys) < 2 {
+			break
+		}
+		if _, ok := m[c.Keys[0]]; ok {
+			return nil
+		}
+		for _, k := range c.Keys[1:] {
+			if _, ok := m[k]; ok {
+				return fmt.Errorf("required key missing (%s is required when %s is set)", c.Keys[0], k)
+			}
+		}
+		return nil
+	case GreaterThanConstraint:
+		if len(c.Keys) != 2 {
+			break
+		}
+		a, aok := m[c.Keys[0]]
+		b, bok := m[c.Keys[1]]
+		if !aok || !bok {
+			return nil
+		}
+		av, at, aok := orderedValue(a)
+		bv, bt, bok := orderedValue(b)
+		if !aok || !bok || at != bt {
+			return fmt.Errorf("values cannot be compared (%s type '%s' and %s type '%s')", c.Keys[0], a.Type(), c.Keys[1], b.Type())
+		}
+		if av <= bv {
+			return fmt.Errorf("value is not greater (%s value %v <= %s value %v)", c.Keys[0], plainValue(a), c.Keys[1], plainValue(b))
+		}
+		return nil
+	case MutuallyExclusiveConstraint:
+		var set []string
+		for _, k := range c.Keys {
+			if _, ok := m[k]; ok {
+				set = append(set, k)
+			}
+		}
+		if len(set) > 1 {
+			return fmt.Errorf("keys are mutually exclusive (%s are set)", strings.Join(set, ", "))
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid constraint (%s on [%s])", c.Type, strings.Join(c.Keys, ", "))
+}
+
+// orderedValue returns the value of a number or duration as a float and the
+// kind of value it is, integers and floats being comparable together.
+func orderedValue(cv ctypes.ConfigValue) (float64, string, bool) {
+	switch v := cv.(type) {
+	case ctypes.ConfigValueInt:
+		return float64(v.Value), "number", true
+	case *ctypes.ConfigValueInt:
+		return float64(v.Value), "number", true
+	case ctypes.ConfigValueFloat:
+		return v.Value, "number", true
+	case *ctypes.ConfigValueFloat:
+		return v.Value, "number", true
+	case ctypes.ConfigValueDuration:
+		return float64(v.Value), "duration", true
+	case *ctypes.ConfigValueDuration:
+		return float64(v.Value), "duration", true
+	}
+	return 0, "", false
+}
+
+// plainValue returns the value of a config value for error messages.
+func plainValue(cv ctypes.ConfigValue) interface{} {
+	switch v := cv.(type) {
+	case ctypes.ConfigValueInt:
+		return v.Value
+	case *ctypes.ConfigValueInt:
+		return v.Value
+	case ctypes.ConfigValueFloat:
+		return v.Value
+	case *ctypes.ConfigValueFloat:
+		return v.Value
+	case ctypes.ConfigValueDuration:
+		return v.Value
+	case *ctypes.ConfigValueDuration:
+		return v.Value
+	}
+	return cv
+}
@@ -0,0 +1,102 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpolicy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/core/ctypes"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConstraint(t *testing.T) {
+	Convey("Constraint", t, func() {
+
+		Convey("required with", func() {
+			c := RequiredWith("password", "user")
+
+			Convey("passes when neither is set", func() {
+				So(c.Check(map[string]ctypes.ConfigValue{}), ShouldBeNil)
+			})
+
+			Convey("passes when both are set", func() {
+				m := map[string]ctypes.ConfigValue{
+					"user":     ctypes.ConfigValueStr{Value: "root"},
+					"password": ctypes.ConfigValueStr{Value: "secret"},
+				}
+				So(c.Check(m), ShouldBeNil)
+			})
+
+			Convey("errors when the other is set alone", func() {
+				m := map[string]ctypes.ConfigValue{"user": ctypes.ConfigValueStr{Value: "root"}}
+				So(c.Check(m), ShouldResemble, errors.New("required key missing (password is required when user is set)"))
+			})
+		})
+
+		Convey("greater than", func() {
+			c := GreaterThan("max", "min")
+
+			Convey("passes when one is unset", func() {
+				So(c.Check(map[string]ctypes.ConfigValue{"max": ctypes.ConfigValueInt{Value: 1}}), ShouldBeNil)
+			})
+
+			Convey("compares integers and floats", func() {
+				m := map[string]ctypes.ConfigValue{
+					"max": ctypes.ConfigValueFloat{Value: 10.5},
+					"min": &ctypes.ConfigValueInt{Value: 10},
+				}
+				So(c.Check(m), ShouldBeNil)
+				m["max"] = ctypes.ConfigValueInt{Value: 10}
+				So(c.Check(m), ShouldResemble, errors.New("value is not greater (max value 10 <= min value 10)"))
+			})
+
+			Convey("compares durations", func() {
+				m := map[string]ctypes.ConfigValue{
+					"max": ctypes.ConfigValueDuration{Value: time.Second},
+					"min": ctypes.ConfigValueDuration{Value: time.Minute},
+				}
+				So(c.Check(m), ShouldResemble, errors.New("value is not greater (max value 1s <= min value 1m0s)"))
+			})
+
+			Convey("errors on values which cannot be compared", func() {
+				m := map[string]ctypes.ConfigValue{
+					"max": ctypes.ConfigValueDuration{Value: time.Second},
+					"min": ctypes.ConfigValueInt{Value: 1},
+				}
+				So(c.Check(m), ShouldResemble, errors.New("values cannot be compared (max type 'duration' and min type 'integer')"))
+			})
+		})
+
+		Convey("mutually exclusive", func() {
+			c := MutuallyExclusive("file", "url", "inline")
+			m := map[string]ctypes.ConfigValue{"url": ctypes.ConfigValueStr{Value: "http://localhost"}}
+			So(c.Check(m), ShouldBeNil)
+			m["inline"] = ctypes.ConfigValueBool{Value: true}
+			So(c.Check(m), ShouldResemble, errors.New("keys are mutually exclusive (url, inline are set)"))
+		})
+
+		Convey("invalid constraint", func() {
+			c := &Constraint{Type: "before", Keys: []string{"a", "b"}}
+			So(c.Check(map[string]ctypes.ConfigValue{}), ShouldResemble, errors.New("invalid constraint (before on [a, b])"))
+		})
+	})
+}
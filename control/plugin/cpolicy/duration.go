@@ -0,0 +1,207 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpolicy
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+// A rule validating against duration-typed config. Durations may be provided
+// as strings ("500ms", "1m30s") which are converted to durations.
+type DurationRule struct {
+	rule
+
+	key      string
+	required bool
+	default_ *time.Duration
+	minimum  *time.Duration
+	maximum  *time.Duration
+}
+
+// Returns a new duration-typed rule. Arguments are key(string), required(bool), default(time.Duration).
+func NewDurationRule(key string, req bool, opts ...time.Duration) (*DurationRule, error) {
+	// Return error if key is empty
+	if key == "" {
+		return nil, EmptyKeyError
+	}
+
+	d := &DurationRule{
+		key:      key,
+		required: req,
+	}
+	if len(opts) > 0 {
+		d.default_ = &opts[0]
+	}
+	return d, nil
+}
+
+func (d *DurationRule) Type() string {
+	return "duration"
+}
+
+// MarshalJSON marshals a DurationRule into JSON
+func (d *DurationRule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Key      string             `json:"key"`
+		Required bool               `json:"required"`
+		Default  ctypes.ConfigValue `json:"default,omitempty"`
+		Minimum  ctypes.ConfigValue `json:"minimum,omitempty"`
+		Maximum  ctypes.ConfigValue `json:"maximum,omitempty"`
+		Type     string             `json:"type"`
+	}{
+		Key:      d.key,
+		Required: d.required,
+		Default:  d.Default(),
+		Minimum:  d.Minimum(),
+		Maximum:  d.Maximum(),
+		Type:     "duration",
+	})
+}
+
+// GobEncode encodes a DurationRule into a GOB
+func (d *DurationRule) GobEncode() ([]byte, error) {
+	w := new(bytes.Buffer)
+	encoder := gob.NewEncoder(w)
+	if err := encoder.Encode(d.key); err != nil {
+		return nil, err
+	}
+	if err := encoder.Encode(d.required); err != nil {
+		return nil, err
+	}
+	for _, v := range []*time.Duration{d.default_, d.minimum, d.maximum} {
+		if v == nil {
+			encoder.Encode(false)
+			continue
+		}
+		encoder.Encode(true)
+		if err := encoder.Encode(v); err != nil {
+			return nil, err
+		}
+	}
+	return w.Bytes(), nil
+}
+
+// GobDecode decodes a GOB into a DurationRule
+func (d *DurationRule) GobDecode(buf []byte) error {
+	decoder := gob.NewDecoder(bytes.NewBuffer(buf))
+	if err := decoder.Decode(&d.key); err != nil {
+		return err
+	}
+	if err := decoder.Decode(&d.required); err != nil {
+		return err
+	}
+	for _, v := range []**time.Duration{&d.default_, &d.minimum, &d.maximum} {
+		var is_set bool
+		decoder.Decode(&is_set)
+		if !is_set {
+			continue
+		}
+		if err := decoder.Decode(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the key
+func (d *DurationRule) Key() string {
+	return d.key
+}
+
+// Validates a config value against this rule.
+func (d *DurationRule) Validate(cv ctypes.ConfigValue) error {
+	v, err := durationValue(d.key, cv)
+	if err != nil {
+		return err
+	}
+	if d.minimum != nil && v < *d.minimum {
+		return fmt.Errorf("value is under minimum (%s value %v < %v)", d.key, v, *d.minimum)
+	}
+	if d.maximum != nil && v > *d.maximum {
+		return fmt.Errorf("value is over maximum (%s value %v > %v)", d.key, v, *d.maximum)
+	}
+	return nil
+}
+
+func (d *DurationRule) convert(cv ctypes.ConfigValue) ctypes.ConfigValue {
+	v, err := durationValue(d.key, cv)
+	if err != nil {
+		return cv
+	}
+	return ctypes.ConfigValueDuration{Value: v}
+}
+
+// durationValue returns the duration of a duration or string config value.
+func durationValue(key string, cv ctypes.ConfigValue) (time.Duration, error) {
+	switch v := cv.(type) {
+	case ctypes.ConfigValueDuration:
+		return v.Value, nil
+	case ctypes.ConfigValueStr:
+		d, err := time.ParseDuration(v.Value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration (%s value '%s': %v)", key, v.Value, err)
+		}
+		return d, nil
+	}
+	return 0, wrongType(key, cv.Type(), "duration")
+}
+
+// Returns a default value is it exists.
+func (d *DurationRule) Default() ctypes.ConfigValue {
+	if d.default_ != nil {
+		return &ctypes.ConfigValueDuration{Value: *d.default_}
+	}
+	return nil
+}
+
+// Indicates this rule is required.
+func (d *DurationRule) Required() bool {
+	return d.required
+}
+
+// SetMinimum sets the minimum allowed value
+func (d *DurationRule) SetMinimum(m time.Duration) {
+	d.minimum = &m
+}
+
+// SetMaximum sets the maximum allowed value
+func (d *DurationRule) SetMaximum(m time.Duration) {
+	d.maximum = &m
+}
+
+func (d *DurationRule) Minimum() ctypes.ConfigValue {
+	if d.minimum != nil {
+		return &ctypes.ConfigValueDuration{Value: *d.minimum}
+	}
+	return nil
+}
+
+func (d *DurationRule) Maximum() ctypes.ConfigValue {
+	if d.maximum != nil {
+		return &ctypes.ConfigValueDuration{Value: *d.maximum}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpolicy
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/core/ctypes"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConfigPolicyRuleDuration(t *testing.T) {
+	Convey("NewDurationRule", t, func() {
+
+		Convey("empty key", func() {
+			r, e := NewDurationRule("", true)
+			So(r, ShouldBeNil)
+			So(e, ShouldResemble, EmptyKeyError)
+		})
+
+		Convey("default is set", func() {
+			r, e := NewDurationRule("timeout", false, 5*time.Second)
+			So(e, ShouldBeNil)
+			So(r.Type(), ShouldEqual, "duration")
+			So(r.Default().(*ctypes.ConfigValueDuration).Value, ShouldEqual, 5*time.Second)
+		})
+
+		Convey("processing", func() {
+			r, _ := NewDurationRule("timeout", true)
+			r.SetMinimum(time.Second)
+			r.SetMaximum(time.Minute)
+
+			Convey("passes with a duration config value", func() {
+				So(r.Validate(ctypes.ConfigValueDuration{Value: 10 * time.Second}), ShouldBeNil)
+			})
+
+			Convey("passes with a duration string and converts it", func() {
+				v := ctypes.ConfigValueStr{Value: "1m"}
+				So(r.Validate(v), ShouldBeNil)
+				So(r.convert(v), ShouldResemble, ctypes.ConfigValueDuration{Value: time.Minute})
+			})
+
+			Convey("errors with an invalid duration string", func() {
+				e := r.Validate(ctypes.ConfigValueStr{Value: "soon"})
+				So(e, ShouldNotBeNil)
+				So(e.Error(), ShouldStartWith, "invalid duration (timeout value 'soon': ")
+			})
+
+			Convey("errors under the minimum", func() {
+				e := r.Validate(ctypes.ConfigValueStr{Value: "500ms"})
+				So(e, ShouldResemble, errors.New("value is under minimum (timeout value 500ms < 1s)"))
+			})
+
+			Convey("errors over the maximum", func() {
+				e := r.Validate(ctypes.ConfigValueDuration{Value: time.Hour})
+				So(e, ShouldResemble, errors.New("value is over maximum (timeout value 1h0m0s > 1m0s)"))
+			})
+
+			Convey("errors with non-duration config value", func() {
+				e := r.Validate(ctypes.ConfigValueInt{Value: 5})
+				So(e, ShouldResemble, errors.New("type mismatch (timeout wanted type 'duration' but provided type 'integer')"))
+			})
+		})
+
+		Convey("gob encoding", func() {
+			r, _ := NewDurationRule("timeout", false, 5*time.Second)
+			r.SetMaximum(time.Minute)
+			buf := new(bytes.Buffer)
+			So(gob.NewEncoder(buf).Encode(r), ShouldBeNil)
+			r2 := &DurationRule{}
+			So(gob.NewDecoder(buf).Decode(r2), ShouldBeNil)
+			So(r2.Key(), ShouldEqual, "timeout")
+			So(r2.Default().(*ctypes.ConfigValueDuration).Value, ShouldEqual, 5*time.Second)
+			So(r2.Minimum(), ShouldBeNil)
+			So(r2.Maximum().(*ctypes.ConfigValueDuration).Value, ShouldEqual, time.Minute)
+		})
+	})
+}
@@ -0,0 +1,187 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpolicy
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+var (
+	EmptyChoicesError = errors.New("choices cannot be empty")
+)
+
+// A rule validating against string-typed config restricted to a set of choices
+type EnumRule struct {
+	rule
+
+	key      string
+	required bool
+	choices  []string
+	default_ *string
+}
+
+// Returns a new enum rule. Arguments are key(string), required(bool), choices([]string), default(string).
+func NewEnumRule(key string, req bool, choices []string, opts ...string) (*EnumRule, error) {
+	// Return error if key is empty
+	if key == "" {
+		return nil, EmptyKeyError
+	}
+	if len(choices) == 0 {
+		return nil, EmptyChoicesError
+	}
+
+	e := &EnumRule{
+		key:      key,
+		required: req,
+		choices:  choices,
+	}
+	if len(opts) > 0 {
+		if !e.allows(opts[0]) {
+			return nil, fmt.Errorf("default is not one of the choices (%s default '%s' not in [%s])", key, opts[0], strings.Join(choices, ", "))
+		}
+		e.default_ = &opts[0]
+	}
+	return e, nil
+}
+
+func (e *EnumRule) Type() string {
+	return "enum"
+}
+
+// MarshalJSON marshals an EnumRule into JSON
+func (e *EnumRule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Key      string             `json:"key"`
+		Required bool               `json:"required"`
+		Default  ctypes.ConfigValue `json:"default,omitempty"`
+		Choices  []string           `json:"choices"`
+		Type     string             `json:"type"`
+	}{
+		Key:      e.key,
+		Required: e.required,
+		Default:  e.Default(),
+		Choices:  e.choices,
+		Type:     "enum",
+	})
+}
+
+// GobEncode encodes an EnumRule into a GOB
+func (e *EnumRule) GobEncode() ([]byte, error) {
+	w := new(bytes.Buffer)
+	encoder := gob.NewEncoder(w)
+	if err := encoder.Encode(e.key); err != nil {
+		return nil, err
+	}
+	if err := encoder.Encode(e.required); err != nil {
+		return nil, err
+	}
+	if err := encoder.Encode(e.choices); err != nil {
+		return nil, err
+	}
+	if e.default_ == nil {
+		encoder.Encode(false)
+	} else {
+		encoder.Encode(true)
+		if err := encoder.Encode(e.default_); err != nil {
+			return nil, err
+		}
+	}
+	return w.Bytes(), nil
+}
+
+// GobDecode decodes a GOB into an EnumRule
+func (e *EnumRule) GobDecode(buf []byte) error {
+	r := bytes.NewBuffer(buf)
+	decoder := gob.NewDecoder(r)
+	if err := decoder.Decode(&e.key); err != nil {
+		return err
+	}
+	if err := decoder.Decode(&e.required); err != nil {
+		return err
+	}
+	if err := decoder.Decode(&e.choices); err != nil {
+		return err
+	}
+	var is_default_set bool
+	decoder.Decode(&is_default_set)
+	if is_default_set {
+		return decoder.Decode(&e.default_)
+	}
+	return nil
+}
+
+// Returns the key
+func (e *EnumRule) Key() string {
+	return e.key
+}
+
+// Validates a config value against this rule.
+func (e *EnumRule) Validate(cv ctypes.ConfigValue) error {
+	// Check that type is correct
+	if cv.Type() != "string" {
+		return wrongType(e.key, cv.Type(), "string")
+	}
+	if v := cv.(ctypes.ConfigValueStr).Value; !e.allows(v) {
+		return fmt.Errorf("value is not one of the choices (%s value '%s' not in [%s])", e.key, v, strings.Join(e.choices, ", "))
+	}
+	return nil
+}
+
+func (e *EnumRule) allows(v string) bool {
+	for _, c := range e.choices {
+		if c == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns a default value is it exists.
+func (e *EnumRule) Default() ctypes.ConfigValue {
+	if e.default_ != nil {
+		return &ctypes.ConfigValueStr{Value: *e.default_}
+	}
+	return nil
+}
+
+// Indicates this rule is required.
+func (e *EnumRule) Required() bool {
+	return e.required
+}
+
+// Returns the values allowed by this rule.
+func (e *EnumRule) Choices() []string {
+	return e.choices
+}
+
+func (e *EnumRule) Minimum() ctypes.ConfigValue {
+	return nil
+}
+
+func (e *EnumRule) Maximum() ctypes.ConfigValue {
+	return nil
+}
@@ -0,0 +1,91 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpolicy
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"testing"
+
+	"github.com/intelsdi-x/snap/core/ctypes"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConfigPolicyRuleEnum(t *testing.T) {
+	Convey("NewEnumRule", t, func() {
+
+		Convey("empty key", func() {
+			r, e := NewEnumRule("", true, []string{"fast"})
+			So(r, ShouldBeNil)
+			So(e, ShouldResemble, EmptyKeyError)
+		})
+
+		Convey("empty choices", func() {
+			r, e := NewEnumRule("mode", true, nil)
+			So(r, ShouldBeNil)
+			So(e, ShouldResemble, EmptyChoicesError)
+		})
+
+		Convey("default is set", func() {
+			r, e := NewEnumRule("mode", false, []string{"fast", "slow"}, "slow")
+			So(e, ShouldBeNil)
+			So(r.Type(), ShouldEqual, "enum")
+			So(r.Choices(), ShouldResemble, []string{"fast", "slow"})
+			So(r.Default().(*ctypes.ConfigValueStr).Value, ShouldEqual, "slow")
+		})
+
+		Convey("default is not a choice", func() {
+			r, e := NewEnumRule("mode", false, []string{"fast", "slow"}, "medium")
+			So(r, ShouldBeNil)
+			So(e, ShouldResemble, errors.New("default is not one of the choices (mode default 'medium' not in [fast, slow])"))
+		})
+
+		Convey("processing", func() {
+			r, _ := NewEnumRule("mode", true, []string{"fast", "slow"})
+
+			Convey("passes with a choice", func() {
+				So(r.Validate(ctypes.ConfigValueStr{Value: "fast"}), ShouldBeNil)
+			})
+
+			Convey("errors with a value which is not a choice", func() {
+				e := r.Validate(ctypes.ConfigValueStr{Value: "medium"})
+				So(e, ShouldResemble, errors.New("value is not one of the choices (mode value 'medium' not in [fast, slow])"))
+			})
+
+			Convey("errors with non-string config value", func() {
+				e := r.Validate(ctypes.ConfigValueInt{Value: 1})
+				So(e, ShouldResemble, errors.New("type mismatch (mode wanted type 'string' but provided type 'integer')"))
+			})
+		})
+
+		Convey("gob encoding", func() {
+			r, _ := NewEnumRule("mode", true, []string{"fast", "slow"}, "fast")
+			buf := new(bytes.Buffer)
+			So(gob.NewEncoder(buf).Encode(r), ShouldBeNil)
+			r2 := &EnumRule{}
+			So(gob.NewDecoder(buf).Decode(r2), ShouldBeNil)
+			So(r2.Key(), ShouldEqual, "mode")
+			So(r2.Required(), ShouldBeTrue)
+			So(r2.Choices(), ShouldResemble, []string{"fast", "slow"})
+			So(r2.Default().(*ctypes.ConfigValueStr).Value, ShouldEqual, "fast")
+		})
+	})
+}
@@ -0,0 +1,279 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpolicy
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+// The types of the elements of a list rule
+var listElementTypes = []string{"string", "integer", "float", "bool", "duration"}
+
+// A rule validating against list-typed config whose elements all have the
+// same type. Integers are converted to floats in lists of floats and strings
+// to durations in lists of durations.
+type ListRule struct {
+	rule
+
+	key         string
+	required    bool
+	elementType string
+	default_    *ctypes.ConfigValueList
+}
+
+// Returns a new list-typed rule. Arguments are key(string), required(bool),
+// elementType(string, one of string, integer, float, bool or duration),
+// default(ctypes.ConfigValueList).
+func NewListRule(key string, req bool, elementType string, opts ...ctypes.ConfigValueList) (*ListRule, error) {
+	// Return error if key is empty
+	if key == "" {
+		return nil, EmptyKeyError
+	}
+	if !isListElementType(elementType) {
+		return nil, fmt.Errorf("unsupported list element type (%s element type '%s')", key, elementType)
+	}
+
+	l := &ListRule{
+		key:         key,
+		required:    req,
+		elementType: elementType,
+	}
+	if len(opts) > 0 {
+		if err := l.Validate(opts[0]); err != nil {
+			return nil, err
+		}
+		def := l.convert(opts[0]).(ctypes.ConfigValueList)
+		l.default_ = &def
+	}
+	return l, nil
+}
+
+func isListElementType(t string) bool {
+	for _, e := range listElementTypes {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *ListRule) Type() string {
+	return "list"
+}
+
+// MarshalJSON marshals a ListRule into JSON
+func (l *ListRule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Key         string             `json:"key"`
+		Required    bool               `json:"required"`
+		Default     ctypes.ConfigValue `json:"default,omitempty"`
+		ElementType string             `json:"element_type"`
+		Type        string             `json:"type"`
+	}{
+		Key:         l.key,
+		Required:    l.required,
+		Default:     l.Default(),
+		ElementType: l.elementType,
+		Type:        "list",
+	})
+}
+
+// GobEncode encodes a ListRule into a GOB. The default is encoded as JSON so
+// that its elements need no registration.
+func (l *ListRule) GobEncode() ([]byte, error) {
+	w := new(bytes.Buffer)
+	encoder := gob.NewEncoder(w)
+	if err := encoder.Encode(l.key); err != nil {
+		return nil, err
+	}
+	if err := encoder.Encode(l.required); err != nil {
+		return nil, err
+	}
+	if err := encoder.Encode(l.elementType); err != nil {
+		return nil, err
+	}
+	if l.default_ == nil {
+		encoder.Encode(false)
+	} else {
+		encoder.Encode(true)
+		b, err := json.Marshal(l.default_)
+		if err != nil {
+			return nil, err
+		}
+		if err := encoder.Encode(b); err != nil {
+			return nil, err
+		}
+	}
+	return w.Bytes(), nil
+}
+
+// GobDecode decodes a GOB into a ListRule
+func (l *ListRule) GobDecode(buf []byte) error {
+	decoder := gob.NewDecoder(bytes.NewBuffer(buf))
+	if err := decoder.Decode(&l.key); err != nil {
+		return err
+	}
+	if err := decoder.Decode(&l.required); err != nil {
+		return err
+	}
+	if err := decoder.Decode(&l.elementType); err != nil {
+		return err
+	}
+	var is_default_set bool
+	decoder.Decode(&is_default_set)
+	if !is_default_set {
+		return nil
+	}
+	var b []byte
+	if err := decoder.Decode(&b); err != nil {
+		return err
+	}
+	items := []interface{}{}
+	if err := json.Unmarshal(b, &items); err != nil {
+		return err
+	}
+	def, err := listFromJSON(l.key, l.elementType, items)
+	if err != nil {
+		return err
+	}
+	l.default_ = &def
+	return nil
+}
+
+// Returns the key
+func (l *ListRule) Key() string {
+	return l.key
+}
+
+// Validates a config value against this rule.
+func (l *ListRule) Validate(cv ctypes.ConfigValue) error {
+	// Check that type is correct
+	if cv.Type() != "list" {
+		return wrongType(l.key, cv.Type(), "list")
+	}
+	for i, e := range cv.(ctypes.ConfigValueList).Value {
+		if _, err := l.element(i, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *ListRule) convert(cv ctypes.ConfigValue) ctypes.ConfigValue {
+	in, ok := cv.(ctypes.ConfigValueList)
+	if !ok {
+		return cv
+	}
+	out := ctypes.ConfigValueList{Value: make([]ctypes.ConfigValue, len(in.Value))}
+	for i, e := range in.Value {
+		v, err := l.element(i, e)
+		if err != nil {
+			return cv
+		}
+		out.Value[i] = v
+	}
+	return out
+}
+
+// element returns the i-th element of a list, converted to the element type
+// of the rule.
+func (l *ListRule) element(i int, cv ctypes.ConfigValue) (ctypes.ConfigValue, error) {
+	switch {
+	case l.elementType == "duration":
+		d, err := durationValue(fmt.Sprintf("%s[%d]", l.key, i), cv)
+		if err != nil {
+			return nil, err
+		}
+		return ctypes.ConfigValueDuration{Value: d}, nil
+	case cv.Type() == l.elementType:
+		return cv, nil
+	case l.elementType == "float" && cv.Type() == "integer":
+		return ctypes.ConfigValueFloat{Value: float64(cv.(ctypes.ConfigValueInt).Value)}, nil
+	}
+	return nil, wrongType(fmt.Sprintf("%s[%d]", l.key, i), cv.Type(), l.elementType)
+}
+
+// listFromJSON converts the items of a list decoded from JSON into a list of
+// elementType config values.
+func listFromJSON(key, elementType string, items []interface{}) (ctypes.ConfigValueList, error) {
+	l := ctypes.ConfigValueList{Value: make([]ctypes.ConfigValue, len(items))}
+	for i, item := range items {
+		var cv ctypes.ConfigValue
+		switch v := item.(type) {
+		case string:
+			if elementType == "duration" {
+				d, err := time.ParseDuration(v)
+				if err != nil {
+					return l, fmt.Errorf("invalid duration (%s[%d] value '%s': %v)", key, i, v, err)
+				}
+				cv = ctypes.ConfigValueDuration{Value: d}
+			} else {
+				cv = ctypes.ConfigValueStr{Value: v}
+			}
+		case float64:
+			if elementType == "integer" {
+				cv = ctypes.ConfigValueInt{Value: int(v)}
+			} else {
+				cv = ctypes.ConfigValueFloat{Value: v}
+			}
+		case bool:
+			cv = ctypes.ConfigValueBool{Value: v}
+		default:
+			return l, fmt.Errorf("unsupported list element (%s[%d] value %v)", key, i, v)
+		}
+		if cv.Type() != elementType {
+			return l, wrongType(fmt.Sprintf("%s[%d]", key, i), cv.Type(), elementType)
+		}
+		l.Value[i] = cv
+	}
+	return l, nil
+}
+
+// Returns a default value is it exists.
+func (l *ListRule) Default() ctypes.ConfigValue {
+	if l.default_ != nil {
+		return l.default_
+	}
+	return nil
+}
+
+// Indicates this rule is required.
+func (l *ListRule) Required() bool {
+	return l.required
+}
+
+// Returns the type of the elements of the lists validated by this rule.
+func (l *ListRule) ElementType() string {
+	return l.elementType
+}
+
+func (l *ListRule) Minimum() ctypes.ConfigValue {
+	return nil
+}
+
+func (l *ListRule) Maximum() ctypes.ConfigValue {
+	return nil
+}
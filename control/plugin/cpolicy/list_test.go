@@ -0,0 +1,117 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpolicy
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/core/ctypes"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConfigPolicyRuleList(t *testing.T) {
+	Convey("NewListRule", t, func() {
+
+		Convey("empty key", func() {
+			r, e := NewListRule("", true, "string")
+			So(r, ShouldBeNil)
+			So(e, ShouldResemble, EmptyKeyError)
+		})
+
+		Convey("unsupported element type", func() {
+			r, e := NewListRule("hosts", true, "list")
+			So(r, ShouldBeNil)
+			So(e, ShouldResemble, errors.New("unsupported list element type (hosts element type 'list')"))
+		})
+
+		Convey("default is set", func() {
+			def := ctypes.ConfigValueList{Value: []ctypes.ConfigValue{ctypes.ConfigValueStr{Value: "a"}, ctypes.ConfigValueStr{Value: "b"}}}
+			r, e := NewListRule("hosts", false, "string", def)
+			So(e, ShouldBeNil)
+			So(r.Type(), ShouldEqual, "list")
+			So(r.ElementType(), ShouldEqual, "string")
+			So(*r.Default().(*ctypes.ConfigValueList), ShouldResemble, def)
+		})
+
+		Convey("default has elements of another type", func() {
+			def := ctypes.ConfigValueList{Value: []ctypes.ConfigValue{ctypes.ConfigValueInt{Value: 1}}}
+			r, e := NewListRule("hosts", false, "string", def)
+			So(r, ShouldBeNil)
+			So(e, ShouldResemble, errors.New("type mismatch (hosts[0] wanted type 'string' but provided type 'integer')"))
+		})
+
+		Convey("processing", func() {
+
+			Convey("passes with elements of the element type", func() {
+				r, _ := NewListRule("ports", true, "integer")
+				v := ctypes.ConfigValueList{Value: []ctypes.ConfigValue{ctypes.ConfigValueInt{Value: 80}, ctypes.ConfigValueInt{Value: 443}}}
+				So(r.Validate(v), ShouldBeNil)
+				So(r.convert(v), ShouldResemble, v)
+			})
+
+			Convey("passes with an empty list", func() {
+				r, _ := NewListRule("ports", true, "integer")
+				So(r.Validate(ctypes.ConfigValueList{}), ShouldBeNil)
+			})
+
+			Convey("errors with elements of another type", func() {
+				r, _ := NewListRule("ports", true, "integer")
+				v := ctypes.ConfigValueList{Value: []ctypes.ConfigValue{ctypes.ConfigValueInt{Value: 80}, ctypes.ConfigValueStr{Value: "https"}}}
+				So(r.Validate(v), ShouldResemble, errors.New("type mismatch (ports[1] wanted type 'integer' but provided type 'string')"))
+			})
+
+			Convey("converts integers in lists of floats", func() {
+				r, _ := NewListRule("thresholds", true, "float")
+				v := ctypes.ConfigValueList{Value: []ctypes.ConfigValue{ctypes.ConfigValueInt{Value: 1}, ctypes.ConfigValueFloat{Value: 2.5}}}
+				So(r.Validate(v), ShouldBeNil)
+				So(r.convert(v), ShouldResemble, ctypes.ConfigValueList{Value: []ctypes.ConfigValue{ctypes.ConfigValueFloat{Value: 1}, ctypes.ConfigValueFloat{Value: 2.5}}})
+			})
+
+			Convey("converts strings in lists of durations", func() {
+				r, _ := NewListRule("intervals", true, "duration")
+				v := ctypes.ConfigValueList{Value: []ctypes.ConfigValue{ctypes.ConfigValueStr{Value: "1s"}, ctypes.ConfigValueDuration{Value: time.Minute}}}
+				So(r.Validate(v), ShouldBeNil)
+				So(r.convert(v), ShouldResemble, ctypes.ConfigValueList{Value: []ctypes.ConfigValue{ctypes.ConfigValueDuration{Value: time.Second}, ctypes.ConfigValueDuration{Value: time.Minute}}})
+			})
+
+			Convey("errors with non-list config value", func() {
+				r, _ := NewListRule("ports", true, "integer")
+				e := r.Validate(ctypes.ConfigValueInt{Value: 80})
+				So(e, ShouldResemble, errors.New("type mismatch (ports wanted type 'list' but provided type 'integer')"))
+			})
+		})
+
+		Convey("gob encoding", func() {
+			def := ctypes.ConfigValueList{Value: []ctypes.ConfigValue{ctypes.ConfigValueStr{Value: "1s"}}}
+			r, _ := NewListRule("intervals", false, "duration", def)
+			buf := new(bytes.Buffer)
+			So(gob.NewEncoder(buf).Encode(r), ShouldBeNil)
+			r2 := &ListRule{}
+			So(gob.NewDecoder(buf).Decode(r2), ShouldBeNil)
+			So(r2.Key(), ShouldEqual, "intervals")
+			So(r2.ElementType(), ShouldEqual, "duration")
+			So(*r2.Default().(*ctypes.ConfigValueList), ShouldResemble, ctypes.ConfigValueList{Value: []ctypes.ConfigValue{ctypes.ConfigValueDuration{Value: time.Second}}})
+		})
+	})
+}
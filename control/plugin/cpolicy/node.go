@@ -25,7 +25,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"sync"
+	"time"
 
 	"github.com/intelsdi-x/snap/core/ctypes"
 	"github.com/intelsdi-x/snap/pkg/ctree"
@@ -60,8 +62,9 @@ func (p *ProcessingErrors) AddError(e error) {
 }
 
 type ConfigPolicyNode struct {
-	rules map[string]Rule
-	mutex *sync.Mutex
+	rules       map[string]Rule
+	constraints []*Constraint
+	mutex       *sync.Mutex
 }
 
 func NewPolicyNode() *ConfigPolicyNode {
@@ -85,6 +88,9 @@ func (c *ConfigPolicyNode) UnmarshalJSON(data []byte) error {
 					addRulesToConfigPolicyNode(rules, c)
 				}
 			}
+			if cs, ok := pn["constraints"].([]interface{}); ok {
+				addConstraintsToConfigPolicyNode(cs, c)
+			}
 		}
 	}
 	return nil
@@ -92,9 +98,11 @@ func (c *ConfigPolicyNode) UnmarshalJSON(data []byte) error {
 
 func (c *ConfigPolicyNode) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
-		Rules map[string]Rule `json:"rules"`
+		Rules       map[string]Rule `json:"rules"`
+		Constraints []*Constraint   `json:"constraints,omitempty"`
 	}{
-		Rules: c.rules,
+		Rules:       c.rules,
+		Constraints: c.constraints,
 	})
 }
 
@@ -104,6 +112,9 @@ func (c *ConfigPolicyNode) GobEncode() ([]byte, error) {
 	if err := encoder.Encode(&c.rules); err != nil {
 		return nil, err
 	}
+	if err := encoder.Encode(c.constraints); err != nil {
+		return nil, err
+	}
 	return w.Bytes(), nil
 }
 
@@ -111,7 +122,14 @@ func (c *ConfigPolicyNode) GobDecode(buf []byte) error {
 	c.mutex = &sync.Mutex{}
 	r := bytes.NewBuffer(buf)
 	decoder := gob.NewDecoder(r)
-	return decoder.Decode(&c.rules)
+	if err := decoder.Decode(&c.rules); err != nil {
+		return err
+	}
+	// nodes encoded by plugins built before constraints have none
+	if err := decoder.Decode(&c.constraints); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
 }
 
 // Adds a rule to this policy node
@@ -123,6 +141,20 @@ func (p *ConfigPolicyNode) Add(rules ...Rule) {
 	}
 }
 
+// Adds constraints spanning several keys to this policy node
+func (p *ConfigPolicyNode) AddConstraint(constraints ...*Constraint) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.constraints = append(p.constraints, constraints...)
+}
+
+// Returns the constraints of this policy node
+func (p *ConfigPolicyNode) Constraints() []*Constraint {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.constraints
+}
+
 type RuleTable struct {
 	Name     string
 	Type     string
@@ -130,6 +162,12 @@ type RuleTable struct {
 	Required bool
 	Minimum  interface{}
 	Maximum  interface{}
+	// Choices of an enum rule
+	Choices []string
+	// Pattern of a regex rule
+	Pattern string
+	// ElementType of a list rule
+	ElementType string
 }
 
 func (p *ConfigPolicyNode) RulesAsTable() []RuleTable {
@@ -138,18 +176,40 @@ func (p *ConfigPolicyNode) RulesAsTable() []RuleTable {
 
 	rt := make([]RuleTable, 0, len(p.rules))
 	for _, r := range p.rules {
-		rt = append(rt, RuleTable{
+		t := RuleTable{
 			Name:     r.Key(),
 			Type:     r.Type(),
 			Default:  r.Default(),
 			Required: r.Required(),
 			Minimum:  r.Minimum(),
 			Maximum:  r.Maximum(),
-		})
+		}
+		switch r := r.(type) {
+		case *EnumRule:
+			t.Choices = r.Choices()
+		case *RegexRule:
+			t.Pattern = r.Pattern()
+		case *ListRule:
+			t.ElementType = r.ElementType()
+		}
+		rt = append(rt, t)
 	}
 	return rt
 }
 
+// Defaults returns the default values of the rules having one
+func (c *ConfigPolicyNode) Defaults() map[string]ctypes.ConfigValue {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defaults := make(map[string]ctypes.ConfigValue)
+	for key, rule := range c.rules {
+		if cv := rule.Default(); cv != nil {
+			defaults[key] = cv
+		}
+	}
+	return defaults
+}
+
 func (c *ConfigPolicyNode) HasRules() bool {
 	if len(c.rules) > 0 {
 		return true
@@ -162,6 +222,7 @@ func (c *ConfigPolicyNode) Process(m map[string]ctypes.ConfigValue) (*map[string
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 	pErrors := NewProcessingErrors()
+	invalid := map[string]bool{}
 	// Loop through each rule and process
 	for key, rule := range c.rules {
 		// items exists for rule
@@ -170,6 +231,9 @@ func (c *ConfigPolicyNode) Process(m map[string]ctypes.ConfigValue) (*map[string
 			e := rule.Validate(cv)
 			if e != nil {
 				pErrors.AddError(e)
+				invalid[key] = true
+			} else if c, ok := rule.(converter); ok {
+				m[key] = c.convert(cv)
 			}
 		} else {
 			// If it was required add error
@@ -187,12 +251,43 @@ func (c *ConfigPolicyNode) Process(m map[string]ctypes.ConfigValue) (*map[string
 		}
 	}
 
+	// Check the constraints against the config completed with defaults,
+	// leaving out those on values already reported invalid
+constraints:
+	for _, cs := range c.constraints {
+		for _, k := range cs.Keys {
+			if invalid[k] {
+				continue constraints
+			}
+		}
+		if e := cs.Check(m); e != nil {
+			pErrors.AddError(e)
+		}
+	}
+
 	if pErrors.HasErrors() {
 		return nil, pErrors
 	}
 	return &m, pErrors
 }
 
+// Validate checks the values given in m against the rules of their keys.
+// Unlike Process it does not report the required keys missing nor check the
+// constraints, as the config may be completed by config given elsewhere.
+func (c *ConfigPolicyNode) Validate(m map[string]ctypes.ConfigValue) *ProcessingErrors {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	pErrors := NewProcessingErrors()
+	for key, rule := range c.rules {
+		if cv, ok := m[key]; ok {
+			if e := rule.Validate(cv); e != nil {
+				pErrors.AddError(e)
+			}
+		}
+	}
+	return pErrors
+}
+
 // Merges a ConfigPolicyNode on top of this one (overwriting items where it occurs).
 func (c ConfigPolicyNode) Merge(n ctree.Node) ctree.Node {
 	// Because Add only allows the ConfigPolicyNode type we
@@ -203,10 +298,33 @@ func (c ConfigPolicyNode) Merge(n ctree.Node) ctree.Node {
 	for _, r := range cd.rules {
 		c.Add(r)
 	}
+	// Constraints of both nodes apply. The slice is copied as c is a copy
+	// sharing it with the node merged into.
+	c.constraints = append(append([]*Constraint{}, c.constraints...), cd.constraints...)
 	// Return modified version of ConfigPolicyNode(as ctree.Node)
 	return c
 }
 
+// addConstraintsToConfigPolicyNode adds the constraints of a JSON decoded
+// policy node to the ConfigPolicyNode provided as the second argument.
+func addConstraintsToConfigPolicyNode(constraints []interface{}, cpn *ConfigPolicyNode) {
+	for _, c := range constraints {
+		m, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		cs := &Constraint{}
+		cs.Type, _ = m["type"].(string)
+		keys, _ := m["keys"].([]interface{})
+		for _, k := range keys {
+			if k, ok := k.(string); ok {
+				cs.Keys = append(cs.Keys, k)
+			}
+		}
+		cpn.AddConstraint(cs)
+	}
+}
+
 // addRulesToConfigPolicyNode accepts a map of empty interfaces that will be
 // marshalled into rules which will be added to the ConfigPolicyNode provided
 // as the second argument.  This function is called used by the UnmarshalJSON
@@ -260,6 +378,65 @@ func addRulesToConfigPolicyNode(rules map[string]interface{}, cpn *ConfigPolicyN
 					r.maximum = &max
 				}
 				cpn.Add(r)
+			case "bool":
+				r, _ := NewBoolRule(k, req)
+				if d, ok := rule["default"]; ok {
+					def, _ := d.(bool)
+					r.default_ = &def
+				}
+				cpn.Add(r)
+			case "enum":
+				var choices []string
+				cs, _ := rule["choices"].([]interface{})
+				for _, c := range cs {
+					if c, ok := c.(string); ok {
+						choices = append(choices, c)
+					}
+				}
+				r, err := NewEnumRule(k, req, choices)
+				if err != nil {
+					return err
+				}
+				if d, ok := rule["default"].(string); ok {
+					r.default_ = &d
+				}
+				cpn.Add(r)
+			case "regex":
+				pattern, _ := rule["pattern"].(string)
+				r, err := NewRegexRule(k, req, pattern)
+				if err != nil {
+					return err
+				}
+				if d, ok := rule["default"].(string); ok {
+					r.default_ = &d
+				}
+				cpn.Add(r)
+			case "duration":
+				r, _ := NewDurationRule(k, req)
+				for name, v := range map[string]**time.Duration{"default": &r.default_, "minimum": &r.minimum, "maximum": &r.maximum} {
+					if d, ok := rule[name].(string); ok {
+						dur, err := time.ParseDuration(d)
+						if err != nil {
+							return err
+						}
+						*v = &dur
+					}
+				}
+				cpn.Add(r)
+			case "list":
+				elementType, _ := rule["element_type"].(string)
+				r, err := NewListRule(k, req, elementType)
+				if err != nil {
+					return err
+				}
+				if d, ok := rule["default"].([]interface{}); ok {
+					def, err := listFromJSON(k, elementType, d)
+					if err != nil {
+						return err
+					}
+					r.default_ = &def
+				}
+				cpn.Add(r)
 			default:
 				return errors.New("unknown type")
 			}
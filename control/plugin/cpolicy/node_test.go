@@ -21,6 +21,7 @@ package cpolicy
 
 import (
 	"testing"
+	"time"
 
 	"github.com/intelsdi-x/snap/core/ctypes"
 	. "github.com/smartystreets/goconvey/convey"
@@ -234,5 +235,91 @@ func TestConfigPolicyNode(t *testing.T) {
 
 		So(len(pe.Errors()), ShouldEqual, 1)
 	})
+	Convey("Test values are converted by the rules validating them", t, func() {
+		n := NewPolicyNode()
+
+		m := map[string]ctypes.ConfigValue{}
+		m["timeout"] = ctypes.ConfigValueStr{Value: "5s"}
+		m["thresholds"] = ctypes.ConfigValueList{Value: []ctypes.ConfigValue{ctypes.ConfigValueInt{Value: 1}}}
+
+		r1, _ := NewDurationRule("timeout", true)
+		r2, _ := NewListRule("thresholds", true, "float")
+		r3, _ := NewEnumRule("mode", false, []string{"fast", "slow"}, "fast")
+
+		n.Add(r1, r2, r3)
+
+		m2, pe := n.Process(m)
+
+		So(len(pe.Errors()), ShouldEqual, 0)
+		So((*m2)["timeout"], ShouldResemble, ctypes.ConfigValueDuration{Value: 5 * time.Second})
+		So((*m2)["thresholds"], ShouldResemble, ctypes.ConfigValueList{Value: []ctypes.ConfigValue{ctypes.ConfigValueFloat{Value: 1}}})
+		So((*m2)["mode"].(*ctypes.ConfigValueStr).Value, ShouldEqual, "fast")
+	})
+
+	Convey("Test rules as table describe enum, regex and list rules", t, func() {
+		n := NewPolicyNode()
+		r1, _ := NewEnumRule("mode", false, []string{"fast", "slow"})
+		r2, _ := NewRegexRule("host", false, "^[a-z]+$")
+		r3, _ := NewListRule("ports", false, "integer")
+		n.Add(r1, r2, r3)
+
+		rt := map[string]RuleTable{}
+		for _, r := range n.RulesAsTable() {
+			rt[r.Name] = r
+		}
+		So(rt["mode"].Choices, ShouldResemble, []string{"fast", "slow"})
+		So(rt["host"].Pattern, ShouldEqual, "^[a-z]+$")
+		So(rt["ports"].ElementType, ShouldEqual, "integer")
+	})
+
+	Convey("Test constraints are checked after the rules", t, func() {
+		n := NewPolicyNode()
+
+		m := map[string]ctypes.ConfigValue{}
+		m["user"] = ctypes.ConfigValueStr{Value: "root"}
+		m["max"] = ctypes.ConfigValueInt{Value: 5}
 
+		r1, _ := NewStringRule("user", false)
+		r2, _ := NewStringRule("password", false)
+		r3, _ := NewIntegerRule("min", false, 10)
+		r4, _ := NewIntegerRule("max", false)
+
+		n.Add(r1, r2, r3, r4)
+		n.AddConstraint(RequiredWith("password", "user"), GreaterThan("max", "min"))
+
+		_, pe := n.Process(m)
+
+		So(len(pe.Errors()), ShouldEqual, 2)
+		So(errorsMsg(pe.Errors()), ShouldContain, "required key missing (password is required when user is set)")
+		So(errorsMsg(pe.Errors()), ShouldContain, "value is not greater (max value 5 <= min value 10)")
+	})
+
+	Convey("Test constraints on invalid values are not checked", t, func() {
+		n := NewPolicyNode()
+
+		m := map[string]ctypes.ConfigValue{}
+		m["max"] = ctypes.ConfigValueStr{Value: "5"}
+		m["min"] = ctypes.ConfigValueInt{Value: 10}
+
+		r1, _ := NewIntegerRule("min", false)
+		r2, _ := NewIntegerRule("max", false)
+
+		n.Add(r1, r2)
+		n.AddConstraint(GreaterThan("max", "min"))
+
+		_, pe := n.Process(m)
+
+		So(errorsMsg(pe.Errors()), ShouldResemble, []string{"type mismatch (max wanted type 'integer' but provided type 'string')"})
+	})
+
+	Convey("Test merged nodes keep the constraints of both", t, func() {
+		n1 := NewPolicyNode()
+		n1.AddConstraint(RequiredWith("password", "user"))
+		n2 := NewPolicyNode()
+		n2.AddConstraint(GreaterThan("max", "min"))
+
+		n3 := n1.Merge(n2).(ConfigPolicyNode)
+		So(n3.Constraints(), ShouldResemble, []*Constraint{RequiredWith("password", "user"), GreaterThan("max", "min")})
+		So(len(n1.Constraints()), ShouldEqual, 1)
+	})
 }
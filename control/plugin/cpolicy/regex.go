@@ -0,0 +1,180 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpolicy
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+// A rule validating against string-typed config matching a regular expression
+type RegexRule struct {
+	rule
+
+	key      string
+	required bool
+	pattern  *regexp.Regexp
+	default_ *string
+}
+
+// Returns a new regex rule. Arguments are key(string), required(bool), pattern(string), default(string).
+// The pattern is not anchored, use ^ and $ to match the whole value.
+func NewRegexRule(key string, req bool, pattern string, opts ...string) (*RegexRule, error) {
+	// Return error if key is empty
+	if key == "" {
+		return nil, EmptyKeyError
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &RegexRule{
+		key:      key,
+		required: req,
+		pattern:  re,
+	}
+	if len(opts) > 0 {
+		if !re.MatchString(opts[0]) {
+			return nil, fmt.Errorf("default does not match the pattern (%s default '%s' does not match '%s')", key, opts[0], pattern)
+		}
+		r.default_ = &opts[0]
+	}
+	return r, nil
+}
+
+func (r *RegexRule) Type() string {
+	return "regex"
+}
+
+// MarshalJSON marshals a RegexRule into JSON
+func (r *RegexRule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Key      string             `json:"key"`
+		Required bool               `json:"required"`
+		Default  ctypes.ConfigValue `json:"default,omitempty"`
+		Pattern  string             `json:"pattern"`
+		Type     string             `json:"type"`
+	}{
+		Key:      r.key,
+		Required: r.required,
+		Default:  r.Default(),
+		Pattern:  r.Pattern(),
+		Type:     "regex",
+	})
+}
+
+// GobEncode encodes a RegexRule into a GOB
+func (r *RegexRule) GobEncode() ([]byte, error) {
+	w := new(bytes.Buffer)
+	encoder := gob.NewEncoder(w)
+	if err := encoder.Encode(r.key); err != nil {
+		return nil, err
+	}
+	if err := encoder.Encode(r.required); err != nil {
+		return nil, err
+	}
+	if err := encoder.Encode(r.Pattern()); err != nil {
+		return nil, err
+	}
+	if r.default_ == nil {
+		encoder.Encode(false)
+	} else {
+		encoder.Encode(true)
+		if err := encoder.Encode(r.default_); err != nil {
+			return nil, err
+		}
+	}
+	return w.Bytes(), nil
+}
+
+// GobDecode decodes a GOB into a RegexRule
+func (r *RegexRule) GobDecode(buf []byte) error {
+	decoder := gob.NewDecoder(bytes.NewBuffer(buf))
+	if err := decoder.Decode(&r.key); err != nil {
+		return err
+	}
+	if err := decoder.Decode(&r.required); err != nil {
+		return err
+	}
+	var pattern string
+	if err := decoder.Decode(&pattern); err != nil {
+		return err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	r.pattern = re
+	var is_default_set bool
+	decoder.Decode(&is_default_set)
+	if is_default_set {
+		return decoder.Decode(&r.default_)
+	}
+	return nil
+}
+
+// Returns the key
+func (r *RegexRule) Key() string {
+	return r.key
+}
+
+// Validates a config value against this rule.
+func (r *RegexRule) Validate(cv ctypes.ConfigValue) error {
+	// Check that type is correct
+	if cv.Type() != "string" {
+		return wrongType(r.key, cv.Type(), "string")
+	}
+	if v := cv.(ctypes.ConfigValueStr).Value; !r.pattern.MatchString(v) {
+		return fmt.Errorf("value does not match the pattern (%s value '%s' does not match '%s')", r.key, v, r.Pattern())
+	}
+	return nil
+}
+
+// Returns a default value is it exists.
+func (r *RegexRule) Default() ctypes.ConfigValue {
+	if r.default_ != nil {
+		return &ctypes.ConfigValueStr{Value: *r.default_}
+	}
+	return nil
+}
+
+// Indicates this rule is required.
+func (r *RegexRule) Required() bool {
+	return r.required
+}
+
+// Returns the regular expression the values must match.
+func (r *RegexRule) Pattern() string {
+	return r.pattern.String()
+}
+
+func (r *RegexRule) Minimum() ctypes.ConfigValue {
+	return nil
+}
+
+func (r *RegexRule) Maximum() ctypes.ConfigValue {
+	return nil
+}
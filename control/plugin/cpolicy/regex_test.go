@@ -0,0 +1,91 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpolicy
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"testing"
+
+	"github.com/intelsdi-x/snap/core/ctypes"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConfigPolicyRuleRegex(t *testing.T) {
+	Convey("NewRegexRule", t, func() {
+
+		Convey("empty key", func() {
+			r, e := NewRegexRule("", true, "^[a-z]+$")
+			So(r, ShouldBeNil)
+			So(e, ShouldResemble, EmptyKeyError)
+		})
+
+		Convey("invalid pattern", func() {
+			r, e := NewRegexRule("host", true, "[a-z")
+			So(r, ShouldBeNil)
+			So(e, ShouldNotBeNil)
+		})
+
+		Convey("default is set", func() {
+			r, e := NewRegexRule("host", false, "^[a-z]+$", "localhost")
+			So(e, ShouldBeNil)
+			So(r.Type(), ShouldEqual, "regex")
+			So(r.Pattern(), ShouldEqual, "^[a-z]+$")
+			So(r.Default().(*ctypes.ConfigValueStr).Value, ShouldEqual, "localhost")
+		})
+
+		Convey("default does not match", func() {
+			r, e := NewRegexRule("host", false, "^[a-z]+$", "127.0.0.1")
+			So(r, ShouldBeNil)
+			So(e, ShouldResemble, errors.New("default does not match the pattern (host default '127.0.0.1' does not match '^[a-z]+$')"))
+		})
+
+		Convey("processing", func() {
+			r, _ := NewRegexRule("host", true, "^[a-z]+$")
+
+			Convey("passes with a matching value", func() {
+				So(r.Validate(ctypes.ConfigValueStr{Value: "node"}), ShouldBeNil)
+			})
+
+			Convey("errors with a value which does not match", func() {
+				e := r.Validate(ctypes.ConfigValueStr{Value: "node1"})
+				So(e, ShouldResemble, errors.New("value does not match the pattern (host value 'node1' does not match '^[a-z]+$')"))
+			})
+
+			Convey("errors with non-string config value", func() {
+				e := r.Validate(ctypes.ConfigValueBool{Value: true})
+				So(e, ShouldResemble, errors.New("type mismatch (host wanted type 'string' but provided type 'bool')"))
+			})
+		})
+
+		Convey("gob encoding", func() {
+			r, _ := NewRegexRule("host", false, "^[a-z]+$", "node")
+			buf := new(bytes.Buffer)
+			So(gob.NewEncoder(buf).Encode(r), ShouldBeNil)
+			r2 := &RegexRule{}
+			So(gob.NewDecoder(buf).Decode(r2), ShouldBeNil)
+			So(r2.Key(), ShouldEqual, "host")
+			So(r2.Pattern(), ShouldEqual, "^[a-z]+$")
+			So(r2.Default().(*ctypes.ConfigValueStr).Value, ShouldEqual, "node")
+			So(r2.Validate(ctypes.ConfigValueStr{Value: "node1"}), ShouldNotBeNil)
+		})
+	})
+}
@@ -43,6 +43,12 @@ type Rule interface {
 	Maximum() ctypes.ConfigValue
 }
 
+// A rule converting the values it validated, e.g. a duration provided as a
+// string, before they are handed to the plugin.
+type converter interface {
+	convert(ctypes.ConfigValue) ctypes.ConfigValue
+}
+
 type rule struct {
 	Description string
 }
@@ -94,6 +94,9 @@ func unmarshalJSON(m map[string]interface{}, keys *[]string, config *ctree.Confi
 						return err
 					}
 				}
+				if cs, ok := node["constraints"].([]interface{}); ok {
+					addConstraintsToConfigPolicyNode(cs, cpn)
+				}
 				config.Add(*keys, cpn)
 			}
 		}
@@ -21,7 +21,9 @@ package cpolicy
 
 import (
 	"encoding/gob"
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/intelsdi-x/snap/core/ctypes"
 	. "github.com/smartystreets/goconvey/convey"
@@ -133,3 +135,60 @@ func TestConfigPolicy(t *testing.T) {
 
 	})
 }
+
+func TestConfigPolicyJSON(t *testing.T) {
+	Convey("ConfigPolicy with enum, regex, duration and list rules and constraints", t, func() {
+		cp := New()
+		cpn := NewPolicyNode()
+		r1, _ := NewEnumRule("mode", true, []string{"fast", "slow"}, "slow")
+		r2, _ := NewRegexRule("host", false, "^[a-z]+$", "node")
+		r3, _ := NewDurationRule("timeout", false, 5*time.Second)
+		r3.SetMinimum(time.Second)
+		def := ctypes.ConfigValueList{Value: []ctypes.ConfigValue{ctypes.ConfigValueInt{Value: 80}}}
+		r4, _ := NewListRule("ports", false, "integer", def)
+		cpn.Add(r1, r2, r3, r4)
+		cpn.AddConstraint(RequiredWith("host", "mode"))
+		ns := []string{"one", "two"}
+		cp.Add(ns, cpn)
+
+		Convey("marshals and unmarshals JSON", func() {
+			b, err := json.Marshal(cp)
+			So(err, ShouldBeNil)
+			cp2 := &ConfigPolicy{}
+			So(json.Unmarshal(b, cp2), ShouldBeNil)
+			n := cp2.Get(ns)
+			So(n, ShouldNotBeNil)
+
+			mode := n.rules["mode"].(*EnumRule)
+			So(mode.Required(), ShouldBeTrue)
+			So(mode.Choices(), ShouldResemble, []string{"fast", "slow"})
+			So(mode.Default().(*ctypes.ConfigValueStr).Value, ShouldEqual, "slow")
+
+			host := n.rules["host"].(*RegexRule)
+			So(host.Pattern(), ShouldEqual, "^[a-z]+$")
+			So(host.Default().(*ctypes.ConfigValueStr).Value, ShouldEqual, "node")
+
+			timeout := n.rules["timeout"].(*DurationRule)
+			So(timeout.Default().(*ctypes.ConfigValueDuration).Value, ShouldEqual, 5*time.Second)
+			So(timeout.Minimum().(*ctypes.ConfigValueDuration).Value, ShouldEqual, time.Second)
+			So(timeout.Maximum(), ShouldBeNil)
+
+			ports := n.rules["ports"].(*ListRule)
+			So(ports.ElementType(), ShouldEqual, "integer")
+			So(*ports.Default().(*ctypes.ConfigValueList), ShouldResemble, def)
+			So(n.Constraints(), ShouldResemble, []*Constraint{RequiredWith("host", "mode")})
+		})
+
+		Convey("encodes and decodes constraints as a GOB", func() {
+			gob.Register(&EnumRule{})
+			gob.Register(&RegexRule{})
+			gob.Register(&DurationRule{})
+			gob.Register(&ListRule{})
+			b, err := cpn.GobEncode()
+			So(err, ShouldBeNil)
+			n := NewPolicyNode()
+			So(n.GobDecode(b), ShouldBeNil)
+			So(n.Constraints(), ShouldResemble, []*Constraint{RequiredWith("host", "mode")})
+		})
+	})
+}
@@ -0,0 +1,175 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+const (
+	// Encodings snapd produces for publishers which do not decode snap
+	// content themselves. They are never returned by processors.
+
+	// JSONLinesContentType one metric serialized into json per line
+	JSONLinesContentType = "json.lines"
+	// JSONEnvelopeContentType metrics serialized into json, in an envelope
+	// naming the version of their schema
+	JSONEnvelopeContentType = "json.envelope"
+	// InfluxLineContentType metrics in the InfluxDB line protocol
+	InfluxLineContentType = "influx.line"
+	// OTLPJSONContentType metrics as an OpenTelemetry OTLP/JSON export
+	// request
+	OTLPJSONContentType = "otlp.json"
+	// OTLPProtoContentType metrics as an OpenTelemetry export request in
+	// the protobuf encoding of OTLP/HTTP and OTLP/gRPC
+	OTLPProtoContentType = "otlp.proto"
+)
+
+// IsEncodedContentType returns whether the content type is one of the
+// encodings snapd produces for publishers, which it does not decode
+func IsEncodedContentType(contentType string) bool {
+	return core.CanEncode(contentType) && !core.CanDecode(contentType)
+}
+
+// EncodePluginMetricTypes encodes the metrics in one of the encodings snapd
+// produces for publishers
+func EncodePluginMetricTypes(contentType string, metrics []PluginMetricType) ([]byte, error) {
+	if !IsEncodedContentType(contentType) {
+		return nil, fmt.Errorf("invalid encoded content type: %s", contentType)
+	}
+	return core.EncodeMetrics(contentType, toCoreMetrics(metrics))
+}
+
+func encodeJSONLines(metrics []PluginMetricType) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, m := range metrics {
+		// the encoder ends each value with a newline
+		if err := enc.Encode(m); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// MetricEnvelope holds a batch of metrics along with the schema and the
+// version of the schema they follow
+type MetricEnvelope struct {
+	Schema        string             `json:"schema"`
+	SchemaVersion int                `json:"schema_version"`
+	Metrics       []PluginMetricType `json:"metrics"`
+}
+
+func encodeJSONEnvelope(metrics []PluginMetricType) ([]byte, error) {
+	if metrics == nil {
+		metrics = []PluginMetricType{}
+	}
+	return json.Marshal(MetricEnvelope{
+		Schema:        MetricSchemaName,
+		SchemaVersion: MetricSchemaVersion,
+		Metrics:       metrics,
+	})
+}
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxTagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	influxStringEscaper      = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+)
+
+// encodeInfluxLines writes a line per metric, measured by its namespace and
+// tagged with its tags and source, the data being the "value" field. Data
+// which is neither a number, a boolean nor a string is written as JSON.
+func encodeInfluxLines(metrics []PluginMetricType) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, m := range metrics {
+		field, err := influxField(m.Data_)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", strings.Join(m.Namespace_, "/"), err)
+		}
+		buf.WriteString(influxMeasurementEscaper.Replace(strings.Join(m.Namespace_, "/")))
+		tags := metricTags(m)
+		keys := make([]string, 0, len(tags))
+		for k := range tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if tags[k] == "" {
+				continue
+			}
+			buf.WriteByte(',')
+			buf.WriteString(influxTagEscaper.Replace(k))
+			buf.WriteByte('=')
+			buf.WriteString(influxTagEscaper.Replace(tags[k]))
+		}
+		buf.WriteString(" value=")
+		buf.WriteString(field)
+		if !m.Timestamp_.IsZero() {
+			buf.WriteByte(' ')
+			buf.WriteString(strconv.FormatInt(m.Timestamp_.UnixNano(), 10))
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func influxField(data interface{}) (string, error) {
+	switch v := data.(type) {
+	case bool:
+		return strconv.FormatBool(v), nil
+	case string:
+		return `"` + influxStringEscaper.Replace(v) + `"`, nil
+	}
+	if i, f, isInt, ok := core.NumberValue(data); ok {
+		if isInt {
+			return strconv.FormatInt(i, 10) + "i", nil
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	}
+	switch v := data.(type) {
+	case float32, float64:
+		return "", fmt.Errorf("%v cannot be written in the line protocol", v)
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return `"` + influxStringEscaper.Replace(string(b)) + `"`, nil
+}
+
+// metricTags returns the tags of the metric along with its source
+func metricTags(m PluginMetricType) map[string]string {
+	tags := make(map[string]string, len(m.Tags_)+1)
+	for k, v := range m.Tags_ {
+		tags[k] = v
+	}
+	if m.Source_ != "" {
+		tags["source"] = m.Source_
+	}
+	return tags
+}
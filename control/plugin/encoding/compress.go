@@ -0,0 +1,117 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/golang/snappy"
+)
+
+// The compressions of the messages exchanged with the plugins
+const (
+	NoCompression     = "none"
+	GzipCompression   = "gzip"
+	SnappyCompression = "snappy"
+)
+
+// ErrDecompressedTooLarge is returned for the messages decompressing to more
+// than the max message size
+var ErrDecompressedTooLarge = errors.New("decompressed message exceeds the max message size")
+
+// Compressions are the names of the supported compressions
+var Compressions = []string{NoCompression, GzipCompression, SnappyCompression}
+
+// ValidCompression returns true if the compression is supported, no
+// compression being given by "none" or an empty name
+func ValidCompression(name string) bool {
+	if name == "" {
+		return true
+	}
+	for _, c := range Compressions {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+func compress(name string, in []byte) ([]byte, error) {
+	switch name {
+	case "", NoCompression:
+		return in, nil
+	case GzipCompression:
+		buf := &bytes.Buffer{}
+		w := gzip.NewWriter(buf)
+		if _, err := w.Write(in); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case SnappyCompression:
+		return snappy.Encode(nil, in), nil
+	}
+	return nil, fmt.Errorf("unknown compression %s", name)
+}
+
+// decompress decompresses a message, failing with ErrDecompressedTooLarge
+// once it is larger than max, never when max is 0
+func decompress(name string, in []byte, max int) ([]byte, error) {
+	switch name {
+	case "", NoCompression:
+		return in, nil
+	case GzipCompression:
+		r, err := gzip.NewReader(bytes.NewReader(in))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		if max <= 0 {
+			return ioutil.ReadAll(r)
+		}
+		out, err := ioutil.ReadAll(io.LimitReader(r, int64(max)+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(out) > max {
+			return nil, fmt.Errorf("%v: the max is %d bytes", ErrDecompressedTooLarge, max)
+		}
+		return out, nil
+	case SnappyCompression:
+		if max > 0 {
+			n, err := snappy.DecodedLen(in)
+			if err != nil {
+				return nil, err
+			}
+			if n > max {
+				return nil, fmt.Errorf("%v: %d bytes, the max is %d", ErrDecompressedTooLarge, n, max)
+			}
+		}
+		return snappy.Decode(nil, in)
+	}
+	return nil, fmt.Errorf("unknown compression %s", name)
+}
@@ -0,0 +1,67 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type compressed struct {
+	Name   string
+	Values []int
+}
+
+func TestCompression(t *testing.T) {
+	in := compressed{Name: "intel/mock/foo", Values: make([]int, 1000)}
+	Convey("Given the encoders", t, func() {
+		for name, enc := range map[string]Encoder{"gob": NewGobEncoder(), "json": NewJsonEncoder()} {
+			plain, err := enc.Encode(in)
+			So(err, ShouldBeNil)
+			for _, c := range []string{GzipCompression, SnappyCompression} {
+				Convey(name+" messages compressed with "+c+" are smaller and decoded", func() {
+					So(enc.SetCompression(c), ShouldBeNil)
+					b, err := enc.Encode(in)
+					So(err, ShouldBeNil)
+					So(len(b), ShouldBeLessThan, len(plain))
+					var out compressed
+					So(enc.Decode(b, &out), ShouldBeNil)
+					So(out, ShouldResemble, in)
+				})
+				Convey(name+" messages decompressing to more than the max message size are refused with "+c, func() {
+					So(enc.SetCompression(c), ShouldBeNil)
+					b, err := enc.Encode(in)
+					So(err, ShouldBeNil)
+					enc.SetMaxMessageSize(len(plain) - 1)
+					var out compressed
+					err = enc.Decode(b, &out)
+					So(err, ShouldNotBeNil)
+					So(err.Error(), ShouldContainSubstring, ErrDecompressedTooLarge.Error())
+					enc.SetMaxMessageSize(len(plain))
+					So(enc.Decode(b, &out), ShouldBeNil)
+				})
+			}
+			Convey(name+" refuses an unknown compression", func() {
+				So(enc.SetCompression("lz4"), ShouldNotBeNil)
+			})
+		}
+	})
+}
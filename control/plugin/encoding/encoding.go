@@ -25,4 +25,10 @@ type Encoder interface {
 	Encode(interface{}) ([]byte, error)
 	Decode([]byte, interface{}) error
 	SetEncrypter(*encrypter.Encrypter)
+	// SetCompression sets the compression of the encoded messages, applied
+	// before they are encrypted
+	SetCompression(string) error
+	// SetMaxMessageSize limits the size of the messages decompressed, none
+	// when 0
+	SetMaxMessageSize(int)
 }
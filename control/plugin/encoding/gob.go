@@ -22,12 +22,15 @@ package encoding
 import (
 	"bytes"
 	"encoding/gob"
+	"fmt"
 
 	"github.com/intelsdi-x/snap/control/plugin/encrypter"
 )
 
 type gobEncoder struct {
 	e *encrypter.Encrypter
+	c string
+	m int
 }
 
 func NewGobEncoder() *gobEncoder {
@@ -38,6 +41,18 @@ func (g *gobEncoder) SetEncrypter(e *encrypter.Encrypter) {
 	g.e = e
 }
 
+func (g *gobEncoder) SetCompression(c string) error {
+	if !ValidCompression(c) {
+		return fmt.Errorf("unknown compression %s", c)
+	}
+	g.c = c
+	return nil
+}
+
+func (g *gobEncoder) SetMaxMessageSize(size int) {
+	g.m = size
+}
+
 func (g *gobEncoder) Encode(in interface{}) ([]byte, error) {
 	buff := &bytes.Buffer{}
 	enc := gob.NewEncoder(buff)
@@ -46,11 +61,16 @@ func (g *gobEncoder) Encode(in interface{}) ([]byte, error) {
 		return nil, err
 	}
 
+	out, err := compress(g.c, buff.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
 	if g.e != nil {
-		return g.e.Encrypt(buff)
+		return g.e.Encrypt(bytes.NewReader(out))
 	}
 
-	return buff.Bytes(), err
+	return out, nil
 }
 
 func (g *gobEncoder) Decode(in []byte, out interface{}) error {
@@ -61,6 +81,10 @@ func (g *gobEncoder) Decode(in []byte, out interface{}) error {
 			return err
 		}
 	}
+	in, err = decompress(g.c, in, g.m)
+	if err != nil {
+		return err
+	}
 	dec := gob.NewDecoder(bytes.NewReader(in))
 	err = dec.Decode(out)
 	if err != nil {
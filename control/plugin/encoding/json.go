@@ -22,12 +22,15 @@ package encoding
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 
 	"github.com/intelsdi-x/snap/control/plugin/encrypter"
 )
 
 type jsonEncoder struct {
 	e *encrypter.Encrypter
+	c string
+	m int
 }
 
 func NewJsonEncoder() *jsonEncoder {
@@ -38,11 +41,27 @@ func (j *jsonEncoder) SetEncrypter(e *encrypter.Encrypter) {
 	j.e = e
 }
 
+func (j *jsonEncoder) SetCompression(c string) error {
+	if !ValidCompression(c) {
+		return fmt.Errorf("unknown compression %s", c)
+	}
+	j.c = c
+	return nil
+}
+
+func (j *jsonEncoder) SetMaxMessageSize(size int) {
+	j.m = size
+}
+
 func (j *jsonEncoder) Encode(in interface{}) ([]byte, error) {
 	out, err := json.Marshal(in)
 	if err != nil {
 		return nil, err
 	}
+	out, err = compress(j.c, out)
+	if err != nil {
+		return nil, err
+	}
 	if j.e != nil {
 		out, err = j.e.Encrypt(bytes.NewReader(out))
 	}
@@ -57,5 +76,9 @@ func (j *jsonEncoder) Decode(in []byte, out interface{}) error {
 			return err
 		}
 	}
+	in, err = decompress(j.c, in, j.m)
+	if err != nil {
+		return err
+	}
 	return json.Unmarshal(in, out)
 }
@@ -0,0 +1,187 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEncodePluginMetricTypes(t *testing.T) {
+	ts := time.Unix(1476526000, 0)
+	metrics := []PluginMetricType{
+		{
+			Namespace_: []string{"intel", "psutil", "load", "load1"},
+			Source_:    "host 1",
+			Tags_:      map[string]string{"rack": "r,1"},
+			Timestamp_: ts,
+			Data_:      0.5,
+		},
+		{
+			Namespace_: []string{"intel", "psutil", "procs"},
+			Source_:    "host 1",
+			Timestamp_: ts,
+			Data_:      int64(42),
+		},
+		{
+			Namespace_: []string{"intel", "mock", "name"},
+			Timestamp_: ts,
+			Data_:      `say "hi"`,
+		},
+	}
+
+	Convey("Given metrics to encode", t, func() {
+		Convey("the content types snapd encodes are known", func() {
+			So(IsEncodedContentType(JSONLinesContentType), ShouldBeTrue)
+			So(IsEncodedContentType(InfluxLineContentType), ShouldBeTrue)
+			So(IsEncodedContentType(OTLPJSONContentType), ShouldBeTrue)
+			So(IsEncodedContentType(SnapGOBContentType), ShouldBeFalse)
+			_, err := EncodePluginMetricTypes(SnapJSONContentType, metrics)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("the codecs of the content types are registered in core", func() {
+			So(core.CanDecode(SnapGOBContentType), ShouldBeTrue)
+			So(core.CanDecode(SnapJSONContentType), ShouldBeTrue)
+			So(core.CanEncode(OTLPProtoContentType), ShouldBeTrue)
+			So(core.CanDecode(OTLPProtoContentType), ShouldBeFalse)
+			b, err := core.EncodeMetrics(SnapJSONContentType, toCoreMetrics(metrics))
+			So(err, ShouldBeNil)
+			decoded, err := core.DecodeMetrics(SnapJSONContentType, b)
+			So(err, ShouldBeNil)
+			So(decoded, ShouldHaveLength, 3)
+			So(decoded[0].Namespace(), ShouldResemble, metrics[0].Namespace_)
+			So(decoded[0].(PluginMetricType).Tags_, ShouldResemble, metrics[0].Tags_)
+		})
+		Convey("JSON lines hold a metric per line", func() {
+			b, err := EncodePluginMetricTypes(JSONLinesContentType, metrics)
+			So(err, ShouldBeNil)
+			lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+			So(len(lines), ShouldEqual, 3)
+			var m PluginMetricType
+			So(json.Unmarshal([]byte(lines[1]), &m), ShouldBeNil)
+			So(m.Namespace_, ShouldResemble, []string{"intel", "psutil", "procs"})
+		})
+		Convey("the JSON envelope names the current version of the metric schema", func() {
+			b, err := EncodePluginMetricTypes(JSONEnvelopeContentType, metrics)
+			So(err, ShouldBeNil)
+			var e MetricEnvelope
+			So(json.Unmarshal(b, &e), ShouldBeNil)
+			So(e.Schema, ShouldEqual, MetricSchemaName)
+			So(e.SchemaVersion, ShouldEqual, MetricSchemaVersion)
+			So(e.Metrics, ShouldHaveLength, 3)
+			So(e.Metrics[0].Namespace_, ShouldResemble, metrics[0].Namespace_)
+			s, err := core.GetSchema(MetricSchemaName, 0)
+			So(err, ShouldBeNil)
+			So(s.Version, ShouldEqual, MetricSchemaVersion)
+			So(s.ContentTypes, ShouldContain, JSONEnvelopeContentType)
+			s, err = core.GetSchema(EnvelopeSchemaName, 0)
+			So(err, ShouldBeNil)
+			So(s.Version, ShouldEqual, EnvelopeSchemaVersion)
+		})
+		Convey("the line protocol escapes the tags and types the values", func() {
+			b, err := EncodePluginMetricTypes(InfluxLineContentType, metrics)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual,
+				`intel/psutil/load/load1,rack=r\,1,source=host\ 1 value=0.5 1476526000000000000`+"\n"+
+					`intel/psutil/procs,source=host\ 1 value=42i 1476526000000000000`+"\n"+
+					`intel/mock/name value="say \"hi\"" 1476526000000000000`+"\n")
+		})
+		Convey("OTLP groups the numeric metrics by source", func() {
+			b, err := EncodePluginMetricTypes(OTLPJSONContentType, metrics)
+			So(err, ShouldBeNil)
+			var req otlpRequest
+			So(json.Unmarshal(b, &req), ShouldBeNil)
+			So(len(req.ResourceMetrics), ShouldEqual, 1)
+			rm := req.ResourceMetrics[0]
+			So(rm.Resource.Attributes[0].Value.StringValue, ShouldEqual, "host 1")
+			mts := rm.ScopeMetrics[0].Metrics
+			So(len(mts), ShouldEqual, 2)
+			So(mts[0].Name, ShouldEqual, "intel.psutil.load.load1")
+			So(*mts[0].Gauge.DataPoints[0].AsDouble, ShouldEqual, 0.5)
+			So(*mts[1].Gauge.DataPoints[0].AsInt, ShouldEqual, "42")
+			So(mts[1].Gauge.DataPoints[0].TimeUnixNano, ShouldEqual, "1476526000000000000")
+		})
+		Convey("OTLP makes attributes of the dynamic elements of the namespace too", func() {
+			dyn := []PluginMetricType{{
+				Namespace_: []string{"intel", "mock", "host1", "baz"},
+				Labels_:    []core.Label{{Index: 2, Name: "host"}},
+				Tags_:      map[string]string{"rack": "r1"},
+				Timestamp_: ts,
+				Data_:      3,
+			}}
+			b, err := EncodePluginMetricTypes(OTLPJSONContentType, dyn)
+			So(err, ShouldBeNil)
+			var req otlpRequest
+			So(json.Unmarshal(b, &req), ShouldBeNil)
+			m := req.ResourceMetrics[0].ScopeMetrics[0].Metrics[0]
+			So(m.Name, ShouldEqual, "intel.mock.host1.baz")
+			So(m.Gauge.DataPoints[0].Attributes, ShouldResemble, []otlpAttribute{
+				{Key: "host", Value: otlpAnyValue{StringValue: "host1"}},
+				{Key: "rack", Value: otlpAnyValue{StringValue: "r1"}},
+			})
+		})
+		Convey("OTLP protobuf holds the same request", func() {
+			b, err := EncodePluginMetricTypes(OTLPProtoContentType, metrics)
+			So(err, ShouldBeNil)
+			// request > resource metrics > scope metrics > metrics
+			rms := protoFields(b)[1]
+			So(len(rms), ShouldEqual, 1)
+			resource := protoFields(protoFields(rms[0])[1][0])
+			attr := protoFields(resource[1][0])
+			So(string(attr[1][0]), ShouldEqual, "host.name")
+			mts := protoFields(protoFields(rms[0])[2][0])[2]
+			So(len(mts), ShouldEqual, 2)
+			So(string(protoFields(mts[0])[1][0]), ShouldEqual, "intel.psutil.load.load1")
+			dp := protoFields(protoFields(mts[1])[5][0])[1][0]
+			// time_unix_nano (3) then as_int (6), both fixed64
+			So(dp[0], ShouldEqual, 3<<3|protoFixed64)
+			So(binary.LittleEndian.Uint64(dp[1:9]), ShouldEqual, uint64(ts.UnixNano()))
+			So(dp[9], ShouldEqual, 6<<3|protoFixed64)
+			So(binary.LittleEndian.Uint64(dp[10:18]), ShouldEqual, 42)
+		})
+	})
+}
+
+// protoFields returns the values of the length-delimited fields of a
+// protobuf message by field number, skipping the fixed64 ones
+func protoFields(b []byte) map[int][][]byte {
+	fields := map[int][][]byte{}
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		b = b[n:]
+		switch key & 7 {
+		case protoFixed64:
+			b = b[8:]
+		case protoBytes:
+			l, n := binary.Uvarint(b)
+			fields[int(key>>3)] = append(fields[int(key>>3)], b[n:n+int(l)])
+			b = b[n+int(l):]
+		default:
+			return fields
+		}
+	}
+	return fields
+}
@@ -21,14 +21,14 @@ package plugin
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"io"
 	"log"
-	"os"
 	"os/exec"
-	"path/filepath"
-	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/Sirupsen/logrus"
@@ -46,12 +46,87 @@ const (
 	pluginResponseBad                   // plugin response received (invalid)
 )
 
+// StderrTailLines is the number of the last lines of STDERR kept in memory
+// for the crash reports of plugins
+const StderrTailLines = 100
+
+// StderrLineLength is the length past which a line of STDERR is kept only
+// by its end, bounding the memory kept for a plugin which never writes a
+// newline
+const StderrLineLength = 4096
+
 // A plugin that is executable as a forked process on *Linux.
 type ExecutablePlugin struct {
-	cmd    *exec.Cmd
-	stdout io.Reader
-	stderr io.Reader
-	args   Arg
+	cmd        *exec.Cmd
+	stdout     io.Reader
+	stderr     io.Reader
+	stderrTail *lineTail
+	args       Arg
+	// output holds the files the output of the plugin is logged to
+	output *Output
+
+	exitMutex *sync.Mutex
+	exit      *ExitStatus
+}
+
+// ExitStatus describes how the process of a plugin exited
+type ExitStatus struct {
+	// Code is the exit code, -1 when the process was killed by a signal
+	Code int `json:"code"`
+	// Signal is the signal which killed the process, if any
+	Signal string `json:"signal,omitempty"`
+}
+
+// lineTail is a writer keeping the last lines written to it
+type lineTail struct {
+	sync.Mutex
+	max     int
+	lines   []string
+	partial []byte
+}
+
+func newLineTail(max int) *lineTail {
+	return &lineTail{max: max}
+}
+
+func (t *lineTail) Write(b []byte) (int, error) {
+	t.Lock()
+	defer t.Unlock()
+	t.partial = append(t.partial, b...)
+	for {
+		i := bytes.IndexByte(t.partial, '\n')
+		if i < 0 {
+			break
+		}
+		t.lines = append(t.lines, string(lineEnd(bytes.TrimSuffix(t.partial[:i], []byte("\r")))))
+		t.partial = t.partial[i+1:]
+	}
+	if len(t.partial) > StderrLineLength {
+		t.partial = append([]byte(nil), lineEnd(t.partial)...)
+	}
+	if len(t.lines) > t.max {
+		t.lines = append([]string(nil), t.lines[len(t.lines)-t.max:]...)
+	}
+	return len(b), nil
+}
+
+// lineEnd returns the last StderrLineLength bytes of the line
+func lineEnd(b []byte) []byte {
+	if len(b) > StderrLineLength {
+		return b[len(b)-StderrLineLength:]
+	}
+	return b
+}
+
+// Lines returns the last lines written, including an unterminated one
+func (t *lineTail) Lines() []string {
+	t.Lock()
+	defer t.Unlock()
+	lines := append([]string(nil), t.lines...)
+	if len(t.partial) > 0 {
+		lines = append(lines, string(t.partial))
+	}
+	return lines
 }
 
 // A interface representing an executable plugin.
@@ -93,7 +168,41 @@ func (e *ExecutablePlugin) Kill() error {
 
 // Waits for plugin to halt. If error is returned then plugin stopped with error. If not plugin stopped safely.
 func (e *ExecutablePlugin) WaitForExit() error {
-	return e.cmd.Wait()
+	err := e.cmd.Wait()
+	if ps := e.cmd.ProcessState; ps != nil {
+		es := &ExitStatus{Code: -1}
+		if ws, ok := ps.Sys().(syscall.WaitStatus); ok {
+			if ws.Signaled() {
+				es.Signal = ws.Signal().String()
+			} else {
+				es.Code = ws.ExitStatus()
+			}
+		}
+		e.exitMutex.Lock()
+		e.exit = es
+		e.exitMutex.Unlock()
+	}
+	return err
+}
+
+// Pid returns the process ID of the plugin, 0 when it is not started
+func (e *ExecutablePlugin) Pid() int {
+	if e.cmd.Process == nil {
+		return 0
+	}
+	return e.cmd.Process.Pid
+}
+
+// ExitStatus returns how the plugin exited, nil while it runs
+func (e *ExecutablePlugin) ExitStatus() *ExitStatus {
+	e.exitMutex.Lock()
+	defer e.exitMutex.Unlock()
+	return e.exit
+}
+
+// StderrTail returns the last lines the plugin wrote to STDERR
+func (e *ExecutablePlugin) StderrTail() []string {
+	return e.stderrTail.Lines()
 }
 
 // The STDOUT pipe for the plugin as io.Reader. Use to read from plugin process STDOUT.
@@ -130,19 +239,27 @@ func NewExecutablePlugin(a Arg, path string) (*ExecutablePlugin, error) {
 	ePlugin.cmd = cmd
 	ePlugin.stdout = stdout
 	ePlugin.args = a
-	ePlugin.stderr = stderr
+	ePlugin.exitMutex = &sync.Mutex{}
+	ePlugin.stderrTail = newLineTail(StderrTailLines)
+	ePlugin.stderr = io.TeeReader(stderr, ePlugin.stderrTail)
 
 	return ePlugin, nil
 }
 
+// SetOutput sets the output the plugin logs what it writes to STDOUT and
+// STDERR to, only logged by snapd when there is none
+func (e *ExecutablePlugin) SetOutput(o *Output) {
+	e.output = o
+}
+
 // Waits for a plugin response from a started plugin
 func (e *ExecutablePlugin) WaitForResponse(timeout time.Duration) (*Response, error) {
-	r, err := waitHandling(e, timeout, e.args.PluginLogPath)
+	r, err := waitHandling(e, timeout, e.output, e.args.PluginLogPath)
 	return r, err
 }
 
 // Private method which handles behavior for wait for response for daemon and non-daemon modes.
-func waitHandling(p pluginExecutor, timeout time.Duration, logpath string) (*Response, error) {
+func waitHandling(p pluginExecutor, timeout time.Duration, output *Output, logpath string) (*Response, error) {
 	log := execLogger.WithField("_block", "waitHandling")
 
 	/*
@@ -183,12 +300,21 @@ func waitHandling(p pluginExecutor, timeout time.Duration, logpath string) (*Res
 	log.Debug("timeout chan start")
 	go waitForPluginTimeout(timeout, p, waitChannel)
 
+	// the output of the plugin is logged with its process ID, as the
+	// processes of a plugin share its output files
+	var pid int
+	if pp, ok := p.(interface {
+		Pid() int
+	}); ok {
+		pid = pp.Pid()
+	}
+
 	// send response received signal to our channel on response
 	log.Debug("response chan start")
-	go waitForResponseFromPlugin(p.ResponseReader(), waitChannel, logpath)
+	go waitForResponseFromPlugin(p.ResponseReader(), waitChannel, output.newStream(logpath, "stdout", pid))
 
 	// log stderr from the plugin
-	go logStdErr(p.ErrorResponseReader(), logpath)
+	go logStdErr(p.ErrorResponseReader(), output.newStream(logpath, "stderr", pid))
 
 	// send killed plugin signal to our channel on kill
 	log.Debug("kill chan start")
@@ -273,11 +399,7 @@ func waitForPluginTimeout(timeout time.Duration, p pluginExecutor, waitChannel c
 	waitChannel <- waitSignalValue{Signal: pluginTimeout}
 }
 
-func waitForResponseFromPlugin(r io.Reader, waitChannel chan waitSignalValue, logpath string) {
-	lp := strings.TrimSuffix(logpath, filepath.Ext(logpath))
-	lf, _ := os.OpenFile(lp+".stdout", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
-	defer lf.Close()
-	logger := log.New(lf, "", log.Ldate|log.Ltime)
+func waitForResponseFromPlugin(r io.Reader, waitChannel chan waitSignalValue, out *outputStream) {
 	processedResponse := false
 	scanner := bufio.NewScanner(r)
 	resp := new(Response)
@@ -302,36 +424,32 @@ OK:
 			waitChannel <- waitSignalValue{Signal: pluginResponseOk, Response: resp}
 			processedResponse = true
 		} else {
-			logger.Println(scanner.Text())
+			out.Println(scanner.Text())
 		}
 	}
 	if err := scanner.Err(); err != nil {
 		if err == bufio.ErrTooLong {
 			reader := bufio.NewReader(r)
-			logger.Println(reader.ReadLine())
+			out.Println(reader.ReadLine())
 			goto OK
 		}
-		logger.Println(err)
+		out.Println(err)
 	}
 }
 
-func logStdErr(r io.Reader, logpath string) {
-	lp := strings.TrimSuffix(logpath, filepath.Ext(logpath))
-	lf, _ := os.OpenFile(lp+".stderr", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
-	defer lf.Close()
-	logger := log.New(lf, "", log.Ldate|log.Ltime)
+func logStdErr(r io.Reader, out *outputStream) {
 	scanner := bufio.NewScanner(r)
 OK:
 	for scanner.Scan() {
-		logger.Println(scanner.Text())
+		out.Println(scanner.Text())
 	}
 	if err := scanner.Err(); err != nil {
 		if err == bufio.ErrTooLong {
 			reader := bufio.NewReader(r)
-			logger.Println(reader.ReadLine())
+			out.Println(reader.ReadLine())
 			goto OK
 		}
-		logger.Println(err)
+		out.Println(err)
 	}
 }
 
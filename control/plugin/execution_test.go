@@ -24,8 +24,10 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"io/ioutil"
 	"os"
 	"path"
+	"strings"
 	"time"
 
 	"testing"
@@ -109,6 +111,43 @@ func TestNewExecutablePlugin(t *testing.T) {
 
 }
 
+func TestExecutablePluginForensics(t *testing.T) {
+	Convey("the last lines of STDERR are kept", t, func() {
+		lt := newLineTail(2)
+		lt.Write([]byte("one\ntwo\nth"))
+		lt.Write([]byte("ree\r\nfour"))
+		So(lt.Lines(), ShouldResemble, []string{"two", "three", "four"})
+		Convey("and a line too long is kept by its end", func() {
+			long := strings.Repeat("x", StderrLineLength) + "end"
+			lt.Write([]byte(long))
+			So(lt.partial, ShouldHaveLength, StderrLineLength)
+			lt.Write([]byte("\n" + long + "\n"))
+			lines := lt.Lines()
+			So(lines, ShouldHaveLength, 2)
+			So(lines[1], ShouldHaveLength, StderrLineLength)
+			So(lines[1], ShouldEndWith, "end")
+		})
+	})
+	Convey("the exit of a crashed plugin is recorded", t, func() {
+		f, err := ioutil.TempFile("", "snap-crashing-plugin")
+		So(err, ShouldBeNil)
+		defer os.Remove(f.Name())
+		f.WriteString("#!/bin/sh\necho 'panic: boom' >&2\nexit 3\n")
+		f.Close()
+		So(os.Chmod(f.Name(), 0700), ShouldBeNil)
+
+		ex, err := NewExecutablePlugin(new(MockController).GenerateArgs(), f.Name())
+		So(err, ShouldBeNil)
+		So(ex.ExitStatus(), ShouldBeNil)
+		So(ex.Start(), ShouldBeNil)
+		So(ex.Pid(), ShouldBeGreaterThan, 0)
+		ioutil.ReadAll(ex.ErrorResponseReader())
+		So(ex.WaitForExit(), ShouldNotBeNil)
+		So(ex.ExitStatus(), ShouldResemble, &ExitStatus{Code: 3})
+		So(ex.StderrTail(), ShouldResemble, []string{"panic: boom"})
+	})
+}
+
 func TestWaitForPluginResponse(t *testing.T) {
 	Convey(".waitHandling", t, func() {
 
@@ -117,14 +156,14 @@ func TestWaitForPluginResponse(t *testing.T) {
 			mockExecutor.Response = "{}"
 			mockExecutor.WaitTime = time.Millisecond * 1
 			Convey("daemon mode off", func() {
-				resp, err := waitHandling(mockExecutor, time.Second*3, "/tmp/some.log")
+				resp, err := waitHandling(mockExecutor, time.Second*3, nil, "/tmp/some.log")
 
 				So(mockExecutor.Killed, ShouldEqual, false)
 				So(resp, ShouldNotBeNil)
 				So(err, ShouldBeNil)
 			})
 			Convey("daemon mode on", func() {
-				resp, err := waitHandling(mockExecutor, time.Second*3, "/tmp/some.log")
+				resp, err := waitHandling(mockExecutor, time.Second*3, nil, "/tmp/some.log")
 
 				So(mockExecutor.Killed, ShouldEqual, false)
 				So(resp, ShouldNotBeNil)
@@ -138,14 +177,14 @@ func TestWaitForPluginResponse(t *testing.T) {
 			mockExecutor.WaitTime = time.Millisecond * 1000
 
 			Convey("daemon mode off", func() {
-				resp, err := waitHandling(mockExecutor, time.Millisecond*100, "/tmp/some.log")
+				resp, err := waitHandling(mockExecutor, time.Millisecond*100, nil, "/tmp/some.log")
 				So(mockExecutor.Killed, ShouldEqual, true)
 				So(resp, ShouldBeNil)
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldStartWith, "JSONError")
 			})
 			Convey("daemon mode on", func() {
-				resp, err := waitHandling(mockExecutor, time.Millisecond*100, "/tmp/some.log")
+				resp, err := waitHandling(mockExecutor, time.Millisecond*100, nil, "/tmp/some.log")
 				So(mockExecutor.Killed, ShouldEqual, true)
 				So(resp, ShouldBeNil)
 				So(err, ShouldNotBeNil)
@@ -157,7 +196,7 @@ func TestWaitForPluginResponse(t *testing.T) {
 			mockExecutor := new(MockPluginExecutor)
 			mockExecutor.WaitTime = time.Millisecond * 100
 			mockExecutor.WaitError = errors.New("Exit 127")
-			resp, err := waitHandling(mockExecutor, time.Millisecond*500, "/tmp/some.log")
+			resp, err := waitHandling(mockExecutor, time.Millisecond*500, nil, "/tmp/some.log")
 
 			So(mockExecutor.Killed, ShouldEqual, false)
 			So(resp, ShouldBeNil)
@@ -168,7 +207,7 @@ func TestWaitForPluginResponse(t *testing.T) {
 		Convey("called with PluginExecutor that will run longer than timeout without responding", func() {
 			mockExecutor := new(MockPluginExecutor)
 			mockExecutor.WaitTime = time.Second * 120
-			resp, err := waitHandling(mockExecutor, time.Millisecond*100, "/tmp/some.log")
+			resp, err := waitHandling(mockExecutor, time.Millisecond*100, nil, "/tmp/some.log")
 
 			So(mockExecutor.Killed, ShouldEqual, true)
 			So(resp, ShouldBeNil)
@@ -0,0 +1,102 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import "strings"
+
+// Feature is a bitmap of optional capabilities. Plugins advertise the
+// features they implement in their meta and snapd passes the features it
+// supports in the plugin's Arg during the handshake. A feature is only used
+// when both sides support it, so plugins and snapd built before a feature
+// existed keep working unchanged.
+type Feature uint64
+
+const (
+	// FeatureStreaming is support for streaming collection
+	FeatureStreaming Feature = 1 << iota
+	// FeatureCancellation is support for cancelling calls in flight
+	FeatureCancellation
+	// FeatureProtobuf is support for the protobuf content type, snap.pb
+	FeatureProtobuf
+	// FeatureDynamicMetrics is support for metric types changing while
+	// the plugin is loaded
+	FeatureDynamicMetrics
+	// FeatureCatalogUpdates is support for collectors pushing changes of
+	// their metric types to snapd while running
+	FeatureCatalogUpdates
+	// FeatureReadiness is support for collectors reporting when they are
+	// ready to collect
+	FeatureReadiness
+	// FeatureChunking is support for transferring the metric batches larger
+	// than the chunk size in chunks
+	FeatureChunking
+	// FeatureCompression is support for compressing the args and replies
+	// exchanged with snapd
+	FeatureCompression
+)
+
+// SnapdFeatures are the features supported by this version of snapd. Features
+// are added as snapd learns to make use of them: the metric types of
+// collectors supporting dynamic metrics are refreshed while they are running.
+var SnapdFeatures = FeatureProtobuf | FeatureDynamicMetrics | FeatureCatalogUpdates | FeatureReadiness | FeatureChunking | FeatureCompression
+
+var featureNames = []struct {
+	f    Feature
+	name string
+}{
+	{FeatureStreaming, "streaming"},
+	{FeatureCancellation, "cancellation"},
+	{FeatureProtobuf, "protobuf"},
+	{FeatureDynamicMetrics, "dynamic-metrics"},
+	{FeatureCatalogUpdates, "catalog-updates"},
+	{FeatureReadiness, "readiness"},
+	{FeatureChunking, "chunking"},
+	{FeatureCompression, "compression"},
+}
+
+// Has returns true if all the given features are set
+func (f Feature) Has(features Feature) bool {
+	return f&features == features
+}
+
+// Names returns the names of the features set. Unknown bits are ignored.
+func (f Feature) Names() []string {
+	names := []string{}
+	for _, fn := range featureNames {
+		if f.Has(fn.f) {
+			names = append(names, fn.name)
+		}
+	}
+	return names
+}
+
+func (f Feature) String() string {
+	return strings.Join(f.Names(), ",")
+}
+
+// Features is an option that can be be provided to the func NewPluginMeta.
+// It advertises the optional features the plugin implements.
+func Features(features ...Feature) metaOp {
+	return func(m *PluginMeta) {
+		for _, f := range features {
+			m.Features |= f
+		}
+	}
+}
@@ -0,0 +1,55 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFeatures(t *testing.T) {
+	Convey("Features", t, func() {
+		Convey("are added to the meta", func() {
+			m := NewPluginMeta("test", 1, CollectorPluginType, nil, nil,
+				Features(FeatureStreaming, FeatureDynamicMetrics))
+			So(m.Features.Has(FeatureStreaming), ShouldBeTrue)
+			So(m.Features.Has(FeatureDynamicMetrics), ShouldBeTrue)
+			So(m.Features.Has(FeatureCancellation), ShouldBeFalse)
+			So(m.Features.Has(FeatureStreaming|FeatureCancellation), ShouldBeFalse)
+		})
+		Convey("default to none", func() {
+			m := NewPluginMeta("test", 1, CollectorPluginType, nil, nil)
+			So(m.Features, ShouldEqual, Feature(0))
+			So(m.Features.Names(), ShouldBeEmpty)
+		})
+		Convey("have names", func() {
+			f := FeatureCancellation | FeatureProtobuf | Feature(1<<40)
+			So(f.Names(), ShouldResemble, []string{"cancellation", "protobuf"})
+			So(f.String(), ShouldEqual, "cancellation,protobuf")
+		})
+		Convey("supported by snapd are passed to plugins", func() {
+			So(NewArg("/tmp/plugin.log").Features, ShouldEqual, SnapdFeatures)
+		})
+		Convey("supported by snapd include dynamic metrics", func() {
+			So(SnapdFeatures.Has(FeatureDynamicMetrics), ShouldBeTrue)
+		})
+	})
+}
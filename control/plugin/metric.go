@@ -25,6 +25,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
@@ -42,10 +43,18 @@ const (
 	SnapGOBContentType = "snap.gob"
 	// SnapJSON snap metrics serialized into json
 	SnapJSONContentType = "snap.json"
-	// SnapProtoBuff snap metrics serialized into protocol buffers
-	// SnapProtoBuff = "snap.pb" // TO BE IMPLEMENTED
+	// SnapProtobufContentType snap metrics serialized into protocol buffers
+	SnapProtobufContentType = "snap.pb"
+	// SnapMsgpackContentType snap metrics serialized into msgpack
+	SnapMsgpackContentType = "snap.msgpack"
 )
 
+// IsSnapContentType returns whether the content type is one of the snap
+// content types snap.* stands for: those named snap. which snapd decodes
+func IsSnapContentType(contentType string) bool {
+	return contentType != SnapAllContentType && strings.HasPrefix(contentType, "snap.") && core.CanDecode(contentType)
+}
+
 type PluginConfigType struct {
 	*cdata.ConfigDataNode
 }
@@ -110,11 +119,22 @@ type PluginMetricType struct {
 
 	Tags_ map[string]string `json:"tags"`
 
-	// The source of the metric (host, IP, etc).
+	// The source of the metric (host, IP, etc). It is set by snapd to the
+	// hostname of Host_ when the plugin leaves it empty, so plugins only
+	// set it for metrics about another host, e.g. a device polled remotely.
 	Source_ string `json:"source"`
 
+	// The snapd which collected the metric, set by snapd.
+	Host_ core.Host `json:"host"`
+
 	// The timestamp from when the metric was created.
 	Timestamp_ time.Time `json:"timestamp"`
+
+	// The unit the metric is measured in (e.g. bytes, seconds, percent).
+	Unit_ string `json:"unit,omitempty"`
+
+	// A human readable description of the metric.
+	Description_ string `json:"description,omitempty"`
 }
 
 // // PluginMetricType Constructor
@@ -169,6 +189,21 @@ func (p PluginMetricType) Source() string {
 	return p.Source_
 }
 
+// returns the snapd which collected the metric
+func (p PluginMetricType) Host() core.Host {
+	return p.Host_
+}
+
+// returns the unit the metric is measured in
+func (p PluginMetricType) Unit() string {
+	return p.Unit_
+}
+
+// returns the description of the metric
+func (p PluginMetricType) Description() string {
+	return p.Description_
+}
+
 func (p PluginMetricType) Data() interface{} {
 	return p.Data_
 }
@@ -189,36 +224,11 @@ func MarshalPluginMetricTypes(contentType string, metrics []PluginMetricType) ([
 		}).Error("error while marshalling")
 		return nil, "", errors.New(es)
 	}
-	// Switch on content type
-	switch contentType {
-	case SnapAllContentType, SnapGOBContentType:
-		// NOTE: A snap All wildcard will result in GOB
-		var buf bytes.Buffer
-		enc := gob.NewEncoder(&buf)
-		err := enc.Encode(metrics)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"_module": "control-plugin",
-				"block":   "marshal-content-type",
-				"error":   err.Error(),
-			}).Error("error while marshalling")
-			return nil, "", err
-		}
-		// contentType := SnapGOBContentType
-		return buf.Bytes(), SnapGOBContentType, nil
-	case SnapJSONContentType:
-		// Serialize into JSON
-		b, err := json.Marshal(metrics)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"_module": "control-plugin",
-				"block":   "marshal-content-type",
-				"error":   err.Error(),
-			}).Error("error while marshalling")
-			return nil, "", err
-		}
-		return b, SnapJSONContentType, nil
-	default:
+	// NOTE: A snap All wildcard will result in GOB
+	if contentType == SnapAllContentType {
+		contentType = SnapGOBContentType
+	}
+	if !core.CanEncode(contentType) {
 		// We don't recognize this content type. Log and return error.
 		es := fmt.Sprintf("invalid snap content type: %s", contentType)
 		log.WithFields(log.Fields{
@@ -228,37 +238,21 @@ func MarshalPluginMetricTypes(contentType string, metrics []PluginMetricType) ([
 		}).Error("error while marshalling")
 		return nil, "", errors.New(es)
 	}
+	b, err := core.EncodeMetrics(contentType, toCoreMetrics(metrics))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"_module": "control-plugin",
+			"block":   "marshal-content-type",
+			"error":   err.Error(),
+		}).Error("error while marshalling")
+		return nil, "", err
+	}
+	return b, contentType, nil
 }
 
 // UnmarshallPluginMetricTypes takes a content type and []byte payload and returns a []PluginMetricType
 func UnmarshallPluginMetricTypes(contentType string, payload []byte) ([]PluginMetricType, error) {
-	switch contentType {
-	case SnapGOBContentType:
-		var metrics []PluginMetricType
-		r := bytes.NewBuffer(payload)
-		err := gob.NewDecoder(r).Decode(&metrics)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"_module": "control-plugin",
-				"block":   "unmarshal-content-type",
-				"error":   err.Error(),
-			}).Error("error while unmarshalling")
-			return nil, err
-		}
-		return metrics, nil
-	case SnapJSONContentType:
-		var metrics []PluginMetricType
-		err := json.Unmarshal(payload, &metrics)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"_module": "control-plugin",
-				"block":   "unmarshal-content-type",
-				"error":   err.Error(),
-			}).Error("error while unmarshalling")
-			return nil, err
-		}
-		return metrics, nil
-	default:
+	if !core.CanDecode(contentType) {
 		// We don't recognize this content type as one we can unmarshal. Log and return error.
 		es := fmt.Sprintf("invalid snap content type for unmarshalling: %s", contentType)
 		log.WithFields(log.Fields{
@@ -268,6 +262,16 @@ func UnmarshallPluginMetricTypes(contentType string, payload []byte) ([]PluginMe
 		}).Error("error while unmarshalling")
 		return nil, errors.New(es)
 	}
+	metrics, err := core.DecodeMetrics(contentType, payload)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"_module": "control-plugin",
+			"block":   "unmarshal-content-type",
+			"error":   err.Error(),
+		}).Error("error while unmarshalling")
+		return nil, err
+	}
+	return toPluginMetricTypes(metrics)
 }
 
 // SwapPluginMetricContentType swaps a payload with one content type to another one.
@@ -24,6 +24,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/ctypes"
 	. "github.com/smartystreets/goconvey/convey"
@@ -86,6 +87,7 @@ func TestMetric(t *testing.T) {
 			*NewPluginMetricType([]string{"foo", "bar"}, time.Now(), "", nil, nil, 1),
 			*NewPluginMetricType([]string{"foo", "baz"}, time.Now(), "", nil, nil, "2"),
 		}
+		m[0].Host_ = core.Host{Hostname: "snapd-host", AgentID: "id"}
 		a, c, e := MarshalPluginMetricTypes("snap.gob", m)
 		So(e, ShouldBeNil)
 		So(a, ShouldNotBeNil)
@@ -97,6 +99,7 @@ func TestMetric(t *testing.T) {
 			So(e, ShouldBeNil)
 			So(strings.Join(m[0].Namespace(), "/"), ShouldResemble, "foo/bar")
 			So(m[0].Data(), ShouldResemble, 1)
+			So(m[0].Host(), ShouldResemble, core.Host{Hostname: "snapd-host", AgentID: "id"})
 			So(strings.Join(m[1].Namespace(), "/"), ShouldResemble, "foo/baz")
 			So(m[1].Data(), ShouldResemble, "2")
 		})
@@ -114,6 +117,7 @@ func TestMetric(t *testing.T) {
 			*NewPluginMetricType([]string{"foo", "bar"}, time.Now(), "", nil, nil, 1),
 			*NewPluginMetricType([]string{"foo", "baz"}, time.Now(), "", nil, nil, "2"),
 		}
+		m[0].Host_ = core.Host{Hostname: "snapd-host", AgentID: "id"}
 		a, c, e := MarshalPluginMetricTypes("snap.json", m)
 		So(e, ShouldBeNil)
 		So(a, ShouldNotBeNil)
@@ -125,6 +129,7 @@ func TestMetric(t *testing.T) {
 			So(e, ShouldBeNil)
 			So(strings.Join(m[0].Namespace(), "/"), ShouldResemble, "foo/bar")
 			So(m[0].Data(), ShouldResemble, float64(1))
+			So(m[0].Host(), ShouldResemble, core.Host{Hostname: "snapd-host", AgentID: "id"})
 			So(strings.Join(m[1].Namespace(), "/"), ShouldResemble, "foo/baz")
 			So(m[1].Data(), ShouldResemble, "2")
 		})
@@ -137,6 +142,52 @@ func TestMetric(t *testing.T) {
 		})
 	})
 
+	for _, contentType := range []string{"snap.pb", "snap.msgpack"} {
+		Convey("marshall using "+contentType, t, func() {
+			ts := time.Unix(1476526000, 42)
+			config := cdata.NewNode()
+			config.AddItem("user", ctypes.ConfigValueStr{Value: "snap"})
+			m := []PluginMetricType{
+				*NewPluginMetricType([]string{"foo", "host1", "bar"}, ts, "host1", map[string]string{"rack": "r1"}, []core.Label{{Index: 1, Name: "host"}}, 1),
+				*NewPluginMetricType([]string{"foo", "baz"}, ts, "", nil, nil, map[string]interface{}{"a": "b"}),
+				*NewPluginMetricType([]string{"foo", "qux"}, ts, "", nil, nil, 1.5),
+			}
+			m[0].Host_ = core.Host{Hostname: "snapd-host", AgentID: "id"}
+			m[0].Version_ = 2
+			m[0].Config_ = config
+			m[0].Unit_ = "B"
+			a, c, e := MarshalPluginMetricTypes(contentType, m)
+			So(e, ShouldBeNil)
+			So(len(a), ShouldBeGreaterThan, 0)
+			So(c, ShouldEqual, contentType)
+			So(IsSnapContentType(contentType), ShouldBeTrue)
+
+			Convey("unmarshal "+contentType, func() {
+				m, e = UnmarshallPluginMetricTypes(contentType, a)
+				So(e, ShouldBeNil)
+				So(m, ShouldHaveLength, 3)
+				So(m[0].Namespace(), ShouldResemble, []string{"foo", "host1", "bar"})
+				So(m[0].Data(), ShouldEqual, int64(1))
+				So(m[0].Timestamp().Equal(ts), ShouldBeTrue)
+				So(m[0].LastAdvertisedTime().IsZero(), ShouldBeTrue)
+				So(m[0].Source(), ShouldEqual, "host1")
+				So(m[0].Tags(), ShouldResemble, map[string]string{"rack": "r1"})
+				So(m[0].Labels(), ShouldResemble, []core.Label{{Index: 1, Name: "host"}})
+				So(m[0].Host(), ShouldResemble, core.Host{Hostname: "snapd-host", AgentID: "id"})
+				So(m[0].Version(), ShouldEqual, 2)
+				So(m[0].Config().Table()["user"], ShouldResemble, ctypes.ConfigValueStr{Value: "snap"})
+				So(m[0].Unit(), ShouldEqual, "B")
+				So(m[1].Data(), ShouldResemble, map[string]interface{}{"a": "b"})
+				So(m[2].Data(), ShouldEqual, 1.5)
+			})
+
+			Convey("error on bad corrupt data", func() {
+				_, e = UnmarshallPluginMetricTypes(contentType, []byte{0xff, 1, 0, 1})
+				So(e, ShouldNotBeNil)
+			})
+		})
+	}
+
 	Convey("error on unmarshall using bad content type", t, func() {
 		m := []PluginMetricType{
 			*NewPluginMetricType([]string{"foo", "bar"}, time.Now(), "", nil, nil, 1),
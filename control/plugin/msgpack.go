@@ -0,0 +1,151 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/hashicorp/go-msgpack/codec"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+)
+
+// msgpackMetric is a metric of the snap.msgpack content type, an array of
+// maps keyed by the names of the JSON fields of a metric. The times are unix
+// nanoseconds, 0 when not set, and the config node is in JSON. Integers are
+// decoded as int64 and uint64, floats as float64 and maps as
+// map[string]interface{}.
+type msgpackMetric struct {
+	Namespace          []string          `codec:"namespace"`
+	LastAdvertisedTime int64             `codec:"last_advertised_time"`
+	Version            int               `codec:"version"`
+	Config             []byte            `codec:"config"`
+	Data               interface{}       `codec:"data"`
+	Labels             []msgpackLabel    `codec:"labels"`
+	Tags               map[string]string `codec:"tags"`
+	Source             string            `codec:"source"`
+	Host               msgpackHost       `codec:"host"`
+	Timestamp          int64             `codec:"timestamp"`
+	Unit               string            `codec:"unit"`
+	Description        string            `codec:"description"`
+}
+
+type msgpackLabel struct {
+	Index int    `codec:"index"`
+	Name  string `codec:"name"`
+}
+
+type msgpackHost struct {
+	Hostname string `codec:"hostname"`
+	IP       string `codec:"ip"`
+	AgentID  string `codec:"agent_id"`
+	Member   string `codec:"member"`
+}
+
+func msgpackHandle() *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{RawToString: true}
+	h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	return h
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+func encodeMsgpack(metrics []PluginMetricType) ([]byte, error) {
+	mms := make([]msgpackMetric, len(metrics))
+	for i, m := range metrics {
+		mm := msgpackMetric{
+			Namespace:          m.Namespace_,
+			LastAdvertisedTime: unixNano(m.LastAdvertisedTime_),
+			Version:            m.Version_,
+			Data:               m.Data_,
+			Tags:               m.Tags_,
+			Source:             m.Source_,
+			Host:               msgpackHost(m.Host_),
+			Timestamp:          unixNano(m.Timestamp_),
+			Unit:               m.Unit_,
+			Description:        m.Description_,
+		}
+		if m.Config_ != nil {
+			config, err := json.Marshal(m.Config_)
+			if err != nil {
+				return nil, err
+			}
+			mm.Config = config
+		}
+		for _, l := range m.Labels_ {
+			mm.Labels = append(mm.Labels, msgpackLabel(l))
+		}
+		mms[i] = mm
+	}
+	var buf bytes.Buffer
+	if err := codec.NewEncoder(&buf, msgpackHandle()).Encode(mms); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeMsgpack(content []byte) ([]PluginMetricType, error) {
+	var mms []msgpackMetric
+	if err := codec.NewDecoder(bytes.NewReader(content), msgpackHandle()).Decode(&mms); err != nil {
+		return nil, err
+	}
+	metrics := make([]PluginMetricType, len(mms))
+	for i, mm := range mms {
+		m := PluginMetricType{
+			Namespace_:          mm.Namespace,
+			LastAdvertisedTime_: fromUnixNano(mm.LastAdvertisedTime),
+			Version_:            mm.Version,
+			Data_:               mm.Data,
+			Tags_:               mm.Tags,
+			Source_:             mm.Source,
+			Host_:               core.Host(mm.Host),
+			Timestamp_:          fromUnixNano(mm.Timestamp),
+			Unit_:               mm.Unit,
+			Description_:        mm.Description,
+		}
+		if mm.Config != nil {
+			m.Config_ = cdata.NewNode()
+			if err := m.Config_.UnmarshalJSON(mm.Config); err != nil {
+				return nil, err
+			}
+		}
+		for _, l := range mm.Labels {
+			m.Labels_ = append(m.Labels_, core.Label(l))
+		}
+		metrics[i] = m
+	}
+	return metrics, nil
+}
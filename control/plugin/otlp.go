@@ -0,0 +1,289 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// The subset of an OTLP ExportMetricsServiceRequest snapd produces: a
+// resource per source holding a gauge per metric. The JSON tags are those of
+// the OTLP/JSON encoding, the protobuf field numbers those of the
+// opentelemetry-proto messages.
+type otlpRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpMetric struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Unit        string    `json:"unit,omitempty"`
+	Gauge       otlpGauge `json:"gauge"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpDataPoint struct {
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+	TimeUnixNano string          `json:"timeUnixNano"`
+	AsInt        *string         `json:"asInt,omitempty"`
+	AsDouble     *float64        `json:"asDouble,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+func encodeOTLPJSON(metrics []PluginMetricType) ([]byte, error) {
+	return json.Marshal(newOTLPRequest(metrics))
+}
+
+func encodeOTLPProto(metrics []PluginMetricType) ([]byte, error) {
+	return newOTLPRequest(metrics).marshalProto(), nil
+}
+
+// newOTLPRequest groups the metrics by source into resources, a metric being
+// a gauge named after its namespace with a data point attributed with its
+// tags. The dynamic elements of the namespace are attributes named after
+// their labels as well, so that the metrics of a dynamic namespace can be
+// aggregated on them. Metrics whose data is not a finite number or a boolean
+// have no OTLP representation and are left out.
+func newOTLPRequest(metrics []PluginMetricType) otlpRequest {
+	req := otlpRequest{ResourceMetrics: []otlpResourceMetrics{}}
+	bySource := map[string]int{}
+	for _, m := range metrics {
+		dp, ok := otlpPoint(m.Data_)
+		if !ok {
+			continue
+		}
+		name, attrs := otlpNameAttributes(m)
+		dp.TimeUnixNano = strconv.FormatInt(m.Timestamp_.UnixNano(), 10)
+		dp.Attributes = otlpAttributes(attrs)
+
+		i, ok := bySource[m.Source_]
+		if !ok {
+			rm := otlpResourceMetrics{
+				ScopeMetrics: []otlpScopeMetrics{{Scope: otlpScope{Name: "snap"}}},
+			}
+			if m.Source_ != "" {
+				rm.Resource.Attributes = []otlpAttribute{{Key: "host.name", Value: otlpAnyValue{StringValue: m.Source_}}}
+			}
+			req.ResourceMetrics = append(req.ResourceMetrics, rm)
+			i = len(req.ResourceMetrics) - 1
+			bySource[m.Source_] = i
+		}
+		sm := &req.ResourceMetrics[i].ScopeMetrics[0]
+		sm.Metrics = append(sm.Metrics, otlpMetric{
+			Name:        name,
+			Description: m.Description_,
+			Unit:        m.Unit_,
+			Gauge:       otlpGauge{DataPoints: []otlpDataPoint{dp}},
+		})
+	}
+	return req
+}
+
+// otlpNameAttributes returns the name of the gauge of a metric, its
+// namespace joined with dots, and the attributes of its data point, the
+// dynamic elements of the namespace by label and the tags
+func otlpNameAttributes(m PluginMetricType) (string, map[string]string) {
+	attrs := make(map[string]string, len(m.Labels_)+len(m.Tags_))
+	for _, l := range m.Labels_ {
+		if l.Index >= 0 && l.Index < len(m.Namespace_) {
+			attrs[l.Name] = m.Namespace_[l.Index]
+		}
+	}
+	for k, v := range m.Tags_ {
+		attrs[k] = v
+	}
+	return strings.Join(m.Namespace_, "."), attrs
+}
+
+func otlpPoint(data interface{}) (otlpDataPoint, bool) {
+	var dp otlpDataPoint
+	i, f, isInt, ok := core.NumberValue(data)
+	if !ok {
+		return dp, false
+	}
+	// the integers of OTLP are signed, a larger unsigned integer is a double
+	if isInt {
+		s := strconv.FormatInt(i, 10)
+		dp.AsInt = &s
+	} else {
+		dp.AsDouble = &f
+	}
+	return dp, true
+}
+
+func otlpAttributes(tags map[string]string) []otlpAttribute {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]otlpAttribute, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, otlpAttribute{Key: k, Value: otlpAnyValue{StringValue: tags[k]}})
+	}
+	return attrs
+}
+
+// protobuf wire types
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+)
+
+// protoBuffer writes the fields of a protobuf message
+type protoBuffer struct {
+	bytes.Buffer
+}
+
+func (b *protoBuffer) varint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	b.Write(buf[:binary.PutUvarint(buf[:], v)])
+}
+
+func (b *protoBuffer) key(field, wireType int) {
+	b.varint(uint64(field<<3 | wireType))
+}
+
+func (b *protoBuffer) bytesField(field int, p []byte) {
+	b.key(field, protoBytes)
+	b.varint(uint64(len(p)))
+	b.Write(p)
+}
+
+// stringField writes a string field, left out when empty like proto3 does
+func (b *protoBuffer) stringField(field int, s string) {
+	if s != "" {
+		b.bytesField(field, []byte(s))
+	}
+}
+
+func (b *protoBuffer) fixed64Field(field int, v uint64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	b.key(field, protoFixed64)
+	b.Write(buf[:])
+}
+
+// message writes the message written by f as a field
+func (b *protoBuffer) message(field int, f func(*protoBuffer)) {
+	m := &protoBuffer{}
+	f(m)
+	b.bytesField(field, m.Bytes())
+}
+
+func (r otlpRequest) marshalProto() []byte {
+	b := &protoBuffer{}
+	for _, rm := range r.ResourceMetrics {
+		b.message(1, rm.marshalProto)
+	}
+	return b.Bytes()
+}
+
+func (rm otlpResourceMetrics) marshalProto(b *protoBuffer) {
+	b.message(1, func(b *protoBuffer) {
+		for _, a := range rm.Resource.Attributes {
+			b.message(1, a.marshalProto)
+		}
+	})
+	for _, sm := range rm.ScopeMetrics {
+		b.message(2, sm.marshalProto)
+	}
+}
+
+func (sm otlpScopeMetrics) marshalProto(b *protoBuffer) {
+	b.message(1, func(b *protoBuffer) {
+		b.stringField(1, sm.Scope.Name)
+	})
+	for _, m := range sm.Metrics {
+		b.message(2, m.marshalProto)
+	}
+}
+
+func (m otlpMetric) marshalProto(b *protoBuffer) {
+	b.stringField(1, m.Name)
+	b.stringField(2, m.Description)
+	b.stringField(3, m.Unit)
+	b.message(5, func(b *protoBuffer) {
+		for _, dp := range m.Gauge.DataPoints {
+			b.message(1, dp.marshalProto)
+		}
+	})
+}
+
+func (dp otlpDataPoint) marshalProto(b *protoBuffer) {
+	ts, _ := strconv.ParseUint(dp.TimeUnixNano, 10, 64)
+	b.fixed64Field(3, ts)
+	if dp.AsDouble != nil {
+		b.fixed64Field(4, math.Float64bits(*dp.AsDouble))
+	}
+	if dp.AsInt != nil {
+		i, _ := strconv.ParseInt(*dp.AsInt, 10, 64)
+		b.fixed64Field(6, uint64(i))
+	}
+	for _, a := range dp.Attributes {
+		b.message(7, a.marshalProto)
+	}
+}
+
+func (a otlpAttribute) marshalProto(b *protoBuffer) {
+	b.stringField(1, a.Key)
+	b.message(2, func(b *protoBuffer) {
+		// the value is set even when empty, being one of a oneof
+		b.bytesField(1, []byte(a.Value.StringValue))
+	})
+}
@@ -0,0 +1,232 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/pkg/logbuffer"
+)
+
+const (
+	// DefaultOutputMaxSize is the default size in bytes the output files of
+	// the plugins are rotated at
+	DefaultOutputMaxSize int64 = 10 << 20
+	// DefaultOutputMaxFiles is the default number of rotated output files
+	// kept per plugin and stream
+	DefaultOutputMaxFiles = 5
+)
+
+// Output holds the output files of the plugins, shared by the processes of
+// a plugin so their lines are not mixed up or truncated, and how they are
+// rotated and logged. snapd keeps one for all the plugins it starts.
+type Output struct {
+	mutex sync.Mutex
+	// maxSize is the size in bytes the files are rotated at, never when 0
+	maxSize int64
+	// maxFiles is the number of rotated files kept per plugin and stream
+	maxFiles int
+	// inline also logs the output in snapd's log
+	inline bool
+	files  map[string]*rotatingFile
+}
+
+// NewOutput returns the output of the plugins rotated at maxSize bytes,
+// never when 0, keeping maxFiles rotated files, and also logged in snapd's
+// log when inline
+func NewOutput(maxSize int64, maxFiles int, inline bool) *Output {
+	o := &Output{files: map[string]*rotatingFile{}}
+	o.Configure(maxSize, maxFiles, inline)
+	return o
+}
+
+// Configure sets the size in bytes the files are rotated at, never when 0,
+// how many rotated files are kept and whether the output is also logged in
+// snapd's log
+func (o *Output) Configure(maxSize int64, maxFiles int, inline bool) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.maxSize = maxSize
+	o.maxFiles = maxFiles
+	o.inline = inline
+}
+
+// rotation returns the size the files are rotated at and how many rotated
+// files are kept
+func (o *Output) rotation() (int64, int) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.maxSize, o.maxFiles
+}
+
+// Close closes the output files. A file written to again is opened again.
+func (o *Output) Close() error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	var err error
+	for _, rf := range o.files {
+		if e := rf.close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// openFile returns the file at path, shared by the processes of a plugin
+func (o *Output) openFile(path string) *rotatingFile {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	rf, ok := o.files[path]
+	if !ok {
+		rf = &rotatingFile{path: path, output: o}
+		o.files[path] = rf
+	}
+	return rf
+}
+
+// rotatingFile appends to a file, which is renamed with a .1 suffix, the
+// previous ones shifted, once it grows beyond the max size of the output
+type rotatingFile struct {
+	sync.Mutex
+	path   string
+	output *Output
+	f      *os.File
+	size   int64
+}
+
+func (r *rotatingFile) Write(b []byte) (int, error) {
+	maxSize, maxFiles := r.output.rotation()
+	r.Lock()
+	defer r.Unlock()
+	if r.f != nil && maxSize > 0 && r.size+int64(len(b)) > maxSize {
+		r.rotate(maxFiles)
+	}
+	if r.f == nil {
+		f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+		if err != nil {
+			return 0, err
+		}
+		r.f = f
+		r.size = 0
+		if fi, err := f.Stat(); err == nil {
+			r.size = fi.Size()
+		}
+	}
+	n, err := r.f.Write(b)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate closes the file and shifts it with the previous ones, the file
+// opened on the next write
+func (r *rotatingFile) rotate(maxFiles int) {
+	r.f.Close()
+	r.f = nil
+	if maxFiles < 1 {
+		os.Remove(r.path)
+		return
+	}
+	os.Remove(fmt.Sprintf("%s.%d", r.path, maxFiles))
+	for i := maxFiles - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	os.Rename(r.path, r.path+".1")
+}
+
+func (r *rotatingFile) close() error {
+	r.Lock()
+	defer r.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
+
+// outputStream logs the lines a plugin process writes to STDOUT or STDERR
+type outputStream struct {
+	logger *log.Logger
+	inline bool
+	plugin string
+	stream string
+	pid    int
+}
+
+// newStream returns the stream of the plugin logging to the file next to
+// its log file with the name of the stream as extension. Without an output
+// the lines are only logged by snapd.
+func (o *Output) newStream(logpath, stream string, pid int) *outputStream {
+	lp := strings.TrimSuffix(logpath, filepath.Ext(logpath))
+	prefix := ""
+	if pid > 0 {
+		prefix = fmt.Sprintf("[%d] ", pid)
+	}
+	out := &outputStream{
+		plugin: filepath.Base(lp),
+		stream: stream,
+		pid:    pid,
+	}
+	if o == nil {
+		out.logger = log.New(ioutil.Discard, prefix, 0)
+		return out
+	}
+	out.logger = log.New(o.openFile(lp+"."+stream), prefix, log.Ldate|log.Ltime)
+	o.mutex.Lock()
+	out.inline = o.inline
+	o.mutex.Unlock()
+	return out
+}
+
+// Println logs the line to the output file of the plugin, and to snapd's log
+// or else to its buffer of recent entries, to be tailed
+func (o *outputStream) Println(v ...interface{}) {
+	o.logger.Println(v...)
+	line := strings.TrimSuffix(fmt.Sprintln(v...), "\n")
+	if o.inline {
+		logrus.WithFields(logrus.Fields{
+			"_module": logbuffer.PluginComponent,
+			"plugin":  o.plugin,
+			"stream":  o.stream,
+			"pid":     o.pid,
+		}).Info(line)
+		return
+	}
+	logbuffer.Default.Add(logbuffer.Entry{
+		Time:      time.Now(),
+		Level:     logrus.InfoLevel.String(),
+		Component: logbuffer.PluginComponent + ":" + o.plugin,
+		Message:   line,
+		Fields: map[string]string{
+			"stream": o.stream,
+			"pid":    strconv.Itoa(o.pid),
+		},
+	})
+}
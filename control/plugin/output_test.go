@@ -0,0 +1,81 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPluginOutput(t *testing.T) {
+	Convey("Given the output of a plugin", t, func() {
+		dir, err := ioutil.TempDir("", "snap-plugin-output")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		logpath := filepath.Join(dir, "snap-collector-mock.log")
+		path := filepath.Join(dir, "snap-collector-mock.stderr")
+
+		output := NewOutput(DefaultOutputMaxSize, DefaultOutputMaxFiles, false)
+		defer output.Close()
+
+		Convey("the processes of the plugin append to the same file", func() {
+			output.newStream(logpath, "stderr", 10).Println("first")
+			output.newStream(logpath, "stderr", 11).Println("second")
+			b, err := ioutil.ReadFile(path)
+			So(err, ShouldBeNil)
+			lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+			So(lines, ShouldHaveLength, 2)
+			So(lines[0], ShouldStartWith, "[10] ")
+			So(lines[0], ShouldEndWith, " first")
+			So(lines[1], ShouldStartWith, "[11] ")
+			So(lines[1], ShouldEndWith, " second")
+		})
+		Convey("the file is rotated once it grows too big", func() {
+			output := NewOutput(100, 2, false)
+			defer output.Close()
+
+			out := output.newStream(logpath, "stderr", 0)
+			for i := 0; i < 10; i++ {
+				out.Println(strings.Repeat("x", 30))
+			}
+			for _, p := range []string{path, path + ".1", path + ".2"} {
+				fi, err := os.Stat(p)
+				So(err, ShouldBeNil)
+				So(fi.Size(), ShouldBeLessThanOrEqualTo, 100)
+			}
+			_, err := os.Stat(path + ".3")
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+		Convey("closing the output closes its files", func() {
+			output.newStream(logpath, "stderr", 10).Println("first")
+			So(output.Close(), ShouldBeNil)
+			So(output.files[path].f, ShouldBeNil)
+			output.newStream(logpath, "stderr", 10).Println("second")
+			b, err := ioutil.ReadFile(path)
+			So(err, ShouldBeNil)
+			So(strings.Count(string(b), "\n"), ShouldEqual, 2)
+		})
+	})
+}
@@ -24,6 +24,7 @@ import (
 	"bytes"
 	"crypto/rsa"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io" // Don't use "fmt.Print*"
 	"log"
@@ -84,9 +85,16 @@ type RPCType int
 const (
 	NativeRPC RPCType = iota
 	JSONRPC
+	// PlainJSONRPC is the language-neutral protocol. Params and results are
+	// plain JSON objects instead of encoded byte arrays so plugins written in
+	// any language can implement it. See docs/PLUGIN_PROTOCOL.md.
+	PlainJSONRPC
 )
 
 var (
+	// ErrPlainJSONRPCNotSupported is returned when a Go plugin asks to be served over PlainJSONRPC
+	ErrPlainJSONRPCNotSupported = errors.New("PlainJSONRPC is not supported by Go plugins; use JSONRPC or NativeRPC")
+
 	// Timeout settings
 	// How much time must elapse before a lack of Ping results in a timeout
 	PingTimeoutDurationDefault = time.Millisecond * 1500
@@ -138,6 +146,46 @@ type PluginMeta struct {
 	// RoutingStrategy will override the routing strategy this plugin requires.
 	// The default routing strategy round-robin.
 	RoutingStrategy RoutingStrategyType
+	// Dependencies are other plugins which must be loaded for this plugin
+	// to be loaded or used in a task.
+	Dependencies []PluginDependency
+	// MinSnapdVersion and MaxSnapdVersion bound the versions of snapd
+	// (e.g. "0.13.0") the plugin can be loaded into. Empty means unbounded.
+	MinSnapdVersion string
+	MaxSnapdVersion string
+	// Features are the optional features implemented by the plugin.
+	Features Feature
+	// WarmUp is how long a collector needs after starting before it can
+	// collect. The first collection of a task is deferred until it is over.
+	WarmUp time.Duration
+}
+
+// PluginDependency is a plugin required by another plugin
+type PluginDependency struct {
+	Type PluginType
+	Name string
+	// MinVersion and MaxVersion bound the accepted versions of the
+	// required plugin. Zero means unbounded.
+	MinVersion int
+	MaxVersion int
+}
+
+func (d PluginDependency) String() string {
+	s := fmt.Sprintf("%s:%s", d.Type, d.Name)
+	switch {
+	case d.MinVersion > 0 && d.MaxVersion > 0:
+		s += fmt.Sprintf(" (version %d to %d)", d.MinVersion, d.MaxVersion)
+	case d.MinVersion > 0:
+		s += fmt.Sprintf(" (version %d or later)", d.MinVersion)
+	case d.MaxVersion > 0:
+		s += fmt.Sprintf(" (version %d or earlier)", d.MaxVersion)
+	}
+	return s
+}
+
+// Accepts returns true if version is in the accepted range
+func (d PluginDependency) Accepts(version int) bool {
+	return (d.MinVersion == 0 || version >= d.MinVersion) && (d.MaxVersion == 0 || version <= d.MaxVersion)
 }
 
 type metaOp func(m *PluginMeta)
@@ -177,6 +225,39 @@ func CacheTTL(t time.Duration) metaOp {
 	}
 }
 
+// RequiresPlugin is an option that can be be provided to the func NewPluginMeta.
+// It declares a dependency on another plugin; minVersion and maxVersion may be
+// zero to leave the version range open.
+func RequiresPlugin(pluginType PluginType, name string, minVersion, maxVersion int) metaOp {
+	return func(m *PluginMeta) {
+		m.Dependencies = append(m.Dependencies, PluginDependency{
+			Type:       pluginType,
+			Name:       name,
+			MinVersion: minVersion,
+			MaxVersion: maxVersion,
+		})
+	}
+}
+
+// RequiresSnapd is an option that can be be provided to the func NewPluginMeta.
+// It bounds the versions of snapd the plugin can be loaded into; either may
+// be empty.
+func RequiresSnapd(minVersion, maxVersion string) metaOp {
+	return func(m *PluginMeta) {
+		m.MinSnapdVersion = minVersion
+		m.MaxSnapdVersion = maxVersion
+	}
+}
+
+// WarmUp is an option that can be be provided to the func NewPluginMeta.
+// It declares how long the collector needs after starting before it can
+// collect, e.g. to open connections or scan hardware.
+func WarmUp(d time.Duration) metaOp {
+	return func(m *PluginMeta) {
+		m.WarmUp = d
+	}
+}
+
 // NewPluginMeta constructs and returns a PluginMeta struct
 func NewPluginMeta(name string, version int, pluginType PluginType, acceptContentTypes, returnContentTypes []string, opts ...metaOp) *PluginMeta {
 	// An empty accepted content type default to "snap.*"
@@ -229,6 +310,11 @@ type Arg struct {
 	PingTimeoutDuration time.Duration
 
 	NoDaemon bool
+	// Features are the optional features supported by snapd
+	Features Feature
+	// ChunkSize is the size in bytes of the chunks the replies larger
+	// than it are transferred in, with FeatureChunking
+	ChunkSize int
 	// The listen port
 	listenPort string
 }
@@ -237,6 +323,8 @@ func NewArg(logpath string) Arg {
 	return Arg{
 		PluginLogPath:       logpath,
 		PingTimeoutDuration: PingTimeoutDurationDefault,
+		Features:            SnapdFeatures,
+		ChunkSize:           DefaultChunkSize,
 	}
 }
 
@@ -259,6 +347,10 @@ type Response struct {
 // requestString - plugins arguments (marshaled json of control/plugin Arg struct)
 // returns an error and exitCode (exitCode from SessionState initilization or plugin termination code)
 func Start(m *PluginMeta, c Plugin, requestString string) (error, int) {
+	// The plain protocol is only served by plugins written in other languages.
+	if m.RPCType == PlainJSONRPC {
+		return ErrPlainJSONRPCNotSupported, 2
+	}
 	s, sErr, retCode := NewSessionState(requestString, c, m)
 	if sErr != nil {
 		return sErr, retCode
@@ -268,6 +360,9 @@ func Start(m *PluginMeta, c Plugin, requestString string) (error, int) {
 		r        *Response
 		exitCode int = 0
 	)
+	// the metric batches are chunked and the messages compressed by the
+	// session, whatever the plugin
+	m.Features |= FeatureChunking | FeatureCompression
 
 	switch m.Type {
 	case CollectorPluginType:
@@ -278,6 +373,7 @@ func Start(m *PluginMeta, c Plugin, requestString string) (error, int) {
 		}
 		// Register the proxy under the "Collector" namespace
 		rpc.RegisterName("Collector", proxy)
+		s.chunks.handle("Collector.CollectMetrics", proxy.CollectMetrics)
 
 		r = &Response{
 			Type:  CollectorPluginType,
@@ -304,6 +400,7 @@ func Start(m *PluginMeta, c Plugin, requestString string) (error, int) {
 
 		// Register the proxy under the "Publisher" namespace
 		rpc.RegisterName("Publisher", proxy)
+		s.chunks.handle("Publisher.Publish", proxy.Publish)
 	case ProcessorPluginType:
 		r = &Response{
 			Type:  ProcessorPluginType,
@@ -320,6 +417,7 @@ func Start(m *PluginMeta, c Plugin, requestString string) (error, int) {
 		}
 		// Register the proxy under the "Publisher" namespace
 		rpc.RegisterName("Processor", proxy)
+		s.chunks.handle("Processor.Process", proxy.Process)
 	}
 
 	// Register common plugin methods used for utility reasons
@@ -96,4 +96,8 @@ func TestPlugin(t *testing.T) {
 		mockPluginMeta.CacheTTL = time.Duration(100 * time.Millisecond)
 		So(mockPluginMeta.CacheTTL, ShouldEqual, time.Duration(100*time.Millisecond))
 	})
+	Convey("Plugin WarmUp", t, func() {
+		mockPluginMeta := NewPluginMeta("test", 1, CollectorPluginType, a, b, WarmUp(5*time.Second))
+		So(mockPluginMeta.WarmUp, ShouldEqual, 5*time.Second)
+	})
 }
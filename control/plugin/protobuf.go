@@ -0,0 +1,371 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+)
+
+// The snap.pb content type is a batch of metrics in protobuf, the messages
+// being:
+//
+//	message MetricBatch { repeated Metric metrics = 1; }
+//	message Metric {
+//	  repeated string namespace = 1;
+//	  int64 last_advertised_time = 2; // unix nanoseconds, 0 when not set
+//	  int64 version = 3;
+//	  bytes config = 4;               // the config node in JSON
+//	  Value data = 5;
+//	  repeated Label labels = 6;
+//	  map<string, string> tags = 7;
+//	  string source = 8;
+//	  Host host = 9;
+//	  int64 timestamp = 10;           // unix nanoseconds, 0 when not set
+//	  string unit = 11;
+//	  string description = 12;
+//	}
+//	message Value {
+//	  oneof value {
+//	    int64 int_value = 1;
+//	    uint64 uint_value = 2;
+//	    double double_value = 3;
+//	    string string_value = 4;
+//	    bool bool_value = 5;
+//	    bytes bytes_value = 6;
+//	    bytes json_value = 7;         // any other data in JSON
+//	  }
+//	}
+//	message Label { int64 index = 1; string name = 2; }
+//	message Host { string hostname = 1; string ip = 2; string agent_id = 3; string member = 4; }
+//
+// Integers are decoded as int64 and uint64, floats as float64.
+
+// protobuf wire type of the fixed32 fields, which snap.pb does not use but
+// a decoder skips
+const protoFixed32 = 5
+
+var errProtoMessage = errors.New("malformed protobuf message")
+
+func (b *protoBuffer) varintField(field int, v uint64) {
+	b.key(field, protoVarint)
+	b.varint(v)
+}
+
+// timeField writes a time as unix nanoseconds, left out when zero
+func (b *protoBuffer) timeField(field int, t time.Time) {
+	if !t.IsZero() {
+		b.varintField(field, uint64(t.UnixNano()))
+	}
+}
+
+func encodeProtobuf(metrics []PluginMetricType) ([]byte, error) {
+	b := &protoBuffer{}
+	for _, m := range metrics {
+		mb := &protoBuffer{}
+		if err := marshalProtoMetric(mb, m); err != nil {
+			return nil, err
+		}
+		b.bytesField(1, mb.Bytes())
+	}
+	return b.Bytes(), nil
+}
+
+func marshalProtoMetric(b *protoBuffer, m PluginMetricType) error {
+	for _, e := range m.Namespace_ {
+		b.bytesField(1, []byte(e))
+	}
+	b.timeField(2, m.LastAdvertisedTime_)
+	if m.Version_ != 0 {
+		b.varintField(3, uint64(m.Version_))
+	}
+	if m.Config_ != nil {
+		config, err := json.Marshal(m.Config_)
+		if err != nil {
+			return err
+		}
+		b.bytesField(4, config)
+	}
+	if m.Data_ != nil {
+		value := &protoBuffer{}
+		if err := marshalProtoValue(value, m.Data_); err != nil {
+			return err
+		}
+		b.bytesField(5, value.Bytes())
+	}
+	for _, l := range m.Labels_ {
+		b.message(6, func(b *protoBuffer) {
+			b.varintField(1, uint64(l.Index))
+			b.stringField(2, l.Name)
+		})
+	}
+	for k, v := range m.Tags_ {
+		b.message(7, func(b *protoBuffer) {
+			b.stringField(1, k)
+			b.stringField(2, v)
+		})
+	}
+	b.stringField(8, m.Source_)
+	if m.Host_ != (core.Host{}) {
+		b.message(9, func(b *protoBuffer) {
+			b.stringField(1, m.Host_.Hostname)
+			b.stringField(2, m.Host_.IP)
+			b.stringField(3, m.Host_.AgentID)
+			b.stringField(4, m.Host_.Member)
+		})
+	}
+	b.timeField(10, m.Timestamp_)
+	b.stringField(11, m.Unit_)
+	b.stringField(12, m.Description_)
+	return nil
+}
+
+func marshalProtoValue(b *protoBuffer, data interface{}) error {
+	switch v := data.(type) {
+	case int:
+		b.varintField(1, uint64(v))
+	case int8:
+		b.varintField(1, uint64(v))
+	case int16:
+		b.varintField(1, uint64(v))
+	case int32:
+		b.varintField(1, uint64(v))
+	case int64:
+		b.varintField(1, uint64(v))
+	case uint:
+		b.varintField(2, uint64(v))
+	case uint8:
+		b.varintField(2, uint64(v))
+	case uint16:
+		b.varintField(2, uint64(v))
+	case uint32:
+		b.varintField(2, uint64(v))
+	case uint64:
+		b.varintField(2, v)
+	case float32:
+		b.fixed64Field(3, math.Float64bits(float64(v)))
+	case float64:
+		b.fixed64Field(3, math.Float64bits(v))
+	case string:
+		b.bytesField(4, []byte(v))
+	case bool:
+		var i uint64
+		if v {
+			i = 1
+		}
+		b.varintField(5, i)
+	case []byte:
+		b.bytesField(6, v)
+	default:
+		j, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		b.bytesField(7, j)
+	}
+	return nil
+}
+
+func decodeProtobuf(content []byte) ([]PluginMetricType, error) {
+	metrics := []PluginMetricType{}
+	err := protoDecode(content, func(field, wireType int, _ uint64, p []byte) error {
+		if field != 1 {
+			return nil
+		}
+		if wireType != protoBytes {
+			return errProtoMessage
+		}
+		m, err := unmarshalProtoMetric(p)
+		if err != nil {
+			return err
+		}
+		metrics = append(metrics, m)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// protoWireTypes are the wire types of the fields of a Metric message
+var protoWireTypes = map[int]int{
+	1: protoBytes, 2: protoVarint, 3: protoVarint, 4: protoBytes,
+	5: protoBytes, 6: protoBytes, 7: protoBytes, 8: protoBytes,
+	9: protoBytes, 10: protoVarint, 11: protoBytes, 12: protoBytes,
+}
+
+func unmarshalProtoMetric(b []byte) (PluginMetricType, error) {
+	var m PluginMetricType
+	err := protoDecode(b, func(field, wireType int, v uint64, p []byte) error {
+		wt, ok := protoWireTypes[field]
+		if !ok {
+			return nil
+		}
+		if wt != wireType {
+			return errProtoMessage
+		}
+		switch field {
+		case 1:
+			m.Namespace_ = append(m.Namespace_, string(p))
+		case 2:
+			m.LastAdvertisedTime_ = time.Unix(0, int64(v))
+		case 3:
+			m.Version_ = int(v)
+		case 4:
+			m.Config_ = cdata.NewNode()
+			return m.Config_.UnmarshalJSON(p)
+		case 5:
+			data, err := unmarshalProtoValue(p)
+			m.Data_ = data
+			return err
+		case 6:
+			var l core.Label
+			err := protoDecode(p, func(field, _ int, v uint64, p []byte) error {
+				switch field {
+				case 1:
+					l.Index = int(v)
+				case 2:
+					l.Name = string(p)
+				}
+				return nil
+			})
+			m.Labels_ = append(m.Labels_, l)
+			return err
+		case 7:
+			var k, val string
+			err := protoDecode(p, func(field, _ int, _ uint64, p []byte) error {
+				switch field {
+				case 1:
+					k = string(p)
+				case 2:
+					val = string(p)
+				}
+				return nil
+			})
+			if m.Tags_ == nil {
+				m.Tags_ = map[string]string{}
+			}
+			m.Tags_[k] = val
+			return err
+		case 8:
+			m.Source_ = string(p)
+		case 9:
+			return protoDecode(p, func(field, _ int, _ uint64, p []byte) error {
+				switch field {
+				case 1:
+					m.Host_.Hostname = string(p)
+				case 2:
+					m.Host_.IP = string(p)
+				case 3:
+					m.Host_.AgentID = string(p)
+				case 4:
+					m.Host_.Member = string(p)
+				}
+				return nil
+			})
+		case 10:
+			m.Timestamp_ = time.Unix(0, int64(v))
+		case 11:
+			m.Unit_ = string(p)
+		case 12:
+			m.Description_ = string(p)
+		}
+		return nil
+	})
+	return m, err
+}
+
+func unmarshalProtoValue(b []byte) (interface{}, error) {
+	var data interface{}
+	err := protoDecode(b, func(field, wireType int, v uint64, p []byte) error {
+		switch field {
+		case 1:
+			data = int64(v)
+		case 2:
+			data = v
+		case 3:
+			if wireType != protoFixed64 {
+				return errProtoMessage
+			}
+			data = math.Float64frombits(v)
+		case 4:
+			data = string(p)
+		case 5:
+			data = v != 0
+		case 6:
+			data = append([]byte{}, p...)
+		case 7:
+			return json.Unmarshal(p, &data)
+		}
+		return nil
+	})
+	return data, err
+}
+
+// protoDecode calls f with each field of a protobuf message: the value of a
+// varint or fixed field, the bytes of a length delimited one
+func protoDecode(b []byte, f func(field, wireType int, v uint64, p []byte) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 || key>>3 == 0 {
+			return errProtoMessage
+		}
+		b = b[n:]
+		var v uint64
+		var p []byte
+		wireType := int(key & 7)
+		switch wireType {
+		case protoVarint:
+			if v, n = binary.Uvarint(b); n <= 0 {
+				return errProtoMessage
+			}
+			b = b[n:]
+		case protoFixed64:
+			if len(b) < 8 {
+				return errProtoMessage
+			}
+			v, b = binary.LittleEndian.Uint64(b), b[8:]
+		case protoBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || l > uint64(len(b)-n) {
+				return errProtoMessage
+			}
+			p, b = b[n:n+int(l)], b[n+int(l):]
+		case protoFixed32:
+			if len(b) < 4 {
+				return errProtoMessage
+			}
+			v, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		default:
+			return errProtoMessage
+		}
+		if err := f(int(key>>3), wireType, v, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -19,7 +19,16 @@ limitations under the License.
 
 package plugin
 
-import "github.com/intelsdi-x/snap/core/ctypes"
+import (
+	"errors"
+
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+// ErrSlowDown is returned by a publisher which cannot keep up with the
+// metrics it is sent. The content is not published and the task responds
+// as the back-pressure policy of the publisher's workflow node tells it.
+var ErrSlowDown = errors.New("publisher asked to slow down")
 
 // Publisher plugin
 type PublisherPlugin interface {
@@ -33,6 +33,8 @@ type PublishArgs struct {
 }
 
 type PublishReply struct {
+	// SlowDown is set when the publisher asked to slow down
+	SlowDown bool
 }
 
 type publisherPluginProxy struct {
@@ -50,9 +52,17 @@ func (p *publisherPluginProxy) Publish(args []byte, reply *[]byte) error {
 		return err
 	}
 
+	r := PublishReply{}
 	err = p.Plugin.Publish(dargs.ContentType, dargs.Content, dargs.Config)
-	if err != nil {
+	if err == ErrSlowDown {
+		r.SlowDown = true
+	} else if err != nil {
 		return errors.New(fmt.Sprintf("Publish call error: %v", err.Error()))
 	}
+
+	*reply, err = p.Session.Encode(r)
+	if err != nil {
+		return err
+	}
 	return nil
 }
@@ -20,11 +20,14 @@ limitations under the License.
 package plugin
 
 import (
+	"errors"
 	"log"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/control/plugin/encoding"
 	"github.com/intelsdi-x/snap/core/ctypes"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -119,3 +122,46 @@ func TestStartPublisher(t *testing.T) {
 		})
 	})
 }
+
+type mockSlowPublisher struct {
+	err error
+}
+
+func (p *mockSlowPublisher) Publish(_ string, _ []byte, _ map[string]ctypes.ConfigValue) error {
+	return p.err
+}
+
+func (p *mockSlowPublisher) GetConfigPolicy() (*cpolicy.ConfigPolicy, error) {
+	return cpolicy.New(), nil
+}
+
+func TestPublisherProxy(t *testing.T) {
+	Convey("Publisher proxy", t, func() {
+		session := &MockSessionState{
+			Encoder:             encoding.NewGobEncoder(),
+			listenPort:          "0",
+			token:               "abcdef",
+			logger:              log.New(os.Stdout, "test: ", log.Ldate|log.Ltime|log.Lshortfile),
+			PingTimeoutDuration: time.Millisecond * 100,
+			killChan:            make(chan int),
+		}
+		pub := &mockSlowPublisher{}
+		p := &publisherPluginProxy{Plugin: pub, Session: session}
+		args, err := session.Encode(PublishArgs{ContentType: SnapGOBContentType})
+		So(err, ShouldBeNil)
+
+		Convey("replies the publisher asked to slow down", func() {
+			pub.err = ErrSlowDown
+			var reply []byte
+			So(p.Publish(args, &reply), ShouldBeNil)
+			r := PublishReply{}
+			So(session.Decode(reply, &r), ShouldBeNil)
+			So(r.SlowDown, ShouldBeTrue)
+		})
+		Convey("fails the call on other errors", func() {
+			pub.err = errors.New("disk full")
+			var reply []byte
+			So(p.Publish(args, &reply), ShouldNotBeNil)
+		})
+	})
+}
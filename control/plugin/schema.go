@@ -0,0 +1,119 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"encoding/json"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+const (
+	// MetricSchemaName names the schema of a metric serialized into json
+	MetricSchemaName = "snap.metric"
+	// MetricSchemaVersion is the current version of the metric schema
+	MetricSchemaVersion = 1
+	// EnvelopeSchemaName names the schema of the json.envelope batches
+	EnvelopeSchemaName = "snap.envelope"
+	// EnvelopeSchemaVersion is the current version of the envelope schema
+	EnvelopeSchemaVersion = 1
+)
+
+// metricSchemaV1 describes PluginMetricType serialized into json. Unknown
+// fields are allowed so that the consumers of version 1 keep reading the
+// metrics once optional fields are added.
+const metricSchemaV1 = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "snap.metric/1",
+  "title": "snap metric",
+  "type": "object",
+  "required": ["namespace", "version", "data", "timestamp"],
+  "properties": {
+    "namespace": {"type": "array", "items": {"type": "string"}},
+    "last_advertised_time": {"type": "string", "format": "date-time"},
+    "version": {"type": "integer"},
+    "config": {"type": ["object", "null"]},
+    "data": {},
+    "labels": {
+      "type": ["array", "null"],
+      "items": {
+        "type": "object",
+        "properties": {
+          "index": {"type": "integer"},
+          "name": {"type": "string"}
+        }
+      }
+    },
+    "tags": {"type": ["object", "null"], "additionalProperties": {"type": "string"}},
+    "source": {"type": "string"},
+    "host": {
+      "type": "object",
+      "properties": {
+        "hostname": {"type": "string"},
+        "ip": {"type": "string"},
+        "agent_id": {"type": "string"},
+        "member": {"type": "string"}
+      }
+    },
+    "timestamp": {"type": "string", "format": "date-time"},
+    "unit": {"type": "string"},
+    "description": {"type": "string"}
+  },
+  "additionalProperties": true
+}`
+
+// envelopeSchemaV1 describes MetricEnvelope, its metrics following the
+// version of the metric schema it names
+const envelopeSchemaV1 = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "snap.envelope/1",
+  "title": "snap metric envelope",
+  "type": "object",
+  "required": ["schema", "schema_version", "metrics"],
+  "properties": {
+    "schema": {"type": "string", "const": "snap.metric"},
+    "schema_version": {"type": "integer", "minimum": 1},
+    "metrics": {"type": "array", "items": {"type": "object"}}
+  },
+  "additionalProperties": true
+}`
+
+// The schemas of the json content types are registered in core, where the
+// REST API exposes them
+func init() {
+	for _, s := range []core.Schema{
+		{
+			Name:         MetricSchemaName,
+			Version:      MetricSchemaVersion,
+			ContentTypes: []string{SnapJSONContentType, JSONLinesContentType, JSONEnvelopeContentType},
+			Document:     json.RawMessage(metricSchemaV1),
+		},
+		{
+			Name:         EnvelopeSchemaName,
+			Version:      EnvelopeSchemaVersion,
+			ContentTypes: []string{JSONEnvelopeContentType},
+			Document:     json.RawMessage(envelopeSchemaV1),
+		},
+	} {
+		if err := core.RegisterSchema(s); err != nil {
+			panic(err)
+		}
+	}
+}
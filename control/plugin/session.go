@@ -84,6 +84,7 @@ type SessionState struct {
 	logger        *log.Logger
 	privateKey    *rsa.PrivateKey
 	encoder       encoding.Encoder
+	chunks        *chunkTransfers
 }
 
 type GetConfigPolicyArgs struct{}
@@ -191,6 +192,17 @@ func (s *SessionState) SetKey(args SetKeyArgs, reply *[]byte) error {
 	return nil
 }
 
+type SetCompressionArgs struct {
+	Compression string
+}
+
+// SetCompression sets the compression of the args and replies exchanged
+// with snapd from now on, with FeatureCompression
+func (s *SessionState) SetCompression(args SetCompressionArgs, reply *[]byte) error {
+	s.logger.Println("SetCompression called")
+	return s.Encoder.SetCompression(args.Compression)
+}
+
 func (s *SessionState) generateResponse(r *Response) []byte {
 	// Add common plugin response properties
 	r.ListenAddress = s.listenAddress
@@ -281,6 +293,7 @@ func NewSessionState(pluginArgsMsg string, plugin Plugin, meta *PluginMeta) (*Se
 		token:    rs,
 		killChan: make(chan int),
 		logger:   logger,
+		chunks:   newChunkTransfers(),
 	}
 
 	if !meta.Unsecure {
@@ -301,10 +314,16 @@ func init() {
 	gob.RegisterName("conf_value_int", *(&ctypes.ConfigValueInt{}))
 	gob.RegisterName("conf_value_float", *(&ctypes.ConfigValueFloat{}))
 	gob.RegisterName("conf_value_bool", *(&ctypes.ConfigValueBool{}))
+	gob.RegisterName("conf_value_duration", *(&ctypes.ConfigValueDuration{}))
+	gob.RegisterName("conf_value_list", *(&ctypes.ConfigValueList{}))
 
 	gob.RegisterName("conf_policy_node", cpolicy.NewPolicyNode())
 	gob.RegisterName("conf_data_node", &cdata.ConfigDataNode{})
 	gob.RegisterName("conf_policy_string", &cpolicy.StringRule{})
 	gob.RegisterName("conf_policy_int", &cpolicy.IntRule{})
 	gob.RegisterName("conf_policy_float", &cpolicy.FloatRule{})
+	gob.RegisterName("conf_policy_enum", &cpolicy.EnumRule{})
+	gob.RegisterName("conf_policy_regex", &cpolicy.RegexRule{})
+	gob.RegisterName("conf_policy_duration", &cpolicy.DurationRule{})
+	gob.RegisterName("conf_policy_list", &cpolicy.ListRule{})
 }
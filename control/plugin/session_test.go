@@ -42,6 +42,7 @@ type MockSessionState struct {
 	token               string
 	logger              *log.Logger
 	killChan            chan int
+	heartbeats          int
 }
 
 func (s *MockSessionState) Ping(arg []byte, reply *[]byte) error {
@@ -82,7 +83,7 @@ func (s *MockSessionState) Token() string {
 }
 
 func (m *MockSessionState) ResetHeartbeat() {
-
+	m.heartbeats++
 }
 
 func (s *MockSessionState) KillChan() chan int {
@@ -0,0 +1,354 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+)
+
+// The shim collector runs a script (Python, shell, ...) for every collection.
+// The requested metrics are written to the script's stdin as JSON and the
+// collected metrics are read back from its stdout. Everything else a plugin
+// needs to provide (name, version, metric types and config policy) is read
+// from a static descriptor file.
+
+const (
+	// ShimDescriptorExt is appended to the path of the shim executable to
+	// find its descriptor when one is not given explicitly.
+	ShimDescriptorExt = ".shim.json"
+	// ShimDescriptorEnv is the environment variable which may hold the
+	// path to the descriptor.
+	ShimDescriptorEnv = "SNAP_SHIM_DESCRIPTOR"
+	// DefaultShimTimeout is how long a script may run for a collection.
+	DefaultShimTimeout = 5 * time.Second
+)
+
+var (
+	ErrShimNoName     = errors.New("shim descriptor is missing a name")
+	ErrShimNoCommand  = errors.New("shim descriptor is missing a command")
+	ErrShimNoMetrics  = errors.New("shim descriptor does not declare any metrics")
+	ErrShimTimeout    = errors.New("shim script timed out")
+	ErrShimBadVersion = errors.New("shim descriptor version must be greater than zero")
+)
+
+// make sure that we actually satisfy the required interface
+var _ CollectorPlugin = (*ShimCollector)(nil)
+
+// ShimDescriptor describes a script based collector.
+type ShimDescriptor struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+	// Command is the script and its arguments. A relative script path is
+	// resolved against the directory holding the descriptor.
+	Command []string `json:"command"`
+	// Metrics are the namespaces advertised by the script (/intel/foo/bar).
+	Metrics []string `json:"metrics"`
+	// Policy maps a namespace prefix to the config rules applying below it.
+	Policy map[string][]ShimRule `json:"policy"`
+	// Timeout for a single run of the script ("5s", "500ms").
+	Timeout string `json:"timeout"`
+	// CacheTTL overrides the default cache TTL for the metrics.
+	CacheTTL string `json:"cache_ttl"`
+
+	dir string
+}
+
+// ShimRule is a config policy rule declared in a shim descriptor.
+type ShimRule struct {
+	Key      string      `json:"key"`
+	Type     string      `json:"type"`
+	Required bool        `json:"required"`
+	Default  interface{} `json:"default"`
+	Minimum  *float64    `json:"minimum"`
+	Maximum  *float64    `json:"maximum"`
+}
+
+// ReadShimDescriptor reads and validates the descriptor at path.
+func ReadShimDescriptor(path string) (*ShimDescriptor, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &ShimDescriptor{}
+	if err := json.Unmarshal(b, d); err != nil {
+		return nil, fmt.Errorf("unable to parse shim descriptor %s: %v", path, err)
+	}
+	d.dir = filepath.Dir(path)
+	if err := d.validate(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// FindShimDescriptor returns the descriptor path for the running shim, taken
+// from SNAP_SHIM_DESCRIPTOR or else from the executable path + ".shim.json".
+func FindShimDescriptor() string {
+	if p := os.Getenv(ShimDescriptorEnv); p != "" {
+		return p
+	}
+	return os.Args[0] + ShimDescriptorExt
+}
+
+func (d *ShimDescriptor) validate() error {
+	if d.Name == "" {
+		return ErrShimNoName
+	}
+	if d.Version < 1 {
+		return ErrShimBadVersion
+	}
+	if len(d.Command) == 0 {
+		return ErrShimNoCommand
+	}
+	if len(d.Metrics) == 0 {
+		return ErrShimNoMetrics
+	}
+	if _, err := d.timeout(); err != nil {
+		return err
+	}
+	if _, err := d.cacheTTL(); err != nil {
+		return err
+	}
+	_, err := d.configPolicy()
+	return err
+}
+
+func (d *ShimDescriptor) timeout() (time.Duration, error) {
+	if d.Timeout == "" {
+		return DefaultShimTimeout, nil
+	}
+	return time.ParseDuration(d.Timeout)
+}
+
+func (d *ShimDescriptor) cacheTTL() (time.Duration, error) {
+	if d.CacheTTL == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(d.CacheTTL)
+}
+
+func (d *ShimDescriptor) configPolicy() (*cpolicy.ConfigPolicy, error) {
+	cp := cpolicy.New()
+	for ns, rules := range d.Policy {
+		node := cpolicy.NewPolicyNode()
+		for _, r := range rules {
+			rule, err := r.toRule()
+			if err != nil {
+				return nil, err
+			}
+			node.Add(rule)
+		}
+		cp.Add(splitNamespace(ns), node)
+	}
+	return cp, nil
+}
+
+func (r ShimRule) toRule() (cpolicy.Rule, error) {
+	badDefault := fmt.Errorf("invalid default for %s rule %s: %v", r.Type, r.Key, r.Default)
+	switch r.Type {
+	case "string":
+		if r.Default == nil {
+			return cpolicy.NewStringRule(r.Key, r.Required)
+		}
+		def, ok := r.Default.(string)
+		if !ok {
+			return nil, badDefault
+		}
+		return cpolicy.NewStringRule(r.Key, r.Required, def)
+	case "integer":
+		var rule *cpolicy.IntRule
+		var err error
+		if r.Default == nil {
+			rule, err = cpolicy.NewIntegerRule(r.Key, r.Required)
+		} else {
+			def, ok := r.Default.(float64)
+			if !ok {
+				return nil, badDefault
+			}
+			rule, err = cpolicy.NewIntegerRule(r.Key, r.Required, int(def))
+		}
+		if err != nil {
+			return nil, err
+		}
+		if r.Minimum != nil {
+			rule.SetMinimum(int(*r.Minimum))
+		}
+		if r.Maximum != nil {
+			rule.SetMaximum(int(*r.Maximum))
+		}
+		return rule, nil
+	case "float":
+		var rule *cpolicy.FloatRule
+		var err error
+		if r.Default == nil {
+			rule, err = cpolicy.NewFloatRule(r.Key, r.Required)
+		} else {
+			def, ok := r.Default.(float64)
+			if !ok {
+				return nil, badDefault
+			}
+			rule, err = cpolicy.NewFloatRule(r.Key, r.Required, def)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if r.Minimum != nil {
+			rule.SetMinimum(*r.Minimum)
+		}
+		if r.Maximum != nil {
+			rule.SetMaximum(*r.Maximum)
+		}
+		return rule, nil
+	case "bool":
+		if r.Default == nil {
+			return cpolicy.NewBoolRule(r.Key, r.Required)
+		}
+		def, ok := r.Default.(bool)
+		if !ok {
+			return nil, badDefault
+		}
+		return cpolicy.NewBoolRule(r.Key, r.Required, def)
+	}
+	return nil, fmt.Errorf("unknown type %q for rule %s", r.Type, r.Key)
+}
+
+// Meta returns the plugin meta for the shim.
+func (d *ShimDescriptor) Meta() *PluginMeta {
+	opts := []metaOp{Unsecure(true)}
+	if ttl, _ := d.cacheTTL(); ttl > 0 {
+		opts = append(opts, CacheTTL(ttl))
+	}
+	m := NewPluginMeta(d.Name, d.Version, CollectorPluginType, []string{SnapJSONContentType}, []string{SnapJSONContentType}, opts...)
+	m.RPCType = JSONRPC
+	return m
+}
+
+// ShimCollector is a CollectorPlugin backed by a script.
+type ShimCollector struct {
+	descriptor *ShimDescriptor
+}
+
+// NewShimCollector returns a collector running the script described by d.
+func NewShimCollector(d *ShimDescriptor) *ShimCollector {
+	return &ShimCollector{descriptor: d}
+}
+
+// shimRequest is written to the script's stdin.
+type shimRequest struct {
+	Metrics []PluginMetricType `json:"metrics"`
+}
+
+// CollectMetrics runs the script once for the requested metrics.
+func (s *ShimCollector) CollectMetrics(mts []PluginMetricType) ([]PluginMetricType, error) {
+	in, err := json.Marshal(shimRequest{Metrics: mts})
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.run(in)
+	if err != nil {
+		return nil, err
+	}
+	var metrics []PluginMetricType
+	if err := json.Unmarshal(out, &metrics); err != nil {
+		return nil, fmt.Errorf("unable to parse output of shim script: %v", err)
+	}
+	hostname, _ := os.Hostname()
+	now := time.Now()
+	for i := range metrics {
+		if metrics[i].Timestamp_.IsZero() {
+			metrics[i].Timestamp_ = now
+		}
+		if metrics[i].Source_ == "" {
+			metrics[i].Source_ = hostname
+		}
+		if metrics[i].Version_ == 0 {
+			metrics[i].Version_ = s.descriptor.Version
+		}
+	}
+	return metrics, nil
+}
+
+// GetMetricTypes returns the metrics declared in the descriptor.
+func (s *ShimCollector) GetMetricTypes(_ PluginConfigType) ([]PluginMetricType, error) {
+	mts := make([]PluginMetricType, len(s.descriptor.Metrics))
+	for i, ns := range s.descriptor.Metrics {
+		mts[i] = PluginMetricType{Namespace_: splitNamespace(ns)}
+	}
+	return mts, nil
+}
+
+// GetConfigPolicy returns the policy declared in the descriptor.
+func (s *ShimCollector) GetConfigPolicy() (*cpolicy.ConfigPolicy, error) {
+	return s.descriptor.configPolicy()
+}
+
+func (s *ShimCollector) run(in []byte) ([]byte, error) {
+	timeout, err := s.descriptor.timeout()
+	if err != nil {
+		return nil, err
+	}
+	name := s.descriptor.Command[0]
+	if !filepath.IsAbs(name) && strings.Contains(name, string(filepath.Separator)) {
+		name = filepath.Join(s.descriptor.dir, name)
+	}
+	cmd := exec.Command(name, s.descriptor.Command[1:]...)
+	// Scripts run from the descriptor directory so relative arguments work
+	cmd.Dir = s.descriptor.dir
+	cmd.Stdin = bytes.NewReader(in)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, fmt.Errorf("shim script failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+		}
+	case <-time.After(timeout):
+		// Children of the script may hold stdout open so we don't wait on it
+		cmd.Process.Kill()
+		return nil, ErrShimTimeout
+	}
+	return stdout.Bytes(), nil
+}
+
+func splitNamespace(ns string) []string {
+	ns = strings.Trim(ns, "/")
+	if ns == "" {
+		return []string{}
+	}
+	return strings.Split(ns, "/")
+}
@@ -0,0 +1,121 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const shimScript = `#!/bin/sh
+read req
+case "$req" in
+  *sleep*) sleep 2 ;;
+  *fail*) echo "boom" >&2; exit 3 ;;
+esac
+echo '[{"namespace": ["shim", "foo"], "data": 7}]'
+`
+
+func writeShim(dir, descriptor string) string {
+	ioutil.WriteFile(filepath.Join(dir, "collect.sh"), []byte(shimScript), 0755)
+	p := filepath.Join(dir, "snap-collector-test"+ShimDescriptorExt)
+	ioutil.WriteFile(p, []byte(descriptor), 0644)
+	return p
+}
+
+func TestShimCollector(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "snap-shim")
+	defer os.RemoveAll(dir)
+
+	Convey("Shim descriptor", t, func() {
+		Convey("is read and validated", func() {
+			p := writeShim(dir, `{
+				"name": "test", "version": 2,
+				"command": ["./collect.sh"],
+				"metrics": ["/shim/foo", "/shim/sleep", "/shim/fail"],
+				"policy": {"/shim": [
+					{"key": "user", "type": "string", "required": true},
+					{"key": "port", "type": "integer", "default": 80, "minimum": 1, "maximum": 65535}
+				]},
+				"timeout": "500ms"
+			}`)
+			d, err := ReadShimDescriptor(p)
+			So(err, ShouldBeNil)
+			So(d.Meta().Name, ShouldEqual, "test")
+			So(d.Meta().Version, ShouldEqual, 2)
+			So(d.Meta().Unsecure, ShouldBeTrue)
+
+			c := NewShimCollector(d)
+			Convey("GetMetricTypes returns the declared metrics", func() {
+				mts, err := c.GetMetricTypes(NewPluginConfigType())
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 3)
+				So(mts[0].Namespace(), ShouldResemble, []string{"shim", "foo"})
+			})
+			Convey("GetConfigPolicy returns the declared rules", func() {
+				cp, err := c.GetConfigPolicy()
+				So(err, ShouldBeNil)
+				node := cp.Get([]string{"shim", "foo"})
+				So(len(node.RulesAsTable()), ShouldEqual, 2)
+				_, errs := node.Process(map[string]ctypes.ConfigValue{})
+				So(errs.HasErrors(), ShouldBeTrue)
+			})
+			Convey("CollectMetrics runs the script", func() {
+				cfg := cdata.NewNode()
+				cfg.AddItem("user", ctypes.ConfigValueStr{Value: "bob"})
+				mts, err := c.CollectMetrics([]PluginMetricType{
+					{Namespace_: []string{"shim", "foo"}, Config_: cfg},
+				})
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 1)
+				So(mts[0].Data(), ShouldEqual, float64(7))
+				So(mts[0].Version(), ShouldEqual, 2)
+				So(mts[0].Source(), ShouldNotBeEmpty)
+				So(mts[0].Timestamp().IsZero(), ShouldBeFalse)
+			})
+			Convey("CollectMetrics reports script failures", func() {
+				_, err := c.CollectMetrics([]PluginMetricType{{Namespace_: []string{"shim", "fail"}}})
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "boom")
+			})
+			Convey("CollectMetrics times out slow scripts", func() {
+				_, err := c.CollectMetrics([]PluginMetricType{{Namespace_: []string{"shim", "sleep"}}})
+				So(err, ShouldEqual, ErrShimTimeout)
+			})
+		})
+		Convey("without metrics is rejected", func() {
+			p := writeShim(dir, `{"name": "test", "version": 1, "command": ["./collect.sh"]}`)
+			_, err := ReadShimDescriptor(p)
+			So(err, ShouldEqual, ErrShimNoMetrics)
+		})
+		Convey("with a bad rule is rejected", func() {
+			p := writeShim(dir, `{"name": "test", "version": 1, "command": ["./collect.sh"],
+				"metrics": ["/shim/foo"], "policy": {"/shim": [{"key": "a", "type": "integer", "default": "x"}]}}`)
+			_, err := ReadShimDescriptor(p)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,147 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/control_event"
+	"github.com/intelsdi-x/snap/core/serror"
+)
+
+// SetPluginConfig merges cdn into the global config of the plugins it applies
+// to or, with replace, swaps their global config for it once validated
+// against the config policies of the loaded plugins. A merged config is
+// applied as is, as it always was, the policies being enforced when the tasks
+// are validated. The tasks subscribed to the plugins are notified through a
+// PluginConfigUpdatedEvent so that the new config is applied to them. The
+// effective config of the plugins is returned.
+func (p *pluginControl) SetPluginConfig(pluginType core.PluginType, name string, ver int, cdn *cdata.ConfigDataNode, replace bool) (cdata.ConfigDataNode, []serror.SnapError) {
+	if replace {
+		if serrs := p.validatePluginConfig(pluginType, name, ver, cdn); len(serrs) > 0 {
+			return cdata.ConfigDataNode{}, serrs
+		}
+	}
+
+	var res cdata.ConfigDataNode
+	if replace {
+		res = p.Config.ReplacePluginConfigDataNode(pluginType, name, ver, cdn)
+	} else {
+		res = p.Config.MergePluginConfigDataNode(pluginType, name, ver, cdn)
+	}
+
+	ids := p.tasksUsingPlugins(pluginType, name, ver)
+	controlLogger.WithFields(log.Fields{
+		"_block":  "set-plugin-config",
+		"type":    pluginType.String(),
+		"name":    name,
+		"version": ver,
+		"replace": replace,
+		"tasks":   len(ids),
+	}).Info("plugin config updated")
+	p.eventManager.Emit(&control_event.PluginConfigUpdatedEvent{
+		PluginName:    name,
+		PluginVersion: ver,
+		PluginType:    int(pluginType),
+		TaskIDs:       ids,
+	})
+	return res, nil
+}
+
+// tasksUsingPlugins returns the IDs of the tasks subscribed to the plugins of
+// the type, name (any when empty) and version (any when below 1)
+func (p *pluginControl) tasksUsingPlugins(pluginType core.PluginType, name string, ver int) []string {
+	if name != "" {
+		return p.TasksUsingPlugin(pluginType.String(), name, ver)
+	}
+	ids := map[string]bool{}
+	for _, lp := range p.pluginManager.all() {
+		if core.PluginType(lp.Type) != pluginType {
+			continue
+		}
+		for _, id := range p.TasksUsingPlugin(pluginType.String(), lp.Name(), lp.Version()) {
+			ids[id] = true
+		}
+	}
+	return sortedKeys(ids)
+}
+
+// validatePluginConfig checks the values of cdn against the rules of the
+// config policies of the loaded plugins matching the type, name (any when
+// empty) and version (any when below 1). The policies of collectors are
+// those of their metric types.
+func (p *pluginControl) validatePluginConfig(pluginType core.PluginType, name string, ver int, cdn *cdata.ConfigDataNode) []serror.SnapError {
+	matches := func(lp *loadedPlugin) bool {
+		return core.PluginType(lp.Type) == pluginType &&
+			(name == "" || lp.Name() == name) &&
+			(ver < 1 || lp.Version() == ver)
+	}
+
+	policies := map[string][]*cpolicy.ConfigPolicyNode{}
+	if pluginType == core.CollectorPluginType {
+		mts, _ := p.metricCatalog.Fetch([]string{})
+		for _, mt := range mts {
+			node, ok := mt.policy.(*cpolicy.ConfigPolicyNode)
+			if !ok || mt.Plugin == nil || !matches(mt.Plugin) {
+				continue
+			}
+			key := fmt.Sprintf("%s:%d", mt.Plugin.Name(), mt.Plugin.Version())
+			policies[key] = append(policies[key], node)
+		}
+	} else {
+		for _, lp := range p.pluginManager.all() {
+			if !matches(lp) || lp.ConfigPolicy == nil {
+				continue
+			}
+			key := fmt.Sprintf("%s:%d", lp.Name(), lp.Version())
+			policies[key] = append(policies[key], lp.ConfigPolicy.Get([]string{""}))
+		}
+	}
+
+	var serrs []serror.SnapError
+	seen := map[string]bool{}
+	for key, nodes := range policies {
+		for _, node := range nodes {
+			if node == nil {
+				continue
+			}
+			errs := node.Validate(cdn.Table())
+			if !errs.HasErrors() {
+				continue
+			}
+			for _, e := range errs.Errors() {
+				// the metric types of a plugin commonly share rules
+				if seen[key+e.Error()] {
+					continue
+				}
+				seen[key+e.Error()] = true
+				serrs = append(serrs, serror.New(e, map[string]interface{}{
+					"plugin": key,
+				}))
+			}
+		}
+	}
+	return serrs
+}
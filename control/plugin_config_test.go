@@ -0,0 +1,110 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/gomit"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/control/strategy"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/control_event"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+type listenToPluginConfigUpdatedEvent struct {
+	events chan *control_event.PluginConfigUpdatedEvent
+}
+
+func (l *listenToPluginConfigUpdatedEvent) HandleGomitEvent(e gomit.Event) {
+	if v, ok := e.Body.(*control_event.PluginConfigUpdatedEvent); ok {
+		l.events <- v
+	}
+}
+
+func TestSetPluginConfig(t *testing.T) {
+	Convey("Given a collector whose metric type has a config policy", t, func() {
+		c := New(GetDefaultConfig())
+		lp := &loadedPlugin{}
+		lp.Meta.Name = "foo"
+		lp.Meta.Version = 1
+		lp.Type = plugin.CollectorPluginType
+		mt := newMetricType([]string{"intel", "foo"}, time.Now(), lp)
+		node := cpolicy.NewPolicyNode()
+		rule, err := cpolicy.NewIntegerRule("port", false)
+		So(err, ShouldBeNil)
+		node.Add(rule)
+		mt.policy = node
+		c.metricCatalog.Add(mt)
+		pool, err := c.pluginRunner.AvailablePlugins().getOrCreatePool(lp.Key())
+		So(err, ShouldBeNil)
+		pool.Subscribe("task1", strategy.UnboundSubscriptionType)
+
+		l := &listenToPluginConfigUpdatedEvent{events: make(chan *control_event.PluginConfigUpdatedEvent, 1)}
+		c.RegisterEventHandler("test", l)
+
+		Convey("a replacing config not matching the policy is refused", func() {
+			cdn := cdata.NewNode()
+			cdn.AddItem("port", ctypes.ConfigValueStr{Value: "http"})
+			_, serrs := c.SetPluginConfig(core.CollectorPluginType, "foo", 1, cdn, true)
+			So(serrs, ShouldNotBeEmpty)
+			So(serrs[0].Fields()["plugin"], ShouldEqual, "foo:1")
+			n := c.Config.GetPluginConfigDataNode(core.CollectorPluginType, "foo", 1)
+			So(n.Table(), ShouldBeEmpty)
+		})
+		Convey("a merged config is applied without being validated", func() {
+			cdn := cdata.NewNode()
+			cdn.AddItem("port", ctypes.ConfigValueStr{Value: "http"})
+			res, serrs := c.SetPluginConfig(core.CollectorPluginType, "foo", 1, cdn, false)
+			So(serrs, ShouldBeEmpty)
+			So(res.Table()["port"], ShouldResemble, ctypes.ConfigValueStr{Value: "http"})
+		})
+		Convey("a valid config is applied and the tasks using the plugin are notified", func() {
+			cdn := cdata.NewNode()
+			cdn.AddItem("port", ctypes.ConfigValueInt{Value: 8080})
+			cdn.AddItem("user", ctypes.ConfigValueStr{Value: "jane"})
+			res, serrs := c.SetPluginConfig(core.CollectorPluginType, "foo", 1, cdn, false)
+			So(serrs, ShouldBeEmpty)
+			So(res.Table()["port"], ShouldResemble, ctypes.ConfigValueInt{Value: 8080})
+			var e *control_event.PluginConfigUpdatedEvent
+			select {
+			case e = <-l.events:
+			case <-time.After(5 * time.Second):
+			}
+			So(e, ShouldNotBeNil)
+			So(e.PluginName, ShouldEqual, "foo")
+			So(e.TaskIDs, ShouldResemble, []string{"task1"})
+
+			Convey("and replacing it drops the keys left out", func() {
+				cdn := cdata.NewNode()
+				cdn.AddItem("port", ctypes.ConfigValueInt{Value: 9090})
+				res, serrs := c.SetPluginConfig(core.CollectorPluginType, "foo", 1, cdn, true)
+				So(serrs, ShouldBeEmpty)
+				So(res.Table(), ShouldResemble, map[string]ctypes.ConfigValue{"port": ctypes.ConfigValueInt{Value: 9090}})
+			})
+		})
+	})
+}
@@ -43,6 +43,7 @@ import (
 	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/pkg/datadir"
 )
 
 const (
@@ -187,6 +188,16 @@ func (lp *loadedPlugin) PluginPath() string {
 	return lp.Details.Path
 }
 
+// Features returns the names of the optional features the plugin advertises
+func (lp *loadedPlugin) Features() []string {
+	return lp.Meta.Features.Names()
+}
+
+// Supports returns true if both the plugin and snapd support the features
+func (lp *loadedPlugin) Supports(f plugin.Feature) bool {
+	return (lp.Meta.Features & plugin.SnapdFeatures).Has(f)
+}
+
 // Key returns plugin type, name and version
 func (lp *loadedPlugin) Key() string {
 	return fmt.Sprintf("%s:%s:%d", lp.TypeName(), lp.Name(), lp.Version())
@@ -216,6 +227,12 @@ func (lp *loadedPlugin) IsSigned() bool {
 	return lp.Details.Signed
 }
 
+// Signature returns the signature the plugin was loaded with, if any
+// implements the CatalogedPlugin interface
+func (lp *loadedPlugin) Signature() []byte {
+	return lp.Details.Signature
+}
+
 // LoadedTimestamp returns a unix timestamp of the LoadTime of a plugin
 // implements the CatalogedPlugin interface
 func (lp *loadedPlugin) LoadedTimestamp() *time.Time {
@@ -233,6 +250,11 @@ type pluginManager struct {
 	loadedPlugins *loadedPlugins
 	logPath       string
 	pluginConfig  *pluginConfig
+	dataDir       *datadir.DataDir
+	// transports are the transport settings of the plugins started
+	transports *transportSettings
+	// output holds the files the output of the plugins is logged to
+	output *plugin.Output
 }
 
 func newPluginManager(opts ...pluginManagerOpt) *pluginManager {
@@ -244,6 +266,7 @@ func newPluginManager(opts ...pluginManagerOpt) *pluginManager {
 		loadedPlugins: newLoadedPlugins(),
 		logPath:       logPath,
 		pluginConfig:  newPluginConfig(),
+		transports:    newTransportSettings(),
 	}
 
 	for _, opt := range opts {
@@ -255,6 +278,20 @@ func newPluginManager(opts ...pluginManagerOpt) *pluginManager {
 
 type pluginManagerOpt func(*pluginManager)
 
+// optSetTransports sets the transport settings of the plugins started
+func optSetTransports(t *transportSettings) pluginManagerOpt {
+	return func(p *pluginManager) {
+		p.transports = t
+	}
+}
+
+// optSetOutput sets the output of the plugins started
+func optSetOutput(o *plugin.Output) pluginManagerOpt {
+	return func(p *pluginManager) {
+		p.output = o
+	}
+}
+
 // OptSetPluginConfig sets the config on the plugin manager
 func OptSetPluginConfig(cf *pluginConfig) pluginManagerOpt {
 	return func(p *pluginManager) {
@@ -267,6 +304,14 @@ func (p *pluginManager) SetPluginConfig(cf *pluginConfig) {
 	p.pluginConfig = cf
 }
 
+// SetDataDir sets the data directory. Plugin logs are written to its logs
+// subdirectory and uploaded plugins found in its plugins subdirectory are
+// removed when unloaded.
+func (p *pluginManager) SetDataDir(d *datadir.DataDir) {
+	p.dataDir = d
+	p.logPath = d.Path(datadir.Logs)
+}
+
 // SetMetricCatalog sets metric catalog
 func (p *pluginManager) SetMetricCatalog(mc catalogsMetrics) {
 	p.metricCatalog = mc
@@ -283,7 +328,7 @@ func (p *pluginManager) LoadPlugin(details *pluginDetails, emitter gomit.Emitter
 		"_block": "load-plugin",
 		"path":   filepath.Base(lPlugin.Details.Exec),
 	}).Info("plugin load called")
-	ePlugin, err := plugin.NewExecutablePlugin(p.GenerateArgs(lPlugin.Details.Exec), path.Join(lPlugin.Details.ExecPath, lPlugin.Details.Exec))
+	ePlugin, err := p.newExecutablePlugin(lPlugin.Details)
 
 	if err != nil {
 		pmLogger.WithFields(log.Fields{
@@ -313,7 +358,7 @@ func (p *pluginManager) LoadPlugin(details *pluginDetails, emitter gomit.Emitter
 		return nil, serror.New(err)
 	}
 
-	ap, err := newAvailablePlugin(resp, emitter, ePlugin)
+	ap, err := newAvailablePlugin(resp, emitter, ePlugin, p.transports)
 	if err != nil {
 		pmLogger.WithFields(log.Fields{
 			"_block": "load-plugin",
@@ -353,6 +398,8 @@ func (p *pluginManager) LoadPlugin(details *pluginDetails, emitter gomit.Emitter
 		return nil, serror.New(err)
 	}
 	lPlugin.ConfigPolicy = cp
+	lPlugin.Meta = resp.Meta
+	lPlugin.Type = resp.Type
 
 	if resp.Type == plugin.CollectorPluginType {
 		colClient := ap.client.(client.PluginCollectorClient)
@@ -371,23 +418,24 @@ func (p *pluginManager) LoadPlugin(details *pluginDetails, emitter gomit.Emitter
 			return nil, serror.New(err)
 		}
 
+		if err := p.metricCatalog.CheckMetricLimits(lPlugin, len(metricTypes)); err != nil {
+			pmLogger.WithFields(log.Fields{
+				"_block":         "load-plugin",
+				"plugin-name":    resp.Meta.Name,
+				"plugin-version": resp.Meta.Version,
+				"metric-count":   len(metricTypes),
+				"error":          err.Error(),
+			}).Error("metric limit exceeded")
+			if le, ok := err.(*metricLimitError); ok && emitter != nil {
+				emitter.Emit(le.event())
+			}
+			ePlugin.Kill()
+			return nil, serror.New(err)
+		}
+
 		// Add metric types to metric catalog
 		for _, nmt := range metricTypes {
-			// If the version is 0 default it to the plugin version
-			// This honors the plugins explicit version but falls back
-			// to the plugin version as default
-			if nmt.Version() < 1 {
-				// Since we have to override version we convert to a internal struct
-				nmt = &metricType{
-					namespace:          nmt.Namespace(),
-					version:            resp.Meta.Version,
-					lastAdvertisedTime: nmt.LastAdvertisedTime(),
-					config:             nmt.Config(),
-					data:               nmt.Data(),
-					tags:               nmt.Tags(),
-					labels:             nmt.Labels(),
-				}
-			}
+			nmt = defaultMetricVersion(nmt, resp.Meta.Version)
 			// We quit and throw an error on bad metric versions (<1)
 			// the is a safety catch otherwise the catalog will be corrupted
 			if nmt.Version() < 1 {
@@ -439,12 +487,20 @@ func (p *pluginManager) LoadPlugin(details *pluginDetails, emitter gomit.Emitter
 		return nil, serror.New(e)
 	}
 
-	lPlugin.Meta = resp.Meta
-	lPlugin.Type = resp.Type
 	lPlugin.Token = resp.Token
 	lPlugin.LoadedTime = time.Now()
 	lPlugin.State = LoadedState
 
+	if lPlugin.Meta.Features != 0 {
+		pmLogger.WithFields(log.Fields{
+			"_block":           "load-plugin",
+			"plugin-name":      lPlugin.Name(),
+			"plugin-version":   lPlugin.Version(),
+			"plugin-features":  lPlugin.Meta.Features.String(),
+			"enabled-features": (lPlugin.Meta.Features & plugin.SnapdFeatures).String(),
+		}).Debug("plugin advertised optional features")
+	}
+
 	aErr := p.loadedPlugins.add(lPlugin)
 	if aErr != nil {
 		pmLogger.WithFields(log.Fields{
@@ -479,8 +535,9 @@ func (p *pluginManager) UnloadPlugin(pl core.Plugin) (*loadedPlugin, serror.Snap
 		return nil, se
 	}
 
-	// If the plugin was loaded from os.TempDir() clean up
-	if strings.Contains(plugin.Details.Path, os.TempDir()) {
+	// If the plugin was loaded from os.TempDir() or was uploaded to the data
+	// directory clean up
+	if strings.Contains(plugin.Details.Path, os.TempDir()) || p.uploaded(plugin.Details.Path) {
 		pmLogger.WithFields(log.Fields{
 			"plugin-type":    plugin.TypeName(),
 			"plugin-name":    plugin.Name(),
@@ -515,10 +572,29 @@ func (p *pluginManager) UnloadPlugin(pl core.Plugin) (*loadedPlugin, serror.Snap
 	return plugin, nil
 }
 
+// uploaded returns true if the path is in the plugins subdirectory of the
+// data directory
+func (p *pluginManager) uploaded(path string) bool {
+	return p.dataDir != nil && p.dataDir.Contains(datadir.Plugins, path)
+}
+
 // GenerateArgs generates the cli args to send when stating a plugin
 func (p *pluginManager) GenerateArgs(pluginPath string) plugin.Arg {
 	pluginLog := filepath.Join(p.logPath, filepath.Base(pluginPath)) + ".log"
-	return plugin.NewArg(pluginLog)
+	arg := plugin.NewArg(pluginLog)
+	arg.ChunkSize = p.transports.getChunkSize()
+	return arg
+}
+
+// newExecutablePlugin returns the executable of the plugin, logging its
+// output to the output of the plugins
+func (p *pluginManager) newExecutablePlugin(details *pluginDetails) (*plugin.ExecutablePlugin, error) {
+	ePlugin, err := plugin.NewExecutablePlugin(p.GenerateArgs(details.Exec), path.Join(details.ExecPath, details.Exec))
+	if err != nil {
+		return nil, err
+	}
+	ePlugin.SetOutput(p.output)
+	return ePlugin, nil
 }
 
 func (p *pluginManager) teardown() {
@@ -539,6 +615,25 @@ func (p *pluginManager) get(key string) (*loadedPlugin, error) {
 	return p.loadedPlugins.get(key)
 }
 
+// defaultMetricVersion defaults the version of a metric advertised with a
+// version of 0 to the plugin version. This honors the plugins explicit version
+// but falls back to the plugin version as default.
+func defaultMetricVersion(mt core.Metric, version int) core.Metric {
+	if mt.Version() > 0 {
+		return mt
+	}
+	// Since we have to override version we convert to a internal struct
+	return &metricType{
+		namespace:          mt.Namespace(),
+		version:            version,
+		lastAdvertisedTime: mt.LastAdvertisedTime(),
+		config:             mt.Config(),
+		data:               mt.Data(),
+		tags:               mt.Tags(),
+		labels:             mt.Labels(),
+	}
+}
+
 func (p *pluginManager) all() map[string]*loadedPlugin {
 	p.loadedPlugins.RLock()
 	defer p.loadedPlugins.RUnlock()
@@ -21,6 +21,7 @@ package control
 
 import (
 	"errors"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
@@ -32,6 +33,7 @@ import (
 	"github.com/intelsdi-x/snap/control/plugin"
 	"github.com/intelsdi-x/snap/core/ctypes"
 	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/pkg/datadir"
 )
 
 var (
@@ -72,6 +74,26 @@ func TestLoadedPlugins(t *testing.T) {
 	})
 }
 
+func TestPluginManagerDataDir(t *testing.T) {
+	Convey("pluginManager.SetDataDir", t, func() {
+		tmp, err := ioutil.TempDir("", "snap-datadir")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(tmp)
+		dd, err := datadir.New(tmp)
+		So(err, ShouldBeNil)
+		p := newPluginManager()
+		p.SetDataDir(dd)
+		Convey("writes plugin logs to the data directory", func() {
+			arg := p.GenerateArgs("/opt/snap/plugin/snap-collector-foo")
+			So(arg.PluginLogPath, ShouldEqual, filepath.Join(tmp, datadir.Logs, "snap-collector-foo.log"))
+		})
+		Convey("recognizes uploaded plugins", func() {
+			So(p.uploaded(filepath.Join(tmp, datadir.Plugins, "123", "snap-collector-foo")), ShouldBeTrue)
+			So(p.uploaded("/opt/snap/plugin/snap-collector-foo"), ShouldBeFalse)
+		})
+	})
+}
+
 func loadPlugin(p *pluginManager, path string) (*loadedPlugin, serror.SnapError) {
 	// This is a Travis optimized loading of plugins. From time to time, tests will error in Travis
 	// due to a timeout when waiting for a response from a plugin. We are going to attempt loading a plugin
@@ -244,6 +266,15 @@ func TestLoadedPlugin(t *testing.T) {
 			So(lp.TypeName(), ShouldEqual, "collector")
 		})
 	})
+	Convey(".Supports()", t, func() {
+		Convey("it returns true for the features advertised by the plugin and supported by snapd", func() {
+			lp.Meta.Features = plugin.FeatureDynamicMetrics
+			So(lp.Supports(plugin.FeatureDynamicMetrics), ShouldBeTrue)
+			So(lp.Supports(plugin.FeatureStreaming), ShouldBeFalse)
+			lp.Meta.Features = 0
+			So(lp.Supports(plugin.FeatureDynamicMetrics), ShouldBeFalse)
+		})
+	})
 	Convey(".Status()", t, func() {
 		lp.State = LoadedState
 		Convey("it returns a string of the current plugin state", func() {
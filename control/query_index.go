@@ -0,0 +1,126 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"strings"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// queryIndex indexes the wildcard keys of metric queries (e.g. "intel.cpu.*.idle")
+// by their literal prefix, the elements before the first one holding a
+// wildcard or a tuple. A catalog key can only be matched by the queries found
+// along its own path in the index, so adding or removing a key only tests
+// those queries instead of all of them.
+type queryIndex struct {
+	children map[string]*queryIndex
+	// queries maps the wildcard keys ending at this node to their compiled query
+	queries map[string]*core.WildcardNamespace
+}
+
+func newQueryIndex() *queryIndex {
+	return &queryIndex{
+		children: map[string]*queryIndex{},
+		queries:  map[string]*core.WildcardNamespace{},
+	}
+}
+
+// get returns the compiled query of an indexed wildcard key
+func (qi *queryIndex) get(wkey string) (*core.WildcardNamespace, bool) {
+	node := qi
+	for _, n := range literalPrefix(wkey) {
+		node = node.children[n]
+		if node == nil {
+			return nil, false
+		}
+	}
+	w, ok := node.queries[wkey]
+	return w, ok
+}
+
+// add indexes the wildcard key with its compiled query
+func (qi *queryIndex) add(wkey string, w *core.WildcardNamespace) {
+	node := qi
+	for _, n := range literalPrefix(wkey) {
+		child := node.children[n]
+		if child == nil {
+			child = newQueryIndex()
+			node.children[n] = child
+		}
+		node = child
+	}
+	node.queries[wkey] = w
+}
+
+// remove removes the wildcard key from the index along with the nodes left
+// empty
+func (qi *queryIndex) remove(wkey string) {
+	prefix := literalPrefix(wkey)
+	path := []*queryIndex{qi}
+	node := qi
+	for _, n := range prefix {
+		node = node.children[n]
+		if node == nil {
+			return
+		}
+		path = append(path, node)
+	}
+	delete(node.queries, wkey)
+	for i := len(prefix); i > 0; i-- {
+		node = path[i]
+		if len(node.queries) > 0 || len(node.children) > 0 {
+			return
+		}
+		delete(path[i-1].children, prefix[i-1])
+	}
+}
+
+// matching returns the indexed wildcard keys which match the catalog key
+func (qi *queryIndex) matching(key string) []string {
+	var wkeys []string
+	ns := strings.Split(key, ".")
+	node := qi
+	for _, n := range append(ns, "") {
+		for wkey, w := range node.queries {
+			if w.Match(ns) {
+				wkeys = append(wkeys, wkey)
+			}
+		}
+		node = node.children[n]
+		if node == nil {
+			break
+		}
+	}
+	return wkeys
+}
+
+// literalPrefix returns the elements of a wildcard key before the first
+// element which holds a wildcard, a tuple or an exclusion
+func literalPrefix(wkey string) []string {
+	var prefix []string
+	for _, n := range strings.Split(wkey, ".") {
+		if strings.ContainsAny(n, "*(|)!") {
+			break
+		}
+		prefix = append(prefix, n)
+	}
+	return prefix
+}
@@ -0,0 +1,69 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/intelsdi-x/snap/core"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestQueryIndex(t *testing.T) {
+	Convey("queryIndex", t, func() {
+		qi := newQueryIndex()
+		for _, wkey := range []string{"intel.cpu.*", "intel.*.idle", "intel.(cpu|mem).used", "intel.cpu.0.idle", "mock.*"} {
+			w, err := core.CompileWildcardNamespace(strings.Split(wkey, "."))
+			So(err, ShouldBeNil)
+			qi.add(wkey, w)
+		}
+		Convey("indexes wildcard keys by their literal prefix", func() {
+			So(literalPrefix("intel.cpu.*.idle"), ShouldResemble, []string{"intel", "cpu"})
+			So(literalPrefix("intel.(cpu|mem).used"), ShouldResemble, []string{"intel"})
+			So(literalPrefix("*"), ShouldBeEmpty)
+			_, ok := qi.get("intel.*.idle")
+			So(ok, ShouldBeTrue)
+			_, ok = qi.get("intel.*.used")
+			So(ok, ShouldBeFalse)
+		})
+		Convey("returns the wildcard keys matching a catalog key", func() {
+			wkeys := qi.matching("intel.cpu.0.idle")
+			sort.Strings(wkeys)
+			So(wkeys, ShouldResemble, []string{"intel.*.idle", "intel.cpu.*", "intel.cpu.0.idle"})
+			So(qi.matching("intel.mem.used"), ShouldResemble, []string{"intel.(cpu|mem).used"})
+			So(qi.matching("other.cpu.0.idle"), ShouldBeEmpty)
+		})
+		Convey("removes wildcard keys and the nodes left empty", func() {
+			qi.remove("intel.cpu.0.idle")
+			_, ok := qi.get("intel.cpu.0.idle")
+			So(ok, ShouldBeFalse)
+			So(qi.children["intel"].children["cpu"].children, ShouldBeEmpty)
+			qi.remove("intel.cpu.*")
+			So(qi.children["intel"].children, ShouldBeEmpty)
+			qi.remove("mock.*")
+			So(qi.children, ShouldNotContainKey, "mock")
+			_, ok = qi.get("intel.*.idle")
+			So(ok, ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,260 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+)
+
+// recordedResponse is the response of a collector or processor plugin
+// captured by a responseRecorder
+type recordedResponse struct {
+	TaskID      string
+	PluginKey   string
+	Metrics     []plugin.PluginMetricType
+	ContentType string
+	Content     []byte
+	Errors      []string
+}
+
+func newRecordedResponse(pluginKey, taskID string, errs []error) recordedResponse {
+	r := recordedResponse{TaskID: taskID, PluginKey: pluginKey}
+	for _, e := range errs {
+		r.Errors = append(r.Errors, e.Error())
+	}
+	return r
+}
+
+func (r recordedResponse) errors() []error {
+	var errs []error
+	for _, e := range r.Errors {
+		errs = append(errs, errors.New(e))
+	}
+	return errs
+}
+
+// responseRecorder writes the responses of plugins to a file
+type responseRecorder struct {
+	mutex sync.Mutex
+	file  *os.File
+	enc   *gob.Encoder
+}
+
+func newResponseRecorder(path string) (*responseRecorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &responseRecorder{file: f, enc: gob.NewEncoder(f)}, nil
+}
+
+func (r *responseRecorder) record(resp recordedResponse) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.enc.Encode(resp); err != nil {
+		controlLogger.WithFields(log.Fields{
+			"_block":     "record",
+			"plugin-key": resp.PluginKey,
+			"task-id":    resp.TaskID,
+			"error":      err.Error(),
+		}).Error("unable to record plugin response")
+	}
+}
+
+func (r *responseRecorder) recordCollect(pluginKey, taskID string, mts []core.Metric, err error) {
+	var errs []error
+	if err != nil {
+		errs = append(errs, err)
+	}
+	resp := newRecordedResponse(pluginKey, taskID, errs)
+	for _, m := range mts {
+		if mt, ok := m.(plugin.PluginMetricType); ok {
+			resp.Metrics = append(resp.Metrics, mt)
+		}
+	}
+	r.record(resp)
+}
+
+func (r *responseRecorder) recordProcess(pluginKey, taskID, contentType string, content []byte, errs []error) {
+	resp := newRecordedResponse(pluginKey, taskID, errs)
+	resp.ContentType = contentType
+	resp.Content = content
+	r.record(resp)
+}
+
+func (r *responseRecorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.file.Close()
+}
+
+// responseReplayer answers the calls to plugins with the responses recorded
+// for them, in the order they were recorded and starting over once all of
+// them were replayed
+type responseReplayer struct {
+	mutex     sync.Mutex
+	responses map[string][]recordedResponse
+	next      map[string]int
+}
+
+func newResponseReplayer(path string) (*responseReplayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := &responseReplayer{
+		responses: map[string][]recordedResponse{},
+		next:      map[string]int{},
+	}
+	dec := gob.NewDecoder(f)
+	for {
+		var resp recordedResponse
+		err := dec.Decode(&resp)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read recorded plugin responses from %s: %v", path, err)
+		}
+		r.responses[resp.PluginKey] = append(r.responses[resp.PluginKey], resp)
+	}
+	return r, nil
+}
+
+func (r *responseReplayer) replay(pluginKey string) (recordedResponse, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	resps := r.responses[pluginKey]
+	if len(resps) == 0 {
+		return recordedResponse{}, fmt.Errorf("no recorded response for plugin %s", pluginKey)
+	}
+	resp := resps[r.next[pluginKey]]
+	r.next[pluginKey] = (r.next[pluginKey] + 1) % len(resps)
+	return resp, nil
+}
+
+func (r *responseReplayer) replayCollect(pluginKey string) ([]core.Metric, error) {
+	resp, err := r.replay(pluginKey)
+	if err != nil {
+		return nil, err
+	}
+	if errs := resp.errors(); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	mts := make([]core.Metric, len(resp.Metrics))
+	for i, m := range resp.Metrics {
+		mts[i] = m
+	}
+	return mts, nil
+}
+
+func (r *responseReplayer) replayProcess(pluginKey string) (string, []byte, []error) {
+	resp, err := r.replay(pluginKey)
+	if err != nil {
+		return "", nil, []error{err}
+	}
+	return resp.ContentType, resp.Content, resp.errors()
+}
+
+// ReplayedResponses answers the calls a simulated task makes to plugins with
+// the responses snapd recorded to a file (see record_path), without loading
+// any plugin
+type ReplayedResponses struct {
+	replayer *responseReplayer
+}
+
+// NewReplayedResponses reads the plugin responses recorded to the file
+func NewReplayedResponses(path string) (*ReplayedResponses, error) {
+	r, err := newResponseReplayer(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayedResponses{replayer: r}, nil
+}
+
+// Collect replays the next response of each collector which recorded metrics
+// matching one of the namespaces, and returns the metrics of these responses
+// which match them
+func (r *ReplayedResponses) Collect(nss []*core.WildcardNamespace) ([]core.Metric, error) {
+	var keys []string
+	for key, resps := range r.replayer.responses {
+		if strings.HasPrefix(key, core.CollectorPluginType.String()+":") && recordedMatch(resps, nss) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var collected []core.Metric
+	for _, key := range keys {
+		mts, err := r.replayer.replayCollect(key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+		for _, m := range mts {
+			if namespaceMatch(m.Namespace(), nss) {
+				collected = append(collected, m)
+			}
+		}
+	}
+	return collected, nil
+}
+
+// Process replays the next response recorded for the processor and returns
+// its errors, none when no response was recorded for it
+func (r *ReplayedResponses) Process(pluginName string, pluginVersion int) []error {
+	key := fmt.Sprintf("%s:%s:%d", core.ProcessorPluginType.String(), pluginName, pluginVersion)
+	if len(r.replayer.responses[key]) == 0 {
+		return nil
+	}
+	_, _, errs := r.replayer.replayProcess(key)
+	return errs
+}
+
+func recordedMatch(resps []recordedResponse, nss []*core.WildcardNamespace) bool {
+	for _, resp := range resps {
+		for _, m := range resp.Metrics {
+			if namespaceMatch(m.Namespace(), nss) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func namespaceMatch(ns []string, nss []*core.WildcardNamespace) bool {
+	for _, wn := range nss {
+		if wn.Match(ns) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestResponseRecorder(t *testing.T) {
+	Convey("Recorded plugin responses", t, func() {
+		dir, err := ioutil.TempDir("", "snap-recorder")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "responses")
+
+		rec, err := newResponseRecorder(path)
+		So(err, ShouldBeNil)
+		foo := plugin.PluginMetricType{Namespace_: []string{"intel", "mock", "foo"}, Data_: 1}
+		bar := plugin.PluginMetricType{Namespace_: []string{"intel", "mock", "bar"}, Data_: "bar"}
+		rec.recordCollect("collector:mock:1", "task", []core.Metric{foo}, nil)
+		rec.recordCollect("collector:mock:1", "task", []core.Metric{bar}, nil)
+		rec.recordCollect("collector:mock:2", "task", nil, errors.New("collection failed"))
+		rec.recordProcess("processor:passthru:1", "task", plugin.SnapGOBContentType, []byte("content"), nil)
+		So(rec.Close(), ShouldBeNil)
+
+		rep, err := newResponseReplayer(path)
+		So(err, ShouldBeNil)
+
+		Convey("are replayed in order and then from the start", func() {
+			for _, want := range []plugin.PluginMetricType{foo, bar, foo} {
+				mts, err := rep.replayCollect("collector:mock:1")
+				So(err, ShouldBeNil)
+				So(mts, ShouldHaveLength, 1)
+				So(mts[0].Namespace(), ShouldResemble, want.Namespace())
+				So(mts[0].Data(), ShouldEqual, want.Data())
+			}
+		})
+		Convey("replay the errors of the plugin", func() {
+			_, err := rep.replayCollect("collector:mock:2")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "collection failed")
+		})
+		Convey("replay the content returned by processors", func() {
+			ct, content, errs := rep.replayProcess("processor:passthru:1")
+			So(errs, ShouldBeEmpty)
+			So(ct, ShouldEqual, plugin.SnapGOBContentType)
+			So(string(content), ShouldEqual, "content")
+		})
+		Convey("report a plugin without recorded responses", func() {
+			_, err := rep.replayCollect("collector:mock:3")
+			So(err, ShouldNotBeNil)
+		})
+		Convey("answer the collections of a simulated task", func() {
+			rr, err := NewReplayedResponses(path)
+			So(err, ShouldBeNil)
+			wn, err := core.CompileWildcardNamespace([]string{"intel", "mock", "foo"})
+			So(err, ShouldBeNil)
+			mts, err := rr.Collect([]*core.WildcardNamespace{wn})
+			So(err, ShouldBeNil)
+			So(mts, ShouldHaveLength, 1)
+			So(mts[0].Namespace(), ShouldResemble, foo.Namespace())
+			// the next response of the collector has no metric of the task
+			mts, err = rr.Collect([]*core.WildcardNamespace{wn})
+			So(err, ShouldBeNil)
+			So(mts, ShouldBeEmpty)
+			So(rr.Process("passthru", 1), ShouldBeEmpty)
+			So(rr.Process("passthru", 2), ShouldBeNil)
+		})
+	})
+
+	Convey("Replaying a missing file fails", t, func() {
+		_, err := newResponseReplayer("/tmp/snap-replay-does-not-exist")
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,172 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+// A task of another snapd may offload a process step to a processor loaded
+// on this snapd. The processor is subscribed to on behalf of the remote task
+// on its first batch, under the ID of the task prefixed with
+// RemoteSubscriberPrefix, and unsubscribed from once no batch came from the
+// task for remoteSubscriptionTTL. Those subscriptions keep the processor
+// running but are not tasks of this snapd, so they do not keep it from being
+// unloaded.
+
+// RemoteSubscriberPrefix prefixes the IDs of the tasks of other snapd
+// instances subscribed to a processor of this snapd
+const RemoteSubscriberPrefix = "remote/"
+
+// remoteSubscriptionTTL is how long a remote task is subscribed to a
+// processor after its last batch
+const remoteSubscriptionTTL = 10 * time.Minute
+
+var (
+	// ErrRemoteContentType - The error message for a batch of a remote task in a content type the processor does not accept and snap cannot convert
+	ErrRemoteContentType = errors.New("Processor does not accept the content type of the batch")
+)
+
+type remoteSubscription struct {
+	subscriber string
+	plugin     *loadedPlugin
+}
+
+// remoteSubscriptions are the subscriptions of remote tasks to the processors
+// of this snapd, with the time of their last batch
+type remoteSubscriptions struct {
+	sync.Mutex
+	used map[remoteSubscription]time.Time
+}
+
+func newRemoteSubscriptions() *remoteSubscriptions {
+	return &remoteSubscriptions{
+		used: map[remoteSubscription]time.Time{},
+	}
+}
+
+// isRemoteSubscriber returns true if the ID is the one of a remote task
+func isRemoteSubscriber(id string) bool {
+	return strings.HasPrefix(id, RemoteSubscriberPrefix)
+}
+
+// ProcessRemoteMetrics processes a batch of metrics a task of another snapd
+// offloaded to a processor of this snapd. The batch is converted to a
+// content type the processor accepts if needed, and the processor is started
+// when no task uses it yet.
+func (p *pluginControl) ProcessRemoteMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, taskID string) (string, []byte, []error) {
+	lp, err := p.pluginManager.get(fmt.Sprintf("%s:%s:%d", core.ProcessorPluginType.String(), pluginName, pluginVersion))
+	if err != nil {
+		return "", nil, []error{err}
+	}
+	contentType, content, err = convertRemoteContent(contentType, content, lp.Meta.AcceptedContentTypes)
+	if err != nil {
+		return "", nil, []error{err}
+	}
+	sub := remoteSubscription{
+		subscriber: RemoteSubscriberPrefix + taskID,
+		plugin:     lp,
+	}
+	p.remote.Lock()
+	_, ok := p.remote.used[sub]
+	p.remote.used[sub] = time.Now()
+	p.remote.Unlock()
+	if !ok {
+		if errs := p.SubscribeDeps(sub.subscriber, nil, []core.Plugin{lp}); len(errs) > 0 {
+			p.remote.Lock()
+			delete(p.remote.used, sub)
+			p.remote.Unlock()
+			return "", nil, []error{errs[0]}
+		}
+		controlLogger.WithFields(log.Fields{
+			"_block":         "process-remote-metrics",
+			"subscriber":     sub.subscriber,
+			"plugin-name":    lp.Name(),
+			"plugin-version": lp.Version(),
+		}).Info("remote task subscribed to processor")
+	}
+	if config == nil {
+		config = map[string]ctypes.ConfigValue{}
+	}
+	return p.ProcessMetrics(contentType, content, lp.Name(), lp.Version(), config, sub.subscriber)
+}
+
+// convertRemoteContent returns the content in a content type the processor
+// accepts
+func convertRemoteContent(contentType string, content []byte, accepted []string) (string, []byte, error) {
+	for _, ac := range accepted {
+		if ac == contentType || ac == plugin.SnapAllContentType && plugin.IsSnapContentType(contentType) {
+			return contentType, content, nil
+		}
+	}
+	for _, ac := range accepted {
+		if !core.CanEncode(ac) {
+			continue
+		}
+		mts, err := core.DecodeMetrics(contentType, content)
+		if err != nil {
+			return "", nil, err
+		}
+		content, err = core.EncodeMetrics(ac, mts)
+		if err != nil {
+			return "", nil, err
+		}
+		return ac, content, nil
+	}
+	return "", nil, fmt.Errorf("%v: %s, accepted: %v", ErrRemoteContentType, contentType, accepted)
+}
+
+// sweepRemoteSubscriptions unsubscribes the remote tasks from the processors
+// they sent no batch to within the TTL
+func (p *pluginControl) sweepRemoteSubscriptions(ttl time.Duration) {
+	var expired []remoteSubscription
+	p.remote.Lock()
+	for sub, used := range p.remote.used {
+		if time.Since(used) > ttl {
+			expired = append(expired, sub)
+			delete(p.remote.used, sub)
+		}
+	}
+	p.remote.Unlock()
+	for _, sub := range expired {
+		f := log.Fields{
+			"_block":         "sweep-remote-subscriptions",
+			"subscriber":     sub.subscriber,
+			"plugin-name":    sub.plugin.Name(),
+			"plugin-version": sub.plugin.Version(),
+		}
+		if errs := p.UnsubscribeDeps(sub.subscriber, nil, []core.Plugin{sub.plugin}); len(errs) > 0 {
+			f["error"] = errs[0].Error()
+			controlLogger.WithFields(f).Warn("unable to unsubscribe remote task from processor")
+			continue
+		}
+		controlLogger.WithFields(f).Info("remote task unsubscribed from idle processor")
+	}
+}
@@ -0,0 +1,90 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/strategy"
+	"github.com/intelsdi-x/snap/core"
+)
+
+func TestConvertRemoteContent(t *testing.T) {
+	Convey("Given a batch of metrics offloaded by a remote task", t, func() {
+		mts := []core.Metric{plugin.PluginMetricType{Namespace_: []string{"intel", "foo"}, Data_: 42}}
+		content, err := core.EncodeMetrics(plugin.SnapGOBContentType, mts)
+		So(err, ShouldBeNil)
+		Convey("it is passed as is to a processor accepting its content type", func() {
+			ct, c, err := convertRemoteContent(plugin.SnapGOBContentType, content, []string{plugin.SnapJSONContentType, plugin.SnapGOBContentType})
+			So(err, ShouldBeNil)
+			So(ct, ShouldEqual, plugin.SnapGOBContentType)
+			So(c, ShouldResemble, content)
+			ct, _, err = convertRemoteContent(plugin.SnapGOBContentType, content, []string{plugin.SnapAllContentType})
+			So(err, ShouldBeNil)
+			So(ct, ShouldEqual, plugin.SnapGOBContentType)
+		})
+		Convey("it is converted for a processor accepting another content type", func() {
+			ct, c, err := convertRemoteContent(plugin.SnapGOBContentType, content, []string{"unknown", plugin.SnapJSONContentType})
+			So(err, ShouldBeNil)
+			So(ct, ShouldEqual, plugin.SnapJSONContentType)
+			decoded, err := core.DecodeMetrics(ct, c)
+			So(err, ShouldBeNil)
+			So(decoded, ShouldHaveLength, 1)
+			So(decoded[0].Namespace(), ShouldResemble, []string{"intel", "foo"})
+		})
+		Convey("it is refused by a processor accepting no content type snap encodes", func() {
+			_, _, err := convertRemoteContent(plugin.SnapGOBContentType, content, []string{"unknown"})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, ErrRemoteContentType.Error())
+		})
+	})
+}
+
+func TestRemoteSubscriptions(t *testing.T) {
+	Convey("Given a processor a remote task is subscribed to", t, func() {
+		c := New(GetDefaultConfig())
+		lp := &loadedPlugin{}
+		lp.Meta.Name = "foo"
+		lp.Meta.Version = 1
+		lp.Type = plugin.ProcessorPluginType
+		pool, err := c.pluginRunner.AvailablePlugins().getOrCreatePool(lp.Key())
+		So(err, ShouldBeNil)
+		pool.Subscribe("task1", strategy.BoundSubscriptionType)
+		pool.Subscribe(RemoteSubscriberPrefix+"task2", strategy.BoundSubscriptionType)
+		c.remote.used[remoteSubscription{subscriber: RemoteSubscriberPrefix + "task2", plugin: lp}] = time.Now()
+		Convey("the remote task does not keep it from being unloaded", func() {
+			So(c.DependentTasks(lp), ShouldResemble, []string{"task1"})
+		})
+		Convey("the remote task stays subscribed while it sends batches", func() {
+			c.sweepRemoteSubscriptions(time.Minute)
+			So(c.remote.used, ShouldHaveLength, 1)
+			So(pool.Subscribers(), ShouldResemble, []string{"remote/task2", "task1"})
+		})
+		Convey("the remote task is unsubscribed once idle", func() {
+			c.sweepRemoteSubscriptions(0)
+			So(c.remote.used, ShouldBeEmpty)
+			So(pool.Subscribers(), ShouldResemble, []string{"task1"})
+		})
+	})
+}
@@ -198,7 +198,7 @@ func (r *runner) startPlugin(p executablePlugin) (*availablePlugin, error) {
 	}
 
 	// build availablePlugin
-	ap, err := newAvailablePlugin(resp, r.emitter, p)
+	ap, err := newAvailablePlugin(resp, r.emitter, p, r.availablePlugins.transports)
 	if err != nil {
 		return nil, err
 	}
@@ -219,6 +219,12 @@ func (r *runner) startPlugin(p executablePlugin) (*availablePlugin, error) {
 		"available-plugin-type": ap.TypeName(),
 	}).Info("available plugin started")
 
+	if r.pluginManager != nil {
+		if lp, err := r.pluginManager.get(ap.key); err == nil && lp.Supports(plugin.FeatureCatalogUpdates) {
+			go r.watchCatalogUpdates(ap, lp)
+		}
+	}
+
 	return ap, nil
 }
 
@@ -264,7 +270,7 @@ func (r *runner) HandleGomitEvent(e gomit.Event) {
 					runnerLog.WithFields(log.Fields{
 						"_block":  "handle-events",
 						"aplugin": v.String,
-					}).Error(err.Error())
+					}).Error(e.Error())
 					return
 				}
 				pool.IncRestartCount()
@@ -514,7 +520,7 @@ func (r *runner) runPlugin(details *pluginDetails) error {
 		}
 		details.ExecPath = path.Join(tempPath, "rootfs")
 	}
-	ePlugin, err := plugin.NewExecutablePlugin(r.pluginManager.GenerateArgs(details.Exec), path.Join(details.ExecPath, details.Exec))
+	ePlugin, err := r.pluginManager.newExecutablePlugin(details)
 	if err != nil {
 		runnerLog.WithFields(log.Fields{
 			"_block": "run-plugin",
@@ -21,6 +21,7 @@ package strategy
 
 import (
 	"errors"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -67,9 +68,12 @@ type Pool interface {
 	RUnlock()
 	SelectAndKill(taskID, reason string)
 	SelectAP(taskID string) (SelectablePlugin, serror.SnapError)
+	SelectAPs(taskID string, count int) ([]SelectablePlugin, serror.SnapError)
 	Strategy() RoutingAndCaching
 	Subscribe(taskID string, subType SubscriptionType)
 	SubscriptionCount() int
+	Subscribers() []string
+	BoundSubscribers() []string
 	Unsubscribe(taskID string)
 	Version() int
 	RestartCount() int
@@ -306,6 +310,7 @@ func (p *pool) SelectAndKill(taskID, reason string) {
 			"taskID": taskID,
 			"reason": reason,
 		}).Error(err)
+		return
 	}
 	if err := rp.Kill(reason); err != nil {
 		log.WithFields(log.Fields{
@@ -346,6 +351,33 @@ func (p *pool) SubscriptionCount() int {
 	return len(p.subs)
 }
 
+// Subscribers returns the sorted IDs of the tasks subscribed to the pool
+func (p *pool) Subscribers() []string {
+	p.RLock()
+	defer p.RUnlock()
+	ids := make([]string, 0, len(p.subs))
+	for id := range p.subs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// BoundSubscribers returns the sorted IDs of the tasks subscribed to the
+// pool's version explicitly
+func (p *pool) BoundSubscribers() []string {
+	p.RLock()
+	defer p.RUnlock()
+	ids := []string{}
+	for id, sub := range p.subs {
+		if sub.SubType == BoundSubscriptionType {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 // SelectAP selects an available plugin from the pool
 func (p *pool) SelectAP(taskID string) (SelectablePlugin, serror.SnapError) {
 	p.RLock()
@@ -364,6 +396,36 @@ func (p *pool) SelectAP(taskID string) (SelectablePlugin, serror.SnapError) {
 	return sap, nil
 }
 
+// SelectAPs selects up to count distinct available plugins from the pool,
+// each in turn by the strategy among the plugins not yet selected
+func (p *pool) SelectAPs(taskID string, count int) ([]SelectablePlugin, serror.SnapError) {
+	p.RLock()
+	defer p.RUnlock()
+
+	sp := make([]SelectablePlugin, 0, len(p.plugins))
+	for _, plg := range p.plugins {
+		sp = append(sp, plg)
+	}
+	saps := make([]SelectablePlugin, 0, count)
+	for len(saps) < count && len(sp) > 0 {
+		sap, err := p.Select(sp, taskID)
+		if err != nil || sap == nil {
+			return nil, serror.New(err)
+		}
+		i := 0
+		for i < len(sp) && sp[i] != sap {
+			i++
+		}
+		if i == len(sp) {
+			// the strategy selects the same plugin again
+			break
+		}
+		saps = append(saps, sap)
+		sp = append(sp[:i], sp[i+1:]...)
+	}
+	return saps, nil
+}
+
 // generatePID returns the next available pid for the pool
 func (p *pool) generatePID() uint32 {
 	atomic.AddUint32(&p.pidCounter, 1)
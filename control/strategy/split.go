@@ -0,0 +1,157 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// DefaultSplitter is the name of the splitter used when none is configured
+const DefaultSplitter = "contiguous"
+
+var (
+	// ErrUnknownSplitter is returned when no splitter is registered under a name
+	ErrUnknownSplitter = errors.New("unknown split strategy")
+)
+
+// Splitter splits the metrics a task collects from a plugin into parts
+// collected in parallel by the instances of its pool
+type Splitter interface {
+	// Split returns at most n parts holding all the metrics, some of which
+	// may be empty
+	Split(metrics []core.Metric, n int) [][]core.Metric
+	String() string
+}
+
+var splitters = struct {
+	sync.RWMutex
+	table map[string]Splitter
+}{table: map[string]Splitter{}}
+
+func init() {
+	RegisterSplitter(&contiguous{})
+	RegisterSplitter(&roundRobin{})
+	RegisterSplitter(&namespaceHash{})
+}
+
+// RegisterSplitter makes a splitter available under its name
+func RegisterSplitter(s Splitter) error {
+	splitters.Lock()
+	defer splitters.Unlock()
+	if s.String() == "" {
+		return errors.New("split strategy must have a name")
+	}
+	if _, ok := splitters.table[s.String()]; ok {
+		return fmt.Errorf("split strategy %s is already registered", s.String())
+	}
+	splitters.table[s.String()] = s
+	return nil
+}
+
+// GetSplitter returns the splitter registered under a name
+func GetSplitter(name string) (Splitter, error) {
+	splitters.RLock()
+	defer splitters.RUnlock()
+	s, ok := splitters.table[name]
+	if !ok {
+		return nil, fmt.Errorf("%v: %s", ErrUnknownSplitter, name)
+	}
+	return s, nil
+}
+
+// Splitters returns the names of the registered splitters
+func Splitters() []string {
+	splitters.RLock()
+	defer splitters.RUnlock()
+	names := make([]string, 0, len(splitters.table))
+	for name := range splitters.table {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// contiguous splits the metrics sorted by namespace into ranges of equal
+// size, so the metrics sharing a namespace prefix mostly go together
+type contiguous struct{}
+
+func (c *contiguous) String() string {
+	return "contiguous"
+}
+
+func (c *contiguous) Split(metrics []core.Metric, n int) [][]core.Metric {
+	sorted := make([]core.Metric, len(metrics))
+	copy(sorted, metrics)
+	sort.Sort(byNamespace(sorted))
+	parts := make([][]core.Metric, n)
+	for i := range parts {
+		parts[i] = sorted[i*len(sorted)/n : (i+1)*len(sorted)/n]
+	}
+	return parts
+}
+
+// roundRobin deals the metrics out to the parts in turn
+type roundRobin struct{}
+
+func (r *roundRobin) String() string {
+	return "round-robin"
+}
+
+func (r *roundRobin) Split(metrics []core.Metric, n int) [][]core.Metric {
+	parts := make([][]core.Metric, n)
+	for i, m := range metrics {
+		parts[i%n] = append(parts[i%n], m)
+	}
+	return parts
+}
+
+// namespaceHash puts a metric in the part given by the hash of its
+// namespace, so each instance keeps collecting the same metrics while the
+// size of the pool does not change
+type namespaceHash struct{}
+
+func (h *namespaceHash) String() string {
+	return "namespace-hash"
+}
+
+func (h *namespaceHash) Split(metrics []core.Metric, n int) [][]core.Metric {
+	parts := make([][]core.Metric, n)
+	for _, m := range metrics {
+		f := fnv.New32a()
+		f.Write([]byte(core.JoinNamespace(m.Namespace())))
+		i := f.Sum32() % uint32(n)
+		parts[i] = append(parts[i], m)
+	}
+	return parts
+}
+
+type byNamespace []core.Metric
+
+func (b byNamespace) Len() int      { return len(b) }
+func (b byNamespace) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byNamespace) Less(i, j int) bool {
+	return core.JoinNamespace(b[i].Namespace()) < core.JoinNamespace(b[j].Namespace())
+}
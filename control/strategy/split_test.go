@@ -0,0 +1,74 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package strategy
+
+import (
+	"testing"
+
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSplitters(t *testing.T) {
+	mts := []core.Metric{
+		newMockMetricType("intel/mock/e"),
+		newMockMetricType("intel/mock/a"),
+		newMockMetricType("intel/mock/d"),
+		newMockMetricType("intel/mock/b"),
+		newMockMetricType("intel/mock/c"),
+	}
+	Convey("Given the registered splitters", t, func() {
+		So(Splitters(), ShouldResemble, []string{"contiguous", "namespace-hash", "round-robin"})
+		_, err := GetSplitter("random")
+		So(err, ShouldNotBeNil)
+		Convey("a splitter is registered once", func() {
+			s, err := GetSplitter(DefaultSplitter)
+			So(err, ShouldBeNil)
+			So(RegisterSplitter(s), ShouldNotBeNil)
+		})
+		Convey("contiguous splits the sorted metrics in ranges", func() {
+			s, _ := GetSplitter("contiguous")
+			parts := s.Split(mts, 2)
+			So(parts, ShouldHaveLength, 2)
+			So(parts[0], ShouldResemble, []core.Metric{mts[1], mts[3]})
+			So(parts[1], ShouldResemble, []core.Metric{mts[4], mts[2], mts[0]})
+		})
+		Convey("round-robin deals the metrics out in turn", func() {
+			s, _ := GetSplitter("round-robin")
+			parts := s.Split(mts, 2)
+			So(parts[0], ShouldResemble, []core.Metric{mts[0], mts[2], mts[4]})
+			So(parts[1], ShouldResemble, []core.Metric{mts[1], mts[3]})
+		})
+		Convey("namespace-hash always puts a metric in the same part", func() {
+			s, _ := GetSplitter("namespace-hash")
+			parts := s.Split(mts, 3)
+			again := s.Split([]core.Metric{mts[4], mts[3], mts[2], mts[1], mts[0]}, 3)
+			total := 0
+			for i := range parts {
+				total += len(parts[i])
+				So(again[i], ShouldHaveLength, len(parts[i]))
+				for _, m := range parts[i] {
+					So(again[i], ShouldContain, m)
+				}
+			}
+			So(total, ShouldEqual, len(mts))
+		})
+	})
+}
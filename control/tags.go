@@ -0,0 +1,185 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+)
+
+// The values of the tags in the configuration can be taken from the host
+// instead of being given literally.
+const (
+	// TagSourceHostname is replaced by the hostname of snapd
+	TagSourceHostname = "$hostname"
+	// TagSourceEC2 followed by a path, e.g. $ec2:placement/availability-zone,
+	// is replaced by the EC2 instance metadata at that path
+	TagSourceEC2 = "$ec2:"
+	// TagSourceGCE followed by a path, e.g. $gce:instance/zone, is replaced
+	// by the GCE instance metadata at that path
+	TagSourceGCE = "$gce:"
+)
+
+// The tags added to every metric when snapd runs in a Kubernetes pod, taken
+// from the environment variables the downward API is expected to set
+var downwardAPITags = map[string]string{
+	"kubernetes_pod":       "POD_NAME",
+	"kubernetes_namespace": "POD_NAMESPACE",
+	"kubernetes_node":      "NODE_NAME",
+}
+
+var (
+	ec2MetadataURL  = "http://169.254.169.254/latest/meta-data/"
+	gceMetadataURL  = "http://metadata.google.internal/computeMetadata/v1/"
+	metadataTimeout = 2 * time.Second
+)
+
+// validTagValue returns an error when the value of a configured tag refers
+// to a source which does not exist.
+func validTagValue(v string) error {
+	if !strings.HasPrefix(v, "$") {
+		return nil
+	}
+	switch {
+	case v == TagSourceHostname:
+	case strings.HasPrefix(v, TagSourceEC2) && len(v) > len(TagSourceEC2):
+	case strings.HasPrefix(v, TagSourceGCE) && len(v) > len(TagSourceGCE):
+	default:
+		return fmt.Errorf("%q is not one of %s, %s<path> or %s<path>", v, TagSourceHostname, TagSourceEC2, TagSourceGCE)
+	}
+	return nil
+}
+
+// resolveTags returns the tags with the values taken from the host resolved.
+// Tags whose value cannot be resolved are left out.
+func resolveTags(tags map[string]string) map[string]string {
+	resolved := make(map[string]string, len(tags))
+	for k, v := range tags {
+		rv, err := resolveTagValue(v)
+		if err != nil {
+			controlLogger.WithFields(log.Fields{
+				"_block": "resolve-tags",
+				"tag":    k,
+				"value":  v,
+			}).Warn("tag left out: ", err)
+			continue
+		}
+		resolved[k] = rv
+	}
+	return resolved
+}
+
+// addDownwardAPITags adds the tags of the downward API environment variables
+// set, the tags configured taking precedence.
+func addDownwardAPITags(tags map[string]string) {
+	for k, env := range downwardAPITags {
+		v := os.Getenv(env)
+		if v == "" {
+			continue
+		}
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+}
+
+func resolveTagValue(v string) (string, error) {
+	switch {
+	case v == TagSourceHostname:
+		return os.Hostname()
+	case strings.HasPrefix(v, TagSourceEC2):
+		return fetchMetadata(ec2MetadataURL+strings.TrimPrefix(v, TagSourceEC2), nil)
+	case strings.HasPrefix(v, TagSourceGCE):
+		return fetchMetadata(gceMetadataURL+strings.TrimPrefix(v, TagSourceGCE), map[string]string{"Metadata-Flavor": "Google"})
+	}
+	return v, nil
+}
+
+func fetchMetadata(url string, header map[string]string) (string, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	c := &http.Client{Timeout: metadataTimeout}
+	rsp, err := c.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata %s: %s", url, rsp.Status)
+	}
+	b, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// addTags adds the tags to the metrics, the tags set by the plugins taking
+// precedence.
+func addTags(mts []core.Metric, tags map[string]string) {
+	if len(tags) == 0 {
+		return
+	}
+	for i, m := range mts {
+		mt, ok := m.(plugin.PluginMetricType)
+		if !ok {
+			continue
+		}
+		merged := make(map[string]string, len(tags)+len(mt.Tags_))
+		for k, v := range tags {
+			merged[k] = v
+		}
+		for k, v := range mt.Tags_ {
+			merged[k] = v
+		}
+		mt.Tags_ = merged
+		mts[i] = mt
+	}
+}
+
+// addHost sets the host of the metrics, and their source to its hostname
+// when the plugins did not set one.
+func addHost(mts []core.Metric, h core.Host) {
+	for i, m := range mts {
+		mt, ok := m.(plugin.PluginMetricType)
+		if !ok {
+			continue
+		}
+		mt.Host_ = h
+		if mt.Source_ == "" {
+			mt.Source_ = h.String()
+		}
+		mts[i] = mt
+	}
+}
@@ -0,0 +1,136 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+)
+
+func TestResolveTags(t *testing.T) {
+	Convey("resolveTags", t, func() {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/ec2/placement/availability-zone":
+				fmt.Fprint(w, "us-east-1a\n")
+			case "/gce/instance/zone":
+				if r.Header.Get("Metadata-Flavor") != "Google" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				fmt.Fprint(w, "projects/1/zones/europe-west1-b")
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer ts.Close()
+		ec2, gce := ec2MetadataURL, gceMetadataURL
+		ec2MetadataURL, gceMetadataURL = ts.URL+"/ec2/", ts.URL+"/gce/"
+		defer func() {
+			ec2MetadataURL, gceMetadataURL = ec2, gce
+		}()
+
+		hostname, err := os.Hostname()
+		So(err, ShouldBeNil)
+		tags := resolveTags(map[string]string{
+			"datacenter": "dc1",
+			"host":       TagSourceHostname,
+			"ec2_zone":   TagSourceEC2 + "placement/availability-zone",
+			"gce_zone":   TagSourceGCE + "instance/zone",
+			"missing":    TagSourceEC2 + "instance-id",
+		})
+		So(tags, ShouldResemble, map[string]string{
+			"datacenter": "dc1",
+			"host":       hostname,
+			"ec2_zone":   "us-east-1a",
+			"gce_zone":   "projects/1/zones/europe-west1-b",
+		})
+	})
+}
+
+func TestAddDownwardAPITags(t *testing.T) {
+	Convey("addDownwardAPITags", t, func() {
+		for _, env := range []string{"POD_NAME", "POD_NAMESPACE", "NODE_NAME"} {
+			defer os.Setenv(env, os.Getenv(env))
+		}
+		os.Setenv("POD_NAME", "snap-x7k2p")
+		os.Setenv("POD_NAMESPACE", "monitoring")
+		os.Setenv("NODE_NAME", "")
+		tags := map[string]string{"kubernetes_namespace": "ops"}
+		addDownwardAPITags(tags)
+		Convey("adds the tags of the variables set, the configured tags taking precedence", func() {
+			So(tags, ShouldResemble, map[string]string{
+				"kubernetes_pod":       "snap-x7k2p",
+				"kubernetes_namespace": "ops",
+			})
+		})
+	})
+}
+
+func TestAddTags(t *testing.T) {
+	Convey("addTags", t, func() {
+		mts := []core.Metric{
+			plugin.PluginMetricType{Namespace_: []string{"foo", "bar"}},
+			plugin.PluginMetricType{
+				Namespace_: []string{"foo", "baz"},
+				Tags_:      map[string]string{"host": "collected-host", "cpu": "0"},
+			},
+		}
+		tags := map[string]string{"host": "snapd-host", "datacenter": "dc1"}
+		addTags(mts, tags)
+		Convey("adds the tags to every metric", func() {
+			So(mts[0].Tags(), ShouldResemble, tags)
+		})
+		Convey("keeps the tags set by the plugin", func() {
+			So(mts[1].Tags(), ShouldResemble, map[string]string{"host": "collected-host", "cpu": "0", "datacenter": "dc1"})
+		})
+		Convey("does not alter the configured tags", func() {
+			So(tags, ShouldResemble, map[string]string{"host": "snapd-host", "datacenter": "dc1"})
+		})
+	})
+}
+
+func TestAddHost(t *testing.T) {
+	Convey("addHost", t, func() {
+		mts := []core.Metric{
+			plugin.PluginMetricType{Namespace_: []string{"foo", "bar"}},
+			plugin.PluginMetricType{Namespace_: []string{"foo", "baz"}, Source_: "switch-12"},
+		}
+		h := core.Host{Hostname: "snapd-host", IP: "10.0.0.1", AgentID: "id", Member: "member-1"}
+		addHost(mts, h)
+		Convey("sets the host of every metric", func() {
+			for _, m := range mts {
+				So(m.(core.HostedMetric).Host(), ShouldResemble, h)
+			}
+		})
+		Convey("sets the source to the hostname when the plugin did not", func() {
+			So(mts[0].Source(), ShouldEqual, "snapd-host")
+			So(mts[1].Source(), ShouldEqual, "switch-12")
+		})
+	})
+}
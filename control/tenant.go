@@ -0,0 +1,247 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+)
+
+// ErrUnknownTenant is returned for a tenant missing from control.tenants
+var ErrUnknownTenant = errors.New("unknown tenant")
+
+// TenantConfig scopes the tasks of a tenant, so that teams sharing snapd do
+// not interfere with each other: the tasks of a tenant only see and collect
+// the metrics of its namespaces, and run the plugins with its config.
+type TenantConfig struct {
+	// Namespaces are the namespaces, wildcards allowed, of the metrics the
+	// tenant sees, all of them when empty
+	Namespaces []string `json:"namespaces,omitempty"yaml:"namespaces,omitempty"`
+	// Plugins is the config of the plugins run by the tasks of the tenant,
+	// structured as control.plugins and taking precedence over it
+	Plugins *pluginConfig `json:"plugins,omitempty"yaml:"plugins,omitempty"`
+	// Quota bounds the tasks of the tenant, none when nil
+	Quota *core.Quota `json:"quota,omitempty"yaml:"quota,omitempty"`
+}
+
+// UnmarshalJSON unmarshals the config of a tenant, its plugins config being
+// empty when left out
+func (t *TenantConfig) UnmarshalJSON(data []byte) error {
+	tc := struct {
+		Namespaces []string        `json:"namespaces"`
+		Plugins    json.RawMessage `json:"plugins"`
+		Quota      *core.Quota     `json:"quota"`
+	}{}
+	if err := json.Unmarshal(data, &tc); err != nil {
+		return err
+	}
+	t.Namespaces = tc.Namespaces
+	t.Quota = tc.Quota
+	t.Plugins = newPluginConfig()
+	if len(tc.Plugins) > 0 && string(tc.Plugins) != "null" {
+		return t.Plugins.UnmarshalJSON(tc.Plugins)
+	}
+	return nil
+}
+
+// validateTenants returns the problems found in the tenants of the config
+func validateTenants(tenants map[string]*TenantConfig) []error {
+	var errs []error
+	for id, t := range tenants {
+		if id == "" {
+			errs = append(errs, fmt.Errorf("control.tenants: tenant IDs must not be empty"))
+			continue
+		}
+		if t == nil {
+			continue
+		}
+		for _, ns := range t.Namespaces {
+			if _, err := compileTenantNamespace(ns); err != nil {
+				errs = append(errs, fmt.Errorf("control.tenants.%s.namespaces: %v", id, err))
+			}
+		}
+		errs = append(errs, t.Quota.Validate("control.tenants."+id+".quota")...)
+	}
+	return errs
+}
+
+func compileTenantNamespace(ns string) (*core.WildcardNamespace, error) {
+	if !strings.HasPrefix(ns, "/") || len(ns) < 2 {
+		return nil, fmt.Errorf("Invalid namespace %q: must start with /", ns)
+	}
+	parsed := strings.Split(strings.Trim(ns, "/"), "/")
+	for _, e := range parsed {
+		if e == "" {
+			return nil, fmt.Errorf("Invalid namespace %q: empty element", ns)
+		}
+	}
+	return core.CompileWildcardNamespace(parsed)
+}
+
+// tenantScope is the compiled config of a tenant
+type tenantScope struct {
+	namespaces []*core.WildcardNamespace
+	plugins    *pluginConfig
+	quota      *core.Quota
+}
+
+// sees returns whether the namespace is one of the tenant's
+func (s *tenantScope) sees(ns []string) bool {
+	if len(s.namespaces) == 0 {
+		return true
+	}
+	for _, w := range s.namespaces {
+		if w.Match(ns) {
+			return true
+		}
+	}
+	return false
+}
+
+// tenantScopes holds the scopes of the tenants and the tenant of each task
+// scoped to one
+type tenantScopes struct {
+	sync.RWMutex
+	scopes map[string]*tenantScope
+	tasks  map[string]string
+}
+
+func newTenantScopes(tenants map[string]*TenantConfig) *tenantScopes {
+	ts := &tenantScopes{
+		scopes: make(map[string]*tenantScope, len(tenants)),
+		tasks:  map[string]string{},
+	}
+	for id, t := range tenants {
+		scope := &tenantScope{plugins: newPluginConfig()}
+		if t != nil {
+			for _, ns := range t.Namespaces {
+				// the namespaces are checked by the validation of the config
+				if w, err := compileTenantNamespace(ns); err == nil {
+					scope.namespaces = append(scope.namespaces, w)
+				}
+			}
+			if t.Plugins != nil {
+				scope.plugins = t.Plugins
+			}
+			scope.quota = t.Quota
+		}
+		ts.scopes[id] = scope
+	}
+	return ts
+}
+
+// taskScope returns the scope of the tenant of the task, nil when the task
+// is not scoped
+func (ts *tenantScopes) taskScope(taskID string) *tenantScope {
+	if ts == nil {
+		return nil
+	}
+	ts.RLock()
+	defer ts.RUnlock()
+	tenant, ok := ts.tasks[taskID]
+	if !ok {
+		return nil
+	}
+	return ts.scopes[tenant]
+}
+
+// scope returns the scope of the tenant
+func (ts *tenantScopes) scope(tenant string) (*tenantScope, bool) {
+	if ts == nil {
+		return nil, false
+	}
+	ts.RLock()
+	defer ts.RUnlock()
+	scope, ok := ts.scopes[tenant]
+	return scope, ok
+}
+
+// SetTaskTenant scopes the task to the tenant, or unscopes it when the
+// tenant is empty
+func (p *pluginControl) SetTaskTenant(taskID, tenant string) error {
+	if tenant == "" {
+		if p.tenants != nil {
+			p.tenants.Lock()
+			delete(p.tenants.tasks, taskID)
+			p.tenants.Unlock()
+		}
+		return nil
+	}
+	if _, ok := p.tenants.scope(tenant); !ok {
+		return fmt.Errorf("%v: %s", ErrUnknownTenant, tenant)
+	}
+	p.tenants.Lock()
+	defer p.tenants.Unlock()
+	p.tenants.tasks[taskID] = tenant
+	return nil
+}
+
+// MetricVisible returns whether the tenant sees the metrics of the
+// namespace, which every metric is to snapd itself (an empty tenant). An
+// unknown tenant sees none.
+func (p *pluginControl) MetricVisible(tenant string, ns []string) bool {
+	if tenant == "" {
+		return true
+	}
+	scope, ok := p.tenants.scope(tenant)
+	return ok && scope.sees(ns)
+}
+
+// TenantQuota returns the quota of the tenant, nil when it has none or is
+// unknown
+func (p *pluginControl) TenantQuota(tenant string) *core.Quota {
+	if scope, ok := p.tenants.scope(tenant); ok {
+		return scope.quota
+	}
+	return nil
+}
+
+// visibleMetrics returns the metrics the tenant of the task sees
+func visibleMetrics(scope *tenantScope, mts []core.Metric) []core.Metric {
+	if scope == nil || len(scope.namespaces) == 0 {
+		return mts
+	}
+	visible := make([]core.Metric, 0, len(mts))
+	for _, mt := range mts {
+		if scope.sees(mt.Namespace()) {
+			visible = append(visible, mt)
+		}
+	}
+	return visible
+}
+
+// tenantPluginConfig returns the config the tenant of the task runs the
+// plugin with, nil when the task is not scoped
+func (p *pluginControl) tenantPluginConfig(taskID string, pluginType core.PluginType, name string, ver int) *cdata.ConfigDataNode {
+	scope := p.tenants.taskScope(taskID)
+	if scope == nil {
+		return nil
+	}
+	p.tenants.Lock()
+	defer p.tenants.Unlock()
+	// the plugins config caches what it merges
+	return scope.plugins.getPluginConfigDataNode(pluginType, name, ver)
+}
@@ -0,0 +1,93 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+func TestTenants(t *testing.T) {
+	Convey("Given the config of tenants", t, func() {
+		cfg := GetDefaultConfig()
+		So(json.Unmarshal([]byte(`{
+			"team-a": {
+				"namespaces": ["/intel/psutil/*"],
+				"plugins": {
+					"collector": {"psutil": {"all": {"user": "team-a"}}},
+					"publisher": {"influx": {"all": {"database": "team_a"}}}
+				},
+				"quota": {"max_tasks": 10, "max_metrics": 500}
+			},
+			"team-b": {}
+		}`), &cfg.Tenants), ShouldBeNil)
+		So(cfg.Validate(), ShouldBeEmpty)
+		c := New(cfg)
+
+		Convey("a tenant sees the metrics of its namespaces", func() {
+			So(c.MetricVisible("team-a", []string{"intel", "psutil", "load", "load1"}), ShouldBeTrue)
+			So(c.MetricVisible("team-a", []string{"intel", "docker", "cpu"}), ShouldBeFalse)
+			So(c.MetricVisible("team-b", []string{"intel", "docker", "cpu"}), ShouldBeTrue)
+			So(c.MetricVisible("", []string{"intel", "docker", "cpu"}), ShouldBeTrue)
+			So(c.MetricVisible("team-c", []string{"intel", "docker", "cpu"}), ShouldBeFalse)
+		})
+		Convey("a tenant has its quota", func() {
+			So(c.TenantQuota("team-a"), ShouldResemble, &core.Quota{MaxTasks: 10, MaxMetrics: 500})
+			So(c.TenantQuota("team-b"), ShouldBeNil)
+			So(c.TenantQuota("team-c"), ShouldBeNil)
+		})
+		Convey("a task is scoped to a known tenant", func() {
+			So(c.SetTaskTenant("task-1", "team-a"), ShouldBeNil)
+			So(c.SetTaskTenant("task-2", "team-c"), ShouldNotBeNil)
+			mts := visibleMetrics(c.tenants.taskScope("task-1"), []core.Metric{
+				&metricType{namespace: []string{"intel", "psutil", "load", "load1"}},
+				&metricType{namespace: []string{"intel", "docker", "cpu"}},
+			})
+			So(mts, ShouldHaveLength, 1)
+			So(mts[0].Namespace(), ShouldResemble, []string{"intel", "psutil", "load", "load1"})
+
+			Convey("and runs the plugins with the config of the tenant", func() {
+				cdn := c.tenantPluginConfig("task-1", core.CollectorPluginType, "psutil", 1)
+				So(cdn, ShouldNotBeNil)
+				So(cdn.Table()["user"], ShouldResemble, ctypes.ConfigValueStr{Value: "team-a"})
+				cdn = c.tenantPluginConfig("task-1", core.PublisherPluginType, "influx", 2)
+				So(cdn.Table()["database"], ShouldResemble, ctypes.ConfigValueStr{Value: "team_a"})
+				So(c.tenantPluginConfig("task-2", core.CollectorPluginType, "psutil", 1), ShouldBeNil)
+			})
+			Convey("until it is unscoped", func() {
+				So(c.SetTaskTenant("task-1", ""), ShouldBeNil)
+				So(c.tenants.taskScope("task-1"), ShouldBeNil)
+			})
+		})
+	})
+	Convey("The namespaces and quota of a tenant must be valid", t, func() {
+		errs := validateTenants(map[string]*TenantConfig{
+			"team-a": {Namespaces: []string{"intel/psutil"}},
+			"team-b": {Quota: &core.Quota{MaxTasks: -1}},
+			"":       {},
+		})
+		So(errs, ShouldHaveLength, 3)
+	})
+}
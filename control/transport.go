@@ -0,0 +1,138 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/client"
+	"github.com/intelsdi-x/snap/control/plugin/encoding"
+	"github.com/intelsdi-x/snap/core"
+)
+
+// PluginTransport holds the settings of the transport of the messages
+// exchanged with a plugin
+type PluginTransport struct {
+	// MaxMessageSize is the max size in bytes of the metric batches, none
+	// when 0
+	MaxMessageSize int `json:"max_message_size,omitempty"yaml:"max_message_size,omitempty"`
+	// Compression is the compression of the messages, none when empty
+	Compression string `json:"compression,omitempty"yaml:"compression,omitempty"`
+}
+
+// validate returns the problems found in the settings of the transport
+func (t *PluginTransport) validate() []error {
+	var errs []error
+	if t.MaxMessageSize < 0 {
+		errs = append(errs, fmt.Errorf("max_message_size: must not be negative"))
+	}
+	if !encoding.ValidCompression(t.Compression) {
+		errs = append(errs, fmt.Errorf("compression: %q is not one of %s", t.Compression, strings.Join(encoding.Compressions, ", ")))
+	}
+	return errs
+}
+
+// transportKey validates the key of the transport settings of a plugin,
+// <type>:<name>
+func transportKey(key string) error {
+	tn := strings.Split(key, ":")
+	if len(tn) != 2 || tn[1] == "" {
+		return fmt.Errorf("%q is not <type>:<name>", key)
+	}
+	if _, err := core.ToPluginType(tn[0]); err != nil {
+		return fmt.Errorf("%q: %v", key, err)
+	}
+	return nil
+}
+
+// PluginTransports holds the transport settings of plugins by <type>:<name>
+type PluginTransports map[string]*PluginTransport
+
+// transportSettings holds the settings of the transport to the plugins
+// started from now on: the size of the chunks the metric batches are
+// transferred in, the settings of the connections, and the max message size
+// and compression, the ones given for a plugin taking precedence over the
+// defaults
+type transportSettings struct {
+	sync.RWMutex
+	chunkSize int
+	conn      client.ConnSettings
+	defaults  PluginTransport
+	plugins   PluginTransports
+}
+
+func newTransportSettings() *transportSettings {
+	return &transportSettings{
+		chunkSize: plugin.DefaultChunkSize,
+		conn:      client.DefaultConnSettings(),
+	}
+}
+
+// setChunkSize sets the size in bytes of the chunks the metric batches
+// larger than it are transferred in, never when 0
+func (t *transportSettings) setChunkSize(size int) {
+	t.Lock()
+	defer t.Unlock()
+	t.chunkSize = size
+}
+
+func (t *transportSettings) getChunkSize() int {
+	t.RLock()
+	defer t.RUnlock()
+	return t.chunkSize
+}
+
+func (t *transportSettings) setConn(cs client.ConnSettings) {
+	t.Lock()
+	defer t.Unlock()
+	t.conn = cs
+}
+
+func (t *transportSettings) getConn() client.ConnSettings {
+	t.RLock()
+	defer t.RUnlock()
+	return t.conn
+}
+
+func (t *transportSettings) set(defaults PluginTransport, plugins PluginTransports) {
+	t.Lock()
+	defer t.Unlock()
+	t.defaults = defaults
+	t.plugins = plugins
+}
+
+// get returns the transport settings of a plugin
+func (t *transportSettings) get(pluginType plugin.PluginType, name string) PluginTransport {
+	t.RLock()
+	defer t.RUnlock()
+	pt := t.defaults
+	if p, ok := t.plugins[pluginType.String()+":"+name]; ok && p != nil {
+		if p.MaxMessageSize > 0 {
+			pt.MaxMessageSize = p.MaxMessageSize
+		}
+		if p.Compression != "" {
+			pt.Compression = p.Compression
+		}
+	}
+	return pt
+}
@@ -0,0 +1,46 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPluginTransports(t *testing.T) {
+	Convey("Given transport settings for a plugin", t, func() {
+		ts := &transportSettings{}
+		ts.set(PluginTransport{MaxMessageSize: 1 << 20, Compression: "gzip"}, PluginTransports{
+			"collector:psutil": {MaxMessageSize: 1 << 28},
+			"publisher:influx": {Compression: "none"},
+			"collector:mock":   nil,
+		})
+		Convey("the plugin gets its settings over the defaults", func() {
+			So(ts.get(plugin.CollectorPluginType, "psutil"), ShouldResemble, PluginTransport{MaxMessageSize: 1 << 28, Compression: "gzip"})
+			So(ts.get(plugin.PublisherPluginType, "influx"), ShouldResemble, PluginTransport{MaxMessageSize: 1 << 20, Compression: "none"})
+		})
+		Convey("the other plugins get the defaults", func() {
+			So(ts.get(plugin.ProcessorPluginType, "psutil"), ShouldResemble, PluginTransport{MaxMessageSize: 1 << 20, Compression: "gzip"})
+			So(ts.get(plugin.CollectorPluginType, "mock"), ShouldResemble, PluginTransport{MaxMessageSize: 1 << 20, Compression: "gzip"})
+		})
+	})
+}
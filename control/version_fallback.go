@@ -0,0 +1,63 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+// The version fallback policies decide what happens when a task pins a
+// plugin version (e.g. metric version 3) which is not loaded.
+const (
+	// VersionFallbackFail fails the task (the default)
+	VersionFallbackFail = "fail"
+	// VersionFallbackCompatible substitutes the latest loaded version newer
+	// than the pinned one, as newer plugins are expected to keep serving the
+	// metrics of earlier versions
+	VersionFallbackCompatible = "compatible"
+	// VersionFallbackAny substitutes the latest loaded version
+	VersionFallbackAny = "any"
+)
+
+// VersionFallbackPolicies lists the valid version fallback policies
+var VersionFallbackPolicies = []string{VersionFallbackFail, VersionFallbackCompatible, VersionFallbackAny}
+
+func validVersionFallback(policy string) bool {
+	for _, p := range VersionFallbackPolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// fallbackVersion picks the version to use instead of the pinned version out of
+// the loaded versions. It returns false if the policy does not allow any of them.
+func fallbackVersion(policy string, pinned int, loaded []int) (int, bool) {
+	if policy != VersionFallbackCompatible && policy != VersionFallbackAny {
+		return 0, false
+	}
+	ver := -1
+	for _, v := range loaded {
+		if v == pinned || (policy == VersionFallbackCompatible && v < pinned) {
+			continue
+		}
+		if v > ver {
+			ver = v
+		}
+	}
+	return ver, ver > 0
+}
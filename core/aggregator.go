@@ -0,0 +1,58 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import "time"
+
+// Aggregator is the state of the aggregator role of snapd, which republishes
+// the metrics the tasks of the other tribe members forward to it
+type Aggregator struct {
+	Enabled bool
+	// Interval is the window the metrics are buffered for before they are
+	// deduplicated, downsampled and published
+	Interval   time.Duration
+	Dedup      bool
+	Downsample string
+	// Buffered is the number of metrics waiting for the next flush
+	Buffered int
+	// Received is the number of metrics forwarded, out of which Duplicates
+	// were dropped as duplicates
+	Received   uint64
+	Duplicates uint64
+	// Published is the number of metrics published once downsampled
+	Published uint64
+	// Dropped is the number of metrics refused while the buffer was full,
+	// or left out of it when a failed flush was buffered again
+	Dropped uint64
+	// Failures is the number of flushes a publisher failed
+	Failures  uint64
+	LastFlush time.Time
+	LastError string
+	// Sources are the principals the members which forwarded metrics
+	// authenticated as
+	Sources []AggregatorSource
+}
+
+// AggregatorSource is a principal forwarding metrics to the aggregator
+type AggregatorSource struct {
+	Principal string
+	Received  uint64
+	LastSeen  time.Time
+}
@@ -0,0 +1,221 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The severities of alert rules
+const (
+	AlertSeverityInfo     = "info"
+	AlertSeverityWarning  = "warning"
+	AlertSeverityCritical = "critical"
+)
+
+var (
+	AlertSeverities = []string{AlertSeverityInfo, AlertSeverityWarning, AlertSeverityCritical}
+
+	alertOperators = map[string]func(a, b float64) bool{
+		">":  func(a, b float64) bool { return a > b },
+		">=": func(a, b float64) bool { return a >= b },
+		"<":  func(a, b float64) bool { return a < b },
+		"<=": func(a, b float64) bool { return a <= b },
+		"==": func(a, b float64) bool { return a == b },
+		"!=": func(a, b float64) bool { return a != b },
+	}
+)
+
+// AlertRule is evaluated against the metrics collected by a task. Its
+// expression compares the value of the metrics matching a namespace, which
+// may hold the wildcards of a requested metric, to a threshold, e.g.
+// "/intel/procfs/cpu/*/utilization > 90". The rule fires once the comparison
+// holds for a metric during For.
+type AlertRule struct {
+	Name       string
+	Expression string
+	For        time.Duration
+	Severity   string
+
+	namespace *WildcardNamespace
+	compare   func(a, b float64) bool
+	threshold float64
+}
+
+// NewAlertRule parses the expression of a rule. An empty severity defaults
+// to warning.
+func NewAlertRule(name, expression string, forDuration time.Duration, severity string) (AlertRule, error) {
+	r := AlertRule{
+		Name:       name,
+		Expression: expression,
+		For:        forDuration,
+		Severity:   severity,
+	}
+	if name == "" {
+		return r, fmt.Errorf("alert rule %q has no name", expression)
+	}
+	if forDuration < 0 {
+		return r, fmt.Errorf("alert rule %s: duration must not be negative", name)
+	}
+	if r.Severity == "" {
+		r.Severity = AlertSeverityWarning
+	}
+	if !validAlertSeverity(r.Severity) {
+		return r, fmt.Errorf("alert rule %s: severity %q is not one of %v", name, r.Severity, AlertSeverities)
+	}
+	fields := strings.Fields(expression)
+	if len(fields) != 3 {
+		return r, fmt.Errorf("alert rule %s: expression %q is not of the form '<namespace> <operator> <threshold>'", name, expression)
+	}
+	compare, ok := alertOperators[fields[1]]
+	if !ok {
+		return r, fmt.Errorf("alert rule %s: unknown operator %q", name, fields[1])
+	}
+	threshold, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return r, fmt.Errorf("alert rule %s: invalid threshold %q", name, fields[2])
+	}
+	ns, err := CompileWildcardNamespace(strings.Split(strings.TrimPrefix(fields[0], "/"), "/"))
+	if err != nil {
+		return r, fmt.Errorf("alert rule %s: %v", name, err)
+	}
+	r.namespace, r.compare, r.threshold = ns, compare, threshold
+	return r, nil
+}
+
+func validAlertSeverity(severity string) bool {
+	for _, s := range AlertSeverities {
+		if s == severity {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate returns whether the rule applies to the metric, which it does if
+// the namespace matches and the data is a number, and whether the
+// comparison holds.
+func (r AlertRule) Evaluate(m Metric) (value float64, applies bool, holds bool) {
+	if r.namespace == nil || !r.namespace.Match(m.Namespace()) {
+		return 0, false, false
+	}
+	value, ok := NumericValue(m.Data())
+	if !ok {
+		return 0, false, false
+	}
+	return value, true, r.compare(value, r.threshold)
+}
+
+// NumericValue returns the data of a metric as a float64, false when the
+// data is not a number
+func NumericValue(data interface{}) (float64, bool) {
+	switch v := data.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// NumberValue returns the data of a metric which is a number, the booleans
+// being 1 or 0: as an int64 when it is an integer fitting one, isInt being
+// true, and as a float64 otherwise. ok is false for data which is not a
+// number, NaN and infinite floats included, which the sinks of metrics
+// cannot take.
+func NumberValue(data interface{}) (i int64, f float64, isInt, ok bool) {
+	switch v := data.(type) {
+	case int:
+		return int64(v), 0, true, true
+	case int8:
+		return int64(v), 0, true, true
+	case int16:
+		return int64(v), 0, true, true
+	case int32:
+		return int64(v), 0, true, true
+	case int64:
+		return v, 0, true, true
+	case uint:
+		return NumberValue(uint64(v))
+	case uint8:
+		return int64(v), 0, true, true
+	case uint16:
+		return int64(v), 0, true, true
+	case uint32:
+		return int64(v), 0, true, true
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, float64(v), false, true
+		}
+		return int64(v), 0, true, true
+	case float32:
+		return NumberValue(float64(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return 0, 0, false, false
+		}
+		return 0, v, false, true
+	case bool:
+		if v {
+			return 1, 0, true, true
+		}
+		return 0, 0, true, true
+	}
+	return 0, 0, false, false
+}
+
+// Alert is an alert rule of a task firing for a metric
+type Alert struct {
+	TaskID    string
+	TaskName  string
+	Rule      string
+	Severity  string
+	Namespace string
+	// Value is the last value of the metric
+	Value float64
+	// Since is when the expression of the rule started to hold
+	Since time.Time
+	// FiredAt is when the alert fired, after the duration of the rule
+	FiredAt time.Time
+}
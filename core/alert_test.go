@@ -0,0 +1,86 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/core/cdata"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type alertMetric struct {
+	ns   []string
+	data interface{}
+}
+
+func (m alertMetric) Namespace() []string           { return m.ns }
+func (m alertMetric) Version() int                  { return 1 }
+func (m alertMetric) Config() *cdata.ConfigDataNode { return nil }
+func (m alertMetric) LastAdvertisedTime() time.Time { return time.Time{} }
+func (m alertMetric) Data() interface{}             { return m.data }
+func (m alertMetric) Source() string                { return "" }
+func (m alertMetric) Labels() []Label               { return nil }
+func (m alertMetric) Tags() map[string]string       { return nil }
+func (m alertMetric) Timestamp() time.Time          { return time.Time{} }
+
+func TestAlertRule(t *testing.T) {
+	Convey("NewAlertRule", t, func() {
+		Convey("parses the expression", func() {
+			r, err := NewAlertRule("cpu-high", "/intel/cpu/*/utilization >= 90.5", time.Minute, "")
+			So(err, ShouldBeNil)
+			So(r.Severity, ShouldEqual, AlertSeverityWarning)
+			So(r.For, ShouldEqual, time.Minute)
+		})
+		Convey("returns an error for an invalid rule", func() {
+			for _, c := range []struct{ name, expr, severity string }{
+				{"", "/intel/mock/foo > 1", ""},
+				{"r", "/intel/mock/foo >", ""},
+				{"r", "/intel/mock/foo ~ 1", ""},
+				{"r", "/intel/mock/foo > high", ""},
+				{"r", "/intel/mock/(foo > 1", ""},
+				{"r", "/intel/mock/foo > 1", "fatal"},
+			} {
+				_, err := NewAlertRule(c.name, c.expr, 0, c.severity)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+	Convey("Evaluate", t, func() {
+		r, err := NewAlertRule("cpu-high", "/intel/cpu/*/utilization > 90", 0, AlertSeverityCritical)
+		So(err, ShouldBeNil)
+		Convey("compares the numbers of the metrics matching the namespace", func() {
+			v, applies, holds := r.Evaluate(alertMetric{ns: []string{"intel", "cpu", "0", "utilization"}, data: int64(95)})
+			So(applies, ShouldBeTrue)
+			So(holds, ShouldBeTrue)
+			So(v, ShouldEqual, 95)
+			_, applies, holds = r.Evaluate(alertMetric{ns: []string{"intel", "cpu", "1", "utilization"}, data: float32(12.5)})
+			So(applies, ShouldBeTrue)
+			So(holds, ShouldBeFalse)
+		})
+		Convey("does not apply to other metrics", func() {
+			_, applies, _ := r.Evaluate(alertMetric{ns: []string{"intel", "cpu", "0", "idle"}, data: 95})
+			So(applies, ShouldBeFalse)
+			_, applies, _ = r.Evaluate(alertMetric{ns: []string{"intel", "cpu", "0", "utilization"}, data: "95"})
+			So(applies, ShouldBeFalse)
+		})
+	})
+}
@@ -70,28 +70,45 @@ func (c *ConfigDataNode) UnmarshalJSON(data []byte) error {
 	}
 
 	for k, i := range t {
-		switch t := i.(type) {
-		case string:
-			c.table[k] = ctypes.ConfigValueStr{Value: t}
-		case bool:
-			c.table[k] = ctypes.ConfigValueBool{Value: t}
-		case json.Number:
-			if v, err := t.Int64(); err == nil {
-				c.table[k] = ctypes.ConfigValueInt{Value: int(v)}
-				continue
-			}
-			if v, err := t.Float64(); err == nil {
-				c.table[k] = ctypes.ConfigValueFloat{Value: v}
-				continue
-			}
-		default:
-			return fmt.Errorf("Error Unmarshalling JSON ConfigDataNode. Key: %v Type: %v is unsupported.", k, t)
+		v, ok := fromJSON(i)
+		if !ok {
+			return fmt.Errorf("Error Unmarshalling JSON ConfigDataNode. Key: %v Type: %v is unsupported.", k, i)
 		}
+		c.table[k] = v
 	}
 	c.mutex = new(sync.Mutex)
 	return nil
 }
 
+// fromJSON converts a value decoded from JSON into a config value. Durations
+// are marshalled as strings and come back as such.
+func fromJSON(i interface{}) (ctypes.ConfigValue, bool) {
+	switch t := i.(type) {
+	case string:
+		return ctypes.ConfigValueStr{Value: t}, true
+	case bool:
+		return ctypes.ConfigValueBool{Value: t}, true
+	case json.Number:
+		if v, err := t.Int64(); err == nil {
+			return ctypes.ConfigValueInt{Value: int(v)}, true
+		}
+		if v, err := t.Float64(); err == nil {
+			return ctypes.ConfigValueFloat{Value: v}, true
+		}
+	case []interface{}:
+		l := ctypes.ConfigValueList{Value: make([]ctypes.ConfigValue, len(t))}
+		for n, e := range t {
+			v, ok := fromJSON(e)
+			if !ok {
+				return nil, false
+			}
+			l.Value[n] = v
+		}
+		return l, true
+	}
+	return nil, false
+}
+
 // Returns a new and empty node.
 func NewNode() *ConfigDataNode {
 	return &ConfigDataNode{
@@ -140,6 +157,21 @@ func (c ConfigDataNode) Merge(n ctree.Node) ctree.Node {
 	return c
 }
 
+// Merges a ConfigDataNode under this one (keeping the items of this one where
+// both have them).
+func (c *ConfigDataNode) ReverseMerge(n ctree.Node) ctree.Node {
+	cd := n.(*ConfigDataNode)
+	t := cd.Table()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for k, v := range t {
+		if _, ok := c.table[k]; !ok && k != "" {
+			c.table[k] = v
+		}
+	}
+	return c
+}
+
 // Deletes a field in ConfigDataNode. If the field does not exist Delete is
 // considered a no-op
 func (c ConfigDataNode) DeleteItem(k string) {
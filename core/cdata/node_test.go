@@ -20,7 +20,9 @@ limitations under the License.
 package cdata
 
 import (
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/intelsdi-x/snap/core/ctypes"
 	. "github.com/smartystreets/goconvey/convey"
@@ -82,5 +84,29 @@ func TestConfigDataNode(t *testing.T) {
 			So(t["f"].(ctypes.ConfigValueFloat).Value, ShouldEqual, 2.3)
 			So(len(t), ShouldEqual, 3)
 		})
+
+		Convey("lists and durations go through JSON", func() {
+			cd1.AddItem("l", ctypes.ConfigValueList{Value: []ctypes.ConfigValue{ctypes.ConfigValueStr{Value: "a"}, ctypes.ConfigValueInt{Value: 2}}})
+			cd1.AddItem("d", ctypes.ConfigValueDuration{Value: 5 * time.Second})
+			b, err := json.Marshal(cd1)
+			So(err, ShouldBeNil)
+			cd2 := NewNode()
+			So(json.Unmarshal(b, cd2), ShouldBeNil)
+			t := cd2.Table()
+			So(t["l"], ShouldResemble, ctypes.ConfigValueList{Value: []ctypes.ConfigValue{ctypes.ConfigValueStr{Value: "a"}, ctypes.ConfigValueInt{Value: 2}}})
+			// durations are marshalled as strings
+			So(t["d"], ShouldResemble, ctypes.ConfigValueStr{Value: "5s"})
+		})
+
+		Convey("reverse merge keeps the existing items", func() {
+			cd1.AddItem("s", ctypes.ConfigValueStr{Value: "foo"})
+			cd2 := NewNode()
+			cd2.AddItem("s", ctypes.ConfigValueStr{Value: "bar"})
+			cd2.AddItem("i", ctypes.ConfigValueInt{Value: 1})
+			cd1.ReverseMerge(cd2)
+			t := cd1.Table()
+			So(t["s"].(ctypes.ConfigValueStr).Value, ShouldEqual, "foo")
+			So(t["i"].(ctypes.ConfigValueInt).Value, ShouldEqual, 1)
+		})
 	})
 }
@@ -0,0 +1,79 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cdata
+
+import "github.com/intelsdi-x/snap/core/ctypes"
+
+// The sources of the config data a plugin receives, from the lowest
+// precedence to the highest
+const (
+	// DefaultConfigSource is the defaults of the config policy of the plugin
+	DefaultConfigSource = "default"
+	// GlobalConfigSource is the plugin config of snapd
+	GlobalConfigSource = "global"
+	// TaskConfigSource is the config of a task for a namespace and the
+	// namespaces under it
+	TaskConfigSource = "task"
+	// MetricConfigSource is the config of a task for an exact metric
+	MetricConfigSource = "metric"
+)
+
+// ConfigLayer is the config data coming from a single source
+type ConfigLayer struct {
+	Source string
+	Node   *ConfigDataNode
+}
+
+// ConfigConflict is a value set by a source but overridden by a source of
+// higher precedence
+type ConfigConflict struct {
+	Source string
+	Value  ctypes.ConfigValue
+}
+
+// ResolvedConfigValue is the final value of a config item along with the
+// source it comes from and the values it overrides
+type ResolvedConfigValue struct {
+	Value      ctypes.ConfigValue
+	Source     string
+	Overridden []ConfigConflict
+}
+
+// Resolve merges the layers given, ordered from the lowest precedence to the
+// highest, and reports the source of each resulting value. The value of a
+// layer replaces the values of the layers before it.
+func Resolve(layers ...ConfigLayer) map[string]ResolvedConfigValue {
+	resolved := make(map[string]ResolvedConfigValue)
+	for _, l := range layers {
+		if l.Node == nil {
+			continue
+		}
+		for k, v := range l.Node.Table() {
+			rv, ok := resolved[k]
+			if ok {
+				rv.Overridden = append(rv.Overridden, ConfigConflict{Source: rv.Source, Value: rv.Value})
+			}
+			rv.Value = v
+			rv.Source = l.Source
+			resolved[k] = rv
+		}
+	}
+	return resolved
+}
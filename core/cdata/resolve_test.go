@@ -0,0 +1,63 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cdata
+
+import (
+	"testing"
+
+	"github.com/intelsdi-x/snap/core/ctypes"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestResolve(t *testing.T) {
+	Convey("Resolve()", t, func() {
+		defaults := NewNode()
+		defaults.AddItem("user", ctypes.ConfigValueStr{Value: "nobody"})
+		defaults.AddItem("port", ctypes.ConfigValueInt{Value: 80})
+		global := NewNode()
+		global.AddItem("user", ctypes.ConfigValueStr{Value: "snap"})
+		global.AddItem("password", ctypes.ConfigValueStr{Value: "secret"})
+		task := NewNode()
+		task.AddItem("user", ctypes.ConfigValueStr{Value: "root"})
+
+		r := Resolve(
+			ConfigLayer{Source: DefaultConfigSource, Node: defaults},
+			ConfigLayer{Source: GlobalConfigSource, Node: global},
+			ConfigLayer{Source: TaskConfigSource + ":/intel", Node: task},
+			ConfigLayer{Source: MetricConfigSource + ":/intel/foo"},
+		)
+		So(len(r), ShouldEqual, 3)
+
+		Convey("the layer of highest precedence wins", func() {
+			So(r["user"].Value, ShouldResemble, ctypes.ConfigValueStr{Value: "root"})
+			So(r["user"].Source, ShouldEqual, "task:/intel")
+			So(r["port"].Source, ShouldEqual, DefaultConfigSource)
+			So(r["password"].Source, ShouldEqual, GlobalConfigSource)
+		})
+
+		Convey("the overridden values are reported in order", func() {
+			So(r["user"].Overridden, ShouldResemble, []ConfigConflict{
+				{Source: DefaultConfigSource, Value: ctypes.ConfigValueStr{Value: "nobody"}},
+				{Source: GlobalConfigSource, Value: ctypes.ConfigValueStr{Value: "snap"}},
+			})
+			So(r["port"].Overridden, ShouldBeEmpty)
+		})
+	})
+}
@@ -22,20 +22,26 @@ package cdata
 import (
 	"bytes"
 	"encoding/gob"
+	"io"
+	"strings"
 
 	"github.com/intelsdi-x/snap/pkg/ctree"
 )
 
 // Allows adding of config data by namespace and retrieving of data from tree
 // at a specific namespace (merging the relevant hiearchy). Uses pkg.ConfigTree.
+// Config data added for an exact namespace only applies to that namespace and
+// is merged last, overriding the config inherited from its prefixes.
 type ConfigDataTree struct {
 	cTree *ctree.ConfigTree
+	exact map[string]*ConfigDataNode
 }
 
 // Returns a new ConfigDataTree.
 func NewTree() *ConfigDataTree {
 	return &ConfigDataTree{
 		cTree: ctree.New(),
+		exact: make(map[string]*ConfigDataNode),
 	}
 }
 
@@ -45,13 +51,27 @@ func (c *ConfigDataTree) GobEncode() ([]byte, error) {
 	if err := encoder.Encode(c.cTree); err != nil {
 		return nil, err
 	}
+	if c.exact == nil {
+		c.exact = make(map[string]*ConfigDataNode)
+	}
+	if err := encoder.Encode(c.exact); err != nil {
+		return nil, err
+	}
 	return w.Bytes(), nil
 }
 
 func (c *ConfigDataTree) GobDecode(buf []byte) error {
 	r := bytes.NewBuffer(buf)
 	decoder := gob.NewDecoder(r)
-	return decoder.Decode(&c.cTree)
+	if err := decoder.Decode(&c.cTree); err != nil {
+		return err
+	}
+	c.exact = make(map[string]*ConfigDataNode)
+	// trees encoded before exact config data existed end after their cTree
+	if err := decoder.Decode(&c.exact); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
 }
 
 // Adds a ConfigDataNode at the provided namespace.
@@ -59,24 +79,53 @@ func (c *ConfigDataTree) Add(ns []string, cdn *ConfigDataNode) {
 	c.cTree.Add(ns, cdn)
 }
 
+// Adds a ConfigDataNode applying only to the exact namespace provided. It takes
+// precedence over any config data added at a prefix of the namespace.
+func (c *ConfigDataTree) AddExact(ns []string, cdn *ConfigDataNode) {
+	if c.exact == nil {
+		c.exact = make(map[string]*ConfigDataNode)
+	}
+	c.exact[exactKey(ns)] = cdn
+}
+
 // Returns a ConfigDataNode that is a merged version of the namespace provided.
+// The config data of longer namespaces overrides the one of shorter ones and
+// the config data added for the exact namespace overrides them all. The nodes
+// added to the tree are left untouched.
 func (c *ConfigDataTree) Get(ns []string) *ConfigDataNode {
+	layers := c.Layers(ns)
+	if len(layers) == 0 {
+		return nil
+	}
+	merged := NewNode()
+	for _, l := range layers {
+		merged.Merge(l.Node)
+	}
+	return merged
+}
+
+// Returns the config data applying to the namespace provided, as layers
+// ordered from the lowest precedence to the highest.
+func (c *ConfigDataTree) Layers(ns []string) []ConfigLayer {
 	// Automatically freeze on first Get
 	if !c.cTree.Frozen() {
 		c.cTree.Freeze()
 	}
 
-	n := c.cTree.Get(ns)
-	if n == nil {
-		return nil
+	var layers []ConfigLayer
+	for _, m := range c.cTree.Matches(ns) {
+		layers = append(layers, ConfigLayer{
+			Source: TaskConfigSource + ":" + exactKey(m.Namespace),
+			Node:   toConfigDataNode(m.Node),
+		})
 	}
-	switch t := n.(type) {
-	case ConfigDataNode:
-		return &t
-	default:
-		return t.(*ConfigDataNode)
-
+	if e, ok := c.exact[exactKey(ns)]; ok {
+		layers = append(layers, ConfigLayer{
+			Source: MetricConfigSource + ":" + exactKey(ns),
+			Node:   e,
+		})
 	}
+	return layers
 }
 
 // Freezes the ConfigDataTree from future writes (adds) and triggers compression
@@ -84,3 +133,16 @@ func (c *ConfigDataTree) Get(ns []string) *ConfigDataNode {
 func (c *ConfigDataTree) Freeze() {
 	c.cTree.Freeze()
 }
+
+func exactKey(ns []string) string {
+	return "/" + strings.Join(ns, "/")
+}
+
+func toConfigDataNode(n ctree.Node) *ConfigDataNode {
+	switch t := n.(type) {
+	case ConfigDataNode:
+		return &t
+	default:
+		return t.(*ConfigDataNode)
+	}
+}
@@ -20,6 +20,7 @@ limitations under the License.
 package cdata
 
 import (
+	"bytes"
 	"encoding/gob"
 	"fmt"
 	"testing"
@@ -120,6 +121,63 @@ func TestConfigDataTree(t *testing.T) {
 					So(t2["f"].(ctypes.ConfigValueFloat).Value, ShouldEqual, 2.3)
 
 				})
+				Convey("decode a tree encoded without exact config data", func() {
+					var w bytes.Buffer
+					So(gob.NewEncoder(&w).Encode(cdt.cTree), ShouldBeNil)
+					cdt2 := NewTree()
+					So(cdt2.GobDecode(w.Bytes()), ShouldBeNil)
+					So(cdt2.exact, ShouldBeEmpty)
+					a2 := cdt2.Get([]string{"1", "2"})
+					So(a2, ShouldNotBeNil)
+					So(a2.Table()["x"].(ctypes.ConfigValueStr).Value, ShouldEqual, "wat")
+				})
+			})
+
+			Convey("exact match takes precedence over prefixes", func() {
+				cd1 := NewNode()
+				cd1.AddItem("s", ctypes.ConfigValueStr{Value: "foo"})
+				cd1.AddItem("i", ctypes.ConfigValueInt{Value: -1})
+				cd2 := NewNode()
+				cd2.AddItem("s", ctypes.ConfigValueStr{Value: "bar"})
+				cd3 := NewNode()
+				cd3.AddItem("s", ctypes.ConfigValueStr{Value: "baz"})
+
+				cdt.Add([]string{"1"}, cd1)
+				cdt.AddExact([]string{"1", "host0", "3"}, cd2)
+				cdt.Add([]string{"1", "host0", "3"}, cd3)
+
+				a := cdt.Get([]string{"1", "host0", "3"})
+				So(a, ShouldNotBeNil)
+				So(a.Table()["s"].(ctypes.ConfigValueStr).Value, ShouldEqual, "bar")
+				So(a.Table()["i"].(ctypes.ConfigValueInt).Value, ShouldEqual, -1)
+
+				b := cdt.Get([]string{"1", "host0", "3", "4"})
+				So(b, ShouldNotBeNil)
+				So(b.Table()["s"].(ctypes.ConfigValueStr).Value, ShouldEqual, "baz")
+
+				Convey("without changing the nodes of the tree", func() {
+					c := cdt.Get([]string{"1", "host1", "3"})
+					So(c, ShouldNotBeNil)
+					So(c.Table()["s"].(ctypes.ConfigValueStr).Value, ShouldEqual, "foo")
+					So(cd1.Table()["s"].(ctypes.ConfigValueStr).Value, ShouldEqual, "foo")
+				})
+
+				Convey("layers report the source of the config", func() {
+					l := cdt.Layers([]string{"1", "host0", "3"})
+					So(len(l), ShouldEqual, 3)
+					So(l[0].Source, ShouldEqual, "task:/1")
+					So(l[1].Source, ShouldEqual, "task:/1/host0/3")
+					So(l[2].Source, ShouldEqual, "metric:/1/host0/3")
+				})
+			})
+
+			Convey("exact match without tree config", func() {
+				cd1 := NewNode()
+				cd1.AddItem("s", ctypes.ConfigValueStr{Value: "foo"})
+				cdt.AddExact([]string{"1", "2"}, cd1)
+
+				So(cdt.Get([]string{"1", "2"}).Table()["s"].(ctypes.ConfigValueStr).Value, ShouldEqual, "foo")
+				So(cdt.Get([]string{"1"}), ShouldBeNil)
 			})
 
 		})
@@ -0,0 +1,127 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrUnknownContentType is returned for a content type no codec is
+	// registered for
+	ErrUnknownContentType = errors.New("unknown content type")
+	// ErrNotDecodable is returned when decoding a content type snapd only
+	// encodes
+	ErrNotDecodable = errors.New("content type cannot be decoded")
+)
+
+// Codec encodes the metric batches sent to the plugins in a content type,
+// and decodes those the processors return in it.
+type Codec struct {
+	// Encode encodes a batch of metrics
+	Encode func(metrics []Metric) ([]byte, error)
+	// Decode decodes a batch of metrics. It is nil for a content type snapd
+	// only encodes for publishers, which processors cannot return.
+	Decode func(content []byte) ([]Metric, error)
+}
+
+// codecs are the registered codecs by content type
+var codecs = struct {
+	sync.RWMutex
+	table map[string]Codec
+}{table: map[string]Codec{}}
+
+// RegisterCodec registers the codec of a content type, making it a content
+// type plugins may accept and, when the codec decodes, return. A content
+// type is registered once.
+func RegisterCodec(contentType string, c Codec) error {
+	if contentType == "" {
+		return errors.New("codec without a content type")
+	}
+	if c.Encode == nil {
+		return fmt.Errorf("codec of %s does not encode", contentType)
+	}
+	codecs.Lock()
+	defer codecs.Unlock()
+	if _, ok := codecs.table[contentType]; ok {
+		return fmt.Errorf("codec of %s already registered", contentType)
+	}
+	codecs.table[contentType] = c
+	return nil
+}
+
+// GetCodec returns the codec of a content type
+func GetCodec(contentType string) (Codec, error) {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	c, ok := codecs.table[contentType]
+	if !ok {
+		return Codec{}, fmt.Errorf("%v: %s", ErrUnknownContentType, contentType)
+	}
+	return c, nil
+}
+
+// CodecContentTypes returns the content types codecs are registered for
+func CodecContentTypes() []string {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	cts := make([]string, 0, len(codecs.table))
+	for ct := range codecs.table {
+		cts = append(cts, ct)
+	}
+	sort.Strings(cts)
+	return cts
+}
+
+// CanEncode returns whether snapd encodes metrics in the content type
+func CanEncode(contentType string) bool {
+	_, err := GetCodec(contentType)
+	return err == nil
+}
+
+// CanDecode returns whether snapd decodes metrics from the content type
+func CanDecode(contentType string) bool {
+	c, err := GetCodec(contentType)
+	return err == nil && c.Decode != nil
+}
+
+// EncodeMetrics encodes the metrics in the content type
+func EncodeMetrics(contentType string, metrics []Metric) ([]byte, error) {
+	c, err := GetCodec(contentType)
+	if err != nil {
+		return nil, err
+	}
+	return c.Encode(metrics)
+}
+
+// DecodeMetrics decodes metrics from content of the content type
+func DecodeMetrics(contentType string, content []byte) ([]Metric, error) {
+	c, err := GetCodec(contentType)
+	if err != nil {
+		return nil, err
+	}
+	if c.Decode == nil {
+		return nil, fmt.Errorf("%v: %s", ErrNotDecodable, contentType)
+	}
+	return c.Decode(content)
+}
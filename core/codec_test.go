@@ -0,0 +1,98 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCodecRegistry(t *testing.T) {
+	// namespaces joined by lines, decoded back into metrics without data
+	lines := Codec{
+		Encode: func(metrics []Metric) ([]byte, error) {
+			nss := make([]string, len(metrics))
+			for i, m := range metrics {
+				nss[i] = JoinNamespace(m.Namespace())
+			}
+			return []byte(strings.Join(nss, "\n")), nil
+		},
+		Decode: func(content []byte) ([]Metric, error) {
+			var metrics []Metric
+			for _, l := range strings.Split(string(content), "\n") {
+				metrics = append(metrics, alertMetric{ns: strings.Split(l, "/")[1:]})
+			}
+			return metrics, nil
+		},
+	}
+
+	Convey("Given a codec registered for a content type", t, func() {
+		So(RegisterCodec("test.lines", lines), ShouldBeNil)
+		defer func() {
+			codecs.Lock()
+			delete(codecs.table, "test.lines")
+			codecs.Unlock()
+		}()
+
+		Convey("metrics are encoded and decoded in the content type", func() {
+			So(CanEncode("test.lines"), ShouldBeTrue)
+			So(CanDecode("test.lines"), ShouldBeTrue)
+			So(CodecContentTypes(), ShouldContain, "test.lines")
+			b, err := EncodeMetrics("test.lines", []Metric{
+				alertMetric{ns: []string{"intel", "mock", "foo"}},
+				alertMetric{ns: []string{"intel", "mock", "bar"}},
+			})
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "/intel/mock/foo\n/intel/mock/bar")
+			metrics, err := DecodeMetrics("test.lines", b)
+			So(err, ShouldBeNil)
+			So(metrics, ShouldHaveLength, 2)
+			So(metrics[1].Namespace(), ShouldResemble, []string{"intel", "mock", "bar"})
+		})
+		Convey("the content type cannot be registered again", func() {
+			So(RegisterCodec("test.lines", lines), ShouldNotBeNil)
+		})
+	})
+	Convey("A codec without a decoder only encodes", t, func() {
+		So(RegisterCodec("test.encoded", Codec{Encode: lines.Encode}), ShouldBeNil)
+		defer func() {
+			codecs.Lock()
+			delete(codecs.table, "test.encoded")
+			codecs.Unlock()
+		}()
+		So(CanEncode("test.encoded"), ShouldBeTrue)
+		So(CanDecode("test.encoded"), ShouldBeFalse)
+		_, err := DecodeMetrics("test.encoded", nil)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, ErrNotDecodable.Error())
+	})
+	Convey("An unknown content type is an error", t, func() {
+		So(CanEncode("test.unknown"), ShouldBeFalse)
+		_, err := EncodeMetrics("test.unknown", nil)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, ErrUnknownContentType.Error())
+	})
+	Convey("A codec must have a content type and an encoder", t, func() {
+		So(RegisterCodec("", lines), ShouldNotBeNil)
+		So(RegisterCodec("test.nothing", Codec{Decode: lines.Decode}), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,259 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrDivisionByZero - The error message for the expression of a computed metric dividing by zero
+var ErrDivisionByZero = errors.New("Division by zero")
+
+// ComputedMetric is a metric whose value is computed from the values of
+// other metrics by an arithmetic expression, e.g. the expression
+// "/intel/mem/used / /intel/mem/total * 100" of /company/mem/used_pct. An
+// expression is made of the namespaces of metrics, numbers, the operators
+// +, -, * and / separated from namespaces by spaces, and parentheses.
+type ComputedMetric struct {
+	Namespace  []string
+	Expression string
+
+	expr     computedExpr
+	operands [][]string
+}
+
+// NewComputedMetric parses the expression of a computed metric
+func NewComputedMetric(namespace []string, expression string) (*ComputedMetric, error) {
+	p := &computedParser{tokens: tokenizeComputed(expression)}
+	expr, err := p.parseSum()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("computed metric %s: invalid expression %q: %v", JoinNamespace(namespace), expression, err)
+	}
+	if len(p.operands) == 0 {
+		return nil, fmt.Errorf("computed metric %s: expression %q uses no metric", JoinNamespace(namespace), expression)
+	}
+	return &ComputedMetric{
+		Namespace:  namespace,
+		Expression: expression,
+		expr:       expr,
+		operands:   p.operands,
+	}, nil
+}
+
+// Operands returns the namespaces of the metrics the expression uses, in
+// the order they first appear
+func (c *ComputedMetric) Operands() [][]string {
+	return c.operands
+}
+
+// Evaluate computes the value of the metric from the values of its operands,
+// given by joined namespace (e.g. "/intel/mem/used"). The data of an operand
+// must be a number.
+func (c *ComputedMetric) Evaluate(values map[string]interface{}) (float64, error) {
+	return c.expr.eval(values)
+}
+
+type computedExpr interface {
+	eval(values map[string]interface{}) (float64, error)
+}
+
+type computedNumber float64
+
+func (n computedNumber) eval(map[string]interface{}) (float64, error) {
+	return float64(n), nil
+}
+
+type computedOperand string
+
+func (o computedOperand) eval(values map[string]interface{}) (float64, error) {
+	data, ok := values[string(o)]
+	if !ok {
+		return 0, fmt.Errorf("metric %s was not collected", o)
+	}
+	v, ok := NumericValue(data)
+	if !ok {
+		return 0, fmt.Errorf("metric %s is not a number: %v", o, data)
+	}
+	return v, nil
+}
+
+type computedOperation struct {
+	op          byte
+	left, right computedExpr
+}
+
+func (o computedOperation) eval(values map[string]interface{}) (float64, error) {
+	l, err := o.left.eval(values)
+	if err != nil {
+		return 0, err
+	}
+	r, err := o.right.eval(values)
+	if err != nil {
+		return 0, err
+	}
+	switch o.op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	}
+	if r == 0 {
+		return 0, ErrDivisionByZero
+	}
+	return l / r, nil
+}
+
+// tokenizeComputed splits an expression into numbers, namespaces, operators
+// and parentheses. A slash followed by a letter starts a namespace, which
+// runs until a space or a parenthesis.
+func tokenizeComputed(expression string) []string {
+	var tokens []string
+	rs := []rune(expression)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '/' && i+1 < len(rs) && unicode.IsLetter(rs[i+1]):
+			j := i + 1
+			for j < len(rs) && !unicode.IsSpace(rs[j]) && rs[j] != '(' && rs[j] != ')' {
+				j++
+			}
+			tokens = append(tokens, string(rs[i:j]))
+			i = j
+		case unicode.IsDigit(r) || r == '.':
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, string(rs[i:j]))
+			i = j
+		default:
+			tokens = append(tokens, string(r))
+			i++
+		}
+	}
+	return tokens
+}
+
+// computedParser parses the tokens of an expression by recursive descent,
+// * and / binding tighter than + and -
+type computedParser struct {
+	tokens   []string
+	pos      int
+	operands [][]string
+}
+
+func (p *computedParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *computedParser) parseSum() (computedExpr, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t == "+" || t == "-"; t = p.peek() {
+		p.pos++
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = computedOperation{op: t[0], left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *computedParser) parseProduct() (computedExpr, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t == "*" || t == "/"; t = p.peek() {
+		p.pos++
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = computedOperation{op: t[0], left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *computedParser) parseFactor() (computedExpr, error) {
+	t := p.peek()
+	p.pos++
+	switch {
+	case t == "":
+		return nil, errors.New("unexpected end")
+	case t == "(":
+		e, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, errors.New("missing )")
+		}
+		p.pos++
+		return e, nil
+	case t == "-":
+		e, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return computedOperation{op: '-', left: computedNumber(0), right: e}, nil
+	case strings.HasPrefix(t, "/"):
+		ns := strings.Split(strings.TrimPrefix(t, "/"), "/")
+		for _, e := range ns {
+			if e == "" || strings.Contains(e, "*") {
+				return nil, fmt.Errorf("%s is not a concrete namespace", t)
+			}
+		}
+		p.addOperand(ns)
+		return computedOperand(JoinNamespace(ns)), nil
+	}
+	n, err := strconv.ParseFloat(t, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected %q", t)
+	}
+	return computedNumber(n), nil
+}
+
+func (p *computedParser) addOperand(ns []string) {
+	key := JoinNamespace(ns)
+	for _, o := range p.operands {
+		if JoinNamespace(o) == key {
+			return
+		}
+	}
+	p.operands = append(p.operands, ns)
+}
@@ -0,0 +1,86 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestComputedMetric(t *testing.T) {
+	ns := []string{"company", "mem", "used_pct"}
+	Convey("NewComputedMetric()", t, func() {
+		Convey("parses the operands of the expression", func() {
+			c, err := NewComputedMetric(ns, "/intel/mem/used / /intel/mem/total * 100")
+			So(err, ShouldBeNil)
+			So(c.Operands(), ShouldResemble, [][]string{{"intel", "mem", "used"}, {"intel", "mem", "total"}})
+		})
+		Convey("rejects invalid expressions", func() {
+			for _, e := range []string{
+				"",
+				"100",
+				"/intel/mem/used +",
+				"(/intel/mem/used",
+				"/intel/mem/used % 2",
+				"/intel/mem/* * 2",
+				"/intel/mem/used /intel/mem/total",
+			} {
+				_, err := NewComputedMetric(ns, e)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+	Convey("Evaluate()", t, func() {
+		values := map[string]interface{}{
+			"/intel/mem/used":  uint64(25),
+			"/intel/mem/total": 200,
+			"/intel/mem/free":  0.0,
+			"/intel/mem/name":  "foo",
+		}
+		eval := func(e string) (float64, error) {
+			c, err := NewComputedMetric(ns, e)
+			So(err, ShouldBeNil)
+			return c.Evaluate(values)
+		}
+		Convey("applies the precedence of the operators", func() {
+			v, err := eval("/intel/mem/used / /intel/mem/total * 100")
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, 12.5)
+			v, err = eval("/intel/mem/used + 2 * 3")
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, 31)
+			v, err = eval("(/intel/mem/total - /intel/mem/used) / 5")
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, 35)
+			v, err = eval("-/intel/mem/used + 1")
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, -24)
+		})
+		Convey("fails on missing, non numeric or zero divisors", func() {
+			_, err := eval("/intel/mem/used / /intel/mem/free")
+			So(err, ShouldEqual, ErrDivisionByZero)
+			_, err = eval("/intel/mem/name * 2")
+			So(err, ShouldNotBeNil)
+			_, err = eval("/intel/mem/cached * 2")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
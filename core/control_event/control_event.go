@@ -19,10 +19,13 @@ limitations under the License.
 
 package control_event
 
+import "time"
+
 const (
 	AvailablePluginDead      = "Control.AvailablePluginDead"
 	AvailablePluginRestarted = "Control.RestartedAvailablePlugin"
 	PluginRestartsExceeded   = "Control.PluginRestartsExceeded"
+	RPCFailuresExceeded      = "Control.PluginRPCFailuresExceeded"
 	PluginLoaded             = "Control.PluginLoaded"
 	PluginUnloaded           = "Control.PluginUnloaded"
 	PluginsSwapped           = "Control.PluginsSwapped"
@@ -34,6 +37,11 @@ const (
 	MetricUnsubscribed       = "Control.MetricUnsubscribed"
 	HealthCheckFailed        = "Control.PluginHealthCheckFailed"
 	MoveSubscription         = "Control.PluginSubscriptionMoved"
+	VersionSubstituted       = "Control.PluginVersionSubstituted"
+	MetricLimitExceeded      = "Control.MetricLimitExceeded"
+	MetricRemoved            = "Control.MetricRemoved"
+	CatalogUpdated           = "Control.CatalogUpdated"
+	PluginConfigUpdated      = "Control.PluginConfigUpdated"
 )
 
 type LoadPluginEvent struct {
@@ -64,12 +72,33 @@ type DeadAvailablePluginEvent struct {
 	Key     string
 	Id      uint32
 	String  string
+	// CrashReport is the path of the crash report written for the plugin,
+	// if any
+	CrashReport string
 }
 
 func (e *DeadAvailablePluginEvent) Namespace() string {
 	return AvailablePluginDead
 }
 
+// RPCFailuresExceededEvent is emitted when the calls to a running plugin
+// failed too many times within the window, before it is declared dead and
+// reloaded
+type RPCFailuresExceededEvent struct {
+	Name      string
+	Version   int
+	Type      int
+	Key       string
+	Id        uint32
+	Failures  int
+	Window    time.Duration
+	LastError string
+}
+
+func (e *RPCFailuresExceededEvent) Namespace() string {
+	return RPCFailuresExceeded
+}
+
 type RestartedAvailablePluginEvent struct {
 	Name    string
 	Version int
@@ -150,3 +179,78 @@ type MovePluginSubscriptionEvent struct {
 func (mse MovePluginSubscriptionEvent) Namespace() string {
 	return MoveSubscription
 }
+
+// VersionSubstitutionEvent is emitted when a task pins a plugin version which
+// is not loaded and the version fallback policy substitutes another version.
+type VersionSubstitutionEvent struct {
+	TaskId           string
+	PluginName       string
+	PluginType       int
+	RequestedVersion int
+	Version          int
+	Policy           string
+}
+
+func (vse VersionSubstitutionEvent) Namespace() string {
+	return VersionSubstituted
+}
+
+// MetricLimitExceededEvent is emitted when cataloging the metric types of a
+// plugin or creating a task is refused because it exceeds a metric limit.
+// Limit is the name of the exceeded setting, Count the number of metrics
+// requested.
+type MetricLimitExceededEvent struct {
+	Limit         string
+	Max           int
+	Count         int
+	PluginName    string
+	PluginVersion int
+}
+
+func (mle *MetricLimitExceededEvent) Namespace() string {
+	return MetricLimitExceeded
+}
+
+// MetricRemovedEvent is emitted when a metric type of a plugin supporting
+// dynamic metrics is removed from the catalog because it was neither
+// advertised nor collected for longer than the metric TTL.
+type MetricRemovedEvent struct {
+	MetricNamespace string
+	Version         int
+	PluginName      string
+	PluginVersion   int
+	LastSeen        time.Time
+}
+
+func (mre *MetricRemovedEvent) Namespace() string {
+	return MetricRemoved
+}
+
+// CatalogUpdatedEvent is emitted when a running collector pushed changes of
+// its metric types which were applied to the catalog. Added and Removed hold
+// the namespaces of the metric types.
+type CatalogUpdatedEvent struct {
+	PluginName    string
+	PluginVersion int
+	Added         []string
+	Removed       []string
+}
+
+func (cue *CatalogUpdatedEvent) Namespace() string {
+	return CatalogUpdated
+}
+
+// PluginConfigUpdatedEvent is emitted when the global config of plugins was
+// changed through the API. An empty PluginName stands for all the plugins of
+// the type, a PluginVersion below 1 for all the versions of the plugin.
+// TaskIDs are the tasks subscribed to the plugins at the time.
+type PluginConfigUpdatedEvent struct {
+	PluginName    string
+	PluginVersion int
+	PluginType    int
+	TaskIDs       []string
+}
+
+func (pcue *PluginConfigUpdatedEvent) Namespace() string {
+	return PluginConfigUpdated
+}
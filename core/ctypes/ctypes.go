@@ -19,7 +19,11 @@ limitations under the License.
 
 package ctypes
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
 
 // TODO constructors for each that have typing for value (and optionally validate)
 
@@ -60,7 +64,15 @@ func (c ConfigValueFloat) Type() string {
 }
 
 func (c ConfigValueFloat) MarshalJSON() ([]byte, error) {
-	return json.Marshal(c.Value)
+	b, err := json.Marshal(c.Value)
+	if err != nil {
+		return nil, err
+	}
+	// a whole float stays a float once unmarshalled, e.g. 2.0 and not 2
+	if !bytes.ContainsAny(b, ".eE") {
+		b = append(b, ".0"...)
+	}
+	return b, nil
 }
 
 type ConfigValueBool struct {
@@ -75,6 +87,31 @@ func (c ConfigValueBool) MarshalJSON() ([]byte, error) {
 	return json.Marshal(c.Value)
 }
 
+type ConfigValueDuration struct {
+	Value time.Duration
+}
+
+func (c ConfigValueDuration) Type() string {
+	return "duration"
+}
+
+func (c ConfigValueDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Value.String())
+}
+
+// ConfigValueList is a list of config values, all of the same type.
+type ConfigValueList struct {
+	Value []ConfigValue
+}
+
+func (c ConfigValueList) Type() string {
+	return "list"
+}
+
+func (c ConfigValueList) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Value)
+}
+
 // Returns a slice of string keywords for the types supported by ConfigValue.
 func SupportedTypes() []string {
 	// This is kind of a hack but keeps the definition of types here in
@@ -90,6 +127,10 @@ func SupportedTypes() []string {
 		ConfigValueFloat{}.Type(),
 		// Bool
 		ConfigValueBool{}.Type(),
+		// Duration
+		ConfigValueDuration{}.Type(),
+		// List
+		ConfigValueList{}.Type(),
 	}
 	return t
 }
@@ -0,0 +1,48 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+// Host identifies the snapd instance which collected a metric.
+type Host struct {
+	// Hostname is the hostname of the host snapd runs on
+	Hostname string `json:"hostname"`
+	// IP is the address snapd advertises to the other members of the tribe
+	IP string `json:"ip,omitempty"`
+	// AgentID identifies snapd across restarts, as long as its data
+	// directory is kept
+	AgentID string `json:"agent_id,omitempty"`
+	// Member is the name of snapd in the tribe, empty when tribe is disabled
+	Member string `json:"member,omitempty"`
+}
+
+// String returns the hostname of the host, or its IP when the hostname is
+// not known.
+func (h Host) String() string {
+	if h.Hostname != "" {
+		return h.Hostname
+	}
+	return h.IP
+}
+
+// HostedMetric is implemented by metrics which carry the host of the snapd
+// which collected them.
+type HostedMetric interface {
+	Host() Host
+}
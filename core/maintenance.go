@@ -0,0 +1,37 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import "time"
+
+// Maintenance is the maintenance mode of snapd. While it is on the running
+// tasks are paused, or keep collecting while their publishing is held in
+// write-ahead logs, so the maintenance of the host raises no alerts
+// downstream.
+type Maintenance struct {
+	Enabled bool
+	// HoldPublish is set when the tasks keep collecting into write-ahead logs
+	// instead of being paused
+	HoldPublish bool
+	Since       time.Time
+	// Tasks are the IDs of the tasks paused or holding their publishing for
+	// the maintenance
+	Tasks []string
+}
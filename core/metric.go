@@ -50,12 +50,36 @@ type RequestedMetric interface {
 	Version() int
 }
 
+// PinnedMetric is a requested metric which may be pinned to the collector
+// plugin of a name. A pinned metric is only collected by the exact version
+// of that plugin.
+type PinnedMetric interface {
+	RequestedMetric
+	PluginName() string
+}
+
+// MetricPluginName returns the name of the plugin the requested metric is
+// pinned to, "" when it is not pinned.
+func MetricPluginName(m RequestedMetric) string {
+	if pm, ok := m.(PinnedMetric); ok {
+		return pm.PluginName()
+	}
+	return ""
+}
+
 type CatalogedMetric interface {
 	RequestedMetric
 	LastAdvertisedTime() time.Time
 	Policy() *cpolicy.ConfigPolicyNode
 }
 
+// DescribedMetric is implemented by metrics which carry the unit they are
+// measured in and a human readable description, as advertised by plugins.
+type DescribedMetric interface {
+	Unit() string
+	Description() string
+}
+
 func JoinNamespace(ns []string) string {
 	return "/" + strings.Join(ns, "/")
 }
@@ -21,6 +21,7 @@ package core
 
 import (
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"time"
@@ -29,6 +30,9 @@ import (
 	"github.com/intelsdi-x/snap/core/cdata"
 )
 
+// ErrPluginInUse - error message when unloading a plugin running tasks depend on
+var ErrPluginInUse = errors.New("Plugin is in use by running tasks")
+
 type Plugin interface {
 	TypeName() string
 	Name() string
@@ -78,6 +82,7 @@ type AvailablePlugin interface {
 type CatalogedPlugin interface {
 	Plugin
 	IsSigned() bool
+	Signature() []byte
 	Status() string
 	PluginPath() string
 	LoadedTimestamp() *time.Time
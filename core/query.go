@@ -0,0 +1,276 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A metric query selects metrics of the catalog by namespace, version, plugin,
+// unit and tags, e.g.:
+//
+//	ns=/intel/cpu/* AND plugin=psutil AND version>=3 AND tag.unit=percent
+//
+// A query is a list of conditions joined with AND. A condition is a field, an
+// operator and a value. The fields are:
+//
+//	ns         the metric namespace
+//	plugin     the name of the plugin exposing the metric
+//	version    the metric version
+//	unit       the unit the metric is measured in
+//	tag.<key>  the value of the tag <key>
+//
+// version supports =, !=, <, <=, > and >=, the other fields = and !=. In ns
+// and plugin values '*' matches any characters and '(a|b)' either alternative,
+// like in the metric namespaces of a task manifest. Values containing spaces
+// may be double quoted.
+
+var (
+	ErrEmptyMetricQuery = errors.New("metric query is empty")
+)
+
+const (
+	queryFieldNamespace = "ns"
+	queryFieldPlugin    = "plugin"
+	queryFieldVersion   = "version"
+	queryFieldUnit      = "unit"
+	queryFieldTagPrefix = "tag."
+)
+
+// operators ordered so that the two character ones are tried first
+var queryOperators = []string{"!=", ">=", "<=", "=", ">", "<"}
+
+// QueriedMetric is a metric a MetricQuery can be evaluated against
+type QueriedMetric interface {
+	RequestedMetric
+	Tags() map[string]string
+	PluginName() string
+}
+
+// MetricQuery is a parsed metric query
+type MetricQuery struct {
+	raw   string
+	conds []queryCondition
+}
+
+type queryCondition struct {
+	field string
+	op    string
+	value string
+	// set for the fields matched with wildcards
+	pattern *regexp.Regexp
+	// set for version
+	version int
+}
+
+// ParseMetricQuery parses a metric query
+func ParseMetricQuery(s string) (*MetricQuery, error) {
+	toks, err := tokenizeQuery(s)
+	if err != nil {
+		return nil, err
+	}
+	q := &MetricQuery{raw: strings.TrimSpace(s)}
+	var cur []string
+	for i := 0; i <= len(toks); i++ {
+		if i < len(toks) && !strings.EqualFold(toks[i], "AND") {
+			cur = append(cur, toks[i])
+			continue
+		}
+		if len(cur) == 0 {
+			if len(toks) == 0 {
+				return nil, ErrEmptyMetricQuery
+			}
+			return nil, fmt.Errorf("invalid metric query %q: missing condition around AND", s)
+		}
+		c, err := parseCondition(strings.Join(cur, ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid metric query %q: %v", s, err)
+		}
+		q.conds = append(q.conds, c)
+		cur = nil
+	}
+	return q, nil
+}
+
+// String returns the query as it was given
+func (q *MetricQuery) String() string {
+	return q.raw
+}
+
+// Match returns true if the metric satisfies all conditions of the query
+func (q *MetricQuery) Match(m QueriedMetric) bool {
+	for _, c := range q.conds {
+		if !c.match(m) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c queryCondition) match(m QueriedMetric) bool {
+	var v string
+	switch {
+	case c.field == queryFieldVersion:
+		return compareInts(m.Version(), c.op, c.version)
+	case c.field == queryFieldNamespace:
+		v = JoinNamespace(m.Namespace())
+	case c.field == queryFieldPlugin:
+		v = m.PluginName()
+	case c.field == queryFieldUnit:
+		if d, ok := m.(DescribedMetric); ok {
+			v = d.Unit()
+		}
+	default:
+		v = m.Tags()[strings.TrimPrefix(c.field, queryFieldTagPrefix)]
+	}
+	var eq bool
+	if c.pattern != nil {
+		eq = c.pattern.MatchString(v)
+	} else {
+		eq = v == c.value
+	}
+	if c.op == "!=" {
+		return !eq
+	}
+	return eq
+}
+
+func compareInts(a int, op string, b int) bool {
+	switch op {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+func parseCondition(s string) (queryCondition, error) {
+	c := queryCondition{}
+	idx, op := -1, ""
+	for _, o := range queryOperators {
+		if i := strings.Index(s, o); i >= 0 && (idx < 0 || i < idx || (i == idx && len(o) > len(op))) {
+			idx, op = i, o
+		}
+	}
+	if idx < 0 {
+		return c, fmt.Errorf("condition %q has no operator", s)
+	}
+	c.field, c.op, c.value = strings.ToLower(s[:idx]), op, s[idx+len(op):]
+	if c.field == "" {
+		return c, fmt.Errorf("condition %q has no field", s)
+	}
+	if strings.HasPrefix(c.value, `"`) {
+		v, err := strconv.Unquote(c.value)
+		if err != nil {
+			return c, fmt.Errorf("condition %q has a badly quoted value", s)
+		}
+		c.value = v
+	}
+	switch {
+	case c.field == queryFieldVersion:
+		v, err := strconv.Atoi(c.value)
+		if err != nil {
+			return c, fmt.Errorf("version %q is not a number", c.value)
+		}
+		c.version = v
+		return c, nil
+	case c.field == queryFieldNamespace, c.field == queryFieldPlugin:
+		p, err := wildcardRegexp(c.value)
+		if err != nil {
+			return c, fmt.Errorf("invalid pattern %q", c.value)
+		}
+		c.pattern = p
+	case c.field == queryFieldUnit:
+	case strings.HasPrefix(c.field, queryFieldTagPrefix) && len(c.field) > len(queryFieldTagPrefix):
+		// tag keys are case sensitive
+		c.field = queryFieldTagPrefix + s[len(queryFieldTagPrefix):idx]
+	default:
+		return c, fmt.Errorf("unknown field %q", s[:idx])
+	}
+	if c.op != "=" && c.op != "!=" {
+		return c, fmt.Errorf("operator %s is not supported for %s", c.op, c.field)
+	}
+	return c, nil
+}
+
+// wildcardRegexp converts a value where '*' matches any characters and
+// '(a|b)' matches one of the alternatives into a regexp
+func wildcardRegexp(v string) (*regexp.Regexp, error) {
+	exp := ""
+	for _, r := range v {
+		switch r {
+		case '*':
+			exp += ".*"
+		case '(', ')', '|':
+			exp += string(r)
+		default:
+			exp += regexp.QuoteMeta(string(r))
+		}
+	}
+	return regexp.Compile("^" + exp + "$")
+}
+
+// tokenizeQuery splits a query on whitespace. Double quoted strings are kept
+// together, quotes included.
+func tokenizeQuery(s string) ([]string, error) {
+	var (
+		toks   []string
+		cur    []rune
+		quoted bool
+		escape bool
+	)
+	for _, r := range s {
+		switch {
+		case escape:
+			escape = false
+		case quoted && r == '\\':
+			escape = true
+		case r == '"':
+			quoted = !quoted
+		case !quoted && (r == ' ' || r == '\t' || r == '\n'):
+			if len(cur) > 0 {
+				toks = append(toks, string(cur))
+				cur = nil
+			}
+			continue
+		}
+		cur = append(cur, r)
+	}
+	if quoted {
+		return nil, fmt.Errorf("invalid metric query %q: unterminated quote", s)
+	}
+	if len(cur) > 0 {
+		toks = append(toks, string(cur))
+	}
+	return toks, nil
+}
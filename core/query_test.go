@@ -0,0 +1,96 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type queriedMetric struct {
+	ns     []string
+	ver    int
+	plugin string
+	unit   string
+	tags   map[string]string
+}
+
+func (m queriedMetric) Namespace() []string     { return m.ns }
+func (m queriedMetric) Version() int            { return m.ver }
+func (m queriedMetric) Tags() map[string]string { return m.tags }
+func (m queriedMetric) PluginName() string      { return m.plugin }
+func (m queriedMetric) Unit() string            { return m.unit }
+func (m queriedMetric) Description() string     { return "" }
+
+func TestMetricQuery(t *testing.T) {
+	m := queriedMetric{
+		ns:     []string{"intel", "cpu", "0", "user"},
+		ver:    3,
+		plugin: "psutil",
+		unit:   "percent",
+		tags:   map[string]string{"unit": "percent", "desc": "cpu user time"},
+	}
+	Convey("Parsing and matching metric queries", t, func() {
+		Convey("matches all conditions", func() {
+			q, err := ParseMetricQuery("ns=/intel/cpu/* AND plugin=psutil AND version>=3 AND tag.unit=percent")
+			So(err, ShouldBeNil)
+			So(q.Match(m), ShouldBeTrue)
+			So(q.String(), ShouldEqual, "ns=/intel/cpu/* AND plugin=psutil AND version>=3 AND tag.unit=percent")
+		})
+		Convey("fails when a condition does not hold", func() {
+			for _, s := range []string{
+				"ns=/intel/mem/*",
+				"plugin!=psutil",
+				"version>3",
+				"version<=2",
+				"tag.unit=bytes",
+				"tag.missing=x",
+				"unit=bytes",
+			} {
+				q, err := ParseMetricQuery(s)
+				So(err, ShouldBeNil)
+				So(q.Match(m), ShouldBeFalse)
+			}
+		})
+		Convey("accepts spaces, lower case and quoted values", func() {
+			q, err := ParseMetricQuery(`ns = /intel/(cpu|mem)/*/user and tag.desc="cpu user time" AND unit=percent`)
+			So(err, ShouldBeNil)
+			So(q.Match(m), ShouldBeTrue)
+		})
+		Convey("rejects invalid queries", func() {
+			for _, s := range []string{
+				"",
+				"ns",
+				"foo=bar",
+				"version=x",
+				"plugin>=psutil",
+				"ns=/intel/* AND",
+				"AND ns=/intel/*",
+				`tag.desc="cpu`,
+				"=psutil",
+				"ns=/intel/(cpu",
+			} {
+				_, err := ParseMetricQuery(s)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
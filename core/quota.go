@@ -0,0 +1,69 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrQuotaExceeded is returned when a task would bring a tenant or a
+	// principal of the API over one of its quotas, which another task
+	// removed or slowed down makes room for
+	ErrQuotaExceeded = errors.New("Quota exceeded")
+	// ErrTaskOverQuota is returned when a task exceeds one of the quotas of
+	// its tenant or principal on its own, whatever the other tasks
+	ErrTaskOverQuota = errors.New("Task exceeds the quota on its own")
+)
+
+// Quota bounds what the tasks of a tenant, or those created by a principal
+// of the API, may ask of snapd. A limit of 0 means no limit.
+type Quota struct {
+	// MaxTasks is the maximum number of tasks, deleted ones aside
+	MaxTasks int `json:"max_tasks,omitempty"yaml:"max_tasks,omitempty"`
+	// MaxCollectionRate is the maximum number of collections per second of
+	// the tasks combined. The tasks fired by events rather than on time
+	// are not counted.
+	MaxCollectionRate float64 `json:"max_collection_rate,omitempty"yaml:"max_collection_rate,omitempty"`
+	// MaxMetrics is the maximum number of metrics a task collects once
+	// its namespaces are expanded, checked on each collection as the
+	// catalog grows
+	MaxMetrics int `json:"max_metrics,omitempty"yaml:"max_metrics,omitempty"`
+}
+
+// Validate returns the problems found in the quota, prefixed with the path
+// of the quota in the configuration
+func (q *Quota) Validate(path string) []error {
+	if q == nil {
+		return nil
+	}
+	var errs []error
+	if q.MaxTasks < 0 {
+		errs = append(errs, fmt.Errorf("%s.max_tasks: must not be negative", path))
+	}
+	if q.MaxCollectionRate < 0 {
+		errs = append(errs, fmt.Errorf("%s.max_collection_rate: must not be negative", path))
+	}
+	if q.MaxMetrics < 0 {
+		errs = append(errs, fmt.Errorf("%s.max_metrics: must not be negative", path))
+	}
+	return errs
+}
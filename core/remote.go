@@ -0,0 +1,85 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RemoteSnapd is how to reach the REST API of another snapd
+type RemoteSnapd struct {
+	// URL is the URL of the REST API, e.g. "https://10.0.0.2:8181"
+	URL                string
+	InsecureSkipVerify bool
+	// Password is the password of the REST API, if it needs one
+	Password string
+	// Sign signs the requests made to the REST API, e.g. by the members of a
+	// tribe signing their requests to each other. The password is not sent
+	// with a signed request.
+	Sign func(*http.Request) error
+	// TLSConfig is the TLS configuration of the requests over HTTPS, e.g.
+	// the certificate presented, InsecureSkipVerify aside. The default one
+	// is used when nil.
+	TLSConfig *tls.Config
+}
+
+// Equal returns whether the two snapd are reached the same way. The signers
+// are left out, as functions cannot be compared.
+func (s RemoteSnapd) Equal(o RemoteSnapd) bool {
+	return s.URL == o.URL && s.InsecureSkipVerify == o.InsecureSkipVerify &&
+		s.Password == o.Password && s.TLSConfig == o.TLSConfig
+}
+
+// MemberResolver tells how to reach the snapd of a tribe member given its
+// name
+type MemberResolver interface {
+	ResolveMember(name string) (RemoteSnapd, error)
+}
+
+// AggregatorResolver tells how to reach the snapd of the tribe member with
+// the aggregator role, which republishes the metrics the other members
+// forward to it
+type AggregatorResolver interface {
+	ResolveAggregator() (string, RemoteSnapd, error)
+}
+
+// ParseRemoteSnapd returns how to reach the snapd with the REST API at the
+// URL, in the format of http(s)://<host>:<port>. The user info of the URL,
+// if any, is the credentials of the REST API.
+func ParseRemoteSnapd(rawurl string) (RemoteSnapd, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return RemoteSnapd{}, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return RemoteSnapd{}, fmt.Errorf("%q is not an http or https URL", rawurl)
+	}
+	snapd := RemoteSnapd{}
+	if u.User != nil {
+		snapd.Password, _ = u.User.Password()
+		u.User = nil
+	}
+	snapd.URL = strings.TrimSuffix(u.String(), "/")
+	return snapd, nil
+}
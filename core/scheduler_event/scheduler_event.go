@@ -20,17 +20,24 @@ limitations under the License.
 package scheduler_event
 
 import (
+	"time"
+
 	"github.com/intelsdi-x/snap/core"
 )
 
 const (
 	TaskCreated            = "Scheduler.TaskCreated"
 	TaskDeleted            = "Scheduler.TaskDeleted"
+	TaskRestored           = "Scheduler.TaskRestored"
 	TaskStarted            = "Scheduler.TaskStarted"
 	TaskStopped            = "Scheduler.TaskStopped"
 	TaskDisabled           = "Scheduler.TaskDisabled"
+	TaskPaused             = "Scheduler.TaskPaused"
+	TaskResumed            = "Scheduler.TaskResumed"
+	MaintenanceChanged     = "Scheduler.MaintenanceChanged"
 	MetricCollected        = "Scheduler.MetricsCollected"
 	MetricCollectionFailed = "Scheduler.MetricCollectionFailed"
+	TaskRunFinished        = "Scheduler.TaskRunFinished"
 )
 
 type TaskStartedEvent struct {
@@ -61,6 +68,14 @@ func (e TaskDeletedEvent) Namespace() string {
 	return TaskDeleted
 }
 
+type TaskRestoredEvent struct {
+	TaskID string
+}
+
+func (e TaskRestoredEvent) Namespace() string {
+	return TaskRestored
+}
+
 type TaskStoppedEvent struct {
 	TaskID string
 	Source string
@@ -79,6 +94,34 @@ func (e TaskDisabledEvent) Namespace() string {
 	return TaskDisabled
 }
 
+type TaskPausedEvent struct {
+	TaskID string
+	Until  time.Time
+	Source string
+}
+
+func (e TaskPausedEvent) Namespace() string {
+	return TaskPaused
+}
+
+type TaskResumedEvent struct {
+	TaskID string
+	Source string
+}
+
+func (e TaskResumedEvent) Namespace() string {
+	return TaskResumed
+}
+
+type MaintenanceChangedEvent struct {
+	Enabled     bool
+	HoldPublish bool
+}
+
+func (e MaintenanceChangedEvent) Namespace() string {
+	return MaintenanceChanged
+}
+
 type MetricCollectedEvent struct {
 	TaskID  string
 	Metrics []core.Metric
@@ -96,3 +139,12 @@ type MetricCollectionFailedEvent struct {
 func (e MetricCollectionFailedEvent) Namespace() string {
 	return MetricCollectionFailed
 }
+
+type TaskRunFinishedEvent struct {
+	TaskID string
+	Failed bool
+}
+
+func (e TaskRunFinishedEvent) Namespace() string {
+	return TaskRunFinished
+}
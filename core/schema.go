@@ -0,0 +1,139 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownSchema is returned for a schema name or version which is not
+// registered
+var ErrUnknownSchema = errors.New("unknown schema")
+
+// Schema is a versioned JSON Schema of the metrics snapd publishes as JSON,
+// so that the consumers of the content types it describes can validate it
+// and follow its evolution. A version only adds optional fields to the one
+// before it; removing, renaming or retyping a field makes a new version.
+type Schema struct {
+	// Name identifies the schema across its versions, e.g. snap.metric
+	Name string `json:"name"`
+	// Version is incremented on each incompatible change of the document
+	Version int `json:"version"`
+	// ContentTypes are the content types the schema describes
+	ContentTypes []string `json:"content_types"`
+	// Document is the JSON Schema
+	Document json.RawMessage `json:"document"`
+}
+
+// schemas are the registered schemas by name and version
+var schemas = struct {
+	sync.RWMutex
+	table map[string]map[int]Schema
+}{table: map[string]map[int]Schema{}}
+
+// RegisterSchema registers a version of a schema. The version with the
+// highest number is the current one.
+func RegisterSchema(s Schema) error {
+	if s.Name == "" {
+		return errors.New("schema without a name")
+	}
+	if s.Version < 1 {
+		return fmt.Errorf("schema %s: version %d is not positive", s.Name, s.Version)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(s.Document, &doc); err != nil {
+		return fmt.Errorf("schema %s: document is not a JSON object: %v", s.Name, err)
+	}
+	schemas.Lock()
+	defer schemas.Unlock()
+	versions, ok := schemas.table[s.Name]
+	if !ok {
+		versions = map[int]Schema{}
+		schemas.table[s.Name] = versions
+	}
+	if _, ok := versions[s.Version]; ok {
+		return fmt.Errorf("schema %s version %d already registered", s.Name, s.Version)
+	}
+	versions[s.Version] = s
+	return nil
+}
+
+// GetSchema returns a version of a schema, the current one when the version
+// is 0
+func GetSchema(name string, version int) (Schema, error) {
+	schemas.RLock()
+	defer schemas.RUnlock()
+	versions, ok := schemas.table[name]
+	if !ok {
+		return Schema{}, fmt.Errorf("%v: %s", ErrUnknownSchema, name)
+	}
+	if version == 0 {
+		return versions[currentVersion(versions)], nil
+	}
+	s, ok := versions[version]
+	if !ok {
+		return Schema{}, fmt.Errorf("%v: %s version %d", ErrUnknownSchema, name, version)
+	}
+	return s, nil
+}
+
+// Schemas returns the current version of each schema, sorted by name
+func Schemas() []Schema {
+	schemas.RLock()
+	defer schemas.RUnlock()
+	names := make([]string, 0, len(schemas.table))
+	for name := range schemas.table {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ss := make([]Schema, len(names))
+	for i, name := range names {
+		versions := schemas.table[name]
+		ss[i] = versions[currentVersion(versions)]
+	}
+	return ss
+}
+
+// SchemaVersions returns the registered versions of a schema, in increasing
+// order
+func SchemaVersions(name string) []int {
+	schemas.RLock()
+	defer schemas.RUnlock()
+	vs := make([]int, 0, len(schemas.table[name]))
+	for v := range schemas.table[name] {
+		vs = append(vs, v)
+	}
+	sort.Ints(vs)
+	return vs
+}
+
+func currentVersion(versions map[int]Schema) int {
+	current := 0
+	for v := range versions {
+		if v > current {
+			current = v
+		}
+	}
+	return current
+}
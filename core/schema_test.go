@@ -0,0 +1,75 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSchemaRegistry(t *testing.T) {
+	doc := json.RawMessage(`{"type": "object"}`)
+
+	Convey("Given two versions of a schema", t, func() {
+		So(RegisterSchema(Schema{Name: "test.metric", Version: 1, ContentTypes: []string{"test.json"}, Document: doc}), ShouldBeNil)
+		So(RegisterSchema(Schema{Name: "test.metric", Version: 2, ContentTypes: []string{"test.json"}, Document: doc}), ShouldBeNil)
+		defer func() {
+			schemas.Lock()
+			delete(schemas.table, "test.metric")
+			schemas.Unlock()
+		}()
+
+		Convey("the highest version is the current one", func() {
+			s, err := GetSchema("test.metric", 0)
+			So(err, ShouldBeNil)
+			So(s.Version, ShouldEqual, 2)
+			var current []Schema
+			for _, s := range Schemas() {
+				if s.Name == "test.metric" {
+					current = append(current, s)
+				}
+			}
+			So(current, ShouldHaveLength, 1)
+			So(current[0].Version, ShouldEqual, 2)
+		})
+		Convey("the previous versions are kept", func() {
+			s, err := GetSchema("test.metric", 1)
+			So(err, ShouldBeNil)
+			So(s.Version, ShouldEqual, 1)
+			So(SchemaVersions("test.metric"), ShouldResemble, []int{1, 2})
+		})
+		Convey("a version cannot be registered again", func() {
+			So(RegisterSchema(Schema{Name: "test.metric", Version: 2, Document: doc}), ShouldNotBeNil)
+		})
+		Convey("an unknown version is an error", func() {
+			_, err := GetSchema("test.metric", 3)
+			So(err, ShouldNotBeNil)
+			_, err = GetSchema("test.unknown", 0)
+			So(err.Error(), ShouldContainSubstring, ErrUnknownSchema.Error())
+		})
+	})
+	Convey("A schema needs a name, a positive version and a JSON object", t, func() {
+		So(RegisterSchema(Schema{Version: 1, Document: doc}), ShouldNotBeNil)
+		So(RegisterSchema(Schema{Name: "test.invalid", Document: doc}), ShouldNotBeNil)
+		So(RegisterSchema(Schema{Name: "test.invalid", Version: 1, Document: json.RawMessage(`[]`)}), ShouldNotBeNil)
+	})
+}
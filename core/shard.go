@@ -0,0 +1,43 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"hash/fnv"
+	"strings"
+)
+
+// Sharder tells which share of the metrics of a sharded task this snapd
+// collects: the index of its shard and the number of shards, which is 1
+// when the task is not shared with other snapd instances.
+type Sharder interface {
+	Shard(taskID string) (index, count int)
+}
+
+// InShard returns whether a namespace belongs to a shard. Every namespace
+// belongs to exactly one of the shards, whichever snapd computes it.
+func InShard(ns []string, index, count int) bool {
+	if count < 2 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(strings.Join(ns, "/")))
+	return int(h.Sum32()%uint32(count)) == index
+}
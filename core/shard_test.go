@@ -0,0 +1,59 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInShard(t *testing.T) {
+	Convey("Sharding namespaces", t, func() {
+		var nss [][]string
+		for i := 0; i < 1000; i++ {
+			nss = append(nss, []string{"intel", "mock", fmt.Sprintf("host%d", i), "cpu"})
+		}
+		Convey("puts every namespace in exactly one shard", func() {
+			counts := make([]int, 3)
+			for _, ns := range nss {
+				n := 0
+				for i := range counts {
+					if InShard(ns, i, 3) {
+						counts[i]++
+						n++
+					}
+				}
+				So(n, ShouldEqual, 1)
+			}
+			Convey("and spreads them across the shards", func() {
+				for _, c := range counts {
+					So(c, ShouldBeGreaterThan, 250)
+				}
+			})
+		})
+		Convey("keeps every namespace when there is a single shard", func() {
+			for _, ns := range nss {
+				So(InShard(ns, 0, 1), ShouldBeTrue)
+			}
+		})
+	})
+}
@@ -20,6 +20,8 @@ limitations under the License.
 package core
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
@@ -38,6 +40,8 @@ const (
 	TaskFiring
 	TaskEnded
 	TaskStopping
+	TaskPaused
+	TaskDeleted
 )
 
 var (
@@ -48,13 +52,320 @@ var (
 		TaskFiring:   "Running",  // running (firing can happen so briefly we don't want to try and render it as a string state)
 		TaskEnded:    "Ended",    // ended, not resumable because the schedule will not fire again
 		TaskStopping: "Stopping", // channel has been closed, wait for TaskStopped state
+		TaskPaused:   "Paused",   // subscribed but not firing, resumable
+		TaskDeleted:  "Deleted",  // deleted but retained, restorable until purged
 	}
 )
 
+// Overrun policies decide what happens when the schedule of a task fires
+// while a collection of the task is still running
+const (
+	// OverrunSkip waits on the schedule once the run is done, as tasks always
+	// did, skipping the intervals which fired during the run
+	OverrunSkip = "skip"
+	// OverrunQueue runs the intervals which fired during the run as soon as
+	// it finishes, up to the queue depth, and skips the rest
+	OverrunQueue = "queue"
+	// OverrunStretch waits a full interval from the end of a run which
+	// overran, stretching the interval by the duration of the runs while
+	// they overrun
+	OverrunStretch = "stretch"
+)
+
+// OverrunPolicies lists the valid overrun policies
+var OverrunPolicies = []string{OverrunSkip, OverrunQueue, OverrunStretch}
+
+// ValidateOverrunPolicy returns an error if the overrun policy or its queue
+// depth are not valid
+func ValidateOverrunPolicy(policy string, depth uint) error {
+	switch policy {
+	case OverrunSkip, OverrunStretch:
+		return nil
+	case OverrunQueue:
+		if depth < 1 {
+			return fmt.Errorf("overrun queue depth must be greater than 0")
+		}
+		return nil
+	}
+	return fmt.Errorf("overrun policy %q is not one of %v", policy, OverrunPolicies)
+}
+
+// Back-pressure policies decide how a task responds to a publisher of its
+// workflow asking it to slow down
+const (
+	// BackPressureBatch holds the metrics of the runs back and publishes
+	// them together, doubling the number of runs batched each time the
+	// publisher asks to slow down
+	BackPressureBatch = "batch"
+	// BackPressureStretch skips intervals after each run, doubling the
+	// number skipped each time the publisher asks to slow down, and drops
+	// the metrics the publisher refused
+	BackPressureStretch = "stretch"
+	// BackPressureWAL writes the metrics the publisher refused to a
+	// write-ahead log, and publishes them with the metrics of the next runs
+	// until the publisher accepts them
+	BackPressureWAL = "wal"
+)
+
+// BackPressurePolicies lists the valid back-pressure policies
+var BackPressurePolicies = []string{BackPressureBatch, BackPressureStretch, BackPressureWAL}
+
+// ValidateBackPressurePolicy returns an error if the back-pressure policy is
+// not valid. An empty policy records a publisher asking to slow down as a
+// failure of the run.
+func ValidateBackPressurePolicy(policy string) error {
+	switch policy {
+	case "", BackPressureBatch, BackPressureStretch, BackPressureWAL:
+		return nil
+	}
+	return fmt.Errorf("back-pressure policy %q is not one of %v", policy, BackPressurePolicies)
+}
+
+// BackPressureState is the back pressure a publisher of a task puts on it
+type BackPressureState struct {
+	// Publisher is the name and version of the publisher, e.g. "file:3"
+	Publisher string `json:"publisher"`
+	Policy    string `json:"policy"`
+	// Active is set while the task publishes slower because of the publisher
+	Active bool `json:"active"`
+	// SlowDowns counts the times the publisher asked to slow down
+	SlowDowns    uint      `json:"slow_downs"`
+	LastSlowDown time.Time `json:"last_slow_down,omitempty"`
+	// BatchSize is the number of runs published together by the batch policy
+	BatchSize uint `json:"batch_size,omitempty"`
+	// Stretch is the number of intervals skipped after each run by the
+	// stretch policy
+	Stretch uint `json:"stretch,omitempty"`
+	// Skipped counts the intervals skipped by the stretch policy
+	Skipped uint `json:"skipped,omitempty"`
+	// Buffered is the number of runs held in the write-ahead log by the wal
+	// policy
+	Buffered uint `json:"buffered,omitempty"`
+	// Dropped counts the runs dropped: refused by the publisher without a
+	// policy, under the stretch policy or past the largest batch, or past the
+	// runs the write-ahead log holds
+	Dropped uint `json:"dropped,omitempty"`
+	// Held is set while the runs are held in the write-ahead log for the
+	// maintenance of snapd
+	Held bool `json:"held,omitempty"`
+}
+
+// Task priorities decide the order in which the collections of tasks are
+// worked when the collector workers are saturated
+const (
+	// TaskPriorityCritical collections are worked before the others
+	TaskPriorityCritical = "critical"
+	// TaskPriorityNormal is the default priority
+	TaskPriorityNormal = "normal"
+	// TaskPriorityLow collections are shed when the work queue is full
+	TaskPriorityLow = "low"
+)
+
+// TaskPriorities lists the valid task priorities
+var TaskPriorities = []string{TaskPriorityCritical, TaskPriorityNormal, TaskPriorityLow}
+
+// ValidateTaskPriority returns an error if the task priority is not valid
+func ValidateTaskPriority(priority string) error {
+	for _, p := range TaskPriorities {
+		if p == priority {
+			return nil
+		}
+	}
+	return fmt.Errorf("task priority %q is not one of %v", priority, TaskPriorities)
+}
+
+// Dependency conditions decide which runs of the task a task depends on run
+// the dependent task
+const (
+	// DependOnSuccess runs the dependent task when a run succeeds
+	DependOnSuccess = "success"
+	// DependOnFailure runs the dependent task when a run fails
+	DependOnFailure = "failure"
+	// DependOnCompletion runs the dependent task when a run ends either way
+	DependOnCompletion = "completion"
+)
+
+// DependencyConditions lists the valid dependency conditions
+var DependencyConditions = []string{DependOnSuccess, DependOnFailure, DependOnCompletion}
+
+// TaskDependency makes a task with a trigger schedule run when a run of the
+// task it depends on ends as the condition says
+type TaskDependency struct {
+	TaskID string
+	On     string
+}
+
+// ValidateTaskDependency returns an error if the task dependency is not valid
+func ValidateTaskDependency(d TaskDependency) error {
+	if d.TaskID == "" {
+		return fmt.Errorf("task dependency is missing the task id")
+	}
+	for _, c := range DependencyConditions {
+		if c == d.On {
+			return nil
+		}
+	}
+	return fmt.Errorf("dependency condition %q is not one of %v", d.On, DependencyConditions)
+}
+
+// Matches returns whether a run of the task depended on, which failed or
+// not, runs the dependent task
+func (d TaskDependency) Matches(failed bool) bool {
+	switch d.On {
+	case DependOnSuccess:
+		return !failed
+	case DependOnFailure:
+		return failed
+	}
+	return true
+}
+
+// Timestamp sources say where the timestamps of the metrics collected by a
+// task come from
+const (
+	// TimestampSourcePlugin keeps the timestamps set by the collectors, the
+	// default
+	TimestampSourcePlugin = "plugin"
+	// TimestampSourceReceipt timestamps the metrics when snapd receives them
+	TimestampSourceReceipt = "receipt"
+	// TimestampSourceTick timestamps the metrics of a run with the time the
+	// schedule fired it
+	TimestampSourceTick = "tick"
+)
+
+// TimestampSources lists the valid timestamp sources
+var TimestampSources = []string{TimestampSourcePlugin, TimestampSourceReceipt, TimestampSourceTick}
+
+// Out of range actions say what happens to a metric whose plugin timestamp
+// is out of the bounds of the timestamp policy
+const (
+	// TimestampOutOfRangeReject drops the metric, the default
+	TimestampOutOfRangeReject = "reject"
+	// TimestampOutOfRangeClamp moves the timestamp to the bound it crossed
+	TimestampOutOfRangeClamp = "clamp"
+)
+
+// TimestampPolicy says where the timestamps of the metrics collected by a
+// task come from and bounds those set by the collectors, relative to the
+// time snapd receives the metrics
+type TimestampPolicy struct {
+	Source string
+	// MaxFuture and MaxPast bound the timestamps set by the collectors, 0
+	// leaving them unbounded
+	MaxFuture  time.Duration
+	MaxPast    time.Duration
+	OutOfRange string
+}
+
+// ValidateTimestampPolicy returns an error if the timestamp policy is not
+// valid. Empty fields stand for the defaults.
+func ValidateTimestampPolicy(p TimestampPolicy) error {
+	switch p.Source {
+	case "", TimestampSourcePlugin, TimestampSourceReceipt, TimestampSourceTick:
+	default:
+		return fmt.Errorf("timestamp source %q is not one of %v", p.Source, TimestampSources)
+	}
+	switch p.OutOfRange {
+	case "", TimestampOutOfRangeReject, TimestampOutOfRangeClamp:
+	default:
+		return fmt.Errorf("timestamp out of range action %q is not one of %v", p.OutOfRange, []string{TimestampOutOfRangeReject, TimestampOutOfRangeClamp})
+	}
+	if p.MaxFuture < 0 || p.MaxPast < 0 {
+		return errors.New("the timestamp bounds cannot be negative")
+	}
+	return nil
+}
+
+// Timestamp returns the timestamp of a metric set to ts by its collector,
+// received at receipt in a run fired at tick, or false for a metric to drop.
+// A collector leaving the timestamp unset is taken to mean the receipt.
+func (p TimestampPolicy) Timestamp(ts, receipt, tick time.Time) (time.Time, bool) {
+	switch p.Source {
+	case TimestampSourceReceipt:
+		return receipt, true
+	case TimestampSourceTick:
+		return tick, true
+	}
+	if ts.IsZero() {
+		return receipt, true
+	}
+	if p.MaxFuture > 0 {
+		if max := receipt.Add(p.MaxFuture); ts.After(max) {
+			return max, p.OutOfRange == TimestampOutOfRangeClamp
+		}
+	}
+	if p.MaxPast > 0 {
+		if min := receipt.Add(-p.MaxPast); ts.Before(min) {
+			return min, p.OutOfRange == TimestampOutOfRangeClamp
+		}
+	}
+	return ts, true
+}
+
+// TaskRun records a run of the workflow of a task
+type TaskRun struct {
+	Start    time.Time
+	Duration time.Duration
+	// MetricCount is the number of metrics collected
+	MetricCount int
+	Steps       []TaskRunStep
+}
+
+// TaskRunStep records the collection, or a process or publish step of a
+// task run
+type TaskRunStep struct {
+	// Type is the type of the plugin, "collector", "processor" or "publisher"
+	Type string
+	// Plugin is the name and version of processor and publisher plugins
+	Plugin   string
+	Start    time.Time
+	Duration time.Duration
+	Errors   []string
+}
+
+// Failed returns true if a step of the run failed
+func (r TaskRun) Failed() bool {
+	for _, s := range r.Steps {
+		if len(s.Errors) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 type TaskWatcherCloser interface {
 	Close() error
 }
 
+// MaxTaskWatchReplay is the number of lifecycle events kept per task to be
+// replayed to new watchers
+const MaxTaskWatchReplay = 20
+
+// TaskWatchOptions selects the events received by a task watcher
+type TaskWatchOptions struct {
+	// LifecycleOnly leaves out the collected metrics, the watcher only
+	// receives the task started, stopped and disabled events
+	LifecycleOnly bool
+	// Replay is the number of the last lifecycle events of the task replayed
+	// to the watcher when it starts watching, up to MaxTaskWatchReplay
+	Replay uint
+}
+
+// TaskUpdate describes the changes made to a task without recreating it
+type TaskUpdate struct {
+	// Interval replaces the interval of the schedule when not 0
+	Interval time.Duration
+	// Config sets config items of the collect node by namespace, a nil value
+	// removes the item
+	Config map[string]map[string]interface{}
+	// AddMetrics adds metrics to the collect node with their versions
+	AddMetrics map[string]int
+	// RemoveMetrics removes metrics from the collect node
+	RemoveMetrics []string
+	// Description replaces the description of the task when not nil
+	Description *string
+}
+
 type TaskWatcherHandler interface {
 	CatchCollection([]Metric)
 	CatchTaskStarted()
@@ -78,17 +389,56 @@ type Task interface {
 	FailedCount() uint
 	LastFailureMessage() string
 	LastRunTime() *time.Time
+	PausedUntil() *time.Time
 	CreationTime() *time.Time
 	DeadlineDuration() time.Duration
 	SetDeadlineDuration(time.Duration)
 	SetTaskID(id string)
 	SetStopOnFailure(uint)
 	GetStopOnFailure() uint
+	SetOverrunPolicy(string, uint)
+	OverrunPolicy() (string, uint)
+	OverrunCount() uint
+	SetPriority(string)
+	Priority() string
+	ShedCount() uint
+	DroppedMetricCount() uint
+	SuspectMetricCount() uint
+	BackPressure() []BackPressureState
+	Runs() []TaskRun
+	SetAlertRules([]AlertRule)
+	AlertRules() []AlertRule
+	SetSharded(bool)
+	Sharded() bool
+	SetMetricRefreshInterval(time.Duration)
+	MetricRefreshInterval() time.Duration
+	SetTimestampPolicy(TimestampPolicy)
+	TimestampPolicy() TimestampPolicy
+	SetDescription(string)
+	Description() string
+	SetDependencies([]TaskDependency)
+	Dependencies() []TaskDependency
+	SetCreatedBy(string)
+	CreatedBy() string
+	SetTenant(string)
+	Tenant() string
+	RecordUpdate(by string, at time.Time)
+	UpdatedBy() string
+	UpdateTime() *time.Time
 	Option(...TaskOption) TaskOption
 	WMap() *wmap.WorkflowMap
 	Schedule() schedule.Schedule
 }
 
+// DeletedTask is a task retained after its deletion, from which it can be
+// restored until it is purged
+type DeletedTask struct {
+	Task       Task
+	DeleteTime time.Time
+	// PurgeTime is when the task is purged for good
+	PurgeTime time.Time
+}
+
 type TaskOption func(Task) TaskOption
 
 // TaskDeadlineDuration sets the tasks deadline.
@@ -128,6 +478,152 @@ func OptionStopOnFailure(v uint) TaskOption {
 	}
 }
 
+// OptionOverrunPolicy sets the tasks overrun policy and, for the queue
+// policy, the maximum number of runs queued behind a run which overruns
+func OptionOverrunPolicy(policy string, depth uint) TaskOption {
+	return func(t Task) TaskOption {
+		previous, previousDepth := t.OverrunPolicy()
+		t.SetOverrunPolicy(policy, depth)
+		log.WithFields(log.Fields{
+			"_module":             "core",
+			"_block":              "OptionOverrunPolicy",
+			"task-id":             t.ID(),
+			"task-name":           t.GetName(),
+			"overrun-policy":      policy,
+			"overrun-queue-depth": depth,
+		}).Debug("Setting overrun policy for task")
+		return OptionOverrunPolicy(previous, previousDepth)
+	}
+}
+
+// OptionTaskPriority sets the tasks priority
+func OptionTaskPriority(priority string) TaskOption {
+	return func(t Task) TaskOption {
+		previous := t.Priority()
+		t.SetPriority(priority)
+		log.WithFields(log.Fields{
+			"_module":       "core",
+			"_block":        "OptionTaskPriority",
+			"task-id":       t.ID(),
+			"task-name":     t.GetName(),
+			"task-priority": priority,
+		}).Debug("Setting priority for task")
+		return OptionTaskPriority(previous)
+	}
+}
+
+// OptionAlertRules sets the alert rules evaluated against the metrics
+// collected by the task
+func OptionAlertRules(rules []AlertRule) TaskOption {
+	return func(t Task) TaskOption {
+		previous := t.AlertRules()
+		t.SetAlertRules(rules)
+		log.WithFields(log.Fields{
+			"_module":     "core",
+			"_block":      "OptionAlertRules",
+			"task-id":     t.ID(),
+			"task-name":   t.GetName(),
+			"alert-rules": len(rules),
+		}).Debug("Setting alert rules for task")
+		return OptionAlertRules(previous)
+	}
+}
+
+// OptionTaskSharded sets whether each snapd sharing the task collects only
+// its shard of the metrics of the task
+func OptionTaskSharded(sharded bool) TaskOption {
+	return func(t Task) TaskOption {
+		previous := t.Sharded()
+		t.SetSharded(sharded)
+		log.WithFields(log.Fields{
+			"_module":   "core",
+			"_block":    "OptionTaskSharded",
+			"task-id":   t.ID(),
+			"task-name": t.GetName(),
+			"sharded":   sharded,
+		}).Debug("Setting sharding for task")
+		return OptionTaskSharded(previous)
+	}
+}
+
+// OptionMetricRefreshInterval sets how often the metric types of the
+// collectors supporting dynamic metrics the task collects from are refreshed
+func OptionMetricRefreshInterval(interval time.Duration) TaskOption {
+	return func(t Task) TaskOption {
+		previous := t.MetricRefreshInterval()
+		t.SetMetricRefreshInterval(interval)
+		log.WithFields(log.Fields{
+			"_module":                 "core",
+			"_block":                  "OptionMetricRefreshInterval",
+			"task-id":                 t.ID(),
+			"task-name":               t.GetName(),
+			"metric-refresh-interval": interval,
+		}).Debug("Setting metric refresh interval for task")
+		return OptionMetricRefreshInterval(previous)
+	}
+}
+
+// OptionTimestampPolicy sets where the timestamps of the metrics collected
+// by the task come from and the bounds of those set by the collectors
+func OptionTimestampPolicy(policy TimestampPolicy) TaskOption {
+	return func(t Task) TaskOption {
+		previous := t.TimestampPolicy()
+		t.SetTimestampPolicy(policy)
+		log.WithFields(log.Fields{
+			"_module":          "core",
+			"_block":           "OptionTimestampPolicy",
+			"task-id":          t.ID(),
+			"task-name":        t.GetName(),
+			"timestamp-source": policy.Source,
+		}).Debug("Setting timestamp policy for task")
+		return OptionTimestampPolicy(previous)
+	}
+}
+
+// OptionTaskDescription sets the free-text description of the task
+func OptionTaskDescription(description string) TaskOption {
+	return func(t Task) TaskOption {
+		previous := t.Description()
+		t.SetDescription(description)
+		return OptionTaskDescription(previous)
+	}
+}
+
+// OptionTaskDependencies sets the tasks the runs of which run the task
+func OptionTaskDependencies(deps []TaskDependency) TaskOption {
+	return func(t Task) TaskOption {
+		previous := t.Dependencies()
+		t.SetDependencies(deps)
+		log.WithFields(log.Fields{
+			"_module":      "core",
+			"_block":       "OptionTaskDependencies",
+			"task-id":      t.ID(),
+			"task-name":    t.GetName(),
+			"dependencies": len(deps),
+		}).Debug("Setting dependencies for task")
+		return OptionTaskDependencies(previous)
+	}
+}
+
+// OptionTaskCreatedBy sets the principal of the API which created the task
+func OptionTaskCreatedBy(principal string) TaskOption {
+	return func(t Task) TaskOption {
+		previous := t.CreatedBy()
+		t.SetCreatedBy(principal)
+		return OptionTaskCreatedBy(previous)
+	}
+}
+
+// OptionTaskTenant scopes the task to the tenant of the principal of the API
+// which created it
+func OptionTaskTenant(tenant string) TaskOption {
+	return func(t Task) TaskOption {
+		previous := t.Tenant()
+		t.SetTenant(tenant)
+		return OptionTaskTenant(previous)
+	}
+}
+
 // SetTaskName sets the name of the task.
 // This is optional.
 // If task name is not set, the task name is then defaulted to "Task-<task-id>"
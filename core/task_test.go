@@ -0,0 +1,80 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTimestampPolicy(t *testing.T) {
+	receipt := time.Unix(1000, 0)
+	tick := time.Unix(990, 0)
+	Convey("Validating timestamp policies", t, func() {
+		So(ValidateTimestampPolicy(TimestampPolicy{}), ShouldBeNil)
+		So(ValidateTimestampPolicy(TimestampPolicy{Source: TimestampSourceTick, OutOfRange: TimestampOutOfRangeClamp}), ShouldBeNil)
+		So(ValidateTimestampPolicy(TimestampPolicy{Source: "collector"}), ShouldNotBeNil)
+		So(ValidateTimestampPolicy(TimestampPolicy{OutOfRange: "drop"}), ShouldNotBeNil)
+		So(ValidateTimestampPolicy(TimestampPolicy{MaxPast: -time.Second}), ShouldNotBeNil)
+	})
+	Convey("The timestamps of the receipt and the tick replace those of the plugins", t, func() {
+		ts, ok := TimestampPolicy{Source: TimestampSourceReceipt}.Timestamp(time.Unix(5, 0), receipt, tick)
+		So(ok, ShouldBeTrue)
+		So(ts, ShouldResemble, receipt)
+		ts, ok = TimestampPolicy{Source: TimestampSourceTick}.Timestamp(time.Unix(5, 0), receipt, tick)
+		So(ok, ShouldBeTrue)
+		So(ts, ShouldResemble, tick)
+	})
+	Convey("The timestamps of the plugins", t, func() {
+		p := TimestampPolicy{MaxFuture: time.Minute, MaxPast: time.Hour}
+		Convey("are kept within the bounds", func() {
+			ts, ok := p.Timestamp(receipt.Add(-time.Minute), receipt, tick)
+			So(ok, ShouldBeTrue)
+			So(ts, ShouldResemble, receipt.Add(-time.Minute))
+		})
+		Convey("are the receipt when unset", func() {
+			ts, ok := p.Timestamp(time.Time{}, receipt, tick)
+			So(ok, ShouldBeTrue)
+			So(ts, ShouldResemble, receipt)
+		})
+		Convey("are rejected past the bounds", func() {
+			_, ok := p.Timestamp(receipt.Add(2*time.Minute), receipt, tick)
+			So(ok, ShouldBeFalse)
+			_, ok = p.Timestamp(receipt.Add(-2*time.Hour), receipt, tick)
+			So(ok, ShouldBeFalse)
+		})
+		Convey("are clamped to the bounds", func() {
+			p.OutOfRange = TimestampOutOfRangeClamp
+			ts, ok := p.Timestamp(receipt.Add(2*time.Minute), receipt, tick)
+			So(ok, ShouldBeTrue)
+			So(ts, ShouldResemble, receipt.Add(time.Minute))
+			ts, ok = p.Timestamp(receipt.Add(-2*time.Hour), receipt, tick)
+			So(ok, ShouldBeTrue)
+			So(ts, ShouldResemble, receipt.Add(-time.Hour))
+		})
+		Convey("are unbounded by default", func() {
+			ts, ok := TimestampPolicy{}.Timestamp(time.Unix(5, 0), receipt, tick)
+			So(ok, ShouldBeTrue)
+			So(ts, ShouldResemble, time.Unix(5, 0))
+		})
+	})
+}
@@ -0,0 +1,50 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tribe_event
+
+import "time"
+
+const (
+	MemberLeft = "Tribe.MemberLeft"
+	ClockSkew  = "Tribe.ClockSkew"
+)
+
+type MemberLeftEvent struct {
+	Name string
+	Addr string
+}
+
+func (e MemberLeftEvent) Namespace() string {
+	return MemberLeft
+}
+
+// ClockSkewEvent is emitted when the clock of a member is found off the
+// local clock by more than the threshold
+type ClockSkewEvent struct {
+	Name string
+	// Skew is how far the clock of the member is ahead of the local clock,
+	// negative when it is behind
+	Skew      time.Duration
+	Threshold time.Duration
+}
+
+func (e ClockSkewEvent) Namespace() string {
+	return ClockSkew
+}
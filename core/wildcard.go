@@ -0,0 +1,213 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The namespace of a requested metric may select several cataloged metrics:
+//   - '*' matches any characters
+//   - a tuple '(a|b)' matches one of its alternatives
+//   - a tuple alternative 'n-m' matches the numbers from n to m, so
+//     /intel/cpu/(0-15)/idle matches the idle time of the first 16 cpus
+//   - an exclusion '!(a|b)' matches a namespace element matching none of the
+//     alternatives, so /intel/net/!(lo)/bytes skips the loopback interface
+// Any other character, regexp metacharacters included, is matched literally.
+
+var rangeRegexp = regexp.MustCompile(`^(\d+)-(\d+)$`)
+
+// WildcardNamespace is a compiled metric namespace query
+type WildcardNamespace struct {
+	re *regexp.Regexp
+	// checks holds a check for the value of each capture group of re
+	checks []func(string) bool
+}
+
+// CompileWildcardNamespace compiles the namespace of a requested metric
+func CompileWildcardNamespace(ns []string) (*WildcardNamespace, error) {
+	w := &WildcardNamespace{}
+	var exp bytes.Buffer
+	exp.WriteString("^")
+	for i, el := range ns {
+		if i > 0 {
+			exp.WriteString("[.]")
+		}
+		if err := w.compileElement(el, &exp); err != nil {
+			return nil, fmt.Errorf("Invalid metric query %s: %v", JoinNamespace(ns), err)
+		}
+	}
+	exp.WriteString("$")
+	re, err := regexp.Compile(exp.String())
+	if err != nil {
+		return nil, fmt.Errorf("Invalid metric query %s: %v", JoinNamespace(ns), err)
+	}
+	w.re = re
+	return w, nil
+}
+
+func (w *WildcardNamespace) compileElement(el string, exp *bytes.Buffer) error {
+	rs := []rune(el)
+	for i := 0; i < len(rs); i++ {
+		switch r := rs[i]; {
+		case r == '*':
+			exp.WriteString(".*")
+		case r == '!' && i+1 < len(rs) && rs[i+1] == '(':
+			t, n, err := parseTuple(rs[i+1:])
+			if err != nil {
+				return err
+			}
+			i += n
+			exp.WriteString("([^.]+)")
+			w.checks = append(w.checks, func(v string) bool { return !t.match(v) })
+		case r == '(':
+			t, n, err := parseTuple(rs[i:])
+			if err != nil {
+				return err
+			}
+			i += n - 1
+			exp.WriteString(t.pattern())
+			if len(t.ranges) > 0 {
+				w.checks = append(w.checks, t.inRange)
+			}
+		case r == '|' || r == ')':
+			return fmt.Errorf("'%c' outside of a tuple", r)
+		default:
+			exp.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return nil
+}
+
+// Match returns true if the namespace is selected by the query
+func (w *WildcardNamespace) Match(ns []string) bool {
+	key := strings.Join(ns, ".")
+	m := w.re.FindStringSubmatchIndex(key)
+	if m == nil {
+		return false
+	}
+	for i, check := range w.checks {
+		start, end := m[2*i+2], m[2*i+3]
+		if start >= 0 && !check(key[start:end]) {
+			return false
+		}
+	}
+	return true
+}
+
+type tuple struct {
+	// alts holds the expressions of the alternatives which are not ranges
+	alts   []string
+	ranges [][2]int
+	re     *regexp.Regexp
+}
+
+// parseTuple parses the tuple rs starts with and returns it with the number
+// of runes it spans
+func parseTuple(rs []rune) (*tuple, int, error) {
+	t := &tuple{}
+	var alt []rune
+	for i := 1; i < len(rs); i++ {
+		switch rs[i] {
+		case '(':
+			return nil, 0, fmt.Errorf("tuples cannot be nested")
+		case '|', ')':
+			if err := t.add(string(alt)); err != nil {
+				return nil, 0, err
+			}
+			alt = alt[:0]
+			if rs[i] == ')' {
+				if len(t.alts) > 0 {
+					t.re = regexp.MustCompile("^(?:" + strings.Join(t.alts, "|") + ")$")
+				}
+				return t, i + 1, nil
+			}
+		default:
+			alt = append(alt, rs[i])
+		}
+	}
+	return nil, 0, fmt.Errorf("unclosed tuple")
+}
+
+func (t *tuple) add(alt string) error {
+	if alt == "" {
+		return fmt.Errorf("empty tuple alternative")
+	}
+	if m := rangeRegexp.FindStringSubmatch(alt); m != nil {
+		from, err := strconv.Atoi(m[1])
+		if err != nil {
+			return fmt.Errorf("invalid range %s: %v", alt, err)
+		}
+		to, err := strconv.Atoi(m[2])
+		if err != nil {
+			return fmt.Errorf("invalid range %s: %v", alt, err)
+		}
+		if from > to {
+			return fmt.Errorf("invalid range %s: %d is greater than %d", alt, from, to)
+		}
+		t.ranges = append(t.ranges, [2]int{from, to})
+		return nil
+	}
+	exp := ""
+	for _, r := range alt {
+		if r == '*' {
+			exp += ".*"
+		} else {
+			exp += regexp.QuoteMeta(string(r))
+		}
+	}
+	t.alts = append(t.alts, exp)
+	return nil
+}
+
+// pattern returns the expression matching the tuple. Ranges are matched by a
+// capture group checked with inRange, after the other alternatives so those
+// are preferred.
+func (t *tuple) pattern() string {
+	alts := t.alts
+	if len(t.ranges) > 0 {
+		alts = append(alts[:len(alts):len(alts)], `(\d+)`)
+	}
+	return "(?:" + strings.Join(alts, "|") + ")"
+}
+
+func (t *tuple) inRange(v string) bool {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return false
+	}
+	for _, r := range t.ranges {
+		if n >= r[0] && n <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *tuple) match(v string) bool {
+	if t.re != nil && t.re.MatchString(v) {
+		return true
+	}
+	return t.inRange(v)
+}
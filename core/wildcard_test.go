@@ -0,0 +1,75 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCompileWildcardNamespace(t *testing.T) {
+	Convey("CompileWildcardNamespace", t, func() {
+		compile := func(ns ...string) *WildcardNamespace {
+			w, err := CompileWildcardNamespace(ns)
+			So(err, ShouldBeNil)
+			return w
+		}
+		Convey("matches wildcards and tuples", func() {
+			w := compile("intel", "*", "(idle|user)")
+			So(w.Match([]string{"intel", "cpu0", "idle"}), ShouldBeTrue)
+			So(w.Match([]string{"intel", "cpu0", "user"}), ShouldBeTrue)
+			So(w.Match([]string{"intel", "cpu0", "system"}), ShouldBeFalse)
+			So(w.Match([]string{"intelxcpu0", "idle"}), ShouldBeFalse)
+		})
+		Convey("matches regexp metacharacters literally", func() {
+			w := compile("intel", "[foo]+", "bar")
+			So(w.Match([]string{"intel", "[foo]+", "bar"}), ShouldBeTrue)
+			So(w.Match([]string{"intel", "fff", "bar"}), ShouldBeFalse)
+		})
+		Convey("matches numeric ranges", func() {
+			w := compile("intel", "cpu", "(0-15)", "idle")
+			So(w.Match([]string{"intel", "cpu", "0", "idle"}), ShouldBeTrue)
+			So(w.Match([]string{"intel", "cpu", "15", "idle"}), ShouldBeTrue)
+			So(w.Match([]string{"intel", "cpu", "16", "idle"}), ShouldBeFalse)
+			So(w.Match([]string{"intel", "cpu", "total", "idle"}), ShouldBeFalse)
+			w = compile("intel", "cpu(0-1|8-9|total)")
+			So(w.Match([]string{"intel", "cpu9"}), ShouldBeTrue)
+			So(w.Match([]string{"intel", "cputotal"}), ShouldBeTrue)
+			So(w.Match([]string{"intel", "cpu5"}), ShouldBeFalse)
+		})
+		Convey("matches exclusions", func() {
+			w := compile("intel", "net", "!(lo|docker*)", "bytes")
+			So(w.Match([]string{"intel", "net", "eth0", "bytes"}), ShouldBeTrue)
+			So(w.Match([]string{"intel", "net", "lo", "bytes"}), ShouldBeFalse)
+			So(w.Match([]string{"intel", "net", "docker0", "bytes"}), ShouldBeFalse)
+			w = compile("intel", "cpu", "!(0-3)")
+			So(w.Match([]string{"intel", "cpu", "4"}), ShouldBeTrue)
+			So(w.Match([]string{"intel", "cpu", "2"}), ShouldBeFalse)
+		})
+		Convey("rejects malformed queries", func() {
+			for _, el := range []string{"(foo", "foo)", "foo|bar", "(foo||bar)", "((foo))", "()", "(5-1)", "!(lo"} {
+				_, err := CompileWildcardNamespace([]string{"intel", el})
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldStartWith, "Invalid metric query /intel/")
+			}
+		})
+	})
+}
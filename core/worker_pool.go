@@ -0,0 +1,56 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import "time"
+
+// The worker pools of the scheduler, one per kind of job
+const (
+	CollectWorkerPool = "collect"
+	ProcessWorkerPool = "process"
+	PublishWorkerPool = "publish"
+)
+
+// WorkerPools lists the worker pools of the scheduler
+var WorkerPools = []string{CollectWorkerPool, ProcessWorkerPool, PublishWorkerPool}
+
+// WorkerPool is the state of a worker pool of the scheduler and of the queue
+// of jobs in front of it
+type WorkerPool struct {
+	Name    string
+	Workers uint
+	// Busy is the number of workers running a job
+	Busy uint
+	// QueueDepth is the number of jobs waiting for a worker, out of
+	// QueueLimit (0 when the queue is unbounded)
+	QueueDepth uint
+	QueueLimit uint
+	// Jobs is the number of jobs the workers started
+	Jobs uint64
+	// Shed is the number of low priority jobs shed from the full queue
+	Shed uint64
+	// Utilization is the fraction of the time of the workers spent running
+	// jobs since snapd started
+	Utilization float64
+	// The time jobs waited for a worker since they were queued
+	LastWait time.Duration
+	MeanWait time.Duration
+	MaxWait  time.Duration
+}
@@ -0,0 +1,299 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alert
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/intelsdi-x/gomit"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/alert_event"
+	"github.com/intelsdi-x/snap/core/scheduler_event"
+)
+
+const (
+	// HandlerRegistrationName is the name the alert manager registers its
+	// handler of scheduler events with
+	HandlerRegistrationName = "alert"
+)
+
+var (
+	alertLogger = log.WithField("_module", "alert")
+)
+
+type managesTasks interface {
+	GetTask(string) (core.Task, error)
+}
+
+// postsWebhooks posts a JSON body to a webhook, signed with the secret and
+// retried on failure
+type postsWebhooks interface {
+	Post(url, event string, secret, b []byte)
+}
+
+type ruleKey struct {
+	rule      string
+	namespace string
+}
+
+// ruleState follows a rule of a task for a metric while its expression holds
+type ruleState struct {
+	alert  core.Alert
+	firing bool
+}
+
+// Manager evaluates the alert rules of the tasks against the metrics they
+// collect. The alerts fired and resolved are emitted as events and posted
+// to the webhooks configured.
+type Manager struct {
+	webhooks     []string
+	secret       []byte
+	taskManager  managesTasks
+	poster       postsWebhooks
+	eventManager *gomit.EventController
+	mutex        sync.Mutex
+	// states of the rules by task ID, then by rule name and metric namespace
+	states map[string]map[ruleKey]*ruleState
+}
+
+// New returns an alert manager. The task manager must be set before the
+// manager handles scheduler events, and the poster before it posts to the
+// webhooks.
+func New(cfg *Config) *Manager {
+	return &Manager{
+		webhooks:     cfg.Webhooks,
+		secret:       []byte(cfg.Secret),
+		eventManager: gomit.NewEventController(),
+		states:       make(map[string]map[ruleKey]*ruleState),
+	}
+}
+
+func (m *Manager) SetTaskManager(t managesTasks) {
+	m.taskManager = t
+}
+
+// SetPoster sets what posts the alerts to the webhooks, the notifier of snapd
+// which retries and signs them as it does its notifications
+func (m *Manager) SetPoster(p postsWebhooks) {
+	m.poster = p
+}
+
+func (m *Manager) RegisterEventHandler(name string, h gomit.Handler) error {
+	return m.eventManager.RegisterHandler(name, h)
+}
+
+func (m *Manager) Name() string {
+	return "alert"
+}
+
+func (m *Manager) Start() error {
+	alertLogger.WithFields(log.Fields{
+		"_block":   "start",
+		"webhooks": len(m.webhooks),
+	}).Info("alert manager started")
+	return nil
+}
+
+func (m *Manager) Stop() {
+	alertLogger.WithFields(log.Fields{
+		"_block": "stop",
+	}).Info("alert manager stopped")
+}
+
+// ActiveAlerts returns the alerts firing, ordered by task, rule and metric
+func (m *Manager) ActiveAlerts() []core.Alert {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	alerts := []core.Alert{}
+	for _, states := range m.states {
+		for _, s := range states {
+			if s.firing {
+				alerts = append(alerts, s.alert)
+			}
+		}
+	}
+	sort.Sort(byTaskRuleNamespace(alerts))
+	return alerts
+}
+
+func (m *Manager) HandleGomitEvent(e gomit.Event) {
+	switch v := e.Body.(type) {
+	case *scheduler_event.MetricCollectedEvent:
+		m.evaluate(v.TaskID, v.Metrics, time.Now())
+	case *scheduler_event.TaskStoppedEvent:
+		m.resolveTask(v.TaskID)
+	case *scheduler_event.TaskDisabledEvent:
+		m.resolveTask(v.TaskID)
+	case *scheduler_event.TaskDeletedEvent:
+		m.resolveTask(v.TaskID)
+	}
+}
+
+// evaluate evaluates the rules of the task against the metrics collected
+func (m *Manager) evaluate(taskID string, metrics []core.Metric, now time.Time) {
+	if m.taskManager == nil {
+		return
+	}
+	t, err := m.taskManager.GetTask(taskID)
+	if err != nil || len(t.AlertRules()) == 0 {
+		return
+	}
+	var fired, resolved []core.Alert
+	m.mutex.Lock()
+	states := m.states[taskID]
+	if states == nil {
+		states = make(map[ruleKey]*ruleState)
+		m.states[taskID] = states
+	}
+	for _, r := range t.AlertRules() {
+		for _, mt := range metrics {
+			value, applies, holds := r.Evaluate(mt)
+			if !applies {
+				continue
+			}
+			ns := core.JoinNamespace(mt.Namespace())
+			key := ruleKey{rule: r.Name, namespace: ns}
+			s := states[key]
+			if !holds {
+				if s != nil {
+					delete(states, key)
+					if s.firing {
+						resolved = append(resolved, s.alert)
+					}
+				}
+				continue
+			}
+			if s == nil {
+				s = &ruleState{
+					alert: core.Alert{
+						TaskID:    taskID,
+						TaskName:  t.GetName(),
+						Rule:      r.Name,
+						Severity:  r.Severity,
+						Namespace: ns,
+						Since:     now,
+					},
+				}
+				states[key] = s
+			}
+			s.alert.Value = value
+			if !s.firing && now.Sub(s.alert.Since) >= r.For {
+				s.firing = true
+				s.alert.FiredAt = now
+				fired = append(fired, s.alert)
+			}
+		}
+	}
+	m.mutex.Unlock()
+	for _, a := range fired {
+		m.notify(a, true)
+	}
+	for _, a := range resolved {
+		m.notify(a, false)
+	}
+}
+
+// resolveTask resolves the alerts of a task which no longer collects
+func (m *Manager) resolveTask(taskID string) {
+	m.mutex.Lock()
+	states := m.states[taskID]
+	delete(m.states, taskID)
+	m.mutex.Unlock()
+	for _, s := range states {
+		if s.firing {
+			m.notify(s.alert, false)
+		}
+	}
+}
+
+func (m *Manager) notify(a core.Alert, fired bool) {
+	n := notification{
+		Status:    "resolved",
+		TaskID:    a.TaskID,
+		TaskName:  a.TaskName,
+		Rule:      a.Rule,
+		Severity:  a.Severity,
+		Namespace: a.Namespace,
+		Value:     a.Value,
+		Since:     a.Since.Unix(),
+		FiredAt:   a.FiredAt.Unix(),
+	}
+	if fired {
+		n.Status = "firing"
+		m.eventManager.Emit(&alert_event.AlertFiredEvent{Alert: a})
+	} else {
+		m.eventManager.Emit(&alert_event.AlertResolvedEvent{Alert: a})
+	}
+	alertLogger.WithFields(log.Fields{
+		"_block":    "notify",
+		"task-id":   a.TaskID,
+		"rule":      a.Rule,
+		"severity":  a.Severity,
+		"namespace": a.Namespace,
+		"value":     a.Value,
+	}).Info("alert ", n.Status)
+	if len(m.webhooks) == 0 || m.poster == nil {
+		return
+	}
+	b, err := json.Marshal(n)
+	if err != nil {
+		return
+	}
+	event := "alert-resolved"
+	if fired {
+		event = "alert-fired"
+	}
+	for _, url := range m.webhooks {
+		m.poster.Post(url, event, m.secret, b)
+	}
+}
+
+// notification is the body posted to the webhooks
+type notification struct {
+	// Status is firing or resolved
+	Status    string  `json:"status"`
+	TaskID    string  `json:"task_id"`
+	TaskName  string  `json:"task_name"`
+	Rule      string  `json:"rule"`
+	Severity  string  `json:"severity"`
+	Namespace string  `json:"namespace"`
+	Value     float64 `json:"value"`
+	Since     int64   `json:"since"`
+	FiredAt   int64   `json:"fired_at"`
+}
+
+type byTaskRuleNamespace []core.Alert
+
+func (a byTaskRuleNamespace) Len() int      { return len(a) }
+func (a byTaskRuleNamespace) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a byTaskRuleNamespace) Less(i, j int) bool {
+	if a[i].TaskID != a[j].TaskID {
+		return a[i].TaskID < a[j].TaskID
+	}
+	if a[i].Rule != a[j].Rule {
+		return a[i].Rule < a[j].Rule
+	}
+	return a[i].Namespace < a[j].Namespace
+}
@@ -0,0 +1,142 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alert
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/gomit"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/alert_event"
+	"github.com/intelsdi-x/snap/core/scheduler_event"
+	"github.com/intelsdi-x/snap/mgmt/notify"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type mockTask struct {
+	core.Task
+	rules []core.AlertRule
+}
+
+func (t *mockTask) GetName() string              { return "mock-task" }
+func (t *mockTask) AlertRules() []core.AlertRule { return t.rules }
+
+type mockTaskManager map[string]*mockTask
+
+func (m mockTaskManager) GetTask(id string) (core.Task, error) {
+	if t, ok := m[id]; ok {
+		return t, nil
+	}
+	return nil, errors.New("Task not found")
+}
+
+type listenToAlerts struct {
+	events chan gomit.EventBody
+}
+
+func (l *listenToAlerts) HandleGomitEvent(e gomit.Event) {
+	l.events <- e.Body
+}
+
+func metric(data interface{}) core.Metric {
+	return plugin.PluginMetricType{Namespace_: []string{"intel", "mock", "foo"}, Data_: data}
+}
+
+func TestAlertManager(t *testing.T) {
+	Convey("Given an alert manager and a task with an alert rule", t, func() {
+		posted := make(chan notification, 10)
+		signatures := make(chan string, 10)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var n notification
+			json.NewDecoder(r.Body).Decode(&n)
+			signatures <- r.Header.Get(notify.SignatureHeader)
+			posted <- n
+		}))
+		defer ts.Close()
+		m := New(&Config{Webhooks: []string{ts.URL}, Secret: "s3cret"})
+		n, err := notify.New(&notify.Config{})
+		So(err, ShouldBeNil)
+		m.SetPoster(n)
+		rule, err := core.NewAlertRule("foo-high", "/intel/mock/foo > 10", time.Minute, core.AlertSeverityCritical)
+		So(err, ShouldBeNil)
+		m.SetTaskManager(mockTaskManager{"1": &mockTask{rules: []core.AlertRule{rule}}})
+		l := &listenToAlerts{events: make(chan gomit.EventBody, 10)}
+		m.RegisterEventHandler("test", l)
+		now := time.Now()
+
+		Convey("the rule does not fire before its duration", func() {
+			m.evaluate("1", []core.Metric{metric(20)}, now)
+			m.evaluate("1", []core.Metric{metric(30)}, now.Add(30*time.Second))
+			So(m.ActiveAlerts(), ShouldBeEmpty)
+			Convey("and starts over when the expression stops holding", func() {
+				m.evaluate("1", []core.Metric{metric(5)}, now.Add(45*time.Second))
+				m.evaluate("1", []core.Metric{metric(20)}, now.Add(90*time.Second))
+				So(m.ActiveAlerts(), ShouldBeEmpty)
+			})
+		})
+		Convey("the rule fires once the expression held for its duration", func() {
+			m.evaluate("1", []core.Metric{metric(20)}, now)
+			m.evaluate("1", []core.Metric{metric(30)}, now.Add(time.Minute))
+			alerts := m.ActiveAlerts()
+			So(alerts, ShouldHaveLength, 1)
+			So(alerts[0].Rule, ShouldEqual, "foo-high")
+			So(alerts[0].TaskName, ShouldEqual, "mock-task")
+			So(alerts[0].Namespace, ShouldEqual, "/intel/mock/foo")
+			So(alerts[0].Value, ShouldEqual, 30)
+			e := <-l.events
+			So(e, ShouldHaveSameTypeAs, &alert_event.AlertFiredEvent{})
+			n := <-posted
+			So(n.Status, ShouldEqual, "firing")
+			So(n.Severity, ShouldEqual, core.AlertSeverityCritical)
+			So(<-signatures, ShouldStartWith, "sha256=")
+			Convey("and is resolved when the expression stops holding", func() {
+				m.evaluate("1", []core.Metric{metric(1)}, now.Add(2*time.Minute))
+				So(m.ActiveAlerts(), ShouldBeEmpty)
+				So(<-l.events, ShouldHaveSameTypeAs, &alert_event.AlertResolvedEvent{})
+				<-signatures
+				So((<-posted).Status, ShouldEqual, "resolved")
+			})
+			Convey("and is resolved when the task stops", func() {
+				m.HandleGomitEvent(gomit.Event{Body: &scheduler_event.TaskStoppedEvent{TaskID: "1"}})
+				So(m.ActiveAlerts(), ShouldBeEmpty)
+				So(<-l.events, ShouldHaveSameTypeAs, &alert_event.AlertResolvedEvent{})
+			})
+		})
+		Convey("metrics of tasks without rules are ignored", func() {
+			m.evaluate("2", []core.Metric{metric(20)}, now.Add(-time.Hour))
+			m.evaluate("2", []core.Metric{metric(20)}, now)
+			So(m.ActiveAlerts(), ShouldBeEmpty)
+		})
+	})
+}
+
+func TestAlertConfig(t *testing.T) {
+	Convey("Validate", t, func() {
+		So((&Config{Webhooks: []string{"http://localhost:9000/alerts", "https://example.com/hook"}}).Validate(), ShouldBeEmpty)
+		So((&Config{Webhooks: []string{"localhost:9000", "ftp://example.com"}}).Validate(), ShouldHaveLength, 2)
+	})
+}
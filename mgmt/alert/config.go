@@ -0,0 +1,50 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alert
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// holds the configuration passed in through the SNAP config file
+type Config struct {
+	// Webhooks are the URLs the fired and resolved alerts are posted to
+	Webhooks []string `json:"webhooks,omitempty"yaml:"webhooks,omitempty"`
+	// Secret signs the body posted to the webhooks with HMAC-SHA256
+	Secret string `json:"secret,omitempty"yaml:"secret,omitempty"secret:"true"`
+}
+
+// get the default snapd configuration
+func GetDefaultConfig() *Config {
+	return &Config{}
+}
+
+// Validate returns the problems found in the configuration
+func (c *Config) Validate() []error {
+	var errs []error
+	for _, w := range c.Webhooks {
+		u, err := url.Parse(w)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("alert.webhooks: %q is not an http or https URL", w))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,62 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdns
+
+import (
+	"fmt"
+	"net"
+)
+
+// holds the configuration passed in through the SNAP config file
+type Config struct {
+	// Enable advertises snapd on the local network over mDNS
+	Enable bool `json:"enable"yaml:"enable"`
+	// Instance is the name snapd is advertised under, its hostname by
+	// default
+	Instance string `json:"instance,omitempty"yaml:"instance,omitempty"`
+	// Interface is the network interface snapd is advertised on, every
+	// multicast interface by default
+	Interface string `json:"interface,omitempty"yaml:"interface,omitempty"`
+
+	// What is advertised, set by snapd
+	Version      string `json:"-"yaml:"-"`
+	AgentID      string `json:"-"yaml:"-"`
+	RestAPIPort  int    `json:"-"yaml:"-"`
+	RestAPIProto string `json:"-"yaml:"-"`
+	TribeMember  string `json:"-"yaml:"-"`
+	TribePort    int    `json:"-"yaml:"-"`
+	IP           string `json:"-"yaml:"-"`
+}
+
+// get the default snapd configuration
+func GetDefaultConfig() *Config {
+	return &Config{}
+}
+
+// Validate returns the problems found in the configuration
+func (c *Config) Validate() []error {
+	var errs []error
+	if c.Enable && c.Interface != "" {
+		if _, err := net.InterfaceByName(c.Interface); err != nil {
+			errs = append(errs, fmt.Errorf("mdns.interface: %v", err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,275 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package mdns advertises snapd on the local network over mDNS, as a DNS-SD
+// service with its REST API and tribe port, and discovers the snapd
+// advertised, e.g. for snapctl discover in labs and edge sites where the
+// addresses of the agents are not known beforehand.
+package mdns
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/hashicorp/mdns"
+)
+
+// Service is the DNS-SD service type snapd is advertised as
+const Service = "_snap._tcp"
+
+// the keys of the TXT record of snapd
+const (
+	txtVersion   = "version"
+	txtAgentID   = "agent_id"
+	txtRestProto = "rest_proto"
+	txtMember    = "tribe_member"
+	txtTribePort = "tribe_port"
+)
+
+var mdnsLogger = log.WithField("_module", "mdns")
+
+// Advertiser advertises snapd on the local network while it is started
+type Advertiser struct {
+	config *Config
+
+	mutex  sync.Mutex
+	server *mdns.Server
+}
+
+// New returns the advertiser of snapd with the configuration
+func New(cfg *Config) *Advertiser {
+	return &Advertiser{config: cfg}
+}
+
+func (a *Advertiser) Name() string {
+	return "mdns"
+}
+
+// Start advertises snapd, unless it is not enabled
+func (a *Advertiser) Start() error {
+	if !a.config.Enable {
+		return nil
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	instance := a.config.Instance
+	if instance == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+		instance = hostname
+	}
+	var iface *net.Interface
+	if a.config.Interface != "" {
+		i, err := net.InterfaceByName(a.config.Interface)
+		if err != nil {
+			return err
+		}
+		iface = i
+	}
+	ips, err := a.ips(iface)
+	if err != nil {
+		return err
+	}
+	svc, err := mdns.NewMDNSService(instance, Service, "", "", a.config.RestAPIPort, ips, txtRecord(a.config))
+	if err != nil {
+		return err
+	}
+	server, err := mdns.NewServer(&mdns.Config{Zone: svc, Iface: iface})
+	if err != nil {
+		return err
+	}
+	a.server = server
+	mdnsLogger.WithFields(log.Fields{
+		"_block":    "start",
+		"instance":  instance,
+		"service":   Service,
+		"port":      a.config.RestAPIPort,
+		"interface": a.config.Interface,
+	}).Info("snapd advertised over mDNS")
+	return nil
+}
+
+// ips returns the addresses snapd is advertised with: the address it
+// advertises to the tribe, else those of the interface. None lets the
+// addresses be resolved from the hostname.
+func (a *Advertiser) ips(iface *net.Interface) ([]net.IP, error) {
+	if ip := net.ParseIP(a.config.IP); ip != nil && !ip.IsUnspecified() {
+		return []net.IP{ip}, nil
+	}
+	if iface == nil {
+		return nil, nil
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	var ips []net.IP
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok {
+			ips = append(ips, ipnet.IP)
+		}
+	}
+	return ips, nil
+}
+
+// Stop stops advertising snapd
+func (a *Advertiser) Stop() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.server == nil {
+		return
+	}
+	if err := a.server.Shutdown(); err != nil {
+		mdnsLogger.WithField("_block", "stop").Warn(err)
+	}
+	a.server = nil
+}
+
+// txtRecord returns the TXT record of snapd, a key=value pair per string
+func txtRecord(c *Config) []string {
+	txt := []string{txtVersion + "=" + c.Version}
+	if c.AgentID != "" {
+		txt = append(txt, txtAgentID+"="+c.AgentID)
+	}
+	if c.RestAPIPort > 0 {
+		txt = append(txt, txtRestProto+"="+c.RestAPIProto)
+	}
+	if c.TribePort > 0 {
+		txt = append(txt, txtMember+"="+c.TribeMember, txtTribePort+"="+strconv.Itoa(c.TribePort))
+	}
+	return txt
+}
+
+// Agent is a snapd advertised on the local network
+type Agent struct {
+	Instance string
+	Host     string
+	IP       net.IP
+	Version  string
+	AgentID  string
+	// RestAPIPort is 0 when the REST API of snapd is disabled
+	RestAPIPort  int
+	RestAPIProto string
+	// TribeMember and TribePort are empty when tribe is disabled
+	TribeMember string
+	TribePort   int
+}
+
+// URL returns the URL of the REST API of the agent, empty when it is
+// disabled
+func (a Agent) URL() string {
+	if a.RestAPIPort == 0 || a.IP == nil {
+		return ""
+	}
+	proto := a.RestAPIProto
+	if proto == "" {
+		proto = "http"
+	}
+	return fmt.Sprintf("%s://%s", proto, net.JoinHostPort(a.IP.String(), strconv.Itoa(a.RestAPIPort)))
+}
+
+// TribeAddr returns the address of the agent the members of a tribe gossip
+// with, empty when tribe is disabled
+func (a Agent) TribeAddr() string {
+	if a.TribePort == 0 || a.IP == nil {
+		return ""
+	}
+	return net.JoinHostPort(a.IP.String(), strconv.Itoa(a.TribePort))
+}
+
+// agentFromEntry returns the agent advertised by a service entry
+func agentFromEntry(e *mdns.ServiceEntry) Agent {
+	a := Agent{
+		Instance:    strings.TrimSuffix(e.Name, "."+Service+".local."),
+		Host:        strings.TrimSuffix(e.Host, "."),
+		IP:          e.AddrV4,
+		RestAPIPort: e.Port,
+	}
+	if a.IP == nil {
+		a.IP = e.AddrV6
+	}
+	for _, field := range e.InfoFields {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case txtVersion:
+			a.Version = kv[1]
+		case txtAgentID:
+			a.AgentID = kv[1]
+		case txtRestProto:
+			a.RestAPIProto = kv[1]
+		case txtMember:
+			a.TribeMember = kv[1]
+		case txtTribePort:
+			a.TribePort, _ = strconv.Atoi(kv[1])
+		}
+	}
+	return a
+}
+
+// Discover returns the snapd advertised on the local network which answered
+// within the timeout, sorted by instance. The query is sent on every
+// multicast interface unless one is given.
+func Discover(timeout time.Duration, iface *net.Interface) ([]Agent, error) {
+	entries := make(chan *mdns.ServiceEntry, 32)
+	found := map[string]Agent{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for e := range entries {
+			a := agentFromEntry(e)
+			found[a.Instance] = a
+		}
+	}()
+	err := mdns.Query(&mdns.QueryParam{
+		Service:   Service,
+		Domain:    "local",
+		Timeout:   timeout,
+		Interface: iface,
+		Entries:   entries,
+	})
+	close(entries)
+	<-done
+	if err != nil {
+		return nil, err
+	}
+	agents := make([]Agent, 0, len(found))
+	for _, a := range found {
+		agents = append(agents, a)
+	}
+	sort.Sort(byInstance(agents))
+	return agents, nil
+}
+
+type byInstance []Agent
+
+func (b byInstance) Len() int           { return len(b) }
+func (b byInstance) Less(i, j int) bool { return b[i].Instance < b[j].Instance }
+func (b byInstance) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
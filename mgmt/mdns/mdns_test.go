@@ -0,0 +1,108 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdns
+
+import (
+	"net"
+	"testing"
+
+	"github.com/hashicorp/mdns"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTXTRecord(t *testing.T) {
+	Convey("Given the configuration of an advertised snapd", t, func() {
+		c := &Config{
+			Enable:       true,
+			Version:      "1.2.0",
+			AgentID:      "b4c1",
+			RestAPIPort:  8181,
+			RestAPIProto: "https",
+			TribeMember:  "edge-1",
+			TribePort:    6000,
+		}
+		Convey("the TXT record holds the REST API and tribe of snapd", func() {
+			So(txtRecord(c), ShouldResemble, []string{
+				"version=1.2.0",
+				"agent_id=b4c1",
+				"rest_proto=https",
+				"tribe_member=edge-1",
+				"tribe_port=6000",
+			})
+		})
+		Convey("the TXT record leaves out what is disabled", func() {
+			c.RestAPIPort = 0
+			c.TribePort = 0
+			c.AgentID = ""
+			So(txtRecord(c), ShouldResemble, []string{"version=1.2.0"})
+		})
+		Convey("the agent is parsed back from the service entry", func() {
+			a := agentFromEntry(&mdns.ServiceEntry{
+				Name:       "edge-1." + Service + ".local.",
+				Host:       "edge-1.local.",
+				AddrV4:     net.ParseIP("10.0.0.7"),
+				Port:       c.RestAPIPort,
+				InfoFields: append(txtRecord(c), "unknown=1", "malformed"),
+			})
+			So(a.Instance, ShouldEqual, "edge-1")
+			So(a.Host, ShouldEqual, "edge-1.local")
+			So(a.Version, ShouldEqual, "1.2.0")
+			So(a.AgentID, ShouldEqual, "b4c1")
+			So(a.TribeMember, ShouldEqual, "edge-1")
+			So(a.URL(), ShouldEqual, "https://10.0.0.7:8181")
+			So(a.TribeAddr(), ShouldEqual, "10.0.0.7:6000")
+		})
+	})
+}
+
+func TestAgent(t *testing.T) {
+	Convey("Given an agent with its REST API and tribe disabled", t, func() {
+		a := agentFromEntry(&mdns.ServiceEntry{
+			Name:       "lab." + Service + ".local.",
+			AddrV6:     net.ParseIP("fe80::1"),
+			InfoFields: []string{"version=1.2.0"},
+		})
+		So(a.Instance, ShouldEqual, "lab")
+		So(a.URL(), ShouldBeEmpty)
+		So(a.TribeAddr(), ShouldBeEmpty)
+		Convey("the REST API defaults to http", func() {
+			a.RestAPIPort = 8181
+			So(a.URL(), ShouldEqual, "http://[fe80::1]:8181")
+		})
+	})
+}
+
+func TestConfig(t *testing.T) {
+	Convey("Given the mdns configuration", t, func() {
+		c := GetDefaultConfig()
+		Convey("it is disabled by default", func() {
+			So(c.Enable, ShouldBeFalse)
+			So(c.Validate(), ShouldBeEmpty)
+		})
+		Convey("an unknown interface is rejected", func() {
+			c.Enable = true
+			c.Interface = "nosuchif0"
+			errs := c.Validate()
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Error(), ShouldStartWith, "mdns.interface:")
+		})
+	})
+}
@@ -0,0 +1,163 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"text/template"
+	"time"
+
+	"github.com/vrischmann/jsonutil"
+
+	"github.com/intelsdi-x/snap/pkg/tlspolicy"
+)
+
+// default configuration values
+const (
+	defaultRetries      int           = 3
+	defaultRetryBackoff time.Duration = time.Second
+)
+
+// holds the configuration passed in through the SNAP config file
+type Config struct {
+	Webhooks []*WebhookConfig `json:"webhooks,omitempty"yaml:"webhooks,omitempty"`
+	Slack    []*SlackConfig   `json:"slack,omitempty"yaml:"slack,omitempty"`
+	Email    []*EmailConfig   `json:"email,omitempty"yaml:"email,omitempty"`
+	// Retries is the number of times a notification is posted again after
+	// the first attempt failed
+	Retries int `json:"retries"yaml:"retries"`
+	// RetryBackoff is the delay before the first retry, doubled for each of
+	// the next ones
+	RetryBackoff jsonutil.Duration `json:"retry_backoff,omitempty"yaml:"retry_backoff,omitempty"`
+	// TLSPolicy restricts the TLS versions and cipher suites of the
+	// webhooks, Slack and email, as set by the tls section of the snapd
+	// configuration
+	TLSPolicy *tlspolicy.Config `json:"-"yaml:"-"`
+}
+
+// WebhookConfig is a URL the notifications of the selected events are
+// posted to
+type WebhookConfig struct {
+	URL string `json:"url"yaml:"url"`
+	// Events are the event classes notified, all of them when empty
+	Events []string `json:"events,omitempty"yaml:"events,omitempty"`
+	// Template is the Go template of the body posted, the notification in
+	// JSON by default
+	Template string `json:"template,omitempty"yaml:"template,omitempty"`
+	// Secret signs the body posted with HMAC-SHA256
+	Secret string `json:"secret,omitempty"yaml:"secret,omitempty"secret:"true"`
+}
+
+// SlackConfig is a Slack incoming webhook the notifications of the selected
+// events are posted to as messages
+type SlackConfig struct {
+	URL string `json:"url"yaml:"url"`
+	// Channel and Username override the ones of the incoming webhook
+	Channel  string `json:"channel,omitempty"yaml:"channel,omitempty"`
+	Username string `json:"username,omitempty"yaml:"username,omitempty"`
+	// Events are the event classes notified, all of them when empty
+	Events []string `json:"events,omitempty"yaml:"events,omitempty"`
+}
+
+// EmailConfig is an SMTP server the notifications of the selected events are
+// mailed through
+type EmailConfig struct {
+	// Server is the host:port of the SMTP server
+	Server string `json:"server"yaml:"server"`
+	// Username and Password authenticate to the server when set
+	Username string   `json:"username,omitempty"yaml:"username,omitempty"`
+	Password string   `json:"password,omitempty"yaml:"password,omitempty"secret:"true"`
+	From     string   `json:"from"yaml:"from"`
+	To       []string `json:"to"yaml:"to"`
+	// Events are the event classes notified, all of them when empty
+	Events []string `json:"events,omitempty"yaml:"events,omitempty"`
+}
+
+// get the default snapd configuration
+func GetDefaultConfig() *Config {
+	return &Config{
+		Retries:      defaultRetries,
+		RetryBackoff: jsonutil.Duration{defaultRetryBackoff},
+	}
+}
+
+// Validate returns the problems found in the configuration
+func (c *Config) Validate() []error {
+	var errs []error
+	if c.Retries < 0 {
+		errs = append(errs, fmt.Errorf("notify.retries: must not be negative"))
+	}
+	if c.RetryBackoff.Duration < 0 {
+		errs = append(errs, fmt.Errorf("notify.retry_backoff: must not be negative"))
+	}
+	for _, w := range c.Webhooks {
+		if !validURL(w.URL) {
+			errs = append(errs, fmt.Errorf("notify.webhooks: %q is not an http or https URL", w.URL))
+		}
+		errs = append(errs, validateEvents("notify.webhooks", w.URL, w.Events)...)
+		if w.Template != "" {
+			if _, err := template.New(w.URL).Funcs(templateFuncs).Parse(w.Template); err != nil {
+				errs = append(errs, fmt.Errorf("notify.webhooks: template of %s: %v", w.URL, err))
+			}
+		}
+	}
+	for _, s := range c.Slack {
+		if !validURL(s.URL) {
+			errs = append(errs, fmt.Errorf("notify.slack: %q is not an http or https URL", s.URL))
+		}
+		errs = append(errs, validateEvents("notify.slack", s.URL, s.Events)...)
+	}
+	for _, e := range c.Email {
+		if _, _, err := net.SplitHostPort(e.Server); err != nil {
+			errs = append(errs, fmt.Errorf("notify.email: server %q is not a host:port", e.Server))
+		}
+		if _, err := mail.ParseAddress(e.From); err != nil {
+			errs = append(errs, fmt.Errorf("notify.email: from address %q of %s: %v", e.From, e.Server, err))
+		}
+		if len(e.To) == 0 {
+			errs = append(errs, fmt.Errorf("notify.email: no to address for %s", e.Server))
+		}
+		for _, to := range e.To {
+			if _, err := mail.ParseAddress(to); err != nil {
+				errs = append(errs, fmt.Errorf("notify.email: to address %q of %s: %v", to, e.Server, err))
+			}
+		}
+		errs = append(errs, validateEvents("notify.email", e.Server, e.Events)...)
+	}
+	return errs
+}
+
+func validURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func validateEvents(key, target string, events []string) []error {
+	var errs []error
+	for _, e := range events {
+		if !validEvent(e) {
+			errs = append(errs, fmt.Errorf("%s: event %q of %s is not one of %v", key, e, target, Events))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,137 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// email mails the notifications through an SMTP server
+type email struct {
+	eventFilter
+	server string
+	auth   smtp.Auth
+	from   string
+	to     []string
+	// tlsConfig is the TLS configuration of STARTTLS
+	tlsConfig *tls.Config
+}
+
+func newEmail(cfg *EmailConfig, tlsConfig *tls.Config) *email {
+	host, _, _ := net.SplitHostPort(cfg.Server)
+	e := &email{
+		eventFilter: newEventFilter(cfg.Events),
+		server:      cfg.Server,
+		from:        cfg.From,
+		to:          cfg.To,
+		tlsConfig:   &tls.Config{},
+	}
+	if tlsConfig != nil {
+		e.tlsConfig = tlsConfig.Clone()
+	}
+	e.tlsConfig.ServerName = host
+	if cfg.Username != "" {
+		e.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+	return e
+}
+
+func (e *email) target() string {
+	return "smtp " + e.server
+}
+
+// message returns the mail of the notification, with its fields listed
+// below the message
+func (e *email) message(n *Notification) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", e.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(e.to, ", "))
+	fmt.Fprintf(&b, "Subject: [snap] %s\r\n", n.Message)
+	fmt.Fprintf(&b, "Date: %s\r\n", n.Timestamp.Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	fmt.Fprintf(&b, "%s\r\n\r\n", n.Message)
+	fmt.Fprintf(&b, "event: %s\r\n", n.Event)
+	for _, k := range sortedKeys(n.Fields) {
+		fmt.Fprintf(&b, "%s: %s\r\n", k, n.Fields[k])
+	}
+	return b.Bytes()
+}
+
+func (e *email) send(n *Notification) (bool, error) {
+	err := e.sendMail(e.message(n))
+	if err == nil {
+		return false, nil
+	}
+	// permanent SMTP errors, like an unknown recipient, are not retried
+	if te, ok := err.(*textproto.Error); ok && te.Code >= 500 {
+		return false, err
+	}
+	return true, err
+}
+
+// sendMail sends the mail as smtp.SendMail does, but with the TLS
+// configuration of the email for STARTTLS
+func (e *email) sendMail(msg []byte) error {
+	c, err := smtp.Dial(e.server)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(e.tlsConfig); err != nil {
+			return err
+		}
+	}
+	if e.auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := c.Auth(e.auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(e.from); err != nil {
+		return err
+	}
+	for _, to := range e.to {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,346 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/intelsdi-x/gomit"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/alert_event"
+	"github.com/intelsdi-x/snap/core/control_event"
+	"github.com/intelsdi-x/snap/core/scheduler_event"
+	"github.com/intelsdi-x/snap/core/tribe_event"
+)
+
+// The event classes notified
+const (
+	EventTaskDisabled    = "task-disabled"
+	EventPluginCrashed   = "plugin-crashed"
+	EventTribeMemberLeft = "tribe-member-left"
+	EventTribeClockSkew  = "tribe-clock-skew"
+	EventAlertFired      = "alert-fired"
+	EventAlertResolved   = "alert-resolved"
+
+	// HandlerRegistrationName is the name the notifier registers its handler
+	// of events with
+	HandlerRegistrationName = "notify"
+
+	// SignatureHeader holds the HMAC-SHA256 of the body posted to webhooks
+	// with a secret, as sha256=<hex digest>
+	SignatureHeader = "X-Snap-Signature"
+
+	postTimeout = 10 * time.Second
+)
+
+var (
+	Events = []string{EventTaskDisabled, EventPluginCrashed, EventTribeMemberLeft, EventTribeClockSkew, EventAlertFired, EventAlertResolved}
+
+	notifyLogger = log.WithField("_module", "notify")
+
+	templateFuncs = template.FuncMap{
+		// json quotes a value to be inserted in a JSON body
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}
+)
+
+func validEvent(e string) bool {
+	for _, v := range Events {
+		if v == e {
+			return true
+		}
+	}
+	return false
+}
+
+// Notification is posted to the webhooks, as JSON or as the data of their
+// template
+type Notification struct {
+	Event     string            `json:"event"`
+	Timestamp time.Time         `json:"timestamp"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields"`
+}
+
+// sender sends notifications to one destination
+type sender interface {
+	// target names the destination in the logs
+	target() string
+	selects(event string) bool
+	// send sends the notification once and returns whether a failure is
+	// worth retrying
+	send(nt *Notification) (bool, error)
+}
+
+// eventFilter selects the event classes a sender notifies, all of them when
+// empty
+type eventFilter map[string]bool
+
+func newEventFilter(events []string) eventFilter {
+	f := eventFilter{}
+	for _, e := range events {
+		f[e] = true
+	}
+	return f
+}
+
+func (f eventFilter) selects(event string) bool {
+	return len(f) == 0 || f[event]
+}
+
+// Notifier sends notifications of the events of snapd to webhooks, Slack
+// and email
+type Notifier struct {
+	senders []sender
+	retries int
+	backoff time.Duration
+	client  *http.Client
+	quit    chan struct{}
+}
+
+// New returns a notifier sending to the destinations of the configuration,
+// which must be valid
+func New(cfg *Config) (*Notifier, error) {
+	tlsConfig := cfg.TLSPolicy.TLSConfig()
+	n := &Notifier{
+		retries: cfg.Retries,
+		backoff: cfg.RetryBackoff.Duration,
+		client: &http.Client{
+			Timeout: postTimeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		},
+		quit: make(chan struct{}),
+	}
+	for _, wc := range cfg.Webhooks {
+		w, err := newWebhook(wc, n.client)
+		if err != nil {
+			return nil, err
+		}
+		n.senders = append(n.senders, w)
+	}
+	for _, sc := range cfg.Slack {
+		n.senders = append(n.senders, newSlack(sc, n.client))
+	}
+	for _, ec := range cfg.Email {
+		n.senders = append(n.senders, newEmail(ec, tlsConfig))
+	}
+	return n, nil
+}
+
+func (n *Notifier) Name() string {
+	return "notify"
+}
+
+func (n *Notifier) Start() error {
+	notifyLogger.WithFields(log.Fields{
+		"_block":       "start",
+		"destinations": len(n.senders),
+	}).Info("notifier started")
+	return nil
+}
+
+// Stop abandons the notifications waiting to be retried
+func (n *Notifier) Stop() {
+	close(n.quit)
+	notifyLogger.WithFields(log.Fields{
+		"_block": "stop",
+	}).Info("notifier stopped")
+}
+
+func (n *Notifier) HandleGomitEvent(e gomit.Event) {
+	if nt := notificationFromEvent(e.Body, time.Now()); nt != nil {
+		n.notify(nt)
+	}
+}
+
+func notificationFromEvent(e gomit.EventBody, now time.Time) *Notification {
+	switch v := e.(type) {
+	case *scheduler_event.TaskDisabledEvent:
+		return &Notification{
+			Event:     EventTaskDisabled,
+			Timestamp: now,
+			Message:   fmt.Sprintf("Task %s disabled: %s", v.TaskID, v.Why),
+			Fields: map[string]string{
+				"task_id": v.TaskID,
+				"reason":  v.Why,
+			},
+		}
+	case *control_event.DeadAvailablePluginEvent:
+		nt := &Notification{
+			Event:     EventPluginCrashed,
+			Timestamp: now,
+			Message:   fmt.Sprintf("Plugin %s crashed", v.String),
+			Fields: map[string]string{
+				"plugin_name":    v.Name,
+				"plugin_version": strconv.Itoa(v.Version),
+				"plugin_type":    core.PluginType(v.Type).String(),
+			},
+		}
+		if v.CrashReport != "" {
+			nt.Fields["crash_report"] = v.CrashReport
+		}
+		return nt
+	case *tribe_event.MemberLeftEvent:
+		return &Notification{
+			Event:     EventTribeMemberLeft,
+			Timestamp: now,
+			Message:   fmt.Sprintf("Tribe member %s (%s) left", v.Name, v.Addr),
+			Fields: map[string]string{
+				"member_name": v.Name,
+				"member_addr": v.Addr,
+			},
+		}
+	case *tribe_event.ClockSkewEvent:
+		return &Notification{
+			Event:     EventTribeClockSkew,
+			Timestamp: now,
+			Message:   fmt.Sprintf("Clock of tribe member %s is off by %s, more than %s", v.Name, v.Skew, v.Threshold),
+			Fields: map[string]string{
+				"member_name": v.Name,
+				"skew":        v.Skew.String(),
+				"threshold":   v.Threshold.String(),
+			},
+		}
+	case *alert_event.AlertFiredEvent:
+		return alertNotification(EventAlertFired, "fired", v.Alert, now)
+	case *alert_event.AlertResolvedEvent:
+		return alertNotification(EventAlertResolved, "resolved", v.Alert, now)
+	}
+	return nil
+}
+
+func alertNotification(event, status string, a core.Alert, now time.Time) *Notification {
+	return &Notification{
+		Event:     event,
+		Timestamp: now,
+		Message:   fmt.Sprintf("Alert %s (%s) %s for %s of task %s: %v", a.Rule, a.Severity, status, a.Namespace, a.TaskName, a.Value),
+		Fields: map[string]string{
+			"task_id":   a.TaskID,
+			"task_name": a.TaskName,
+			"rule":      a.Rule,
+			"severity":  a.Severity,
+			"namespace": a.Namespace,
+			"value":     strconv.FormatFloat(a.Value, 'g', -1, 64),
+		},
+	}
+}
+
+func (n *Notifier) notify(nt *Notification) {
+	for _, sd := range n.senders {
+		if sd.selects(nt.Event) {
+			go n.send(sd, nt)
+		}
+	}
+}
+
+// send sends the notification, retrying with an exponential backoff while
+// it fails
+func (n *Notifier) send(sd sender, nt *Notification) {
+	n.retry(sd.target(), nt.Event, func() (bool, error) {
+		return sd.send(nt)
+	})
+}
+
+// Post posts the JSON body to the URL, signed with the secret when there is
+// one, and retries it as the notifications are. The other modules post their
+// own bodies through it, the alert manager to its webhooks.
+func (n *Notifier) Post(url, event string, secret, b []byte) {
+	header := http.Header{}
+	if len(secret) > 0 {
+		header.Set(SignatureHeader, "sha256="+sign(secret, b))
+	}
+	go n.retry(url, event, func() (bool, error) {
+		return postJSON(n.client, url, b, header)
+	})
+}
+
+// retry calls send again with an exponential backoff while it fails with an
+// error worth retrying
+func (n *Notifier) retry(target, event string, send func() (bool, error)) {
+	backoff := n.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := send()
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= n.retries {
+			notifyLogger.WithFields(log.Fields{
+				"_block":   "send",
+				"target":   target,
+				"event":    event,
+				"attempts": attempt + 1,
+				"_error":   err,
+			}).Error("unable to send notification")
+			return
+		}
+		notifyLogger.WithFields(log.Fields{
+			"_block": "send",
+			"target": target,
+			"event":  event,
+			"retry":  backoff,
+			"_error": err,
+		}).Warning("sending notification failed")
+		select {
+		case <-time.After(backoff):
+		case <-n.quit:
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// postJSON posts the body to the URL and returns whether a failure is worth
+// retrying, which a request refused by the server is not
+func postJSON(client *http.Client, url string, b []byte, header http.Header) (bool, error) {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(b))
+	if err != nil {
+		return false, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return false, fmt.Errorf("notification refused: %s", resp.Status)
+	}
+	return true, fmt.Errorf("notification failed: %s", resp.Status)
+}
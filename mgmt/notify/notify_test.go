@@ -0,0 +1,248 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/gomit"
+	"github.com/vrischmann/jsonutil"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/alert_event"
+	"github.com/intelsdi-x/snap/core/control_event"
+	"github.com/intelsdi-x/snap/core/scheduler_event"
+	"github.com/intelsdi-x/snap/core/tribe_event"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type posted struct {
+	body      []byte
+	signature string
+}
+
+func newWebhookServer(failures int32) (*httptest.Server, chan posted, *int32) {
+	ch := make(chan posted, 10)
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		ch <- posted{body: b, signature: r.Header.Get(SignatureHeader)}
+	}))
+	return ts, ch, &calls
+}
+
+// newSMTPServer accepts one SMTP session and sends the mail received, or
+// refuses the recipients with the reply given
+func newSMTPServer(rcptReply string) (string, chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	ch := make(chan string, 1)
+	go func() {
+		defer ln.Close()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+			case "RCPT":
+				reply(rcptReply)
+			case "DATA":
+				reply("354 go ahead")
+				var data []string
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data = append(data, l)
+				}
+				ch <- strings.Join(data, "")
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return ln.Addr().String(), ch
+}
+
+func testConfig(webhooks ...*WebhookConfig) *Config {
+	cfg := GetDefaultConfig()
+	cfg.RetryBackoff = jsonutil.Duration{time.Millisecond}
+	cfg.Webhooks = webhooks
+	return cfg
+}
+
+func TestNotifier(t *testing.T) {
+	Convey("Given a notifier", t, func() {
+		Convey("the notification of an event is posted in JSON", func() {
+			ts, ch, _ := newWebhookServer(0)
+			defer ts.Close()
+			n, err := New(testConfig(&WebhookConfig{URL: ts.URL}))
+			So(err, ShouldBeNil)
+			n.HandleGomitEvent(gomit.Event{Body: &scheduler_event.TaskDisabledEvent{TaskID: "1", Why: "too many failures"}})
+			p := <-ch
+			var nt Notification
+			So(json.Unmarshal(p.body, &nt), ShouldBeNil)
+			So(nt.Event, ShouldEqual, EventTaskDisabled)
+			So(nt.Fields["task_id"], ShouldEqual, "1")
+			So(nt.Fields["reason"], ShouldEqual, "too many failures")
+			So(p.signature, ShouldBeEmpty)
+		})
+		Convey("only the events selected are posted", func() {
+			ts, ch, calls := newWebhookServer(0)
+			defer ts.Close()
+			n, err := New(testConfig(&WebhookConfig{URL: ts.URL, Events: []string{EventTribeMemberLeft}}))
+			So(err, ShouldBeNil)
+			n.HandleGomitEvent(gomit.Event{Body: &control_event.DeadAvailablePluginEvent{Name: "mock", Version: 1}})
+			n.HandleGomitEvent(gomit.Event{Body: &tribe_event.MemberLeftEvent{Name: "node2", Addr: "10.0.0.2"}})
+			p := <-ch
+			So(string(p.body), ShouldContainSubstring, EventTribeMemberLeft)
+			So(atomic.LoadInt32(calls), ShouldEqual, 1)
+		})
+		Convey("the body is rendered with the template and signed", func() {
+			ts, ch, _ := newWebhookServer(0)
+			defer ts.Close()
+			n, err := New(testConfig(&WebhookConfig{
+				URL:      ts.URL,
+				Template: `{"text": {{json .Message}}}`,
+				Secret:   "s3cr3t",
+			}))
+			So(err, ShouldBeNil)
+			n.HandleGomitEvent(gomit.Event{Body: &tribe_event.MemberLeftEvent{Name: "node2", Addr: "10.0.0.2"}})
+			p := <-ch
+			So(string(p.body), ShouldEqual, `{"text": "Tribe member node2 (10.0.0.2) left"}`)
+			So(p.signature, ShouldEqual, "sha256="+sign([]byte("s3cr3t"), p.body))
+		})
+		Convey("a failed post is retried", func() {
+			ts, ch, calls := newWebhookServer(2)
+			defer ts.Close()
+			n, err := New(testConfig(&WebhookConfig{URL: ts.URL}))
+			So(err, ShouldBeNil)
+			n.HandleGomitEvent(gomit.Event{Body: &scheduler_event.TaskDisabledEvent{TaskID: "1"}})
+			<-ch
+			So(atomic.LoadInt32(calls), ShouldEqual, 3)
+		})
+		Convey("a body posted by another module is signed and retried", func() {
+			ts, ch, calls := newWebhookServer(1)
+			defer ts.Close()
+			n, err := New(testConfig())
+			So(err, ShouldBeNil)
+			n.Post(ts.URL, EventAlertFired, []byte("s3cr3t"), []byte(`{"status": "firing"}`))
+			p := <-ch
+			So(string(p.body), ShouldEqual, `{"status": "firing"}`)
+			So(p.signature, ShouldEqual, "sha256="+sign([]byte("s3cr3t"), p.body))
+			So(atomic.LoadInt32(calls), ShouldEqual, 2)
+		})
+		Convey("the notification is posted as a Slack message", func() {
+			ts, ch, _ := newWebhookServer(0)
+			defer ts.Close()
+			cfg := testConfig()
+			cfg.Slack = []*SlackConfig{{URL: ts.URL, Channel: "#ops", Events: []string{EventAlertFired}}}
+			n, err := New(cfg)
+			So(err, ShouldBeNil)
+			n.HandleGomitEvent(gomit.Event{Body: &alert_event.AlertFiredEvent{Alert: core.Alert{
+				TaskID:    "1",
+				TaskName:  "load",
+				Rule:      "high-load",
+				Severity:  "critical",
+				Namespace: "/intel/load",
+				Value:     95,
+			}}})
+			var m slackMessage
+			So(json.Unmarshal((<-ch).body, &m), ShouldBeNil)
+			So(m.Text, ShouldEqual, "Alert high-load (critical) fired for /intel/load of task load: 95")
+			So(m.Channel, ShouldEqual, "#ops")
+			So(m.Attachments, ShouldHaveLength, 1)
+			So(m.Attachments[0].Color, ShouldEqual, "danger")
+			So(m.Attachments[0].Fields[0], ShouldResemble, slackField{Title: "namespace", Value: "/intel/load", Short: true})
+		})
+		Convey("the notification is mailed", func() {
+			addr, ch := newSMTPServer("250 ok")
+			cfg := testConfig()
+			cfg.Email = []*EmailConfig{{Server: addr, From: "snap@example.com", To: []string{"ops@example.com"}}}
+			n, err := New(cfg)
+			So(err, ShouldBeNil)
+			n.HandleGomitEvent(gomit.Event{Body: &control_event.DeadAvailablePluginEvent{Name: "mock", Version: 1, Type: int(core.CollectorPluginType), String: "collector:mock:v1", CrashReport: "/tmp/snap-crashes/collector-mock-1/report.json"}})
+			mail := <-ch
+			So(mail, ShouldContainSubstring, "To: ops@example.com\r\n")
+			So(mail, ShouldContainSubstring, "Subject: [snap] Plugin collector:mock:v1 crashed\r\n")
+			So(mail, ShouldContainSubstring, "plugin_name: mock\r\n")
+			So(mail, ShouldContainSubstring, "crash_report: /tmp/snap-crashes/collector-mock-1/report.json\r\n")
+		})
+		Convey("a mail refused by the server is not retried", func() {
+			addr, _ := newSMTPServer("550 no such user")
+			e := newEmail(&EmailConfig{Server: addr, From: "snap@example.com", To: []string{"nobody@example.com"}}, nil)
+			retry, err := e.send(&Notification{Event: EventTaskDisabled, Message: "Task 1 disabled"})
+			So(err, ShouldNotBeNil)
+			So(retry, ShouldBeFalse)
+		})
+		Convey("other events are ignored", func() {
+			So(notificationFromEvent(&scheduler_event.TaskStartedEvent{TaskID: "1"}, time.Now()), ShouldBeNil)
+		})
+	})
+}
+
+func TestNotifyConfig(t *testing.T) {
+	Convey("Validate", t, func() {
+		So(testConfig(&WebhookConfig{URL: "https://hooks.example.com/snap", Events: []string{EventPluginCrashed}, Template: "{{.Message}}"}).Validate(), ShouldBeEmpty)
+		cfg := testConfig(
+			&WebhookConfig{URL: "hooks.example.com"},
+			&WebhookConfig{URL: "http://hooks.example.com", Events: []string{"task-started"}},
+			&WebhookConfig{URL: "http://hooks.example.com", Template: "{{.Message"},
+		)
+		cfg.Retries = -1
+		So(cfg.Validate(), ShouldHaveLength, 4)
+		cfg = testConfig()
+		cfg.Slack = []*SlackConfig{{URL: "hooks.slack.com"}}
+		cfg.Email = []*EmailConfig{
+			{Server: "smtp.example.com:25", From: "snap@example.com", To: []string{"ops@example.com"}},
+			{Server: "smtp.example.com", From: "snap", Events: []string{"task-started"}},
+		}
+		So(cfg.Validate(), ShouldHaveLength, 5)
+	})
+}
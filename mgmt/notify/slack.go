@@ -0,0 +1,112 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+// slack posts the notifications as messages to a Slack incoming webhook
+type slack struct {
+	eventFilter
+	url      string
+	channel  string
+	username string
+	client   *http.Client
+}
+
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Channel     string            `json:"channel,omitempty"`
+	Username    string            `json:"username,omitempty"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Fields []slackField `json:"fields"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+func newSlack(cfg *SlackConfig, client *http.Client) *slack {
+	return &slack{
+		eventFilter: newEventFilter(cfg.Events),
+		url:         cfg.URL,
+		channel:     cfg.Channel,
+		username:    cfg.Username,
+		client:      client,
+	}
+}
+
+func (s *slack) target() string {
+	return "slack " + s.url
+}
+
+func (s *slack) message(n *Notification) *slackMessage {
+	a := slackAttachment{Color: slackColor(n)}
+	for _, k := range sortedKeys(n.Fields) {
+		a.Fields = append(a.Fields, slackField{Title: k, Value: n.Fields[k], Short: true})
+	}
+	return &slackMessage{
+		Text:        n.Message,
+		Channel:     s.channel,
+		Username:    s.username,
+		Attachments: []slackAttachment{a},
+	}
+}
+
+func (s *slack) send(n *Notification) (bool, error) {
+	b, err := json.Marshal(s.message(n))
+	if err != nil {
+		return false, err
+	}
+	return postJSON(s.client, s.url, b, nil)
+}
+
+// slackColor colors the message by how bad the news is
+func slackColor(n *Notification) string {
+	switch n.Event {
+	case EventAlertResolved:
+		return "good"
+	case EventAlertFired:
+		if n.Fields["severity"] != "critical" {
+			return "warning"
+		}
+	case EventTribeMemberLeft, EventTribeClockSkew:
+		return "warning"
+	}
+	return "danger"
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,92 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+)
+
+// webhook posts the notifications in JSON, or rendered with a template, to
+// a URL
+type webhook struct {
+	eventFilter
+	url      string
+	template *template.Template
+	secret   []byte
+	client   *http.Client
+}
+
+func newWebhook(cfg *WebhookConfig, client *http.Client) (*webhook, error) {
+	w := &webhook{
+		eventFilter: newEventFilter(cfg.Events),
+		url:         cfg.URL,
+		secret:      []byte(cfg.Secret),
+		client:      client,
+	}
+	if cfg.Template != "" {
+		t, err := template.New(cfg.URL).Funcs(templateFuncs).Parse(cfg.Template)
+		if err != nil {
+			return nil, fmt.Errorf("template of %s: %v", cfg.URL, err)
+		}
+		w.template = t
+	}
+	return w, nil
+}
+
+func (w *webhook) target() string {
+	return w.url
+}
+
+// body returns the body posted for the notification
+func (w *webhook) body(n *Notification) ([]byte, error) {
+	if w.template == nil {
+		return json.Marshal(n)
+	}
+	var b bytes.Buffer
+	if err := w.template.Execute(&b, n); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (w *webhook) send(n *Notification) (bool, error) {
+	b, err := w.body(n)
+	if err != nil {
+		return false, fmt.Errorf("unable to render notification: %v", err)
+	}
+	header := http.Header{}
+	if len(w.secret) > 0 {
+		header.Set(SignatureHeader, "sha256="+sign(w.secret, b))
+	}
+	return postJSON(w.client, w.url, b, header)
+}
+
+func sign(secret, b []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(b)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,69 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/vrischmann/jsonutil"
+)
+
+// default configuration values
+const (
+	defaultInterval time.Duration = 30 * time.Second
+)
+
+// holds the configuration passed in through the SNAP config file
+type Config struct {
+	// File is the path of the desired-state file, reconciliation is disabled
+	// when it is empty
+	File string `json:"file,omitempty"yaml:"file,omitempty"`
+	// Interval is the time between two passes comparing the desired state
+	// with the state of snapd
+	Interval jsonutil.Duration `json:"interval,omitempty"yaml:"interval,omitempty"`
+	// Prune unloads the plugins and removes the tasks the reconciler created
+	// once they are no longer in the desired state
+	Prune bool `json:"prune"yaml:"prune"`
+}
+
+// get the default snapd configuration
+func GetDefaultConfig() *Config {
+	return &Config{
+		Interval: jsonutil.Duration{defaultInterval},
+	}
+}
+
+// Validate returns the problems found in the configuration
+func (c *Config) Validate() []error {
+	var errs []error
+	if c.Interval.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("reconcile.interval: must be greater than 0"))
+	}
+	if c.File != "" {
+		if f, err := os.Stat(c.File); err != nil {
+			errs = append(errs, fmt.Errorf("reconcile.file: %v", err))
+		} else if f.IsDir() {
+			errs = append(errs, fmt.Errorf("reconcile.file: %s is not a file", c.File))
+		}
+	}
+	return errs
+}
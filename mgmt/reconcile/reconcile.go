@@ -0,0 +1,417 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/mgmt/rest"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// Kinds of the corrective actions taken by the reconciler
+const (
+	ActionLoadPlugin   = "load-plugin"
+	ActionUnloadPlugin = "unload-plugin"
+	ActionCreateTask   = "create-task"
+	ActionStartTask    = "start-task"
+	ActionStopTask     = "stop-task"
+	ActionEnableTask   = "enable-task"
+	ActionRemoveTask   = "remove-task"
+)
+
+var (
+	reconcileLogger = log.WithField("_module", "reconcile")
+)
+
+type managesPlugins interface {
+	Load(*core.RequestedPlugin) (core.CatalogedPlugin, serror.SnapError)
+	Unload(core.Plugin) (core.CatalogedPlugin, serror.SnapError)
+	PluginCatalog() core.PluginCatalog
+}
+
+type managesTasks interface {
+	GetTasks() map[string]core.Task
+	GetTask(id string) (core.Task, error)
+	CreateTask(sch schedule.Schedule, wfMap *wmap.WorkflowMap, startOnCreate bool, opts ...core.TaskOption) (core.Task, core.TaskErrors)
+	StartTask(id string) []serror.SnapError
+	StopTask(id string) []serror.SnapError
+	EnableTask(id string) (core.Task, error)
+	RemoveTask(id string) error
+}
+
+// Action is a corrective action taken by the reconciler to converge the
+// state of snapd towards the desired state
+type Action struct {
+	Kind string
+	// Target is the plugin (type:name:version) or the task (name and ID)
+	// acted on
+	Target string
+	Err    error
+}
+
+// Reconciler periodically compares the desired state read from a file with
+// the plugins and tasks of snapd, and converges them. The tasks of the
+// desired state own their names: a task with the same name but created from
+// another request is replaced. Tasks being stopped are removed on a later
+// pass, once they are stopped.
+type Reconciler struct {
+	file          string
+	interval      time.Duration
+	prune         bool
+	pluginManager managesPlugins
+	taskManager   managesTasks
+	mutex         sync.Mutex
+	// the plugins loaded and the tasks created by the reconciler, pruned
+	// once they are no longer in the desired state
+	plugins map[string]core.Plugin
+	tasks   map[string]string
+	stop    chan struct{}
+}
+
+// New returns a reconciler. The plugin and task managers must be set
+// before it is started.
+func New(cfg *Config) *Reconciler {
+	return &Reconciler{
+		file:     cfg.File,
+		interval: cfg.Interval.Duration,
+		prune:    cfg.Prune,
+		plugins:  make(map[string]core.Plugin),
+		tasks:    make(map[string]string),
+	}
+}
+
+func (r *Reconciler) SetPluginManager(p managesPlugins) {
+	r.pluginManager = p
+}
+
+func (r *Reconciler) SetTaskManager(t managesTasks) {
+	r.taskManager = t
+}
+
+func (r *Reconciler) Name() string {
+	return "reconcile"
+}
+
+func (r *Reconciler) Start() error {
+	if r.file == "" {
+		reconcileLogger.WithField("_block", "start").Info("reconciliation is disabled")
+		return nil
+	}
+	if r.pluginManager == nil || r.taskManager == nil {
+		return fmt.Errorf("reconciler needs a plugin manager and a task manager")
+	}
+	r.stop = make(chan struct{})
+	go r.loop(r.stop)
+	reconcileLogger.WithFields(log.Fields{
+		"_block":   "start",
+		"file":     r.file,
+		"interval": r.interval,
+		"prune":    r.prune,
+	}).Info("reconciler started")
+	return nil
+}
+
+func (r *Reconciler) Stop() {
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+	reconcileLogger.WithField("_block", "stop").Info("reconciler stopped")
+}
+
+func (r *Reconciler) loop(stop chan struct{}) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		r.Reconcile()
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Reconcile reads the desired state and takes the actions converging the
+// state of snapd towards it, which are logged and returned. Nothing is done
+// when the desired state cannot be read or is not valid.
+func (r *Reconciler) Reconcile() []Action {
+	logger := reconcileLogger.WithFields(log.Fields{
+		"_block": "reconcile",
+		"file":   r.file,
+	})
+	s, err := ReadState(r.file)
+	if err != nil {
+		logger.Error(err)
+		return nil
+	}
+	if errs := s.Validate(); len(errs) > 0 {
+		for _, e := range errs {
+			logger.Error(e)
+		}
+		return nil
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var actions []Action
+	actions = append(actions, r.reconcilePlugins(s.Plugins)...)
+	actions = append(actions, r.reconcileTasks(s.Tasks)...)
+	if r.prune {
+		actions = append(actions, r.pruneTasks(s.Tasks)...)
+		actions = append(actions, r.prunePlugins(s.Plugins)...)
+	}
+	for _, a := range actions {
+		l := logger.WithFields(log.Fields{
+			"action": a.Kind,
+			"target": a.Target,
+		})
+		if a.Err != nil {
+			l.WithField("_error", a.Err).Error("corrective action failed")
+		} else {
+			l.Info("corrective action taken")
+		}
+	}
+	return actions
+}
+
+// reconcilePlugins loads the plugins missing
+func (r *Reconciler) reconcilePlugins(plugins []PluginState) []Action {
+	var actions []Action
+	for _, p := range plugins {
+		if r.loaded(p) != nil {
+			continue
+		}
+		a := Action{Kind: ActionLoadPlugin, Target: p.key()}
+		a.Err = r.loadPlugin(p)
+		actions = append(actions, a)
+	}
+	return actions
+}
+
+func (r *Reconciler) loadPlugin(p PluginState) error {
+	rp, err := core.NewRequestedPlugin(p.Path)
+	if err != nil {
+		return err
+	}
+	pl, serr := r.pluginManager.Load(rp)
+	if serr != nil {
+		return serr
+	}
+	if !p.matches(pl) {
+		if _, serr := r.pluginManager.Unload(pl); serr != nil {
+			return serr
+		}
+		return fmt.Errorf("%s is %s:%s:%d", p.Path, pl.TypeName(), pl.Name(), pl.Version())
+	}
+	r.plugins[p.key()] = pl
+	return nil
+}
+
+// loaded returns the plugin of the catalog satisfying the state, if any
+func (r *Reconciler) loaded(p PluginState) core.Plugin {
+	for _, pl := range r.pluginManager.PluginCatalog() {
+		if p.matches(pl) {
+			return pl
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) cataloged(p core.Plugin) bool {
+	for _, pl := range r.pluginManager.PluginCatalog() {
+		if pl.TypeName() == p.TypeName() && pl.Name() == p.Name() && pl.Version() == p.Version() {
+			return true
+		}
+	}
+	return false
+}
+
+// reconcileTasks creates the tasks missing, replaces the tasks created from
+// another request and starts or stops the tasks as their state asks
+func (r *Reconciler) reconcileTasks(tasks []TaskState) []Action {
+	var actions []Action
+	current := r.taskManager.GetTasks()
+	for _, ts := range tasks {
+		tr, err := ts.Request()
+		var id string
+		if err == nil {
+			id, err = TaskID(tr)
+		}
+		if err != nil {
+			actions = append(actions, Action{Kind: ActionCreateTask, Target: ts.Name, Err: err})
+			continue
+		}
+		for oid, t := range current {
+			if oid != id && t.GetName() == ts.Name {
+				actions = append(actions, r.removeTask(t)...)
+			}
+		}
+		t, ok := current[id]
+		if !ok {
+			a := Action{Kind: ActionCreateTask, Target: target(ts.Name, id)}
+			a.Err = r.createTask(tr, id, !ts.Stopped)
+			actions = append(actions, a)
+			continue
+		}
+		r.tasks[id] = ts.Name
+		actions = append(actions, r.convergeTask(t, ts.Stopped)...)
+	}
+	return actions
+}
+
+func (r *Reconciler) createTask(tr *request.TaskCreationRequest, id string, start bool) error {
+	sch, err := rest.MakeSchedule(tr.Schedule)
+	if err != nil {
+		return err
+	}
+	opts, err := rest.MakeTaskOptions(tr)
+	if err != nil {
+		return err
+	}
+	opts = append(opts, core.SetTaskID(id), core.OptionTaskCreatedBy("reconcile"))
+	if _, errs := r.taskManager.CreateTask(sch, tr.Workflow, start, opts...); errs != nil && len(errs.Errors()) > 0 {
+		return errs.Errors()[0]
+	}
+	r.tasks[id] = tr.Name
+	return nil
+}
+
+// convergeTask starts or stops the task as its state asks. A disabled task
+// is enabled first. The tasks paused, ended or being stopped are left as
+// they are.
+func (r *Reconciler) convergeTask(t core.Task, stopped bool) []Action {
+	var actions []Action
+	tgt := target(t.GetName(), t.ID())
+	state := t.State()
+	if state == core.TaskDisabled {
+		a := Action{Kind: ActionEnableTask, Target: tgt}
+		_, a.Err = r.taskManager.EnableTask(t.ID())
+		actions = append(actions, a)
+		if a.Err != nil {
+			return actions
+		}
+		state = core.TaskStopped
+	}
+	switch {
+	case state == core.TaskStopped && !stopped:
+		a := Action{Kind: ActionStartTask, Target: tgt}
+		a.Err = firstError(r.taskManager.StartTask(t.ID()))
+		actions = append(actions, a)
+	case (state == core.TaskSpinning || state == core.TaskFiring) && stopped:
+		a := Action{Kind: ActionStopTask, Target: tgt}
+		a.Err = firstError(r.taskManager.StopTask(t.ID()))
+		actions = append(actions, a)
+	}
+	return actions
+}
+
+// removeTask removes a stopped task, or stops it to remove it on a later
+// pass
+func (r *Reconciler) removeTask(t core.Task) []Action {
+	tgt := target(t.GetName(), t.ID())
+	switch t.State() {
+	case core.TaskStopped:
+		a := Action{Kind: ActionRemoveTask, Target: tgt}
+		if a.Err = r.taskManager.RemoveTask(t.ID()); a.Err == nil {
+			delete(r.tasks, t.ID())
+		}
+		return []Action{a}
+	case core.TaskDisabled:
+		a := Action{Kind: ActionEnableTask, Target: tgt}
+		_, a.Err = r.taskManager.EnableTask(t.ID())
+		return []Action{a}
+	case core.TaskSpinning, core.TaskFiring, core.TaskPaused:
+		a := Action{Kind: ActionStopTask, Target: tgt}
+		a.Err = firstError(r.taskManager.StopTask(t.ID()))
+		return []Action{a}
+	}
+	return nil
+}
+
+// pruneTasks removes the tasks created by the reconciler which are no longer
+// in the desired state
+func (r *Reconciler) pruneTasks(tasks []TaskState) []Action {
+	desired := map[string]bool{}
+	for _, ts := range tasks {
+		desired[ts.Name] = true
+	}
+	var actions []Action
+	for id, name := range r.tasks {
+		if desired[name] {
+			continue
+		}
+		t, err := r.taskManager.GetTask(id)
+		if err != nil {
+			// removed by someone else
+			delete(r.tasks, id)
+			continue
+		}
+		actions = append(actions, r.removeTask(t)...)
+	}
+	return actions
+}
+
+// prunePlugins unloads the plugins loaded by the reconciler which are no
+// longer in the desired state
+func (r *Reconciler) prunePlugins(plugins []PluginState) []Action {
+	desired := map[string]bool{}
+	for _, p := range plugins {
+		desired[p.key()] = true
+	}
+	var actions []Action
+	for key, pl := range r.plugins {
+		if desired[key] {
+			continue
+		}
+		if !r.cataloged(pl) {
+			// unloaded by someone else
+			delete(r.plugins, key)
+			continue
+		}
+		a := Action{Kind: ActionUnloadPlugin, Target: key}
+		if _, serr := r.pluginManager.Unload(pl); serr != nil {
+			a.Err = serr
+		} else {
+			delete(r.plugins, key)
+		}
+		actions = append(actions, a)
+	}
+	return actions
+}
+
+func target(name, id string) string {
+	return fmt.Sprintf("%s (%s)", name, id)
+}
+
+func firstError(errs []serror.SnapError) error {
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
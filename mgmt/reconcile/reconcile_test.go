@@ -0,0 +1,272 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type mockPlugin struct {
+	core.CatalogedPlugin
+	typ  string
+	name string
+	ver  int
+}
+
+func (p *mockPlugin) TypeName() string { return p.typ }
+func (p *mockPlugin) Name() string     { return p.name }
+func (p *mockPlugin) Version() int     { return p.ver }
+
+type mockPluginManager struct {
+	catalog core.PluginCatalog
+	// the plugin loaded from any path
+	next *mockPlugin
+}
+
+func (m *mockPluginManager) Load(*core.RequestedPlugin) (core.CatalogedPlugin, serror.SnapError) {
+	m.catalog = append(m.catalog, m.next)
+	return m.next, nil
+}
+
+func (m *mockPluginManager) Unload(pl core.Plugin) (core.CatalogedPlugin, serror.SnapError) {
+	for i, c := range m.catalog {
+		if c.TypeName() == pl.TypeName() && c.Name() == pl.Name() && c.Version() == pl.Version() {
+			m.catalog = append(m.catalog[:i], m.catalog[i+1:]...)
+			return c, nil
+		}
+	}
+	return nil, serror.New(errors.New("plugin not found"))
+}
+
+func (m *mockPluginManager) PluginCatalog() core.PluginCatalog {
+	return m.catalog
+}
+
+type mockTask struct {
+	core.Task
+	id            string
+	name          string
+	state         core.TaskState
+	stopOnFailure uint
+	createdBy     string
+}
+
+func (t *mockTask) ID() string              { return t.id }
+func (t *mockTask) SetID(id string)         { t.id = id }
+func (t *mockTask) GetName() string         { return t.name }
+func (t *mockTask) SetName(name string)     { t.name = name }
+func (t *mockTask) State() core.TaskState   { return t.state }
+func (t *mockTask) GetStopOnFailure() uint  { return t.stopOnFailure }
+func (t *mockTask) SetStopOnFailure(v uint) { t.stopOnFailure = v }
+func (t *mockTask) CreatedBy() string       { return t.createdBy }
+func (t *mockTask) SetCreatedBy(p string)   { t.createdBy = p }
+
+type mockTaskManager map[string]*mockTask
+
+func (m mockTaskManager) GetTasks() map[string]core.Task {
+	tasks := map[string]core.Task{}
+	for id, t := range m {
+		tasks[id] = t
+	}
+	return tasks
+}
+
+func (m mockTaskManager) GetTask(id string) (core.Task, error) {
+	if t, ok := m[id]; ok {
+		return t, nil
+	}
+	return nil, errors.New("Task not found")
+}
+
+func (m mockTaskManager) CreateTask(sch schedule.Schedule, wfMap *wmap.WorkflowMap, start bool, opts ...core.TaskOption) (core.Task, core.TaskErrors) {
+	t := &mockTask{state: core.TaskStopped}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if start {
+		t.state = core.TaskSpinning
+	}
+	m[t.id] = t
+	return t, nil
+}
+
+func (m mockTaskManager) StartTask(id string) []serror.SnapError {
+	m[id].state = core.TaskSpinning
+	return nil
+}
+
+// StopTask leaves the task stopping, as the scheduler does until its
+// running job returns
+func (m mockTaskManager) StopTask(id string) []serror.SnapError {
+	m[id].state = core.TaskStopping
+	return nil
+}
+
+func (m mockTaskManager) EnableTask(id string) (core.Task, error) {
+	m[id].state = core.TaskStopped
+	return m[id], nil
+}
+
+func (m mockTaskManager) RemoveTask(id string) error {
+	if m[id].state != core.TaskStopped {
+		return errors.New("Task must be stopped")
+	}
+	delete(m, id)
+	return nil
+}
+
+func kinds(actions []Action) []string {
+	var k []string
+	for _, a := range actions {
+		So(a.Err, ShouldBeNil)
+		k = append(k, a.Kind)
+	}
+	return k
+}
+
+const task = `
+    task:
+      version: 1
+      schedule:
+        type: simple
+        interval: %s
+      workflow:
+        collect:
+          metrics:
+            /intel/mock/foo: {}
+`
+
+func TestReconciler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reconcile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pluginPath := filepath.Join(dir, "snap-collector-mock")
+	ioutil.WriteFile(pluginPath, []byte("mock"), 0755)
+	stateFile := filepath.Join(dir, "state.yaml")
+	writeState := func(interval string, stopped bool, withTask bool) {
+		s := "plugins:\n  - name: mock\n    type: collector\n    version: 2\n    path: " + pluginPath + "\n"
+		if withTask {
+			s += "tasks:\n  - name: cpu\n"
+			if stopped {
+				s += "    stopped: true\n"
+			}
+			s += fmt.Sprintf(task, interval)
+		}
+		ioutil.WriteFile(stateFile, []byte(s), 0644)
+	}
+
+	Convey("Given a reconciler and a desired state", t, func() {
+		writeState("1s", false, true)
+		pm := &mockPluginManager{next: &mockPlugin{typ: "collector", name: "mock", ver: 2}}
+		tm := mockTaskManager{}
+		r := New(&Config{File: stateFile, Prune: true})
+		r.SetPluginManager(pm)
+		r.SetTaskManager(tm)
+
+		Convey("the plugins and tasks missing are loaded and created", func() {
+			So(kinds(r.Reconcile()), ShouldResemble, []string{ActionLoadPlugin, ActionCreateTask})
+			So(pm.catalog, ShouldHaveLength, 1)
+			So(tm, ShouldHaveLength, 1)
+			for _, t := range tm {
+				So(t.name, ShouldEqual, "cpu")
+				So(t.state, ShouldEqual, core.TaskSpinning)
+				So(t.createdBy, ShouldEqual, "reconcile")
+			}
+
+			Convey("and nothing is done once snapd is in the desired state", func() {
+				So(r.Reconcile(), ShouldBeEmpty)
+			})
+			Convey("a stopped task is started again", func() {
+				for _, t := range tm {
+					t.state = core.TaskStopped
+				}
+				So(kinds(r.Reconcile()), ShouldResemble, []string{ActionStartTask})
+			})
+			Convey("a disabled task is enabled and started again", func() {
+				for _, t := range tm {
+					t.state = core.TaskDisabled
+				}
+				So(kinds(r.Reconcile()), ShouldResemble, []string{ActionEnableTask, ActionStartTask})
+			})
+			Convey("a task asked to be stopped is stopped", func() {
+				writeState("1s", true, true)
+				So(kinds(r.Reconcile()), ShouldResemble, []string{ActionStopTask})
+			})
+			Convey("a changed task is replaced", func() {
+				writeState("5s", false, true)
+				So(kinds(r.Reconcile()), ShouldResemble, []string{ActionStopTask, ActionCreateTask})
+				So(tm, ShouldHaveLength, 2)
+				for _, t := range tm {
+					if t.state == core.TaskStopping {
+						t.state = core.TaskStopped
+					}
+				}
+				So(kinds(r.Reconcile()), ShouldResemble, []string{ActionRemoveTask})
+				So(tm, ShouldHaveLength, 1)
+			})
+			Convey("a task no longer desired is pruned", func() {
+				writeState("1s", false, false)
+				So(kinds(r.Reconcile()), ShouldResemble, []string{ActionStopTask})
+				for _, t := range tm {
+					t.state = core.TaskStopped
+				}
+				So(kinds(r.Reconcile()), ShouldResemble, []string{ActionRemoveTask})
+				So(tm, ShouldBeEmpty)
+			})
+			Convey("a plugin no longer desired is pruned", func() {
+				ioutil.WriteFile(stateFile, []byte("plugins: []\n"), 0644)
+				for _, t := range tm {
+					t.state = core.TaskStopped
+				}
+				So(kinds(r.Reconcile()), ShouldResemble, []string{ActionRemoveTask, ActionUnloadPlugin})
+				So(pm.catalog, ShouldBeEmpty)
+			})
+		})
+		Convey("a plugin loaded already is not loaded again", func() {
+			pm.catalog = core.PluginCatalog{&mockPlugin{typ: "collector", name: "mock", ver: 2}}
+			So(kinds(r.Reconcile()), ShouldResemble, []string{ActionCreateTask})
+		})
+		Convey("a plugin of another version than desired is not kept", func() {
+			pm.next = &mockPlugin{typ: "collector", name: "mock", ver: 1}
+			actions := r.Reconcile()
+			So(actions[0].Kind, ShouldEqual, ActionLoadPlugin)
+			So(actions[0].Err, ShouldNotBeNil)
+			So(pm.catalog, ShouldBeEmpty)
+		})
+		Convey("nothing is done when the desired state is not valid", func() {
+			ioutil.WriteFile(stateFile, []byte("tasks:\n  - name: cpu\n"), 0644)
+			So(r.Reconcile(), ShouldBeEmpty)
+			So(tm, ShouldBeEmpty)
+		})
+	})
+}
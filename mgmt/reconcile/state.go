@@ -0,0 +1,146 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ghodss/yaml"
+	"github.com/pborman/uuid"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
+)
+
+// State is the desired state of snapd: the plugins loaded and the tasks
+// created. It is read from a JSON or YAML file.
+type State struct {
+	Plugins []PluginState `json:"plugins,omitempty"yaml:"plugins,omitempty"`
+	Tasks   []TaskState   `json:"tasks,omitempty"yaml:"tasks,omitempty"`
+}
+
+// PluginState is a plugin loaded, from Path when it is not. Any version of
+// the plugin satisfies the state when Version is 0.
+type PluginState struct {
+	Name    string `json:"name"yaml:"name"`
+	Type    string `json:"type"yaml:"type"`
+	Version int    `json:"version,omitempty"yaml:"version,omitempty"`
+	Path    string `json:"path"yaml:"path"`
+}
+
+// TaskState is a task, identified by its name. The task is given inline or
+// by the path of its task manifest, and is kept running unless Stopped is
+// set.
+type TaskState struct {
+	Name     string                       `json:"name"yaml:"name"`
+	Manifest string                       `json:"manifest,omitempty"yaml:"manifest,omitempty"`
+	Task     *request.TaskCreationRequest `json:"task,omitempty"yaml:"task,omitempty"`
+	Stopped  bool                         `json:"stopped,omitempty"yaml:"stopped,omitempty"`
+}
+
+// ReadState reads the desired state from a JSON or YAML file.
+func ReadState(fpath string) (*State, error) {
+	b, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+	s := &State{}
+	// yaml.Unmarshal handles JSON as well
+	if err := yaml.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("%s: %v", fpath, err)
+	}
+	return s, nil
+}
+
+// Validate returns the problems found in the state.
+func (s *State) Validate() []error {
+	var errs []error
+	plugins := map[string]bool{}
+	for i, p := range s.Plugins {
+		if p.Name == "" || p.Type == "" || p.Path == "" {
+			errs = append(errs, fmt.Errorf("plugins[%d]: a name, type and path must be given", i))
+			continue
+		}
+		if _, err := core.ToPluginType(p.Type); err != nil {
+			errs = append(errs, fmt.Errorf("plugins[%d]: %v", i, err))
+		}
+		if plugins[p.key()] {
+			errs = append(errs, fmt.Errorf("plugins[%d]: %s is declared twice", i, p.key()))
+		}
+		plugins[p.key()] = true
+	}
+	names := map[string]bool{}
+	for i, t := range s.Tasks {
+		if t.Name == "" {
+			errs = append(errs, fmt.Errorf("tasks[%d]: name must not be empty", i))
+		} else if names[t.Name] {
+			errs = append(errs, fmt.Errorf("tasks[%d]: %q is declared twice", i, t.Name))
+		}
+		names[t.Name] = true
+		if (t.Manifest == "") == (t.Task == nil) {
+			errs = append(errs, fmt.Errorf("tasks[%d]: either a manifest or a task must be given", i))
+		}
+	}
+	return errs
+}
+
+// key identifies the plugin, as type:name:version
+func (p PluginState) key() string {
+	return fmt.Sprintf("%s:%s:%d", p.Type, p.Name, p.Version)
+}
+
+// matches returns whether the plugin satisfies the state
+func (p PluginState) matches(pl core.Plugin) bool {
+	return pl.Name() == p.Name && pl.TypeName() == p.Type && (p.Version == 0 || pl.Version() == p.Version)
+}
+
+// Request returns the request creating the task, named after the state.
+func (t *TaskState) Request() (*request.TaskCreationRequest, error) {
+	tr := &request.TaskCreationRequest{}
+	if t.Task != nil {
+		*tr = *t.Task
+	} else {
+		b, err := ioutil.ReadFile(t.Manifest)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(b, tr); err != nil {
+			return nil, fmt.Errorf("%s: %v", t.Manifest, err)
+		}
+	}
+	tr.Name = t.Name
+	tr.Start = false
+	return tr, nil
+}
+
+// TaskID returns the ID of the task created for the request. The ID changes
+// with the request, so that the task is replaced when its state changes.
+func TaskID(tr *request.TaskCreationRequest) (string, error) {
+	b, err := json.Marshal(tr)
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum(b)
+	return uuid.NewSHA1(uuid.NameSpace_URL, []byte("snap-reconcile:"+tr.Name+"/"+hex.EncodeToString(sum[:]))).String(), nil
+}
@@ -0,0 +1,65 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
+)
+
+var (
+	ErrAggregatorDisabled = errors.New("The aggregator role is not enabled")
+	ErrAggregatorFull     = errors.New("The buffer of the aggregator is full")
+)
+
+func (s *Server) getAggregator(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	respond(200, &rbody.AggregatorReturned{Aggregator: rbody.AggregatorFromAggregator(s.mt.Aggregator())}, w)
+}
+
+func (s *Server) ingestAggregatorMetrics(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	req := &request.AggregatorMetrics{}
+	errCode, err := marshalBody(req, r.Body)
+	if errCode != 0 && err != nil {
+		respond(errCode, rbody.FromError(err), w)
+		return
+	}
+	n, err := s.mt.IngestMetrics(principal(r), req.TaskID, req.ContentType, req.Content)
+	if err != nil {
+		se := serror.New(err, map[string]interface{}{"task-id": req.TaskID})
+		if strings.Contains(err.Error(), ErrAggregatorDisabled.Error()) {
+			respond(409, rbody.FromSnapError(se), w)
+			return
+		}
+		if strings.Contains(err.Error(), ErrAggregatorFull.Error()) {
+			respond(503, rbody.FromSnapError(se), w)
+			return
+		}
+		respond(400, rbody.FromSnapError(se), w)
+		return
+	}
+	respond(200, &rbody.AggregatorMetricsIngested{Metrics: n}, w)
+}
@@ -0,0 +1,111 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type aggregatorTaskManager struct {
+	managesTasks
+	enabled bool
+	full    bool
+}
+
+func (m *aggregatorTaskManager) IngestMetrics(source, taskID, contentType string, content []byte) (int, error) {
+	if !m.enabled {
+		return 0, errors.New("The aggregator role is not enabled.")
+	}
+	if m.full {
+		return 0, errors.New("The buffer of the aggregator is full.")
+	}
+	if contentType != "test" {
+		return 0, errors.New("unknown content type")
+	}
+	return len(content), nil
+}
+
+func (m *aggregatorTaskManager) Aggregator() core.Aggregator {
+	return core.Aggregator{
+		Enabled:  m.enabled,
+		Interval: 10 * time.Second,
+		Received: 3,
+		Sources:  []core.AggregatorSource{{Principal: "edge", Received: 3, LastSeen: time.Unix(1000, 0)}},
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	Convey("The aggregator API", t, func() {
+		s, err := New(GetDefaultConfig())
+		So(err, ShouldBeNil)
+		tm := &aggregatorTaskManager{enabled: true}
+		s.BindTaskManager(tm)
+		s.addRoutes()
+		do := func(method, uri, body string) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(method, uri, strings.NewReader(body))
+			s.n.ServeHTTP(rec, req)
+			return rec
+		}
+		Convey("returns the state of the aggregator", func() {
+			rec := do("GET", "/v1/aggregator", "")
+			So(rec.Code, ShouldEqual, 200)
+			var resp struct {
+				Body rbody.Aggregator `json:"body"`
+			}
+			So(json.Unmarshal(rec.Body.Bytes(), &resp), ShouldBeNil)
+			So(resp.Body.Enabled, ShouldBeTrue)
+			So(resp.Body.Interval, ShouldEqual, "10s")
+			So(resp.Body.Received, ShouldEqual, 3)
+			So(resp.Body.Sources, ShouldHaveLength, 1)
+			So(resp.Body.Sources[0].Principal, ShouldEqual, "edge")
+			So(resp.Body.Sources[0].LastSeenTimestamp, ShouldEqual, 1000)
+		})
+		Convey("ingests the metrics forwarded", func() {
+			rec := do("POST", "/v1/aggregator/metrics", `{"task_id": "t1", "content_type": "test", "content": "YWJj"}`)
+			So(rec.Code, ShouldEqual, 200)
+			So(rec.Body.String(), ShouldContainSubstring, rbody.AggregatorMetricsIngestedType)
+		})
+		Convey("fails for content which cannot be decoded", func() {
+			rec := do("POST", "/v1/aggregator/metrics", `{"task_id": "t1", "content_type": "other"}`)
+			So(rec.Code, ShouldEqual, 400)
+		})
+		Convey("fails when the aggregator role is not enabled", func() {
+			tm.enabled = false
+			rec := do("POST", "/v1/aggregator/metrics", `{"task_id": "t1", "content_type": "test"}`)
+			So(rec.Code, ShouldEqual, 409)
+		})
+		Convey("fails while the buffer of the aggregator is full", func() {
+			tm.full = true
+			rec := do("POST", "/v1/aggregator/metrics", `{"task_id": "t1", "content_type": "test"}`)
+			So(rec.Code, ShouldEqual, 503)
+		})
+	})
+}
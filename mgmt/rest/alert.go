@@ -1,4 +1,4 @@
-<!--
+/*
 http://www.apache.org/licenses/LICENSE-2.0.txt
 
 
@@ -15,9 +15,18 @@ distributed under the License is distributed on an "AS IS" BASIS,
 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
 See the License for the specific language governing permissions and
 limitations under the License.
--->
+*/
 
-Go snap client
-===============
+package rest
 
-Go bindings for snap's REST API
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+func (s *Server) getAlerts(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	respond(200, rbody.AlertListFromAlerts(s.ma.ActiveAlerts()), w)
+}
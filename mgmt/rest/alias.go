@@ -0,0 +1,79 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+var (
+	ErrAliasNotFound = errors.New("Alias not found")
+)
+
+func (s *Server) getAliases(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	respond(200, rbody.AliasListFromAliases(s.mm.Aliases()), w)
+}
+
+func (s *Server) addAlias(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	alias := p.ByName("namespace")
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respond(500, rbody.FromError(err), w)
+		return
+	}
+	m := struct {
+		Target string `json:"target"`
+	}{}
+	if err := json.Unmarshal(b, &m); err != nil || m.Target == "" {
+		fields := map[string]interface{}{
+			"error": err,
+			"hint":  `The body of the request should be of the form '{"target": "/intel/psutil/cpu/percent"}'`,
+		}
+		respond(400, rbody.FromSnapError(serror.New(ErrInvalidJSON, fields)), w)
+		return
+	}
+	if err := s.mm.AddAlias(alias, m.Target); err != nil {
+		respond(400, rbody.FromError(err), w)
+		return
+	}
+	respond(200, &rbody.AliasAdded{Alias: alias, Target: m.Target}, w)
+}
+
+func (s *Server) removeAlias(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	alias := p.ByName("namespace")
+	if err := s.mm.RemoveAlias(alias); err != nil {
+		if strings.Contains(err.Error(), ErrAliasNotFound.Error()) {
+			respond(404, rbody.FromError(err), w)
+			return
+		}
+		respond(400, rbody.FromError(err), w)
+		return
+	}
+	respond(200, &rbody.AliasRemoved{Alias: alias}, w)
+}
@@ -0,0 +1,271 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/tlspolicy"
+)
+
+// passwordPrincipal is the principal of the requests authenticated with the
+// rest_auth password
+const passwordPrincipal = "snap"
+
+// anonymousPrincipal is the principal of the requests when no authentication
+// is enabled
+const anonymousPrincipal = "anonymous"
+
+var (
+	// ErrNoCredentials is returned by an auth provider given a request
+	// without the credentials it handles, for the next one to be tried
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials is returned by an auth provider given
+	// credentials it rejects
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// AuthProvider authenticates the requests made to the REST API. A request is
+// authenticated by the first provider accepting its credentials, which lets
+// snapd be integrated with the identity systems in place: static tokens,
+// LDAP, OIDC or any other provider added with AddAuthProvider.
+type AuthProvider interface {
+	// Name names the provider, e.g. "ldap"
+	Name() string
+	// Authenticate returns the principal making the request, or
+	// ErrNoCredentials when the request holds no credentials the provider
+	// handles
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// Principal is the identity a request to the REST API is authenticated as
+type Principal struct {
+	Name   string
+	Groups []string
+	// Provider is the name of the provider which authenticated the principal
+	Provider string
+	// Tenant is the tenant the principal is scoped to, empty when the
+	// principal sees all of snapd
+	Tenant string
+}
+
+type principalKey struct{}
+
+// PrincipalFromRequest returns the principal the request was authenticated
+// as, if any
+func PrincipalFromRequest(r *http.Request) (*Principal, bool) {
+	p, ok := r.Context().Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// AuthConfig configures the providers authenticating the requests made to
+// the REST API besides the rest_auth password. Configuring any of them
+// enables authentication.
+type AuthConfig struct {
+	// Tokens are static bearer tokens, e.g. for automation
+	Tokens []TokenConfig `json:"tokens,omitempty"yaml:"tokens,omitempty"`
+	// LDAP authenticates the users of basic authentication with an LDAP bind
+	LDAP *LDAPConfig `json:"ldap,omitempty"yaml:"ldap,omitempty"`
+	// OIDC authenticates the bearer tokens issued by an OpenID Connect provider
+	OIDC *OIDCConfig `json:"oidc,omitempty"yaml:"oidc,omitempty"`
+	// Tenants scopes the principals to the tenants of control.tenants by
+	// tenant ID
+	Tenants map[string]TenantPrincipals `json:"tenants,omitempty"yaml:"tenants,omitempty"`
+	// Quotas bounds the tasks created by the principals, by name
+	Quotas map[string]*core.Quota `json:"quotas,omitempty"yaml:"quotas,omitempty"`
+}
+
+// TenantPrincipals are the principals scoped to a tenant, by name or group
+type TenantPrincipals struct {
+	Principals []string `json:"principals,omitempty"yaml:"principals,omitempty"`
+	Groups     []string `json:"groups,omitempty"yaml:"groups,omitempty"`
+}
+
+// TokenConfig is a static bearer token and the principal it authenticates
+type TokenConfig struct {
+	Name   string   `json:"name"yaml:"name"`
+	Token  string   `json:"token"yaml:"token"secret:"true"`
+	Groups []string `json:"groups,omitempty"yaml:"groups,omitempty"`
+}
+
+// Validate returns the problems found in the configuration
+func (c *AuthConfig) Validate() []error {
+	if c == nil {
+		return nil
+	}
+	var errs []error
+	tokens := map[string]bool{}
+	for i, t := range c.Tokens {
+		if t.Name == "" {
+			errs = append(errs, fmt.Errorf("restapi.auth.tokens[%d].name: must be set", i))
+		}
+		if t.Token == "" {
+			errs = append(errs, fmt.Errorf("restapi.auth.tokens[%d].token: must be set", i))
+		} else if tokens[t.Token] {
+			errs = append(errs, fmt.Errorf("restapi.auth.tokens[%d].token: already given to another principal", i))
+		}
+		tokens[t.Token] = true
+	}
+	errs = append(errs, validateTenantPrincipals(c.Tenants)...)
+	for name, q := range c.Quotas {
+		// the shared password and no authentication verify no identity, the
+		// quota would bound every client at once
+		if name == passwordPrincipal || name == anonymousPrincipal {
+			errs = append(errs, fmt.Errorf("restapi.auth.quotas.%s: quotas only bound the principals of tokens, LDAP or OIDC", name))
+			continue
+		}
+		errs = append(errs, q.Validate("restapi.auth.quotas."+name)...)
+	}
+	if c.LDAP != nil {
+		errs = append(errs, c.LDAP.validate()...)
+	}
+	if c.OIDC != nil {
+		errs = append(errs, c.OIDC.validate()...)
+	}
+	return errs
+}
+
+// newAuthProviders returns the providers of the configuration, in the order
+// they are tried: tokens, LDAP then OIDC, the latter two connecting within
+// the TLS policy
+func newAuthProviders(c *AuthConfig, policy *tlspolicy.Config) ([]AuthProvider, error) {
+	if c == nil {
+		return nil, nil
+	}
+	var providers []AuthProvider
+	if len(c.Tokens) > 0 {
+		providers = append(providers, newTokenAuth(c.Tokens))
+	}
+	if c.LDAP != nil {
+		p, err := newLDAPAuth(c.LDAP, policy)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+	if c.OIDC != nil {
+		p, err := newOIDCAuth(c.OIDC, policy)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+	return providers, nil
+}
+
+// authenticate returns the principal of the first provider accepting the
+// credentials of the request
+func authenticate(providers []AuthProvider, r *http.Request) (*Principal, error) {
+	err := ErrNoCredentials
+	for _, p := range providers {
+		principal, perr := p.Authenticate(r)
+		if perr == nil {
+			principal.Provider = p.Name()
+			return principal, nil
+		}
+		if perr != ErrNoCredentials {
+			restLogger.WithFields(log.Fields{
+				"_block":      "authenticate",
+				"provider":    p.Name(),
+				"remote-addr": r.RemoteAddr,
+			}).Debug(perr)
+			err = ErrInvalidCredentials
+		}
+	}
+	return nil, err
+}
+
+// withPrincipal returns the request carrying the principal it is
+// authenticated as
+func withPrincipal(r *http.Request, p *Principal) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), principalKey{}, p))
+}
+
+// passwordAuth authenticates the basic authentication with the rest_auth
+// password, whatever the user. As the password is shared, the user is not
+// verified, and every request is authenticated as the same principal.
+type passwordAuth struct {
+	password string
+}
+
+func (p *passwordAuth) Name() string {
+	return "password"
+}
+
+func (p *passwordAuth) Authenticate(r *http.Request) (*Principal, error) {
+	_, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	if subtle.ConstantTimeCompare([]byte(password), []byte(p.password)) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	return &Principal{Name: passwordPrincipal}, nil
+}
+
+// tokenAuth authenticates the static bearer tokens of the configuration
+type tokenAuth struct {
+	tokens []TokenConfig
+}
+
+func newTokenAuth(tokens []TokenConfig) *tokenAuth {
+	return &tokenAuth{tokens: tokens}
+}
+
+func (t *tokenAuth) Name() string {
+	return "token"
+}
+
+func (t *tokenAuth) Authenticate(r *http.Request) (*Principal, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, ErrNoCredentials
+	}
+	// every token is compared not to tell how many match
+	var found *TokenConfig
+	for i := range t.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t.tokens[i].Token)) == 1 {
+			found = &t.tokens[i]
+		}
+	}
+	if found == nil {
+		return nil, ErrInvalidCredentials
+	}
+	return &Principal{Name: found.Name, Groups: found.Groups}, nil
+}
+
+// bearerToken returns the bearer token of the Authorization header of the
+// request, if any
+func bearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	const prefix = "bearer "
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(auth[len(prefix):])
+}
@@ -0,0 +1,240 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vrischmann/jsonutil"
+	"gopkg.in/ldap.v2"
+
+	"github.com/intelsdi-x/snap/pkg/tlspolicy"
+)
+
+const defaultLDAPTimeout = 5 * time.Second
+
+// LDAPConfig configures the authentication of the users of basic
+// authentication with a bind to an LDAP directory
+type LDAPConfig struct {
+	// URL is the directory, ldap://host[:389] or ldaps://host[:636]
+	URL string `json:"url"yaml:"url"`
+	// UserDN is the template of the DN the users bind as, %s standing for
+	// the user, e.g. uid=%s,ou=people,dc=example,dc=com
+	UserDN string `json:"user_dn"yaml:"user_dn"`
+	// StartTLS upgrades an ldap:// connection to TLS before binding
+	StartTLS bool `json:"start_tls,omitempty"yaml:"start_tls,omitempty"`
+	// CACertificate is the path to the CA certificates the certificate of the
+	// directory is verified against, the system ones by default
+	CACertificate      string `json:"ca_certificate,omitempty"yaml:"ca_certificate,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"yaml:"insecure_skip_verify,omitempty"`
+	// Timeout bounds connecting and binding to the directory, 5s by default
+	Timeout jsonutil.Duration `json:"timeout,omitempty"yaml:"timeout,omitempty"`
+	// CacheTTL is how long a successful bind is remembered, sparing a bind
+	// per request. Binds are not cached by default.
+	CacheTTL jsonutil.Duration `json:"cache_ttl,omitempty"yaml:"cache_ttl,omitempty"`
+}
+
+func (c *LDAPConfig) validate() []error {
+	var errs []error
+	if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("restapi.auth.ldap.url: %q is not an ldap:// or ldaps:// URL", c.URL))
+	} else if u.Scheme == "ldaps" && c.StartTLS {
+		errs = append(errs, fmt.Errorf("restapi.auth.ldap.start_tls: cannot be used with an ldaps:// URL"))
+	}
+	if strings.Count(c.UserDN, "%s") != 1 {
+		errs = append(errs, fmt.Errorf("restapi.auth.ldap.user_dn: %q must hold %%s once, standing for the user", c.UserDN))
+	}
+	if c.CACertificate != "" {
+		if _, err := ioutil.ReadFile(c.CACertificate); err != nil {
+			errs = append(errs, fmt.Errorf("restapi.auth.ldap.ca_certificate: %v", err))
+		}
+	}
+	if c.Timeout.Duration < 0 {
+		errs = append(errs, fmt.Errorf("restapi.auth.ldap.timeout: %s must not be negative", c.Timeout.Duration))
+	}
+	if c.CacheTTL.Duration < 0 {
+		errs = append(errs, fmt.Errorf("restapi.auth.ldap.cache_ttl: %s must not be negative", c.CacheTTL.Duration))
+	}
+	return errs
+}
+
+// ldapAuth authenticates the users of basic authentication by binding to the
+// directory as them
+type ldapAuth struct {
+	config    *LDAPConfig
+	addr      string
+	tls       bool
+	tlsConfig *tls.Config
+	timeout   time.Duration
+	// binds to the directory, replaced in tests
+	bind func(dn, password string) error
+
+	mutex sync.Mutex
+	// the expiry of the successful binds, by hash of the credentials
+	cache map[[sha256.Size]byte]time.Time
+}
+
+func newLDAPAuth(c *LDAPConfig, policy *tlspolicy.Config) (*ldapAuth, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, err
+	}
+	l := &ldapAuth{
+		config:  c,
+		addr:    u.Host,
+		tls:     u.Scheme == "ldaps",
+		timeout: c.Timeout.Duration,
+		cache:   map[[sha256.Size]byte]time.Time{},
+	}
+	if l.timeout == 0 {
+		l.timeout = defaultLDAPTimeout
+	}
+	if u.Port() == "" {
+		port := "389"
+		if l.tls {
+			port = "636"
+		}
+		l.addr = net.JoinHostPort(u.Hostname(), port)
+	}
+	l.tlsConfig = policy.TLSConfig()
+	l.tlsConfig.ServerName = u.Hostname()
+	l.tlsConfig.InsecureSkipVerify = c.InsecureSkipVerify
+	if c.CACertificate != "" {
+		pem, err := ioutil.ReadFile(c.CACertificate)
+		if err != nil {
+			return nil, err
+		}
+		l.tlsConfig.RootCAs = x509.NewCertPool()
+		if !l.tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", c.CACertificate)
+		}
+	}
+	l.bind = l.bindDirectory
+	return l, nil
+}
+
+func (l *ldapAuth) Name() string {
+	return "ldap"
+}
+
+func (l *ldapAuth) Authenticate(r *http.Request) (*Principal, error) {
+	user, password, ok := r.BasicAuth()
+	if !ok || user == "" {
+		return nil, ErrNoCredentials
+	}
+	// a bind without password is an anonymous bind, which directories
+	// usually accept
+	if password == "" {
+		return nil, ErrInvalidCredentials
+	}
+	key := sha256.Sum256([]byte(user + "\x00" + password))
+	if l.cached(key) {
+		return &Principal{Name: user}, nil
+	}
+	dn := fmt.Sprintf(l.config.UserDN, escapeDN(user))
+	if err := l.bind(dn, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap: unable to bind as %s: %v", dn, err)
+	}
+	l.remember(key)
+	return &Principal{Name: user}, nil
+}
+
+func (l *ldapAuth) cached(key [sha256.Size]byte) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	expiry, ok := l.cache[key]
+	return ok && time.Now().Before(expiry)
+}
+
+func (l *ldapAuth) remember(key [sha256.Size]byte) {
+	if l.config.CacheTTL.Duration == 0 {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := time.Now()
+	for k, expiry := range l.cache {
+		if !now.Before(expiry) {
+			delete(l.cache, k)
+		}
+	}
+	l.cache[key] = now.Add(l.config.CacheTTL.Duration)
+}
+
+// bindDirectory binds to the directory as the DN with the password
+func (l *ldapAuth) bindDirectory(dn, password string) error {
+	conn, err := net.DialTimeout("tcp", l.addr, l.timeout)
+	if err != nil {
+		return err
+	}
+	if l.tls {
+		tc := tls.Client(conn, l.tlsConfig)
+		tc.SetDeadline(time.Now().Add(l.timeout))
+		if err := tc.Handshake(); err != nil {
+			conn.Close()
+			return err
+		}
+		tc.SetDeadline(time.Time{})
+		conn = tc
+	}
+	c := ldap.NewConn(conn, l.tls)
+	c.Start()
+	defer c.Close()
+	c.SetTimeout(l.timeout)
+	if l.config.StartTLS {
+		if err := c.StartTLS(l.tlsConfig); err != nil {
+			return err
+		}
+	}
+	return c.Bind(dn, password)
+}
+
+// escapeDN escapes the special characters of a DN attribute value (RFC 4514)
+func escapeDN(v string) string {
+	var b bytes.Buffer
+	for i, r := range v {
+		switch {
+		case strings.ContainsRune(`,+"\<>;=`, r),
+			i == 0 && (r == ' ' || r == '#'),
+			i == len(v)-1 && r == ' ':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == 0:
+			b.WriteString(`\00`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
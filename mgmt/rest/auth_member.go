@@ -0,0 +1,54 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+
+	"github.com/intelsdi-x/snap/pkg/reqsign"
+)
+
+// memberAuth authenticates the requests the members of a tribe sign when
+// calling the REST API of each other
+type memberAuth struct {
+	verifier *reqsign.Verifier
+}
+
+// NewMemberAuthProvider returns the provider authenticating the requests
+// signed by the members of the tribe with the key, as the member which
+// signed them
+func NewMemberAuthProvider(key []byte) AuthProvider {
+	return &memberAuth{verifier: reqsign.NewVerifier(key, reqsign.DefaultMaxAge)}
+}
+
+func (m *memberAuth) Name() string {
+	return "tribe"
+}
+
+func (m *memberAuth) Authenticate(r *http.Request) (*Principal, error) {
+	member, err := m.verifier.Verify(r)
+	if err == reqsign.ErrNotSigned {
+		return nil, ErrNoCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{Name: member, Groups: []string{"tribe"}}, nil
+}
@@ -0,0 +1,184 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc"
+
+	"github.com/intelsdi-x/snap/pkg/tlspolicy"
+)
+
+const (
+	defaultOIDCUsernameClaim = "sub"
+	defaultOIDCGroupsClaim   = "groups"
+	// bounds the requests made to the OIDC provider for its discovery
+	// document and keys
+	oidcRequestTimeout = 10 * time.Second
+)
+
+// OIDCConfig configures the authentication of the bearer tokens issued by
+// an OpenID Connect provider
+type OIDCConfig struct {
+	// Issuer is the URL of the provider, which its discovery document is
+	// fetched from and which the tokens must be issued by
+	Issuer string `json:"issuer"yaml:"issuer"`
+	// Audience is the audience the tokens must be issued for, e.g. the
+	// client ID of snapd at the provider
+	Audience string `json:"audience"yaml:"audience"`
+	// UsernameClaim is the claim naming the principal, sub by default
+	UsernameClaim string `json:"username_claim,omitempty"yaml:"username_claim,omitempty"`
+	// GroupsClaim is the claim listing the groups of the principal, groups
+	// by default
+	GroupsClaim string `json:"groups_claim,omitempty"yaml:"groups_claim,omitempty"`
+	// CACertificate is the path to the CA certificates the certificate of the
+	// provider is verified against, the system ones by default
+	CACertificate string `json:"ca_certificate,omitempty"yaml:"ca_certificate,omitempty"`
+}
+
+func (c *OIDCConfig) validate() []error {
+	var errs []error
+	if u, err := url.Parse(c.Issuer); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("restapi.auth.oidc.issuer: %q is not an http(s) URL", c.Issuer))
+	}
+	if c.Audience == "" {
+		errs = append(errs, fmt.Errorf("restapi.auth.oidc.audience: must be set"))
+	}
+	if c.CACertificate != "" {
+		if _, err := ioutil.ReadFile(c.CACertificate); err != nil {
+			errs = append(errs, fmt.Errorf("restapi.auth.oidc.ca_certificate: %v", err))
+		}
+	}
+	return errs
+}
+
+// oidcAuth authenticates the bearer tokens signed by the OIDC provider for
+// the audience. The discovery document of the provider is fetched on the
+// first token, and again on the next ones until it is.
+type oidcAuth struct {
+	config *OIDCConfig
+	ctx    context.Context
+
+	mutex    sync.Mutex
+	verifier *oidc.IDTokenVerifier
+}
+
+func newOIDCAuth(c *OIDCConfig, policy *tlspolicy.Config) (*oidcAuth, error) {
+	tc := policy.TLSConfig()
+	if c.CACertificate != "" {
+		pem, err := ioutil.ReadFile(c.CACertificate)
+		if err != nil {
+			return nil, err
+		}
+		tc.RootCAs = x509.NewCertPool()
+		if !tc.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", c.CACertificate)
+		}
+	}
+	client := &http.Client{
+		Timeout: oidcRequestTimeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tc,
+		},
+	}
+	return &oidcAuth{
+		config: c,
+		// the keys of the provider are fetched with the client of the context
+		ctx: oidc.ClientContext(context.Background(), client),
+	}, nil
+}
+
+func (o *oidcAuth) Name() string {
+	return "oidc"
+}
+
+func (o *oidcAuth) Authenticate(r *http.Request) (*Principal, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, ErrNoCredentials
+	}
+	v, err := o.getVerifier()
+	if err != nil {
+		return nil, err
+	}
+	idToken, err := v.Verify(o.ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: %v", err)
+	}
+	claims := map[string]interface{}{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("oidc: %v", err)
+	}
+	usernameClaim := o.config.UsernameClaim
+	if usernameClaim == "" {
+		usernameClaim = defaultOIDCUsernameClaim
+	}
+	name, _ := claims[usernameClaim].(string)
+	if name == "" {
+		return nil, fmt.Errorf("oidc: the token has no %s claim", usernameClaim)
+	}
+	groupsClaim := o.config.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = defaultOIDCGroupsClaim
+	}
+	return &Principal{Name: name, Groups: claimStrings(claims[groupsClaim])}, nil
+}
+
+// getVerifier returns the verifier of the tokens of the provider, fetching
+// its discovery document the first time
+func (o *oidcAuth) getVerifier() (*oidc.IDTokenVerifier, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.verifier != nil {
+		return o.verifier, nil
+	}
+	p, err := oidc.NewProvider(o.ctx, o.config.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: unable to discover %s: %v", o.config.Issuer, err)
+	}
+	o.verifier = p.Verifier(&oidc.Config{ClientID: o.config.Audience})
+	return o.verifier, nil
+}
+
+// claimStrings returns the strings of a claim holding a string or a list
+func claimStrings(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var s []string
+		for _, e := range v {
+			if str, ok := e.(string); ok {
+				s = append(s, str)
+			}
+		}
+		return s
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vrischmann/jsonutil"
+	"gopkg.in/ldap.v2"
+	"gopkg.in/square/go-jose.v2"
+
+	"github.com/intelsdi-x/snap/pkg/reqsign"
+	"github.com/intelsdi-x/snap/pkg/tlspolicy"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// authenticated returns the code answered to the request and the principal
+// it was authenticated as
+func authenticated(s *Server, req *http.Request) (int, *Principal) {
+	rec := httptest.NewRecorder()
+	var got *Principal
+	s.authMiddleware(rec, req, func(w http.ResponseWriter, r *http.Request) {
+		got, _ = PrincipalFromRequest(r)
+		w.WriteHeader(200)
+	})
+	return rec.Code, got
+}
+
+func TestAuthProviders(t *testing.T) {
+	Convey("Given a REST API with a password and static tokens", t, func() {
+		cfg := GetDefaultConfig()
+		cfg.Auth.Tokens = []TokenConfig{
+			{Name: "ci", Token: "t0k3n", Groups: []string{"automation"}},
+		}
+		s, err := New(cfg)
+		So(err, ShouldBeNil)
+		s.SetAPIAuth(true)
+		s.SetAPIAuthPwd("secret")
+		req := httptest.NewRequest("GET", "/v1/plugins", nil)
+
+		Convey("a request without credentials is refused", func() {
+			code, _ := authenticated(s, req)
+			So(code, ShouldEqual, 401)
+		})
+		Convey("the password authenticates the same principal whatever the user", func() {
+			req.SetBasicAuth("ops", "secret")
+			code, p := authenticated(s, req)
+			So(code, ShouldEqual, 200)
+			So(p, ShouldResemble, &Principal{Name: "snap", Provider: "password"})
+			So(principal(withPrincipal(req, p)), ShouldEqual, "snap")
+		})
+		Convey("a wrong password is refused", func() {
+			req.SetBasicAuth("snap", "wrong")
+			code, _ := authenticated(s, req)
+			So(code, ShouldEqual, 401)
+		})
+		Convey("a static token authenticates its principal", func() {
+			req.Header.Set("Authorization", "Bearer t0k3n")
+			code, p := authenticated(s, req)
+			So(code, ShouldEqual, 200)
+			So(p, ShouldResemble, &Principal{Name: "ci", Groups: []string{"automation"}, Provider: "token"})
+		})
+		Convey("an unknown token is refused", func() {
+			req.Header.Set("Authorization", "bearer other")
+			code, _ := authenticated(s, req)
+			So(code, ShouldEqual, 401)
+		})
+		Convey("the probes need no credentials", func() {
+			req := httptest.NewRequest("GET", LivenessPath, nil)
+			code, p := authenticated(s, req)
+			So(code, ShouldEqual, 200)
+			So(p, ShouldBeNil)
+		})
+	})
+	Convey("Given a REST API without any provider", t, func() {
+		s, err := New(GetDefaultConfig())
+		So(err, ShouldBeNil)
+		req := httptest.NewRequest("GET", "/v1/plugins", nil)
+		code, p := authenticated(s, req)
+		So(code, ShouldEqual, 200)
+		So(p, ShouldBeNil)
+		So(principal(req), ShouldEqual, "anonymous")
+		req.SetBasicAuth("ops", "")
+		So(principal(req), ShouldEqual, "anonymous")
+		Convey("adding a provider enables authentication", func() {
+			s.AddAuthProvider(newTokenAuth([]TokenConfig{{Name: "ci", Token: "t0k3n"}}))
+			code, _ := authenticated(s, req)
+			So(code, ShouldEqual, 401)
+		})
+	})
+}
+
+func TestMemberAuth(t *testing.T) {
+	Convey("Given a REST API verifying the requests signed by the members of the tribe", t, func() {
+		key := reqsign.DeriveKey([]byte("0123456789abcdef"))
+		s, err := New(GetDefaultConfig())
+		So(err, ShouldBeNil)
+		s.AddAuthProvider(NewMemberAuthProvider(key))
+		req := httptest.NewRequest("GET", "/v1/tasks", nil)
+
+		Convey("a request signed by a member is authenticated as the member", func() {
+			So(reqsign.NewSigner("edge-1", key).Sign(req), ShouldBeNil)
+			code, p := authenticated(s, req)
+			So(code, ShouldEqual, 200)
+			So(p, ShouldResemble, &Principal{Name: "edge-1", Groups: []string{"tribe"}, Provider: "tribe"})
+		})
+		Convey("a request signed with another key is refused", func() {
+			So(reqsign.NewSigner("edge-1", reqsign.DeriveKey([]byte("fedcba9876543210"))).Sign(req), ShouldBeNil)
+			code, _ := authenticated(s, req)
+			So(code, ShouldEqual, 401)
+		})
+		Convey("a request which is not signed is refused", func() {
+			code, _ := authenticated(s, req)
+			So(code, ShouldEqual, 401)
+		})
+	})
+}
+
+func TestLDAPAuth(t *testing.T) {
+	Convey("Given an LDAP provider", t, func() {
+		l, err := newLDAPAuth(&LDAPConfig{
+			URL:    "ldaps://ldap.example.com",
+			UserDN: "uid=%s,ou=people,dc=example,dc=com",
+		}, &tlspolicy.Config{MinVersion: "1.2"})
+		So(err, ShouldBeNil)
+		So(l.addr, ShouldEqual, "ldap.example.com:636")
+		So(l.tlsConfig.ServerName, ShouldEqual, "ldap.example.com")
+		So(l.tlsConfig.MinVersion, ShouldEqual, tls.VersionTLS12)
+		var binds []string
+		l.bind = func(dn, password string) error {
+			binds = append(binds, dn)
+			if password != "pa55" {
+				return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+			}
+			return nil
+		}
+		req, _ := http.NewRequest("GET", "/v1/plugins", nil)
+
+		Convey("the user binds with its DN", func() {
+			req.SetBasicAuth("jdoe", "pa55")
+			p, err := l.Authenticate(req)
+			So(err, ShouldBeNil)
+			So(p.Name, ShouldEqual, "jdoe")
+			So(binds, ShouldResemble, []string{"uid=jdoe,ou=people,dc=example,dc=com"})
+		})
+		Convey("the user is escaped in the DN", func() {
+			req.SetBasicAuth("doe, john+x", "pa55")
+			_, err := l.Authenticate(req)
+			So(err, ShouldBeNil)
+			So(binds, ShouldResemble, []string{`uid=doe\, john\+x,ou=people,dc=example,dc=com`})
+		})
+		Convey("a wrong password is refused", func() {
+			req.SetBasicAuth("jdoe", "wrong")
+			_, err := l.Authenticate(req)
+			So(err, ShouldEqual, ErrInvalidCredentials)
+		})
+		Convey("an empty password is refused without binding", func() {
+			req.SetBasicAuth("jdoe", "")
+			_, err := l.Authenticate(req)
+			So(err, ShouldEqual, ErrInvalidCredentials)
+			So(binds, ShouldBeEmpty)
+		})
+		Convey("a request without basic authentication is left to the next provider", func() {
+			_, err := l.Authenticate(req)
+			So(err, ShouldEqual, ErrNoCredentials)
+		})
+		Convey("successful binds are cached with a cache TTL", func() {
+			l.config.CacheTTL = jsonutil.Duration{time.Minute}
+			req.SetBasicAuth("jdoe", "pa55")
+			_, err := l.Authenticate(req)
+			So(err, ShouldBeNil)
+			_, err = l.Authenticate(req)
+			So(err, ShouldBeNil)
+			So(binds, ShouldHaveLength, 1)
+		})
+	})
+}
+
+func TestOIDCAuth(t *testing.T) {
+	Convey("Given an OIDC provider", t, func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		So(err, ShouldBeNil)
+		mux := http.NewServeMux()
+		srv := httptest.NewServer(mux)
+		defer srv.Close()
+		mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"issuer":                                srv.URL,
+				"jwks_uri":                              srv.URL + "/keys",
+				"authorization_endpoint":                srv.URL + "/auth",
+				"token_endpoint":                        srv.URL + "/token",
+				"id_token_signing_alg_values_supported": []string{"RS256"},
+			})
+		})
+		mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+				{Key: &key.PublicKey, KeyID: "k1", Algorithm: "RS256", Use: "sig"},
+			}})
+		})
+		signer, err := jose.NewSigner(jose.SigningKey{
+			Algorithm: jose.RS256,
+			Key:       jose.JSONWebKey{Key: key, KeyID: "k1"},
+		}, nil)
+		So(err, ShouldBeNil)
+		token := func(claims map[string]interface{}) string {
+			b, _ := json.Marshal(claims)
+			jws, err := signer.Sign(b)
+			So(err, ShouldBeNil)
+			raw, err := jws.CompactSerialize()
+			So(err, ShouldBeNil)
+			return raw
+		}
+		claims := map[string]interface{}{
+			"iss":    srv.URL,
+			"aud":    "snapd",
+			"sub":    "0a1b2c",
+			"email":  "jdoe@example.com",
+			"groups": []string{"ops", "admins"},
+			"exp":    time.Now().Add(time.Hour).Unix(),
+		}
+		o, err := newOIDCAuth(&OIDCConfig{Issuer: srv.URL, Audience: "snapd", UsernameClaim: "email"}, nil)
+		So(err, ShouldBeNil)
+		req, _ := http.NewRequest("GET", "/v1/plugins", nil)
+
+		Convey("a token of the provider authenticates the principal of its claims", func() {
+			req.Header.Set("Authorization", "Bearer "+token(claims))
+			p, err := o.Authenticate(req)
+			So(err, ShouldBeNil)
+			So(p.Name, ShouldEqual, "jdoe@example.com")
+			So(p.Groups, ShouldResemble, []string{"ops", "admins"})
+		})
+		Convey("a token for another audience is refused", func() {
+			claims["aud"] = "other"
+			req.Header.Set("Authorization", "Bearer "+token(claims))
+			_, err := o.Authenticate(req)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("an expired token is refused", func() {
+			claims["exp"] = time.Now().Add(-time.Hour).Unix()
+			req.Header.Set("Authorization", "Bearer "+token(claims))
+			_, err := o.Authenticate(req)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("a token without the username claim is refused", func() {
+			delete(claims, "email")
+			req.Header.Set("Authorization", "Bearer "+token(claims))
+			_, err := o.Authenticate(req)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("a request without a bearer token is left to the next provider", func() {
+			req.SetBasicAuth("jdoe", "pa55")
+			_, err := o.Authenticate(req)
+			So(err, ShouldEqual, ErrNoCredentials)
+		})
+	})
+}
+
+func TestAuthConfig(t *testing.T) {
+	Convey("Given the auth configuration of the REST API", t, func() {
+		c := &AuthConfig{}
+		So(c.Validate(), ShouldBeEmpty)
+		Convey("tokens need a name and a token of their own", func() {
+			c.Tokens = []TokenConfig{{Name: "a", Token: "t"}, {Token: "t"}}
+			So(c.Validate(), ShouldHaveLength, 2)
+		})
+		Convey("LDAP needs an ldap URL and a user DN template", func() {
+			c.LDAP = &LDAPConfig{URL: "http://ldap", UserDN: "uid=jdoe"}
+			So(c.Validate(), ShouldHaveLength, 2)
+			c.LDAP = &LDAPConfig{URL: "ldap://ldap:389", UserDN: "uid=%s,dc=example", StartTLS: true}
+			So(c.Validate(), ShouldBeEmpty)
+		})
+		Convey("OIDC needs an issuer URL and an audience", func() {
+			c.OIDC = &OIDCConfig{Issuer: "accounts"}
+			So(c.Validate(), ShouldHaveLength, 2)
+			c.OIDC = &OIDCConfig{Issuer: "https://accounts.example.com", Audience: "snapd"}
+			So(c.Validate(), ShouldBeEmpty)
+		})
+	})
+}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/serror"
 	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
 	"github.com/julienschmidt/httprouter"
 )
@@ -139,7 +140,16 @@ func (s *Server) setPluginConfigItem(w http.ResponseWriter, r *http.Request, p h
 	if styp == "" {
 		res = s.mc.MergePluginConfigDataNodeAll(src)
 	} else {
-		res = s.mc.MergePluginConfigDataNode(typ, name, iver, src)
+		// the config is applied to the running tasks, replace swaps the
+		// config of the plugins for the one given, once validated against
+		// their policies, instead of merging it
+		var errs []serror.SnapError
+		replace := r.URL.Query().Get("replace") == "true"
+		res, errs = s.mm.SetPluginConfig(typ, name, iver, src, replace)
+		if len(errs) > 0 {
+			respond(400, rbody.FromSnapErrors(errs), w)
+			return
+		}
 	}
 
 	item := &rbody.SetPluginConfigItem{ConfigDataNode: res}
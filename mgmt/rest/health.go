@@ -0,0 +1,86 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+// The paths of the probes, answered without authentication so that an
+// orchestrator such as Kubernetes can reach them
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+)
+
+// HealthCheck returns an error when the part of snapd it checks is not
+// healthy
+type HealthCheck func() error
+
+type namedHealthCheck struct {
+	name  string
+	check HealthCheck
+}
+
+// AddLivenessCheck adds a check to the liveness probe, which fails when
+// snapd should be restarted. The REST API answering the probe is checked
+// implicitly.
+func (s *Server) AddLivenessCheck(name string, c HealthCheck) {
+	s.liveness = append(s.liveness, namedHealthCheck{name: name, check: c})
+}
+
+// AddReadinessCheck adds a check to the readiness probe, which fails while
+// snapd is not ready to collect. The readiness probe runs the checks of the
+// liveness probe as well.
+func (s *Server) AddReadinessCheck(name string, c HealthCheck) {
+	s.readiness = append(s.readiness, namedHealthCheck{name: name, check: c})
+}
+
+func (s *Server) getLiveness(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	respondHealth(s.liveness, w)
+}
+
+func (s *Server) getReadiness(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	checks := append([]namedHealthCheck{}, s.liveness...)
+	respondHealth(append(checks, s.readiness...), w)
+}
+
+// respondHealth runs the checks, answering 503 when any of them fails
+func respondHealth(checks []namedHealthCheck, w http.ResponseWriter) {
+	h := &rbody.HealthReturned{Healthy: true, Checks: []rbody.HealthCheck{}}
+	for _, c := range checks {
+		hc := rbody.HealthCheck{Name: c.name, OK: true}
+		if err := c.check(); err != nil {
+			hc.OK = false
+			hc.Message = err.Error()
+			h.Healthy = false
+		}
+		h.Checks = append(h.Checks, hc)
+	}
+	if !h.Healthy {
+		respond(503, h, w)
+		return
+	}
+	respond(200, h, w)
+}
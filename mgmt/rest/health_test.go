@@ -0,0 +1,76 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHealthProbes(t *testing.T) {
+	Convey("Given a REST API with liveness and readiness checks", t, func() {
+		s, err := New(GetDefaultConfig())
+		So(err, ShouldBeNil)
+		var pluginsErr error
+		s.AddLivenessCheck("scheduler", func() error { return nil })
+		s.AddReadinessCheck("plugins", func() error { return pluginsErr })
+		s.SetAPIAuth(true)
+		s.SetAPIAuthPwd("secret")
+		s.addRoutes()
+		probe := func(path string) (int, *rbody.HealthReturned) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", path, nil)
+			s.n.ServeHTTP(rec, req)
+			resp := &rbody.APIResponse{}
+			So(json.Unmarshal(rec.Body.Bytes(), resp), ShouldBeNil)
+			return rec.Code, resp.Body.(*rbody.HealthReturned)
+		}
+
+		Convey("the probes answer without authentication while the checks pass", func() {
+			code, h := probe(LivenessPath)
+			So(code, ShouldEqual, 200)
+			So(h.Healthy, ShouldBeTrue)
+			So(h.Checks, ShouldResemble, []rbody.HealthCheck{{Name: "scheduler", OK: true}})
+			code, h = probe(ReadinessPath)
+			So(code, ShouldEqual, 200)
+			So(h.Checks, ShouldHaveLength, 2)
+		})
+		Convey("a failed readiness check fails the readiness probe only", func() {
+			pluginsErr = errors.New("1 plugin failed to load")
+			code, _ := probe(LivenessPath)
+			So(code, ShouldEqual, 200)
+			code, h := probe(ReadinessPath)
+			So(code, ShouldEqual, 503)
+			So(h.Healthy, ShouldBeFalse)
+			So(h.Checks[1], ShouldResemble, rbody.HealthCheck{Name: "plugins", Message: "1 plugin failed to load"})
+		})
+		Convey("the other routes still need authentication", func() {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/v1/system/maintenance", nil)
+			s.n.ServeHTTP(rec, req)
+			So(rec.Code, ShouldEqual, 401)
+		})
+	})
+}
@@ -0,0 +1,99 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/pkg/logbuffer"
+)
+
+const (
+	// DefaultLogLines is the number of recent log entries returned when no
+	// lines are asked for
+	DefaultLogLines = 100
+)
+
+var ErrBadLogLines = errors.New("lines must be a positive number")
+
+// getLogs returns the recent log entries of a component, and with follow
+// streams the entries logged next until the client disconnects
+func (s *Server) getLogs(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	q := r.URL.Query()
+	component := q.Get("component")
+	lines := DefaultLogLines
+	if v := q.Get("lines"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			respond(400, rbody.FromError(ErrBadLogLines), w)
+			return
+		}
+		lines = n
+	}
+	if q.Get("follow") != "true" {
+		respond(200, rbody.LogEntriesFromEntries(s.ml.Recent(component, lines)), w)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respond(500, rbody.FromError(ErrStreamingUnsupported), w)
+		return
+	}
+	recent, next, stop := s.ml.Tail(component, lines)
+	defer stop()
+
+	// Make this Server Sent Events compatible
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	for _, e := range recent {
+		le := rbody.LogEntryFromEntry(e)
+		fmt.Fprintf(w, "data: %s\n\n", le.ToJSON())
+	}
+	flusher.Flush()
+
+	n := w.(http.CloseNotifier).CloseNotify()
+	for {
+		select {
+		case e := <-next:
+			le := rbody.LogEntryFromEntry(e)
+			fmt.Fprintf(w, "data: %s\n\n", le.ToJSON())
+			flusher.Flush()
+		case <-n:
+			restLogger.WithFields(log.Fields{
+				"_block":    "get-logs",
+				"client":    r.RemoteAddr,
+				"component": component,
+			}).Debug("client disconnecting")
+			return
+		}
+	}
+}
+
+var _ managesLogs = logbuffer.Default
@@ -32,12 +32,32 @@ import (
 )
 
 func (s *Server) getMetrics(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if q := r.URL.Query().Get("q"); q != "" {
+		s.queryMetrics(q, w, r)
+		return
+	}
 	mets, err := s.mm.MetricCatalog()
 	if err != nil {
 		respond(500, rbody.FromError(err), w)
 		return
 	}
-	respondWithMetrics(r.Host, mets, w)
+	respondWithMetrics(r.Host, s.visibleMetrics(r, mets), w)
+}
+
+// queryMetrics responds with the metrics matching a catalog query
+// (e.g. ns=/intel/cpu/* AND version>=3)
+func (s *Server) queryMetrics(q string, w http.ResponseWriter, r *http.Request) {
+	mq, err := core.ParseMetricQuery(q)
+	if err != nil {
+		respond(400, rbody.FromError(err), w)
+		return
+	}
+	mets, err := s.mm.QueryMetrics(mq)
+	if err != nil {
+		respond(500, rbody.FromError(err), w)
+		return
+	}
+	respondWithMetrics(r.Host, s.visibleMetrics(r, mets), w)
 }
 
 func (s *Server) getMetricsFromTree(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
@@ -77,7 +97,7 @@ func (s *Server) getMetricsFromTree(w http.ResponseWriter, r *http.Request, para
 			respond(404, rbody.FromError(err), w)
 			return
 		}
-		respondWithMetrics(r.Host, mets, w)
+		respondWithMetrics(r.Host, s.visibleMetrics(r, mets), w)
 		return
 	}
 
@@ -88,7 +108,7 @@ func (s *Server) getMetricsFromTree(w http.ResponseWriter, r *http.Request, para
 			respond(404, rbody.FromError(err), w)
 			return
 		}
-		respondWithMetrics(r.Host, mts, w)
+		respondWithMetrics(r.Host, s.visibleMetrics(r, mts), w)
 		return
 	}
 
@@ -99,6 +119,9 @@ func (s *Server) getMetricsFromTree(w http.ResponseWriter, r *http.Request, para
 		return
 	}
 	mt, err := s.mm.GetMetric(ns, ver)
+	if err == nil && len(s.visibleMetrics(r, []core.CatalogedMetric{mt})) == 0 {
+		err = fmt.Errorf("Metric not found: %s (version: %d)", core.JoinNamespace(ns), ver)
+	}
 	if err != nil {
 		respond(404, rbody.FromError(err), w)
 		return
@@ -111,16 +134,23 @@ func (s *Server) getMetricsFromTree(w http.ResponseWriter, r *http.Request, para
 		LastAdvertisedTimestamp: mt.LastAdvertisedTime().Unix(),
 		Href: catalogedMetricURI(r.Host, mt),
 	}
+	if d, ok := mt.(core.DescribedMetric); ok {
+		mb.Unit = d.Unit()
+		mb.Description = d.Description()
+	}
 	rt := mt.Policy().RulesAsTable()
 	policies := make([]rbody.PolicyTable, 0, len(rt))
 	for _, r := range rt {
 		policies = append(policies, rbody.PolicyTable{
-			Name:     r.Name,
-			Type:     r.Type,
-			Default:  r.Default,
-			Required: r.Required,
-			Minimum:  r.Minimum,
-			Maximum:  r.Maximum,
+			Name:        r.Name,
+			Type:        r.Type,
+			Default:     r.Default,
+			Required:    r.Required,
+			Minimum:     r.Minimum,
+			Maximum:     r.Maximum,
+			Choices:     r.Choices,
+			Pattern:     r.Pattern,
+			ElementType: r.ElementType,
 		})
 	}
 	mb.Policy = policies
@@ -136,21 +166,29 @@ func respondWithMetrics(host string, mets []core.CatalogedMetric, w http.Respons
 		policies := make([]rbody.PolicyTable, 0, len(rt))
 		for _, r := range rt {
 			policies = append(policies, rbody.PolicyTable{
-				Name:     r.Name,
-				Type:     r.Type,
-				Default:  r.Default,
-				Required: r.Required,
-				Minimum:  r.Minimum,
-				Maximum:  r.Maximum,
+				Name:        r.Name,
+				Type:        r.Type,
+				Default:     r.Default,
+				Required:    r.Required,
+				Minimum:     r.Minimum,
+				Maximum:     r.Maximum,
+				Choices:     r.Choices,
+				Pattern:     r.Pattern,
+				ElementType: r.ElementType,
 			})
 		}
-		b = append(b, rbody.Metric{
+		m := rbody.Metric{
 			Namespace:               core.JoinNamespace(met.Namespace()),
 			Version:                 met.Version(),
 			LastAdvertisedTimestamp: met.LastAdvertisedTime().Unix(),
 			Policy:                  policies,
 			Href:                    catalogedMetricURI(host, met),
-		})
+		}
+		if d, ok := met.(core.DescribedMetric); ok {
+			m.Unit = d.Unit()
+			m.Description = d.Description()
+		}
+		b = append(b, m)
 	}
 	sort.Sort(b)
 	respond(200, b, w)
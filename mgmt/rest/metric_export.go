@@ -0,0 +1,145 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+// Formats the metric catalog can be exported in
+const (
+	ExportFormatJSON        = "json"
+	ExportFormatPrometheus  = "prometheus"
+	ExportFormatOpenMetrics = "openmetrics"
+
+	prometheusContentType  = "text/plain; version=0.0.4; charset=utf-8"
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+var ErrUnknownExportFormat = errors.New("unknown export format (json, prometheus or openmetrics)")
+
+// exportMetrics returns the descriptors of the metrics in the catalog. Only
+// the latest version of each metric is exported.
+func (s *Server) exportMetrics(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	mets, err := s.mm.MetricCatalog()
+	if err != nil {
+		respond(500, rbody.FromError(err), w)
+		return
+	}
+	exported := exportCatalog(s.visibleMetrics(r, mets))
+	switch r.URL.Query().Get("format") {
+	case "", ExportFormatJSON:
+		respond(200, exported, w)
+	case ExportFormatPrometheus:
+		w.Header().Set("Content-Type", prometheusContentType)
+		w.WriteHeader(200)
+		writeMetricDescriptors(w, exported, false)
+	case ExportFormatOpenMetrics:
+		w.Header().Set("Content-Type", openMetricsContentType)
+		w.WriteHeader(200)
+		writeMetricDescriptors(w, exported, true)
+	default:
+		respond(400, rbody.FromError(ErrUnknownExportFormat), w)
+	}
+}
+
+func exportCatalog(mets []core.CatalogedMetric) rbody.MetricCatalogExported {
+	latest := map[string]rbody.ExportedMetric{}
+	for _, met := range mets {
+		m := rbody.ExportedMetric{
+			Name:      metricName(met.Namespace()),
+			Namespace: core.JoinNamespace(met.Namespace()),
+			Version:   met.Version(),
+		}
+		if d, ok := met.(core.DescribedMetric); ok {
+			m.Unit = d.Unit()
+			m.Description = d.Description()
+		}
+		if prev, ok := latest[m.Name]; ok && prev.Version > m.Version {
+			continue
+		}
+		latest[m.Name] = m
+	}
+	exported := make(rbody.MetricCatalogExported, 0, len(latest))
+	for _, m := range latest {
+		exported = append(exported, m)
+	}
+	sort.Sort(exportedByName(exported))
+	return exported
+}
+
+type exportedByName rbody.MetricCatalogExported
+
+func (e exportedByName) Len() int           { return len(e) }
+func (e exportedByName) Less(i, j int) bool { return e[i].Name < e[j].Name }
+func (e exportedByName) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+
+// writeMetricDescriptors writes the metadata lines of the Prometheus text
+// format, or of the OpenMetrics format which adds units and a terminating EOF.
+// snap does not know the type of its metrics so they are untyped/unknown.
+func writeMetricDescriptors(w io.Writer, mets rbody.MetricCatalogExported, openMetrics bool) {
+	typ := "untyped"
+	if openMetrics {
+		typ = "unknown"
+	}
+	for _, m := range mets {
+		help := m.Description
+		if help == "" {
+			help = fmt.Sprintf("snap metric %s (version %d)", m.Namespace, m.Version)
+		}
+		fmt.Fprintf(w, "# HELP %s %s\n", m.Name, escapeHelp(help))
+		fmt.Fprintf(w, "# TYPE %s %s\n", m.Name, typ)
+		if openMetrics && m.Unit != "" {
+			fmt.Fprintf(w, "# UNIT %s %s\n", m.Name, metricName([]string{m.Unit}))
+		}
+	}
+	if openMetrics {
+		fmt.Fprint(w, "# EOF\n")
+	}
+}
+
+// metricName turns a namespace into a valid Prometheus metric name
+func metricName(ns []string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+			return r
+		}
+		return '_'
+	}, strings.Join(ns, "_"))
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
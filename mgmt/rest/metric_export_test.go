@@ -0,0 +1,84 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type mockCatalogedMetric struct {
+	ns          []string
+	ver         int
+	unit        string
+	description string
+}
+
+func (m mockCatalogedMetric) Namespace() []string               { return m.ns }
+func (m mockCatalogedMetric) Version() int                      { return m.ver }
+func (m mockCatalogedMetric) LastAdvertisedTime() time.Time     { return time.Now() }
+func (m mockCatalogedMetric) Policy() *cpolicy.ConfigPolicyNode { return nil }
+func (m mockCatalogedMetric) Unit() string                      { return m.unit }
+func (m mockCatalogedMetric) Description() string               { return m.description }
+
+func TestMetricExport(t *testing.T) {
+	Convey("Exporting the metric catalog", t, func() {
+		mets := []core.CatalogedMetric{
+			mockCatalogedMetric{ns: []string{"intel", "mock", "foo"}, ver: 1},
+			mockCatalogedMetric{ns: []string{"intel", "mock", "foo"}, ver: 2, unit: "bytes", description: "foo\nbar"},
+			mockCatalogedMetric{ns: []string{"intel", "mock", "bar-baz"}, ver: 1},
+		}
+		exported := exportCatalog(mets)
+		Convey("keeps the latest version of each metric", func() {
+			So(exported, ShouldHaveLength, 2)
+			So(exported[0].Name, ShouldEqual, "intel_mock_bar_baz")
+			So(exported[1].Name, ShouldEqual, "intel_mock_foo")
+			So(exported[1].Namespace, ShouldEqual, "/intel/mock/foo")
+			So(exported[1].Version, ShouldEqual, 2)
+			So(exported[1].Unit, ShouldEqual, "bytes")
+		})
+		Convey("writes the Prometheus format", func() {
+			var buf bytes.Buffer
+			writeMetricDescriptors(&buf, exported, false)
+			So(buf.String(), ShouldEqual, "# HELP intel_mock_bar_baz snap metric /intel/mock/bar-baz (version 1)\n"+
+				"# TYPE intel_mock_bar_baz untyped\n"+
+				"# HELP intel_mock_foo foo\\nbar\n"+
+				"# TYPE intel_mock_foo untyped\n")
+		})
+		Convey("writes the OpenMetrics format", func() {
+			var buf bytes.Buffer
+			writeMetricDescriptors(&buf, rbody.MetricCatalogExported{exported[1]}, true)
+			So(buf.String(), ShouldEqual, "# HELP intel_mock_foo foo\\nbar\n"+
+				"# TYPE intel_mock_foo unknown\n"+
+				"# UNIT intel_mock_foo bytes\n"+
+				"# EOF\n")
+		})
+	})
+	Convey("metricName", t, func() {
+		So(metricName([]string{"intel", "cpu", "0", "user%"}), ShouldEqual, "intel_cpu_0_user_")
+		So(metricName([]string{"1a"}), ShouldEqual, "_1a")
+	})
+}
@@ -40,8 +40,11 @@ import (
 	"github.com/julienschmidt/httprouter"
 
 	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
 	"github.com/intelsdi-x/snap/core/serror"
 	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
+	"github.com/intelsdi-x/snap/pkg/datadir"
 )
 
 const PluginAlreadyLoaded = "plugin is already loaded"
@@ -49,6 +52,9 @@ const PluginAlreadyLoaded = "plugin is already loaded"
 var (
 	ErrMissingPluginName = errors.New("missing plugin name")
 	ErrPluginNotFound    = errors.New("plugin not found")
+	ErrPluginNotSigned   = errors.New("plugin was not loaded with a signature")
+	ErrNotProcessor      = errors.New("only processors process metrics")
+	ErrMissingTaskID     = errors.New("missing task ID")
 )
 
 type plugin struct {
@@ -128,7 +134,7 @@ func (s *Server) loadPlugin(w http.ResponseWriter, r *http.Request, _ httprouter
 					respond(500, rbody.FromError(e), w)
 					return
 				}
-				if pluginPath, err = writeFile(p.FileName(), b); err != nil {
+				if pluginPath, err = s.writeFile(p.FileName(), b); err != nil {
 					respond(500, rbody.FromError(err), w)
 					return
 				}
@@ -186,9 +192,15 @@ func (s *Server) loadPlugin(w http.ResponseWriter, r *http.Request, _ httprouter
 	}
 }
 
-func writeFile(filename string, b []byte) (string, error) {
-	// Create temporary directory
-	dir, err := ioutil.TempDir("", "")
+func (s *Server) writeFile(filename string, b []byte) (string, error) {
+	// Create a directory for the plugin, in the data directory if one is set
+	var dir string
+	var err error
+	if s.dataDir != nil {
+		dir, err = s.dataDir.TempDir(datadir.Plugins)
+	} else {
+		dir, err = ioutil.TempDir("", "")
+	}
 	if err != nil {
 		return "", err
 	}
@@ -241,11 +253,34 @@ func (s *Server) unloadPlugin(w http.ResponseWriter, r *http.Request, p httprout
 		respond(400, rbody.FromSnapError(se), w)
 		return
 	}
-	up, se := s.mm.Unload(&plugin{
+	pl := &plugin{
 		name:       plName,
 		version:    int(plVersion),
 		pluginType: plType,
-	})
+	}
+	// refuse to break running tasks unless asked to stop them first
+	if tasks := s.mm.DependentTasks(pl); len(tasks) > 0 {
+		if r.URL.Query().Get("force") != "true" {
+			f["tasks"] = strings.Join(tasks, ",")
+			se := serror.New(core.ErrPluginInUse, f)
+			respond(409, rbody.FromSnapError(se), w)
+			return
+		}
+		for _, id := range tasks {
+			if errs := s.mt.StopTask(id); len(errs) > 0 {
+				f["task-id"] = id
+				se := errs[0]
+				se.SetFields(f)
+				respond(500, rbody.FromSnapError(se), w)
+				return
+			}
+			restLogger.WithFields(log.Fields{
+				"task-id": id,
+				"plugin":  fmt.Sprintf("%s:%s:%d", plType, plName, plVersion),
+			}).Warning("stopped task to unload plugin")
+		}
+	}
+	up, se := s.mm.Unload(pl)
 	if se != nil {
 		se.SetFields(f)
 		respond(500, rbody.FromSnapError(se), w)
@@ -335,8 +370,14 @@ func getPlugins(mm managesMetrics, detail bool, h string, plName string, plType
 	return &plugins
 }
 
+// featured is implemented by cataloged plugins which advertise optional
+// features
+type featured interface {
+	Features() []string
+}
+
 func catalogedPluginToLoaded(host string, c core.CatalogedPlugin) *rbody.LoadedPlugin {
-	return &rbody.LoadedPlugin{
+	lp := &rbody.LoadedPlugin{
 		Name:            c.Name(),
 		Version:         c.Version(),
 		Type:            c.TypeName(),
@@ -345,6 +386,10 @@ func catalogedPluginToLoaded(host string, c core.CatalogedPlugin) *rbody.LoadedP
 		LoadedTimestamp: c.LoadedTimestamp().Unix(),
 		Href:            pluginURI(host, c),
 	}
+	if f, ok := c.(featured); ok {
+		lp.Features = f.Features()
+	}
+	return lp
 }
 
 func (s *Server) getPlugin(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
@@ -401,12 +446,15 @@ func (s *Server) getPlugin(w http.ResponseWriter, r *http.Request, p httprouter.
 		configPolicy = make([]rbody.PolicyTable, 0, len(rules))
 		for _, r := range rules {
 			configPolicy = append(configPolicy, rbody.PolicyTable{
-				Name:     r.Name,
-				Type:     r.Type,
-				Default:  r.Default,
-				Required: r.Required,
-				Minimum:  r.Minimum,
-				Maximum:  r.Maximum,
+				Name:        r.Name,
+				Type:        r.Type,
+				Default:     r.Default,
+				Required:    r.Required,
+				Minimum:     r.Minimum,
+				Maximum:     r.Maximum,
+				Choices:     r.Choices,
+				Pattern:     r.Pattern,
+				ElementType: r.ElementType,
 			})
 		}
 
@@ -414,6 +462,21 @@ func (s *Server) getPlugin(w http.ResponseWriter, r *http.Request, p httprouter.
 		configPolicy = nil
 	}
 
+	// the signature lets the members of a tribe agreement which download
+	// the plugin load it as signed as well
+	rs := r.FormValue("signature")
+	sig, _ := strconv.ParseBool(rs)
+	if sig {
+		if len(plugin.Signature()) == 0 {
+			se := serror.New(ErrPluginNotSigned, f)
+			respond(404, rbody.FromSnapError(se), w)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(plugin.Signature())
+		return
+	}
+
 	if d {
 		b, err := ioutil.ReadFile(plugin.PluginPath())
 		if err != nil {
@@ -452,3 +515,57 @@ func (s *Server) getPlugin(w http.ResponseWriter, r *http.Request, p httprouter.
 func pluginURI(host string, c core.Plugin) string {
 	return fmt.Sprintf("%s://%s/v1/plugins/%s/%s/%d", protocolPrefix, host, c.TypeName(), c.Name(), c.Version())
 }
+
+// processMetrics processes a batch of metrics a task of another snapd
+// offloads to a processor of this snapd
+func (s *Server) processMetrics(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	plName := p.ByName("name")
+	plType := p.ByName("type")
+	plVersion, iErr := strconv.ParseInt(p.ByName("version"), 10, 0)
+	f := map[string]interface{}{
+		"plugin-name":    plName,
+		"plugin-version": plVersion,
+		"plugin-type":    plType,
+	}
+
+	if iErr != nil {
+		se := serror.New(errors.New("invalid version"))
+		se.SetFields(f)
+		respond(400, rbody.FromSnapError(se), w)
+		return
+	}
+	if plType != core.ProcessorPluginType.String() {
+		respond(400, rbody.FromSnapError(serror.New(ErrNotProcessor, f)), w)
+		return
+	}
+
+	req := &request.ProcessRequest{}
+	errCode, err := marshalBody(req, r.Body)
+	if errCode != 0 && err != nil {
+		respond(errCode, rbody.FromError(err), w)
+		return
+	}
+	if req.TaskID == "" {
+		respond(400, rbody.FromSnapError(serror.New(ErrMissingTaskID, f)), w)
+		return
+	}
+	config := map[string]ctypes.ConfigValue{}
+	if req.Config != nil {
+		for k, v := range req.Config.Table() {
+			config[k] = v
+		}
+	}
+
+	ct, content, errs := s.mm.ProcessRemoteMetrics(req.ContentType, req.Content, plName, int(plVersion), config, req.TaskID)
+	if len(errs) > 0 {
+		f["task-id"] = req.TaskID
+		se := serror.New(errs[0], f)
+		if strings.Contains(errs[0].Error(), ErrPluginNotFound.Error()) {
+			respond(404, rbody.FromSnapError(se), w)
+			return
+		}
+		respond(500, rbody.FromSnapError(se), w)
+		return
+	}
+	respond(200, &rbody.PluginProcessed{ContentType: ct, Content: content}, w)
+}
@@ -20,12 +20,19 @@ limitations under the License.
 package rest
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
 	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
 	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -39,6 +46,7 @@ func (m MockLoadedPlugin) TypeName() string   { return m.MyType }
 func (m MockLoadedPlugin) Version() int       { return 0 }
 func (m MockLoadedPlugin) Plugin() string     { return "" }
 func (m MockLoadedPlugin) IsSigned() bool     { return false }
+func (m MockLoadedPlugin) Signature() []byte  { return nil }
 func (m MockLoadedPlugin) Status() string     { return "" }
 func (m MockLoadedPlugin) PluginPath() string { return "" }
 func (m MockLoadedPlugin) LoadedTimestamp() *time.Time {
@@ -62,6 +70,18 @@ func (m MockManagesMetrics) MetricCatalog() ([]core.CatalogedMetric, error) {
 func (m MockManagesMetrics) FetchMetrics([]string, int) ([]core.CatalogedMetric, error) {
 	return nil, nil
 }
+func (m MockManagesMetrics) QueryMetrics(*core.MetricQuery) ([]core.CatalogedMetric, error) {
+	return nil, nil
+}
+func (m MockManagesMetrics) TasksUsingMetric(string) ([]string, error) {
+	return nil, nil
+}
+func (m MockManagesMetrics) TasksUsingPlugin(string, string, int) []string {
+	return nil
+}
+func (m MockManagesMetrics) DependentTasks(core.Plugin) []string {
+	return nil
+}
 func (m MockManagesMetrics) GetMetricVersions([]string) ([]core.CatalogedMetric, error) {
 	return nil, nil
 }
@@ -74,6 +94,33 @@ func (m MockManagesMetrics) Load(*core.RequestedPlugin) (core.CatalogedPlugin, s
 func (m MockManagesMetrics) Unload(core.Plugin) (core.CatalogedPlugin, serror.SnapError) {
 	return nil, nil
 }
+func (m MockManagesMetrics) AddAlias(string, string) error {
+	return nil
+}
+func (m MockManagesMetrics) RemoveAlias(string) error {
+	return nil
+}
+func (m MockManagesMetrics) Aliases() map[string]string {
+	return nil
+}
+func (m MockManagesMetrics) SetPluginConfig(core.PluginType, string, int, *cdata.ConfigDataNode, bool) (cdata.ConfigDataNode, []serror.SnapError) {
+	return cdata.ConfigDataNode{}, nil
+}
+
+// ProcessRemoteMetrics upper-cases the content for the processor foo
+func (m MockManagesMetrics) ProcessRemoteMetrics(contentType string, content []byte, name string, version int, config map[string]ctypes.ConfigValue, taskID string) (string, []byte, []error) {
+	if name != "foo" {
+		return "", nil, []error{ErrPluginNotFound}
+	}
+	if config["suffix"] != nil {
+		content = append(content, config["suffix"].(ctypes.ConfigValueStr).Value...)
+	}
+	return contentType, []byte(strings.ToUpper(string(content))), nil
+}
+
+func (m MockManagesMetrics) MetricVisible(string, []string) bool {
+	return true
+}
 
 func (m MockManagesMetrics) PluginCatalog() core.PluginCatalog {
 	return []core.CatalogedPlugin{
@@ -168,3 +215,42 @@ func TestGetPlugins(t *testing.T) {
 	})
 
 }
+
+func TestProcessMetrics(t *testing.T) {
+	Convey("Processing the metrics offloaded by a remote task", t, func() {
+		s, err := New(GetDefaultConfig())
+		So(err, ShouldBeNil)
+		s.BindMetricManager(MockManagesMetrics{})
+		s.addRoutes()
+		post := func(uri, body string) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", uri, strings.NewReader(body))
+			s.n.ServeHTTP(rec, req)
+			return rec
+		}
+		Convey("returns the content the processor returned", func() {
+			rec := post("/v1/plugins/processor/foo/1/process", `{"task_id": "t1", "content_type": "test", "content": "YWJj", "config": {"suffix": "d"}}`)
+			So(rec.Code, ShouldEqual, 200)
+			var resp struct {
+				Body rbody.PluginProcessed `json:"body"`
+			}
+			So(json.Unmarshal(rec.Body.Bytes(), &resp), ShouldBeNil)
+			So(resp.Body.ContentType, ShouldEqual, "test")
+			So(string(resp.Body.Content), ShouldEqual, "ABCD")
+		})
+		Convey("fails for a plugin which is not a processor", func() {
+			rec := post("/v1/plugins/publisher/foo/1/process", `{"task_id": "t1"}`)
+			So(rec.Code, ShouldEqual, 400)
+			So(rec.Body.String(), ShouldContainSubstring, ErrNotProcessor.Error())
+		})
+		Convey("fails without the ID of the remote task", func() {
+			rec := post("/v1/plugins/processor/foo/1/process", `{"content_type": "test"}`)
+			So(rec.Code, ShouldEqual, 400)
+			So(rec.Body.String(), ShouldContainSubstring, ErrMissingTaskID.Error())
+		})
+		Convey("fails for a processor which is not loaded", func() {
+			rec := post("/v1/plugins/processor/bar/1/process", `{"task_id": "t1"}`)
+			So(rec.Code, ShouldEqual, 404)
+		})
+	})
+}
@@ -0,0 +1,114 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbody
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+const (
+	AggregatorReturnedType        = "aggregator_returned"
+	AggregatorMetricsIngestedType = "aggregator_metrics_ingested"
+)
+
+type AggregatorReturned struct {
+	Aggregator
+}
+
+func (a *AggregatorReturned) ResponseBodyMessage() string {
+	return "Aggregator returned"
+}
+
+func (a *AggregatorReturned) ResponseBodyType() string {
+	return AggregatorReturnedType
+}
+
+type AggregatorMetricsIngested struct {
+	Metrics int `json:"metrics"`
+}
+
+func (a *AggregatorMetricsIngested) ResponseBodyMessage() string {
+	return fmt.Sprintf("Metrics (%d) ingested", a.Metrics)
+}
+
+func (a *AggregatorMetricsIngested) ResponseBodyType() string {
+	return AggregatorMetricsIngestedType
+}
+
+// Aggregator is the state of the aggregator role of snapd. The interval is a
+// duration, e.g. "10s".
+type Aggregator struct {
+	Enabled            bool               `json:"enabled"`
+	Interval           string             `json:"interval,omitempty"`
+	Dedup              bool               `json:"dedup,omitempty"`
+	Downsample         string             `json:"downsample,omitempty"`
+	Buffered           int                `json:"buffered"`
+	Received           uint64             `json:"received"`
+	Duplicates         uint64             `json:"duplicates"`
+	Dropped            uint64             `json:"dropped"`
+	Published          uint64             `json:"published"`
+	Failures           uint64             `json:"failures"`
+	LastFlushTimestamp int64              `json:"last_flush_timestamp,omitempty"`
+	LastError          string             `json:"last_error,omitempty"`
+	Sources            []AggregatorSource `json:"sources,omitempty"`
+}
+
+// AggregatorSource is a principal forwarding metrics to the aggregator
+type AggregatorSource struct {
+	Principal         string `json:"principal"`
+	Received          uint64 `json:"received"`
+	LastSeenTimestamp int64  `json:"last_seen_timestamp"`
+}
+
+func AggregatorFromAggregator(a core.Aggregator) Aggregator {
+	r := Aggregator{
+		Enabled:    a.Enabled,
+		Dedup:      a.Dedup,
+		Downsample: a.Downsample,
+		Buffered:   a.Buffered,
+		Received:   a.Received,
+		Duplicates: a.Duplicates,
+		Dropped:    a.Dropped,
+		Published:  a.Published,
+		Failures:   a.Failures,
+		LastError:  a.LastError,
+	}
+	if a.Enabled {
+		r.Interval = a.Interval.String()
+	}
+	if !a.LastFlush.IsZero() {
+		r.LastFlushTimestamp = a.LastFlush.Unix()
+	}
+	for _, src := range a.Sources {
+		r.Sources = append(r.Sources, AggregatorSource{
+			Principal:         src.Principal,
+			Received:          src.Received,
+			LastSeenTimestamp: src.LastSeen.Unix(),
+		})
+	}
+	return r
+}
+
+func (a *Aggregator) LastFlush() time.Time {
+	return time.Unix(a.LastFlushTimestamp, 0)
+}
@@ -0,0 +1,66 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbody
+
+import "github.com/intelsdi-x/snap/core"
+
+const (
+	AlertListReturnedType = "alert_list_returned"
+)
+
+type AlertListReturned struct {
+	Alerts []Alert `json:"alerts"`
+}
+
+func (a *AlertListReturned) ResponseBodyMessage() string {
+	return "Firing alerts returned"
+}
+
+func (a *AlertListReturned) ResponseBodyType() string {
+	return AlertListReturnedType
+}
+
+type Alert struct {
+	TaskID    string  `json:"task_id"`
+	TaskName  string  `json:"task_name"`
+	Rule      string  `json:"rule"`
+	Severity  string  `json:"severity"`
+	Namespace string  `json:"namespace"`
+	Value     float64 `json:"value"`
+	Since     int64   `json:"since"`
+	FiredAt   int64   `json:"fired_at"`
+}
+
+func AlertListFromAlerts(alerts []core.Alert) *AlertListReturned {
+	al := &AlertListReturned{Alerts: make([]Alert, len(alerts))}
+	for i, a := range alerts {
+		al.Alerts[i] = Alert{
+			TaskID:    a.TaskID,
+			TaskName:  a.TaskName,
+			Rule:      a.Rule,
+			Severity:  a.Severity,
+			Namespace: a.Namespace,
+			Value:     a.Value,
+			Since:     a.Since.Unix(),
+			FiredAt:   a.FiredAt.Unix(),
+		}
+	}
+	return al
+}
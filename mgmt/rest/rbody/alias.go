@@ -0,0 +1,87 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbody
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	AliasListReturnedType = "alias_list_returned"
+	AliasAddedType        = "alias_added"
+	AliasRemovedType      = "alias_removed"
+)
+
+// Alias is a namespace of the metric catalog standing for another namespace
+type Alias struct {
+	Alias  string `json:"alias"`
+	Target string `json:"target"`
+}
+
+type AliasListReturned struct {
+	Aliases []Alias `json:"aliases"`
+}
+
+func (a *AliasListReturned) ResponseBodyMessage() string {
+	return "Aliases returned"
+}
+
+func (a *AliasListReturned) ResponseBodyType() string {
+	return AliasListReturnedType
+}
+
+type AliasAdded Alias
+
+func (a *AliasAdded) ResponseBodyMessage() string {
+	return fmt.Sprintf("Alias (%s) added for %s", a.Alias, a.Target)
+}
+
+func (a *AliasAdded) ResponseBodyType() string {
+	return AliasAddedType
+}
+
+type AliasRemoved struct {
+	Alias string `json:"alias"`
+}
+
+func (a *AliasRemoved) ResponseBodyMessage() string {
+	return fmt.Sprintf("Alias (%s) removed", a.Alias)
+}
+
+func (a *AliasRemoved) ResponseBodyType() string {
+	return AliasRemovedType
+}
+
+// AliasListFromAliases returns the aliases, given by alias, ordered by alias
+func AliasListFromAliases(aliases map[string]string) *AliasListReturned {
+	al := &AliasListReturned{Aliases: make([]Alias, 0, len(aliases))}
+	for alias, target := range aliases {
+		al.Aliases = append(al.Aliases, Alias{Alias: alias, Target: target})
+	}
+	sort.Sort(aliasesByAlias(al.Aliases))
+	return al
+}
+
+type aliasesByAlias []Alias
+
+func (a aliasesByAlias) Len() int           { return len(a) }
+func (a aliasesByAlias) Less(i, j int) bool { return a[i].Alias < a[j].Alias }
+func (a aliasesByAlias) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
@@ -80,6 +80,10 @@ func UnmarshalBody(t string, b []byte) (Body, error) {
 		return unmarshalAndHandleError(b, &PluginList{})
 	case PluginsLoadedType:
 		return unmarshalAndHandleError(b, &PluginsLoaded{})
+	case PluginReturnedType:
+		return unmarshalAndHandleError(b, &PluginReturned{})
+	case PluginProcessedType:
+		return unmarshalAndHandleError(b, &PluginProcessed{})
 	case PluginUnloadedType:
 		return unmarshalAndHandleError(b, &PluginUnloaded{})
 	case ScheduledTaskListReturnedType:
@@ -94,14 +98,36 @@ func UnmarshalBody(t string, b []byte) (Body, error) {
 		return unmarshalAndHandleError(b, &ScheduledTaskStarted{})
 	case ScheduledTaskStoppedType:
 		return unmarshalAndHandleError(b, &ScheduledTaskStopped{})
+	case ScheduledTaskPausedType:
+		return unmarshalAndHandleError(b, &ScheduledTaskPaused{})
+	case ScheduledTaskResumedType:
+		return unmarshalAndHandleError(b, &ScheduledTaskResumed{})
+	case ScheduledTaskTriggeredType:
+		return unmarshalAndHandleError(b, &ScheduledTaskTriggered{})
+	case TaskGraphReturnedType:
+		return unmarshalAndHandleError(b, &TaskGraphReturned{})
 	case ScheduledTaskRemovedType:
 		return unmarshalAndHandleError(b, &ScheduledTaskRemoved{})
 	case ScheduledTaskEnabledType:
 		return unmarshalAndHandleError(b, &ScheduledTaskEnabled{})
+	case ScheduledTaskUpdatedType:
+		return unmarshalAndHandleError(b, &ScheduledTaskUpdated{})
+	case ScheduledTaskRestoredType:
+		return unmarshalAndHandleError(b, &ScheduledTaskRestored{})
+	case DeletedTaskListReturnedType:
+		return unmarshalAndHandleError(b, &DeletedTaskListReturned{})
+	case DeletedTaskReturnedType:
+		return unmarshalAndHandleError(b, &DeletedTaskReturned{})
 	case MetricReturnedType:
 		return unmarshalAndHandleError(b, &MetricReturned{})
 	case MetricsReturnedType:
 		return unmarshalAndHandleError(b, &MetricsReturned{})
+	case MetricCatalogExportedType:
+		return unmarshalAndHandleError(b, &MetricCatalogExported{})
+	case ScheduledTaskRunsReturnedType:
+		return unmarshalAndHandleError(b, &ScheduledTaskRunsReturned{})
+	case ScheduledTaskConfigReturnedType:
+		return unmarshalAndHandleError(b, &ScheduledTaskConfigReturned{})
 	case ScheduledTaskWatchingEndedType:
 		return unmarshalAndHandleError(b, &ScheduledTaskWatchingEnded{})
 	case TribeMemberListType:
@@ -118,6 +144,24 @@ func UnmarshalBody(t string, b []byte) (Body, error) {
 		return unmarshalAndHandleError(b, &TribeJoinAgreement{})
 	case TribeLeaveAgreementType:
 		return unmarshalAndHandleError(b, &TribeLeaveAgreement{})
+	case TribeAddPluginType:
+		return unmarshalAndHandleError(b, &TribeAddPlugin{})
+	case TribeRemovePluginType:
+		return unmarshalAndHandleError(b, &TribeRemovePlugin{})
+	case TribeListTasksType:
+		return unmarshalAndHandleError(b, &TribeListAgreementTasks{})
+	case TribeAddTaskType:
+		return unmarshalAndHandleError(b, &TribeAddAgreementTask{})
+	case TribeRemoveTaskType:
+		return unmarshalAndHandleError(b, &TribeRemoveAgreementTask{})
+	case TribeStartTaskType:
+		return unmarshalAndHandleError(b, &TribeStartAgreementTask{})
+	case TribeStopTaskType:
+		return unmarshalAndHandleError(b, &TribeStopAgreementTask{})
+	case TribeStartRolloutType:
+		return unmarshalAndHandleError(b, &TribeStartRollout{})
+	case TribeGetRolloutType:
+		return unmarshalAndHandleError(b, &TribeGetRollout{})
 	case TribeGetAgreementType:
 		return unmarshalAndHandleError(b, &TribeGetAgreement{})
 	case PluginConfigItemType:
@@ -126,6 +170,34 @@ func UnmarshalBody(t string, b []byte) (Body, error) {
 		return unmarshalAndHandleError(b, &SetPluginConfigItem{*cdata.NewNode()})
 	case DeletePluginConfigItemType:
 		return unmarshalAndHandleError(b, &DeletePluginConfigItem{*cdata.NewNode()})
+	case AlertListReturnedType:
+		return unmarshalAndHandleError(b, &AlertListReturned{})
+	case LogEntriesReturnedType:
+		return unmarshalAndHandleError(b, &LogEntriesReturned{})
+	case WorkerPoolListReturnedType:
+		return unmarshalAndHandleError(b, &WorkerPoolListReturned{})
+	case WorkerPoolResizedType:
+		return unmarshalAndHandleError(b, &WorkerPoolResized{})
+	case MaintenanceReturnedType:
+		return unmarshalAndHandleError(b, &MaintenanceReturned{})
+	case MaintenanceChangedType:
+		return unmarshalAndHandleError(b, &MaintenanceChanged{})
+	case AliasListReturnedType:
+		return unmarshalAndHandleError(b, &AliasListReturned{})
+	case AliasAddedType:
+		return unmarshalAndHandleError(b, &AliasAdded{})
+	case AliasRemovedType:
+		return unmarshalAndHandleError(b, &AliasRemoved{})
+	case HealthReturnedType:
+		return unmarshalAndHandleError(b, &HealthReturned{})
+	case SchemaListReturnedType:
+		return unmarshalAndHandleError(b, &SchemaListReturned{})
+	case SchemaReturnedType:
+		return unmarshalAndHandleError(b, &SchemaReturned{})
+	case AggregatorReturnedType:
+		return unmarshalAndHandleError(b, &AggregatorReturned{})
+	case AggregatorMetricsIngestedType:
+		return unmarshalAndHandleError(b, &AggregatorMetricsIngested{})
 	case ErrorType:
 		return unmarshalAndHandleError(b, &Error{})
 	default:
@@ -0,0 +1,49 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbody
+
+const (
+	HealthReturnedType = "health_returned"
+)
+
+// HealthCheck is the result of a check of a part of snapd
+type HealthCheck struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+// HealthReturned is the result of the checks of a probe, healthy when all
+// of them pass
+type HealthReturned struct {
+	Healthy bool          `json:"healthy"`
+	Checks  []HealthCheck `json:"checks"`
+}
+
+func (h *HealthReturned) ResponseBodyMessage() string {
+	if h.Healthy {
+		return "All checks passed"
+	}
+	return "Some checks failed"
+}
+
+func (h *HealthReturned) ResponseBodyType() string {
+	return HealthReturnedType
+}
@@ -0,0 +1,76 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbody
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/intelsdi-x/snap/pkg/logbuffer"
+)
+
+const (
+	LogEntriesReturnedType = "log_entries_returned"
+)
+
+type LogEntriesReturned struct {
+	Entries []LogEntry `json:"entries"`
+}
+
+func (l *LogEntriesReturned) ResponseBodyMessage() string {
+	return "Log entries returned"
+}
+
+func (l *LogEntriesReturned) ResponseBodyType() string {
+	return LogEntriesReturnedType
+}
+
+// LogEntry is a line logged by a component of snapd, or by a plugin for the
+// plugin:<name> components
+type LogEntry struct {
+	Time      time.Time         `json:"time"`
+	Level     string            `json:"level"`
+	Component string            `json:"component"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+func (l *LogEntry) ToJSON() string {
+	j, _ := json.Marshal(l)
+	return string(j)
+}
+
+func LogEntryFromEntry(e logbuffer.Entry) LogEntry {
+	return LogEntry{
+		Time:      e.Time,
+		Level:     e.Level,
+		Component: e.Component,
+		Message:   e.Message,
+		Fields:    e.Fields,
+	}
+}
+
+func LogEntriesFromEntries(entries []logbuffer.Entry) *LogEntriesReturned {
+	l := &LogEntriesReturned{Entries: make([]LogEntry, len(entries))}
+	for i, e := range entries {
+		l.Entries[i] = LogEntryFromEntry(e)
+	}
+	return l
+}
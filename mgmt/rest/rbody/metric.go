@@ -22,23 +22,29 @@ package rbody
 import "fmt"
 
 const (
-	MetricsReturnedType = "metrics_returned"
-	MetricReturnedType  = "metric_returned"
+	MetricsReturnedType       = "metrics_returned"
+	MetricReturnedType        = "metric_returned"
+	MetricCatalogExportedType = "metric_catalog_exported"
 )
 
 type PolicyTable struct {
-	Name     string      `json:"name"`
-	Type     string      `json:"type"`
-	Default  interface{} `json:"default,omitempty"`
-	Required bool        `json:"required"`
-	Minimum  interface{} `json:"minimum,omitempty"`
-	Maximum  interface{} `json:"maximum,omitempty"`
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Default     interface{} `json:"default,omitempty"`
+	Required    bool        `json:"required"`
+	Minimum     interface{} `json:"minimum,omitempty"`
+	Maximum     interface{} `json:"maximum,omitempty"`
+	Choices     []string    `json:"choices,omitempty"`
+	Pattern     string      `json:"pattern,omitempty"`
+	ElementType string      `json:"element_type,omitempty"`
 }
 
 type Metric struct {
 	LastAdvertisedTimestamp int64         `json:"last_advertised_timestamp,omitempty"`
 	Namespace               string        `json:"namespace,omitempty"`
 	Version                 int           `json:"version,omitempty"`
+	Unit                    string        `json:"unit,omitempty"`
+	Description             string        `json:"description,omitempty"`
 	Policy                  []PolicyTable `json:"policy,omitempty"`
 	Href                    string        `json:"href"`
 }
@@ -80,3 +86,23 @@ func (m MetricsReturned) ResponseBodyMessage() string {
 func (m MetricsReturned) ResponseBodyType() string {
 	return MetricsReturnedType
 }
+
+// ExportedMetric describes a metric of the catalog for external systems
+type ExportedMetric struct {
+	// Name is the metric name used in the Prometheus and OpenMetrics formats
+	Name        string `json:"name"`
+	Namespace   string `json:"namespace"`
+	Version     int    `json:"version"`
+	Unit        string `json:"unit,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type MetricCatalogExported []ExportedMetric
+
+func (m MetricCatalogExported) ResponseBodyMessage() string {
+	return "Metric catalog exported"
+}
+
+func (m MetricCatalogExported) ResponseBodyType() string {
+	return MetricCatalogExportedType
+}
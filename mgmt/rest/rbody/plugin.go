@@ -25,10 +25,11 @@ import (
 )
 
 const (
-	PluginsLoadedType  = "plugins_loaded"
-	PluginUnloadedType = "plugin_unloaded"
-	PluginListType     = "plugin_list_returned"
-	PluginReturnedType = "plugin_returned"
+	PluginsLoadedType   = "plugins_loaded"
+	PluginUnloadedType  = "plugin_unloaded"
+	PluginListType      = "plugin_list_returned"
+	PluginReturnedType  = "plugin_returned"
+	PluginProcessedType = "plugin_processed"
 )
 
 // Successful response to the loading of a plugins
@@ -65,6 +66,21 @@ func (u *PluginUnloaded) ResponseBodyType() string {
 	return PluginUnloadedType
 }
 
+// Successful response to the processing of a batch of metrics offloaded by
+// a task of another snapd
+type PluginProcessed struct {
+	ContentType string `json:"content_type"`
+	Content     []byte `json:"content"`
+}
+
+func (p *PluginProcessed) ResponseBodyMessage() string {
+	return "Metrics processed"
+}
+
+func (p *PluginProcessed) ResponseBodyType() string {
+	return PluginProcessedType
+}
+
 type PluginList struct {
 	LoadedPlugins    []LoadedPlugin    `json:"loaded_plugins,omitempty"`
 	AvailablePlugins []AvailablePlugin `json:"available_plugins,omitempty"`
@@ -96,6 +112,7 @@ type LoadedPlugin struct {
 	Status          string        `json:"status"`
 	LoadedTimestamp int64         `json:"loaded_timestamp"`
 	Href            string        `json:"href"`
+	Features        []string      `json:"features,omitempty"`
 	ConfigPolicy    []PolicyTable `json:"policy,omitempty"`
 }
 
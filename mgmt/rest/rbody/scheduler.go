@@ -0,0 +1,95 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbody
+
+import (
+	"fmt"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+const (
+	WorkerPoolListReturnedType = "worker_pool_list_returned"
+	WorkerPoolResizedType      = "worker_pool_resized"
+)
+
+type WorkerPoolListReturned struct {
+	Pools []WorkerPool `json:"pools"`
+}
+
+func (w *WorkerPoolListReturned) ResponseBodyMessage() string {
+	return "Worker pools returned"
+}
+
+func (w *WorkerPoolListReturned) ResponseBodyType() string {
+	return WorkerPoolListReturnedType
+}
+
+type WorkerPoolResized struct {
+	WorkerPool
+}
+
+func (w *WorkerPoolResized) ResponseBodyMessage() string {
+	return fmt.Sprintf("Worker pool (%s) resized to %d workers", w.Name, w.Workers)
+}
+
+func (w *WorkerPoolResized) ResponseBodyType() string {
+	return WorkerPoolResizedType
+}
+
+// WorkerPool is the state of a worker pool of the scheduler. The wait times
+// are durations, e.g. "1.5ms".
+type WorkerPool struct {
+	Name        string  `json:"name"`
+	Workers     uint    `json:"workers"`
+	Busy        uint    `json:"busy"`
+	QueueDepth  uint    `json:"queue_depth"`
+	QueueLimit  uint    `json:"queue_limit"`
+	Jobs        uint64  `json:"jobs"`
+	Shed        uint64  `json:"shed"`
+	Utilization float64 `json:"utilization"`
+	LastWait    string  `json:"last_wait"`
+	MeanWait    string  `json:"mean_wait"`
+	MaxWait     string  `json:"max_wait"`
+}
+
+func WorkerPoolFromWorkerPool(p core.WorkerPool) WorkerPool {
+	return WorkerPool{
+		Name:        p.Name,
+		Workers:     p.Workers,
+		Busy:        p.Busy,
+		QueueDepth:  p.QueueDepth,
+		QueueLimit:  p.QueueLimit,
+		Jobs:        p.Jobs,
+		Shed:        p.Shed,
+		Utilization: p.Utilization,
+		LastWait:    p.LastWait.String(),
+		MeanWait:    p.MeanWait.String(),
+		MaxWait:     p.MaxWait.String(),
+	}
+}
+
+func WorkerPoolListFromWorkerPools(pools []core.WorkerPool) *WorkerPoolListReturned {
+	wl := &WorkerPoolListReturned{Pools: make([]WorkerPool, len(pools))}
+	for i, p := range pools {
+		wl.Pools[i] = WorkerPoolFromWorkerPool(p)
+	}
+	return wl
+}
@@ -0,0 +1,60 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbody
+
+import (
+	"fmt"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+const (
+	SchemaListReturnedType = "schema_list_returned"
+	SchemaReturnedType     = "schema_returned"
+)
+
+// SchemaListReturned holds the current version of each schema of the
+// metrics snapd publishes as JSON
+type SchemaListReturned struct {
+	Schemas []core.Schema `json:"schemas"`
+}
+
+func (s *SchemaListReturned) ResponseBodyMessage() string {
+	return "Schemas returned"
+}
+
+func (s *SchemaListReturned) ResponseBodyType() string {
+	return SchemaListReturnedType
+}
+
+// SchemaReturned is a version of a schema, along with the versions of the
+// schema registered
+type SchemaReturned struct {
+	core.Schema
+	Versions []int `json:"versions"`
+}
+
+func (s *SchemaReturned) ResponseBodyMessage() string {
+	return fmt.Sprintf("Schema %s version %d returned", s.Name, s.Version)
+}
+
+func (s *SchemaReturned) ResponseBodyType() string {
+	return SchemaReturnedType
+}
@@ -0,0 +1,89 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbody
+
+import (
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+const (
+	MaintenanceReturnedType = "maintenance_returned"
+	MaintenanceChangedType  = "maintenance_changed"
+
+	// The status of snapd in and out of the maintenance mode
+	SystemStatusOK          = "ok"
+	SystemStatusMaintenance = "maintenance"
+)
+
+type MaintenanceReturned struct {
+	Maintenance
+}
+
+func (m *MaintenanceReturned) ResponseBodyMessage() string {
+	return "Maintenance mode returned"
+}
+
+func (m *MaintenanceReturned) ResponseBodyType() string {
+	return MaintenanceReturnedType
+}
+
+type MaintenanceChanged struct {
+	Maintenance
+}
+
+func (m *MaintenanceChanged) ResponseBodyMessage() string {
+	if m.Enabled {
+		return "Maintenance mode on"
+	}
+	return "Maintenance mode off"
+}
+
+func (m *MaintenanceChanged) ResponseBodyType() string {
+	return MaintenanceChangedType
+}
+
+// Maintenance is the maintenance mode of snapd and the status it gives snapd.
+type Maintenance struct {
+	Status         string   `json:"status"`
+	Enabled        bool     `json:"enabled"`
+	HoldPublish    bool     `json:"hold_publish,omitempty"`
+	SinceTimestamp int64    `json:"since_timestamp,omitempty"`
+	Tasks          []string `json:"tasks,omitempty"`
+}
+
+func MaintenanceFromMaintenance(m core.Maintenance) Maintenance {
+	r := Maintenance{
+		Status:      SystemStatusOK,
+		Enabled:     m.Enabled,
+		HoldPublish: m.HoldPublish,
+		Tasks:       m.Tasks,
+	}
+	if m.Enabled {
+		r.Status = SystemStatusMaintenance
+		r.SinceTimestamp = m.Since.Unix()
+	}
+	return r
+}
+
+func (m *Maintenance) Since() time.Time {
+	return time.Unix(m.SinceTimestamp, 0)
+}
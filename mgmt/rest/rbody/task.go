@@ -20,26 +20,39 @@ limitations under the License.
 package rbody
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/mgmt/rest/request"
 	"github.com/intelsdi-x/snap/pkg/schedule"
 	"github.com/intelsdi-x/snap/scheduler/wmap"
 )
 
 const (
-	ScheduledTaskListReturnedType  = "scheduled_task_list_returned"
-	ScheduledTaskReturnedType      = "scheduled_task_returned"
-	AddScheduledTaskType           = "scheduled_task_created"
-	ScheduledTaskType              = "scheduled_task"
-	ScheduledTaskStartedType       = "scheduled_task_started"
-	ScheduledTaskStoppedType       = "scheduled_task_stopped"
-	ScheduledTaskRemovedType       = "scheduled_task_removed"
-	ScheduledTaskWatchingEndedType = "schedule_task_watch_ended"
-	ScheduledTaskEnabledType       = "scheduled_task_enabled"
+	ScheduledTaskListReturnedType   = "scheduled_task_list_returned"
+	ScheduledTaskReturnedType       = "scheduled_task_returned"
+	AddScheduledTaskType            = "scheduled_task_created"
+	ScheduledTaskType               = "scheduled_task"
+	ScheduledTaskStartedType        = "scheduled_task_started"
+	ScheduledTaskStoppedType        = "scheduled_task_stopped"
+	ScheduledTaskPausedType         = "scheduled_task_paused"
+	ScheduledTaskResumedType        = "scheduled_task_resumed"
+	ScheduledTaskTriggeredType      = "scheduled_task_triggered"
+	ScheduledTaskRemovedType        = "scheduled_task_removed"
+	ScheduledTaskWatchingEndedType  = "schedule_task_watch_ended"
+	ScheduledTaskEnabledType        = "scheduled_task_enabled"
+	ScheduledTaskUpdatedType        = "scheduled_task_updated"
+	ScheduledTaskRunsReturnedType   = "scheduled_task_runs_returned"
+	ScheduledTaskConfigReturnedType = "scheduled_task_config_returned"
+	ScheduledTaskRestoredType       = "scheduled_task_restored"
+	DeletedTaskListReturnedType     = "deleted_task_list_returned"
+	DeletedTaskReturnedType         = "deleted_task_returned"
+	TaskGraphReturnedType           = "task_graph_returned"
 
 	// Event types for task watcher streaming
 	TaskWatchStreamOpen   = "stream-open"
@@ -107,35 +120,119 @@ func AddSchedulerTaskFromTask(t core.Task) *AddScheduledTask {
 		FailedCount:        int(t.FailedCount()),
 		LastFailureMessage: t.LastFailureMessage(),
 		State:              t.State().String(),
+		OverrunCount:       int(t.OverrunCount()),
+		Priority:           t.Priority(),
+		ShedCount:          int(t.ShedCount()),
+		DroppedMetricCount: int(t.DroppedMetricCount()),
+		SuspectMetricCount: int(t.SuspectMetricCount()),
+		Sharded:            t.Sharded(),
+		BackPressure:       t.BackPressure(),
+		Description:        t.Description(),
+		CreatedBy:          t.CreatedBy(),
+		UpdatedBy:          t.UpdatedBy(),
+		Tenant:             t.Tenant(),
 		Workflow:           t.WMap(),
 	}
+	for _, r := range t.AlertRules() {
+		ar := request.AlertRule{
+			Name:       r.Name,
+			Expression: r.Expression,
+			Severity:   r.Severity,
+		}
+		if r.For > 0 {
+			ar.For = r.For.String()
+		}
+		st.Alerts = append(st.Alerts, ar)
+	}
+	for _, d := range t.Dependencies() {
+		st.DependsOn = append(st.DependsOn, request.TaskDependency{TaskID: d.TaskID, On: d.On})
+	}
+	st.Timestamps = timestampPolicy(t.TimestampPolicy())
 	assertSchedule(t.Schedule(), st)
+	policy, depth := t.OverrunPolicy()
+	st.OverrunPolicy, st.OverrunQueueDepth = policy, int(depth)
+	if ri := t.MetricRefreshInterval(); ri > 0 {
+		st.MetricRefresh = ri.String()
+	}
 	if st.LastRunTimestamp < 0 {
 		st.LastRunTimestamp = -1
 	}
+	if pu := t.PausedUntil(); pu != nil && !pu.IsZero() {
+		st.PausedUntilTimestamp = pu.Unix()
+	}
+	if ut := t.UpdateTime(); ut != nil && !ut.IsZero() {
+		st.UpdateTimestamp = ut.Unix()
+	}
 	return st
 }
 
 type ScheduledTask struct {
-	ID                 string            `json:"id"`
-	Name               string            `json:"name"`
-	Deadline           string            `json:"deadline"`
-	Workflow           *wmap.WorkflowMap `json:"workflow,omitempty"`
-	Schedule           *request.Schedule `json:"schedule,omitempty"`
-	CreationTimestamp  int64             `json:"creation_timestamp,omitempty"`
-	LastRunTimestamp   int64             `json:"last_run_timestamp,omitempty"`
-	HitCount           int               `json:"hit_count,omitempty"`
-	MissCount          int               `json:"miss_count,omitempty"`
-	FailedCount        int               `json:"failed_count,omitempty"`
-	LastFailureMessage string            `json:"last_failure_message,omitempty"`
-	State              string            `json:"task_state"`
-	Href               string            `json:"href"`
+	ID                   string                   `json:"id"`
+	Name                 string                   `json:"name"`
+	Deadline             string                   `json:"deadline"`
+	Workflow             *wmap.WorkflowMap        `json:"workflow,omitempty"`
+	Schedule             *request.Schedule        `json:"schedule,omitempty"`
+	CreationTimestamp    int64                    `json:"creation_timestamp,omitempty"`
+	LastRunTimestamp     int64                    `json:"last_run_timestamp,omitempty"`
+	HitCount             int                      `json:"hit_count,omitempty"`
+	MissCount            int                      `json:"miss_count,omitempty"`
+	FailedCount          int                      `json:"failed_count,omitempty"`
+	LastFailureMessage   string                   `json:"last_failure_message,omitempty"`
+	State                string                   `json:"task_state"`
+	PausedUntilTimestamp int64                    `json:"paused_until_timestamp,omitempty"`
+	OverrunPolicy        string                   `json:"overrun_policy,omitempty"`
+	OverrunQueueDepth    int                      `json:"overrun_queue_depth,omitempty"`
+	OverrunCount         int                      `json:"overrun_count,omitempty"`
+	Priority             string                   `json:"priority,omitempty"`
+	ShedCount            int                      `json:"shed_count,omitempty"`
+	DroppedMetricCount   int                      `json:"dropped_metric_count,omitempty"`
+	SuspectMetricCount   int                      `json:"suspect_metric_count,omitempty"`
+	Sharded              bool                     `json:"sharded,omitempty"`
+	MetricRefresh        string                   `json:"metric_refresh_interval,omitempty"`
+	BackPressure         []core.BackPressureState `json:"backpressure,omitempty"`
+	Alerts               []request.AlertRule      `json:"alerts,omitempty"`
+	DependsOn            []request.TaskDependency `json:"depends_on,omitempty"`
+	Timestamps           *request.TimestampPolicy `json:"timestamps,omitempty"`
+	Description          string                   `json:"description,omitempty"`
+	CreatedBy            string                   `json:"created_by,omitempty"`
+	UpdatedBy            string                   `json:"updated_by,omitempty"`
+	Tenant               string                   `json:"tenant,omitempty"`
+	UpdateTimestamp      int64                    `json:"update_timestamp,omitempty"`
+	DeleteTimestamp      int64                    `json:"delete_timestamp,omitempty"`
+	PurgeTimestamp       int64                    `json:"purge_timestamp,omitempty"`
+	Href                 string                   `json:"href"`
 }
 
 func (s *ScheduledTask) CreationTime() time.Time {
 	return time.Unix(s.CreationTimestamp, 0)
 }
 
+// UpdateTime returns when the task was last changed through the API, the
+// zero time when it never was
+func (s *ScheduledTask) UpdateTime() time.Time {
+	if s.UpdateTimestamp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(s.UpdateTimestamp, 0)
+}
+
+// DeleteTime returns when the task was deleted, the zero time when it is not
+func (s *ScheduledTask) DeleteTime() time.Time {
+	if s.DeleteTimestamp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(s.DeleteTimestamp, 0)
+}
+
+// PurgeTime returns when the deleted task will be purged, the zero time when
+// it is not deleted
+func (s *ScheduledTask) PurgeTime() time.Time {
+	if s.PurgeTimestamp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(s.PurgeTimestamp, 0)
+}
+
 func (s *ScheduledTask) ResponseBodyMessage() string {
 	return fmt.Sprintf("Scheduled task created (%s)", s.ID)
 }
@@ -156,13 +253,54 @@ func SchedulerTaskFromTask(t core.Task) *ScheduledTask {
 		FailedCount:        int(t.FailedCount()),
 		LastFailureMessage: t.LastFailureMessage(),
 		State:              t.State().String(),
+		OverrunCount:       int(t.OverrunCount()),
+		Priority:           t.Priority(),
+		ShedCount:          int(t.ShedCount()),
+		DroppedMetricCount: int(t.DroppedMetricCount()),
+		SuspectMetricCount: int(t.SuspectMetricCount()),
+		Sharded:            t.Sharded(),
+		BackPressure:       t.BackPressure(),
+		Description:        t.Description(),
+		CreatedBy:          t.CreatedBy(),
+		UpdatedBy:          t.UpdatedBy(),
+		Tenant:             t.Tenant(),
+	}
+	policy, depth := t.OverrunPolicy()
+	st.OverrunPolicy, st.OverrunQueueDepth = policy, int(depth)
+	if ri := t.MetricRefreshInterval(); ri > 0 {
+		st.MetricRefresh = ri.String()
 	}
 	if st.LastRunTimestamp < 0 {
 		st.LastRunTimestamp = -1
 	}
+	if pu := t.PausedUntil(); pu != nil && !pu.IsZero() {
+		st.PausedUntilTimestamp = pu.Unix()
+	}
+	if ut := t.UpdateTime(); ut != nil && !ut.IsZero() {
+		st.UpdateTimestamp = ut.Unix()
+	}
 	return st
 }
 
+// timestampPolicy returns the timestamp policy of a task, nil for the default
+// one
+func timestampPolicy(p core.TimestampPolicy) *request.TimestampPolicy {
+	if p == (core.TimestampPolicy{}) {
+		return nil
+	}
+	tp := &request.TimestampPolicy{
+		Source:     p.Source,
+		OutOfRange: p.OutOfRange,
+	}
+	if p.MaxFuture > 0 {
+		tp.MaxFuture = p.MaxFuture.String()
+	}
+	if p.MaxPast > 0 {
+		tp.MaxPast = p.MaxPast.String()
+	}
+	return tp
+}
+
 type ScheduledTaskStarted struct {
 	// TODO return resource
 	ID string `json:"id"`
@@ -189,6 +327,46 @@ func (s *ScheduledTaskStopped) ResponseBodyType() string {
 	return ScheduledTaskStoppedType
 }
 
+type ScheduledTaskPaused struct {
+	ID                   string `json:"id"`
+	PausedUntilTimestamp int64  `json:"paused_until_timestamp,omitempty"`
+}
+
+func (s *ScheduledTaskPaused) ResponseBodyMessage() string {
+	if s.PausedUntilTimestamp != 0 {
+		return fmt.Sprintf("Scheduled task (%s) paused until %s", s.ID, time.Unix(s.PausedUntilTimestamp, 0).Format(time.RFC1123))
+	}
+	return fmt.Sprintf("Scheduled task (%s) paused", s.ID)
+}
+
+func (s *ScheduledTaskPaused) ResponseBodyType() string {
+	return ScheduledTaskPausedType
+}
+
+type ScheduledTaskResumed struct {
+	ID string `json:"id"`
+}
+
+func (s *ScheduledTaskResumed) ResponseBodyMessage() string {
+	return fmt.Sprintf("Scheduled task (%s) resumed", s.ID)
+}
+
+func (s *ScheduledTaskResumed) ResponseBodyType() string {
+	return ScheduledTaskResumedType
+}
+
+type ScheduledTaskTriggered struct {
+	ID string `json:"id"`
+}
+
+func (s *ScheduledTaskTriggered) ResponseBodyMessage() string {
+	return fmt.Sprintf("Scheduled task (%s) triggered", s.ID)
+}
+
+func (s *ScheduledTaskTriggered) ResponseBodyType() string {
+	return ScheduledTaskTriggeredType
+}
+
 type ScheduledTaskRemoved struct {
 	// TODO return resource
 	ID string `json:"id"`
@@ -214,6 +392,191 @@ func (s *ScheduledTaskEnabled) ResponseBodyType() string {
 	return ScheduledTaskEnabledType
 }
 
+type ScheduledTaskRestored struct {
+	AddScheduledTask
+}
+
+func (s *ScheduledTaskRestored) ResponseBodyMessage() string {
+	return fmt.Sprintf("Deleted task (%s) restored", s.AddScheduledTask.ID)
+}
+
+func (s *ScheduledTaskRestored) ResponseBodyType() string {
+	return ScheduledTaskRestoredType
+}
+
+type DeletedTaskListReturned struct {
+	DeletedTasks []ScheduledTask
+}
+
+func (s *DeletedTaskListReturned) Len() int {
+	return len(s.DeletedTasks)
+}
+
+func (s *DeletedTaskListReturned) Less(i, j int) bool {
+	return s.DeletedTasks[i].DeleteTimestamp > s.DeletedTasks[j].DeleteTimestamp
+}
+
+func (s *DeletedTaskListReturned) Swap(i, j int) {
+	s.DeletedTasks[i], s.DeletedTasks[j] = s.DeletedTasks[j], s.DeletedTasks[i]
+}
+
+func (s *DeletedTaskListReturned) ResponseBodyMessage() string {
+	return "Deleted tasks retrieved"
+}
+
+func (s *DeletedTaskListReturned) ResponseBodyType() string {
+	return DeletedTaskListReturnedType
+}
+
+type DeletedTaskReturned struct {
+	AddScheduledTask
+}
+
+func (s *DeletedTaskReturned) ResponseBodyMessage() string {
+	return fmt.Sprintf("Deleted task (%s) returned", s.ID)
+}
+
+func (s *DeletedTaskReturned) ResponseBodyType() string {
+	return DeletedTaskReturnedType
+}
+
+// DeletedTaskFromTask returns the summary of a deleted task, with when it was
+// deleted and when it will be purged
+func DeletedTaskFromTask(d core.DeletedTask) *ScheduledTask {
+	st := SchedulerTaskFromTask(d.Task)
+	st.DeleteTimestamp = d.DeleteTime.Unix()
+	st.PurgeTimestamp = d.PurgeTime.Unix()
+	return st
+}
+
+// AddDeletedTaskFromTask returns the details of a deleted task, with when it
+// was deleted and when it will be purged
+func AddDeletedTaskFromTask(d core.DeletedTask) *AddScheduledTask {
+	st := AddSchedulerTaskFromTask(d.Task)
+	st.DeleteTimestamp = d.DeleteTime.Unix()
+	st.PurgeTimestamp = d.PurgeTime.Unix()
+	return st
+}
+
+type ScheduledTaskUpdated struct {
+	AddScheduledTask
+}
+
+func (s *ScheduledTaskUpdated) ResponseBodyMessage() string {
+	return fmt.Sprintf("Scheduled task (%s) updated", s.AddScheduledTask.ID)
+}
+
+func (s *ScheduledTaskUpdated) ResponseBodyType() string {
+	return ScheduledTaskUpdatedType
+}
+
+type ScheduledTaskRunsReturned struct {
+	ID   string             `json:"id"`
+	Runs []ScheduledTaskRun `json:"runs"`
+}
+
+func (s *ScheduledTaskRunsReturned) ResponseBodyMessage() string {
+	return fmt.Sprintf("Runs of scheduled task (%s) returned", s.ID)
+}
+
+func (s *ScheduledTaskRunsReturned) ResponseBodyType() string {
+	return ScheduledTaskRunsReturnedType
+}
+
+// ScheduledTaskConfigReturned holds the config each metric of a task is
+// collected with, keyed by namespace
+type ScheduledTaskConfigReturned struct {
+	ID      string                                `json:"id"`
+	Metrics map[string]map[string]TaskConfigValue `json:"metrics"`
+}
+
+func (s *ScheduledTaskConfigReturned) ResponseBodyMessage() string {
+	return fmt.Sprintf("Config of scheduled task (%s) returned", s.ID)
+}
+
+func (s *ScheduledTaskConfigReturned) ResponseBodyType() string {
+	return ScheduledTaskConfigReturnedType
+}
+
+// TaskConfigValue is the value of a config item. When resolved, the source
+// it comes from and the values it overrides are given as well.
+type TaskConfigValue struct {
+	Value      interface{}          `json:"value"`
+	Source     string               `json:"source,omitempty"`
+	Overridden []TaskConfigConflict `json:"overridden,omitempty"`
+}
+
+// TaskConfigConflict is a value overridden by a source of higher precedence
+type TaskConfigConflict struct {
+	Source string      `json:"source"`
+	Value  interface{} `json:"value"`
+}
+
+// TaskConfigFromResolved builds the config of a task from the config
+// resolved for its metrics, leaving out the sources unless resolve is true
+func TaskConfigFromResolved(id string, rc map[string]map[string]cdata.ResolvedConfigValue, resolve bool) *ScheduledTaskConfigReturned {
+	tc := &ScheduledTaskConfigReturned{
+		ID:      id,
+		Metrics: make(map[string]map[string]TaskConfigValue, len(rc)),
+	}
+	for ns, items := range rc {
+		values := make(map[string]TaskConfigValue, len(items))
+		for k, rv := range items {
+			v := TaskConfigValue{Value: rv.Value}
+			if resolve {
+				v.Source = rv.Source
+				for _, o := range rv.Overridden {
+					v.Overridden = append(v.Overridden, TaskConfigConflict{Source: o.Source, Value: o.Value})
+				}
+			}
+			values[k] = v
+		}
+		tc.Metrics[ns] = values
+	}
+	return tc
+}
+
+type ScheduledTaskRun struct {
+	Timestamp   int64                  `json:"timestamp"`
+	Duration    string                 `json:"duration"`
+	MetricCount int                    `json:"metric_count"`
+	Failed      bool                   `json:"failed"`
+	Steps       []ScheduledTaskRunStep `json:"steps"`
+}
+
+type ScheduledTaskRunStep struct {
+	Type     string   `json:"type"`
+	Plugin   string   `json:"plugin,omitempty"`
+	Duration string   `json:"duration"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+func TaskRunsFromTask(t core.Task) *ScheduledTaskRunsReturned {
+	runs := t.Runs()
+	tr := &ScheduledTaskRunsReturned{
+		ID:   t.ID(),
+		Runs: make([]ScheduledTaskRun, len(runs)),
+	}
+	for i, r := range runs {
+		tr.Runs[i] = ScheduledTaskRun{
+			Timestamp:   r.Start.Unix(),
+			Duration:    r.Duration.String(),
+			MetricCount: r.MetricCount,
+			Failed:      r.Failed(),
+			Steps:       make([]ScheduledTaskRunStep, len(r.Steps)),
+		}
+		for j, s := range r.Steps {
+			tr.Runs[i].Steps[j] = ScheduledTaskRunStep{
+				Type:     s.Type,
+				Plugin:   s.Plugin,
+				Duration: s.Duration.String(),
+				Errors:   s.Errors,
+			}
+		}
+	}
+	return tr
+}
+
 func assertSchedule(s schedule.Schedule, t *AddScheduledTask) {
 	switch v := s.(type) {
 	case *schedule.SimpleSchedule:
@@ -221,11 +584,39 @@ func assertSchedule(s schedule.Schedule, t *AddScheduledTask) {
 			Type:     "simple",
 			Interval: v.Interval.String(),
 		}
+		if v.Jitter > 0 {
+			t.Schedule.Jitter = v.Jitter.String()
+		}
+		return
+	case *schedule.TriggerSchedule:
+		t.Schedule = &request.Schedule{
+			Type: "trigger",
+		}
+		for _, src := range v.Sources {
+			c := src.Config()
+			t.Schedule.Triggers = append(t.Schedule.Triggers, request.Trigger{
+				Type:    c.Type,
+				Path:    c.Path,
+				URL:     redactURL(c.URL),
+				Subject: c.Subject,
+			})
+		}
 		return
 	}
 	t.Schedule = &request.Schedule{}
 }
 
+// redactURL strips the userinfo of a URL, e.g. the credentials of the NATS
+// server of a trigger
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	u.User = nil
+	return u.String()
+}
+
 type ScheduledTaskWatchingEnded struct {
 }
 
@@ -242,6 +633,8 @@ type StreamedTaskEvent struct {
 	EventType string          `json:"type"`
 	Message   string          `json:"message"`
 	Event     StreamedMetrics `json:"event,omitempty"`
+	// Replayed is set on the past events replayed when the stream opens
+	Replayed bool `json:"replayed,omitempty"`
 }
 
 func (s *StreamedTaskEvent) ToJSON() string {
@@ -253,6 +646,7 @@ type StreamedMetric struct {
 	Namespace string            `json:"namespace"`
 	Data      interface{}       `json:"data"`
 	Source    string            `json:"source"`
+	Host      *core.Host        `json:"host,omitempty"`
 	Timestamp time.Time         `json:"timestamp"`
 	Tags      map[string]string `json:"tags"`
 }
@@ -270,3 +664,49 @@ func (s StreamedMetrics) Less(i, j int) bool {
 func (s StreamedMetrics) Swap(i, j int) {
 	s[i], s[j] = s[j], s[i]
 }
+
+// TaskGraphReturned is the graph of the dependencies between the tasks. An
+// edge goes from the task depended on to the task its runs run.
+type TaskGraphReturned struct {
+	Nodes []TaskGraphNode `json:"nodes"`
+	Edges []TaskGraphEdge `json:"edges"`
+}
+
+type TaskGraphNode struct {
+	ID    string `json:"id"`
+	Name  string `json:"name,omitempty"`
+	State string `json:"task_state"`
+}
+
+type TaskGraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	On   string `json:"on"`
+}
+
+func (t *TaskGraphReturned) ResponseBodyMessage() string {
+	return "Task graph retrieved"
+}
+
+func (t *TaskGraphReturned) ResponseBodyType() string {
+	return TaskGraphReturnedType
+}
+
+// DOT returns the graph in the Graphviz DOT language, e.g. to be rendered
+// with `dot -Tsvg`
+func (t *TaskGraphReturned) DOT() string {
+	var b bytes.Buffer
+	b.WriteString("digraph tasks {\n")
+	for _, n := range t.Nodes {
+		label := n.Name
+		if label == "" {
+			label = n.ID
+		}
+		fmt.Fprintf(&b, "  %q [label=%q];\n", n.ID, label+"\n"+n.State)
+	}
+	for _, e := range t.Edges {
+		fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", e.From, e.To, e.On)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
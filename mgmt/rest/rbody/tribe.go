@@ -29,6 +29,15 @@ const (
 	TribeAddMemberType       = "tribe_member_added"
 	TribeJoinAgreementType   = "tribe_agreement_joined"
 	TribeLeaveAgreementType  = "tribe_agreement_left"
+	TribeAddPluginType       = "tribe_agreement_plugin_added"
+	TribeRemovePluginType    = "tribe_agreement_plugin_removed"
+	TribeListTasksType       = "tribe_agreement_task_list_returned"
+	TribeAddTaskType         = "tribe_agreement_task_added"
+	TribeRemoveTaskType      = "tribe_agreement_task_removed"
+	TribeStartTaskType       = "tribe_agreement_task_started"
+	TribeStopTaskType        = "tribe_agreement_task_stopped"
+	TribeStartRolloutType    = "tribe_rollout_started"
+	TribeGetRolloutType      = "tribe_rollout_returned"
 	TribeMemberListType      = "tribe_member_list_returned"
 	TribeMemberShowType      = "tribe_member_details_returned"
 )
@@ -105,6 +114,123 @@ func (t *TribeLeaveAgreement) ResponseBodyType() string {
 	return TribeLeaveAgreementType
 }
 
+type TribeAddPlugin struct {
+	Agreement *agreement.Agreement `json:"agreement"`
+}
+
+func (t *TribeAddPlugin) ResponseBodyMessage() string {
+	return "Plugin added to tribe agreement"
+}
+
+func (t *TribeAddPlugin) ResponseBodyType() string {
+	return TribeAddPluginType
+}
+
+type TribeRemovePlugin struct {
+	Agreement *agreement.Agreement `json:"agreement"`
+}
+
+func (t *TribeRemovePlugin) ResponseBodyMessage() string {
+	return "Plugin removed from tribe agreement"
+}
+
+func (t *TribeRemovePlugin) ResponseBodyType() string {
+	return TribeRemovePluginType
+}
+
+// TribeAgreementTask is a task of an agreement, with its name and state on
+// the member asked, which are empty when the member does not have the task.
+type TribeAgreementTask struct {
+	ID            string `json:"id"`
+	Name          string `json:"name,omitempty"`
+	State         string `json:"state,omitempty"`
+	StartOnCreate bool   `json:"start_on_create"`
+}
+
+type TribeListAgreementTasks struct {
+	Tasks []TribeAgreementTask `json:"tasks"`
+}
+
+func (t *TribeListAgreementTasks) ResponseBodyMessage() string {
+	return "Tribe agreement tasks retrieved"
+}
+
+func (t *TribeListAgreementTasks) ResponseBodyType() string {
+	return TribeListTasksType
+}
+
+type TribeAddAgreementTask struct {
+	Agreement *agreement.Agreement `json:"agreement"`
+}
+
+func (t *TribeAddAgreementTask) ResponseBodyMessage() string {
+	return "Task added to tribe agreement"
+}
+
+func (t *TribeAddAgreementTask) ResponseBodyType() string {
+	return TribeAddTaskType
+}
+
+type TribeRemoveAgreementTask struct {
+	Agreement *agreement.Agreement `json:"agreement"`
+}
+
+func (t *TribeRemoveAgreementTask) ResponseBodyMessage() string {
+	return "Task removed from tribe agreement"
+}
+
+func (t *TribeRemoveAgreementTask) ResponseBodyType() string {
+	return TribeRemoveTaskType
+}
+
+type TribeStartAgreementTask struct {
+	Agreement *agreement.Agreement `json:"agreement"`
+}
+
+func (t *TribeStartAgreementTask) ResponseBodyMessage() string {
+	return "Tribe agreement task started"
+}
+
+func (t *TribeStartAgreementTask) ResponseBodyType() string {
+	return TribeStartTaskType
+}
+
+type TribeStopAgreementTask struct {
+	Agreement *agreement.Agreement `json:"agreement"`
+}
+
+func (t *TribeStopAgreementTask) ResponseBodyMessage() string {
+	return "Tribe agreement task stopped"
+}
+
+func (t *TribeStopAgreementTask) ResponseBodyType() string {
+	return TribeStopTaskType
+}
+
+type TribeStartRollout struct {
+	Rollout *agreement.Rollout `json:"rollout"`
+}
+
+func (t *TribeStartRollout) ResponseBodyMessage() string {
+	return "Tribe rollout started"
+}
+
+func (t *TribeStartRollout) ResponseBodyType() string {
+	return TribeStartRolloutType
+}
+
+type TribeGetRollout struct {
+	Rollout *agreement.Rollout `json:"rollout"`
+}
+
+func (t *TribeGetRollout) ResponseBodyMessage() string {
+	return "Tribe rollout returned"
+}
+
+func (t *TribeGetRollout) ResponseBodyType() string {
+	return TribeGetRolloutType
+}
+
 type TribeMemberList struct {
 	Members []string `json:"members"`
 }
@@ -119,9 +245,13 @@ func (t *TribeMemberList) ResponseBodyType() string {
 
 type TribeMemberShow struct {
 	Name            string            `json:"name"`
+	Addr            string            `json:"addr,omitempty"`
 	PluginAgreement string            `json:"plugin_agreement"`
 	Tags            map[string]string `json:"tags"`
 	TaskAgreements  []string          `json:"task_agreements"`
+	// ClockSkew is how far the clock of the member is ahead of the clock of
+	// the member asked, negative when it is behind
+	ClockSkew string `json:"clock_skew,omitempty"`
 }
 
 func (t *TribeMemberShow) ResponseBodyMessage() string {
@@ -0,0 +1,29 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package request
+
+// AggregatorMetrics is a batch of metrics the task of another snapd forwards
+// to the aggregator, e.g. {"task_id": "<id>", "content_type": "snap.gob",
+// "content": "<base64>"}.
+type AggregatorMetrics struct {
+	TaskID      string `json:"task_id"`
+	ContentType string `json:"content_type"`
+	Content     []byte `json:"content"`
+}
@@ -29,11 +29,96 @@ type TaskCreationRequest struct {
 	Workflow *wmap.WorkflowMap `json:"workflow"`
 	Schedule Schedule          `json:"schedule"`
 	Start    bool              `json:"start"`
+	Priority string            `json:"priority,omitempty"`
+	Alerts   []AlertRule       `json:"alerts,omitempty"`
+	// Shard makes each member of the tribe agreements sharing the task
+	// collect its shard of the metrics of the task only
+	Shard bool `json:"shard,omitempty"`
+	// Timestamps says where the timestamps of the metrics collected come
+	// from and bounds those set by the collectors
+	Timestamps *TimestampPolicy `json:"timestamps,omitempty"`
+	// Description is a free-text description of the task
+	Description string `json:"description,omitempty"`
+	// DependsOn runs the task, which must have a trigger schedule, when the
+	// runs of the tasks it depends on end
+	DependsOn []TaskDependency `json:"depends_on,omitempty"`
+	// MetricRefreshInterval is how often the metric types of the collectors
+	// supporting dynamic metrics the task collects from are refreshed
+	MetricRefreshInterval string `json:"metric_refresh_interval,omitempty"`
+}
+
+// TaskDependency makes a task run when a run of the task it depends on ends
+// as the condition says, e.g. {"task_id": "<id>", "on": "success"}. The
+// conditions are success (the default), failure and completion.
+type TaskDependency struct {
+	TaskID string `json:"task_id"`
+	On     string `json:"on,omitempty"`
+}
+
+// TaskUpdateRequest changes a task without recreating it, e.g.
+// {"interval": "5s", "config": {"/intel/mock": {"password": "secret"}},
+// "add_metrics": {"/intel/mock/bar": 0}, "remove_metrics": ["/intel/mock/foo"]}.
+// A null config value removes the config item.
+type TaskUpdateRequest struct {
+	Interval      string                            `json:"interval,omitempty"`
+	Config        map[string]map[string]interface{} `json:"config,omitempty"`
+	AddMetrics    map[string]int                    `json:"add_metrics,omitempty"`
+	RemoveMetrics []string                          `json:"remove_metrics,omitempty"`
+	Description   *string                           `json:"description,omitempty"`
+}
+
+// TaskPauseRequest pauses a task, e.g. {"for": "2h"}. A task paused without
+// a duration stays paused until it is resumed.
+type TaskPauseRequest struct {
+	For string `json:"for,omitempty"`
+}
+
+// MaintenanceRequest turns the maintenance mode of snapd on or off, e.g.
+// {"enabled": true, "hold_publish": true}. With hold_publish the running tasks
+// keep collecting while their publishing is held instead of being paused.
+type MaintenanceRequest struct {
+	Enabled     bool `json:"enabled"`
+	HoldPublish bool `json:"hold_publish,omitempty"`
+}
+
+// AlertRule is an alert rule evaluated against the metrics collected by the
+// task, e.g. {"name": "mock-high", "expression": "/intel/mock/foo > 90",
+// "for": "30s", "severity": "critical"}
+type AlertRule struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+	For        string `json:"for,omitempty"`
+	Severity   string `json:"severity,omitempty"`
+}
+
+// TimestampPolicy says where the timestamps of the metrics collected by the
+// task come from, e.g. {"source": "plugin", "max_future": "1m", "max_past":
+// "1h", "out_of_range": "clamp"}
+type TimestampPolicy struct {
+	Source     string `json:"source,omitempty"`
+	MaxFuture  string `json:"max_future,omitempty"`
+	MaxPast    string `json:"max_past,omitempty"`
+	OutOfRange string `json:"out_of_range,omitempty"`
 }
 
 type Schedule struct {
-	Type           string `json:"type,omitempty"`
-	Interval       string `json:"interval,omitempty"`
-	StartTimestamp *int64 `json:"start_timestamp,omitempty"`
-	StopTimestamp  *int64 `json:"stop_timestamp,omitempty"`
+	Type              string `json:"type,omitempty"`
+	Interval          string `json:"interval,omitempty"`
+	Jitter            string `json:"jitter,omitempty"`
+	StartTimestamp    *int64 `json:"start_timestamp,omitempty"`
+	StopTimestamp     *int64 `json:"stop_timestamp,omitempty"`
+	Overrun           string `json:"overrun,omitempty"`
+	OverrunQueueDepth uint   `json:"overrun_queue_depth,omitempty"`
+	// Triggers are the sources of the events firing a "trigger" schedule
+	Triggers []Trigger `json:"triggers,omitempty"`
+}
+
+// Trigger is a source of the events firing a "trigger" schedule, e.g.
+// {"type": "file", "path": "/var/run/app.done"} or {"type": "nats", "url":
+// "nats://localhost:4222", "subject": "snap.collect"}
+type Trigger struct {
+	Type    string `json:"type"`
+	Path    string `json:"path,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Subject string `json:"subject,omitempty"`
 }
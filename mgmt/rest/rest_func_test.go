@@ -219,6 +219,29 @@ func removeTask(id string, port int) *rbody.APIResponse {
 	return getAPIResponse(resp)
 }
 
+func restoreTask(id string, port int) *rbody.APIResponse {
+	uri := fmt.Sprintf("http://localhost:%d/v1/tasks/%s/restore", port, id)
+	client := &http.Client{}
+	req, err := http.NewRequest("PUT", uri, bytes.NewReader([]byte{}))
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return getAPIResponse(resp)
+}
+
+func getDeletedTasks(port int) *rbody.APIResponse {
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/v1/deleted_tasks", port))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return getAPIResponse(resp)
+}
+
 func createTask(sample, name, interval string, noStart bool, port int) *rbody.APIResponse {
 	jsonP, err := ioutil.ReadFile("./wmap_sample/" + sample)
 	if err != nil {
@@ -624,5 +647,43 @@ func TestPluginRestCalls(t *testing.T) {
 				So(plr4.ErrorMessage, ShouldEqual, "Task must be disabled")
 			})
 		})
+
+		Convey("Restore task - put - /v1/tasks/:id/restore", func() {
+			Convey("Restore a removed task", func(c C) {
+				r := startAPI(getDefaultMockConfig())
+				port := r.port
+
+				uploadPlugin(MOCK_PLUGIN_PATH2, port)
+				uploadPlugin(FILE_PLUGIN_PATH, port)
+
+				r1 := createTask("1.json", "yeti", "1s", true, port)
+				So(r1.Body, ShouldHaveSameTypeAs, new(rbody.AddScheduledTask))
+				id := r1.Body.(*rbody.AddScheduledTask).ID
+
+				r2 := removeTask(id, port)
+				So(r2.Body, ShouldHaveSameTypeAs, new(rbody.ScheduledTaskRemoved))
+				r3 := getTask(id, port)
+				So(r3.Body, ShouldHaveSameTypeAs, new(rbody.Error))
+
+				r4 := getDeletedTasks(port)
+				So(r4.Body, ShouldHaveSameTypeAs, new(rbody.DeletedTaskListReturned))
+				plr4 := r4.Body.(*rbody.DeletedTaskListReturned)
+				So(plr4.DeletedTasks, ShouldHaveLength, 1)
+				So(plr4.DeletedTasks[0].ID, ShouldEqual, id)
+				So(plr4.DeletedTasks[0].State, ShouldEqual, "Deleted")
+				So(plr4.DeletedTasks[0].UpdatedBy, ShouldEqual, "anonymous")
+
+				r5 := restoreTask(id, port)
+				So(r5.Body, ShouldHaveSameTypeAs, new(rbody.ScheduledTaskRestored))
+				plr5 := r5.Body.(*rbody.ScheduledTaskRestored)
+				So(plr5.ID, ShouldEqual, id)
+				So(plr5.State, ShouldEqual, "Stopped")
+				r6 := getTask(id, port)
+				So(r6.Body, ShouldHaveSameTypeAs, new(rbody.ScheduledTaskReturned))
+
+				r7 := restoreTask(id, port)
+				So(r7.Body, ShouldHaveSameTypeAs, new(rbody.Error))
+			})
+		})
 	})
 }
@@ -0,0 +1,130 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+// getWorkerPools returns the state of the worker pools, as self-metrics of
+// snapd when a Prometheus or OpenMetrics format is asked for
+func (s *Server) getWorkerPools(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	pools := s.mt.WorkerPools()
+	switch r.URL.Query().Get("format") {
+	case "", ExportFormatJSON:
+		respond(200, rbody.WorkerPoolListFromWorkerPools(pools), w)
+	case ExportFormatPrometheus:
+		w.Header().Set("Content-Type", prometheusContentType)
+		w.WriteHeader(200)
+		writeWorkerPoolMetrics(w, pools, false)
+	case ExportFormatOpenMetrics:
+		w.Header().Set("Content-Type", openMetricsContentType)
+		w.WriteHeader(200)
+		writeWorkerPoolMetrics(w, pools, true)
+	default:
+		respond(400, rbody.FromError(ErrUnknownExportFormat), w)
+	}
+}
+
+// workerPoolMetric is a self-metric of snapd with a sample per worker pool
+type workerPoolMetric struct {
+	name    string
+	counter bool
+	help    string
+	value   func(core.WorkerPool) float64
+}
+
+var workerPoolMetrics = []workerPoolMetric{
+	{"workers", false, "Workers of the worker pool.", func(p core.WorkerPool) float64 { return float64(p.Workers) }},
+	{"busy_workers", false, "Workers of the worker pool running a job.", func(p core.WorkerPool) float64 { return float64(p.Busy) }},
+	{"queue_depth", false, "Jobs waiting for a worker of the worker pool.", func(p core.WorkerPool) float64 { return float64(p.QueueDepth) }},
+	{"queue_limit", false, "Jobs the queue of the worker pool holds, 0 when unbounded.", func(p core.WorkerPool) float64 { return float64(p.QueueLimit) }},
+	{"jobs", true, "Jobs started by the workers of the worker pool.", func(p core.WorkerPool) float64 { return float64(p.Jobs) }},
+	{"jobs_shed", true, "Low priority jobs shed from the full queue of the worker pool.", func(p core.WorkerPool) float64 { return float64(p.Shed) }},
+	{"utilization", false, "Fraction of the time of the workers spent running jobs.", func(p core.WorkerPool) float64 { return p.Utilization }},
+	{"job_wait_last_seconds", false, "Time the last job started waited for a worker.", func(p core.WorkerPool) float64 { return p.LastWait.Seconds() }},
+	{"job_wait_mean_seconds", false, "Mean time the jobs waited for a worker.", func(p core.WorkerPool) float64 { return p.MeanWait.Seconds() }},
+	{"job_wait_max_seconds", false, "Longest time a job waited for a worker.", func(p core.WorkerPool) float64 { return p.MaxWait.Seconds() }},
+}
+
+// writeWorkerPoolMetrics writes the state of the worker pools as self-metrics
+// of snapd in the Prometheus text format, or in the OpenMetrics format which
+// names the family of a counter without its _total suffix.
+func writeWorkerPoolMetrics(w io.Writer, pools []core.WorkerPool, openMetrics bool) {
+	for _, m := range workerPoolMetrics {
+		family := "snapd_scheduler_" + m.name
+		sample, typ := family, "gauge"
+		if m.counter {
+			sample, typ = family+"_total", "counter"
+			if !openMetrics {
+				family = sample
+			}
+		}
+		fmt.Fprintf(w, "# HELP %s %s\n", family, m.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", family, typ)
+		for _, p := range pools {
+			fmt.Fprintf(w, "%s{pool=\"%s\"} %s\n", sample, p.Name, strconv.FormatFloat(m.value(p), 'g', -1, 64))
+		}
+	}
+	if openMetrics {
+		fmt.Fprint(w, "# EOF\n")
+	}
+}
+
+func (s *Server) resizeWorkerPool(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	pool := p.ByName("pool")
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respond(500, rbody.FromError(err), w)
+		return
+	}
+	m := struct {
+		Workers uint `json:"workers"`
+	}{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		fields := map[string]interface{}{
+			"error": err,
+			"hint":  `The body of the request should be of the form '{"workers": 4}'`,
+		}
+		respond(400, rbody.FromSnapError(serror.New(ErrInvalidJSON, fields)), w)
+		return
+	}
+	if err := s.mt.ResizeWorkerPool(pool, m.Workers); err != nil {
+		respond(400, rbody.FromError(err), w)
+		return
+	}
+	for _, wp := range s.mt.WorkerPools() {
+		if wp.Name == pool {
+			respond(200, &rbody.WorkerPoolResized{WorkerPool: rbody.WorkerPoolFromWorkerPool(wp)}, w)
+			return
+		}
+	}
+}
@@ -0,0 +1,59 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWorkerPoolMetrics(t *testing.T) {
+	Convey("Writing the worker pools as self-metrics", t, func() {
+		pools := []core.WorkerPool{
+			{Name: core.CollectWorkerPool, Workers: 2, Busy: 1, QueueDepth: 3, Jobs: 12, Shed: 4, MaxWait: 250 * time.Millisecond},
+			{Name: core.PublishWorkerPool, Workers: 1},
+		}
+		Convey("in the Prometheus format", func() {
+			var buf bytes.Buffer
+			writeWorkerPoolMetrics(&buf, pools, false)
+			out := buf.String()
+			So(out, ShouldContainSubstring, "# TYPE snapd_scheduler_workers gauge\n"+
+				"snapd_scheduler_workers{pool=\"collect\"} 2\n"+
+				"snapd_scheduler_workers{pool=\"publish\"} 1\n")
+			So(out, ShouldContainSubstring, "# TYPE snapd_scheduler_jobs_total counter\n"+
+				"snapd_scheduler_jobs_total{pool=\"collect\"} 12\n")
+			So(out, ShouldContainSubstring, "snapd_scheduler_jobs_shed_total{pool=\"collect\"} 4\n")
+			So(out, ShouldContainSubstring, "snapd_scheduler_job_wait_max_seconds{pool=\"collect\"} 0.25\n")
+			So(out, ShouldNotContainSubstring, "# EOF")
+		})
+		Convey("in the OpenMetrics format", func() {
+			var buf bytes.Buffer
+			writeWorkerPoolMetrics(&buf, pools, true)
+			out := buf.String()
+			So(out, ShouldContainSubstring, "# TYPE snapd_scheduler_jobs counter\n"+
+				"snapd_scheduler_jobs_total{pool=\"collect\"} 12\n")
+			So(out, ShouldEndWith, "# EOF\n")
+		})
+	})
+}
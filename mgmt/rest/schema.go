@@ -0,0 +1,55 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+func (s *Server) getSchemas(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	respond(200, &rbody.SchemaListReturned{Schemas: core.Schemas()}, w)
+}
+
+// getSchema returns a version of a schema, the current one when the
+// version is left out
+func (s *Server) getSchema(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	name := p.ByName("name")
+	version := 0
+	if v := p.ByName("version"); v != "" {
+		var err error
+		if version, err = strconv.Atoi(v); err != nil || version < 1 {
+			respond(400, rbody.FromError(errors.New("invalid version")), w)
+			return
+		}
+	}
+	schema, err := core.GetSchema(name, version)
+	if err != nil {
+		respond(404, rbody.FromError(err), w)
+		return
+	}
+	respond(200, &rbody.SchemaReturned{Schema: schema, Versions: core.SchemaVersions(name)}, w)
+}
@@ -0,0 +1,77 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	cplugin "github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSchemas(t *testing.T) {
+	Convey("Given a REST API", t, func() {
+		s, err := New(GetDefaultConfig())
+		So(err, ShouldBeNil)
+		s.addRoutes()
+		get := func(path string) (int, *rbody.APIResponse) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", path, nil)
+			s.n.ServeHTTP(rec, req)
+			resp := &rbody.APIResponse{}
+			So(json.Unmarshal(rec.Body.Bytes(), resp), ShouldBeNil)
+			return rec.Code, resp
+		}
+
+		Convey("the current schemas are listed", func() {
+			code, resp := get("/v1/schemas")
+			So(code, ShouldEqual, 200)
+			var names []string
+			for _, schema := range resp.Body.(*rbody.SchemaListReturned).Schemas {
+				names = append(names, schema.Name)
+			}
+			So(names, ShouldContain, cplugin.MetricSchemaName)
+			So(names, ShouldContain, cplugin.EnvelopeSchemaName)
+		})
+		Convey("a schema is returned with its versions", func() {
+			code, resp := get("/v1/schemas/" + cplugin.MetricSchemaName)
+			So(code, ShouldEqual, 200)
+			schema := resp.Body.(*rbody.SchemaReturned)
+			So(schema.Version, ShouldEqual, cplugin.MetricSchemaVersion)
+			So(schema.Versions, ShouldContain, cplugin.MetricSchemaVersion)
+			var doc map[string]interface{}
+			So(json.Unmarshal(schema.Document, &doc), ShouldBeNil)
+			So(doc["required"], ShouldContain, "namespace")
+			code, _ = get("/v1/schemas/" + cplugin.MetricSchemaName + "/1")
+			So(code, ShouldEqual, 200)
+		})
+		Convey("an unknown schema or version is not found", func() {
+			code, _ := get("/v1/schemas/unknown")
+			So(code, ShouldEqual, 404)
+			code, _ = get("/v1/schemas/" + cplugin.MetricSchemaName + "/999")
+			So(code, ShouldEqual, 404)
+			code, _ = get("/v1/schemas/" + cplugin.MetricSchemaName + "/latest")
+			So(code, ShouldEqual, 400)
+		})
+	})
+}
@@ -27,19 +27,28 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/codegangsta/negroni"
 	"github.com/julienschmidt/httprouter"
+	"github.com/vrischmann/jsonutil"
 
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
 	"github.com/intelsdi-x/snap/core/serror"
 	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
 	"github.com/intelsdi-x/snap/mgmt/tribe/agreement"
+	"github.com/intelsdi-x/snap/pkg/datadir"
+	"github.com/intelsdi-x/snap/pkg/logbuffer"
+	"github.com/intelsdi-x/snap/pkg/netaddr"
 	cschedule "github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/pkg/tlspolicy"
 	"github.com/intelsdi-x/snap/scheduler/wmap"
 )
 
@@ -69,11 +78,26 @@ var (
 type Config struct {
 	Enable           bool   `json:"enable,omitempty"yaml:"enable,omitempty"`
 	Port             int    `json:"port,omitempty"yaml:"port,omitempty"`
+	Address          string `json:"addr,omitempty"yaml:"addr,omitempty"`
 	HTTPS            bool   `json:"https,omitempty"yaml:"https,omitempty"`
 	RestCertificate  string `json:"rest_certificate,omitempty"yaml:"rest_certificate,omitempty"`
 	RestKey          string `json:"rest_key,omitempty"yaml:"rest_key,omitempty"`
 	RestAuth         bool   `json:"rest_auth,omitempty"yaml:"rest_auth,omitempty"`
-	RestAuthPassword string `json:"rest_auth_password,omitempty"yaml:"rest_auth_password,omitempty"`
+	RestAuthPassword string `json:"rest_auth_password,omitempty"yaml:"rest_auth_password,omitempty"secret:"true"`
+	// Auth configures the providers authenticating the requests besides
+	// the rest_auth password
+	Auth *AuthConfig `json:"auth,omitempty"yaml:"auth,omitempty"`
+	// RestClientCACertificate sets the CA certificates the certificates of
+	// the clients are verified against over HTTPS, and RequireClientCert
+	// refuses the clients without one
+	RestClientCACertificate string `json:"rest_client_ca_certificate,omitempty"yaml:"rest_client_ca_certificate,omitempty"`
+	RequireClientCert       bool   `json:"require_client_cert,omitempty"yaml:"require_client_cert,omitempty"`
+	// HSTSMaxAge sets the max-age of the Strict-Transport-Security header of
+	// the responses over HTTPS, none when 0
+	HSTSMaxAge jsonutil.Duration `json:"hsts_max_age,omitempty"yaml:"hsts_max_age,omitempty"`
+	// TLSPolicy restricts the TLS versions and cipher suites, as set by the
+	// tls section of the snapd configuration
+	TLSPolicy *tlspolicy.Config `json:"-"yaml:"-"`
 }
 
 type managesMetrics interface {
@@ -81,11 +105,21 @@ type managesMetrics interface {
 	FetchMetrics([]string, int) ([]core.CatalogedMetric, error)
 	GetMetricVersions([]string) ([]core.CatalogedMetric, error)
 	GetMetric([]string, int) (core.CatalogedMetric, error)
+	QueryMetrics(*core.MetricQuery) ([]core.CatalogedMetric, error)
+	TasksUsingMetric(string) ([]string, error)
+	TasksUsingPlugin(string, string, int) []string
+	DependentTasks(core.Plugin) []string
 	Load(*core.RequestedPlugin) (core.CatalogedPlugin, serror.SnapError)
 	Unload(core.Plugin) (core.CatalogedPlugin, serror.SnapError)
 	PluginCatalog() core.PluginCatalog
 	AvailablePlugins() []core.AvailablePlugin
 	GetAutodiscoverPaths() []string
+	AddAlias(string, string) error
+	RemoveAlias(string) error
+	Aliases() map[string]string
+	SetPluginConfig(core.PluginType, string, int, *cdata.ConfigDataNode, bool) (cdata.ConfigDataNode, []serror.SnapError)
+	ProcessRemoteMetrics(string, []byte, string, int, map[string]ctypes.ConfigValue, string) (string, []byte, []error)
+	MetricVisible(string, []string) bool
 }
 
 type managesTasks interface {
@@ -94,9 +128,24 @@ type managesTasks interface {
 	GetTask(string) (core.Task, error)
 	StartTask(string) []serror.SnapError
 	StopTask(string) []serror.SnapError
+	PauseTask(string, time.Duration) []serror.SnapError
+	ResumeTask(string) []serror.SnapError
+	TriggerTask(string) []serror.SnapError
 	RemoveTask(string) error
-	WatchTask(string, core.TaskWatcherHandler) (core.TaskWatcherCloser, error)
+	GetDeletedTasks() []core.DeletedTask
+	GetDeletedTask(string) (core.DeletedTask, error)
+	RestoreTask(string) (core.Task, error)
+	WatchTask(string, core.TaskWatcherHandler, core.TaskWatchOptions) (core.TaskWatcherCloser, error)
 	EnableTask(string) (core.Task, error)
+	WorkerPools() []core.WorkerPool
+	ResizeWorkerPool(string, uint) error
+	ResolveTaskConfig(string) (map[string]map[string]cdata.ResolvedConfigValue, error)
+	UpdateTask(string, core.TaskUpdate) (core.Task, core.TaskErrors)
+	EnableMaintenance(bool) (core.Maintenance, error)
+	DisableMaintenance() (core.Maintenance, error)
+	Maintenance() core.Maintenance
+	IngestMetrics(string, string, string, []byte) (int, error)
+	Aggregator() core.Aggregator
 }
 
 type managesTribe interface {
@@ -106,8 +155,27 @@ type managesTribe interface {
 	RemoveAgreement(name string) serror.SnapError
 	JoinAgreement(agreementName, memberName string) serror.SnapError
 	LeaveAgreement(agreementName, memberName string) serror.SnapError
+	AddPlugin(agreementName string, p agreement.Plugin) error
+	RemovePlugin(agreementName string, p agreement.Plugin) error
+	AddTask(agreementName string, task agreement.Task) serror.SnapError
+	RemoveTask(agreementName string, task agreement.Task) serror.SnapError
+	StartTask(agreementName string, task agreement.Task) serror.SnapError
+	StopTask(agreementName string, task agreement.Task) serror.SnapError
 	GetMembers() []string
 	GetMember(name string) *agreement.Member
+	ClockSkew(name string) (time.Duration, bool)
+	ValidateTask(tr *request.TaskCreationRequest) serror.SnapError
+	StartRollout(agreementName, taskID string, tr *request.TaskCreationRequest, opts agreement.RolloutOptions) (*agreement.Rollout, serror.SnapError)
+	GetRollout(agreementName string) (*agreement.Rollout, serror.SnapError)
+}
+
+type managesAlerts interface {
+	ActiveAlerts() []core.Alert
+}
+
+type managesLogs interface {
+	Recent(component string, n int) []logbuffer.Entry
+	Tail(component string, n int) ([]logbuffer.Entry, <-chan logbuffer.Entry, func())
 }
 
 type managesConfig interface {
@@ -124,13 +192,24 @@ type Server struct {
 	mt      managesTasks
 	tr      managesTribe
 	mc      managesConfig
+	ma      managesAlerts
+	ml      managesLogs
 	n       *negroni.Negroni
 	r       *httprouter.Router
-	tls     *tls
+	tls     *tlsCert
+	srv     *http.Server
 	auth    bool
 	authpwd string
+	// the providers authenticating the requests besides the password
+	providers []AuthProvider
+	// the principals scoped to tenants by tenant ID
+	tenants map[string]TenantPrincipals
 	addr    net.Addr
 	err     chan error
+	dataDir *datadir.DataDir
+	// the checks of the liveness and readiness probes
+	liveness  []namedHealthCheck
+	readiness []namedHealthCheck
 }
 
 // func New(https bool, cpath, kpath string) (*Server, error) {
@@ -148,15 +227,33 @@ func New(cfg *Config) (*Server, error) {
 		if err != nil {
 			return nil, err
 		}
+		tc, err := newTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		s.srv = &http.Server{TLSConfig: tc}
 		protocolPrefix = "https"
 	}
+	providers, err := newAuthProviders(cfg.Auth, cfg.TLSPolicy)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range providers {
+		s.AddAuthProvider(p)
+	}
+	if cfg.Auth != nil {
+		s.tenants = cfg.Auth.Tenants
+	}
+	if len(providers) > 0 && !https {
+		restLogger.Warning("Using REST API authentication providers without HTTPS enabled.")
+	}
 
 	restLogger.Info(fmt.Sprintf("Configuring REST API with HTTPS set to: %v", https))
-	s.n = negroni.New(
-		NewLogger(),
-		negroni.NewRecovery(),
-		negroni.HandlerFunc(s.authMiddleware),
-	)
+	handlers := []negroni.Handler{NewLogger(), negroni.NewRecovery()}
+	if https && cfg.HSTSMaxAge.Duration > 0 {
+		handlers = append(handlers, hsts(cfg.HSTSMaxAge.Duration))
+	}
+	s.n = negroni.New(append(handlers, negroni.HandlerFunc(s.authMiddleware))...)
 	s.r = httprouter.New()
 	// Use negroni to handle routes
 	s.n.UseHandler(s.r)
@@ -173,7 +270,63 @@ func GetDefaultConfig() *Config {
 		RestKey:          defaultRestKey,
 		RestAuth:         defaultAuth,
 		RestAuthPassword: defaultAuthPassword,
+		Auth:             &AuthConfig{},
+	}
+}
+
+// Validate returns the problems found in the configuration. Nothing is
+// checked when the REST API is disabled.
+func (c *Config) Validate() []error {
+	if !c.Enable {
+		return nil
+	}
+	var errs []error
+	if c.Port < 1 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("restapi.port: %d is not a valid port", c.Port))
+	}
+	if c.Address != "" {
+		if _, err := netaddr.Resolve(c.Address); err != nil {
+			errs = append(errs, fmt.Errorf("restapi.addr: %v", err))
+		}
+	}
+	if (c.RestCertificate == "") != (c.RestKey == "") {
+		errs = append(errs, fmt.Errorf("restapi: rest_certificate and rest_key must be set together"))
+	}
+	if c.RestCertificate != "" {
+		if _, err := os.Stat(c.RestCertificate); err != nil {
+			errs = append(errs, fmt.Errorf("restapi.rest_certificate: %v", err))
+		}
+	}
+	if c.RestKey != "" {
+		if _, err := os.Stat(c.RestKey); err != nil {
+			errs = append(errs, fmt.Errorf("restapi.rest_key: %v", err))
+		}
+	}
+	if c.RestClientCACertificate != "" {
+		if _, err := os.Stat(c.RestClientCACertificate); err != nil {
+			errs = append(errs, fmt.Errorf("restapi.rest_client_ca_certificate: %v", err))
+		}
+	} else if c.RequireClientCert {
+		errs = append(errs, fmt.Errorf("restapi.require_client_cert: needs rest_client_ca_certificate"))
+	}
+	if c.HSTSMaxAge.Duration < 0 {
+		errs = append(errs, fmt.Errorf("restapi.hsts_max_age: must not be negative"))
 	}
+	errs = append(errs, c.Auth.Validate()...)
+	return errs
+}
+
+// ListenAddr returns the host:port the REST API listens on, on all the
+// addresses when no address is set.
+func (c *Config) ListenAddr() (string, error) {
+	addr := ""
+	if c.Address != "" {
+		var err error
+		if addr, err = netaddr.Resolve(c.Address); err != nil {
+			return "", err
+		}
+	}
+	return net.JoinHostPort(addr, strconv.Itoa(c.Port)), nil
 }
 
 // SetAPIAuth sets API authentication to enabled or disabled
@@ -181,27 +334,49 @@ func (s *Server) SetAPIAuth(auth bool) {
 	s.auth = auth
 }
 
+// SetDataDir sets the data directory uploaded plugins are written to
+func (s *Server) SetDataDir(d *datadir.DataDir) {
+	s.dataDir = d
+}
+
 // SetAPIAuthPwd sets the API authentication password from snapd
 func (s *Server) SetAPIAuthPwd(pwd string) {
 	s.authpwd = pwd
 }
 
+// AddAuthProvider adds a provider authenticating the requests, tried after
+// the rest_auth password and the providers added before it. Adding one
+// enables authentication.
+func (s *Server) AddAuthProvider(p AuthProvider) {
+	s.providers = append(s.providers, p)
+}
+
+// authProviders returns the providers authenticating the requests, none
+// when authentication is disabled
+func (s *Server) authProviders() []AuthProvider {
+	if !s.auth {
+		return s.providers
+	}
+	return append([]AuthProvider{&passwordAuth{password: s.authpwd}}, s.providers...)
+}
+
 // Auth Middleware for REST API
 func (s *Server) authMiddleware(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 	defer r.Body.Close()
-	if s.auth {
-		_, password, ok := r.BasicAuth()
-		// If we have valid password or going to tribe/agreements endpoint
-		// go to next. tribe/agreements endpoint used for populating
-		// snapctl help page when tribe mode is turned on.
-		if ok && password == s.authpwd {
-			next(rw, r)
-		} else {
-			http.Error(rw, "Not Authorized", 401)
-		}
-	} else {
+	providers := s.authProviders()
+	if len(providers) == 0 || r.URL.Path == LivenessPath || r.URL.Path == ReadinessPath {
 		next(rw, r)
+		return
+	}
+	p, err := authenticate(providers, r)
+	if err != nil {
+		http.Error(rw, "Not Authorized", 401)
+		return
 	}
+	if p.Tenant == "" {
+		p.Tenant = tenantOf(s.tenants, p)
+	}
+	next(rw, withPrincipal(r, p))
 }
 
 func (s *Server) Start(addrString string) {
@@ -232,7 +407,9 @@ func (s *Server) run(addrString string) {
 }
 
 func (s *Server) serveTLS(addrString string) {
-	err := http.ListenAndServeTLS(addrString, s.tls.cert, s.tls.key, s.n)
+	s.srv.Addr = addrString
+	s.srv.Handler = s.n
+	err := s.srv.ListenAndServeTLS(s.tls.cert, s.tls.key)
 	if err != nil {
 		restLogger.Error(err)
 		s.err <- err
@@ -281,41 +458,109 @@ func (s *Server) BindConfigManager(c managesConfig) {
 	s.mc = c
 }
 
+func (s *Server) BindAlertManager(a managesAlerts) {
+	s.ma = a
+}
+
+func (s *Server) BindLogBuffer(l managesLogs) {
+	s.ml = l
+}
+
 func (s *Server) addRoutes() {
 	// plugin routes
 	s.r.GET("/v1/plugins", s.getPlugins)
 	s.r.GET("/v1/plugins/:type", s.getPlugins)
 	s.r.GET("/v1/plugins/:type/:name", s.getPlugins)
 	s.r.GET("/v1/plugins/:type/:name/:version", s.getPlugin)
-	s.r.POST("/v1/plugins", s.loadPlugin)
-	s.r.DELETE("/v1/plugins/:type/:name/:version", s.unloadPlugin)
-	s.r.GET("/v1/plugins/:type/:name/:version/config", s.getPluginConfigItem)
-	s.r.PUT("/v1/plugins/:type/:name/:version/config", s.setPluginConfigItem)
-	s.r.DELETE("/v1/plugins/:type/:name/:version/config", s.deletePluginConfigItem)
+	s.r.POST("/v1/plugins", unscoped(s.loadPlugin))
+	s.r.DELETE("/v1/plugins/:type/:name/:version", unscoped(s.unloadPlugin))
+	s.r.GET("/v1/plugins/:type/:name/:version/config", unscoped(s.getPluginConfigItem))
+	s.r.PUT("/v1/plugins/:type/:name/:version/config", unscoped(s.setPluginConfigItem))
+	s.r.DELETE("/v1/plugins/:type/:name/:version/config", unscoped(s.deletePluginConfigItem))
+	s.r.POST("/v1/plugins/:type/:name/:version/process", unscoped(s.processMetrics))
 
 	// metric routes
 	s.r.GET("/v1/metrics", s.getMetrics)
 	s.r.GET("/v1/metrics/*namespace", s.getMetricsFromTree)
+	s.r.GET("/v1/catalog", s.exportMetrics)
+
+	// alias routes
+	s.r.GET("/v1/aliases", s.getAliases)
+	s.r.PUT("/v1/aliases/*namespace", unscoped(s.addAlias))
+	s.r.DELETE("/v1/aliases/*namespace", unscoped(s.removeAlias))
 
 	// task routes
 	s.r.GET("/v1/tasks", s.getTasks)
-	s.r.GET("/v1/tasks/:id", s.getTask)
-	s.r.GET("/v1/tasks/:id/watch", s.watchTask)
+	s.r.GET("/v1/tasks/:id", s.scopedTask(s.getTask))
+	s.r.GET("/v1/tasks/:id/watch", s.scopedTask(s.watchTask))
+	s.r.GET("/v1/tasks/:id/runs", s.scopedTask(s.getTaskRuns))
+	s.r.GET("/v1/tasks/:id/config", s.scopedTask(s.getTaskConfig))
 	s.r.POST("/v1/tasks", s.addTask)
-	s.r.PUT("/v1/tasks/:id/start", s.startTask)
-	s.r.PUT("/v1/tasks/:id/stop", s.stopTask)
-	s.r.DELETE("/v1/tasks/:id", s.removeTask)
-	s.r.PUT("/v1/tasks/:id/enable", s.enableTask)
+	s.r.PUT("/v1/tasks/:id/start", s.scopedTask(s.startTask))
+	s.r.PUT("/v1/tasks/:id/stop", s.scopedTask(s.stopTask))
+	s.r.PUT("/v1/tasks/:id/pause", s.scopedTask(s.pauseTask))
+	s.r.PUT("/v1/tasks/:id/resume", s.scopedTask(s.resumeTask))
+	s.r.POST("/v1/tasks/:id/trigger", s.scopedTask(s.triggerTask))
+	s.r.DELETE("/v1/tasks/:id", s.scopedTask(s.removeTask))
+	s.r.PATCH("/v1/tasks/:id", s.scopedTask(s.updateTask))
+	s.r.PUT("/v1/tasks/:id/enable", s.scopedTask(s.enableTask))
+	s.r.PUT("/v1/tasks/:id/restore", s.scopedTask(s.restoreTask))
+	s.r.GET("/v1/deleted_tasks", s.getDeletedTasks)
+	s.r.GET("/v1/deleted_tasks/:id", s.scopedTask(s.getDeletedTask))
+	s.r.GET("/v1/task_graph", s.getTaskGraph)
+
+	// scheduler routes
+	s.r.GET("/v1/scheduler/workers", s.getWorkerPools)
+	s.r.PUT("/v1/scheduler/workers/:pool", unscoped(s.resizeWorkerPool))
+
+	// system routes
+	s.r.GET("/v1/system/maintenance", s.getMaintenance)
+	s.r.PUT("/v1/system/maintenance", unscoped(s.setMaintenance))
+	s.r.GET("/status", unscoped(s.getStatus))
+
+	// aggregator routes
+	s.r.GET("/v1/aggregator", s.getAggregator)
+	s.r.POST("/v1/aggregator/metrics", unscoped(s.ingestAggregatorMetrics))
+
+	// schema routes
+	s.r.GET("/v1/schemas", s.getSchemas)
+	s.r.GET("/v1/schemas/:name", s.getSchema)
+	s.r.GET("/v1/schemas/:name/:version", s.getSchema)
+
+	// health routes
+	s.r.GET(LivenessPath, s.getLiveness)
+	s.r.GET(ReadinessPath, s.getReadiness)
+
+	// alert routes
+	if s.ma != nil {
+		s.r.GET("/v1/alerts", unscoped(s.getAlerts))
+	}
+
+	// log routes
+	if s.ml != nil {
+		s.r.GET("/v1/logs", unscoped(s.getLogs))
+	}
 
 	// tribe routes
 	if s.tr != nil {
 		s.r.GET("/v1/tribe/agreements", s.getAgreements)
-		s.r.POST("/v1/tribe/agreements", s.addAgreement)
+		s.r.POST("/v1/tribe/agreements", unscoped(s.addAgreement))
 		s.r.GET("/v1/tribe/agreements/:name", s.getAgreement)
-		s.r.DELETE("/v1/tribe/agreements/:name", s.deleteAgreement)
-		s.r.PUT("/v1/tribe/agreements/:name/join", s.joinAgreement)
-		s.r.DELETE("/v1/tribe/agreements/:name/leave", s.leaveAgreement)
+		s.r.DELETE("/v1/tribe/agreements/:name", unscoped(s.deleteAgreement))
+		s.r.PUT("/v1/tribe/agreements/:name/join", unscoped(s.joinAgreement))
+		s.r.DELETE("/v1/tribe/agreements/:name/leave", unscoped(s.leaveAgreement))
+		s.r.PUT("/v1/tribe/agreements/:name/plugins", unscoped(s.addAgreementPlugin))
+		s.r.DELETE("/v1/tribe/agreements/:name/plugins", unscoped(s.removeAgreementPlugin))
+		s.r.GET("/v1/tribe/agreements/:name/tasks", s.getAgreementTasks)
+		s.r.POST("/v1/tribe/agreements/:name/tasks", unscoped(s.addAgreementTask))
+		s.r.DELETE("/v1/tribe/agreements/:name/tasks/:id", unscoped(s.removeAgreementTask))
+		s.r.PUT("/v1/tribe/agreements/:name/tasks/:id/start", unscoped(s.startAgreementTask))
+		s.r.PUT("/v1/tribe/agreements/:name/tasks/:id/stop", unscoped(s.stopAgreementTask))
+		s.r.POST("/v1/tribe/agreements/:name/rollout", unscoped(s.startRollout))
+		s.r.GET("/v1/tribe/agreements/:name/rollout", s.getRollout)
 		s.r.GET("/v1/tribe/members", s.getMembers)
+		s.r.GET("/v1/tribe/members/:name", s.getMember)
+		// the singular route predates the one above
 		s.r.GET("/v1/tribe/member/:name", s.getMember)
 	}
 }
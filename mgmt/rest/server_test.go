@@ -20,9 +20,14 @@ limitations under the License.
 package rest
 
 import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/intelsdi-x/snap/pkg/cfgfile"
+	"github.com/intelsdi-x/snap/pkg/tlspolicy"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -134,3 +139,78 @@ func TestRestAPIDefaultConfig(t *testing.T) {
 		})
 	})
 }
+
+func TestRestAPIConfigValidate(t *testing.T) {
+	Convey("Validating a REST API config", t, func() {
+		cfg := GetDefaultConfig()
+		Convey("the default config is valid", func() {
+			So(cfg.Validate(), ShouldBeEmpty)
+		})
+		Convey("invalid values are reported", func() {
+			cfg.Port = 0
+			cfg.RestCertificate = "/this/path/does/not/exist"
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 3)
+			So(errs[0].Error(), ShouldStartWith, "restapi.port")
+			So(errs[2].Error(), ShouldStartWith, "restapi.rest_certificate")
+		})
+		Convey("an address which is neither an IP nor an interface is reported", func() {
+			cfg.Address = "no-such-iface0"
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Error(), ShouldStartWith, "restapi.addr")
+		})
+		Convey("requiring client certificates without CA certificates is reported", func() {
+			cfg.RequireClientCert = true
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Error(), ShouldStartWith, "restapi.require_client_cert")
+		})
+		Convey("nothing is checked when disabled", func() {
+			cfg.Enable = false
+			cfg.Port = 0
+			So(cfg.Validate(), ShouldBeEmpty)
+		})
+	})
+}
+
+func TestRestAPITLSConfig(t *testing.T) {
+	Convey("The TLS configuration of the REST API", t, func() {
+		cfg := GetDefaultConfig()
+		Convey("is restricted by the TLS policy", func() {
+			cfg.TLSPolicy = &tlspolicy.Config{MinVersion: "1.2"}
+			tc, err := newTLSConfig(cfg)
+			So(err, ShouldBeNil)
+			So(tc.MinVersion, ShouldEqual, tls.VersionTLS12)
+			So(tc.ClientAuth, ShouldEqual, tls.NoClientCert)
+		})
+		Convey("fails with CA certificates which cannot be read", func() {
+			cfg.RestClientCACertificate = "/this/path/does/not/exist"
+			_, err := newTLSConfig(cfg)
+			So(err, ShouldNotBeNil)
+		})
+	})
+	Convey("The HSTS middleware sets the Strict-Transport-Security header", t, func() {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/v1/plugins", nil)
+		hsts(time.Hour)(rec, req, func(http.ResponseWriter, *http.Request) {})
+		So(rec.Header().Get("Strict-Transport-Security"), ShouldEqual, "max-age=3600")
+	})
+}
+
+func TestRestAPIListenAddr(t *testing.T) {
+	Convey("The REST API listens", t, func() {
+		cfg := GetDefaultConfig()
+		Convey("on all the addresses by default", func() {
+			addr, err := cfg.ListenAddr()
+			So(err, ShouldBeNil)
+			So(addr, ShouldEqual, ":8181")
+		})
+		Convey("on an IPv6 address", func() {
+			cfg.Address = "::1"
+			addr, err := cfg.ListenAddr()
+			So(err, ShouldBeNil)
+			So(addr, ShouldEqual, "[::1]:8181")
+		})
+	})
+}
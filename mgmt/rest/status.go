@@ -0,0 +1,200 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/pkg/logbuffer"
+)
+
+const (
+	// the number of the last errors logged shown on the status page
+	statusErrors = 10
+	// the number of the last log entries searched for errors
+	statusLogEntries = 1000
+)
+
+// statusPage is what the status page summarizes
+type statusPage struct {
+	Generated   time.Time
+	Maintenance core.Maintenance
+	Plugins     []core.CatalogedPlugin
+	Tasks       []core.Task
+	Alerts      []core.Alert
+	Errors      []logbuffer.Entry
+	Tribe       bool
+	Members     []string
+	// ClockSkews are how far the clocks of the members are off the local
+	// clock, by member
+	ClockSkews map[string]time.Duration
+}
+
+func (p statusPage) TaskState(t core.Task) string {
+	return core.TaskStateLookup[t.State()]
+}
+
+// getStatus renders a read-only HTML page summarizing the plugins loaded,
+// the states of the tasks, the alerts firing, the last errors logged and the
+// members of the tribe, for the operators who would rather use a browser
+// than the JSON API
+func (s *Server) getStatus(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	p := statusPage{
+		Generated:   time.Now(),
+		Maintenance: s.mt.Maintenance(),
+		Plugins:     s.mm.PluginCatalog(),
+	}
+	sort.Sort(byTypeNameVersion(p.Plugins))
+	for _, t := range s.mt.GetTasks() {
+		p.Tasks = append(p.Tasks, t)
+	}
+	sort.Sort(byTaskName(p.Tasks))
+	if s.ma != nil {
+		p.Alerts = s.ma.ActiveAlerts()
+	}
+	if s.ml != nil {
+		p.Errors = lastErrors(s.ml.Recent("", statusLogEntries), statusErrors)
+	}
+	if s.tr != nil {
+		p.Tribe = true
+		p.Members = s.tr.GetMembers()
+		sort.Strings(p.Members)
+		p.ClockSkews = map[string]time.Duration{}
+		for _, m := range p.Members {
+			if skew, ok := s.tr.ClockSkew(m); ok {
+				p.ClockSkews[m] = skew
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(200)
+	if err := statusTemplate.Execute(w, p); err != nil {
+		restLogger.WithField("_block", "get-status").Error(err)
+	}
+}
+
+// lastErrors returns the last n entries logged at the error level or above,
+// the latest first
+func lastErrors(entries []logbuffer.Entry, n int) []logbuffer.Entry {
+	var errs []logbuffer.Entry
+	for i := len(entries) - 1; i >= 0 && len(errs) < n; i-- {
+		switch entries[i].Level {
+		case "error", "fatal", "panic":
+			errs = append(errs, entries[i])
+		}
+	}
+	return errs
+}
+
+type byTypeNameVersion []core.CatalogedPlugin
+
+func (p byTypeNameVersion) Len() int      { return len(p) }
+func (p byTypeNameVersion) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p byTypeNameVersion) Less(i, j int) bool {
+	if p[i].TypeName() != p[j].TypeName() {
+		return p[i].TypeName() < p[j].TypeName()
+	}
+	if p[i].Name() != p[j].Name() {
+		return p[i].Name() < p[j].Name()
+	}
+	return p[i].Version() < p[j].Version()
+}
+
+type byTaskName []core.Task
+
+func (t byTaskName) Len() int      { return len(t) }
+func (t byTaskName) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+func (t byTaskName) Less(i, j int) bool {
+	if t[i].GetName() != t[j].GetName() {
+		return t[i].GetName() < t[j].GetName()
+	}
+	return t[i].ID() < t[j].ID()
+}
+
+var statusTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+	"time": func(t *time.Time) string {
+		if t == nil || t.IsZero() {
+			return "never"
+		}
+		return t.Format(time.RFC3339)
+	},
+	"status": func(m core.Maintenance) string {
+		if m.Enabled {
+			return rbody.SystemStatusMaintenance
+		}
+		return rbody.SystemStatusOK
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="30">
+<title>snapd status</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+th { background: #eee; }
+.failed { color: #b00; }
+</style>
+</head>
+<body>
+<h1>snapd status: {{status .Maintenance}}</h1>
+<p>Generated at {{.Generated.Format "2006-01-02T15:04:05Z07:00"}}, refreshed every 30 seconds.</p>
+
+<h2>Plugins ({{len .Plugins}})</h2>
+<table>
+<tr><th>Type</th><th>Name</th><th>Version</th><th>Status</th><th>Signed</th><th>Loaded</th></tr>
+{{range .Plugins}}<tr><td>{{.TypeName}}</td><td>{{.Name}}</td><td>{{.Version}}</td><td>{{.Status}}</td><td>{{.IsSigned}}</td><td>{{time .LoadedTimestamp}}</td></tr>
+{{end}}</table>
+
+<h2>Tasks ({{len .Tasks}})</h2>
+<table>
+<tr><th>Name</th><th>ID</th><th>State</th><th>Hits</th><th>Misses</th><th>Failures</th><th>Last run</th><th>Last failure</th></tr>
+{{range .Tasks}}<tr><td>{{.GetName}}</td><td>{{.ID}}</td><td>{{$.TaskState .}}</td><td>{{.HitCount}}</td><td>{{.MissedCount}}</td><td>{{.FailedCount}}</td><td>{{time .LastRunTime}}</td><td class="failed">{{.LastFailureMessage}}</td></tr>
+{{end}}</table>
+
+<h2>Alerts firing ({{len .Alerts}})</h2>
+<table>
+<tr><th>Task</th><th>Rule</th><th>Severity</th><th>Metric</th><th>Value</th></tr>
+{{range .Alerts}}<tr><td>{{.TaskName}}</td><td>{{.Rule}}</td><td>{{.Severity}}</td><td>{{.Namespace}}</td><td>{{.Value}}</td></tr>
+{{end}}</table>
+
+<h2>Last errors ({{len .Errors}})</h2>
+<table>
+<tr><th>Time</th><th>Component</th><th>Message</th></tr>
+{{range .Errors}}<tr><td>{{.Time.Format "2006-01-02T15:04:05Z07:00"}}</td><td>{{.Component}}</td><td class="failed">{{.Message}}</td></tr>
+{{end}}</table>
+{{if .Tribe}}
+<h2>Tribe members ({{len .Members}})</h2>
+<table>
+<tr><th>Name</th><th>Clock skew</th></tr>
+{{range .Members}}<tr><td>{{.}}</td><td>{{with index $.ClockSkews .}}{{.}}{{end}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
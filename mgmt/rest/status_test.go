@@ -0,0 +1,117 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/logbuffer"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type statusPlugin struct {
+	core.CatalogedPlugin
+	name string
+}
+
+func (p *statusPlugin) TypeName() string            { return "collector" }
+func (p *statusPlugin) Name() string                { return p.name }
+func (p *statusPlugin) Version() int                { return 1 }
+func (p *statusPlugin) Status() string              { return "loaded" }
+func (p *statusPlugin) IsSigned() bool              { return false }
+func (p *statusPlugin) LoadedTimestamp() *time.Time { return nil }
+
+type statusTask struct {
+	core.Task
+}
+
+func (t *statusTask) ID() string                 { return "7cd4b229" }
+func (t *statusTask) GetName() string            { return "<cpu>" }
+func (t *statusTask) State() core.TaskState      { return core.TaskDisabled }
+func (t *statusTask) HitCount() uint             { return 3 }
+func (t *statusTask) MissedCount() uint          { return 0 }
+func (t *statusTask) FailedCount() uint          { return 10 }
+func (t *statusTask) LastRunTime() *time.Time    { return nil }
+func (t *statusTask) LastFailureMessage() string { return "plugin crashed" }
+
+type statusMetricManager struct {
+	managesMetrics
+}
+
+func (m *statusMetricManager) PluginCatalog() core.PluginCatalog {
+	return core.PluginCatalog{&statusPlugin{name: "psutil"}, &statusPlugin{name: "mock"}}
+}
+
+type statusTaskManager struct {
+	managesTasks
+}
+
+func (m *statusTaskManager) GetTasks() map[string]core.Task {
+	return map[string]core.Task{"7cd4b229": &statusTask{}}
+}
+
+func (m *statusTaskManager) Maintenance() core.Maintenance {
+	return core.Maintenance{}
+}
+
+type statusLogs struct {
+	managesLogs
+}
+
+func (l *statusLogs) Recent(component string, n int) []logbuffer.Entry {
+	return []logbuffer.Entry{
+		{Level: "error", Component: "scheduler", Message: "first error"},
+		{Level: "info", Component: "scheduler", Message: "task started"},
+		{Level: "error", Component: "control", Message: "last error"},
+	}
+}
+
+func TestStatusPage(t *testing.T) {
+	Convey("The status page", t, func() {
+		s, err := New(GetDefaultConfig())
+		So(err, ShouldBeNil)
+		s.BindMetricManager(&statusMetricManager{})
+		s.BindTaskManager(&statusTaskManager{})
+		s.BindLogBuffer(&statusLogs{})
+		s.addRoutes()
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/status", nil)
+		s.n.ServeHTTP(rec, req)
+		So(rec.Code, ShouldEqual, 200)
+		So(rec.Header().Get("Content-Type"), ShouldStartWith, "text/html")
+		page := rec.Body.String()
+		Convey("summarizes the plugins and tasks", func() {
+			So(page, ShouldContainSubstring, "<h1>snapd status: ok</h1>")
+			So(page, ShouldContainSubstring, "<td>collector</td><td>mock</td><td>1</td><td>loaded</td>")
+			So(page, ShouldContainSubstring, "<td>&lt;cpu&gt;</td><td>7cd4b229</td><td>Disabled</td>")
+			So(page, ShouldContainSubstring, "plugin crashed")
+			So(page, ShouldNotContainSubstring, "Tribe members")
+		})
+		Convey("lists the errors logged, the latest first", func() {
+			So(page, ShouldNotContainSubstring, "task started")
+			So(page, ShouldContainSubstring, "Last errors (2)")
+			So(strings.Index(page, "last error"), ShouldBeLessThan, strings.Index(page, "first error"))
+		})
+	})
+}
@@ -0,0 +1,76 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
+)
+
+var (
+	ErrMaintenanceEnabled  = errors.New("Maintenance mode is already on")
+	ErrMaintenanceDisabled = errors.New("Maintenance mode is not on")
+)
+
+func (s *Server) getMaintenance(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	respond(200, &rbody.MaintenanceReturned{Maintenance: rbody.MaintenanceFromMaintenance(s.mt.Maintenance())}, w)
+}
+
+func (s *Server) setMaintenance(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respond(500, rbody.FromError(err), w)
+		return
+	}
+	mr := request.MaintenanceRequest{}
+	if err := json.Unmarshal(b, &mr); err != nil {
+		fields := map[string]interface{}{
+			"error": err,
+			"hint":  `The body of the request should be of the form '{"enabled": true, "hold_publish": false}'`,
+		}
+		respond(400, rbody.FromSnapError(serror.New(ErrInvalidJSON, fields)), w)
+		return
+	}
+	var m core.Maintenance
+	if mr.Enabled {
+		m, err = s.mt.EnableMaintenance(mr.HoldPublish)
+	} else {
+		m, err = s.mt.DisableMaintenance()
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), ErrMaintenanceEnabled.Error()) || strings.Contains(err.Error(), ErrMaintenanceDisabled.Error()) {
+			respond(409, rbody.FromError(err), w)
+			return
+		}
+		respond(400, rbody.FromError(err), w)
+		return
+	}
+	respond(200, &rbody.MaintenanceChanged{Maintenance: rbody.MaintenanceFromMaintenance(m)}, w)
+}
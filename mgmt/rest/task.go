@@ -20,18 +20,23 @@ limitations under the License.
 package rest
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/julienschmidt/httprouter"
 
 	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/serror"
 	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
 	"github.com/intelsdi-x/snap/mgmt/rest/request"
 	cschedule "github.com/intelsdi-x/snap/pkg/schedule"
@@ -45,6 +50,9 @@ var (
 	ErrStreamingUnsupported    = errors.New("Streaming unsupported")
 	ErrTaskNotFound            = errors.New("Task not found")
 	ErrTaskDisabledNotRunnable = errors.New("Task is disabled. Cannot be started")
+	ErrTaskNotRunning          = errors.New("Task must be running")
+	ErrTaskNotPaused           = errors.New("Task must be paused")
+	ErrTaskNotTriggered        = errors.New("Task schedule must be a trigger schedule")
 )
 
 type configItem struct {
@@ -76,26 +84,27 @@ func (s *Server) addTask(w http.ResponseWriter, r *http.Request, _ httprouter.Pa
 		return
 	}
 
-	sch, err := makeSchedule(tr.Schedule)
+	sch, err := MakeSchedule(tr.Schedule)
 	if err != nil {
 		respond(500, rbody.FromError(err), w)
 		return
 	}
 
-	var opts []core.TaskOption
-	if tr.Deadline != "" {
-		dl, err := time.ParseDuration(tr.Deadline)
-		if err != nil {
-			respond(500, rbody.FromError(err), w)
-			return
-		}
-		opts = append(opts, core.TaskDeadlineDuration(dl))
+	opts, err := MakeTaskOptions(tr)
+	if err != nil {
+		respond(500, rbody.FromError(err), w)
+		return
 	}
+	opts = append(opts, core.OptionTaskCreatedBy(principal(r)), core.OptionTaskTenant(tenant(r)))
 
-	if tr.Name != "" {
-		opts = append(opts, core.SetTaskName(tr.Name))
+	// a task shared by tribe is only created, and started, once every
+	// member of its agreements is known to be able to create it
+	if s.tr != nil {
+		if serr := s.tr.ValidateTask(tr); serr != nil {
+			respond(500, rbody.FromSnapError(serr), w)
+			return
+		}
 	}
-	opts = append(opts, core.OptionStopOnFailure(10))
 
 	task, errs := s.mt.CreateTask(sch, tr.Workflow, tr.Start, opts...)
 	if errs != nil && len(errs.Errors()) != 0 {
@@ -103,17 +112,41 @@ func (s *Server) addTask(w http.ResponseWriter, r *http.Request, _ httprouter.Pa
 		for _, e := range errs.Errors() {
 			errMsg = errMsg + e.Error() + " -- "
 		}
-		respond(500, rbody.FromError(errors.New(errMsg[:len(errMsg)-4])), w)
+		respond(quotaCode(errs.Errors(), 500), rbody.FromError(errors.New(errMsg[:len(errMsg)-4])), w)
 		return
 	}
 
+	logTaskChange(r, task.ID(), "create")
 	taskB := rbody.AddSchedulerTaskFromTask(task)
 	taskB.Href = taskURI(r.Host, task)
 	respond(201, taskB, w)
 }
 
 func (s *Server) getTasks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	sts := s.mt.GetTasks()
+	sts := scopedTasks(r, s.mt.GetTasks())
+
+	// the tasks can be filtered to those depending on a metric or a plugin
+	q := r.URL.Query()
+	if ns := q.Get("metric"); ns != "" {
+		ids, err := s.mm.TasksUsingMetric(ns)
+		if err != nil {
+			respond(404, rbody.FromError(err), w)
+			return
+		}
+		sts = filterTasks(sts, ids)
+	}
+	if name := q.Get("plugin"); name != "" {
+		ver := 0
+		if v := q.Get("plugin_version"); v != "" {
+			var err error
+			ver, err = strconv.Atoi(v)
+			if err != nil {
+				respond(400, rbody.FromError(err), w)
+				return
+			}
+		}
+		sts = filterTasks(sts, s.mm.TasksUsingPlugin(q.Get("plugin_type"), name, ver))
+	}
 
 	tasks := &rbody.ScheduledTaskListReturned{}
 	tasks.ScheduledTasks = make([]rbody.ScheduledTask, len(sts))
@@ -128,6 +161,17 @@ func (s *Server) getTasks(w http.ResponseWriter, r *http.Request, _ httprouter.P
 	respond(200, tasks, w)
 }
 
+// filterTasks keeps the tasks with the given IDs
+func filterTasks(sts map[string]core.Task, ids []string) map[string]core.Task {
+	filtered := make(map[string]core.Task, len(ids))
+	for _, id := range ids {
+		if t, ok := sts[id]; ok {
+			filtered[id] = t
+		}
+	}
+	return filtered
+}
+
 func (s *Server) getTask(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 	id := p.ByName("id")
 	t, err1 := s.mt.GetTask(id)
@@ -141,6 +185,32 @@ func (s *Server) getTask(w http.ResponseWriter, r *http.Request, p httprouter.Pa
 	respond(200, task, w)
 }
 
+func (s *Server) getTaskRuns(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("id")
+	t, err1 := s.mt.GetTask(id)
+	if err1 != nil {
+		respond(404, rbody.FromError(err1), w)
+		return
+	}
+	respond(200, rbody.TaskRunsFromTask(t), w)
+}
+
+func (s *Server) getTaskConfig(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("id")
+	rc, err := s.mt.ResolveTaskConfig(id)
+	if err != nil {
+		if strings.Contains(err.Error(), ErrTaskNotFound.Error()) {
+			respond(404, rbody.FromError(err), w)
+			return
+		}
+		respond(500, rbody.FromError(err), w)
+		return
+	}
+	// the sources of the values are reported on ?resolve=true
+	resolve, _ := strconv.ParseBool(r.URL.Query().Get("resolve"))
+	respond(200, rbody.TaskConfigFromResolved(id, rc, resolve), w)
+}
+
 func (s *Server) watchTask(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 	logger := log.WithFields(log.Fields{
 		"_module": "api",
@@ -153,11 +223,30 @@ func (s *Server) watchTask(w http.ResponseWriter, r *http.Request, p httprouter.
 	logger.WithFields(log.Fields{
 		"task-id": id,
 	}).Debug("request to watch task")
+	var opts core.TaskWatchOptions
+	q := r.URL.Query()
+	opts.LifecycleOnly = q.Get("lifecycle") == "true"
+	if v := q.Get("replay"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 0)
+		if err != nil {
+			respond(400, rbody.FromError(err), w)
+			return
+		}
+		if n > core.MaxTaskWatchReplay {
+			n = core.MaxTaskWatchReplay
+		}
+		opts.Replay = uint(n)
+	}
+	// The replayed events are caught while the task is being watched, before
+	// the events are streamed, so they are buffered
 	tw := &TaskWatchHandler{
-		alive: true,
-		mChan: make(chan rbody.StreamedTaskEvent),
+		alive:     true,
+		replaying: 1,
+		mChan:     make(chan rbody.StreamedTaskEvent, opts.Replay),
 	}
-	tc, err1 := s.mt.WatchTask(id, tw)
+	tc, err1 := s.mt.WatchTask(id, tw, opts)
+	// the events caught from now on are live
+	atomic.StoreInt32(&tw.replaying, 0)
 	if err1 != nil {
 		if strings.Contains(err1.Error(), ErrTaskNotFound.Error()) {
 			respond(404, rbody.FromError(err1), w)
@@ -199,12 +288,15 @@ func (s *Server) watchTask(w http.ResponseWriter, r *http.Request, p httprouter.
 				"task-id":            id,
 				"task-watcher-event": e.EventType,
 			}).Debug("new event")
-			switch e.EventType {
-			case rbody.TaskWatchMetricEvent, rbody.TaskWatchTaskStarted:
+			switch {
+			case e.Replayed:
+				// A replayed event does not end the streaming
+				fmt.Fprintf(w, "data: %s\n\n", e.ToJSON())
+			case e.EventType == rbody.TaskWatchMetricEvent, e.EventType == rbody.TaskWatchTaskStarted:
 				// The client can decide to stop receiving on the stream on Task Stopped.
 				// We write the event to the buffer
 				fmt.Fprintf(w, "data: %s\n\n", e.ToJSON())
-			case rbody.TaskWatchTaskDisabled, rbody.TaskWatchTaskStopped:
+			case e.EventType == rbody.TaskWatchTaskDisabled, e.EventType == rbody.TaskWatchTaskStopped:
 				// A disabled task should end the streaming and close the connection
 				fmt.Fprintf(w, "data: %s\n\n", e.ToJSON())
 				// Flush since we are sending nothing new
@@ -248,6 +340,7 @@ func (s *Server) startTask(w http.ResponseWriter, r *http.Request, p httprouter.
 		respond(500, rbody.FromSnapErrors(errs), w)
 		return
 	}
+	s.recordTaskUpdate(r, id, "start")
 	// TODO should return resource
 	respond(200, &rbody.ScheduledTaskStarted{ID: id}, w)
 }
@@ -263,9 +356,94 @@ func (s *Server) stopTask(w http.ResponseWriter, r *http.Request, p httprouter.P
 		respond(500, rbody.FromSnapErrors(errs), w)
 		return
 	}
+	s.recordTaskUpdate(r, id, "stop")
 	respond(200, &rbody.ScheduledTaskStopped{ID: id}, w)
 }
 
+func (s *Server) pauseTask(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("id")
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respond(500, rbody.FromError(err), w)
+		return
+	}
+	pr := request.TaskPauseRequest{}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &pr); err != nil {
+			fields := map[string]interface{}{
+				"error": err,
+				"hint":  `The body of the request should be empty or of the form '{"for": "2h"}'`,
+			}
+			respond(400, rbody.FromSnapError(serror.New(ErrInvalidJSON, fields)), w)
+			return
+		}
+	}
+	var d time.Duration
+	if pr.For != "" {
+		if d, err = time.ParseDuration(pr.For); err != nil {
+			respond(400, rbody.FromError(err), w)
+			return
+		}
+	}
+	errs := s.mt.PauseTask(id, d)
+	if errs != nil {
+		if strings.Contains(errs[0].Error(), ErrTaskNotFound.Error()) {
+			respond(404, rbody.FromSnapErrors(errs), w)
+			return
+		}
+		if strings.Contains(errs[0].Error(), ErrTaskNotRunning.Error()) {
+			respond(409, rbody.FromSnapErrors(errs), w)
+			return
+		}
+		respond(500, rbody.FromSnapErrors(errs), w)
+		return
+	}
+	s.recordTaskUpdate(r, id, "pause")
+	task := &rbody.ScheduledTaskPaused{ID: id}
+	if d > 0 {
+		task.PausedUntilTimestamp = time.Now().Add(d).Unix()
+	}
+	respond(200, task, w)
+}
+
+func (s *Server) resumeTask(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("id")
+	errs := s.mt.ResumeTask(id)
+	if errs != nil {
+		if strings.Contains(errs[0].Error(), ErrTaskNotFound.Error()) {
+			respond(404, rbody.FromSnapErrors(errs), w)
+			return
+		}
+		if strings.Contains(errs[0].Error(), ErrTaskNotPaused.Error()) {
+			respond(409, rbody.FromSnapErrors(errs), w)
+			return
+		}
+		respond(500, rbody.FromSnapErrors(errs), w)
+		return
+	}
+	s.recordTaskUpdate(r, id, "resume")
+	respond(200, &rbody.ScheduledTaskResumed{ID: id}, w)
+}
+
+func (s *Server) triggerTask(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("id")
+	errs := s.mt.TriggerTask(id)
+	if errs != nil {
+		if strings.Contains(errs[0].Error(), ErrTaskNotFound.Error()) {
+			respond(404, rbody.FromSnapErrors(errs), w)
+			return
+		}
+		if strings.Contains(errs[0].Error(), ErrTaskNotRunning.Error()) ||
+			strings.Contains(errs[0].Error(), ErrTaskNotTriggered.Error()) {
+			respond(409, rbody.FromSnapErrors(errs), w)
+			return
+		}
+		respond(500, rbody.FromSnapErrors(errs), w)
+		return
+	}
+	respond(200, &rbody.ScheduledTaskTriggered{ID: id}, w)
+}
+
 func (s *Server) removeTask(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 	id := p.ByName("id")
 	err := s.mt.RemoveTask(id)
@@ -277,9 +455,66 @@ func (s *Server) removeTask(w http.ResponseWriter, r *http.Request, p httprouter
 		respond(500, rbody.FromError(err), w)
 		return
 	}
+	// the task is the deleted one from then on, and it records who deleted it
+	if d, err := s.mt.GetDeletedTask(id); err == nil {
+		d.Task.RecordUpdate(principal(r), time.Now())
+	}
+	logTaskChange(r, id, "remove")
 	respond(200, &rbody.ScheduledTaskRemoved{ID: id}, w)
 }
 
+func (s *Server) getDeletedTasks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	dts := s.mt.GetDeletedTasks()
+	if tn := tenant(r); tn != "" {
+		var scoped []core.DeletedTask
+		for _, d := range dts {
+			if d.Task.Tenant() == tn {
+				scoped = append(scoped, d)
+			}
+		}
+		dts = scoped
+	}
+	tasks := &rbody.DeletedTaskListReturned{}
+	tasks.DeletedTasks = make([]rbody.ScheduledTask, len(dts))
+	for i, d := range dts {
+		tasks.DeletedTasks[i] = *rbody.DeletedTaskFromTask(d)
+		tasks.DeletedTasks[i].Href = deletedTaskURI(r.Host, d.Task)
+	}
+	sort.Sort(tasks)
+	respond(200, tasks, w)
+}
+
+func (s *Server) getDeletedTask(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	d, err := s.mt.GetDeletedTask(p.ByName("id"))
+	if err != nil {
+		respond(404, rbody.FromError(err), w)
+		return
+	}
+	task := &rbody.DeletedTaskReturned{}
+	task.AddScheduledTask = *rbody.AddDeletedTaskFromTask(d)
+	task.Href = deletedTaskURI(r.Host, d.Task)
+	respond(200, task, w)
+}
+
+// restoreTask brings a deleted task back, stopped
+func (s *Server) restoreTask(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("id")
+	tsk, err := s.mt.RestoreTask(id)
+	if err != nil {
+		if strings.Contains(err.Error(), ErrTaskNotFound.Error()) {
+			respond(404, rbody.FromError(err), w)
+			return
+		}
+		respond(quotaCode([]serror.SnapError{serror.New(err)}, 500), rbody.FromError(err), w)
+		return
+	}
+	s.recordTaskUpdate(r, id, "restore")
+	task := &rbody.ScheduledTaskRestored{}
+	task.AddScheduledTask = *rbody.AddSchedulerTaskFromTask(tsk)
+	task.Href = taskURI(r.Host, tsk)
+	respond(200, task, w)
+}
+
 //enableTask changes the task state from Disabled to Stopped
 func (s *Server) enableTask(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 	id := p.ByName("id")
@@ -292,11 +527,145 @@ func (s *Server) enableTask(w http.ResponseWriter, r *http.Request, p httprouter
 		respond(500, rbody.FromError(err), w)
 		return
 	}
+	s.recordTaskUpdate(r, id, "enable")
 	task := &rbody.ScheduledTaskEnabled{}
 	task.AddScheduledTask = *rbody.AddSchedulerTaskFromTask(tsk)
 	respond(200, task, w)
 }
 
+func (s *Server) updateTask(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("id")
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respond(500, rbody.FromError(err), w)
+		return
+	}
+	tr := request.TaskUpdateRequest{}
+	if err := json.Unmarshal(b, &tr); err != nil {
+		fields := map[string]interface{}{
+			"error": err,
+			"hint":  `The body of the request should be of the form '{"interval": "5s", "config": {"/intel/mock": {"password": "secret"}}, "add_metrics": {"/intel/mock/bar": 0}, "remove_metrics": ["/intel/mock/foo"]}'`,
+		}
+		respond(400, rbody.FromSnapError(serror.New(ErrInvalidJSON, fields)), w)
+		return
+	}
+	u := core.TaskUpdate{
+		Config:        tr.Config,
+		AddMetrics:    tr.AddMetrics,
+		RemoveMetrics: tr.RemoveMetrics,
+		Description:   tr.Description,
+	}
+	if tr.Interval != "" {
+		if u.Interval, err = time.ParseDuration(tr.Interval); err != nil {
+			respond(400, rbody.FromError(err), w)
+			return
+		}
+	}
+
+	tsk, errs := s.mt.UpdateTask(id, u)
+	if errs != nil && len(errs.Errors()) != 0 {
+		code := quotaCode(errs.Errors(), 400)
+		if strings.Contains(errs.Errors()[0].Error(), ErrTaskNotFound.Error()) {
+			code = 404
+		}
+		respond(code, rbody.FromSnapErrors(errs.Errors()), w)
+		return
+	}
+	s.recordTaskUpdate(r, id, "update")
+	task := &rbody.ScheduledTaskUpdated{}
+	task.AddScheduledTask = *rbody.AddSchedulerTaskFromTask(tsk)
+	task.Href = taskURI(r.Host, tsk)
+	respond(200, task, w)
+}
+
+// principal returns the principal of the API making the request: the one
+// it was authenticated as, or anonymous
+func principal(r *http.Request) string {
+	if p, ok := PrincipalFromRequest(r); ok {
+		return p.Name
+	}
+	return anonymousPrincipal
+}
+
+// recordTaskUpdate records the principal making the request as the last one
+// to change the task, and logs the change
+func (s *Server) recordTaskUpdate(r *http.Request, id, change string) {
+	if t, err := s.mt.GetTask(id); err == nil {
+		t.RecordUpdate(principal(r), time.Now())
+	}
+	logTaskChange(r, id, change)
+}
+
+// quotaCode returns the code answered for errors holding an exceeded quota:
+// 429 when the other tasks leave no room for the task, 403 when the task
+// exceeds the quota on its own. Otherwise code is returned.
+func quotaCode(errs []serror.SnapError, code int) int {
+	for _, e := range errs {
+		switch {
+		case strings.Contains(e.Error(), core.ErrQuotaExceeded.Error()):
+			return 429
+		case strings.Contains(e.Error(), core.ErrTaskOverQuota.Error()):
+			return 403
+		}
+	}
+	return code
+}
+
+// logTaskChange logs a change made to a task through the API, for the
+// changes to be traced back to the principals making them
+func logTaskChange(r *http.Request, id, change string) {
+	restLogger.WithFields(log.Fields{
+		"_block":      "task-change",
+		"task-id":     id,
+		"change":      change,
+		"principal":   principal(r),
+		"remote-addr": r.RemoteAddr,
+	}).Info("task changed")
+}
+
+func makeAlertRules(ars []request.AlertRule) ([]core.AlertRule, error) {
+	rules := make([]core.AlertRule, len(ars))
+	names := map[string]bool{}
+	for i, ar := range ars {
+		var d time.Duration
+		if ar.For != "" {
+			var err error
+			if d, err = time.ParseDuration(ar.For); err != nil {
+				return nil, fmt.Errorf("alert rule %s: %v", ar.Name, err)
+			}
+		}
+		if names[ar.Name] {
+			return nil, fmt.Errorf("alert rule %s: duplicate name", ar.Name)
+		}
+		names[ar.Name] = true
+		r, err := core.NewAlertRule(ar.Name, ar.Expression, d, ar.Severity)
+		if err != nil {
+			return nil, err
+		}
+		rules[i] = r
+	}
+	return rules, nil
+}
+
+func makeTimestampPolicy(tp request.TimestampPolicy) (core.TimestampPolicy, error) {
+	policy := core.TimestampPolicy{
+		Source:     tp.Source,
+		OutOfRange: tp.OutOfRange,
+	}
+	var err error
+	if tp.MaxFuture != "" {
+		if policy.MaxFuture, err = time.ParseDuration(tp.MaxFuture); err != nil {
+			return policy, fmt.Errorf("timestamps max_future: %v", err)
+		}
+	}
+	if tp.MaxPast != "" {
+		if policy.MaxPast, err = time.ParseDuration(tp.MaxPast); err != nil {
+			return policy, fmt.Errorf("timestamps max_past: %v", err)
+		}
+	}
+	return policy, core.ValidateTimestampPolicy(policy)
+}
+
 func marshalTask(body io.ReadCloser) (*request.TaskCreationRequest, error) {
 	var tr request.TaskCreationRequest
 	errCode, err := marshalBody(&tr, body)
@@ -306,7 +675,82 @@ func marshalTask(body io.ReadCloser) (*request.TaskCreationRequest, error) {
 	return &tr, nil
 }
 
-func makeSchedule(s request.Schedule) (cschedule.Schedule, error) {
+// MakeTaskOptions returns the options of the task created by the request.
+func MakeTaskOptions(tr *request.TaskCreationRequest) ([]core.TaskOption, error) {
+	var opts []core.TaskOption
+	if tr.Deadline != "" {
+		dl, err := time.ParseDuration(tr.Deadline)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, core.TaskDeadlineDuration(dl))
+	}
+
+	if tr.Name != "" {
+		opts = append(opts, core.SetTaskName(tr.Name))
+	}
+	if tr.Description != "" {
+		opts = append(opts, core.OptionTaskDescription(tr.Description))
+	}
+	opts = append(opts, core.OptionStopOnFailure(10))
+	if tr.Schedule.Overrun != "" {
+		if err := core.ValidateOverrunPolicy(tr.Schedule.Overrun, tr.Schedule.OverrunQueueDepth); err != nil {
+			return nil, err
+		}
+		opts = append(opts, core.OptionOverrunPolicy(tr.Schedule.Overrun, tr.Schedule.OverrunQueueDepth))
+	}
+	if tr.Shard {
+		opts = append(opts, core.OptionTaskSharded(true))
+	}
+	if tr.MetricRefreshInterval != "" {
+		interval, err := time.ParseDuration(tr.MetricRefreshInterval)
+		if err != nil {
+			return nil, err
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("metric_refresh_interval must be greater than 0")
+		}
+		opts = append(opts, core.OptionMetricRefreshInterval(interval))
+	}
+
+	if tr.Priority != "" {
+		if err := core.ValidateTaskPriority(tr.Priority); err != nil {
+			return nil, err
+		}
+		opts = append(opts, core.OptionTaskPriority(tr.Priority))
+	}
+	if tr.Timestamps != nil {
+		policy, err := makeTimestampPolicy(*tr.Timestamps)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, core.OptionTimestampPolicy(policy))
+	}
+	if len(tr.Alerts) > 0 {
+		rules, err := makeAlertRules(tr.Alerts)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, core.OptionAlertRules(rules))
+	}
+	if len(tr.DependsOn) > 0 {
+		deps := make([]core.TaskDependency, len(tr.DependsOn))
+		for i, d := range tr.DependsOn {
+			deps[i] = core.TaskDependency{TaskID: d.TaskID, On: d.On}
+			if deps[i].On == "" {
+				deps[i].On = core.DependOnSuccess
+			}
+			if err := core.ValidateTaskDependency(deps[i]); err != nil {
+				return nil, err
+			}
+		}
+		opts = append(opts, core.OptionTaskDependencies(deps))
+	}
+	return opts, nil
+}
+
+// MakeSchedule returns the validated schedule of a task creation request
+func MakeSchedule(s request.Schedule) (cschedule.Schedule, error) {
 	switch s.Type {
 	case "simple":
 		d, err := time.ParseDuration(s.Interval)
@@ -314,6 +758,12 @@ func makeSchedule(s request.Schedule) (cschedule.Schedule, error) {
 			return nil, err
 		}
 		sch := cschedule.NewSimpleSchedule(d)
+		if s.Jitter != "" {
+			sch.Jitter, err = time.ParseDuration(s.Jitter)
+			if err != nil {
+				return nil, err
+			}
+		}
 
 		err = sch.Validate()
 		if err != nil {
@@ -357,6 +807,21 @@ func makeSchedule(s request.Schedule) (cschedule.Schedule, error) {
 			return nil, err
 		}
 		return sch, nil
+	case "trigger":
+		sources := make([]cschedule.TriggerSource, len(s.Triggers))
+		for i, t := range s.Triggers {
+			src, err := cschedule.NewTriggerSource(cschedule.TriggerConfig{
+				Type:    t.Type,
+				Path:    t.Path,
+				URL:     t.URL,
+				Subject: t.Subject,
+			})
+			if err != nil {
+				return nil, err
+			}
+			sources[i] = src
+		}
+		return cschedule.NewTriggerSchedule(sources...), nil
 	default:
 		return nil, errors.New("unknown schedule type " + s.Type)
 	}
@@ -365,7 +830,14 @@ func makeSchedule(s request.Schedule) (cschedule.Schedule, error) {
 type TaskWatchHandler struct {
 	streamCount int
 	alive       bool
-	mChan       chan rbody.StreamedTaskEvent
+	// replaying is 1 while the past events are replayed, it is read by the
+	// task catching live events concurrently
+	replaying int32
+	mChan     chan rbody.StreamedTaskEvent
+}
+
+func (t *TaskWatchHandler) replayed() bool {
+	return atomic.LoadInt32(&t.replaying) == 1
 }
 
 func (t *TaskWatchHandler) CatchCollection(m []core.Metric) {
@@ -378,6 +850,10 @@ func (t *TaskWatchHandler) CatchCollection(m []core.Metric) {
 			Timestamp: m[i].Timestamp(),
 			Tags:      m[i].Tags(),
 		}
+		if h, ok := m[i].(core.HostedMetric); ok {
+			host := h.Host()
+			sm[i].Host = &host
+		}
 	}
 	t.mChan <- rbody.StreamedTaskEvent{
 		EventType: rbody.TaskWatchMetricEvent,
@@ -389,12 +865,14 @@ func (t *TaskWatchHandler) CatchCollection(m []core.Metric) {
 func (t *TaskWatchHandler) CatchTaskStarted() {
 	t.mChan <- rbody.StreamedTaskEvent{
 		EventType: rbody.TaskWatchTaskStarted,
+		Replayed:  t.replayed(),
 	}
 }
 
 func (t *TaskWatchHandler) CatchTaskStopped() {
 	t.mChan <- rbody.StreamedTaskEvent{
 		EventType: rbody.TaskWatchTaskStopped,
+		Replayed:  t.replayed(),
 	}
 }
 
@@ -402,9 +880,14 @@ func (t *TaskWatchHandler) CatchTaskDisabled(why string) {
 	t.mChan <- rbody.StreamedTaskEvent{
 		EventType: rbody.TaskWatchTaskDisabled,
 		Message:   why,
+		Replayed:  t.replayed(),
 	}
 }
 
 func taskURI(host string, t core.Task) string {
 	return fmt.Sprintf("%s://%s/v1/tasks/%s", protocolPrefix, host, t.ID())
 }
+
+func deletedTaskURI(host string, t core.Task) string {
+	return fmt.Sprintf("%s://%s/v1/deleted_tasks/%s", protocolPrefix, host, t.ID())
+}
@@ -0,0 +1,99 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+// Formats the task graph can be returned in
+const (
+	GraphFormatJSON = "json"
+	GraphFormatDOT  = "dot"
+
+	dotContentType = "text/vnd.graphviz; charset=utf-8"
+)
+
+var ErrUnknownGraphFormat = errors.New("unknown graph format (json or dot)")
+
+// getTaskGraph returns the graph of the dependencies between the tasks. Only
+// the tasks which depend on or are depended on by another task are in it.
+func (s *Server) getTaskGraph(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	graph := &rbody.TaskGraphReturned{
+		Nodes: []rbody.TaskGraphNode{},
+		Edges: []rbody.TaskGraphEdge{},
+	}
+	tasks := scopedTasks(r, s.mt.GetTasks())
+	nodes := map[string]bool{}
+	addNode := func(id string) {
+		if nodes[id] {
+			return
+		}
+		nodes[id] = true
+		n := rbody.TaskGraphNode{ID: id, State: "Not found"}
+		if t, ok := tasks[id]; ok {
+			n.Name, n.State = t.GetName(), t.State().String()
+		}
+		graph.Nodes = append(graph.Nodes, n)
+	}
+	for id, t := range tasks {
+		for _, d := range t.Dependencies() {
+			addNode(d.TaskID)
+			addNode(id)
+			graph.Edges = append(graph.Edges, rbody.TaskGraphEdge{From: d.TaskID, To: id, On: d.On})
+		}
+	}
+	sort.Sort(graphNodesByID(graph.Nodes))
+	sort.Sort(graphEdgesByID(graph.Edges))
+
+	switch r.URL.Query().Get("format") {
+	case "", GraphFormatJSON:
+		respond(200, graph, w)
+	case GraphFormatDOT:
+		w.Header().Set("Content-Type", dotContentType)
+		w.WriteHeader(200)
+		w.Write([]byte(graph.DOT()))
+	default:
+		respond(400, rbody.FromError(ErrUnknownGraphFormat), w)
+	}
+}
+
+type graphNodesByID []rbody.TaskGraphNode
+
+func (g graphNodesByID) Len() int           { return len(g) }
+func (g graphNodesByID) Less(i, j int) bool { return g[i].ID < g[j].ID }
+func (g graphNodesByID) Swap(i, j int)      { g[i], g[j] = g[j], g[i] }
+
+type graphEdgesByID []rbody.TaskGraphEdge
+
+func (g graphEdgesByID) Len() int { return len(g) }
+func (g graphEdgesByID) Less(i, j int) bool {
+	if g[i].From != g[j].From {
+		return g[i].From < g[j].From
+	}
+	return g[i].To < g[j].To
+}
+func (g graphEdgesByID) Swap(i, j int) { g[i], g[j] = g[j], g[i] }
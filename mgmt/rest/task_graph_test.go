@@ -0,0 +1,95 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type graphTask struct {
+	core.Task
+	id   string
+	deps []core.TaskDependency
+}
+
+func (t *graphTask) ID() string                          { return t.id }
+func (t *graphTask) GetName() string                     { return "Task-" + t.id }
+func (t *graphTask) State() core.TaskState               { return core.TaskSpinning }
+func (t *graphTask) Dependencies() []core.TaskDependency { return t.deps }
+
+type graphTaskManager struct {
+	managesTasks
+}
+
+func (m *graphTaskManager) GetTasks() map[string]core.Task {
+	return map[string]core.Task{
+		"a": &graphTask{id: "a"},
+		"b": &graphTask{id: "b", deps: []core.TaskDependency{{TaskID: "a", On: core.DependOnSuccess}}},
+		"c": &graphTask{id: "c", deps: []core.TaskDependency{{TaskID: "b", On: core.DependOnFailure}, {TaskID: "x", On: core.DependOnCompletion}}},
+		"d": &graphTask{id: "d"},
+	}
+}
+
+func TestTaskGraph(t *testing.T) {
+	Convey("The task graph", t, func() {
+		s, err := New(GetDefaultConfig())
+		So(err, ShouldBeNil)
+		s.BindTaskManager(&graphTaskManager{})
+		s.addRoutes()
+		get := func(uri string) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", uri, nil)
+			s.n.ServeHTTP(rec, req)
+			return rec
+		}
+		Convey("holds the tasks chained by their dependencies", func() {
+			rec := get("/v1/task_graph")
+			So(rec.Code, ShouldEqual, 200)
+			var resp struct {
+				Body rbody.TaskGraphReturned `json:"body"`
+			}
+			So(json.Unmarshal(rec.Body.Bytes(), &resp), ShouldBeNil)
+			g := resp.Body
+			So(g.Nodes, ShouldHaveLength, 4)
+			So(g.Nodes[0], ShouldResemble, rbody.TaskGraphNode{ID: "a", Name: "Task-a", State: "Running"})
+			So(g.Nodes[3], ShouldResemble, rbody.TaskGraphNode{ID: "x", State: "Not found"})
+			So(g.Edges, ShouldResemble, []rbody.TaskGraphEdge{
+				{From: "a", To: "b", On: "success"},
+				{From: "b", To: "c", On: "failure"},
+				{From: "x", To: "c", On: "completion"},
+			})
+		})
+		Convey("is rendered in the DOT language", func() {
+			rec := get("/v1/task_graph?format=dot")
+			So(rec.Code, ShouldEqual, 200)
+			So(rec.Header().Get("Content-Type"), ShouldStartWith, "text/vnd.graphviz")
+			So(strings.HasPrefix(rec.Body.String(), "digraph tasks {\n"), ShouldBeTrue)
+			So(rec.Body.String(), ShouldContainSubstring, `"a" -> "b" [label="success"];`)
+			So(get("/v1/task_graph?format=svg").Code, ShouldEqual, 400)
+		})
+	})
+}
@@ -0,0 +1,175 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+// ErrTenantForbidden is returned to the principals scoped to a tenant for
+// the requests changing the whole of snapd
+var ErrTenantForbidden = errors.New("Forbidden to the principals scoped to a tenant")
+
+// validateTenantPrincipals returns the problems found in the principals of
+// the tenants, a principal or a group being scoped to one tenant at most
+func validateTenantPrincipals(tenants map[string]TenantPrincipals) []error {
+	ids := make([]string, 0, len(tenants))
+	for id := range tenants {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var errs []error
+	principals, groups := map[string]string{}, map[string]string{}
+	for _, id := range ids {
+		if id == "" {
+			errs = append(errs, fmt.Errorf("restapi.auth.tenants: tenant IDs must not be empty"))
+			continue
+		}
+		for _, name := range tenants[id].Principals {
+			if other, ok := principals[name]; ok && other != id {
+				errs = append(errs, fmt.Errorf("restapi.auth.tenants.%s.principals: %s is already scoped to %s", id, name, other))
+			}
+			principals[name] = id
+		}
+		for _, group := range tenants[id].Groups {
+			if other, ok := groups[group]; ok && other != id {
+				errs = append(errs, fmt.Errorf("restapi.auth.tenants.%s.groups: %s is already scoped to %s", id, group, other))
+			}
+			groups[group] = id
+		}
+	}
+	return errs
+}
+
+// tenantOf returns the tenant the principal is scoped to by name or, failing
+// that, by the first of its groups scoped to one
+func tenantOf(tenants map[string]TenantPrincipals, p *Principal) string {
+	for id, tp := range tenants {
+		for _, name := range tp.Principals {
+			if name == p.Name {
+				return id
+			}
+		}
+	}
+	for _, g := range p.Groups {
+		for id, tp := range tenants {
+			for _, group := range tp.Groups {
+				if g == group {
+					return id
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// tenant returns the tenant the principal making the request is scoped to,
+// empty when it is not
+func tenant(r *http.Request) string {
+	if p, ok := PrincipalFromRequest(r); ok {
+		return p.Tenant
+	}
+	return ""
+}
+
+// TenantNames returns the IDs of the tenants the principals are scoped to
+func (c *AuthConfig) TenantNames() []string {
+	if c == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(c.Tenants))
+	for id := range c.Tenants {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// scopedTask wraps the handler of a route of a task, answering a principal
+// scoped to a tenant as if the tasks of the other tenants did not exist
+func (s *Server) scopedTask(h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		if tn := tenant(r); tn != "" {
+			id := p.ByName("id")
+			var t core.Task
+			if st, err := s.mt.GetTask(id); err == nil {
+				t = st
+			} else if d, err := s.mt.GetDeletedTask(id); err == nil {
+				t = d.Task
+			}
+			if t != nil && t.Tenant() != tn {
+				respond(404, rbody.FromError(fmt.Errorf("%v: %s", ErrTaskNotFound, id)), w)
+				return
+			}
+		}
+		h(w, r, p)
+	}
+}
+
+// unscoped wraps the handler of a route changing the whole of snapd, such as
+// loading plugins, which the principals scoped to a tenant are forbidden
+func unscoped(h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		if tenant(r) != "" {
+			respond(403, rbody.FromError(ErrTenantForbidden), w)
+			return
+		}
+		h(w, r, p)
+	}
+}
+
+// scopedTasks returns the tasks of the tenant of the request
+func scopedTasks(r *http.Request, tasks map[string]core.Task) map[string]core.Task {
+	tn := tenant(r)
+	if tn == "" {
+		return tasks
+	}
+	scoped := make(map[string]core.Task, len(tasks))
+	for id, t := range tasks {
+		if t.Tenant() == tn {
+			scoped[id] = t
+		}
+	}
+	return scoped
+}
+
+// visibleMetrics returns the metrics of the catalog the tenant of the
+// request sees
+func (s *Server) visibleMetrics(r *http.Request, mets []core.CatalogedMetric) []core.CatalogedMetric {
+	tn := tenant(r)
+	if tn == "" {
+		return mets
+	}
+	visible := make([]core.CatalogedMetric, 0, len(mets))
+	for _, m := range mets {
+		if s.mm.MetricVisible(tn, m.Namespace()) {
+			visible = append(visible, m)
+		}
+	}
+	return visible
+}
@@ -0,0 +1,231 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type tenantTask struct {
+	core.Task
+	id     string
+	tenant string
+	deps   []core.TaskDependency
+}
+
+func (t *tenantTask) ID() string                          { return t.id }
+func (t *tenantTask) GetName() string                     { return "Task-" + t.id }
+func (t *tenantTask) State() core.TaskState               { return core.TaskSpinning }
+func (t *tenantTask) Dependencies() []core.TaskDependency { return t.deps }
+func (t *tenantTask) Tenant() string                      { return t.tenant }
+func (t *tenantTask) RecordUpdate(string, time.Time)      {}
+
+type tenantTaskManager struct {
+	managesTasks
+	tasks   map[string]core.Task
+	started []string
+}
+
+func (m *tenantTaskManager) GetTasks() map[string]core.Task {
+	return m.tasks
+}
+
+func (m *tenantTaskManager) GetTask(id string) (core.Task, error) {
+	if t, ok := m.tasks[id]; ok {
+		return t, nil
+	}
+	return nil, ErrTaskNotFound
+}
+
+func (m *tenantTaskManager) GetDeletedTask(string) (core.DeletedTask, error) {
+	return core.DeletedTask{}, ErrTaskNotFound
+}
+
+func (m *tenantTaskManager) StartTask(id string) []serror.SnapError {
+	m.started = append(m.started, id)
+	return nil
+}
+
+type tenantAlerts struct{}
+
+func (a *tenantAlerts) ActiveAlerts() []core.Alert {
+	return nil
+}
+
+// tenantMetricManager lets the tenant team-a see the metrics of the mock
+// plugin only
+type tenantMetricManager struct {
+	managesMetrics
+}
+
+func (m *tenantMetricManager) MetricCatalog() ([]core.CatalogedMetric, error) {
+	return []core.CatalogedMetric{
+		mockCatalogedMetric{ns: []string{"intel", "mock", "foo"}, ver: 1},
+		mockCatalogedMetric{ns: []string{"intel", "psutil", "load"}, ver: 1},
+	}, nil
+}
+
+func (m *tenantMetricManager) MetricVisible(tenant string, ns []string) bool {
+	return tenant == "" || (tenant == "team-a" && ns[1] == "mock")
+}
+
+func TestTenants(t *testing.T) {
+	Convey("Given a REST API scoping principals to tenants", t, func() {
+		cfg := GetDefaultConfig()
+		cfg.Auth.Tokens = []TokenConfig{
+			{Name: "alice", Token: "a"},
+			{Name: "bob", Token: "b", Groups: []string{"team-b-ops"}},
+			{Name: "root", Token: "r"},
+		}
+		cfg.Auth.Tenants = map[string]TenantPrincipals{
+			"team-a": {Principals: []string{"alice"}},
+			"team-b": {Groups: []string{"team-b-ops"}},
+		}
+		So(cfg.Auth.Validate(), ShouldBeEmpty)
+		s, err := New(cfg)
+		So(err, ShouldBeNil)
+		mt := &tenantTaskManager{tasks: map[string]core.Task{
+			"a1": &tenantTask{id: "a1", tenant: "team-a"},
+			"a2": &tenantTask{id: "a2", tenant: "team-a", deps: []core.TaskDependency{{TaskID: "a1", On: core.DependOnSuccess}}},
+			"b1": &tenantTask{id: "b1", tenant: "team-b"},
+			"b2": &tenantTask{id: "b2", tenant: "team-b", deps: []core.TaskDependency{{TaskID: "b1", On: core.DependOnSuccess}}},
+		}}
+		s.BindTaskManager(mt)
+		s.BindMetricManager(&tenantMetricManager{})
+		s.BindAlertManager(&tenantAlerts{})
+		s.BindLogBuffer(&statusLogs{})
+		s.addRoutes()
+		do := func(method, uri, token string) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(method, uri, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			s.n.ServeHTTP(rec, req)
+			return rec
+		}
+
+		Convey("principals are scoped to tenants by name or group", func() {
+			req := httptest.NewRequest("GET", "/v1/tasks", nil)
+			req.Header.Set("Authorization", "Bearer a")
+			_, p := authenticated(s, req)
+			So(p.Tenant, ShouldEqual, "team-a")
+			req.Header.Set("Authorization", "Bearer b")
+			_, p = authenticated(s, req)
+			So(p.Tenant, ShouldEqual, "team-b")
+			req.Header.Set("Authorization", "Bearer r")
+			_, p = authenticated(s, req)
+			So(p.Tenant, ShouldEqual, "")
+		})
+		Convey("a tenant only sees its own tasks", func() {
+			graph := func(token string) []rbody.TaskGraphNode {
+				rec := do("GET", "/v1/task_graph", token)
+				So(rec.Code, ShouldEqual, 200)
+				var resp struct {
+					Body rbody.TaskGraphReturned `json:"body"`
+				}
+				So(json.Unmarshal(rec.Body.Bytes(), &resp), ShouldBeNil)
+				return resp.Body.Nodes
+			}
+			nodes := graph("a")
+			So(nodes, ShouldHaveLength, 2)
+			So(nodes[0].ID, ShouldEqual, "a1")
+			So(graph("r"), ShouldHaveLength, 4)
+		})
+		Convey("the tasks of another tenant are not found", func() {
+			So(do("PUT", "/v1/tasks/b1/start", "a").Code, ShouldEqual, 404)
+			So(mt.started, ShouldBeEmpty)
+			So(do("PUT", "/v1/tasks/b1/start", "b").Code, ShouldEqual, 200)
+			So(do("PUT", "/v1/tasks/b1/start", "r").Code, ShouldEqual, 200)
+			So(mt.started, ShouldResemble, []string{"b1", "b1"})
+		})
+		Convey("changing the whole of snapd is forbidden to tenants", func() {
+			So(do("POST", "/v1/plugins", "a").Code, ShouldEqual, 403)
+			So(do("PUT", "/v1/system/maintenance", "b").Code, ShouldEqual, 403)
+		})
+		Convey("the global state of snapd is forbidden to tenants", func() {
+			for _, route := range [][2]string{
+				{"GET", "/v1/plugins/collector/mock/1/config"},
+				{"POST", "/v1/plugins/processor/passthru/1/process"},
+				{"GET", "/v1/logs"},
+				{"GET", "/v1/alerts"},
+				{"GET", "/status"},
+			} {
+				So(do(route[0], route[1], "a").Code, ShouldEqual, 403)
+			}
+			So(do("GET", "/v1/alerts", "r").Code, ShouldEqual, 200)
+		})
+		Convey("the catalog holds the metrics the tenant sees", func() {
+			catalog := func(token string) rbody.MetricCatalogExported {
+				rec := do("GET", "/v1/catalog", token)
+				So(rec.Code, ShouldEqual, 200)
+				var resp struct {
+					Body rbody.MetricCatalogExported `json:"body"`
+				}
+				So(json.Unmarshal(rec.Body.Bytes(), &resp), ShouldBeNil)
+				return resp.Body
+			}
+			So(catalog("a"), ShouldHaveLength, 1)
+			So(catalog("a")[0].Namespace, ShouldEqual, "/intel/mock/foo")
+			So(catalog("b"), ShouldBeEmpty)
+			So(catalog("r"), ShouldHaveLength, 2)
+		})
+	})
+	Convey("A principal is scoped to one tenant at most", t, func() {
+		errs := validateTenantPrincipals(map[string]TenantPrincipals{
+			"team-a": {Principals: []string{"alice"}, Groups: []string{"ops"}},
+			"team-b": {Principals: []string{"alice"}, Groups: []string{"ops"}},
+			"":       {},
+		})
+		So(errs, ShouldHaveLength, 3)
+		So(errs[0].Error(), ShouldEqual, "restapi.auth.tenants: tenant IDs must not be empty")
+		So(errs[1].Error(), ShouldEqual, "restapi.auth.tenants.team-b.principals: alice is already scoped to team-a")
+	})
+}
+
+func TestQuotaCode(t *testing.T) {
+	Convey("Exceeded quotas are answered", t, func() {
+		over := func(err error) []serror.SnapError {
+			return []serror.SnapError{serror.New(fmt.Errorf("%v: tenant team-a", err))}
+		}
+		So(quotaCode(over(core.ErrQuotaExceeded), 500), ShouldEqual, 429)
+		So(quotaCode(over(core.ErrTaskOverQuota), 500), ShouldEqual, 403)
+		So(quotaCode(over(ErrTaskNotFound), 500), ShouldEqual, 500)
+	})
+	Convey("The quotas of the principals must be valid", t, func() {
+		cfg := &AuthConfig{Quotas: map[string]*core.Quota{"ci": {MaxMetrics: -1}}}
+		So(cfg.Validate(), ShouldHaveLength, 1)
+	})
+	Convey("The principals verifying no identity have no quota", t, func() {
+		for _, name := range []string{passwordPrincipal, anonymousPrincipal} {
+			cfg := &AuthConfig{Quotas: map[string]*core.Quota{name: {MaxTasks: 1}}}
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Error(), ShouldStartWith, "restapi.auth.quotas."+name)
+		}
+	})
+}
@@ -31,12 +31,12 @@ import (
 	"time"
 )
 
-type tls struct {
+type tlsCert struct {
 	cert, key string
 }
 
-func newtls(certPath, keyPath string) (*tls, error) {
-	t := &tls{}
+func newtls(certPath, keyPath string) (*tlsCert, error) {
+	t := &tlsCert{}
 	if certPath != "" && keyPath != "" {
 		cert, err := os.Open(certPath)
 		if err != nil {
@@ -78,7 +78,7 @@ func newtls(certPath, keyPath string) (*tls, error) {
 	return t, nil
 }
 
-func generateCert(t *tls) error {
+func generateCert(t *tlsCert) error {
 	// good for 1 year
 	notBefore := time.Now()
 	notAfter := notBefore.Add(time.Hour * 24 * 365)
@@ -102,7 +102,7 @@ func generateCert(t *tls) error {
 		BasicConstraintsValid: true,
 	}
 
-	priv, err := rsa.GenerateKey(rand.Reader, 1024)
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
 		return err
 	}
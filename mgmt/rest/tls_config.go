@@ -0,0 +1,65 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/codegangsta/negroni"
+)
+
+// newTLSConfig returns the TLS configuration of the REST API over HTTPS:
+// the versions and cipher suites of the TLS policy, and the certificates of
+// the clients verified against the CA certificates of the configuration.
+func newTLSConfig(cfg *Config) (*tls.Config, error) {
+	tc := &tls.Config{}
+	cfg.TLSPolicy.Apply(tc)
+	if cfg.RestClientCACertificate == "" {
+		return tc, nil
+	}
+	b, err := ioutil.ReadFile(cfg.RestClientCACertificate)
+	if err != nil {
+		return nil, err
+	}
+	tc.ClientCAs = x509.NewCertPool()
+	if !tc.ClientCAs.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificate found in %s", cfg.RestClientCACertificate)
+	}
+	tc.ClientAuth = tls.VerifyClientCertIfGiven
+	if cfg.RequireClientCert {
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tc, nil
+}
+
+// hsts returns the middleware telling the browsers to only reach the REST
+// API over HTTPS for maxAge
+func hsts(maxAge time.Duration) negroni.HandlerFunc {
+	v := fmt.Sprintf("max-age=%d", int64(maxAge/time.Second))
+	return func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		rw.Header().Set("Strict-Transport-Security", v)
+		next(rw, r)
+	}
+}
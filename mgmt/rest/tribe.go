@@ -24,11 +24,15 @@ import (
 	"errors"
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 
+	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/serror"
 	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
+	"github.com/intelsdi-x/snap/mgmt/tribe/agreement"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -37,11 +41,46 @@ var (
 		"_module": "rest-tribe",
 	})
 
-	ErrInvalidJSON           = errors.New("Invalid JSON")
-	ErrAgreementDoesNotExist = errors.New("Agreement not found")
-	ErrMemberNotFound        = errors.New("Member not found")
+	ErrInvalidJSON             = errors.New("Invalid JSON")
+	ErrAgreementDoesNotExist   = errors.New("Agreement not found")
+	ErrAgreementAlreadyExists  = errors.New("Agreement already exists")
+	ErrMemberNotFound          = errors.New("Member not found")
+	ErrAgreementTaskNotFound   = errors.New("Task not found in agreement")
+	ErrAgreementTaskExists     = errors.New("Task already in agreement")
+	ErrAgreementPluginNotFound = errors.New("Plugin not found in agreement")
+	ErrAgreementPluginExists   = errors.New("Plugin already in agreement")
 )
 
+// The tribe endpoints respond with a 404 when the agreement, member, task or
+// plugin of the request does not exist, a 409 when what is added exists
+// already and a 400 when the body of the request is invalid.
+
+// agreementExists responds with a 404 unless the agreement exists.
+func (s *Server) agreementExists(name string, w http.ResponseWriter) bool {
+	if _, ok := s.tr.GetAgreements()[name]; !ok {
+		fields := map[string]interface{}{
+			"agreement_name": name,
+		}
+		tribeLogger.WithFields(fields).Error(ErrAgreementDoesNotExist)
+		respond(404, rbody.FromSnapError(serror.New(ErrAgreementDoesNotExist, fields)), w)
+		return false
+	}
+	return true
+}
+
+// memberExists responds with a 404 unless the member belongs to the tribe.
+func (s *Server) memberExists(name string, w http.ResponseWriter) bool {
+	if s.tr.GetMember(name) == nil {
+		fields := map[string]interface{}{
+			"member_name": name,
+		}
+		tribeLogger.WithFields(fields).Error(ErrMemberNotFound)
+		respond(404, rbody.FromSnapError(serror.New(ErrMemberNotFound, fields)), w)
+		return false
+	}
+	return true
+}
+
 func (s *Server) getAgreements(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	res := &rbody.TribeListAgreement{}
 	res.Agreements = s.tr.GetAgreements()
@@ -49,43 +88,33 @@ func (s *Server) getAgreements(w http.ResponseWriter, r *http.Request, _ httprou
 }
 
 func (s *Server) getAgreement(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-	tribeLogger = tribeLogger.WithField("_block", "getAgreement")
+	logger := tribeLogger.WithField("_block", "getAgreement")
 	name := p.ByName("name")
-	if _, ok := s.tr.GetAgreements()[name]; !ok {
-		fields := map[string]interface{}{
-			"agreement_name": name,
-		}
-		tribeLogger.WithFields(fields).Error(ErrAgreementDoesNotExist)
-		respond(400, rbody.FromSnapError(serror.New(ErrAgreementDoesNotExist, fields)), w)
+	if !s.agreementExists(name, w) {
 		return
 	}
 	a := &rbody.TribeGetAgreement{}
 	var serr serror.SnapError
 	a.Agreement, serr = s.tr.GetAgreement(name)
 	if serr != nil {
-		tribeLogger.Error(serr)
-		respond(400, rbody.FromSnapError(serr), w)
+		logger.Error(serr)
+		respond(404, rbody.FromSnapError(serr), w)
 		return
 	}
 	respond(200, a, w)
 }
 
 func (s *Server) deleteAgreement(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-	tribeLogger = tribeLogger.WithField("_block", "deleteAgreement")
+	logger := tribeLogger.WithField("_block", "deleteAgreement")
 	name := p.ByName("name")
-	if _, ok := s.tr.GetAgreements()[name]; !ok {
-		fields := map[string]interface{}{
-			"agreement_name": name,
-		}
-		tribeLogger.WithFields(fields).Error(ErrAgreementDoesNotExist)
-		respond(400, rbody.FromSnapError(serror.New(ErrAgreementDoesNotExist, fields)), w)
+	if !s.agreementExists(name, w) {
 		return
 	}
 
 	var serr serror.SnapError
 	serr = s.tr.RemoveAgreement(name)
 	if serr != nil {
-		tribeLogger.Error(serr)
+		logger.Error(serr)
 		respond(400, rbody.FromSnapError(serr), w)
 		return
 	}
@@ -96,20 +125,15 @@ func (s *Server) deleteAgreement(w http.ResponseWriter, r *http.Request, p httpr
 }
 
 func (s *Server) joinAgreement(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-	tribeLogger = tribeLogger.WithField("_block", "joinAgreement")
+	logger := tribeLogger.WithField("_block", "joinAgreement")
 	name := p.ByName("name")
-	if _, ok := s.tr.GetAgreements()[name]; !ok {
-		fields := map[string]interface{}{
-			"agreement_name": name,
-		}
-		tribeLogger.WithFields(fields).Error(ErrAgreementDoesNotExist)
-		respond(400, rbody.FromSnapError(serror.New(ErrAgreementDoesNotExist, fields)), w)
+	if !s.agreementExists(name, w) {
 		return
 	}
 
 	b, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		tribeLogger.Error(err)
+		logger.Error(err)
 		respond(500, rbody.FromError(err), w)
 		return
 	}
@@ -124,14 +148,18 @@ func (s *Server) joinAgreement(w http.ResponseWriter, r *http.Request, p httprou
 			"hint":  `The body of the request should be of the form '{"member_name": "some_value"}'`,
 		}
 		se := serror.New(ErrInvalidJSON, fields)
-		tribeLogger.WithFields(fields).Error(ErrInvalidJSON)
+		logger.WithFields(fields).Error(ErrInvalidJSON)
 		respond(400, rbody.FromSnapError(se), w)
 		return
 	}
 
+	if !s.memberExists(m.MemberName, w) {
+		return
+	}
+
 	serr := s.tr.JoinAgreement(name, m.MemberName)
 	if serr != nil {
-		tribeLogger.Error(serr)
+		logger.Error(serr)
 		respond(400, rbody.FromSnapError(serr), w)
 		return
 	}
@@ -141,20 +169,15 @@ func (s *Server) joinAgreement(w http.ResponseWriter, r *http.Request, p httprou
 }
 
 func (s *Server) leaveAgreement(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-	tribeLogger = tribeLogger.WithField("_block", "leaveAgreement")
+	logger := tribeLogger.WithField("_block", "leaveAgreement")
 	name := p.ByName("name")
-	if _, ok := s.tr.GetAgreements()[name]; !ok {
-		fields := map[string]interface{}{
-			"agreement_name": name,
-		}
-		tribeLogger.WithFields(fields).Error(ErrAgreementDoesNotExist)
-		respond(400, rbody.FromSnapError(serror.New(ErrAgreementDoesNotExist, fields)), w)
+	if !s.agreementExists(name, w) {
 		return
 	}
 
 	b, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		tribeLogger.Error(err)
+		logger.Error(err)
 		respond(500, rbody.FromError(err), w)
 		return
 	}
@@ -169,14 +192,18 @@ func (s *Server) leaveAgreement(w http.ResponseWriter, r *http.Request, p httpro
 			"hint":  `The body of the request should be of the form '{"member_name": "some_value"}'`,
 		}
 		se := serror.New(ErrInvalidJSON, fields)
-		tribeLogger.WithFields(fields).Error(ErrInvalidJSON)
+		logger.WithFields(fields).Error(ErrInvalidJSON)
 		respond(400, rbody.FromSnapError(se), w)
 		return
 	}
 
+	if !s.memberExists(m.MemberName, w) {
+		return
+	}
+
 	serr := s.tr.LeaveAgreement(name, m.MemberName)
 	if serr != nil {
-		tribeLogger.Error(serr)
+		logger.Error(serr)
 		respond(400, rbody.FromSnapError(serr), w)
 		return
 	}
@@ -184,20 +211,156 @@ func (s *Server) leaveAgreement(w http.ResponseWriter, r *http.Request, p httpro
 	respond(200, &rbody.TribeLeaveAgreement{Agreement: agreement}, w)
 }
 
+// addAgreementPlugin adds a plugin which no member may have yet to an
+// agreement. The members download it from the URL of the plugin when none of
+// them has it.
+func (s *Server) addAgreementPlugin(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	logger := tribeLogger.WithField("_block", "addAgreementPlugin")
+	name := p.ByName("name")
+	if !s.agreementExists(name, w) {
+		return
+	}
+
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		logger.Error(err)
+		respond(500, rbody.FromError(err), w)
+		return
+	}
+
+	m := struct {
+		Name    string `json:"name"`
+		Type    string `json:"type"`
+		Version int    `json:"version"`
+		URL     string `json:"url"`
+	}{}
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		fields := map[string]interface{}{
+			"error": err,
+			"hint":  `The body of the request should be of the form '{"name": "some_value", "type": "collector", "version": 1, "url": "some_value"}'`,
+		}
+		se := serror.New(ErrInvalidJSON, fields)
+		logger.WithFields(fields).Error(ErrInvalidJSON)
+		respond(400, rbody.FromSnapError(se), w)
+		return
+	}
+	typ, err := core.ToPluginType(m.Type)
+	if err != nil {
+		respond(400, rbody.FromError(err), w)
+		return
+	}
+	if m.Name == "" || m.Version < 1 || m.URL == "" {
+		fields := map[string]interface{}{
+			"hint": "The name, version and url of the plugin must be given",
+		}
+		respond(400, rbody.FromSnapError(serror.New(ErrInvalidJSON, fields)), w)
+		return
+	}
+
+	plugin := agreement.Plugin{Name_: m.Name, Version_: m.Version, Type_: typ, URL_: m.URL}
+	a, _ := s.tr.GetAgreement(name)
+	if ok, _ := a.PluginAgreement.Plugins.Contains(plugin); ok {
+		fields := map[string]interface{}{
+			"agreement_name": name,
+			"plugin_name":    m.Name,
+			"plugin_version": m.Version,
+		}
+		logger.WithFields(fields).Error(ErrAgreementPluginExists)
+		respond(409, rbody.FromSnapError(serror.New(ErrAgreementPluginExists, fields)), w)
+		return
+	}
+
+	err = s.tr.AddPlugin(name, plugin)
+	if err != nil {
+		logger.Error(err)
+		respond(400, rbody.FromError(err), w)
+		return
+	}
+	a, _ = s.tr.GetAgreement(name)
+	respond(200, &rbody.TribeAddPlugin{Agreement: a}, w)
+}
+
+// startRollout replaces a task of an agreement by a new task, on canary
+// members first.
+func (s *Server) startRollout(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	logger := tribeLogger.WithField("_block", "startRollout")
+	name := p.ByName("name")
+	if !s.agreementExists(name, w) {
+		return
+	}
+
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		logger.Error(err)
+		respond(500, rbody.FromError(err), w)
+		return
+	}
+
+	m := struct {
+		TaskID     string                       `json:"task_id"`
+		Task       *request.TaskCreationRequest `json:"task"`
+		Count      int                          `json:"canary_count"`
+		Percent    int                          `json:"canary_percent"`
+		BakePeriod string                       `json:"bake_period"`
+	}{}
+	err = json.Unmarshal(b, &m)
+	if err == nil && (m.TaskID == "" || m.Task == nil) {
+		err = errors.New("a task_id and a task must be given")
+	}
+	if err != nil {
+		fields := map[string]interface{}{
+			"error": err,
+			"hint":  `The body of the request should be of the form '{"task_id": "some_value", "task": {...}, "canary_count": 1, "canary_percent": 10, "bake_period": "5m"}'`,
+		}
+		se := serror.New(ErrInvalidJSON, fields)
+		logger.WithFields(fields).Error(ErrInvalidJSON)
+		respond(400, rbody.FromSnapError(se), w)
+		return
+	}
+	opts := agreement.RolloutOptions{Count: m.Count, Percent: m.Percent}
+	if m.BakePeriod != "" {
+		opts.BakePeriod, err = time.ParseDuration(m.BakePeriod)
+		if err != nil {
+			respond(400, rbody.FromError(err), w)
+			return
+		}
+	}
+
+	rollout, serr := s.tr.StartRollout(name, m.TaskID, m.Task, opts)
+	if serr != nil {
+		logger.Error(serr)
+		respond(400, rbody.FromSnapError(serr), w)
+		return
+	}
+	respond(201, &rbody.TribeStartRollout{Rollout: rollout}, w)
+}
+
+func (s *Server) getRollout(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	logger := tribeLogger.WithField("_block", "getRollout")
+	rollout, serr := s.tr.GetRollout(p.ByName("name"))
+	if serr != nil {
+		logger.Error(serr)
+		respond(404, rbody.FromSnapError(serr), w)
+		return
+	}
+	respond(200, &rbody.TribeGetRollout{Rollout: rollout}, w)
+}
+
 func (s *Server) getMembers(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 	members := s.tr.GetMembers()
 	respond(200, &rbody.TribeMemberList{Members: members}, w)
 }
 
 func (s *Server) getMember(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-	tribeLogger = tribeLogger.WithField("_block", "getMember")
+	logger := tribeLogger.WithField("_block", "getMember")
 	name := p.ByName("name")
 	member := s.tr.GetMember(name)
 	if member == nil {
 		fields := map[string]interface{}{
 			"name": name,
 		}
-		tribeLogger.WithFields(fields).Error(ErrMemberNotFound)
+		logger.WithFields(fields).Error(ErrMemberNotFound)
 		respond(404, rbody.FromSnapError(serror.New(ErrMemberNotFound, fields)), w)
 		return
 	}
@@ -205,6 +368,9 @@ func (s *Server) getMember(w http.ResponseWriter, r *http.Request, p httprouter.
 		Name: member.Name,
 		Tags: member.Tags,
 	}
+	if member.Node != nil {
+		resp.Addr = member.GetAddr().String()
+	}
 	if member.PluginAgreement != nil {
 		resp.PluginAgreement = member.PluginAgreement.Name
 	}
@@ -213,14 +379,17 @@ func (s *Server) getMember(w http.ResponseWriter, r *http.Request, p httprouter.
 			resp.TaskAgreements = append(resp.TaskAgreements, k)
 		}
 	}
+	if skew, ok := s.tr.ClockSkew(name); ok {
+		resp.ClockSkew = skew.String()
+	}
 	respond(200, resp, w)
 }
 
 func (s *Server) addAgreement(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-	tribeLogger = tribeLogger.WithField("_block", "addAgreement")
+	logger := tribeLogger.WithField("_block", "addAgreement")
 	b, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		tribeLogger.Error(err)
+		logger.Error(err)
 		respond(500, rbody.FromError(err), w)
 		return
 	}
@@ -233,7 +402,7 @@ func (s *Server) addAgreement(w http.ResponseWriter, r *http.Request, p httprout
 			"hint":  `The body of the request should be of the form '{"name": "agreement_name"}'`,
 		}
 		se := serror.New(ErrInvalidJSON, fields)
-		tribeLogger.WithFields(fields).Error(ErrInvalidJSON)
+		logger.WithFields(fields).Error(ErrInvalidJSON)
 		respond(400, rbody.FromSnapError(se), w)
 		return
 	}
@@ -243,14 +412,23 @@ func (s *Server) addAgreement(w http.ResponseWriter, r *http.Request, p httprout
 			"hint": `The body of the request should be of the form '{"name": "agreement_name"}'`,
 		}
 		se := serror.New(ErrInvalidJSON, fields)
-		tribeLogger.WithFields(fields).Error(ErrInvalidJSON)
+		logger.WithFields(fields).Error(ErrInvalidJSON)
 		respond(400, rbody.FromSnapError(se), w)
 		return
 	}
 
+	if _, ok := s.tr.GetAgreements()[a.Name]; ok {
+		fields := map[string]interface{}{
+			"agreement_name": a.Name,
+		}
+		logger.WithFields(fields).Error(ErrAgreementAlreadyExists)
+		respond(409, rbody.FromSnapError(serror.New(ErrAgreementAlreadyExists, fields)), w)
+		return
+	}
+
 	err = s.tr.AddAgreement(a.Name)
 	if err != nil {
-		tribeLogger.WithField("agreement-name", a.Name).Error(err)
+		logger.WithField("agreement-name", a.Name).Error(err)
 		respond(400, rbody.FromError(err), w)
 		return
 	}
@@ -260,3 +438,199 @@ func (s *Server) addAgreement(w http.ResponseWriter, r *http.Request, p httprout
 
 	respond(200, res, w)
 }
+
+// removeAgreementPlugin removes a plugin from an agreement. The members of the
+// agreement unload it.
+func (s *Server) removeAgreementPlugin(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	logger := tribeLogger.WithField("_block", "removeAgreementPlugin")
+	name := p.ByName("name")
+	if !s.agreementExists(name, w) {
+		return
+	}
+
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		logger.Error(err)
+		respond(500, rbody.FromError(err), w)
+		return
+	}
+
+	m := struct {
+		Name    string `json:"name"`
+		Type    string `json:"type"`
+		Version int    `json:"version"`
+	}{}
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		fields := map[string]interface{}{
+			"error": err,
+			"hint":  `The body of the request should be of the form '{"name": "some_value", "type": "collector", "version": 1}'`,
+		}
+		se := serror.New(ErrInvalidJSON, fields)
+		logger.WithFields(fields).Error(ErrInvalidJSON)
+		respond(400, rbody.FromSnapError(se), w)
+		return
+	}
+	typ, err := core.ToPluginType(m.Type)
+	if err != nil {
+		respond(400, rbody.FromError(err), w)
+		return
+	}
+
+	plugin := agreement.Plugin{Name_: m.Name, Version_: m.Version, Type_: typ}
+	a, _ := s.tr.GetAgreement(name)
+	if ok, _ := a.PluginAgreement.Plugins.Contains(plugin); !ok {
+		fields := map[string]interface{}{
+			"agreement_name": name,
+			"plugin_name":    m.Name,
+			"plugin_version": m.Version,
+		}
+		logger.WithFields(fields).Error(ErrAgreementPluginNotFound)
+		respond(404, rbody.FromSnapError(serror.New(ErrAgreementPluginNotFound, fields)), w)
+		return
+	}
+
+	err = s.tr.RemovePlugin(name, plugin)
+	if err != nil {
+		logger.Error(err)
+		respond(400, rbody.FromError(err), w)
+		return
+	}
+	a, _ = s.tr.GetAgreement(name)
+	respond(200, &rbody.TribeRemovePlugin{Agreement: a}, w)
+}
+
+// getAgreementTasks lists the tasks of an agreement, with their name and
+// state on this member.
+func (s *Server) getAgreementTasks(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	name := p.ByName("name")
+	if !s.agreementExists(name, w) {
+		return
+	}
+
+	a, _ := s.tr.GetAgreement(name)
+	res := &rbody.TribeListAgreementTasks{Tasks: []rbody.TribeAgreementTask{}}
+	for _, t := range a.TaskAgreement.Tasks {
+		at := rbody.TribeAgreementTask{ID: t.ID, StartOnCreate: t.StartOnCreate}
+		if task, err := s.mt.GetTask(t.ID); err == nil {
+			at.Name = task.GetName()
+			at.State = task.State().String()
+		}
+		res.Tasks = append(res.Tasks, at)
+	}
+	respond(200, res, w)
+}
+
+// addAgreementTask adds a task of this member to an agreement. The other
+// members of the agreement create it.
+func (s *Server) addAgreementTask(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	logger := tribeLogger.WithField("_block", "addAgreementTask")
+	name := p.ByName("name")
+	if !s.agreementExists(name, w) {
+		return
+	}
+
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		logger.Error(err)
+		respond(500, rbody.FromError(err), w)
+		return
+	}
+
+	m := struct {
+		TaskID        string `json:"task_id"`
+		StartOnCreate bool   `json:"start_on_create"`
+	}{}
+	err = json.Unmarshal(b, &m)
+	if err == nil && m.TaskID == "" {
+		err = errors.New("a task_id must be given")
+	}
+	if err != nil {
+		fields := map[string]interface{}{
+			"error": err,
+			"hint":  `The body of the request should be of the form '{"task_id": "some_value", "start_on_create": true}'`,
+		}
+		se := serror.New(ErrInvalidJSON, fields)
+		logger.WithFields(fields).Error(ErrInvalidJSON)
+		respond(400, rbody.FromSnapError(se), w)
+		return
+	}
+
+	fields := map[string]interface{}{
+		"agreement_name": name,
+		"task_id":        m.TaskID,
+	}
+	if _, err := s.mt.GetTask(m.TaskID); err != nil {
+		logger.WithFields(fields).Error(ErrTaskNotFound)
+		respond(404, rbody.FromSnapError(serror.New(ErrTaskNotFound, fields)), w)
+		return
+	}
+	task := agreement.Task{ID: m.TaskID, StartOnCreate: m.StartOnCreate}
+	a, _ := s.tr.GetAgreement(name)
+	if ok, _ := a.TaskAgreement.Tasks.Contains(task); ok {
+		logger.WithFields(fields).Error(ErrAgreementTaskExists)
+		respond(409, rbody.FromSnapError(serror.New(ErrAgreementTaskExists, fields)), w)
+		return
+	}
+
+	serr := s.tr.AddTask(name, task)
+	if serr != nil {
+		logger.Error(serr)
+		respond(400, rbody.FromSnapError(serr), w)
+		return
+	}
+	a, _ = s.tr.GetAgreement(name)
+	respond(201, &rbody.TribeAddAgreementTask{Agreement: a}, w)
+}
+
+func (s *Server) removeAgreementTask(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	logger := tribeLogger.WithField("_block", "removeAgreementTask")
+	if a, ok := s.changeAgreementTask(w, p, logger, s.tr.RemoveTask); ok {
+		respond(200, &rbody.TribeRemoveAgreementTask{Agreement: a}, w)
+	}
+}
+
+func (s *Server) startAgreementTask(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	logger := tribeLogger.WithField("_block", "startAgreementTask")
+	if a, ok := s.changeAgreementTask(w, p, logger, s.tr.StartTask); ok {
+		respond(200, &rbody.TribeStartAgreementTask{Agreement: a}, w)
+	}
+}
+
+func (s *Server) stopAgreementTask(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	logger := tribeLogger.WithField("_block", "stopAgreementTask")
+	if a, ok := s.changeAgreementTask(w, p, logger, s.tr.StopTask); ok {
+		respond(200, &rbody.TribeStopAgreementTask{Agreement: a}, w)
+	}
+}
+
+// changeAgreementTask applies change to the task of an agreement on every
+// member of the agreement, and returns the agreement changed. It responds
+// with the error, logged to logger, and returns false when the task cannot
+// be changed.
+func (s *Server) changeAgreementTask(w http.ResponseWriter, p httprouter.Params, logger *log.Entry, change func(string, agreement.Task) serror.SnapError) (*agreement.Agreement, bool) {
+	name := p.ByName("name")
+	if !s.agreementExists(name, w) {
+		return nil, false
+	}
+
+	task := agreement.Task{ID: p.ByName("id")}
+	a, _ := s.tr.GetAgreement(name)
+	if ok, _ := a.TaskAgreement.Tasks.Contains(task); !ok {
+		fields := map[string]interface{}{
+			"agreement_name": name,
+			"task_id":        task.ID,
+		}
+		logger.WithFields(fields).Error(ErrAgreementTaskNotFound)
+		respond(404, rbody.FromSnapError(serror.New(ErrAgreementTaskNotFound, fields)), w)
+		return nil, false
+	}
+
+	if serr := change(name, task); serr != nil {
+		logger.Error(serr)
+		respond(400, rbody.FromSnapError(serr), w)
+		return nil, false
+	}
+	a, _ = s.tr.GetAgreement(name)
+	return a, true
+}
@@ -22,9 +22,11 @@ package rest
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -36,8 +38,10 @@ import (
 
 	"github.com/intelsdi-x/snap/control"
 	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/serror"
 	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
 	"github.com/intelsdi-x/snap/mgmt/tribe"
+	"github.com/intelsdi-x/snap/mgmt/tribe/agreement"
 	"github.com/intelsdi-x/snap/scheduler"
 )
 
@@ -662,6 +666,105 @@ func TestTribePluginAgreements(t *testing.T) {
 	})
 }
 
+type routesTribe struct {
+	managesTribe
+	agreements map[string]*agreement.Agreement
+	calls      []string
+}
+
+func (t *routesTribe) GetAgreements() map[string]*agreement.Agreement {
+	return t.agreements
+}
+
+func (t *routesTribe) GetAgreement(name string) (*agreement.Agreement, serror.SnapError) {
+	return t.agreements[name], nil
+}
+
+func (t *routesTribe) GetMember(name string) *agreement.Member {
+	if name != "member-1" {
+		return nil
+	}
+	return &agreement.Member{Name: name}
+}
+
+func (t *routesTribe) AddTask(name string, task agreement.Task) serror.SnapError {
+	t.agreements[name].TaskAgreement.Add(task)
+	return nil
+}
+
+func (t *routesTribe) StopTask(name string, task agreement.Task) serror.SnapError {
+	t.calls = append(t.calls, "stop "+task.ID)
+	return nil
+}
+
+func (t *routesTribe) RemoveTask(name string, task agreement.Task) serror.SnapError {
+	t.calls = append(t.calls, "remove "+task.ID)
+	return nil
+}
+
+type routesTaskManager struct {
+	managesTasks
+}
+
+func (m *routesTaskManager) GetTask(id string) (core.Task, error) {
+	if id != "7cd4b229" {
+		return nil, errors.New("Task not found: ID(" + id + ")")
+	}
+	return &statusTask{}, nil
+}
+
+func TestTribeRoutes(t *testing.T) {
+	Convey("The tribe routes", t, func() {
+		tr := &routesTribe{agreements: map[string]*agreement.Agreement{"a1": agreement.New("a1")}}
+		s, err := New(GetDefaultConfig())
+		So(err, ShouldBeNil)
+		s.BindTaskManager(&routesTaskManager{})
+		s.BindTribeManager(tr)
+		s.addRoutes()
+		do := func(method, uri, body string) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(method, uri, bytes.NewBufferString(body))
+			s.n.ServeHTTP(rec, req)
+			return rec
+		}
+		Convey("respond with a 404 when the agreement does not exist", func() {
+			So(do("GET", "/v1/tribe/agreements/a2", "").Code, ShouldEqual, 404)
+			So(do("GET", "/v1/tribe/agreements/a2/tasks", "").Code, ShouldEqual, 404)
+			So(do("PUT", "/v1/tribe/agreements/a2/tasks/7cd4b229/start", "").Code, ShouldEqual, 404)
+		})
+		Convey("respond with a 404 when the member does not exist", func() {
+			So(do("PUT", "/v1/tribe/agreements/a1/join", `{"member_name": "member-2"}`).Code, ShouldEqual, 404)
+			So(do("GET", "/v1/tribe/members/member-2", "").Code, ShouldEqual, 404)
+		})
+		Convey("respond with a 409 when the agreement exists", func() {
+			So(do("POST", "/v1/tribe/agreements", `{"name": "a1"}`).Code, ShouldEqual, 409)
+		})
+		Convey("add a task of the member to an agreement", func() {
+			So(do("POST", "/v1/tribe/agreements/a1/tasks", `{"task_id": 1}`).Code, ShouldEqual, 400)
+			So(do("POST", "/v1/tribe/agreements/a1/tasks", `{"task_id": "3f5ab2e0"}`).Code, ShouldEqual, 404)
+			So(do("POST", "/v1/tribe/agreements/a1/tasks", `{"task_id": "7cd4b229", "start_on_create": true}`).Code, ShouldEqual, 201)
+			So(do("POST", "/v1/tribe/agreements/a1/tasks", `{"task_id": "7cd4b229"}`).Code, ShouldEqual, 409)
+			Convey("list the tasks of the agreement with their state", func() {
+				rec := do("GET", "/v1/tribe/agreements/a1/tasks", "")
+				So(rec.Code, ShouldEqual, 200)
+				var resp struct {
+					Body rbody.TribeListAgreementTasks `json:"body"`
+				}
+				So(json.Unmarshal(rec.Body.Bytes(), &resp), ShouldBeNil)
+				So(resp.Body.Tasks, ShouldResemble, []rbody.TribeAgreementTask{
+					{ID: "7cd4b229", Name: "<cpu>", State: "Disabled", StartOnCreate: true},
+				})
+			})
+			Convey("stop and remove the tasks of the agreement", func() {
+				So(do("PUT", "/v1/tribe/agreements/a1/tasks/3f5ab2e0/stop", "").Code, ShouldEqual, 404)
+				So(do("PUT", "/v1/tribe/agreements/a1/tasks/7cd4b229/stop", "").Code, ShouldEqual, 200)
+				So(do("DELETE", "/v1/tribe/agreements/a1/tasks/7cd4b229", "").Code, ShouldEqual, 200)
+				So(tr.calls, ShouldResemble, []string{"stop 7cd4b229", "remove 7cd4b229"})
+			})
+		})
+	})
+}
+
 // returns an array of the mgtports and the tribe port for the last node
 func startTribes(count int, seed string) ([]int, int) {
 	var wg sync.WaitGroup
@@ -32,6 +32,14 @@ const (
 	RestPort               = "rest_api_port"
 	RestProtocol           = "rest_proto"
 	RestInsecureSkipVerify = "rest_insecure"
+	// Maintenance is set on a member in maintenance mode, to one of the modes
+	// below
+	Maintenance            = "maintenance"
+	MaintenancePaused      = "paused"
+	MaintenanceHoldPublish = "hold_publish"
+	// Aggregator is set on a member with the aggregator role, which
+	// republishes the metrics the other members forward to it
+	Aggregator = "aggregator"
 )
 
 var logger = log.WithFields(log.Fields{
@@ -110,6 +118,16 @@ func (m *Member) GetRestInsecureSkipVerify() bool {
 	return false
 }
 
+// InMaintenance returns true if the member is in maintenance mode
+func (m *Member) InMaintenance() bool {
+	return m.Tags[Maintenance] != ""
+}
+
+// IsAggregator returns true if the member has the aggregator role
+func (m *Member) IsAggregator() bool {
+	return m.Tags[Aggregator] == "true"
+}
+
 func (m *Member) GetName() string {
 	return m.Name
 }
@@ -122,6 +140,9 @@ type Plugin struct {
 	Name_    string          `json:"name"`
 	Version_ int             `json:"version"`
 	Type_    core.PluginType `json:"type"`
+	// URL_ is where the members download the plugin from when no other
+	// member has it
+	URL_ string `json:"url,omitempty"`
 }
 
 func (p Plugin) Name() string {
@@ -136,6 +157,10 @@ func (p Plugin) TypeName() string {
 	return p.Type_.String()
 }
 
+func (p Plugin) URL() string {
+	return p.URL_
+}
+
 func newPlugin(n string, v int, t core.PluginType) *Plugin {
 	return &Plugin{
 		Name_:    n,
@@ -0,0 +1,87 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agreement
+
+import (
+	"sort"
+	"time"
+)
+
+// The states a rollout goes through. A rollout bakes on its canaries, then
+// is either promoted to the other members and completed, or rolled back.
+const (
+	RolloutBaking     = "baking"
+	RolloutPromoting  = "promoting"
+	RolloutCompleted  = "completed"
+	RolloutRolledBack = "rolled_back"
+)
+
+// RolloutOptions select the canaries of a rollout and how long the new task
+// bakes on them. Count takes precedence over Percent; when neither is given
+// a single canary is selected.
+type RolloutOptions struct {
+	Count      int
+	Percent    int
+	BakePeriod time.Duration
+}
+
+// Rollout replaces a task of an agreement by a new task on a few canary
+// members first, and on the other members once the new task ran without
+// failing on the canaries for the bake period.
+type Rollout struct {
+	ID         string    `json:"id"`
+	Agreement  string    `json:"agreement"`
+	OldTaskID  string    `json:"old_task_id"`
+	NewTaskID  string    `json:"new_task_id"`
+	Canaries   []string  `json:"canaries"`
+	Members    []string  `json:"members"`
+	BakePeriod string    `json:"bake_period"`
+	State      string    `json:"state"`
+	Started    time.Time `json:"started"`
+	Updated    time.Time `json:"updated"`
+	// Owner is the member running the rollout. A rollout in progress whose
+	// owner left the tribe is rolled back by the next one started.
+	Owner string `json:"owner"`
+	// Failures are the reasons the new task failed, by member name
+	Failures map[string]string `json:"failures,omitempty"`
+}
+
+// Done returns whether the rollout was completed or rolled back.
+func (r *Rollout) Done() bool {
+	return r.State == RolloutCompleted || r.State == RolloutRolledBack
+}
+
+// Canaries returns the members a rollout starts on: the first of the member
+// names in sorted order, as many as the options select and at least one.
+func Canaries(members []string, opts RolloutOptions) []string {
+	names := append([]string(nil), members...)
+	sort.Strings(names)
+	n := opts.Count
+	if n == 0 && opts.Percent > 0 {
+		n = (len(names)*opts.Percent + 99) / 100
+	}
+	if n < 1 {
+		n = 1
+	}
+	if n > len(names) {
+		n = len(names)
+	}
+	return names[:n]
+}
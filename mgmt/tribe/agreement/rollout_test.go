@@ -0,0 +1,47 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agreement
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCanaries(t *testing.T) {
+	members := []string{"web-3", "web-1", "web-4", "web-2"}
+	Convey("The canaries of a rollout", t, func() {
+		Convey("are the first members in sorted order", func() {
+			So(Canaries(members, RolloutOptions{Count: 2}), ShouldResemble, []string{"web-1", "web-2"})
+		})
+		Convey("are a percentage of the members rounded up", func() {
+			So(Canaries(members, RolloutOptions{Percent: 30}), ShouldResemble, []string{"web-1", "web-2"})
+			So(Canaries(members, RolloutOptions{Percent: 100}), ShouldHaveLength, 4)
+		})
+		Convey("are at least one and at most all of the members", func() {
+			So(Canaries(members, RolloutOptions{}), ShouldResemble, []string{"web-1"})
+			So(Canaries(members, RolloutOptions{Count: 10}), ShouldHaveLength, 4)
+		})
+		Convey("do not reorder the members given", func() {
+			Canaries(members, RolloutOptions{Count: 1})
+			So(members[0], ShouldEqual, "web-3")
+		})
+	})
+}
@@ -0,0 +1,163 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agreement
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/ghodss/yaml"
+	"github.com/pborman/uuid"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
+)
+
+// Spec declares an agreement, the members selected to join it and the
+// plugins and tasks they share. Agreements are exported to, imported from
+// and bootstrapped from specs.
+type Spec struct {
+	Name string `json:"name"yaml:"name"`
+	// Members are patterns (e.g. "web-*") matched against the names of the
+	// members, as path.Match does
+	Members []string     `json:"members,omitempty"yaml:"members,omitempty"`
+	Plugins []PluginSpec `json:"plugins,omitempty"yaml:"plugins,omitempty"`
+	Tasks   []TaskSpec   `json:"tasks,omitempty"yaml:"tasks,omitempty"`
+}
+
+// PluginSpec is a plugin of an agreement. A plugin no member has yet is
+// loaded from Path, or else downloaded by the members from URL.
+type PluginSpec struct {
+	Name    string `json:"name,omitempty"yaml:"name,omitempty"`
+	Type    string `json:"type,omitempty"yaml:"type,omitempty"`
+	Version int    `json:"version,omitempty"yaml:"version,omitempty"`
+	Path    string `json:"path,omitempty"yaml:"path,omitempty"`
+	URL     string `json:"url,omitempty"yaml:"url,omitempty"`
+}
+
+// TaskSpec is a task of an agreement, identified by its name. The task is
+// given inline or by the path of its task manifest, and is started once
+// created unless NoStart is set.
+type TaskSpec struct {
+	Name     string                       `json:"name"yaml:"name"`
+	Manifest string                       `json:"manifest,omitempty"yaml:"manifest,omitempty"`
+	Task     *request.TaskCreationRequest `json:"task,omitempty"yaml:"task,omitempty"`
+	NoStart  bool                         `json:"no_start,omitempty"yaml:"no_start,omitempty"`
+}
+
+// ReadSpec reads the spec of an agreement from a JSON or YAML file.
+func ReadSpec(fpath string) (*Spec, error) {
+	b, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+	s := &Spec{}
+	// yaml.Unmarshal handles JSON as well
+	if err := yaml.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Validate returns the problems found in the spec.
+func (s *Spec) Validate() []error {
+	var errs []error
+	if s.Name == "" {
+		errs = append(errs, fmt.Errorf("name: must not be empty"))
+	}
+	for i, m := range s.Members {
+		if _, err := path.Match(m, ""); err != nil {
+			errs = append(errs, fmt.Errorf("members[%d]: %q: %v", i, m, err))
+		}
+	}
+	for i, p := range s.Plugins {
+		if p.Path == "" && (p.Name == "" || p.Type == "") {
+			errs = append(errs, fmt.Errorf("plugins[%d]: either a path or a name and type must be given", i))
+		}
+		if p.URL != "" && (p.Name == "" || p.Type == "" || p.Version == 0) {
+			errs = append(errs, fmt.Errorf("plugins[%d]: a name, type and version must be given with a url", i))
+		}
+		if p.Type != "" {
+			if _, err := core.ToPluginType(p.Type); err != nil {
+				errs = append(errs, fmt.Errorf("plugins[%d]: %v", i, err))
+			}
+		}
+		if p.Path != "" {
+			if _, err := os.Stat(p.Path); err != nil {
+				errs = append(errs, fmt.Errorf("plugins[%d]: %v", i, err))
+			}
+		}
+	}
+	names := map[string]bool{}
+	for i, t := range s.Tasks {
+		if t.Name == "" {
+			errs = append(errs, fmt.Errorf("tasks[%d]: name must not be empty", i))
+		} else if names[t.Name] {
+			errs = append(errs, fmt.Errorf("tasks[%d]: %q is declared twice", i, t.Name))
+		}
+		names[t.Name] = true
+		if (t.Manifest == "") == (t.Task == nil) {
+			errs = append(errs, fmt.Errorf("tasks[%d]: either a manifest or a task must be given", i))
+		} else if t.Manifest != "" {
+			if _, err := os.Stat(t.Manifest); err != nil {
+				errs = append(errs, fmt.Errorf("tasks[%d]: %v", i, err))
+			}
+		}
+	}
+	return errs
+}
+
+// Selects returns whether the member is selected to join the agreement.
+func (s *Spec) Selects(member string) bool {
+	for _, m := range s.Members {
+		if ok, _ := path.Match(m, member); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Request returns the request creating the task, named and started after
+// the spec.
+func (t *TaskSpec) Request() (*request.TaskCreationRequest, error) {
+	tr := &request.TaskCreationRequest{}
+	if t.Task != nil {
+		*tr = *t.Task
+	} else {
+		b, err := ioutil.ReadFile(t.Manifest)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(b, tr); err != nil {
+			return nil, fmt.Errorf("%s: %v", t.Manifest, err)
+		}
+	}
+	tr.Name = t.Name
+	tr.Start = !t.NoStart
+	return tr, nil
+}
+
+// TaskID returns the ID of the task with the given name bootstrapped in the
+// agreement, which is the same on every member.
+func TaskID(agreementName, taskName string) string {
+	return uuid.NewSHA1(uuid.NameSpace_URL, []byte("snap-agreement:"+agreementName+"/"+taskName)).String()
+}
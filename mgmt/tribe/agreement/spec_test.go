@@ -0,0 +1,100 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agreement
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSpec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agreement-spec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	manifest := filepath.Join(dir, "task.yaml")
+	ioutil.WriteFile(manifest, []byte("version: 1\nschedule:\n  type: simple\n  interval: 1s\ndeadline: 5s\n"), 0644)
+	fspec := filepath.Join(dir, "spec.yaml")
+	ioutil.WriteFile(fspec, []byte(`
+name: web
+members: ["web-*", "db-1"]
+plugins:
+  - name: mock
+    type: collector
+tasks:
+  - name: cpu
+    manifest: `+manifest+`
+    no_start: true
+`), 0644)
+
+	Convey("A spec read from a file", t, func() {
+		s, err := ReadSpec(fspec)
+		So(err, ShouldBeNil)
+		So(s.Validate(), ShouldBeEmpty)
+		So(s.Plugins, ShouldResemble, []PluginSpec{{Name: "mock", Type: "collector"}})
+
+		Convey("selects the members matching its patterns", func() {
+			So(s.Selects("web-1"), ShouldBeTrue)
+			So(s.Selects("db-1"), ShouldBeTrue)
+			So(s.Selects("db-2"), ShouldBeFalse)
+		})
+		Convey("names its tasks after the spec", func() {
+			tr, err := s.Tasks[0].Request()
+			So(err, ShouldBeNil)
+			So(tr.Name, ShouldEqual, "cpu")
+			So(tr.Start, ShouldBeFalse)
+			So(tr.Schedule.Interval, ShouldEqual, "1s")
+			So(tr.Deadline, ShouldEqual, "5s")
+		})
+		Convey("reports its problems", func() {
+			s.Name = ""
+			s.Members = append(s.Members, "[")
+			s.Plugins = append(s.Plugins, PluginSpec{Name: "mock"}, PluginSpec{Name: "mock", Type: "nope"})
+			s.Tasks = append(s.Tasks, TaskSpec{Name: "cpu"})
+			errs := s.Validate()
+			So(errs, ShouldHaveLength, 6)
+			So(errs[0].Error(), ShouldStartWith, "name")
+			So(errs[1].Error(), ShouldStartWith, "members[2]")
+			So(errs[2].Error(), ShouldStartWith, "plugins[1]")
+			So(errs[3].Error(), ShouldStartWith, "plugins[2]")
+			So(errs[4].Error(), ShouldStartWith, "tasks[1]: \"cpu\" is declared twice")
+			So(errs[5].Error(), ShouldStartWith, "tasks[1]: either a manifest or a task")
+		})
+		Convey("requires the version of a plugin given by url", func() {
+			s.Plugins = []PluginSpec{{Name: "mock", Type: "collector", URL: "https://example.com/snap-plugin-collector-mock"}}
+			errs := s.Validate()
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Error(), ShouldStartWith, "plugins[0]: a name, type and version")
+			s.Plugins[0].Version = 2
+			So(s.Validate(), ShouldBeEmpty)
+		})
+	})
+
+	Convey("The ID of a bootstrapped task is the same on every member", t, func() {
+		So(TaskID("web", "cpu"), ShouldEqual, TaskID("web", "cpu"))
+		So(TaskID("web", "cpu"), ShouldNotEqual, TaskID("web", "mem"))
+		So(TaskID("web", "cpu"), ShouldNotEqual, TaskID("db", "cpu"))
+	})
+}
@@ -20,12 +20,24 @@ limitations under the License.
 package tribe
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
 	"net"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/hashicorp/memberlist"
 	"github.com/pborman/uuid"
+	"github.com/vrischmann/jsonutil"
+
+	"github.com/intelsdi-x/snap/mgmt/tribe/agreement"
+	"github.com/intelsdi-x/snap/pkg/netaddr"
+	"github.com/intelsdi-x/snap/pkg/reqsign"
+	"github.com/intelsdi-x/snap/pkg/tlspolicy"
 )
 
 // default configuration values
@@ -38,6 +50,7 @@ const (
 	defaultRestAPIPassword           string        = ""
 	defaultRestAPIPort               int           = 8181
 	defaultRestAPIInsecureSkipVerify string        = "true"
+	defaultMaxClockSkew              time.Duration = 2 * time.Second
 )
 
 // holds the configuration passed in through the SNAP config file
@@ -46,12 +59,27 @@ type Config struct {
 	Enable                    bool               `json:"enable,omitempty"yaml:"enable,omitempty"`
 	BindAddr                  string             `json:"bind_addr,omitempty"yaml:"bind_addr,omitempty"`
 	BindPort                  int                `json:"bind_port,omitempty"yaml:"bind_port,omitempty"`
+	AdvertiseAddr             string             `json:"advertise_addr,omitempty"yaml:"advertise_addr,omitempty"`
+	AdvertisePort             int                `json:"advertise_port,omitempty"yaml:"advertise_port,omitempty"`
 	Seed                      string             `json:"seed,omitempty"yaml:"seed,omitempty"`
+	TLSCertificate            string             `json:"tls_certificate,omitempty"yaml:"tls_certificate,omitempty"`
+	TLSKey                    string             `json:"tls_key,omitempty"yaml:"tls_key,omitempty"`
+	TLSCACertificate          string             `json:"tls_ca_certificate,omitempty"yaml:"tls_ca_certificate,omitempty"`
+	GossipKey                 string             `json:"gossip_key,omitempty"yaml:"gossip_key,omitempty"secret:"true"`
+	SignRequests              bool               `json:"sign_requests,omitempty"yaml:"sign_requests,omitempty"`
+	Agreements                []*agreement.Spec  `json:"agreements,omitempty"yaml:"agreements,omitempty"`
+	MaxClockSkew              jsonutil.Duration  `json:"max_clock_skew,omitempty"yaml:"max_clock_skew,omitempty"`
 	MemberlistConfig          *memberlist.Config `json:"-"yaml:"-"`
 	RestAPIProto              string             `json:"-"yaml:"-"`
 	RestAPIPassword           string             `json:"-"yaml:"-"`
 	RestAPIPort               int                `json:"-"yaml:"-"`
 	RestAPIInsecureSkipVerify string             `json:"-"yaml:"-"`
+	// Aggregator is set when the member has the aggregator role
+	Aggregator bool `json:"-"yaml:"-"`
+	// TLSPolicy restricts the TLS versions and cipher suites of the
+	// connections between the members, as set by the tls section of the
+	// snapd configuration
+	TLSPolicy *tlspolicy.Config `json:"-"yaml:"-"`
 }
 
 // get the default snapd configuration
@@ -65,6 +93,7 @@ func GetDefaultConfig() *Config {
 		BindAddr:                  getIP(),
 		BindPort:                  defaultBindPort,
 		Seed:                      defaultSeed,
+		MaxClockSkew:              jsonutil.Duration{defaultMaxClockSkew},
 		MemberlistConfig:          mlCfg,
 		RestAPIProto:              defaultRestAPIProto,
 		RestAPIPassword:           defaultRestAPIPassword,
@@ -82,34 +111,130 @@ func getHostname() string {
 }
 
 func getIP() string {
-	ifaces, err := net.Interfaces()
-	if err != nil {
-		logger.WithField("_block", "getIP").Error(err)
-		return "127.0.0.1"
+	return netaddr.Default()
+}
+
+// Validate returns the problems found in the configuration. Nothing is
+// checked when tribe is disabled.
+func (c *Config) Validate() []error {
+	if !c.Enable {
+		return nil
+	}
+	var errs []error
+	if c.Name == "" {
+		errs = append(errs, fmt.Errorf("tribe.name: must not be empty"))
+	}
+	if c.BindAddr != "" {
+		if _, err := netaddr.Resolve(c.BindAddr); err != nil {
+			errs = append(errs, fmt.Errorf("tribe.bind_addr: %v", err))
+		}
+	}
+	if c.BindPort < 1 || c.BindPort > 65535 {
+		errs = append(errs, fmt.Errorf("tribe.bind_port: %d is not a valid port", c.BindPort))
+	}
+	if c.AdvertiseAddr != "" && net.ParseIP(c.AdvertiseAddr) == nil {
+		errs = append(errs, fmt.Errorf("tribe.advertise_addr: %q is not an IP address", c.AdvertiseAddr))
+	}
+	if c.AdvertisePort < 0 || c.AdvertisePort > 65535 {
+		errs = append(errs, fmt.Errorf("tribe.advertise_port: %d is not a valid port", c.AdvertisePort))
 	}
-	for _, i := range ifaces {
-		addrs, err := i.Addrs()
+	if c.Seed != "" {
+		_, port, err := net.SplitHostPort(c.Seed)
 		if err != nil {
-			logger.WithField("_block", "getIP").Error(err)
-			return "127.0.0.1"
+			errs = append(errs, fmt.Errorf("tribe.seed: %v", err))
+		} else if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+			errs = append(errs, fmt.Errorf("tribe.seed: %q is not a valid port", port))
 		}
-		for _, addr := range addrs {
-			var ip net.IP
-			switch v := addr.(type) {
-			case *net.IPAddr:
-				ip = v.IP
-			case *net.IPNet:
-				ip = v.IP
-			}
-			if ip == nil || ip.IsLoopback() {
+	}
+	if c.TLSCertificate != "" || c.TLSKey != "" || c.TLSCACertificate != "" {
+		if c.TLSCertificate == "" || c.TLSKey == "" || c.TLSCACertificate == "" {
+			errs = append(errs, fmt.Errorf("tribe: tls_certificate, tls_key and tls_ca_certificate must be set together"))
+		}
+		for _, f := range []struct{ key, path string }{
+			{"tls_certificate", c.TLSCertificate},
+			{"tls_key", c.TLSKey},
+			{"tls_ca_certificate", c.TLSCACertificate},
+		} {
+			if f.path == "" {
 				continue
 			}
-			ip = ip.To4()
-			if ip == nil {
-				continue // not an ipv4 address
+			if _, err := os.Stat(f.path); err != nil {
+				errs = append(errs, fmt.Errorf("tribe.%s: %v", f.key, err))
 			}
-			return ip.String()
 		}
 	}
-	return "127.0.0.1"
+	if c.GossipKey != "" {
+		if _, err := c.gossipKey(); err != nil {
+			errs = append(errs, fmt.Errorf("tribe.gossip_key: %v", err))
+		}
+	} else if c.SignRequests {
+		errs = append(errs, fmt.Errorf("tribe.sign_requests: needs a gossip_key to derive the signing key from"))
+	}
+	if c.MaxClockSkew.Duration < 0 {
+		errs = append(errs, fmt.Errorf("tribe.max_clock_skew: cannot be negative"))
+	}
+	names := map[string]bool{}
+	for i, a := range c.Agreements {
+		for _, err := range a.Validate() {
+			errs = append(errs, fmt.Errorf("tribe.agreements[%d].%v", i, err))
+		}
+		if names[a.Name] {
+			errs = append(errs, fmt.Errorf("tribe.agreements[%d]: %q is declared twice", i, a.Name))
+		}
+		names[a.Name] = true
+	}
+	return errs
+}
+
+// gossipKey returns the decoded gossip key
+func (c *Config) gossipKey() ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(c.GossipKey)
+	if err != nil {
+		return nil, err
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	}
+	return nil, fmt.Errorf("the key is %d bytes long instead of 16, 24 or 32", len(key))
+}
+
+// RequestSigningKey returns the key the members sign their requests to the
+// REST API of each other with, nil unless they sign them
+func (c *Config) RequestSigningKey() []byte {
+	if !c.SignRequests {
+		return nil
+	}
+	key, err := c.gossipKey()
+	if err != nil {
+		return nil
+	}
+	return reqsign.DeriveKey(key)
+}
+
+// tlsConfig loads the certificate this member presents to the others and the
+// CA certificates theirs are verified against, either way, restricted by the
+// TLS policy.
+func (c *Config) tlsConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.TLSCertificate, c.TLSKey)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(c.TLSCACertificate)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificate found in %s", c.TLSCACertificate)
+	}
+	tc := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}
+	c.TLSPolicy.Apply(tc)
+	return tc, nil
 }
@@ -23,6 +23,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/intelsdi-x/snap/mgmt/tribe/agreement"
 	"github.com/intelsdi-x/snap/pkg/cfgfile"
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -135,3 +136,73 @@ func TestTribeDefaultConfig(t *testing.T) {
 		})
 	})
 }
+
+func TestTribeConfigValidate(t *testing.T) {
+	Convey("Validating a tribe config", t, func() {
+		cfg := GetDefaultConfig()
+		Convey("nothing is checked when disabled", func() {
+			cfg.BindPort = 0
+			So(cfg.Validate(), ShouldBeEmpty)
+		})
+		Convey("invalid values are reported when enabled", func() {
+			cfg.Enable = true
+			cfg.BindAddr = "not-an-ip"
+			cfg.BindPort = 70000
+			cfg.Seed = "127.0.0.1"
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 3)
+			So(errs[0].Error(), ShouldStartWith, "tribe.bind_addr")
+			So(errs[1].Error(), ShouldStartWith, "tribe.bind_port")
+			So(errs[2].Error(), ShouldStartWith, "tribe.seed")
+		})
+		Convey("an advertised address which is not an IP is reported", func() {
+			cfg.Enable = true
+			cfg.AdvertiseAddr = "eth0"
+			cfg.AdvertisePort = -1
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 2)
+			So(errs[0].Error(), ShouldStartWith, "tribe.advertise_addr")
+			So(errs[1].Error(), ShouldStartWith, "tribe.advertise_port")
+		})
+		Convey("TLS files are set together and must exist", func() {
+			cfg.Enable = true
+			cfg.TLSCertificate = "/does/not/exist.crt"
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 2)
+			So(errs[0].Error(), ShouldStartWith, "tribe: tls_certificate, tls_key and tls_ca_certificate")
+			So(errs[1].Error(), ShouldStartWith, "tribe.tls_certificate")
+		})
+		Convey("the gossip key is a base64 AES key which signed requests need", func() {
+			cfg.Enable = true
+			cfg.SignRequests = true
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Error(), ShouldStartWith, "tribe.sign_requests")
+			cfg.GossipKey = "c2hvcnQ="
+			errs = cfg.Validate()
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Error(), ShouldStartWith, "tribe.gossip_key")
+			cfg.GossipKey = "MDEyMzQ1Njc4OWFiY2RlZg=="
+			So(cfg.Validate(), ShouldBeEmpty)
+			So(cfg.RequestSigningKey(), ShouldHaveLength, 32)
+		})
+		Convey("agreements to bootstrap are validated", func() {
+			cfg.Enable = true
+			cfg.Agreements = []*agreement.Spec{
+				{Name: "web", Members: []string{"web-*"}},
+				{Name: "web", Tasks: []agreement.TaskSpec{{Name: "t"}}},
+			}
+			errs := cfg.Validate()
+			So(errs, ShouldHaveLength, 2)
+			So(errs[0].Error(), ShouldStartWith, "tribe.agreements[1].tasks[0]")
+			So(errs[1].Error(), ShouldStartWith, "tribe.agreements[1]: \"web\" is declared twice")
+		})
+		Convey("IPv6 addresses are accepted", func() {
+			cfg.Enable = true
+			cfg.BindAddr = "::1"
+			cfg.AdvertiseAddr = "2001:db8::10"
+			cfg.Seed = "[2001:db8::20]:6000"
+			So(cfg.Validate(), ShouldBeEmpty)
+		})
+	})
+}
@@ -21,6 +21,7 @@ package tribe
 
 import (
 	"fmt"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 )
@@ -31,9 +32,10 @@ type delegate struct {
 
 func (t *delegate) NodeMeta(limit int) []byte {
 	t.tribe.logger.WithField("_block", "delegate-node-meta").Debugln("getting node meta data")
-	tags := t.tribe.encodeTags(t.tribe.tags)
+	local := t.tribe.localTags()
+	tags := t.tribe.encodeTags(local)
 	if len(tags) > limit {
-		panic(fmt.Errorf("Node tags '%v' exceeds length limit of %d bytes", t.tribe.tags, limit))
+		panic(fmt.Errorf("Node tags '%v' exceeds length limit of %d bytes", local, limit))
 	}
 	return tags
 }
@@ -135,6 +137,29 @@ func (t *delegate) NotifyMsg(buf []byte) {
 			}
 		}
 		queryResp.lock.Unlock()
+	case validateTaskMsgType:
+		msg := &validateTaskMsg{}
+		if err := decodeMessage(buf[1:], msg); err != nil {
+			panic(err)
+		}
+		// validating may take a while, which must not hold up the gossip
+		go t.tribe.handleValidateTask(msg)
+		return
+	case validateTaskResponseMsgType:
+		msg := &validateTaskResponseMsg{}
+		if err := decodeMessage(buf[1:], msg); err != nil {
+			panic(err)
+		}
+		t.tribe.handleValidateTaskResponse(msg)
+		return
+	case rolloutMsgType:
+		msg := &rolloutMsg{}
+		if err := decodeMessage(buf[1:], msg); err != nil {
+			panic(err)
+		}
+		// replacing a task may take a while, which must not hold up the gossip
+		go t.tribe.handleRollout(msg)
+		return
 
 	default:
 		logger.WithFields(log.Fields{
@@ -233,6 +258,8 @@ func (t *delegate) LocalState(join bool) []byte {
 		TaskIntentMsgs:      taskIntentMsgs,
 		Agreements:          t.tribe.agreements,
 		Members:             t.tribe.members,
+		From:                t.tribe.config.Name,
+		Time:                time.Now().UnixNano(),
 	}
 
 	buf, err := encodeMessage(fullStateMsgType, fs)
@@ -257,6 +284,10 @@ func (t *delegate) MergeRemoteState(buf []byte, join bool) {
 	if err := decodeMessage(buf[1:], fs); err != nil {
 		panic(err)
 	}
+	// members from before the clock check send no time
+	if fs.From != "" && fs.Time != 0 {
+		t.tribe.checkClockSkew(fs.From, time.Unix(0, fs.Time), time.Now())
+	}
 
 	if t.tribe.clock.Time() > fs.LTime {
 		return
@@ -48,10 +48,40 @@ var (
 
 	flTribeAdvertiseAddr = cli.StringFlag{
 		Name:   "tribe-addr",
-		Usage:  "Addr tribe gossips over to maintain membership",
+		Usage:  "Addr (IP address or network interface) tribe gossips over to maintain membership",
 		EnvVar: "SNAP_TRIBE_ADDR",
 	}
 
+	flTribePublicAddr = cli.StringFlag{
+		Name:   "tribe-advertise-addr",
+		Usage:  "IP address advertised to the other members when it differs from the one tribe binds to, e.g. behind NAT",
+		EnvVar: "SNAP_TRIBE_ADVERTISE_ADDR",
+	}
+
+	flTribePublicPort = cli.IntFlag{
+		Name:   "tribe-advertise-port",
+		Usage:  "Port advertised to the other members (default: the port tribe binds to)",
+		EnvVar: "SNAP_TRIBE_ADVERTISE_PORT",
+	}
+
+	flTribeTLSCert = cli.StringFlag{
+		Name:   "tribe-tls-cert",
+		Usage:  "Path to the certificate this member presents to the others, enabling TLS between the members",
+		EnvVar: "SNAP_TRIBE_TLS_CERT",
+	}
+
+	flTribeTLSKey = cli.StringFlag{
+		Name:   "tribe-tls-key",
+		Usage:  "Path to the key of the tribe TLS certificate",
+		EnvVar: "SNAP_TRIBE_TLS_KEY",
+	}
+
+	flTribeTLSCA = cli.StringFlag{
+		Name:   "tribe-tls-ca-cert",
+		Usage:  "Path to the CA certificates the certificates of the other members are verified against",
+		EnvVar: "SNAP_TRIBE_TLS_CA_CERT",
+	}
+
 	// Flags consumed by snapd
-	Flags = []cli.Flag{flTribeNodeName, flTribe, flTribeSeed, flTribeAdvertiseAddr, flTribeAdvertisePort}
+	Flags = []cli.Flag{flTribeNodeName, flTribe, flTribeSeed, flTribeAdvertiseAddr, flTribeAdvertisePort, flTribePublicAddr, flTribePublicPort, flTribeTLSCert, flTribeTLSKey, flTribeTLSCA}
 )
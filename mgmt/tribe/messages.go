@@ -45,6 +45,9 @@ const (
 	startTaskMsgType
 	getTaskStateMsgType
 	taskStateQueryResponseMsgType
+	validateTaskMsgType
+	validateTaskResponseMsgType
+	rolloutMsgType
 )
 
 var msgTypes = []string{
@@ -61,6 +64,9 @@ var msgTypes = []string{
 	"Start task",
 	"Get task state",
 	"Get task state response",
+	"Validate task",
+	"Validate task response",
+	"Rollout",
 }
 
 func (m msgType) String() string {
@@ -203,6 +209,37 @@ type taskStateQueryResponseMsg struct {
 	State core.TaskState
 }
 
+// validateTaskMsg asks a member whether it can create a task, before the
+// task is shared with it. It is sent to the member directly.
+type validateTaskMsg struct {
+	UUID          string
+	From          string
+	AgreementName string
+	// Task is the task creation request encoded in JSON
+	Task []byte
+}
+
+type validateTaskResponseMsg struct {
+	UUID   string
+	From   string
+	Errors []string
+}
+
+// rolloutMsg tells a member the state of a rollout of its agreement, and
+// whether it is one of the members the new task of the rollout is applied
+// to, or reverted on. It is sent to every member of the agreement directly.
+type rolloutMsg struct {
+	// LTime orders the messages of the rollouts of an agreement, a message
+	// older than the last one handled being ignored
+	LTime LTime
+	// Rollout is the rollout encoded in JSON
+	Rollout []byte
+	Targets []string
+	Revert  bool
+	// Task is the creation request of the new task encoded in JSON
+	Task []byte
+}
+
 type fullStateMsg struct {
 	LTime               LTime
 	PluginMsgs          []*pluginMsg
@@ -214,6 +251,11 @@ type fullStateMsg struct {
 
 	Agreements map[string]*agreement.Agreement
 	Members    map[string]*agreement.Member
+
+	// From is the member which sent the state at Time, the UnixNano of its
+	// clock, to check the clocks of the members against each other
+	From string
+	Time int64
 }
 
 func decodeMessage(buf []byte, out interface{}) error {
@@ -0,0 +1,98 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tribe
+
+import (
+	"net"
+	"testing"
+
+	"github.com/hashicorp/memberlist"
+
+	"github.com/intelsdi-x/snap/mgmt/tribe/agreement"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestResolveMember(t *testing.T) {
+	Convey("Given a member of the tribe", t, func() {
+		m := agreement.NewMember(&memberlist.Node{Name: "aggregator", Addr: net.ParseIP("10.0.0.2")})
+		m.Tags = map[string]string{
+			agreement.RestPort:               "8181",
+			agreement.RestProtocol:           "https",
+			agreement.RestInsecureSkipVerify: "true",
+		}
+		tr := &tribe{
+			config:  &Config{RestAPIPassword: "secret"},
+			members: map[string]*agreement.Member{"aggregator": m},
+		}
+		Convey("its REST API is resolved from its name", func() {
+			r, err := tr.ResolveMember("aggregator")
+			So(err, ShouldBeNil)
+			So(r.URL, ShouldEqual, "https://10.0.0.2:8181")
+			So(r.InsecureSkipVerify, ShouldBeTrue)
+			So(r.Password, ShouldEqual, "secret")
+		})
+		Convey("an unknown member is not resolved", func() {
+			_, err := tr.ResolveMember("edge")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, errUnknownMember.Error())
+		})
+	})
+}
+
+func TestResolveAggregator(t *testing.T) {
+	Convey("Given a tribe", t, func() {
+		member := func(name, ip string, tags map[string]string) *agreement.Member {
+			m := agreement.NewMember(&memberlist.Node{Name: name, Addr: net.ParseIP(ip)})
+			m.Tags = map[string]string{
+				agreement.RestPort:     "8181",
+				agreement.RestProtocol: "http",
+			}
+			for k, v := range tags {
+				m.Tags[k] = v
+			}
+			return m
+		}
+		tr := &tribe{
+			config: &Config{},
+			members: map[string]*agreement.Member{
+				"edge": member("edge", "10.0.0.1", nil),
+			},
+		}
+		Convey("no aggregator is resolved when no member has the role", func() {
+			_, _, err := tr.ResolveAggregator()
+			So(err, ShouldEqual, errNoAggregator)
+		})
+		Convey("the member with the aggregator role is resolved", func() {
+			tr.members["agg-b"] = member("agg-b", "10.0.0.3", map[string]string{agreement.Aggregator: "true"})
+			tr.members["agg-a"] = member("agg-a", "10.0.0.2", map[string]string{agreement.Aggregator: "true"})
+			name, r, err := tr.ResolveAggregator()
+			So(err, ShouldBeNil)
+			So(name, ShouldEqual, "agg-a")
+			So(r.URL, ShouldEqual, "http://10.0.0.2:8181")
+			Convey("unless it is in maintenance mode", func() {
+				tr.members["agg-a"].Tags[agreement.Maintenance] = agreement.MaintenancePaused
+				name, _, err := tr.ResolveAggregator()
+				So(err, ShouldBeNil)
+				So(name, ShouldEqual, "agg-b")
+			})
+		})
+	})
+}
@@ -0,0 +1,361 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tribe
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/pborman/uuid"
+
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
+	"github.com/intelsdi-x/snap/mgmt/tribe/agreement"
+	"github.com/intelsdi-x/snap/mgmt/tribe/worker"
+)
+
+// rolloutCheckInterval is how often the new task of a rollout is checked on
+// the members it was applied to.
+var rolloutCheckInterval = 5 * time.Second
+
+// StartRollout replaces a task of an agreement by the task of the request on
+// the canaries the options select, and returns the rollout started. The new
+// task bakes on the canaries for the bake period of the options. It is rolled
+// back as soon as it fails on one of them, and applied to the other members
+// of the agreement otherwise. A rollout of the agreement still in progress
+// is rolled back first when the member running it left the tribe.
+func (t *tribe) StartRollout(agreementName, taskID string, tr *request.TaskCreationRequest, opts agreement.RolloutOptions) (*agreement.Rollout, serror.SnapError) {
+	fields := log.Fields{
+		"agreement": agreementName,
+		"task-id":   taskID,
+	}
+	local := t.memberlist.LocalNode().Name
+	t.mutex.RLock()
+	a, ok := t.agreements[agreementName]
+	if !ok {
+		t.mutex.RUnlock()
+		return nil, serror.New(errAgreementDoesNotExist, fields)
+	}
+	if ok, _ := a.TaskAgreement.Tasks.Contains(agreement.Task{ID: taskID}); !ok {
+		t.mutex.RUnlock()
+		return nil, serror.New(errTaskDoesNotExist, fields)
+	}
+	if _, ok := a.Members[local]; !ok {
+		t.mutex.RUnlock()
+		return nil, serror.New(errNotAMember, fields)
+	}
+	members := make([]string, 0, len(a.Members))
+	for name := range a.Members {
+		members = append(members, name)
+	}
+	t.mutex.RUnlock()
+	sort.Strings(members)
+
+	// the new task is only rolled out once every member can create it
+	if t.taskManager == nil {
+		return nil, serror.New(errTaskManagerNotSet, fields)
+	}
+	if errs := worker.ValidateTask(t.taskManager, tr); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	if serr := t.ValidateTask(tr); serr != nil {
+		return nil, serr
+	}
+	body, err := json.Marshal(tr)
+	if err != nil {
+		return nil, serror.New(err, fields)
+	}
+
+	now := time.Now()
+	r := &agreement.Rollout{
+		ID:         uuid.New(),
+		Agreement:  agreementName,
+		OldTaskID:  taskID,
+		NewTaskID:  uuid.New(),
+		Canaries:   agreement.Canaries(members, opts),
+		Members:    members,
+		Owner:      local,
+		BakePeriod: opts.BakePeriod.String(),
+		State:      agreement.RolloutBaking,
+		Started:    now,
+		Updated:    now,
+	}
+	t.abandonOrphanedRollout(agreementName)
+	t.mutex.Lock()
+	if cur, ok := t.rollouts[agreementName]; ok && !cur.Done() {
+		t.mutex.Unlock()
+		return nil, serror.New(errRolloutInProgress, fields)
+	}
+	cp := *r
+	t.rollouts[agreementName] = &cp
+	t.mutex.Unlock()
+
+	t.logger.WithFields(fields).WithFields(log.Fields{
+		"_block":   "start-rollout",
+		"rollout":  r.ID,
+		"canaries": r.Canaries,
+	}).Info("rollout started")
+	t.sendRollout(r, r.Canaries, false, body)
+	go t.runRollout(*r, opts.BakePeriod, body)
+	return &cp, nil
+}
+
+// GetRollout returns the last rollout of an agreement.
+func (t *tribe) GetRollout(agreementName string) (*agreement.Rollout, serror.SnapError) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	r, ok := t.rollouts[agreementName]
+	if !ok {
+		return nil, serror.New(errRolloutDoesNotExist, map[string]interface{}{
+			"agreement": agreementName,
+		})
+	}
+	cp := *r
+	return &cp, nil
+}
+
+// abandonOrphanedRollout rolls back the rollout of the agreement in progress
+// when its owner left the tribe, which would otherwise block the next ones.
+func (t *tribe) abandonOrphanedRollout(agreementName string) {
+	t.mutex.RLock()
+	cur, ok := t.rollouts[agreementName]
+	if !ok || cur.Done() {
+		t.mutex.RUnlock()
+		return
+	}
+	_, alive := t.members[cur.Owner]
+	r := *cur
+	t.mutex.RUnlock()
+	if alive {
+		return
+	}
+	logger := t.logger.WithFields(log.Fields{
+		"_block":    "abandon-rollout",
+		"agreement": r.Agreement,
+		"rollout":   r.ID,
+		"owner":     r.Owner,
+	})
+	t.rollBack(&r, r.Members, map[string]string{r.Owner: errRolloutOwnerLeft.Error()}, logger)
+}
+
+// runRollout watches the new task of the rollout on the canaries for the
+// bake period, then either promotes the rollout or rolls it back.
+func (t *tribe) runRollout(r agreement.Rollout, bake time.Duration, task []byte) {
+	logger := t.logger.WithFields(log.Fields{
+		"_block":    "run-rollout",
+		"agreement": r.Agreement,
+		"rollout":   r.ID,
+	})
+	ticker := time.NewTicker(rolloutCheckInterval)
+	defer ticker.Stop()
+	deadline := time.After(bake)
+	for baking := true; baking; {
+		select {
+		case <-ticker.C:
+			if failures := t.rolloutFailures(&r, r.Canaries); len(failures) > 0 {
+				t.rollBack(&r, r.Canaries, failures, logger)
+				return
+			}
+		case <-deadline:
+			baking = false
+		}
+	}
+	if failures := t.rolloutFailures(&r, r.Canaries); len(failures) > 0 {
+		t.rollBack(&r, r.Canaries, failures, logger)
+		return
+	}
+
+	logger.Info("rollout baked on its canaries, promoting it")
+	r.State = agreement.RolloutPromoting
+	canaries := map[string]bool{}
+	for _, name := range r.Canaries {
+		canaries[name] = true
+	}
+	var others []string
+	for _, name := range r.Members {
+		if !canaries[name] {
+			others = append(others, name)
+		}
+	}
+	t.sendRollout(&r, others, false, task)
+	time.Sleep(rolloutCheckInterval)
+	// the new task failing on the other members is rolled back on every
+	// member, the canaries included, leaving the agreement as it was
+	if failures := t.rolloutFailures(&r, others); len(failures) > 0 {
+		t.rollBack(&r, r.Members, failures, logger)
+		return
+	}
+
+	// the members joining the agreement from now on get the new task only
+	if serr := t.AddTask(r.Agreement, agreement.Task{ID: r.NewTaskID, StartOnCreate: true}); serr != nil {
+		logger.WithFields(serr.Fields()).Error(serr)
+	}
+	if serr := t.RemoveTask(r.Agreement, agreement.Task{ID: r.OldTaskID}); serr != nil {
+		logger.WithFields(serr.Fields()).Error(serr)
+	}
+	r.State = agreement.RolloutCompleted
+	t.sendRollout(&r, nil, false, nil)
+	logger.Info("rollout completed")
+}
+
+// rollBack reverts the new task of the rollout on the targets it was applied
+// to.
+func (t *tribe) rollBack(r *agreement.Rollout, targets []string, failures map[string]string, logger *log.Entry) {
+	logger.WithField("failures", failures).Warn("rolling back new task")
+	r.Failures = failures
+	r.State = agreement.RolloutRolledBack
+	t.sendRollout(r, targets, true, nil)
+}
+
+// rolloutFailures returns why the new task of the rollout fails on the given
+// members, by member name.
+func (t *tribe) rolloutFailures(r *agreement.Rollout, names []string) map[string]string {
+	failures := map[string]string{}
+	for _, name := range names {
+		t.mutex.RLock()
+		m, ok := t.members[name]
+		t.mutex.RUnlock()
+		if !ok {
+			failures[name] = errUnknownMember.Error()
+			continue
+		}
+		if f := worker.TaskFailure(m, t, r.NewTaskID); f != "" {
+			failures[name] = f
+		}
+	}
+	return failures
+}
+
+// sendRollout tells the members of the agreement, and the members the
+// rollout started with, the state of the rollout and the targets to apply or
+// revert the new task of the rollout.
+func (t *tribe) sendRollout(r *agreement.Rollout, targets []string, revert bool, task []byte) {
+	logger := t.logger.WithFields(log.Fields{
+		"_block":    "send-rollout",
+		"agreement": r.Agreement,
+		"rollout":   r.ID,
+	})
+	r.Updated = time.Now()
+	b, err := json.Marshal(r)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	msg := &rolloutMsg{
+		LTime:   t.clock.Increment(),
+		Rollout: b,
+		Targets: targets,
+		Revert:  revert,
+		Task:    task,
+	}
+	raw, err := encodeMessage(rolloutMsgType, msg)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	recipients := map[string]bool{}
+	for _, name := range r.Members {
+		recipients[name] = true
+	}
+	t.mutex.RLock()
+	if a, ok := t.agreements[r.Agreement]; ok {
+		for name := range a.Members {
+			recipients[name] = true
+		}
+	}
+	t.mutex.RUnlock()
+	local := t.memberlist.LocalNode().Name
+	for _, n := range t.memberlist.Members() {
+		if n.Name == local || !recipients[n.Name] {
+			continue
+		}
+		if err := t.memberlist.SendReliable(n, raw); err != nil {
+			logger.WithFields(log.Fields{
+				"member": n.Name,
+				"err":    err,
+			}).Warn("failed to send rollout")
+		}
+	}
+	t.handleRollout(msg)
+}
+
+// handleRollout records the state of the rollout of the message, and
+// applies or reverts its new task when this member is one of its targets. A
+// message older than the last one handled for the agreement is ignored.
+func (t *tribe) handleRollout(msg *rolloutMsg) {
+	logger := t.logger.WithFields(log.Fields{
+		"_block": "handle-rollout",
+	})
+	r := &agreement.Rollout{}
+	if err := json.Unmarshal(msg.Rollout, r); err != nil {
+		logger.Error(err)
+		return
+	}
+	logger = logger.WithFields(log.Fields{
+		"agreement": r.Agreement,
+		"rollout":   r.ID,
+	})
+	t.clock.Update(msg.LTime)
+	t.mutex.Lock()
+	if last, ok := t.rolloutTimes[r.Agreement]; ok && msg.LTime <= last {
+		t.mutex.Unlock()
+		logger.WithFields(log.Fields{
+			"ltime": msg.LTime,
+			"last":  last,
+		}).Debug("ignoring rollout message older than the last one handled")
+		return
+	}
+	t.rolloutTimes[r.Agreement] = msg.LTime
+	t.rollouts[r.Agreement] = r
+	t.mutex.Unlock()
+
+	local := t.memberlist.LocalNode().Name
+	targeted := false
+	for _, name := range msg.Targets {
+		if name == local {
+			targeted = true
+		}
+	}
+	if !targeted {
+		return
+	}
+	if t.taskManager == nil {
+		logger.Error(errTaskManagerNotSet)
+		return
+	}
+	var errs []serror.SnapError
+	if msg.Revert {
+		logger.Info("reverting new task of rollout")
+		errs = worker.RevertTask(t.taskManager, r.OldTaskID, r.NewTaskID)
+	} else {
+		logger.Info("applying new task of rollout")
+		tr := &request.TaskCreationRequest{}
+		if err := json.Unmarshal(msg.Task, tr); err != nil {
+			logger.Error(err)
+			return
+		}
+		errs = worker.ReplaceTask(t.taskManager, r.OldTaskID, r.NewTaskID, tr)
+	}
+	for _, err := range errs {
+		logger.WithFields(err.Fields()).Error(err)
+	}
+}
@@ -0,0 +1,73 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tribe
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/intelsdi-x/snap/mgmt/tribe/agreement"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func rolloutMessage(ltime LTime, r *agreement.Rollout) *rolloutMsg {
+	b, err := json.Marshal(r)
+	if err != nil {
+		panic(err)
+	}
+	return &rolloutMsg{LTime: ltime, Rollout: b}
+}
+
+func TestRollout(t *testing.T) {
+	tr := getTribes(1, nil)[0]
+	defer tr.memberlist.Shutdown()
+	local := tr.memberlist.LocalNode().Name
+	Convey("Given a member handling rollout messages", t, func() {
+		tr.mutex.Lock()
+		tr.rollouts = map[string]*agreement.Rollout{}
+		tr.rolloutTimes = map[string]LTime{}
+		tr.mutex.Unlock()
+
+		Convey("a message older than the last one handled is ignored", func() {
+			tr.handleRollout(rolloutMessage(5, &agreement.Rollout{ID: "r1", Agreement: "a1", Owner: local, State: agreement.RolloutCompleted}))
+			tr.handleRollout(rolloutMessage(3, &agreement.Rollout{ID: "r1", Agreement: "a1", Owner: local, State: agreement.RolloutBaking}))
+			r, serr := tr.GetRollout("a1")
+			So(serr, ShouldBeNil)
+			So(r.State, ShouldEqual, agreement.RolloutCompleted)
+		})
+		Convey("a rollout in progress", func() {
+			tr.handleRollout(rolloutMessage(tr.clock.Increment(), &agreement.Rollout{ID: "r1", Agreement: "a1", Owner: local, State: agreement.RolloutBaking}))
+			Convey("is kept while its owner is in the tribe", func() {
+				tr.abandonOrphanedRollout("a1")
+				r, _ := tr.GetRollout("a1")
+				So(r.State, ShouldEqual, agreement.RolloutBaking)
+			})
+			Convey("is rolled back once its owner left the tribe", func() {
+				tr.handleRollout(rolloutMessage(tr.clock.Increment(), &agreement.Rollout{ID: "r2", Agreement: "a1", Owner: "gone", State: agreement.RolloutBaking}))
+				tr.abandonOrphanedRollout("a1")
+				r, _ := tr.GetRollout("a1")
+				So(r.ID, ShouldEqual, "r2")
+				So(r.State, ShouldEqual, agreement.RolloutRolledBack)
+				So(r.Failures, ShouldResemble, map[string]string{"gone": errRolloutOwnerLeft.Error()})
+			})
+		})
+	})
+}
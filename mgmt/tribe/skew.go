@@ -0,0 +1,88 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tribe
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core/tribe_event"
+)
+
+// checkClockSkew records how far the clock of a member, which sent its state
+// at sent, is off the local clock, which received the state at received. The
+// skew measured includes the time the state took to get here, a small part of
+// the threshold on a LAN. A warning is logged whenever the skew exceeds the
+// threshold, and an event emitted when it starts exceeding it.
+func (t *tribe) checkClockSkew(member string, sent, received time.Time) {
+	if member == t.config.Name {
+		return
+	}
+	skew := sent.Sub(received)
+	threshold := t.config.MaxClockSkew.Duration
+
+	t.clockSkewMutex.Lock()
+	previous, known := t.clockSkews[member]
+	t.clockSkews[member] = skew
+	t.clockSkewMutex.Unlock()
+
+	if threshold <= 0 {
+		return
+	}
+	wasSkewed := known && abs(previous) > threshold
+	logger := t.logger.WithFields(log.Fields{
+		"_block":    "check-clock-skew",
+		"member":    member,
+		"skew":      skew.String(),
+		"threshold": threshold.String(),
+	})
+	if abs(skew) <= threshold {
+		if wasSkewed {
+			logger.Info("clock of the member back within the threshold")
+		}
+		return
+	}
+	logger.Warn("clock of the member is off the local clock, windowed schedules and metric timestamps are inconsistent across the tribe")
+	if !wasSkewed {
+		t.eventManager.Emit(&tribe_event.ClockSkewEvent{
+			Name:      member,
+			Skew:      skew,
+			Threshold: threshold,
+		})
+	}
+}
+
+// ClockSkew returns how far the clock of a member is ahead of the local clock,
+// negative when it is behind, as of the last exchange of state with it, or
+// false when no state was exchanged with it yet
+func (t *tribe) ClockSkew(member string) (time.Duration, bool) {
+	t.clockSkewMutex.Lock()
+	defer t.clockSkewMutex.Unlock()
+	skew, ok := t.clockSkews[member]
+	return skew, ok
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
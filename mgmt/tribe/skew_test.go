@@ -0,0 +1,99 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tribe
+
+import (
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/gomit"
+	"github.com/vrischmann/jsonutil"
+
+	"github.com/intelsdi-x/snap/core/tribe_event"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type clockSkewHandler struct {
+	events chan *tribe_event.ClockSkewEvent
+}
+
+func (h *clockSkewHandler) HandleGomitEvent(e gomit.Event) {
+	if v, ok := e.Body.(*tribe_event.ClockSkewEvent); ok {
+		h.events <- v
+	}
+}
+
+func TestClockSkew(t *testing.T) {
+	Convey("Given a member checking the clocks of the others", t, func() {
+		tr := &tribe{
+			config:       &Config{Name: "local", MaxClockSkew: jsonutil.Duration{time.Second}},
+			clockSkews:   map[string]time.Duration{},
+			eventManager: gomit.NewEventController(),
+			logger:       logger,
+		}
+		h := &clockSkewHandler{events: make(chan *tribe_event.ClockSkewEvent, 10)}
+		tr.eventManager.RegisterHandler("test", h)
+		// gomit runs each handler in a goroutine of its own
+		received := func() []*tribe_event.ClockSkewEvent {
+			var events []*tribe_event.ClockSkewEvent
+			for {
+				select {
+				case e := <-h.events:
+					events = append(events, e)
+				case <-time.After(100 * time.Millisecond):
+					return events
+				}
+			}
+		}
+		now := time.Now()
+
+		Convey("a skew within the threshold is recorded without an event", func() {
+			tr.checkClockSkew("remote", now.Add(-500*time.Millisecond), now)
+			skew, ok := tr.ClockSkew("remote")
+			So(ok, ShouldBeTrue)
+			So(skew, ShouldEqual, -500*time.Millisecond)
+			So(received(), ShouldBeEmpty)
+		})
+		Convey("a skew past the threshold emits an event once", func() {
+			tr.checkClockSkew("remote", now.Add(3*time.Second), now)
+			tr.checkClockSkew("remote", now.Add(4*time.Second), now)
+			events := received()
+			So(events, ShouldHaveLength, 1)
+			e := events[0]
+			So(e.Name, ShouldEqual, "remote")
+			So(e.Skew, ShouldEqual, 3*time.Second)
+			So(e.Threshold, ShouldEqual, time.Second)
+
+			Convey("and again once it was back within the threshold", func() {
+				tr.checkClockSkew("remote", now, now)
+				tr.checkClockSkew("remote", now.Add(-2*time.Second), now)
+				So(received(), ShouldHaveLength, 1)
+			})
+		})
+		Convey("the local member and unknown members have no skew", func() {
+			tr.checkClockSkew("local", now.Add(time.Hour), now)
+			_, ok := tr.ClockSkew("local")
+			So(ok, ShouldBeFalse)
+			_, ok = tr.ClockSkew("other")
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,360 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tribe
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/hashicorp/memberlist"
+
+	"github.com/intelsdi-x/snap/pkg/netaddr"
+)
+
+// The first byte written on a connection between members tells whether it
+// carries gossip packets or a memberlist stream.
+const (
+	packetConn byte = iota
+	streamConn
+)
+
+const (
+	// packets are bounded the way UDP datagrams would be
+	maxPacketSize = 65536
+	// bounds reading what an accepted connection is for
+	headerTimeout = 10 * time.Second
+)
+
+// tlsTransport is a memberlist.Transport carrying the gossip packets as well
+// as the streams over TLS connections the members authenticate each other
+// on. Packets to a member are written as length prefixed frames on a
+// connection kept open for it, which starts with the address this member
+// advertises so that the replies find their way back.
+type tlsTransport struct {
+	config   *tls.Config
+	timeout  time.Duration
+	listener net.Listener
+	packetCh chan *memberlist.Packet
+	streamCh chan net.Conn
+	logger   *log.Entry
+	wg       sync.WaitGroup
+
+	mutex     sync.Mutex
+	advertise string
+	outgoing  map[string]*packetSender
+	incoming  map[net.Conn]struct{}
+	shutdown  chan struct{}
+}
+
+// packetSender holds the connection the packets to one member are written on.
+type packetSender struct {
+	sync.Mutex
+	conn net.Conn
+}
+
+// newTLSTransport listens on addr and port, dialing the other members and
+// writing packets to them within timeout.
+func newTLSTransport(addr string, port int, config *tls.Config, timeout time.Duration) (*tlsTransport, error) {
+	ln, err := tls.Listen("tcp", net.JoinHostPort(addr, strconv.Itoa(port)), config)
+	if err != nil {
+		return nil, err
+	}
+	t := &tlsTransport{
+		config:   config,
+		timeout:  timeout,
+		listener: ln,
+		packetCh: make(chan *memberlist.Packet),
+		streamCh: make(chan net.Conn),
+		logger:   logger.WithField("_block", "tls-transport"),
+		outgoing: map[string]*packetSender{},
+		incoming: map[net.Conn]struct{}{},
+		shutdown: make(chan struct{}),
+	}
+	t.wg.Add(1)
+	go t.listen()
+	return t, nil
+}
+
+// FinalAdvertiseAddr returns the address and port given, else the ones the
+// transport listens on.
+func (t *tlsTransport) FinalAdvertiseAddr(ip string, port int) (net.IP, int, error) {
+	laddr := t.listener.Addr().(*net.TCPAddr)
+	var addr net.IP
+	if ip != "" {
+		if addr = net.ParseIP(ip); addr == nil {
+			return nil, 0, fmt.Errorf("Failed to parse advertise address %q", ip)
+		}
+	} else {
+		addr = laddr.IP
+		if addr.IsUnspecified() {
+			addr = net.ParseIP(netaddr.Default())
+		}
+		port = laddr.Port
+	}
+	if ip4 := addr.To4(); ip4 != nil {
+		addr = ip4
+	}
+	if port == 0 {
+		port = laddr.Port
+	}
+	t.mutex.Lock()
+	t.advertise = net.JoinHostPort(addr.String(), strconv.Itoa(port))
+	t.mutex.Unlock()
+	return addr, port, nil
+}
+
+// WriteTo writes the packet on the connection kept for addr, dialing it when
+// there is none or the one kept turns out to be closed.
+func (t *tlsTransport) WriteTo(b []byte, addr string) (time.Time, error) {
+	t.mutex.Lock()
+	s, ok := t.outgoing[addr]
+	if !ok {
+		s = &packetSender{}
+		t.outgoing[addr] = s
+	}
+	t.mutex.Unlock()
+
+	s.Lock()
+	defer s.Unlock()
+	if s.conn != nil {
+		if err := t.writePacket(s.conn, b); err == nil {
+			return time.Now(), nil
+		}
+		s.conn.Close()
+		s.conn = nil
+	}
+	conn, err := t.dial(addr, packetConn, t.timeout)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := t.writePacket(conn, b); err != nil {
+		conn.Close()
+		return time.Time{}, err
+	}
+	s.conn = conn
+	return time.Now(), nil
+}
+
+func (t *tlsTransport) PacketCh() <-chan *memberlist.Packet {
+	return t.packetCh
+}
+
+func (t *tlsTransport) DialTimeout(addr string, timeout time.Duration) (net.Conn, error) {
+	return t.dial(addr, streamConn, timeout)
+}
+
+func (t *tlsTransport) StreamCh() <-chan net.Conn {
+	return t.streamCh
+}
+
+// Shutdown closes the listener and every connection carrying packets, and
+// waits for the goroutines reading them.
+func (t *tlsTransport) Shutdown() error {
+	t.mutex.Lock()
+	select {
+	case <-t.shutdown:
+		t.mutex.Unlock()
+		return nil
+	default:
+	}
+	close(t.shutdown)
+	for conn := range t.incoming {
+		conn.Close()
+	}
+	senders := make([]*packetSender, 0, len(t.outgoing))
+	for _, s := range t.outgoing {
+		senders = append(senders, s)
+	}
+	t.mutex.Unlock()
+
+	err := t.listener.Close()
+	for _, s := range senders {
+		s.Lock()
+		if s.conn != nil {
+			s.conn.Close()
+			s.conn = nil
+		}
+		s.Unlock()
+	}
+	t.wg.Wait()
+	return err
+}
+
+// dial opens a connection to the member at addr and tells it what the
+// connection is for.
+func (t *tlsTransport) dial(addr string, kind byte, timeout time.Duration) (net.Conn, error) {
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, t.config)
+	if err != nil {
+		return nil, err
+	}
+	header := []byte{kind}
+	if kind == packetConn {
+		t.mutex.Lock()
+		header = append(header, byte(len(t.advertise)))
+		header = append(header, t.advertise...)
+		t.mutex.Unlock()
+	}
+	conn.SetWriteDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(header); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetWriteDeadline(time.Time{})
+	return conn, nil
+}
+
+func (t *tlsTransport) writePacket(conn net.Conn, b []byte) error {
+	frame := make([]byte, 4+len(b))
+	binary.BigEndian.PutUint32(frame, uint32(len(b)))
+	copy(frame[4:], b)
+	conn.SetWriteDeadline(time.Now().Add(t.timeout))
+	_, err := conn.Write(frame)
+	return err
+}
+
+func (t *tlsTransport) listen() {
+	defer t.wg.Done()
+	for {
+		conn, err := t.listener.Accept()
+		if err != nil {
+			select {
+			case <-t.shutdown:
+				return
+			default:
+			}
+			t.logger.Error(err)
+			continue
+		}
+		t.wg.Add(1)
+		go t.handle(conn)
+	}
+}
+
+// handle reads what an accepted connection is for and hands it over to
+// memberlist.
+func (t *tlsTransport) handle(conn net.Conn) {
+	defer t.wg.Done()
+	conn.SetReadDeadline(time.Now().Add(headerTimeout))
+	kind := make([]byte, 1)
+	if _, err := io.ReadFull(conn, kind); err != nil {
+		t.logger.WithField("remote", conn.RemoteAddr().String()).Error(err)
+		conn.Close()
+		return
+	}
+	switch kind[0] {
+	case streamConn:
+		conn.SetReadDeadline(time.Time{})
+		select {
+		case t.streamCh <- conn:
+		case <-t.shutdown:
+			conn.Close()
+		}
+	case packetConn:
+		t.readPackets(conn)
+	default:
+		t.logger.WithField("remote", conn.RemoteAddr().String()).Errorf("unknown connection type %d", kind[0])
+		conn.Close()
+	}
+}
+
+// readPackets hands the packets read from conn over to memberlist until the
+// member writing them closes it.
+func (t *tlsTransport) readPackets(conn net.Conn) {
+	defer conn.Close()
+	t.mutex.Lock()
+	select {
+	case <-t.shutdown:
+		t.mutex.Unlock()
+		return
+	default:
+	}
+	t.incoming[conn] = struct{}{}
+	t.mutex.Unlock()
+	defer func() {
+		t.mutex.Lock()
+		delete(t.incoming, conn)
+		t.mutex.Unlock()
+	}()
+
+	logger := t.logger.WithField("remote", conn.RemoteAddr().String())
+	from, err := readSender(conn)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+	for {
+		buf, err := readPacket(conn)
+		ts := time.Now()
+		if err != nil {
+			select {
+			case <-t.shutdown:
+			default:
+				if err != io.EOF {
+					logger.Error(err)
+				}
+			}
+			return
+		}
+		select {
+		case t.packetCh <- &memberlist.Packet{Buf: buf, From: from, Timestamp: ts}:
+		case <-t.shutdown:
+			return
+		}
+	}
+}
+
+// readSender reads the address the member writing the packets advertises.
+func readSender(r io.Reader) (net.Addr, error) {
+	size := make([]byte, 1)
+	if _, err := io.ReadFull(r, size); err != nil {
+		return nil, err
+	}
+	if size[0] == 0 {
+		return nil, fmt.Errorf("no address given for the packets")
+	}
+	addr := make([]byte, size[0])
+	if _, err := io.ReadFull(r, addr); err != nil {
+		return nil, err
+	}
+	return net.ResolveTCPAddr("tcp", string(addr))
+}
+
+func readPacket(r io.Reader) ([]byte, error) {
+	var size uint32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
+	if size == 0 || size > maxPacketSize {
+		return nil, fmt.Errorf("invalid packet size %d", size)
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
@@ -0,0 +1,161 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tribe
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// writePKI writes a CA certificate to dir along with a certificate signed by
+// it for 127.0.0.1, and returns their paths.
+func writePKI(dir string) (cert, key, ca string) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "tribe CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		panic(err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	memberKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	memberTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "member"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	memberDER, err := x509.CreateCertificate(rand.Reader, memberTmpl, caCert, &memberKey.PublicKey, caKey)
+	if err != nil {
+		panic(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(memberKey)
+
+	cert = filepath.Join(dir, "member.crt")
+	key = filepath.Join(dir, "member.key")
+	ca = filepath.Join(dir, "ca.crt")
+	ioutil.WriteFile(cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: memberDER}), 0644)
+	ioutil.WriteFile(key, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	ioutil.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0644)
+	return cert, key, ca
+}
+
+func getTLSTestConfig(name, seed, cert, key, ca string) *Config {
+	cfg := getTestConfig()
+	cfg.Name = name
+	cfg.Seed = seed
+	cfg.TLSCertificate = cert
+	cfg.TLSKey = key
+	cfg.TLSCACertificate = ca
+	return cfg
+}
+
+func TestTribeTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tribe-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cert, key, ca := writePKI(dir)
+	otherDir := filepath.Join(dir, "other")
+	os.Mkdir(otherDir, 0755)
+	otherCert, otherKey, otherCA := writePKI(otherDir)
+
+	Convey("Tribe members gossiping over TLS", t, func() {
+		numOfTribes := 3
+		tribes := []*tribe{}
+		seed := ""
+		for i := 0; i < numOfTribes; i++ {
+			cfg := getTLSTestConfig(fmt.Sprintf("tls-member-%d", i), seed, cert, key, ca)
+			So(cfg.Validate(), ShouldBeEmpty)
+			tr, err := New(cfg)
+			So(err, ShouldBeNil)
+			So(cfg.MemberlistConfig.Transport, ShouldHaveSameTypeAs, &tlsTransport{})
+			tribes = append(tribes, tr)
+			if seed == "" {
+				seed = fmt.Sprintf("127.0.0.1:%d", cfg.BindPort)
+			}
+		}
+		defer func() {
+			for _, tr := range tribes {
+				tr.memberlist.Shutdown()
+			}
+		}()
+
+		Convey("agree on membership", func() {
+			for _, tr := range tribes {
+				So(waitFor(func() bool { return len(tr.memberlist.Members()) == numOfTribes }), ShouldBeTrue)
+			}
+			Convey("and share agreements", func() {
+				So(tribes[numOfTribes-1].AddAgreement("tls-agreement"), ShouldBeNil)
+				for _, tr := range tribes {
+					So(waitFor(func() bool {
+						tr.mutex.RLock()
+						defer tr.mutex.RUnlock()
+						_, ok := tr.agreements["tls-agreement"]
+						return ok
+					}), ShouldBeTrue)
+				}
+				Convey("while a member with a certificate from another CA cannot join", func() {
+					cfg := getTLSTestConfig("tls-intruder", seed, otherCert, otherKey, otherCA)
+					_, err := New(cfg)
+					So(err, ShouldEqual, errMemberlistJoin)
+				})
+			})
+		})
+	})
+}
+
+func waitFor(cond func() bool) bool {
+	timeout := time.After(15 * time.Second)
+	for !cond() {
+		select {
+		case <-timeout:
+			return false
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	return true
+}
@@ -21,10 +21,12 @@ package tribe
 
 import (
 	"bytes"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"math"
 	"net"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -35,8 +37,11 @@ import (
 	"github.com/intelsdi-x/snap/core/control_event"
 	"github.com/intelsdi-x/snap/core/scheduler_event"
 	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/core/tribe_event"
 	"github.com/intelsdi-x/snap/mgmt/tribe/agreement"
 	"github.com/intelsdi-x/snap/mgmt/tribe/worker"
+	"github.com/intelsdi-x/snap/pkg/netaddr"
+	"github.com/intelsdi-x/snap/pkg/reqsign"
 	"github.com/pborman/uuid"
 
 	"github.com/hashicorp/go-msgpack/codec"
@@ -59,6 +64,11 @@ var (
 	errMemberlistJoin                 = errors.New("Failed to join tribe")
 	errPluginCatalogNotSet            = errors.New("Plugin Catalog not set")
 	errTaskManagerNotSet              = errors.New("Task Manager not set")
+	errTaskNotValidOnMembers          = errors.New("Task cannot be created on every member of its agreements")
+	errRolloutInProgress              = errors.New("A rollout of the agreement is in progress")
+	errRolloutDoesNotExist            = errors.New("Rollout does not exist")
+	errRolloutOwnerLeft               = errors.New("Owner of the rollout left the tribe")
+	errNoAggregator                   = errors.New("No member of the tribe has the aggregator role")
 )
 
 var logger = log.WithFields(log.Fields{
@@ -76,9 +86,24 @@ type tribe struct {
 	logger             *log.Entry
 	taskStartStopCache *cache
 	taskStateResponses map[string]*taskStateQueryResponse
+	taskValidations    map[string]chan *validateTaskResponseMsg
+	rollouts           map[string]*agreement.Rollout
+	rolloutTimes       map[string]LTime
 	members            map[string]*agreement.Member
 	tags               map[string]string
+	tagsMutex          sync.RWMutex
 	config             *Config
+	eventManager       *gomit.EventController
+	// clockSkews holds how far the clocks of the members are off the local
+	// clock, as of the last exchange of state with them
+	clockSkews     map[string]time.Duration
+	clockSkewMutex sync.Mutex
+	// signer signs the requests made to the REST API of the other members,
+	// nil unless they are signed
+	signer *reqsign.Signer
+	// restTLS is the TLS configuration of the requests made to the REST API
+	// of the other members
+	restTLS *tls.Config
 
 	pluginCatalog   worker.ManagesPlugins
 	taskManager     worker.ManagesTasks
@@ -90,19 +115,68 @@ type tribe struct {
 }
 
 func New(cfg *Config) (*tribe, error) {
+	bindAddr := "0.0.0.0"
+	if cfg.BindAddr != "" {
+		var err error
+		if bindAddr, err = netaddr.Resolve(cfg.BindAddr); err != nil {
+			return nil, err
+		}
+	}
 	cfg.MemberlistConfig.Name = cfg.Name
-	cfg.MemberlistConfig.BindAddr = cfg.BindAddr
+	cfg.MemberlistConfig.BindAddr = bindAddr
 	cfg.MemberlistConfig.BindPort = cfg.BindPort
+	// memberlist only finds the address to advertise by itself when bound
+	// to all the IPv4 addresses
+	advertiseAddr := cfg.AdvertiseAddr
+	if advertiseAddr == "" && bindAddr != "0.0.0.0" && net.ParseIP(bindAddr).IsUnspecified() {
+		advertiseAddr = getIP()
+	}
+	if advertiseAddr != "" {
+		cfg.MemberlistConfig.AdvertiseAddr = advertiseAddr
+		cfg.MemberlistConfig.AdvertisePort = cfg.AdvertisePort
+		if cfg.AdvertisePort == 0 {
+			cfg.MemberlistConfig.AdvertisePort = cfg.BindPort
+		}
+	}
+	if cfg.GossipKey != "" {
+		key, err := cfg.gossipKey()
+		if err != nil {
+			return nil, err
+		}
+		cfg.MemberlistConfig.SecretKey = key
+	}
+	restTLS := cfg.TLSPolicy.TLSConfig()
+	var transport *tlsTransport
+	if cfg.TLSCertificate != "" {
+		tlsCfg, err := cfg.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		// the members present their certificate to the REST API of each
+		// other as well
+		restTLS.Certificates = tlsCfg.Certificates
+		transport, err = newTLSTransport(bindAddr, cfg.BindPort, tlsCfg, cfg.MemberlistConfig.ProbeTimeout)
+		if err != nil {
+			return nil, err
+		}
+		cfg.MemberlistConfig.Transport = transport
+	}
 	logger := logger.WithFields(log.Fields{
-		"port": cfg.MemberlistConfig.BindPort,
-		"addr": cfg.MemberlistConfig.BindAddr,
-		"name": cfg.MemberlistConfig.Name,
+		"port":           cfg.MemberlistConfig.BindPort,
+		"addr":           cfg.MemberlistConfig.BindAddr,
+		"advertise-addr": cfg.MemberlistConfig.AdvertiseAddr,
+		"name":           cfg.MemberlistConfig.Name,
+		"tls":            transport != nil,
 	})
 
 	tribe := &tribe{
 		agreements:         map[string]*agreement.Agreement{},
 		members:            map[string]*agreement.Member{},
 		taskStateResponses: map[string]*taskStateQueryResponse{},
+		taskValidations:    map[string]chan *validateTaskResponseMsg{},
+		rollouts:           map[string]*agreement.Rollout{},
+		rolloutTimes:       map[string]LTime{},
+		clockSkews:         map[string]time.Duration{},
 		taskStartStopCache: newCache(),
 		msgBuffer:          make([]msg, 512),
 		intentBuffer:       []msg{},
@@ -117,6 +191,14 @@ func New(cfg *Config) (*tribe, error) {
 		workerQuitChan:  make(chan struct{}),
 		workerWaitGroup: &sync.WaitGroup{},
 		config:          cfg,
+		eventManager:    gomit.NewEventController(),
+		restTLS:         restTLS,
+	}
+	if cfg.Aggregator {
+		tribe.tags[agreement.Aggregator] = "true"
+	}
+	if key := cfg.RequestSigningKey(); key != nil {
+		tribe.signer = reqsign.NewSigner(cfg.Name, key)
 	}
 
 	tribe.broadcasts = &memberlist.TransmitLimitedQueue{
@@ -132,6 +214,9 @@ func New(cfg *Config) (*tribe, error) {
 
 	ml, err := memberlist.Create(cfg.MemberlistConfig)
 	if err != nil {
+		if transport != nil {
+			transport.Shutdown()
+		}
 		logger.Error(err)
 		return nil, err
 	}
@@ -277,6 +362,122 @@ func (t *tribe) GetPluginAgreementMembers() ([]worker.Member, error) {
 	return members, nil
 }
 
+// Shard returns the shard of the metrics of a sharded task collected by this
+// member: its position among the members of the agreements sharing the
+// task, sorted by name, and their number. The shards follow the membership
+// of the agreements as members join and leave them.
+func (t *tribe) Shard(taskID string) (int, int) {
+	local := t.memberlist.LocalNode().Name
+	t.mutex.RLock()
+	names := map[string]bool{}
+	for _, a := range t.agreements {
+		if _, ok := a.Members[local]; !ok {
+			continue
+		}
+		if ok, _ := a.TaskAgreement.Tasks.Contains(agreement.Task{ID: taskID}); !ok {
+			continue
+		}
+		for name := range a.Members {
+			names[name] = true
+		}
+	}
+	t.mutex.RUnlock()
+	members := make([]string, 0, len(names))
+	for name := range names {
+		members = append(members, name)
+	}
+	sort.Strings(members)
+	for i, name := range members {
+		if name == local {
+			return i, len(members)
+		}
+	}
+	return 0, 1
+}
+
+// ResolveMember returns how to reach the REST API of the member with the
+// name, for the tasks offloading process steps to the processors of another
+// member
+func (t *tribe) ResolveMember(name string) (core.RemoteSnapd, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	m, ok := t.members[name]
+	if !ok || m.Node == nil {
+		return core.RemoteSnapd{}, fmt.Errorf("%v: %s", errUnknownMember, name)
+	}
+	return t.remoteSnapd(m), nil
+}
+
+// ResolveAggregator returns the name of the member with the aggregator role
+// and how to reach its REST API, for the tasks forwarding their metrics to
+// it. A member in maintenance mode is only picked when no other member has
+// the role, and the first member by name is picked among several.
+func (t *tribe) ResolveAggregator() (string, core.RemoteSnapd, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	var aggregator *agreement.Member
+	for _, m := range t.members {
+		if m.Node == nil || !m.IsAggregator() {
+			continue
+		}
+		if aggregator == nil ||
+			aggregator.InMaintenance() && !m.InMaintenance() ||
+			aggregator.InMaintenance() == m.InMaintenance() && m.Name < aggregator.Name {
+			aggregator = m
+		}
+	}
+	if aggregator == nil {
+		return "", core.RemoteSnapd{}, errNoAggregator
+	}
+	return aggregator.Name, t.remoteSnapd(aggregator), nil
+}
+
+// remoteSnapd returns how to reach the REST API of a member
+func (t *tribe) remoteSnapd(m *agreement.Member) core.RemoteSnapd {
+	r := core.RemoteSnapd{
+		URL:                fmt.Sprintf("%s://%s", m.GetRestProto(), net.JoinHostPort(m.GetAddr().String(), m.GetRestPort())),
+		InsecureSkipVerify: m.GetRestInsecureSkipVerify(),
+		Password:           t.GetRequestPassword(),
+		TLSConfig:          t.restTLS,
+	}
+	if t.signer != nil {
+		r.Sign = t.signer.Sign
+	}
+	return r
+}
+
+// setMaintenanceTag marks the maintenance mode of the local member in its
+// metadata and gossips it to the other members
+func (t *tribe) setMaintenanceTag(enabled, holdPublish bool) {
+	t.tagsMutex.Lock()
+	tags := make(map[string]string, len(t.tags)+1)
+	for k, v := range t.tags {
+		tags[k] = v
+	}
+	delete(tags, agreement.Maintenance)
+	if enabled {
+		tags[agreement.Maintenance] = agreement.MaintenancePaused
+		if holdPublish {
+			tags[agreement.Maintenance] = agreement.MaintenanceHoldPublish
+		}
+	}
+	t.tags = tags
+	t.tagsMutex.Unlock()
+	if err := t.memberlist.UpdateNode(time.Second); err != nil {
+		t.logger.WithFields(log.Fields{
+			"_block": "set-maintenance-tag",
+			"error":  err,
+		}).Error("Failed to update the metadata of the member")
+	}
+}
+
+// localTags returns the tags of the local member
+func (t *tribe) localTags() map[string]string {
+	t.tagsMutex.RLock()
+	defer t.tagsMutex.RUnlock()
+	return t.tags
+}
+
 // encodeTags
 func (t *tribe) encodeTags(tags map[string]string) []byte {
 	var buf bytes.Buffer
@@ -302,6 +503,11 @@ func (t *tribe) decodeTags(buf []byte) map[string]string {
 }
 
 // HandleGomitEvent handles events emitted from control
+// RegisterEventHandler registers a handler of the events emitted by tribe
+func (t *tribe) RegisterEventHandler(name string, h gomit.Handler) error {
+	return t.eventManager.RegisterHandler(name, h)
+}
+
 func (t *tribe) HandleGomitEvent(e gomit.Event) {
 	logger := t.logger.WithFields(log.Fields{
 		"_block": "handle-gomit-event",
@@ -345,6 +551,13 @@ func (t *tribe) HandleGomitEvent(e gomit.Event) {
 				}
 			}
 		}
+	case *scheduler_event.MaintenanceChangedEvent:
+		logger.WithFields(log.Fields{
+			"event":        e.Namespace(),
+			"enabled":      v.Enabled,
+			"hold-publish": v.HoldPublish,
+		}).Debugf("handling maintenance changed event")
+		t.setMaintenanceTag(v.Enabled, v.HoldPublish)
 	case *scheduler_event.TaskCreatedEvent:
 		if v.Source != "tribe" {
 			logger.WithFields(log.Fields{
@@ -708,6 +921,7 @@ func (t *tribe) processAddPluginIntents() bool {
 							Name_:    intent.Plugin.Name(),
 							Version_: intent.Plugin.Version(),
 							Type_:    ptype,
+							URL_:     intent.Plugin.URL(),
 						},
 						RequestType: worker.PluginLoadedType,
 					}
@@ -902,6 +1116,7 @@ func (t *tribe) handleAddPlugin(msg *pluginMsg) bool {
 					Name_:    msg.Plugin.Name(),
 					Version_: msg.Plugin.Version(),
 					Type_:    ptype,
+					URL_:     msg.Plugin.URL(),
 				},
 				RequestType: worker.PluginLoadedType,
 			}
@@ -1072,6 +1287,13 @@ func (t *tribe) handleMemberLeave(n *memberlist.Node) {
 			delete(t.agreements[k].Members, n.Name)
 		}
 		delete(t.members, n.Name)
+		t.clockSkewMutex.Lock()
+		delete(t.clockSkews, n.Name)
+		t.clockSkewMutex.Unlock()
+		t.eventManager.Emit(&tribe_event.MemberLeftEvent{
+			Name: n.Name,
+			Addr: n.Addr.String(),
+		})
 	}
 }
 
@@ -1297,6 +1519,7 @@ func (t *tribe) joinAgreement(msg *agreementMsg) serror.SnapError {
 						Name_:    p.Name(),
 						Version_: p.Version(),
 						Type_:    ptype,
+						URL_:     p.URL(),
 					},
 					RequestType: worker.PluginLoadedType,
 				}
@@ -1306,7 +1529,7 @@ func (t *tribe) joinAgreement(msg *agreementMsg) serror.SnapError {
 			for _, tsk := range a.TaskAgreement.Tasks {
 				state := t.TaskStateQuery(msg.Agreement(), tsk.ID)
 				startOnCreate := false
-				if state == core.TaskSpinning || state == core.TaskFiring {
+				if state == core.TaskSpinning || state == core.TaskFiring || state == core.TaskPaused {
 					startOnCreate = true
 				}
 				work := worker.TaskRequest{
@@ -1499,3 +1722,15 @@ func (t *tribe) getTimeout() time.Duration {
 func (t *tribe) GetRequestPassword() string {
 	return t.config.RestAPIPassword
 }
+
+// GetRequestSigner returns the signer of the requests made to the REST API of
+// the other members, nil unless they are signed
+func (t *tribe) GetRequestSigner() *reqsign.Signer {
+	return t.signer
+}
+
+// GetRequestTLSConfig returns the TLS configuration of the requests made to
+// the REST API of the other members
+func (t *tribe) GetRequestTLSConfig() *tls.Config {
+	return t.restTLS
+}
@@ -45,6 +45,9 @@ func (m *mockTaskManager) GetTask(id string) (core.Task, error) { return &mockTa
 func (m *mockTaskManager) CreateTaskTribe(sch schedule.Schedule, wfMap *wmap.WorkflowMap, startOnCreate bool, opts ...core.TaskOption) (core.Task, core.TaskErrors) {
 	return nil, nil
 }
+func (m *mockTaskManager) ValidateTask(sch schedule.Schedule, wfMap *wmap.WorkflowMap) core.TaskErrors {
+	return nil
+}
 func (m *mockTaskManager) StopTaskTribe(id string) []serror.SnapError  { return nil }
 func (m *mockTaskManager) StartTaskTribe(id string) []serror.SnapError { return nil }
 func (m *mockTaskManager) RemoveTaskTribe(id string) error             { return nil }
@@ -61,12 +64,42 @@ func (t *mockTask) MissedCount() uint                         { return 0 }
 func (t *mockTask) FailedCount() uint                         { return 0 }
 func (t *mockTask) LastFailureMessage() string                { return "" }
 func (t *mockTask) LastRunTime() *time.Time                   { return nil }
+func (t *mockTask) PausedUntil() *time.Time                   { return nil }
 func (t *mockTask) CreationTime() *time.Time                  { return nil }
 func (t *mockTask) DeadlineDuration() time.Duration           { return 0 }
 func (t *mockTask) SetDeadlineDuration(time.Duration)         { return }
 func (t *mockTask) SetTaskID(id string)                       { return }
 func (t *mockTask) SetStopOnFailure(uint)                     { return }
 func (t *mockTask) GetStopOnFailure() uint                    { return 0 }
+func (t *mockTask) SetOverrunPolicy(string, uint)             {}
+func (t *mockTask) OverrunPolicy() (string, uint)             { return core.OverrunSkip, 0 }
+func (t *mockTask) OverrunCount() uint                        { return 0 }
+func (t *mockTask) SetPriority(string)                        {}
+func (t *mockTask) Priority() string                          { return core.TaskPriorityNormal }
+func (t *mockTask) ShedCount() uint                           { return 0 }
+func (t *mockTask) DroppedMetricCount() uint                  { return 0 }
+func (t *mockTask) SuspectMetricCount() uint                  { return 0 }
+func (t *mockTask) BackPressure() []core.BackPressureState    { return nil }
+func (t *mockTask) SetSharded(bool)                           {}
+func (t *mockTask) Sharded() bool                             { return false }
+func (t *mockTask) SetMetricRefreshInterval(time.Duration)    {}
+func (t *mockTask) MetricRefreshInterval() time.Duration      { return 0 }
+func (t *mockTask) SetTimestampPolicy(core.TimestampPolicy)   {}
+func (t *mockTask) TimestampPolicy() core.TimestampPolicy     { return core.TimestampPolicy{} }
+func (t *mockTask) SetDescription(string)                     {}
+func (t *mockTask) Description() string                       { return "" }
+func (t *mockTask) SetDependencies([]core.TaskDependency)     {}
+func (t *mockTask) Dependencies() []core.TaskDependency       { return nil }
+func (t *mockTask) SetCreatedBy(string)                       {}
+func (t *mockTask) CreatedBy() string                         { return "" }
+func (t *mockTask) SetTenant(string)                          {}
+func (t *mockTask) Tenant() string                            { return "" }
+func (t *mockTask) RecordUpdate(string, time.Time)            {}
+func (t *mockTask) UpdatedBy() string                         { return "" }
+func (t *mockTask) UpdateTime() *time.Time                    { return nil }
+func (t *mockTask) Runs() []core.TaskRun                      { return nil }
+func (t *mockTask) SetAlertRules([]core.AlertRule)            {}
+func (t *mockTask) AlertRules() []core.AlertRule              { return nil }
 func (t *mockTask) Option(...core.TaskOption) core.TaskOption { return core.TaskDeadlineDuration(0) }
 func (t *mockTask) WMap() *wmap.WorkflowMap                   { return nil }
 func (t *mockTask) Schedule() schedule.Schedule               { return nil }
@@ -388,6 +421,16 @@ func TestTribeTaskAgreements(t *testing.T) {
 										}(t)
 									}
 									wg.Wait()
+									// the members sharing a task split its metrics in shards; the
+									// tribes outlive the runs of the conveys, which is why this is
+									// no convey of its own
+									_, n := t.Shard(task1.ID)
+									So(n, ShouldEqual, 2)
+									_, n = t.Shard(task2.ID)
+									So(n, ShouldEqual, 1)
+									i, n := tribes[2].Shard(task1.ID)
+									So(i, ShouldEqual, 0)
+									So(n, ShouldEqual, 1)
 									Convey("the agreement is queried for the state of a given task", func() {
 										t := tribes[rand.Intn(numOfTribes)]
 										resp := t.taskStateQuery(agreementName, task1.ID)
@@ -645,6 +688,29 @@ func TestTribeMembership(t *testing.T) {
 				)
 				So(len(tribes[0].members), ShouldEqual, len(tribes[i].members))
 			}
+			Convey("A member in maintenance mode is marked on the others", func() {
+				name := tribes[0].memberlist.LocalNode().Name
+				inMaintenance := func(t *tribe) bool {
+					t.mutex.RLock()
+					defer t.mutex.RUnlock()
+					m, ok := t.members[name]
+					return ok && m.InMaintenance()
+				}
+				tribes[0].setMaintenanceTag(true, true)
+				So(tribes[0].localTags()[agreement.Maintenance], ShouldEqual, agreement.MaintenanceHoldPublish)
+				timer := time.After(5 * time.Second)
+				for !inMaintenance(tribes[numOfTribes-1]) {
+					select {
+					case <-timer:
+						So(inMaintenance(tribes[numOfTribes-1]), ShouldBeTrue)
+						return
+					default:
+						time.Sleep(50 * time.Millisecond)
+					}
+				}
+				tribes[0].setMaintenanceTag(false, false)
+				So(tribes[0].localTags(), ShouldNotContainKey, agreement.Maintenance)
+			})
 			Convey("Adds an agreement", func(c C) {
 				a := "agreement1"
 				t := tribes[numOfTribes-1]
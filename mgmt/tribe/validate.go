@@ -0,0 +1,209 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tribe
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/hashicorp/memberlist"
+	"github.com/pborman/uuid"
+
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
+	"github.com/intelsdi-x/snap/mgmt/tribe/worker"
+)
+
+// taskValidationTimeout is how long the members are waited for to validate
+// a task.
+var taskValidationTimeout = 5 * time.Second
+
+// ValidateTask asks every member sharing a task agreement with this one
+// whether it can create the task of the request, before the task is created
+// and shared with them. The members which cannot, or do not answer in time,
+// are reported by name in the fields of the error returned.
+func (t *tribe) ValidateTask(tr *request.TaskCreationRequest) serror.SnapError {
+	logger := t.logger.WithFields(log.Fields{
+		"_block": "validate-task",
+	})
+	local := t.memberlist.LocalNode().Name
+	nodes := map[string]*memberlist.Node{}
+	for _, n := range t.memberlist.Members() {
+		nodes[n.Name] = n
+	}
+	// the agreement each member is validated for
+	targets := map[string]string{}
+	t.mutex.RLock()
+	if m, ok := t.members[local]; ok {
+		for name := range m.TaskAgreements {
+			if a, ok := t.agreements[name]; ok {
+				for mname := range a.Members {
+					if mname != local {
+						targets[mname] = name
+					}
+				}
+			}
+		}
+	}
+	t.mutex.RUnlock()
+	if len(targets) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(tr)
+	if err != nil {
+		return serror.New(err)
+	}
+	id := uuid.New()
+	resp := make(chan *validateTaskResponseMsg, len(targets))
+	t.mutex.Lock()
+	t.taskValidations[id] = resp
+	t.mutex.Unlock()
+	defer func() {
+		t.mutex.Lock()
+		delete(t.taskValidations, id)
+		t.mutex.Unlock()
+	}()
+
+	failures := map[string]string{}
+	pending := map[string]bool{}
+	for mname, aname := range targets {
+		node, ok := nodes[mname]
+		if !ok {
+			failures[mname] = "member is not reachable"
+			continue
+		}
+		raw, err := encodeMessage(validateTaskMsgType, &validateTaskMsg{
+			UUID:          id,
+			From:          local,
+			AgreementName: aname,
+			Task:          body,
+		})
+		if err != nil {
+			return serror.New(err)
+		}
+		if err := t.memberlist.SendReliable(node, raw); err != nil {
+			failures[mname] = err.Error()
+			continue
+		}
+		pending[mname] = true
+	}
+	timeout := time.After(taskValidationTimeout)
+	for len(pending) > 0 {
+		select {
+		case r := <-resp:
+			if !pending[r.From] {
+				continue
+			}
+			delete(pending, r.From)
+			if len(r.Errors) > 0 {
+				failures[r.From] = strings.Join(r.Errors, "; ")
+			}
+		case <-timeout:
+			for mname := range pending {
+				failures[mname] = fmt.Sprintf("no answer within %v", taskValidationTimeout)
+			}
+			pending = nil
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+
+	var names []string
+	for mname := range failures {
+		names = append(names, mname)
+	}
+	sort.Strings(names)
+	fields := log.Fields{}
+	msgs := make([]string, len(names))
+	for i, mname := range names {
+		fields[mname] = failures[mname]
+		msgs[i] = fmt.Sprintf("%s: %s", mname, failures[mname])
+	}
+	logger.WithFields(fields).Warn(errTaskNotValidOnMembers)
+	return serror.New(fmt.Errorf("%v: %s", errTaskNotValidOnMembers, strings.Join(msgs, ", ")), fields)
+}
+
+// handleValidateTask validates the task of the message with the task
+// manager and answers the member which sent it.
+func (t *tribe) handleValidateTask(msg *validateTaskMsg) {
+	logger := t.logger.WithFields(log.Fields{
+		"_block":    "handle-validate-task",
+		"agreement": msg.AgreementName,
+		"from":      msg.From,
+	})
+	resp := &validateTaskResponseMsg{
+		UUID: msg.UUID,
+		From: t.memberlist.LocalNode().Name,
+	}
+	tr := &request.TaskCreationRequest{}
+	if err := json.Unmarshal(msg.Task, tr); err != nil {
+		resp.Errors = append(resp.Errors, err.Error())
+	} else if t.taskManager == nil {
+		resp.Errors = append(resp.Errors, errTaskManagerNotSet.Error())
+	} else {
+		for _, err := range worker.ValidateTask(t.taskManager, tr) {
+			resp.Errors = append(resp.Errors, err.Error())
+		}
+	}
+
+	var from *memberlist.Node
+	for _, n := range t.memberlist.Members() {
+		if n.Name == msg.From {
+			from = n
+		}
+	}
+	if from == nil {
+		logger.Error(errUnknownMember)
+		return
+	}
+	raw, err := encodeMessage(validateTaskResponseMsgType, resp)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	if err := t.memberlist.SendReliable(from, raw); err != nil {
+		logger.WithField("err", err).Error("failed to send task validation reply")
+	}
+}
+
+// handleValidateTaskResponse hands the answer of a member over to the
+// validation waiting for it.
+func (t *tribe) handleValidateTaskResponse(msg *validateTaskResponseMsg) {
+	t.mutex.RLock()
+	resp, ok := t.taskValidations[msg.UUID]
+	t.mutex.RUnlock()
+	if !ok {
+		t.logger.WithFields(log.Fields{
+			"_block": "handle-validate-task-response",
+			"from":   msg.From,
+		}).Debug("task validation does not exist - nothing to do")
+		return
+	}
+	select {
+	case resp <- msg:
+	default:
+	}
+}
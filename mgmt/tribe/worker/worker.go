@@ -20,22 +20,25 @@ limitations under the License.
 package worker
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"math/rand"
 	"net"
+	"net/http"
 	"os"
 	"path"
 	"sync"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/intelsdi-x/snap/client"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/serror"
-	"github.com/intelsdi-x/snap/mgmt/rest/client"
 	"github.com/intelsdi-x/snap/mgmt/rest/request"
+	"github.com/intelsdi-x/snap/pkg/reqsign"
 	"github.com/intelsdi-x/snap/pkg/schedule"
 	"github.com/intelsdi-x/snap/scheduler"
 	"github.com/intelsdi-x/snap/scheduler/wmap"
@@ -110,15 +113,32 @@ type ManagesPlugins interface {
 type ManagesTasks interface {
 	GetTask(id string) (core.Task, error)
 	CreateTaskTribe(sch schedule.Schedule, wfMap *wmap.WorkflowMap, startOnCreate bool, opts ...core.TaskOption) (core.Task, core.TaskErrors)
+	ValidateTask(sch schedule.Schedule, wfMap *wmap.WorkflowMap) core.TaskErrors
 	StopTaskTribe(id string) []serror.SnapError
 	StartTaskTribe(id string) []serror.SnapError
 	RemoveTaskTribe(id string) error
 }
 
+// artifactPlugin is a plugin of an agreement which records the URL it can
+// be downloaded from.
+type artifactPlugin interface {
+	core.Plugin
+	URL() string
+}
+
 type getsMembers interface {
 	GetPluginAgreementMembers() ([]Member, error)
 	GetTaskAgreementMembers() ([]Member, error)
+	RequestAuth
+}
+
+// RequestAuth authenticates the requests made to the REST API of the other
+// members: they are signed when the member has a signer, and sent with the
+// password otherwise, over TLS with the given configuration
+type RequestAuth interface {
 	GetRequestPassword() string
+	GetRequestSigner() *reqsign.Signer
+	GetRequestTLSConfig() *tls.Config
 }
 
 type Member interface {
@@ -316,8 +336,8 @@ func (w worker) loadPlugin(plugin core.Plugin) error {
 		return err
 	}
 	for _, member := range shuffle(members) {
-		url := fmt.Sprintf("%s://%s:%s/v1/plugins/%s/%s/%d?download=true", member.GetRestProto(), member.GetAddr(), member.GetRestPort(), plugin.TypeName(), plugin.Name(), plugin.Version())
-		c, err := client.New(url, "v1", member.GetRestInsecureSkipVerify(), client.Password(w.memberManager.GetRequestPassword()))
+		url := fmt.Sprintf("%s://%s/v1/plugins/%s/%s/%d?download=true", member.GetRestProto(), net.JoinHostPort(member.GetAddr().String(), member.GetRestPort()), plugin.TypeName(), plugin.Name(), plugin.Version())
+		c, err := memberClient(url, member, w.memberManager)
 		if err != nil {
 			logger.WithFields(log.Fields{
 				"err": err,
@@ -337,43 +357,119 @@ func (w worker) loadPlugin(plugin core.Plugin) error {
 			if resp.Header.Get("Content-Type") != "application/x-gzip" {
 				logger.WithField("content-type", resp.Header.Get("Content-Type")).Error("Expected application/x-gzip")
 			}
-			dir, err := ioutil.TempDir("", "")
-			if err != nil {
-				logger.Error(err)
-				return err
-			}
-			f, err := os.Create(path.Join(dir, fmt.Sprintf("%s-%s-%d", plugin.TypeName(), plugin.Name(), plugin.Version())))
-			if err != nil {
-				logger.Error(err)
-				f.Close()
-				return err
-			}
-			io.Copy(f, resp.Body)
-			f.Close()
-			err = os.Chmod(f.Name(), 0700)
-			if err != nil {
-				logger.Error(err)
-				return err
-			}
-			rp, err := core.NewRequestedPlugin(f.Name())
-			if err != nil {
-				logger.Error(err)
-				return err
-			}
-			_, err = w.pluginManager.Load(rp)
-			if err != nil {
-				logger.Error(err)
-				return err
-			}
-			if w.isPluginLoaded(plugin.Name(), plugin.TypeName(), plugin.Version()) {
-				return nil
-			}
-			return errors.New("failed to load plugin")
+			defer resp.Body.Close()
+			return w.loadDownloadedPlugin(plugin, resp.Body, w.getPluginSignature(member, plugin))
 		}
+		resp.Body.Close()
+	}
+	if p, ok := plugin.(artifactPlugin); ok && p.URL() != "" {
+		logger.WithField("url", p.URL()).Info("no member has the plugin, downloading it from the agreement's URL")
+		return w.loadPluginFromURL(p)
 	}
 	return errors.New("failed to find a member with the plugin")
 }
 
+// getPluginSignature returns the signature a member loaded the plugin with,
+// or nil when the member has none.
+func (w worker) getPluginSignature(member Member, plugin core.Plugin) []byte {
+	url := fmt.Sprintf("%s://%s/v1/plugins/%s/%s/%d?signature=true", member.GetRestProto(), net.JoinHostPort(member.GetAddr().String(), member.GetRestPort()), plugin.TypeName(), plugin.Name(), plugin.Version())
+	c, err := memberClient(url, member, w.memberManager)
+	if err != nil {
+		return nil
+	}
+	resp, err := c.TribeRequest()
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// loadPluginFromURL downloads the plugin from the URL recorded in its
+// agreement. The signature of the plugin, if any, is downloaded from the
+// same URL with the ".asc" extension.
+func (w worker) loadPluginFromURL(plugin artifactPlugin) error {
+	logger := w.logger.WithFields(log.Fields{
+		"plugin-name":    plugin.Name(),
+		"plugin-version": plugin.Version(),
+		"plugin-type":    plugin.TypeName(),
+		"url":            plugin.URL(),
+		"_block":         "load-plugin-from-url",
+	})
+	resp, err := http.Get(plugin.URL())
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		err := fmt.Errorf("failed to download plugin from %s: %s", plugin.URL(), resp.Status)
+		logger.Error(err)
+		return err
+	}
+	var signature []byte
+	if sresp, err := http.Get(plugin.URL() + ".asc"); err == nil {
+		if sresp.StatusCode == 200 {
+			signature, _ = ioutil.ReadAll(sresp.Body)
+		}
+		sresp.Body.Close()
+	}
+	return w.loadDownloadedPlugin(plugin, resp.Body, signature)
+}
+
+// loadDownloadedPlugin writes the plugin downloaded to a temporary file and
+// loads it with its signature. The plugin manager verifies the signature as
+// the trust level of snapd requires.
+func (w worker) loadDownloadedPlugin(plugin core.Plugin, r io.Reader, signature []byte) error {
+	logger := w.logger.WithFields(log.Fields{
+		"plugin-name":    plugin.Name(),
+		"plugin-version": plugin.Version(),
+		"plugin-type":    plugin.TypeName(),
+		"_block":         "load-plugin",
+	})
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	f, err := os.Create(path.Join(dir, fmt.Sprintf("%s-%s-%d", plugin.TypeName(), plugin.Name(), plugin.Version())))
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	io.Copy(f, r)
+	f.Close()
+	err = os.Chmod(f.Name(), 0700)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	rp, err := core.NewRequestedPlugin(f.Name())
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	if len(signature) > 0 {
+		rp.SetSignature(signature)
+	}
+	_, err = w.pluginManager.Load(rp)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	if w.isPluginLoaded(plugin.Name(), plugin.TypeName(), plugin.Version()) {
+		return nil
+	}
+	return errors.New("failed to load plugin")
+}
+
 func (w worker) createTask(taskID string, startOnCreate bool) {
 	logger := w.logger.WithFields(log.Fields{
 		"task-id": taskID,
@@ -391,10 +487,10 @@ func (w worker) createTask(taskID string, startOnCreate bool) {
 			continue
 		}
 		for _, member := range shuffle(members) {
-			uri := fmt.Sprintf("%s://%s:%s", member.GetRestProto(), member.GetAddr(), member.GetRestPort())
+			uri := fmt.Sprintf("%s://%s", member.GetRestProto(), net.JoinHostPort(member.GetAddr().String(), member.GetRestPort()))
 			logger.Debugf("getting task %v from %v", taskID, uri)
 
-			c, err := client.New(uri, "v1", member.GetRestInsecureSkipVerify(), client.Password(w.memberManager.GetRequestPassword()))
+			c, err := memberClient(uri, member, w.memberManager)
 			if err != nil {
 				logger.Error(err)
 				continue
@@ -406,12 +502,15 @@ func (w worker) createTask(taskID string, startOnCreate bool) {
 				continue
 			}
 			logger.Debug("creating task")
-			opt := core.SetTaskID(taskID)
+			opts := []core.TaskOption{core.SetTaskID(taskID)}
+			if taskResult.Sharded {
+				opts = append(opts, core.OptionTaskSharded(true))
+			}
 			_, errs := w.taskManager.CreateTaskTribe(
 				getSchedule(taskResult.ScheduledTaskReturned.Schedule),
 				taskResult.Workflow,
 				startOnCreate,
-				opt)
+				opts...)
 			if errs != nil && len(errs.Errors()) > 0 {
 				fields := log.Fields{}
 				for idx, e := range errs.Errors() {
@@ -487,6 +586,101 @@ func (w worker) removeTask(taskID string) error {
 	return err
 }
 
+// ValidateTask returns the errors the task created by the request would
+// fail with once its agreement is shared with this member, without creating
+// the task.
+func ValidateTask(tm ManagesTasks, tr *request.TaskCreationRequest) []serror.SnapError {
+	sch := getSchedule(&tr.Schedule)
+	if sch == nil {
+		return []serror.SnapError{serror.New(fmt.Errorf("schedule type '%s' with interval '%s' cannot be shared by tribe", tr.Schedule.Type, tr.Schedule.Interval))}
+	}
+	if errs := tm.ValidateTask(sch, tr.Workflow); errs != nil {
+		return errs.Errors()
+	}
+	return nil
+}
+
+// ReplaceTask creates the task of the request with the given ID and starts
+// it, then stops the task it replaces. The task is only created once, so
+// replacing the same task again only stops the old task.
+func ReplaceTask(tm ManagesTasks, oldID, newID string, tr *request.TaskCreationRequest) []serror.SnapError {
+	if _, err := tm.GetTask(newID); err != nil {
+		sch := getSchedule(&tr.Schedule)
+		if sch == nil {
+			return []serror.SnapError{serror.New(fmt.Errorf("schedule type '%s' with interval '%s' cannot be shared by tribe", tr.Schedule.Type, tr.Schedule.Interval))}
+		}
+		opts := []core.TaskOption{core.SetTaskID(newID)}
+		if tr.Shard {
+			opts = append(opts, core.OptionTaskSharded(true))
+		}
+		if _, errs := tm.CreateTaskTribe(sch, tr.Workflow, true, opts...); errs != nil && len(errs.Errors()) > 0 {
+			return errs.Errors()
+		}
+	}
+	if _, err := tm.GetTask(oldID); err != nil {
+		return nil
+	}
+	return ignoreTaskStateErrors(tm.StopTaskTribe(oldID))
+}
+
+// RevertTask stops and removes the task which replaced another one, then
+// starts the latter again.
+func RevertTask(tm ManagesTasks, oldID, newID string) []serror.SnapError {
+	var errs []serror.SnapError
+	if _, err := tm.GetTask(newID); err == nil {
+		errs = append(errs, ignoreTaskStateErrors(tm.StopTaskTribe(newID))...)
+		if err := tm.RemoveTaskTribe(newID); err != nil {
+			errs = append(errs, serror.New(err))
+		}
+	}
+	if _, err := tm.GetTask(oldID); err == nil {
+		errs = append(errs, ignoreTaskStateErrors(tm.StartTaskTribe(oldID))...)
+	}
+	return errs
+}
+
+// ignoreTaskStateErrors drops the errors telling a task was already in the
+// state it was asked to be in.
+func ignoreTaskStateErrors(errs []serror.SnapError) []serror.SnapError {
+	var res []serror.SnapError
+	for _, err := range errs {
+		if err.Error() == scheduler.ErrTaskAlreadyRunning.Error() || err.Error() == scheduler.ErrTaskAlreadyStopped.Error() {
+			continue
+		}
+		res = append(res, err)
+	}
+	return res
+}
+
+// memberClient returns the client of the REST API of a member at the URL
+func memberClient(url string, member Member, auth RequestAuth) (*client.Client, error) {
+	return client.New(url, "v1", member.GetRestInsecureSkipVerify(),
+		client.Password(auth.GetRequestPassword()),
+		client.RequestSigner(auth.GetRequestSigner()),
+		client.TLSConfig(auth.GetRequestTLSConfig()))
+}
+
+// TaskFailure returns why the task of a member is failing, or an empty
+// string when it runs without failures.
+func TaskFailure(member Member, auth RequestAuth, taskID string) string {
+	uri := fmt.Sprintf("%s://%s", member.GetRestProto(), net.JoinHostPort(member.GetAddr().String(), member.GetRestPort()))
+	c, err := memberClient(uri, member, auth)
+	if err != nil {
+		return err.Error()
+	}
+	r := c.GetTask(taskID)
+	if r.Err != nil {
+		return r.Err.Error()
+	}
+	if r.FailedCount > 0 {
+		return fmt.Sprintf("%d failed runs, last failure: %s", r.FailedCount, r.LastFailureMessage)
+	}
+	if r.State == core.TaskDisabled.String() || r.State == core.TaskStopped.String() {
+		return fmt.Sprintf("task is %s", r.State)
+	}
+	return ""
+}
+
 func shuffle(m []Member) []Member {
 	result := make([]Member, len(m))
 	perm := rand.Perm(len(m))
@@ -522,7 +716,14 @@ func getSchedule(s *request.Schedule) schedule.Schedule {
 			log.WithField("_block", "get-schedule").Error(e)
 			return nil
 		}
-		return schedule.NewSimpleSchedule(d)
+		sch := schedule.NewSimpleSchedule(d)
+		if s.Jitter != "" {
+			if sch.Jitter, e = time.ParseDuration(s.Jitter); e != nil {
+				log.WithField("_block", "get-schedule").Error(e)
+				return nil
+			}
+		}
+		return sch
 	}
 	return nil
 }
@@ -3,6 +3,7 @@ package cfgfile
 import (
 	"encoding/json"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -80,3 +81,24 @@ func TestReadConfig(t *testing.T) {
 		So(config.Bar, ShouldResemble, "Justin")
 	})
 }
+
+func TestUnknownKeys(t *testing.T) {
+	Convey("UnknownKeys", t, func() {
+		f, err := ioutil.TempFile("", "keys.conf")
+		So(err, ShouldBeNil)
+		defer os.Remove(f.Name())
+		_, err = f.WriteString("name: foo\nsection:\n  port: 1\n  prot: 2\n  timeout: 1s\nbogus: true\n")
+		So(err, ShouldBeNil)
+		f.Close()
+
+		Convey("returns the keys without a matching field", func() {
+			keys, err := UnknownKeys(f.Name(), &envConfig{})
+			So(err, ShouldBeNil)
+			So(keys, ShouldResemble, []string{"bogus", "section.prot"})
+		})
+		Convey("returns an error for a missing file", func() {
+			_, err := UnknownKeys(f.Name()+".missing", &envConfig{})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,163 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cfgfile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNotStructPointer = errors.New("config must be a pointer to a struct")
+
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// ApplyEnv overrides the fields of the struct pointed to by v with values
+// taken from environment variables. The variable for a field is the prefix
+// followed by the field's JSON key in upper case, joined by underscores, so
+// the key port in a restapi section maps to SNAP_RESTAPI_PORT. Nested structs
+// are walked. Fields implementing json.Unmarshaler take a JSON value (a bare
+// string is accepted as well, e.g. "500ms" for a duration).
+func ApplyEnv(prefix string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrNotStructPointer
+	}
+	return applyEnv(prefix, rv.Elem())
+}
+
+func applyEnv(prefix string, v reflect.Value) error {
+	return walkEnv(prefix, v, func(name string, f reflect.Value) error {
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			return nil
+		}
+		if err := setField(f, val); err != nil {
+			return fmt.Errorf("invalid value %q for %s: %v", val, name, err)
+		}
+		return nil
+	})
+}
+
+// walkEnv calls fn with the variable name of every settable field of v
+func walkEnv(prefix string, v reflect.Value, fn func(string, reflect.Value) error) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		// skip unexported fields
+		if sf.PkgPath != "" {
+			continue
+		}
+		key := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+		name := prefix + "_" + strings.ToUpper(key)
+		f := v.Field(i)
+		if isUnmarshaler(f) {
+			if err := fn(name, f); err != nil {
+				return err
+			}
+			continue
+		}
+		switch f.Kind() {
+		case reflect.Ptr, reflect.Struct:
+			if err := walkEnv(name, f, fn); err != nil {
+				return err
+			}
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			if err := fn(name, f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isUnmarshaler(f reflect.Value) bool {
+	if f.Kind() == reflect.Ptr {
+		return f.Type().Implements(unmarshalerType)
+	}
+	return f.CanAddr() && f.Addr().Type().Implements(unmarshalerType)
+}
+
+func setField(f reflect.Value, val string) error {
+	if isUnmarshaler(f) {
+		if f.Kind() == reflect.Ptr {
+			if f.IsNil() {
+				f.Set(reflect.New(f.Type().Elem()))
+			}
+		} else {
+			f = f.Addr()
+		}
+		u := f.Interface().(json.Unmarshaler)
+		if err := u.UnmarshalJSON([]byte(val)); err != nil {
+			return u.UnmarshalJSON([]byte(strconv.Quote(val)))
+		}
+		return nil
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(val, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(val, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		fl, err := strconv.ParseFloat(val, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(fl)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cfgfile
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vrischmann/jsonutil"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type envSection struct {
+	Port    int               `json:"port,omitempty"yaml:"port,omitempty"`
+	Enable  bool              `json:"enable,omitempty"yaml:"enable,omitempty"`
+	Size    uint              `json:"size,omitempty"yaml:"size,omitempty"`
+	Timeout jsonutil.Duration `json:"timeout,omitempty"yaml:"timeout,omitempty"`
+	Hidden  string            `json:"-"yaml:"-"`
+}
+
+type envConfig struct {
+	Name    string      `json:"name,omitempty"yaml:"name,omitempty"`
+	Section *envSection `json:"section,omitempty"yaml:"section,omitempty"`
+}
+
+func TestApplyEnv(t *testing.T) {
+	Convey("ApplyEnv", t, func() {
+		cfg := &envConfig{Name: "default", Section: &envSection{Port: 1}}
+		env := map[string]string{}
+		setenv := func(k, v string) {
+			env[k] = v
+			os.Setenv(k, v)
+		}
+		Reset(func() {
+			for k := range env {
+				os.Unsetenv(k)
+			}
+		})
+
+		Convey("leaves fields alone without variables", func() {
+			So(ApplyEnv("TEST", cfg), ShouldBeNil)
+			So(cfg.Name, ShouldEqual, "default")
+			So(cfg.Section.Port, ShouldEqual, 1)
+		})
+		Convey("sets top level and nested fields", func() {
+			setenv("TEST_NAME", "env")
+			setenv("TEST_SECTION_PORT", "8282")
+			setenv("TEST_SECTION_ENABLE", "true")
+			setenv("TEST_SECTION_SIZE", "7")
+			setenv("TEST_SECTION_TIMEOUT", "750ms")
+			So(ApplyEnv("TEST", cfg), ShouldBeNil)
+			So(cfg.Name, ShouldEqual, "env")
+			So(cfg.Section.Port, ShouldEqual, 8282)
+			So(cfg.Section.Enable, ShouldBeTrue)
+			So(cfg.Section.Size, ShouldEqual, 7)
+			So(cfg.Section.Timeout.Duration, ShouldEqual, 750*time.Millisecond)
+		})
+		Convey("ignores fields excluded from JSON", func() {
+			setenv("TEST_SECTION_HIDDEN", "x")
+			So(ApplyEnv("TEST", cfg), ShouldBeNil)
+			So(cfg.Section.Hidden, ShouldEqual, "")
+		})
+		Convey("returns an error for an invalid value", func() {
+			setenv("TEST_SECTION_PORT", "abc")
+			err := ApplyEnv("TEST", cfg)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "TEST_SECTION_PORT")
+		})
+		Convey("requires a pointer to a struct", func() {
+			So(ApplyEnv("TEST", *cfg), ShouldEqual, ErrNotStructPointer)
+		})
+	})
+}
@@ -0,0 +1,88 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cfgfile
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// UnknownKeys reads the YAML or JSON file at path and returns the keys in it
+// which do not map to a field of v, as dotted paths (e.g. restapi.prot).
+// Sections decoded by a json.Unmarshaler or into a map are not inspected.
+func UnknownKeys(path string, v interface{}) ([]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is valid YAML so both formats can be converted the same way
+	j, err := yaml.YAMLToJSON(b)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(j, &m); err != nil {
+		return nil, err
+	}
+	unknown := unknownKeys("", m, reflect.TypeOf(v))
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+func unknownKeys(prefix string, m map[string]interface{}, t reflect.Type) []string {
+	for t.Kind() == reflect.Ptr {
+		if t.Implements(unmarshalerType) {
+			return nil
+		}
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || reflect.PtrTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		key := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+		fields[key] = sf.Type
+	}
+	var unknown []string
+	for k, val := range m {
+		ft, ok := fields[k]
+		if !ok {
+			unknown = append(unknown, prefix+k)
+			continue
+		}
+		if sub, ok := val.(map[string]interface{}); ok {
+			unknown = append(unknown, unknownKeys(prefix+k+".", sub, ft)...)
+		}
+	}
+	return unknown
+}
@@ -0,0 +1,86 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cfgfile
+
+import "reflect"
+
+// Redacted replaces the values of the secret fields in the copy Redact returns
+const Redacted = "********"
+
+// Redact returns a copy of the struct pointed to by v whose fields tagged
+// secret:"true" are replaced by Redacted when set, e.g. to print a config.
+// The tag applies to strings and to the string values of maps and slices,
+// e.g. the passwords of several hosts by URL. v itself is left unchanged.
+func Redact(v interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, ErrNotStructPointer
+	}
+	return redact(rv, false).Interface(), nil
+}
+
+// redact returns a copy of v, the strings in it replaced when secret
+func redact(v reflect.Value, secret bool) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(redact(v.Elem(), secret))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			// unexported fields and the ones left out of the config are
+			// copied as they are
+			if t.Field(i).PkgPath != "" || t.Field(i).Tag.Get("json") == "-" {
+				continue
+			}
+			c.Field(i).Set(redact(v.Field(i), t.Field(i).Tag.Get("secret") == "true"))
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(redact(v.Index(i), secret))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMap(v.Type())
+		for _, k := range v.MapKeys() {
+			c.SetMapIndex(k, redact(v.MapIndex(k), secret))
+		}
+		return c
+	case reflect.String:
+		if secret && v.Len() > 0 {
+			return reflect.ValueOf(Redacted).Convert(v.Type())
+		}
+	}
+	return v
+}
@@ -0,0 +1,75 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cfgfile
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type redactToken struct {
+	Name  string `json:"name"yaml:"name"`
+	Token string `json:"token"yaml:"token"secret:"true"`
+}
+
+type redactConfig struct {
+	User      string            `json:"user"yaml:"user"`
+	Password  string            `json:"password"yaml:"password"secret:"true"`
+	Empty     string            `json:"empty"yaml:"empty"secret:"true"`
+	Passwords map[string]string `json:"passwords"yaml:"passwords"secret:"true"`
+	Tokens    []redactToken     `json:"tokens"yaml:"tokens"`
+	Section   *redactConfig     `json:"section"yaml:"section"`
+}
+
+func TestRedact(t *testing.T) {
+	Convey("Redact", t, func() {
+		cfg := &redactConfig{
+			User:      "snap",
+			Password:  "secret",
+			Passwords: map[string]string{"http://10.0.0.2:8181": "secret"},
+			Tokens:    []redactToken{{Name: "ci", Token: "t0k3n"}},
+			Section:   &redactConfig{User: "ops", Password: "secret"},
+		}
+		v, err := Redact(cfg)
+		So(err, ShouldBeNil)
+		r := v.(*redactConfig)
+
+		Convey("replaces the secret fields which are set", func() {
+			So(r.User, ShouldEqual, "snap")
+			So(r.Password, ShouldEqual, Redacted)
+			So(r.Empty, ShouldEqual, "")
+			So(r.Passwords, ShouldResemble, map[string]string{"http://10.0.0.2:8181": Redacted})
+			So(r.Tokens, ShouldResemble, []redactToken{{Name: "ci", Token: Redacted}})
+			So(r.Section.User, ShouldEqual, "ops")
+			So(r.Section.Password, ShouldEqual, Redacted)
+		})
+		Convey("leaves the config unchanged", func() {
+			So(cfg.Password, ShouldEqual, "secret")
+			So(cfg.Passwords["http://10.0.0.2:8181"], ShouldEqual, "secret")
+			So(cfg.Tokens[0].Token, ShouldEqual, "t0k3n")
+			So(cfg.Section.Password, ShouldEqual, "secret")
+		})
+		Convey("takes a pointer to a struct only", func() {
+			_, err := Redact(*cfg)
+			So(err, ShouldEqual, ErrNotStructPointer)
+		})
+	})
+}
@@ -0,0 +1,280 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package convert
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// collectdItem is an option of a collectd configuration, e.g. Interval 10,
+// or a block of them, e.g. <Plugin cpu>...</Plugin>
+type collectdItem struct {
+	key      string
+	values   []string
+	children []*collectdItem
+}
+
+// child returns the values of the first option of the block with the given
+// key, keys being case insensitive in collectd
+func (c *collectdItem) child(key string) []string {
+	for _, o := range c.children {
+		if strings.EqualFold(o.key, key) {
+			return o.values
+		}
+	}
+	return nil
+}
+
+// parseCollectd parses a collectd configuration into its top level items
+func parseCollectd(r io.Reader) ([]*collectdItem, error) {
+	root := &collectdItem{}
+	stack := []*collectdItem{root}
+	s := bufio.NewScanner(r)
+	n := 0
+	for s.Scan() {
+		n++
+		line := strings.TrimSpace(stripComment(s.Text()))
+		if line == "" {
+			continue
+		}
+		cur := stack[len(stack)-1]
+		switch {
+		case strings.HasPrefix(line, "</"):
+			key := strings.TrimSpace(strings.TrimSuffix(line[2:], ">"))
+			if len(stack) == 1 || !strings.EqualFold(key, cur.key) {
+				return nil, fmt.Errorf("line %d: unexpected </%s>", n, key)
+			}
+			stack = stack[:len(stack)-1]
+		case strings.HasPrefix(line, "<"):
+			if !strings.HasSuffix(line, ">") {
+				return nil, fmt.Errorf("line %d: unterminated block %s", n, line)
+			}
+			tokens, err := collectdTokens(line[1 : len(line)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", n, err)
+			}
+			block := &collectdItem{key: tokens[0], values: tokens[1:]}
+			cur.children = append(cur.children, block)
+			stack = append(stack, block)
+		default:
+			tokens, err := collectdTokens(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", n, err)
+			}
+			cur.children = append(cur.children, &collectdItem{key: tokens[0], values: tokens[1:]})
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("unterminated block <%s>", stack[len(stack)-1].key)
+	}
+	return root.children, nil
+}
+
+// collectdTokens splits a line on the spaces outside of quoted strings, and
+// unquotes the strings
+func collectdTokens(line string) ([]string, error) {
+	var tokens []string
+	for line = strings.TrimSpace(line); line != ""; line = strings.TrimSpace(line) {
+		if line[0] != '"' {
+			i := strings.IndexAny(line, " \t")
+			if i < 0 {
+				i = len(line)
+			}
+			tokens = append(tokens, line[:i])
+			line = line[i:]
+			continue
+		}
+		end := 1
+		for end < len(line) && (line[end] != '"' || line[end-1] == '\\') {
+			end++
+		}
+		if end == len(line) {
+			return nil, fmt.Errorf("unterminated string %s", line)
+		}
+		t, err := strconv.Unquote(line[:end+1])
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+		line = line[end+1:]
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty option")
+	}
+	return tokens, nil
+}
+
+// collectdGlobals are the global options of collectd which do not matter to
+// the task
+var collectdGlobals = map[string]bool{
+	"hostname":             true,
+	"fqdnlookup":           true,
+	"basedir":              true,
+	"piddir":               true,
+	"pidfile":              true,
+	"plugindir":            true,
+	"typesdb":              true,
+	"autoloadplugin":       true,
+	"collectinternalstats": true,
+	"timeout":              true,
+	"readthreads":          true,
+	"writethreads":         true,
+	"writequeuelimithigh":  true,
+	"writequeuelimitlow":   true,
+}
+
+// collectdLogging are the logging plugins of collectd, snapd having its own
+// logging
+var collectdLogging = map[string]bool{
+	"logfile":      true,
+	"syslog":       true,
+	"log_logstash": true,
+}
+
+// collectdOutputs converts the collectd write plugins onto the snap
+// publishers
+var collectdOutputs = map[string]func(*Result, *collectdItem){
+	"write_graphite": collectdGraphite,
+	"csv":            collectdCSV,
+}
+
+func fromCollectd(items []*collectdItem) (*Result, error) {
+	r := newResult()
+	var loaded []string
+	blocks := map[string]*collectdItem{}
+	for _, item := range items {
+		key := strings.ToLower(item.key)
+		switch {
+		case key == "interval" && len(item.values) == 1:
+			s, err := strconv.ParseFloat(item.values[0], 64)
+			if err != nil {
+				return nil, fmt.Errorf("Interval: %v", err)
+			}
+			r.Interval = time.Duration(s * float64(time.Second)).String()
+		case key == "loadplugin" && len(item.values) == 1:
+			loaded = append(loaded, item.values[0])
+			if len(item.children) > 0 {
+				r.notef("LoadPlugin %s: options not converted", item.values[0])
+			}
+		case key == "plugin" && len(item.values) == 1:
+			blocks[item.values[0]] = item
+		case key == "include":
+			r.notef("Include %s: not followed, convert the included files too", strings.Join(item.values, " "))
+		case collectdGlobals[key]:
+		default:
+			r.notef("%s: not converted", item.key)
+		}
+	}
+	for _, name := range loaded {
+		block := blocks[name]
+		if block == nil {
+			block = &collectdItem{key: "Plugin", values: []string{name}}
+		}
+		delete(blocks, name)
+		if in, ok := collectdInputs[name]; ok {
+			collectdInput(r, name, in, block)
+			continue
+		}
+		if out, ok := collectdOutputs[name]; ok {
+			out(r, block)
+			continue
+		}
+		if !collectdLogging[name] {
+			r.notef("plugin %s: no corresponding snap plugin, skipped", name)
+		}
+	}
+	for name := range blocks {
+		r.notef("plugin %s: configured but not loaded, skipped", name)
+	}
+	return r.finish()
+}
+
+func collectdInput(r *Result, name string, in input, block *collectdItem) {
+	var instances, options []string
+	ignoreSelected := false
+	for _, o := range block.children {
+		switch {
+		case in.instances != "" && strings.EqualFold(o.key, in.instances):
+			instances = append(instances, o.values...)
+		case strings.EqualFold(o.key, "IgnoreSelected"):
+			ignoreSelected = len(o.values) == 1 && strings.EqualFold(o.values[0], "true")
+		default:
+			options = append(options, o.key)
+		}
+	}
+	if ignoreSelected && len(instances) > 0 {
+		r.notef("plugin %s: the ignored %s %s are collected too", name, in.instances, strings.Join(instances, ", "))
+		instances = nil
+	}
+	r.addInput(in, instances)
+	if len(options) > 0 {
+		r.notef("plugin %s: options %s not converted", name, strings.Join(options, ", "))
+	}
+}
+
+func collectdGraphite(r *Result, block *collectdItem) {
+	for _, node := range block.children {
+		if !strings.EqualFold(node.key, "Node") && !strings.EqualFold(node.key, "Carbon") {
+			continue
+		}
+		host, port, protocol := "localhost", "2003", "tcp"
+		if v := node.child("Host"); len(v) == 1 {
+			host = v[0]
+		}
+		if v := node.child("Port"); len(v) == 1 {
+			port = v[0]
+		}
+		if v := node.child("Protocol"); len(v) == 1 {
+			protocol = strings.ToLower(v[0])
+		}
+		config := map[string]interface{}{
+			"address":  host + ":" + port,
+			"protocol": protocol,
+		}
+		if v := node.child("Prefix"); len(v) == 1 && v[0] != "" {
+			config["path"] = strings.TrimSuffix(v[0], ".") + ".{{.Namespace}}"
+		}
+		r.addPublisher("builtin/graphite", config)
+	}
+}
+
+func collectdCSV(r *Result, block *collectdItem) {
+	dir := "/var/lib/collectd/csv"
+	if v := block.child("DataDir"); len(v) == 1 {
+		dir = v[0]
+	}
+	if dir == "stdout" || dir == "stderr" {
+		r.notef("plugin csv: %s is not converted, snap publishes to files only", dir)
+		return
+	}
+	r.addPublisher("builtin/file", map[string]interface{}{
+		"path":   r.filePath("plugin csv", filepath.Join(dir, "snap-{{.Date}}.csv")),
+		"format": "csv",
+	})
+}
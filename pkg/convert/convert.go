@@ -0,0 +1,211 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package convert maps the configurations of other collection agents,
+// Telegraf and collectd, onto snap task manifests. The inputs and outputs
+// with a corresponding snap plugin become the metrics and the publish nodes
+// of the workflow, the rest is reported in the notes of the conversion.
+package convert
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// The formats configurations can be converted from
+const (
+	Telegraf = "telegraf"
+	Collectd = "collectd"
+)
+
+// Formats lists the formats configurations can be converted from
+var Formats = []string{Telegraf, Collectd}
+
+var (
+	// ErrUnknownFormat is returned for a format which is not one of Formats
+	ErrUnknownFormat = fmt.Errorf("unknown format, must be one of %s", strings.Join(Formats, ", "))
+	// ErrNoInput is returned when none of the inputs of a configuration has
+	// a corresponding snap collector
+	ErrNoInput = errors.New("no input of the configuration maps onto a snap collector")
+)
+
+// defaultInterval is the collection interval of both Telegraf and collectd
+// when their configuration does not set one
+const defaultInterval = "10s"
+
+// input is a snap collector an input of another agent maps onto
+type input struct {
+	// namespace is the namespace prefix of the metrics of the collector
+	namespace string
+	// instances is the option of the input restricting it to some devices
+	// or interfaces, collected as namespace/<instance>/*
+	instances string
+}
+
+// telegrafInputs maps the Telegraf inputs onto the snap collectors
+var telegrafInputs = map[string]input{
+	"cpu":       {namespace: "/intel/psutil/cpu"},
+	"mem":       {namespace: "/intel/psutil/vm"},
+	"system":    {namespace: "/intel/psutil/load"},
+	"net":       {namespace: "/intel/psutil/net", instances: "interfaces"},
+	"disk":      {namespace: "/intel/procfs/filesystem"},
+	"diskio":    {namespace: "/intel/procfs/disk", instances: "devices"},
+	"processes": {namespace: "/intel/procfs/processes"},
+	"swap":      {namespace: "/intel/procfs/swap"},
+	"docker":    {namespace: "/intel/docker"},
+}
+
+// collectdInputs maps the collectd read plugins onto the snap collectors
+var collectdInputs = map[string]input{
+	"cpu":       {namespace: "/intel/psutil/cpu"},
+	"memory":    {namespace: "/intel/psutil/vm"},
+	"load":      {namespace: "/intel/psutil/load"},
+	"interface": {namespace: "/intel/psutil/net", instances: "Interface"},
+	"df":        {namespace: "/intel/procfs/filesystem"},
+	"disk":      {namespace: "/intel/procfs/disk", instances: "Disk"},
+	"processes": {namespace: "/intel/procfs/processes"},
+	"swap":      {namespace: "/intel/procfs/swap"},
+	"docker":    {namespace: "/intel/docker"},
+}
+
+// Result is a configuration converted to a task
+type Result struct {
+	// Interval is the interval of the simple schedule of the task
+	Interval string
+	// Workflow collects the metrics of the inputs and publishes them to the
+	// outputs
+	Workflow *wmap.WorkflowMap
+	// Notes describes what was not converted, or not as is
+	Notes []string
+}
+
+// Convert converts the configuration of the given format read from r
+func Convert(format string, r io.Reader) (*Result, error) {
+	switch format {
+	case Telegraf:
+		tables, err := parseTOML(r)
+		if err != nil {
+			return nil, err
+		}
+		return fromTelegraf(tables)
+	case Collectd:
+		items, err := parseCollectd(r)
+		if err != nil {
+			return nil, err
+		}
+		return fromCollectd(items)
+	}
+	return nil, ErrUnknownFormat
+}
+
+func newResult() *Result {
+	return &Result{
+		Interval: defaultInterval,
+		Workflow: wmap.NewWorkflowMap(),
+	}
+}
+
+func (r *Result) notef(format string, a ...interface{}) {
+	r.Notes = append(r.Notes, fmt.Sprintf(format, a...))
+}
+
+// addInput adds the metrics of the collector an input maps onto, restricted
+// to the instances given
+func (r *Result) addInput(in input, instances []string) {
+	if len(instances) == 0 {
+		r.Workflow.CollectNode.AddMetric(in.namespace+"/*", 0)
+		return
+	}
+	for _, i := range instances {
+		r.Workflow.CollectNode.AddMetric(in.namespace+"/"+i+"/*", 0)
+	}
+}
+
+// addPublisher adds a publish node to the collect node
+func (r *Result) addPublisher(name string, config map[string]interface{}) {
+	p := wmap.NewPublishNode(name, 0)
+	for k, v := range config {
+		p.AddConfigItem(k, v)
+	}
+	r.Workflow.CollectNode.Add(p)
+}
+
+// filePath returns the path builtin/file writes a file of the configuration
+// to, relative to the file directory of snapd which it cannot leave
+func (r *Result) filePath(name, path string) string {
+	rel := filepath.Clean(path)
+	if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		rel = filepath.Base(rel)
+		r.notef("%s: %s is written to %s under the file directory of snapd", name, path, rel)
+	}
+	return rel
+}
+
+func (r *Result) finish() (*Result, error) {
+	if len(r.Workflow.CollectNode.Metrics) == 0 {
+		return nil, ErrNoInput
+	}
+	if len(r.Workflow.CollectNode.PublishNodes) == 0 {
+		r.notef("no output maps onto a snap publisher, add a publish node to the workflow")
+	}
+	return r, nil
+}
+
+// Manifest returns the YAML task manifest of the result, starting with the
+// notes as comments
+func (r *Result) Manifest() ([]byte, error) {
+	wf, err := yaml.Marshal(map[string]interface{}{
+		"version": 1,
+		"schedule": map[string]string{
+			"type":     "simple",
+			"interval": r.Interval,
+		},
+		"workflow": r.Workflow,
+	})
+	if err != nil {
+		return nil, err
+	}
+	b := &bytes.Buffer{}
+	for _, n := range r.Notes {
+		fmt.Fprintf(b, "# %s\n", n)
+	}
+	fmt.Fprintf(b, "---\n")
+	b.Write(wf)
+	return b.Bytes(), nil
+}
+
+// sortedKeys returns the keys of m in order, for the notes to come in the
+// same order each time
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
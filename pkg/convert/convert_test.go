@@ -0,0 +1,205 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package convert
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const telegrafConf = `
+[global_tags]
+  dc = "us-east-1" # the datacenter
+
+[agent]
+  interval = "30s"
+
+[[inputs.cpu]]
+  percpu = true
+  totalcpu = true
+
+[[inputs.net]]
+  interfaces = [
+    "eth0",
+    "eth1",
+  ]
+
+[[inputs.nginx]]
+  urls = ["http://localhost/status"]
+
+[[outputs.graphite]]
+  servers = ["carbon:2003"]
+  prefix = "telegraf"
+
+[[outputs.influxdb]]
+  urls = ["https://influx:8087"]
+  database = 'metrics'
+`
+
+const collectdConf = `
+Hostname "web1"
+Interval 60
+
+LoadPlugin syslog
+LoadPlugin cpu
+LoadPlugin interface
+LoadPlugin write_graphite
+LoadPlugin apache
+
+<Plugin interface>
+	Interface "lo"
+	IgnoreSelected true
+</Plugin>
+
+<Plugin write_graphite>
+	<Node "carbon">
+		Host "carbon.example.com"
+		Port "2003"
+		Protocol "UDP"
+		Prefix "collectd."
+	</Node>
+</Plugin>
+
+<Plugin csv>
+	DataDir "/var/lib/collectd/csv"
+</Plugin>
+`
+
+func TestConvertTelegraf(t *testing.T) {
+	Convey("Converting a Telegraf configuration", t, func() {
+		r, err := Convert(Telegraf, strings.NewReader(telegrafConf))
+		So(err, ShouldBeNil)
+		So(r.Interval, ShouldEqual, "30s")
+
+		Convey("collects the metrics of the inputs with a snap collector", func() {
+			So(r.Workflow.CollectNode.Metrics, ShouldContainKey, "/intel/psutil/cpu/*")
+			So(r.Workflow.CollectNode.Metrics, ShouldContainKey, "/intel/psutil/net/eth0/*")
+			So(r.Workflow.CollectNode.Metrics, ShouldContainKey, "/intel/psutil/net/eth1/*")
+			So(r.Workflow.CollectNode.Metrics, ShouldHaveLength, 3)
+		})
+		Convey("publishes to the outputs with a snap publisher", func() {
+			pubs := r.Workflow.CollectNode.PublishNodes
+			So(pubs, ShouldHaveLength, 2)
+			So(pubs[0].Name, ShouldEqual, "builtin/graphite")
+			So(pubs[0].Config["address"], ShouldEqual, "carbon:2003")
+			So(pubs[0].Config["path"], ShouldEqual, "telegraf.{{.Namespace}}")
+			So(pubs[1].Name, ShouldEqual, "influxdb")
+			So(pubs[1].Config["host"], ShouldEqual, "influx")
+			So(pubs[1].Config["port"], ShouldEqual, 8087)
+			So(pubs[1].Config["database"], ShouldEqual, "metrics")
+			So(pubs[1].Config["https"], ShouldEqual, true)
+		})
+		Convey("notes what is not converted", func() {
+			notes := strings.Join(r.Notes, "\n")
+			So(notes, ShouldContainSubstring, "global_tags")
+			So(notes, ShouldContainSubstring, "inputs.cpu: options percpu, totalcpu not converted")
+			So(notes, ShouldContainSubstring, "inputs.nginx: no corresponding snap collector")
+		})
+		Convey("produces a manifest starting with the notes", func() {
+			m, err := r.Manifest()
+			So(err, ShouldBeNil)
+			So(string(m), ShouldStartWith, "# ")
+			So(string(m), ShouldContainSubstring, "interval: 30s")
+		})
+	})
+	Convey("Converting a Telegraf file output writes it under the file directory of snapd", t, func() {
+		r, err := Convert(Telegraf, strings.NewReader("[[inputs.cpu]]\n[[outputs.file]]\n  files = [\"/tmp/metrics.out\", \"out/metrics.csv\"]\n  data_format = \"csv\"\n"))
+		So(err, ShouldBeNil)
+		pubs := r.Workflow.CollectNode.PublishNodes
+		So(pubs, ShouldHaveLength, 2)
+		So(pubs[0].Config["path"], ShouldEqual, "metrics.out")
+		So(pubs[1].Config["path"], ShouldEqual, "out/metrics.csv")
+		So(strings.Join(r.Notes, "\n"), ShouldContainSubstring, "/tmp/metrics.out is written to metrics.out")
+	})
+	Convey("Converting a Telegraf configuration without any known input fails", t, func() {
+		_, err := Convert(Telegraf, strings.NewReader("[[inputs.nginx]]\n"))
+		So(err, ShouldEqual, ErrNoInput)
+	})
+	Convey("Converting a Telegraf configuration skips the tables written in unsupported TOML", t, func() {
+		conf := `
+[[inputs.cpu]]
+[[inputs.net]]
+  interfaces = ["eth[0]", "eth1"] # brackets in strings
+[[inputs.disk]]
+  tagpass = { path = ["/"] }
+[[outputs.file]]
+  files = ["out.json"]
+  header = """
+[[not.a.table]]
+"""
+[[outputs.graphite]]
+  servers = ["carbon:2003"]
+`
+		r, err := Convert(Telegraf, strings.NewReader(conf))
+		So(err, ShouldBeNil)
+		So(r.Workflow.CollectNode.Metrics, ShouldContainKey, "/intel/psutil/net/eth[0]/*")
+		So(r.Workflow.CollectNode.Metrics, ShouldHaveLength, 3)
+		pubs := r.Workflow.CollectNode.PublishNodes
+		So(pubs, ShouldHaveLength, 1)
+		So(pubs[0].Name, ShouldEqual, "builtin/graphite")
+		notes := strings.Join(r.Notes, "\n")
+		So(notes, ShouldContainSubstring, "inputs.disk: skipped, line 6: tagpass: unsupported value")
+		So(notes, ShouldContainSubstring, "outputs.file: skipped, line 9: header: multi-line strings are not supported")
+		So(notes, ShouldNotContainSubstring, "not.a.table")
+	})
+	Convey("Converting an invalid Telegraf configuration fails", t, func() {
+		_, err := Convert(Telegraf, strings.NewReader("[[inputs.net]]\ninterfaces = [\"eth0\"\n"))
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestConvertCollectd(t *testing.T) {
+	Convey("Converting a collectd configuration", t, func() {
+		r, err := Convert(Collectd, strings.NewReader(collectdConf))
+		So(err, ShouldBeNil)
+		So(r.Interval, ShouldEqual, "1m0s")
+
+		Convey("collects the metrics of the loaded read plugins", func() {
+			So(r.Workflow.CollectNode.Metrics, ShouldContainKey, "/intel/psutil/cpu/*")
+			So(r.Workflow.CollectNode.Metrics, ShouldContainKey, "/intel/psutil/net/*")
+			So(r.Workflow.CollectNode.Metrics, ShouldHaveLength, 2)
+		})
+		Convey("publishes to the loaded write plugins", func() {
+			pubs := r.Workflow.CollectNode.PublishNodes
+			So(pubs, ShouldHaveLength, 1)
+			So(pubs[0].Name, ShouldEqual, "builtin/graphite")
+			So(pubs[0].Config["address"], ShouldEqual, "carbon.example.com:2003")
+			So(pubs[0].Config["protocol"], ShouldEqual, "udp")
+			So(pubs[0].Config["path"], ShouldEqual, "collectd.{{.Namespace}}")
+		})
+		Convey("notes what is not converted", func() {
+			notes := strings.Join(r.Notes, "\n")
+			So(notes, ShouldContainSubstring, "plugin interface: the ignored Interface lo are collected too")
+			So(notes, ShouldContainSubstring, "plugin apache: no corresponding snap plugin")
+			So(notes, ShouldContainSubstring, "plugin csv: configured but not loaded")
+			So(notes, ShouldNotContainSubstring, "syslog")
+		})
+	})
+	Convey("Converting an invalid collectd configuration fails", t, func() {
+		_, err := Convert(Collectd, strings.NewReader("LoadPlugin cpu\n<Plugin cpu>\n"))
+		So(err, ShouldNotBeNil)
+	})
+	Convey("Converting from an unknown format fails", t, func() {
+		_, err := Convert("nagios", strings.NewReader(""))
+		So(err, ShouldEqual, ErrUnknownFormat)
+	})
+}
@@ -0,0 +1,430 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package convert
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// tomlTable is a table of a TOML document, e.g. [agent] or [[inputs.cpu]]
+type tomlTable struct {
+	name   string
+	values map[string]interface{}
+	// unsupported tells why the table is skipped, when a value of the table
+	// is written in TOML the parser does not support
+	unsupported string
+}
+
+// parseTOML parses the subset of TOML Telegraf configurations are written
+// in: tables, arrays of tables and key/value pairs whose values are strings,
+// numbers, booleans or arrays of them, possibly spanning several lines.
+// The tables are returned in the order of the document, the keys before the
+// first table in a table without a name. A table holding a value outside of
+// the subset, e.g. an inline table or a multi-line string, is returned with
+// the reason it is not supported.
+func parseTOML(r io.Reader) ([]tomlTable, error) {
+	tables := []tomlTable{{values: map[string]interface{}{}}}
+	unsupported := func(format string, a ...interface{}) {
+		if t := &tables[len(tables)-1]; t.unsupported == "" {
+			t.unsupported = fmt.Sprintf(format, a...)
+		}
+	}
+	s := bufio.NewScanner(r)
+	n := 0
+	var pending string
+	// multiline is the delimiter of the multi-line string being skipped
+	var multiline string
+	start := 0
+	for s.Scan() {
+		n++
+		if multiline != "" {
+			if strings.Contains(s.Text(), multiline) {
+				multiline = ""
+			}
+			continue
+		}
+		line := strings.TrimSpace(stripComment(s.Text()))
+		if pending != "" {
+			line = pending + " " + line
+		} else {
+			start = n
+		}
+		if line == "" {
+			continue
+		}
+		// a key never starts with a bracket
+		if pending == "" && strings.HasPrefix(line, "[") {
+			name := strings.Trim(line, "[] \t")
+			if name == "" {
+				return nil, fmt.Errorf("line %d: empty table name", n)
+			}
+			tables = append(tables, tomlTable{name: name, values: map[string]interface{}{}})
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value", n)
+		}
+		key := strings.Trim(strings.TrimSpace(line[:i]), `"`)
+		value := strings.TrimSpace(line[i+1:])
+		if pending == "" {
+			if d := multilineDelimiter(value); d != "" {
+				unsupported("line %d: %s: multi-line strings are not supported", n, key)
+				if !strings.Contains(value[len(d):], d) {
+					multiline = d
+				}
+				continue
+			}
+		}
+		// an array goes on until its brackets are balanced
+		if tomlDepth(value) > 0 {
+			pending = line
+			continue
+		}
+		pending = ""
+		v, err := parseTOMLValue(value)
+		if err != nil {
+			unsupported("line %d: %s: %v", start, key, err)
+			continue
+		}
+		tables[len(tables)-1].values[key] = v
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if pending != "" {
+		return nil, fmt.Errorf("line %d: unterminated array", start)
+	}
+	if multiline != "" {
+		return nil, fmt.Errorf("line %d: unterminated multi-line string", start)
+	}
+	return tables, nil
+}
+
+// multilineDelimiter returns the delimiter of the multi-line string a value
+// starts with, if any
+func multilineDelimiter(value string) string {
+	for _, d := range []string{`"""`, "'''"} {
+		if strings.HasPrefix(value, d) {
+			return d
+		}
+	}
+	return ""
+}
+
+// tomlDepth returns the number of brackets of a value left open, those within
+// strings aside
+func tomlDepth(value string) int {
+	var quote rune
+	depth := 0
+	escaped := false
+	for _, c := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && c == '\\':
+			escaped = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		}
+	}
+	return depth
+}
+
+// stripComment removes the comment ending a line, leaving the # in strings
+func stripComment(line string) string {
+	var quote rune
+	for i, c := range line {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func parseTOMLValue(s string) (interface{}, error) {
+	switch {
+	case s == "":
+		return nil, fmt.Errorf("missing value")
+	case s == "true" || s == "false":
+		return s == "true", nil
+	case strings.HasPrefix(s, `"`):
+		return strconv.Unquote(s)
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return nil, fmt.Errorf("unterminated string %s", s)
+		}
+		return s[1 : len(s)-1], nil
+	case strings.HasPrefix(s, "["):
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("unterminated array %s", s)
+		}
+		var values []interface{}
+		for _, e := range splitTOMLArray(s[1 : len(s)-1]) {
+			v, err := parseTOMLValue(e)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	}
+	if i, err := strconv.ParseInt(strings.Replace(s, "_", "", -1), 10, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(strings.Replace(s, "_", "", -1), 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("unsupported value %s", s)
+}
+
+// splitTOMLArray splits the elements of an array on the commas outside of
+// strings and nested arrays
+func splitTOMLArray(s string) []string {
+	var elems []string
+	var quote rune
+	depth, last := 0, 0
+	for i, c := range s {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == ',' && depth == 0:
+			elems = append(elems, strings.TrimSpace(s[last:i]))
+			last = i + 1
+		}
+	}
+	if e := strings.TrimSpace(s[last:]); e != "" {
+		elems = append(elems, e)
+	}
+	return elems
+}
+
+// telegrafOutputs converts the Telegraf outputs onto the snap publishers
+var telegrafOutputs = map[string]func(*Result, string, map[string]interface{}){
+	"file":     telegrafFile,
+	"graphite": telegrafGraphite,
+	"influxdb": telegrafInfluxDB,
+	"kafka":    telegrafKafka,
+}
+
+func fromTelegraf(tables []tomlTable) (*Result, error) {
+	r := newResult()
+	for _, t := range tables {
+		if t.unsupported != "" {
+			name := t.name
+			if name == "" {
+				name = "top-level keys"
+			}
+			r.notef("%s: skipped, %s", name, t.unsupported)
+			continue
+		}
+		parts := strings.Split(t.name, ".")
+		switch {
+		case t.name == "":
+		case t.name == "agent":
+			if v, ok := t.values["interval"].(string); ok {
+				r.Interval = v
+			}
+		case t.name == "global_tags":
+			r.notef("global_tags: not converted, snap tags metrics through the tags of the collect node")
+		case len(parts) == 2 && parts[0] == "inputs":
+			in, ok := telegrafInputs[parts[1]]
+			if !ok {
+				r.notef("%s: no corresponding snap collector, skipped", t.name)
+				continue
+			}
+			r.addInput(in, stringList(t.values[in.instances]))
+			notOptions(r, t, "interval", in.instances)
+		case len(parts) == 2 && parts[0] == "outputs":
+			out, ok := telegrafOutputs[parts[1]]
+			if !ok {
+				r.notef("%s: no corresponding snap publisher, skipped", t.name)
+				continue
+			}
+			out(r, t.name, t.values)
+		default:
+			r.notef("%s: not converted", t.name)
+		}
+	}
+	return r.finish()
+}
+
+// notOptions notes the options of a table which are not converted, the
+// converted ones aside
+func notOptions(r *Result, t tomlTable, converted ...string) {
+	var keys []string
+	for _, k := range sortedKeys(t.values) {
+		skip := false
+		for _, c := range converted {
+			skip = skip || k == c
+		}
+		if !skip {
+			keys = append(keys, k)
+		}
+	}
+	if v, ok := t.values["interval"]; ok {
+		r.notef("%s: the interval %v of the input is not converted, the task collects every %s", t.name, v, r.Interval)
+	}
+	if len(keys) > 0 {
+		r.notef("%s: options %s not converted", t.name, strings.Join(keys, ", "))
+	}
+}
+
+// stringList returns the strings of a value which is a string or an array of
+// them
+func stringList(v interface{}) []string {
+	switch x := v.(type) {
+	case string:
+		return []string{x}
+	case []interface{}:
+		var ss []string
+		for _, e := range x {
+			if s, ok := e.(string); ok {
+				ss = append(ss, s)
+			}
+		}
+		return ss
+	}
+	return nil
+}
+
+func telegrafFile(r *Result, name string, values map[string]interface{}) {
+	format := "json"
+	switch df, _ := values["data_format"].(string); df {
+	case "", "influx":
+		r.notef("%s: the influx data format is written as JSON lines", name)
+	case "json":
+	case "csv":
+		format = "csv"
+	default:
+		r.notef("%s: the %s data format is written as JSON lines", name, df)
+	}
+	for _, f := range stringList(values["files"]) {
+		if f == "stdout" || f == "stderr" {
+			r.notef("%s: %s is not converted, snap publishes to files only", name, f)
+			continue
+		}
+		r.addPublisher("builtin/file", map[string]interface{}{
+			"path":   r.filePath(name, f),
+			"format": format,
+		})
+	}
+}
+
+func telegrafGraphite(r *Result, name string, values map[string]interface{}) {
+	servers := stringList(values["servers"])
+	if len(servers) == 0 {
+		servers = []string{"localhost:2003"}
+	}
+	config := map[string]interface{}{
+		"address": servers[0],
+	}
+	if prefix, _ := values["prefix"].(string); prefix != "" {
+		config["path"] = prefix + ".{{.Namespace}}"
+	}
+	if _, ok := values["template"]; ok {
+		r.notef("%s: the template is not converted, the path of a metric is its namespace", name)
+	}
+	if len(servers) > 1 {
+		r.notef("%s: only the first server, %s, is converted", name, servers[0])
+	}
+	r.addPublisher("builtin/graphite", config)
+}
+
+func telegrafInfluxDB(r *Result, name string, values map[string]interface{}) {
+	urls := stringList(values["urls"])
+	if len(urls) == 0 {
+		urls = []string{"http://localhost:8086"}
+	}
+	u, err := url.Parse(urls[0])
+	if err != nil {
+		r.notef("%s: invalid URL %s, skipped", name, urls[0])
+		return
+	}
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		host, port = u.Host, "8086"
+	}
+	p, _ := strconv.Atoi(port)
+	config := map[string]interface{}{
+		"host":     host,
+		"port":     p,
+		"database": "telegraf",
+	}
+	if db, _ := values["database"].(string); db != "" {
+		config["database"] = db
+	}
+	if user, _ := values["username"].(string); user != "" {
+		config["user"] = user
+	}
+	if password, _ := values["password"].(string); password != "" {
+		config["password"] = password
+	}
+	if u.Scheme == "https" {
+		config["https"] = true
+	}
+	if len(urls) > 1 {
+		r.notef("%s: only the first URL, %s, is converted", name, urls[0])
+	}
+	r.addPublisher("influxdb", config)
+}
+
+func telegrafKafka(r *Result, name string, values map[string]interface{}) {
+	brokers := stringList(values["brokers"])
+	if len(brokers) == 0 {
+		r.notef("%s: no brokers, skipped", name)
+		return
+	}
+	topic, _ := values["topic"].(string)
+	if topic == "" {
+		topic = "telegraf"
+	}
+	r.addPublisher("kafka", map[string]interface{}{
+		"brokers": strings.Join(brokers, ";"),
+		"topic":   topic,
+	})
+}
@@ -122,10 +122,31 @@ func (c *ConfigTree) Add(ns []string, inNode Node) {
 // Get returns a tree node given the namespace
 func (c *ConfigTree) Get(ns []string) Node {
 	c.log(fmt.Sprintf("Get on ns (%s)\n", ns))
+	matches := c.Matches(ns)
+	if len(matches) == 0 {
+		return nil
+	}
+	c.log(fmt.Sprintf("nodes to merge count (%d)\n", len(matches)))
+	// Call Node.Merge() sequentially on the matching nodes
+	rn := matches[0].Node
+	for _, m := range matches[1:] {
+		rn = rn.Merge(m.Node)
+	}
+	return rn
+}
+
+// Match is a node of the tree along with the namespace it was added at
+type Match struct {
+	Namespace []string
+	Node      Node
+}
+
+// Matches returns the nodes added at the namespace given or at any of its
+// prefixes, ordered from the shortest namespace to the longest
+func (c *ConfigTree) Matches(ns []string) []Match {
 	if !c.Frozen() {
 		panic("must freeze before getting")
 	}
-	retNodes := new([]Node)
 	// Return if no root exists (no tree without a root)
 	if c.root == nil {
 		c.log(fmt.Sprintln("ctree: no root - returning nil"))
@@ -151,24 +172,17 @@ func (c *ConfigTree) Get(ns []string) Node {
 	}
 	c.log(fmt.Sprintf("Match root key (match:'%s' == root:'%s')\n", string(nsToByteArray(match)), string(c.root.keysBytes)))
 
+	var matches []Match
 	if c.root.Node != nil {
 		c.log(fmt.Sprintf("adding root node (not nil) to nodes to merge (%v)\n", c.root.Node))
-		*retNodes = append(*retNodes, c.root.Node)
+		matches = append(matches, Match{Namespace: append([]string{}, c.root.keys...), Node: c.root.Node})
 	}
 
 	c.log(fmt.Sprintf("children to get from (%d)\n", len(c.root.nodes)))
 	for _, child := range c.root.nodes {
-		childNodes := child.get(remain)
-		*retNodes = append(*retNodes, *childNodes...)
+		matches = append(matches, child.get(remain, c.root.keys)...)
 	}
-
-	c.log(fmt.Sprintf("nodes to merge count (%d)\n", len(*retNodes)))
-	// Call Node.Merge() sequentially on the retNodes
-	rn := (*retNodes)[0]
-	for _, n := range (*retNodes)[1:] {
-		rn = rn.Merge(n)
-	}
-	return rn
+	return matches
 }
 
 // Freeze sets the ConfigTree's freezeFlag to true
@@ -315,29 +329,30 @@ func (n *node) empty() bool {
 	return n.Node == nil
 }
 
-func (n *node) get(ns []string) *[]Node {
-	retNodes := new([]Node)
+func (n *node) get(ns []string, prefix []string) []Match {
+	var matches []Match
 
 	rootKeyLength := len(n.keys)
 	if len(ns) < rootKeyLength {
-		return retNodes
+		return matches
 	}
 
 	match, remain := ns[:rootKeyLength], ns[rootKeyLength:]
 	if bytes.Compare(nsToByteArray(match), n.keysBytes) == 0 {
+		path := make([]string, 0, len(prefix)+rootKeyLength)
+		path = append(append(path, prefix...), n.keys...)
 		// If Node is present add to the return Nodes
 		if !n.empty() {
-			*retNodes = append(*retNodes, n.Node)
+			matches = append(matches, Match{Namespace: path, Node: n.Node})
 		}
 
 		// For any existing children call get
 		for _, child := range n.nodes {
-			childNodes := child.get(remain)
-			*retNodes = append(*retNodes, *childNodes...)
+			matches = append(matches, child.get(remain, path)...)
 		}
 	}
 
-	return retNodes
+	return matches
 }
 
 func nsToByteArray(str []string) []byte {
@@ -241,6 +241,34 @@ func TestConfigTree(t *testing.T) {
 		})
 	})
 
+	Convey("Matches()", t, func() {
+		d1 := newMockNode()
+		d1.data = "a"
+		d2 := newMockNode()
+		d2.data = "b"
+		d3 := newMockNode()
+		d3.data = "c"
+		c := New()
+		c.Add([]string{"intel", "foo", "sdilabs", "joel"}, d1)
+		c.Add([]string{"intel", "foo"}, d2)
+		c.Add([]string{"intel", "bar"}, d3)
+		c.Freeze()
+
+		Convey("returns the matching nodes from the shortest namespace", func() {
+			m := c.Matches([]string{"intel", "foo", "sdilabs", "joel", "dan"})
+			So(len(m), ShouldEqual, 2)
+			So(m[0].Namespace, ShouldResemble, []string{"intel", "foo"})
+			So(m[0].Node.(*mockNode).data, ShouldEqual, "b")
+			So(m[1].Namespace, ShouldResemble, []string{"intel", "foo", "sdilabs", "joel"})
+			So(m[1].Node.(*mockNode).data, ShouldEqual, "a")
+		})
+
+		Convey("returns nothing when no node matches", func() {
+			So(c.Matches([]string{"intel", "baz"}), ShouldBeEmpty)
+			So(c.Get([]string{"intel", "baz"}), ShouldBeNil)
+		})
+	})
+
 	Convey("Frozen()", t, func() {
 		c := New()
 		c.Freeze()
@@ -0,0 +1,159 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package datadir manages the single directory under which snapd keeps all of
+// its on-disk state.
+package datadir
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pborman/uuid"
+)
+
+// The subdirectories of the data directory
+const (
+	// Plugins holds plugins uploaded through the REST API
+	Plugins = "plugins"
+	// Keyrings holds keyrings used for plugin signature verification
+	Keyrings = "keyrings"
+	// WAL holds write-ahead logs
+	WAL = "wal"
+	// Logs holds plugin logs
+	Logs = "logs"
+	// Files holds the files written by the built-in file publisher
+	Files = "files"
+)
+
+// AgentIDFile is the file in the data directory holding the ID of snapd
+const AgentIDFile = "agent_id"
+
+// PluginConfigFile is the file in the data directory holding the plugin
+// config changed at runtime
+const PluginConfigFile = "plugin_config.json"
+
+// Subdirs are the subdirectories created under the data directory
+var Subdirs = []string{Plugins, Keyrings, WAL, Logs, Files}
+
+// DataDir is a data directory which has been created and checked
+type DataDir struct {
+	root string
+}
+
+// New creates the data directory at root along with its subdirectories
+// and checks that snapd owns them exclusively. Directories are created with
+// 0700 permissions; existing directories that are owned by another user or
+// are group or world writable are rejected.
+func New(root string) (*DataDir, error) {
+	if root == "" {
+		return nil, fmt.Errorf("data directory path is empty")
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	d := &DataDir{root: root}
+	if err := ensureDir(root); err != nil {
+		return nil, err
+	}
+	for _, sub := range Subdirs {
+		if err := ensureDir(d.Path(sub)); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Root returns the absolute path of the data directory
+func (d *DataDir) Root() string {
+	return d.root
+}
+
+// Path returns the path of a subdirectory (or file) in the data directory
+func (d *DataDir) Path(elem ...string) string {
+	return filepath.Join(append([]string{d.root}, elem...)...)
+}
+
+// AgentID returns the ID of snapd kept in the data directory, generating it
+// the first time.
+func (d *DataDir) AgentID() (string, error) {
+	path := d.Path(AgentIDFile)
+	b, err := ioutil.ReadFile(path)
+	if err == nil {
+		if id := strings.TrimSpace(string(b)); id != "" {
+			return id, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	id := uuid.New()
+	if err := ioutil.WriteFile(path, []byte(id+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// TempDir creates a new uniquely named directory in the given subdirectory
+func (d *DataDir) TempDir(sub string) (string, error) {
+	return ioutil.TempDir(d.Path(sub), "")
+}
+
+// Contains returns true if path is inside the given subdirectory
+func (d *DataDir) Contains(sub, path string) bool {
+	rel, err := filepath.Rel(d.Path(sub), path)
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !filepath.IsAbs(rel) && !hasParentPrefix(rel)
+}
+
+func hasParentPrefix(rel string) bool {
+	return len(rel) >= 3 && rel[:3] == ".."+string(filepath.Separator)
+}
+
+func ensureDir(path string) error {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", path)
+	}
+	if err := checkOwner(path, fi); err != nil {
+		return err
+	}
+	// Windows does not report meaningful permission bits
+	if runtime.GOOS != "windows" && fi.Mode().Perm()&0022 != 0 {
+		return fmt.Errorf("%s must not be group or world writable (mode %v)", path, fi.Mode().Perm())
+	}
+	f, err := ioutil.TempFile(path, ".check")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %v", path, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
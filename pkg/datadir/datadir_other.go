@@ -0,0 +1,23 @@
+//go:build !windows
+// +build !windows
+
+package datadir
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkOwner refuses a directory snapd does not own, which another user
+// could have created beforehand, e.g. in the system temp directory
+func checkOwner(path string, fi os.FileInfo) error {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	if uid := os.Getuid(); int(st.Uid) != uid {
+		return fmt.Errorf("%s is owned by uid %d, not by snapd (uid %d)", path, st.Uid, uid)
+	}
+	return nil
+}
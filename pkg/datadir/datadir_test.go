@@ -0,0 +1,106 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datadir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDataDir(t *testing.T) {
+	Convey("DataDir", t, func() {
+		tmp, err := ioutil.TempDir("", "snap-datadir")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(tmp)
+		root := filepath.Join(tmp, "data")
+
+		Convey("creates the root and subdirectories", func() {
+			d, err := New(root)
+			So(err, ShouldBeNil)
+			So(d.Root(), ShouldEqual, root)
+			for _, sub := range Subdirs {
+				fi, err := os.Stat(d.Path(sub))
+				So(err, ShouldBeNil)
+				So(fi.IsDir(), ShouldBeTrue)
+				So(fi.Mode().Perm(), ShouldEqual, os.FileMode(0700))
+			}
+		})
+		Convey("can be opened again", func() {
+			_, err := New(root)
+			So(err, ShouldBeNil)
+			_, err = New(root)
+			So(err, ShouldBeNil)
+		})
+		Convey("rejects a world writable directory", func() {
+			So(os.MkdirAll(root, 0700), ShouldBeNil)
+			So(os.Chmod(root, 0777), ShouldBeNil)
+			_, err := New(root)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "world writable")
+		})
+		Convey("rejects a directory owned by another user", func() {
+			// only root can give the directory to another user
+			if runtime.GOOS == "windows" || os.Getuid() != 0 {
+				return
+			}
+			So(os.MkdirAll(root, 0700), ShouldBeNil)
+			So(os.Chown(root, 65534, 65534), ShouldBeNil)
+			_, err := New(root)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "owned by uid 65534")
+		})
+		Convey("rejects a file", func() {
+			So(ioutil.WriteFile(root, []byte{}, 0600), ShouldBeNil)
+			_, err := New(root)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("rejects an empty path", func() {
+			_, err := New("")
+			So(err, ShouldNotBeNil)
+		})
+		Convey("creates temp dirs and checks containment", func() {
+			d, err := New(root)
+			So(err, ShouldBeNil)
+			dir, err := d.TempDir(Plugins)
+			So(err, ShouldBeNil)
+			So(d.Contains(Plugins, filepath.Join(dir, "plugin")), ShouldBeTrue)
+			So(d.Contains(Plugins, d.Path(Plugins)), ShouldBeFalse)
+			So(d.Contains(Plugins, d.Path(Logs, "x")), ShouldBeFalse)
+			So(d.Contains(Plugins, "/usr/bin/plugin"), ShouldBeFalse)
+		})
+		Convey("keeps the agent ID across openings", func() {
+			d, err := New(root)
+			So(err, ShouldBeNil)
+			id, err := d.AgentID()
+			So(err, ShouldBeNil)
+			So(id, ShouldNotBeEmpty)
+			d, err = New(root)
+			So(err, ShouldBeNil)
+			id2, err := d.AgentID()
+			So(err, ShouldBeNil)
+			So(id2, ShouldEqual, id)
+		})
+	})
+}
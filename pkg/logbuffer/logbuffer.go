@@ -0,0 +1,207 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logbuffer keeps the recent log entries of snapd and of the output
+// of its plugins, so they can be tailed remotely.
+package logbuffer
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	// DefaultSize is the number of entries kept by Default
+	DefaultSize = 1000
+
+	// PluginComponent prefixes the component of the output of plugins, as
+	// plugin:<name>
+	PluginComponent = "plugin"
+
+	// followBuffer is the number of entries a follower may lag behind before
+	// entries are dropped for it
+	followBuffer = 100
+)
+
+// componentAliases are the components of the modules not named after the
+// component they belong to
+var componentAliases = map[string]string{
+	"api":      "rest",
+	"client":   "control",
+	"config":   "control",
+	"routing":  "control",
+	"worker":   "tribe",
+	"schedule": "scheduler",
+}
+
+// Default keeps the entries logged by snapd, once added as a logrus hook,
+// and the output of its plugins
+var Default = New(DefaultSize)
+
+// Entry is a line logged by a component of snapd or a plugin
+type Entry struct {
+	Time      time.Time
+	Level     string
+	Component string
+	Message   string
+	Fields    map[string]string
+}
+
+// Buffer keeps the last entries added to it in a ring and hands the new
+// ones to its followers
+type Buffer struct {
+	mutex     sync.Mutex
+	entries   []Entry
+	next      int
+	full      bool
+	followers map[*follower]struct{}
+}
+
+type follower struct {
+	component string
+	ch        chan Entry
+}
+
+// New returns a buffer keeping the last size entries
+func New(size int) *Buffer {
+	return &Buffer{
+		entries:   make([]Entry, size),
+		followers: map[*follower]struct{}{},
+	}
+}
+
+// Add adds the entry, overwriting the oldest one once the buffer is full.
+// Followers lagging behind miss it.
+func (b *Buffer) Add(e Entry) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if len(b.entries) > 0 {
+		b.entries[b.next] = e
+		b.next = (b.next + 1) % len(b.entries)
+		if b.next == 0 {
+			b.full = true
+		}
+	}
+	for f := range b.followers {
+		if !Matches(e.Component, f.component) {
+			continue
+		}
+		select {
+		case f.ch <- e:
+		default:
+		}
+	}
+}
+
+// Recent returns the last n entries of the component, oldest first
+func (b *Buffer) Recent(component string, n int) []Entry {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.recent(component, n)
+}
+
+func (b *Buffer) recent(component string, n int) []Entry {
+	var entries []Entry
+	count := b.next
+	if b.full {
+		count = len(b.entries)
+	}
+	for i := 1; i <= count && len(entries) < n; i++ {
+		e := b.entries[(b.next-i+len(b.entries))%len(b.entries)]
+		if Matches(e.Component, component) {
+			entries = append(entries, e)
+		}
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries
+}
+
+// Tail returns the last n entries of the component like Recent, and a
+// channel receiving the ones added next until stop is called
+func (b *Buffer) Tail(component string, n int) (recent []Entry, next <-chan Entry, stop func()) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	f := &follower{component: component, ch: make(chan Entry, followBuffer)}
+	b.followers[f] = struct{}{}
+	var once sync.Once
+	return b.recent(component, n), f.ch, func() {
+		once.Do(func() {
+			b.mutex.Lock()
+			delete(b.followers, f)
+			b.mutex.Unlock()
+		})
+	}
+}
+
+// Matches returns whether the entries of the component are selected by the
+// filter: all of them when empty, the output of all the plugins for
+// "plugin", else the component named
+func Matches(component, filter string) bool {
+	switch {
+	case filter == "":
+		return true
+	case filter == PluginComponent:
+		return strings.HasPrefix(component, PluginComponent+":")
+	}
+	return component == filter
+}
+
+// Component returns the component of a module of snapd, which logs the
+// entries of its blocks as control-runner or control-aplugin for instance
+func Component(module string) string {
+	c := strings.SplitN(module, "-", 2)[0]
+	if alias, ok := componentAliases[c]; ok {
+		return alias
+	}
+	return c
+}
+
+// Levels returns the levels of the entries kept when the buffer is a logrus
+// hook, all of them
+func (b *Buffer) Levels() []log.Level {
+	return []log.Level{log.PanicLevel, log.FatalLevel, log.ErrorLevel, log.WarnLevel, log.InfoLevel, log.DebugLevel}
+}
+
+// Fire adds an entry logged, whose component is the one of its _module
+// field. Entries logged for a plugin field of the plugin module are the
+// output of the plugin.
+func (b *Buffer) Fire(le *log.Entry) error {
+	e := Entry{
+		Time:    le.Time,
+		Level:   le.Level.String(),
+		Message: le.Message,
+		Fields:  map[string]string{},
+	}
+	for k, v := range le.Data {
+		e.Fields[k] = fmt.Sprint(v)
+	}
+	e.Component = Component(e.Fields["_module"])
+	if e.Component == PluginComponent && e.Fields["plugin"] != "" {
+		e.Component = PluginComponent + ":" + e.Fields["plugin"]
+	}
+	delete(e.Fields, "_module")
+	b.Add(e)
+	return nil
+}
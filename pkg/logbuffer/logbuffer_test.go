@@ -0,0 +1,90 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logbuffer
+
+import (
+	"testing"
+
+	log "github.com/Sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func messages(entries []Entry) []string {
+	var m []string
+	for _, e := range entries {
+		m = append(m, e.Message)
+	}
+	return m
+}
+
+func TestComponent(t *testing.T) {
+	Convey("Component", t, func() {
+		So(Component("control-runner"), ShouldEqual, "control")
+		So(Component("rest"), ShouldEqual, "rest")
+		So(Component("api"), ShouldEqual, "rest")
+		So(Component("routing-cache"), ShouldEqual, "control")
+		So(Component(""), ShouldEqual, "")
+	})
+}
+
+func TestBuffer(t *testing.T) {
+	Convey("Given a buffer", t, func() {
+		b := New(3)
+		Convey("the last entries are kept, oldest first", func() {
+			for _, m := range []string{"one", "two", "three", "four"} {
+				b.Add(Entry{Component: "control", Message: m})
+			}
+			So(messages(b.Recent("", 10)), ShouldResemble, []string{"two", "three", "four"})
+			So(messages(b.Recent("", 2)), ShouldResemble, []string{"three", "four"})
+		})
+		Convey("the entries are filtered by component", func() {
+			b.Add(Entry{Component: "control", Message: "loaded"})
+			b.Add(Entry{Component: "plugin:snap-collector-mock1", Message: "collecting"})
+			b.Add(Entry{Component: "rest", Message: "API request"})
+			So(messages(b.Recent("rest", 10)), ShouldResemble, []string{"API request"})
+			So(messages(b.Recent("plugin", 10)), ShouldResemble, []string{"collecting"})
+			So(messages(b.Recent("plugin:snap-collector-mock1", 10)), ShouldResemble, []string{"collecting"})
+			So(b.Recent("plugin:snap-collector-mock2", 10), ShouldBeEmpty)
+		})
+		Convey("the entries added next are followed", func() {
+			b.Add(Entry{Component: "control", Message: "before"})
+			recent, next, stop := b.Tail("control", 10)
+			So(messages(recent), ShouldResemble, []string{"before"})
+			b.Add(Entry{Component: "rest", Message: "ignored"})
+			b.Add(Entry{Component: "control", Message: "after"})
+			So((<-next).Message, ShouldEqual, "after")
+			stop()
+			b.Add(Entry{Component: "control", Message: "stopped"})
+			So(next, ShouldHaveLength, 0)
+		})
+		Convey("the entries logged are added as a hook", func() {
+			logger := log.New()
+			logger.Hooks.Add(b)
+			logger.WithFields(log.Fields{"_module": "control-runner", "_block": "start"}).Info("runner started")
+			logger.WithFields(log.Fields{"_module": "plugin", "plugin": "snap-collector-mock1"}).Info("collecting")
+			e := b.Recent("", 10)
+			So(e, ShouldHaveLength, 2)
+			So(e[0].Component, ShouldEqual, "control")
+			So(e[0].Level, ShouldEqual, "info")
+			So(e[0].Fields, ShouldResemble, map[string]string{"_block": "start"})
+			So(e[1].Component, ShouldEqual, "plugin:snap-collector-mock1")
+		})
+	})
+}
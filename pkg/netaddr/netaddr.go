@@ -0,0 +1,94 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package netaddr resolves the addresses snapd listens on, which can be given
+// as an IP address or as the name of a network interface.
+package netaddr
+
+import (
+	"fmt"
+	"net"
+)
+
+// Resolve returns the IP address s stands for: s itself when it is an IP
+// address, otherwise the first address of the network interface named s,
+// IPv4 addresses first.
+func Resolve(s string) (string, error) {
+	if ip := net.ParseIP(s); ip != nil {
+		return ip.String(), nil
+	}
+	iface, err := net.InterfaceByName(s)
+	if err != nil {
+		return "", fmt.Errorf("%q is neither an IP address nor a network interface", s)
+	}
+	ip, err := firstIP([]net.Interface{*iface}, true)
+	if err != nil {
+		return "", err
+	}
+	if ip == nil {
+		return "", fmt.Errorf("network interface %s has no usable address", s)
+	}
+	return ip.String(), nil
+}
+
+// Default returns the first address of the host which is not a loopback one,
+// IPv4 addresses first, or 127.0.0.1 when there is none.
+func Default() string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "127.0.0.1"
+	}
+	ip, err := firstIP(ifaces, false)
+	if err != nil || ip == nil {
+		return "127.0.0.1"
+	}
+	return ip.String()
+}
+
+// firstIP returns the first IPv4 address of the interfaces, or their first
+// IPv6 one when they have none. Link-local IPv6 addresses, which cannot be
+// used without a zone, are skipped, and loopback addresses unless allowed.
+func firstIP(ifaces []net.Interface, loopback bool) (net.IP, error) {
+	var v6 net.IP
+	for _, i := range ifaces {
+		addrs, err := i.Addrs()
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			var ip net.IP
+			switch v := addr.(type) {
+			case *net.IPAddr:
+				ip = v.IP
+			case *net.IPNet:
+				ip = v.IP
+			}
+			if ip == nil || (ip.IsLoopback() && !loopback) || ip.IsLinkLocalUnicast() {
+				continue
+			}
+			if ip4 := ip.To4(); ip4 != nil {
+				return ip4, nil
+			}
+			if v6 == nil {
+				v6 = ip
+			}
+		}
+	}
+	return v6, nil
+}
@@ -0,0 +1,71 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netaddr
+
+import (
+	"net"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func loopbackInterface() string {
+	ifaces, _ := net.Interfaces()
+	for _, i := range ifaces {
+		if i.Flags&net.FlagLoopback != 0 {
+			return i.Name
+		}
+	}
+	return ""
+}
+
+func TestResolve(t *testing.T) {
+	Convey("Resolve", t, func() {
+		Convey("returns IP addresses as they are", func() {
+			ip, err := Resolve("10.1.2.3")
+			So(err, ShouldBeNil)
+			So(ip, ShouldEqual, "10.1.2.3")
+			ip, err = Resolve("fd00::1")
+			So(err, ShouldBeNil)
+			So(ip, ShouldEqual, "fd00::1")
+		})
+		Convey("returns the address of a network interface", func() {
+			lo := loopbackInterface()
+			if lo == "" {
+				SkipSo("no loopback interface")
+				return
+			}
+			ip, err := Resolve(lo)
+			So(err, ShouldBeNil)
+			So(net.ParseIP(ip).IsLoopback(), ShouldBeTrue)
+		})
+		Convey("fails on an unknown network interface", func() {
+			_, err := Resolve("no-such-iface0")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "neither an IP address nor a network interface")
+		})
+	})
+}
+
+func TestDefault(t *testing.T) {
+	Convey("Default returns an IP address", t, func() {
+		So(net.ParseIP(Default()), ShouldNotBeNil)
+	})
+}
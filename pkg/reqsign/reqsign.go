@@ -0,0 +1,242 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package reqsign signs the requests the members of a tribe make to the REST
+// API of each other with an HMAC of a key they share, and verifies them. It
+// authenticates the members without sharing the password of the REST API
+// across the cluster.
+package reqsign
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scheme is the scheme of the Authorization header of the signed requests
+const Scheme = "Snap-HMAC-SHA256"
+
+const (
+	// the header holding the SHA-256 of the body of a signed request
+	contentSHA256Header = "X-Snap-Content-Sha256"
+	// the digest of a body streamed, which cannot be read beforehand
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	// DefaultMaxAge is how long a signature is accepted after the request
+	// was signed, or before given clock skews
+	DefaultMaxAge = 5 * time.Minute
+)
+
+var (
+	// ErrNotSigned is returned by Verify for a request which is not signed
+	ErrNotSigned = errors.New("request not signed")
+	// ErrBadSignature is returned by Verify for a request whose signature
+	// does not match
+	ErrBadSignature = errors.New("bad request signature")
+	// ErrExpired is returned by Verify for a request signed too long ago
+	ErrExpired = errors.New("request signature expired")
+	// ErrReplayed is returned by Verify for a request already verified
+	ErrReplayed = errors.New("request signature replayed")
+	// ErrUnsignedBody is returned by Verify for a request other than a GET
+	// or HEAD whose body was streamed, and so left unsigned
+	ErrUnsignedBody = errors.New("request body not signed")
+	// ErrBodyTooLarge is returned by Verify for a request whose body is
+	// larger than the verifier reads to check its digest
+	ErrBodyTooLarge = errors.New("request body too large")
+
+	// now is replaced in tests
+	now = time.Now
+	// maxBody bounds the body read to check its digest, which holds the
+	// plugins the members load on each other; replaced in tests
+	maxBody int64 = 256 << 20
+)
+
+// DeriveKey derives the key signing the requests from the key encrypting the
+// gossip of the tribe, for the two not to be used for one another
+func DeriveKey(gossipKey []byte) []byte {
+	mac := hmac.New(sha256.New, gossipKey)
+	mac.Write([]byte("snap tribe request signing"))
+	return mac.Sum(nil)
+}
+
+// Signer signs the requests of a member
+type Signer struct {
+	member string
+	key    []byte
+}
+
+// NewSigner returns the signer of the requests of the member
+func NewSigner(member string, key []byte) *Signer {
+	return &Signer{member: member, key: key}
+}
+
+// Sign signs the request as made by the member. The body is signed when it
+// can be read again, i.e. when the request has GetBody set as it has for a
+// bytes.Reader, and left unsigned when it is streamed, which Verify only
+// accepts for a GET or HEAD.
+func (s *Signer) Sign(r *http.Request) error {
+	digest, err := bodyDigest(r)
+	if err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(now().Unix(), 10)
+	r.Header.Set(contentSHA256Header, digest)
+	r.Header.Set("Authorization", fmt.Sprintf("%s Member=%s, Timestamp=%s, Signature=%s",
+		Scheme, url.QueryEscape(s.member), ts, sign(s.key, r, s.member, ts, digest)))
+	return nil
+}
+
+func bodyDigest(r *http.Request) (string, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return hashHex(nil), nil
+	}
+	if r.GetBody == nil {
+		return unsignedPayload, nil
+	}
+	body, err := r.GetBody()
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	return hashHex(b), nil
+}
+
+// sign returns the signature of the request, covering its method, URI,
+// member, timestamp and body digest
+func sign(key []byte, r *http.Request, member, ts, digest string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.Join([]string{r.Method, r.URL.RequestURI(), member, ts, digest}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func hashHex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+// Verifier verifies the requests signed with a key. A signature is accepted
+// once, within MaxAge of the time the request was signed at.
+type Verifier struct {
+	key    []byte
+	maxAge time.Duration
+
+	mutex sync.Mutex
+	// the expiry of the signatures verified
+	seen map[string]time.Time
+}
+
+// NewVerifier returns the verifier of the requests signed with the key,
+// accepting the signatures within maxAge (DefaultMaxAge when 0)
+func NewVerifier(key []byte, maxAge time.Duration) *Verifier {
+	if maxAge == 0 {
+		maxAge = DefaultMaxAge
+	}
+	return &Verifier{key: key, maxAge: maxAge, seen: map[string]time.Time{}}
+}
+
+// Verify returns the member which signed the request, or ErrNotSigned when
+// the request is not signed. The signature is checked over the digest the
+// request claims before its body is read, for clients without the key not to
+// have snapd buffer it. The body is then read to verify its digest and
+// replaced for it to be read again.
+func (v *Verifier) Verify(r *http.Request) (string, error) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, Scheme+" ") {
+		return "", ErrNotSigned
+	}
+	params := map[string]string{}
+	for _, p := range strings.Split(strings.TrimPrefix(auth, Scheme+" "), ",") {
+		kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+		if len(kv) == 2 {
+			params[kv[0]] = kv[1]
+		}
+	}
+	member, err := url.QueryUnescape(params["Member"])
+	if err != nil || member == "" {
+		return "", ErrBadSignature
+	}
+	ts, err := strconv.ParseInt(params["Timestamp"], 10, 64)
+	if err != nil {
+		return "", ErrBadSignature
+	}
+	signedAt := time.Unix(ts, 0)
+	if d := now().Sub(signedAt); d > v.maxAge || d < -v.maxAge {
+		return "", ErrExpired
+	}
+	digest := r.Header.Get(contentSHA256Header)
+	if digest == unsignedPayload && r.Method != "GET" && r.Method != "HEAD" {
+		return "", ErrUnsignedBody
+	}
+	signature := params["Signature"]
+	if !hmac.Equal([]byte(signature), []byte(sign(v.key, r, member, params["Timestamp"], digest))) {
+		return "", ErrBadSignature
+	}
+	if digest != unsignedPayload {
+		var b []byte
+		if r.Body != nil {
+			if b, err = ioutil.ReadAll(io.LimitReader(r.Body, maxBody+1)); err != nil {
+				return "", err
+			}
+			r.Body.Close()
+			if int64(len(b)) > maxBody {
+				return "", ErrBodyTooLarge
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(b))
+		}
+		if !hmac.Equal([]byte(digest), []byte(hashHex(b))) {
+			return "", ErrBadSignature
+		}
+	}
+	if !v.firstSeen(signature, signedAt.Add(v.maxAge)) {
+		return "", ErrReplayed
+	}
+	return member, nil
+}
+
+// firstSeen records the signature until it expires, returning whether it was
+// not seen yet
+func (v *Verifier) firstSeen(signature string, expiry time.Time) bool {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	t := now()
+	for s, e := range v.seen {
+		if t.After(e) {
+			delete(v.seen, s)
+		}
+	}
+	if _, ok := v.seen[signature]; ok {
+		return false
+	}
+	v.seen[signature] = expiry
+	return true
+}
@@ -0,0 +1,140 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reqsign
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSignVerify(t *testing.T) {
+	Convey("Given the signer of a member and the verifier of the tribe", t, func() {
+		key := DeriveKey([]byte("0123456789abcdef"))
+		s := NewSigner("edge 1", key)
+		v := NewVerifier(key, time.Minute)
+		signed := func(method, url, body string) *http.Request {
+			req, _ := http.NewRequest(method, url, nil)
+			if body != "" {
+				req, _ = http.NewRequest(method, url, bytes.NewReader([]byte(body)))
+			}
+			So(s.Sign(req), ShouldBeNil)
+			return req
+		}
+
+		Convey("a signed request is verified as made by the member", func() {
+			req := signed("POST", "http://10.0.0.2:8181/v1/tasks?x=1", `{"name":"t"}`)
+			member, err := v.Verify(req)
+			So(err, ShouldBeNil)
+			So(member, ShouldEqual, "edge 1")
+			Convey("and its body can still be read", func() {
+				b, _ := ioutil.ReadAll(req.Body)
+				So(string(b), ShouldEqual, `{"name":"t"}`)
+			})
+			Convey("but only once", func() {
+				req.Body = ioutil.NopCloser(strings.NewReader(`{"name":"t"}`))
+				_, err := v.Verify(req)
+				So(err, ShouldEqual, ErrReplayed)
+			})
+		})
+		Convey("a request without body is verified", func() {
+			_, err := v.Verify(signed("GET", "http://10.0.0.2:8181/v1/tasks", ""))
+			So(err, ShouldBeNil)
+		})
+		Convey("a request whose body was changed is refused", func() {
+			req := signed("POST", "http://10.0.0.2:8181/v1/tasks", `{"name":"t"}`)
+			req.Body = ioutil.NopCloser(strings.NewReader(`{"name":"u"}`))
+			_, err := v.Verify(req)
+			So(err, ShouldEqual, ErrBadSignature)
+		})
+		Convey("the body of a request signed with another key is not read", func() {
+			body := &countingReader{r: strings.NewReader(`{"name":"t"}`)}
+			req, _ := http.NewRequest("POST", "http://10.0.0.2:8181/v1/tasks", nil)
+			NewSigner("edge 1", DeriveKey([]byte("fedcba9876543210"))).Sign(req)
+			req.Header.Set(contentSHA256Header, hashHex([]byte(`{"name":"t"}`)))
+			req.Body = ioutil.NopCloser(body)
+			_, err := v.Verify(req)
+			So(err, ShouldEqual, ErrBadSignature)
+			So(body.n, ShouldEqual, 0)
+		})
+		Convey("a body larger than the verifier reads is refused", func() {
+			maxBody = 4
+			defer func() { maxBody = 256 << 20 }()
+			_, err := v.Verify(signed("POST", "http://10.0.0.2:8181/v1/tasks", `{"name":"t"}`))
+			So(err, ShouldEqual, ErrBodyTooLarge)
+		})
+		Convey("a request whose URI was changed is refused", func() {
+			req := signed("DELETE", "http://10.0.0.2:8181/v1/tasks/a", "")
+			req.URL.Path = "/v1/tasks/b"
+			_, err := v.Verify(req)
+			So(err, ShouldEqual, ErrBadSignature)
+		})
+		Convey("a request signed with another key is refused", func() {
+			req, _ := http.NewRequest("GET", "http://10.0.0.2:8181/v1/tasks", nil)
+			NewSigner("edge 1", DeriveKey([]byte("fedcba9876543210"))).Sign(req)
+			_, err := v.Verify(req)
+			So(err, ShouldEqual, ErrBadSignature)
+		})
+		Convey("a request signed too long ago is refused", func() {
+			now = func() time.Time { return time.Now().Add(-2 * time.Minute) }
+			req := signed("GET", "http://10.0.0.2:8181/v1/tasks", "")
+			now = time.Now
+			_, err := v.Verify(req)
+			So(err, ShouldEqual, ErrExpired)
+		})
+		Convey("a streamed body is left unsigned", func() {
+			req, _ := http.NewRequest("GET", "http://10.0.0.2:8181/v1/plugins", ioutil.NopCloser(strings.NewReader("plugin")))
+			So(s.Sign(req), ShouldBeNil)
+			So(req.Header.Get(contentSHA256Header), ShouldEqual, unsignedPayload)
+			_, err := v.Verify(req)
+			So(err, ShouldBeNil)
+			Convey("which is refused but for a GET or HEAD", func() {
+				req, _ := http.NewRequest("POST", "http://10.0.0.2:8181/v1/plugins", ioutil.NopCloser(strings.NewReader("plugin")))
+				So(s.Sign(req), ShouldBeNil)
+				_, err := v.Verify(req)
+				So(err, ShouldEqual, ErrUnsignedBody)
+			})
+		})
+		Convey("a request which is not signed is told apart", func() {
+			req, _ := http.NewRequest("GET", "http://10.0.0.2:8181/v1/tasks", nil)
+			req.SetBasicAuth("snap", "secret")
+			_, err := v.Verify(req)
+			So(err, ShouldEqual, ErrNotSigned)
+		})
+	})
+}
+
+// countingReader counts the bytes read from r
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
@@ -0,0 +1,87 @@
+package schedule
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of time the schedules wait on
+type Clock interface {
+	// Returns the current time
+	Now() time.Time
+	// Blocks for the given duration
+	Sleep(time.Duration)
+}
+
+// Clocked is implemented by the schedules which wait on a clock of their
+// own, the real clock unless another one is set
+type Clocked interface {
+	// Returns the clock of the schedule
+	Clock() Clock
+	// Sets the clock of the schedule, before it is waited on
+	SetClock(Clock)
+}
+
+// ClockOf returns the clock a schedule waits on
+func ClockOf(s Schedule) Clock {
+	if c, ok := s.(Clocked); ok {
+		return c.Clock()
+	}
+	return realClock{}
+}
+
+// clocked is embedded by the schedules to implement Clocked
+type clocked struct {
+	clock Clock
+}
+
+// Clock returns the clock of the schedule
+func (c *clocked) Clock() Clock {
+	if c.clock == nil {
+		return realClock{}
+	}
+	return c.clock
+}
+
+// SetClock sets the clock of the schedule
+func (c *clocked) SetClock(clock Clock) {
+	c.clock = clock
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// SimulatedClock is a Clock which does not block: sleeping fast-forwards it
+// instead, so schedules can be run through deterministically
+type SimulatedClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+// NewSimulatedClock returns a SimulatedClock set to the given time
+func NewSimulatedClock(now time.Time) *SimulatedClock {
+	return &SimulatedClock{now: now}
+}
+
+// Now returns the current time of the clock
+func (c *SimulatedClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Sleep moves the clock forward by the given duration
+func (c *SimulatedClock) Sleep(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if d > 0 {
+		c.now = c.now.Add(d)
+	}
+}
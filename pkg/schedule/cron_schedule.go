@@ -34,6 +34,7 @@ type CronSchedule struct {
 	enabled  bool
 	state    ScheduleState
 	schedule *cron.Cron
+	clocked
 }
 
 // NewCronSchedule creates and starts new cron schedule and returns an instance of CronSchedule
@@ -66,7 +67,7 @@ func (c *CronSchedule) Validate() error {
 // Wait waits as long as specified in cron entry
 func (c *CronSchedule) Wait(last time.Time) Response {
 	var err error
-	now := time.Now()
+	now := c.Clock().Now()
 
 	// first run
 	if (last == time.Time{}) {
@@ -97,14 +98,14 @@ func (c *CronSchedule) Wait(last time.Time) Response {
 
 		// wait
 		waitTime := s.Next(now)
-		time.Sleep(waitTime.Sub(now))
+		c.Clock().Sleep(waitTime.Sub(now))
 	}
 
 	return &CronScheduleResponse{
 		state:    c.GetState(),
 		err:      err,
 		missed:   misses,
-		lastTime: time.Now(),
+		lastTime: c.Clock().Now(),
 	}
 }
 
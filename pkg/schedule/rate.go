@@ -0,0 +1,52 @@
+package schedule
+
+import (
+	"time"
+
+	"github.com/robfig/cron"
+)
+
+// the fires of a cron schedule looked at to find its rate
+const cronRateFires = 1000
+
+// Rate returns the number of times per second the schedule fires, 0 for the
+// schedules which fire on events rather than on time. The rate of a cron
+// schedule is that of the shortest interval between its next fires.
+func Rate(s Schedule) float64 {
+	var interval time.Duration
+	switch s := s.(type) {
+	case *SimpleSchedule:
+		interval = s.Interval
+	case *WindowedSchedule:
+		interval = s.Interval
+	case *CronSchedule:
+		interval = s.shortestInterval()
+	}
+	if interval <= 0 {
+		return 0
+	}
+	return float64(time.Second) / float64(interval)
+}
+
+// shortestInterval returns the shortest interval between the next fires of
+// the schedule, over a day at most
+func (c *CronSchedule) shortestInterval() time.Duration {
+	sch, err := cron.Parse(c.entry)
+	if err != nil {
+		return 0
+	}
+	var shortest time.Duration
+	prev := sch.Next(c.Clock().Now())
+	end := prev.Add(24 * time.Hour)
+	for i := 0; i < cronRateFires && prev.Before(end); i++ {
+		next := sch.Next(prev)
+		if next.IsZero() {
+			break
+		}
+		if d := next.Sub(prev); shortest == 0 || d < shortest {
+			shortest = d
+		}
+		prev = next
+	}
+	return shortest
+}
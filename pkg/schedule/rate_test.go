@@ -0,0 +1,25 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRate(t *testing.T) {
+	Convey("The rate of a schedule", t, func() {
+		Convey("is the inverse of its interval", func() {
+			So(Rate(NewSimpleSchedule(500*time.Millisecond)), ShouldEqual, 2)
+			So(Rate(NewWindowedSchedule(10*time.Second, nil, nil)), ShouldEqual, 0.1)
+		})
+		Convey("is that of the shortest interval of a cron schedule", func() {
+			So(Rate(NewCronSchedule("@every 5s")), ShouldEqual, 0.2)
+			So(Rate(NewCronSchedule("0,30 0 * * * *")), ShouldAlmostEqual, 1.0/30)
+			So(Rate(NewCronSchedule("invalid cron entry")), ShouldEqual, 0)
+		})
+		Convey("is 0 for a schedule firing on events", func() {
+			So(Rate(NewTriggerSchedule()), ShouldEqual, 0)
+		})
+	})
+}
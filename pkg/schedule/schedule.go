@@ -14,6 +14,8 @@ var (
 	ErrInvalidStopTime = errors.New("Stop time is in the past")
 	// ErrStopBeforeStart - Error message for the stop time cannot occur before start time
 	ErrStopBeforeStart = errors.New("Stop time cannot occur before start time")
+	// ErrInvalidJitter - Error message for the jitter must not be negative or exceed the interval
+	ErrInvalidJitter = errors.New("Jitter must be greater than or equal to 0 and less than the interval")
 )
 
 // ScheduleState int type
@@ -50,13 +52,25 @@ type Response interface {
 	LastTime() time.Time
 }
 
-func waitOnInterval(last time.Time, i time.Duration) (uint, time.Time) {
+// Starter is implemented by schedules which do not start at the time a task
+// starts spinning
+type Starter interface {
+	// Returns the time the schedule starts from when started at the given time
+	Start(time.Time) time.Time
+}
+
+func waitOnInterval(clock Clock, last time.Time, i time.Duration) (uint, time.Time) {
 	if (last == time.Time{}) {
-		time.Sleep(i)
-		return uint(0), time.Now()
+		clock.Sleep(i)
+		return uint(0), clock.Now()
+	}
+	// the schedule starts in the future
+	if d := last.Sub(clock.Now()); d > 0 {
+		clock.Sleep(d + i)
+		return uint(0), clock.Now()
 	}
 	// Get the difference in time.Duration since last in nanoseconds (int64)
-	timeDiff := time.Since(last).Nanoseconds()
+	timeDiff := clock.Now().Sub(last).Nanoseconds()
 	// cache our schedule interval in nanseconds
 	nanoInterval := i.Nanoseconds()
 	// use modulo operation to obtain the remainder of time over last interval
@@ -65,6 +79,6 @@ func waitOnInterval(last time.Time, i time.Duration) (uint, time.Time) {
 	missed := (timeDiff - remainder) / nanoInterval // timeDiff.Nanoseconds() % s.Interval.Nanoseconds()
 	waitDuration := nanoInterval - remainder
 	// Wait until predicted interval fires
-	time.Sleep(time.Duration(waitDuration))
-	return uint(missed), time.Now()
+	clock.Sleep(time.Duration(waitDuration))
+	return uint(missed), clock.Now()
 }
@@ -1,13 +1,20 @@
 package schedule
 
 import (
+	"hash/fnv"
 	"time"
 )
 
 // SimpleSchedule is a schedule that only implements an endless repeating interval
 type SimpleSchedule struct {
 	Interval time.Duration
-	state    ScheduleState
+	// Jitter delays the first fire by an offset between 0 and Jitter so that
+	// schedules with the same interval do not all fire at the same time.
+	// The offset is derived from the seed which keeps it stable for a task.
+	Jitter time.Duration
+	state  ScheduleState
+	seed   string
+	clocked
 }
 
 // NewSimpleSchedule returns the SimpleSchedule given the time interval
@@ -22,18 +29,44 @@ func (s *SimpleSchedule) GetState() ScheduleState {
 	return s.state
 }
 
+// SetJitterSeed sets the seed the jitter offset is derived from, typically
+// the ID of the task the schedule belongs to
+func (s *SimpleSchedule) SetJitterSeed(seed string) {
+	s.seed = seed
+}
+
+// JitterOffset returns the offset the first fire is delayed by
+func (s *SimpleSchedule) JitterOffset() time.Duration {
+	if s.Jitter <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(s.seed))
+	return time.Duration(h.Sum64() % uint64(s.Jitter))
+}
+
 // Validate returns an error if the interval of schedule is less
-// or equals zero
+// or equals zero or if the jitter is negative or not less than the interval
 func (s *SimpleSchedule) Validate() error {
 	if s.Interval <= 0 {
 		return ErrInvalidInterval
 	}
+	if s.Jitter < 0 || s.Jitter >= s.Interval {
+		return ErrInvalidJitter
+	}
 	return nil
 }
 
+// Start returns the time the schedule starts from when a task starts spinning
+// at now. It is delayed by the jitter offset; later waits are aligned on the
+// last fire so the offset is kept and the interval stays stable.
+func (s *SimpleSchedule) Start(now time.Time) time.Time {
+	return now.Add(s.JitterOffset())
+}
+
 // Wait returns the SimpleSchedule state, misses and the last schedule ran
 func (s *SimpleSchedule) Wait(last time.Time) Response {
-	m, t := waitOnInterval(last, s.Interval)
+	m, t := waitOnInterval(s.Clock(), last, s.Interval)
 	return &SimpleScheduleResponse{state: s.GetState(), missed: m, lastTime: t}
 }
 
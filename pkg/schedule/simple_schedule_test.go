@@ -39,5 +39,42 @@ func TestSimpleSchedule(t *testing.T) {
 			So(err, ShouldResemble, ErrInvalidInterval)
 		})
 
+		Convey("invalid jitter", func() {
+			s := NewSimpleSchedule(time.Second)
+			s.Jitter = -time.Millisecond
+			So(s.Validate(), ShouldResemble, ErrInvalidJitter)
+			s.Jitter = time.Second
+			So(s.Validate(), ShouldResemble, ErrInvalidJitter)
+		})
+
+		Convey("jitter offset is deterministic per seed", func() {
+			s1 := NewSimpleSchedule(time.Second)
+			s1.Jitter = 500 * time.Millisecond
+			s1.SetJitterSeed("task-1")
+			s2 := NewSimpleSchedule(time.Second)
+			s2.Jitter = 500 * time.Millisecond
+			s2.SetJitterSeed("task-1")
+			So(s1.JitterOffset(), ShouldEqual, s2.JitterOffset())
+			So(s1.JitterOffset(), ShouldBeLessThan, s1.Jitter)
+			So(s1.JitterOffset(), ShouldBeGreaterThanOrEqualTo, 0)
+			s2.SetJitterSeed("task-2")
+			So(s2.JitterOffset(), ShouldNotEqual, s1.JitterOffset())
+			s2.Jitter = 0
+			So(s2.JitterOffset(), ShouldEqual, 0)
+		})
+
+		Convey("first wait is delayed by the jitter offset", func() {
+			s := NewSimpleSchedule(20 * time.Millisecond)
+			s.Jitter = 15 * time.Millisecond
+			s.SetJitterSeed("task-1")
+			s.SetClock(NewSimulatedClock(time.Unix(1000, 0)))
+
+			before := s.Clock().Now()
+			r := s.Wait(s.Start(before))
+
+			So(r.State(), ShouldEqual, Active)
+			So(r.LastTime().Sub(before), ShouldEqual, s.Interval+s.JitterOffset())
+		})
+
 	})
 }
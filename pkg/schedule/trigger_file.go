@@ -0,0 +1,42 @@
+package schedule
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ErrTriggerPathMissing - Error message for a file trigger source without a path
+var ErrTriggerPathMissing = errors.New("File trigger source requires a path")
+
+// fileTrigger fires when the file at its path, or any file in the directory
+// at its path, is written or moved in place
+type fileTrigger struct {
+	path string
+}
+
+func newFileTrigger(c TriggerConfig) (TriggerSource, error) {
+	if c.Path == "" {
+		return nil, ErrTriggerPathMissing
+	}
+	return &fileTrigger{path: filepath.Clean(c.Path)}, nil
+}
+
+func (f *fileTrigger) Config() TriggerConfig {
+	return TriggerConfig{Type: "file", Path: f.path}
+}
+
+func (f *fileTrigger) String() string {
+	return "file:" + f.path
+}
+
+// watched returns the directory watched and the name of the file in it the
+// events are filtered on, empty when the path is a directory. The directory
+// of a file is watched so that files replaced atomically and files which do
+// not exist yet are seen.
+func (f *fileTrigger) watched() (dir string, name string) {
+	if fi, err := os.Stat(f.path); err == nil && fi.IsDir() {
+		return f.path, ""
+	}
+	return filepath.Dir(f.path), filepath.Base(f.path)
+}
@@ -0,0 +1,62 @@
+package schedule
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// Listen watches the path with inotify
+func (f *fileTrigger) Listen(trigger func(), stop <-chan struct{}) error {
+	dir, name := f.watched()
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return err
+	}
+	if _, err := syscall.InotifyAddWatch(fd, dir, syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO); err != nil {
+		syscall.Close(fd)
+		return err
+	}
+	// the descriptor is non-blocking so closing the file interrupts the read
+	file := os.NewFile(uintptr(fd), "inotify:"+dir)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+		case <-done:
+		}
+		file.Close()
+	}()
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := file.Read(buf)
+		if err != nil {
+			select {
+			case <-stop:
+				return nil
+			default:
+				return err
+			}
+		}
+		fired := false
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			start := off + syscall.SizeofInotifyEvent
+			off = start + int(ev.Len)
+			if ev.Mask&syscall.IN_IGNORED != 0 {
+				return errors.New("watch of " + dir + " removed")
+			}
+			if name == "" || strings.TrimRight(string(buf[start:off]), "\x00") == name {
+				fired = true
+			}
+		}
+		// the events read at once fire the schedule once
+		if fired {
+			trigger()
+		}
+	}
+}
@@ -0,0 +1,48 @@
+//go:build !linux
+// +build !linux
+
+package schedule
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// the interval the path is polled at where inotify is not available
+const filePollInterval = time.Second
+
+// Listen polls the modification times of the files at the path
+func (f *fileTrigger) Listen(trigger func(), stop <-chan struct{}) error {
+	last := f.modTimes()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(filePollInterval):
+		}
+		current := f.modTimes()
+		for p, t := range current {
+			if !t.Equal(last[p]) {
+				trigger()
+				break
+			}
+		}
+		last = current
+	}
+}
+
+func (f *fileTrigger) modTimes() map[string]time.Time {
+	dir, name := f.watched()
+	paths := []string{filepath.Join(dir, name)}
+	if name == "" {
+		paths, _ = filepath.Glob(filepath.Join(dir, "*"))
+	}
+	times := map[string]time.Time{}
+	for _, p := range paths {
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() {
+			times[p] = fi.ModTime()
+		}
+	}
+	return times
+}
@@ -0,0 +1,215 @@
+package schedule
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	natsDefaultPort = "4222"
+	natsDialTimeout = 5 * time.Second
+	// the server pings its clients every two minutes by default, so a
+	// connection silent for longer is considered dead
+	natsReadTimeout = 5 * time.Minute
+)
+
+var (
+	// ErrTriggerURLMissing - Error message for a NATS trigger source without a server URL
+	ErrTriggerURLMissing = errors.New("NATS trigger source requires a url")
+	// ErrTriggerSubjectMissing - Error message for a NATS trigger source without a subject
+	ErrTriggerSubjectMissing = errors.New("NATS trigger source requires a subject")
+)
+
+// natsTrigger fires on each message published on its subject. It speaks the
+// text protocol of NATS (https://docs.nats.io/reference/reference-protocols/nats-protocol)
+// which is all a subscriber needs. The connection is upgraded to TLS when
+// the server requires it or the url is tls://, the certificate of the server
+// being verified against the CAs of the system.
+type natsTrigger struct {
+	config  TriggerConfig
+	url     *url.URL
+	subject string
+	// tlsConfig is the TLS configuration of the connections upgraded to TLS,
+	// the default one when nil
+	tlsConfig *tls.Config
+}
+
+// NATSTriggerFactory returns the factory of the "nats" trigger sources
+// upgrading their connections to TLS with the TLS configuration, the default
+// one when nil. snapd registers it with the configuration of its TLS policy.
+func NATSTriggerFactory(tc *tls.Config) TriggerSourceFactory {
+	return func(c TriggerConfig) (TriggerSource, error) {
+		t, err := newNATSTrigger(c)
+		if err != nil {
+			return nil, err
+		}
+		t.tlsConfig = tc
+		return t, nil
+	}
+}
+
+func newNATSTrigger(c TriggerConfig) (*natsTrigger, error) {
+	if c.URL == "" {
+		return nil, ErrTriggerURLMissing
+	}
+	if c.Subject == "" || strings.ContainsAny(c.Subject, " \t\r\n") {
+		return nil, ErrTriggerSubjectMissing
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "nats" && u.Scheme != "tls") || u.Host == "" {
+		return nil, fmt.Errorf("invalid NATS url %q: expected nats://host[:port] or tls://host[:port]", c.URL)
+	}
+	if _, _, err := net.SplitHostPort(u.Host); err != nil {
+		u.Host = net.JoinHostPort(u.Host, natsDefaultPort)
+	}
+	return &natsTrigger{config: c, url: u, subject: c.Subject}, nil
+}
+
+func (n *natsTrigger) Config() TriggerConfig {
+	return n.config
+}
+
+func (n *natsTrigger) String() string {
+	return "nats:" + n.url.Host + "/" + n.subject
+}
+
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+}
+
+type natsConnect struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+}
+
+// Listen subscribes to the subject of the source
+func (n *natsTrigger) Listen(trigger func(), stop <-chan struct{}) error {
+	conn, err := net.DialTimeout("tcp", n.url.Host, natsDialTimeout)
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	err = n.subscribe(conn, trigger)
+	select {
+	case <-stop:
+		return nil
+	default:
+		return err
+	}
+}
+
+func (n *natsTrigger) subscribe(conn net.Conn, trigger func()) error {
+	r := bufio.NewReader(conn)
+	// the server greets with its INFO
+	conn.SetDeadline(time.Now().Add(natsDialTimeout))
+	line, err := readNATSLine(r)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO") {
+		return fmt.Errorf("unexpected NATS greeting %q", line)
+	}
+	var info natsInfo
+	if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "INFO"))), &info); err != nil {
+		return fmt.Errorf("unexpected NATS greeting %q", line)
+	}
+	if info.TLSRequired || n.url.Scheme == "tls" {
+		// the client upgrades the connection once greeted
+		tc := tls.Client(conn, n.clientTLSConfig())
+		if err := tc.Handshake(); err != nil {
+			return err
+		}
+		conn = tc
+		r = bufio.NewReader(conn)
+	}
+	conn.SetDeadline(time.Time{})
+	c := natsConnect{Name: "snapd", Lang: "go"}
+	if n.url.User != nil {
+		c.User = n.url.User.Username()
+		c.Pass, _ = n.url.User.Password()
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nSUB %s 1\r\nPING\r\n", b, n.subject); err != nil {
+		return err
+	}
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(natsReadTimeout))
+		line, err := readNATSLine(r)
+		if err != nil {
+			return err
+		}
+		op := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch op {
+		case "MSG":
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			args := strings.Fields(line)
+			if len(args) < 4 {
+				return fmt.Errorf("invalid NATS message %q", line)
+			}
+			size, err := strconv.Atoi(args[len(args)-1])
+			if err != nil {
+				return fmt.Errorf("invalid NATS message %q", line)
+			}
+			// the payload and its trailing CRLF are discarded
+			if _, err := io.CopyN(ioutil.Discard, r, int64(size)+2); err != nil {
+				return err
+			}
+			trigger()
+		case "PING":
+			if _, err := io.WriteString(conn, "PONG\r\n"); err != nil {
+				return err
+			}
+		case "-ERR":
+			return errors.New("NATS server error: " + strings.TrimSpace(strings.TrimPrefix(line, op)))
+		}
+	}
+}
+
+func (n *natsTrigger) clientTLSConfig() *tls.Config {
+	c := &tls.Config{}
+	if n.tlsConfig != nil {
+		c = n.tlsConfig.Clone()
+	}
+	if c.ServerName == "" {
+		c.ServerName, _, _ = net.SplitHostPort(n.url.Host)
+	}
+	return c
+}
+
+func readNATSLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,158 @@
+package schedule
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	// the delays between the attempts to listen to a failing trigger source
+	triggerBackoffMin = time.Second
+	triggerBackoffMax = time.Minute
+)
+
+// Interruptible is implemented by schedules which wait on events rather
+// than on time and so may never fire: the wait is given up once stop closes
+type Interruptible interface {
+	// Blocks until time to fire or until stop is closed, in which case nil is returned
+	WaitOrStop(last time.Time, stop <-chan struct{}) Response
+}
+
+// Runner is implemented by schedules which listen to event sources while
+// the task they belong to spins
+type Runner interface {
+	// Listens to the event sources of the schedule until stop is closed
+	Run(stop <-chan struct{})
+}
+
+// TriggerSchedule is a schedule which fires when it is triggered, either
+// directly with Trigger (e.g. from the REST API) or by one of its sources.
+// Triggers received while the task runs are coalesced into one and handled
+// by the overrun policy of the task.
+type TriggerSchedule struct {
+	Sources []TriggerSource
+	state   ScheduleState
+
+	mutex     sync.Mutex
+	triggered time.Time
+	wake      chan struct{}
+	clocked
+}
+
+// NewTriggerSchedule returns the TriggerSchedule fired by the given sources
+func NewTriggerSchedule(sources ...TriggerSource) *TriggerSchedule {
+	return &TriggerSchedule{
+		Sources: sources,
+		wake:    make(chan struct{}),
+	}
+}
+
+// GetState returns the schedule state
+func (s *TriggerSchedule) GetState() ScheduleState {
+	return s.state
+}
+
+// Validate returns nil: the sources are validated when they are made
+func (s *TriggerSchedule) Validate() error {
+	return nil
+}
+
+// Trigger fires the schedule
+func (s *TriggerSchedule) Trigger() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.triggered = s.Clock().Now()
+	// wake up every waiter
+	close(s.wake)
+	s.wake = make(chan struct{})
+}
+
+// Wait blocks until the schedule is triggered after last
+func (s *TriggerSchedule) Wait(last time.Time) Response {
+	return s.WaitOrStop(last, nil)
+}
+
+// WaitOrStop blocks until the schedule is triggered after last or until stop
+// is closed, in which case nil is returned
+func (s *TriggerSchedule) WaitOrStop(last time.Time, stop <-chan struct{}) Response {
+	for {
+		s.mutex.Lock()
+		triggered, wake := s.triggered, s.wake
+		s.mutex.Unlock()
+		if triggered.After(last) {
+			return &TriggerScheduleResponse{state: s.GetState(), lastTime: triggered}
+		}
+		select {
+		case <-wake:
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// Run listens to each source of the schedule until stop is closed. A source
+// which fails is listened to again after a delay doubling up to a minute.
+func (s *TriggerSchedule) Run(stop <-chan struct{}) {
+	for _, src := range s.Sources {
+		go s.listen(src, stop)
+	}
+}
+
+func (s *TriggerSchedule) listen(src TriggerSource, stop <-chan struct{}) {
+	backoff := triggerBackoffMin
+	for {
+		started := time.Now()
+		err := src.Listen(s.Trigger, stop)
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		// a source which listened for a while starts over with a short delay
+		if time.Since(started) > triggerBackoffMax {
+			backoff = triggerBackoffMin
+		}
+		logger.WithFields(log.Fields{
+			"_block": "trigger-listen",
+			"source": src.String(),
+			"retry":  backoff.String(),
+			"error":  err,
+		}).Warn("trigger source failed")
+		select {
+		case <-stop:
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > triggerBackoffMax {
+			backoff = triggerBackoffMax
+		}
+	}
+}
+
+// TriggerScheduleResponse a response from TriggerSchedule conforming to ScheduleResponse interface
+type TriggerScheduleResponse struct {
+	state    ScheduleState
+	lastTime time.Time
+}
+
+// State returns the state of the Schedule
+func (s *TriggerScheduleResponse) State() ScheduleState {
+	return s.state
+}
+
+// Error returns last error
+func (s *TriggerScheduleResponse) Error() error {
+	return nil
+}
+
+// Missed returns 0: the triggers received while waiting are coalesced
+func (s *TriggerScheduleResponse) Missed() uint {
+	return 0
+}
+
+// LastTime returns the time the schedule was triggered
+func (s *TriggerScheduleResponse) LastTime() time.Time {
+	return s.lastTime
+}
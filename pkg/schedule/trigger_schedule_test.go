@@ -0,0 +1,212 @@
+package schedule
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTriggerSchedule(t *testing.T) {
+	Convey("Trigger Schedule", t, func() {
+		s := NewTriggerSchedule()
+		So(s.Validate(), ShouldBeNil)
+
+		Convey("waits until it is triggered", func() {
+			last := time.Now()
+			fired := make(chan Response)
+			go func() { fired <- s.Wait(last) }()
+			select {
+			case <-fired:
+				t.Fatal("fired without a trigger")
+			case <-time.After(50 * time.Millisecond):
+			}
+			s.Trigger()
+			r := <-fired
+			So(r.State(), ShouldEqual, Active)
+			So(r.Missed(), ShouldEqual, uint(0))
+			So(r.LastTime().After(last), ShouldBeTrue)
+		})
+		Convey("fires at once when triggered since the last fire", func() {
+			last := time.Now()
+			s.Trigger()
+			s.Trigger()
+			r := s.Wait(last)
+			So(r.LastTime().After(last), ShouldBeTrue)
+		})
+		Convey("gives up waiting when stopped", func() {
+			stop := make(chan struct{})
+			fired := make(chan Response)
+			go func() { fired <- s.WaitOrStop(time.Now(), stop) }()
+			close(stop)
+			So(<-fired, ShouldBeNil)
+		})
+		Convey("is triggered by its sources until stopped", func() {
+			src := &testTrigger{events: make(chan struct{})}
+			s.Sources = []TriggerSource{src}
+			stop := make(chan struct{})
+			defer close(stop)
+			s.Run(stop)
+			last := time.Now()
+			src.events <- struct{}{}
+			So(s.Wait(last).LastTime().After(last), ShouldBeTrue)
+		})
+	})
+}
+
+type testTrigger struct {
+	events chan struct{}
+}
+
+func (s *testTrigger) Listen(trigger func(), stop <-chan struct{}) error {
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-s.events:
+			trigger()
+		}
+	}
+}
+
+func (s *testTrigger) Config() TriggerConfig {
+	return TriggerConfig{Type: "test"}
+}
+
+func (s *testTrigger) String() string {
+	return "test"
+}
+
+func TestTriggerSources(t *testing.T) {
+	Convey("Trigger sources", t, func() {
+		Convey("are made from their configuration", func() {
+			So(TriggerSourceTypes(), ShouldResemble, []string{"file", "nats"})
+			_, err := NewTriggerSource(TriggerConfig{Type: "kafka"})
+			So(err.Error(), ShouldContainSubstring, ErrUnknownTriggerSource.Error())
+			_, err = NewTriggerSource(TriggerConfig{Type: "file"})
+			So(err, ShouldEqual, ErrTriggerPathMissing)
+			_, err = NewTriggerSource(TriggerConfig{Type: "nats", URL: "nats://localhost"})
+			So(err, ShouldEqual, ErrTriggerSubjectMissing)
+			_, err = NewTriggerSource(TriggerConfig{Type: "nats", URL: "http://localhost", Subject: "s"})
+			So(err, ShouldNotBeNil)
+			_, err = NewTriggerSource(TriggerConfig{Type: "nats", URL: "tls://localhost", Subject: "s"})
+			So(err, ShouldBeNil)
+			src, err := NewTriggerSource(TriggerConfig{Type: "nats", URL: "nats://localhost", Subject: "snap.collect"})
+			So(err, ShouldBeNil)
+			So(src.String(), ShouldEqual, "nats:localhost:4222/snap.collect")
+			So(src.Config().URL, ShouldEqual, "nats://localhost")
+		})
+		Convey("a file source fires when the file is written", func() {
+			dir, err := ioutil.TempDir("", "snap-trigger")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "done")
+			src, err := NewTriggerSource(TriggerConfig{Type: "file", Path: path})
+			So(err, ShouldBeNil)
+
+			fired := make(chan struct{}, 10)
+			stop := make(chan struct{})
+			errc := make(chan error)
+			go func() { errc <- src.Listen(func() { fired <- struct{}{} }, stop) }()
+			// writes keep coming until the watch is set up
+			ok := false
+			for i := 0; i < 50 && !ok; i++ {
+				ioutil.WriteFile(filepath.Join(dir, "other"), []byte("x"), 0644)
+				So(ioutil.WriteFile(path, []byte("x"), 0644), ShouldBeNil)
+				select {
+				case <-fired:
+					ok = true
+				case <-time.After(100 * time.Millisecond):
+				}
+			}
+			So(ok, ShouldBeTrue)
+			close(stop)
+			So(<-errc, ShouldBeNil)
+		})
+		Convey("a NATS source fires on the messages of its subject", func() {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			So(err, ShouldBeNil)
+			defer ln.Close()
+			subscribed := make(chan string, 1)
+			go serveNATS(ln, nil, subscribed)
+			src, err := NewTriggerSource(TriggerConfig{Type: "nats", URL: "nats://" + ln.Addr().String(), Subject: "snap.collect"})
+			So(err, ShouldBeNil)
+
+			fired := make(chan struct{}, 1)
+			stop := make(chan struct{})
+			errc := make(chan error)
+			go func() { errc <- src.Listen(func() { fired <- struct{}{} }, stop) }()
+			So(<-subscribed, ShouldEqual, "SUB snap.collect 1")
+			select {
+			case <-fired:
+			case <-time.After(time.Second):
+				t.Fatal("not fired")
+			}
+			close(stop)
+			So(<-errc, ShouldBeNil)
+		})
+		Convey("a NATS source upgrades to TLS when the server requires it", func() {
+			ts := httptest.NewTLSServer(nil)
+			ts.Close()
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			So(err, ShouldBeNil)
+			defer ln.Close()
+			subscribed := make(chan string, 1)
+			go serveNATS(ln, &tls.Config{Certificates: ts.TLS.Certificates}, subscribed)
+			src, err := NewTriggerSource(TriggerConfig{Type: "nats", URL: "nats://" + ln.Addr().String(), Subject: "snap.collect"})
+			So(err, ShouldBeNil)
+			roots := x509.NewCertPool()
+			roots.AddCert(ts.Certificate())
+			src.(*natsTrigger).tlsConfig = &tls.Config{RootCAs: roots}
+
+			fired := make(chan struct{}, 1)
+			stop := make(chan struct{})
+			errc := make(chan error)
+			go func() { errc <- src.Listen(func() { fired <- struct{}{} }, stop) }()
+			So(<-subscribed, ShouldEqual, "SUB snap.collect 1")
+			select {
+			case <-fired:
+			case <-time.After(time.Second):
+				t.Fatal("not fired")
+			}
+			close(stop)
+			So(<-errc, ShouldBeNil)
+		})
+	})
+}
+
+// serveNATS greets a NATS client, over TLS when tc is not nil, and publishes
+// a message once it subscribed
+func serveNATS(ln net.Listener, tc *tls.Config, subscribed chan<- string) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	if tc == nil {
+		conn.Write([]byte("INFO {\"server_id\":\"test\"}\r\n"))
+	} else {
+		conn.Write([]byte("INFO {\"server_id\":\"test\",\"tls_required\":true}\r\n"))
+		conn = tls.Server(conn, tc)
+	}
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if strings.HasPrefix(line, "SUB ") {
+			subscribed <- strings.TrimSpace(line)
+			conn.Write([]byte("PING\r\nMSG snap.collect 1 5\r\nhello\r\n"))
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrUnknownTriggerSource - Error message for a trigger source of a type which is not registered
+	ErrUnknownTriggerSource = errors.New("Unknown trigger source type")
+
+	triggerSourcesMutex sync.RWMutex
+	triggerSources      = map[string]TriggerSourceFactory{}
+)
+
+// TriggerSource is a source of events firing a TriggerSchedule
+type TriggerSource interface {
+	// Calls trigger on each event until stop is closed, in which case nil is
+	// returned, or until the source fails
+	Listen(trigger func(), stop <-chan struct{}) error
+	// Returns the configuration the source was made from
+	Config() TriggerConfig
+	// Describes the source, e.g. "file:/var/run/app.done"
+	String() string
+}
+
+// TriggerConfig is the configuration of a trigger source. The fields used
+// depend on the type of the source.
+type TriggerConfig struct {
+	Type string
+	// the file or directory watched by a "file" source
+	Path string
+	// the server a "nats" source subscribes on
+	URL string
+	// the subject a "nats" source subscribes to
+	Subject string
+}
+
+// TriggerSourceFactory makes a trigger source given its configuration
+type TriggerSourceFactory func(TriggerConfig) (TriggerSource, error)
+
+// RegisterTriggerSource registers the factory of the trigger sources of the
+// given type. The "file" and "nats" types are registered by default.
+func RegisterTriggerSource(typ string, f TriggerSourceFactory) {
+	triggerSourcesMutex.Lock()
+	defer triggerSourcesMutex.Unlock()
+	triggerSources[typ] = f
+}
+
+// TriggerSourceTypes returns the registered types of trigger sources
+func TriggerSourceTypes() []string {
+	triggerSourcesMutex.RLock()
+	defer triggerSourcesMutex.RUnlock()
+	types := make([]string, 0, len(triggerSources))
+	for typ := range triggerSources {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// NewTriggerSource returns the validated trigger source of the given configuration
+func NewTriggerSource(c TriggerConfig) (TriggerSource, error) {
+	triggerSourcesMutex.RLock()
+	f, ok := triggerSources[c.Type]
+	triggerSourcesMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%s: %q", ErrUnknownTriggerSource, c.Type)
+	}
+	return f(c)
+}
+
+func init() {
+	RegisterTriggerSource("file", newFileTrigger)
+	RegisterTriggerSource("nats", NATSTriggerFactory(nil))
+}
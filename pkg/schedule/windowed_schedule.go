@@ -16,6 +16,7 @@ type WindowedSchedule struct {
 	StartTime *time.Time
 	StopTime  *time.Time
 	state     ScheduleState
+	clocked
 }
 
 // NewWindowedSchedule returns an instance of WindowedSchedule given duration,
@@ -36,7 +37,7 @@ func (w *WindowedSchedule) GetState() ScheduleState {
 // Validate validates the start, stop and duration interval of
 // WindowedSchedule
 func (w *WindowedSchedule) Validate() error {
-	if w.StopTime != nil && time.Now().After(*w.StopTime) {
+	if w.StopTime != nil && w.Clock().Now().After(*w.StopTime) {
 		return ErrInvalidStopTime
 	}
 	if w.StopTime != nil && w.StartTime != nil && w.StopTime.Before(*w.StartTime) {
@@ -54,13 +55,13 @@ func (w *WindowedSchedule) Wait(last time.Time) Response {
 	// Do we even have a specific start time?
 	if w.StartTime != nil {
 		// Wait till it is time to start if before the window start
-		if time.Now().Before(*w.StartTime) {
-			wait := w.StartTime.Sub(time.Now())
+		if w.Clock().Now().Before(*w.StartTime) {
+			wait := w.StartTime.Sub(w.Clock().Now())
 			logger.WithFields(log.Fields{
 				"_block":         "windowed-wait",
 				"sleep-duration": wait,
 			}).Debug("Waiting for window to start")
-			time.Sleep(wait)
+			w.Clock().Sleep(wait)
 		}
 		if (last == time.Time{}) {
 			logger.WithFields(log.Fields{
@@ -73,7 +74,7 @@ func (w *WindowedSchedule) Wait(last time.Time) Response {
 			logger.WithFields(log.Fields{
 				"_block": "windowed-wait",
 			}).Debug("Last was unset using start time")
-			last = time.Now()
+			last = w.Clock().Now()
 		}
 	}
 
@@ -82,17 +83,17 @@ func (w *WindowedSchedule) Wait(last time.Time) Response {
 	var m uint
 	// Do we even have a stop time?
 	if w.StopTime != nil {
-		if time.Now().Before(*w.StopTime) {
+		if w.Clock().Now().Before(*w.StopTime) {
 			logger.WithFields(log.Fields{
 				"_block":           "windowed-wait",
-				"time-before-stop": w.StopTime.Sub(time.Now()),
+				"time-before-stop": w.StopTime.Sub(w.Clock().Now()),
 			}).Debug("Within window, calling interval")
 			logger.WithFields(log.Fields{
 				"_block":   "windowed-wait",
 				"last":     last,
 				"interval": w.Interval,
 			}).Debug("waiting for interval")
-			m, _ = waitOnInterval(last, w.Interval)
+			m, _ = waitOnInterval(w.Clock(), last, w.Interval)
 		} else {
 			w.state = Ended
 			m = 0
@@ -104,13 +105,13 @@ func (w *WindowedSchedule) Wait(last time.Time) Response {
 			"interval": w.Interval,
 		}).Debug("waiting for interval")
 		// This has no end like a simple schedule
-		m, _ = waitOnInterval(last, w.Interval)
+		m, _ = waitOnInterval(w.Clock(), last, w.Interval)
 
 	}
 	return &WindowedScheduleResponse{
 		state:    w.GetState(),
 		missed:   m,
-		lastTime: time.Now(),
+		lastTime: w.Clock().Now(),
 	}
 }
 
@@ -0,0 +1,181 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tlspolicy restricts the TLS versions, cipher suites and curves
+// negotiated by the listeners and clients of snapd, for the deployments with
+// compliance requirements.
+package tlspolicy
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sort"
+)
+
+// Config holds the TLS versions and cipher suites allowed. The zero value
+// leaves the defaults of Go.
+type Config struct {
+	// MinVersion and MaxVersion bound the TLS versions, "1.0", "1.1", "1.2"
+	// or "1.3"
+	MinVersion string `json:"min_version,omitempty"yaml:"min_version,omitempty"`
+	MaxVersion string `json:"max_version,omitempty"yaml:"max_version,omitempty"`
+	// CipherSuites are the names of the cipher suites allowed, e.g.
+	// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, in order of preference. They
+	// only restrict TLS 1.2 and below, as Go does not let the cipher suites
+	// of TLS 1.3 be restricted.
+	CipherSuites []string `json:"cipher_suites,omitempty"yaml:"cipher_suites,omitempty"`
+	// FIPS restricts TLS to version 1.2, the AES cipher suites and the NIST
+	// curves, as approved by FIPS 140-2. TLS 1.3 is only allowed when it is
+	// the MaxVersion, as its cipher suites include ChaCha20.
+	FIPS bool `json:"fips,omitempty"yaml:"fips,omitempty"`
+}
+
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// the cipher suites which may be allowed, by name, RC4 and 3DES ones left out
+var cipherSuites = map[string]uint16{
+	"TLS_RSA_WITH_AES_128_CBC_SHA":            tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_RSA_WITH_AES_256_CBC_SHA":            tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_RSA_WITH_AES_128_CBC_SHA256":         tls.TLS_RSA_WITH_AES_128_CBC_SHA256,
+	"TLS_RSA_WITH_AES_128_GCM_SHA256":         tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_RSA_WITH_AES_256_GCM_SHA384":         tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA":    tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA":    tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA":      tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA":      tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256": tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256":   tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256":   tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256": tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384":   tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384": tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305":    tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305":  tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+}
+
+// the cipher suites not approved by FIPS 140-2
+var nonFIPSCipherSuites = map[uint16]bool{
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305:   true,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305: true,
+}
+
+// the cipher suites allowed in FIPS mode when none is given
+var fipsCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+}
+
+var fipsCurves = []tls.CurveID{tls.CurveP256, tls.CurveP384, tls.CurveP521}
+
+// CipherSuites returns the names of the cipher suites which may be allowed
+func CipherSuites() []string {
+	names := make([]string, 0, len(cipherSuites))
+	for name := range cipherSuites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate returns the problems found in the configuration
+func (c *Config) Validate() []error {
+	if c == nil {
+		return nil
+	}
+	var errs []error
+	min, okMin := versions[c.MinVersion]
+	if c.MinVersion != "" && !okMin {
+		errs = append(errs, fmt.Errorf("tls.min_version: %q is not one of 1.0, 1.1, 1.2, 1.3", c.MinVersion))
+	}
+	max, okMax := versions[c.MaxVersion]
+	if c.MaxVersion != "" && !okMax {
+		errs = append(errs, fmt.Errorf("tls.max_version: %q is not one of 1.0, 1.1, 1.2, 1.3", c.MaxVersion))
+	}
+	if okMin && okMax && min > max {
+		errs = append(errs, fmt.Errorf("tls: min_version %s is above max_version %s", c.MinVersion, c.MaxVersion))
+	}
+	if c.FIPS && okMax && max < tls.VersionTLS12 {
+		errs = append(errs, fmt.Errorf("tls.max_version: must be 1.2 or 1.3 with fips"))
+	}
+	for _, name := range c.CipherSuites {
+		id, ok := cipherSuites[name]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("tls.cipher_suites: unknown cipher suite %q", name))
+		case c.FIPS && nonFIPSCipherSuites[id]:
+			errs = append(errs, fmt.Errorf("tls.cipher_suites: %s is not approved by FIPS 140-2", name))
+		}
+	}
+	return errs
+}
+
+// Apply restricts the TLS configuration to the versions, cipher suites and
+// curves allowed. A nil policy leaves the configuration as it is, as do the
+// unset fields of the policy. The policy is expected to be valid.
+func (c *Config) Apply(t *tls.Config) {
+	if c == nil {
+		return
+	}
+	if v, ok := versions[c.MinVersion]; ok {
+		t.MinVersion = v
+	}
+	if v, ok := versions[c.MaxVersion]; ok {
+		t.MaxVersion = v
+	}
+	if len(c.CipherSuites) > 0 {
+		t.CipherSuites = make([]uint16, 0, len(c.CipherSuites))
+		for _, name := range c.CipherSuites {
+			if id, ok := cipherSuites[name]; ok {
+				t.CipherSuites = append(t.CipherSuites, id)
+			}
+		}
+		t.PreferServerCipherSuites = true
+	}
+	if !c.FIPS {
+		return
+	}
+	if t.MinVersion < tls.VersionTLS12 {
+		t.MinVersion = tls.VersionTLS12
+	}
+	// the cipher suites of TLS 1.3 cannot be restricted, so it is left out
+	// unless the max version asks for it
+	if c.MaxVersion != "1.3" {
+		t.MaxVersion = tls.VersionTLS12
+	}
+	if len(t.CipherSuites) == 0 {
+		t.CipherSuites = fipsCipherSuites
+		t.PreferServerCipherSuites = true
+	}
+	t.CurvePreferences = fipsCurves
+}
+
+// TLSConfig returns a new TLS configuration restricted by the policy, e.g.
+// for a client
+func (c *Config) TLSConfig() *tls.Config {
+	t := &tls.Config{}
+	c.Apply(t)
+	return t
+}
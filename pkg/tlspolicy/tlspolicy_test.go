@@ -0,0 +1,100 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tlspolicy
+
+import (
+	"crypto/tls"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValidate(t *testing.T) {
+	Convey("A policy", t, func() {
+		Convey("is valid when empty or nil", func() {
+			So((&Config{}).Validate(), ShouldBeEmpty)
+			So((*Config)(nil).Validate(), ShouldBeEmpty)
+		})
+		Convey("is valid with known versions and cipher suites", func() {
+			c := &Config{MinVersion: "1.1", MaxVersion: "1.2", CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}}
+			So(c.Validate(), ShouldBeEmpty)
+		})
+		Convey("is invalid with an unknown version", func() {
+			So((&Config{MinVersion: "1.4"}).Validate(), ShouldHaveLength, 1)
+			So((&Config{MinVersion: "1.3"}).Validate(), ShouldBeEmpty)
+			So((&Config{MaxVersion: "ssl3"}).Validate(), ShouldHaveLength, 1)
+		})
+		Convey("is invalid with a min version above the max version", func() {
+			So((&Config{MinVersion: "1.2", MaxVersion: "1.1"}).Validate(), ShouldHaveLength, 1)
+		})
+		Convey("is invalid with an unknown cipher suite", func() {
+			So((&Config{CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}}).Validate(), ShouldHaveLength, 1)
+		})
+		Convey("in FIPS mode", func() {
+			Convey("is invalid with a cipher suite which is not approved", func() {
+				c := &Config{FIPS: true, CipherSuites: []string{"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305"}}
+				So(c.Validate(), ShouldHaveLength, 1)
+			})
+			Convey("is invalid with a max version below 1.2", func() {
+				So((&Config{FIPS: true, MaxVersion: "1.1"}).Validate(), ShouldHaveLength, 1)
+			})
+		})
+	})
+}
+
+func TestApply(t *testing.T) {
+	Convey("Applying a policy", t, func() {
+		Convey("leaves the configuration as it is when nil or empty", func() {
+			tc := &tls.Config{MinVersion: tls.VersionTLS12}
+			(*Config)(nil).Apply(tc)
+			(&Config{}).Apply(tc)
+			So(tc, ShouldResemble, &tls.Config{MinVersion: tls.VersionTLS12})
+		})
+		Convey("sets the versions and the cipher suites in order", func() {
+			tc := (&Config{
+				MinVersion:   "1.1",
+				MaxVersion:   "1.2",
+				CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+			}).TLSConfig()
+			So(tc.MinVersion, ShouldEqual, tls.VersionTLS11)
+			So(tc.MaxVersion, ShouldEqual, tls.VersionTLS12)
+			So(tc.CipherSuites, ShouldResemble, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256})
+			So(tc.PreferServerCipherSuites, ShouldBeTrue)
+		})
+		Convey("in FIPS mode restricts to TLS 1.2, the approved cipher suites and the NIST curves", func() {
+			tc := (&Config{FIPS: true, MinVersion: "1.0"}).TLSConfig()
+			So(tc.MinVersion, ShouldEqual, tls.VersionTLS12)
+			So(tc.MaxVersion, ShouldEqual, tls.VersionTLS12)
+			So(tc.CipherSuites, ShouldResemble, fipsCipherSuites)
+			So(tc.CurvePreferences, ShouldResemble, fipsCurves)
+		})
+		Convey("in FIPS mode allows TLS 1.3 when it is the max version", func() {
+			tc := (&Config{FIPS: true, MaxVersion: "1.3"}).TLSConfig()
+			So(tc.MinVersion, ShouldEqual, tls.VersionTLS12)
+			So(tc.MaxVersion, ShouldEqual, tls.VersionTLS13)
+			tc = (&Config{FIPS: true, MinVersion: "1.3", MaxVersion: "1.3"}).TLSConfig()
+			So(tc.MinVersion, ShouldEqual, tls.VersionTLS13)
+		})
+		Convey("in FIPS mode keeps the cipher suites given", func() {
+			tc := (&Config{FIPS: true, CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}}).TLSConfig()
+			So(tc.CipherSuites, ShouldResemble, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256})
+		})
+	})
+}
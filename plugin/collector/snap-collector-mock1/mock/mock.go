@@ -22,13 +22,13 @@ package mock
 import (
 	"fmt"
 	"math/rand"
-	"os"
 	"time"
 
 	"github.com/intelsdi-x/snap/control/plugin"
 	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/plugin/helper/fixture"
 )
 
 const (
@@ -49,18 +49,23 @@ type Mock struct {
 
 // CollectMetrics collects metrics for testing
 func (f *Mock) CollectMetrics(mts []plugin.PluginMetricType) ([]plugin.PluginMetricType, error) {
+	fb, err := fixture.FromMetrics(mts)
+	if err != nil {
+		return nil, err
+	}
+	if err := fb.Apply(); err != nil {
+		return nil, err
+	}
 	metrics := []plugin.PluginMetricType{}
 	rand.Seed(time.Now().UTC().UnixNano())
-	hostname, _ := os.Hostname()
 	for i, p := range mts {
 		if mts[i].Namespace()[2] == "*" {
-			for j := 0; j < 10; j++ {
+			for j := 0; j < fb.MetricCountOr(10); j++ {
 				v := fmt.Sprintf("host%d", j)
-				data := randInt(65, 90)
+				data := fb.Payload(randInt(65, 90))
 				mt := plugin.PluginMetricType{
 					Data_:      data,
 					Namespace_: []string{"intel", "mock", v, "baz"},
-					Source_:    hostname,
 					Timestamp_: time.Now(),
 					Labels_:    mts[i].Labels(),
 					Version_:   mts[i].Version(),
@@ -73,8 +78,8 @@ func (f *Mock) CollectMetrics(mts []plugin.PluginMetricType) ([]plugin.PluginMet
 			} else {
 				p.Data_ = fmt.Sprintf("The mock collected data! config data: user=%s password=%s", p.Config().Table()["user"], p.Config().Table()["password"])
 			}
+			p.Data_ = fb.Payload(p.Data_)
 			p.Timestamp_ = time.Now()
-			p.Source_ = hostname
 			metrics = append(metrics, p)
 		}
 	}
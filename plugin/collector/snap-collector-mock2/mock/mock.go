@@ -23,13 +23,13 @@ import (
 	"fmt"
 	"log"
 	"math/rand"
-	"os"
 	"time"
 
 	"github.com/intelsdi-x/snap/control/plugin"
 	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/plugin/helper/fixture"
 )
 
 const (
@@ -50,6 +50,13 @@ func (f *Mock) CollectMetrics(mts []plugin.PluginMetricType) ([]plugin.PluginMet
 	for _, p := range mts {
 		log.Printf("collecting %+v\n", p)
 	}
+	fb, err := fixture.FromMetrics(mts)
+	if err != nil {
+		return nil, err
+	}
+	if err := fb.Apply(); err != nil {
+		return nil, err
+	}
 
 	rand.Seed(time.Now().UTC().UnixNano())
 	metrics := []plugin.PluginMetricType{}
@@ -58,14 +65,12 @@ func (f *Mock) CollectMetrics(mts []plugin.PluginMetricType) ([]plugin.PluginMet
 			panic("Opps!")
 		}
 		if mts[i].Namespace()[2] == "*" {
-			hostname, _ := os.Hostname()
-			for j := 0; j < 10; j++ {
+			for j := 0; j < fb.MetricCountOr(10); j++ {
 				v := fmt.Sprintf("host%d", j)
-				data := randInt(65, 90)
+				data := fb.Payload(randInt(65, 90))
 				mt := plugin.PluginMetricType{
 					Data_:      data,
 					Namespace_: []string{"intel", "mock", v, "baz"},
-					Source_:    hostname,
 					Timestamp_: time.Now(),
 					Labels_:    mts[i].Labels(),
 					Version_:   mts[i].Version(),
@@ -73,9 +78,8 @@ func (f *Mock) CollectMetrics(mts []plugin.PluginMetricType) ([]plugin.PluginMet
 				metrics = append(metrics, mt)
 			}
 		} else {
-			data := randInt(65, 90)
+			data := fb.Payload(randInt(65, 90))
 			mts[i].Data_ = data
-			mts[i].Source_, _ = os.Hostname()
 			mts[i].Timestamp_ = time.Now()
 			metrics = append(metrics, mts[i])
 		}
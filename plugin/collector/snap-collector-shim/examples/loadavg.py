@@ -0,0 +1,30 @@
+#!/usr/bin/env python
+# http://www.apache.org/licenses/LICENSE-2.0.txt
+#
+# Copyright 2016 Intel Corporation
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Example collector for snap-collector-shim reporting the load average.
+import json
+import os
+import sys
+
+req = json.load(sys.stdin)
+loads = dict(zip(["1min", "5min", "15min"], os.getloadavg()))
+
+out = []
+for m in req["metrics"]:
+    scale = (m.get("config") or {}).get("scale", 1.0)
+    out.append({"namespace": m["namespace"], "data": loads[m["namespace"][-1]] * scale})
+json.dump(out, sys.stdout)
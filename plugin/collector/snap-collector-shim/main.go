@@ -0,0 +1,41 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+
+	// Import the snap plugin library
+	"github.com/intelsdi-x/snap/control/plugin"
+)
+
+// The shim runs the script described by a descriptor file found at
+// $SNAP_SHIM_DESCRIPTOR or next to this binary as <binary>.shim.json.
+// Copy or link the binary once per script, e.g. snap-collector-loadavg with
+// snap-collector-loadavg.shim.json, to load several scripts side by side.
+func main() {
+	d, err := plugin.ReadShimDescriptor(plugin.FindShimDescriptor())
+	if err != nil {
+		// stdout is reserved for the plugin response
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	plugin.Start(d.Meta(), plugin.NewShimCollector(d), os.Args[1])
+}
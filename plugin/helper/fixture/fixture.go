@@ -0,0 +1,168 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fixture holds the configurable behaviors of the mock plugins, which
+// make them usable as fixtures to load test a snapd deployment. The behaviors
+// are only applied when the config of the plugin sets test_fixture to true.
+package fixture
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+// Config keys of the behaviors
+const (
+	// FlagKey enables the behaviors
+	FlagKey = "test_fixture"
+	// LatencyKey delays each call to the plugin (e.g. "250ms")
+	LatencyKey = "fixture_latency"
+	// ErrorRateKey makes this share of the calls fail (0 to 1)
+	ErrorRateKey = "fixture_error_rate"
+	// PayloadSizeKey sets the size in bytes of the data of each metric collected
+	PayloadSizeKey = "fixture_payload_size"
+	// MetricCountKey sets the number of metrics a dynamic metric expands to
+	MetricCountKey = "fixture_metric_count"
+)
+
+// ErrInjected is returned by the calls failed by the error rate
+var ErrInjected = errors.New("fixture: injected error")
+
+var (
+	randMutex sync.Mutex
+	random    = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// Behavior holds the behaviors configured for a plugin
+type Behavior struct {
+	Latency     time.Duration
+	ErrorRate   float64
+	PayloadSize int
+	MetricCount int
+}
+
+// FromConfig returns the behaviors set in the config of a plugin, or nil when
+// the config does not enable them
+func FromConfig(cfg map[string]ctypes.ConfigValue) (*Behavior, error) {
+	if v, ok := cfg[FlagKey].(ctypes.ConfigValueBool); !ok || !v.Value {
+		return nil, nil
+	}
+	b := &Behavior{}
+	if v, ok := cfg[LatencyKey]; ok {
+		s, ok := v.(ctypes.ConfigValueStr)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a duration", LatencyKey)
+		}
+		d, err := time.ParseDuration(s.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", LatencyKey, err)
+		}
+		b.Latency = d
+	}
+	if v, ok := cfg[ErrorRateKey]; ok {
+		switch r := v.(type) {
+		case ctypes.ConfigValueFloat:
+			b.ErrorRate = r.Value
+		case ctypes.ConfigValueInt:
+			b.ErrorRate = float64(r.Value)
+		}
+		if b.ErrorRate < 0 || b.ErrorRate > 1 {
+			return nil, fmt.Errorf("%s must be a number from 0 to 1", ErrorRateKey)
+		}
+	}
+	var err error
+	if b.PayloadSize, err = intValue(cfg, PayloadSizeKey); err != nil {
+		return nil, err
+	}
+	if b.MetricCount, err = intValue(cfg, MetricCountKey); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// FromMetrics returns the behaviors set in the config of the metrics requested
+// from a collector
+func FromMetrics(mts []plugin.PluginMetricType) (*Behavior, error) {
+	if len(mts) == 0 || mts[0].Config() == nil {
+		return nil, nil
+	}
+	return FromConfig(mts[0].Config().Table())
+}
+
+func intValue(cfg map[string]ctypes.ConfigValue, key string) (int, error) {
+	v, ok := cfg[key]
+	if !ok {
+		return 0, nil
+	}
+	i, ok := v.(ctypes.ConfigValueInt)
+	if !ok || i.Value < 0 {
+		return 0, fmt.Errorf("%s must be a positive integer", key)
+	}
+	return i.Value, nil
+}
+
+// Apply waits for the latency and returns ErrInjected for the share of calls
+// set by the error rate. It does nothing for a nil Behavior.
+func (b *Behavior) Apply() error {
+	if b == nil {
+		return nil
+	}
+	if b.Latency > 0 {
+		time.Sleep(b.Latency)
+	}
+	if b.ErrorRate > 0 {
+		randMutex.Lock()
+		f := random.Float64()
+		randMutex.Unlock()
+		if f < b.ErrorRate {
+			return ErrInjected
+		}
+	}
+	return nil
+}
+
+// MetricCountOr returns the number of metrics a dynamic metric expands to,
+// or def when it is not set
+func (b *Behavior) MetricCountOr(def int) int {
+	if b == nil || b.MetricCount == 0 {
+		return def
+	}
+	return b.MetricCount
+}
+
+// Payload returns the data of a metric of the payload size, or data as is
+// when the payload size is not set
+func (b *Behavior) Payload(data interface{}) interface{} {
+	if b == nil || b.PayloadSize == 0 {
+		return data
+	}
+	p := make([]byte, b.PayloadSize)
+	randMutex.Lock()
+	for i := range p {
+		p[i] = byte('a' + random.Intn(26))
+	}
+	randMutex.Unlock()
+	return string(p)
+}
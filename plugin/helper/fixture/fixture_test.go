@@ -0,0 +1,88 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fixture
+
+import (
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/core/ctypes"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFromConfig(t *testing.T) {
+	Convey("FromConfig", t, func() {
+		Convey("returns nil unless the fixture is enabled", func() {
+			b, err := FromConfig(map[string]ctypes.ConfigValue{
+				LatencyKey: ctypes.ConfigValueStr{Value: "1s"},
+			})
+			So(err, ShouldBeNil)
+			So(b, ShouldBeNil)
+			So(b.Apply(), ShouldBeNil)
+			So(b.MetricCountOr(10), ShouldEqual, 10)
+			So(b.Payload(1), ShouldEqual, 1)
+		})
+		Convey("reads the behaviors", func() {
+			b, err := FromConfig(map[string]ctypes.ConfigValue{
+				FlagKey:        ctypes.ConfigValueBool{Value: true},
+				LatencyKey:     ctypes.ConfigValueStr{Value: "10ms"},
+				ErrorRateKey:   ctypes.ConfigValueFloat{Value: 0.5},
+				PayloadSizeKey: ctypes.ConfigValueInt{Value: 64},
+				MetricCountKey: ctypes.ConfigValueInt{Value: 100},
+			})
+			So(err, ShouldBeNil)
+			So(b, ShouldResemble, &Behavior{
+				Latency:     10 * time.Millisecond,
+				ErrorRate:   0.5,
+				PayloadSize: 64,
+				MetricCount: 100,
+			})
+			So(b.MetricCountOr(10), ShouldEqual, 100)
+			So(b.Payload(1), ShouldHaveLength, 64)
+		})
+		Convey("reports invalid behaviors", func() {
+			for k, v := range map[string]ctypes.ConfigValue{
+				LatencyKey:     ctypes.ConfigValueStr{Value: "soon"},
+				ErrorRateKey:   ctypes.ConfigValueInt{Value: 2},
+				PayloadSizeKey: ctypes.ConfigValueStr{Value: "big"},
+				MetricCountKey: ctypes.ConfigValueInt{Value: -1},
+			} {
+				_, err := FromConfig(map[string]ctypes.ConfigValue{
+					FlagKey: ctypes.ConfigValueBool{Value: true},
+					k:       v,
+				})
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+
+	Convey("Apply", t, func() {
+		Convey("waits for the latency", func() {
+			b := &Behavior{Latency: 20 * time.Millisecond}
+			start := time.Now()
+			So(b.Apply(), ShouldBeNil)
+			So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 20*time.Millisecond)
+		})
+		Convey("fails every call with an error rate of 1", func() {
+			b := &Behavior{ErrorRate: 1}
+			So(b.Apply(), ShouldEqual, ErrInjected)
+		})
+	})
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/intelsdi-x/snap/control/plugin"
 	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
 	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/plugin/helper/fixture"
 )
 
 const (
@@ -56,6 +57,14 @@ func (p *passthruProcessor) Process(contentType string, content []byte, config m
 	logger := log.New()
 	logger.Println("Processor started")
 
+	fb, err := fixture.FromConfig(config)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := fb.Apply(); err != nil {
+		return "", nil, err
+	}
+
 	// The following block is for testing config see.. control_test.go
 	if _, ok := config["test"]; ok {
 		logger.Print("test configuration found")
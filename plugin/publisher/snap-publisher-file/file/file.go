@@ -32,6 +32,7 @@ import (
 	"github.com/intelsdi-x/snap/control/plugin"
 	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
 	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/plugin/helper/fixture"
 )
 
 const (
@@ -50,6 +51,14 @@ func NewFilePublisher() *filePublisher {
 func (f *filePublisher) Publish(contentType string, content []byte, config map[string]ctypes.ConfigValue) error {
 	logger := log.New()
 	logger.Println("Publishing started")
+
+	fb, err := fixture.FromConfig(config)
+	if err != nil {
+		return err
+	}
+	if err := fb.Apply(); err != nil {
+		return err
+	}
 	var metrics []plugin.PluginMetricType
 
 	switch contentType {
@@ -0,0 +1,481 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+)
+
+// aggregatorSubscriber subscribes the aggregator to the plugins of its
+// publish nodes, and is the task ID they are called with. Like the
+// subscribers of the processors run for other snapd, it has the "remote/"
+// prefix of control, so that it does not keep the plugins from being
+// unloaded as a task would.
+const aggregatorSubscriber = "remote/aggregator"
+
+// the functions the series of the metrics forwarded to the aggregator are
+// downsampled with
+const (
+	DownsampleLast = "last"
+	DownsampleAvg  = "avg"
+	DownsampleMin  = "min"
+	DownsampleMax  = "max"
+	DownsampleSum  = "sum"
+)
+
+var downsampleFuncs = []string{DownsampleLast, DownsampleAvg, DownsampleMin, DownsampleMax, DownsampleSum}
+
+var (
+	// ErrAggregatorDisabled - The error message for metrics forwarded to a snapd without the aggregator role
+	ErrAggregatorDisabled = errors.New("The aggregator role is not enabled.")
+	// ErrAggregatorFull - The error message for metrics forwarded while the buffer of the aggregator is full
+	ErrAggregatorFull = errors.New("The buffer of the aggregator is full.")
+)
+
+// validDownsample returns whether f is one of the downsampling functions,
+// or empty for none
+func validDownsample(f string) bool {
+	if f == "" {
+		return true
+	}
+	for _, df := range downsampleFuncs {
+		if f == df {
+			return true
+		}
+	}
+	return false
+}
+
+// aggregator is the aggregator role of snapd: the tasks of the other members
+// of the tribe forward their metrics to it with the builtin/aggregator
+// publisher, and it publishes them with its own publish nodes once per
+// interval, deduplicated and downsampled as configured. This gives a
+// two-tier topology, the edge members collecting and the aggregator holding
+// the credentials of and the connections to the backends.
+type aggregator struct {
+	config        *AggregatorConfig
+	metricManager managesMetrics
+	publishNodes  []*publishNode
+
+	mutex   sync.Mutex
+	state   core.Aggregator
+	sources map[string]*core.AggregatorSource
+	metrics []core.Metric
+	// seen and seenBefore hold the metrics forwarded during the current and
+	// the previous windows, when deduplicating
+	seen       map[string]struct{}
+	seenBefore map[string]struct{}
+
+	// flushMutex serializes the flushes, and guards subscribed
+	flushMutex sync.Mutex
+	subscribed bool
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// newAggregator returns the aggregator publishing with the publish nodes of
+// the config
+func newAggregator(cfg *AggregatorConfig) (*aggregator, error) {
+	nodes, err := convertPublishNode(cfg.Publish)
+	if err != nil {
+		return nil, err
+	}
+	return &aggregator{
+		config:       cfg,
+		publishNodes: nodes,
+		state: core.Aggregator{
+			Enabled:    true,
+			Interval:   cfg.Interval.Duration,
+			Dedup:      cfg.Dedup,
+			Downsample: cfg.Downsample,
+		},
+		sources:    map[string]*core.AggregatorSource{},
+		seen:       map[string]struct{}{},
+		seenBefore: map[string]struct{}{},
+	}, nil
+}
+
+// start flushes the metrics forwarded once per interval until stopped
+func (a *aggregator) start(mm managesMetrics) {
+	a.metricManager = mm
+	a.stop = make(chan struct{})
+	a.done = make(chan struct{})
+	go func() {
+		defer close(a.done)
+		ticker := time.NewTicker(a.config.Interval.Duration)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				a.flush()
+			case <-a.stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopFlushing stops the flushes, publishing the metrics buffered a last
+// time and unsubscribing from the plugins of the publish nodes
+func (a *aggregator) stopFlushing() {
+	if a.stop == nil {
+		return
+	}
+	close(a.stop)
+	<-a.done
+	a.stop = nil
+	a.flush()
+	a.flushMutex.Lock()
+	defer a.flushMutex.Unlock()
+	a.unsubscribe()
+	closePublishers(a.publishNodes, schedulerLogger.WithFields(log.Fields{
+		"_block": "stop-aggregator",
+	}))
+}
+
+// ingest buffers the metrics of the content forwarded by the task of another
+// member, the source being the principal it authenticated as, until the next
+// flush, and returns their number. The whole content is refused when it
+// does not fit in the buffer.
+func (a *aggregator) ingest(source, taskID, contentType string, content []byte) (int, error) {
+	metrics, err := core.DecodeMetrics(contentType, content)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	src, ok := a.sources[source]
+	if !ok {
+		src = &core.AggregatorSource{Principal: source}
+		a.sources[source] = src
+	}
+	src.LastSeen = now
+	if max := a.config.MaxBuffered; max > 0 && len(a.metrics)+len(metrics) > max {
+		a.state.Dropped += uint64(len(metrics))
+		return 0, ErrAggregatorFull
+	}
+	var duplicates int
+	for _, m := range metrics {
+		pm := toPluginMetric(m)
+		src.Received++
+		a.state.Received++
+		if a.config.Dedup {
+			key := seriesKey(pm) + "@" + pm.Timestamp_.UTC().Format(time.RFC3339Nano)
+			_, seen := a.seen[key]
+			_, seenBefore := a.seenBefore[key]
+			if seen || seenBefore {
+				a.state.Duplicates++
+				duplicates++
+				continue
+			}
+			a.seen[key] = struct{}{}
+		}
+		a.metrics = append(a.metrics, pm)
+	}
+	schedulerLogger.WithFields(log.Fields{
+		"_block":     "aggregator-ingest",
+		"source":     source,
+		"task-id":    taskID,
+		"metrics":    len(metrics),
+		"duplicates": duplicates,
+	}).Debug("metrics forwarded to the aggregator")
+	return len(metrics), nil
+}
+
+// flush publishes the metrics buffered during the window. When publishing
+// fails, the metrics are buffered again ahead of the ones forwarded since, as
+// many as fit, to be published on the next flush; as they are then published
+// with every publish node, those which did not fail publish them twice.
+func (a *aggregator) flush() {
+	a.flushMutex.Lock()
+	defer a.flushMutex.Unlock()
+	a.mutex.Lock()
+	buffered := a.metrics
+	a.metrics = nil
+	a.seenBefore, a.seen = a.seen, map[string]struct{}{}
+	a.mutex.Unlock()
+	if len(buffered) == 0 {
+		return
+	}
+	metrics := buffered
+	if a.config.Downsample != "" {
+		metrics = downsample(metrics, a.config.Downsample)
+	}
+	errs := a.publish(metrics)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.state.LastFlush = time.Now()
+	logger := schedulerLogger.WithFields(log.Fields{
+		"_block":  "aggregator-flush",
+		"metrics": len(metrics),
+	})
+	if len(errs) > 0 {
+		a.state.Failures++
+		a.state.LastError = errs[0].Error()
+		for _, err := range errs {
+			logger.WithField("error", err.Error()).Warn("publishing the metrics of the aggregator failed")
+		}
+		a.rebuffer(buffered)
+		return
+	}
+	a.state.Published += uint64(len(metrics))
+	a.state.LastError = ""
+	logger.Debug("metrics of the aggregator published")
+}
+
+// rebuffer buffers the metrics of a failed flush again ahead of the ones
+// forwarded since, dropping the oldest of them which do not fit. The mutex
+// must be held.
+func (a *aggregator) rebuffer(metrics []core.Metric) {
+	if max := a.config.MaxBuffered; max > 0 && len(metrics)+len(a.metrics) > max {
+		keep := max - len(a.metrics)
+		if keep < 0 {
+			keep = 0
+		}
+		a.state.Dropped += uint64(len(metrics) - keep)
+		metrics = metrics[len(metrics)-keep:]
+	}
+	a.metrics = append(append([]core.Metric{}, metrics...), a.metrics...)
+}
+
+// publish publishes the metrics with each publish node. The flush mutex must
+// be held.
+func (a *aggregator) publish(metrics []core.Metric) []error {
+	if !a.subscribed {
+		if err := a.subscribe(); err != nil {
+			return []error{err}
+		}
+	}
+	batch := newMetricBatch(metrics)
+	var errs []error
+	for _, pu := range a.publishNodes {
+		b := batch
+		if len(pu.routes) > 0 {
+			var err error
+			if b, err = batch.route(pu.routes); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if b == nil {
+				continue
+			}
+		}
+		content, err := b.Encode(pu.InboundContentType)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		var publisher publishesMetrics = a.metricManager
+		if pu.builtin != nil {
+			publisher = pu.builtin
+		}
+		for _, err := range publisher.PublishMetrics(pu.InboundContentType, content, pu.Name(), pu.Version(), pu.config.Table(), aggregatorSubscriber) {
+			errs = append(errs, fmt.Errorf("%s: %v", pluginString(pu.Name(), pu.Version()), err))
+		}
+	}
+	// the plugins are bound again on the next flush, in case one was
+	// unloaded or replaced
+	if len(errs) > 0 {
+		a.unsubscribe()
+	}
+	return errs
+}
+
+// subscribe binds the content types of the publish nodes and subscribes to
+// their plugins, which may be loaded after snapd started. The flush mutex
+// must be held.
+func (a *aggregator) subscribe() error {
+	if err := bindPluginContentTypes(a.publishNodes, nil, a.metricManager, []string{plugin.SnapGOBContentType}); err != nil {
+		return err
+	}
+	if plugins := a.plugins(); len(plugins) > 0 {
+		if errs := a.metricManager.SubscribeDeps(aggregatorSubscriber, nil, plugins); len(errs) > 0 {
+			return errs[0]
+		}
+	}
+	a.subscribed = true
+	return nil
+}
+
+// unsubscribe unsubscribes from the plugins of the publish nodes. The flush
+// mutex must be held.
+func (a *aggregator) unsubscribe() {
+	if !a.subscribed {
+		return
+	}
+	if plugins := a.plugins(); len(plugins) > 0 {
+		a.metricManager.UnsubscribeDeps(aggregatorSubscriber, nil, plugins)
+	}
+	for _, pu := range a.publishNodes {
+		pu.InboundContentType = ""
+	}
+	a.subscribed = false
+}
+
+// plugins returns the plugins of the publish nodes which are not built in
+func (a *aggregator) plugins() []core.Plugin {
+	var plugins []core.Plugin
+	for _, pu := range a.publishNodes {
+		if pu.builtin == nil {
+			plugins = append(plugins, pu)
+		}
+	}
+	return plugins
+}
+
+// get returns the state of the aggregator
+func (a *aggregator) get() core.Aggregator {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	state := a.state
+	state.Buffered = len(a.metrics)
+	state.Sources = make([]core.AggregatorSource, 0, len(a.sources))
+	for _, src := range a.sources {
+		state.Sources = append(state.Sources, *src)
+	}
+	sort.Sort(aggregatorSources(state.Sources))
+	return state
+}
+
+type aggregatorSources []core.AggregatorSource
+
+func (s aggregatorSources) Len() int           { return len(s) }
+func (s aggregatorSources) Less(i, j int) bool { return s[i].Principal < s[j].Principal }
+func (s aggregatorSources) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// IngestMetrics buffers the metrics forwarded by the task of another member
+// of the tribe, authenticated as the source, to be published by the
+// aggregator, and returns their number
+func (s *scheduler) IngestMetrics(source, taskID, contentType string, content []byte) (int, error) {
+	if s.aggregator == nil {
+		return 0, ErrAggregatorDisabled
+	}
+	return s.aggregator.ingest(source, taskID, contentType, content)
+}
+
+// Aggregator returns the state of the aggregator role of snapd
+func (s *scheduler) Aggregator() core.Aggregator {
+	if s.aggregator == nil {
+		return core.Aggregator{}
+	}
+	return s.aggregator.get()
+}
+
+// downsample reduces each series of the metrics, i.e. the metrics of a
+// namespace with the same source, host and tags, to one metric with the
+// function. The metric is the last one of the series, with the value of the
+// function of the values of the series. A series with values which are not
+// numbers is reduced to its last metric.
+func downsample(metrics []core.Metric, f string) []core.Metric {
+	series := map[string][]plugin.PluginMetricType{}
+	var keys []string
+	for _, m := range metrics {
+		pm := toPluginMetric(m)
+		key := seriesKey(pm)
+		if _, ok := series[key]; !ok {
+			keys = append(keys, key)
+		}
+		series[key] = append(series[key], pm)
+	}
+	downsampled := make([]core.Metric, 0, len(keys))
+	for _, key := range keys {
+		mts := series[key]
+		last := mts[0]
+		for _, m := range mts[1:] {
+			if !m.Timestamp_.Before(last.Timestamp_) {
+				last = m
+			}
+		}
+		if f != DownsampleLast {
+			if value, ok := downsampleValues(mts, f); ok {
+				last.Data_ = value
+			}
+		}
+		downsampled = append(downsampled, last)
+	}
+	return downsampled
+}
+
+// downsampleValues returns the function of the values of a series, false
+// when one of them is not a number
+func downsampleValues(mts []plugin.PluginMetricType, f string) (float64, bool) {
+	var result float64
+	for i, m := range mts {
+		v, ok := core.NumericValue(m.Data_)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case i == 0:
+			result = v
+		case f == DownsampleMin && v < result, f == DownsampleMax && v > result:
+			result = v
+		case f == DownsampleAvg, f == DownsampleSum:
+			result += v
+		}
+	}
+	if f == DownsampleAvg {
+		result /= float64(len(mts))
+	}
+	return result, true
+}
+
+// seriesKey identifies the series of a metric
+func seriesKey(m plugin.PluginMetricType) string {
+	tags := make([]string, 0, len(m.Tags_))
+	for k, v := range m.Tags_ {
+		tags = append(tags, k+"="+v)
+	}
+	sort.Strings(tags)
+	return fmt.Sprintf("%s|%s|%s|%s|%s", m.Host_.AgentID, m.Host_.String(), m.Source_, strings.Join(m.Namespace_, "/"), strings.Join(tags, ","))
+}
+
+// toPluginMetric returns the metric as decoded from the content of a plugin
+func toPluginMetric(m core.Metric) plugin.PluginMetricType {
+	switch pm := m.(type) {
+	case plugin.PluginMetricType:
+		return pm
+	case *plugin.PluginMetricType:
+		return *pm
+	}
+	return plugin.PluginMetricType{
+		Namespace_:          m.Namespace(),
+		LastAdvertisedTime_: m.LastAdvertisedTime(),
+		Version_:            m.Version(),
+		Config_:             m.Config(),
+		Data_:               m.Data(),
+		Labels_:             m.Labels(),
+		Tags_:               m.Tags(),
+		Source_:             m.Source(),
+		Timestamp_:          m.Timestamp(),
+	}
+}
@@ -0,0 +1,229 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vrischmann/jsonutil"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// aggregatorMetricManager records the metrics the aggregator publishes
+type aggregatorMetricManager struct {
+	*mockMetricManager
+	subscribed int
+	published  []core.Metric
+	fail       bool
+}
+
+func (m *aggregatorMetricManager) SubscribeDeps(taskID string, mts []core.Metric, prs []core.Plugin) []serror.SnapError {
+	m.subscribed++
+	return nil
+}
+
+func (m *aggregatorMetricManager) PublishMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, taskID string) []error {
+	if m.fail {
+		return []error{errors.New("backend unreachable")}
+	}
+	metrics, err := core.DecodeMetrics(contentType, content)
+	if err != nil {
+		return []error{err}
+	}
+	m.published = append(m.published, metrics...)
+	return nil
+}
+
+func aggregatorContent(member string, metrics ...plugin.PluginMetricType) []byte {
+	for i := range metrics {
+		metrics[i].Host_ = core.Host{Hostname: member, Member: member}
+	}
+	b, _, _ := plugin.MarshalPluginMetricTypes(plugin.SnapGOBContentType, metrics)
+	return b
+}
+
+func TestAggregator(t *testing.T) {
+	ts := time.Unix(1476526000, 0)
+	foo := func(data interface{}, ts time.Time) plugin.PluginMetricType {
+		return plugin.PluginMetricType{Namespace_: []string{"intel", "mock", "foo"}, Data_: data, Timestamp_: ts}
+	}
+	Convey("Given an aggregator", t, func() {
+		cfg := &AggregatorConfig{
+			Enable:   true,
+			Interval: jsonutil.Duration{time.Minute},
+			Publish:  []wmap.PublishWorkflowMapNode{{Name: "influxdb", Version: 1}},
+		}
+		mm := &aggregatorMetricManager{mockMetricManager: &mockMetricManager{}}
+		mm.setAcceptedContentType("influxdb", core.PublisherPluginType, 1, []string{plugin.SnapGOBContentType})
+		newAgg := func() *aggregator {
+			a, err := newAggregator(cfg)
+			So(err, ShouldBeNil)
+			a.metricManager = mm
+			return a
+		}
+
+		Convey("the metrics forwarded are published on flush", func() {
+			a := newAgg()
+			n, err := a.ingest("edge-1", "t1", plugin.SnapGOBContentType, aggregatorContent("edge-1", foo(1, ts), foo(2, ts.Add(time.Second))))
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 2)
+			_, err = a.ingest("edge-2", "t2", plugin.SnapGOBContentType, aggregatorContent("edge-2", foo(3, ts)))
+			So(err, ShouldBeNil)
+			So(a.get().Buffered, ShouldEqual, 3)
+			a.flush()
+			So(mm.subscribed, ShouldEqual, 1)
+			So(mm.published, ShouldHaveLength, 3)
+			state := a.get()
+			So(state.Buffered, ShouldEqual, 0)
+			So(state.Received, ShouldEqual, 3)
+			So(state.Published, ShouldEqual, 3)
+			So(state.Sources, ShouldHaveLength, 2)
+			So(state.Sources[0].Principal, ShouldEqual, "edge-1")
+			So(state.Sources[0].Received, ShouldEqual, 2)
+			Convey("and subscribed to once", func() {
+				a.ingest("edge-1", "t1", plugin.SnapGOBContentType, aggregatorContent("edge-1", foo(4, ts.Add(2*time.Second))))
+				a.flush()
+				So(mm.subscribed, ShouldEqual, 1)
+				So(mm.published, ShouldHaveLength, 4)
+			})
+		})
+		Convey("the metrics forwarded twice are dropped with dedup", func() {
+			cfg.Dedup = true
+			a := newAgg()
+			content := aggregatorContent("edge-1", foo(1, ts))
+			a.ingest("edge-1", "t1", plugin.SnapGOBContentType, content)
+			a.ingest("edge-1", "t1", plugin.SnapGOBContentType, content)
+			a.flush()
+			// a retry spanning two windows is a duplicate as well
+			a.ingest("edge-1", "t1", plugin.SnapGOBContentType, content)
+			a.flush()
+			So(mm.published, ShouldHaveLength, 1)
+			So(a.get().Duplicates, ShouldEqual, 2)
+		})
+		Convey("each series is reduced to one metric when downsampling", func() {
+			cfg.Downsample = DownsampleAvg
+			a := newAgg()
+			a.ingest("edge-1", "t1", plugin.SnapGOBContentType, aggregatorContent("edge-1", foo(1, ts), foo(3, ts.Add(time.Second))))
+			a.ingest("edge-2", "t2", plugin.SnapGOBContentType, aggregatorContent("edge-2", foo(10, ts)))
+			a.flush()
+			So(mm.published, ShouldHaveLength, 2)
+			values := map[string]interface{}{}
+			for _, m := range mm.published {
+				pm := m.(plugin.PluginMetricType)
+				values[pm.Host_.Member] = pm.Data_
+				if pm.Host_.Member == "edge-1" {
+					So(pm.Timestamp_.Equal(ts.Add(time.Second)), ShouldBeTrue)
+				}
+			}
+			So(values["edge-1"], ShouldEqual, 2.0)
+			So(values["edge-2"], ShouldEqual, 10.0)
+		})
+		Convey("a failed flush is recorded", func() {
+			mm.fail = true
+			a := newAgg()
+			a.ingest("edge-1", "t1", plugin.SnapGOBContentType, aggregatorContent("edge-1", foo(1, ts)))
+			a.flush()
+			state := a.get()
+			So(state.Failures, ShouldEqual, 1)
+			So(state.LastError, ShouldContainSubstring, "backend unreachable")
+			So(a.subscribed, ShouldBeFalse)
+			Convey("and its metrics published on the next flush", func() {
+				mm.fail = false
+				a.ingest("edge-1", "t1", plugin.SnapGOBContentType, aggregatorContent("edge-1", foo(2, ts.Add(time.Second))))
+				So(a.get().Buffered, ShouldEqual, 2)
+				a.flush()
+				So(mm.published, ShouldHaveLength, 2)
+				So(mm.published[0].Data(), ShouldEqual, 1)
+				So(a.get().Published, ShouldEqual, 2)
+			})
+		})
+		Convey("the sources are the principals the metrics were forwarded as", func() {
+			a := newAgg()
+			a.ingest("snap", "t1", plugin.SnapGOBContentType, aggregatorContent("edge-1", foo(1, ts)))
+			a.ingest("snap", "t2", plugin.SnapGOBContentType, aggregatorContent("edge-2", foo(2, ts)))
+			state := a.get()
+			So(state.Sources, ShouldHaveLength, 1)
+			So(state.Sources[0].Principal, ShouldEqual, "snap")
+			So(state.Sources[0].Received, ShouldEqual, 2)
+		})
+		Convey("the metrics forwarded while the buffer is full are refused", func() {
+			cfg.MaxBuffered = 2
+			a := newAgg()
+			_, err := a.ingest("edge-1", "t1", plugin.SnapGOBContentType, aggregatorContent("edge-1", foo(1, ts), foo(2, ts.Add(time.Second))))
+			So(err, ShouldBeNil)
+			_, err = a.ingest("edge-1", "t1", plugin.SnapGOBContentType, aggregatorContent("edge-1", foo(3, ts.Add(2*time.Second))))
+			So(err, ShouldEqual, ErrAggregatorFull)
+			state := a.get()
+			So(state.Buffered, ShouldEqual, 2)
+			So(state.Dropped, ShouldEqual, 1)
+			Convey("and the metrics of a failed flush kept", func() {
+				mm.fail = true
+				a.flush()
+				So(a.get().Buffered, ShouldEqual, 2)
+			})
+			Convey("and the oldest metrics of a failed flush which do not fit dropped", func() {
+				a.mutex.Lock()
+				metrics := a.metrics
+				a.metrics = []core.Metric{foo(3, ts.Add(2*time.Second))}
+				a.rebuffer(metrics)
+				a.mutex.Unlock()
+				a.flush()
+				So(mm.published, ShouldHaveLength, 2)
+				So(mm.published[0].Data(), ShouldEqual, 2)
+				So(mm.published[1].Data(), ShouldEqual, 3)
+				So(a.get().Dropped, ShouldEqual, 2)
+			})
+		})
+		Convey("content which cannot be decoded is refused", func() {
+			a := newAgg()
+			_, err := a.ingest("edge-1", "t1", plugin.SnapGOBContentType, []byte("garbage"))
+			So(err, ShouldNotBeNil)
+		})
+	})
+	Convey("The downsampling functions", t, func() {
+		mts := []plugin.PluginMetricType{foo(4, ts), foo(1, ts), foo(7, ts)}
+		for f, expected := range map[string]float64{
+			DownsampleAvg: 4,
+			DownsampleMin: 1,
+			DownsampleMax: 7,
+			DownsampleSum: 12,
+		} {
+			v, ok := downsampleValues(mts, f)
+			So(ok, ShouldBeTrue)
+			So(v, ShouldEqual, expected)
+		}
+		_, ok := downsampleValues(append(mts, foo("up", ts)), DownsampleAvg)
+		So(ok, ShouldBeFalse)
+	})
+	Convey("A scheduler without the aggregator role refuses forwarded metrics", t, func() {
+		s := New(GetDefaultConfig())
+		_, err := s.IngestMetrics("edge-1", "t1", plugin.SnapGOBContentType, nil)
+		So(err, ShouldEqual, ErrAggregatorDisabled)
+		So(s.Aggregator().Enabled, ShouldBeFalse)
+	})
+}
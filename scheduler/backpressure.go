@@ -0,0 +1,550 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+)
+
+const (
+	// maxBackPressure bounds the number of runs batched, of intervals skipped
+	// and of runs of the write-ahead log published at once when a publisher
+	// keeps asking to slow down
+	maxBackPressure = 16
+	// maxWALRuns bounds the number of runs a write-ahead log holds, the runs
+	// past it being dropped
+	maxWALRuns = 1024
+)
+
+var (
+	// ErrWALNotSet - The error message for the wal back-pressure policy of a workflow when the scheduler has no write-ahead log directory
+	ErrWALNotSet = errors.New("The wal back-pressure policy needs a data directory.")
+
+	errBatchFull = errors.New("publisher asked to slow down past the largest batch, dropping the batch")
+)
+
+// runContent is the content of a run to publish, in the content type it is
+// encoded in
+type runContent struct {
+	contentType string
+	content     []byte
+}
+
+// backPressure is how a publish node of a task responds to its publisher
+// asking to slow down
+type backPressure struct {
+	sync.Mutex
+	policy       string
+	slowDowns    uint
+	lastSlowDown time.Time
+	// skipped counts the intervals skipped by the stretch policy, dropped the
+	// runs dropped
+	skipped uint
+	dropped uint
+	// batch is the number of runs published together by the batch policy
+	batch uint
+	// pending is the content of the runs the batch policy holds back
+	pending []runContent
+	// stretch is the number of intervals skipped after each run
+	stretch uint
+	// wal is the path of the write-ahead log of the wal policy, buffered the
+	// number of runs it holds which were not published yet, from walOffset
+	wal       string
+	buffered  uint
+	walOffset int64
+	// taken is the number of runs of the log in the content taken last, which
+	// end at takenEnd
+	taken    uint
+	takenEnd int64
+	// held is set while every run is held in the write-ahead log for the
+	// maintenance of snapd
+	held bool
+}
+
+func newBackPressure(policy string) *backPressure {
+	if policy == "" {
+		return nil
+	}
+	return &backPressure{
+		policy: policy,
+		batch:  1,
+	}
+}
+
+// setWAL sets the write-ahead log of the node and counts the runs a previous
+// task with the same ID left in it
+func (b *backPressure) setWAL(path string) error {
+	b.Lock()
+	defer b.Unlock()
+	b.wal = path
+	return b.recoverWAL()
+}
+
+// take returns the content to publish for a run, or nil when the run is held
+// back
+func (b *backPressure) take(run runContent) (*runContent, error) {
+	b.Lock()
+	defer b.Unlock()
+	if b.held {
+		return nil, b.logRun(run)
+	}
+	// the run is logged behind the runs in the log, and the oldest of them
+	// are published
+	if b.buffered > 0 {
+		if err := b.logRun(run); err != nil {
+			return nil, err
+		}
+		runs, end, err := b.readWAL(maxBackPressure)
+		if err != nil {
+			return nil, err
+		}
+		if len(runs) == 0 {
+			// the log was removed from under the node
+			b.buffered = 0
+			b.walOffset = 0
+			return &run, nil
+		}
+		b.taken = uint(len(runs))
+		b.takenEnd = end
+		return mergeContent(run.contentType, runs)
+	}
+	switch b.policy {
+	case core.BackPressureBatch:
+		b.pending = append(b.pending, run)
+		if uint(len(b.pending)) < b.batch {
+			return nil, nil
+		}
+		pending := b.pending
+		b.pending = nil
+		return mergeContent(run.contentType, pending)
+	}
+	return &run, nil
+}
+
+// slowedDown responds to the publisher refusing the content taken last
+func (b *backPressure) slowedDown(run runContent) error {
+	b.Lock()
+	defer b.Unlock()
+	b.slowDowns++
+	b.lastSlowDown = time.Now()
+	// the content is still in the log once the log holds runs
+	if b.buffered > 0 {
+		b.taken = 0
+		return nil
+	}
+	switch b.policy {
+	case "":
+		// a node without a policy only holds runs for the maintenance, and
+		// drops the runs refused
+		b.dropped++
+	case core.BackPressureBatch:
+		if b.batch >= maxBackPressure {
+			b.dropped += b.batch
+			return errBatchFull
+		}
+		b.batch *= 2
+		b.pending = append([]runContent{run}, b.pending...)
+	case core.BackPressureStretch:
+		b.dropped++
+		b.stretch *= 2
+		if b.stretch == 0 {
+			b.stretch = 1
+		}
+		if b.stretch > maxBackPressure {
+			b.stretch = maxBackPressure
+		}
+	case core.BackPressureWAL:
+		return b.logRun(run)
+	}
+	return nil
+}
+
+// accepted eases the back pressure after the publisher accepted the content
+// taken last
+func (b *backPressure) accepted() error {
+	b.Lock()
+	defer b.Unlock()
+	if b.batch > 1 {
+		b.batch /= 2
+	}
+	b.stretch /= 2
+	if b.taken > 0 {
+		b.buffered -= b.taken
+		b.walOffset = b.takenEnd
+		b.taken = 0
+		if b.buffered == 0 {
+			if err := os.Remove(b.wal); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			b.walOffset = 0
+		}
+	}
+	return nil
+}
+
+// intervals returns the number of intervals to skip after a run
+func (b *backPressure) intervals() uint {
+	b.Lock()
+	defer b.Unlock()
+	b.skipped += b.stretch
+	return b.stretch
+}
+
+func (b *backPressure) state(publisher string) core.BackPressureState {
+	b.Lock()
+	defer b.Unlock()
+	return core.BackPressureState{
+		Publisher:    publisher,
+		Policy:       b.policy,
+		Active:       b.batch > 1 || b.stretch > 0 || b.buffered > 0 || b.held,
+		SlowDowns:    b.slowDowns,
+		LastSlowDown: b.lastSlowDown,
+		BatchSize:    b.batch,
+		Stretch:      b.stretch,
+		Skipped:      b.skipped,
+		Buffered:     b.buffered,
+		Dropped:      b.dropped,
+		Held:         b.held,
+	}
+}
+
+// hold holds every run in the write-ahead log at path until released
+func (b *backPressure) hold(path string) error {
+	b.Lock()
+	defer b.Unlock()
+	if b.wal == "" {
+		b.wal = path
+		if err := b.recoverWAL(); err != nil {
+			return err
+		}
+	}
+	b.held = true
+	return nil
+}
+
+// release lets the runs be published again, the next one along with the runs
+// held in the write-ahead log
+func (b *backPressure) release() {
+	b.Lock()
+	defer b.Unlock()
+	b.held = false
+}
+
+// idle returns true if the node has no back-pressure policy and holds no runs
+func (b *backPressure) idle() bool {
+	b.Lock()
+	defer b.Unlock()
+	return b.policy == "" && !b.held && b.buffered == 0
+}
+
+// logRun appends a run to the write-ahead log, or drops it when the log is
+// full
+func (b *backPressure) logRun(run runContent) error {
+	if b.buffered >= maxWALRuns {
+		b.dropped++
+		return nil
+	}
+	if err := b.log(run); err != nil {
+		return err
+	}
+	b.buffered++
+	return nil
+}
+
+// log appends the content of a run to the write-ahead log, prefixed by its
+// length and its content type
+func (b *backPressure) log(run runContent) error {
+	if b.wal == "" {
+		return ErrWALNotSet
+	}
+	f, err := os.OpenFile(b.wal, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err := binary.Write(w, binary.BigEndian, uint32(len(run.content))); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint8(len(run.contentType))); err != nil {
+		return err
+	}
+	if _, err := w.WriteString(run.contentType); err != nil {
+		return err
+	}
+	if _, err := w.Write(run.content); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// recoverWAL counts the runs of the write-ahead log left by snapd stopping,
+// and truncates a run it partially wrote so the next ones follow the others
+func (b *backPressure) recoverWAL() error {
+	b.walOffset = 0
+	runs, end, err := b.readWAL(0)
+	if err != nil {
+		return err
+	}
+	if fi, err := os.Stat(b.wal); err == nil && fi.Size() > end {
+		if err := os.Truncate(b.wal, end); err != nil {
+			return err
+		}
+	}
+	b.buffered = uint(len(runs))
+	if b.buffered > maxWALRuns {
+		b.buffered = maxWALRuns
+	}
+	return nil
+}
+
+// readWAL returns the runs of the write-ahead log from walOffset on, at most
+// max of them unless max is 0, and the offset they end at. A run partially
+// written when snapd stopped is ignored.
+func (b *backPressure) readWAL(max int) ([]runContent, int64, error) {
+	end := b.walOffset
+	if b.wal == "" {
+		return nil, end, nil
+	}
+	f, err := os.Open(b.wal)
+	if os.IsNotExist(err) {
+		return nil, end, nil
+	}
+	if err != nil {
+		return nil, end, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(b.walOffset, 0); err != nil {
+		return nil, end, err
+	}
+	r := bufio.NewReader(f)
+	var runs []runContent
+	for max == 0 || len(runs) < max {
+		var n uint32
+		var ctLen uint8
+		err := binary.Read(r, binary.BigEndian, &n)
+		if err == nil {
+			err = binary.Read(r, binary.BigEndian, &ctLen)
+		}
+		ct := make([]byte, ctLen)
+		if err == nil {
+			_, err = io.ReadFull(r, ct)
+		}
+		content := make([]byte, n)
+		if err == nil {
+			_, err = io.ReadFull(r, content)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, end, err
+		}
+		runs = append(runs, runContent{contentType: string(ct), content: content})
+		end += 4 + 1 + int64(ctLen) + int64(n)
+	}
+	return runs, end, nil
+}
+
+// walPath returns the path of the write-ahead log of the publish node of a
+// task at the given position in its workflow
+func walPath(dir, taskID string, node int, name string) string {
+	return filepath.Join(dir, fmt.Sprintf("%s-%d-%s.wal", taskID, node, name))
+}
+
+// heldContentType returns the content type the runs of a publish node are
+// held back in: the one of the publisher when snapd decodes it, so the runs
+// held are merged without converting them, or else GOB
+func heldContentType(contentType string) string {
+	if core.CanDecode(contentType) {
+		return contentType
+	}
+	return plugin.SnapGOBContentType
+}
+
+// publishContent returns the metrics of the parent job of a publish job
+// encoded in the content type
+func publishContent(pj job, contentType string) (runContent, error) {
+	b, err := jobBatch(pj)
+	if err != nil {
+		return runContent{}, err
+	}
+	content, err := b.Encode(contentType)
+	return runContent{contentType: contentType, content: content}, err
+}
+
+// contentJob returns a job holding content to publish in place of the parent
+// job of a publish job
+func contentJob(pj job, run runContent) job {
+	return &processJob{
+		coreJob:     newCoreJob(processJobType, pj.Deadline(), pj.TaskID(), pj.Priority(), pj.Name(), pj.Version()),
+		contentType: run.contentType,
+		content:     run.content,
+		batch:       newEncodedBatch(run.contentType, run.content),
+	}
+}
+
+// mergeContent returns the metrics of the content of several runs as the
+// content of one, in the content type
+func mergeContent(contentType string, runs []runContent) (*runContent, error) {
+	if len(runs) == 1 {
+		return &runs[0], nil
+	}
+	metrics := []core.Metric{}
+	for _, r := range runs {
+		mts, err := core.DecodeMetrics(r.contentType, r.content)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, mts...)
+	}
+	content, err := core.EncodeMetrics(contentType, metrics)
+	if err != nil {
+		return nil, err
+	}
+	return &runContent{contentType: contentType, content: content}, nil
+}
+
+// slowDown returns true if the publisher asked to slow down
+func slowDown(errs []error) bool {
+	for _, err := range errs {
+		if err == plugin.ErrSlowDown {
+			return true
+		}
+	}
+	return false
+}
+
+// allPublishNodes returns the publish nodes of the workflow, the nodes under
+// its process nodes included, depth first
+func (s *schedulerWorkflow) allPublishNodes() []*publishNode {
+	var walk func(pus []*publishNode, prs []*processNode) []*publishNode
+	walk = func(pus []*publishNode, prs []*processNode) []*publishNode {
+		nodes := append([]*publishNode(nil), pus...)
+		for _, pr := range prs {
+			nodes = append(nodes, walk(pr.PublishNodes, pr.ProcessNodes)...)
+		}
+		return nodes
+	}
+	return walk(s.publishNodes, s.processNodes)
+}
+
+// usesWAL returns true if a publish node of the workflow has the wal
+// back-pressure policy
+func (s *schedulerWorkflow) usesWAL() bool {
+	for _, pu := range s.allPublishNodes() {
+		if pu.backPressure != nil && pu.backPressure.policy == core.BackPressureWAL {
+			return true
+		}
+	}
+	return false
+}
+
+// setWAL sets the write-ahead logs of the publish nodes of the task with the
+// wal back-pressure policy in the directory
+func (s *schedulerWorkflow) setWAL(dir, taskID string) error {
+	for i, pu := range s.allPublishNodes() {
+		if pu.backPressure == nil || pu.backPressure.policy != core.BackPressureWAL {
+			continue
+		}
+		if err := pu.backPressure.setWAL(walPath(dir, taskID, i, pu.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stretch returns the number of intervals the task skips after a run, the
+// largest the publish nodes with the stretch back-pressure policy ask for
+func (s *schedulerWorkflow) stretch() uint {
+	var n uint
+	for _, pu := range s.allPublishNodes() {
+		if pu.backPressure == nil {
+			continue
+		}
+		if i := pu.backPressure.intervals(); i > n {
+			n = i
+		}
+	}
+	return n
+}
+
+// backPressure returns the back-pressure state of the publish nodes of the
+// workflow which have a back-pressure policy
+func (s *schedulerWorkflow) backPressure() []core.BackPressureState {
+	var states []core.BackPressureState
+	for _, pu := range s.allPublishNodes() {
+		if pu.backPressure == nil || pu.backPressure.idle() {
+			continue
+		}
+		states = append(states, pu.backPressure.state(pluginString(pu.Name(), pu.Version())))
+	}
+	return states
+}
+
+// holdPublish holds the runs of every publish node of the task in a
+// write-ahead log in the directory, whatever its back-pressure policy
+func (s *schedulerWorkflow) holdPublish(dir, taskID string) error {
+	for i, pu := range s.allPublishNodes() {
+		if pu.backPressure == nil {
+			pu.backPressure = &backPressure{batch: 1}
+		}
+		if err := pu.backPressure.hold(walPath(dir, taskID, i, pu.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releasePublish releases the runs held by the publish nodes of the workflow
+func (s *schedulerWorkflow) releasePublish() {
+	for _, pu := range s.allPublishNodes() {
+		if pu.backPressure != nil {
+			pu.backPressure.release()
+		}
+	}
+}
+
+// removeWAL removes the write-ahead logs of the publish nodes of the workflow
+func (s *schedulerWorkflow) removeWAL() error {
+	for _, pu := range s.allPublishNodes() {
+		if pu.backPressure == nil || pu.backPressure.wal == "" {
+			continue
+		}
+		if err := os.Remove(pu.backPressure.wal); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
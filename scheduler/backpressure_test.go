@@ -0,0 +1,214 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBackPressure(t *testing.T) {
+	contentIn := func(contentType string, names ...string) runContent {
+		var mts []core.Metric
+		for _, n := range names {
+			mts = append(mts, plugin.PluginMetricType{Namespace_: []string{"intel", n}})
+		}
+		c, err := core.EncodeMetrics(contentType, mts)
+		So(err, ShouldBeNil)
+		return runContent{contentType: contentType, content: c}
+	}
+	content := func(names ...string) runContent {
+		return contentIn(plugin.SnapGOBContentType, names...)
+	}
+	names := func(c *runContent) []string {
+		mts, err := core.DecodeMetrics(c.contentType, c.content)
+		So(err, ShouldBeNil)
+		var ns []string
+		for _, m := range mts {
+			ns = append(ns, m.Namespace()[1])
+		}
+		return ns
+	}
+
+	Convey("no policy", t, func() {
+		So(newBackPressure(""), ShouldBeNil)
+		So(core.ValidateBackPressurePolicy(""), ShouldBeNil)
+		So(core.ValidateBackPressurePolicy("drop"), ShouldNotBeNil)
+	})
+
+	Convey("batch policy", t, func() {
+		b := newBackPressure(core.BackPressureBatch)
+		c, err := b.take(content("a"))
+		So(err, ShouldBeNil)
+		So(names(c), ShouldResemble, []string{"a"})
+
+		Convey("batches twice as many runs after a slow down", func() {
+			So(b.slowedDown(*c), ShouldBeNil)
+			So(b.state("file:1").Active, ShouldBeTrue)
+			So(b.state("file:1").BatchSize, ShouldEqual, 2)
+
+			// the refused run is published with the next one
+			c, err = b.take(content("b"))
+			So(err, ShouldBeNil)
+			So(names(c), ShouldResemble, []string{"a", "b"})
+			So(b.accepted(), ShouldBeNil)
+			So(b.state("file:1").Active, ShouldBeFalse)
+		})
+		Convey("drops the batch past the largest one", func() {
+			for b.batch < maxBackPressure {
+				So(b.slowedDown(*c), ShouldBeNil)
+			}
+			So(b.slowedDown(*c), ShouldEqual, errBatchFull)
+			So(b.state("file:1").Dropped, ShouldEqual, maxBackPressure)
+		})
+		Convey("merges the runs in the content type of the publisher", func() {
+			So(b.slowedDown(*c), ShouldBeNil)
+			c, err = b.take(contentIn(plugin.SnapJSONContentType, "b"))
+			So(err, ShouldBeNil)
+			So(c.contentType, ShouldEqual, plugin.SnapJSONContentType)
+			So(names(c), ShouldResemble, []string{"a", "b"})
+		})
+	})
+
+	Convey("stretch policy", t, func() {
+		b := newBackPressure(core.BackPressureStretch)
+		So(b.slowedDown(content("a")), ShouldBeNil)
+		So(b.intervals(), ShouldEqual, 1)
+		So(b.slowedDown(content("b")), ShouldBeNil)
+		So(b.intervals(), ShouldEqual, 2)
+		So(b.accepted(), ShouldBeNil)
+		So(b.intervals(), ShouldEqual, 1)
+		state := b.state("file:1")
+		So(state.SlowDowns, ShouldEqual, 2)
+		So(state.Skipped, ShouldEqual, 4)
+		So(state.Dropped, ShouldEqual, 2)
+	})
+
+	Convey("wal policy", t, func() {
+		dir, err := ioutil.TempDir("", "snap-wal")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "task-0-file.wal")
+
+		b := newBackPressure(core.BackPressureWAL)
+		So(b.setWAL(path), ShouldBeNil)
+		c, err := b.take(content("a"))
+		So(err, ShouldBeNil)
+		So(b.slowedDown(*c), ShouldBeNil)
+		So(b.state("file:1").Buffered, ShouldEqual, 1)
+
+		Convey("publishes the logged runs with the next one", func() {
+			c, err = b.take(content("b"))
+			So(err, ShouldBeNil)
+			So(names(c), ShouldResemble, []string{"a", "b"})
+			So(b.state("file:1").Buffered, ShouldEqual, 2)
+			So(b.accepted(), ShouldBeNil)
+			So(b.state("file:1").Buffered, ShouldEqual, 0)
+			_, err = os.Stat(path)
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+		Convey("recovers the logged runs", func() {
+			r := newBackPressure(core.BackPressureWAL)
+			So(r.setWAL(path), ShouldBeNil)
+			So(r.state("file:1").Buffered, ShouldEqual, 1)
+		})
+		Convey("drops a run partially logged when recovering", func() {
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+			So(err, ShouldBeNil)
+			f.Write([]byte{0, 0, 1})
+			f.Close()
+			r := newBackPressure(core.BackPressureWAL)
+			So(r.setWAL(path), ShouldBeNil)
+			So(r.state("file:1").Buffered, ShouldEqual, 1)
+			c, err = r.take(content("b"))
+			So(err, ShouldBeNil)
+			So(names(c), ShouldResemble, []string{"a", "b"})
+		})
+		Convey("needs a log", func() {
+			r := newBackPressure(core.BackPressureWAL)
+			So(r.slowedDown(*c), ShouldEqual, ErrWALNotSet)
+		})
+	})
+
+	Convey("held for the maintenance", t, func() {
+		dir, err := ioutil.TempDir("", "snap-wal")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "task-0-file.wal")
+
+		b := &backPressure{batch: 1}
+		So(b.idle(), ShouldBeTrue)
+		So(b.hold(path), ShouldBeNil)
+		for _, n := range []string{"a", "b"} {
+			c, err := b.take(content(n))
+			So(err, ShouldBeNil)
+			So(c, ShouldBeNil)
+		}
+		So(b.state("file:1").Held, ShouldBeTrue)
+		So(b.state("file:1").Buffered, ShouldEqual, 2)
+
+		Convey("publishes the held runs with the next one once released", func() {
+			b.release()
+			c, err := b.take(content("c"))
+			So(err, ShouldBeNil)
+			So(names(c), ShouldResemble, []string{"a", "b", "c"})
+			So(b.accepted(), ShouldBeNil)
+			So(b.idle(), ShouldBeTrue)
+		})
+		Convey("keeps the held runs when the publisher asks to slow down", func() {
+			b.release()
+			c, err := b.take(content("c"))
+			So(err, ShouldBeNil)
+			So(b.slowedDown(*c), ShouldBeNil)
+			So(b.state("file:1").Buffered, ShouldEqual, 3)
+		})
+		Convey("drops a run refused without a policy once published", func() {
+			b.release()
+			c, err := b.take(content("c"))
+			So(err, ShouldBeNil)
+			So(b.accepted(), ShouldBeNil)
+			So(b.slowedDown(*c), ShouldBeNil)
+			So(b.state("file:1").Dropped, ShouldEqual, 1)
+		})
+		Convey("publishes at most the largest batch of the held runs at once", func() {
+			for i := 0; i < maxBackPressure; i++ {
+				_, err := b.take(content("x"))
+				So(err, ShouldBeNil)
+			}
+			b.release()
+			c, err := b.take(content("c"))
+			So(err, ShouldBeNil)
+			So(names(c), ShouldHaveLength, maxBackPressure)
+			So(b.accepted(), ShouldBeNil)
+			So(b.state("file:1").Buffered, ShouldEqual, 3)
+			c, err = b.take(content("d"))
+			So(err, ShouldBeNil)
+			So(names(c), ShouldResemble, []string{"x", "x", "c", "d"})
+			So(b.accepted(), ShouldBeNil)
+			So(b.idle(), ShouldBeTrue)
+		})
+	})
+}
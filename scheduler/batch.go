@@ -0,0 +1,155 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+)
+
+// metricBatch is the metrics a job of a run hands to the nodes of the
+// workflow under it. A batch is shared by all these nodes and never
+// modified once created: it is encoded at most once per content type, when
+// a node first asks for that content type, and the content a processor
+// returned is decoded at most once, when a node routes it. Neither the
+// metrics nor the content returned may be modified.
+type metricBatch struct {
+	sync.Mutex
+	// metrics is nil until the content of a processor is decoded
+	metrics []core.Metric
+	decoded bool
+	// encoded holds the content of the batch by content type
+	encoded map[string][]byte
+	// contentType is the content type of the content the batch was created
+	// from, if any
+	contentType string
+}
+
+// newMetricBatch returns a batch of collected metrics
+func newMetricBatch(metrics []core.Metric) *metricBatch {
+	return &metricBatch{
+		metrics: metrics,
+		decoded: true,
+		encoded: map[string][]byte{},
+	}
+}
+
+// newEncodedBatch returns a batch of the content a processor returned
+func newEncodedBatch(contentType string, content []byte) *metricBatch {
+	if contentType == "" {
+		contentType = plugin.SnapGOBContentType
+	}
+	return &metricBatch{
+		encoded:     map[string][]byte{contentType: content},
+		contentType: contentType,
+	}
+}
+
+// Metrics returns the metrics of the batch, decoding them on first use when
+// the batch was created from content.
+func (b *metricBatch) Metrics() ([]core.Metric, error) {
+	b.Lock()
+	defer b.Unlock()
+	if err := b.decode(); err != nil {
+		return nil, err
+	}
+	return b.metrics, nil
+}
+
+// Encode returns the metrics of the batch in the content type, encoding them
+// on first use.
+func (b *metricBatch) Encode(contentType string) ([]byte, error) {
+	if contentType == plugin.SnapAllContentType {
+		contentType = plugin.SnapGOBContentType
+	}
+	b.Lock()
+	defer b.Unlock()
+	if content, ok := b.encoded[contentType]; ok {
+		return content, nil
+	}
+	if err := b.decode(); err != nil {
+		return nil, err
+	}
+	content, err := core.EncodeMetrics(contentType, b.metrics)
+	if err != nil {
+		return nil, err
+	}
+	b.encoded[contentType] = content
+	return content, nil
+}
+
+// decode decodes the content the batch was created from in its content type.
+// The batch must be locked.
+func (b *metricBatch) decode() error {
+	if b.decoded {
+		return nil
+	}
+	metrics, err := core.DecodeMetrics(b.contentType, b.encoded[b.contentType])
+	if err != nil {
+		return err
+	}
+	b.metrics = metrics
+	b.decoded = true
+	return nil
+}
+
+// route returns the batch of the metrics matching the routes, nil when none
+// of them match. The batch itself is returned when all of them match, so the
+// nodes it is routed to share its encodings.
+func (b *metricBatch) route(routes []*core.WildcardNamespace) (*metricBatch, error) {
+	metrics, err := b.Metrics()
+	if err != nil {
+		return nil, err
+	}
+	routedMetrics := []core.Metric{}
+	for _, m := range metrics {
+		if routed(m.Namespace(), routes) {
+			routedMetrics = append(routedMetrics, m)
+		}
+	}
+	switch len(routedMetrics) {
+	case 0:
+		return nil, nil
+	case len(metrics):
+		return b, nil
+	}
+	return newMetricBatch(routedMetrics), nil
+}
+
+// jobBatch returns the batch of metrics a collector or process job hands to
+// the nodes under it
+func jobBatch(pj job) (*metricBatch, error) {
+	switch pt := pj.(type) {
+	case *collectorJob:
+		if pt.batch == nil {
+			return newMetricBatch(pt.metrics), nil
+		}
+		return pt.batch, nil
+	case *processJob:
+		if pt.batch == nil {
+			return newEncodedBatch(pt.contentType, pt.content), nil
+		}
+		return pt.batch, nil
+	}
+	return nil, fmt.Errorf("a %s job has no metrics to hand over", pj.TypeString())
+}
@@ -0,0 +1,187 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func batchMetrics(n int) []core.Metric {
+	metrics := make([]core.Metric, n)
+	for i := range metrics {
+		metrics[i] = plugin.PluginMetricType{
+			Namespace_: []string{"intel", "mock", fmt.Sprintf("foo%d", i)},
+			Data_:      i,
+			Timestamp_: time.Now(),
+		}
+	}
+	return metrics
+}
+
+func TestMetricBatch(t *testing.T) {
+	Convey("metricBatch", t, func() {
+		metrics := batchMetrics(3)
+		b := newMetricBatch(metrics)
+
+		Convey("encodes once per content type", func() {
+			gobContent, err := b.Encode(plugin.SnapGOBContentType)
+			So(err, ShouldBeNil)
+			again, err := b.Encode(plugin.SnapGOBContentType)
+			So(err, ShouldBeNil)
+			So(&again[0], ShouldEqual, &gobContent[0])
+
+			jsonContent, err := b.Encode(plugin.SnapJSONContentType)
+			So(err, ShouldBeNil)
+			So(bytes.HasPrefix(jsonContent, []byte("[")), ShouldBeTrue)
+
+			_, err = b.Encode("snap.foo")
+			So(err, ShouldNotBeNil)
+		})
+		Convey("encodes the metrics for the publishers in the encodings snap produces", func() {
+			content, err := b.Encode(plugin.InfluxLineContentType)
+			So(err, ShouldBeNil)
+			So(bytes.Count(content, []byte("\n")), ShouldEqual, 3)
+			So(bytes.HasPrefix(content, []byte("intel/mock/foo0 value=0i ")), ShouldBeTrue)
+
+			content, err = b.Encode(plugin.JSONLinesContentType)
+			So(err, ShouldBeNil)
+			So(bytes.Count(content, []byte("\n")), ShouldEqual, 3)
+		})
+		Convey("decodes the content of a processor once", func() {
+			var buf bytes.Buffer
+			So(gob.NewEncoder(&buf).Encode([]plugin.PluginMetricType{metrics[0].(plugin.PluginMetricType)}), ShouldBeNil)
+			eb := newEncodedBatch(plugin.SnapGOBContentType, buf.Bytes())
+			content, err := eb.Encode(plugin.SnapGOBContentType)
+			So(err, ShouldBeNil)
+			So(content, ShouldResemble, buf.Bytes())
+			mts, err := eb.Metrics()
+			So(err, ShouldBeNil)
+			So(mts, ShouldHaveLength, 1)
+			So(mts[0].Namespace(), ShouldResemble, metrics[0].Namespace())
+
+			_, err = newEncodedBatch(plugin.SnapGOBContentType, []byte("foo")).Metrics()
+			So(err, ShouldNotBeNil)
+		})
+		Convey("is shared by the nodes all its metrics are routed to", func() {
+			routes, err := compileRoutes([]string{"/intel/mock/*"})
+			So(err, ShouldBeNil)
+			rb, err := b.route(routes)
+			So(err, ShouldBeNil)
+			So(rb, ShouldEqual, b)
+
+			routes, err = compileRoutes([]string{"/intel/mock/foo1"})
+			So(err, ShouldBeNil)
+			rb, err = b.route(routes)
+			So(err, ShouldBeNil)
+			So(rb, ShouldNotEqual, b)
+			mts, err := rb.Metrics()
+			So(err, ShouldBeNil)
+			So(mts, ShouldHaveLength, 1)
+
+			routes, err = compileRoutes([]string{"/intel/disk/*"})
+			So(err, ShouldBeNil)
+			rb, err = b.route(routes)
+			So(err, ShouldBeNil)
+			So(rb, ShouldBeNil)
+		})
+	})
+}
+
+// The benchmarks hand the metrics of a run to 4 publishers, as the jobs of a
+// workflow with 4 publish nodes under its collect node do.
+const benchmarkPublishers = 4
+
+// BenchmarkPublishSharedBatch encodes the metrics once for all the publishers
+func BenchmarkPublishSharedBatch(b *testing.B) {
+	metrics := batchMetrics(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cj := &collectorJob{coreJob: &coreJob{}, metrics: metrics, batch: newMetricBatch(metrics)}
+		for p := 0; p < benchmarkPublishers; p++ {
+			pb, err := jobBatch(cj)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := pb.Encode(plugin.SnapGOBContentType); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkPublishCopiedBatch copies and encodes the metrics for each
+// publisher, as the jobs did before batches were shared
+func BenchmarkPublishCopiedBatch(b *testing.B) {
+	metrics := batchMetrics(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for p := 0; p < benchmarkPublishers; p++ {
+			mts := make([]plugin.PluginMetricType, len(metrics))
+			for j, m := range metrics {
+				mts[j] = m.(plugin.PluginMetricType)
+			}
+			var buf bytes.Buffer
+			if err := gob.NewEncoder(&buf).Encode(mts); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkRouteSharedBatch routes processed content to publishers whose
+// routes match all of it, decoding it once
+func BenchmarkRouteSharedBatch(b *testing.B) {
+	var buf bytes.Buffer
+	mts := make([]plugin.PluginMetricType, 1000)
+	for i, m := range batchMetrics(len(mts)) {
+		mts[i] = m.(plugin.PluginMetricType)
+	}
+	if err := gob.NewEncoder(&buf).Encode(mts); err != nil {
+		b.Fatal(err)
+	}
+	routes, err := compileRoutes([]string{"/intel/mock/*"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pj := &processJob{coreJob: &coreJob{}, content: buf.Bytes(), batch: newEncodedBatch(plugin.SnapGOBContentType, buf.Bytes())}
+		for p := 0; p < benchmarkPublishers; p++ {
+			rj, err := routeJob(pj, routes)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := publishContent(rj, plugin.SnapGOBContentType); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,136 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builtin
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
+)
+
+type mockAggregatorResolver struct {
+	aggregator string
+	members    map[string]core.RemoteSnapd
+}
+
+func (m *mockAggregatorResolver) ResolveMember(name string) (core.RemoteSnapd, error) {
+	snapd, ok := m.members[name]
+	if !ok {
+		return snapd, errors.New("Unknown member")
+	}
+	return snapd, nil
+}
+
+func (m *mockAggregatorResolver) ResolveAggregator() (string, core.RemoteSnapd, error) {
+	if m.aggregator == "" {
+		return "", core.RemoteSnapd{}, errors.New("No member of the tribe has the aggregator role")
+	}
+	return m.aggregator, m.members[m.aggregator], nil
+}
+
+func TestAggregatorForwarder(t *testing.T) {
+	content, _, _ := plugin.MarshalPluginMetricTypes(plugin.SnapGOBContentType, []plugin.PluginMetricType{{
+		Namespace_: []string{"intel", "mock", "foo"},
+		Data_:      1,
+		Timestamp_: time.Unix(1476526000, 0),
+	}})
+
+	Convey("Given an aggregator", t, func() {
+		var path, user, password string
+		var forwarded request.AggregatorMetrics
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			user, password, _ = r.BasicAuth()
+			b, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(b, &forwarded)
+			w.Write([]byte(`{"meta": {"code": 200, "message": "Metrics (1) ingested", "type": "aggregator_metrics_ingested", "version": 1}, "body": {"metrics": 1}}`))
+		}))
+		defer srv.Close()
+		resolver := &mockAggregatorResolver{
+			aggregator: "agg",
+			members: map[string]core.RemoteSnapd{
+				"agg":   {URL: srv.URL, Password: "secret"},
+				"other": {URL: "http://127.0.0.1:1"},
+			},
+		}
+
+		Convey("the metrics are forwarded to the member with the aggregator role", func() {
+			p, err := New(AggregatorPublisher, nil)
+			So(err, ShouldBeNil)
+			So(p.ContentType(), ShouldEqual, plugin.SnapGOBContentType)
+			So(p.(MemberResolving).SetMemberResolver(resolver), ShouldBeNil)
+			So(p.PublishMetrics(plugin.SnapGOBContentType, content, AggregatorPublisher, -1, nil, "task1"), ShouldBeEmpty)
+			So(path, ShouldEqual, "/v1/aggregator/metrics")
+			So(user, ShouldEqual, "snap")
+			So(password, ShouldEqual, "secret")
+			So(forwarded.TaskID, ShouldEqual, "task1")
+			So(forwarded.ContentType, ShouldEqual, plugin.SnapGOBContentType)
+			So(forwarded.Content, ShouldResemble, content)
+		})
+		Convey("the metrics are forwarded to the member given", func() {
+			p, err := New(AggregatorPublisher, map[string]ctypes.ConfigValue{
+				"member": ctypes.ConfigValueStr{Value: "other"},
+			})
+			So(err, ShouldBeNil)
+			So(p.(MemberResolving).SetMemberResolver(resolver), ShouldBeNil)
+			So(p.PublishMetrics(plugin.SnapGOBContentType, content, AggregatorPublisher, -1, nil, "task1"), ShouldHaveLength, 1)
+			So(path, ShouldBeEmpty)
+		})
+		Convey("the metrics are forwarded to the URL given without tribe", func() {
+			p, err := New(AggregatorPublisher, map[string]ctypes.ConfigValue{
+				"url": ctypes.ConfigValueStr{Value: srv.URL},
+			})
+			So(err, ShouldBeNil)
+			So(p.(MemberResolving).SetMemberResolver(nil), ShouldBeNil)
+			So(p.PublishMetrics(plugin.SnapGOBContentType, content, AggregatorPublisher, -1, nil, "task1"), ShouldBeEmpty)
+			So(path, ShouldEqual, "/v1/aggregator/metrics")
+		})
+		Convey("an aggregator of the tribe needs tribe", func() {
+			p, err := New(AggregatorPublisher, nil)
+			So(err, ShouldBeNil)
+			So(p.(MemberResolving).SetMemberResolver(nil), ShouldNotBeNil)
+		})
+		Convey("no aggregator is an error", func() {
+			resolver.aggregator = ""
+			p, err := New(AggregatorPublisher, nil)
+			So(err, ShouldBeNil)
+			So(p.(MemberResolving).SetMemberResolver(resolver), ShouldBeNil)
+			So(p.PublishMetrics(plugin.SnapGOBContentType, content, AggregatorPublisher, -1, nil, "task1"), ShouldHaveLength, 1)
+		})
+		Convey("a member and a URL cannot both be given", func() {
+			_, err := New(AggregatorPublisher, map[string]ctypes.ConfigValue{
+				"member": ctypes.ConfigValueStr{Value: "agg"},
+				"url":    ctypes.ConfigValueStr{Value: srv.URL},
+			})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
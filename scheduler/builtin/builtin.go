@@ -0,0 +1,175 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package builtin holds the publishers snapd runs itself rather than through
+// a plugin, covering the common sinks a plugin binary is overkill for. A
+// publish node of a workflow uses one by naming it with the Prefix, e.g.
+// "builtin/file".
+package builtin
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/pkg/tlspolicy"
+)
+
+// Prefix marks the name of a built-in publisher in a workflow
+const Prefix = "builtin/"
+
+var (
+	// ErrUnknownPublisher is returned for a name no built-in publisher has
+	ErrUnknownPublisher = errors.New("unknown built-in publisher")
+)
+
+// Publisher is a built-in publisher. It is created for a publish node and
+// used for all the runs of its task, which may overlap.
+type Publisher interface {
+	// ContentType is the content type the publisher is sent
+	ContentType() string
+	// PublishMetrics publishes the content, the plugin name, version and
+	// config being those of the publish node
+	PublishMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, taskID string) []error
+	// Close releases the connections the publisher keeps between the runs
+	// once its task is removed. The publisher opens them again if it is
+	// used afterwards, e.g. by a restored task.
+	Close() error
+}
+
+// MemberResolving is implemented by the built-in publishers which reach
+// other members of the tribe. The resolver is nil when tribe is not enabled,
+// in which case an error is returned if the publisher needs it.
+type MemberResolving interface {
+	SetMemberResolver(core.MemberResolver) error
+}
+
+// FileWriting is implemented by the built-in publishers writing local files,
+// which are confined to a directory of snapd. An error is returned when the
+// directory is empty.
+type FileWriting interface {
+	SetFileDir(dir string) error
+}
+
+// TLSRestricting is implemented by the built-in publishers connecting over
+// TLS, whose connections are restricted by the TLS policy of snapd. A nil
+// policy leaves them as they are.
+type TLSRestricting interface {
+	SetTLSPolicy(*tlspolicy.Config)
+}
+
+type publisherType struct {
+	policy func() *cpolicy.ConfigPolicyNode
+	new    func(config map[string]ctypes.ConfigValue) (Publisher, error)
+}
+
+// publishers are the built-in publishers by name
+var publishers = map[string]publisherType{
+	"file":       {policy: filePolicy, new: newFilePublisher},
+	"statsd":     {policy: forwardPolicy(forwardStatsd), new: newForwarder(forwardStatsd)},
+	"graphite":   {policy: forwardPolicy(forwardGraphite), new: newForwarder(forwardGraphite)},
+	"otlp":       {policy: otlpPolicy, new: newOTLPPublisher},
+	"aggregator": {policy: aggregatorPolicy, new: newAggregatorForwarder},
+}
+
+// IsBuiltin returns whether the plugin name of a publish node names a
+// built-in publisher
+func IsBuiltin(pluginName string) bool {
+	return strings.HasPrefix(pluginName, Prefix)
+}
+
+// Names returns the names of the built-in publishers, with the Prefix
+func Names() []string {
+	names := make([]string, 0, len(publishers))
+	for name := range publishers {
+		names = append(names, Prefix+name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns the built-in publisher of the plugin name, its config
+// processed against the config policy of the publisher
+func New(pluginName string, config map[string]ctypes.ConfigValue) (Publisher, error) {
+	pt, ok := publishers[strings.TrimPrefix(pluginName, Prefix)]
+	if !ok || !IsBuiltin(pluginName) {
+		return nil, fmt.Errorf("%v: %s (one of %s)", ErrUnknownPublisher, pluginName, strings.Join(Names(), ", "))
+	}
+	cfg := make(map[string]ctypes.ConfigValue, len(config))
+	for k, v := range config {
+		cfg[k] = v
+	}
+	processed, errs := pt.policy().Process(cfg)
+	if errs.HasErrors() {
+		msgs := make([]string, len(errs.Errors()))
+		for i, e := range errs.Errors() {
+			msgs[i] = e.Error()
+		}
+		return nil, fmt.Errorf("%s: %s", pluginName, strings.Join(msgs, "; "))
+	}
+	return pt.new(*processed)
+}
+
+// The config values of the defaults of the policy rules are pointers, those
+// given in the workflow are not.
+
+func configStr(config map[string]ctypes.ConfigValue, key string) string {
+	switch v := config[key].(type) {
+	case ctypes.ConfigValueStr:
+		return v.Value
+	case *ctypes.ConfigValueStr:
+		return v.Value
+	}
+	return ""
+}
+
+func configInt(config map[string]ctypes.ConfigValue, key string) int {
+	switch v := config[key].(type) {
+	case ctypes.ConfigValueInt:
+		return v.Value
+	case *ctypes.ConfigValueInt:
+		return v.Value
+	}
+	return 0
+}
+
+func configBool(config map[string]ctypes.ConfigValue, key string) bool {
+	switch v := config[key].(type) {
+	case ctypes.ConfigValueBool:
+		return v.Value
+	case *ctypes.ConfigValueBool:
+		return v.Value
+	}
+	return false
+}
+
+func configDuration(config map[string]ctypes.ConfigValue, key string) time.Duration {
+	switch v := config[key].(type) {
+	case ctypes.ConfigValueDuration:
+		return v.Value
+	case *ctypes.ConfigValueDuration:
+		return v.Value
+	}
+	return 0
+}
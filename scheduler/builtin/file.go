@@ -0,0 +1,284 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builtin
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+// file formats
+const (
+	fileFormatJSON = "json"
+	fileFormatCSV  = "csv"
+)
+
+var (
+	// ErrFileDirNotSet is returned when the directory the files are written
+	// under is not set
+	ErrFileDirNotSet = errors.New("the directory of the built-in file publisher is not set")
+)
+
+// csvHeader is the first line of the CSV files
+var csvHeader = []string{"timestamp", "namespace", "version", "data", "unit", "source", "tags"}
+
+func filePolicy() *cpolicy.ConfigPolicyNode {
+	node := cpolicy.NewPolicyNode()
+	path, _ := cpolicy.NewStringRule("path", true)
+	path.Description = "Path of the file relative to the file directory of snapd, a template of the task ID ({{.Task}}), the day ({{.Date}}) and the hour ({{.Hour}})"
+	format, _ := cpolicy.NewEnumRule("format", false, []string{fileFormatJSON, fileFormatCSV}, fileFormatJSON)
+	format.Description = "Format of the file: a metric serialized into JSON per line, or CSV"
+	maxSize, _ := cpolicy.NewIntegerRule("max_size", false, 0)
+	maxSize.Description = "Size in bytes past which the file is rotated, 0 to never rotate on size"
+	maxAge, _ := cpolicy.NewDurationRule("max_age", false, 0)
+	maxAge.Description = "Age past which the file is rotated, 0 to never rotate on age"
+	maxFiles, _ := cpolicy.NewIntegerRule("max_files", false, 5)
+	maxFiles.Description = "Number of rotated files kept"
+	node.Add(path, format, maxSize, maxAge, maxFiles)
+	return node
+}
+
+// filePublisher appends the metrics to local files, rotating them past a
+// size or an age
+type filePublisher struct {
+	sync.Mutex
+	// dir is the directory the paths are relative to
+	dir      string
+	path     *template.Template
+	format   string
+	maxSize  int64
+	maxAge   time.Duration
+	maxFiles int
+	// created holds when the files written to were created, or first
+	// written to when they existed before
+	created map[string]time.Time
+	// now is replaced by the tests
+	now func() time.Time
+}
+
+// filePathData is what the template of the path is executed with
+type filePathData struct {
+	Task string
+	Date string
+	Hour string
+}
+
+func newFilePublisher(config map[string]ctypes.ConfigValue) (Publisher, error) {
+	tmpl, err := template.New("path").Parse(configStr(config, "path"))
+	if err != nil {
+		return nil, fmt.Errorf("path: %v", err)
+	}
+	f := &filePublisher{
+		path:     tmpl,
+		format:   configStr(config, "format"),
+		maxSize:  int64(configInt(config, "max_size")),
+		maxAge:   configDuration(config, "max_age"),
+		maxFiles: configInt(config, "max_files"),
+		created:  map[string]time.Time{},
+		now:      time.Now,
+	}
+	if f.maxSize < 0 || f.maxAge < 0 || f.maxFiles < 0 {
+		return nil, fmt.Errorf("max_size, max_age and max_files cannot be negative")
+	}
+	// the parts of the path which are not templated are checked right away
+	if _, err := f.filePath(filePathData{Task: "task", Date: "2006-01-02", Hour: "15"}); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// SetFileDir sets the directory the paths are relative to
+func (f *filePublisher) SetFileDir(dir string) error {
+	if dir == "" {
+		return ErrFileDirNotSet
+	}
+	f.Lock()
+	defer f.Unlock()
+	f.dir = dir
+	return nil
+}
+
+// filePath returns the path of the file for the data, relative to the
+// directory of the publisher. Absolute paths and paths leaving the directory
+// are refused.
+func (f *filePublisher) filePath(data filePathData) (string, error) {
+	var buf bytes.Buffer
+	if err := f.path.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	path := filepath.Clean(buf.String())
+	if filepath.IsAbs(path) || path == "." || path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path: %q is not a file under the file directory", buf.String())
+	}
+	return path, nil
+}
+
+// ContentType is JSON lines, written as is, or gob decoded to write CSV
+func (f *filePublisher) ContentType() string {
+	if f.format == fileFormatCSV {
+		return plugin.SnapGOBContentType
+	}
+	return plugin.JSONLinesContentType
+}
+
+func (f *filePublisher) PublishMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, taskID string) []error {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.dir == "" {
+		return []error{ErrFileDirNotSet}
+	}
+	now := f.now()
+	path, err := f.filePath(filePathData{
+		Task: taskID,
+		Date: now.Format("2006-01-02"),
+		Hour: now.Format("15"),
+	})
+	if err != nil {
+		return []error{err}
+	}
+	path = filepath.Join(f.dir, path)
+
+	var out []byte
+	switch contentType {
+	case plugin.JSONLinesContentType:
+		out = content
+	case plugin.SnapGOBContentType:
+		var metrics []plugin.PluginMetricType
+		if err := gob.NewDecoder(bytes.NewReader(content)).Decode(&metrics); err != nil {
+			return []error{err}
+		}
+		if out, err = csvLines(metrics); err != nil {
+			return []error{err}
+		}
+	default:
+		return []error{fmt.Errorf("unsupported content type %q", contentType)}
+	}
+
+	if err := f.rotate(path, int64(len(out)), now); err != nil {
+		return []error{err}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return []error{err}
+	}
+	fi, err := os.Stat(path)
+	isNew := os.IsNotExist(err)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return []error{err}
+	}
+	defer file.Close()
+	if _, ok := f.created[path]; !ok {
+		f.created[path] = now
+	}
+	if f.format == fileFormatCSV && (isNew || fi.Size() == 0) {
+		w := csv.NewWriter(file)
+		w.Write(csvHeader)
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return []error{err}
+		}
+	}
+	if _, err := file.Write(out); err != nil {
+		return []error{err}
+	}
+	return nil
+}
+
+// Close does nothing, the files are opened for each batch
+func (f *filePublisher) Close() error {
+	return nil
+}
+
+// rotate renames the file to path.1, shifting the files rotated before,
+// when writing n more bytes to it would take it past the size, or when it
+// is past the age. The files past the number kept are removed.
+func (f *filePublisher) rotate(path string, n int64, now time.Time) error {
+	fi, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		delete(f.created, path)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	overSize := f.maxSize > 0 && fi.Size() > 0 && fi.Size()+n > f.maxSize
+	created, ok := f.created[path]
+	overAge := f.maxAge > 0 && ok && now.Sub(created) >= f.maxAge
+	if !overSize && !overAge {
+		return nil
+	}
+	delete(f.created, path)
+	if f.maxFiles == 0 {
+		return os.Remove(path)
+	}
+	os.Remove(fmt.Sprintf("%s.%d", path, f.maxFiles))
+	for i := f.maxFiles - 1; i > 0; i-- {
+		from := fmt.Sprintf("%s.%d", path, i)
+		if _, err := os.Stat(from); err == nil {
+			if err := os.Rename(from, fmt.Sprintf("%s.%d", path, i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	return os.Rename(path, path+".1")
+}
+
+func csvLines(metrics []plugin.PluginMetricType) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	for _, m := range metrics {
+		keys := make([]string, 0, len(m.Tags_))
+		for k := range m.Tags_ {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		tags := make([]string, len(keys))
+		for i, k := range keys {
+			tags[i] = k + "=" + m.Tags_[k]
+		}
+		w.Write([]string{
+			m.Timestamp_.Format(time.RFC3339Nano),
+			"/" + strings.Join(m.Namespace_, "/"),
+			strconv.Itoa(m.Version_),
+			fmt.Sprintf("%v", m.Data_),
+			m.Unit_,
+			m.Source_,
+			strings.Join(tags, ";"),
+		})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,169 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builtin
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+func gobMetrics(n int) []byte {
+	metrics := make([]plugin.PluginMetricType, n)
+	for i := range metrics {
+		metrics[i] = plugin.PluginMetricType{
+			Namespace_: []string{"intel", "mock", "foo"},
+			Version_:   1,
+			Data_:      i,
+			Source_:    "host1",
+			Tags_:      map[string]string{"rack": "r1"},
+			Timestamp_: time.Unix(1476526000, 0).UTC(),
+		}
+	}
+	var buf bytes.Buffer
+	gob.NewEncoder(&buf).Encode(metrics)
+	return buf.Bytes()
+}
+
+// newFilePublisherIn returns the built-in file publisher of the config,
+// writing under dir
+func newFilePublisherIn(dir string, config map[string]ctypes.ConfigValue) (Publisher, error) {
+	p, err := New("builtin/file", config)
+	if err != nil {
+		return nil, err
+	}
+	return p, p.(FileWriting).SetFileDir(dir)
+}
+
+func TestFilePublisher(t *testing.T) {
+	Convey("Given a directory to publish to", t, func() {
+		dir, err := ioutil.TempDir("", "snap-builtin-file")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		Convey("an unknown built-in publisher or a config not matching the policy is refused", func() {
+			_, err := New("builtin/nope", nil)
+			So(err, ShouldNotBeNil)
+			_, err = New("builtin/file", nil)
+			So(err, ShouldNotBeNil)
+			_, err = New("builtin/file", map[string]ctypes.ConfigValue{
+				"path":   ctypes.ConfigValueStr{Value: "x"},
+				"format": ctypes.ConfigValueStr{Value: "xml"},
+			})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("a path which is absolute or leaves the file directory is refused", func() {
+			for _, path := range []string{"/etc/passwd", "../x", "a/../../x", "{{.Task}}/../../x", "."} {
+				_, err := New("builtin/file", map[string]ctypes.ConfigValue{
+					"path": ctypes.ConfigValueStr{Value: path},
+				})
+				So(err, ShouldNotBeNil)
+			}
+			p, err := New("builtin/file", map[string]ctypes.ConfigValue{
+				"path": ctypes.ConfigValueStr{Value: "{{.Task}}/metrics.json"},
+			})
+			So(err, ShouldBeNil)
+			So(p.(FileWriting).SetFileDir(""), ShouldEqual, ErrFileDirNotSet)
+			errs := p.PublishMetrics(plugin.JSONLinesContentType, []byte("{}\n"), "builtin/file", -1, nil, "task1")
+			So(errs, ShouldResemble, []error{ErrFileDirNotSet})
+			So(p.(FileWriting).SetFileDir(dir), ShouldBeNil)
+			errs = p.PublishMetrics(plugin.JSONLinesContentType, []byte("{}\n"), "builtin/file", -1, nil, "..")
+			So(errs, ShouldNotBeEmpty)
+		})
+		Convey("the metrics are written as JSON lines to the templated path", func() {
+			p, err := newFilePublisherIn(dir, map[string]ctypes.ConfigValue{
+				"path": ctypes.ConfigValueStr{Value: "{{.Task}}/{{.Date}}.json"},
+			})
+			So(err, ShouldBeNil)
+			So(p.ContentType(), ShouldEqual, plugin.JSONLinesContentType)
+			p.(*filePublisher).now = func() time.Time { return time.Date(2016, 10, 15, 12, 0, 0, 0, time.UTC) }
+			errs := p.PublishMetrics(plugin.JSONLinesContentType, []byte("{\"a\":1}\n"), "builtin/file", -1, nil, "task1")
+			So(errs, ShouldBeEmpty)
+			errs = p.PublishMetrics(plugin.JSONLinesContentType, []byte("{\"a\":2}\n"), "builtin/file", -1, nil, "task1")
+			So(errs, ShouldBeEmpty)
+			b, err := ioutil.ReadFile(filepath.Join(dir, "task1", "2016-10-15.json"))
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "{\"a\":1}\n{\"a\":2}\n")
+		})
+		Convey("the metrics are written as CSV under a header", func() {
+			path := filepath.Join(dir, "metrics.csv")
+			p, err := newFilePublisherIn(dir, map[string]ctypes.ConfigValue{
+				"path":   ctypes.ConfigValueStr{Value: "metrics.csv"},
+				"format": ctypes.ConfigValueStr{Value: "csv"},
+			})
+			So(err, ShouldBeNil)
+			So(p.ContentType(), ShouldEqual, plugin.SnapGOBContentType)
+			So(p.PublishMetrics(plugin.SnapGOBContentType, gobMetrics(2), "builtin/file", -1, nil, "task1"), ShouldBeEmpty)
+			b, err := ioutil.ReadFile(path)
+			So(err, ShouldBeNil)
+			lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+			So(lines, ShouldResemble, []string{
+				"timestamp,namespace,version,data,unit,source,tags",
+				"2016-10-15T10:06:40Z,/intel/mock/foo,1,0,,host1,rack=r1",
+				"2016-10-15T10:06:40Z,/intel/mock/foo,1,1,,host1,rack=r1",
+			})
+		})
+		Convey("the file is rotated past its size, keeping the number of files configured", func() {
+			path := filepath.Join(dir, "metrics.json")
+			p, err := newFilePublisherIn(dir, map[string]ctypes.ConfigValue{
+				"path":      ctypes.ConfigValueStr{Value: "metrics.json"},
+				"max_size":  ctypes.ConfigValueInt{Value: 10},
+				"max_files": ctypes.ConfigValueInt{Value: 2},
+			})
+			So(err, ShouldBeNil)
+			for i := 0; i < 4; i++ {
+				So(p.PublishMetrics(plugin.JSONLinesContentType, []byte("0123456\n"), "builtin/file", -1, nil, "task1"), ShouldBeEmpty)
+			}
+			for _, f := range []string{path, path + ".1", path + ".2"} {
+				b, err := ioutil.ReadFile(f)
+				So(err, ShouldBeNil)
+				So(string(b), ShouldEqual, "0123456\n")
+			}
+			_, err = os.Stat(path + ".3")
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+		Convey("the file is rotated past its age", func() {
+			path := filepath.Join(dir, "metrics.json")
+			p, err := newFilePublisherIn(dir, map[string]ctypes.ConfigValue{
+				"path":    ctypes.ConfigValueStr{Value: "metrics.json"},
+				"max_age": ctypes.ConfigValueStr{Value: "1h"},
+			})
+			So(err, ShouldBeNil)
+			now := time.Now()
+			p.(*filePublisher).now = func() time.Time { return now }
+			So(p.PublishMetrics(plugin.JSONLinesContentType, []byte("a\n"), "builtin/file", -1, nil, "task1"), ShouldBeEmpty)
+			now = now.Add(time.Hour)
+			So(p.PublishMetrics(plugin.JSONLinesContentType, []byte("b\n"), "builtin/file", -1, nil, "task1"), ShouldBeEmpty)
+			b, err := ioutil.ReadFile(path + ".1")
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "a\n")
+		})
+	})
+}
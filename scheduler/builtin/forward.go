@@ -0,0 +1,249 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builtin
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+// forward formats
+const (
+	forwardStatsd   = "statsd"
+	forwardGraphite = "graphite"
+)
+
+// maxDatagramSize keeps the UDP packets under the usual MTU
+const maxDatagramSize = 1432
+
+// pathReplacer replaces the characters statsd or Graphite give a meaning to
+// in a path
+var pathReplacer = strings.NewReplacer(" ", "_", ":", "_", "|", "_", "@", "_", "\n", "_")
+
+// elementReplacer keeps a namespace element, a source or a tag in one
+// element of the dotted path
+var elementReplacer = strings.NewReplacer(".", "_", "/", "_")
+
+func forwardPolicy(format string) func() *cpolicy.ConfigPolicyNode {
+	return func() *cpolicy.ConfigPolicyNode {
+		node := cpolicy.NewPolicyNode()
+		address, _ := cpolicy.NewStringRule("address", true)
+		address.Description = "Address (host:port) the metrics are sent to"
+		protocol, _ := cpolicy.NewEnumRule("protocol", false, []string{"udp", "tcp"}, "udp")
+		protocol.Description = "Protocol the metrics are sent over"
+		path, _ := cpolicy.NewStringRule("path", false, "{{.Namespace}}")
+		path.Description = "Dotted path of a metric, a template of its namespace ({{.Namespace}}), namespace elements ({{index .Elements 0}}), source ({{.Source}}) and tags ({{.Tags.name}})"
+		timeout, _ := cpolicy.NewDurationRule("timeout", false, 5*time.Second)
+		timeout.Description = "Timeout of connecting and writing"
+		node.Add(address, protocol, path, timeout)
+		if format == forwardStatsd {
+			typ, _ := cpolicy.NewEnumRule("type", false, []string{"g", "c", "ms"}, "g")
+			typ.Description = "statsd type of the metrics: gauge, counter or timer"
+			node.Add(typ)
+		}
+		return node
+	}
+}
+
+func newForwarder(format string) func(map[string]ctypes.ConfigValue) (Publisher, error) {
+	return func(config map[string]ctypes.ConfigValue) (Publisher, error) {
+		tmpl, err := template.New("path").Option("missingkey=zero").Parse(configStr(config, "path"))
+		if err != nil {
+			return nil, fmt.Errorf("path: %v", err)
+		}
+		return &forwarder{
+			format:   format,
+			address:  configStr(config, "address"),
+			protocol: configStr(config, "protocol"),
+			path:     tmpl,
+			timeout:  configDuration(config, "timeout"),
+			typ:      configStr(config, "type"),
+			now:      time.Now,
+		}, nil
+	}
+}
+
+// forwarder sends the metrics in the statsd or Graphite plaintext format.
+// Over TCP the connection is kept between the runs and opened again after
+// an error.
+type forwarder struct {
+	sync.Mutex
+	format   string
+	address  string
+	protocol string
+	path     *template.Template
+	timeout  time.Duration
+	typ      string
+	conn     net.Conn
+	// now is replaced by the tests
+	now func() time.Time
+}
+
+// forwardPathData is what the template of the path is executed with
+type forwardPathData struct {
+	// Namespace is the namespace elements joined with dots
+	Namespace string
+	Elements  []string
+	Source    string
+	Tags      map[string]string
+}
+
+func (f *forwarder) ContentType() string {
+	return plugin.SnapGOBContentType
+}
+
+func (f *forwarder) PublishMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, taskID string) []error {
+	if contentType != plugin.SnapGOBContentType {
+		return []error{fmt.Errorf("unsupported content type %q", contentType)}
+	}
+	var metrics []plugin.PluginMetricType
+	if err := gob.NewDecoder(bytes.NewReader(content)).Decode(&metrics); err != nil {
+		return []error{err}
+	}
+	var errs []error
+	lines := make([]string, 0, len(metrics))
+	for _, m := range metrics {
+		line, ok, err := f.line(m)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if ok {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return errs
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	if err := f.send(lines); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// Close closes the connection kept between the runs
+func (f *forwarder) Close() error {
+	f.Lock()
+	defer f.Unlock()
+	if f.conn == nil {
+		return nil
+	}
+	err := f.conn.Close()
+	f.conn = nil
+	return err
+}
+
+// line returns the line of a metric, or false for a metric whose data is not
+// a number
+func (f *forwarder) line(m plugin.PluginMetricType) (string, bool, error) {
+	value, ok := forwardValue(m.Data_)
+	if !ok {
+		return "", false, nil
+	}
+	data := forwardPathData{
+		Elements: make([]string, len(m.Namespace_)),
+		Source:   elementReplacer.Replace(m.Source_),
+		Tags:     make(map[string]string, len(m.Tags_)),
+	}
+	for i, e := range m.Namespace_ {
+		data.Elements[i] = elementReplacer.Replace(e)
+	}
+	data.Namespace = strings.Join(data.Elements, ".")
+	for k, v := range m.Tags_ {
+		data.Tags[k] = elementReplacer.Replace(v)
+	}
+	var buf bytes.Buffer
+	if err := f.path.Execute(&buf, data); err != nil {
+		return "", false, err
+	}
+	path := pathReplacer.Replace(strings.Trim(buf.String(), "."))
+	if path == "" {
+		return "", false, fmt.Errorf("empty path for metric /%s", strings.Join(m.Namespace_, "/"))
+	}
+	if f.format == forwardStatsd {
+		return fmt.Sprintf("%s:%s|%s\n", path, value, f.typ), true, nil
+	}
+	ts := m.Timestamp_
+	if ts.IsZero() {
+		ts = f.now()
+	}
+	return fmt.Sprintf("%s %s %d\n", path, value, ts.Unix()), true, nil
+}
+
+// send writes the lines, packed into datagrams over UDP
+func (f *forwarder) send(lines []string) error {
+	if f.conn == nil {
+		conn, err := net.DialTimeout(f.protocol, f.address, f.timeout)
+		if err != nil {
+			return err
+		}
+		f.conn = conn
+	}
+	var packets [][]byte
+	if f.protocol == "udp" {
+		var buf bytes.Buffer
+		for _, l := range lines {
+			if buf.Len() > 0 && buf.Len()+len(l) > maxDatagramSize {
+				packets = append(packets, append([]byte(nil), buf.Bytes()...))
+				buf.Reset()
+			}
+			buf.WriteString(l)
+		}
+		packets = append(packets, buf.Bytes())
+	} else {
+		packets = [][]byte{[]byte(strings.Join(lines, ""))}
+	}
+	for _, p := range packets {
+		f.conn.SetWriteDeadline(time.Now().Add(f.timeout))
+		if _, err := f.conn.Write(p); err != nil {
+			f.conn.Close()
+			f.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// forwardValue formats the data of a metric, booleans being 1 or 0
+func forwardValue(data interface{}) (string, bool) {
+	i, f, isInt, ok := core.NumberValue(data)
+	if !ok {
+		return "", false
+	}
+	if isInt {
+		return strconv.FormatInt(i, 10), true
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64), true
+}
@@ -0,0 +1,123 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builtin
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core/ctypes"
+)
+
+func TestForwarder(t *testing.T) {
+	Convey("Given a statsd server over UDP", t, func() {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer conn.Close()
+
+		p, err := New("builtin/statsd", map[string]ctypes.ConfigValue{
+			"address": ctypes.ConfigValueStr{Value: conn.LocalAddr().String()},
+			"path":    ctypes.ConfigValueStr{Value: "snap.{{.Source}}.{{.Namespace}}"},
+		})
+		So(err, ShouldBeNil)
+		So(p.ContentType(), ShouldEqual, plugin.SnapGOBContentType)
+
+		Convey("the metrics are sent as gauges under their dotted paths", func() {
+			So(p.PublishMetrics(plugin.SnapGOBContentType, gobMetrics(2), "builtin/statsd", -1, nil, "task1"), ShouldBeEmpty)
+			buf := make([]byte, maxDatagramSize)
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			n, _, err := conn.ReadFrom(buf)
+			So(err, ShouldBeNil)
+			So(string(buf[:n]), ShouldEqual, "snap.host1.intel.mock.foo:0|g\nsnap.host1.intel.mock.foo:1|g\n")
+		})
+	})
+	Convey("Given a Graphite server over TCP", t, func() {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer ln.Close()
+		lines := make(chan string, 10)
+		closed := make(chan struct{})
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			s := bufio.NewScanner(conn)
+			for s.Scan() {
+				lines <- s.Text()
+			}
+			close(closed)
+		}()
+
+		p, err := New("builtin/graphite", map[string]ctypes.ConfigValue{
+			"address":  ctypes.ConfigValueStr{Value: ln.Addr().String()},
+			"protocol": ctypes.ConfigValueStr{Value: "tcp"},
+			"path":     ctypes.ConfigValueStr{Value: "{{.Tags.rack}}.{{index .Elements 2}}"},
+		})
+		So(err, ShouldBeNil)
+
+		Convey("the metrics are sent in the plaintext format with their timestamps", func() {
+			So(p.PublishMetrics(plugin.SnapGOBContentType, gobMetrics(1), "builtin/graphite", -1, nil, "task1"), ShouldBeEmpty)
+			select {
+			case l := <-lines:
+				So(l, ShouldEqual, "r1.foo 0 1476526000")
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for the metrics")
+			}
+
+			Convey("and closing the publisher closes its connection", func() {
+				So(p.Close(), ShouldBeNil)
+				select {
+				case <-closed:
+				case <-time.After(5 * time.Second):
+					t.Fatal("timed out waiting for the connection to close")
+				}
+				So(p.(*forwarder).conn, ShouldBeNil)
+			})
+		})
+	})
+	Convey("The dotted path keeps a namespace element in one element", t, func() {
+		p, err := newForwarder(forwardGraphite)(map[string]ctypes.ConfigValue{
+			"path": ctypes.ConfigValueStr{Value: "{{.Namespace}}"},
+		})
+		So(err, ShouldBeNil)
+		f := p.(*forwarder)
+		line, ok, err := f.line(plugin.PluginMetricType{
+			Namespace_: []string{"intel", "disk", "sda1.part", "read time"},
+			Data_:      1.5,
+			Timestamp_: time.Unix(10, 0),
+		})
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+		So(line, ShouldEqual, "intel.disk.sda1_part.read_time 1.5 10\n")
+
+		Convey("and metrics whose data is not a number are left out", func() {
+			_, ok, err := f.line(plugin.PluginMetricType{Namespace_: []string{"a"}, Data_: "up"})
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
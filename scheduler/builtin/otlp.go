@@ -0,0 +1,304 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builtin
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/pkg/tlspolicy"
+)
+
+// otlp protocols
+const (
+	otlpHTTP = "http"
+	otlpGRPC = "grpc"
+)
+
+// otlpGRPCMethod is the gRPC method the metrics are exported with
+const otlpGRPCMethod = "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"
+
+func otlpPolicy() *cpolicy.ConfigPolicyNode {
+	node := cpolicy.NewPolicyNode()
+	endpoint, _ := cpolicy.NewStringRule("endpoint", true)
+	endpoint.Description = "URL of the OTLP receiver, e.g. http://collector:4318; over HTTP /v1/metrics is the path when none is given"
+	protocol, _ := cpolicy.NewEnumRule("protocol", false, []string{otlpHTTP, otlpGRPC}, otlpHTTP)
+	protocol.Description = "OTLP/HTTP with protobuf, or OTLP/gRPC; an http endpoint is reached in plaintext, an https one over TLS"
+	headers, _ := cpolicy.NewStringRule("headers", false, "")
+	headers.Description = "Headers sent with each export, as name=value pairs separated by commas"
+	timeout, _ := cpolicy.NewDurationRule("timeout", false, 10*time.Second)
+	timeout.Description = "Timeout of an export"
+	caFile, _ := cpolicy.NewStringRule("tls_ca_file", false, "")
+	caFile.Description = "PEM file of the CAs the certificate of an https endpoint is verified with, the system ones when empty"
+	certFile, _ := cpolicy.NewStringRule("tls_cert_file", false, "")
+	certFile.Description = "PEM file of the client certificate presented to an https endpoint"
+	keyFile, _ := cpolicy.NewStringRule("tls_key_file", false, "")
+	keyFile.Description = "PEM file of the key of the client certificate"
+	skipVerify, _ := cpolicy.NewBoolRule("tls_insecure_skip_verify", false, false)
+	skipVerify.Description = "Whether the certificate of an https endpoint is not verified"
+	node.Add(endpoint, protocol, headers, timeout, caFile, certFile, keyFile, skipVerify)
+	return node
+}
+
+func newOTLPPublisher(config map[string]ctypes.ConfigValue) (Publisher, error) {
+	u, err := url.Parse(configStr(config, "endpoint"))
+	if err != nil {
+		return nil, fmt.Errorf("endpoint: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("endpoint: %q is not an http or https URL", u.String())
+	}
+	protocol := configStr(config, "protocol")
+	if protocol == otlpHTTP && (u.Path == "" || u.Path == "/") {
+		u.Path = "/v1/metrics"
+	}
+	headers, err := otlpHeaders(configStr(config, "headers"))
+	if err != nil {
+		return nil, err
+	}
+	o := &otlpPublisher{
+		url:      u.String(),
+		protocol: protocol,
+		headers:  headers,
+		timeout:  configDuration(config, "timeout"),
+	}
+	if u.Scheme == "https" {
+		if o.tlsConfig, err = otlpTLSConfig(config); err != nil {
+			return nil, err
+		}
+	}
+	if protocol == otlpGRPC {
+		o.target = u.Host
+		if u.Port() == "" {
+			o.target = u.Host + ":" + map[string]string{"http": "80", "https": "443"}[u.Scheme]
+		}
+	} else {
+		o.client = &http.Client{
+			Timeout: o.timeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: o.tlsConfig,
+			},
+		}
+	}
+	return o, nil
+}
+
+// otlpTLSConfig returns the TLS config an https endpoint is reached with
+func otlpTLSConfig(config map[string]ctypes.ConfigValue) (*tls.Config, error) {
+	tc := &tls.Config{InsecureSkipVerify: configBool(config, "tls_insecure_skip_verify")}
+	if caFile := configStr(config, "tls_ca_file"); caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls_ca_file: %v", err)
+		}
+		tc.RootCAs = x509.NewCertPool()
+		if !tc.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls_ca_file: no certificate found in %s", caFile)
+		}
+	}
+	certFile, keyFile := configStr(config, "tls_cert_file"), configStr(config, "tls_key_file")
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls_cert_file: %v", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	return tc, nil
+}
+
+// otlpHeaders parses the name=value pairs of the headers option
+func otlpHeaders(s string) (http.Header, error) {
+	headers := http.Header{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("headers: %q is not a name=value pair", pair)
+		}
+		headers.Add(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+	}
+	return headers, nil
+}
+
+// otlpCodec passes the export request, already encoded in protobuf, as the
+// message of the gRPC call and discards the response
+type otlpCodec struct{}
+
+func (otlpCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("otlp codec cannot marshal %T", v)
+	}
+	return *b, nil
+}
+
+func (otlpCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("otlp codec cannot unmarshal into %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (otlpCodec) Name() string {
+	return "proto"
+}
+
+// otlpPublisher exports the metrics to an OpenTelemetry collector, or any
+// other OTLP receiver, as the protobuf export request of the otlp.proto
+// content type.
+type otlpPublisher struct {
+	url       string
+	protocol  string
+	headers   http.Header
+	timeout   time.Duration
+	tlsConfig *tls.Config
+	// client exports over HTTP
+	client *http.Client
+	// target is dialed for the gRPC connection, opened on the first export
+	target string
+	mutex  sync.Mutex
+	conn   *grpc.ClientConn
+}
+
+// SetTLSPolicy restricts the TLS config an https endpoint is reached with
+func (o *otlpPublisher) SetTLSPolicy(p *tlspolicy.Config) {
+	if o.tlsConfig != nil {
+		p.Apply(o.tlsConfig)
+	}
+}
+
+func (o *otlpPublisher) ContentType() string {
+	return plugin.OTLPProtoContentType
+}
+
+// Close closes the gRPC connection and the idle HTTP connections
+func (o *otlpPublisher) Close() error {
+	if o.client != nil {
+		closeIdleConnections(o.client)
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.conn == nil {
+		return nil
+	}
+	err := o.conn.Close()
+	o.conn = nil
+	return err
+}
+
+func (o *otlpPublisher) PublishMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, taskID string) []error {
+	if contentType != plugin.OTLPProtoContentType {
+		return []error{fmt.Errorf("unsupported content type %q", contentType)}
+	}
+	if len(content) == 0 {
+		return nil
+	}
+	var err error
+	if o.protocol == otlpGRPC {
+		err = o.exportGRPC(content)
+	} else {
+		err = o.exportHTTP(content)
+	}
+	if err != nil {
+		return []error{err}
+	}
+	return nil
+}
+
+func (o *otlpPublisher) exportHTTP(content []byte) error {
+	req, _ := http.NewRequest("POST", o.url, bytes.NewReader(content))
+	for k, v := range o.headers {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("OTLP export to %s: %s: %s", o.url, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// grpcConn returns the gRPC connection to the receiver, dialing it if there
+// is none
+func (o *otlpPublisher) grpcConn() (*grpc.ClientConn, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.conn != nil {
+		return o.conn, nil
+	}
+	creds := insecure.NewCredentials()
+	if o.tlsConfig != nil {
+		creds = credentials.NewTLS(o.tlsConfig)
+	}
+	conn, err := grpc.Dial(o.target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+	o.conn = conn
+	return conn, nil
+}
+
+// exportGRPC calls the Export method with the export request as its message
+func (o *otlpPublisher) exportGRPC(content []byte) error {
+	conn, err := o.grpcConn()
+	if err != nil {
+		return fmt.Errorf("OTLP export to %s: %v", o.url, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+	defer cancel()
+	md := metadata.MD{}
+	for k, v := range o.headers {
+		md[strings.ToLower(k)] = v
+	}
+	ctx = metadata.NewOutgoingContext(ctx, md)
+	var reply []byte
+	if err := conn.Invoke(ctx, otlpGRPCMethod, &content, &reply, grpc.ForceCodec(otlpCodec{})); err != nil {
+		return fmt.Errorf("OTLP export to %s: %v", o.url, err)
+	}
+	return nil
+}
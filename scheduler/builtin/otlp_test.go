@@ -0,0 +1,163 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builtin
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/pkg/tlspolicy"
+)
+
+func otlpContent() []byte {
+	b, _ := plugin.EncodePluginMetricTypes(plugin.OTLPProtoContentType, []plugin.PluginMetricType{{
+		Namespace_: []string{"intel", "mock", "foo"},
+		Data_:      1,
+		Source_:    "host1",
+		Timestamp_: time.Unix(1476526000, 0),
+	}})
+	return b
+}
+
+func TestOTLPPublisher(t *testing.T) {
+	content := otlpContent()
+
+	Convey("Given an OTLP/HTTP receiver", t, func() {
+		var path, contentType, auth string
+		var body []byte
+		status := http.StatusOK
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path, contentType, auth = r.URL.Path, r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+			body, _ = ioutil.ReadAll(r.Body)
+			w.WriteHeader(status)
+		}))
+		defer srv.Close()
+
+		p, err := New("builtin/otlp", map[string]ctypes.ConfigValue{
+			"endpoint": ctypes.ConfigValueStr{Value: srv.URL},
+			"headers":  ctypes.ConfigValueStr{Value: "Authorization=Bearer abc"},
+		})
+		So(err, ShouldBeNil)
+		So(p.ContentType(), ShouldEqual, plugin.OTLPProtoContentType)
+
+		Convey("the export request is posted to /v1/metrics as protobuf", func() {
+			So(p.PublishMetrics(plugin.OTLPProtoContentType, content, "builtin/otlp", -1, nil, "task1"), ShouldBeEmpty)
+			So(path, ShouldEqual, "/v1/metrics")
+			So(contentType, ShouldEqual, "application/x-protobuf")
+			So(auth, ShouldEqual, "Bearer abc")
+			So(body, ShouldResemble, content)
+		})
+		Convey("a refused export is an error", func() {
+			status = http.StatusBadRequest
+			So(p.PublishMetrics(plugin.OTLPProtoContentType, content, "builtin/otlp", -1, nil, "task1"), ShouldHaveLength, 1)
+		})
+	})
+	Convey("Given an OTLP/gRPC receiver served in plaintext", t, func() {
+		var method string
+		var md metadata.MD
+		var body []byte
+		var refusal error
+		srv := grpc.NewServer(
+			grpc.ForceServerCodec(otlpCodec{}),
+			grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+				method, _ = grpc.MethodFromServerStream(stream)
+				md, _ = metadata.FromIncomingContext(stream.Context())
+				if err := stream.RecvMsg(&body); err != nil {
+					return err
+				}
+				if refusal != nil {
+					return refusal
+				}
+				reply := []byte{}
+				return stream.SendMsg(&reply)
+			}),
+		)
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		go srv.Serve(lis)
+		defer srv.Stop()
+
+		p, err := New("builtin/otlp", map[string]ctypes.ConfigValue{
+			"endpoint": ctypes.ConfigValueStr{Value: "http://" + lis.Addr().String()},
+			"protocol": ctypes.ConfigValueStr{Value: "grpc"},
+			"headers":  ctypes.ConfigValueStr{Value: "Authorization=Bearer abc"},
+		})
+		So(err, ShouldBeNil)
+		defer p.Close()
+
+		Convey("the Export method is called with the export request as its message", func() {
+			So(p.PublishMetrics(plugin.OTLPProtoContentType, content, "builtin/otlp", -1, nil, "task1"), ShouldBeEmpty)
+			So(method, ShouldEqual, otlpGRPCMethod)
+			So(md.Get("authorization"), ShouldResemble, []string{"Bearer abc"})
+			So(body, ShouldResemble, content)
+		})
+		Convey("a gRPC status other than OK is an error", func() {
+			refusal = status.Error(codes.Unauthenticated, "bad token")
+			errs := p.PublishMetrics(plugin.OTLPProtoContentType, content, "builtin/otlp", -1, nil, "task1")
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Error(), ShouldContainSubstring, "bad token")
+		})
+	})
+	Convey("The CAs of an https endpoint must be readable", t, func() {
+		dir, err := ioutil.TempDir("", "otlp")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		caFile := filepath.Join(dir, "ca.pem")
+		So(ioutil.WriteFile(caFile, []byte("not a certificate"), 0600), ShouldBeNil)
+		for _, protocol := range []string{"http", "grpc"} {
+			_, err := New("builtin/otlp", map[string]ctypes.ConfigValue{
+				"endpoint":    ctypes.ConfigValueStr{Value: "https://collector:4317"},
+				"protocol":    ctypes.ConfigValueStr{Value: protocol},
+				"tls_ca_file": ctypes.ConfigValueStr{Value: caFile},
+			})
+			So(err, ShouldNotBeNil)
+		}
+	})
+	Convey("An https endpoint is reached within the TLS policy", t, func() {
+		p, err := New("builtin/otlp", map[string]ctypes.ConfigValue{
+			"endpoint": ctypes.ConfigValueStr{Value: "https://collector:4318"},
+		})
+		So(err, ShouldBeNil)
+		p.(TLSRestricting).SetTLSPolicy(&tlspolicy.Config{MinVersion: "1.2"})
+		So(p.(*otlpPublisher).tlsConfig.MinVersion, ShouldEqual, tls.VersionTLS12)
+	})
+	Convey("Headers must be name=value pairs", t, func() {
+		_, err := New("builtin/otlp", map[string]ctypes.ConfigValue{
+			"endpoint": ctypes.ConfigValueStr{Value: "http://collector:4318"},
+			"headers":  ctypes.ConfigValueStr{Value: "a=1,b"},
+		})
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,111 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builtin
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+)
+
+// NewSnapdClient returns an HTTP client of the REST API of another snapd
+func NewSnapdClient(snapd core.RemoteSnapd, timeout time.Duration) *http.Client {
+	tc := &tls.Config{}
+	if snapd.TLSConfig != nil {
+		tc = snapd.TLSConfig.Clone()
+	}
+	tc.InsecureSkipVerify = snapd.InsecureSkipVerify
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tc,
+		},
+	}
+}
+
+// closeIdleConnections closes the idle connections of a client returned by
+// NewSnapdClient, if any
+func closeIdleConnections(c *http.Client) {
+	if c == nil {
+		return
+	}
+	if t, ok := c.Transport.(*http.Transport); ok {
+		t.CloseIdleConnections()
+	}
+}
+
+// PostSnapd posts the request, as JSON, to the path of the REST API of
+// another snapd and returns the body of the response. An error response is
+// returned as the error.
+func PostSnapd(c *http.Client, snapd core.RemoteSnapd, path string, in interface{}) (rbody.Body, error) {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", snapd.URL+path, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if snapd.Sign != nil {
+		if err := snapd.Sign(req); err != nil {
+			return nil, err
+		}
+	} else if snapd.Password != "" {
+		req.SetBasicAuth("snap", snapd.Password)
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, errors.New("Invalid credentials")
+	}
+	rb, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	// rbody.APIResponse does not unmarshal a response which is not one
+	var ar struct {
+		Meta *rbody.APIResponseMeta `json:"meta"`
+		Body json.RawMessage        `json:"body"`
+	}
+	if err := json.Unmarshal(rb, &ar); err != nil || ar.Meta == nil {
+		return nil, fmt.Errorf("unexpected response with status %d", resp.StatusCode)
+	}
+	body, err := rbody.UnmarshalBody(ar.Meta.Type, ar.Body)
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := body.(*rbody.Error); ok {
+		return nil, e
+	}
+	return body, nil
+}
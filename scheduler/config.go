@@ -19,16 +19,62 @@ limitations under the License.
 
 package scheduler
 
+import (
+	"fmt"
+	"time"
+
+	"github.com/vrischmann/jsonutil"
+
+	"github.com/intelsdi-x/snap/scheduler/builtin"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
 // default configuration values
 const (
-	defaultWorkManagerQueueSize uint = 25
-	defaultWorkManagerPoolSize  uint = 4
+	defaultWorkManagerQueueSize  uint = 25
+	defaultWorkManagerPoolSize   uint = 4
+	defaultAggregatorInterval         = 10 * time.Second
+	defaultAggregatorMaxBuffered      = 100000
 )
 
 // holds the configuration passed in through the SNAP config file
 type Config struct {
 	WorkManagerQueueSize uint `json:"work_manager_queue_size,omitempty"yaml:"work_manager_queue_size,omitempty"`
 	WorkManagerPoolSize  uint `json:"work_manager_pool_size,omitempty"yaml:"work_manager_pool_size,omitempty"`
+	TaskRunHistory       uint `json:"task_run_history"yaml:"task_run_history"`
+	// DeletedTaskRetention is how long deleted tasks can be restored for,
+	// 0 purges them as soon as they are deleted
+	DeletedTaskRetention jsonutil.Duration `json:"deleted_task_retention"yaml:"deleted_task_retention"`
+	// FileDir is the directory the built-in file publishers write under,
+	// the files directory of the data directory when empty
+	FileDir string `json:"file_dir,omitempty"yaml:"file_dir,omitempty"`
+	// RemotePasswords are the passwords of the REST APIs of the snapds
+	// running remote processors, by the URL the process nodes give, so that
+	// the workflows do not carry them
+	RemotePasswords map[string]string `json:"remote_passwords,omitempty"yaml:"remote_passwords,omitempty"secret:"true"`
+	// Aggregator configures the aggregator role, republishing the metrics
+	// the other members of the tribe forward to this snapd
+	Aggregator *AggregatorConfig `json:"aggregator,omitempty"yaml:"aggregator,omitempty"`
+}
+
+// AggregatorConfig is the configuration of the aggregator role
+type AggregatorConfig struct {
+	Enable bool `json:"enable"yaml:"enable"`
+	// Interval is the window the metrics forwarded are buffered for before
+	// they are published
+	Interval jsonutil.Duration `json:"interval"yaml:"interval"`
+	// Dedup drops the metrics forwarded more than once, e.g. when a member
+	// retried a batch which timed out
+	Dedup bool `json:"dedup"yaml:"dedup"`
+	// Downsample reduces each series to one metric per window with one of
+	// the functions below, none by default
+	Downsample string `json:"downsample"yaml:"downsample"`
+	// MaxBuffered is the number of metrics buffered between two flushes,
+	// beyond which the metrics forwarded are refused, unbounded when 0
+	MaxBuffered int `json:"max_buffered"yaml:"max_buffered"`
+	// Publish are the publish nodes the metrics are published with, as in a
+	// task workflow
+	Publish []wmap.PublishWorkflowMapNode `json:"publish"yaml:"publish"`
 }
 
 // get the default snapd configuration
@@ -36,5 +82,47 @@ func GetDefaultConfig() *Config {
 	return &Config{
 		WorkManagerQueueSize: defaultWorkManagerQueueSize,
 		WorkManagerPoolSize:  defaultWorkManagerPoolSize,
+		TaskRunHistory:       DefaultTaskRunHistory,
+		DeletedTaskRetention: jsonutil.Duration{DefaultDeletedTaskRetention},
+		Aggregator: &AggregatorConfig{
+			Interval:    jsonutil.Duration{defaultAggregatorInterval},
+			MaxBuffered: defaultAggregatorMaxBuffered,
+		},
+	}
+}
+
+// Validate returns the problems found in the configuration
+func (c *Config) Validate() []error {
+	var errs []error
+	if c.WorkManagerQueueSize == 0 {
+		errs = append(errs, fmt.Errorf("scheduler.work_manager_queue_size: must be greater than 0"))
+	}
+	if c.WorkManagerPoolSize == 0 {
+		errs = append(errs, fmt.Errorf("scheduler.work_manager_pool_size: must be greater than 0"))
+	}
+	if c.DeletedTaskRetention.Duration < 0 {
+		errs = append(errs, fmt.Errorf("scheduler.deleted_task_retention: must not be negative"))
+	}
+	if a := c.Aggregator; a != nil && a.Enable {
+		if a.Interval.Duration <= 0 {
+			errs = append(errs, fmt.Errorf("scheduler.aggregator.interval: must be greater than 0"))
+		}
+		if a.MaxBuffered < 0 {
+			errs = append(errs, fmt.Errorf("scheduler.aggregator.max_buffered: must not be negative"))
+		}
+		if !validDownsample(a.Downsample) {
+			errs = append(errs, fmt.Errorf("scheduler.aggregator.downsample: must be one of %v", downsampleFuncs))
+		}
+		if len(a.Publish) == 0 {
+			errs = append(errs, fmt.Errorf("scheduler.aggregator.publish: must have at least one publish node"))
+		} else if _, err := convertPublishNode(a.Publish); err != nil {
+			errs = append(errs, fmt.Errorf("scheduler.aggregator.publish: %v", err))
+		}
+		for _, pu := range a.Publish {
+			if pu.Name == builtin.AggregatorPublisher {
+				errs = append(errs, fmt.Errorf("scheduler.aggregator.publish: %s would forward the metrics back to an aggregator", pu.Name))
+			}
+		}
 	}
+	return errs
 }
@@ -21,8 +21,10 @@ package scheduler
 
 import (
 	"testing"
+	"time"
 
 	"github.com/intelsdi-x/snap/pkg/cfgfile"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -74,6 +76,9 @@ func TestSchedulerConfigYaml(t *testing.T) {
 		Convey("WorkManagerPoolSize should equal 2", func() {
 			So(cfg.WorkManagerPoolSize, ShouldEqual, 2)
 		})
+		Convey("DeletedTaskRetention should equal 24h", func() {
+			So(cfg.DeletedTaskRetention.Duration, ShouldEqual, 24*time.Hour)
+		})
 	})
 
 }
@@ -89,3 +94,28 @@ func TestSchedulerDefaultConfig(t *testing.T) {
 		})
 	})
 }
+
+func TestSchedulerConfigValidate(t *testing.T) {
+	Convey("Validating a scheduler config", t, func() {
+		cfg := GetDefaultConfig()
+		So(cfg.Validate(), ShouldBeEmpty)
+		cfg.WorkManagerPoolSize = 0
+		errs := cfg.Validate()
+		So(errs, ShouldHaveLength, 1)
+		So(errs[0].Error(), ShouldStartWith, "scheduler.work_manager_pool_size")
+	})
+	Convey("Validating the aggregator of a scheduler config", t, func() {
+		cfg := GetDefaultConfig()
+		cfg.Aggregator.Enable = true
+		errs := cfg.Validate()
+		So(errs, ShouldHaveLength, 1)
+		So(errs[0].Error(), ShouldStartWith, "scheduler.aggregator.publish")
+		cfg.Aggregator.Publish = []wmap.PublishWorkflowMapNode{{Name: "builtin/file", Config: map[string]interface{}{"path": "aggregated.json"}}}
+		So(cfg.Validate(), ShouldBeEmpty)
+		cfg.Aggregator.Downsample = "median"
+		So(cfg.Validate(), ShouldHaveLength, 1)
+		cfg.Aggregator.Downsample = DownsampleLast
+		cfg.Aggregator.Publish = append(cfg.Aggregator.Publish, wmap.PublishWorkflowMapNode{Name: "builtin/aggregator"})
+		So(cfg.Validate(), ShouldHaveLength, 1)
+	})
+}
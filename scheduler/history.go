@@ -0,0 +1,125 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// DefaultTaskRunHistory is the number of runs kept per task by default
+const DefaultTaskRunHistory uint = 10
+
+// runHistory is a ring buffer holding the last runs of a task
+type runHistory struct {
+	sync.Mutex
+	runs []core.TaskRun
+	// next is the index the next run is stored at
+	next int
+	full bool
+}
+
+func newRunHistory(size uint) *runHistory {
+	return &runHistory{runs: make([]core.TaskRun, size)}
+}
+
+// add adds the run, replacing the oldest one once the history is full
+func (h *runHistory) add(r core.TaskRun) {
+	h.Lock()
+	defer h.Unlock()
+	if len(h.runs) == 0 {
+		return
+	}
+	h.runs[h.next] = r
+	h.next = (h.next + 1) % len(h.runs)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// all returns the runs from the oldest to the latest
+func (h *runHistory) all() []core.TaskRun {
+	h.Lock()
+	defer h.Unlock()
+	if !h.full {
+		return append([]core.TaskRun{}, h.runs[:h.next]...)
+	}
+	return append(append([]core.TaskRun{}, h.runs[h.next:]...), h.runs[:h.next]...)
+}
+
+// runRecorder records the steps of a run while the jobs of the workflow are
+// worked, which may happen concurrently. A nil *runRecorder records nothing.
+type runRecorder struct {
+	sync.Mutex
+	run core.TaskRun
+}
+
+func newRunRecorder(start time.Time) *runRecorder {
+	return &runRecorder{run: core.TaskRun{Start: start}}
+}
+
+// step records a step of the run which started at the given time and
+// ended now
+func (r *runRecorder) step(typ, plugin string, start time.Time, errs []error) {
+	if r == nil {
+		return
+	}
+	s := core.TaskRunStep{
+		Type:     typ,
+		Plugin:   plugin,
+		Start:    start,
+		Duration: time.Since(start),
+	}
+	for _, e := range errs {
+		s.Errors = append(s.Errors, e.Error())
+	}
+	r.Lock()
+	r.run.Steps = append(r.run.Steps, s)
+	r.Unlock()
+}
+
+// collected records the number of metrics collected in the run
+func (r *runRecorder) collected(n int) {
+	if r == nil {
+		return
+	}
+	r.Lock()
+	r.run.MetricCount = n
+	r.Unlock()
+}
+
+// finish ends the run and adds it to the history
+func (r *runRecorder) finish(h *runHistory) {
+	if r == nil || h == nil {
+		return
+	}
+	r.Lock()
+	r.run.Duration = time.Since(r.run.Start)
+	run := r.run
+	r.Unlock()
+	h.add(run)
+}
+
+func pluginString(name string, version int) string {
+	return fmt.Sprintf("%s:%d", name, version)
+}
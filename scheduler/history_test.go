@@ -0,0 +1,89 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRunHistory(t *testing.T) {
+	Convey("Given a run history of 3 runs", t, func() {
+		h := newRunHistory(3)
+		So(h.all(), ShouldBeEmpty)
+		Convey("runs are returned from the oldest to the latest", func() {
+			for i := 1; i <= 2; i++ {
+				h.add(core.TaskRun{MetricCount: i})
+			}
+			runs := h.all()
+			So(runs, ShouldHaveLength, 2)
+			So(runs[0].MetricCount, ShouldEqual, 1)
+			So(runs[1].MetricCount, ShouldEqual, 2)
+		})
+		Convey("the oldest runs are dropped once it is full", func() {
+			for i := 1; i <= 5; i++ {
+				h.add(core.TaskRun{MetricCount: i})
+			}
+			runs := h.all()
+			So(runs, ShouldHaveLength, 3)
+			So(runs[0].MetricCount, ShouldEqual, 3)
+			So(runs[2].MetricCount, ShouldEqual, 5)
+		})
+	})
+	Convey("Given a run history of 0 runs", t, func() {
+		h := newRunHistory(0)
+		h.add(core.TaskRun{})
+		So(h.all(), ShouldBeEmpty)
+	})
+}
+
+func TestRunRecorder(t *testing.T) {
+	Convey("Given a run recorder", t, func() {
+		h := newRunHistory(DefaultTaskRunHistory)
+		start := time.Now()
+		r := newRunRecorder(start)
+		r.step("collector", "", start, nil)
+		r.collected(4)
+		r.step("publisher", pluginString("file", 3), start, []error{errors.New("publish failed")})
+		r.finish(h)
+		Convey("the run is added to the history when it is finished", func() {
+			runs := h.all()
+			So(runs, ShouldHaveLength, 1)
+			So(runs[0].Start, ShouldResemble, start)
+			So(runs[0].MetricCount, ShouldEqual, 4)
+			So(runs[0].Failed(), ShouldBeTrue)
+			So(runs[0].Steps, ShouldHaveLength, 2)
+			So(runs[0].Steps[1].Plugin, ShouldEqual, "file:3")
+			So(runs[0].Steps[1].Errors, ShouldResemble, []string{"publish failed"})
+		})
+	})
+	Convey("Given a nil run recorder", t, func() {
+		var r *runRecorder
+		So(func() {
+			r.step("collector", "", time.Now(), nil)
+			r.collected(1)
+			r.finish(newRunHistory(1))
+		}, ShouldNotPanic)
+	})
+}
@@ -20,9 +20,6 @@ limitations under the License.
 package scheduler
 
 import (
-	"bytes"
-	"encoding/gob"
-	"fmt"
 	"sync"
 	"time"
 
@@ -32,6 +29,7 @@ import (
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/pkg/chrono"
 	. "github.com/intelsdi-x/snap/pkg/promise"
 )
 
@@ -53,17 +51,20 @@ const (
 type queuedJob interface {
 	Job() job
 	Promise() Promise
+	QueuedTime() time.Time
 }
 
 type qj struct {
 	job     job
 	promise Promise
+	queued  time.Time
 }
 
 func newQueuedJob(job job) queuedJob {
 	return &qj{
 		job:     job,
 		promise: NewPromise(),
+		queued:  chrono.Chrono.Now(),
 	}
 }
 
@@ -77,6 +78,11 @@ func (j *qj) Promise() Promise {
 	return j.promise
 }
 
+// Returns the time the job was queued.
+func (j *qj) QueuedTime() time.Time {
+	return j.queued
+}
+
 // Primary type for job inside
 // the scheduler.  Job encompasses all
 // all job types -- collect, process, and publish.
@@ -90,6 +96,7 @@ type job interface {
 	Type() jobType
 	TypeString() string
 	TaskID() string
+	Priority() string
 	Run()
 }
 
@@ -100,19 +107,21 @@ type coreJob struct {
 	name      string
 	version   int
 	taskID    string
+	priority  string
 	jtype     jobType
 	deadline  time.Time
 	starttime time.Time
 	errors    []error
 }
 
-func newCoreJob(t jobType, deadline time.Time, taskID string, name string, version int) *coreJob {
+func newCoreJob(t jobType, deadline time.Time, taskID string, priority string, name string, version int) *coreJob {
 	return &coreJob{
 		jtype:     t,
 		name:      name,
 		version:   version,
 		deadline:  deadline,
 		taskID:    taskID,
+		priority:  priority,
 		errors:    make([]error, 0),
 		starttime: time.Now(),
 	}
@@ -166,28 +175,49 @@ func (c *coreJob) TaskID() string {
 	return c.taskID
 }
 
+// Priority returns the priority of the task the job belongs to
+func (c *coreJob) Priority() string {
+	if c.priority == "" {
+		return core.TaskPriorityNormal
+	}
+	return c.priority
+}
+
 type collectorJob struct {
 	*coreJob
 	collector      collectsMetrics
 	metricTypes    []core.RequestedMetric
 	metrics        []core.Metric
 	configDataTree *cdata.ConfigDataTree
+	// batch shares the metrics collected with the nodes of the workflow
+	batch *metricBatch
+	// the shard of the metrics collected, out of shardCount shards
+	shardIndex int
+	shardCount int
+	// timestampPolicy sets the timestamps of the metrics collected, tick
+	// being the time the schedule fired the run
+	timestampPolicy core.TimestampPolicy
+	tick            time.Time
+	// metricQuota bounds the metrics collected once the namespaces are
+	// expanded, which the catalog growing may bring over it
+	metricQuota metricQuota
 }
 
-func newCollectorJob(metricTypes []core.RequestedMetric, deadlineDuration time.Duration, collector collectsMetrics, cdt *cdata.ConfigDataTree, taskID string) job {
+func newCollectorJob(metricTypes []core.RequestedMetric, deadlineDuration time.Duration, collector collectsMetrics, cdt *cdata.ConfigDataTree, taskID string, priority string) job {
 	return &collectorJob{
 		collector:      collector,
 		metricTypes:    metricTypes,
 		metrics:        []core.Metric{},
-		coreJob:        newCoreJob(collectJobType, time.Now().Add(deadlineDuration), taskID, "", 0),
+		coreJob:        newCoreJob(collectJobType, time.Now().Add(deadlineDuration), taskID, priority, "", 0),
 		configDataTree: cdt,
 	}
 }
 
 type metric struct {
-	namespace []string
-	version   int
-	config    *cdata.ConfigDataNode
+	namespace  []string
+	version    int
+	pluginName string
+	config     *cdata.ConfigDataNode
 }
 
 func (m *metric) Namespace() []string {
@@ -202,6 +232,10 @@ func (m *metric) Version() int {
 	return m.version
 }
 
+func (m *metric) PluginName() string {
+	return m.pluginName
+}
+
 func (m *metric) Data() interface{}             { return nil }
 func (m *metric) Tags() map[string]string       { return nil }
 func (m *metric) Labels() []core.Label          { return nil }
@@ -226,20 +260,31 @@ func (c *collectorJob) Run() {
 		}
 
 		for _, ns := range nss {
+			// the namespaces left to expand by the plugin are sharded
+			// once collected
+			if !hasWildcard(ns) && !core.InShard(ns, c.shardIndex, c.shardCount) {
+				continue
+			}
 			config := c.configDataTree.Get(ns)
 
 			if config == nil {
 				config = cdata.NewNode()
 			}
 			metric := &metric{
-				namespace: ns,
-				version:   rmt.Version(),
-				config:    config,
+				namespace:  ns,
+				version:    rmt.Version(),
+				pluginName: core.MetricPluginName(rmt),
+				config:     config,
 			}
 			metrics = append(metrics, metric)
 		}
 	}
 
+	if err := c.metricQuota.check(len(metrics)); err != nil {
+		c.quotaExceeded(err)
+		return
+	}
+
 	ret, errs := c.collector.CollectMetrics(metrics, c.Deadline(), c.TaskID())
 
 	log.WithFields(log.Fields{
@@ -249,7 +294,24 @@ func (c *collectorJob) Run() {
 		"metric-count": len(ret),
 	}).Debug("collector run completed")
 
+	if c.shardCount > 1 {
+		shard := ret[:0]
+		for _, m := range ret {
+			if core.InShard(m.Namespace(), c.shardIndex, c.shardCount) {
+				shard = append(shard, m)
+			}
+		}
+		ret = shard
+	}
+	// the plugins may expand the namespaces left with wildcards further
+	if err := c.metricQuota.check(len(ret)); err != nil {
+		c.quotaExceeded(err)
+		return
+	}
+	ret = c.timestamp(ret, chrono.Chrono.Now())
+
 	c.metrics = ret
+	c.batch = newMetricBatch(ret)
 	if errs != nil {
 		for _, e := range errs {
 			log.WithFields(log.Fields{
@@ -263,6 +325,54 @@ func (c *collectorJob) Run() {
 	}
 }
 
+// quotaExceeded fails the run of a task collecting more metrics than its
+// quota allows
+func (c *collectorJob) quotaExceeded(err error) {
+	log.WithFields(log.Fields{
+		"_module":  "scheduler-job",
+		"block":    "run",
+		"job-type": "collector",
+		"error":    err,
+	}).Error("collector run error")
+	c.AddErrors(err)
+}
+
+// timestamp sets the timestamps of the metrics received at receipt as the
+// timestamp policy says, dropping the metrics whose timestamps are out of its
+// bounds
+func (c *collectorJob) timestamp(metrics []core.Metric, receipt time.Time) []core.Metric {
+	tick := c.tick
+	if tick.IsZero() {
+		tick = receipt
+	}
+	ret := metrics[:0]
+	rejected := 0
+	for _, m := range metrics {
+		mt, ok := m.(plugin.PluginMetricType)
+		if !ok {
+			ret = append(ret, m)
+			continue
+		}
+		ts, ok := c.timestampPolicy.Timestamp(mt.Timestamp_, receipt, tick)
+		if !ok {
+			rejected++
+			continue
+		}
+		mt.Timestamp_ = ts
+		ret = append(ret, mt)
+	}
+	if rejected > 0 {
+		log.WithFields(log.Fields{
+			"_module":        "scheduler-job",
+			"block":          "run",
+			"job-type":       "collector",
+			"task-id":        c.TaskID(),
+			"rejected-count": rejected,
+		}).Warn("metrics dropped, their timestamps are out of the bounds of the timestamp policy")
+	}
+	return ret
+}
+
 type processJob struct {
 	*coreJob
 	processor   processesMetrics
@@ -270,14 +380,17 @@ type processJob struct {
 	metrics     []core.Metric
 	config      map[string]ctypes.ConfigValue
 	contentType string
-	content     []byte
+	// content is the content the processor returned, batch shares it with
+	// the nodes of the workflow
+	content []byte
+	batch   *metricBatch
 }
 
 func newProcessJob(parentJob job, pluginName string, pluginVersion int, contentType string, config map[string]ctypes.ConfigValue, processor processesMetrics, taskID string) job {
 	return &processJob{
 		parentJob:   parentJob,
 		metrics:     []core.Metric{},
-		coreJob:     newCoreJob(processJobType, parentJob.Deadline(), taskID, pluginName, pluginVersion),
+		coreJob:     newCoreJob(processJobType, parentJob.Deadline(), taskID, parentJob.Priority(), pluginName, pluginVersion),
 		config:      config,
 		processor:   processor,
 		contentType: contentType,
@@ -295,80 +408,30 @@ func (p *processJob) Run() {
 		"plugin-config":  p.config,
 	}).Debug("starting processor job")
 
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
-
-	switch pt := p.parentJob.(type) {
-	case *collectorJob:
-		switch p.contentType {
-		case plugin.SnapGOBContentType:
-			metrics := make([]plugin.PluginMetricType, len(pt.metrics))
-			for i, m := range pt.metrics {
-				if mt, ok := m.(plugin.PluginMetricType); ok {
-					metrics[i] = mt
-				} else {
-					// TODO; add a log statement and return an error
-					// (instead of panicking)
-					panic("unsupported type")
-				}
-			}
-			enc.Encode(metrics)
-			_, content, errs := p.processor.ProcessMetrics(p.contentType, buf.Bytes(), p.name, p.version, p.config, p.taskID)
-			if errs != nil {
-				for _, e := range errs {
-					log.WithFields(log.Fields{
-						"_module":        "scheduler-job",
-						"block":          "run",
-						"job-type":       "processor",
-						"content-type":   p.contentType,
-						"plugin-name":    p.name,
-						"plugin-version": p.version,
-						"plugin-config":  p.config,
-						"error":          e.Error(),
-					}).Error("error with processor job")
-				}
-				p.AddErrors(errs...)
-			}
-			p.content = content
-		default:
-			// TODO; change log from Fatal to Error and return an error
-			// (instead of panicking)
-			log.WithFields(log.Fields{
-				"_module":        "scheduler-job",
-				"block":          "run",
-				"job-type":       "processor",
-				"content-type":   p.contentType,
-				"plugin-name":    p.name,
-				"plugin-version": p.version,
-				"plugin-config":  p.config,
-			}).Fatal("unsupported content type")
-			panic(fmt.Sprintf("unsupported content type. {plugin name: %s version: %v content-type: '%v'}", p.name, p.version, p.contentType))
-		}
-	case *processJob:
-		// TODO: Remove switch statement and rely on processor to catch errors in type
-		// (separation of concerns; remove content-type definition from the framework?)
-		switch p.contentType {
-		case plugin.SnapGOBContentType:
-			_, content, errs := p.processor.ProcessMetrics(p.contentType, pt.content, p.name, p.version, p.config, p.taskID)
-			if errs != nil {
-				for _, e := range errs {
-					log.WithFields(log.Fields{
-						"_module":        "scheduler-job",
-						"block":          "run",
-						"job-type":       "processor",
-						"content-type":   p.contentType,
-						"plugin-name":    p.name,
-						"plugin-version": p.version,
-						"plugin-config":  p.config,
-						"error":          e.Error(),
-					}).Error("error with processor job")
-				}
-				p.AddErrors(errs...)
-			}
-			p.content = content
-		default:
-			// TODO; change log from Fatal to Error and return an error
-			// (instead of panicking)
+	// the content the parent job was encoded in for a sibling node is reused
+	var content []byte
+	b, err := jobBatch(p.parentJob)
+	if err == nil {
+		content, err = b.Encode(p.contentType)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"_module":         "scheduler-job",
+			"block":           "run",
+			"job-type":        "processor",
+			"content-type":    p.contentType,
+			"plugin-name":     p.name,
+			"plugin-version":  p.version,
+			"plugin-config":   p.config,
+			"parent-job-type": p.parentJob.TypeString(),
+			"error":           err.Error(),
+		}).Error("error encoding the metrics of processor job")
+		p.AddErrors(err)
+		return
+	}
+	contentType, content, errs := p.processor.ProcessMetrics(p.contentType, content, p.name, p.version, p.config, p.taskID)
+	if errs != nil {
+		for _, e := range errs {
 			log.WithFields(log.Fields{
 				"_module":        "scheduler-job",
 				"block":          "run",
@@ -377,24 +440,13 @@ func (p *processJob) Run() {
 				"plugin-name":    p.name,
 				"plugin-version": p.version,
 				"plugin-config":  p.config,
-			}).Fatal("unsupported content type")
-			panic(fmt.Sprintf("unsupported content type. {plugin name: %s version: %v content-type: '%v'}", p.name, p.version, p.contentType))
+				"error":          e.Error(),
+			}).Error("error with processor job")
 		}
-	default:
-		// TODO; change log from Fatal to Error and return an error
-		// (instead of panicking)
-		log.WithFields(log.Fields{
-			"_module":         "scheduler-job",
-			"block":           "run",
-			"job-type":        "processor",
-			"content-type":    p.contentType,
-			"plugin-name":     p.name,
-			"plugin-version":  p.version,
-			"plugin-config":   p.config,
-			"parent-job-type": p.parentJob.Type(),
-		}).Fatal("unsupported parent job type")
-		panic("unsupported parent job type")
+		p.AddErrors(errs...)
 	}
+	p.content = content
+	p.batch = newEncodedBatch(contentType, content)
 }
 
 type publisherJob struct {
@@ -409,7 +461,7 @@ func newPublishJob(parentJob job, pluginName string, pluginVersion int, contentT
 	return &publisherJob{
 		parentJob:   parentJob,
 		publisher:   publisher,
-		coreJob:     newCoreJob(publishJobType, parentJob.Deadline(), taskID, pluginName, pluginVersion),
+		coreJob:     newCoreJob(publishJobType, parentJob.Deadline(), taskID, parentJob.Priority(), pluginName, pluginVersion),
 		config:      config,
 		contentType: contentType,
 	}
@@ -425,40 +477,31 @@ func (p *publisherJob) Run() {
 		"plugin-version": p.version,
 		"plugin-config":  p.config,
 	}).Debug("starting publisher job")
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
 
-	switch p.parentJob.Type() {
-	case collectJobType:
-		switch p.contentType {
-		case plugin.SnapGOBContentType:
-			metrics := make([]plugin.PluginMetricType, len(p.parentJob.(*collectorJob).metrics))
-			for i, m := range p.parentJob.(*collectorJob).metrics {
-				switch mt := m.(type) {
-				case plugin.PluginMetricType:
-					metrics[i] = mt
-				default:
-					panic("unsupported type")
-				}
-			}
-			enc.Encode(metrics)
-			errs := p.publisher.PublishMetrics(p.contentType, buf.Bytes(), p.name, p.version, p.config, p.taskID)
-			if errs != nil {
-				for _, e := range errs {
-					log.WithFields(log.Fields{
-						"_module":        "scheduler-job",
-						"block":          "run",
-						"job-type":       "publisher",
-						"content-type":   p.contentType,
-						"plugin-name":    p.name,
-						"plugin-version": p.version,
-						"plugin-config":  p.config,
-						"error":          e.Error(),
-					}).Error("error with publisher job")
-				}
-				p.AddErrors(errs...)
-			}
-		default:
+	// the content the parent job was encoded in for a sibling node is reused
+	var content []byte
+	b, err := jobBatch(p.parentJob)
+	if err == nil {
+		content, err = b.Encode(p.contentType)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"_module":         "scheduler-job",
+			"block":           "run",
+			"job-type":        "publisher",
+			"content-type":    p.contentType,
+			"plugin-name":     p.name,
+			"plugin-version":  p.version,
+			"plugin-config":   p.config,
+			"parent-job-type": p.parentJob.TypeString(),
+			"error":           err.Error(),
+		}).Error("error encoding the metrics of publisher job")
+		p.AddErrors(err)
+		return
+	}
+	errs := p.publisher.PublishMetrics(p.contentType, content, p.name, p.version, p.config, p.taskID)
+	if errs != nil {
+		for _, e := range errs {
 			log.WithFields(log.Fields{
 				"_module":        "scheduler-job",
 				"block":          "run",
@@ -467,42 +510,20 @@ func (p *publisherJob) Run() {
 				"plugin-name":    p.name,
 				"plugin-version": p.version,
 				"plugin-config":  p.config,
-			}).Fatal("unsupported content type")
-			panic(fmt.Sprintf("unsupported content type. {plugin name: %s version: %v content-type: '%v'}", p.name, p.version, p.contentType))
+				"error":          e.Error(),
+			}).Error("error with publisher job")
 		}
-	case processJobType:
-		// TODO: Remove switch statement and rely on publisher to catch errors in type
-		// (separation of concerns; remove content-type definition from the framework?)
-		switch p.contentType {
-		case plugin.SnapGOBContentType:
-			errs := p.publisher.PublishMetrics(p.contentType, p.parentJob.(*processJob).content, p.name, p.version, p.config, p.taskID)
-			if errs != nil {
-				for _, e := range errs {
-					log.WithFields(log.Fields{
-						"_module":        "scheduler-job",
-						"block":          "run",
-						"job-type":       "publisher",
-						"content-type":   p.contentType,
-						"plugin-name":    p.name,
-						"plugin-version": p.version,
-						"plugin-config":  p.config,
-						"error":          e.Error(),
-					}).Error("error with publisher job")
-				}
-				p.AddErrors(errs...)
-			}
+		p.AddErrors(errs...)
+	}
+}
+
+// hasWildcard returns whether a namespace has elements the collector plugin
+// expands itself
+func hasWildcard(ns []string) bool {
+	for _, el := range ns {
+		if el == "*" {
+			return true
 		}
-	default:
-		log.WithFields(log.Fields{
-			"_module":         "scheduler-job",
-			"block":           "run",
-			"job-type":        "publisher",
-			"content-type":    p.contentType,
-			"plugin-name":     p.name,
-			"plugin-version":  p.version,
-			"plugin-config":   p.config,
-			"parent-job-type": p.parentJob.Type(),
-		}).Fatal("unsupported parent job type")
-		panic("unsupported job type")
 	}
+	return false
 }
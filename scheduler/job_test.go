@@ -21,9 +21,11 @@ package scheduler
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/intelsdi-x/snap/control/plugin"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
 
@@ -47,37 +49,37 @@ func TestCollectorJob(t *testing.T) {
 	cdt := cdata.NewTree()
 	Convey("newCollectorJob()", t, func() {
 		Convey("it returns an init-ed collectorJob", func() {
-			cj := newCollectorJob([]core.RequestedMetric{}, defaultDeadline, &mockCollector{}, cdt, "taskid")
+			cj := newCollectorJob([]core.RequestedMetric{}, defaultDeadline, &mockCollector{}, cdt, "taskid", core.TaskPriorityNormal)
 			So(cj, ShouldHaveSameTypeAs, &collectorJob{})
 		})
 	})
 	Convey("StartTime()", t, func() {
 		Convey("it should return the job starttime", func() {
-			cj := newCollectorJob([]core.RequestedMetric{}, defaultDeadline, &mockCollector{}, cdt, "taskid")
+			cj := newCollectorJob([]core.RequestedMetric{}, defaultDeadline, &mockCollector{}, cdt, "taskid", core.TaskPriorityNormal)
 			So(cj.StartTime(), ShouldHaveSameTypeAs, time.Now())
 		})
 	})
 	Convey("Deadline()", t, func() {
 		Convey("it should return the job daedline", func() {
-			cj := newCollectorJob([]core.RequestedMetric{}, defaultDeadline, &mockCollector{}, cdt, "taskid")
+			cj := newCollectorJob([]core.RequestedMetric{}, defaultDeadline, &mockCollector{}, cdt, "taskid", core.TaskPriorityNormal)
 			So(cj.Deadline(), ShouldResemble, cj.(*collectorJob).deadline)
 		})
 	})
 	Convey("Type()", t, func() {
 		Convey("it should return the job type", func() {
-			cj := newCollectorJob([]core.RequestedMetric{}, defaultDeadline, &mockCollector{}, cdt, "taskid")
+			cj := newCollectorJob([]core.RequestedMetric{}, defaultDeadline, &mockCollector{}, cdt, "taskid", core.TaskPriorityNormal)
 			So(cj.Type(), ShouldEqual, collectJobType)
 		})
 	})
 	Convey("Errors()", t, func() {
 		Convey("it should return the errors from the job", func() {
-			cj := newCollectorJob([]core.RequestedMetric{}, defaultDeadline, &mockCollector{}, cdt, "taskid")
+			cj := newCollectorJob([]core.RequestedMetric{}, defaultDeadline, &mockCollector{}, cdt, "taskid", core.TaskPriorityNormal)
 			So(cj.Errors(), ShouldResemble, []error{})
 		})
 	})
 	Convey("AddErrors()", t, func() {
 		Convey("it should append errors to the job", func() {
-			cj := newCollectorJob([]core.RequestedMetric{}, defaultDeadline, &mockCollector{}, cdt, "taskid")
+			cj := newCollectorJob([]core.RequestedMetric{}, defaultDeadline, &mockCollector{}, cdt, "taskid", core.TaskPriorityNormal)
 			So(cj.Errors(), ShouldResemble, []error{})
 
 			e1 := errors.New("1")
@@ -92,26 +94,102 @@ func TestCollectorJob(t *testing.T) {
 	})
 	Convey("Run()", t, func() {
 		Convey("it should complete without errors", func() {
-			cj := newCollectorJob([]core.RequestedMetric{}, defaultDeadline, &mockCollector{}, cdt, "taskid")
+			cj := newCollectorJob([]core.RequestedMetric{}, defaultDeadline, &mockCollector{}, cdt, "taskid", core.TaskPriorityNormal)
 			cj.(*collectorJob).Run()
 			So(cj.Errors(), ShouldResemble, []error{})
 		})
 	})
 }
 
+// expandingCollector expands every namespace to 100 namespaces and collects
+// the metrics it is asked for
+type expandingCollector struct{}
+
+func (m *expandingCollector) CollectMetrics(mts []core.Metric, _ time.Time, _ string) ([]core.Metric, []error) {
+	return mts, nil
+}
+
+func (m *expandingCollector) ExpandWildcards(ns []string) ([][]string, serror.SnapError) {
+	var nss [][]string
+	for i := 0; i < 100; i++ {
+		nss = append(nss, []string{"intel", "mock", fmt.Sprintf("host%d", i), "foo"})
+	}
+	return nss, nil
+}
+
+func TestCollectorJobShard(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	cdt := cdata.NewTree()
+	mts := []core.RequestedMetric{&metric{namespace: []string{"intel", "mock", "*", "foo"}}}
+	Convey("A sharded collector job", t, func() {
+		Convey("collects its shard of the namespaces only", func() {
+			seen := map[string]int{}
+			for i := 0; i < 3; i++ {
+				cj := newCollectorJob(mts, defaultDeadline, &expandingCollector{}, cdt, "taskid", core.TaskPriorityNormal)
+				cj.(*collectorJob).shardIndex, cj.(*collectorJob).shardCount = i, 3
+				cj.(*collectorJob).Run()
+				So(len(cj.(*collectorJob).metrics), ShouldBeLessThan, 100)
+				for _, m := range cj.(*collectorJob).metrics {
+					seen[core.JoinNamespace(m.Namespace())]++
+				}
+			}
+			So(seen, ShouldHaveLength, 100)
+			for _, n := range seen {
+				So(n, ShouldEqual, 1)
+			}
+		})
+		Convey("collects every namespace when it is the only shard", func() {
+			cj := newCollectorJob(mts, defaultDeadline, &expandingCollector{}, cdt, "taskid", core.TaskPriorityNormal)
+			cj.(*collectorJob).shardIndex, cj.(*collectorJob).shardCount = 0, 1
+			cj.(*collectorJob).Run()
+			So(cj.(*collectorJob).metrics, ShouldHaveLength, 100)
+		})
+	})
+}
+
+func TestCollectorJobTimestamps(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	receipt := time.Unix(1000, 0)
+	metrics := func() []core.Metric {
+		return []core.Metric{
+			plugin.PluginMetricType{Namespace_: []string{"a"}, Timestamp_: receipt.Add(-time.Second)},
+			plugin.PluginMetricType{Namespace_: []string{"b"}, Timestamp_: receipt.Add(time.Hour)},
+		}
+	}
+	Convey("A collector job", t, func() {
+		cj := newCollectorJob(nil, defaultDeadline, &mockCollector{}, cdata.NewTree(), "taskid", core.TaskPriorityNormal).(*collectorJob)
+		Convey("drops the metrics whose timestamps are out of bounds", func() {
+			cj.timestampPolicy = core.TimestampPolicy{MaxFuture: time.Minute}
+			ret := cj.timestamp(metrics(), receipt)
+			So(ret, ShouldHaveLength, 1)
+			So(ret[0].Namespace(), ShouldResemble, []string{"a"})
+			So(ret[0].Timestamp(), ShouldResemble, receipt.Add(-time.Second))
+		})
+		Convey("timestamps the metrics with the tick of the run", func() {
+			cj.timestampPolicy = core.TimestampPolicy{Source: core.TimestampSourceTick}
+			cj.tick = time.Unix(990, 0)
+			ret := cj.timestamp(metrics(), receipt)
+			So(ret, ShouldHaveLength, 2)
+			for _, m := range ret {
+				So(m.Timestamp(), ShouldResemble, time.Unix(990, 0))
+			}
+		})
+	})
+}
+
 func TestQueuedJob(t *testing.T) {
 	log.SetLevel(log.FatalLevel)
 	cdt := cdata.NewTree()
 	Convey("Job()", t, func() {
 		Convey("it should return the underlying job", func() {
-			cj := newCollectorJob([]core.RequestedMetric{}, defaultDeadline, &mockCollector{}, cdt, "taskid")
+			cj := newCollectorJob([]core.RequestedMetric{}, defaultDeadline, &mockCollector{}, cdt, "taskid", core.TaskPriorityNormal)
 			qj := newQueuedJob(cj)
 			So(qj.Job(), ShouldEqual, cj)
 		})
 	})
 	Convey("Promise()", t, func() {
 		Convey("it should return the underlying promise", func() {
-			cj := newCollectorJob([]core.RequestedMetric{}, defaultDeadline, &mockCollector{}, cdt, "taskid")
+			cj := newCollectorJob([]core.RequestedMetric{}, defaultDeadline, &mockCollector{}, cdt, "taskid", core.TaskPriorityNormal)
 			qj := newQueuedJob(cj)
 			So(qj.Promise().IsComplete(), ShouldBeFalse)
 		})
@@ -0,0 +1,169 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/scheduler_event"
+)
+
+var (
+	// ErrMaintenanceEnabled - The error message for turning the maintenance mode on when it is already on
+	ErrMaintenanceEnabled = errors.New("Maintenance mode is already on.")
+	// ErrMaintenanceDisabled - The error message for turning the maintenance mode off when it is not on
+	ErrMaintenanceDisabled = errors.New("Maintenance mode is not on.")
+)
+
+// maintenance is the maintenance mode of the scheduler
+type maintenance struct {
+	sync.Mutex
+	core.Maintenance
+}
+
+// EnableMaintenance turns the maintenance mode of snapd on. The running tasks
+// are paused, or, with holdPublish, keep collecting while the runs they
+// publish are held in write-ahead logs. Tasks started during the maintenance
+// are paused or held as well.
+func (s *scheduler) EnableMaintenance(holdPublish bool) (core.Maintenance, error) {
+	logger := schedulerLogger.WithFields(log.Fields{
+		"_block":       "enable-maintenance",
+		"hold-publish": holdPublish,
+	})
+	s.maintenance.Lock()
+	defer s.maintenance.Unlock()
+	if s.maintenance.Enabled {
+		return s.maintenance.get(), ErrMaintenanceEnabled
+	}
+	if holdPublish && s.walDir == "" {
+		return s.maintenance.get(), ErrWALNotSet
+	}
+
+	s.maintenance.Maintenance = core.Maintenance{
+		Enabled:     true,
+		HoldPublish: holdPublish,
+		Since:       time.Now(),
+	}
+	for _, t := range s.tasks.Table() {
+		s.maintainTask(t)
+	}
+	defer s.eventManager.Emit(&scheduler_event.MaintenanceChangedEvent{
+		Enabled:     true,
+		HoldPublish: holdPublish,
+	})
+	logger.WithField("tasks", len(s.maintenance.Tasks)).Info("maintenance mode on")
+	return s.maintenance.get(), nil
+}
+
+// DisableMaintenance turns the maintenance mode of snapd off. The tasks
+// paused for the maintenance are resumed and the tasks holding their runs
+// publish them along with their next run.
+func (s *scheduler) DisableMaintenance() (core.Maintenance, error) {
+	logger := schedulerLogger.WithField("_block", "disable-maintenance")
+	s.maintenance.Lock()
+	defer s.maintenance.Unlock()
+	if !s.maintenance.Enabled {
+		return s.maintenance.get(), ErrMaintenanceDisabled
+	}
+
+	for _, id := range s.maintenance.Tasks {
+		t, err := s.getTask(id)
+		if err != nil {
+			continue
+		}
+		if s.maintenance.HoldPublish {
+			t.releasePublish()
+			continue
+		}
+		// a task resumed or stopped during the maintenance is left alone
+		if err := t.resume(); err == nil {
+			s.eventManager.Emit(&scheduler_event.TaskResumedEvent{
+				TaskID: id,
+				Source: "maintenance",
+			})
+		}
+	}
+	logger.WithField("tasks", len(s.maintenance.Tasks)).Info("maintenance mode off")
+	s.maintenance.Maintenance = core.Maintenance{}
+	defer s.eventManager.Emit(&scheduler_event.MaintenanceChangedEvent{})
+	return s.maintenance.get(), nil
+}
+
+// Maintenance returns the maintenance mode of snapd.
+func (s *scheduler) Maintenance() core.Maintenance {
+	s.maintenance.Lock()
+	defer s.maintenance.Unlock()
+	return s.maintenance.get()
+}
+
+// maintainTask pauses or holds the publishing of a running task for the
+// maintenance. The maintenance must be locked.
+func (s *scheduler) maintainTask(t *task) {
+	if !s.maintenance.Enabled || (t.State() != core.TaskSpinning && t.State() != core.TaskFiring) {
+		return
+	}
+	logger := schedulerLogger.WithFields(log.Fields{
+		"_block":  "maintain-task",
+		"task-id": t.ID(),
+	})
+	if s.maintenance.HoldPublish {
+		if err := t.holdPublish(s.walDir); err != nil {
+			logger.WithField("_error", err.Error()).Error("unable to hold the publishing of the task")
+			return
+		}
+	} else {
+		if err := t.pause(0, nil); err != nil {
+			logger.WithField("_error", err.Error()).Error("unable to pause the task")
+			return
+		}
+		s.eventManager.Emit(&scheduler_event.TaskPausedEvent{
+			TaskID: t.ID(),
+			Source: "maintenance",
+		})
+	}
+	s.maintenance.Tasks = append(s.maintenance.Tasks, t.ID())
+}
+
+// get returns a copy of the maintenance mode. The maintenance must be locked.
+func (m *maintenance) get() core.Maintenance {
+	c := m.Maintenance
+	c.Tasks = append([]string(nil), m.Tasks...)
+	return c
+}
+
+// holdPublish holds the runs the task publishes in write-ahead logs in the
+// directory until released.
+func (t *task) holdPublish(dir string) error {
+	t.Lock()
+	defer t.Unlock()
+	return t.workflow.holdPublish(dir, t.id)
+}
+
+// releasePublish publishes the runs the task held along with its next run.
+func (t *task) releasePublish() {
+	t.Lock()
+	defer t.Unlock()
+	t.workflow.releasePublish()
+}
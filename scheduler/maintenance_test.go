@@ -0,0 +1,94 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMaintenance(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	Convey("Maintenance mode", t, func() {
+		c := new(mockMetricManager)
+		c.setAcceptedContentType("file", core.PublisherPluginType, -1, []string{"snap.json"})
+		s := New(GetDefaultConfig())
+		s.SetMetricManager(c)
+		So(s.Start(), ShouldBeNil)
+
+		w := wmap.NewWorkflowMap()
+		w.CollectNode.AddMetric("/foo/bar", 1)
+		w.CollectNode.Add(wmap.NewPublishNode("file", -1))
+		running, te := s.CreateTask(schedule.NewSimpleSchedule(time.Hour), w, false)
+		So(te.Errors(), ShouldBeEmpty)
+		stopped, te := s.CreateTask(schedule.NewSimpleSchedule(time.Hour), w, false)
+		So(te.Errors(), ShouldBeEmpty)
+		running.(*task).Spin()
+		defer running.(*task).Stop()
+
+		So(s.Maintenance().Enabled, ShouldBeFalse)
+		_, err := s.DisableMaintenance()
+		So(err, ShouldEqual, ErrMaintenanceDisabled)
+
+		Convey("pauses the running tasks", func() {
+			m, err := s.EnableMaintenance(false)
+			So(err, ShouldBeNil)
+			So(m.Enabled, ShouldBeTrue)
+			So(m.Tasks, ShouldResemble, []string{running.ID()})
+			So(running.State(), ShouldEqual, core.TaskPaused)
+			So(stopped.State(), ShouldEqual, core.TaskStopped)
+			_, err = s.EnableMaintenance(false)
+			So(err, ShouldEqual, ErrMaintenanceEnabled)
+
+			m, err = s.DisableMaintenance()
+			So(err, ShouldBeNil)
+			So(m.Enabled, ShouldBeFalse)
+			So(running.State(), ShouldNotEqual, core.TaskPaused)
+		})
+		Convey("holds the publishing of the running tasks", func() {
+			_, err := s.EnableMaintenance(true)
+			So(err, ShouldEqual, ErrWALNotSet)
+
+			dir, err := ioutil.TempDir("", "snap-wal")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+			s.SetWALDir(dir)
+			m, err := s.EnableMaintenance(true)
+			So(err, ShouldBeNil)
+			So(m.HoldPublish, ShouldBeTrue)
+			So(running.State(), ShouldNotEqual, core.TaskPaused)
+			bp := running.BackPressure()
+			So(len(bp), ShouldEqual, 1)
+			So(bp[0].Held, ShouldBeTrue)
+
+			_, err = s.DisableMaintenance()
+			So(err, ShouldBeNil)
+			So(running.BackPressure(), ShouldBeEmpty)
+		})
+	})
+}
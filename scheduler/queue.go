@@ -22,11 +22,14 @@ package scheduler
 import (
 	"errors"
 	"sync"
+
+	"github.com/intelsdi-x/snap/core"
 )
 
 var (
 	errQueueEmpty    = errors.New("queue empty")
 	errLimitExceeded = errors.New("limit exceeded")
+	errJobShed       = errors.New("low priority job shed")
 )
 
 type jobHandler func(queuedJob)
@@ -41,6 +44,8 @@ type queue struct {
 	items   []queuedJob
 	mutex   *sync.Mutex
 	status  queueStatus
+	// shed is the number of low priority jobs shed from the full queue
+	shed uint64
 }
 
 type queueStatus int
@@ -111,13 +116,12 @@ func (q *queue) start() {
 	for {
 		select {
 		case e := <-q.Event:
-			if err := q.push(e); err != nil {
-				qe := &queuingError{
-					Err: err,
-					Job: e.Job(),
-				}
-				q.Err <- qe
-				e.Promise().Complete([]error{qe}) // Signal job termination.
+			shed, err := q.push(e)
+			if shed != nil {
+				q.fail(shed, errJobShed)
+			}
+			if err != nil {
+				q.fail(e, err)
 				continue
 			}
 
@@ -143,6 +147,15 @@ func (q *queue) start() {
 
 }
 
+func (q *queue) fail(j queuedJob, err error) {
+	qe := &queuingError{
+		Err: err,
+		Job: j.Job(),
+	}
+	q.Err <- qe
+	j.Promise().Complete([]error{qe}) // Signal job termination.
+}
+
 func (q *queue) handle() {
 	for {
 		item, err := q.pop()
@@ -160,18 +173,60 @@ func (q *queue) length() int {
 	return len(q.items)
 }
 
-func (q *queue) push(j queuedJob) error {
+// depth returns the number of jobs in the queue
+func (q *queue) depth() uint {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return uint(q.length())
+}
+
+// shedCount returns the number of low priority jobs shed from the full queue
+func (q *queue) shedCount() uint64 {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.shed
+}
+
+// priorityRank orders the jobs by the priority of their task, lower first
+func priorityRank(j queuedJob) int {
+	switch j.Job().Priority() {
+	case core.TaskPriorityCritical:
+		return 0
+	case core.TaskPriorityLow:
+		return 2
+	}
+	return 1
+}
+
+// push adds the job to the queue. When the queue is full a low priority job
+// is shed: the job itself, or else the latest low priority job queued which is
+// then returned to make room for the job.
+func (q *queue) push(j queuedJob) (queuedJob, error) {
 
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
 
 	if q.limit == 0 || uint(q.length())+1 <= q.limit {
 		q.items = append(q.items, j)
-		return nil
+		return nil, nil
 	}
-	return errLimitExceeded
+	if priorityRank(j) == 2 {
+		q.shed++
+		return nil, errJobShed
+	}
+	for i := q.length() - 1; i >= 0; i-- {
+		if priorityRank(q.items[i]) == 2 {
+			shed := q.items[i]
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			q.items = append(q.items, j)
+			q.shed++
+			return shed, nil
+		}
+	}
+	return nil, errLimitExceeded
 }
 
+// pop removes the first job of the highest priority from the queue
 func (q *queue) pop() (queuedJob, error) {
 
 	q.mutex.Lock()
@@ -183,8 +238,14 @@ func (q *queue) pop() (queuedJob, error) {
 		return j, errQueueEmpty
 	}
 
-	j = q.items[0]
-	q.items = q.items[1:]
+	next := 0
+	for i := 1; i < q.length() && priorityRank(q.items[next]) > 0; i++ {
+		if priorityRank(q.items[i]) < priorityRank(q.items[next]) {
+			next = i
+		}
+	}
+	j = q.items[next]
+	q.items = append(q.items[:next], q.items[next+1:]...)
 
 	return j, nil
 }
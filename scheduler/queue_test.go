@@ -25,6 +25,7 @@ import (
 	"time"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/intelsdi-x/snap/core"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -82,7 +83,7 @@ func TestQueue(t *testing.T) {
 		q := newQueue(3, func(queuedJob) { time.Sleep(1 * time.Second) })
 		q.Start()
 		for i := 0; i < 5; i++ {
-			q.Event <- newQueuedJob(&collectorJob{})
+			q.Event <- newQueuedJob(&collectorJob{coreJob: &coreJob{}})
 		}
 		err := <-q.Err
 		So(err, ShouldNotBeNil)
@@ -90,13 +91,56 @@ func TestQueue(t *testing.T) {
 		q.Stop()
 	})
 
+	Convey("it works the jobs by priority", t, func() {
+		q := newQueue(5, func(queuedJob) {})
+		for _, p := range []string{core.TaskPriorityLow, core.TaskPriorityNormal, core.TaskPriorityCritical, core.TaskPriorityNormal} {
+			q.push(newQueuedJob(&collectorJob{coreJob: &coreJob{priority: p}}))
+		}
+		order := []string{}
+		for q.length() > 0 {
+			j, err := q.pop()
+			So(err, ShouldBeNil)
+			order = append(order, j.Job().Priority())
+		}
+		So(order, ShouldResemble, []string{
+			core.TaskPriorityCritical,
+			core.TaskPriorityNormal,
+			core.TaskPriorityNormal,
+			core.TaskPriorityLow,
+		})
+	})
+
+	Convey("it sheds low priority jobs when the queue bound is exceeded", t, func() {
+		q := newQueue(2, func(queuedJob) {})
+		low := newQueuedJob(&collectorJob{coreJob: &coreJob{priority: core.TaskPriorityLow}})
+		normal := newQueuedJob(&collectorJob{coreJob: &coreJob{priority: core.TaskPriorityNormal}})
+		q.push(low)
+		q.push(normal)
+		Convey("an incoming low priority job is shed", func() {
+			shed, err := q.push(newQueuedJob(&collectorJob{coreJob: &coreJob{priority: core.TaskPriorityLow}}))
+			So(shed, ShouldBeNil)
+			So(err, ShouldEqual, errJobShed)
+			So(q.shedCount(), ShouldEqual, 1)
+		})
+		Convey("a queued low priority job makes room for a critical job", func() {
+			shed, err := q.push(newQueuedJob(&collectorJob{coreJob: &coreJob{priority: core.TaskPriorityCritical}}))
+			So(err, ShouldBeNil)
+			So(shed, ShouldEqual, low)
+			So(q.length(), ShouldEqual, 2)
+			shed, err = q.push(newQueuedJob(&collectorJob{coreJob: &coreJob{priority: core.TaskPriorityCritical}}))
+			So(shed, ShouldBeNil)
+			So(err, ShouldEqual, errLimitExceeded)
+			So(q.shedCount(), ShouldEqual, 1)
+		})
+	})
+
 	Convey("stop closes the queue", t, func() {
 		q := newQueue(3, func(queuedJob) { time.Sleep(1 * time.Second) })
 		q.Start()
 		q.Stop()
 		time.Sleep(10 * time.Millisecond)
 		So(func() { q.kill <- struct{}{} }, ShouldPanic)
-		So(func() { q.Event <- newQueuedJob(&collectorJob{}) }, ShouldPanic)
+		So(func() { q.Event <- newQueuedJob(&collectorJob{coreJob: &coreJob{}}) }, ShouldPanic)
 	})
 
 }
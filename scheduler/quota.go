@@ -0,0 +1,132 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+)
+
+// metricQuota bounds the number of metrics a task collects on each run,
+// once its namespaces are expanded
+type metricQuota struct {
+	max int
+	// owner is the tenant or principal whose quota sets max
+	owner string
+}
+
+// check returns an error if the task collects more metrics than the quota
+func (q metricQuota) check(count int) error {
+	if q.max > 0 && count > q.max {
+		return fmt.Errorf("%v: the task collects %d metrics where max_metrics of %s is %d", core.ErrTaskOverQuota, count, q.owner, q.max)
+	}
+	return nil
+}
+
+// quotaOwner is a tenant or a principal of the API with a quota, and the
+// tasks the quota bounds
+type quotaOwner struct {
+	name  string
+	quota *core.Quota
+	owns  func(*task) bool
+}
+
+// quotaOwners returns the owners of the quotas bounding the task: its tenant
+// and the principal of the API which created it
+func (s *scheduler) quotaOwners(t *task) []quotaOwner {
+	var owners []quotaOwner
+	if tenant := t.Tenant(); tenant != "" {
+		if q := s.metricManager.TenantQuota(tenant); q != nil {
+			owners = append(owners, quotaOwner{
+				name:  "tenant " + tenant,
+				quota: q,
+				owns:  func(o *task) bool { return o.Tenant() == tenant },
+			})
+		}
+	}
+	if by := t.CreatedBy(); by != "" {
+		if q := s.principalQuotas[by]; q != nil {
+			owners = append(owners, quotaOwner{
+				name:  "principal " + by,
+				quota: q,
+				owns:  func(o *task) bool { return o.CreatedBy() == by },
+			})
+		}
+	}
+	return owners
+}
+
+// applyQuotas checks that the task, given its schedule and workflow, keeps
+// its tenant and the principal which created it within their quotas, and
+// bounds the metrics the workflow collects on each run by them
+func (s *scheduler) applyQuotas(t *task, sch schedule.Schedule, wf *schedulerWorkflow) error {
+	owners := s.quotaOwners(t)
+	if len(owners) == 0 {
+		return nil
+	}
+	rate := schedule.Rate(sch)
+	tasks := s.tasks.Table()
+	// an updated task is counted already
+	_, exists := tasks[t.id]
+	var mq metricQuota
+	for _, o := range owners {
+		q := o.quota
+		if q.MaxCollectionRate > 0 && rate > q.MaxCollectionRate {
+			return fmt.Errorf("%v: the task collects %g times per second where max_collection_rate of %s is %g", core.ErrTaskOverQuota, rate, o.name, q.MaxCollectionRate)
+		}
+		count, total := 0, rate
+		for id, other := range tasks {
+			if id == t.id || !o.owns(other) {
+				continue
+			}
+			count++
+			total += schedule.Rate(other.Schedule())
+		}
+		if q.MaxTasks > 0 && !exists && count >= q.MaxTasks {
+			return fmt.Errorf("%v: %s already has %d task(s) where max_tasks is %d", core.ErrQuotaExceeded, o.name, count, q.MaxTasks)
+		}
+		if q.MaxCollectionRate > 0 && total > q.MaxCollectionRate {
+			return fmt.Errorf("%v: the tasks of %s would collect %g times per second where max_collection_rate is %g", core.ErrQuotaExceeded, o.name, total, q.MaxCollectionRate)
+		}
+		if q.MaxMetrics > 0 && (mq.max == 0 || q.MaxMetrics < mq.max) {
+			mq = metricQuota{max: q.MaxMetrics, owner: o.name}
+		}
+	}
+	mts, _ := s.gatherMetricsAndPlugins(wf)
+	if err := mq.check(len(mts)); err != nil {
+		return err
+	}
+	wf.metricQuota = mq
+	return nil
+}
+
+// SetPrincipalQuotas sets the quotas of the principals of the API, by name,
+// bounding the tasks they create from then on
+func (s *scheduler) SetPrincipalQuotas(quotas map[string]*core.Quota) {
+	s.principalQuotas = quotas
+	schedulerLogger.WithFields(log.Fields{
+		"_block":     "set-principal-quotas",
+		"principals": len(quotas),
+	}).Debug("principal quotas set")
+}
@@ -0,0 +1,138 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+func TestQuotas(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	Convey("Given a tenant and a principal with quotas", t, func() {
+		c := &mockMetricManager{
+			tenants: map[string]string{"team-a": "/intel"},
+			quotas:  map[string]*core.Quota{"team-a": {MaxTasks: 2, MaxCollectionRate: 1.5}},
+		}
+		s := New(GetDefaultConfig())
+		s.SetMetricManager(c)
+		s.SetPrincipalQuotas(map[string]*core.Quota{"ci": {MaxTasks: 1}})
+		So(s.Start(), ShouldBeNil)
+		w := wmap.NewWorkflowMap()
+		w.CollectNode.AddMetric("/intel/mock/foo", 1)
+		create := func(interval time.Duration, opts ...core.TaskOption) (core.Task, core.TaskErrors) {
+			return s.CreateTask(schedule.NewSimpleSchedule(interval), w, false, opts...)
+		}
+
+		Convey("the tasks of the tenant are counted", func() {
+			_, te := create(2*time.Second, core.OptionTaskTenant("team-a"))
+			So(te.Errors(), ShouldBeEmpty)
+			tsk, te := create(2*time.Second, core.OptionTaskTenant("team-a"))
+			So(te.Errors(), ShouldBeEmpty)
+			_, te = create(2*time.Second, core.OptionTaskTenant("team-a"))
+			So(te.Errors(), ShouldHaveLength, 1)
+			So(te.Errors()[0].Error(), ShouldStartWith, core.ErrQuotaExceeded.Error())
+			So(te.Errors()[0].Error(), ShouldContainSubstring, "tenant team-a already has 2 task(s)")
+
+			Convey("and so are their collections per second", func() {
+				_, te := s.UpdateTask(tsk.ID(), core.TaskUpdate{Interval: time.Second})
+				So(te.Errors(), ShouldBeEmpty)
+				_, te = s.UpdateTask(tsk.ID(), core.TaskUpdate{Interval: 700 * time.Millisecond})
+				So(te.Errors(), ShouldHaveLength, 1)
+				So(te.Errors()[0].Error(), ShouldStartWith, core.ErrQuotaExceeded.Error())
+			})
+		})
+		Convey("a task over the quota on its own is refused", func() {
+			_, te := create(500*time.Millisecond, core.OptionTaskTenant("team-a"))
+			So(te.Errors(), ShouldHaveLength, 1)
+			So(te.Errors()[0].Error(), ShouldStartWith, core.ErrTaskOverQuota.Error())
+		})
+		Convey("the tasks of the principal are counted", func() {
+			_, te := create(time.Second, core.OptionTaskCreatedBy("ci"))
+			So(te.Errors(), ShouldBeEmpty)
+			_, te = create(time.Second, core.OptionTaskCreatedBy("ci"))
+			So(te.Errors(), ShouldHaveLength, 1)
+			So(te.Errors()[0].Error(), ShouldContainSubstring, "principal ci already has 1 task(s)")
+			_, te = create(time.Second, core.OptionTaskCreatedBy("ops"))
+			So(te.Errors(), ShouldBeEmpty)
+		})
+		Convey("a task restored over the quota stays deleted", func() {
+			tsk, te := create(time.Second, core.OptionTaskCreatedBy("ci"))
+			So(te.Errors(), ShouldBeEmpty)
+			So(s.RemoveTask(tsk.ID()), ShouldBeNil)
+			_, te = create(time.Second, core.OptionTaskCreatedBy("ci"))
+			So(te.Errors(), ShouldBeEmpty)
+			_, err := s.RestoreTask(tsk.ID())
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, core.ErrQuotaExceeded.Error())
+			So(s.GetTasks(), ShouldHaveLength, 1)
+			d, err := s.GetDeletedTask(tsk.ID())
+			So(err, ShouldBeNil)
+			So(d.Task.State(), ShouldEqual, core.TaskDeleted)
+		})
+		Convey("concurrent creations do not both pass max_tasks", func() {
+			var wg sync.WaitGroup
+			start := make(chan struct{})
+			refused := make(chan int, 10)
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					<-start
+					_, te := create(time.Second, core.OptionTaskCreatedBy("ci"))
+					refused <- len(te.Errors())
+				}()
+			}
+			close(start)
+			wg.Wait()
+			close(refused)
+			created := 0
+			for n := range refused {
+				if n == 0 {
+					created++
+				}
+			}
+			So(created, ShouldEqual, 1)
+		})
+	})
+	Convey("A collector job checks the metrics expanded against its quota", t, func() {
+		mts := []core.RequestedMetric{&metric{namespace: []string{"intel", "mock", "*", "foo"}}}
+		cj := newCollectorJob(mts, defaultDeadline, &expandingCollector{}, cdata.NewTree(), "taskid", core.TaskPriorityNormal)
+		cj.(*collectorJob).metricQuota = metricQuota{max: 50, owner: "tenant team-a"}
+		cj.(*collectorJob).Run()
+		So(cj.Errors(), ShouldHaveLength, 1)
+		So(cj.Errors()[0].Error(), ShouldEqual, "Task exceeds the quota on its own: the task collects 100 metrics where max_metrics of tenant team-a is 50")
+		So(cj.(*collectorJob).metrics, ShouldBeEmpty)
+
+		cj = newCollectorJob(mts, defaultDeadline, &expandingCollector{}, cdata.NewTree(), "taskid", core.TaskPriorityNormal)
+		cj.(*collectorJob).metricQuota = metricQuota{max: 100, owner: "tenant team-a"}
+		cj.(*collectorJob).Run()
+		So(cj.Errors(), ShouldBeEmpty)
+	})
+}
@@ -0,0 +1,134 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// DefaultDeletedTaskRetention is how long deleted tasks are kept by default
+// before they are purged
+const DefaultDeletedTaskRetention = 24 * time.Hour
+
+// deletedTaskPurgeInterval is how often the deleted tasks whose retention
+// elapsed are purged, besides when tasks are deleted or restored
+var deletedTaskPurgeInterval = time.Minute
+
+// deletedTask is a task in the recycle bin
+type deletedTask struct {
+	task       *task
+	deleteTime time.Time
+}
+
+// recycleBin holds the deleted tasks for their retention, for deletions to be
+// undone
+type recycleBin struct {
+	*sync.Mutex
+
+	retention time.Duration
+	table     map[string]*deletedTask
+}
+
+func newRecycleBin(retention time.Duration) *recycleBin {
+	return &recycleBin{
+		Mutex: &sync.Mutex{},
+
+		retention: retention,
+		table:     make(map[string]*deletedTask),
+	}
+}
+
+// add puts a deleted task in the bin, unless the bin retains nothing in
+// which case false is returned
+func (b *recycleBin) add(t *task, at time.Time) bool {
+	if b.retention <= 0 {
+		return false
+	}
+	b.Lock()
+	defer b.Unlock()
+	b.table[t.id] = &deletedTask{task: t, deleteTime: at}
+	return true
+}
+
+// put puts back a deleted task taken from the bin
+func (b *recycleBin) put(d *deletedTask) {
+	b.Lock()
+	defer b.Unlock()
+	b.table[d.task.id] = d
+}
+
+// get returns the deleted task with the given id, or nil
+func (b *recycleBin) get(id string) *deletedTask {
+	b.Lock()
+	defer b.Unlock()
+	return b.table[id]
+}
+
+// take removes the deleted task with the given id from the bin and returns
+// it, or nil
+func (b *recycleBin) take(id string) *deletedTask {
+	b.Lock()
+	defer b.Unlock()
+	d, ok := b.table[id]
+	if ok {
+		delete(b.table, id)
+	}
+	return d
+}
+
+// list returns the deleted tasks
+func (b *recycleBin) list() []*deletedTask {
+	b.Lock()
+	defer b.Unlock()
+	ds := make([]*deletedTask, 0, len(b.table))
+	for _, d := range b.table {
+		ds = append(ds, d)
+	}
+	return ds
+}
+
+// purge removes the tasks whose retention has elapsed at now and returns them
+func (b *recycleBin) purge(now time.Time) []*task {
+	b.Lock()
+	defer b.Unlock()
+	var purged []*task
+	for id, d := range b.table {
+		if !now.Before(d.purgeTime(b.retention)) {
+			purged = append(purged, d.task)
+			delete(b.table, id)
+		}
+	}
+	return purged
+}
+
+func (d *deletedTask) purgeTime(retention time.Duration) time.Time {
+	return d.deleteTime.Add(retention)
+}
+
+func (d *deletedTask) core(retention time.Duration) core.DeletedTask {
+	return core.DeletedTask{
+		Task:       d.task,
+		DeleteTime: d.deleteTime,
+		PurgeTime:  d.purgeTime(retention),
+	}
+}
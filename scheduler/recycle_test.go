@@ -0,0 +1,123 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/chrono"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/vrischmann/jsonutil"
+)
+
+func TestRecycleBin(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	defer func(interval time.Duration) { deletedTaskPurgeInterval = interval }(deletedTaskPurgeInterval)
+	deletedTaskPurgeInterval = 10 * time.Millisecond
+	Convey("Deleting a task", t, func() {
+		defer chrono.Chrono.Reset()
+		defer chrono.Chrono.Continue()
+		chrono.Chrono.Pause()
+
+		c := new(mockMetricManager)
+		c.setAcceptedContentType("file", core.PublisherPluginType, -1, []string{"snap.json"})
+		cfg := GetDefaultConfig()
+		cfg.DeletedTaskRetention = jsonutil.Duration{time.Hour}
+		s := New(cfg)
+		s.SetMetricManager(c)
+		So(s.Start(), ShouldBeNil)
+		defer s.Stop()
+
+		w := wmap.NewWorkflowMap()
+		w.CollectNode.AddMetric("/foo/bar", 1)
+		w.CollectNode.Add(wmap.NewPublishNode("file", -1))
+		tsk, te := s.CreateTask(schedule.NewSimpleSchedule(time.Hour), w, false)
+		So(te.Errors(), ShouldBeEmpty)
+		So(s.RemoveTask(tsk.ID()), ShouldBeNil)
+
+		Convey("moves it to the recycle bin", func() {
+			_, err := s.GetTask(tsk.ID())
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, ErrTaskNotFound.Error())
+			d, err := s.GetDeletedTask(tsk.ID())
+			So(err, ShouldBeNil)
+			So(d.Task.State(), ShouldEqual, core.TaskDeleted)
+			So(d.PurgeTime.Sub(d.DeleteTime), ShouldEqual, time.Hour)
+			So(s.GetDeletedTasks(), ShouldHaveLength, 1)
+		})
+		Convey("can be undone", func() {
+			r, err := s.RestoreTask(tsk.ID())
+			So(err, ShouldBeNil)
+			So(r.State(), ShouldEqual, core.TaskStopped)
+			_, err = s.GetTask(tsk.ID())
+			So(err, ShouldBeNil)
+			So(s.GetDeletedTasks(), ShouldBeEmpty)
+			_, err = s.RestoreTask(tsk.ID())
+			So(err, ShouldEqual, ErrTaskNotFound)
+		})
+		Convey("is not undone when a task of the same ID exists, and stays in the bin", func() {
+			So(s.tasks.add(tsk.(*task)), ShouldBeNil)
+			_, err := s.RestoreTask(tsk.ID())
+			So(err, ShouldNotBeNil)
+			d, err := s.GetDeletedTask(tsk.ID())
+			So(err, ShouldBeNil)
+			So(d.Task.State(), ShouldEqual, core.TaskDeleted)
+		})
+		Convey("purges it once its retention elapses", func() {
+			chrono.Chrono.Forward(time.Hour)
+			_, err := s.GetDeletedTask(tsk.ID())
+			So(err, ShouldEqual, ErrTaskNotFound)
+			_, err = s.RestoreTask(tsk.ID())
+			So(err, ShouldEqual, ErrTaskNotFound)
+		})
+		Convey("purges it periodically once its retention elapses", func() {
+			chrono.Chrono.Forward(time.Hour)
+			deadline := time.Now().Add(5 * time.Second)
+			for len(s.deletedTasks.list()) > 0 && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			So(s.deletedTasks.list(), ShouldBeEmpty)
+		})
+	})
+	Convey("Deleting a task without retention purges it", t, func() {
+		c := new(mockMetricManager)
+		c.setAcceptedContentType("file", core.PublisherPluginType, -1, []string{"snap.json"})
+		cfg := GetDefaultConfig()
+		cfg.DeletedTaskRetention = jsonutil.Duration{0}
+		s := New(cfg)
+		s.SetMetricManager(c)
+		So(s.Start(), ShouldBeNil)
+
+		w := wmap.NewWorkflowMap()
+		w.CollectNode.AddMetric("/foo/bar", 1)
+		w.CollectNode.Add(wmap.NewPublishNode("file", -1))
+		tsk, te := s.CreateTask(schedule.NewSimpleSchedule(time.Hour), w, false)
+		So(te.Errors(), ShouldBeEmpty)
+		So(s.RemoveTask(tsk.ID()), ShouldBeNil)
+		So(s.GetDeletedTasks(), ShouldBeEmpty)
+		_, err := s.RestoreTask(tsk.ID())
+		So(err, ShouldEqual, ErrTaskNotFound)
+	})
+}
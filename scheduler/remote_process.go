@@ -0,0 +1,212 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
+	"github.com/intelsdi-x/snap/scheduler/builtin"
+)
+
+// remoteProcessTimeout bounds the time another snapd takes to process a
+// batch of metrics
+const remoteProcessTimeout = 30 * time.Second
+
+var (
+	// ErrRemoteNeedsTribe - The error message for a process node run by a tribe member when tribe is not enabled
+	ErrRemoteNeedsTribe = errors.New("A remote processor named after a tribe member needs tribe to be enabled")
+	// ErrRemoteURL - The error message for a process node run by another snapd given a URL snap does not support
+	ErrRemoteURL = errors.New("The URL of a remote processor must be in the format of http(s)://<host>:<port>")
+	// ErrRemoteCredentials - The error message for a process node run by another snapd given a URL with credentials
+	ErrRemoteCredentials = errors.New("The URL of a remote processor cannot carry credentials, they are set in scheduler.remote_passwords")
+)
+
+// remoteProcessor has the processor of a process node run by another snapd,
+// e.g. to offload heavy processing from an edge node to an aggregator. The
+// other snapd is given by the URL of its REST API or by the name of a tribe
+// member, which is resolved on each batch so that the node follows the
+// member when its address changes.
+type remoteProcessor struct {
+	remote   string
+	resolver core.MemberResolver
+	// passwords are the passwords of the snapds given by URL
+	passwords map[string]string
+
+	mutex  sync.Mutex
+	snapd  core.RemoteSnapd
+	client *http.Client
+}
+
+// newRemoteProcessor returns the remote processor of a process node, checking
+// the URL it is given if any
+func newRemoteProcessor(remote string) (*remoteProcessor, error) {
+	if isRemoteURL(remote) {
+		u, err := url.Parse(remote)
+		if err == nil && u.User != nil {
+			return nil, ErrRemoteCredentials
+		}
+		if _, err := core.ParseRemoteSnapd(remote); err != nil {
+			return nil, fmt.Errorf("%v: %s", ErrRemoteURL, remote)
+		}
+	}
+	return &remoteProcessor{remote: remote}, nil
+}
+
+// isRemoteURL returns true if the remote of a process node is a URL rather
+// than the name of a tribe member
+func isRemoteURL(remote string) bool {
+	return strings.Contains(remote, "://")
+}
+
+// setResolver sets what resolves the name of the tribe member running the
+// processor, which is required unless the processor is given by a URL
+func (r *remoteProcessor) setResolver(resolver core.MemberResolver) error {
+	if !isRemoteURL(r.remote) && resolver == nil {
+		return fmt.Errorf("%v: %s", ErrRemoteNeedsTribe, r.remote)
+	}
+	r.resolver = resolver
+	return nil
+}
+
+// resolve returns how to reach the snapd running the processor, with the
+// password set for its URL if given by URL
+func (r *remoteProcessor) resolve() (core.RemoteSnapd, error) {
+	if !isRemoteURL(r.remote) {
+		if r.resolver == nil {
+			return core.RemoteSnapd{}, fmt.Errorf("%v: %s", ErrRemoteNeedsTribe, r.remote)
+		}
+		return r.resolver.ResolveMember(r.remote)
+	}
+	snapd, err := core.ParseRemoteSnapd(r.remote)
+	if err != nil {
+		return snapd, err
+	}
+	snapd.Password = r.passwords[snapd.URL]
+	return snapd, nil
+}
+
+// getClient returns the HTTP client of the snapd running the processor,
+// made again when the snapd resolves differently
+func (r *remoteProcessor) getClient() (core.RemoteSnapd, *http.Client, error) {
+	snapd, err := r.resolve()
+	if err != nil {
+		return snapd, nil, err
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.client == nil || !r.snapd.Equal(snapd) {
+		r.snapd = snapd
+		r.client = builtin.NewSnapdClient(snapd, remoteProcessTimeout)
+	}
+	return snapd, r.client, nil
+}
+
+// ProcessMetrics has the other snapd process the content with its processor
+func (r *remoteProcessor) ProcessMetrics(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, taskID string) (string, []byte, []error) {
+	ct, content, err := r.process(contentType, content, pluginName, pluginVersion, config, taskID)
+	if err != nil {
+		return "", nil, []error{fmt.Errorf("remote processor %s: %v", r.remote, err)}
+	}
+	return ct, content, nil
+}
+
+func (r *remoteProcessor) process(contentType string, content []byte, pluginName string, pluginVersion int, config map[string]ctypes.ConfigValue, taskID string) (string, []byte, error) {
+	snapd, c, err := r.getClient()
+	if err != nil {
+		return "", nil, err
+	}
+	body, err := builtin.PostSnapd(c, snapd, fmt.Sprintf("/v1/plugins/processor/%s/%d/process", url.QueryEscape(pluginName), pluginVersion), &request.ProcessRequest{
+		TaskID:      taskID,
+		ContentType: contentType,
+		Content:     content,
+		Config:      cdata.FromTable(config),
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	pp, ok := body.(*rbody.PluginProcessed)
+	if !ok {
+		return "", nil, fmt.Errorf("unexpected response of type %s", body.ResponseBodyType())
+	}
+	return pp.ContentType, pp.Content, nil
+}
+
+// remoteProcessNodes returns the process nodes of the workflow run by
+// another snapd
+func (s *schedulerWorkflow) remoteProcessNodes() []*processNode {
+	var walk func(prs []*processNode) []*processNode
+	walk = func(prs []*processNode) []*processNode {
+		var nodes []*processNode
+		for _, pr := range prs {
+			if pr.remote != nil {
+				nodes = append(nodes, pr)
+			}
+			nodes = append(nodes, walk(pr.ProcessNodes)...)
+		}
+		return nodes
+	}
+	return walk(s.processNodes)
+}
+
+// setRemotePasswords sets the passwords of the snapds running the
+// processors of the workflow given by URL
+func (s *schedulerWorkflow) setRemotePasswords(passwords map[string]string) {
+	for _, pr := range s.remoteProcessNodes() {
+		pr.remote.passwords = passwords
+	}
+}
+
+// setMemberResolver sets what resolves the tribe members running the
+// processors of the workflow, or the built-in publishers reach
+func (s *schedulerWorkflow) setMemberResolver(resolver core.MemberResolver) error {
+	for _, pr := range s.remoteProcessNodes() {
+		if err := pr.remote.setResolver(resolver); err != nil {
+			return err
+		}
+	}
+	var walk func(pus []*publishNode, prs []*processNode) error
+	walk = func(pus []*publishNode, prs []*processNode) error {
+		for _, pu := range pus {
+			if mr, ok := pu.builtin.(builtin.MemberResolving); ok {
+				if err := mr.SetMemberResolver(resolver); err != nil {
+					return err
+				}
+			}
+		}
+		for _, pr := range prs {
+			if err := walk(pr.PublishNodes, pr.ProcessNodes); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(s.publishNodes, s.processNodes)
+}
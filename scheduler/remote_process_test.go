@@ -0,0 +1,155 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/mgmt/rest/rbody"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+type mockMemberResolver map[string]core.RemoteSnapd
+
+func (m mockMemberResolver) ResolveMember(name string) (core.RemoteSnapd, error) {
+	if r, ok := m[name]; ok {
+		return r, nil
+	}
+	return core.RemoteSnapd{}, errors.New("Unknown member")
+}
+
+func respondProcessed(w http.ResponseWriter, code int, b rbody.Body) {
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(&rbody.APIResponse{
+		Meta: &rbody.APIResponseMeta{Code: code, Type: b.ResponseBodyType()},
+		Body: b,
+	})
+}
+
+func TestRemoteProcessor(t *testing.T) {
+	Convey("Given a snapd processing the metrics offloaded by tasks", t, func() {
+		var received *request.ProcessRequest
+		var password string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, password, _ = r.BasicAuth()
+			if r.URL.Path != "/v1/plugins/processor/passthru/1/process" {
+				respondProcessed(w, 404, &rbody.Error{ErrorMessage: "plugin not found"})
+				return
+			}
+			received = &request.ProcessRequest{}
+			json.NewDecoder(r.Body).Decode(received)
+			respondProcessed(w, 200, &rbody.PluginProcessed{
+				ContentType: received.ContentType,
+				Content:     []byte(strings.ToUpper(string(received.Content))),
+			})
+		}))
+		defer srv.Close()
+		config := map[string]ctypes.ConfigValue{"threshold": ctypes.ConfigValueInt{Value: 10}}
+
+		Convey("a remote processor given by URL has it process the content", func() {
+			rp, err := newRemoteProcessor(srv.URL)
+			So(err, ShouldBeNil)
+			So(rp.setResolver(nil), ShouldBeNil)
+			rp.passwords = map[string]string{srv.URL: "secret"}
+			ct, content, errs := rp.ProcessMetrics("test", []byte("abc"), "passthru", 1, config, "task1")
+			So(errs, ShouldBeEmpty)
+			So(ct, ShouldEqual, "test")
+			So(string(content), ShouldEqual, "ABC")
+			So(received.TaskID, ShouldEqual, "task1")
+			So(received.Config.Table()["threshold"], ShouldResemble, ctypes.ConfigValueInt{Value: 10})
+			So(password, ShouldEqual, "secret")
+		})
+		Convey("a remote processor given by tribe member resolves it", func() {
+			rp, err := newRemoteProcessor("aggregator")
+			So(err, ShouldBeNil)
+			So(rp.setResolver(mockMemberResolver{"aggregator": {URL: srv.URL, Password: "tribe"}}), ShouldBeNil)
+			_, content, errs := rp.ProcessMetrics("test", []byte("abc"), "passthru", 1, config, "task1")
+			So(errs, ShouldBeEmpty)
+			So(string(content), ShouldEqual, "ABC")
+			So(password, ShouldEqual, "tribe")
+			Convey("and fails when it is not a member", func() {
+				rp.remote = "edge"
+				_, _, errs := rp.ProcessMetrics("test", []byte("abc"), "passthru", 1, config, "task1")
+				So(errs, ShouldHaveLength, 1)
+				So(errs[0].Error(), ShouldContainSubstring, "Unknown member")
+			})
+		})
+		Convey("a remote processor returns the error of the snapd", func() {
+			rp, err := newRemoteProcessor(srv.URL)
+			So(err, ShouldBeNil)
+			_, _, errs := rp.ProcessMetrics("test", []byte("abc"), "missing", 1, config, "task1")
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Error(), ShouldContainSubstring, "plugin not found")
+		})
+		Convey("a remote processor given by tribe member needs tribe", func() {
+			rp, err := newRemoteProcessor("aggregator")
+			So(err, ShouldBeNil)
+			err = rp.setResolver(nil)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, ErrRemoteNeedsTribe.Error())
+		})
+		Convey("a remote processor given by a URL with credentials is refused", func() {
+			_, err := newRemoteProcessor(strings.Replace(srv.URL, "http://", "http://snap:secret@", 1))
+			So(err, ShouldEqual, ErrRemoteCredentials)
+		})
+		Convey("a remote processor given by an unsupported URL is refused", func() {
+			_, err := newRemoteProcessor("ftp://aggregator:8181")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, ErrRemoteURL.Error())
+		})
+	})
+}
+
+func TestRemoteProcessNodes(t *testing.T) {
+	Convey("Given a workflow with a remote process node", t, func() {
+		wfMap := wmap.NewWorkflowMap()
+		wfMap.CollectNode.AddMetric("/foo/bar", 1)
+		pr := wmap.NewProcessNode("heavy", 1)
+		pr.Remote = "aggregator"
+		pu := wmap.NewPublishNode("file", 1)
+		pr.Add(pu)
+		wfMap.CollectNode.Add(pr)
+		wf, err := wmapToWorkflow(wfMap)
+		So(err, ShouldBeNil)
+		So(wf.remoteProcessNodes(), ShouldHaveLength, 1)
+
+		Convey("the processor is not a plugin of this snapd", func() {
+			s := &scheduler{}
+			var plugins []core.SubscribedPlugin
+			s.walkWorkflow(wf.processNodes, wf.publishNodes, &plugins)
+			So(plugins, ShouldHaveLength, 1)
+			So(plugins[0].Name(), ShouldEqual, "file")
+		})
+		Convey("the tribe member running it is resolved", func() {
+			So(wf.setMemberResolver(nil), ShouldNotBeNil)
+			So(wf.setMemberResolver(mockMemberResolver{}), ShouldBeNil)
+		})
+	})
+}
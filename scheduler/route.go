@@ -0,0 +1,101 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+// compileRoutes compiles the namespaces routing metrics to a workflow node
+func compileRoutes(routes []string) ([]*core.WildcardNamespace, error) {
+	if len(routes) == 0 {
+		return nil, nil
+	}
+	wns := make([]*core.WildcardNamespace, len(routes))
+	for i, r := range routes {
+		if !strings.HasPrefix(r, "/") {
+			return nil, fmt.Errorf("Invalid route %s: namespace must start with '/'", r)
+		}
+		wn, err := core.CompileWildcardNamespace(strings.Split(r, "/")[1:])
+		if err != nil {
+			return nil, err
+		}
+		wns[i] = wn
+	}
+	return wns, nil
+}
+
+// routed returns true if the namespace matches one of the routes
+func routed(ns []string, routes []*core.WildcardNamespace) bool {
+	for _, r := range routes {
+		if r.Match(ns) {
+			return true
+		}
+	}
+	return false
+}
+
+// routeJob returns a copy of the parent job holding only the metrics matching
+// the routes of a node, or nil when none of them match. The parent job is
+// returned as is when the node has no routes or all its metrics match them.
+func routeJob(pj job, routes []*core.WildcardNamespace) (job, error) {
+	if len(routes) == 0 {
+		return pj, nil
+	}
+	b, err := jobBatch(pj)
+	if err != nil {
+		return nil, fmt.Errorf("cannot route metrics of a %s job", pj.TypeString())
+	}
+	rb, err := b.route(routes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot route the content of %s %s: %v", pj.TypeString(), pj.Name(), err)
+	}
+	if rb == nil {
+		return nil, nil
+	}
+	if rb == b {
+		return pj, nil
+	}
+	metrics, _ := rb.Metrics()
+	switch pt := pj.(type) {
+	case *collectorJob:
+		return &collectorJob{
+			coreJob: pt.coreJob,
+			metrics: metrics,
+			batch:   rb,
+		}, nil
+	case *processJob:
+		// the routed content is in the content type the processor returned
+		content, err := rb.Encode(b.contentType)
+		if err != nil {
+			return nil, err
+		}
+		return &processJob{
+			coreJob:     pt.coreJob,
+			contentType: b.contentType,
+			content:     content,
+			batch:       rb,
+		}, nil
+	}
+	return nil, fmt.Errorf("cannot route metrics of a %s job", pj.TypeString())
+}
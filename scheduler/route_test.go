@@ -0,0 +1,104 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRouteJob(t *testing.T) {
+	disk := plugin.PluginMetricType{Namespace_: []string{"intel", "disk", "sda", "reads"}}
+	net := plugin.PluginMetricType{Namespace_: []string{"intel", "net", "eth0", "bytes"}}
+
+	Convey("compileRoutes", t, func() {
+		Convey("compiles namespaces", func() {
+			routes, err := compileRoutes([]string{"/intel/disk/*", "/intel/net/!(lo)/*"})
+			So(err, ShouldBeNil)
+			So(routes, ShouldHaveLength, 2)
+		})
+		Convey("rejects a namespace without a leading '/'", func() {
+			_, err := compileRoutes([]string{"intel/disk/*"})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("rejects a malformed namespace", func() {
+			_, err := compileRoutes([]string{"/intel/(disk"})
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("routeJob", t, func() {
+		routes, err := compileRoutes([]string{"/intel/disk/*"})
+		So(err, ShouldBeNil)
+
+		Convey("returns the parent job when the node has no routes", func() {
+			cj := &collectorJob{coreJob: &coreJob{}, metrics: []core.Metric{disk, net}}
+			j, err := routeJob(cj, nil)
+			So(err, ShouldBeNil)
+			So(j, ShouldEqual, cj)
+		})
+		Convey("routes the metrics of a collector job", func() {
+			cj := &collectorJob{coreJob: &coreJob{}, metrics: []core.Metric{disk, net}}
+			j, err := routeJob(cj, routes)
+			So(err, ShouldBeNil)
+			So(j.(*collectorJob).metrics, ShouldResemble, []core.Metric{disk})
+			So(cj.metrics, ShouldHaveLength, 2)
+		})
+		Convey("returns nil when no metric is routed", func() {
+			cj := &collectorJob{coreJob: &coreJob{}, metrics: []core.Metric{net}}
+			j, err := routeJob(cj, routes)
+			So(err, ShouldBeNil)
+			So(j, ShouldBeNil)
+		})
+		Convey("routes the content of a process job", func() {
+			var buf bytes.Buffer
+			So(gob.NewEncoder(&buf).Encode([]plugin.PluginMetricType{disk, net}), ShouldBeNil)
+			pj := &processJob{coreJob: &coreJob{}, content: buf.Bytes()}
+			j, err := routeJob(pj, routes)
+			So(err, ShouldBeNil)
+			var mts []plugin.PluginMetricType
+			So(gob.NewDecoder(bytes.NewReader(j.(*processJob).content)).Decode(&mts), ShouldBeNil)
+			So(mts, ShouldHaveLength, 1)
+			So(mts[0].Namespace(), ShouldResemble, disk.Namespace())
+		})
+		Convey("routes the content of a process job in its content type", func() {
+			content, err := core.EncodeMetrics(plugin.SnapJSONContentType, []core.Metric{disk, net})
+			So(err, ShouldBeNil)
+			pj := &processJob{coreJob: &coreJob{}, contentType: plugin.SnapJSONContentType, content: content}
+			j, err := routeJob(pj, routes)
+			So(err, ShouldBeNil)
+			So(j.(*processJob).contentType, ShouldEqual, plugin.SnapJSONContentType)
+			mts, err := core.DecodeMetrics(plugin.SnapJSONContentType, j.(*processJob).content)
+			So(err, ShouldBeNil)
+			So(mts, ShouldHaveLength, 1)
+			So(mts[0].Namespace(), ShouldResemble, disk.Namespace())
+		})
+		Convey("returns an error when the content of a process job is not decodable", func() {
+			pj := &processJob{coreJob: &coreJob{}, content: []byte("foo")}
+			_, err := routeJob(pj, routes)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -23,16 +23,21 @@ import (
 	"errors"
 	"fmt"
 	// "strings"
+	"sync"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/intelsdi-x/gomit"
 
 	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/cdata"
+	"github.com/intelsdi-x/snap/core/control_event"
 	"github.com/intelsdi-x/snap/core/ctypes"
 	"github.com/intelsdi-x/snap/core/scheduler_event"
 	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/pkg/chrono"
 	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/pkg/tlspolicy"
 	"github.com/intelsdi-x/snap/scheduler/wmap"
 )
 
@@ -55,6 +60,8 @@ var (
 	ErrTaskAlreadyStopped = errors.New("Task is already stopped.")
 	// ErrTaskDisabledNotRunnable - The error message for task is disabled and cannot be started
 	ErrTaskDisabledNotRunnable = errors.New("Task is disabled. Cannot be started.")
+	// ErrQueryMatchesNoMetrics - The error message for a metric query in a workflow which matches no metrics
+	ErrQueryMatchesNoMetrics = errors.New("Metric query matches no metrics.")
 )
 
 type schedulerState int
@@ -75,6 +82,13 @@ type managesMetrics interface {
 	SubscribeDeps(string, []core.Metric, []core.Plugin) []serror.SnapError
 	UnsubscribeDeps(string, []core.Metric, []core.Plugin) []serror.SnapError
 	MatchQueryToNamespaces([]string) ([][]string, serror.SnapError)
+	QueryMetrics(*core.MetricQuery) ([]core.CatalogedMetric, error)
+	CollectorsReady(string) error
+	ConfigLayers(core.RequestedMetric) ([]cdata.ConfigLayer, error)
+	SetTaskTenant(taskID, tenant string) error
+	SetMetricRefreshInterval(taskID string, interval time.Duration)
+	MetricVisible(tenant string, ns []string) bool
+	TenantQuota(tenant string) *core.Quota
 }
 
 // ManagesPluginContentTypes is an interface to a plugin manager that can tell us what content accept and returns are supported.
@@ -102,6 +116,33 @@ type scheduler struct {
 	state           schedulerState
 	eventManager    *gomit.EventController
 	taskWatcherColl *taskWatcherCollection
+	taskRunHistory  uint
+	// deletedTasks holds the deleted tasks until they are purged
+	deletedTasks *recycleBin
+	// stopPurge stops the periodic purge of the deleted tasks
+	stopPurge chan struct{}
+	sharder   core.Sharder
+	// memberResolver resolves the tribe members running remote processors
+	memberResolver core.MemberResolver
+	// remotePasswords are the passwords of the snapds running remote
+	// processors by URL
+	remotePasswords map[string]string
+	// walDir is where the write-ahead logs of the wal back-pressure policy
+	// are written
+	walDir string
+	// fileDir is the directory the built-in file publishers write under
+	fileDir string
+	// tlsPolicy restricts the TLS connections of the built-in publishers
+	tlsPolicy   *tlspolicy.Config
+	maintenance maintenance
+	// aggregator republishes the metrics the other members of the tribe
+	// forward to snapd, nil unless snapd has the aggregator role
+	aggregator *aggregator
+	// principalQuotas are the quotas of the principals of the API by name
+	principalQuotas map[string]*core.Quota
+	// quotaMutex makes checking the quotas of a task and adding it one step,
+	// so that concurrent creations cannot both pass max_tasks
+	quotaMutex sync.Mutex
 }
 
 type managesWork interface {
@@ -120,6 +161,14 @@ func New(cfg *Config) *scheduler {
 		"_block": "New",
 		"value":  cfg.WorkManagerPoolSize,
 	}).Info("Setting work manager pool size")
+	schedulerLogger.WithFields(log.Fields{
+		"_block": "New",
+		"value":  cfg.TaskRunHistory,
+	}).Info("Setting task run history size")
+	schedulerLogger.WithFields(log.Fields{
+		"_block": "New",
+		"value":  cfg.DeletedTaskRetention.Duration,
+	}).Info("Setting deleted task retention")
 	opts := []workManagerOption{
 		CollectQSizeOption(cfg.WorkManagerQueueSize),
 		CollectWkrSizeOption(cfg.WorkManagerPoolSize),
@@ -132,6 +181,26 @@ func New(cfg *Config) *scheduler {
 		tasks:           newTaskCollection(),
 		eventManager:    gomit.NewEventController(),
 		taskWatcherColl: newTaskWatcherCollection(),
+		taskRunHistory:  cfg.TaskRunHistory,
+		deletedTasks:    newRecycleBin(cfg.DeletedTaskRetention.Duration),
+		remotePasswords: cfg.RemotePasswords,
+	}
+	if cfg.Aggregator != nil && cfg.Aggregator.Enable {
+		a, err := newAggregator(cfg.Aggregator)
+		if err != nil {
+			schedulerLogger.WithFields(log.Fields{
+				"_block": "New",
+				"_error": err.Error(),
+			}).Error("aggregator role disabled")
+		} else {
+			schedulerLogger.WithFields(log.Fields{
+				"_block":     "New",
+				"interval":   cfg.Aggregator.Interval.Duration,
+				"dedup":      cfg.Aggregator.Dedup,
+				"downsample": cfg.Aggregator.Downsample,
+			}).Info("Setting aggregator role")
+			s.aggregator = a
+		}
 	}
 
 	// we are setting the size of the queue and number of workers for
@@ -173,63 +242,62 @@ func (s *scheduler) createTask(sch schedule.Schedule, wfMap *wmap.WorkflowMap, s
 		"_block": "create-task",
 		"source": source,
 	})
-	// Create a container for task errors
-	te := &taskErrors{
-		errs: make([]serror.SnapError, 0),
+	wf, te := s.validateTask(sch, wfMap, logger)
+	if len(te.errs) > 0 {
+		return nil, te
 	}
 
-	// Return error if we are not started.
-	if s.state != schedulerStarted {
-		te.errs = append(te.errs, serror.New(ErrSchedulerNotStarted))
+	// Create the task object
+	task := newTask(sch, wf, s.workManager, s.metricManager, s.eventManager, opts...)
+	task.runs = newRunHistory(s.taskRunHistory)
+	task.sharder = s.sharder
+	if err := s.validateDependencies(task); err != nil {
+		te.errs = append(te.errs, serror.New(err))
 		f := buildErrorsLog(te.Errors(), logger)
-		f.Error("scheduler not started")
+		f.Error("invalid task dependencies")
 		return nil, te
 	}
-
-	// Ensure the schedule is valid at this point and time.
-	if err := sch.Validate(); err != nil {
+	if err := s.validateTenant(task, wf); err != nil {
 		te.errs = append(te.errs, serror.New(err))
 		f := buildErrorsLog(te.Errors(), logger)
-		f.Error("schedule passed not valid")
+		f.Error("task out of the scope of its tenant")
 		return nil, te
 	}
-
-	// Generate a workflow from the workflow map
-	wf, err := wmapToWorkflow(wfMap)
-	if err != nil {
+	s.quotaMutex.Lock()
+	if err := s.applyQuotas(task, sch, wf); err != nil {
+		s.quotaMutex.Unlock()
 		te.errs = append(te.errs, serror.New(err))
 		f := buildErrorsLog(te.Errors(), logger)
-		f.Error(ErrSchedulerNotStarted.Error())
+		f.Error("task over quota")
 		return nil, te
 	}
-
-	// validate plugins and metrics
-	mts, plugins := s.gatherMetricsAndPlugins(wf)
-	errs := s.metricManager.ValidateDeps(mts, plugins)
-	if len(errs) > 0 {
-		te.errs = append(te.errs, errs...)
+	if err := wf.setWAL(s.walDir, task.id); err != nil {
+		s.quotaMutex.Unlock()
+		te.errs = append(te.errs, serror.New(err))
+		f := buildErrorsLog(te.Errors(), logger)
+		f.Error("unable to read the write-ahead logs of the task")
 		return nil, te
 	}
 
-	// Bind plugin content type selections in workflow
-	err = wf.BindPluginContentTypes(s.metricManager)
+	// Add task to taskCollection
+	err := s.tasks.add(task)
+	s.quotaMutex.Unlock()
 	if err != nil {
 		te.errs = append(te.errs, serror.New(err))
 		f := buildErrorsLog(te.Errors(), logger)
-		f.Error("unable to bind plugin content types")
+		f.Error("errors during task creation")
 		return nil, te
 	}
-
-	// Create the task object
-	task := newTask(sch, wf, s.workManager, s.metricManager, s.eventManager, opts...)
-
-	// Add task to taskCollection
-	if err := s.tasks.add(task); err != nil {
+	// the tenant scopes the metrics the task collects and the config of
+	// the plugins it runs, before the task is started
+	if err := s.metricManager.SetTaskTenant(task.id, task.Tenant()); err != nil {
+		s.tasks.remove(task)
 		te.errs = append(te.errs, serror.New(err))
 		f := buildErrorsLog(te.Errors(), logger)
-		f.Error("errors during task creation")
+		f.Error("unable to scope the task to its tenant")
 		return nil, te
 	}
+	s.metricManager.SetMetricRefreshInterval(task.id, task.MetricRefreshInterval())
 
 	logger.WithFields(log.Fields{
 		"task-id":    task.ID(),
@@ -258,6 +326,100 @@ func (s *scheduler) createTask(sch schedule.Schedule, wfMap *wmap.WorkflowMap, s
 	return task, te
 }
 
+// ValidateTask returns the errors creating the task would fail with,
+// without creating it.
+func (s *scheduler) ValidateTask(sch schedule.Schedule, wfMap *wmap.WorkflowMap) core.TaskErrors {
+	logger := schedulerLogger.WithFields(log.Fields{
+		"_block": "validate-task",
+	})
+	_, te := s.validateTask(sch, wfMap, logger)
+	return te
+}
+
+// validateTask checks the schedule and the workflow of a task, and returns
+// the workflow of the task when they are valid.
+func (s *scheduler) validateTask(sch schedule.Schedule, wfMap *wmap.WorkflowMap, logger *log.Entry) (*schedulerWorkflow, *taskErrors) {
+	// Create a container for task errors
+	te := &taskErrors{
+		errs: make([]serror.SnapError, 0),
+	}
+
+	// Return error if we are not started.
+	if s.state != schedulerStarted {
+		te.errs = append(te.errs, serror.New(ErrSchedulerNotStarted))
+		f := buildErrorsLog(te.Errors(), logger)
+		f.Error("scheduler not started")
+		return nil, te
+	}
+
+	// Ensure the schedule is valid at this point and time.
+	if err := sch.Validate(); err != nil {
+		te.errs = append(te.errs, serror.New(err))
+		f := buildErrorsLog(te.Errors(), logger)
+		f.Error("schedule passed not valid")
+		return nil, te
+	}
+
+	// Generate a workflow from the workflow map
+	wf, err := wmapToWorkflow(wfMap)
+	if err != nil {
+		te.errs = append(te.errs, serror.New(err))
+		f := buildErrorsLog(te.Errors(), logger)
+		f.Error(ErrSchedulerNotStarted.Error())
+		return nil, te
+	}
+
+	if s.walDir == "" && wf.usesWAL() {
+		te.errs = append(te.errs, serror.New(ErrWALNotSet))
+		f := buildErrorsLog(te.Errors(), logger)
+		f.Error(ErrWALNotSet.Error())
+		return nil, te
+	}
+
+	wf.setRemotePasswords(s.remotePasswords)
+	if err := wf.setMemberResolver(s.memberResolver); err != nil {
+		te.errs = append(te.errs, serror.New(err))
+		f := buildErrorsLog(te.Errors(), logger)
+		f.Error("unable to resolve the tribe members of the workflow")
+		return nil, te
+	}
+
+	if err := setFileDir(wf.allPublishNodes(), s.fileDir); err != nil {
+		te.errs = append(te.errs, serror.New(err))
+		f := buildErrorsLog(te.Errors(), logger)
+		f.Error("unable to set the file directory of the workflow")
+		return nil, te
+	}
+	setTLSPolicy(wf.allPublishNodes(), s.tlsPolicy)
+
+	// Add the metrics selected by the catalog queries of the workflow
+	if err := s.resolveQueries(wf); err != nil {
+		te.errs = append(te.errs, serror.New(err))
+		f := buildErrorsLog(te.Errors(), logger)
+		f.Error("unable to resolve metric queries")
+		return nil, te
+	}
+
+	// validate plugins and metrics
+	mts, plugins := s.gatherMetricsAndPlugins(wf)
+	errs := s.metricManager.ValidateDeps(mts, plugins)
+	if len(errs) > 0 {
+		te.errs = append(te.errs, errs...)
+		return nil, te
+	}
+
+	// Bind plugin content type selections in workflow
+	err = wf.BindPluginContentTypes(s.metricManager)
+	if err != nil {
+		te.errs = append(te.errs, serror.New(err))
+		f := buildErrorsLog(te.Errors(), logger)
+		f.Error("unable to bind plugin content types")
+		return nil, te
+	}
+
+	return wf, te
+}
+
 // RemoveTask given a tasks id.  The task must be stopped.
 // Can return errors ErrTaskNotFound and ErrTaskNotStopped.
 func (s *scheduler) RemoveTask(id string) error {
@@ -285,7 +447,122 @@ func (s *scheduler) removeTask(id, source string) error {
 		Source: source,
 	}
 	defer s.eventManager.Emit(event)
-	return s.tasks.remove(t)
+	if err := s.tasks.remove(t); err != nil {
+		return err
+	}
+	s.taskWatcherColl.forget(t.id)
+	closePublishers(t.workflow.allPublishNodes(), logger.WithField("task-id", t.id))
+	now := chrono.Chrono.Now()
+	s.purgeDeletedTasks(now)
+	t.Lock()
+	t.state = core.TaskDeleted
+	t.Unlock()
+	if !s.deletedTasks.add(t, now) {
+		s.purgeTask(t)
+	}
+	return nil
+}
+
+// purgeTask removes what is left of a deleted task
+func (s *scheduler) purgeTask(t *task) {
+	s.metricManager.SetTaskTenant(t.id, "")
+	s.metricManager.SetMetricRefreshInterval(t.id, 0)
+	if err := t.workflow.removeWAL(); err != nil {
+		schedulerLogger.WithFields(log.Fields{
+			"_block":  "purge-task",
+			"task id": t.id,
+		}).Warn(err)
+	}
+}
+
+// purgeDeletedTasks purges the deleted tasks whose retention has elapsed
+func (s *scheduler) purgeDeletedTasks(now time.Time) {
+	for _, t := range s.deletedTasks.purge(now) {
+		s.purgeTask(t)
+		schedulerLogger.WithFields(log.Fields{
+			"_block":  "purge-task",
+			"task-id": t.id,
+		}).Info("deleted task purged")
+	}
+}
+
+// GetDeletedTasks returns the deleted tasks which are not purged yet
+func (s *scheduler) GetDeletedTasks() []core.DeletedTask {
+	s.purgeDeletedTasks(chrono.Chrono.Now())
+	ds := s.deletedTasks.list()
+	tasks := make([]core.DeletedTask, len(ds))
+	for i, d := range ds {
+		tasks[i] = d.core(s.deletedTasks.retention)
+	}
+	return tasks
+}
+
+// GetDeletedTask returns the deleted task with the given id if it is not
+// purged yet
+func (s *scheduler) GetDeletedTask(id string) (core.DeletedTask, error) {
+	s.purgeDeletedTasks(chrono.Chrono.Now())
+	d := s.deletedTasks.get(id)
+	if d == nil {
+		return core.DeletedTask{}, ErrTaskNotFound
+	}
+	return d.core(s.deletedTasks.retention), nil
+}
+
+// purgeDeletedTasksEvery purges the deleted tasks whose retention elapsed
+// once per interval until stopped
+func (s *scheduler) purgeDeletedTasksEvery(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.purgeDeletedTasks(chrono.Chrono.Now())
+		case <-stop:
+			return
+		}
+	}
+}
+
+// RestoreTask brings a deleted task back, stopped, if it is not purged yet
+func (s *scheduler) RestoreTask(id string) (core.Task, error) {
+	logger := schedulerLogger.WithFields(log.Fields{
+		"_block":  "restore-task",
+		"task-id": id,
+	})
+	s.purgeDeletedTasks(chrono.Chrono.Now())
+	d := s.deletedTasks.take(id)
+	if d == nil {
+		logger.Error(ErrTaskNotFound)
+		return nil, ErrTaskNotFound
+	}
+	t := d.task
+	// a restored task counts against the quotas as a created one does
+	s.quotaMutex.Lock()
+	if err := s.applyQuotas(t, t.Schedule(), t.workflow); err != nil {
+		s.quotaMutex.Unlock()
+		s.deletedTasks.put(d)
+		logger.Error(err)
+		return nil, err
+	}
+	t.Lock()
+	t.state = core.TaskStopped
+	t.Unlock()
+	err := s.tasks.add(t)
+	s.quotaMutex.Unlock()
+	if err != nil {
+		// the task stays in the bin, e.g. when its ID was taken meanwhile
+		t.Lock()
+		t.state = core.TaskDeleted
+		t.Unlock()
+		s.deletedTasks.put(d)
+		logger.Error(err)
+		return nil, err
+	}
+	s.eventManager.Emit(&scheduler_event.TaskRestoredEvent{
+		TaskID: t.id,
+	})
+	logger.Info("task restored")
+	return t, nil
 }
 
 // GetTasks returns a copy of the tasks in a map where the task id is the key
@@ -345,7 +622,7 @@ func (s *scheduler) startTask(id, source string) []serror.SnapError {
 			serror.New(ErrTaskDisabledNotRunnable),
 		}
 	}
-	if t.state == core.TaskFiring || t.state == core.TaskSpinning {
+	if t.state == core.TaskFiring || t.state == core.TaskSpinning || t.state == core.TaskPaused {
 		logger.WithFields(log.Fields{
 			"task-id":    t.ID(),
 			"task-state": t.State(),
@@ -375,6 +652,9 @@ func (s *scheduler) startTask(id, source string) []serror.SnapError {
 	}
 	defer s.eventManager.Emit(event)
 	t.Spin()
+	s.maintenance.Lock()
+	s.maintainTask(t)
+	s.maintenance.Unlock()
 	logger.WithFields(log.Fields{
 		"task-id":    t.ID(),
 		"task-state": t.State(),
@@ -437,7 +717,7 @@ func (s *scheduler) stopTask(id, source string) []serror.SnapError {
 	return nil
 }
 
-//EnableTask changes state from disabled to stopped
+// EnableTask changes state from disabled to stopped
 func (s *scheduler) EnableTask(id string) (core.Task, error) {
 	t, e := s.getTask(id)
 	if e != nil {
@@ -475,13 +755,35 @@ func (s *scheduler) Start() error {
 		}).Error("error on scheduler start")
 		return ErrMetricManagerNotSet
 	}
+	if s.aggregator != nil {
+		if err := setFileDir(s.aggregator.publishNodes, s.fileDir); err != nil {
+			schedulerLogger.WithFields(log.Fields{
+				"_block": "start-scheduler",
+				"_error": err.Error(),
+			}).Error("error on scheduler start")
+			return err
+		}
+		setTLSPolicy(s.aggregator.publishNodes, s.tlsPolicy)
+	}
 	s.state = schedulerStarted
+	if s.stopPurge == nil {
+		s.stopPurge = make(chan struct{})
+		go s.purgeDeletedTasksEvery(deletedTaskPurgeInterval, s.stopPurge)
+	}
+	if s.aggregator != nil {
+		s.aggregator.start(s.metricManager)
+	}
 	schedulerLogger.WithFields(log.Fields{
 		"_block": "start-scheduler",
 	}).Info("scheduler started")
 	return nil
 }
 
+// Running returns whether the scheduler is started
+func (s *scheduler) Running() bool {
+	return s.state == schedulerStarted
+}
+
 func (s *scheduler) Stop() {
 	s.state = schedulerStopped
 	// stop all tasks that are not already stopped
@@ -489,6 +791,13 @@ func (s *scheduler) Stop() {
 		// Kill ensure another task can't turn it back on while we are shutting down
 		t.Kill()
 	}
+	if s.stopPurge != nil {
+		close(s.stopPurge)
+		s.stopPurge = nil
+	}
+	if s.aggregator != nil {
+		s.aggregator.stopFlushing()
+	}
 	schedulerLogger.WithFields(log.Fields{
 		"_block": "stop-scheduler",
 	}).Info("scheduler stopped")
@@ -502,8 +811,101 @@ func (s *scheduler) SetMetricManager(mm managesMetrics) {
 	}).Debug("metric manager linked")
 }
 
-//
-func (s *scheduler) WatchTask(id string, tw core.TaskWatcherHandler) (core.TaskWatcherCloser, error) {
+// SetSharder sets what tells the sharded tasks created from then on which
+// shard of their metrics they collect
+func (s *scheduler) SetSharder(sh core.Sharder) {
+	s.sharder = sh
+	schedulerLogger.WithFields(log.Fields{
+		"_block": "set-sharder",
+	}).Debug("sharder linked")
+}
+
+// SetMemberResolver sets what resolves the tribe members running the remote
+// processors of the tasks created from then on
+func (s *scheduler) SetMemberResolver(r core.MemberResolver) {
+	s.memberResolver = r
+	schedulerLogger.WithFields(log.Fields{
+		"_block": "set-member-resolver",
+	}).Debug("member resolver linked")
+}
+
+// WorkerPools returns the state of the worker pools of the scheduler
+func (s *scheduler) WorkerPools() []core.WorkerPool {
+	return s.workManager.Pools()
+}
+
+// ResizeWorkerPool sets the number of workers of a worker pool
+func (s *scheduler) ResizeWorkerPool(pool string, size uint) error {
+	if err := s.workManager.Resize(pool, size); err != nil {
+		return err
+	}
+	schedulerLogger.WithFields(log.Fields{
+		"_block":  "resize-worker-pool",
+		"pool":    pool,
+		"workers": size,
+	}).Info("worker pool resized")
+	return nil
+}
+
+// ResolveTaskConfig returns the config each metric of a task is collected
+// with, keyed by namespace, along with the source of each value
+func (s *scheduler) ResolveTaskConfig(id string) (map[string]map[string]cdata.ResolvedConfigValue, error) {
+	t, err := s.getTask(id)
+	if err != nil {
+		return nil, err
+	}
+	resolved := make(map[string]map[string]cdata.ResolvedConfigValue)
+	for _, rmt := range t.workflow.metrics {
+		nss, serr := s.metricManager.ExpandWildcards(rmt.Namespace())
+		if serr != nil || len(nss) == 0 {
+			nss = [][]string{rmt.Namespace()}
+		}
+		for _, ns := range nss {
+			layers, err := s.metricManager.ConfigLayers(&metric{
+				namespace:  ns,
+				version:    rmt.Version(),
+				pluginName: core.MetricPluginName(rmt),
+			})
+			if err != nil {
+				return nil, err
+			}
+			layers = append(layers, t.workflow.configTree.Layers(ns)...)
+			resolved[core.JoinNamespace(ns)] = cdata.Resolve(layers...)
+		}
+	}
+	return resolved, nil
+}
+
+// SetWALDir sets the directory the write-ahead logs of the tasks created from
+// then on are written to
+func (s *scheduler) SetWALDir(dir string) {
+	s.walDir = dir
+	schedulerLogger.WithFields(log.Fields{
+		"_block": "set-wal-dir",
+		"path":   dir,
+	}).Debug("write-ahead log directory set")
+}
+
+// SetTLSPolicy sets the TLS policy restricting the connections of the
+// built-in publishers of the tasks created from then on, and of the
+// aggregator
+func (s *scheduler) SetTLSPolicy(p *tlspolicy.Config) {
+	s.tlsPolicy = p
+}
+
+// SetFileDir sets the directory the built-in file publishers of the tasks
+// created from then on, and of the aggregator, write under
+func (s *scheduler) SetFileDir(dir string) {
+	s.fileDir = dir
+	schedulerLogger.WithFields(log.Fields{
+		"_block": "set-file-dir",
+		"path":   dir,
+	}).Debug("file directory set")
+}
+
+// WatchTask adds a watcher of the task which receives the events selected
+// by opts, starting with the replayed lifecycle events
+func (s *scheduler) WatchTask(id string, tw core.TaskWatcherHandler, opts core.TaskWatchOptions) (core.TaskWatcherCloser, error) {
 	task, err := s.getTask(id)
 	if err != nil {
 		schedulerLogger.WithFields(log.Fields{
@@ -513,7 +915,7 @@ func (s *scheduler) WatchTask(id string, tw core.TaskWatcherHandler) (core.TaskW
 		}).Error("error watching task")
 		return nil, err
 	}
-	return s.taskWatcherColl.add(task.ID(), tw)
+	return s.taskWatcherColl.add(task.ID(), tw, opts)
 }
 
 // Central handling for all async events in scheduler
@@ -537,6 +939,15 @@ func (s *scheduler) HandleGomitEvent(e gomit.Event) {
 			"task-id":         v.TaskID,
 			"errors-count":    v.Errors,
 		}).Debug("event received")
+	case *scheduler_event.TaskRunFinishedEvent:
+		log.WithFields(log.Fields{
+			"_module":         "scheduler-events",
+			"_block":          "handle-events",
+			"event-namespace": e.Namespace(),
+			"task-id":         v.TaskID,
+			"failed":          v.Failed,
+		}).Debug("event received")
+		s.triggerDependents(v.TaskID, v.Failed)
 	case *scheduler_event.TaskStartedEvent:
 		log.WithFields(log.Fields{
 			"_module":         "scheduler-events",
@@ -567,6 +978,16 @@ func (s *scheduler) HandleGomitEvent(e gomit.Event) {
 		cps := returnCorePlugin(plugins)
 		s.metricManager.UnsubscribeDeps(task.ID(), mts, cps)
 		s.taskWatcherColl.handleTaskDisabled(v.TaskID, v.Why)
+	case *control_event.PluginConfigUpdatedEvent:
+		log.WithFields(log.Fields{
+			"_module":         "scheduler-events",
+			"_block":          "handle-events",
+			"event-namespace": e.Namespace(),
+			"plugin-name":     v.PluginName,
+			"plugin-version":  v.PluginVersion,
+			"task-count":      len(v.TaskIDs),
+		}).Debug("event received")
+		s.applyPluginConfig(v.TaskIDs)
 	default:
 		log.WithFields(log.Fields{
 			"_module":         "scheduler-events",
@@ -576,6 +997,40 @@ func (s *scheduler) HandleGomitEvent(e gomit.Event) {
 	}
 }
 
+// applyPluginConfig validates the dependencies of the running tasks given
+// again once the global config of their plugins changed. The config of their
+// processors and publishers is first set back to the one of the task, so that
+// the keys a replaced global config dropped no longer apply, and validating
+// merges the new global config into it. The collectors read the global
+// config on each collection.
+func (s *scheduler) applyPluginConfig(ids []string) {
+	for _, id := range ids {
+		t, err := s.getTask(id)
+		if err != nil {
+			continue
+		}
+		if state := t.State(); state != core.TaskSpinning && state != core.TaskFiring {
+			continue
+		}
+		logger := log.WithFields(log.Fields{
+			"_module": "scheduler",
+			"_block":  "apply-plugin-config",
+			"task-id": id,
+		})
+		wf := t.workflow
+		if wf.workflowMap != nil && wf.workflowMap.CollectNode != nil {
+			cn := wf.workflowMap.CollectNode
+			if err := resetPluginConfig(wf.processNodes, wf.publishNodes, cn.ProcessNodes, cn.PublishNodes); err != nil {
+				logger.WithField("_error", err.Error()).Warn("could not reset the plugin config of the task")
+			}
+		}
+		mts, plugins := s.gatherMetricsAndPlugins(wf)
+		if errs := s.metricManager.ValidateDeps(mts, plugins); len(errs) > 0 {
+			buildErrorsLog(errs, logger).Warn("task is not valid with the updated plugin config")
+		}
+	}
+}
+
 func (s *scheduler) getTask(id string) (*task, error) {
 	task := s.tasks.Get(id)
 	if task == nil {
@@ -599,9 +1054,10 @@ func (s *scheduler) gatherMetricsAndPlugins(wf *schedulerWorkflow) ([]core.Metri
 
 		for _, ns := range nss {
 			mts = append(mts, &metric{
-				namespace: ns,
-				version:   m.Version(),
-				config:    wf.configTree.Get(ns),
+				namespace:  ns,
+				version:    m.Version(),
+				pluginName: core.MetricPluginName(m),
+				config:     wf.configTree.Get(ns),
 			})
 		}
 	}
@@ -610,12 +1066,64 @@ func (s *scheduler) gatherMetricsAndPlugins(wf *schedulerWorkflow) ([]core.Metri
 	return mts, plugins
 }
 
+// resolveQueries adds the metrics matching the catalog queries of the workflow
+// to its metrics. For each namespace only the latest matching version is
+// added, and namespaces already listed in the workflow are left alone.
+func (s *scheduler) resolveQueries(wf *schedulerWorkflow) error {
+	if len(wf.queries) == 0 {
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, m := range wf.metrics {
+		seen[core.JoinNamespace(m.Namespace())] = true
+	}
+	for _, q := range wf.queries {
+		mets, err := s.metricManager.QueryMetrics(q)
+		if err != nil {
+			return err
+		}
+		if len(mets) == 0 {
+			return fmt.Errorf("%v Query: %s", ErrQueryMatchesNoMetrics, q)
+		}
+		latest := map[string]core.CatalogedMetric{}
+		var order []string
+		for _, m := range mets {
+			key := core.JoinNamespace(m.Namespace())
+			if seen[key] {
+				continue
+			}
+			prev, ok := latest[key]
+			if !ok {
+				order = append(order, key)
+			}
+			if !ok || m.Version() > prev.Version() {
+				latest[key] = m
+			}
+		}
+		for _, key := range order {
+			seen[key] = true
+			wf.metrics = append(wf.metrics, &metric{
+				namespace: latest[key].Namespace(),
+				version:   latest[key].Version(),
+			})
+		}
+	}
+	return nil
+}
+
 func (s *scheduler) walkWorkflow(prnodes []*processNode, pbnodes []*publishNode, plugins *[]core.SubscribedPlugin) {
 	for _, pr := range prnodes {
-		*plugins = append(*plugins, pr)
+		// remote processors are plugins of another snapd
+		if pr.remote == nil {
+			*plugins = append(*plugins, pr)
+		}
 		s.walkWorkflow(pr.ProcessNodes, pr.PublishNodes, plugins)
 	}
 	for _, pb := range pbnodes {
+		// built-in publishers are not plugins
+		if pb.builtin != nil {
+			continue
+		}
 		*plugins = append(*plugins, pb)
 	}
 }
@@ -22,12 +22,15 @@ package scheduler
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
 	. "github.com/smartystreets/goconvey/convey"
 
+	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/ctypes"
@@ -42,6 +45,15 @@ type mockMetricManager struct {
 	failuredSoFar              int
 	acceptedContentTypes       map[string][]string
 	returnedContentTypes       map[string][]string
+	catalog                    []mockMetricType
+	// notReady is the number of readiness checks the collectors fail
+	notReady int32
+	// tenants holds the namespace prefix each tenant sees, and taskTenants
+	// the tenant of each task scoped to one
+	tenants     map[string]string
+	taskTenants map[string]string
+	// quotas holds the quota of each tenant with one
+	quotas map[string]*core.Quota
 }
 
 func (m *mockMetricManager) lazyContentType(key string) {
@@ -91,6 +103,49 @@ func (m *mockMetricManager) ProcessMetrics(contentType string, content []byte, p
 	return "", nil, nil
 }
 
+func (m *mockMetricManager) CollectorsReady(string) error {
+	if atomic.AddInt32(&m.notReady, -1) >= 0 {
+		return errors.New("warming up")
+	}
+	atomic.StoreInt32(&m.notReady, 0)
+	return nil
+}
+
+func (m *mockMetricManager) SetMetricRefreshInterval(string, time.Duration) {}
+
+func (m *mockMetricManager) SetTaskTenant(taskID, tenant string) error {
+	if m.taskTenants == nil {
+		m.taskTenants = map[string]string{}
+	}
+	if tenant == "" {
+		delete(m.taskTenants, taskID)
+		return nil
+	}
+	if _, ok := m.tenants[tenant]; !ok {
+		return errors.New("unknown tenant")
+	}
+	m.taskTenants[taskID] = tenant
+	return nil
+}
+
+func (m *mockMetricManager) MetricVisible(tenant string, ns []string) bool {
+	if tenant == "" {
+		return true
+	}
+	prefix, ok := m.tenants[tenant]
+	return ok && strings.HasPrefix(core.JoinNamespace(ns), prefix)
+}
+
+func (m *mockMetricManager) TenantQuota(tenant string) *core.Quota {
+	return m.quotas[tenant]
+}
+
+func (m *mockMetricManager) ConfigLayers(core.RequestedMetric) ([]cdata.ConfigLayer, error) {
+	global := cdata.NewNode()
+	global.AddItem("username", ctypes.ConfigValueStr{Value: "snap"})
+	return []cdata.ConfigLayer{{Source: cdata.GlobalConfigSource, Node: global}}, nil
+}
+
 func (m *mockMetricManager) ValidateDeps(mts []core.Metric, prs []core.SubscribedPlugin) []serror.SnapError {
 	if m.failValidatingMetrics {
 		return []serror.SnapError{
@@ -117,6 +172,16 @@ func (m *mockMetricManager) ExpandWildcards([]string) ([][]string, serror.SnapEr
 	return nil, nil
 }
 
+func (m *mockMetricManager) QueryMetrics(q *core.MetricQuery) ([]core.CatalogedMetric, error) {
+	var mts []core.CatalogedMetric
+	for _, mt := range m.catalog {
+		if q.Match(mt) {
+			mts = append(mts, mt)
+		}
+	}
+	return mts, nil
+}
+
 type mockMetricManagerError struct {
 	errs []error
 }
@@ -126,6 +191,8 @@ type mockMetricType struct {
 	namespace          []string
 	lastAdvertisedTime time.Time
 	config             *cdata.ConfigDataNode
+	plugin             string
+	tags               map[string]string
 }
 
 func (m mockMetricType) Version() int {
@@ -148,6 +215,18 @@ func (m mockMetricType) Data() interface{} {
 	return nil
 }
 
+func (m mockMetricType) Tags() map[string]string {
+	return m.tags
+}
+
+func (m mockMetricType) PluginName() string {
+	return m.plugin
+}
+
+func (m mockMetricType) Policy() *cpolicy.ConfigPolicyNode {
+	return nil
+}
+
 type mockScheduleResponse struct {
 }
 
@@ -231,6 +310,35 @@ func TestScheduler(t *testing.T) {
 
 		})
 
+		Convey("adds the metrics matching the queries of the workflow", func() {
+			c.catalog = []mockMetricType{
+				{namespace: []string{"intel", "cpu", "user"}, version: 2, plugin: "psutil"},
+				{namespace: []string{"intel", "cpu", "user"}, version: 3, plugin: "psutil"},
+				{namespace: []string{"intel", "cpu", "idle"}, version: 3, plugin: "psutil"},
+				{namespace: []string{"foo", "bar"}, version: 1, plugin: "psutil"},
+				{namespace: []string{"intel", "mock", "foo"}, version: 1, plugin: "mock"},
+			}
+			w.CollectNode.AddQuery("plugin=psutil AND version>=1")
+			tsk, err := s.CreateTask(schedule.NewSimpleSchedule(time.Second*1), w, false)
+			So(err.Errors(), ShouldBeEmpty)
+			mts := tsk.(*task).workflow.metrics
+			So(mts, ShouldHaveLength, 4)
+			So(mts[2].Namespace(), ShouldResemble, []string{"intel", "cpu", "user"})
+			So(mts[2].Version(), ShouldEqual, 3)
+			So(mts[3].Namespace(), ShouldResemble, []string{"intel", "cpu", "idle"})
+			Convey("returns an error when a query matches no metrics", func() {
+				w.CollectNode.AddQuery("plugin=nope")
+				_, err := s.CreateTask(schedule.NewSimpleSchedule(time.Second*1), w, false)
+				So(err.Errors(), ShouldHaveLength, 1)
+				So(err.Errors()[0].Error(), ShouldContainSubstring, ErrQueryMatchesNoMetrics.Error())
+			})
+			Convey("returns an error when a query is invalid", func() {
+				w.CollectNode.AddQuery("plugin>psutil")
+				_, err := s.CreateTask(schedule.NewSimpleSchedule(time.Second*1), w, false)
+				So(err.Errors(), ShouldHaveLength, 1)
+			})
+		})
+
 		Convey("returns an error when scheduler started and MetricManager is not set", func() {
 			s1 := New(GetDefaultConfig())
 			err := s1.Start()
@@ -240,6 +348,20 @@ func TestScheduler(t *testing.T) {
 
 		})
 
+		Convey("returns an error when a namespace holds a malformed tuple", func() {
+			w.CollectNode.AddMetric("/intel/cpu/(15-0)/idle", 1)
+			_, err := s.CreateTask(schedule.NewSimpleSchedule(time.Second*1), w, false)
+			So(err.Errors(), ShouldHaveLength, 1)
+			So(err.Errors()[0].Error(), ShouldEqual, "Invalid metric query /intel/cpu/(15-0)/idle: invalid range 15-0: 15 is greater than 0")
+		})
+
+		Convey("returns an error when a metric is pinned to a plugin without its version", func() {
+			w.CollectNode.PinMetric("/intel/mock/foo", "mock", 0)
+			_, err := s.CreateTask(schedule.NewSimpleSchedule(time.Second*1), w, false)
+			So(err.Errors(), ShouldHaveLength, 1)
+			So(err.Errors()[0].Error(), ShouldEqual, "Metric pinned to a plugin has no plugin version: /intel/mock/foo")
+		})
+
 		Convey("returns an error when wrong namespace is given wo workflowmap ", func() {
 			w.CollectNode.AddMetric("****/&&&", 3)
 			w.CollectNode.AddConfigItem("****/&&&", "username", "user")
@@ -281,6 +403,17 @@ func TestScheduler(t *testing.T) {
 				So(err, ShouldBeNil)
 				So(t, ShouldEqual, tsk)
 			})
+			Convey("resolve the config of the metrics of the task", func() {
+				rc, err := s.ResolveTaskConfig(tsk.ID())
+				So(err, ShouldBeNil)
+				So(len(rc), ShouldEqual, 2)
+				So(rc["/foo/bar"]["username"].Value, ShouldResemble, ctypes.ConfigValueStr{Value: "root"})
+				So(rc["/foo/bar"]["username"].Source, ShouldEqual, "task:/foo/bar")
+				So(rc["/foo/bar"]["username"].Overridden[0].Source, ShouldEqual, cdata.GlobalConfigSource)
+				So(rc["/foo/baz"]["username"].Source, ShouldEqual, cdata.GlobalConfigSource)
+				_, err = s.ResolveTaskConfig("1234")
+				So(err, ShouldNotBeNil)
+			})
 			Convey("error when attempting to get a task that doesn't exist", func() {
 				t, err := s.GetTask("1234")
 				So(err, ShouldNotBeNil)
@@ -291,6 +424,68 @@ func TestScheduler(t *testing.T) {
 				So(len(err), ShouldEqual, 1)
 				So(err[0].Error(), ShouldEqual, "Task is already stopped.")
 			})
+			Convey("pause and resume a task", func() {
+				errs := s.PauseTask(tsk.ID(), 0)
+				So(len(errs), ShouldEqual, 1)
+				So(errs[0].Error(), ShouldEqual, ErrTaskNotRunning.Error())
+				errs = s.ResumeTask(tsk.ID())
+				So(len(errs), ShouldEqual, 1)
+				So(errs[0].Error(), ShouldEqual, ErrTaskNotPaused.Error())
+				So(s.PauseTask("1234", 0), ShouldNotBeEmpty)
+				So(s.ResumeTask("1234"), ShouldNotBeEmpty)
+
+				tsk.(*task).Spin()
+				So(s.PauseTask(tsk.ID(), time.Hour), ShouldBeEmpty)
+				So(tsk.State(), ShouldEqual, core.TaskPaused)
+				So(tsk.PausedUntil().After(time.Now()), ShouldBeTrue)
+				errs = s.StartTask(tsk.ID())
+				So(len(errs), ShouldEqual, 1)
+				So(errs[0].Error(), ShouldEqual, ErrTaskAlreadyRunning.Error())
+				So(s.ResumeTask(tsk.ID()), ShouldBeEmpty)
+				So(tsk.State(), ShouldNotEqual, core.TaskPaused)
+				So(tsk.PausedUntil().IsZero(), ShouldBeTrue)
+				tsk.(*task).Stop()
+			})
+		})
+
+		Convey("chain tasks with dependencies", func() {
+			a, te := s.CreateTask(schedule.NewTriggerSchedule(), w, false)
+			So(te.Errors(), ShouldBeEmpty)
+			onSuccess := core.OptionTaskDependencies([]core.TaskDependency{{TaskID: a.ID(), On: core.DependOnSuccess}})
+			b, te := s.CreateTask(schedule.NewTriggerSchedule(), w, false, onSuccess)
+			So(te.Errors(), ShouldBeEmpty)
+			So(b.Dependencies(), ShouldHaveLength, 1)
+
+			Convey("a dependent task must have a trigger schedule", func() {
+				_, te := s.CreateTask(schedule.NewSimpleSchedule(time.Second), w, false, onSuccess)
+				So(te.Errors()[0].Error(), ShouldEqual, ErrTaskDependencyNotTriggered.Error())
+			})
+			Convey("the task depended on must exist", func() {
+				_, te := s.CreateTask(schedule.NewTriggerSchedule(), w, false,
+					core.OptionTaskDependencies([]core.TaskDependency{{TaskID: "1234", On: core.DependOnSuccess}}))
+				So(te.Errors()[0].Error(), ShouldContainSubstring, ErrTaskDependencyNotFound.Error())
+				_, te = s.CreateTask(schedule.NewTriggerSchedule(), w, false,
+					core.OptionTaskDependencies([]core.TaskDependency{{TaskID: a.ID(), On: "always"}}))
+				So(te.Errors(), ShouldHaveLength, 1)
+			})
+			Convey("dependencies do not form a cycle", func() {
+				// a task created with the id of a depends on b which depends on a
+				_, te := s.CreateTask(schedule.NewTriggerSchedule(), w, false,
+					core.OptionTaskDependencies([]core.TaskDependency{{TaskID: b.ID(), On: core.DependOnCompletion}}),
+					core.SetTaskID(a.ID()))
+				So(te.Errors()[0].Error(), ShouldStartWith, ErrTaskDependencyCycle.Error())
+				So(te.Errors()[0].Error(), ShouldContainSubstring, a.ID()+" -> "+b.ID()+" -> "+a.ID())
+			})
+			Convey("a run of the task depended on runs the dependent task", func() {
+				b.(*task).Spin()
+				s.triggerDependents(a.ID(), true)
+				time.Sleep(time.Millisecond * 20)
+				So(b.HitCount(), ShouldEqual, 0)
+				s.triggerDependents(a.ID(), false)
+				time.Sleep(time.Millisecond * 20)
+				So(b.HitCount(), ShouldEqual, 1)
+				b.(*task).Stop()
+			})
 		})
 
 		// 		// // TODO NICK
@@ -0,0 +1,132 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// ErrNotSimulated is returned by Simulate for a schedule which does not wait
+// on a clock, e.g. one fired by triggers
+var ErrNotSimulated = errors.New("the schedule cannot be simulated")
+
+// SimulatedResponses answers the calls a simulated task makes to plugins,
+// e.g. with the responses recorded by snapd
+type SimulatedResponses interface {
+	// Collect returns the metrics the collectors return for the namespaces
+	Collect(namespaces []*core.WildcardNamespace) ([]core.Metric, error)
+	// Process returns the errors the processor returns
+	Process(pluginName string, pluginVersion int) []error
+}
+
+// SimulatedRun is a run of a task found by Simulate
+type SimulatedRun struct {
+	// The time the schedule fired
+	Time time.Time
+	// The intervals missed since the previous run
+	Missed uint
+	// The metrics collected, when simulated with plugin responses
+	Metrics int
+	// The errors the run failed with
+	Errors []error
+}
+
+// Simulate validates the workflow map and fast-forwards the schedule on a
+// simulated clock from the start time. It returns the first runs of the task,
+// fewer of them if the schedule ends. No plugin is loaded nor called: the
+// runs collect and process with the responses given, if any.
+func Simulate(sch schedule.Schedule, wfMap *wmap.WorkflowMap, start time.Time, runs int, responses SimulatedResponses) ([]SimulatedRun, error) {
+	if wfMap == nil {
+		return nil, ErrNullCollectNode
+	}
+	wf, err := wmapToWorkflow(wfMap)
+	if err != nil {
+		return nil, err
+	}
+	if err := sch.Validate(); err != nil {
+		return nil, err
+	}
+	c, ok := sch.(schedule.Clocked)
+	if _, interruptible := sch.(schedule.Interruptible); !ok || interruptible {
+		return nil, ErrNotSimulated
+	}
+	previous := c.Clock()
+	c.SetClock(schedule.NewSimulatedClock(start))
+	defer c.SetClock(previous)
+
+	var namespaces []*core.WildcardNamespace
+	for _, m := range wf.metrics {
+		wn, err := core.CompileWildcardNamespace(m.Namespace())
+		if err != nil {
+			return nil, err
+		}
+		namespaces = append(namespaces, wn)
+	}
+
+	// the schedule is waited on as a task spinning from the start time would
+	last := start
+	if s, ok := sch.(schedule.Starter); ok {
+		last = s.Start(last)
+	}
+	sims := []SimulatedRun{}
+	for len(sims) < runs {
+		sr := sch.Wait(last)
+		if sr.Error() != nil {
+			return sims, sr.Error()
+		}
+		if sr.State() != schedule.Active {
+			break
+		}
+		sim := SimulatedRun{Time: sr.LastTime(), Missed: sr.Missed()}
+		if responses != nil {
+			simulateRun(&sim, wf, namespaces, responses)
+		}
+		sims = append(sims, sim)
+		last = sr.LastTime()
+	}
+	return sims, nil
+}
+
+// simulateRun collects the metrics of the workflow and processes them with
+// the responses
+func simulateRun(sim *SimulatedRun, wf *schedulerWorkflow, namespaces []*core.WildcardNamespace, responses SimulatedResponses) {
+	mts, err := responses.Collect(namespaces)
+	if err != nil {
+		sim.Errors = append(sim.Errors, err)
+		return
+	}
+	sim.Metrics = len(mts)
+	var process func(nodes []*processNode)
+	process = func(nodes []*processNode) {
+		for _, pr := range nodes {
+			if errs := responses.Process(pr.name, pr.version); len(errs) > 0 {
+				sim.Errors = append(sim.Errors, errs...)
+				continue
+			}
+			process(pr.ProcessNodes)
+		}
+	}
+	process(wf.processNodes)
+}
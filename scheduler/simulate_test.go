@@ -0,0 +1,110 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type mockSimulatedResponses struct {
+	collect   [][]core.Metric
+	errs      []error
+	runs      int
+	processed int
+}
+
+func (m *mockSimulatedResponses) Collect(namespaces []*core.WildcardNamespace) ([]core.Metric, error) {
+	i := m.runs % len(m.collect)
+	m.runs++
+	return m.collect[i], m.errs[i]
+}
+
+func (m *mockSimulatedResponses) Process(pluginName string, pluginVersion int) []error {
+	m.processed++
+	return nil
+}
+
+func TestSimulate(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	Convey("Simulate", t, func() {
+		Convey("fires a simple schedule on each interval", func() {
+			sims, err := Simulate(schedule.NewSimpleSchedule(time.Minute), wmap.Sample(), start, 3, nil)
+			So(err, ShouldBeNil)
+			So(sims, ShouldHaveLength, 3)
+			for i, s := range sims {
+				So(s.Time.Equal(start.Add(time.Duration(i+1)*time.Minute)), ShouldBeTrue)
+				So(s.Missed, ShouldEqual, 0)
+			}
+		})
+		Convey("stops when a windowed schedule ends", func() {
+			winStart := time.Now().Add(time.Hour)
+			winStop := winStart.Add(150 * time.Second)
+			sch := schedule.NewWindowedSchedule(time.Minute, &winStart, &winStop)
+			sims, err := Simulate(sch, wmap.Sample(), time.Now(), 10, nil)
+			So(err, ShouldBeNil)
+			So(len(sims), ShouldBeBetween, 0, 10)
+			So(sims[0].Time.Before(winStart), ShouldBeFalse)
+			So(sims[len(sims)-1].Time.Before(winStop.Add(time.Minute)), ShouldBeTrue)
+		})
+		Convey("does not change the clock of the schedule", func() {
+			sch := schedule.NewSimpleSchedule(time.Minute)
+			_, err := Simulate(sch, wmap.Sample(), start, 1, nil)
+			So(err, ShouldBeNil)
+			So(sch.Clock().Now(), ShouldHappenAfter, start)
+		})
+		Convey("collects and processes with the responses", func() {
+			responses := &mockSimulatedResponses{
+				collect: [][]core.Metric{
+					{plugin.PluginMetricType{Namespace_: []string{"intel", "mock", "foo"}}},
+					nil,
+				},
+				errs: []error{nil, errors.New("collection failed")},
+			}
+			wf := wmap.Sample()
+			So(wf.CollectNode.Add(wmap.NewProcessNode("passthru", 1)), ShouldBeNil)
+			sims, err := Simulate(schedule.NewSimpleSchedule(time.Minute), wf, start, 2, responses)
+			So(err, ShouldBeNil)
+			So(sims, ShouldHaveLength, 2)
+			So(sims[0].Metrics, ShouldEqual, 1)
+			So(sims[0].Errors, ShouldBeEmpty)
+			So(sims[1].Errors, ShouldHaveLength, 1)
+			So(responses.processed, ShouldEqual, 1)
+		})
+		Convey("does not simulate a schedule fired by triggers", func() {
+			_, err := Simulate(schedule.NewTriggerSchedule(), wmap.Sample(), start, 1, nil)
+			So(err, ShouldEqual, ErrNotSimulated)
+		})
+		Convey("returns an error for an invalid workflow", func() {
+			_, err := Simulate(schedule.NewSimpleSchedule(time.Minute), nil, start, 1, nil)
+			So(err, ShouldEqual, ErrNullCollectNode)
+			_, err = Simulate(schedule.NewSimpleSchedule(time.Minute), wmap.NewWorkflowMap(), start, 1, nil)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
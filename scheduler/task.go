@@ -55,6 +55,10 @@ var (
 	ErrTaskDisabledOnFailures = errors.New("Task disabled due to consecutive failures")
 	// ErrTaskNotDisabled - The error message for task must be disabled
 	ErrTaskNotDisabled = errors.New("Task must be disabled")
+	// ErrTaskNotRunning - The error message for pausing a task which is not running
+	ErrTaskNotRunning = errors.New("Task must be running")
+	// ErrTaskNotPaused - The error message for resuming a task which is not paused
+	ErrTaskNotPaused = errors.New("Task must be paused")
 )
 
 type task struct {
@@ -62,13 +66,16 @@ type task struct {
 
 	id                 string
 	name               string
-	schResponseChan    chan schedule.Response
 	killChan           chan struct{}
+	scheduleMutex      sync.Mutex
 	schedule           schedule.Schedule
 	workflow           *schedulerWorkflow
 	state              core.TaskState
 	creationTime       time.Time
 	lastFireTime       time.Time
+	pausedUntil        time.Time
+	pauseTimer         *time.Timer
+	pauses             uint
 	manager            managesWork
 	metricsManager     managesMetrics
 	deadlineDuration   time.Duration
@@ -79,7 +86,29 @@ type task struct {
 	lastFailureMessage string
 	lastFailureTime    time.Time
 	stopOnFailure      uint
+	overrunPolicy      string
+	overrunQueueDepth  uint
+	overrunCount       uint
+	priority           string
+	shedCount          uint
+	droppedMetricCount uint
+	suspectMetricCount uint
+	runs               *runHistory
+	alertRules         []core.AlertRule
+	sharded            bool
+	sharder            core.Sharder
+	refreshInterval    time.Duration
+	timestampPolicy    core.TimestampPolicy
+	dependencies       []core.TaskDependency
 	eventEmitter       gomit.Emitter
+	// metadataMutex guards who created and updated the task, when, and its
+	// description, which the API changes while the task runs
+	metadataMutex sync.Mutex
+	description   string
+	createdBy     string
+	tenant        string
+	updatedBy     string
+	updateTime    time.Time
 }
 
 //NewTask creates a Task
@@ -95,7 +124,6 @@ func newTask(s schedule.Schedule, wf *schedulerWorkflow, m *workManager, mm mana
 	task := &task{
 		id:               taskID,
 		name:             name,
-		schResponseChan:  make(chan schedule.Response),
 		schedule:         s,
 		state:            core.TaskStopped,
 		creationTime:     time.Now(),
@@ -104,8 +132,15 @@ func newTask(s schedule.Schedule, wf *schedulerWorkflow, m *workManager, mm mana
 		metricsManager:   mm,
 		deadlineDuration: DefaultDeadlineDuration,
 		stopOnFailure:    DefaultStopOnFailure,
+		overrunPolicy:    core.OverrunSkip,
+		priority:         core.TaskPriorityNormal,
+		runs:             newRunHistory(DefaultTaskRunHistory),
 		eventEmitter:     emitter,
 	}
+	// jitter is derived from the task ID so it stays the same across restarts
+	if ss, ok := s.(*schedule.SimpleSchedule); ok {
+		ss.SetJitterSeed(taskID)
+	}
 	//set options
 	for _, opt := range opts {
 		opt(task)
@@ -164,6 +199,12 @@ func (t *task) LastRunTime() *time.Time {
 	return &t.lastFireTime
 }
 
+// PausedUntil returns the time a paused task resumes at. It is zero when the
+// task is not paused or is paused until it is resumed.
+func (t *task) PausedUntil() *time.Time {
+	return &t.pausedUntil
+}
+
 // MissedCount returns the number of intervals missed.
 func (t *task) MissedCount() uint {
 	return t.missedIntervals
@@ -201,6 +242,182 @@ func (t *task) GetStopOnFailure() uint {
 	return t.stopOnFailure
 }
 
+func (t *task) SetOverrunPolicy(policy string, depth uint) {
+	t.overrunPolicy = policy
+	t.overrunQueueDepth = depth
+}
+
+// OverrunPolicy returns the overrun policy of the task and its queue depth
+func (t *task) OverrunPolicy() (string, uint) {
+	return t.overrunPolicy, t.overrunQueueDepth
+}
+
+// OverrunCount returns the number of intervals which fired while the task
+// was still running
+func (t *task) OverrunCount() uint {
+	return t.overrunCount
+}
+
+func (t *task) SetPriority(priority string) {
+	t.priority = priority
+}
+
+// Priority returns the priority class of the task
+func (t *task) Priority() string {
+	return t.priority
+}
+
+// ShedCount returns the number of collections dropped because the work
+// queue was full when the task was fired
+func (t *task) ShedCount() uint {
+	return t.shedCount
+}
+
+// DroppedMetricCount returns the number of metrics dropped because they
+// violated a validation rule of the workflow
+func (t *task) DroppedMetricCount() uint {
+	return t.droppedMetricCount
+}
+
+// SuspectMetricCount returns the number of metrics tagged as suspect because
+// they violated a validation rule of the workflow
+func (t *task) SuspectMetricCount() uint {
+	return t.suspectMetricCount
+}
+
+// BackPressure returns the back pressure the publishers of the task put on it
+func (t *task) BackPressure() []core.BackPressureState {
+	return t.workflow.backPressure()
+}
+
+func (t *task) SetSharded(sharded bool) {
+	t.sharded = sharded
+}
+
+// Sharded returns whether the task only collects its shard of the metrics
+// it selects
+func (t *task) Sharded() bool {
+	return t.sharded
+}
+
+func (t *task) SetMetricRefreshInterval(interval time.Duration) {
+	t.refreshInterval = interval
+}
+
+// MetricRefreshInterval returns how often the metric types of the collectors
+// supporting dynamic metrics the task collects from are refreshed
+func (t *task) MetricRefreshInterval() time.Duration {
+	return t.refreshInterval
+}
+
+func (t *task) SetTimestampPolicy(policy core.TimestampPolicy) {
+	t.timestampPolicy = policy
+}
+
+// TimestampPolicy returns where the timestamps of the metrics collected by
+// the task come from and the bounds of those set by the collectors
+func (t *task) TimestampPolicy() core.TimestampPolicy {
+	return t.timestampPolicy
+}
+
+func (t *task) SetDependencies(deps []core.TaskDependency) {
+	t.dependencies = deps
+}
+
+// Dependencies returns the tasks the runs of which run the task
+func (t *task) Dependencies() []core.TaskDependency {
+	return t.dependencies
+}
+
+func (t *task) SetDescription(description string) {
+	t.metadataMutex.Lock()
+	defer t.metadataMutex.Unlock()
+	t.description = description
+}
+
+// Description returns the free-text description of the task
+func (t *task) Description() string {
+	t.metadataMutex.Lock()
+	defer t.metadataMutex.Unlock()
+	return t.description
+}
+
+func (t *task) SetCreatedBy(principal string) {
+	t.metadataMutex.Lock()
+	defer t.metadataMutex.Unlock()
+	t.createdBy = principal
+}
+
+// CreatedBy returns the principal of the API which created the task
+func (t *task) CreatedBy() string {
+	t.metadataMutex.Lock()
+	defer t.metadataMutex.Unlock()
+	return t.createdBy
+}
+
+func (t *task) SetTenant(tenant string) {
+	t.metadataMutex.Lock()
+	defer t.metadataMutex.Unlock()
+	t.tenant = tenant
+}
+
+// Tenant returns the tenant the task is scoped to, empty when it is not
+func (t *task) Tenant() string {
+	t.metadataMutex.Lock()
+	defer t.metadataMutex.Unlock()
+	return t.tenant
+}
+
+// RecordUpdate records the principal of the API which last changed the task
+// and when
+func (t *task) RecordUpdate(principal string, at time.Time) {
+	t.metadataMutex.Lock()
+	defer t.metadataMutex.Unlock()
+	t.updatedBy = principal
+	t.updateTime = at
+}
+
+// UpdatedBy returns the principal of the API which last changed the task
+func (t *task) UpdatedBy() string {
+	t.metadataMutex.Lock()
+	defer t.metadataMutex.Unlock()
+	return t.updatedBy
+}
+
+// UpdateTime returns when the task was last changed through the API, the
+// zero time when it never was
+func (t *task) UpdateTime() *time.Time {
+	t.metadataMutex.Lock()
+	defer t.metadataMutex.Unlock()
+	ut := t.updateTime
+	return &ut
+}
+
+// shard returns the shard of the metrics of the task collected by this
+// snapd, and the number of shards
+func (t *task) shard() (int, int) {
+	if !t.sharded || t.sharder == nil {
+		return 0, 1
+	}
+	return t.sharder.Shard(t.id)
+}
+
+// SetAlertRules sets the alert rules evaluated against the metrics collected
+// by the task
+func (t *task) SetAlertRules(rules []core.AlertRule) {
+	t.alertRules = rules
+}
+
+// AlertRules returns the alert rules of the task
+func (t *task) AlertRules() []core.AlertRule {
+	return t.alertRules
+}
+
+// Runs returns the last runs of the task from the oldest to the latest
+func (t *task) Runs() []core.TaskRun {
+	return t.runs.all()
+}
+
 // Spin will start a task spinning in its own routine while it waits for its
 // schedule.
 func (t *task) Spin() {
@@ -212,10 +429,14 @@ func (t *task) Spin() {
 	// in time that a task starts spinning. E.g. stopping a task,
 	// waiting a period of time, and starting the task won't show
 	// misses for the interval while stopped.
-	t.lastFireTime = time.Now()
+	t.lastFireTime = schedule.ClockOf(t.schedule).Now()
 	if t.state == core.TaskStopped {
 		t.state = core.TaskSpinning
 		t.killChan = make(chan struct{})
+		// a schedule fired by events listens to its sources while the task spins
+		if r, ok := t.schedule.(schedule.Runner); ok {
+			r.Run(t.killChan)
+		}
 		// spin in a goroutine
 		go t.spin()
 	}
@@ -224,12 +445,71 @@ func (t *task) Spin() {
 func (t *task) Stop() {
 	t.Lock()
 	defer t.Unlock()
-	if t.state == core.TaskFiring || t.state == core.TaskSpinning {
+	if t.state == core.TaskFiring || t.state == core.TaskSpinning || t.state == core.TaskPaused {
+		t.clearPause()
 		t.state = core.TaskStopping
 		close(t.killChan)
 	}
 }
 
+// pause keeps a running task spinning, and so subscribed to its plugins,
+// but skips its schedule until the task is resumed. When d is not zero the
+// task is resumed once d elapses, and resumed is called. Pausing a paused task
+// replaces the duration of the pause.
+func (t *task) pause(d time.Duration, resumed func()) error {
+	t.Lock()
+	defer t.Unlock()
+	if t.state != core.TaskSpinning && t.state != core.TaskPaused {
+		return ErrTaskNotRunning
+	}
+	t.clearPause()
+	t.state = core.TaskPaused
+	if d > 0 {
+		t.pausedUntil = time.Now().Add(d)
+		pauses := t.pauses
+		t.pauseTimer = time.AfterFunc(d, func() {
+			// a pause replaced since the timer was set is left alone
+			t.Lock()
+			defer t.Unlock()
+			if t.state != core.TaskPaused || t.pauses != pauses {
+				return
+			}
+			t.clearPause()
+			t.state = core.TaskSpinning
+			go resumed()
+		})
+	}
+	return nil
+}
+
+// resume lets a paused task fire again on its schedule.
+func (t *task) resume() error {
+	t.Lock()
+	defer t.Unlock()
+	if t.state != core.TaskPaused {
+		return ErrTaskNotPaused
+	}
+	t.clearPause()
+	t.state = core.TaskSpinning
+	return nil
+}
+
+// clearPause cancels the pending resume of the task. The task must be locked.
+func (t *task) clearPause() {
+	if t.pauseTimer != nil {
+		t.pauseTimer.Stop()
+		t.pauseTimer = nil
+	}
+	t.pausedUntil = time.Time{}
+	t.pauses++
+}
+
+func (t *task) paused() bool {
+	t.Lock()
+	defer t.Unlock()
+	return t.state == core.TaskPaused
+}
+
 //Enable changes the state from Disabled to Stopped
 func (t *task) Enable() error {
 	t.Lock()
@@ -246,7 +526,8 @@ func (t *task) Enable() error {
 func (t *task) Kill() {
 	t.Lock()
 	defer t.Unlock()
-	if t.state == core.TaskFiring || t.state == core.TaskSpinning {
+	if t.state == core.TaskFiring || t.state == core.TaskSpinning || t.state == core.TaskPaused {
+		t.clearPause()
 		close(t.killChan)
 		t.state = core.TaskDisabled
 	}
@@ -257,59 +538,174 @@ func (t *task) WMap() *wmap.WorkflowMap {
 }
 
 func (t *task) Schedule() schedule.Schedule {
+	t.scheduleMutex.Lock()
+	defer t.scheduleMutex.Unlock()
 	return t.schedule
 }
 
+// update replaces the schedule and the collection of the workflow of the
+// task. The task is locked while it fires, so a running task picks the
+// changes up between two runs.
+func (t *task) update(sch schedule.Schedule, wf *schedulerWorkflow) {
+	t.Lock()
+	defer t.Unlock()
+	t.scheduleMutex.Lock()
+	t.schedule = sch
+	t.scheduleMutex.Unlock()
+	t.workflow.updateCollection(wf)
+}
+
 func (t *task) spin() {
-	var consecutiveFailures uint
+	var (
+		consecutiveFailures uint
+		// the time the schedule is waited on from and the end of the last run
+		waitFrom, lastRunEnd time.Time
+		// the first collection is deferred until the collectors are ready
+		ready    bool
+		deferred uint
+		// intervals skipped because a publisher asked to slow down
+		stretched uint
+		// the schedule was waited on from the start of the last run
+		waitedAfterRun bool
+	)
+	// The schedule is waited on while the task fires, so a response which
+	// fired before the run ended tells the run overran the interval. The skip
+	// policy waits on the schedule once the run is done instead, so the
+	// intervals which fired during the run are the ones it missed.
+	// Each spin has its own channel so a waiter left over by a previous spin
+	// never delivers a stale response.
+	schResponseChan := make(chan schedule.Response, 1)
+	// the runs are timed on the clock the schedule waits on
+	clock := schedule.ClockOf(t.schedule)
+	waitFrom = t.lastFireTime
+	// a schedule may start later than the task, e.g. delayed by its jitter
+	if s, ok := t.schedule.(schedule.Starter); ok {
+		waitFrom = s.Start(waitFrom)
+	}
+	go t.waitForSchedule(waitFrom, schResponseChan)
 	for {
 		taskLogger.Debug("task spin loop")
-		// Start go routine to wait on schedule
-		go t.waitForSchedule()
 		// wait here on
 		//  schResponseChan - response from schedule
 		//  killChan - signals task needs to be stopped
 		select {
-		case sr := <-t.schResponseChan:
+		case sr := <-schResponseChan:
 			switch sr.State() {
 			// If response show this schedule is stil active we fire
 			case schedule.Active:
-				t.missedIntervals += sr.Missed()
-				t.lastFireTime = time.Now()
-				t.hitCount++
-				t.fire()
-				if t.lastFailureTime == t.lastFireTime {
-					consecutiveFailures++
-					taskLogger.WithFields(log.Fields{
-						"_block":                    "spin",
-						"task-id":                   t.id,
-						"task-name":                 t.name,
-						"consecutive failures":      consecutiveFailures,
-						"consecutive failure limit": t.stopOnFailure,
-						"error":                     t.lastFailureMessage,
-					}).Warn("Task failed")
+				overran := waitedAfterRun
+				waitedAfterRun = false
+				// a paused task waits on its schedule without firing
+				if t.paused() {
+					waitFrom = sr.LastTime()
+					go t.waitForSchedule(waitFrom, schResponseChan)
+					continue
+				}
+				if !ready {
+					if err := t.metricsManager.CollectorsReady(t.id); err != nil {
+						deferred++
+						f := taskLogger.WithFields(log.Fields{
+							"_block":    "spin",
+							"task-id":   t.id,
+							"task-name": t.name,
+							"reason":    err.Error(),
+						})
+						if deferred == 1 {
+							f.Info("deferring the first collection until the collectors are ready")
+						} else {
+							f.Debug("collectors not ready yet")
+						}
+						waitFrom = sr.LastTime()
+						go t.waitForSchedule(waitFrom, schResponseChan)
+						continue
+					}
+					ready = true
+				}
+				if stretched > 0 {
+					stretched--
+					t.missedIntervals += 1 + sr.Missed()
+					waitFrom = sr.LastTime()
+					go t.waitForSchedule(waitFrom, schResponseChan)
+					continue
+				}
+				runs := uint(1)
+				if sr.LastTime().Before(lastRunEnd) {
+					n, last := overruns(sr, waitFrom, lastRunEnd)
+					runs = t.handleOverrun(n)
+					if runs == 0 {
+						// wait for the first interval after the skipped ones
+						waitFrom = last
+						if t.overrunPolicy == core.OverrunStretch {
+							// or a full interval after the end of the run
+							waitFrom = lastRunEnd
+						}
+						go t.waitForSchedule(waitFrom, schResponseChan)
+						continue
+					}
+				} else if overran && sr.Missed() > 0 {
+					// skipped, the run fires on the next interval
+					t.handleOverrun(sr.Missed())
 				} else {
-					consecutiveFailures = 0
+					t.missedIntervals += sr.Missed()
+				}
+				for i := uint(0); i < runs; i++ {
+					// queued runs are abandoned when the task is stopped
+					if i > 0 && t.killed() {
+						break
+					}
+					t.lastFireTime = clock.Now()
+					if i == 0 && t.overrunPolicy != core.OverrunSkip {
+						waitFrom = t.lastFireTime
+						go t.waitForSchedule(waitFrom, schResponseChan)
+					}
+					if !t.fire() {
+						break
+					}
+					lastRunEnd = clock.Now()
+					// the tasks depending on the task run once it is done
+					t.eventEmitter.Emit(&scheduler_event.TaskRunFinishedEvent{
+						TaskID: t.id,
+						Failed: t.lastFailureTime == t.lastFireTime,
+					})
+					if t.lastFailureTime == t.lastFireTime {
+						consecutiveFailures++
+						taskLogger.WithFields(log.Fields{
+							"_block":                    "spin",
+							"task-id":                   t.id,
+							"task-name":                 t.name,
+							"consecutive failures":      consecutiveFailures,
+							"consecutive failure limit": t.stopOnFailure,
+							"error":                     t.lastFailureMessage,
+						}).Warn("Task failed")
+					} else {
+						consecutiveFailures = 0
+					}
+					if consecutiveFailures >= t.stopOnFailure {
+						taskLogger.WithFields(log.Fields{
+							"_block":               "spin",
+							"task-id":              t.id,
+							"task-name":            t.name,
+							"consecutive failures": consecutiveFailures,
+							"error":                t.lastFailureMessage,
+						}).Error(ErrTaskDisabledOnFailures)
+						// You must lock on state change for tasks
+						t.Lock()
+						t.state = core.TaskDisabled
+						t.Unlock()
+						// Send task disabled event
+						event := new(scheduler_event.TaskDisabledEvent)
+						event.TaskID = t.id
+						event.Why = fmt.Sprintf("Task disabled with error: %s", t.lastFailureMessage)
+						defer t.eventEmitter.Emit(event)
+						return
+					}
 				}
-				if consecutiveFailures >= t.stopOnFailure {
-					taskLogger.WithFields(log.Fields{
-						"_block":               "spin",
-						"task-id":              t.id,
-						"task-name":            t.name,
-						"consecutive failures": consecutiveFailures,
-						"error":                t.lastFailureMessage,
-					}).Error(ErrTaskDisabledOnFailures)
-					// You must lock on state change for tasks
-					t.Lock()
-					t.state = core.TaskDisabled
-					t.Unlock()
-					// Send task disabled event
-					event := new(scheduler_event.TaskDisabledEvent)
-					event.TaskID = t.id
-					event.Why = fmt.Sprintf("Task disabled with error: %s", t.lastFailureMessage)
-					defer t.eventEmitter.Emit(event)
-					return
+				if t.overrunPolicy == core.OverrunSkip {
+					waitFrom = t.lastFireTime
+					waitedAfterRun = true
+					go t.waitForSchedule(waitFrom, schResponseChan)
 				}
+				stretched = t.workflow.stretch()
 			// Schedule has ended
 			case schedule.Ended:
 				// You must lock task to change state
@@ -338,20 +734,92 @@ func (t *task) spin() {
 	}
 }
 
-func (t *task) fire() {
+// overruns returns the number of intervals which fired during a run which
+// started at start and ended at end, and the time the last of them fired,
+// given the first response which fired during the run. The waiter is blocked
+// on that response, so the intervals which fired after it are estimated from
+// the time between the start of the run and the response.
+func overruns(sr schedule.Response, start, end time.Time) (uint, time.Time) {
+	// the triggers received during the run are coalesced into one
+	if _, ok := sr.(*schedule.TriggerScheduleResponse); ok {
+		return 1, sr.LastTime()
+	}
+	n := 1 + sr.Missed()
+	interval := sr.LastTime().Sub(start) / time.Duration(n)
+	if interval <= 0 {
+		return n, sr.LastTime()
+	}
+	more := end.Sub(sr.LastTime()) / interval
+	return n + uint(more), sr.LastTime().Add(more * interval)
+}
+
+// handleOverrun applies the overrun policy of the task to the intervals
+// which fired while the task was running and returns how many runs to fire.
+func (t *task) handleOverrun(overruns uint) uint {
+	t.overrunCount += overruns
+	var runs uint
+	switch t.overrunPolicy {
+	case core.OverrunQueue:
+		runs = overruns
+		if runs > t.overrunQueueDepth {
+			runs = t.overrunQueueDepth
+		}
+		t.missedIntervals += overruns - runs
+	case core.OverrunStretch:
+		// the intervals are stretched rather than missed
+	default:
+		t.missedIntervals += overruns
+	}
+	taskLogger.WithFields(log.Fields{
+		"_block":         "spin",
+		"task-id":        t.id,
+		"task-name":      t.name,
+		"overrun-policy": t.overrunPolicy,
+		"overruns":       overruns,
+		"runs":           runs,
+	}).Debug("Task overran its interval")
+	return runs
+}
+
+func (t *task) killed() bool {
+	select {
+	case <-t.killChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// fire runs the workflow of the task unless the task was paused since its
+// schedule fired. It returns whether the workflow ran.
+func (t *task) fire() bool {
 	t.Lock()
 	defer t.Unlock()
 
+	if t.state == core.TaskPaused {
+		return false
+	}
+	t.hitCount++
 	t.state = core.TaskFiring
 	t.workflow.Start(t)
 	t.state = core.TaskSpinning
+	return true
 }
 
-func (t *task) waitForSchedule() {
+func (t *task) waitForSchedule(last time.Time, schResponseChan chan<- schedule.Response) {
+	var sr schedule.Response
+	// a schedule waiting on events rather than time gives up when the task stops
+	if s, ok := t.Schedule().(schedule.Interruptible); ok {
+		if sr = s.WaitOrStop(last, t.killChan); sr == nil {
+			return
+		}
+	} else {
+		sr = t.Schedule().Wait(last)
+	}
 	select {
 	case <-t.killChan:
 		return
-	case t.schResponseChan <- t.schedule.Wait(t.lastFireTime):
+	case schResponseChan <- sr:
 	}
 }
 
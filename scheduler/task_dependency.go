@@ -0,0 +1,117 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+)
+
+var (
+	// ErrTaskDependencyNotTriggered - The error message for a task with dependencies without a trigger schedule
+	ErrTaskDependencyNotTriggered = errors.New("Task with dependencies must have a trigger schedule")
+	// ErrTaskDependencyNotFound - The error message for a dependency on a task which does not exist
+	ErrTaskDependencyNotFound = errors.New("Task depended on not found")
+	// ErrTaskDependencyCycle - The error message for dependencies forming a cycle
+	ErrTaskDependencyCycle = errors.New("Task dependencies form a cycle")
+)
+
+// validateDependencies returns an error if the dependencies of a task about
+// to be added are not valid or would form a cycle with the dependencies of
+// the tasks of the scheduler.
+func (s *scheduler) validateDependencies(t *task) error {
+	if len(t.dependencies) == 0 {
+		return nil
+	}
+	if _, ok := t.schedule.(*schedule.TriggerSchedule); !ok {
+		return ErrTaskDependencyNotTriggered
+	}
+	tasks := s.tasks.Table()
+	for _, d := range t.dependencies {
+		if err := core.ValidateTaskDependency(d); err != nil {
+			return err
+		}
+		if _, ok := tasks[d.TaskID]; !ok && d.TaskID != t.id {
+			return fmt.Errorf("%s: %s", ErrTaskDependencyNotFound, d.TaskID)
+		}
+	}
+	tasks[t.id] = t
+	if cycle := dependencyCycle(tasks, t.id); cycle != nil {
+		return fmt.Errorf("%s: %s", ErrTaskDependencyCycle, strings.Join(cycle, " -> "))
+	}
+	return nil
+}
+
+// dependencyCycle returns the ids of the tasks of a cycle of dependencies
+// going through the task, nil when there is none.
+func dependencyCycle(tasks map[string]*task, id string) []string {
+	visited := map[string]bool{}
+	var visit func(path []string) []string
+	visit = func(path []string) []string {
+		t, ok := tasks[path[len(path)-1]]
+		if !ok {
+			return nil
+		}
+		for _, d := range t.dependencies {
+			if d.TaskID == id {
+				return append(path, id)
+			}
+			if visited[d.TaskID] {
+				continue
+			}
+			visited[d.TaskID] = true
+			if cycle := visit(append(path, d.TaskID)); cycle != nil {
+				return cycle
+			}
+		}
+		return nil
+	}
+	return visit([]string{id})
+}
+
+// triggerDependents runs the tasks depending on a task which finished a run.
+// A dependent task which is not running is left alone.
+func (s *scheduler) triggerDependents(id string, failed bool) {
+	for _, t := range s.tasks.Table() {
+		for _, d := range t.dependencies {
+			if d.TaskID != id || !d.Matches(failed) {
+				continue
+			}
+			f := schedulerLogger.WithFields(log.Fields{
+				"_block":     "trigger-dependents",
+				"task-id":    t.id,
+				"depends-on": id,
+				"on":         d.On,
+			})
+			if err := t.trigger(); err != nil {
+				f.WithField("_error", err.Error()).Debug("dependent task not triggered")
+			} else {
+				f.Debug("dependent task triggered")
+			}
+			break
+		}
+	}
+}
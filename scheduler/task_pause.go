@@ -0,0 +1,107 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core/scheduler_event"
+	"github.com/intelsdi-x/snap/core/serror"
+)
+
+// PauseTask pauses a running task. Unlike a stopped task a paused task stays
+// subscribed to its plugins, so their pools stay warm, but it does not fire
+// until it is resumed. A task paused for a duration other than zero resumes
+// once the duration elapses.
+func (s *scheduler) PauseTask(id string, d time.Duration) []serror.SnapError {
+	logger := schedulerLogger.WithFields(log.Fields{
+		"_block":  "pause-task",
+		"task-id": id,
+	})
+	t, err := s.getTask(id)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"_error": err.Error(),
+		}).Error("error pausing task")
+		return []serror.SnapError{
+			serror.New(err),
+		}
+	}
+
+	err = t.pause(d, func() {
+		s.eventManager.Emit(&scheduler_event.TaskResumedEvent{
+			TaskID: id,
+			Source: "schedule",
+		})
+		logger.Info("task resumed")
+	})
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"_error":     err.Error(),
+			"task-state": t.State(),
+		}).Error("error pausing task")
+		return []serror.SnapError{
+			serror.New(err),
+		}
+	}
+
+	event := &scheduler_event.TaskPausedEvent{
+		TaskID: id,
+		Until:  *t.PausedUntil(),
+		Source: "user",
+	}
+	defer s.eventManager.Emit(event)
+	f := logger
+	if d > 0 {
+		f = logger.WithField("paused-for", d.String())
+	}
+	f.Info("task paused")
+	return nil
+}
+
+// ResumeTask lets a paused task fire again on its schedule.
+func (s *scheduler) ResumeTask(id string) []serror.SnapError {
+	logger := schedulerLogger.WithFields(log.Fields{
+		"_block":  "resume-task",
+		"task-id": id,
+	})
+	t, err := s.getTask(id)
+	if err == nil {
+		err = t.resume()
+	}
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"_error": err.Error(),
+		}).Error("error resuming task")
+		return []serror.SnapError{
+			serror.New(err),
+		}
+	}
+
+	event := &scheduler_event.TaskResumedEvent{
+		TaskID: id,
+		Source: "user",
+	}
+	defer s.eventManager.Emit(event)
+	logger.Info("task resumed")
+	return nil
+}
@@ -20,6 +20,7 @@ limitations under the License.
 package scheduler
 
 import (
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -110,6 +111,15 @@ func TestTask(t *testing.T) {
 			})
 		})
 
+		Convey("a task delayed by its jitter has not run yet", func() {
+			sch := schedule.NewSimpleSchedule(time.Second * 5)
+			sch.Jitter = time.Second * 4
+			task := newTask(sch, wf, newWorkManager(), c, emitter)
+			task.Spin()
+			So(task.LastRunTime().After(time.Now()), ShouldBeFalse)
+			task.Stop()
+		})
+
 		Convey("task fires", func() {
 			sch := schedule.NewSimpleSchedule(time.Nanosecond * 100)
 			task := newTask(sch, wf, newWorkManager(), c, emitter)
@@ -120,6 +130,84 @@ func TestTask(t *testing.T) {
 			task.Stop()
 		})
 
+		Convey("task defers the first collection until the collectors are ready", func() {
+			mm := &mockMetricManager{notReady: 1 << 20}
+			sch := schedule.NewSimpleSchedule(time.Millisecond)
+			task := newTask(sch, wf, newWorkManager(), mm, emitter)
+			task.Spin()
+			time.Sleep(time.Millisecond * 50)
+			So(task.HitCount(), ShouldEqual, 0)
+			So(task.MissedCount(), ShouldEqual, 0)
+			atomic.StoreInt32(&mm.notReady, 0)
+			time.Sleep(time.Millisecond * 50)
+			So(task.HitCount(), ShouldBeGreaterThan, 0)
+			task.Stop()
+		})
+
+		Convey("task is paused and resumed", func() {
+			sch := schedule.NewSimpleSchedule(time.Millisecond)
+			task := newTask(sch, wf, newWorkManager(), c, emitter)
+			So(task.pause(0, func() {}), ShouldEqual, ErrTaskNotRunning)
+			task.Spin()
+			time.Sleep(time.Millisecond * 20)
+			So(task.pause(0, func() {}), ShouldBeNil)
+			So(task.State(), ShouldEqual, core.TaskPaused)
+			So(task.PausedUntil().IsZero(), ShouldBeTrue)
+			hits := task.HitCount()
+			time.Sleep(time.Millisecond * 20)
+			So(task.HitCount(), ShouldEqual, hits)
+			So(task.resume(), ShouldBeNil)
+			So(task.resume(), ShouldEqual, ErrTaskNotPaused)
+			time.Sleep(time.Millisecond * 20)
+			So(task.HitCount(), ShouldBeGreaterThan, hits)
+			task.Stop()
+		})
+
+		Convey("task paused for a duration resumes on its own", func() {
+			sch := schedule.NewSimpleSchedule(time.Millisecond)
+			task := newTask(sch, wf, newWorkManager(), c, emitter)
+			task.Spin()
+			resumed := make(chan struct{})
+			So(task.pause(time.Millisecond*20, func() { close(resumed) }), ShouldBeNil)
+			So(task.PausedUntil().IsZero(), ShouldBeFalse)
+			select {
+			case <-resumed:
+			case <-time.After(time.Second):
+			}
+			So(task.State(), ShouldNotEqual, core.TaskPaused)
+			So(task.PausedUntil().IsZero(), ShouldBeTrue)
+			task.Stop()
+		})
+
+		Convey("a paused task is stopped", func() {
+			sch := schedule.NewSimpleSchedule(time.Millisecond * 10)
+			task := newTask(sch, wf, newWorkManager(), c, emitter)
+			task.Spin()
+			So(task.pause(time.Hour, func() {}), ShouldBeNil)
+			task.Stop()
+			time.Sleep(time.Millisecond * 10) // it is a race so we slow down the test
+			So(task.State(), ShouldEqual, core.TaskStopped)
+			So(task.PausedUntil().IsZero(), ShouldBeTrue)
+		})
+
+		Convey("task fires when triggered", func() {
+			sch := schedule.NewTriggerSchedule()
+			task := newTask(sch, wf, newWorkManager(), c, emitter)
+			So(task.trigger(), ShouldEqual, ErrTaskNotRunning)
+			task.Spin()
+			time.Sleep(time.Millisecond * 20)
+			So(task.HitCount(), ShouldEqual, 0)
+			So(task.trigger(), ShouldBeNil)
+			time.Sleep(time.Millisecond * 20)
+			So(task.HitCount(), ShouldEqual, 1)
+			task.Stop()
+			time.Sleep(time.Millisecond * 10) // it is a race so we slow down the test
+			So(task.State(), ShouldEqual, core.TaskStopped)
+
+			other := newTask(schedule.NewSimpleSchedule(time.Second), wf, newWorkManager(), c, emitter)
+			So(other.trigger(), ShouldEqual, ErrTaskNotTriggered)
+		})
+
 		Convey("Enable a running task", func() {
 			sch := schedule.NewSimpleSchedule(time.Millisecond * 10)
 			task := newTask(sch, wf, newWorkManager(), c, emitter)
@@ -129,6 +217,41 @@ func TestTask(t *testing.T) {
 			So(task.State(), ShouldEqual, core.TaskSpinning)
 		})
 
+		Convey("Task overrun policy", func() {
+			sch := schedule.NewSimpleSchedule(time.Millisecond * 10)
+			task := newTask(sch, wf, newWorkManager(), c, emitter)
+			policy, _ := task.OverrunPolicy()
+			So(policy, ShouldEqual, core.OverrunSkip)
+			Convey("skip skips the intervals which fired during the run", func() {
+				So(task.handleOverrun(3), ShouldEqual, 0)
+				So(task.OverrunCount(), ShouldEqual, 3)
+				So(task.MissedCount(), ShouldEqual, 3)
+			})
+			Convey("queue runs up to the queue depth", func() {
+				task.Option(core.OptionOverrunPolicy(core.OverrunQueue, 2))
+				So(task.handleOverrun(3), ShouldEqual, 2)
+				So(task.handleOverrun(1), ShouldEqual, 1)
+				So(task.OverrunCount(), ShouldEqual, 4)
+				So(task.MissedCount(), ShouldEqual, 1)
+			})
+			Convey("stretch waits for the next interval without missing any", func() {
+				task.Option(core.OptionOverrunPolicy(core.OverrunStretch, 0))
+				So(task.handleOverrun(3), ShouldEqual, 0)
+				So(task.OverrunCount(), ShouldEqual, 3)
+				So(task.MissedCount(), ShouldEqual, 0)
+			})
+		})
+
+		Convey("Task priority", func() {
+			sch := schedule.NewSimpleSchedule(time.Millisecond * 10)
+			task := newTask(sch, wf, newWorkManager(), c, emitter)
+			So(task.Priority(), ShouldEqual, core.TaskPriorityNormal)
+			task.Option(core.OptionTaskPriority(core.TaskPriorityLow))
+			So(task.Priority(), ShouldEqual, core.TaskPriorityLow)
+			So(task.ShedCount(), ShouldEqual, 0)
+			So(core.ValidateTaskPriority("urgent"), ShouldNotBeNil)
+		})
+
 		Convey("Enable a disabled task", func() {
 			sch := schedule.NewSimpleSchedule(time.Millisecond * 10)
 			task := newTask(sch, wf, newWorkManager(), c, emitter)
@@ -188,3 +311,46 @@ func TestTask(t *testing.T) {
 
 	})
 }
+
+type mockOverrunResponse struct {
+	missed   uint
+	lastTime time.Time
+}
+
+func (m mockOverrunResponse) State() schedule.ScheduleState { return schedule.Active }
+func (m mockOverrunResponse) Error() error                  { return nil }
+func (m mockOverrunResponse) Missed() uint                  { return m.missed }
+func (m mockOverrunResponse) LastTime() time.Time           { return m.lastTime }
+
+func TestOverruns(t *testing.T) {
+	Convey("overruns", t, func() {
+		start := time.Now()
+		Convey("counts the intervals which fired during the run", func() {
+			sr := mockOverrunResponse{lastTime: start.Add(10 * time.Millisecond)}
+			n, last := overruns(sr, start, start.Add(45*time.Millisecond))
+			So(n, ShouldEqual, 4)
+			So(last, ShouldResemble, start.Add(40*time.Millisecond))
+		})
+		Convey("counts the intervals missed before the response", func() {
+			sr := mockOverrunResponse{missed: 1, lastTime: start.Add(20 * time.Millisecond)}
+			n, last := overruns(sr, start, start.Add(25*time.Millisecond))
+			So(n, ShouldEqual, 2)
+			So(last, ShouldResemble, start.Add(20*time.Millisecond))
+		})
+		Convey("coalesces the triggers received during the run", func() {
+			sch := schedule.NewTriggerSchedule()
+			sch.Trigger()
+			sr := sch.Wait(start.Add(-time.Second))
+			n, last := overruns(sr, start.Add(-time.Second), sr.LastTime().Add(time.Hour))
+			So(n, ShouldEqual, 1)
+			So(last, ShouldResemble, sr.LastTime())
+		})
+	})
+	Convey("ValidateOverrunPolicy", t, func() {
+		So(core.ValidateOverrunPolicy(core.OverrunSkip, 0), ShouldBeNil)
+		So(core.ValidateOverrunPolicy(core.OverrunStretch, 0), ShouldBeNil)
+		So(core.ValidateOverrunPolicy(core.OverrunQueue, 2), ShouldBeNil)
+		So(core.ValidateOverrunPolicy(core.OverrunQueue, 0), ShouldNotBeNil)
+		So(core.ValidateOverrunPolicy("drop", 0), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,71 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+)
+
+// ErrTaskNotTriggered - The error message for triggering a task whose schedule is not a trigger schedule
+var ErrTaskNotTriggered = errors.New("Task schedule must be a trigger schedule")
+
+// TriggerTask fires a running task with a trigger schedule. A task triggered
+// while it runs fires again once the run ends, as the overrun policy of the
+// task allows.
+func (s *scheduler) TriggerTask(id string) []serror.SnapError {
+	logger := schedulerLogger.WithFields(log.Fields{
+		"_block":  "trigger-task",
+		"task-id": id,
+	})
+	t, err := s.getTask(id)
+	if err == nil {
+		err = t.trigger()
+	}
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"_error": err.Error(),
+		}).Error("error triggering task")
+		return []serror.SnapError{
+			serror.New(err),
+		}
+	}
+	logger.Debug("task triggered")
+	return nil
+}
+
+// trigger fires the trigger schedule of a running task.
+func (t *task) trigger() error {
+	sch, ok := t.Schedule().(*schedule.TriggerSchedule)
+	if !ok {
+		return ErrTaskNotTriggered
+	}
+	// the task is locked while it fires, so its state is read without locking
+	if st := t.State(); st != core.TaskSpinning && st != core.TaskFiring {
+		return ErrTaskNotRunning
+	}
+	sch.Trigger()
+	return nil
+}
@@ -0,0 +1,184 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+var (
+	// ErrTaskUpdateSchedule - The error message for updating the interval of a task whose schedule is not a simple schedule
+	ErrTaskUpdateSchedule = errors.New("Only the interval of a simple schedule can be updated.")
+	// ErrMetricNotInTask - The error message for removing a metric the task does not collect
+	ErrMetricNotInTask = errors.New("Metric is not collected by the task.")
+)
+
+// UpdateTask changes the schedule interval, the collector config and the
+// metrics of a task without recreating it. The changes are validated like a
+// new task, and a running task picks them up between two runs.
+func (s *scheduler) UpdateTask(id string, u core.TaskUpdate) (core.Task, core.TaskErrors) {
+	logger := schedulerLogger.WithFields(log.Fields{
+		"_block":  "update-task",
+		"task-id": id,
+	})
+	te := &taskErrors{
+		errs: make([]serror.SnapError, 0),
+	}
+	t, err := s.getTask(id)
+	if err != nil {
+		te.errs = append(te.errs, serror.New(err))
+		f := buildErrorsLog(te.Errors(), logger)
+		f.Error("error updating task")
+		return nil, te
+	}
+
+	sch := t.Schedule()
+	if u.Interval != 0 {
+		ss, ok := sch.(*schedule.SimpleSchedule)
+		if !ok {
+			te.errs = append(te.errs, serror.New(ErrTaskUpdateSchedule))
+			f := buildErrorsLog(te.Errors(), logger)
+			f.Error(ErrTaskUpdateSchedule.Error())
+			return nil, te
+		}
+		updated := &schedule.SimpleSchedule{
+			Interval: u.Interval,
+			Jitter:   ss.Jitter,
+		}
+		updated.SetJitterSeed(t.id)
+		updated.SetClock(ss.Clock())
+		sch = updated
+	}
+
+	wfMap, err := updateWorkflowMap(t.WMap(), u)
+	if err != nil {
+		te.errs = append(te.errs, serror.New(err))
+		f := buildErrorsLog(te.Errors(), logger)
+		f.Error("unable to update the workflow")
+		return nil, te
+	}
+	wf, vte := s.validateTask(sch, wfMap, logger)
+	if len(vte.errs) > 0 {
+		return nil, vte
+	}
+	if err := s.validateTenant(t, wf); err != nil {
+		te.errs = append(te.errs, serror.New(err))
+		f := buildErrorsLog(te.Errors(), logger)
+		f.Error("task out of the scope of its tenant")
+		return nil, te
+	}
+	if err := s.applyQuotas(t, sch, wf); err != nil {
+		te.errs = append(te.errs, serror.New(err))
+		f := buildErrorsLog(te.Errors(), logger)
+		f.Error("task over quota")
+		return nil, te
+	}
+
+	if state := t.State(); state != core.TaskFiring && state != core.TaskSpinning && state != core.TaskPaused {
+		t.update(sch, wf)
+		if u.Description != nil {
+			t.SetDescription(*u.Description)
+		}
+		logger.Info("task updated")
+		return t, te
+	}
+
+	// A running task subscribes to the metrics it is about to collect before
+	// the update is applied, and unsubscribes from the ones it no longer
+	// collects once applied. The new metrics were validated above, there is
+	// no rollback: subscriptions are per task, undoing them would unsubscribe
+	// the plugins of the current metrics as well.
+	oldMts, _ := s.gatherMetricsAndPlugins(t.workflow)
+	newMts, _ := s.gatherMetricsAndPlugins(wf)
+	if serrs := s.metricManager.SubscribeDeps(t.id, newMts, nil); len(serrs) > 0 {
+		te.errs = append(te.errs, serrs...)
+		f := buildErrorsLog(te.Errors(), logger)
+		f.Error("task failed to update due to dependencies")
+		return nil, te
+	}
+	t.update(sch, wf)
+	if u.Description != nil {
+		t.SetDescription(*u.Description)
+	}
+	if removed := removedMetrics(oldMts, newMts); len(removed) > 0 {
+		s.metricManager.UnsubscribeDeps(t.id, removed, nil)
+		// the plugins collecting both removed and kept metrics stay subscribed
+		s.metricManager.SubscribeDeps(t.id, newMts, nil)
+	}
+	logger.WithFields(log.Fields{
+		"task-state": t.State(),
+	}).Info("task updated")
+	return t, te
+}
+
+// updateWorkflowMap returns a copy of the workflow map with the changes made
+// to its collect node
+func updateWorkflowMap(wfMap *wmap.WorkflowMap, u core.TaskUpdate) (*wmap.WorkflowMap, error) {
+	b, err := wfMap.ToJson()
+	if err != nil {
+		return nil, err
+	}
+	updated, err := wmap.FromJson(b)
+	if err != nil {
+		return nil, err
+	}
+	cnode := updated.CollectNode
+	for ns, items := range u.Config {
+		for k, v := range items {
+			if v == nil {
+				cnode.RemoveConfigItem(ns, k)
+				continue
+			}
+			cnode.AddConfigItem(ns, k, v)
+		}
+	}
+	for _, ns := range u.RemoveMetrics {
+		if !cnode.RemoveMetric(ns) {
+			return nil, fmt.Errorf("%v: %s", ErrMetricNotInTask, ns)
+		}
+	}
+	for ns, v := range u.AddMetrics {
+		cnode.AddMetric(ns, v)
+	}
+	return updated, nil
+}
+
+// removedMetrics returns the metrics of before which are not in after
+func removedMetrics(before, after []core.Metric) []core.Metric {
+	kept := make(map[string]bool, len(after))
+	for _, m := range after {
+		kept[fmt.Sprintf("%s:%d", core.JoinNamespace(m.Namespace()), m.Version())] = true
+	}
+	var removed []core.Metric
+	for _, m := range before {
+		if !kept[fmt.Sprintf("%s:%d", core.JoinNamespace(m.Namespace()), m.Version())] {
+			removed = append(removed, m)
+		}
+	}
+	return removed
+}
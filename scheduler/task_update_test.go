@@ -0,0 +1,125 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2015 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUpdateTask(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	Convey("UpdateTask()", t, func() {
+		c := new(mockMetricManager)
+		c.setAcceptedContentType("file", core.PublisherPluginType, -1, []string{"snap.json"})
+		s := New(GetDefaultConfig())
+		s.SetMetricManager(c)
+		So(s.Start(), ShouldBeNil)
+
+		w := wmap.NewWorkflowMap()
+		w.CollectNode.AddMetric("/foo/bar", 1)
+		w.CollectNode.AddMetric("/foo/baz", 2)
+		w.CollectNode.AddConfigItem("/foo", "username", "root")
+		w.CollectNode.AddConfigItem("/foo", "port", 8080)
+		w.CollectNode.Add(wmap.NewPublishNode("file", -1))
+
+		tsk, te := s.CreateTask(schedule.NewSimpleSchedule(time.Second), w, false)
+		So(te.Errors(), ShouldBeEmpty)
+		pu := tsk.(*task).workflow.publishNodes[0]
+
+		Convey("changes the interval, the config and the metrics", func() {
+			ut, te := s.UpdateTask(tsk.ID(), core.TaskUpdate{
+				Interval: 5 * time.Second,
+				Config: map[string]map[string]interface{}{
+					"/foo":     {"username": "snap", "port": nil},
+					"/foo/qux": {"timeout": 3},
+				},
+				AddMetrics:    map[string]int{"/foo/qux": 1},
+				RemoveMetrics: []string{"/foo/baz"},
+			})
+			So(te.Errors(), ShouldBeEmpty)
+			So(ut, ShouldEqual, tsk)
+			So(tsk.Schedule().(*schedule.SimpleSchedule).Interval, ShouldEqual, 5*time.Second)
+
+			metrics := tsk.(*task).WMap().CollectNode.Metrics
+			So(len(metrics), ShouldEqual, 2)
+			So(metrics, ShouldContainKey, "/foo/bar")
+			So(metrics, ShouldContainKey, "/foo/qux")
+			So(len(tsk.(*task).workflow.metrics), ShouldEqual, 2)
+
+			cfg := tsk.(*task).workflow.configTree.Get([]string{"foo", "qux"}).Table()
+			So(cfg["username"], ShouldResemble, ctypes.ConfigValueStr{Value: "snap"})
+			So(cfg["timeout"], ShouldResemble, ctypes.ConfigValueInt{Value: 3})
+			So(cfg, ShouldNotContainKey, "port")
+
+			// the publish node keeps its state
+			So(tsk.(*task).workflow.publishNodes[0], ShouldEqual, pu)
+		})
+
+		Convey("leaves the task alone when the update is invalid", func() {
+			_, te := s.UpdateTask(tsk.ID(), core.TaskUpdate{
+				Interval:      5 * time.Second,
+				RemoveMetrics: []string{"/foo/qux"},
+			})
+			So(te.Errors(), ShouldNotBeEmpty)
+			So(te.Errors()[0].Error(), ShouldContainSubstring, ErrMetricNotInTask.Error())
+			So(tsk.Schedule().(*schedule.SimpleSchedule).Interval, ShouldEqual, time.Second)
+			So(len(tsk.(*task).WMap().CollectNode.Metrics), ShouldEqual, 2)
+
+			_, te = s.UpdateTask(tsk.ID(), core.TaskUpdate{Interval: -time.Second})
+			So(te.Errors(), ShouldNotBeEmpty)
+			So(tsk.Schedule().(*schedule.SimpleSchedule).Interval, ShouldEqual, time.Second)
+		})
+
+		Convey("only updates the interval of simple schedules", func() {
+			start := time.Now().Add(time.Hour)
+			tsk2, te := s.CreateTask(schedule.NewWindowedSchedule(time.Second, &start, nil), w, false)
+			So(te.Errors(), ShouldBeEmpty)
+			_, te = s.UpdateTask(tsk2.ID(), core.TaskUpdate{Interval: 5 * time.Second})
+			So(te.Errors(), ShouldNotBeEmpty)
+			So(te.Errors()[0].Error(), ShouldEqual, ErrTaskUpdateSchedule.Error())
+		})
+
+		Convey("changes the description, keeping the creator", func() {
+			tsk.SetCreatedBy("alice")
+			d := "collects foo"
+			_, te := s.UpdateTask(tsk.ID(), core.TaskUpdate{Description: &d})
+			So(te.Errors(), ShouldBeEmpty)
+			So(tsk.Description(), ShouldEqual, "collects foo")
+			So(tsk.CreatedBy(), ShouldEqual, "alice")
+
+			_, te = s.UpdateTask(tsk.ID(), core.TaskUpdate{})
+			So(te.Errors(), ShouldBeEmpty)
+			So(tsk.Description(), ShouldEqual, "collects foo")
+		})
+
+		Convey("returns an error when the task does not exist", func() {
+			_, te := s.UpdateTask("1234", core.TaskUpdate{})
+			So(te.Errors(), ShouldNotBeEmpty)
+		})
+	})
+}
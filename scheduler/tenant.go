@@ -0,0 +1,56 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/intelsdi-x/snap/core"
+)
+
+var (
+	// ErrMetricNotInTenantScope - The error message for a task of a tenant requesting a metric the tenant does not see
+	ErrMetricNotInTenantScope = errors.New("Metric is not in the scope of the tenant of the task")
+	// ErrTaskDependencyOtherTenant - The error message for a task depending on a task of another tenant
+	ErrTaskDependencyOtherTenant = errors.New("Task depended on belongs to another tenant")
+)
+
+// validateTenant checks that the task of a tenant only requests the metrics
+// the tenant sees and only depends on the tasks of the tenant, so that the
+// teams sharing snapd do not interfere with each other
+func (s *scheduler) validateTenant(t *task, wf *schedulerWorkflow) error {
+	tenant := t.Tenant()
+	if tenant == "" {
+		return nil
+	}
+	for _, m := range wf.metrics {
+		if !s.metricManager.MetricVisible(tenant, m.Namespace()) {
+			return fmt.Errorf("%v: %s", ErrMetricNotInTenantScope, core.JoinNamespace(m.Namespace()))
+		}
+	}
+	tasks := s.tasks.Table()
+	for _, d := range t.dependencies {
+		if dt, ok := tasks[d.TaskID]; ok && dt.Tenant() != tenant {
+			return fmt.Errorf("%s: %s", ErrTaskDependencyOtherTenant, d.TaskID)
+		}
+	}
+	return nil
+}
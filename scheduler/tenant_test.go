@@ -0,0 +1,77 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+func TestTenantScope(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	Convey("Given tenants seeing some namespaces", t, func() {
+		c := &mockMetricManager{tenants: map[string]string{"team-a": "/intel/psutil", "team-b": "/intel/docker"}}
+		s := New(GetDefaultConfig())
+		s.SetMetricManager(c)
+		So(s.Start(), ShouldBeNil)
+		w := wmap.NewWorkflowMap()
+		w.CollectNode.AddMetric("/intel/psutil/load/load1", 1)
+
+		Convey("the task of a tenant is scoped to it", func() {
+			tsk, te := s.CreateTask(schedule.NewSimpleSchedule(time.Second), w, false, core.OptionTaskTenant("team-a"))
+			So(te.Errors(), ShouldBeEmpty)
+			So(tsk.Tenant(), ShouldEqual, "team-a")
+			So(c.taskTenants[tsk.ID()], ShouldEqual, "team-a")
+
+			Convey("and unscoped once purged", func() {
+				So(s.RemoveTask(tsk.ID()), ShouldBeNil)
+				s.purgeTask(tsk.(*task))
+				So(c.taskTenants, ShouldNotContainKey, tsk.ID())
+			})
+			Convey("a task of another tenant cannot depend on it", func() {
+				wd := wmap.NewWorkflowMap()
+				wd.CollectNode.AddMetric("/intel/docker/cpu", 1)
+				_, te := s.CreateTask(schedule.NewTriggerSchedule(), wd, false,
+					core.OptionTaskTenant("team-b"),
+					core.OptionTaskDependencies([]core.TaskDependency{{TaskID: tsk.ID(), On: core.DependOnSuccess}}))
+				So(te.Errors(), ShouldHaveLength, 1)
+				So(te.Errors()[0].Error(), ShouldContainSubstring, ErrTaskDependencyOtherTenant.Error())
+			})
+		})
+		Convey("the task of a tenant cannot request the metrics of another", func() {
+			_, te := s.CreateTask(schedule.NewSimpleSchedule(time.Second), w, false, core.OptionTaskTenant("team-b"))
+			So(te.Errors(), ShouldHaveLength, 1)
+			So(te.Errors()[0].Error(), ShouldContainSubstring, ErrMetricNotInTenantScope.Error())
+		})
+		Convey("a task without a tenant sees every metric", func() {
+			tsk, te := s.CreateTask(schedule.NewSimpleSchedule(time.Second), w, false)
+			So(te.Errors(), ShouldBeEmpty)
+			So(tsk.Tenant(), ShouldBeEmpty)
+			So(c.taskTenants, ShouldNotContainKey, tsk.ID())
+		})
+	})
+}
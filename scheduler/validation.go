@@ -0,0 +1,197 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/chrono"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+)
+
+// Validation actions say what happens to a metric violating a validation
+// rule of the workflow
+const (
+	// ValidationDrop drops the metric, the default
+	ValidationDrop = "drop"
+	// ValidationTag tags the metric with SuspectTag
+	ValidationTag = "tag"
+)
+
+// SuspectTag is the tag of the metrics violating a validation rule whose
+// action is tag, valued with the constraints they violate, e.g. "max"
+const SuspectTag = "suspect"
+
+// validationRule is a validation rule of a workflow, applying to the metrics
+// whose namespace matches it
+type validationRule struct {
+	namespace    *core.WildcardNamespace
+	min, max     *float64
+	notNull      bool
+	maxStaleness time.Duration
+	tag          bool
+}
+
+// compileValidation compiles the validation rules of a workflow, sorted by
+// namespace
+func compileValidation(rules map[string]wmap.ValidationRule) ([]*validationRule, error) {
+	nss := make([]string, 0, len(rules))
+	for ns := range rules {
+		nss = append(nss, ns)
+	}
+	sort.Strings(nss)
+	compiled := make([]*validationRule, 0, len(rules))
+	for _, ns := range nss {
+		r := rules[ns]
+		if !strings.HasPrefix(ns, "/") {
+			return nil, fmt.Errorf("Invalid validation rule %s: namespace must start with '/'", ns)
+		}
+		wn, err := core.CompileWildcardNamespace(strings.Split(ns, "/")[1:])
+		if err != nil {
+			return nil, err
+		}
+		vr := &validationRule{namespace: wn, min: r.Min, max: r.Max, notNull: r.NotNull}
+		if vr.min != nil && vr.max != nil && *vr.min > *vr.max {
+			return nil, fmt.Errorf("Invalid validation rule %s: min is above max", ns)
+		}
+		if r.MaxStaleness != "" {
+			if vr.maxStaleness, err = time.ParseDuration(r.MaxStaleness); err != nil || vr.maxStaleness <= 0 {
+				return nil, fmt.Errorf("Invalid validation rule %s: max_staleness %q is not a positive duration", ns, r.MaxStaleness)
+			}
+		}
+		switch r.Action {
+		case "", ValidationDrop:
+		case ValidationTag:
+			vr.tag = true
+		default:
+			return nil, fmt.Errorf("Invalid validation rule %s: action %q is not one of %s, %s", ns, r.Action, ValidationDrop, ValidationTag)
+		}
+		compiled = append(compiled, vr)
+	}
+	return compiled, nil
+}
+
+// violations returns the constraints of the rule the metric violates at now
+func (r *validationRule) violations(m core.Metric, now time.Time) []string {
+	var violated []string
+	data := m.Data()
+	if r.notNull && data == nil {
+		violated = append(violated, "not_null")
+	}
+	if data != nil && (r.min != nil || r.max != nil) {
+		v, ok := core.NumericValue(data)
+		switch {
+		case !ok || math.IsNaN(v):
+			violated = append(violated, "numeric")
+		case r.min != nil && v < *r.min:
+			violated = append(violated, "min")
+		case r.max != nil && v > *r.max:
+			violated = append(violated, "max")
+		}
+	}
+	if r.maxStaleness > 0 && !m.Timestamp().IsZero() && now.Sub(m.Timestamp()) > r.maxStaleness {
+		violated = append(violated, "max_staleness")
+	}
+	return violated
+}
+
+// validateMetrics returns the metrics following the validation rules, with
+// those violating rules whose action is tag tagged as suspect, and the
+// numbers of metrics dropped and tagged
+func validateMetrics(metrics []core.Metric, rules []*validationRule, now time.Time) ([]core.Metric, uint, uint) {
+	var dropped, tagged uint
+	valid := make([]core.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		drop := false
+		var suspect []string
+		for _, r := range rules {
+			if !r.namespace.Match(m.Namespace()) {
+				continue
+			}
+			violated := r.violations(m, now)
+			if len(violated) == 0 {
+				continue
+			}
+			if !r.tag {
+				drop = true
+				break
+			}
+			suspect = append(suspect, violated...)
+		}
+		switch {
+		case drop:
+			dropped++
+			continue
+		case len(suspect) > 0:
+			tagged++
+			m = tagSuspect(m, suspect)
+		}
+		valid = append(valid, m)
+	}
+	return valid, dropped, tagged
+}
+
+// tagSuspect returns the metric tagged with the constraints it violates.
+// Only the metrics of plugins can be tagged.
+func tagSuspect(m core.Metric, violated []string) core.Metric {
+	mt, ok := m.(plugin.PluginMetricType)
+	if !ok {
+		return m
+	}
+	tags := make(map[string]string, len(mt.Tags_)+1)
+	for k, v := range mt.Tags_ {
+		tags[k] = v
+	}
+	tags[SuspectTag] = strings.Join(violated, ",")
+	mt.Tags_ = tags
+	return mt
+}
+
+// validate drops the metrics collected by the job which violate the
+// validation rules of the workflow, or tags them as suspect, counting them
+// in the task
+func (s *schedulerWorkflow) validate(j *collectorJob, t *task) {
+	if len(s.validation) == 0 {
+		return
+	}
+	metrics, dropped, tagged := validateMetrics(j.metrics, s.validation, chrono.Chrono.Now())
+	if dropped == 0 && tagged == 0 {
+		return
+	}
+	t.droppedMetricCount += dropped
+	t.suspectMetricCount += tagged
+	j.metrics = metrics
+	j.batch = newMetricBatch(metrics)
+	workflowLogger.WithFields(log.Fields{
+		"_block":        "workflow-validate",
+		"task-id":       t.id,
+		"task-name":     t.name,
+		"dropped-count": dropped,
+		"suspect-count": tagged,
+	}).Warn("metrics violating the validation rules of the workflow")
+}
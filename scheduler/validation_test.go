@@ -0,0 +1,106 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+
+
+Copyright 2016 Intel Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/scheduler/wmap"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValidation(t *testing.T) {
+	zero, hundred := 0.0, 100.0
+	now := time.Now()
+
+	Convey("compileValidation", t, func() {
+		Convey("compiles the rules", func() {
+			rules, err := compileValidation(map[string]wmap.ValidationRule{
+				"/intel/cpu/*":  {Min: &zero, Max: &hundred},
+				"/intel/disk/*": {NotNull: true, MaxStaleness: "1m", Action: "tag"},
+			})
+			So(err, ShouldBeNil)
+			So(rules, ShouldHaveLength, 2)
+			So(rules[1].maxStaleness, ShouldEqual, time.Minute)
+			So(rules[1].tag, ShouldBeTrue)
+		})
+		Convey("rejects a namespace without a leading '/'", func() {
+			_, err := compileValidation(map[string]wmap.ValidationRule{"intel/cpu/*": {NotNull: true}})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("rejects a min above the max", func() {
+			_, err := compileValidation(map[string]wmap.ValidationRule{"/intel/cpu/*": {Min: &hundred, Max: &zero}})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("rejects a max staleness which is not a positive duration", func() {
+			_, err := compileValidation(map[string]wmap.ValidationRule{"/intel/cpu/*": {MaxStaleness: "soon"}})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("rejects an unknown action", func() {
+			_, err := compileValidation(map[string]wmap.ValidationRule{"/intel/cpu/*": {Action: "ignore"}})
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("validateMetrics", t, func() {
+		cpu := func(data interface{}, ts time.Time) plugin.PluginMetricType {
+			return plugin.PluginMetricType{Namespace_: []string{"intel", "cpu", "0", "utilization"}, Data_: data, Timestamp_: ts}
+		}
+		disk := plugin.PluginMetricType{Namespace_: []string{"intel", "disk", "sda", "reads"}, Data_: -1}
+
+		Convey("drops the metrics violating a rule", func() {
+			rules, err := compileValidation(map[string]wmap.ValidationRule{
+				"/intel/cpu/*": {Min: &zero, Max: &hundred, NotNull: true, MaxStaleness: "1m"},
+			})
+			So(err, ShouldBeNil)
+			metrics := []core.Metric{cpu(50, now), cpu(150, now), cpu(nil, now), cpu("busy", now), cpu(50, now.Add(-time.Hour)), disk}
+			valid, dropped, tagged := validateMetrics(metrics, rules, now)
+			So(valid, ShouldResemble, []core.Metric{cpu(50, now), disk})
+			So(dropped, ShouldEqual, 4)
+			So(tagged, ShouldEqual, 0)
+		})
+		Convey("tags the metrics violating a rule whose action is tag", func() {
+			rules, err := compileValidation(map[string]wmap.ValidationRule{
+				"/intel/cpu/*": {Max: &hundred, MaxStaleness: "1m", Action: "tag"},
+			})
+			So(err, ShouldBeNil)
+			valid, dropped, tagged := validateMetrics([]core.Metric{cpu(150, now.Add(-time.Hour)), cpu(50, now)}, rules, now)
+			So(valid, ShouldHaveLength, 2)
+			So(valid[0].Tags(), ShouldResemble, map[string]string{SuspectTag: "max,max_staleness"})
+			So(valid[1].Tags(), ShouldBeNil)
+			So(dropped, ShouldEqual, 0)
+			So(tagged, ShouldEqual, 1)
+		})
+		Convey("drops a metric violating both a rule to tag and a rule to drop", func() {
+			rules, err := compileValidation(map[string]wmap.ValidationRule{
+				"/intel/*":     {Max: &hundred, Action: "tag"},
+				"/intel/cpu/*": {Max: &hundred},
+			})
+			So(err, ShouldBeNil)
+			valid, dropped, tagged := validateMetrics([]core.Metric{cpu(150, now)}, rules, now)
+			So(valid, ShouldBeEmpty)
+			So(dropped, ShouldEqual, 1)
+			So(tagged, ShouldEqual, 0)
+		})
+	})
+}
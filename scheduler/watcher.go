@@ -33,11 +33,12 @@ var (
 
 // TaskWatcher struct type
 type TaskWatcher struct {
-	id      uint64
-	taskIDs []string
-	parent  *taskWatcherCollection
-	stopped bool
-	handler core.TaskWatcherHandler
+	id            uint64
+	taskIDs       []string
+	parent        *taskWatcherCollection
+	stopped       bool
+	lifecycleOnly bool
+	handler       core.TaskWatcherHandler
 }
 
 // Close stops watching a task. Cannot be restarted.
@@ -48,9 +49,36 @@ func (t *TaskWatcher) Close() error {
 	return nil
 }
 
+type taskEventType int
+
+const (
+	taskStartedEvent taskEventType = iota
+	taskStoppedEvent
+	taskDisabledEvent
+)
+
+// taskEvent is a lifecycle event of a task kept to be replayed to watchers
+type taskEvent struct {
+	typ taskEventType
+	why string
+}
+
+func (e taskEvent) catch(h core.TaskWatcherHandler) {
+	switch e.typ {
+	case taskStartedEvent:
+		h.CatchTaskStarted()
+	case taskStoppedEvent:
+		h.CatchTaskStopped()
+	case taskDisabledEvent:
+		h.CatchTaskDisabled(e.why)
+	}
+}
+
 type taskWatcherCollection struct {
 	// Collection of task watchers by
-	coll       map[string][]*TaskWatcher
+	coll map[string][]*TaskWatcher
+	// The last lifecycle events by task ID
+	events     map[string][]taskEvent
 	tIDCounter uint64
 	mutex      *sync.Mutex
 }
@@ -58,11 +86,29 @@ type taskWatcherCollection struct {
 func newTaskWatcherCollection() *taskWatcherCollection {
 	return &taskWatcherCollection{
 		coll:       make(map[string][]*TaskWatcher),
+		events:     make(map[string][]taskEvent),
 		tIDCounter: 1,
 		mutex:      &sync.Mutex{},
 	}
 }
 
+// record keeps the event to be replayed, dropping the oldest one once
+// MaxTaskWatchReplay events are kept. The caller holds the mutex.
+func (t *taskWatcherCollection) record(taskID string, e taskEvent) {
+	events := append(t.events[taskID], e)
+	if len(events) > core.MaxTaskWatchReplay {
+		events = events[len(events)-core.MaxTaskWatchReplay:]
+	}
+	t.events[taskID] = events
+}
+
+// forget drops the events kept for a removed task
+func (t *taskWatcherCollection) forget(taskID string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.events, taskID)
+}
+
 func (t *taskWatcherCollection) rm(taskID string, tw *TaskWatcher) {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
@@ -82,7 +128,7 @@ func (t *taskWatcherCollection) rm(taskID string, tw *TaskWatcher) {
 	}
 }
 
-func (t *taskWatcherCollection) add(taskID string, twh core.TaskWatcherHandler) (*TaskWatcher, error) {
+func (t *taskWatcherCollection) add(taskID string, twh core.TaskWatcherHandler, opts core.TaskWatchOptions) (*TaskWatcher, error) {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
 	// init map for task ID if it does not eist
@@ -93,9 +139,10 @@ func (t *taskWatcherCollection) add(taskID string, twh core.TaskWatcherHandler)
 		// Assign unique ID to task watcher
 		id: t.tIDCounter,
 		// Add ref to coll for cleanup later
-		parent:  t,
-		stopped: false,
-		handler: twh,
+		parent:        t,
+		stopped:       false,
+		lifecycleOnly: opts.LifecycleOnly,
+		handler:       twh,
 	}
 	// Increment number for next time
 	t.tIDCounter++
@@ -107,6 +154,15 @@ func (t *taskWatcherCollection) add(taskID string, twh core.TaskWatcherHandler)
 		"task-id":         taskID,
 		"task-watcher-id": tw.id,
 	}).Debug("Added to task watcher collection")
+	// Replay the last events before the watcher receives new ones, which
+	// wait for the mutex
+	events := t.events[taskID]
+	if opts.Replay < uint(len(events)) {
+		events = events[uint(len(events))-opts.Replay:]
+	}
+	for _, e := range events {
+		e.catch(twh)
+	}
 	return tw, nil
 }
 
@@ -123,6 +179,9 @@ func (t *taskWatcherCollection) handleMetricCollected(taskID string, m []core.Me
 	}
 	// Walk all watchers for a task ID
 	for _, v := range t.coll[taskID] {
+		if v.lifecycleOnly {
+			continue
+		}
 		// Check if they have a catcher assigned
 		watcherLog.WithFields(log.Fields{
 			"task-id":         taskID,
@@ -136,6 +195,7 @@ func (t *taskWatcherCollection) handleMetricCollected(taskID string, m []core.Me
 func (t *taskWatcherCollection) handleTaskStarted(taskID string) {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
+	t.record(taskID, taskEvent{typ: taskStartedEvent})
 	// no taskID means no watches, early exit
 	if t.coll[taskID] == nil || len(t.coll[taskID]) == 0 {
 		// Uncomment this debug line if needed. Otherwise this is too verbose for even debug level.
@@ -159,6 +219,7 @@ func (t *taskWatcherCollection) handleTaskStarted(taskID string) {
 func (t *taskWatcherCollection) handleTaskStopped(taskID string) {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
+	t.record(taskID, taskEvent{typ: taskStoppedEvent})
 	// no taskID means no watches, early exit
 	if t.coll[taskID] == nil || len(t.coll[taskID]) == 0 {
 		// Uncomment this debug line if needed. Otherwise this is too verbose for even debug level.
@@ -182,6 +243,7 @@ func (t *taskWatcherCollection) handleTaskStopped(taskID string) {
 func (t *taskWatcherCollection) handleTaskDisabled(taskID string, why string) {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
+	t.record(taskID, taskEvent{typ: taskDisabledEvent, why: why})
 	// no taskID means no watches, early exit
 	if t.coll[taskID] == nil || len(t.coll[taskID]) == 0 {
 		// Uncomment this debug line if needed. Otherwise this is too verbose for even debug level.
@@ -65,10 +65,10 @@ func TestTaskWatching(t *testing.T) {
 		d2 := &mockCatcher{}
 		d3 := &mockCatcher{}
 
-		twc.add("1", d1)
-		x, _ := twc.add("1", d2)
-		y, _ := twc.add("2", d2)
-		twc.add("3", d3)
+		twc.add("1", d1, core.TaskWatchOptions{})
+		x, _ := twc.add("1", d2, core.TaskWatchOptions{})
+		y, _ := twc.add("2", d2, core.TaskWatchOptions{})
+		twc.add("3", d3, core.TaskWatchOptions{})
 
 		So(len(twc.coll["1"]), ShouldEqual, 2)
 		So(len(twc.coll["2"]), ShouldEqual, 1)
@@ -104,3 +104,68 @@ func TestTaskWatching(t *testing.T) {
 		So(sum, ShouldEqual, 11)
 	})
 }
+
+type recordingCatcher struct {
+	events []string
+}
+
+func (d *recordingCatcher) CatchCollection(m []core.Metric) {
+	d.events = append(d.events, "collection")
+}
+
+func (d *recordingCatcher) CatchTaskDisabled(why string) {
+	d.events = append(d.events, "disabled: "+why)
+}
+
+func (d *recordingCatcher) CatchTaskStopped() {
+	d.events = append(d.events, "stopped")
+}
+
+func (d *recordingCatcher) CatchTaskStarted() {
+	d.events = append(d.events, "started")
+}
+
+func TestTaskWatchOptions(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	Convey("Given the lifecycle events of a task", t, func() {
+		twc := newTaskWatcherCollection()
+		twc.handleTaskStarted("1")
+		twc.handleMetricCollected("1", nil)
+		twc.handleTaskStopped("1")
+		twc.handleTaskStarted("1")
+		twc.handleTaskDisabled("1", "too many failures")
+		Convey("a watcher gets the last events replayed", func() {
+			d := &recordingCatcher{}
+			twc.add("1", d, core.TaskWatchOptions{Replay: 2})
+			So(d.events, ShouldResemble, []string{"started", "disabled: too many failures"})
+		})
+		Convey("a watcher gets no more events replayed than kept", func() {
+			d := &recordingCatcher{}
+			twc.add("1", d, core.TaskWatchOptions{Replay: 10})
+			So(d.events, ShouldHaveLength, 4)
+			So(d.events[0], ShouldEqual, "started")
+		})
+		Convey("only the last events are kept", func() {
+			for i := 0; i < core.MaxTaskWatchReplay; i++ {
+				twc.handleTaskStopped("1")
+			}
+			d := &recordingCatcher{}
+			twc.add("1", d, core.TaskWatchOptions{Replay: core.MaxTaskWatchReplay + 1})
+			So(d.events, ShouldHaveLength, core.MaxTaskWatchReplay)
+			So(d.events[0], ShouldEqual, "stopped")
+		})
+		Convey("no events are replayed once the task is removed", func() {
+			twc.forget("1")
+			d := &recordingCatcher{}
+			twc.add("1", d, core.TaskWatchOptions{Replay: 10})
+			So(d.events, ShouldBeEmpty)
+		})
+		Convey("a lifecycle only watcher does not get the collections", func() {
+			d := &recordingCatcher{}
+			twc.add("1", d, core.TaskWatchOptions{LifecycleOnly: true})
+			twc.handleMetricCollected("1", nil)
+			twc.handleTaskStopped("1")
+			So(d.events, ShouldResemble, []string{"stopped"})
+		})
+	})
+}
@@ -42,8 +42,32 @@ func (c *CollectWorkflowMapNode) String(pad string) string {
 	for k, v := range c.Metrics {
 		out += pad + fmt.Sprintf("      Namespace: %s\n", k)
 		out += pad + fmt.Sprintf("         Version: %d\n", v.Version_)
+		if v.PluginName_ != "" {
+			out += pad + fmt.Sprintf("         Plugin Name: %s\n", v.PluginName_)
+		}
+		if len(v.Config_) > 0 {
+			out += pad + "         Config:\n"
+			for x, y := range v.Config_ {
+				out += pad + "            " + fmt.Sprintf("%s=%+v\n", x, y)
+			}
+		}
 	}
 	out += "\n"
+	if len(c.Queries) > 0 {
+		out += pad + "Queries:\n"
+		for _, q := range c.Queries {
+			out += pad + "      " + q + "\n"
+		}
+		out += "\n"
+	}
+	if len(c.Validation) > 0 {
+		out += pad + "Validation:\n"
+		for k, v := range c.Validation {
+			out += pad + "   " + k + "\n"
+			out += v.String(pad + "      ")
+		}
+		out += "\n"
+	}
 	out += pad + "Config:\n"
 	for k, v := range c.Config {
 		out += pad + "   " + k + "\n"
@@ -69,11 +93,20 @@ func (p *ProcessWorkflowMapNode) String(pad string) string {
 	var out string
 	out += pad + fmt.Sprintf("   Name: %s\n", p.Name)
 	out += pad + fmt.Sprintf("   Version: %d\n", p.Version)
+	if p.Remote != "" {
+		out += pad + fmt.Sprintf("   Remote: %s\n", p.Remote)
+	}
 
 	out += pad + "   Config:\n"
 	for k, v := range p.Config {
 		out += pad + "      " + fmt.Sprintf("%s=%+v\n", k, v)
 	}
+	if len(p.Routes) > 0 {
+		out += pad + "   Routes:\n"
+		for _, r := range p.Routes {
+			out += pad + "      " + r + "\n"
+		}
+	}
 	out += pad + "   Process Nodes:\n"
 	for _, pr := range p.ProcessNodes {
 		out += pr.String(pad + "   ")
@@ -94,5 +127,37 @@ func (p *PublishWorkflowMapNode) String(pad string) string {
 	for k, v := range p.Config {
 		out += pad + "      " + fmt.Sprintf("%s=%+v\n", k, v)
 	}
+	if len(p.Routes) > 0 {
+		out += pad + "   Routes:\n"
+		for _, r := range p.Routes {
+			out += pad + "      " + r + "\n"
+		}
+	}
+	if p.BackPressure != "" {
+		out += pad + fmt.Sprintf("   Back pressure: %s\n", p.BackPressure)
+	}
+	if p.ContentType != "" {
+		out += pad + fmt.Sprintf("   Content type: %s\n", p.ContentType)
+	}
+	return out
+}
+
+func (r ValidationRule) String(pad string) string {
+	var out string
+	if r.Min != nil {
+		out += pad + fmt.Sprintf("Min: %v\n", *r.Min)
+	}
+	if r.Max != nil {
+		out += pad + fmt.Sprintf("Max: %v\n", *r.Max)
+	}
+	if r.NotNull {
+		out += pad + "Not Null: true\n"
+	}
+	if r.MaxStaleness != "" {
+		out += pad + fmt.Sprintf("Max Staleness: %s\n", r.MaxStaleness)
+	}
+	if r.Action != "" {
+		out += pad + fmt.Sprintf("Action: %s\n", r.Action)
+	}
 	return out
 }
@@ -152,9 +152,25 @@ func (w *WorkflowMap) ToYaml() ([]byte, error) {
 
 type CollectWorkflowMapNode struct {
 	Metrics      map[string]metricInfo             `json:"metrics"yaml:"metrics"`
+	Queries      []string                          `json:"queries,omitempty"yaml:"queries"`
 	Config       map[string]map[string]interface{} `json:"config,omitempty"yaml:"config"`
 	ProcessNodes []ProcessWorkflowMapNode          `json:"process,omitempty"yaml:"process"`
 	PublishNodes []PublishWorkflowMapNode          `json:"publish,omitempty"yaml:"publish"`
+	// Validation holds the rules the values of the metrics collected must
+	// follow, by namespace (e.g. "/intel/cpu/*")
+	Validation map[string]ValidationRule `json:"validation,omitempty"yaml:"validation"`
+}
+
+// ValidationRule constrains the values of the metrics collected, e.g.
+// {"min": 0, "max": 100, "not_null": true, "max_staleness": "1m", "action":
+// "tag"}. A metric violating the rule is dropped, or tagged as suspect when
+// the action is "tag".
+type ValidationRule struct {
+	Min          *float64 `json:"min,omitempty"yaml:"min"`
+	Max          *float64 `json:"max,omitempty"yaml:"max"`
+	NotNull      bool     `json:"not_null,omitempty"yaml:"not_null"`
+	MaxStaleness string   `json:"max_staleness,omitempty"yaml:"max_staleness"`
+	Action       string   `json:"action,omitempty"yaml:"action"`
 }
 
 func (c *CollectWorkflowMapNode) GetMetrics() []Metric {
@@ -163,8 +179,9 @@ func (c *CollectWorkflowMapNode) GetMetrics() []Metric {
 	for k, v := range c.Metrics {
 		ns := strings.Trim(k, `/`)
 		metrics[i] = Metric{
-			namespace: strings.Split(ns, "/"),
-			version:   v.Version_,
+			namespace:  strings.Split(ns, "/"),
+			version:    v.Version_,
+			pluginName: v.PluginName_,
 		}
 		i++
 	}
@@ -188,6 +205,19 @@ func (c *CollectWorkflowMapNode) GetConfigTree() (*cdata.ConfigDataTree, error)
 		}
 		cdt.Add(ns, cdn)
 	}
+	// Config attached to a metric applies to that exact namespace only and
+	// overrides the config of the namespaces containing it
+	for ns_, mi := range c.Metrics {
+		if len(mi.Config_) == 0 {
+			continue
+		}
+		ns := strings.Split(strings.Trim(ns_, "/"), "/")
+		cdn, err := configtoConfigDataNode(mi.Config_, ns_)
+		if err != nil {
+			return nil, err
+		}
+		cdt.AddExact(ns, cdn)
+	}
 	return cdt, nil
 }
 
@@ -206,17 +236,74 @@ func (c *CollectWorkflowMapNode) Add(node interface{}) error {
 func (c *CollectWorkflowMapNode) AddMetric(ns string, v int) error {
 	// TODO regex validation here that this matches /one/two/three format
 	// c.MetricsNamespaces = append(c.MetricsNamespaces, ns)
+	if c.Metrics == nil {
+		c.Metrics = make(map[string]metricInfo)
+	}
 	c.Metrics[ns] = metricInfo{Version_: v}
 	return nil
 }
 
+// RemoveMetric removes a metric, returning false if it is not collected
+func (c *CollectWorkflowMapNode) RemoveMetric(ns string) bool {
+	if _, ok := c.Metrics[ns]; !ok {
+		return false
+	}
+	delete(c.Metrics, ns)
+	return true
+}
+
+// PinMetric adds a metric collected only by the given version of the named
+// collector plugin
+func (c *CollectWorkflowMapNode) PinMetric(ns, pluginName string, v int) {
+	c.Metrics[ns] = metricInfo{Version_: v, PluginName_: pluginName}
+}
+
+// AddQuery adds a catalog query (e.g. "ns=/intel/cpu/* AND version>=3")
+// selecting metrics to collect
+func (c *CollectWorkflowMapNode) AddQuery(q string) {
+	c.Queries = append(c.Queries, q)
+}
+
+// AddValidationRule adds the rule the values of the metrics collected under
+// the namespace (e.g. "/intel/cpu/*") must follow
+func (c *CollectWorkflowMapNode) AddValidationRule(ns string, r ValidationRule) {
+	if c.Validation == nil {
+		c.Validation = make(map[string]ValidationRule)
+	}
+	c.Validation[ns] = r
+}
+
 func (c *CollectWorkflowMapNode) AddConfigItem(ns, key string, value interface{}) {
+	if c.Config == nil {
+		c.Config = make(map[string]map[string]interface{})
+	}
 	if c.Config[ns] == nil {
 		c.Config[ns] = make(map[string]interface{})
 	}
 	c.Config[ns][key] = value
 }
 
+// RemoveConfigItem removes a config item, and the namespace once it has no
+// config left
+func (c *CollectWorkflowMapNode) RemoveConfigItem(ns, key string) {
+	delete(c.Config[ns], key)
+	if len(c.Config[ns]) == 0 {
+		delete(c.Config, ns)
+	}
+}
+
+// AddMetricConfigItem adds a config item applying only to the metric with the
+// exact namespace given (which may contain dynamic instance elements).
+// It takes precedence over the config added for the namespaces containing it.
+func (c *CollectWorkflowMapNode) AddMetricConfigItem(ns, key string, value interface{}) {
+	mi := c.Metrics[ns]
+	if mi.Config_ == nil {
+		mi.Config_ = make(map[string]interface{})
+	}
+	mi.Config_[key] = value
+	c.Metrics[ns] = mi
+}
+
 type ProcessWorkflowMapNode struct {
 	Name         string                   `json:"plugin_name"yaml:"plugin_name"`
 	Version      int                      `json:"plugin_version"yaml:"plugin_version"`
@@ -224,6 +311,12 @@ type ProcessWorkflowMapNode struct {
 	PublishNodes []PublishWorkflowMapNode `json:"publish,omitempty"yaml:"publish"`
 	// TODO processor config
 	Config map[string]interface{} `json:"config,omitempty"yaml:"config"`
+	// Routes restricts the metrics sent to the node to the namespaces
+	// matching one of them, all metrics are sent when empty
+	Routes []string `json:"routes,omitempty"yaml:"routes"`
+	// Remote runs the processor on another snapd, given the URL of its REST
+	// API (e.g. "https://aggregator:8181") or the name of a tribe member
+	Remote string `json:"remote,omitempty"yaml:"remote"`
 }
 
 func NewProcessNode(name string, version int) *ProcessWorkflowMapNode {
@@ -253,6 +346,11 @@ func (p *ProcessWorkflowMapNode) AddConfigItem(key string, value interface{}) {
 	p.Config[key] = value
 }
 
+// AddRoute adds a namespace (e.g. "/intel/disk/*") selecting metrics sent to the node
+func (p *ProcessWorkflowMapNode) AddRoute(ns string) {
+	p.Routes = append(p.Routes, ns)
+}
+
 func (p *ProcessWorkflowMapNode) GetConfigNode() (*cdata.ConfigDataNode, error) {
 	if p.Config == nil {
 		return cdata.NewNode(), nil
@@ -265,6 +363,16 @@ type PublishWorkflowMapNode struct {
 	Version int    `json:"plugin_version"yaml:"plugin_version"`
 	// TODO publisher config
 	Config map[string]interface{} `json:"config,omitempty"yaml:"config"`
+	// Routes restricts the metrics sent to the node to the namespaces
+	// matching one of them, all metrics are sent when empty
+	Routes []string `json:"routes,omitempty"yaml:"routes"`
+	// BackPressure is the policy the task follows when the publisher asks
+	// it to slow down: batch, stretch or wal
+	BackPressure string `json:"backpressure,omitempty"yaml:"backpressure"`
+	// ContentType is the content type the node is sent, one of those the
+	// plugin accepts. The encodings snapd produces (json.lines, influx.line
+	// and otlp.json) let a publisher pass the content on as is.
+	ContentType string `json:"content_type,omitempty"yaml:"content_type"`
 }
 
 func NewPublishNode(name string, version int) *PublishWorkflowMapNode {
@@ -282,6 +390,11 @@ func (p *PublishWorkflowMapNode) AddConfigItem(key string, value interface{}) {
 	p.Config[key] = value
 }
 
+// AddRoute adds a namespace (e.g. "/intel/disk/*") selecting metrics sent to the node
+func (p *PublishWorkflowMapNode) AddRoute(ns string) {
+	p.Routes = append(p.Routes, ns)
+}
+
 func (p *PublishWorkflowMapNode) GetConfigNode() (*cdata.ConfigDataNode, error) {
 	if p.Config == nil {
 		return cdata.NewNode(), nil
@@ -291,11 +404,16 @@ func (p *PublishWorkflowMapNode) GetConfigNode() (*cdata.ConfigDataNode, error)
 
 type metricInfo struct {
 	Version_ int `json:"version"yaml:"version"`
+	// PluginName_ pins the metric to the collector plugin of that name
+	PluginName_ string `json:"plugin_name,omitempty"yaml:"plugin_name"`
+	// Config_ applies to this exact metric only
+	Config_ map[string]interface{} `json:"config,omitempty"yaml:"config"`
 }
 
 type Metric struct {
-	namespace []string
-	version   int
+	namespace  []string
+	version    int
+	pluginName string
 }
 
 func (m Metric) Namespace() []string {
@@ -306,6 +424,12 @@ func (m Metric) Version() int {
 	return m.version
 }
 
+// PluginName returns the name of the collector plugin the metric is pinned
+// to, "" when it is not pinned
+func (m Metric) PluginName() string {
+	return m.pluginName
+}
+
 func isValidNamespaceString(ns string) bool {
 	b, err := regexp.MatchString("^(/[a-z0-9]+)+$", ns)
 	if err != nil {
@@ -318,25 +442,41 @@ func isValidNamespaceString(ns string) bool {
 func configtoConfigDataNode(cmap map[string]interface{}, ns string) (*cdata.ConfigDataNode, error) {
 	cdn := cdata.NewNode()
 	for ck, cv := range cmap {
-		switch v := cv.(type) {
-		case string:
-			cdn.AddItem(ck, ctypes.ConfigValueStr{Value: v})
-		case int:
-			cdn.AddItem(ck, ctypes.ConfigValueInt{Value: v})
-		case float64:
-			//working around the fact that json decodes numbers to floats
-			//if we can convert the number to an int without loss it will be an int
-			if v == float64(int(v)) {
-				cdn.AddItem(ck, ctypes.ConfigValueInt{Value: int(v)})
-			} else {
-				cdn.AddItem(ck, ctypes.ConfigValueFloat{Value: v})
-			}
-		case bool:
-			cdn.AddItem(ck, ctypes.ConfigValueBool{Value: v})
-		default:
+		v, err := toConfigValue(cv)
+		if err != nil {
 			// TODO make sure this is covered in tests!!!
-			return nil, errors.New(fmt.Sprintf("Cannot convert config value to config data node: %s=>%+v", ns, v))
+			return nil, errors.New(fmt.Sprintf("Cannot convert config value to config data node: %s=>%+v", ns, cv))
 		}
+		cdn.AddItem(ck, v)
 	}
 	return cdn, nil
 }
+
+func toConfigValue(cv interface{}) (ctypes.ConfigValue, error) {
+	switch v := cv.(type) {
+	case string:
+		return ctypes.ConfigValueStr{Value: v}, nil
+	case int:
+		return ctypes.ConfigValueInt{Value: v}, nil
+	case float64:
+		//working around the fact that json decodes numbers to floats
+		//if we can convert the number to an int without loss it will be an int
+		if v == float64(int(v)) {
+			return ctypes.ConfigValueInt{Value: int(v)}, nil
+		}
+		return ctypes.ConfigValueFloat{Value: v}, nil
+	case bool:
+		return ctypes.ConfigValueBool{Value: v}, nil
+	case []interface{}:
+		l := ctypes.ConfigValueList{Value: make([]ctypes.ConfigValue, len(v))}
+		for i, e := range v {
+			ev, err := toConfigValue(e)
+			if err != nil {
+				return nil, err
+			}
+			l.Value[i] = ev
+		}
+		return l, nil
+	}
+	return nil, fmt.Errorf("unsupported config value %+v", cv)
+}
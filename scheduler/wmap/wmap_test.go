@@ -24,6 +24,8 @@ import (
 	"io/ioutil"
 	"testing"
 
+	"github.com/intelsdi-x/snap/core/ctypes"
+
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -72,6 +74,18 @@ func TestWorkflow(t *testing.T) {
 			So(wmap.CollectNode.GetMetrics()[0].Namespace(), ShouldResemble, []string{"foo", "bar"})
 			wmap.CollectNode.GetMetrics()[0].Version()
 			So(wmap.CollectNode.GetMetrics()[0].Version(), ShouldResemble, 1)
+			So(wmap.CollectNode.GetMetrics()[0].PluginName(), ShouldBeEmpty)
+		})
+
+		Convey("PinMetric()", func() {
+			wmap := NewWorkflowMap()
+			wmap.CollectNode.PinMetric("/foo/bar", "mock", 2)
+			m := wmap.CollectNode.GetMetrics()[0]
+			So(m.Version(), ShouldEqual, 2)
+			So(m.PluginName(), ShouldEqual, "mock")
+			j, err := wmap.ToJson()
+			So(err, ShouldBeNil)
+			So(string(j), ShouldContainSubstring, `"plugin_name":"mock"`)
 		})
 
 		Convey("AddMetric()/AddConfigItem()", func() {
@@ -167,6 +181,38 @@ func TestWorkflow(t *testing.T) {
 			fmt.Println(wmap)
 		})
 
+		Convey("Converts lists in the config", func() {
+			wmap := NewWorkflowMap()
+			wmap.CollectNode.AddConfigItem("/foo/bar", "hosts", []interface{}{"a", "b"})
+			wmap.CollectNode.AddConfigItem("/foo/bar", "ports", []interface{}{float64(80), 443})
+			ctree, err := wmap.CollectNode.GetConfigTree()
+			So(err, ShouldBeNil)
+			t := ctree.Get([]string{"foo", "bar"}).Table()
+			So(t["hosts"], ShouldResemble, ctypes.ConfigValueList{Value: []ctypes.ConfigValue{ctypes.ConfigValueStr{Value: "a"}, ctypes.ConfigValueStr{Value: "b"}}})
+			So(t["ports"], ShouldResemble, ctypes.ConfigValueList{Value: []ctypes.ConfigValue{ctypes.ConfigValueInt{Value: 80}, ctypes.ConfigValueInt{Value: 443}}})
+
+			wmap.CollectNode.AddConfigItem("/foo/bar", "bad", []interface{}{struct{}{}})
+			_, err = wmap.CollectNode.GetConfigTree()
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Applies metric config to the exact metric", func() {
+			wmap := NewWorkflowMap()
+			wmap.CollectNode.AddMetric("/foo/host0/bar", 1)
+			wmap.CollectNode.AddConfigItem("/foo", "user", "stu")
+			wmap.CollectNode.AddConfigItem("/foo", "port", 80)
+			wmap.CollectNode.AddMetricConfigItem("/foo/host0/bar", "user", "root")
+			So(wmap.CollectNode.Metrics["/foo/host0/bar"].Version_, ShouldEqual, 1)
+
+			ctree, err := wmap.CollectNode.GetConfigTree()
+			So(err, ShouldBeNil)
+			t := ctree.Get([]string{"foo", "host0", "bar"}).Table()
+			So(t["user"], ShouldResemble, ctypes.ConfigValueStr{Value: "root"})
+			So(t["port"], ShouldResemble, ctypes.ConfigValueInt{Value: 80})
+			t = ctree.Get([]string{"foo", "host1", "bar"}).Table()
+			So(t["user"], ShouldResemble, ctypes.ConfigValueStr{Value: "stu"})
+		})
+
 		Convey("Converts strings to bytes or keeps byte type", func() {
 			p, err := inStringBytes("test")
 			So(p, ShouldResemble, []byte("test"))
@@ -19,7 +19,15 @@ limitations under the License.
 
 package scheduler
 
-import "sync"
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/intelsdi-x/snap/core"
+	"github.com/intelsdi-x/snap/pkg/chrono"
+)
 
 /*
 
@@ -59,6 +67,9 @@ type workManager struct {
 	collectchan    chan queuedJob
 	publishchan    chan queuedJob
 	processchan    chan queuedJob
+	collectStats   *poolStats
+	publishStats   *poolStats
+	processStats   *poolStats
 	kill           chan struct{}
 	mutex          *sync.Mutex
 }
@@ -164,21 +175,22 @@ func newWorkManager(opts ...workManagerOption) *workManager {
 	wm.collectq.Start()
 	wm.processq.Start()
 
+	wm.collectStats = newPoolStats(wm.collectWkrSize)
+	wm.publishStats = newPoolStats(wm.publishWkrSize)
+	wm.processStats = newPoolStats(wm.processWkrSize)
+
 	wm.collectWkrs = make([]*worker, wm.collectWkrSize)
 	var i uint
 	for i = 0; i < wm.collectWkrSize; i++ {
-		wm.collectWkrs[i] = newWorker(wm.collectchan)
-		go wm.collectWkrs[i].start()
+		wm.collectWkrs[i] = startWorker(wm.collectchan, wm.collectStats)
 	}
 	wm.publishWkrs = make([]*worker, wm.publishWkrSize)
 	for i = 0; i < wm.publishWkrSize; i++ {
-		wm.publishWkrs[i] = newWorker(wm.publishchan)
-		go wm.publishWkrs[i].start()
+		wm.publishWkrs[i] = startWorker(wm.publishchan, wm.publishStats)
 	}
 	wm.processWkrs = make([]*worker, wm.processWkrSize)
 	for i = 0; i < wm.processWkrSize; i++ {
-		wm.processWkrs[i] = newWorker(wm.processchan)
-		go wm.processWkrs[i].start()
+		wm.processWkrs[i] = startWorker(wm.processchan, wm.processStats)
 	}
 	return wm
 }
@@ -235,28 +247,80 @@ func (w *workManager) Work(j job) queuedJob {
 // AddCollectWorker adds a new worker to
 // the collector worker pool
 func (w *workManager) AddCollectWorker() {
-	nw := newWorker(w.collectchan)
-	go nw.start()
-	w.collectWkrs = append(w.collectWkrs, nw)
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.collectWkrs = append(w.collectWkrs, startWorker(w.collectchan, w.collectStats))
 	w.collectWkrSize++
+	w.collectStats.resize(w.collectWkrSize)
 }
 
 // AddPublishWorker adds a new worker to
 // the publisher worker pool
 func (w *workManager) AddPublishWorker() {
-	nw := newWorker(w.publishchan)
-	go nw.start()
-	w.publishWkrs = append(w.publishWkrs, nw)
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.publishWkrs = append(w.publishWkrs, startWorker(w.publishchan, w.publishStats))
 	w.publishWkrSize++
+	w.publishStats.resize(w.publishWkrSize)
 }
 
 // AddProcessWorker adds a new worker to
 // the processor worker pool
 func (w *workManager) AddProcessWorker() {
-	nw := newWorker(w.processchan)
-	go nw.start()
-	w.processWkrs = append(w.processWkrs, nw)
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.processWkrs = append(w.processWkrs, startWorker(w.processchan, w.processStats))
 	w.processWkrSize++
+	w.processStats.resize(w.processWkrSize)
+}
+
+// Resize adds workers to, or stops workers of, a worker pool until it has
+// the given number of workers. A worker stopped while running a job stops
+// once the job is done.
+func (w *workManager) Resize(pool string, size uint) error {
+	if size < 1 {
+		return ErrWorkerPoolEmpty
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	var (
+		wkrs  *[]*worker
+		n     *uint
+		ch    chan queuedJob
+		stats *poolStats
+	)
+	switch pool {
+	case core.CollectWorkerPool:
+		wkrs, n, ch, stats = &w.collectWkrs, &w.collectWkrSize, w.collectchan, w.collectStats
+	case core.ProcessWorkerPool:
+		wkrs, n, ch, stats = &w.processWkrs, &w.processWkrSize, w.processchan, w.processStats
+	case core.PublishWorkerPool:
+		wkrs, n, ch, stats = &w.publishWkrs, &w.publishWkrSize, w.publishchan, w.publishStats
+	default:
+		return fmt.Errorf("worker pool %q is not one of %v", pool, core.WorkerPools)
+	}
+	for uint(len(*wkrs)) < size {
+		*wkrs = append(*wkrs, startWorker(ch, stats))
+	}
+	for uint(len(*wkrs)) > size {
+		last := len(*wkrs) - 1
+		close((*wkrs)[last].kamikaze)
+		*wkrs = (*wkrs)[:last]
+	}
+	*n = size
+	stats.resize(size)
+	return nil
+}
+
+// Pools returns the state of the worker pools and of their queues
+func (w *workManager) Pools() []core.WorkerPool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return []core.WorkerPool{
+		w.collectStats.pool(core.CollectWorkerPool, w.collectWkrSize, w.collectq),
+		w.processStats.pool(core.ProcessWorkerPool, w.processWkrSize, w.processq),
+		w.publishStats.pool(core.PublishWorkerPool, w.publishWkrSize, w.publishq),
+	}
 }
 
 // sendToWorker is the handler given to the queue.
@@ -271,3 +335,101 @@ func (w *workManager) sendToWorker(j queuedJob) {
 		w.processchan <- j
 	}
 }
+
+// ErrWorkerPoolEmpty - The error message for a worker pool resized to no worker
+var ErrWorkerPoolEmpty = errors.New("A worker pool needs at least one worker.")
+
+// startWorker starts a worker of a pool receiving the jobs from the channel
+func startWorker(rcv <-chan queuedJob, stats *poolStats) *worker {
+	wkr := newWorker(rcv)
+	wkr.stats = stats
+	go wkr.start()
+	return wkr
+}
+
+// poolStats instruments a worker pool
+type poolStats struct {
+	sync.Mutex
+	busy     uint
+	jobs     uint64
+	lastWait time.Duration
+	maxWait  time.Duration
+	sumWait  time.Duration
+	// busyTime is the time the workers spent running jobs, workerTime the
+	// time the workers of the pool existed until updated
+	busyTime   time.Duration
+	workerTime time.Duration
+	workers    uint
+	updated    time.Time
+}
+
+func newPoolStats(workers uint) *poolStats {
+	return &poolStats{
+		workers: workers,
+		updated: chrono.Chrono.Now(),
+	}
+}
+
+// started records a worker starting a job which waited in the queue
+func (s *poolStats) started(wait time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	s.busy++
+	s.jobs++
+	s.lastWait = wait
+	s.sumWait += wait
+	if wait > s.maxWait {
+		s.maxWait = wait
+	}
+}
+
+// done records a worker done with a job it ran for the duration
+func (s *poolStats) done(run time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	s.busy--
+	s.busyTime += run
+}
+
+// resize records the pool having a new number of workers
+func (s *poolStats) resize(workers uint) {
+	s.Lock()
+	defer s.Unlock()
+	s.update()
+	s.workers = workers
+}
+
+// update accounts the time of the workers until now. The stats must be
+// locked.
+func (s *poolStats) update() {
+	now := chrono.Chrono.Now()
+	s.workerTime += time.Duration(s.workers) * now.Sub(s.updated)
+	s.updated = now
+}
+
+func (s *poolStats) pool(name string, workers uint, q *queue) core.WorkerPool {
+	s.Lock()
+	defer s.Unlock()
+	s.update()
+	p := core.WorkerPool{
+		Name:       name,
+		Workers:    workers,
+		Busy:       s.busy,
+		QueueDepth: q.depth(),
+		QueueLimit: q.limit,
+		Jobs:       s.jobs,
+		Shed:       q.shedCount(),
+		LastWait:   s.lastWait,
+		MaxWait:    s.maxWait,
+	}
+	if s.jobs > 0 {
+		p.MeanWait = s.sumWait / time.Duration(s.jobs)
+	}
+	if s.workerTime > 0 {
+		p.Utilization = float64(s.busyTime) / float64(s.workerTime)
+		if p.Utilization > 1 {
+			p.Utilization = 1
+		}
+	}
+	return p
+}
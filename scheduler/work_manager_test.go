@@ -25,6 +25,7 @@ import (
 	"time"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/intelsdi-x/snap/core"
 	. "github.com/intelsdi-x/snap/pkg/promise"
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -34,6 +35,7 @@ type mockJob struct {
 
 	errors          []error
 	worked          bool
+	priority        string
 	deadline        time.Time
 	starttime       time.Time
 	completePromise Promise
@@ -83,6 +85,7 @@ func (mj *mockJob) Deadline() time.Time  { return mj.deadline }
 func (mj *mockJob) Type() jobType        { return collectJobType }
 func (mj *mockJob) TypeString() string   { return "" }
 func (mj *mockJob) TaskID() string       { return "" }
+func (mj *mockJob) Priority() string     { return mj.priority }
 
 // Complete the first incomplete rendez-vous (if there is one)
 func (mj *mockJob) RendezVous() {
@@ -244,4 +247,36 @@ func TestWorkerManager(t *testing.T) {
 			So(mgr.collectWkrSize, ShouldEqual, len(mgr.collectWkrs))
 		})
 	})
+	Convey("Resize()", t, func() {
+		mgr := newWorkManager()
+		Convey("adds and stops workers", func() {
+			So(mgr.Resize(core.PublishWorkerPool, 3), ShouldBeNil)
+			So(mgr.publishWkrSize, ShouldEqual, 3)
+			So(mgr.publishWkrs, ShouldHaveLength, 3)
+			So(mgr.Resize(core.PublishWorkerPool, 1), ShouldBeNil)
+			So(mgr.publishWkrSize, ShouldEqual, 1)
+			So(mgr.publishWkrs, ShouldHaveLength, 1)
+		})
+		Convey("keeps at least one worker", func() {
+			So(mgr.Resize(core.CollectWorkerPool, 0), ShouldEqual, ErrWorkerPoolEmpty)
+		})
+		Convey("errors on an unknown pool", func() {
+			So(mgr.Resize("foo", 2), ShouldNotBeNil)
+		})
+	})
+	Convey("Pools()", t, func() {
+		Convey("instruments the worker pools", func() {
+			mgr := newWorkManager(CollectQSizeOption(10))
+			j := newMockJob()
+			mgr.Work(j).Promise().Await()
+			pools := mgr.Pools()
+			So(pools, ShouldHaveLength, len(core.WorkerPools))
+			So(pools[0].Name, ShouldEqual, core.CollectWorkerPool)
+			So(pools[0].Workers, ShouldEqual, 1)
+			So(pools[0].Jobs, ShouldEqual, 1)
+			So(pools[0].Busy, ShouldEqual, 0)
+			So(pools[0].QueueLimit, ShouldEqual, 10)
+			So(pools[0].Utilization, ShouldBeBetweenOrEqual, 0, 1)
+		})
+	})
 }
@@ -32,6 +32,8 @@ type worker struct {
 	id       string
 	rcv      <-chan queuedJob
 	kamikaze chan struct{}
+	// stats instruments the pool of the worker when set
+	stats *poolStats
 }
 
 func newWorker(rChan <-chan queuedJob) *worker {
@@ -47,13 +49,20 @@ func (w *worker) start() {
 	for {
 		select {
 		case q := <-w.rcv:
+			start := chrono.Chrono.Now()
+			if w.stats != nil {
+				w.stats.started(start.Sub(q.QueuedTime()))
+			}
 			// assert that deadline is not exceeded
-			if chrono.Chrono.Now().Before(q.Job().Deadline()) {
+			if start.Before(q.Job().Deadline()) {
 				q.Job().Run()
 			} else {
 				// the deadline was exceeded and this job will not run
 				q.Job().AddErrors(errors.New("Worker refused to run overdue job."))
 			}
+			if w.stats != nil {
+				w.stats.done(chrono.Chrono.Now().Sub(start))
+			}
 
 			// mark the job complete
 			q.Promise().Complete(q.Job().Errors())
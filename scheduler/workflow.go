@@ -23,6 +23,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/intelsdi-x/gomit"
@@ -31,6 +32,8 @@ import (
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/scheduler_event"
+	"github.com/intelsdi-x/snap/pkg/tlspolicy"
+	"github.com/intelsdi-x/snap/scheduler/builtin"
 	"github.com/intelsdi-x/snap/scheduler/wmap"
 )
 
@@ -54,6 +57,7 @@ var (
 
 	ErrNullCollectNode        = errors.New("Missing collection node in workflow map")
 	ErrNoMetricsInCollectNode = errors.New("Collection node has not metrics defined to collect")
+	ErrPinWithoutVersion      = errors.New("Metric pinned to a plugin has no plugin version")
 )
 
 // WmapToWorkflow attempts to convert a wmap.WorkflowMap to a schedulerWorkflow instance.
@@ -79,16 +83,33 @@ func convertCollectionNode(cnode *wmap.CollectWorkflowMapNode, wf *schedulerWork
 	if cnode == nil {
 		return ErrNullCollectNode
 	}
-	// Collection node has at least one metric in it
-	if len(cnode.Metrics) < 1 {
+	// Collection node has at least one metric or query in it
+	if len(cnode.Metrics) < 1 && len(cnode.Queries) < 1 {
 		return ErrNoMetricsInCollectNode
 	}
 	// Get core.RequestedMetric metrics
 	mts := cnode.GetMetrics()
 	wf.metrics = make([]core.RequestedMetric, len(mts))
 	for i, m := range mts {
+		// Reject malformed wildcards, tuples, ranges and exclusions now
+		// instead of silently matching no metrics
+		if _, err := core.CompileWildcardNamespace(m.Namespace()); err != nil {
+			return err
+		}
+		// A pin is the exact name and version of the plugin
+		if m.PluginName() != "" && m.Version() < 1 {
+			return fmt.Errorf("%v: %s", ErrPinWithoutVersion, core.JoinNamespace(m.Namespace()))
+		}
 		wf.metrics[i] = m
 	}
+	// Parse the catalog queries, they are resolved when the task is created
+	for _, q := range cnode.Queries {
+		mq, err := core.ParseMetricQuery(q)
+		if err != nil {
+			return err
+		}
+		wf.queries = append(wf.queries, mq)
+	}
 
 	// Get our config data tree
 	cdt, err := cnode.GetConfigTree()
@@ -96,6 +117,10 @@ func convertCollectionNode(cnode *wmap.CollectWorkflowMapNode, wf *schedulerWork
 		return err
 	}
 	wf.configTree = cdt
+	// Compile the rules the values of the metrics collected must follow
+	if wf.validation, err = compileValidation(cnode.Validation); err != nil {
+		return err
+	}
 	// Iterate over first level process nodes
 	pr, err := convertProcessNode(cnode.ProcessNodes)
 	if err != nil {
@@ -126,6 +151,10 @@ func convertProcessNode(pr []wmap.ProcessWorkflowMapNode) ([]*processNode, error
 		if err != nil {
 			return nil, err
 		}
+		routes, err := compileRoutes(p.Routes)
+		if err != nil {
+			return nil, err
+		}
 
 		// If version is not 1+ we use -1 to indicate we want
 		// the plugin manager to select the highest version
@@ -139,6 +168,14 @@ func convertProcessNode(pr []wmap.ProcessWorkflowMapNode) ([]*processNode, error
 			config:       cdn,
 			ProcessNodes: prC,
 			PublishNodes: puC,
+			routes:       routes,
+		}
+		if p.Remote != "" {
+			rp, err := newRemoteProcessor(p.Remote)
+			if err != nil {
+				return nil, err
+			}
+			prNodes[i].remote = rp
 		}
 	}
 	return prNodes, nil
@@ -151,6 +188,13 @@ func convertPublishNode(pu []wmap.PublishWorkflowMapNode) ([]*publishNode, error
 		if err != nil {
 			return nil, err
 		}
+		routes, err := compileRoutes(p.Routes)
+		if err != nil {
+			return nil, err
+		}
+		if err := core.ValidateBackPressurePolicy(p.BackPressure); err != nil {
+			return nil, err
+		}
 		// If version is not 1+ we use -1 to indicate we want
 		// the plugin manager to select the highest version
 		// available on plugin calls
@@ -158,20 +202,118 @@ func convertPublishNode(pu []wmap.PublishWorkflowMapNode) ([]*publishNode, error
 			p.Version = -1
 		}
 		puNodes[i] = &publishNode{
-			name:    p.Name,
-			version: p.Version,
-			config:  cdn,
+			name:         p.Name,
+			version:      p.Version,
+			config:       cdn,
+			routes:       routes,
+			backPressure: newBackPressure(p.BackPressure),
+			contentType:  p.ContentType,
+		}
+		if builtin.IsBuiltin(p.Name) {
+			bp, err := builtin.New(p.Name, cdn.Table())
+			if err != nil {
+				return nil, err
+			}
+			puNodes[i].builtin = bp
 		}
 	}
 	return puNodes, nil
 }
 
+// setFileDir sets the directory the built-in publishers of the nodes write
+// their files under
+func setFileDir(pus []*publishNode, dir string) error {
+	for _, pu := range pus {
+		if fw, ok := pu.builtin.(builtin.FileWriting); ok {
+			if err := fw.SetFileDir(dir); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setTLSPolicy restricts the TLS connections of the built-in publishers of
+// the nodes to the policy
+func setTLSPolicy(pus []*publishNode, p *tlspolicy.Config) {
+	for _, pu := range pus {
+		if tr, ok := pu.builtin.(builtin.TLSRestricting); ok {
+			tr.SetTLSPolicy(p)
+		}
+	}
+}
+
+// closePublishers closes the built-in publishers of the nodes, logging the
+// errors
+func closePublishers(pus []*publishNode, logger *log.Entry) {
+	for _, pu := range pus {
+		if pu.builtin == nil {
+			continue
+		}
+		if err := pu.builtin.Close(); err != nil {
+			logger.WithFields(log.Fields{
+				"_error":         err.Error(),
+				"publisher-name": pu.name,
+			}).Warn("unable to close the built-in publisher")
+		}
+	}
+}
+
+// resetPluginConfig sets the config of the process and publish nodes back to
+// the config of the workflow map nodes they were converted from, dropping the
+// keys merged from a global config since then.
+func resetPluginConfig(prnodes []*processNode, pbnodes []*publishNode, pr []wmap.ProcessWorkflowMapNode, pu []wmap.PublishWorkflowMapNode) error {
+	if len(prnodes) != len(pr) || len(pbnodes) != len(pu) {
+		return errors.New("workflow does not match its workflow map")
+	}
+	for i, p := range pr {
+		cdn, err := p.GetConfigNode()
+		if err != nil {
+			return err
+		}
+		resetConfigNode(prnodes[i].config, cdn)
+		if err := resetPluginConfig(prnodes[i].ProcessNodes, prnodes[i].PublishNodes, p.ProcessNodes, p.PublishNodes); err != nil {
+			return err
+		}
+	}
+	for i, p := range pu {
+		cdn, err := p.GetConfigNode()
+		if err != nil {
+			return err
+		}
+		resetConfigNode(pbnodes[i].config, cdn)
+	}
+	return nil
+}
+
+// resetConfigNode makes the items of node those of from, in place since the
+// node is shared with the runs of the workflow
+func resetConfigNode(node, from *cdata.ConfigDataNode) {
+	t := from.Table()
+	var dropped []string
+	for k := range node.Table() {
+		if _, ok := t[k]; !ok {
+			dropped = append(dropped, k)
+		}
+	}
+	for _, k := range dropped {
+		node.DeleteItem(k)
+	}
+	node.Merge(from)
+}
+
 type schedulerWorkflow struct {
 	state WorkflowState
 	// Metrics to collect
 	metrics []core.RequestedMetric
+	// Catalog queries selecting more metrics to collect
+	queries []*core.MetricQuery
 	// The config data tree for collectors
-	configTree   *cdata.ConfigDataTree
+	configTree *cdata.ConfigDataTree
+	// The rules the values of the metrics collected must follow
+	validation []*validationRule
+	// The quota bounding the number of metrics collected on each run
+	metricQuota  metricQuota
 	processNodes []*processNode
 	publishNodes []*publishNode
 	// workflowMap used to generate this workflow
@@ -186,6 +328,10 @@ type processNode struct {
 	ProcessNodes       []*processNode
 	PublishNodes       []*publishNode
 	InboundContentType string
+	routes             []*core.WildcardNamespace
+	// remote is the processor of another snapd the node uses instead of a
+	// plugin of this snapd
+	remote *remoteProcessor
 }
 
 func (p *processNode) Name() string {
@@ -209,6 +355,13 @@ type publishNode struct {
 	version            int
 	config             *cdata.ConfigDataNode
 	InboundContentType string
+	routes             []*core.WildcardNamespace
+	// backPressure is nil when the publisher asking to slow down fails the run
+	backPressure *backPressure
+	// contentType is the content type requested by the workflow, if any
+	contentType string
+	// builtin is the built-in publisher the node uses instead of a plugin
+	builtin builtin.Publisher
 }
 
 func (p *publishNode) Name() string {
@@ -236,6 +389,15 @@ func (s *schedulerWorkflow) BindPluginContentTypes(mm managesPluginContentTypes)
 
 func bindPluginContentTypes(pus []*publishNode, prs []*processNode, mm managesPluginContentTypes, lct []string) error {
 	for _, pr := range prs {
+		// the snapd running a remote processor converts the content for it,
+		// and what it returns is decoded when a node needs another content type
+		if pr.remote != nil {
+			pr.InboundContentType = plugin.SnapGOBContentType
+			if err := bindPluginContentTypes(pr.PublishNodes, pr.ProcessNodes, mm, []string{plugin.SnapGOBContentType}); err != nil {
+				return err
+			}
+			continue
+		}
 		act, rct, err := mm.GetPluginContentTypes(pr.Name(), core.ProcessorPluginType, pr.Version())
 		if err != nil {
 			return err
@@ -255,13 +417,11 @@ func bindPluginContentTypes(pus []*publishNode, prs []*processNode, mm managesPl
 		// the conversion
 		if pr.InboundContentType == "" {
 			for _, ac := range act {
-				switch ac {
-				case plugin.SnapGOBContentType:
-					pr.InboundContentType = plugin.SnapGOBContentType
-				case plugin.SnapJSONContentType:
-					pr.InboundContentType = plugin.SnapJSONContentType
-				case plugin.SnapAllContentType:
+				switch {
+				case ac == plugin.SnapAllContentType:
 					pr.InboundContentType = plugin.SnapGOBContentType
+				case core.CanDecode(ac):
+					pr.InboundContentType = ac
 				}
 			}
 			// else we return an error
@@ -275,21 +435,44 @@ func bindPluginContentTypes(pus []*publishNode, prs []*processNode, mm managesPl
 		}
 	}
 	for _, pu := range pus {
+		if pu.builtin != nil {
+			pu.InboundContentType = pu.builtin.ContentType()
+			continue
+		}
 		act, _, err := mm.GetPluginContentTypes(pu.Name(), core.PublisherPluginType, pu.Version())
 		if err != nil {
 			return err
 		}
+		// the content type requested by the workflow must be accepted by
+		// the plugin and be one snap has a codec for
+		if pu.contentType != "" {
+			if !acceptsContentType(act, pu.contentType) {
+				return fmt.Errorf("Invalid workflow.  Plugin '%s' does not accept the content type '%s', it accepts '%v'.", pu.Name(), pu.contentType, act)
+			}
+			if !core.CanEncode(pu.contentType) {
+				return fmt.Errorf("Invalid workflow.  Content type '%s' of plugin '%s' cannot be produced by snap.", pu.contentType, pu.Name())
+			}
+			pu.InboundContentType = pu.contentType
+		}
 		// if the inbound content type isn't set yet snap may be able to do
 		// the conversion
 		if pu.InboundContentType == "" {
 			for _, ac := range act {
-				switch ac {
-				case plugin.SnapGOBContentType:
-					pu.InboundContentType = plugin.SnapGOBContentType
-				case plugin.SnapJSONContentType:
-					pu.InboundContentType = plugin.SnapJSONContentType
-				case plugin.SnapAllContentType:
+				switch {
+				case ac == plugin.SnapAllContentType:
 					pu.InboundContentType = plugin.SnapGOBContentType
+				case core.CanDecode(ac):
+					pu.InboundContentType = ac
+				}
+			}
+			// a publisher accepting only encodings snap produces is sent
+			// the first of them
+			if pu.InboundContentType == "" {
+				for _, ac := range act {
+					if core.CanEncode(ac) {
+						pu.InboundContentType = ac
+						break
+					}
 				}
 			}
 			// else we return an error
@@ -301,6 +484,20 @@ func bindPluginContentTypes(pus []*publishNode, prs []*processNode, mm managesPl
 	return nil
 }
 
+// acceptsContentType returns whether the content type is one of the
+// accepted ones, snap.* standing for the snap content types
+func acceptsContentType(accepted []string, contentType string) bool {
+	for _, ac := range accepted {
+		if ac == contentType {
+			return true
+		}
+		if ac == plugin.SnapAllContentType && plugin.IsSnapContentType(contentType) {
+			return true
+		}
+	}
+	return false
+}
+
 // Start starts a workflow
 func (s *schedulerWorkflow) Start(t *task) {
 	workflowLogger.WithFields(log.Fields{
@@ -309,11 +506,30 @@ func (s *schedulerWorkflow) Start(t *task) {
 		"task-name": t.name,
 	}).Info(fmt.Sprintf("Starting workflow for task (%s\\%s)", t.id, t.name))
 	s.state = WorkflowStarted
-	j := newCollectorJob(s.metrics, t.deadlineDuration, t.metricsManager, t.workflow.configTree, t.id)
+	j := newCollectorJob(s.metrics, t.deadlineDuration, t.metricsManager, t.workflow.configTree, t.id, t.priority)
+	j.(*collectorJob).shardIndex, j.(*collectorJob).shardCount = t.shard()
+	j.(*collectorJob).timestampPolicy, j.(*collectorJob).tick = t.timestampPolicy, t.lastFireTime
+	j.(*collectorJob).metricQuota = s.metricQuota
+
+	start := time.Now()
+	run := newRunRecorder(start)
+	defer run.finish(t.runs)
 
 	// dispatch 'collect' job to be worked
 	// Block until the job has been either run or skipped.
 	errors := t.manager.Work(j).Promise().Await()
+	run.step(core.CollectorPluginType.String(), "", start, errors)
+
+	if jobShed(errors) {
+		t.shedCount++
+		workflowLogger.WithFields(log.Fields{
+			"_block":    "workflow-start",
+			"task-id":   t.id,
+			"task-name": t.name,
+			"priority":  t.priority,
+		}).Warn("collection shed, work queue is full")
+		return
+	}
 
 	if len(errors) != 0 {
 		t.RecordFailure(j.Errors())
@@ -324,14 +540,29 @@ func (s *schedulerWorkflow) Start(t *task) {
 		return
 	}
 
+	s.validate(j.(*collectorJob), t)
+
 	// Send event
 	event := new(scheduler_event.MetricCollectedEvent)
 	event.TaskID = t.id
 	event.Metrics = j.(*collectorJob).metrics
 	defer s.eventEmitter.Emit(event)
+	run.collected(len(event.Metrics))
 
 	// walk through the tree and dispatch work
-	workJobs(s.processNodes, s.publishNodes, t, j)
+	workJobs(s.processNodes, s.publishNodes, t, j, run)
+}
+
+// updateCollection replaces the metrics, queries, config and validation rules
+// of the collection of the workflow with the ones of another workflow, keeping the process and publish
+// nodes along with their state
+func (s *schedulerWorkflow) updateCollection(wf *schedulerWorkflow) {
+	s.metrics = wf.metrics
+	s.queries = wf.queries
+	s.configTree = wf.configTree
+	s.validation = wf.validation
+	s.metricQuota = wf.metricQuota
+	s.workflowMap = wf.workflowMap
 }
 
 func (s *schedulerWorkflow) State() WorkflowState {
@@ -344,7 +575,7 @@ func (s *schedulerWorkflow) StateString() string {
 
 // workJobs takes a slice of process and publish nodes and submits jobs for each for a task.
 // It then iterates down any process nodes to submit their child node jobs for the task
-func workJobs(prs []*processNode, pus []*publishNode, t *task, pj job) {
+func workJobs(prs []*processNode, pus []*publishNode, t *task, pj job, run *runRecorder) {
 	// optimize for no jobs
 	if len(prs) == 0 && len(pus) == 0 {
 		return
@@ -364,14 +595,14 @@ func workJobs(prs []*processNode, pus []*publishNode, t *task, pj job) {
 		// increment the wait group (before starting goroutine to prevent a race condition)
 		wg.Add(1)
 		// Start goroutine to submit the process job
-		go submitProcessJob(pj, t, wg, pr)
+		go submitProcessJob(pj, t, wg, pr, run)
 	}
 	// range over the publish jobs and call submitPublishJob
 	for _, pu := range pus {
 		// increment the wait group (before starting goroutine to prevent a race condition)
 		wg.Add(1)
 		// Start goroutine to submit the process job
-		go submitPublishJob(pj, t, wg, pu)
+		go submitPublishJob(pj, t, wg, pu, run)
 	}
 	// Wait until all job submisson goroutines are done
 	wg.Wait()
@@ -385,11 +616,42 @@ func workJobs(prs []*processNode, pus []*publishNode, t *task, pj job) {
 	}).Debug("Batch submission complete")
 }
 
-func submitProcessJob(pj job, t *task, wg *sync.WaitGroup, pr *processNode) {
+func submitProcessJob(pj job, t *task, wg *sync.WaitGroup, pr *processNode, run *runRecorder) {
 	// Decrement the waitgroup
 	defer wg.Done()
-	// Create a new process job
-	j := newProcessJob(pj, pr.Name(), pr.Version(), pr.InboundContentType, pr.config.Table(), t.metricsManager, t.id)
+	start := time.Now()
+	// Send the node only the metrics matching its routes
+	pj, err := routeJob(pj, pr.routes)
+	if err != nil {
+		t.RecordFailure([]error{err})
+		run.step(core.ProcessorPluginType.String(), pluginString(pr.Name(), pr.Version()), start, []error{err})
+		workflowLogger.WithFields(log.Fields{
+			"_block":          "submit-process-job",
+			"task-id":         t.id,
+			"task-name":       t.name,
+			"process-name":    pr.Name(),
+			"process-version": pr.Version(),
+			"error":           err.Error(),
+		}).Warn("Routing metrics to process job failed")
+		return
+	}
+	if pj == nil {
+		workflowLogger.WithFields(log.Fields{
+			"_block":          "submit-process-job",
+			"task-id":         t.id,
+			"task-name":       t.name,
+			"process-name":    pr.Name(),
+			"process-version": pr.Version(),
+		}).Debug("No metrics routed to process job")
+		return
+	}
+	// Create a new process job, processed by another snapd for a remote
+	// processor
+	var processor processesMetrics = t.metricsManager
+	if pr.remote != nil {
+		processor = pr.remote
+	}
+	j := newProcessJob(pj, pr.Name(), pr.Version(), pr.InboundContentType, pr.config.Table(), processor, t.id)
 	workflowLogger.WithFields(log.Fields{
 		"_block":           "submit-process-job",
 		"task-id":          t.id,
@@ -400,6 +662,7 @@ func submitProcessJob(pj job, t *task, wg *sync.WaitGroup, pr *processNode) {
 	}).Debug("Submitting process job")
 	// Submit the job against the task.managesWork
 	errors := t.manager.Work(j).Promise().Await()
+	run.step(core.ProcessorPluginType.String(), pluginString(pr.Name(), pr.Version()), start, errors)
 	// Check for errors and update the task
 	if len(errors) != 0 {
 		// Record the failures in the task
@@ -424,14 +687,79 @@ func submitProcessJob(pj job, t *task, wg *sync.WaitGroup, pr *processNode) {
 		"parent-node-type": pj.TypeString(),
 	}).Debug("Process job completed")
 	// Iterate into any child process or publish nodes
-	workJobs(pr.ProcessNodes, pr.PublishNodes, t, j)
+	workJobs(pr.ProcessNodes, pr.PublishNodes, t, j, run)
 }
 
-func submitPublishJob(pj job, t *task, wg *sync.WaitGroup, pu *publishNode) {
+func submitPublishJob(pj job, t *task, wg *sync.WaitGroup, pu *publishNode, run *runRecorder) {
 	// Decrement the waitgroup
 	defer wg.Done()
-	// Create a new process job
-	j := newPublishJob(pj, pu.Name(), pu.Version(), pu.InboundContentType, pu.config.Table(), t.metricsManager, t.id)
+	start := time.Now()
+	// Send the node only the metrics matching its routes
+	pj, err := routeJob(pj, pu.routes)
+	if err != nil {
+		t.RecordFailure([]error{err})
+		run.step(core.PublisherPluginType.String(), pluginString(pu.Name(), pu.Version()), start, []error{err})
+		workflowLogger.WithFields(log.Fields{
+			"_block":          "submit-publish-job",
+			"task-id":         t.id,
+			"task-name":       t.name,
+			"publish-name":    pu.Name(),
+			"publish-version": pu.Version(),
+			"error":           err.Error(),
+		}).Warn("Routing metrics to publish job failed")
+		return
+	}
+	if pj == nil {
+		workflowLogger.WithFields(log.Fields{
+			"_block":          "submit-publish-job",
+			"task-id":         t.id,
+			"task-name":       t.name,
+			"publish-name":    pu.Name(),
+			"publish-version": pu.Version(),
+		}).Debug("No metrics routed to publish job")
+		return
+	}
+	// Hold the metrics back, or publish them with the metrics held back
+	// before, as the back-pressure policy of the node tells
+	var taken *runContent
+	if bp := pu.backPressure; bp != nil {
+		var rc runContent
+		rc, err = publishContent(pj, heldContentType(pu.InboundContentType))
+		if err == nil {
+			taken, err = bp.take(rc)
+		}
+		if err != nil {
+			t.RecordFailure([]error{err})
+			run.step(core.PublisherPluginType.String(), pluginString(pu.Name(), pu.Version()), start, []error{err})
+			workflowLogger.WithFields(log.Fields{
+				"_block":          "submit-publish-job",
+				"task-id":         t.id,
+				"task-name":       t.name,
+				"publish-name":    pu.Name(),
+				"publish-version": pu.Version(),
+				"error":           err.Error(),
+			}).Warn("Applying back pressure to publish job failed")
+			return
+		}
+		if taken == nil {
+			workflowLogger.WithFields(log.Fields{
+				"_block":          "submit-publish-job",
+				"task-id":         t.id,
+				"task-name":       t.name,
+				"publish-name":    pu.Name(),
+				"publish-version": pu.Version(),
+			}).Debug("Metrics held back for the next batch")
+			return
+		}
+		pj = contentJob(pj, *taken)
+	}
+	// Create a new process job, published by snapd itself for a built-in
+	// publisher
+	var publisher publishesMetrics = t.metricsManager
+	if pu.builtin != nil {
+		publisher = pu.builtin
+	}
+	j := newPublishJob(pj, pu.Name(), pu.Version(), pu.InboundContentType, pu.config.Table(), publisher, t.id)
 	workflowLogger.WithFields(log.Fields{
 		"_block":           "submit-publish-job",
 		"task-id":          t.id,
@@ -442,6 +770,33 @@ func submitPublishJob(pj job, t *task, wg *sync.WaitGroup, pu *publishNode) {
 	}).Debug("Submitting publish job")
 	// Submit the job against the task.managesWork
 	errors := t.manager.Work(j).Promise().Await()
+	// A publisher asking to slow down does not fail the run: the node
+	// responds as its back-pressure policy tells, and drops the metrics
+	// without a policy
+	bp := pu.backPressure
+	if slowDown(errors) {
+		errors = nil
+		policy := ""
+		if bp != nil {
+			policy = bp.policy
+			if err := bp.slowedDown(*taken); err != nil {
+				errors = []error{err}
+			}
+		}
+		workflowLogger.WithFields(log.Fields{
+			"_block":          "submit-publish-job",
+			"task-id":         t.id,
+			"task-name":       t.name,
+			"publish-name":    pu.Name(),
+			"publish-version": pu.Version(),
+			"policy":          policy,
+		}).Info("Publisher asked to slow down")
+	} else if bp != nil && len(errors) == 0 {
+		if err := bp.accepted(); err != nil {
+			errors = []error{err}
+		}
+	}
+	run.step(core.PublisherPluginType.String(), pluginString(pu.Name(), pu.Version()), start, errors)
 	// Check for errors and update the task
 	if len(errors) != 0 {
 		// Record the failures in the task
@@ -468,3 +823,14 @@ func submitPublishJob(pj job, t *task, wg *sync.WaitGroup, pu *publishNode) {
 	// Publish nodes cannot contain child nodes (publish is a terminal node)
 	// so unlike process nodes there is not a call to workJobs here for child nodes.
 }
+
+// jobShed returns true if the job was dropped from a full work queue to make
+// room for jobs of a higher priority
+func jobShed(errs []error) bool {
+	for _, err := range errs {
+		if qe, ok := err.(*queuingError); ok && qe.Err == errJobShed {
+			return true
+		}
+	}
+	return false
+}
@@ -60,6 +60,9 @@ func (p *processNode) String(args ...string) string {
 	}
 	out += fmt.Sprintf("%sName: %s\n", pad, p.Name())
 	out += fmt.Sprintf("%s   Version: %d\n", pad, p.Version())
+	if p.remote != nil {
+		out += fmt.Sprintf("%s   Remote: %s\n", pad, p.remote.remote)
+	}
 	out += fmt.Sprintf("%s   Config:\n", pad)
 	for k, v := range p.Config().Table() {
 		out += fmt.Sprintf("%s      %s=%+v\n", pad, k, v)
@@ -30,6 +30,7 @@ import (
 
 	"github.com/intelsdi-x/gomit"
 	"github.com/intelsdi-x/snap/control"
+	"github.com/intelsdi-x/snap/control/plugin"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/cdata"
 	"github.com/intelsdi-x/snap/core/control_event"
@@ -184,6 +185,20 @@ func TestCollectPublishWorkflow(t *testing.T) {
 					So(t.LastFailureMessage(), ShouldBeEmpty)
 					So(t.FailedCount(), ShouldEqual, 0)
 					So(t.HitCount(), ShouldBeGreaterThan, metricsToCollect)
+					Convey("The runs of the task are recorded", func() {
+						runs := t.Runs()
+						So(runs, ShouldNotBeEmpty)
+						So(len(runs), ShouldBeLessThanOrEqualTo, DefaultTaskRunHistory)
+						r := runs[0]
+						So(r.Failed(), ShouldBeFalse)
+						So(r.MetricCount, ShouldEqual, 2)
+						So(r.Steps, ShouldHaveLength, 3)
+						So(r.Steps[0].Type, ShouldEqual, "collector")
+						So(r.Steps[1].Type, ShouldEqual, "processor")
+						So(r.Steps[1].Plugin, ShouldEqual, "passthru:1")
+						So(r.Steps[2].Type, ShouldEqual, "publisher")
+						So(r.Steps[2].Plugin, ShouldEqual, "file:3")
+					})
 				})
 			})
 		})
@@ -311,18 +326,74 @@ func (m *Mock1) Job() job {
 	return nil
 }
 
+func (m *Mock1) QueuedTime() time.Time {
+	return time.Time{}
+}
+
 func (m *Mock1) AndThen(_ func([]error)) {
 }
 
 func (m *Mock1) AndThenUntil(_ time.Duration, _ func([]error)) {
 }
 
+func TestPublishContentTypes(t *testing.T) {
+	Convey("Given a publisher accepting the snap content types and the line protocol", t, func() {
+		mm := &mockMetricManager{}
+		mm.setAcceptedContentType("influx", core.PublisherPluginType, 1, []string{plugin.SnapAllContentType, plugin.InfluxLineContentType})
+		mm.setAcceptedContentType("lines", core.PublisherPluginType, 1, []string{plugin.InfluxLineContentType})
+		Convey("it is sent snap content by default", func() {
+			pu := &publishNode{name: "influx", version: 1}
+			So(bindPluginContentTypes([]*publishNode{pu}, nil, mm, []string{plugin.SnapGOBContentType}), ShouldBeNil)
+			So(pu.InboundContentType, ShouldEqual, plugin.SnapGOBContentType)
+		})
+		Convey("it is sent the encoding requested by the workflow", func() {
+			pu := &publishNode{name: "influx", version: 1, contentType: plugin.InfluxLineContentType}
+			So(bindPluginContentTypes([]*publishNode{pu}, nil, mm, []string{plugin.SnapGOBContentType}), ShouldBeNil)
+			So(pu.InboundContentType, ShouldEqual, plugin.InfluxLineContentType)
+		})
+		Convey("an encoding the plugin does not accept is refused", func() {
+			pu := &publishNode{name: "influx", version: 1, contentType: plugin.OTLPJSONContentType}
+			So(bindPluginContentTypes([]*publishNode{pu}, nil, mm, []string{plugin.SnapGOBContentType}), ShouldNotBeNil)
+		})
+		Convey("a publisher accepting only an encoding is sent it", func() {
+			pu := &publishNode{name: "lines", version: 1}
+			So(bindPluginContentTypes([]*publishNode{pu}, nil, mm, []string{plugin.SnapGOBContentType}), ShouldBeNil)
+			So(pu.InboundContentType, ShouldEqual, plugin.InfluxLineContentType)
+		})
+	})
+}
+
+func TestResetPluginConfig(t *testing.T) {
+	Convey("Given a workflow whose publisher config was merged with a global config", t, func() {
+		w := wmap.NewWorkflowMap()
+		pr := wmap.NewProcessNode("passthru", 1)
+		pu := wmap.NewPublishNode("influx", 1)
+		pu.AddConfigItem("host", "localhost")
+		pr.Add(pu)
+		w.CollectNode.Add(pr)
+		prNodes, err := convertProcessNode(w.CollectNode.ProcessNodes)
+		So(err, ShouldBeNil)
+		cfg := prNodes[0].PublishNodes[0].config
+		cfg.AddItem("host", ctypes.ConfigValueStr{Value: "global"})
+		cfg.AddItem("user", ctypes.ConfigValueStr{Value: "jane"})
+		Convey("resetting it restores the config of the task and drops the global keys", func() {
+			err := resetPluginConfig(prNodes, nil, w.CollectNode.ProcessNodes, nil)
+			So(err, ShouldBeNil)
+			So(cfg.Table(), ShouldResemble, map[string]ctypes.ConfigValue{"host": ctypes.ConfigValueStr{Value: "localhost"}})
+		})
+		Convey("a workflow not matching the workflow map is refused", func() {
+			err := resetPluginConfig(nil, nil, w.CollectNode.ProcessNodes, nil)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
 func TestWorkJobs(t *testing.T) {
 	// log.SetLevel(log.DebugLevel)
 	Convey("Test speed and concurrency of TestWorkJobs\n", t, func() {
 		Convey("submit multiple jobs\n", func() {
 			m1 := &Mock1{queue: make(map[string]int)}
-			pj := newCollectorJob(nil, time.Second*1, m1, nil, "")
+			pj := newCollectorJob(nil, time.Second*1, m1, nil, "", core.TaskPriorityNormal)
 			prs := make([]*processNode, 0)
 			pus := make([]*publishNode, 0)
 			counter := 0
@@ -335,14 +406,14 @@ func TestWorkJobs(t *testing.T) {
 				prs = append(prs, pr)
 				pus = append(pus, pu)
 			}
-			workJobs(prs, pus, t, pj)
+			workJobs(prs, pus, t, pj, nil)
 			So(t.failedRuns, ShouldEqual, 0)
 			So(m1.queue["processor"], ShouldEqual, 3)
 			So(m1.queue["publisher"], ShouldEqual, 3)
 		})
 		Convey("submit multiple jobs with nesting", func() {
 			m2 := &Mock1{queue: make(map[string]int)}
-			pj := newCollectorJob(nil, time.Second*1, m2, nil, "")
+			pj := newCollectorJob(nil, time.Second*1, m2, nil, "", core.TaskPriorityNormal)
 			prs := make([]*processNode, 0)
 			pus := make([]*publishNode, 0)
 			counter := 0
@@ -371,7 +442,7 @@ func TestWorkJobs(t *testing.T) {
 				pr.ProcessNodes = cprs
 				pr.PublishNodes = cpus
 			}
-			workJobs(prs, pus, t, pj)
+			workJobs(prs, pus, t, pj, nil)
 			So(t.failedRuns, ShouldEqual, 0)
 			// (3*3)+3
 			So(m2.queue["processor"], ShouldEqual, 12)
@@ -383,7 +454,7 @@ func TestWorkJobs(t *testing.T) {
 			m3 := &Mock1{queue: make(map[string]int)}
 			// make the 13th job fail
 			m3.errorIndex = 13
-			pj := newCollectorJob(nil, time.Second*1, m3, nil, "")
+			pj := newCollectorJob(nil, time.Second*1, m3, nil, "", core.TaskPriorityNormal)
 			prs := make([]*processNode, 0)
 			pus := make([]*publishNode, 0)
 			counter := 0
@@ -412,7 +483,7 @@ func TestWorkJobs(t *testing.T) {
 				pr.ProcessNodes = cprs
 				pr.PublishNodes = cpus
 			}
-			workJobs(prs, pus, t, pj)
+			workJobs(prs, pus, t, pj, nil)
 			So(t.failedRuns, ShouldEqual, 1)
 			So(t.lastFailureMessage, ShouldEqual, "I am an error")
 			// (3*3)+3
@@ -31,8 +31,8 @@
 [ -f $SNAP_PATH/plugin/snap-processor-passthru ] || { echo 'Error: $SNAP_PATH/plugin/snap-processor-passthru does not exist. Run make to build it.' ; exit 1; }
 [ -f $SNAP_PATH/plugin/snap-publisher-file ] || { echo 'Error: $SNAP_PATH/plugin/snap-publisher-file does not exist. Run make to build it.' ; exit 1; }
 
-TEST_DIRS="cmd/ control/ core/ mgmt/ pkg/ snapd.go scheduler/"
-VET_DIRS="./cmd/... ./control/... ./core/... ./mgmt/... ./pkg/... ./scheduler/... ."
+TEST_DIRS="client/ cmd/ control/ core/ mgmt/ pkg/ snapd.go scheduler/"
+VET_DIRS="./client/... ./cmd/... ./control/... ./core/... ./mgmt/... ./pkg/... ./scheduler/... ."
 
 set -e
 
@@ -20,6 +20,7 @@ limitations under the License.
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -35,15 +36,27 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/codegangsta/cli"
+	"github.com/ghodss/yaml"
 	"github.com/vrischmann/jsonutil"
 
 	"github.com/intelsdi-x/snap/control"
 	"github.com/intelsdi-x/snap/core"
 	"github.com/intelsdi-x/snap/core/serror"
+	"github.com/intelsdi-x/snap/mgmt/alert"
+	"github.com/intelsdi-x/snap/mgmt/mdns"
+	"github.com/intelsdi-x/snap/mgmt/notify"
+	"github.com/intelsdi-x/snap/mgmt/reconcile"
 	"github.com/intelsdi-x/snap/mgmt/rest"
+	"github.com/intelsdi-x/snap/mgmt/rest/request"
 	"github.com/intelsdi-x/snap/mgmt/tribe"
 	"github.com/intelsdi-x/snap/mgmt/tribe/agreement"
+	"github.com/intelsdi-x/snap/mgmt/tribe/worker"
 	"github.com/intelsdi-x/snap/pkg/cfgfile"
+	"github.com/intelsdi-x/snap/pkg/datadir"
+	"github.com/intelsdi-x/snap/pkg/logbuffer"
+	"github.com/intelsdi-x/snap/pkg/netaddr"
+	"github.com/intelsdi-x/snap/pkg/schedule"
+	"github.com/intelsdi-x/snap/pkg/tlspolicy"
 	"github.com/intelsdi-x/snap/scheduler"
 )
 
@@ -56,6 +69,11 @@ var (
 		Name:  "api-port,  p",
 		Usage: "API port (Default: 8181)",
 	}
+	flAPIAddr = cli.StringFlag{
+		Name:   "api-addr",
+		Usage:  "IP address or network interface the API listens on (Default: all)",
+		EnvVar: "SNAP_API_ADDR",
+	}
 	flMaxProcs = cli.IntFlag{
 		Name:   "max-procs, c",
 		Usage:  "Set max cores to use for snap Agent. Default is 1 core.",
@@ -97,6 +115,11 @@ var (
 		Usage:  "The time limit for which a metric cache entry is valid",
 		EnvVar: "SNAP_CACHE_EXPIRATION",
 	}
+	flDataDir = cli.StringFlag{
+		Name:   "data-dir",
+		Usage:  "Directory holding snapd's on-disk state (uploaded plugins, keyrings, plugin logs, ...)",
+		EnvVar: "SNAP_DATA_DIR",
+	}
 	flConfig = cli.StringFlag{
 		Name:   "config",
 		Usage:  "A path to a config file",
@@ -118,6 +141,37 @@ var (
 		Name:  "rest-auth",
 		Usage: "Enables snap's REST API authentication",
 	}
+	flCheckConfig = cli.StringFlag{
+		Name:  "check-config",
+		Usage: "Validate the given config file and exit, with a non-zero status if it is invalid",
+	}
+	flPrintConfig = cli.BoolFlag{
+		Name:  "print-config",
+		Usage: "Print the effective configuration (defaults, config file, environment and flags merged) and exit",
+	}
+	flSimulate = cli.StringFlag{
+		Name:  "simulate",
+		Usage: "Validate the given task manifest, print when its schedule fires on a simulated clock and exit",
+	}
+	flDesiredState = cli.StringFlag{
+		Name:   "desired-state",
+		Usage:  "A path to a desired-state file declaring the plugins and tasks snapd converges to",
+		EnvVar: "SNAP_DESIRED_STATE",
+	}
+	flMDNS = cli.BoolFlag{
+		Name:   "mdns",
+		Usage:  "Advertise snapd's REST API and tribe port on the local network over mDNS",
+		EnvVar: "SNAP_MDNS",
+	}
+	flSimulateRuns = cli.IntFlag{
+		Name:  "simulate-runs",
+		Usage: "The number of runs of the task printed by --simulate",
+		Value: 10,
+	}
+	flSimulateResponses = cli.StringFlag{
+		Name:  "simulate-responses",
+		Usage: "A file of plugin responses recorded by snapd (record_path) the runs of --simulate collect and process with",
+	}
 
 	gitversion  string
 	coreModules []coreModule
@@ -143,18 +197,28 @@ const (
 	defaultLogLevel   int    = 3
 	defaultGoMaxProcs int    = 1
 	defaultLogPath    string = ""
+	defaultDataDir    string = ""
 	defaultConfigPath string = "/etc/snap/snapd.conf"
 )
 
+// prefix of the environment variables overriding configuration values
+const envPrefix = "SNAP"
+
 // holds the configuration passed in through the SNAP config file
 type Config struct {
 	LogLevel   int               `json:"log_level,omitempty"yaml:"log_level,omitempty"`
 	GoMaxProcs int               `json:"gomaxprocs,omitempty"yaml:"gomaxprocs,omitempty"`
 	LogPath    string            `json:"log_path,omitempty"yaml:"log_path,omitempty"`
+	DataDir    string            `json:"data_dir,omitempty"yaml:"data_dir,omitempty"`
 	Control    *control.Config   `json:"control,omitempty"yaml:"control,omitempty"`
 	Scheduler  *scheduler.Config `json:"scheduler,omitempty"yaml:"scheduler,omitempty"`
 	RestAPI    *rest.Config      `json:"restapi,omitempty"yaml:"restapi,omitempty"`
 	Tribe      *tribe.Config     `json:"tribe,omitempty"yaml:"tribe,omitempty"`
+	Alert      *alert.Config     `json:"alert,omitempty"yaml:"alert,omitempty"`
+	Notify     *notify.Config    `json:"notify,omitempty"yaml:"notify,omitempty"`
+	Reconcile  *reconcile.Config `json:"reconcile,omitempty"yaml:"reconcile,omitempty"`
+	MDNS       *mdns.Config      `json:"mdns,omitempty"yaml:"mdns,omitempty"`
+	TLS        *tlspolicy.Config `json:"tls,omitempty"yaml:"tls,omitempty"`
 }
 
 type coreModule interface {
@@ -163,6 +227,17 @@ type coreModule interface {
 	Name() string
 }
 
+// runs is implemented by the modules reporting whether they are started
+type runs interface {
+	Running() bool
+}
+
+// catalogsPlugins is implemented by control, listing the plugins loaded
+type catalogsPlugins interface {
+	runs
+	PluginCatalog() core.PluginCatalog
+}
+
 type managesTribe interface {
 	GetAgreement(name string) (*agreement.Agreement, serror.SnapError)
 	GetAgreements() map[string]*agreement.Agreement
@@ -172,6 +247,16 @@ type managesTribe interface {
 	LeaveAgreement(agreementName, memberName string) serror.SnapError
 	GetMembers() []string
 	GetMember(name string) *agreement.Member
+	ClockSkew(name string) (time.Duration, bool)
+	AddPlugin(agreementName string, p agreement.Plugin) error
+	RemovePlugin(agreementName string, p agreement.Plugin) error
+	AddTask(agreementName string, task agreement.Task) serror.SnapError
+	RemoveTask(agreementName string, task agreement.Task) serror.SnapError
+	StartTask(agreementName string, task agreement.Task) serror.SnapError
+	StopTask(agreementName string, task agreement.Task) serror.SnapError
+	ValidateTask(tr *request.TaskCreationRequest) serror.SnapError
+	StartRollout(agreementName, taskID string, tr *request.TaskCreationRequest, opts agreement.RolloutOptions) (*agreement.Rollout, serror.SnapError)
+	GetRollout(agreementName string) (*agreement.Rollout, serror.SnapError)
 }
 
 func main() {
@@ -187,6 +272,7 @@ func main() {
 	app.Flags = []cli.Flag{
 		flAPIDisabled,
 		flAPIPort,
+		flAPIAddr,
 		flLogLevel,
 		flLogPath,
 		flMaxProcs,
@@ -195,11 +281,19 @@ func main() {
 		flCache,
 		flPluginTrust,
 		flKeyringPaths,
+		flDataDir,
 		flRestCert,
 		flConfig,
 		flRestHTTPS,
 		flRestKey,
 		flRestAuth,
+		flCheckConfig,
+		flPrintConfig,
+		flSimulate,
+		flSimulateRuns,
+		flSimulateResponses,
+		flDesiredState,
+		flMDNS,
 	}
 	app.Flags = append(app.Flags, scheduler.Flags...)
 	app.Flags = append(app.Flags, tribe.Flags...)
@@ -209,18 +303,36 @@ func main() {
 }
 
 func action(ctx *cli.Context) {
+	if ctx.IsSet("check-config") {
+		os.Exit(checkConfig(ctx.String("check-config")))
+	}
+	if ctx.IsSet("simulate") {
+		os.Exit(simulate(ctx.String("simulate"), ctx.Int("simulate-runs"), ctx.String("simulate-responses")))
+	}
+
 	// get default configuration
 	cfg := getDefaultConfig()
 
 	// read config file
 	readConfig(cfg, ctx.String("config"))
 
+	// apply values set through SNAP_* environment variables, named after
+	// the keys in the configuration file (e.g. SNAP_RESTAPI_PORT)
+	if err := cfgfile.ApplyEnv(envPrefix, cfg); err != nil {
+		log.Fatal(err)
+	}
+
 	// apply values that may have been passed from the command line
 	// to the configuration that we have built so far, overriding the
 	// values that may have already been set (if any) for the
 	// same variables in that configuration
 	applyCmdLineFlags(cfg, ctx)
 
+	if ctx.Bool("print-config") {
+		printConfig(cfg)
+		return
+	}
+
 	// If logPath is set, we verify the logPath and set it so that all logging
 	// goes to the log file instead of stdout.
 	logPath := cfg.LogPath
@@ -240,6 +352,8 @@ func action(ctx *cli.Context) {
 		defer file.Close()
 		log.SetOutput(file)
 	}
+	// keep the recent log entries to be tailed through the REST API
+	log.AddHook(logbuffer.Default)
 
 	log.Info("Starting snapd (version: ", gitversion, ")")
 
@@ -249,15 +363,85 @@ func action(ctx *cli.Context) {
 	// Validate log level and trust level settings for snapd
 	validateLevelSettings(cfg.LogLevel, cfg.Control.PluginTrust)
 
+	// Create and check the data directory before anything is written to it
+	dataDir := cfg.DataDir
+	if dataDir == "" {
+		dataDir = filepath.Join(os.TempDir(), "snap")
+		log.Warning("no data directory set, using ", dataDir, " (state will not survive a reboot)")
+	}
+	dd, err := datadir.New(dataDir)
+	if err != nil {
+		log.WithFields(
+			log.Fields{
+				"block":    "main",
+				"_module":  "snapd",
+				"data-dir": dataDir,
+			}).Fatal(err)
+	}
+	log.Info("using data directory: ", dd.Root())
+
 	c := control.New(cfg.Control)
+	// changes made to the plugin config at runtime are kept in the data
+	// directory, replacing the plugin config of the configuration file
+	if err := c.Config.SetPluginConfigFile(dd.Path(datadir.PluginConfigFile)); err != nil {
+		log.WithFields(
+			log.Fields{
+				"block":   "main",
+				"_module": "snapd",
+			}).Fatal(err)
+	}
+	c.SetSnapdVersion(gitversion)
+	c.SetDataDir(dd)
+	host := hostIdentity(cfg, dd)
+	c.SetHost(host)
 
 	coreModules = []coreModule{}
 
 	coreModules = append(coreModules, c)
 	s := scheduler.New(cfg.Scheduler)
 	s.SetMetricManager(c)
+	s.SetWALDir(dd.Path(datadir.WAL))
+	fileDir := cfg.Scheduler.FileDir
+	if fileDir == "" {
+		fileDir = dd.Path(datadir.Files)
+	}
+	s.SetFileDir(fileDir)
+	// the built-in publishers and the NATS triggers connect within the TLS
+	// policy
+	s.SetTLSPolicy(cfg.TLS)
+	schedule.RegisterTriggerSource("nats", schedule.NATSTriggerFactory(cfg.TLS.TLSConfig()))
+	// the principals of the REST API create tasks within their quotas
+	if cfg.RestAPI.Auth != nil {
+		s.SetPrincipalQuotas(cfg.RestAPI.Auth.Quotas)
+	}
+	// control releases the subscriptions of deleted tasks
+	s.RegisterEventHandler("control", c)
+	// the scheduler applies the plugin config changed at runtime to the
+	// running tasks
+	c.RegisterEventHandler("scheduler", s)
 	coreModules = append(coreModules, s)
 
+	// the alert manager evaluates the alert rules of the tasks against the
+	// metrics they collect
+	a := alert.New(cfg.Alert)
+	a.SetTaskManager(s)
+	s.RegisterEventHandler(alert.HandlerRegistrationName, a)
+	coreModules = append(coreModules, a)
+
+	// the notifier posts the events selected to the webhooks configured
+	cfg.Notify.TLSPolicy = cfg.TLS
+	n, err := notify.New(cfg.Notify)
+	if err != nil {
+		printErrorAndExit("notify", err)
+	}
+	c.RegisterEventHandler(notify.HandlerRegistrationName, n)
+	s.RegisterEventHandler(notify.HandlerRegistrationName, n)
+	a.RegisterEventHandler(notify.HandlerRegistrationName, n)
+	// the alert manager posts to its webhooks through the notifier, which
+	// retries and signs them
+	a.SetPoster(n)
+	coreModules = append(coreModules, n)
+
 	// Auth requested and not provided as part of config
 	if cfg.RestAPI.Enable && cfg.RestAPI.RestAuth && cfg.RestAPI.RestAuthPassword == "" {
 		fmt.Println("What password do you want to use for authentication?")
@@ -273,7 +457,11 @@ func action(ctx *cli.Context) {
 	var tr managesTribe
 	if cfg.Tribe.Enable {
 		cfg.Tribe.RestAPIPort = cfg.RestAPI.Port
-		if cfg.RestAPI.RestAuth {
+		cfg.Tribe.TLSPolicy = cfg.TLS
+		// the members forwarding their metrics find the aggregator by its tag
+		cfg.Tribe.Aggregator = cfg.Scheduler.Aggregator != nil && cfg.Scheduler.Aggregator.Enable
+		// members signing their requests don't share the REST API password
+		if cfg.RestAPI.RestAuth && !cfg.Tribe.SignRequests {
 			cfg.Tribe.RestAPIPassword = cfg.RestAPI.RestAuthPassword
 		}
 		log.Info("Tribe is enabled")
@@ -285,6 +473,9 @@ func action(ctx *cli.Context) {
 		t.SetPluginCatalog(c)
 		s.RegisterEventHandler("tribe", t)
 		t.SetTaskManager(s)
+		s.SetSharder(t)
+		s.SetMemberResolver(t)
+		t.RegisterEventHandler(notify.HandlerRegistrationName, n)
 		coreModules = append(coreModules, t)
 		tr = t
 	}
@@ -310,6 +501,10 @@ func action(ctx *cli.Context) {
 	// Keyring checking for trust levels 1 and 2
 	if cfg.Control.PluginTrust > 0 {
 		keyrings := filepath.SplitList(cfg.Control.KeyringPaths)
+		// Keyrings placed in the data directory are always used
+		if files, err := ioutil.ReadDir(dd.Path(datadir.Keyrings)); err == nil && len(files) > 0 {
+			keyrings = append(keyrings, dd.Path(datadir.Keyrings))
+		}
 		if len(keyrings) == 0 {
 			log.WithFields(
 				log.Fields{
@@ -392,6 +587,10 @@ func action(ctx *cli.Context) {
 		}
 	}
 
+	// the files of the plugins which could not be autoloaded, failing the
+	// readiness probe until they are loaded
+	var autoloadFailures []string
+
 	//Autodiscover
 	if cfg.Control.AutoDiscoverPath != "" {
 		log.Info("auto discover path is enabled")
@@ -417,10 +616,37 @@ func action(ctx *cli.Context) {
 						"autodiscoverpath": fullPath,
 					}).Fatal(err)
 			}
+			manifest, err := control.ReadAutodiscoverManifest(fullPath)
+			if err != nil {
+				log.WithFields(
+					log.Fields{
+						"_block":           "main",
+						"_module":          "snapd",
+						"autodiscoverpath": fullPath,
+					}).Fatal(err)
+			}
+			if manifest != nil {
+				log.Info("loading plugins pinned by manifest: ", path.Join(fullPath, control.AutodiscoverManifestFile))
+			}
 			for _, file := range files {
-				if file.IsDir() {
+				if file.IsDir() || file.Name() == control.AutodiscoverManifestFile {
 					continue
 				}
+				var entry *control.ManifestEntry
+				if manifest != nil {
+					entry, err = manifest.Entry(file.Name())
+					if err != nil {
+						if !strings.HasSuffix(file.Name(), ".asc") {
+							log.WithFields(log.Fields{
+								"_block":           "main",
+								"_module":          "snapd",
+								"autodiscoverpath": fullPath,
+								"plugin":           file.Name(),
+							}).Warning(err)
+						}
+						continue
+					}
+				}
 				if strings.HasSuffix(file.Name(), ".aci") || !(strings.HasSuffix(file.Name(), ".asc")) {
 					rp, err := core.NewRequestedPlugin(path.Join(fullPath, file.Name()))
 					if err != nil {
@@ -430,6 +656,8 @@ func action(ctx *cli.Context) {
 							"autodiscoverpath": fullPath,
 							"plugin":           file,
 						}).Error(err)
+						autoloadFailures = append(autoloadFailures, file.Name())
+						continue
 					}
 					signatureFile := file.Name() + ".asc"
 					if _, err := os.Stat(path.Join(fullPath, signatureFile)); err == nil {
@@ -443,7 +671,39 @@ func action(ctx *cli.Context) {
 							}).Error(err)
 						}
 					}
+					if entry != nil {
+						if err := entry.CheckRequested(rp); err != nil {
+							log.WithFields(log.Fields{
+								"_block":           "main",
+								"_module":          "snapd",
+								"autodiscoverpath": fullPath,
+								"plugin":           file.Name(),
+							}).Error(err)
+							autoloadFailures = append(autoloadFailures, file.Name())
+							continue
+						}
+					}
 					pl, err := c.Load(rp)
+					if err == nil && entry != nil {
+						if merr := entry.CheckLoaded(pl); merr != nil {
+							log.WithFields(log.Fields{
+								"_block":           "main",
+								"_module":          "snapd",
+								"autodiscoverpath": fullPath,
+								"plugin":           file.Name(),
+							}).Error(merr)
+							if _, uerr := c.Unload(pl); uerr != nil {
+								log.WithFields(log.Fields{
+									"_block":           "main",
+									"_module":          "snapd",
+									"autodiscoverpath": fullPath,
+									"plugin":           file.Name(),
+								}).Error(uerr)
+							}
+							autoloadFailures = append(autoloadFailures, file.Name())
+							continue
+						}
+					}
 					if err != nil {
 						log.WithFields(log.Fields{
 							"_block":           "main",
@@ -451,6 +711,7 @@ func action(ctx *cli.Context) {
 							"autodiscoverpath": fullPath,
 							"plugin":           file,
 						}).Error(err)
+						autoloadFailures = append(autoloadFailures, file.Name())
 					} else {
 						log.WithFields(log.Fields{
 							"_block":           "main",
@@ -469,15 +730,33 @@ func action(ctx *cli.Context) {
 		log.Info("auto discover path is disabled")
 	}
 
+	// The plugins autoloaded above may be shared by the agreements
+	if tr != nil && len(cfg.Tribe.Agreements) > 0 {
+		bootstrapAgreements(cfg.Tribe, tr, c, s)
+	}
+
+	// The reconciler starts once the plugins are autoloaded and the plugin
+	// trust is set, converging them and the tasks to the desired state
+	rc := reconcile.New(cfg.Reconcile)
+	rc.SetPluginManager(c)
+	rc.SetTaskManager(s)
+	if err := startModule(rc); err != nil {
+		printErrorAndExit(rc.Name(), err)
+	}
+
 	//Setup RESTful API if it was enbled in th configuration
 	if cfg.RestAPI.Enable {
+		cfg.RestAPI.TLSPolicy = cfg.TLS
 		r, err := rest.New(cfg.RestAPI)
 		if err != nil {
 			log.Fatal(err)
 		}
 		r.BindMetricManager(c)
 		r.BindConfigManager(c.Config)
+		r.SetDataDir(dd)
 		r.BindTaskManager(s)
+		r.BindAlertManager(a)
+		r.BindLogBuffer(logbuffer.Default)
 		//Rest Authentication
 		if cfg.RestAPI.RestAuth {
 			log.Info("REST API authentication is enabled")
@@ -491,14 +770,28 @@ func action(ctx *cli.Context) {
 
 		if tr != nil {
 			r.BindTribeManager(tr)
+			if cfg.Tribe.SignRequests {
+				log.Info("REST API authenticates the requests signed by the members of the tribe")
+				r.AddAuthProvider(rest.NewMemberAuthProvider(cfg.Tribe.RequestSigningKey()))
+			}
 		}
+		addHealthChecks(r, c, s, autoloadFailures)
 		go monitorErrors(r.Err())
-		r.Start(fmt.Sprintf(":%d", cfg.RestAPI.Port))
+		addr, err := cfg.RestAPI.ListenAddr()
+		if err != nil {
+			printErrorAndExit("rest", err)
+		}
+		r.Start(addr)
 		log.Info("REST API is enabled")
 	} else {
 		log.Info("REST API is disabled")
 	}
 
+	// snapd is advertised once its REST API listens
+	if cfg.MDNS.Enable {
+		advertise(cfg, host)
+	}
+
 	log.WithFields(
 		log.Fields{
 			"block":   "main",
@@ -518,10 +811,16 @@ func getDefaultConfig() *Config {
 		LogLevel:   defaultLogLevel,
 		GoMaxProcs: defaultGoMaxProcs,
 		LogPath:    defaultLogPath,
+		DataDir:    defaultDataDir,
 		Control:    control.GetDefaultConfig(),
 		Scheduler:  scheduler.GetDefaultConfig(),
 		RestAPI:    rest.GetDefaultConfig(),
 		Tribe:      tribe.GetDefaultConfig(),
+		Alert:      alert.GetDefaultConfig(),
+		Notify:     notify.GetDefaultConfig(),
+		Reconcile:  reconcile.GetDefaultConfig(),
+		MDNS:       mdns.GetDefaultConfig(),
+		TLS:        &tlspolicy.Config{},
 	}
 }
 
@@ -559,11 +858,47 @@ func defaultConfigFile() bool {
 	return true
 }
 
+// flagIsSet returns true if the flag was given on the command line or
+// through the environment variable listed for it
+func flagIsSet(ctx *cli.Context, flagName string) bool {
+	if ctx.IsSet(flagName) {
+		return true
+	}
+	if ctx.App == nil {
+		return false
+	}
+	for _, f := range ctx.App.Flags {
+		var name, envVar string
+		switch fl := f.(type) {
+		case cli.StringFlag:
+			name, envVar = fl.Name, fl.EnvVar
+		case cli.IntFlag:
+			name, envVar = fl.Name, fl.EnvVar
+		case cli.BoolFlag:
+			name, envVar = fl.Name, fl.EnvVar
+		case cli.DurationFlag:
+			name, envVar = fl.Name, fl.EnvVar
+		default:
+			continue
+		}
+		if envVar == "" || strings.TrimSpace(strings.Split(name, ",")[0]) != flagName {
+			continue
+		}
+		for _, e := range strings.Split(envVar, ",") {
+			if os.Getenv(strings.TrimSpace(e)) != "" {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
 // used to set fields in the configuration to values from the
 // command line context if the corresponding flagName is set
 // in that context
 func setBoolVal(field bool, ctx *cli.Context, flagName string, inverse ...bool) bool {
-	if ctx.IsSet(flagName) {
+	if flagIsSet(ctx, flagName) {
 		field = ctx.Bool(flagName)
 		if len(inverse) > 0 {
 			field = !field
@@ -573,33 +908,176 @@ func setBoolVal(field bool, ctx *cli.Context, flagName string, inverse ...bool)
 }
 
 func setStringVal(field string, ctx *cli.Context, flagName string) string {
-	if ctx.IsSet(flagName) {
+	if flagIsSet(ctx, flagName) {
 		field = ctx.String(flagName)
 	}
 	return field
 }
 
 func setIntVal(field int, ctx *cli.Context, flagName string) int {
-	if ctx.IsSet(flagName) {
+	if flagIsSet(ctx, flagName) {
 		field = ctx.Int(flagName)
 	}
 	return field
 }
 
 func setUIntVal(field uint, ctx *cli.Context, flagName string) uint {
-	if ctx.IsSet(flagName) {
+	if flagIsSet(ctx, flagName) {
 		field = uint(ctx.Int(flagName))
 	}
 	return field
 }
 
 func setDurationVal(field time.Duration, ctx *cli.Context, flagName string) time.Duration {
-	if ctx.IsSet(flagName) {
+	if flagIsSet(ctx, flagName) {
 		field = ctx.Duration(flagName)
 	}
 	return field
 }
 
+// checkConfig parses and validates the config file at fpath, printing any
+// problems found, and returns the exit status for snapd
+func checkConfig(fpath string) int {
+	errs := []error{}
+	cfg := getDefaultConfig()
+	b, err := ioutil.ReadFile(fpath)
+	if err == nil {
+		// yaml.Unmarshal handles JSON as well and reports where parsing failed
+		err = yaml.Unmarshal(b, cfg)
+	}
+	if err != nil {
+		errs = append(errs, err)
+	} else {
+		unknown, err := cfgfile.UnknownKeys(fpath, cfg)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, k := range unknown {
+			errs = append(errs, fmt.Errorf("%s: unknown key", k))
+		}
+		errs = append(errs, validateConfig(cfg)...)
+	}
+	if len(errs) > 0 {
+		fmt.Fprintf(os.Stderr, "%s is not valid:\n", fpath)
+		for _, e := range errs {
+			fmt.Fprintf(os.Stderr, "  %v\n", e)
+		}
+		return 1
+	}
+	fmt.Printf("%s is valid\n", fpath)
+	return 0
+}
+
+// simulate runs the schedule of a task manifest through on a simulated clock,
+// collecting and processing with the recorded plugin responses if any
+func simulate(fpath string, runs int, responsesPath string) int {
+	var responses scheduler.SimulatedResponses
+	if responsesPath != "" {
+		rr, err := control.NewReplayedResponses(responsesPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		responses = rr
+	}
+	tr := request.TaskCreationRequest{}
+	b, err := ioutil.ReadFile(fpath)
+	if err == nil {
+		// yaml.Unmarshal handles JSON as well
+		err = yaml.Unmarshal(b, &tr)
+	}
+	var sch schedule.Schedule
+	if err == nil {
+		sch, err = rest.MakeSchedule(tr.Schedule)
+	}
+	var sims []scheduler.SimulatedRun
+	if err == nil {
+		sims, err = scheduler.Simulate(sch, tr.Workflow, time.Now(), runs, responses)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s is not valid:\n  %v\n", fpath, err)
+		return 1
+	}
+	fmt.Printf("%s is valid, its task would run at:\n", fpath)
+	for _, s := range sims {
+		line := "  " + s.Time.Format(time.RFC3339Nano)
+		if s.Missed > 0 {
+			line += fmt.Sprintf(" (%d missed)", s.Missed)
+		}
+		switch {
+		case len(s.Errors) > 0:
+			line += fmt.Sprintf(": failed with %v", s.Errors[0])
+		case responses != nil:
+			line += fmt.Sprintf(": %d metrics", s.Metrics)
+		}
+		fmt.Println(line)
+	}
+	if len(sims) < runs {
+		fmt.Println("  and then the schedule ends")
+	}
+	return 0
+}
+
+// validateConfig returns the problems found in the configuration
+func validateConfig(cfg *Config) []error {
+	var errs []error
+	if cfg.LogLevel < 1 || cfg.LogLevel > 5 {
+		errs = append(errs, fmt.Errorf("log_level: %d is not between 1 and 5", cfg.LogLevel))
+	}
+	if cfg.GoMaxProcs < 1 {
+		errs = append(errs, fmt.Errorf("gomaxprocs: must be greater than 0"))
+	}
+	if cfg.LogPath != "" {
+		if f, err := os.Stat(cfg.LogPath); err != nil {
+			errs = append(errs, fmt.Errorf("log_path: %v", err))
+		} else if !f.IsDir() {
+			errs = append(errs, fmt.Errorf("log_path: %s is not a directory", cfg.LogPath))
+		}
+	}
+	// the data directory is created at startup so it does not have to exist
+	if cfg.DataDir != "" {
+		if f, err := os.Stat(cfg.DataDir); err == nil {
+			if !f.IsDir() {
+				errs = append(errs, fmt.Errorf("data_dir: %s is not a directory", cfg.DataDir))
+			} else if runtime.GOOS != "windows" && f.Mode().Perm()&0022 != 0 {
+				errs = append(errs, fmt.Errorf("data_dir: %s must not be group or world writable", cfg.DataDir))
+			}
+		}
+	}
+	errs = append(errs, cfg.Control.Validate()...)
+	errs = append(errs, cfg.Scheduler.Validate()...)
+	errs = append(errs, cfg.RestAPI.Validate()...)
+	errs = append(errs, cfg.Tribe.Validate()...)
+	errs = append(errs, cfg.Alert.Validate()...)
+	errs = append(errs, cfg.Notify.Validate()...)
+	errs = append(errs, cfg.Reconcile.Validate()...)
+	errs = append(errs, cfg.MDNS.Validate()...)
+	errs = append(errs, cfg.TLS.Validate()...)
+	// the principals of the REST API are scoped to the tenants of control
+	if cfg.RestAPI != nil && cfg.Control != nil {
+		for _, id := range cfg.RestAPI.Auth.TenantNames() {
+			if _, ok := cfg.Control.Tenants[id]; id != "" && !ok {
+				errs = append(errs, fmt.Errorf("restapi.auth.tenants.%s: not a tenant of control.tenants", id))
+			}
+		}
+	}
+	return errs
+}
+
+// Print the configuration in YAML, hiding the REST API password and tokens
+func printConfig(cfg *Config) {
+	// the fields tagged secret, e.g. passwords and keys, are masked
+	c, err := cfgfile.Redact(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(string(b))
+}
+
 // Apply the command line flags set (if any) to override the values
 // in the input configuration
 func applyCmdLineFlags(cfg *Config, ctx *cli.Context) {
@@ -609,6 +1087,7 @@ func applyCmdLineFlags(cfg *Config, ctx *cli.Context) {
 	cfg.GoMaxProcs = setIntVal(cfg.GoMaxProcs, ctx, "max-procs")
 	cfg.LogLevel = setIntVal(cfg.LogLevel, ctx, "log-level")
 	cfg.LogPath = setStringVal(cfg.LogPath, ctx, "log-path")
+	cfg.DataDir = setStringVal(cfg.DataDir, ctx, "data-dir")
 	// next for the flags related to the control package
 	cfg.Control.MaxRunningPlugins = setIntVal(cfg.Control.MaxRunningPlugins, ctx, "max-running-plugins")
 	cfg.Control.PluginTrust = setIntVal(cfg.Control.PluginTrust, ctx, "plugin-trust")
@@ -618,20 +1097,29 @@ func applyCmdLineFlags(cfg *Config, ctx *cli.Context) {
 	// next for the RESTful server related flags
 	cfg.RestAPI.Enable = setBoolVal(cfg.RestAPI.Enable, ctx, "disable-api", invertBoolean)
 	cfg.RestAPI.Port = setIntVal(cfg.RestAPI.Port, ctx, "api-port")
+	cfg.RestAPI.Address = setStringVal(cfg.RestAPI.Address, ctx, "api-addr")
 	cfg.RestAPI.HTTPS = setBoolVal(cfg.RestAPI.HTTPS, ctx, "rest-https")
 	cfg.RestAPI.RestCertificate = setStringVal(cfg.RestAPI.RestCertificate, ctx, "rest-cert")
 	cfg.RestAPI.RestKey = setStringVal(cfg.RestAPI.RestKey, ctx, "rest-key")
 	cfg.RestAPI.RestAuth = setBoolVal(cfg.RestAPI.RestAuth, ctx, "rest-auth")
-	cfg.RestAPI.RestAuthPassword = setStringVal(cfg.RestAPI.RestAuthPassword, ctx, "rest-auth-pwd")
 	// next for the scheduler related flags
 	cfg.Scheduler.WorkManagerQueueSize = setUIntVal(cfg.Scheduler.WorkManagerQueueSize, ctx, "work-manager-queue-size")
 	cfg.Scheduler.WorkManagerPoolSize = setUIntVal(cfg.Scheduler.WorkManagerPoolSize, ctx, "work-manager-pool-size")
-	// and finally for the tribe-related flags
+	// next for the tribe-related flags
 	cfg.Tribe.Name = setStringVal(cfg.Tribe.Name, ctx, "tribe-node-name")
 	cfg.Tribe.Enable = setBoolVal(cfg.Tribe.Enable, ctx, "tribe")
 	cfg.Tribe.BindAddr = setStringVal(cfg.Tribe.BindAddr, ctx, "tribe-addr")
 	cfg.Tribe.BindPort = setIntVal(cfg.Tribe.BindPort, ctx, "tribe-port")
+	cfg.Tribe.AdvertiseAddr = setStringVal(cfg.Tribe.AdvertiseAddr, ctx, "tribe-advertise-addr")
+	cfg.Tribe.AdvertisePort = setIntVal(cfg.Tribe.AdvertisePort, ctx, "tribe-advertise-port")
 	cfg.Tribe.Seed = setStringVal(cfg.Tribe.Seed, ctx, "tribe-seed")
+	cfg.Tribe.TLSCertificate = setStringVal(cfg.Tribe.TLSCertificate, ctx, "tribe-tls-cert")
+	cfg.Tribe.TLSKey = setStringVal(cfg.Tribe.TLSKey, ctx, "tribe-tls-key")
+	cfg.Tribe.TLSCACertificate = setStringVal(cfg.Tribe.TLSCACertificate, ctx, "tribe-tls-ca-cert")
+	// and finally for the desired state the reconciler converges to
+	cfg.Reconcile.File = setStringVal(cfg.Reconcile.File, ctx, "desired-state")
+	// and whether snapd is advertised on the local network
+	cfg.MDNS.Enable = setBoolVal(cfg.MDNS.Enable, ctx, "mdns")
 }
 
 func monitorErrors(ch <-chan error) {
@@ -713,6 +1201,213 @@ func startModule(m coreModule) error {
 	return err
 }
 
+// hostIdentity returns the host added to the metrics snapd collects: its
+// hostname, the address tribe advertises, the agent ID kept in the data
+// directory and, with tribe enabled, the name of the member.
+func hostIdentity(cfg *Config, dd *datadir.DataDir) core.Host {
+	h := core.Host{IP: cfg.Tribe.AdvertiseAddr}
+	if h.IP == "" {
+		h.IP, _ = netaddr.Resolve(cfg.Tribe.BindAddr)
+	}
+	h.Hostname, _ = os.Hostname()
+	id, err := dd.AgentID()
+	if err != nil {
+		log.WithFields(
+			log.Fields{
+				"block":   "main",
+				"_module": "snapd",
+			}).Warn("unable to read the agent ID: ", err)
+	}
+	h.AgentID = id
+	if cfg.Tribe.Enable {
+		h.Member = cfg.Tribe.Name
+	}
+	return h
+}
+
+// advertise advertises the REST API and tribe port of snapd on the local
+// network. Failing to is not fatal: snapd is reachable all the same.
+func advertise(cfg *Config, host core.Host) {
+	cfg.MDNS.Version = gitversion
+	cfg.MDNS.AgentID = host.AgentID
+	cfg.MDNS.IP = host.IP
+	if cfg.RestAPI.Enable {
+		cfg.MDNS.RestAPIPort = cfg.RestAPI.Port
+		cfg.MDNS.RestAPIProto = "http"
+		if cfg.RestAPI.HTTPS {
+			cfg.MDNS.RestAPIProto = "https"
+		}
+	}
+	if cfg.Tribe.Enable {
+		cfg.MDNS.TribeMember = cfg.Tribe.Name
+		cfg.MDNS.TribePort = cfg.Tribe.BindPort
+		if cfg.Tribe.AdvertisePort > 0 {
+			cfg.MDNS.TribePort = cfg.Tribe.AdvertisePort
+		}
+	}
+	m := mdns.New(cfg.MDNS)
+	if err := startModule(m); err != nil {
+		log.WithFields(
+			log.Fields{
+				"block":   "main",
+				"_module": "snapd",
+			}).Warn("unable to advertise snapd over mDNS: ", err)
+	}
+}
+
+// bootstrapAgreements creates the agreements declared in the tribe
+// configuration which do not exist yet. The member joins the agreements
+// selecting it, loads their plugins and creates their tasks. A task gets an
+// ID derived from the names of the agreement and the task, so that it is
+// created once whichever members bootstrap it.
+func bootstrapAgreements(cfg *tribe.Config, tr managesTribe, c worker.ManagesPlugins, s worker.ManagesTasks) {
+	for _, spec := range cfg.Agreements {
+		logger := log.WithFields(log.Fields{
+			"_block":    "bootstrap-agreements",
+			"_module":   "snapd",
+			"agreement": spec.Name,
+		})
+		if _, err := tr.GetAgreement(spec.Name); err != nil {
+			if err := tr.AddAgreement(spec.Name); err != nil {
+				logger.Error(err)
+				continue
+			}
+			logger.Info("agreement created")
+		}
+		if !spec.Selects(cfg.Name) {
+			continue
+		}
+		if a, _ := tr.GetAgreement(spec.Name); a == nil || a.Members[cfg.Name] == nil {
+			if err := tr.JoinAgreement(spec.Name, cfg.Name); err != nil {
+				logger.Error(err)
+				continue
+			}
+			logger.Info("agreement joined")
+		}
+		for _, p := range spec.Plugins {
+			if err := bootstrapPlugin(spec.Name, p, tr, c); err != nil {
+				logger.WithFields(log.Fields{
+					"plugin-name": p.Name,
+					"plugin-path": p.Path,
+				}).Error(err)
+			}
+		}
+		for _, t := range spec.Tasks {
+			if err := bootstrapTask(spec.Name, t, tr, s); err != nil {
+				logger.WithField("task", t.Name).Error(err)
+			}
+		}
+	}
+}
+
+// bootstrapPlugin loads the plugin from its path, and adds the plugin to the
+// agreement when it was loaded beforehand or can be downloaded from its URL.
+func bootstrapPlugin(name string, p agreement.PluginSpec, tr managesTribe, c worker.ManagesPlugins) error {
+	if p.Path != "" {
+		rp, err := core.NewRequestedPlugin(p.Path)
+		if err != nil {
+			return err
+		}
+		// loading the plugin adds it to the agreement of the member
+		if _, serr := c.Load(rp); serr == nil || p.Name == "" {
+			return serr
+		}
+	}
+	for _, lp := range c.PluginCatalog() {
+		if lp.Name() != p.Name || lp.TypeName() != p.Type || (p.Version != 0 && lp.Version() != p.Version) {
+			continue
+		}
+		typ, _ := core.ToPluginType(lp.TypeName())
+		ap := agreement.Plugin{Name_: lp.Name(), Version_: lp.Version(), Type_: typ}
+		if a, _ := tr.GetAgreement(name); a != nil && a.PluginAgreement != nil {
+			if ok, _ := a.PluginAgreement.Plugins.Contains(ap); ok {
+				return nil
+			}
+		}
+		return tr.AddPlugin(name, ap)
+	}
+	// the members of the agreement download it from its URL when none
+	// of them has it
+	if p.URL != "" {
+		typ, _ := core.ToPluginType(p.Type)
+		ap := agreement.Plugin{Name_: p.Name, Version_: p.Version, Type_: typ, URL_: p.URL}
+		if a, _ := tr.GetAgreement(name); a != nil && a.PluginAgreement != nil {
+			if ok, _ := a.PluginAgreement.Plugins.Contains(ap); ok {
+				return nil
+			}
+		}
+		return tr.AddPlugin(name, ap)
+	}
+	// the other members of the agreement share it once they have it
+	return nil
+}
+
+// bootstrapTask creates the task unless it exists already, and adds it to
+// the agreement.
+func bootstrapTask(name string, t agreement.TaskSpec, tr managesTribe, s worker.ManagesTasks) error {
+	id := agreement.TaskID(name, t.Name)
+	task := agreement.Task{ID: id, StartOnCreate: !t.NoStart}
+	if _, err := s.GetTask(id); err != nil {
+		req, err := t.Request()
+		if err != nil {
+			return err
+		}
+		sch, err := rest.MakeSchedule(req.Schedule)
+		if err != nil {
+			return err
+		}
+		opts, err := rest.MakeTaskOptions(req)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, core.SetTaskID(id))
+		if _, errs := s.CreateTaskTribe(sch, req.Workflow, req.Start, opts...); errs != nil && len(errs.Errors()) > 0 {
+			return errs.Errors()[0]
+		}
+	}
+	if a, _ := tr.GetAgreement(name); a != nil && a.TaskAgreement != nil {
+		if ok, _ := a.TaskAgreement.Tasks.Contains(task); ok {
+			return nil
+		}
+	}
+	return tr.AddTask(name, task)
+}
+
+// addHealthChecks adds the checks of the liveness and readiness probes of
+// the REST API: snapd is alive while its scheduler runs, and ready once
+// control is started and every plugin found in the auto discover paths
+// is loaded, when they were autoloaded or later.
+func addHealthChecks(r *rest.Server, c catalogsPlugins, s runs, autoloadFailures []string) {
+	r.AddLivenessCheck("scheduler", func() error {
+		if !s.Running() {
+			return errors.New("scheduler is not running")
+		}
+		return nil
+	})
+	r.AddReadinessCheck("control", func() error {
+		if !c.Running() {
+			return errors.New("control is not started")
+		}
+		return nil
+	})
+	r.AddReadinessCheck("plugins", func() error {
+		loaded := make(map[string]bool)
+		for _, pl := range c.PluginCatalog() {
+			loaded[filepath.Base(pl.PluginPath())] = true
+		}
+		var failed []string
+		for _, name := range autoloadFailures {
+			if !loaded[name] {
+				failed = append(failed, name)
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("%d plugins failed to load: %s", len(failed), strings.Join(failed, ", "))
+		}
+		return nil
+	})
+}
+
 func printErrorAndExit(name string, err error) {
 	log.WithFields(
 		log.Fields{